@@ -15,6 +15,9 @@ type Artifact struct {
 	// A map of regions to alicloud image IDs.
 	AlicloudImages map[string]string
 
+	// The accounts the images are shared with.
+	SharedAccounts []string
+
 	// BuilderId is the unique ID for the builder that created this alicloud image
 	BuilderIdValue string
 
@@ -49,6 +52,10 @@ func (a *Artifact) String() string {
 	}
 
 	sort.Strings(alicloudImageStrings)
+	if len(a.SharedAccounts) > 0 {
+		return fmt.Sprintf("Alicloud images were created and shared with the accounts %s:\n\n%s",
+			strings.Join(a.SharedAccounts, ", "), strings.Join(alicloudImageStrings, "\n"))
+	}
 	return fmt.Sprintf("Alicloud images were created:\n\n%s", strings.Join(alicloudImageStrings, "\n"))
 }
 
@@ -45,3 +45,22 @@ func TestArtifactState_atlasMetadata(t *testing.T) {
 		t.Fatalf("bad: %#v", actual)
 	}
 }
+
+func TestArtifactString(t *testing.T) {
+	a := &Artifact{
+		AlicloudImages: map[string]string{
+			"west": "bar",
+			"east": "foo",
+		},
+	}
+	expected := "Alicloud images were created:\n\neast: foo\nwest: bar"
+	if result := a.String(); result != expected {
+		t.Fatalf("bad: %s", result)
+	}
+
+	a.SharedAccounts = []string{"123", "456"}
+	expected = "Alicloud images were created and shared with the accounts 123, 456:\n\neast: foo\nwest: bar"
+	if result := a.String(); result != expected {
+		t.Fatalf("bad: %s", result)
+	}
+}
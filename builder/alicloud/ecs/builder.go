@@ -173,6 +173,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&stepRegionCopyAlicloudImage{
 			AlicloudImageDestinationRegions: b.config.AlicloudImageDestinationRegions,
 			AlicloudImageDestinationNames:   b.config.AlicloudImageDestinationNames,
+			AlicloudImageCopyEncrypted:      b.config.AlicloudImageCopyEncrypted,
+			AlicloudImageCopyKMSKeyIds:      b.config.AlicloudImageCopyKMSKeyIds,
 			RegionId:                        b.config.AlicloudRegion,
 		},
 		&stepShareAlicloudImage{
@@ -198,6 +200,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	// Build the artifact and return it
 	artifact := &Artifact{
 		AlicloudImages: state.Get("alicloudimages").(map[string]string),
+		SharedAccounts: b.config.AlicloudImageShareAccounts,
 		BuilderIdValue: BuilderId,
 		Client:         client,
 	}
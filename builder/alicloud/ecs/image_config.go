@@ -25,17 +25,19 @@ type AlicloudDiskDevices struct {
 }
 
 type AlicloudImageConfig struct {
-	AlicloudImageName                 string   `mapstructure:"image_name"`
-	AlicloudImageVersion              string   `mapstructure:"image_version"`
-	AlicloudImageDescription          string   `mapstructure:"image_description"`
-	AlicloudImageShareAccounts        []string `mapstructure:"image_share_account"`
-	AlicloudImageUNShareAccounts      []string `mapstructure:"image_unshare_account"`
-	AlicloudImageDestinationRegions   []string `mapstructure:"image_copy_regions"`
-	AlicloudImageDestinationNames     []string `mapstructure:"image_copy_names"`
-	AlicloudImageForceDelete          bool     `mapstructure:"image_force_delete"`
-	AlicloudImageForceDeleteSnapshots bool     `mapstructure:"image_force_delete_snapshots"`
-	AlicloudImageForceDeleteInstances bool     `mapstructure:"image_force_delete_instances"`
-	AlicloudImageSkipRegionValidation bool     `mapstructure:"skip_region_validation"`
+	AlicloudImageName                 string            `mapstructure:"image_name"`
+	AlicloudImageVersion              string            `mapstructure:"image_version"`
+	AlicloudImageDescription          string            `mapstructure:"image_description"`
+	AlicloudImageShareAccounts        []string          `mapstructure:"image_share_account"`
+	AlicloudImageUNShareAccounts      []string          `mapstructure:"image_unshare_account"`
+	AlicloudImageDestinationRegions   []string          `mapstructure:"image_copy_regions"`
+	AlicloudImageDestinationNames     []string          `mapstructure:"image_copy_names"`
+	AlicloudImageCopyEncrypted        bool              `mapstructure:"image_copy_encrypted"`
+	AlicloudImageCopyKMSKeyIds        map[string]string `mapstructure:"image_copy_kms_key_ids"`
+	AlicloudImageForceDelete          bool              `mapstructure:"image_force_delete"`
+	AlicloudImageForceDeleteSnapshots bool              `mapstructure:"image_force_delete_snapshots"`
+	AlicloudImageForceDeleteInstances bool              `mapstructure:"image_force_delete_instances"`
+	AlicloudImageSkipRegionValidation bool              `mapstructure:"skip_region_validation"`
 	AlicloudDiskDevices               `mapstructure:",squash"`
 }
 
@@ -81,6 +83,16 @@ func (c *AlicloudImageConfig) Prepare(ctx *interpolate.Context) []error {
 		c.AlicloudImageDestinationRegions = regions
 	}
 
+	copyRegions := make(map[string]bool)
+	for _, region := range c.AlicloudImageDestinationRegions {
+		copyRegions[region] = true
+	}
+	for region := range c.AlicloudImageCopyKMSKeyIds {
+		if !copyRegions[region] {
+			errs = append(errs, fmt.Errorf("image_copy_kms_key_ids has a key for %s, which is not in image_copy_regions", region))
+		}
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
@@ -62,3 +62,17 @@ func regionsToString() []string {
 	}
 	return regions
 }
+
+func TestECSImageConfigPrepare_copyKMSKeyIds(t *testing.T) {
+	c := testAlicloudImageConfig()
+	c.AlicloudImageDestinationRegions = []string{"cn-beijing"}
+	c.AlicloudImageCopyKMSKeyIds = map[string]string{"cn-beijing": "key-id"}
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.AlicloudImageCopyKMSKeyIds = map[string]string{"cn-hangzhou": "key-id"}
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error for a region that isn't copied to")
+	}
+}
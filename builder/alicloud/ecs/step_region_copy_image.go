@@ -3,6 +3,7 @@ package ecs
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/denverdino/aliyungo/common"
 	"github.com/denverdino/aliyungo/ecs"
@@ -13,7 +14,25 @@ import (
 type stepRegionCopyAlicloudImage struct {
 	AlicloudImageDestinationRegions []string
 	AlicloudImageDestinationNames   []string
+	AlicloudImageCopyEncrypted      bool
+	AlicloudImageCopyKMSKeyIds      map[string]string
 	RegionId                        string
+
+	// copying are the images still being copied, by region, which the
+	// cleanup cancels
+	lock    sync.Mutex
+	copying map[string]string
+}
+
+// copyImageArgs are the arguments of CopyImage, with the encryption the
+// vendored SDK doesn't have.
+type copyImageArgs struct {
+	RegionId             common.Region
+	ImageId              string
+	DestinationRegionId  common.Region
+	DestinationImageName string
+	Encrypted            bool
+	KMSKeyId             string
 }
 
 func (s *stepRegionCopyAlicloudImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -26,6 +45,14 @@ func (s *stepRegionCopyAlicloudImage) Run(_ context.Context, state multistep.Sta
 	alicloudImages := state.Get("alicloudimages").(map[string]string)
 	region := common.Region(s.RegionId)
 
+	ui.Say(fmt.Sprintf("Copying image (%s) to the regions...", imageId))
+
+	// The copies run in parallel, and are added to the images as soon as
+	// they start, and to those being copied until they are ready, for the
+	// cleanup to cancel them
+	s.copying = make(map[string]string)
+	var wg sync.WaitGroup
+	var errs *packer.MultiError
 	numberOfName := len(s.AlicloudImageDestinationNames)
 	for index, destinationRegion := range s.AlicloudImageDestinationRegions {
 		if destinationRegion == s.RegionId {
@@ -35,19 +62,47 @@ func (s *stepRegionCopyAlicloudImage) Run(_ context.Context, state multistep.Sta
 		if numberOfName > 0 && index < numberOfName {
 			ecsImageName = s.AlicloudImageDestinationNames[index]
 		}
-		imageId, err := client.CopyImage(
-			&ecs.CopyImageArgs{
-				RegionId:             region,
-				ImageId:              imageId,
-				DestinationRegionId:  common.Region(destinationRegion),
-				DestinationImageName: ecsImageName,
-			})
-		if err != nil {
-			state.Put("error", err)
-			ui.Say(fmt.Sprintf("Error copying images: %s", err))
-			return multistep.ActionHalt
+		kmsKeyId := s.AlicloudImageCopyKMSKeyIds[destinationRegion]
+		args := &copyImageArgs{
+			RegionId:             region,
+			ImageId:              imageId,
+			DestinationRegionId:  common.Region(destinationRegion),
+			DestinationImageName: ecsImageName,
+			Encrypted:            s.AlicloudImageCopyEncrypted || kmsKeyId != "",
+			KMSKeyId:             kmsKeyId,
 		}
-		alicloudImages[destinationRegion] = imageId
+
+		wg.Add(1)
+		go func(destinationRegion string) {
+			defer wg.Done()
+			copiedImageId, err := copyImage(client, args)
+			if err == nil {
+				s.lock.Lock()
+				alicloudImages[destinationRegion] = copiedImageId
+				s.copying[destinationRegion] = copiedImageId
+				s.lock.Unlock()
+				ui.Message(fmt.Sprintf("Copying image to %s: %s", destinationRegion, copiedImageId))
+
+				err = client.WaitForImageReady(common.Region(destinationRegion), copiedImageId, ALICLOUD_DEFAULT_LONG_TIMEOUT)
+			}
+
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			if err != nil {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("%s: %s", destinationRegion, err))
+				return
+			}
+			delete(s.copying, destinationRegion)
+			ui.Message(fmt.Sprintf("Copied image to %s", destinationRegion))
+		}(destinationRegion)
+	}
+	wg.Wait()
+
+	if errs != nil && len(errs.Errors) > 0 {
+		err := fmt.Errorf("Error copying images: %s", errs)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
 	}
 	return multistep.ActionContinue
 }
@@ -55,18 +110,22 @@ func (s *stepRegionCopyAlicloudImage) Run(_ context.Context, state multistep.Sta
 func (s *stepRegionCopyAlicloudImage) Cleanup(state multistep.StateBag) {
 	_, cancelled := state.GetOk(multistep.StateCancelled)
 	_, halted := state.GetOk(multistep.StateHalted)
-	if cancelled || halted {
+	if (cancelled || halted) && len(s.copying) > 0 {
 		ui := state.Get("ui").(packer.Ui)
 		client := state.Get("client").(*ecs.Client)
-		alicloudImages := state.Get("alicloudimages").(map[string]string)
 		ui.Say(fmt.Sprintf("Stopping copy image because cancellation or error..."))
-		for copiedRegionId, copiedImageId := range alicloudImages {
-			if copiedRegionId == s.RegionId {
-				continue
-			}
+		for copiedRegionId, copiedImageId := range s.copying {
 			if err := client.CancelCopyImage(common.Region(copiedRegionId), copiedImageId); err != nil {
 				ui.Say(fmt.Sprintf("Error cancelling copy image: %v", err))
 			}
 		}
 	}
 }
+
+func copyImage(client *ecs.Client, args *copyImageArgs) (string, error) {
+	response := &ecs.CopyImageResponse{}
+	if err := client.Invoke("CopyImage", args, &response); err != nil {
+		return "", err
+	}
+	return response.ImageId, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
 	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/chroot"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -48,6 +49,12 @@ type Config struct {
 	ctx interpolate.Context
 }
 
+// GetContext is the interpolation context of the configuration, the mount
+// commands are interpolated with.
+func (c *Config) GetContext() interpolate.Context {
+	return c.ctx
+}
+
 type wrappedCommandTemplate struct {
 	Command string
 }
@@ -158,6 +165,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 				errs, errors.New("ami_block_device_mappings is required with from_scratch."))
 		}
 	} else {
+		errs = packer.MultiErrorAppend(errs, b.config.SourceAmiFilter.Prepare()...)
 		if b.config.SourceAmi == "" && b.config.SourceAmiFilter.Empty() {
 			errs = packer.MultiErrorAppend(
 				errs, errors.New("source_ami or source_ami_filter is required."))
@@ -202,13 +210,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	state.Put("awsSession", session)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
-	state.Put("wrappedCommand", CommandWrapper(wrappedCommand))
+	state.Put("wrappedCommand", chroot.CommandWrapper(wrappedCommand))
 
 	// Build the steps
 	steps := []multistep.Step{
 		&awscommon.StepPreValidate{
 			DestAmiName:     b.config.AMIName,
 			ForceDeregister: b.config.AMIForceDeregister,
+			SkipCreateAMI:   b.config.AMISkipCreateImage,
 		},
 		&StepInstanceInfo{},
 	}
@@ -226,72 +235,82 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 
 	steps = append(steps,
-		&StepFlock{},
+		&chroot.StepFlock{},
 		&StepPrepareDevice{},
 		&StepCreateVolume{
 			RootVolumeSize: b.config.RootVolumeSize,
 		},
 		&StepAttachVolume{},
-		&StepEarlyUnflock{},
-		&StepPreMountCommands{
+		&chroot.StepEarlyUnflock{},
+		&chroot.StepPreMountCommands{
 			Commands: b.config.PreMountCommands,
 		},
 		&StepMountDevice{
 			MountOptions:   b.config.MountOptions,
 			MountPartition: b.config.MountPartition,
 		},
-		&StepPostMountCommands{
+		&chroot.StepPostMountCommands{
 			Commands: b.config.PostMountCommands,
 		},
-		&StepMountExtra{},
-		&StepCopyFiles{},
-		&StepChrootProvision{},
-		&StepEarlyCleanup{},
-		&StepSnapshot{},
-		&awscommon.StepDeregisterAMI{
-			AccessConfig:        &b.config.AccessConfig,
-			ForceDeregister:     b.config.AMIForceDeregister,
-			ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
-			AMIName:             b.config.AMIName,
-			Regions:             b.config.AMIRegions,
-		},
-		&StepRegisterAMI{
-			RootVolumeSize:           b.config.RootVolumeSize,
-			EnableAMISriovNetSupport: b.config.AMISriovNetSupport,
-			EnableAMIENASupport:      b.config.AMIENASupport,
-		},
-		&awscommon.StepCreateEncryptedAMICopy{
-			KeyID:             b.config.AMIKmsKeyId,
-			EncryptBootVolume: b.config.AMIEncryptBootVolume,
-			Name:              b.config.AMIName,
-			AMIMappings:       b.config.AMIBlockDevices.AMIMappings,
-		},
-		&awscommon.StepAMIRegionCopy{
-			AccessConfig:      &b.config.AccessConfig,
-			Regions:           b.config.AMIRegions,
-			RegionKeyIds:      b.config.AMIRegionKMSKeyIDs,
-			EncryptBootVolume: b.config.AMIEncryptBootVolume,
-			Name:              b.config.AMIName,
+		&chroot.StepMountExtra{
+			ChrootMounts: b.config.ChrootMounts,
 		},
-		&awscommon.StepModifyAMIAttributes{
-			Description:    b.config.AMIDescription,
-			Users:          b.config.AMIUsers,
-			Groups:         b.config.AMIGroups,
-			ProductCodes:   b.config.AMIProductCodes,
-			SnapshotUsers:  b.config.SnapshotUsers,
-			SnapshotGroups: b.config.SnapshotGroups,
-			Ctx:            b.config.ctx,
-		},
-		&awscommon.StepCreateTags{
-			Tags:         b.config.AMITags,
-			SnapshotTags: b.config.SnapshotTags,
-			Ctx:          b.config.ctx,
+		&chroot.StepCopyFiles{
+			Files: b.config.CopyFiles,
 		},
+		&chroot.StepChrootProvision{},
+		&chroot.StepEarlyCleanup{},
 	)
 
+	if !b.config.AMISkipCreateImage {
+		steps = append(steps,
+			&StepSnapshot{},
+			&awscommon.StepDeregisterAMI{
+				AccessConfig:        &b.config.AccessConfig,
+				ForceDeregister:     b.config.AMIForceDeregister,
+				ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
+				AMIName:             b.config.AMIName,
+				Regions:             b.config.AMIRegions,
+			},
+			&StepRegisterAMI{
+				RootVolumeSize:           b.config.RootVolumeSize,
+				EnableAMISriovNetSupport: b.config.AMISriovNetSupport,
+				EnableAMIENASupport:      b.config.AMIENASupport,
+			},
+			&awscommon.StepCreateEncryptedAMICopy{
+				KeyID:             b.config.AMIKmsKeyId,
+				EncryptBootVolume: b.config.AMIEncryptBootVolume,
+				Name:              b.config.AMIName,
+				AMIMappings:       b.config.AMIBlockDevices.AMIMappings,
+			},
+			&awscommon.StepAMIRegionCopy{
+				AccessConfig:      &b.config.AccessConfig,
+				Regions:           b.config.AMIRegions,
+				RegionKeyIds:      b.config.AMIRegionKMSKeyIDs,
+				EncryptBootVolume: b.config.AMIEncryptBootVolume,
+				Name:              b.config.AMIName,
+			},
+			&awscommon.StepModifyAMIAttributes{
+				Description:    b.config.AMIDescription,
+				Users:          b.config.AMIUsers,
+				Groups:         b.config.AMIGroups,
+				ProductCodes:   b.config.AMIProductCodes,
+				SnapshotUsers:  b.config.SnapshotUsers,
+				SnapshotGroups: b.config.SnapshotGroups,
+				Ctx:            b.config.ctx,
+			},
+			&awscommon.StepCreateTags{
+				Tags:         b.config.AMITags,
+				SnapshotTags: b.config.SnapshotTags,
+				Ctx:          b.config.ctx,
+			},
+		)
+	}
+
 	// Run!
 	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
 	b.runner.Run(state)
+	b.config.ReportMockEC2(ui)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
@@ -309,6 +328,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		BuilderIdValue: BuilderId,
 		Session:        session,
 	}
+	if image, ok := state.GetOk("source_image"); ok {
+		artifact.SourceImage = image.(*ec2.Image)
+	}
 
 	return artifact, nil
 }
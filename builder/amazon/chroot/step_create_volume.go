@@ -82,6 +82,21 @@ func (s *StepCreateVolume) Run(_ context.Context, state multistep.StateBag) mult
 	// Set the volume ID so we remember to delete it later
 	s.volumeId = *createVolumeResp.VolumeId
 	log.Printf("Volume ID: %s", s.volumeId)
+	awscommon.ReportTemporaryResource(ui, ec2conn, true, awscommon.OrphanVolume, s.volumeId)
+
+	_, err = ec2conn.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{&s.volumeId},
+		Tags: []*ec2.Tag{{
+			Key:   aws.String(awscommon.TemporaryResourceTag),
+			Value: aws.String("true"),
+		}},
+	})
+	if err != nil {
+		err := fmt.Errorf("Error tagging root volume: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
 
 	// Wait for the volume to become ready
 	stateChange := awscommon.StateChangeConf{
@@ -123,5 +138,7 @@ func (s *StepCreateVolume) Cleanup(state multistep.StateBag) {
 	_, err := ec2conn.DeleteVolume(&ec2.DeleteVolumeInput{VolumeId: &s.volumeId})
 	if err != nil {
 		ui.Error(fmt.Sprintf("Error deleting EBS volume: %s", err))
+	} else {
+		awscommon.ReportTemporaryResource(ui, ec2conn, false, awscommon.OrphanVolume, s.volumeId)
 	}
 }
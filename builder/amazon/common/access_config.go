@@ -3,30 +3,40 @@ package common
 import (
 	"fmt"
 	"log"
-	"strings"
+	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
 // AccessConfig is for common configuration related to AWS access
 type AccessConfig struct {
-	AccessKey            string `mapstructure:"access_key"`
-	CustomEndpointEc2    string `mapstructure:"custom_endpoint_ec2"`
-	MFACode              string `mapstructure:"mfa_code"`
-	ProfileName          string `mapstructure:"profile"`
-	RawRegion            string `mapstructure:"region"`
-	SecretKey            string `mapstructure:"secret_key"`
-	SkipValidation       bool   `mapstructure:"skip_region_validation"`
-	SkipMetadataApiCheck bool   `mapstructure:"skip_metadata_api_check"`
-	Token                string `mapstructure:"token"`
+	AccessKey            string               `mapstructure:"access_key"`
+	AssumeRole           AssumeRoleConfig     `mapstructure:"assume_role"`
+	CustomEndpointEc2    string               `mapstructure:"custom_endpoint_ec2"`
+	EC2Endpoint          string               `mapstructure:"ec2_endpoint"`
+	MFACode              string               `mapstructure:"mfa_code"`
+	MockEC2              bool                 `mapstructure:"mock_ec2"`
+	MockEC2Errors        map[string]string    `mapstructure:"mock_ec2_errors"`
+	ProfileName          string               `mapstructure:"profile"`
+	RawRegion            string               `mapstructure:"region"`
+	S3Endpoint           string               `mapstructure:"s3_endpoint"`
+	SecretKey            string               `mapstructure:"secret_key"`
+	SkipValidation       bool                 `mapstructure:"skip_region_validation"`
+	SkipMetadataApiCheck bool                 `mapstructure:"skip_metadata_api_check"`
+	STSEndpoint          string               `mapstructure:"sts_endpoint"`
+	Token                string               `mapstructure:"token"`
+	VaultAWSEngine       VaultAWSEngineConfig `mapstructure:"vault_aws_engine"`
 	session              *session.Session
+	mock                 *mockEC2
 }
 
 // Config returns a valid aws.Config object for access to AWS services, or
@@ -36,11 +46,25 @@ func (c *AccessConfig) Session() (*session.Session, error) {
 		return c.session, nil
 	}
 
+	if c.MockEC2 {
+		return c.mockSession()
+	}
+
 	config := aws.NewConfig().WithCredentialsChainVerboseErrors(true)
 
 	staticCreds := credentials.NewStaticCredentials(c.AccessKey, c.SecretKey, c.Token)
 	if _, err := staticCreds.Get(); err != credentials.ErrStaticCredentialsEmpty {
 		config.WithCredentials(staticCreds)
+	} else if c.VaultAWSEngine.Enabled() {
+		provider, err := newVaultProvider(&c.VaultAWSEngine)
+		if err != nil {
+			return nil, packer.NewClassifiedError(packer.ErrorClassCredential, err)
+		}
+		config.WithCredentials(credentials.NewCredentials(provider))
+	} else if ssoCreds, err := c.ssoCredentials(); err != nil {
+		return nil, packer.NewClassifiedError(packer.ErrorClassCredential, err)
+	} else if ssoCreds != nil {
+		config.WithCredentials(ssoCreds)
 	}
 
 	if c.RawRegion != "" {
@@ -49,8 +73,10 @@ func (c *AccessConfig) Session() (*session.Session, error) {
 		config = config.WithRegion(region)
 	}
 
-	if c.CustomEndpointEc2 != "" {
-		config = config.WithEndpoint(c.CustomEndpointEc2)
+	config = config.WithEndpointResolver(c.endpointResolver())
+	if c.S3Endpoint != "" {
+		// S3 compatible APIs rarely serve buckets as subdomains
+		config = config.WithS3ForcePathStyle(true)
 	}
 
 	opts := session.Options{
@@ -62,7 +88,7 @@ func (c *AccessConfig) Session() (*session.Session, error) {
 		opts.Profile = c.ProfileName
 	}
 
-	if c.MFACode != "" {
+	if c.MFACode != "" && !c.AssumeRole.Enabled() {
 		opts.AssumeRoleTokenProvider = func() (string, error) {
 			return c.MFACode, nil
 		}
@@ -76,14 +102,25 @@ func (c *AccessConfig) Session() (*session.Session, error) {
 		log.Printf("Found region %s", *sess.Config.Region)
 		c.session = sess
 
+		if c.AssumeRole.Enabled() {
+			// The base session only supplies the credentials used to call
+			// sts; everything else is done as the assumed role.
+			c.session = sess.Copy(&aws.Config{
+				Credentials: credentials.NewCredentials(
+					newAssumeRoleProvider(sess, &c.AssumeRole, c.MFACode)),
+			})
+		}
+
 		cp, err := c.session.Config.Credentials.Get()
 		if err != nil {
 			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoCredentialProviders" {
-				return nil, fmt.Errorf("No valid credential sources found for AWS Builder. " +
-					"Please see https://www.packer.io/docs/builders/amazon.html#specifying-amazon-credentials " +
-					"for more information on providing credentials for the AWS Builder.")
+				return nil, packer.NewClassifiedError(packer.ErrorClassCredential,
+					fmt.Errorf("No valid credential sources found for AWS Builder. "+
+						"Please see https://www.packer.io/docs/builders/amazon.html#specifying-amazon-credentials "+
+						"for more information on providing credentials for the AWS Builder."))
 			} else {
-				return nil, fmt.Errorf("Error loading credentials for AWS Provider: %s", err)
+				return nil, packer.NewClassifiedError(packer.ErrorClassCredential,
+					fmt.Errorf("Error loading credentials for AWS Provider: %s", err))
 			}
 		}
 		log.Printf("[INFO] AWS Auth provider used: %q", cp.ProviderName)
@@ -91,6 +128,36 @@ func (c *AccessConfig) Session() (*session.Session, error) {
 	return c.session, nil
 }
 
+// ssoCredentials returns credentials for the profile in use if it was
+// configured with `aws configure sso`, which the SDK doesn't know about. It
+// returns nil if the profile isn't an SSO profile, or if credentials are
+// set in the environment, as those take precedence over profiles.
+func (c *AccessConfig) ssoCredentials() (*credentials.Credentials, error) {
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return nil, nil
+	}
+
+	name := c.ProfileName
+	if name == "" {
+		name = os.Getenv("AWS_PROFILE")
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	profile, err := loadSSOProfile(name)
+	if err != nil || profile == nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Using AWS SSO credentials of profile %q", name)
+	provider, err := newSSOProvider(profile)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewCredentials(provider), nil
+}
+
 func (c *AccessConfig) SessionRegion() string {
 	if c.session == nil {
 		panic("access config session should be set.")
@@ -98,12 +165,35 @@ func (c *AccessConfig) SessionRegion() string {
 	return aws.StringValue(c.session.Config.Region)
 }
 
+// Partition returns the partition of the region of the session, such as
+// aws, aws-cn or aws-us-gov.
+func (c *AccessConfig) Partition() string {
+	return RegionPartition(c.SessionRegion())
+}
+
 func (c *AccessConfig) IsGovCloud() bool {
-	return strings.HasPrefix(c.SessionRegion(), "us-gov-")
+	return c.Partition() == endpoints.AwsUsGovPartitionID
 }
 
 func (c *AccessConfig) IsChinaCloud() bool {
-	return strings.HasPrefix(c.SessionRegion(), "cn-")
+	return c.Partition() == endpoints.AwsCnPartitionID
+}
+
+// endpointResolver resolves the endpoints of the services that are
+// overridden to their URL, and the others the way the SDK does.
+func (c *AccessConfig) endpointResolver() endpoints.Resolver {
+	overrides := map[string]string{
+		"ec2": c.EC2Endpoint,
+		"s3":  c.S3Endpoint,
+		"sts": c.STSEndpoint,
+	}
+	resolver := endpoints.DefaultResolver()
+	return endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		if url := overrides[service]; url != "" {
+			return endpoints.ResolvedEndpoint{URL: url, SigningRegion: region}, nil
+		}
+		return resolver.EndpointFor(service, region, opts...)
+	})
 }
 
 // metadataRegion returns the region from the metadata service
@@ -138,7 +228,29 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 			fmt.Errorf("`access_key` and `secret_key` must both be either set or not set."))
 	}
 
-	if c.RawRegion != "" && !c.SkipValidation {
+	errs = append(errs, c.AssumeRole.Prepare(c.MFACode)...)
+
+	errs = append(errs, c.VaultAWSEngine.Prepare()...)
+	if c.VaultAWSEngine.Enabled() && len(c.AccessKey) > 0 {
+		errs = append(errs,
+			fmt.Errorf("`access_key` and `vault_aws_engine` can't both be set."))
+	}
+
+	if len(c.MockEC2Errors) > 0 && !c.MockEC2 {
+		errs = append(errs,
+			fmt.Errorf("`mock_ec2_errors` can only be set with `mock_ec2`."))
+	}
+
+	if c.CustomEndpointEc2 != "" {
+		log.Println("(WARN) custom_endpoint_ec2 is deprecated, use ec2_endpoint instead.")
+		if c.EC2Endpoint == "" {
+			c.EC2Endpoint = c.CustomEndpointEc2
+		}
+	}
+
+	// The regions of EC2 compatible APIs, such as Snowball Edge, aren't
+	// AWS regions
+	if c.RawRegion != "" && !c.SkipValidation && c.EC2Endpoint == "" {
 		if valid := ValidateRegion(c.RawRegion); !valid {
 			errs = append(errs, fmt.Errorf("Unknown region: %s", c.RawRegion))
 		}
@@ -58,3 +58,52 @@ func TestAccessConfigPrepare_RegionRestricted(t *testing.T) {
 		t.Fatal("We should be in gov region.")
 	}
 }
+
+func TestAccessConfigPrepare_Endpoints(t *testing.T) {
+	c := testAccessConfig()
+
+	// The regions of EC2 compatible APIs aren't validated
+	c.RawRegion = "snow"
+	c.EC2Endpoint = "https://192.168.1.2:8243"
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c = testAccessConfig()
+	c.CustomEndpointEc2 = "https://ec2.custom.endpoint.com"
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if c.EC2Endpoint != "https://ec2.custom.endpoint.com" {
+		t.Fatalf("bad: %s", c.EC2Endpoint)
+	}
+
+	c.STSEndpoint = "https://sts.custom.endpoint.com"
+	resolver := c.endpointResolver()
+	for service, expected := range map[string]string{
+		"ec2": "https://ec2.custom.endpoint.com",
+		"sts": "https://sts.custom.endpoint.com",
+		"s3":  "https://s3.cn-north-1.amazonaws.com.cn",
+	} {
+		endpoint, err := resolver.EndpointFor(service, "cn-north-1")
+		if err != nil {
+			t.Fatalf("shouldn't have err: %s", err)
+		}
+		if endpoint.URL != expected {
+			t.Fatalf("bad %s endpoint: %s", service, endpoint.URL)
+		}
+	}
+}
+
+func TestRegionPartition(t *testing.T) {
+	for region, expected := range map[string]string{
+		"us-east-1":     "aws",
+		"cn-north-1":    "aws-cn",
+		"us-gov-east-1": "aws-us-gov",
+		"snow":          "aws",
+	} {
+		if p := RegionPartition(region); p != expected {
+			t.Fatalf("bad partition of %s: %s", region, p)
+		}
+	}
+}
@@ -16,6 +16,7 @@ type AMIConfig struct {
 	AMIGroups               []string          `mapstructure:"ami_groups"`
 	AMIProductCodes         []string          `mapstructure:"ami_product_codes"`
 	AMIRegions              []string          `mapstructure:"ami_regions"`
+	AMISkipCreateImage      bool              `mapstructure:"skip_create_ami"`
 	AMISkipRegionValidation bool              `mapstructure:"skip_region_validation"`
 	AMITags                 TagMap            `mapstructure:"tags"`
 	AMIENASupport           bool              `mapstructure:"ena_support"`
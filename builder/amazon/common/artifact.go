@@ -22,6 +22,10 @@ type Artifact struct {
 
 	// EC2 connection for performing API stuff.
 	Session *session.Session
+
+	// SourceImage is the AMI the build started from, nil if it built from
+	// scratch.
+	SourceImage *ec2.Image
 }
 
 func (a *Artifact) BuilderId() string {
@@ -113,6 +117,11 @@ func (a *Artifact) stateAtlasMetadata() interface{} {
 		k := fmt.Sprintf("region.%s", region)
 		metadata[k] = imageId
 	}
+	if a.SourceImage != nil {
+		metadata["source_ami"] = aws.StringValue(a.SourceImage.ImageId)
+		metadata["source_ami_creation_date"] = aws.StringValue(a.SourceImage.CreationDate)
+		metadata["source_ami_name"] = aws.StringValue(a.SourceImage.Name)
+	}
 
 	return metadata
 }
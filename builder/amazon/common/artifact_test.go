@@ -46,6 +46,24 @@ func TestArtifactState_atlasMetadata(t *testing.T) {
 	}
 }
 
+func TestArtifactState_atlasMetadataSourceImage(t *testing.T) {
+	a := &Artifact{
+		Amis:        map[string]string{"east": "foo"},
+		SourceImage: testImage(),
+	}
+
+	actual := a.State("atlas.artifact.metadata")
+	expected := map[string]string{
+		"region.east":              "foo",
+		"source_ami":               "ami-abcd1234",
+		"source_ami_creation_date": "2018-09-21T12:49:20.000Z",
+		"source_ami_name":          "ami_test_name",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestArtifactString(t *testing.T) {
 	expected := `AMIs were created:
 east: foo
@@ -0,0 +1,208 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// AssumeRoleProviderName is the name reported by the credentials provider
+// used when `assume_role` is configured.
+const AssumeRoleProviderName = "PackerAssumeRoleProvider"
+
+const (
+	assumeRoleMinDuration = 15 * time.Minute
+	assumeRoleMaxDuration = 12 * time.Hour
+
+	// Credentials are renewed this long before they expire so that API calls
+	// made late in a build never race the expiration.
+	assumeRoleExpiryWindow = 1 * time.Minute
+)
+
+// AssumeRoleConfig describes a role that Packer assumes, using the
+// credentials resolved by the rest of AccessConfig, before talking to AWS.
+type AssumeRoleConfig struct {
+	RoleARN           string            `mapstructure:"role_arn"`
+	ExternalID        string            `mapstructure:"external_id"`
+	MFASerial         string            `mapstructure:"mfa_serial"`
+	SessionName       string            `mapstructure:"session_name"`
+	Tags              map[string]string `mapstructure:"tags"`
+	TransitiveTagKeys []string          `mapstructure:"transitive_tag_keys"`
+	Duration          time.Duration     `mapstructure:"duration"`
+}
+
+// Enabled reports whether a role to assume was configured.
+func (c *AssumeRoleConfig) Enabled() bool {
+	return c.RoleARN != ""
+}
+
+func (c *AssumeRoleConfig) Prepare(mfaCode string) []error {
+	var errs []error
+
+	if !c.Enabled() {
+		if c.ExternalID != "" || c.MFASerial != "" || c.SessionName != "" ||
+			len(c.Tags) > 0 || len(c.TransitiveTagKeys) > 0 || c.Duration != 0 {
+			errs = append(errs, fmt.Errorf("assume_role: `role_arn` must be set"))
+		}
+		return errs
+	}
+
+	if c.SessionName == "" {
+		c.SessionName = fmt.Sprintf("packer-%d", time.Now().UTC().Unix())
+	}
+
+	if c.Duration == 0 {
+		c.Duration = time.Hour
+	}
+	if c.Duration < assumeRoleMinDuration || c.Duration > assumeRoleMaxDuration {
+		errs = append(errs, fmt.Errorf(
+			"assume_role: `duration` must be between %s and %s", assumeRoleMinDuration, assumeRoleMaxDuration))
+	}
+
+	if c.MFASerial != "" && mfaCode == "" {
+		errs = append(errs, fmt.Errorf("assume_role: `mfa_code` must be set when `mfa_serial` is set"))
+	}
+
+	for _, key := range c.TransitiveTagKeys {
+		if _, ok := c.Tags[key]; !ok {
+			errs = append(errs, fmt.Errorf(
+				"assume_role: transitive tag key %q is not one of the session `tags`", key))
+		}
+	}
+
+	return errs
+}
+
+// assumeRoleProvider is a credentials.Provider that assumes the configured
+// role. Because it embeds credentials.Expiry the SDK calls Retrieve again
+// once the role session is about to expire, so builds that outlive
+// `duration` transparently get fresh credentials.
+type assumeRoleProvider struct {
+	credentials.Expiry
+
+	config  *AssumeRoleConfig
+	mfaCode string
+	base    client.ConfigProvider
+
+	// sourceCreds are the credentials used to call sts:AssumeRole. When
+	// MFA is required these are obtained once through sts:GetSessionToken
+	// so that renewing the role session doesn't need a new token code.
+	sourceCreds *credentials.Credentials
+}
+
+func newAssumeRoleProvider(base client.ConfigProvider, config *AssumeRoleConfig, mfaCode string) *assumeRoleProvider {
+	return &assumeRoleProvider{
+		config:  config,
+		mfaCode: mfaCode,
+		base:    base,
+	}
+}
+
+func (p *assumeRoleProvider) Retrieve() (credentials.Value, error) {
+	svc, err := p.stsClient()
+	if err != nil {
+		return credentials.Value{ProviderName: AssumeRoleProviderName}, err
+	}
+
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(p.config.RoleARN),
+		RoleSessionName: aws.String(p.config.SessionName),
+		DurationSeconds: aws.Int64(int64(p.config.Duration / time.Second)),
+	}
+	if p.config.ExternalID != "" {
+		input.ExternalId = aws.String(p.config.ExternalID)
+	}
+
+	req, resp := svc.AssumeRoleRequest(input)
+	req.Handlers.Build.PushBackNamed(request.NamedHandler{
+		Name: "packer.AssumeRoleSessionTags",
+		Fn:   sessionTagsHandler(p.config.Tags, p.config.TransitiveTagKeys),
+	})
+	if err := req.Send(); err != nil {
+		return credentials.Value{ProviderName: AssumeRoleProviderName},
+			fmt.Errorf("Error assuming role %s: %s", p.config.RoleARN, err)
+	}
+
+	log.Printf("[INFO] Assumed role %s, session expires at %s",
+		p.config.RoleARN, aws.TimeValue(resp.Credentials.Expiration))
+	p.SetExpiration(*resp.Credentials.Expiration, assumeRoleExpiryWindow)
+
+	return credentials.Value{
+		AccessKeyID:     *resp.Credentials.AccessKeyId,
+		SecretAccessKey: *resp.Credentials.SecretAccessKey,
+		SessionToken:    *resp.Credentials.SessionToken,
+		ProviderName:    AssumeRoleProviderName,
+	}, nil
+}
+
+// stsClient returns an STS client authenticated with the source
+// credentials, exchanging the MFA code for a session token first if
+// necessary.
+func (p *assumeRoleProvider) stsClient() (*sts.STS, error) {
+	if p.config.MFASerial == "" {
+		return sts.New(p.base), nil
+	}
+
+	if p.sourceCreds == nil {
+		resp, err := sts.New(p.base).GetSessionToken(&sts.GetSessionTokenInput{
+			SerialNumber: aws.String(p.config.MFASerial),
+			TokenCode:    aws.String(p.mfaCode),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error getting MFA session token for %s: %s", p.config.MFASerial, err)
+		}
+		p.sourceCreds = credentials.NewStaticCredentials(
+			*resp.Credentials.AccessKeyId,
+			*resp.Credentials.SecretAccessKey,
+			*resp.Credentials.SessionToken)
+	}
+
+	return sts.New(p.base, &aws.Config{Credentials: p.sourceCreds}), nil
+}
+
+// sessionTagsHandler returns a request handler adding the session tag
+// parameters to an already built sts:AssumeRole query.
+func sessionTagsHandler(tags map[string]string, transitive []string) func(*request.Request) {
+	return func(r *request.Request) {
+		if r.Error != nil || (len(tags) == 0 && len(transitive) == 0) {
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.GetBody())
+		if err != nil {
+			r.Error = err
+			return
+		}
+		params, err := url.ParseQuery(string(body))
+		if err != nil {
+			r.Error = err
+			return
+		}
+
+		keys := make([]string, 0, len(tags))
+		for k := range tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			prefix := "Tags.member." + strconv.Itoa(i+1)
+			params.Set(prefix+".Key", k)
+			params.Set(prefix+".Value", tags[k])
+		}
+		for i, k := range transitive {
+			params.Set("TransitiveTagKeys.member."+strconv.Itoa(i+1), k)
+		}
+
+		r.SetBufferBody([]byte(params.Encode()))
+	}
+}
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"io/ioutil"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func TestAssumeRoleConfigPrepare(t *testing.T) {
+	c := &AssumeRoleConfig{}
+	if errs := c.Prepare(""); len(errs) != 0 {
+		t.Fatalf("shouldn't have err: %v", errs)
+	}
+
+	c.ExternalID = "foo"
+	if errs := c.Prepare(""); len(errs) == 0 {
+		t.Fatal("should have error without role_arn")
+	}
+
+	c.RoleARN = "arn:aws:iam::123456789012:role/packer"
+	if errs := c.Prepare(""); len(errs) != 0 {
+		t.Fatalf("shouldn't have err: %v", errs)
+	}
+	if c.SessionName == "" {
+		t.Fatal("session_name should default")
+	}
+	if c.Duration != time.Hour {
+		t.Fatalf("bad duration: %s", c.Duration)
+	}
+
+	c.Duration = time.Minute
+	if errs := c.Prepare(""); len(errs) == 0 {
+		t.Fatal("should have error for short duration")
+	}
+	c.Duration = time.Hour
+
+	c.MFASerial = "arn:aws:iam::123456789012:mfa/user"
+	if errs := c.Prepare(""); len(errs) == 0 {
+		t.Fatal("should have error without mfa_code")
+	}
+	if errs := c.Prepare("123456"); len(errs) != 0 {
+		t.Fatalf("shouldn't have err: %v", errs)
+	}
+
+	c.Tags = map[string]string{"team": "images"}
+	c.TransitiveTagKeys = []string{"project"}
+	if errs := c.Prepare("123456"); len(errs) == 0 {
+		t.Fatal("should have error for unknown transitive tag key")
+	}
+	c.TransitiveTagKeys = []string{"team"}
+	if errs := c.Prepare("123456"); len(errs) != 0 {
+		t.Fatalf("shouldn't have err: %v", errs)
+	}
+}
+
+func TestAccessConfigPrepare_AssumeRole(t *testing.T) {
+	c := testAccessConfig()
+	c.AssumeRole.SessionName = "packer"
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.AssumeRole.RoleARN = "arn:aws:iam::123456789012:role/packer"
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestSessionTagsHandler(t *testing.T) {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+	}))
+	req, _ := sts.New(sess).AssumeRoleRequest(&sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::123456789012:role/packer"),
+		RoleSessionName: aws.String("packer"),
+	})
+	req.Handlers.Build.PushBack(sessionTagsHandler(
+		map[string]string{"team": "images", "env": "ci"},
+		[]string{"team"}))
+	req.Build()
+	if req.Error != nil {
+		t.Fatalf("err: %s", req.Error)
+	}
+
+	body, err := ioutil.ReadAll(req.GetBody())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	params, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{
+		"Action":                     "AssumeRole",
+		"RoleArn":                    "arn:aws:iam::123456789012:role/packer",
+		"Tags.member.1.Key":          "env",
+		"Tags.member.1.Value":        "ci",
+		"Tags.member.2.Key":          "team",
+		"Tags.member.2.Value":        "images",
+		"TransitiveTagKeys.member.1": "team",
+	}
+	for k, v := range expected {
+		if params.Get(k) != v {
+			t.Fatalf("bad %s: %q", k, params.Get(k))
+		}
+	}
+}
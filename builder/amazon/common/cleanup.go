@@ -0,0 +1,250 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The types of the temporary resources a build can leave behind, in the
+// order they have to be deleted in.
+const (
+	OrphanSpotRequest   = "spot-request"
+	OrphanInstance      = "instance"
+	OrphanVolume        = "volume"
+	OrphanSecurityGroup = "security-group"
+	OrphanKeyPair       = "key-pair"
+)
+
+// ReportTemporaryResource tells the core a temporary resource of one of the
+// types above was created or deleted, with the temporary-resource
+// machine-readable message. The manifest of packer build -ship-logs lists
+// the ones that weren't deleted.
+func ReportTemporaryResource(ui packer.Ui, ec2conn *ec2.EC2, created bool, resourceType, id string) {
+	action := "deleted"
+	if created {
+		action = "created"
+	}
+	ui.Machine("temporary-resource", action, resourceType, id, aws.StringValue(ec2conn.Config.Region))
+}
+
+// temporaryNameRegexp matches the names of the temporary key pairs and
+// security groups, which end with a time ordered UUID whose first part is
+// when they were created.
+var temporaryNameRegexp = regexp.MustCompile(
+	`^packer_([0-9a-f]{8})-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// Orphan is a temporary resource of a build that wasn't deleted, as the
+// build crashed or was killed.
+type Orphan struct {
+	Type    string
+	Id      string
+	Name    string
+	Created time.Time
+}
+
+func (o *Orphan) String() string {
+	if o.Name != "" && o.Name != o.Id {
+		return fmt.Sprintf("%s %s (%s)", o.Type, o.Id, o.Name)
+	}
+	return fmt.Sprintf("%s %s", o.Type, o.Id)
+}
+
+// temporaryNameTime returns when the temporary resource with the name was
+// created, or false if Packer didn't generate the name.
+func temporaryNameTime(name string) (time.Time, bool) {
+	match := temporaryNameRegexp.FindStringSubmatch(name)
+	if match == nil {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseUint(match[1], 16, 32)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(unix), 0).UTC(), true
+}
+
+// FindOrphans returns the temporary resources of builds created before the
+// time, in the order they have to be deleted in. Instances, spot requests
+// and the volumes of the chroot builder are found by TemporaryResourceTag,
+// key pairs and security groups by the names Packer gives them.
+func FindOrphans(ec2conn *ec2.EC2, before time.Time) ([]*Orphan, error) {
+	var orphans []*Orphan
+	tagFilter := &ec2.Filter{
+		Name:   aws.String("tag-key"),
+		Values: []*string{aws.String(TemporaryResourceTag)},
+	}
+
+	spotResp, err := ec2conn.DescribeSpotInstanceRequests(&ec2.DescribeSpotInstanceRequestsInput{
+		Filters: []*ec2.Filter{tagFilter, {
+			Name:   aws.String("state"),
+			Values: aws.StringSlice([]string{"open", "active"}),
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error describing spot requests: %s", err)
+	}
+	for _, request := range spotResp.SpotInstanceRequests {
+		if request.CreateTime != nil && request.CreateTime.Before(before) {
+			orphans = append(orphans, &Orphan{
+				Type:    OrphanSpotRequest,
+				Id:      aws.StringValue(request.SpotInstanceRequestId),
+				Created: *request.CreateTime,
+			})
+		}
+	}
+
+	err = ec2conn.DescribeInstancesPages(&ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{tagFilter, {
+			Name:   aws.String("instance-state-name"),
+			Values: aws.StringSlice([]string{"pending", "running", "stopping", "stopped"}),
+		}},
+	}, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.LaunchTime == nil || !instance.LaunchTime.Before(before) {
+					continue
+				}
+				orphan := &Orphan{
+					Type:    OrphanInstance,
+					Id:      aws.StringValue(instance.InstanceId),
+					Created: *instance.LaunchTime,
+				}
+				for _, tag := range instance.Tags {
+					if aws.StringValue(tag.Key) == "Name" {
+						orphan.Name = aws.StringValue(tag.Value)
+					}
+				}
+				orphans = append(orphans, orphan)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error describing instances: %s", err)
+	}
+
+	err = ec2conn.DescribeVolumesPages(&ec2.DescribeVolumesInput{
+		Filters: []*ec2.Filter{tagFilter, {
+			Name:   aws.String("status"),
+			Values: []*string{aws.String("available")},
+		}},
+	}, func(page *ec2.DescribeVolumesOutput, _ bool) bool {
+		for _, volume := range page.Volumes {
+			if volume.CreateTime != nil && volume.CreateTime.Before(before) {
+				orphans = append(orphans, &Orphan{
+					Type:    OrphanVolume,
+					Id:      aws.StringValue(volume.VolumeId),
+					Created: *volume.CreateTime,
+				})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error describing volumes: %s", err)
+	}
+
+	groupResp, err := ec2conn.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{{
+			Name:   aws.String("group-name"),
+			Values: []*string{aws.String("packer_*")},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error describing security groups: %s", err)
+	}
+	for _, group := range groupResp.SecurityGroups {
+		name := aws.StringValue(group.GroupName)
+		if created, ok := temporaryNameTime(name); ok && created.Before(before) {
+			orphans = append(orphans, &Orphan{
+				Type:    OrphanSecurityGroup,
+				Id:      aws.StringValue(group.GroupId),
+				Name:    name,
+				Created: created,
+			})
+		}
+	}
+
+	keyResp, err := ec2conn.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		Filters: []*ec2.Filter{{
+			Name:   aws.String("key-name"),
+			Values: []*string{aws.String("packer_*")},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error describing key pairs: %s", err)
+	}
+	for _, key := range keyResp.KeyPairs {
+		name := aws.StringValue(key.KeyName)
+		if created, ok := temporaryNameTime(name); ok && created.Before(before) {
+			orphans = append(orphans, &Orphan{
+				Type:    OrphanKeyPair,
+				Id:      name,
+				Created: created,
+			})
+		}
+	}
+
+	return orphans, nil
+}
+
+// DeleteOrphans deletes the resources FindOrphans returned. The volumes and
+// security groups are only deleted once the instances are terminated.
+// It carries on when a resource can't be deleted, and returns all the
+// errors.
+func DeleteOrphans(ec2conn *ec2.EC2, ui packer.Ui, orphans []*Orphan) error {
+	var errs *packer.MultiError
+	var instanceIds []*string
+
+	for _, orphan := range orphans {
+		if orphan.Type != OrphanSpotRequest && orphan.Type != OrphanInstance && len(instanceIds) > 0 {
+			ui.Message("Waiting for the instances to be terminated...")
+			err := ec2conn.WaitUntilInstanceTerminated(&ec2.DescribeInstancesInput{
+				InstanceIds: instanceIds,
+			})
+			if err != nil {
+				errs = packer.MultiErrorAppend(errs,
+					fmt.Errorf("Error waiting for the instances to be terminated: %s", err))
+			}
+			instanceIds = nil
+		}
+
+		ui.Say(fmt.Sprintf("Deleting %s...", orphan))
+		var err error
+		switch orphan.Type {
+		case OrphanSpotRequest:
+			_, err = ec2conn.CancelSpotInstanceRequests(&ec2.CancelSpotInstanceRequestsInput{
+				SpotInstanceRequestIds: []*string{&orphan.Id},
+			})
+		case OrphanInstance:
+			_, err = ec2conn.TerminateInstances(&ec2.TerminateInstancesInput{
+				InstanceIds: []*string{&orphan.Id},
+			})
+			if err == nil {
+				instanceIds = append(instanceIds, aws.String(orphan.Id))
+			}
+		case OrphanVolume:
+			_, err = ec2conn.DeleteVolume(&ec2.DeleteVolumeInput{VolumeId: &orphan.Id})
+		case OrphanSecurityGroup:
+			_, err = ec2conn.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{GroupId: &orphan.Id})
+		case OrphanKeyPair:
+			_, err = ec2conn.DeleteKeyPair(&ec2.DeleteKeyPairInput{KeyName: &orphan.Id})
+		default:
+			err = fmt.Errorf("unknown resource type")
+		}
+		if err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Error deleting %s: %s", orphan, err))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
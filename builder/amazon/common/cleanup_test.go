@@ -0,0 +1,30 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/common/uuid"
+)
+
+func TestTemporaryNameTime(t *testing.T) {
+	name := "packer_" + uuid.TimeOrderedUUID()
+	created, ok := temporaryNameTime(name)
+	if !ok {
+		t.Fatalf("%s should be a temporary name", name)
+	}
+	if d := time.Since(created); d < 0 || d > time.Minute {
+		t.Fatalf("bad creation time of %s: %s", name, created)
+	}
+
+	created, ok = temporaryNameTime("packer_5ba4e8d0-1111-2222-3333-444455556666")
+	if !ok || !created.Equal(time.Date(2018, 9, 21, 12, 49, 20, 0, time.UTC)) {
+		t.Fatalf("bad creation time: %s", created)
+	}
+
+	for _, name := range []string{"packer", "packer_builder", "my-key", "packer_5ba4e8d0"} {
+		if _, ok := temporaryNameTime(name); ok {
+			t.Fatalf("%s shouldn't be a temporary name", name)
+		}
+	}
+}
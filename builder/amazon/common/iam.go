@@ -0,0 +1,105 @@
+package common
+
+import (
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+// iamClient calls the handful of IAM operations needed to manage the
+// temporary instance profile. The vendored SDK doesn't include the IAM
+// service, so this sets up a client for its query API the way the
+// generated service clients do.
+type iamClient struct {
+	*client.Client
+}
+
+func newIAMClient(p client.ConfigProvider) *iamClient {
+	c := p.ClientConfig("iam")
+	svc := &iamClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "iam",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2010-05-08",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(query.BuildHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+	// The responses of the operations used here carry nothing of interest.
+	svc.Handlers.Unmarshal.PushBackNamed(protocol.UnmarshalDiscardBodyHandler)
+
+	return svc
+}
+
+func (c *iamClient) send(operation string, input interface{}) error {
+	op := &request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	return c.NewRequest(op, input, &struct{}{}).Send()
+}
+
+type iamRoleInput struct {
+	_                        struct{} `type:"structure"`
+	RoleName                 *string  `type:"string"`
+	AssumeRolePolicyDocument *string  `type:"string"`
+	Description              *string  `type:"string"`
+}
+
+type iamRolePolicyInput struct {
+	_              struct{} `type:"structure"`
+	RoleName       *string  `type:"string"`
+	PolicyName     *string  `type:"string"`
+	PolicyDocument *string  `type:"string"`
+}
+
+type iamInstanceProfileInput struct {
+	_                   struct{} `type:"structure"`
+	InstanceProfileName *string  `type:"string"`
+	RoleName            *string  `type:"string"`
+}
+
+func (c *iamClient) CreateRole(input *iamRoleInput) error {
+	return c.send("CreateRole", input)
+}
+
+func (c *iamClient) DeleteRole(input *iamRoleInput) error {
+	return c.send("DeleteRole", input)
+}
+
+func (c *iamClient) PutRolePolicy(input *iamRolePolicyInput) error {
+	return c.send("PutRolePolicy", input)
+}
+
+func (c *iamClient) DeleteRolePolicy(input *iamRolePolicyInput) error {
+	return c.send("DeleteRolePolicy", input)
+}
+
+func (c *iamClient) CreateInstanceProfile(input *iamInstanceProfileInput) error {
+	return c.send("CreateInstanceProfile", input)
+}
+
+func (c *iamClient) DeleteInstanceProfile(input *iamInstanceProfileInput) error {
+	return c.send("DeleteInstanceProfile", input)
+}
+
+func (c *iamClient) AddRoleToInstanceProfile(input *iamInstanceProfileInput) error {
+	return c.send("AddRoleToInstanceProfile", input)
+}
+
+func (c *iamClient) RemoveRoleFromInstanceProfile(input *iamInstanceProfileInput) error {
+	return c.send("RemoveRoleFromInstanceProfile", input)
+}
@@ -7,17 +7,20 @@ import (
 )
 
 type BuildInfoTemplate struct {
-	BuildRegion   string
-	SourceAMI     string
-	SourceAMIName string
-	SourceAMITags map[string]string
+	BuildPartition        string
+	BuildRegion           string
+	SourceAMI             string
+	SourceAMICreationDate string
+	SourceAMIName         string
+	SourceAMITags         map[string]string
 }
 
 func extractBuildInfo(region string, state multistep.StateBag) *BuildInfoTemplate {
 	rawSourceAMI, hasSourceAMI := state.GetOk("source_image")
 	if !hasSourceAMI {
 		return &BuildInfoTemplate{
-			BuildRegion: region,
+			BuildPartition: RegionPartition(region),
+			BuildRegion:    region,
 		}
 	}
 
@@ -28,9 +31,11 @@ func extractBuildInfo(region string, state multistep.StateBag) *BuildInfoTemplat
 	}
 
 	return &BuildInfoTemplate{
-		BuildRegion:   region,
-		SourceAMI:     aws.StringValue(sourceAMI.ImageId),
-		SourceAMIName: aws.StringValue(sourceAMI.Name),
-		SourceAMITags: sourceAMITags,
+		BuildPartition:        RegionPartition(region),
+		BuildRegion:           region,
+		SourceAMI:             aws.StringValue(sourceAMI.ImageId),
+		SourceAMICreationDate: aws.StringValue(sourceAMI.CreationDate),
+		SourceAMIName:         aws.StringValue(sourceAMI.Name),
+		SourceAMITags:         sourceAMITags,
 	}
 }
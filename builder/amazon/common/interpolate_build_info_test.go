@@ -11,8 +11,9 @@ import (
 
 func testImage() *ec2.Image {
 	return &ec2.Image{
-		ImageId: aws.String("ami-abcd1234"),
-		Name:    aws.String("ami_test_name"),
+		CreationDate: aws.String("2018-09-21T12:49:20.000Z"),
+		ImageId:      aws.String("ami-abcd1234"),
+		Name:         aws.String("ami_test_name"),
 		Tags: []*ec2.Tag{
 			{
 				Key:   aws.String("key-1"),
@@ -36,7 +37,8 @@ func TestInterpolateBuildInfo_extractBuildInfo_noSourceImage(t *testing.T) {
 	buildInfo := extractBuildInfo("foo", state)
 
 	expected := BuildInfoTemplate{
-		BuildRegion: "foo",
+		BuildPartition: "aws",
+		BuildRegion:    "foo",
 	}
 	if !reflect.DeepEqual(*buildInfo, expected) {
 		t.Fatalf("Unexpected BuildInfoTemplate: expected %#v got %#v\n", expected, *buildInfo)
@@ -49,9 +51,11 @@ func TestInterpolateBuildInfo_extractBuildInfo_withSourceImage(t *testing.T) {
 	buildInfo := extractBuildInfo("foo", state)
 
 	expected := BuildInfoTemplate{
-		BuildRegion:   "foo",
-		SourceAMI:     "ami-abcd1234",
-		SourceAMIName: "ami_test_name",
+		BuildPartition:        "aws",
+		BuildRegion:           "foo",
+		SourceAMI:             "ami-abcd1234",
+		SourceAMICreationDate: "2018-09-21T12:49:20.000Z",
+		SourceAMIName:         "ami_test_name",
 		SourceAMITags: map[string]string{
 			"key-1": "value-1",
 			"key-2": "value-2",
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	KeyPairTypeRSA     = "rsa"
+	KeyPairTypeED25519 = "ed25519"
+)
+
+// generateKeyPair generates a key pair locally, to be imported into EC2.
+// It returns the private key, PEM encoded, and the public key in the
+// authorized_keys format.
+func generateKeyPair(keyType string, bits int) (string, []byte, error) {
+	var privateKey []byte
+	var publicKey ssh.PublicKey
+
+	switch keyType {
+	case KeyPairTypeRSA:
+		if bits == 0 {
+			bits = 2048
+		}
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return "", nil, err
+		}
+		privateKey = pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})
+		if publicKey, err = ssh.NewPublicKey(&key.PublicKey); err != nil {
+			return "", nil, err
+		}
+	case KeyPairTypeED25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", nil, err
+		}
+		if privateKey, err = marshalED25519PrivateKey(pub, priv); err != nil {
+			return "", nil, err
+		}
+		if publicKey, err = ssh.NewPublicKey(pub); err != nil {
+			return "", nil, err
+		}
+	default:
+		return "", nil, fmt.Errorf("unknown key pair type: %s", keyType)
+	}
+
+	return string(privateKey), ssh.MarshalAuthorizedKey(publicKey), nil
+}
+
+// marshalED25519PrivateKey encodes an ed25519 private key in the OpenSSH
+// format, the only one OpenSSH and x/crypto/ssh read ed25519 keys from.
+// See PROTOCOL.key in the OpenSSH sources.
+func marshalED25519PrivateKey(pub ed25519.PublicKey, priv ed25519.PrivateKey) ([]byte, error) {
+	var check [4]byte
+	if _, err := rand.Read(check[:]); err != nil {
+		return nil, err
+	}
+	checkInt := binary.BigEndian.Uint32(check[:])
+
+	privateSection := ssh.Marshal(struct {
+		Check1  uint32
+		Check2  uint32
+		Keytype string
+		Pub     []byte
+		Priv    []byte
+		Comment string
+	}{checkInt, checkInt, ssh.KeyAlgoED25519, pub, priv, "packer"})
+
+	// The private section is padded to the cipher block size, 8 when it
+	// isn't encrypted, with the bytes 1, 2, 3...
+	for i := 1; len(privateSection)%8 != 0; i++ {
+		privateSection = append(privateSection, byte(i))
+	}
+
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ssh.Marshal(struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{"none", "none", "", 1, publicKey.Marshal(), privateSection})
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "OPENSSH PRIVATE KEY",
+		Bytes: append([]byte("openssh-key-v1\x00"), key...),
+	}), nil
+}
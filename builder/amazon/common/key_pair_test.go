@@ -0,0 +1,50 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	for _, keyType := range []string{KeyPairTypeRSA, KeyPairTypeED25519} {
+		privateKey, publicKey, err := generateKeyPair(keyType, 0)
+		if err != nil {
+			t.Fatalf("%s: err: %s", keyType, err)
+		}
+
+		signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+		if err != nil {
+			t.Fatalf("%s: can't parse private key: %s", keyType, err)
+		}
+		if !bytes.Equal(ssh.MarshalAuthorizedKey(signer.PublicKey()), publicKey) {
+			t.Fatalf("%s: public key doesn't match private key: %s", keyType, publicKey)
+		}
+	}
+
+	if _, _, err := generateKeyPair("dsa", 0); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestIsKeyTypeUnsupported(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{awserr.New("UnknownParameter", "The parameter KeyType is not recognized", nil), true},
+		{awserr.New("InvalidParameterValue", "Value (ed25519) for parameter keyType is invalid.", nil), true},
+		{awserr.New("InvalidParameterValue", "Value (packer ') for parameter keyName is invalid.", nil), false},
+		{awserr.New("InvalidKeyPair.Duplicate", "The keypair 'packer' already exists.", nil), false},
+		{errors.New("KeyType"), false},
+	}
+
+	for _, tc := range cases {
+		if actual := isKeyTypeUnsupported(tc.err); actual != tc.expected {
+			t.Fatalf("bad: %s: %t", tc.err, actual)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package common
+
+import (
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+)
+
+// logsClient calls the CloudWatch Logs operations needed to stream the
+// output of a build. The vendored SDK doesn't include the CloudWatch Logs
+// service, so this sets up a client for its JSON API the way the generated
+// service clients do.
+type logsClient struct {
+	*client.Client
+}
+
+func newLogsClient(p client.ConfigProvider) *logsClient {
+	c := p.ClientConfig("logs")
+	svc := &logsClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "logs",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2014-03-28",
+				JSONVersion:   "1.1",
+				TargetPrefix:  "Logs_20140328",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(jsonrpc.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(jsonrpc.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(jsonrpc.UnmarshalErrorHandler)
+
+	return svc
+}
+
+func (c *logsClient) send(operation string, input, output interface{}) error {
+	op := &request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	if output == nil {
+		output = &struct{}{}
+	}
+	return c.NewRequest(op, input, output).Send()
+}
+
+type logGroupInput struct {
+	_            struct{}           `type:"structure"`
+	LogGroupName *string            `locationName:"logGroupName" type:"string"`
+	Tags         map[string]*string `locationName:"tags" type:"map"`
+}
+
+type logRetentionInput struct {
+	_               struct{} `type:"structure"`
+	LogGroupName    *string  `locationName:"logGroupName" type:"string"`
+	RetentionInDays *int64   `locationName:"retentionInDays" type:"integer"`
+}
+
+type logStreamInput struct {
+	_             struct{} `type:"structure"`
+	LogGroupName  *string  `locationName:"logGroupName" type:"string"`
+	LogStreamName *string  `locationName:"logStreamName" type:"string"`
+}
+
+type logEvent struct {
+	_         struct{} `type:"structure"`
+	Message   *string  `locationName:"message" type:"string"`
+	Timestamp *int64   `locationName:"timestamp" type:"long"`
+}
+
+type logEventsInput struct {
+	_             struct{}    `type:"structure"`
+	LogEvents     []*logEvent `locationName:"logEvents" type:"list"`
+	LogGroupName  *string     `locationName:"logGroupName" type:"string"`
+	LogStreamName *string     `locationName:"logStreamName" type:"string"`
+	SequenceToken *string     `locationName:"sequenceToken" type:"string"`
+}
+
+type logEventsOutput struct {
+	_                 struct{} `type:"structure"`
+	NextSequenceToken *string  `locationName:"nextSequenceToken" type:"string"`
+}
+
+func (c *logsClient) CreateLogGroup(input *logGroupInput) error {
+	return c.send("CreateLogGroup", input, nil)
+}
+
+func (c *logsClient) PutRetentionPolicy(input *logRetentionInput) error {
+	return c.send("PutRetentionPolicy", input, nil)
+}
+
+func (c *logsClient) CreateLogStream(input *logStreamInput) error {
+	return c.send("CreateLogStream", input, nil)
+}
+
+func (c *logsClient) PutLogEvents(input *logEventsInput) (*logEventsOutput, error) {
+	output := &logEventsOutput{}
+	return output, c.send("PutLogEvents", input, output)
+}
+
+// logRetentionDays are the retention periods CloudWatch Logs accepts.
+var logRetentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1827, 3653}
+
+func validLogRetentionDays(days int) bool {
+	for _, d := range logRetentionDays {
+		if d == days {
+			return true
+		}
+	}
+	return false
+}
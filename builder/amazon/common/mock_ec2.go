@@ -0,0 +1,1566 @@
+package common
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/packer"
+)
+
+const (
+	// mockAccountId owns the resources the builds create in the mock EC2.
+	mockAccountId = "123456789012"
+
+	// mockSourceAccountId owns the source images the mock EC2 makes up.
+	mockSourceAccountId = "210987654321"
+
+	mockVpcId = "vpc-0mock"
+)
+
+// mockEC2 is an in-process EC2 the amazon builders talk to with mock_ec2,
+// so that templates can be tested without AWS. It keeps the resources the
+// builds create in memory, as EC2 would, and fails the calls EC2 would
+// fail, such as deleting a security group an instance still uses. The
+// source images builds ask for are made up. Calls can be made to fail with
+// mock_ec2_errors.
+type mockEC2 struct {
+	errors map[string]string
+
+	lock    sync.Mutex
+	ids     int
+	regions map[string]*mockRegion
+}
+
+type mockRegion struct {
+	ec2  *mockEC2
+	name string
+
+	images       map[string]*ec2.Image
+	instances    map[string]*ec2.Instance
+	keyPairs     map[string]*ec2.KeyPairInfo
+	groups       map[string]*ec2.SecurityGroup
+	snapshots    map[string]*ec2.Snapshot
+	spotRequests map[string]*ec2.SpotInstanceRequest
+	volumes      map[string]*ec2.Volume
+}
+
+// mockSession returns a session whose EC2 clients call a mock EC2 instead
+// of AWS.
+func (c *AccessConfig) mockSession() (*session.Session, error) {
+	region := c.RawRegion
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion(region).
+		WithCredentials(credentials.NewStaticCredentials("mock", "mock", "")))
+	if err != nil {
+		return nil, err
+	}
+
+	c.mock = &mockEC2{
+		errors:  c.MockEC2Errors,
+		regions: make(map[string]*mockRegion),
+	}
+	sess.Handlers.Send.Clear()
+	sess.Handlers.Send.PushBack(c.mock.send)
+	log.Printf("[INFO] Using the mock EC2, in %s", region)
+
+	c.session = sess
+	return sess, nil
+}
+
+// ReportMockEC2 reports the temporary resources the build left behind in
+// the mock EC2, when mock_ec2 is set. Those are the ones packer cleanup
+// would find, which the build should have deleted.
+func (c *AccessConfig) ReportMockEC2(ui packer.Ui) {
+	if c.mock == nil {
+		return
+	}
+
+	for _, region := range c.mock.regionNames() {
+		orphans, err := FindOrphans(ec2.New(c.session, aws.NewConfig().WithRegion(region)),
+			time.Now().Add(time.Hour))
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error looking for the resources left in the mock EC2: %s", err))
+			continue
+		}
+		for _, orphan := range orphans {
+			ui.Error(fmt.Sprintf("The build left %s behind in %s of the mock EC2", orphan, region))
+		}
+	}
+}
+
+func (m *mockEC2) regionNames() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var names []string
+	for name := range m.regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *mockEC2) region(name string) *mockRegion {
+	r, ok := m.regions[name]
+	if !ok {
+		r = &mockRegion{
+			ec2:          m,
+			name:         name,
+			images:       make(map[string]*ec2.Image),
+			instances:    make(map[string]*ec2.Instance),
+			keyPairs:     make(map[string]*ec2.KeyPairInfo),
+			groups:       make(map[string]*ec2.SecurityGroup),
+			snapshots:    make(map[string]*ec2.Snapshot),
+			spotRequests: make(map[string]*ec2.SpotInstanceRequest),
+			volumes:      make(map[string]*ec2.Volume),
+		}
+		m.regions[name] = r
+	}
+	return r
+}
+
+// id returns a new resource ID with the prefix.
+func (m *mockEC2) id(prefix string) string {
+	m.ids++
+	return fmt.Sprintf("%s-%017x", prefix, m.ids)
+}
+
+// send answers the request in place of AWS. It is the only send handler
+// of the session.
+func (m *mockEC2) send(r *request.Request) {
+	r.HTTPResponse = &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+	// The outputs are filled here, there is no response to decode them from
+	r.Handlers.Unmarshal.Clear()
+
+	operation := r.Operation.Name
+	log.Printf("[DEBUG] mock EC2: %s in %s", operation, aws.StringValue(r.Config.Region))
+	if r.ClientInfo.ServiceName != ec2.ServiceName {
+		r.Error = mockError(r, "UnsupportedOperation",
+			"mock_ec2 only mocks EC2, not %s", r.ClientInfo.ServiceName)
+		return
+	}
+	if code, ok := m.errors[operation]; ok {
+		r.Error = mockError(r, code, "%s failed as set in mock_ec2_errors", operation)
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	region := m.region(aws.StringValue(r.Config.Region))
+
+	var err error
+	switch in := r.Params.(type) {
+	case *ec2.DescribeImagesInput:
+		err = region.describeImages(in, r.Data.(*ec2.DescribeImagesOutput))
+	case *ec2.CreateImageInput:
+		err = region.createImage(in, r.Data.(*ec2.CreateImageOutput))
+	case *ec2.RegisterImageInput:
+		err = region.registerImage(in, r.Data.(*ec2.RegisterImageOutput))
+	case *ec2.CopyImageInput:
+		err = region.copyImage(in, r.Data.(*ec2.CopyImageOutput))
+	case *ec2.DeregisterImageInput:
+		err = region.deregisterImage(in)
+	case *ec2.ModifyImageAttributeInput:
+		err = region.modifyImageAttribute(in)
+
+	case *ec2.RunInstancesInput:
+		err = region.runInstances(in, r.Data.(*ec2.Reservation))
+	case *ec2.DescribeInstancesInput:
+		err = region.describeInstances(in, r.Data.(*ec2.DescribeInstancesOutput))
+	case *ec2.StartInstancesInput:
+		out := r.Data.(*ec2.StartInstancesOutput)
+		out.StartingInstances, err = region.setInstanceStates(in.InstanceIds, ec2.InstanceStateNameRunning)
+	case *ec2.StopInstancesInput:
+		out := r.Data.(*ec2.StopInstancesOutput)
+		out.StoppingInstances, err = region.setInstanceStates(in.InstanceIds, ec2.InstanceStateNameStopped)
+	case *ec2.TerminateInstancesInput:
+		out := r.Data.(*ec2.TerminateInstancesOutput)
+		out.TerminatingInstances, err = region.setInstanceStates(in.InstanceIds, ec2.InstanceStateNameTerminated)
+	case *ec2.ModifyInstanceAttributeInput:
+		err = region.modifyInstanceAttribute(in)
+
+	case *ec2.DescribeSpotPriceHistoryInput:
+		region.describeSpotPriceHistory(in, r.Data.(*ec2.DescribeSpotPriceHistoryOutput))
+	case *ec2.RequestSpotInstancesInput:
+		err = region.requestSpotInstances(in, r.Data.(*ec2.RequestSpotInstancesOutput))
+	case *ec2.DescribeSpotInstanceRequestsInput:
+		err = region.describeSpotInstanceRequests(in, r.Data.(*ec2.DescribeSpotInstanceRequestsOutput))
+	case *ec2.CancelSpotInstanceRequestsInput:
+		err = region.cancelSpotInstanceRequests(in, r.Data.(*ec2.CancelSpotInstanceRequestsOutput))
+
+	case *ec2.CreateVolumeInput:
+		err = region.createVolume(in, r.Data.(*ec2.Volume))
+	case *ec2.AttachVolumeInput:
+		err = region.attachVolume(in, r.Data.(*ec2.VolumeAttachment))
+	case *ec2.DetachVolumeInput:
+		err = region.detachVolume(in, r.Data.(*ec2.VolumeAttachment))
+	case *ec2.DeleteVolumeInput:
+		err = region.deleteVolume(in)
+	case *ec2.DescribeVolumesInput:
+		err = region.describeVolumes(in, r.Data.(*ec2.DescribeVolumesOutput))
+
+	case *ec2.CreateSnapshotInput:
+		err = region.createSnapshot(in, r.Data.(*ec2.Snapshot))
+	case *ec2.DescribeSnapshotsInput:
+		err = region.describeSnapshots(in, r.Data.(*ec2.DescribeSnapshotsOutput))
+	case *ec2.DeleteSnapshotInput:
+		err = region.deleteSnapshot(in)
+	case *ec2.ModifySnapshotAttributeInput:
+		if _, ok := region.snapshots[aws.StringValue(in.SnapshotId)]; !ok {
+			err = notFound("InvalidSnapshot.NotFound", aws.StringValue(in.SnapshotId))
+		}
+
+	case *ec2.CreateKeyPairInput:
+		err = region.createKeyPair(in, r.Data.(*ec2.CreateKeyPairOutput))
+	case *ec2.ImportKeyPairInput:
+		err = region.importKeyPair(in, r.Data.(*ec2.ImportKeyPairOutput))
+	case *ec2.DeleteKeyPairInput:
+		delete(region.keyPairs, aws.StringValue(in.KeyName))
+	case *ec2.DescribeKeyPairsInput:
+		err = region.describeKeyPairs(in, r.Data.(*ec2.DescribeKeyPairsOutput))
+
+	case *ec2.CreateSecurityGroupInput:
+		err = region.createSecurityGroup(in, r.Data.(*ec2.CreateSecurityGroupOutput))
+	case *ec2.AuthorizeSecurityGroupIngressInput:
+		err = region.authorizeSecurityGroupIngress(in)
+	case *ec2.DeleteSecurityGroupInput:
+		err = region.deleteSecurityGroup(in)
+	case *ec2.DescribeSecurityGroupsInput:
+		err = region.describeSecurityGroups(in, r.Data.(*ec2.DescribeSecurityGroupsOutput))
+
+	case *ec2.CreateTagsInput:
+		err = region.createTags(in)
+	case *ec2.DescribeTagsInput:
+		region.describeTags(in, r.Data.(*ec2.DescribeTagsOutput))
+	case *ec2.DescribeSubnetsInput:
+		region.describeSubnets(in, r.Data.(*ec2.DescribeSubnetsOutput))
+
+	default:
+		err = fmt.Errorf("mock_ec2 doesn't support %s", operation)
+	}
+
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok {
+			r.Error = mockError(r, awsErr.Code(), "%s", awsErr.Message())
+		} else {
+			r.Error = mockError(r, "UnsupportedOperation", "%s", err)
+		}
+	}
+}
+
+// mockError returns the error EC2 would respond with, setting the status
+// code the retry handlers look at.
+func mockError(r *request.Request, code, format string, args ...interface{}) error {
+	r.HTTPResponse.StatusCode = http.StatusBadRequest
+	return awserr.NewRequestFailure(
+		awserr.New(code, fmt.Sprintf(format, args...), nil), http.StatusBadRequest, "mock")
+}
+
+func notFound(code, id string) error {
+	return awserr.New(code, fmt.Sprintf("The ID '%s' does not exist", id), nil)
+}
+
+// mockGlob returns whether the value matches the filter value, in which
+// * and ? are wildcards.
+func mockGlob(pattern, value string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	expr = strings.Replace(expr, `\?`, ".", -1)
+	ok, _ := regexp.MatchString("^"+expr+"$", value)
+	return ok
+}
+
+// mockMatch returns whether a resource with the tags and values matches
+// the filters. The filters on values the resource doesn't have are
+// ignored, as the mock EC2 doesn't know about all of them.
+func mockMatch(filters []*ec2.Filter, tags []*ec2.Tag, values map[string]string) bool {
+	for _, filter := range filters {
+		name := aws.StringValue(filter.Name)
+
+		var candidates []string
+		switch {
+		case name == "tag-key":
+			for _, tag := range tags {
+				candidates = append(candidates, aws.StringValue(tag.Key))
+			}
+		case strings.HasPrefix(name, "tag:"):
+			for _, tag := range tags {
+				if aws.StringValue(tag.Key) == strings.TrimPrefix(name, "tag:") {
+					candidates = append(candidates, aws.StringValue(tag.Value))
+				}
+			}
+		default:
+			value, ok := values[name]
+			if !ok {
+				log.Printf("[WARN] mock EC2: ignoring the unknown filter %s", name)
+				continue
+			}
+			candidates = []string{value}
+		}
+
+		matched := false
+		for _, pattern := range filter.Values {
+			for _, candidate := range candidates {
+				if mockGlob(aws.StringValue(pattern), candidate) {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeTags returns the tags with the added ones, which replace the tags
+// with the same keys.
+func mergeTags(tags []*ec2.Tag, added []*ec2.Tag) []*ec2.Tag {
+	for _, tag := range added {
+		replaced := false
+		for i, existing := range tags {
+			if aws.StringValue(existing.Key) == aws.StringValue(tag.Key) {
+				tags[i] = tag
+				replaced = true
+			}
+		}
+		if !replaced {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func specTags(specs []*ec2.TagSpecification, resourceType string) []*ec2.Tag {
+	var tags []*ec2.Tag
+	for _, spec := range specs {
+		if aws.StringValue(spec.ResourceType) == resourceType {
+			tags = mergeTags(tags, spec.Tags)
+		}
+	}
+	return tags
+}
+
+func (r *mockRegion) defaultAvailabilityZone() string {
+	return r.name + "a"
+}
+
+// image returns the image with the ID. Source images are owned by others,
+// so the ones the mock EC2 doesn't have are made up.
+func (r *mockRegion) image(id string) *ec2.Image {
+	if image, ok := r.images[id]; ok {
+		return image
+	}
+	if !strings.HasPrefix(id, "ami-") {
+		return nil
+	}
+	image := r.sourceImage(id, fmt.Sprintf("mock-source-%s", id), mockSourceAccountId)
+	r.images[id] = image
+	return image
+}
+
+func (r *mockRegion) sourceImage(id, name, owner string) *ec2.Image {
+	return &ec2.Image{
+		Architecture: aws.String(ec2.ArchitectureValuesX8664),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{{
+			DeviceName: aws.String("/dev/sda1"),
+			Ebs: &ec2.EbsBlockDevice{
+				DeleteOnTermination: aws.Bool(true),
+				SnapshotId:          aws.String(r.ec2.id("snap")),
+				VolumeSize:          aws.Int64(8),
+				VolumeType:          aws.String(ec2.VolumeTypeGp2),
+			},
+		}},
+		CreationDate:       aws.String(time.Now().UTC().Format(time.RFC3339)),
+		EnaSupport:         aws.Bool(true),
+		ImageId:            aws.String(id),
+		ImageType:          aws.String(ec2.ImageTypeValuesMachine),
+		Name:               aws.String(name),
+		OwnerId:            aws.String(owner),
+		Public:             aws.Bool(true),
+		RootDeviceName:     aws.String("/dev/sda1"),
+		RootDeviceType:     aws.String(ec2.DeviceTypeEbs),
+		State:              aws.String(ec2.ImageStateAvailable),
+		VirtualizationType: aws.String(ec2.VirtualizationTypeHvm),
+	}
+}
+
+func imageValues(image *ec2.Image) map[string]string {
+	return map[string]string{
+		"architecture":        aws.StringValue(image.Architecture),
+		"description":         aws.StringValue(image.Description),
+		"image-id":            aws.StringValue(image.ImageId),
+		"image-type":          aws.StringValue(image.ImageType),
+		"is-public":           fmt.Sprint(aws.BoolValue(image.Public)),
+		"name":                aws.StringValue(image.Name),
+		"owner-id":            aws.StringValue(image.OwnerId),
+		"root-device-type":    aws.StringValue(image.RootDeviceType),
+		"state":               aws.StringValue(image.State),
+		"virtualization-type": aws.StringValue(image.VirtualizationType),
+	}
+}
+
+func imageOwnedBy(image *ec2.Image, owners []*string) bool {
+	if len(owners) == 0 {
+		return true
+	}
+	for _, owner := range owners {
+		switch aws.StringValue(owner) {
+		case "self":
+			if aws.StringValue(image.OwnerId) == mockAccountId {
+				return true
+			}
+		case aws.StringValue(image.OwnerId), aws.StringValue(image.ImageOwnerAlias):
+			return true
+		}
+	}
+	return false
+}
+
+func (r *mockRegion) describeImages(in *ec2.DescribeImagesInput, out *ec2.DescribeImagesOutput) error {
+	var images []*ec2.Image
+	if len(in.ImageIds) > 0 {
+		for _, id := range in.ImageIds {
+			image := r.image(aws.StringValue(id))
+			if image == nil {
+				return notFound("InvalidAMIID.NotFound", aws.StringValue(id))
+			}
+			images = append(images, image)
+		}
+	} else {
+		for _, image := range r.images {
+			images = append(images, image)
+		}
+	}
+
+	for _, image := range images {
+		if imageOwnedBy(image, in.Owners) && mockMatch(in.Filters, image.Tags, imageValues(image)) {
+			out.Images = append(out.Images, awsutil.CopyOf(image).(*ec2.Image))
+		}
+	}
+
+	// Make up the image source_ami_filter asks for, if the owners are others
+	if len(out.Images) == 0 && len(in.ImageIds) == 0 && len(in.Owners) > 0 &&
+		aws.StringValue(in.Owners[0]) != "self" {
+		image := r.sourceImage(r.ec2.id("ami"), "mock-source", aws.StringValue(in.Owners[0]))
+		for _, filter := range in.Filters {
+			if len(filter.Values) == 0 {
+				continue
+			}
+			value := strings.Replace(aws.StringValue(filter.Values[0]), "*", "mock", -1)
+			value = strings.Replace(value, "?", "x", -1)
+			switch aws.StringValue(filter.Name) {
+			case "architecture":
+				image.Architecture = aws.String(value)
+			case "name":
+				image.Name = aws.String(value)
+			case "virtualization-type":
+				image.VirtualizationType = aws.String(value)
+			}
+		}
+		if mockMatch(in.Filters, image.Tags, imageValues(image)) {
+			r.images[*image.ImageId] = image
+			out.Images = append(out.Images, awsutil.CopyOf(image).(*ec2.Image))
+		}
+	}
+
+	return nil
+}
+
+// checkImageName fails if the account already has an image with the name.
+func (r *mockRegion) checkImageName(name string) error {
+	for _, image := range r.images {
+		if aws.StringValue(image.OwnerId) == mockAccountId && aws.StringValue(image.Name) == name {
+			return awserr.New("InvalidAMIName.Duplicate",
+				fmt.Sprintf("AMI name %s is already in use by AMI %s", name, *image.ImageId), nil)
+		}
+	}
+	return nil
+}
+
+func (r *mockRegion) newImage(name, description string) *ec2.Image {
+	return &ec2.Image{
+		CreationDate: aws.String(time.Now().UTC().Format(time.RFC3339)),
+		Description:  aws.String(description),
+		ImageId:      aws.String(r.ec2.id("ami")),
+		ImageType:    aws.String(ec2.ImageTypeValuesMachine),
+		Name:         aws.String(name),
+		OwnerId:      aws.String(mockAccountId),
+		Public:       aws.Bool(false),
+		State:        aws.String(ec2.ImageStateAvailable),
+	}
+}
+
+func (r *mockRegion) createImage(in *ec2.CreateImageInput, out *ec2.CreateImageOutput) error {
+	instance, ok := r.instances[aws.StringValue(in.InstanceId)]
+	if !ok {
+		return notFound("InvalidInstanceID.NotFound", aws.StringValue(in.InstanceId))
+	}
+	if err := r.checkImageName(aws.StringValue(in.Name)); err != nil {
+		return err
+	}
+
+	image := r.newImage(aws.StringValue(in.Name), aws.StringValue(in.Description))
+	image.EnaSupport = instance.EnaSupport
+	image.RootDeviceName = instance.RootDeviceName
+	image.RootDeviceType = aws.String(ec2.DeviceTypeEbs)
+	image.SriovNetSupport = instance.SriovNetSupport
+	image.VirtualizationType = instance.VirtualizationType
+	image.Architecture = instance.Architecture
+
+	for _, mapping := range instance.BlockDeviceMappings {
+		volume, ok := r.volumes[aws.StringValue(mapping.Ebs.VolumeId)]
+		if !ok {
+			continue
+		}
+		snapshot := r.snapshot(volume, fmt.Sprintf("Created by CreateImage(%s) for %s",
+			*instance.InstanceId, *image.ImageId))
+		image.BlockDeviceMappings = append(image.BlockDeviceMappings, &ec2.BlockDeviceMapping{
+			DeviceName: mapping.DeviceName,
+			Ebs: &ec2.EbsBlockDevice{
+				DeleteOnTermination: mapping.Ebs.DeleteOnTermination,
+				Encrypted:           volume.Encrypted,
+				SnapshotId:          snapshot.SnapshotId,
+				VolumeSize:          volume.Size,
+				VolumeType:          volume.VolumeType,
+			},
+		})
+	}
+	image.BlockDeviceMappings = mergeMappings(image.BlockDeviceMappings, in.BlockDeviceMappings)
+
+	r.images[*image.ImageId] = image
+	out.ImageId = image.ImageId
+	return nil
+}
+
+// mergeMappings returns the block device mappings with the added ones,
+// which replace the ones of the same devices or remove them with NoDevice.
+func mergeMappings(mappings, added []*ec2.BlockDeviceMapping) []*ec2.BlockDeviceMapping {
+	for _, mapping := range added {
+		var merged []*ec2.BlockDeviceMapping
+		for _, existing := range mappings {
+			if aws.StringValue(existing.DeviceName) != aws.StringValue(mapping.DeviceName) {
+				merged = append(merged, existing)
+				continue
+			}
+			if mapping.Ebs != nil && existing.Ebs != nil {
+				ebs := awsutil.CopyOf(existing.Ebs).(*ec2.EbsBlockDevice)
+				if mapping.Ebs.DeleteOnTermination != nil {
+					ebs.DeleteOnTermination = mapping.Ebs.DeleteOnTermination
+				}
+				if mapping.Ebs.Encrypted != nil {
+					ebs.Encrypted = mapping.Ebs.Encrypted
+				}
+				if mapping.Ebs.VolumeSize != nil {
+					ebs.VolumeSize = mapping.Ebs.VolumeSize
+				}
+				if mapping.Ebs.VolumeType != nil {
+					ebs.VolumeType = mapping.Ebs.VolumeType
+				}
+				mapping = &ec2.BlockDeviceMapping{DeviceName: mapping.DeviceName, Ebs: ebs}
+			}
+		}
+		if mapping.NoDevice == nil {
+			merged = append(merged, mapping)
+		}
+		mappings = merged
+	}
+	return mappings
+}
+
+func (r *mockRegion) registerImage(in *ec2.RegisterImageInput, out *ec2.RegisterImageOutput) error {
+	if err := r.checkImageName(aws.StringValue(in.Name)); err != nil {
+		return err
+	}
+	for _, mapping := range in.BlockDeviceMappings {
+		if mapping.Ebs == nil || mapping.Ebs.SnapshotId == nil {
+			continue
+		}
+		if _, ok := r.snapshots[*mapping.Ebs.SnapshotId]; !ok {
+			return notFound("InvalidSnapshot.NotFound", *mapping.Ebs.SnapshotId)
+		}
+	}
+
+	image := r.newImage(aws.StringValue(in.Name), aws.StringValue(in.Description))
+	image.Architecture = in.Architecture
+	if image.Architecture == nil {
+		image.Architecture = aws.String(ec2.ArchitectureValuesX8664)
+	}
+	image.BlockDeviceMappings = in.BlockDeviceMappings
+	image.EnaSupport = in.EnaSupport
+	image.RootDeviceName = in.RootDeviceName
+	image.RootDeviceType = aws.String(ec2.DeviceTypeEbs)
+	image.SriovNetSupport = in.SriovNetSupport
+	image.VirtualizationType = in.VirtualizationType
+	if image.VirtualizationType == nil {
+		image.VirtualizationType = aws.String(ec2.VirtualizationTypeParavirtual)
+	}
+
+	r.images[*image.ImageId] = image
+	out.ImageId = image.ImageId
+	return nil
+}
+
+func (r *mockRegion) copyImage(in *ec2.CopyImageInput, out *ec2.CopyImageOutput) error {
+	source := r.ec2.region(aws.StringValue(in.SourceRegion)).image(aws.StringValue(in.SourceImageId))
+	if source == nil {
+		return notFound("InvalidAMIID.NotFound", aws.StringValue(in.SourceImageId))
+	}
+	name := aws.StringValue(in.Name)
+	if name == "" {
+		name = aws.StringValue(source.Name)
+	}
+
+	image := awsutil.CopyOf(source).(*ec2.Image)
+	image.CreationDate = aws.String(time.Now().UTC().Format(time.RFC3339))
+	image.Description = in.Description
+	image.ImageId = aws.String(r.ec2.id("ami"))
+	image.Name = aws.String(name)
+	image.OwnerId = aws.String(mockAccountId)
+	image.Public = aws.Bool(false)
+	image.Tags = nil
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
+		}
+		snapshot := &ec2.Snapshot{
+			Description: aws.String(fmt.Sprintf("Copied for DestinationAmi %s from SourceAmi %s",
+				*image.ImageId, *source.ImageId)),
+			Encrypted:  aws.Bool(aws.BoolValue(mapping.Ebs.Encrypted) || aws.BoolValue(in.Encrypted)),
+			KmsKeyId:   in.KmsKeyId,
+			OwnerId:    aws.String(mockAccountId),
+			Progress:   aws.String("100%"),
+			SnapshotId: aws.String(r.ec2.id("snap")),
+			StartTime:  aws.Time(time.Now()),
+			State:      aws.String(ec2.SnapshotStateCompleted),
+			VolumeSize: mapping.Ebs.VolumeSize,
+		}
+		r.snapshots[*snapshot.SnapshotId] = snapshot
+		mapping.Ebs.SnapshotId = snapshot.SnapshotId
+		mapping.Ebs.Encrypted = snapshot.Encrypted
+	}
+
+	r.images[*image.ImageId] = image
+	out.ImageId = image.ImageId
+	return nil
+}
+
+func (r *mockRegion) deregisterImage(in *ec2.DeregisterImageInput) error {
+	image, ok := r.images[aws.StringValue(in.ImageId)]
+	if !ok || aws.StringValue(image.OwnerId) != mockAccountId {
+		return notFound("InvalidAMIID.NotFound", aws.StringValue(in.ImageId))
+	}
+	delete(r.images, *image.ImageId)
+	return nil
+}
+
+func (r *mockRegion) modifyImageAttribute(in *ec2.ModifyImageAttributeInput) error {
+	image, ok := r.images[aws.StringValue(in.ImageId)]
+	if !ok || aws.StringValue(image.OwnerId) != mockAccountId {
+		return notFound("InvalidAMIID.NotFound", aws.StringValue(in.ImageId))
+	}
+	if in.Description != nil {
+		image.Description = in.Description.Value
+	}
+	if in.LaunchPermission != nil {
+		for _, permission := range in.LaunchPermission.Add {
+			if aws.StringValue(permission.Group) == "all" {
+				image.Public = aws.Bool(true)
+			}
+		}
+	}
+	return nil
+}
+
+// mockLaunch is what instances are launched with, by RunInstances or by
+// a spot request.
+type mockLaunch struct {
+	availabilityZone string
+	ebsOptimized     *bool
+	groupIds         []*string
+	hostId           *string
+	imageId          string
+	instanceType     string
+	keyName          string
+	mappings         []*ec2.BlockDeviceMapping
+	subnetId         string
+	tags             []*ec2.Tag
+	tenancy          *string
+	volumeTags       []*ec2.Tag
+}
+
+func (r *mockRegion) launch(launch *mockLaunch) (*ec2.Instance, error) {
+	image := r.image(launch.imageId)
+	if image == nil {
+		return nil, notFound("InvalidAMIID.NotFound", launch.imageId)
+	}
+	if launch.keyName != "" {
+		if _, ok := r.keyPairs[launch.keyName]; !ok {
+			return nil, awserr.New("InvalidKeyPair.NotFound",
+				fmt.Sprintf("The key pair '%s' does not exist", launch.keyName), nil)
+		}
+	}
+	if launch.availabilityZone == "" {
+		launch.availabilityZone = r.defaultAvailabilityZone()
+	}
+	if launch.subnetId == "" {
+		launch.subnetId = "subnet-0mock"
+	}
+
+	n := r.ec2.ids % 250
+	instance := &ec2.Instance{
+		Architecture:       image.Architecture,
+		EbsOptimized:       launch.ebsOptimized,
+		EnaSupport:         image.EnaSupport,
+		ImageId:            image.ImageId,
+		InstanceId:         aws.String(r.ec2.id("i")),
+		InstanceType:       aws.String(launch.instanceType),
+		LaunchTime:         aws.Time(time.Now()),
+		Placement:          &ec2.Placement{AvailabilityZone: &launch.availabilityZone},
+		PrivateDnsName:     aws.String(fmt.Sprintf("ip-10-0-0-%d.ec2.internal", n+4)),
+		PrivateIpAddress:   aws.String(fmt.Sprintf("10.0.0.%d", n+4)),
+		PublicDnsName:      aws.String(fmt.Sprintf("ec2-203-0-113-%d.compute-1.amazonaws.com", n+4)),
+		PublicIpAddress:    aws.String(fmt.Sprintf("203.0.113.%d", n+4)),
+		RootDeviceName:     image.RootDeviceName,
+		RootDeviceType:     image.RootDeviceType,
+		SriovNetSupport:    image.SriovNetSupport,
+		State:              mockInstanceState(ec2.InstanceStateNameRunning),
+		SubnetId:           &launch.subnetId,
+		Tags:               launch.tags,
+		VirtualizationType: image.VirtualizationType,
+		VpcId:              aws.String(mockVpcId),
+	}
+	if launch.keyName != "" {
+		instance.KeyName = &launch.keyName
+	}
+	instance.Placement.HostId = launch.hostId
+	instance.Placement.Tenancy = launch.tenancy
+	for _, id := range launch.groupIds {
+		group := &ec2.GroupIdentifier{GroupId: id}
+		if g, ok := r.groups[aws.StringValue(id)]; ok {
+			group.GroupName = g.GroupName
+		}
+		instance.SecurityGroups = append(instance.SecurityGroups, group)
+	}
+
+	for _, mapping := range mergeMappings(image.BlockDeviceMappings, launch.mappings) {
+		if mapping.Ebs == nil {
+			continue
+		}
+		volume := &ec2.Volume{
+			AvailabilityZone: &launch.availabilityZone,
+			CreateTime:       aws.Time(time.Now()),
+			Encrypted:        aws.Bool(aws.BoolValue(mapping.Ebs.Encrypted)),
+			Iops:             mapping.Ebs.Iops,
+			Size:             mapping.Ebs.VolumeSize,
+			SnapshotId:       mapping.Ebs.SnapshotId,
+			State:            aws.String(ec2.VolumeStateInUse),
+			Tags:             launch.volumeTags,
+			VolumeId:         aws.String(r.ec2.id("vol")),
+			VolumeType:       mapping.Ebs.VolumeType,
+		}
+		if volume.Size == nil {
+			volume.Size = aws.Int64(8)
+		}
+		if volume.VolumeType == nil {
+			volume.VolumeType = aws.String(ec2.VolumeTypeStandard)
+		}
+		deleteOnTermination := mapping.Ebs.DeleteOnTermination == nil || *mapping.Ebs.DeleteOnTermination
+		volume.Attachments = []*ec2.VolumeAttachment{{
+			AttachTime:          aws.Time(time.Now()),
+			DeleteOnTermination: aws.Bool(deleteOnTermination),
+			Device:              mapping.DeviceName,
+			InstanceId:          instance.InstanceId,
+			State:               aws.String(ec2.VolumeAttachmentStateAttached),
+			VolumeId:            volume.VolumeId,
+		}}
+		r.volumes[*volume.VolumeId] = volume
+
+		instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, &ec2.InstanceBlockDeviceMapping{
+			DeviceName: mapping.DeviceName,
+			Ebs: &ec2.EbsInstanceBlockDevice{
+				AttachTime:          aws.Time(time.Now()),
+				DeleteOnTermination: aws.Bool(deleteOnTermination),
+				Status:              aws.String(ec2.AttachmentStatusAttached),
+				VolumeId:            volume.VolumeId,
+			},
+		})
+	}
+
+	r.instances[*instance.InstanceId] = instance
+	return instance, nil
+}
+
+func mockInstanceState(name string) *ec2.InstanceState {
+	codes := map[string]int64{
+		ec2.InstanceStateNamePending:    0,
+		ec2.InstanceStateNameRunning:    16,
+		ec2.InstanceStateNameTerminated: 48,
+		ec2.InstanceStateNameStopped:    80,
+	}
+	return &ec2.InstanceState{Code: aws.Int64(codes[name]), Name: aws.String(name)}
+}
+
+func (r *mockRegion) runInstances(in *ec2.RunInstancesInput, out *ec2.Reservation) error {
+	launch := &mockLaunch{
+		ebsOptimized: in.EbsOptimized,
+		groupIds:     in.SecurityGroupIds,
+		imageId:      aws.StringValue(in.ImageId),
+		instanceType: aws.StringValue(in.InstanceType),
+		keyName:      aws.StringValue(in.KeyName),
+		mappings:     in.BlockDeviceMappings,
+		subnetId:     aws.StringValue(in.SubnetId),
+		tags:         specTags(in.TagSpecifications, ec2.ResourceTypeInstance),
+		volumeTags:   specTags(in.TagSpecifications, ec2.ResourceTypeVolume),
+	}
+	if in.Placement != nil {
+		launch.availabilityZone = aws.StringValue(in.Placement.AvailabilityZone)
+		launch.hostId = in.Placement.HostId
+		launch.tenancy = in.Placement.Tenancy
+	}
+	if len(in.NetworkInterfaces) > 0 {
+		launch.groupIds = in.NetworkInterfaces[0].Groups
+		launch.subnetId = aws.StringValue(in.NetworkInterfaces[0].SubnetId)
+	}
+
+	instance, err := r.launch(launch)
+	if err != nil {
+		return err
+	}
+
+	out.Instances = []*ec2.Instance{awsutil.CopyOf(instance).(*ec2.Instance)}
+	out.OwnerId = aws.String(mockAccountId)
+	out.ReservationId = aws.String(r.ec2.id("r"))
+	return nil
+}
+
+func instanceValues(instance *ec2.Instance) map[string]string {
+	return map[string]string{
+		"availability-zone":   aws.StringValue(instance.Placement.AvailabilityZone),
+		"image-id":            aws.StringValue(instance.ImageId),
+		"instance-id":         aws.StringValue(instance.InstanceId),
+		"instance-state-name": aws.StringValue(instance.State.Name),
+		"instance-type":       aws.StringValue(instance.InstanceType),
+		"key-name":            aws.StringValue(instance.KeyName),
+		"subnet-id":           aws.StringValue(instance.SubnetId),
+		"vpc-id":              aws.StringValue(instance.VpcId),
+	}
+}
+
+func (r *mockRegion) describeInstances(in *ec2.DescribeInstancesInput, out *ec2.DescribeInstancesOutput) error {
+	var instances []*ec2.Instance
+	if len(in.InstanceIds) > 0 {
+		for _, id := range in.InstanceIds {
+			instance, ok := r.instances[aws.StringValue(id)]
+			if !ok {
+				return notFound("InvalidInstanceID.NotFound", aws.StringValue(id))
+			}
+			instances = append(instances, instance)
+		}
+	} else {
+		for _, instance := range r.instances {
+			instances = append(instances, instance)
+		}
+	}
+
+	for _, instance := range instances {
+		if mockMatch(in.Filters, instance.Tags, instanceValues(instance)) {
+			out.Reservations = append(out.Reservations, &ec2.Reservation{
+				Instances: []*ec2.Instance{awsutil.CopyOf(instance).(*ec2.Instance)},
+				OwnerId:   aws.String(mockAccountId),
+			})
+		}
+	}
+	return nil
+}
+
+// setInstanceStates moves the instances to the state right away. The
+// volumes of terminated instances are deleted or detached.
+func (r *mockRegion) setInstanceStates(ids []*string, state string) ([]*ec2.InstanceStateChange, error) {
+	var changes []*ec2.InstanceStateChange
+	for _, id := range ids {
+		instance, ok := r.instances[aws.StringValue(id)]
+		if !ok {
+			return nil, notFound("InvalidInstanceID.NotFound", aws.StringValue(id))
+		}
+		if *instance.State.Name == ec2.InstanceStateNameTerminated && state != ec2.InstanceStateNameTerminated {
+			return nil, awserr.New("IncorrectInstanceState",
+				fmt.Sprintf("The instance '%s' is not in a state from which it can be started or stopped", *id), nil)
+		}
+		changes = append(changes, &ec2.InstanceStateChange{
+			CurrentState:  mockInstanceState(state),
+			InstanceId:    instance.InstanceId,
+			PreviousState: instance.State,
+		})
+		instance.State = mockInstanceState(state)
+		if state != ec2.InstanceStateNameTerminated {
+			continue
+		}
+
+		for _, mapping := range instance.BlockDeviceMappings {
+			volumeId := aws.StringValue(mapping.Ebs.VolumeId)
+			if aws.BoolValue(mapping.Ebs.DeleteOnTermination) {
+				delete(r.volumes, volumeId)
+			} else if volume, ok := r.volumes[volumeId]; ok {
+				volume.Attachments = nil
+				volume.State = aws.String(ec2.VolumeStateAvailable)
+			}
+		}
+		instance.BlockDeviceMappings = nil
+		if request, ok := r.spotRequests[aws.StringValue(instance.SpotInstanceRequestId)]; ok {
+			request.State = aws.String(ec2.SpotInstanceStateClosed)
+		}
+	}
+	return changes, nil
+}
+
+func (r *mockRegion) modifyInstanceAttribute(in *ec2.ModifyInstanceAttributeInput) error {
+	instance, ok := r.instances[aws.StringValue(in.InstanceId)]
+	if !ok {
+		return notFound("InvalidInstanceID.NotFound", aws.StringValue(in.InstanceId))
+	}
+	if in.EnaSupport != nil {
+		instance.EnaSupport = in.EnaSupport.Value
+	}
+	if in.SriovNetSupport != nil {
+		instance.SriovNetSupport = in.SriovNetSupport.Value
+	}
+	return nil
+}
+
+func (r *mockRegion) describeSpotPriceHistory(in *ec2.DescribeSpotPriceHistoryInput, out *ec2.DescribeSpotPriceHistoryOutput) {
+	zone := aws.StringValue(in.AvailabilityZone)
+	if zone == "" {
+		zone = r.defaultAvailabilityZone()
+	}
+	for _, instanceType := range in.InstanceTypes {
+		price := &ec2.SpotPrice{
+			AvailabilityZone: aws.String(zone),
+			InstanceType:     instanceType,
+			SpotPrice:        aws.String("0.010000"),
+			Timestamp:        aws.Time(time.Now()),
+		}
+		if len(in.ProductDescriptions) > 0 {
+			price.ProductDescription = in.ProductDescriptions[0]
+		}
+		out.SpotPriceHistory = append(out.SpotPriceHistory, price)
+	}
+}
+
+func (r *mockRegion) requestSpotInstances(in *ec2.RequestSpotInstancesInput, out *ec2.RequestSpotInstancesOutput) error {
+	spec := in.LaunchSpecification
+	launch := &mockLaunch{
+		ebsOptimized: spec.EbsOptimized,
+		groupIds:     spec.SecurityGroupIds,
+		imageId:      aws.StringValue(spec.ImageId),
+		instanceType: aws.StringValue(spec.InstanceType),
+		keyName:      aws.StringValue(spec.KeyName),
+		mappings:     spec.BlockDeviceMappings,
+		subnetId:     aws.StringValue(spec.SubnetId),
+	}
+	if spec.Placement != nil {
+		launch.availabilityZone = aws.StringValue(spec.Placement.AvailabilityZone)
+		launch.tenancy = spec.Placement.Tenancy
+	}
+	if len(spec.NetworkInterfaces) > 0 {
+		launch.groupIds = spec.NetworkInterfaces[0].Groups
+		launch.subnetId = aws.StringValue(spec.NetworkInterfaces[0].SubnetId)
+	}
+
+	instance, err := r.launch(launch)
+	if err != nil {
+		return err
+	}
+
+	request := &ec2.SpotInstanceRequest{
+		CreateTime:               aws.Time(time.Now()),
+		InstanceId:               instance.InstanceId,
+		LaunchedAvailabilityZone: instance.Placement.AvailabilityZone,
+		SpotInstanceRequestId:    aws.String(r.ec2.id("sir")),
+		SpotPrice:                in.SpotPrice,
+		State:                    aws.String(ec2.SpotInstanceStateActive),
+		Status: &ec2.SpotInstanceStatus{
+			Code:       aws.String("fulfilled"),
+			UpdateTime: aws.Time(time.Now()),
+		},
+		Type: aws.String(ec2.SpotInstanceTypeOneTime),
+	}
+	instance.InstanceLifecycle = aws.String(ec2.InstanceLifecycleTypeSpot)
+	instance.SpotInstanceRequestId = request.SpotInstanceRequestId
+	r.spotRequests[*request.SpotInstanceRequestId] = request
+
+	out.SpotInstanceRequests = []*ec2.SpotInstanceRequest{awsutil.CopyOf(request).(*ec2.SpotInstanceRequest)}
+	return nil
+}
+
+func (r *mockRegion) describeSpotInstanceRequests(in *ec2.DescribeSpotInstanceRequestsInput, out *ec2.DescribeSpotInstanceRequestsOutput) error {
+	var requests []*ec2.SpotInstanceRequest
+	if len(in.SpotInstanceRequestIds) > 0 {
+		for _, id := range in.SpotInstanceRequestIds {
+			request, ok := r.spotRequests[aws.StringValue(id)]
+			if !ok {
+				return notFound("InvalidSpotInstanceRequestID.NotFound", aws.StringValue(id))
+			}
+			requests = append(requests, request)
+		}
+	} else {
+		for _, request := range r.spotRequests {
+			requests = append(requests, request)
+		}
+	}
+
+	for _, request := range requests {
+		values := map[string]string{
+			"instance-id":              aws.StringValue(request.InstanceId),
+			"spot-instance-request-id": aws.StringValue(request.SpotInstanceRequestId),
+			"state":                    aws.StringValue(request.State),
+		}
+		if mockMatch(in.Filters, request.Tags, values) {
+			out.SpotInstanceRequests = append(out.SpotInstanceRequests,
+				awsutil.CopyOf(request).(*ec2.SpotInstanceRequest))
+		}
+	}
+	return nil
+}
+
+func (r *mockRegion) cancelSpotInstanceRequests(in *ec2.CancelSpotInstanceRequestsInput, out *ec2.CancelSpotInstanceRequestsOutput) error {
+	for _, id := range in.SpotInstanceRequestIds {
+		request, ok := r.spotRequests[aws.StringValue(id)]
+		if !ok {
+			return notFound("InvalidSpotInstanceRequestID.NotFound", aws.StringValue(id))
+		}
+		request.State = aws.String(ec2.SpotInstanceStateCancelled)
+		out.CancelledSpotInstanceRequests = append(out.CancelledSpotInstanceRequests,
+			&ec2.CancelledSpotInstanceRequest{
+				SpotInstanceRequestId: request.SpotInstanceRequestId,
+				State:                 aws.String(ec2.CancelSpotInstanceRequestStateCancelled),
+			})
+	}
+	return nil
+}
+
+func (r *mockRegion) createVolume(in *ec2.CreateVolumeInput, out *ec2.Volume) error {
+	size := in.Size
+	if in.SnapshotId != nil {
+		snapshot, ok := r.snapshots[*in.SnapshotId]
+		if ok && size == nil {
+			size = snapshot.VolumeSize
+		}
+		if !ok && size == nil {
+			// A snapshot of a made up image
+			size = aws.Int64(8)
+		}
+	}
+	if size == nil {
+		return awserr.New("MissingParameter", "The request must contain the parameter size or snapshotId", nil)
+	}
+
+	volume := &ec2.Volume{
+		AvailabilityZone: in.AvailabilityZone,
+		CreateTime:       aws.Time(time.Now()),
+		Encrypted:        aws.Bool(aws.BoolValue(in.Encrypted)),
+		Iops:             in.Iops,
+		KmsKeyId:         in.KmsKeyId,
+		Size:             size,
+		SnapshotId:       in.SnapshotId,
+		State:            aws.String(ec2.VolumeStateAvailable),
+		Tags:             specTags(in.TagSpecifications, ec2.ResourceTypeVolume),
+		VolumeId:         aws.String(r.ec2.id("vol")),
+		VolumeType:       in.VolumeType,
+	}
+	if volume.VolumeType == nil {
+		volume.VolumeType = aws.String(ec2.VolumeTypeStandard)
+	}
+	r.volumes[*volume.VolumeId] = volume
+
+	awsutil.Copy(out, volume)
+	return nil
+}
+
+func (r *mockRegion) attachVolume(in *ec2.AttachVolumeInput, out *ec2.VolumeAttachment) error {
+	volume, ok := r.volumes[aws.StringValue(in.VolumeId)]
+	if !ok {
+		return notFound("InvalidVolume.NotFound", aws.StringValue(in.VolumeId))
+	}
+	instance, ok := r.instances[aws.StringValue(in.InstanceId)]
+	if !ok {
+		return notFound("InvalidInstanceID.NotFound", aws.StringValue(in.InstanceId))
+	}
+	if *volume.State != ec2.VolumeStateAvailable {
+		return awserr.New("VolumeInUse",
+			fmt.Sprintf("%s is already attached to an instance", *volume.VolumeId), nil)
+	}
+
+	attachment := &ec2.VolumeAttachment{
+		AttachTime:          aws.Time(time.Now()),
+		DeleteOnTermination: aws.Bool(false),
+		Device:              in.Device,
+		InstanceId:          instance.InstanceId,
+		State:               aws.String(ec2.VolumeAttachmentStateAttached),
+		VolumeId:            volume.VolumeId,
+	}
+	volume.Attachments = []*ec2.VolumeAttachment{attachment}
+	volume.State = aws.String(ec2.VolumeStateInUse)
+	instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, &ec2.InstanceBlockDeviceMapping{
+		DeviceName: in.Device,
+		Ebs: &ec2.EbsInstanceBlockDevice{
+			AttachTime:          attachment.AttachTime,
+			DeleteOnTermination: aws.Bool(false),
+			Status:              aws.String(ec2.AttachmentStatusAttached),
+			VolumeId:            volume.VolumeId,
+		},
+	})
+
+	awsutil.Copy(out, attachment)
+	return nil
+}
+
+func (r *mockRegion) detachVolume(in *ec2.DetachVolumeInput, out *ec2.VolumeAttachment) error {
+	volume, ok := r.volumes[aws.StringValue(in.VolumeId)]
+	if !ok {
+		return notFound("InvalidVolume.NotFound", aws.StringValue(in.VolumeId))
+	}
+	if len(volume.Attachments) == 0 {
+		return awserr.New("IncorrectState", fmt.Sprintf("Volume '%s' is in the 'available' state", *volume.VolumeId), nil)
+	}
+
+	attachment := volume.Attachments[0]
+	if instance, ok := r.instances[aws.StringValue(attachment.InstanceId)]; ok {
+		var mappings []*ec2.InstanceBlockDeviceMapping
+		for _, mapping := range instance.BlockDeviceMappings {
+			if aws.StringValue(mapping.Ebs.VolumeId) != *volume.VolumeId {
+				mappings = append(mappings, mapping)
+			}
+		}
+		instance.BlockDeviceMappings = mappings
+	}
+	volume.Attachments = nil
+	volume.State = aws.String(ec2.VolumeStateAvailable)
+
+	awsutil.Copy(out, attachment)
+	out.State = aws.String(ec2.VolumeAttachmentStateDetached)
+	return nil
+}
+
+func (r *mockRegion) deleteVolume(in *ec2.DeleteVolumeInput) error {
+	volume, ok := r.volumes[aws.StringValue(in.VolumeId)]
+	if !ok {
+		return notFound("InvalidVolume.NotFound", aws.StringValue(in.VolumeId))
+	}
+	if *volume.State != ec2.VolumeStateAvailable {
+		return awserr.New("VolumeInUse",
+			fmt.Sprintf("Volume %s is currently attached to %s", *volume.VolumeId,
+				aws.StringValue(volume.Attachments[0].InstanceId)), nil)
+	}
+	delete(r.volumes, *volume.VolumeId)
+	return nil
+}
+
+func (r *mockRegion) describeVolumes(in *ec2.DescribeVolumesInput, out *ec2.DescribeVolumesOutput) error {
+	var volumes []*ec2.Volume
+	if len(in.VolumeIds) > 0 {
+		for _, id := range in.VolumeIds {
+			volume, ok := r.volumes[aws.StringValue(id)]
+			if !ok {
+				return notFound("InvalidVolume.NotFound", aws.StringValue(id))
+			}
+			volumes = append(volumes, volume)
+		}
+	} else {
+		for _, volume := range r.volumes {
+			volumes = append(volumes, volume)
+		}
+	}
+
+	for _, volume := range volumes {
+		values := map[string]string{
+			"availability-zone": aws.StringValue(volume.AvailabilityZone),
+			"snapshot-id":       aws.StringValue(volume.SnapshotId),
+			"status":            aws.StringValue(volume.State),
+			"volume-id":         aws.StringValue(volume.VolumeId),
+			"volume-type":       aws.StringValue(volume.VolumeType),
+		}
+		if len(volume.Attachments) > 0 {
+			values["attachment.instance-id"] = aws.StringValue(volume.Attachments[0].InstanceId)
+			values["attachment.device"] = aws.StringValue(volume.Attachments[0].Device)
+		}
+		if mockMatch(in.Filters, volume.Tags, values) {
+			out.Volumes = append(out.Volumes, awsutil.CopyOf(volume).(*ec2.Volume))
+		}
+	}
+	return nil
+}
+
+// snapshot creates a completed snapshot of the volume.
+func (r *mockRegion) snapshot(volume *ec2.Volume, description string) *ec2.Snapshot {
+	snapshot := &ec2.Snapshot{
+		Description: aws.String(description),
+		Encrypted:   volume.Encrypted,
+		KmsKeyId:    volume.KmsKeyId,
+		OwnerId:     aws.String(mockAccountId),
+		Progress:    aws.String("100%"),
+		SnapshotId:  aws.String(r.ec2.id("snap")),
+		StartTime:   aws.Time(time.Now()),
+		State:       aws.String(ec2.SnapshotStateCompleted),
+		VolumeId:    volume.VolumeId,
+		VolumeSize:  volume.Size,
+	}
+	r.snapshots[*snapshot.SnapshotId] = snapshot
+	return snapshot
+}
+
+func (r *mockRegion) createSnapshot(in *ec2.CreateSnapshotInput, out *ec2.Snapshot) error {
+	volume, ok := r.volumes[aws.StringValue(in.VolumeId)]
+	if !ok {
+		return notFound("InvalidVolume.NotFound", aws.StringValue(in.VolumeId))
+	}
+	awsutil.Copy(out, r.snapshot(volume, aws.StringValue(in.Description)))
+	return nil
+}
+
+func (r *mockRegion) describeSnapshots(in *ec2.DescribeSnapshotsInput, out *ec2.DescribeSnapshotsOutput) error {
+	var snapshots []*ec2.Snapshot
+	if len(in.SnapshotIds) > 0 {
+		for _, id := range in.SnapshotIds {
+			snapshot, ok := r.snapshots[aws.StringValue(id)]
+			if !ok {
+				return notFound("InvalidSnapshot.NotFound", aws.StringValue(id))
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+	} else {
+		for _, snapshot := range r.snapshots {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		owned := len(in.OwnerIds) == 0
+		for _, owner := range in.OwnerIds {
+			if aws.StringValue(owner) == "self" || aws.StringValue(owner) == mockAccountId {
+				owned = true
+			}
+		}
+		values := map[string]string{
+			"description": aws.StringValue(snapshot.Description),
+			"owner-id":    aws.StringValue(snapshot.OwnerId),
+			"snapshot-id": aws.StringValue(snapshot.SnapshotId),
+			"status":      aws.StringValue(snapshot.State),
+			"volume-id":   aws.StringValue(snapshot.VolumeId),
+		}
+		if owned && mockMatch(in.Filters, snapshot.Tags, values) {
+			out.Snapshots = append(out.Snapshots, awsutil.CopyOf(snapshot).(*ec2.Snapshot))
+		}
+	}
+	return nil
+}
+
+func (r *mockRegion) deleteSnapshot(in *ec2.DeleteSnapshotInput) error {
+	id := aws.StringValue(in.SnapshotId)
+	if _, ok := r.snapshots[id]; !ok {
+		return notFound("InvalidSnapshot.NotFound", id)
+	}
+	for _, image := range r.images {
+		for _, mapping := range image.BlockDeviceMappings {
+			if mapping.Ebs != nil && aws.StringValue(mapping.Ebs.SnapshotId) == id {
+				return awserr.New("InvalidSnapshot.InUse",
+					fmt.Sprintf("The snapshot %s is currently in use by %s", id, *image.ImageId), nil)
+			}
+		}
+	}
+	delete(r.snapshots, id)
+	return nil
+}
+
+func mockFingerprint(name string) string {
+	sum := md5.Sum([]byte(name))
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+func (r *mockRegion) addKeyPair(name string) (*ec2.KeyPairInfo, error) {
+	if _, ok := r.keyPairs[name]; ok {
+		return nil, awserr.New("InvalidKeyPair.Duplicate",
+			fmt.Sprintf("The keypair '%s' already exists.", name), nil)
+	}
+	key := &ec2.KeyPairInfo{KeyFingerprint: aws.String(mockFingerprint(name)), KeyName: aws.String(name)}
+	r.keyPairs[name] = key
+	return key, nil
+}
+
+func (r *mockRegion) createKeyPair(in *ec2.CreateKeyPairInput, out *ec2.CreateKeyPairOutput) error {
+	key, err := r.addKeyPair(aws.StringValue(in.KeyName))
+	if err != nil {
+		return err
+	}
+	privateKey, _, err := generateKeyPair(KeyPairTypeRSA, 2048)
+	if err != nil {
+		return err
+	}
+	out.KeyFingerprint = key.KeyFingerprint
+	out.KeyMaterial = aws.String(privateKey)
+	out.KeyName = key.KeyName
+	return nil
+}
+
+func (r *mockRegion) importKeyPair(in *ec2.ImportKeyPairInput, out *ec2.ImportKeyPairOutput) error {
+	key, err := r.addKeyPair(aws.StringValue(in.KeyName))
+	if err != nil {
+		return err
+	}
+	out.KeyFingerprint = key.KeyFingerprint
+	out.KeyName = key.KeyName
+	return nil
+}
+
+func (r *mockRegion) describeKeyPairs(in *ec2.DescribeKeyPairsInput, out *ec2.DescribeKeyPairsOutput) error {
+	var keys []*ec2.KeyPairInfo
+	if len(in.KeyNames) > 0 {
+		for _, name := range in.KeyNames {
+			key, ok := r.keyPairs[aws.StringValue(name)]
+			if !ok {
+				return awserr.New("InvalidKeyPair.NotFound",
+					fmt.Sprintf("The key pair '%s' does not exist", aws.StringValue(name)), nil)
+			}
+			keys = append(keys, key)
+		}
+	} else {
+		for _, key := range r.keyPairs {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		values := map[string]string{
+			"fingerprint": aws.StringValue(key.KeyFingerprint),
+			"key-name":    aws.StringValue(key.KeyName),
+		}
+		if mockMatch(in.Filters, nil, values) {
+			out.KeyPairs = append(out.KeyPairs, awsutil.CopyOf(key).(*ec2.KeyPairInfo))
+		}
+	}
+	return nil
+}
+
+func (r *mockRegion) createSecurityGroup(in *ec2.CreateSecurityGroupInput, out *ec2.CreateSecurityGroupOutput) error {
+	vpcId := aws.StringValue(in.VpcId)
+	if vpcId == "" {
+		vpcId = mockVpcId
+	}
+	for _, group := range r.groups {
+		if *group.GroupName == aws.StringValue(in.GroupName) && *group.VpcId == vpcId {
+			return awserr.New("InvalidGroup.Duplicate",
+				fmt.Sprintf("The security group '%s' already exists for VPC '%s'", *group.GroupName, vpcId), nil)
+		}
+	}
+
+	group := &ec2.SecurityGroup{
+		Description: in.Description,
+		GroupId:     aws.String(r.ec2.id("sg")),
+		GroupName:   in.GroupName,
+		OwnerId:     aws.String(mockAccountId),
+		VpcId:       aws.String(vpcId),
+	}
+	r.groups[*group.GroupId] = group
+	out.GroupId = group.GroupId
+	return nil
+}
+
+func (r *mockRegion) authorizeSecurityGroupIngress(in *ec2.AuthorizeSecurityGroupIngressInput) error {
+	group, ok := r.groups[aws.StringValue(in.GroupId)]
+	if !ok {
+		return notFound("InvalidGroup.NotFound", aws.StringValue(in.GroupId))
+	}
+	group.IpPermissions = append(group.IpPermissions, in.IpPermissions...)
+	return nil
+}
+
+func (r *mockRegion) deleteSecurityGroup(in *ec2.DeleteSecurityGroupInput) error {
+	group, ok := r.groups[aws.StringValue(in.GroupId)]
+	if !ok {
+		return notFound("InvalidGroup.NotFound", aws.StringValue(in.GroupId))
+	}
+	for _, instance := range r.instances {
+		if *instance.State.Name == ec2.InstanceStateNameTerminated {
+			continue
+		}
+		for _, g := range instance.SecurityGroups {
+			if aws.StringValue(g.GroupId) == *group.GroupId {
+				return awserr.New("DependencyViolation",
+					fmt.Sprintf("resource %s has a dependent object", *group.GroupId), nil)
+			}
+		}
+	}
+	delete(r.groups, *group.GroupId)
+	return nil
+}
+
+func (r *mockRegion) describeSecurityGroups(in *ec2.DescribeSecurityGroupsInput, out *ec2.DescribeSecurityGroupsOutput) error {
+	var groups []*ec2.SecurityGroup
+	if len(in.GroupIds) > 0 {
+		for _, id := range in.GroupIds {
+			group, ok := r.groups[aws.StringValue(id)]
+			if !ok {
+				return notFound("InvalidGroup.NotFound", aws.StringValue(id))
+			}
+			groups = append(groups, group)
+		}
+	} else {
+		for _, group := range r.groups {
+			groups = append(groups, group)
+		}
+	}
+
+	for _, group := range groups {
+		values := map[string]string{
+			"description": aws.StringValue(group.Description),
+			"group-id":    aws.StringValue(group.GroupId),
+			"group-name":  aws.StringValue(group.GroupName),
+			"vpc-id":      aws.StringValue(group.VpcId),
+		}
+		if mockMatch(in.Filters, group.Tags, values) {
+			out.SecurityGroups = append(out.SecurityGroups, awsutil.CopyOf(group).(*ec2.SecurityGroup))
+		}
+	}
+	return nil
+}
+
+// tags returns the tags of the resource with the id, and the type of the
+// resource, or nil if there is no such resource.
+func (r *mockRegion) tags(id string) (*[]*ec2.Tag, string, error) {
+	switch {
+	case strings.HasPrefix(id, "ami-"):
+		if image, ok := r.images[id]; ok {
+			return &image.Tags, ec2.ResourceTypeImage, nil
+		}
+		return nil, "", notFound("InvalidAMIID.NotFound", id)
+	case strings.HasPrefix(id, "i-"):
+		if instance, ok := r.instances[id]; ok {
+			return &instance.Tags, ec2.ResourceTypeInstance, nil
+		}
+		return nil, "", notFound("InvalidInstanceID.NotFound", id)
+	case strings.HasPrefix(id, "sg-"):
+		if group, ok := r.groups[id]; ok {
+			return &group.Tags, ec2.ResourceTypeSecurityGroup, nil
+		}
+		return nil, "", notFound("InvalidGroup.NotFound", id)
+	case strings.HasPrefix(id, "sir-"):
+		if request, ok := r.spotRequests[id]; ok {
+			return &request.Tags, ec2.ResourceTypeSpotInstancesRequest, nil
+		}
+		return nil, "", notFound("InvalidSpotInstanceRequestID.NotFound", id)
+	case strings.HasPrefix(id, "snap-"):
+		if snapshot, ok := r.snapshots[id]; ok {
+			return &snapshot.Tags, ec2.ResourceTypeSnapshot, nil
+		}
+		return nil, "", notFound("InvalidSnapshot.NotFound", id)
+	case strings.HasPrefix(id, "vol-"):
+		if volume, ok := r.volumes[id]; ok {
+			return &volume.Tags, ec2.ResourceTypeVolume, nil
+		}
+		return nil, "", notFound("InvalidVolume.NotFound", id)
+	}
+	return nil, "", awserr.New("InvalidID", fmt.Sprintf("The ID '%s' is not valid", id), nil)
+}
+
+func (r *mockRegion) createTags(in *ec2.CreateTagsInput) error {
+	for _, id := range in.Resources {
+		tags, _, err := r.tags(aws.StringValue(id))
+		if err != nil {
+			return err
+		}
+		for _, tag := range in.Tags {
+			*tags = mergeTags(*tags, []*ec2.Tag{{Key: tag.Key, Value: tag.Value}})
+		}
+	}
+	return nil
+}
+
+func (r *mockRegion) describeTags(in *ec2.DescribeTagsInput, out *ec2.DescribeTagsOutput) {
+	var ids []string
+	for id := range r.images {
+		ids = append(ids, id)
+	}
+	for id := range r.instances {
+		ids = append(ids, id)
+	}
+	for id := range r.groups {
+		ids = append(ids, id)
+	}
+	for id := range r.spotRequests {
+		ids = append(ids, id)
+	}
+	for id := range r.snapshots {
+		ids = append(ids, id)
+	}
+	for id := range r.volumes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		tags, resourceType, _ := r.tags(id)
+		for _, tag := range *tags {
+			values := map[string]string{
+				"key":           aws.StringValue(tag.Key),
+				"resource-id":   id,
+				"resource-type": resourceType,
+				"value":         aws.StringValue(tag.Value),
+			}
+			if mockMatch(in.Filters, nil, values) {
+				out.Tags = append(out.Tags, &ec2.TagDescription{
+					Key:          tag.Key,
+					ResourceId:   aws.String(id),
+					ResourceType: aws.String(resourceType),
+					Value:        tag.Value,
+				})
+			}
+		}
+	}
+}
+
+// describeSubnets makes up the subnets asked for, in the first zone of the
+// region.
+func (r *mockRegion) describeSubnets(in *ec2.DescribeSubnetsInput, out *ec2.DescribeSubnetsOutput) {
+	ids := in.SubnetIds
+	if len(ids) == 0 {
+		ids = []*string{aws.String("subnet-0mock")}
+	}
+	for _, id := range ids {
+		out.Subnets = append(out.Subnets, &ec2.Subnet{
+			AvailabilityZone:        aws.String(r.defaultAvailabilityZone()),
+			AvailableIpAddressCount: aws.Int64(250),
+			CidrBlock:               aws.String("10.0.0.0/24"),
+			State:                   aws.String(ec2.SubnetStateAvailable),
+			SubnetId:                id,
+			VpcId:                   aws.String(mockVpcId),
+		})
+	}
+}
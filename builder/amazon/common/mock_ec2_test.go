@@ -0,0 +1,185 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/packer"
+)
+
+func testMockEC2(t *testing.T, errors map[string]string) (*AccessConfig, *ec2.EC2) {
+	c := &AccessConfig{MockEC2: true, MockEC2Errors: errors, RawRegion: "us-west-2"}
+	sess, err := c.Session()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return c, ec2.New(sess)
+}
+
+func TestAccessConfigPrepare_MockEC2Errors(t *testing.T) {
+	c := testAccessConfig()
+	c.MockEC2Errors = map[string]string{"RunInstances": "InsufficientInstanceCapacity"}
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.MockEC2 = true
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestMockEC2_RunInstanceCreateImage(t *testing.T) {
+	_, ec2conn := testMockEC2(t, nil)
+
+	run, err := ec2conn.RunInstances(&ec2.RunInstancesInput{
+		ImageId:      aws.String("ami-12345678"),
+		InstanceType: aws.String("m3.medium"),
+		MaxCount:     aws.Int64(1),
+		MinCount:     aws.Int64(1),
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String("instance"),
+			Tags:         []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("Packer Builder")}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	instanceId := run.Instances[0].InstanceId
+	if aws.StringValue(run.Instances[0].PublicIpAddress) == "" {
+		t.Fatal("instance should have a public IP")
+	}
+	if len(run.Instances[0].BlockDeviceMappings) != 1 {
+		t.Fatalf("bad: %#v", run.Instances[0].BlockDeviceMappings)
+	}
+
+	err = ec2conn.WaitUntilInstanceRunning(&ec2.DescribeInstancesInput{InstanceIds: []*string{instanceId}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	image, err := ec2conn.CreateImage(&ec2.CreateImageInput{InstanceId: instanceId, Name: aws.String("test")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, err = ec2conn.CreateImage(&ec2.CreateImageInput{InstanceId: instanceId, Name: aws.String("test")})
+	if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "InvalidAMIName.Duplicate" {
+		t.Fatalf("should be a duplicate name error: %s", err)
+	}
+
+	images, err := ec2conn.DescribeImages(&ec2.DescribeImagesInput{
+		Owners:  []*string{aws.String("self")},
+		Filters: []*ec2.Filter{{Name: aws.String("name"), Values: []*string{aws.String("te*")}}},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(images.Images) != 1 || *images.Images[0].ImageId != *image.ImageId {
+		t.Fatalf("bad: %#v", images.Images)
+	}
+	snapshotId := images.Images[0].BlockDeviceMappings[0].Ebs.SnapshotId
+
+	_, err = ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: snapshotId})
+	if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "InvalidSnapshot.InUse" {
+		t.Fatalf("should be an in use error: %s", err)
+	}
+	if _, err := ec2conn.DeregisterImage(&ec2.DeregisterImageInput{ImageId: image.ImageId}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: snapshotId}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = ec2conn.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: []*string{instanceId}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	volumes, err := ec2conn.DescribeVolumes(&ec2.DescribeVolumesInput{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(volumes.Volumes) != 0 {
+		t.Fatalf("the root volume should be deleted with the instance: %#v", volumes.Volumes)
+	}
+}
+
+func TestMockEC2_DependencyViolation(t *testing.T) {
+	_, ec2conn := testMockEC2(t, nil)
+
+	group, err := ec2conn.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{
+		GroupName:   aws.String("test"),
+		Description: aws.String("test"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	run, err := ec2conn.RunInstances(&ec2.RunInstancesInput{
+		ImageId:          aws.String("ami-12345678"),
+		InstanceType:     aws.String("m3.medium"),
+		MaxCount:         aws.Int64(1),
+		MinCount:         aws.Int64(1),
+		SecurityGroupIds: []*string{group.GroupId},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = ec2conn.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{GroupId: group.GroupId})
+	if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "DependencyViolation" {
+		t.Fatalf("should be a dependency violation: %s", err)
+	}
+
+	_, err = ec2conn.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: []*string{run.Instances[0].InstanceId}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ec2conn.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{GroupId: group.GroupId}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestMockEC2_Errors(t *testing.T) {
+	_, ec2conn := testMockEC2(t, map[string]string{"RunInstances": "InsufficientInstanceCapacity"})
+
+	_, err := ec2conn.RunInstances(&ec2.RunInstancesInput{
+		ImageId:      aws.String("ami-12345678"),
+		InstanceType: aws.String("m3.medium"),
+		MaxCount:     aws.Int64(1),
+		MinCount:     aws.Int64(1),
+	})
+	if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "InsufficientInstanceCapacity" {
+		t.Fatalf("should be the injected error: %s", err)
+	}
+	if _, err := ec2conn.DescribeInstances(&ec2.DescribeInstancesInput{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestAccessConfig_ReportMockEC2(t *testing.T) {
+	c, ec2conn := testMockEC2(t, nil)
+
+	name := "packer_" + uuid.TimeOrderedUUID()
+	if _, err := ec2conn.CreateKeyPair(&ec2.CreateKeyPairInput{KeyName: &name}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var out bytes.Buffer
+	c.ReportMockEC2(&packer.BasicUi{Writer: &out, ErrorWriter: &out})
+	if !strings.Contains(out.String(), name) {
+		t.Fatalf("the key pair should be reported: %s", out.String())
+	}
+
+	if _, err := ec2conn.DeleteKeyPair(&ec2.DeleteKeyPairInput{KeyName: &name}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	out.Reset()
+	c.ReportMockEC2(&packer.BasicUi{Writer: &out, ErrorWriter: &out})
+	if out.Len() != 0 {
+		t.Fatalf("nothing should be reported: %s", out.String())
+	}
+}
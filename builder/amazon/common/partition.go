@@ -0,0 +1,26 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// RegionPartition returns the partition of a region: aws-cn for the China
+// regions, aws-us-gov for GovCloud and aws for the others, regions of EC2
+// compatible APIs included.
+func RegionPartition(region string) string {
+	if p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return p.ID()
+	}
+	return endpoints.AwsPartitionID
+}
+
+// servicePrincipal returns the principal of an AWS service in the trust
+// policies of the region, which differs in the China regions.
+func servicePrincipal(region, service string) string {
+	if RegionPartition(region) == endpoints.AwsCnPartitionID {
+		return fmt.Sprintf("%s.amazonaws.com.cn", service)
+	}
+	return fmt.Sprintf("%s.amazonaws.com", service)
+}
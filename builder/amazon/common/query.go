@@ -0,0 +1,32 @@
+package common
+
+import (
+	"io/ioutil"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// queryParamHandler returns a request handler setting a parameter, which
+// the vendored SDK doesn't know about yet, in an already built EC2 query.
+func queryParamHandler(name, value string) func(*request.Request) {
+	return func(r *request.Request) {
+		if r.Error != nil {
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.GetBody())
+		if err != nil {
+			r.Error = err
+			return
+		}
+		params, err := url.ParseQuery(string(body))
+		if err != nil {
+			r.Error = err
+			return
+		}
+
+		params.Set(name, value)
+		r.SetBufferBody([]byte(params.Encode()))
+	}
+}
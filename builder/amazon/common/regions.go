@@ -20,6 +20,7 @@ func listEC2Regions() []string {
 		"us-west-1",
 		"us-west-2",
 		// not part of autogenerated list
+		"us-gov-east-1",
 		"us-gov-west-1",
 		"cn-north-1",
 		"cn-northwest-1",
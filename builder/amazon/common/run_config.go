@@ -13,43 +13,124 @@ import (
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
+const (
+	CPUCreditsStandard  = "standard"
+	CPUCreditsUnlimited = "unlimited"
+
+	TenancyDefault   = "default"
+	TenancyDedicated = "dedicated"
+	TenancyHost      = "host"
+)
+
 var reShutdownBehavior = regexp.MustCompile("^(stop|terminate)$")
 
+// burstableInstanceFamilies are the instance families with a credit option
+// for CPU usage.
+var burstableInstanceFamilies = []string{"t2", "t3", "t3a", "t4g"}
+
 type AmiFilterOptions struct {
-	Filters    map[*string]*string
-	Owners     []*string
-	MostRecent bool `mapstructure:"most_recent"`
+	Filters            map[*string]*string
+	Owners             []*string
+	MostRecent         bool   `mapstructure:"most_recent"`
+	Architecture       string `mapstructure:"architecture"`
+	VirtualizationType string `mapstructure:"virtualization_type"`
 }
 
+var (
+	amiArchitectures       = []string{"i386", "x86_64", "arm64", "x86_64_mac", "arm64_mac"}
+	amiVirtualizationTypes = []string{"hvm", "paravirtual"}
+)
+
 func (d *AmiFilterOptions) Empty() bool {
 	return len(d.Owners) == 0 && len(d.Filters) == 0
 }
 
+// Prepare validates the architecture and virtualization_type shortcuts,
+// which can't be set as filters too.
+func (d *AmiFilterOptions) Prepare() []error {
+	var errs []error
+
+	if d.Architecture != "" && !stringInSlice(amiArchitectures, d.Architecture) {
+		errs = append(errs, fmt.Errorf(
+			"source_ami_filter: architecture must be one of %s",
+			strings.Join(amiArchitectures, ", ")))
+	}
+	if d.VirtualizationType != "" && !stringInSlice(amiVirtualizationTypes, d.VirtualizationType) {
+		errs = append(errs, fmt.Errorf(
+			"source_ami_filter: virtualization_type must be one of %s",
+			strings.Join(amiVirtualizationTypes, ", ")))
+	}
+	for name := range d.Filters {
+		if (*name == "architecture" && d.Architecture != "") ||
+			(*name == "virtualization-type" && d.VirtualizationType != "") {
+			errs = append(errs, fmt.Errorf(
+				"source_ami_filter: the %s filter can't be set with %s",
+				*name, strings.Replace(*name, "-", "_", -1)))
+		}
+	}
+
+	return errs
+}
+
+// PolicyDocument is an IAM policy, as written in the template.
+type PolicyDocument struct {
+	Version   string      `mapstructure:"Version" json:"Version"`
+	Statement []Statement `mapstructure:"Statement" json:"Statement"`
+}
+
+type Statement struct {
+	Effect   string   `mapstructure:"Effect" json:"Effect"`
+	Action   []string `mapstructure:"Action" json:"Action"`
+	Resource []string `mapstructure:"Resource" json:"Resource"`
+}
+
 // RunConfig contains configuration for running an instance from a source
 // AMI and details on how to access that launched image.
 type RunConfig struct {
-	AssociatePublicIpAddress          bool              `mapstructure:"associate_public_ip_address"`
-	AvailabilityZone                  string            `mapstructure:"availability_zone"`
-	DisableStopInstance               bool              `mapstructure:"disable_stop_instance"`
-	EbsOptimized                      bool              `mapstructure:"ebs_optimized"`
-	EnableT2Unlimited                 bool              `mapstructure:"enable_t2_unlimited"`
-	IamInstanceProfile                string            `mapstructure:"iam_instance_profile"`
-	InstanceInitiatedShutdownBehavior string            `mapstructure:"shutdown_behavior"`
-	InstanceType                      string            `mapstructure:"instance_type"`
-	RunTags                           map[string]string `mapstructure:"run_tags"`
-	SecurityGroupId                   string            `mapstructure:"security_group_id"`
-	SecurityGroupIds                  []string          `mapstructure:"security_group_ids"`
-	SourceAmi                         string            `mapstructure:"source_ami"`
-	SourceAmiFilter                   AmiFilterOptions  `mapstructure:"source_ami_filter"`
-	SpotPrice                         string            `mapstructure:"spot_price"`
-	SpotPriceAutoProduct              string            `mapstructure:"spot_price_auto_product"`
-	SubnetId                          string            `mapstructure:"subnet_id"`
-	TemporaryKeyPairName              string            `mapstructure:"temporary_key_pair_name"`
-	TemporarySGSourceCidr             string            `mapstructure:"temporary_security_group_source_cidr"`
-	UserData                          string            `mapstructure:"user_data"`
-	UserDataFile                      string            `mapstructure:"user_data_file"`
-	VpcId                             string            `mapstructure:"vpc_id"`
-	WindowsPasswordTimeout            time.Duration     `mapstructure:"windows_password_timeout"`
+	AllocateHost                              bool              `mapstructure:"allocate_host"`
+	AssociatePublicIpAddress                  bool              `mapstructure:"associate_public_ip_address"`
+	AvailabilityZone                          string            `mapstructure:"availability_zone"`
+	CloudWatchLogs                            bool              `mapstructure:"cloudwatch_logs"`
+	CloudWatchLogsGroup                       string            `mapstructure:"cloudwatch_logs_group"`
+	CloudWatchLogsRetentionDays               int               `mapstructure:"cloudwatch_logs_retention_days"`
+	CPUCredits                                string            `mapstructure:"cpu_credits"`
+	DisableStopInstance                       bool              `mapstructure:"disable_stop_instance"`
+	EbsOptimized                              bool              `mapstructure:"ebs_optimized"`
+	EnableHibernation                         bool              `mapstructure:"enable_hibernation"`
+	EnableT2Unlimited                         bool              `mapstructure:"enable_t2_unlimited"`
+	HostId                                    string            `mapstructure:"host_id"`
+	HostResourceGroupArn                      string            `mapstructure:"host_resource_group_arn"`
+	IamInstanceProfile                        string            `mapstructure:"iam_instance_profile"`
+	InstanceInitiatedShutdownBehavior         string            `mapstructure:"shutdown_behavior"`
+	InstanceType                              string            `mapstructure:"instance_type"`
+	Ipv6AddressCount                          int               `mapstructure:"ipv6_address_count"`
+	KeepHost                                  bool              `mapstructure:"keep_host"`
+	KeepSourceInstance                        bool              `mapstructure:"keep_source_instance"`
+	NetworkInterfaceId                        string            `mapstructure:"network_interface_id"`
+	RunTags                                   map[string]string `mapstructure:"run_tags"`
+	SecurityGroupId                           string            `mapstructure:"security_group_id"`
+	SecurityGroupIds                          []string          `mapstructure:"security_group_ids"`
+	SourceAmi                                 string            `mapstructure:"source_ami"`
+	SourceAmiFilter                           AmiFilterOptions  `mapstructure:"source_ami_filter"`
+	SourceInstanceId                          string            `mapstructure:"source_instance_id"`
+	SpotPrice                                 string            `mapstructure:"spot_price"`
+	SpotPriceAutoProduct                      string            `mapstructure:"spot_price_auto_product"`
+	SubnetId                                  string            `mapstructure:"subnet_id"`
+	Tenancy                                   string            `mapstructure:"tenancy"`
+	TemporaryAddressFamily                    string            `mapstructure:"temporary_address_family"`
+	TemporaryIamInstanceProfilePolicyDocument *PolicyDocument   `mapstructure:"temporary_iam_instance_profile_policy_document"`
+	TemporaryKeyPairBits                      int               `mapstructure:"temporary_key_pair_bits"`
+	TemporaryKeyPairName                      string            `mapstructure:"temporary_key_pair_name"`
+	TemporaryKeyPairType                      string            `mapstructure:"temporary_key_pair_type"`
+	TemporarySGSourceCidr                     string            `mapstructure:"temporary_security_group_source_cidr"`
+	TemporarySGSourceCidrs                    []string          `mapstructure:"temporary_security_group_source_cidrs"`
+	TemporarySGSourceGroupId                  string            `mapstructure:"temporary_security_group_source_group_id"`
+	TemporarySGSourcePublicIp                 bool              `mapstructure:"temporary_security_group_source_public_ip"`
+	UserData                                  string            `mapstructure:"user_data"`
+	UserDataFile                              string            `mapstructure:"user_data_file"`
+	VpcId                                     string            `mapstructure:"vpc_id"`
+	WindowsPasswordPrivateKeyFile             string            `mapstructure:"windows_password_private_key_file"`
+	WindowsPasswordTimeout                    time.Duration     `mapstructure:"windows_password_timeout"`
 
 	// Communicator settings
 	Comm           communicator.Config `mapstructure:",squash"`
@@ -88,15 +169,80 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		errs = append(errs, fmt.Errorf("Unknown interface type: %s", c.SSHInterface))
 	}
 
+	if c.TemporaryAddressFamily != "" &&
+		c.TemporaryAddressFamily != AddressFamilyIPv4 &&
+		c.TemporaryAddressFamily != AddressFamilyIPv6 {
+		errs = append(errs, fmt.Errorf(
+			"temporary_address_family must be either ipv4 or ipv6."))
+	}
+	if c.TemporaryAddressFamily != "" &&
+		(c.SSHInterface == "public_dns" || c.SSHInterface == "private_dns") {
+		errs = append(errs, fmt.Errorf(
+			"temporary_address_family can't be used with ssh_interface %s.", c.SSHInterface))
+	}
+
+	if c.Ipv6AddressCount < 0 {
+		errs = append(errs, fmt.Errorf("ipv6_address_count must be positive."))
+	} else if c.Ipv6AddressCount > 0 && c.SubnetId == "" {
+		errs = append(errs, fmt.Errorf(
+			"subnet_id must be specified to assign IPv6 addresses to the instance."))
+	}
+
 	if c.SSHKeyPairName != "" {
-		if c.Comm.Type == "winrm" && c.Comm.WinRMPassword == "" && c.Comm.SSHPrivateKey == "" {
-			errs = append(errs, fmt.Errorf("ssh_private_key_file must be provided to retrieve the winrm password when using ssh_keypair_name."))
+		if c.Comm.Type == "winrm" {
+			if c.Comm.WinRMPassword == "" && c.Comm.SSHPrivateKey == "" && c.WindowsPasswordPrivateKeyFile == "" {
+				errs = append(errs, fmt.Errorf("ssh_private_key_file or windows_password_private_key_file must be provided to retrieve the winrm password when using ssh_keypair_name."))
+			}
 		} else if c.Comm.SSHPrivateKey == "" && !c.Comm.SSHAgentAuth {
 			errs = append(errs, fmt.Errorf("ssh_private_key_file must be provided or ssh_agent_auth enabled when ssh_keypair_name is specified."))
 		}
 	}
 
-	if c.SourceAmi == "" && c.SourceAmiFilter.Empty() {
+	// The password is encrypted for the key pair the instance is launched
+	// with, which has to be a known one for its private key to be given.
+	if c.WindowsPasswordPrivateKeyFile != "" {
+		if c.SSHKeyPairName == "" {
+			errs = append(errs, fmt.Errorf(
+				"ssh_keypair_name must be specified with windows_password_private_key_file."))
+		}
+		if _, err := os.Stat(c.WindowsPasswordPrivateKeyFile); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"windows_password_private_key_file is invalid: %s", err))
+		}
+	}
+
+	switch c.TemporaryKeyPairType {
+	case "":
+		c.TemporaryKeyPairType = KeyPairTypeRSA
+	case KeyPairTypeRSA:
+	case KeyPairTypeED25519:
+		if c.Comm.Type == "winrm" {
+			errs = append(errs, fmt.Errorf(
+				"temporary_key_pair_type must be rsa to retrieve the winrm password."))
+		}
+	default:
+		errs = append(errs, fmt.Errorf(
+			"temporary_key_pair_type must be either rsa or ed25519."))
+	}
+
+	if c.TemporaryKeyPairBits != 0 {
+		if c.TemporaryKeyPairType != KeyPairTypeRSA {
+			errs = append(errs, fmt.Errorf(
+				"temporary_key_pair_bits can only be set for rsa key pairs."))
+		} else if c.TemporaryKeyPairBits < 2048 {
+			errs = append(errs, fmt.Errorf(
+				"temporary_key_pair_bits must be at least 2048."))
+		}
+	}
+
+	errs = append(errs, c.SourceAmiFilter.Prepare()...)
+	// The source AMI of a reused instance is the one it was launched from
+	if c.SourceInstanceId != "" {
+		if c.SourceAmi != "" || !c.SourceAmiFilter.Empty() {
+			errs = append(errs, fmt.Errorf(
+				"source_ami and source_ami_filter can't be used with source_instance_id"))
+		}
+	} else if c.SourceAmi == "" && c.SourceAmiFilter.Empty() {
 		errs = append(errs, fmt.Errorf("A source_ami or source_ami_filter must be specified"))
 	}
 
@@ -135,11 +281,46 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
-	if c.TemporarySGSourceCidr == "" {
-		c.TemporarySGSourceCidr = "0.0.0.0/0"
-	} else {
-		if _, _, err := net.ParseCIDR(c.TemporarySGSourceCidr); err != nil {
-			errs = append(errs, fmt.Errorf("Error parsing temporary_security_group_source_cidr: %s", err.Error()))
+	if c.TemporarySGSourceCidr != "" {
+		if len(c.TemporarySGSourceCidrs) > 0 {
+			errs = append(errs, fmt.Errorf("Only one of temporary_security_group_source_cidr or "+
+				"temporary_security_group_source_cidrs can be specified."))
+		} else {
+			c.TemporarySGSourceCidrs = []string{c.TemporarySGSourceCidr}
+			c.TemporarySGSourceCidr = ""
+		}
+	}
+
+	if c.TemporarySGSourcePublicIp && len(c.TemporarySGSourceCidrs) > 0 {
+		errs = append(errs, fmt.Errorf("Only one of temporary_security_group_source_public_ip or "+
+			"temporary_security_group_source_cidrs can be specified."))
+	}
+
+	// The instance gets its network configuration from the network
+	// interface, security groups included.
+	if c.NetworkInterfaceId != "" {
+		if c.SubnetId != "" || c.AssociatePublicIpAddress || c.Ipv6AddressCount > 0 {
+			errs = append(errs, fmt.Errorf("subnet_id, associate_public_ip_address and "+
+				"ipv6_address_count can't be specified with network_interface_id."))
+		}
+		if len(c.SecurityGroupIds) > 0 || len(c.TemporarySGSourceCidrs) > 0 ||
+			c.TemporarySGSourceGroupId != "" || c.TemporarySGSourcePublicIp {
+			errs = append(errs, fmt.Errorf("Security groups can't be specified with "+
+				"network_interface_id, the instance uses the groups of the network interface."))
+		}
+	}
+
+	// The temporary security group is open to everyone unless told otherwise
+	if c.NetworkInterfaceId == "" && len(c.TemporarySGSourceCidrs) == 0 && !c.TemporarySGSourcePublicIp && c.TemporarySGSourceGroupId == "" {
+		c.TemporarySGSourceCidrs = []string{"0.0.0.0/0"}
+		if c.Ipv6AddressCount > 0 || c.TemporaryAddressFamily == AddressFamilyIPv6 {
+			c.TemporarySGSourceCidrs = append(c.TemporarySGSourceCidrs, "::/0")
+		}
+	}
+
+	for _, cidr := range c.TemporarySGSourceCidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("Error parsing temporary_security_group_source_cidrs: %s", err.Error()))
 		}
 	}
 
@@ -149,6 +330,120 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		errs = append(errs, fmt.Errorf("shutdown_behavior only accepts 'stop' or 'terminate' values."))
 	}
 
+	if doc := c.TemporaryIamInstanceProfilePolicyDocument; doc != nil {
+		if c.IamInstanceProfile != "" {
+			errs = append(errs, fmt.Errorf("Only one of iam_instance_profile or "+
+				"temporary_iam_instance_profile_policy_document can be specified."))
+		}
+		if doc.Version == "" {
+			doc.Version = "2012-10-17"
+		}
+		if len(doc.Statement) == 0 {
+			errs = append(errs, fmt.Errorf(
+				"temporary_iam_instance_profile_policy_document must have at least one Statement"))
+		}
+		for i, statement := range doc.Statement {
+			if statement.Effect != "Allow" && statement.Effect != "Deny" {
+				errs = append(errs, fmt.Errorf(
+					"Statement %d of temporary_iam_instance_profile_policy_document: "+
+						"Effect must be 'Allow' or 'Deny'", i+1))
+			}
+			if len(statement.Action) == 0 || len(statement.Resource) == 0 {
+				errs = append(errs, fmt.Errorf(
+					"Statement %d of temporary_iam_instance_profile_policy_document: "+
+						"Action and Resource must be set", i+1))
+			}
+		}
+	}
+
+	// A kept instance outlives the build, so it can't depend on the
+	// temporary resources deleted once the build completes.
+	if c.KeepSourceInstance || c.SourceInstanceId != "" {
+		if c.IsSpotInstance() {
+			errs = append(errs, fmt.Errorf(
+				"keep_source_instance and source_instance_id can't be used with spot instances."))
+		}
+		if c.TemporaryKeyPairName != "" {
+			errs = append(errs, fmt.Errorf("ssh_keypair_name, ssh_private_key_file or "+
+				"ssh_password must be specified with keep_source_instance and source_instance_id."))
+		}
+		if len(c.SecurityGroupIds) == 0 && c.NetworkInterfaceId == "" {
+			errs = append(errs, fmt.Errorf("security_group_ids or network_interface_id must "+
+				"be specified with keep_source_instance and source_instance_id."))
+		}
+		if c.TemporaryIamInstanceProfilePolicyDocument != nil {
+			errs = append(errs, fmt.Errorf("temporary_iam_instance_profile_policy_document "+
+				"can't be used with keep_source_instance and source_instance_id."))
+		}
+	}
+
+	// Instances placed on a host run on a dedicated host
+	if c.AllocateHost {
+		if c.HostId != "" || c.HostResourceGroupArn != "" {
+			errs = append(errs, fmt.Errorf(
+				"allocate_host can't be used with host_id and host_resource_group_arn."))
+		}
+		if c.KeepSourceInstance || c.SourceInstanceId != "" {
+			errs = append(errs, fmt.Errorf(
+				"allocate_host can't be used with keep_source_instance and source_instance_id."))
+		}
+		if c.AvailabilityZone == "" && c.SubnetId == "" {
+			errs = append(errs, fmt.Errorf(
+				"availability_zone or subnet_id must be specified with allocate_host."))
+		}
+		if c.Tenancy == "" {
+			c.Tenancy = TenancyHost
+		} else if c.Tenancy != TenancyHost {
+			errs = append(errs, fmt.Errorf("tenancy must be host with allocate_host."))
+		}
+	} else if c.KeepHost {
+		errs = append(errs, fmt.Errorf("keep_host can only be used with allocate_host."))
+	}
+	if c.HostId != "" || c.HostResourceGroupArn != "" {
+		if c.HostId != "" && c.HostResourceGroupArn != "" {
+			errs = append(errs, fmt.Errorf(
+				"Only one of host_id or host_resource_group_arn can be specified."))
+		}
+		if c.Tenancy == "" {
+			c.Tenancy = TenancyHost
+		} else if c.Tenancy != TenancyHost {
+			errs = append(errs, fmt.Errorf(
+				"tenancy must be host with host_id and host_resource_group_arn."))
+		}
+	}
+	switch c.Tenancy {
+	case "", TenancyDefault, TenancyDedicated:
+	case TenancyHost:
+		if c.IsSpotInstance() {
+			errs = append(errs, fmt.Errorf("tenancy host can't be used with spot instances."))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tenancy must be one of default, dedicated or host."))
+	}
+
+	if c.CloudWatchLogsGroup != "" || c.CloudWatchLogsRetentionDays != 0 {
+		if !c.CloudWatchLogs {
+			errs = append(errs, fmt.Errorf("cloudwatch_logs_group and "+
+				"cloudwatch_logs_retention_days can only be used with cloudwatch_logs."))
+		}
+		if c.CloudWatchLogsGroup != "" && c.CloudWatchLogsRetentionDays != 0 {
+			errs = append(errs, fmt.Errorf("cloudwatch_logs_retention_days can't be used "+
+				"with cloudwatch_logs_group, the retention of an existing group isn't changed."))
+		}
+	}
+	if c.CloudWatchLogs && c.CloudWatchLogsGroup == "" {
+		if c.CloudWatchLogsRetentionDays == 0 {
+			c.CloudWatchLogsRetentionDays = 30
+		} else if !validLogRetentionDays(c.CloudWatchLogsRetentionDays) {
+			errs = append(errs, fmt.Errorf("cloudwatch_logs_retention_days must be one of %v.",
+				logRetentionDays))
+		}
+	}
+
+	if c.EnableHibernation && c.IsSpotInstance() {
+		errs = append(errs, fmt.Errorf("enable_hibernation can't be used with spot instances."))
+	}
+
 	if c.EnableT2Unlimited {
 		if c.SpotPrice != "" {
 			errs = append(errs, fmt.Errorf("Error: T2 Unlimited cannot be used in conjuction with Spot Instances"))
@@ -159,11 +454,41 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		} else if c.InstanceType[0:firstDotIndex] != "t2" {
 			errs = append(errs, fmt.Errorf("Error: T2 Unlimited enabled with a non-T2 Instance Type: %s", c.InstanceType))
 		}
+
+		if c.CPUCredits == "" {
+			c.CPUCredits = CPUCreditsUnlimited
+		} else if c.CPUCredits != CPUCreditsUnlimited {
+			errs = append(errs, fmt.Errorf("enable_t2_unlimited can only be used with cpu_credits unlimited."))
+		}
+	} else if c.CPUCredits != "" {
+		if c.CPUCredits != CPUCreditsStandard && c.CPUCredits != CPUCreditsUnlimited {
+			errs = append(errs, fmt.Errorf("cpu_credits must be either standard or unlimited."))
+		}
+		if c.SpotPrice != "" {
+			errs = append(errs, fmt.Errorf("cpu_credits can't be used with spot instances."))
+		}
+		if !isBurstableInstanceType(c.InstanceType) {
+			errs = append(errs, fmt.Errorf(
+				"cpu_credits can only be used with burstable instance types (%s), not %s.",
+				strings.Join(burstableInstanceFamilies, ", "), c.InstanceType))
+		}
 	}
 
 	return errs
 }
 
+// isBurstableInstanceType tells whether the instance type is one of the
+// burstable instance families.
+func isBurstableInstanceType(instanceType string) bool {
+	family := strings.SplitN(instanceType, ".", 2)[0]
+	for _, burstable := range burstableInstanceFamilies {
+		if family == burstable {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *RunConfig) IsSpotInstance() bool {
 	return c.SpotPrice != "" && c.SpotPrice != "0"
 }
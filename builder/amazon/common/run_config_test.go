@@ -3,9 +3,11 @@ package common
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
 	"regexp"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/hashicorp/packer/helper/communicator"
 )
 
@@ -79,6 +81,32 @@ func TestRunConfigPrepare_SourceAmiFilterGood(t *testing.T) {
 	}
 }
 
+func TestRunConfigPrepare_SourceAmiFilterShortcuts(t *testing.T) {
+	c := testConfigFilter()
+	owner := "amazon"
+	c.SourceAmiFilter = AmiFilterOptions{
+		Owners:             []*string{&owner},
+		Architecture:       "arm64",
+		VirtualizationType: "hvm",
+	}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.SourceAmiFilter.Architecture = "amd64"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("should error on an unknown architecture: %s", err)
+	}
+
+	c.SourceAmiFilter.Architecture = "arm64"
+	filterKey := "virtualization-type"
+	filterValue := "hvm"
+	c.SourceAmiFilter.Filters = map[*string]*string{&filterKey: &filterValue}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("should error on virtualization_type set twice: %s", err)
+	}
+}
+
 func TestRunConfigPrepare_EnableT2UnlimitedGood(t *testing.T) {
 	c := testConfig()
 	// Must have a T2 instance type if T2 Unlimited is enabled
@@ -220,3 +248,438 @@ func TestRunConfigPrepare_TemporaryKeyPairName(t *testing.T) {
 		t.Fatal("keypair name does not match")
 	}
 }
+
+func TestRunConfigPrepare_TemporaryIamInstanceProfilePolicyDocument(t *testing.T) {
+	c := testConfig()
+	c.TemporaryIamInstanceProfilePolicyDocument = &PolicyDocument{
+		Statement: []Statement{{
+			Effect:   "Allow",
+			Action:   []string{"s3:GetObject"},
+			Resource: []string{"*"},
+		}},
+	}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.TemporaryIamInstanceProfilePolicyDocument.Version != "2012-10-17" {
+		t.Fatalf("bad policy version: %s", c.TemporaryIamInstanceProfilePolicyDocument.Version)
+	}
+
+	c.IamInstanceProfile = "existing"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if iam_instance_profile is also specified")
+	}
+
+	c.IamInstanceProfile = ""
+	c.TemporaryIamInstanceProfilePolicyDocument.Statement[0].Effect = "Maybe"
+	c.TemporaryIamInstanceProfilePolicyDocument.Statement[0].Resource = nil
+	if err := c.Prepare(nil); len(err) != 2 {
+		t.Fatalf("Should error for a bad statement: %s", err)
+	}
+}
+
+func TestRunConfigPrepare_TemporaryKeyPairType(t *testing.T) {
+	c := testConfig()
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.TemporaryKeyPairType != KeyPairTypeRSA {
+		t.Fatalf("bad key pair type: %s", c.TemporaryKeyPairType)
+	}
+
+	c.TemporaryKeyPairType = KeyPairTypeED25519
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.TemporaryKeyPairBits = 4096
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if bits are set for an ed25519 key pair")
+	}
+
+	c.TemporaryKeyPairType = "dsa"
+	c.TemporaryKeyPairBits = 0
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error for an unknown key pair type")
+	}
+
+	c.TemporaryKeyPairType = KeyPairTypeRSA
+	c.TemporaryKeyPairBits = 1024
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error for a short rsa key")
+	}
+}
+
+func TestRunConfigPrepare_TemporarySGSource(t *testing.T) {
+	c := testConfig()
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(c.TemporarySGSourceCidrs, []string{"0.0.0.0/0"}) {
+		t.Fatalf("bad cidrs: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c = testConfig()
+	c.TemporarySGSourceCidr = "10.0.0.0/8"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(c.TemporarySGSourceCidrs, []string{"10.0.0.0/8"}) {
+		t.Fatalf("bad cidrs: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c = testConfig()
+	c.TemporarySGSourceCidr = "10.0.0.0/8"
+	c.TemporarySGSourceCidrs = []string{"192.168.0.0/16"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error if both cidr options are set")
+	}
+
+	c = testConfig()
+	c.TemporarySGSourceCidrs = []string{"192.168.0.0/16", "bad"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error for a bad cidr")
+	}
+
+	c = testConfig()
+	c.TemporarySGSourceGroupId = "sg-1234"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if len(c.TemporarySGSourceCidrs) != 0 {
+		t.Fatalf("bad cidrs: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c = testConfig()
+	c.TemporarySGSourcePublicIp = true
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if len(c.TemporarySGSourceCidrs) != 0 {
+		t.Fatalf("bad cidrs: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c.TemporarySGSourceCidrs = []string{"192.168.0.0/16"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error if both the public ip and cidrs are set")
+	}
+}
+
+func TestRunConfigPrepare_IPv6(t *testing.T) {
+	c := testConfig()
+	c.Ipv6AddressCount = 1
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error without a subnet_id")
+	}
+
+	c = testConfig()
+	c.Ipv6AddressCount = 1
+	c.SubnetId = "subnet-1234"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(c.TemporarySGSourceCidrs, []string{"0.0.0.0/0", "::/0"}) {
+		t.Fatalf("bad cidrs: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c = testConfig()
+	c.TemporaryAddressFamily = "ipv6"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c = testConfig()
+	c.TemporaryAddressFamily = "ipx"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error for an unknown address family")
+	}
+
+	c = testConfig()
+	c.TemporaryAddressFamily = "ipv6"
+	c.SSHInterface = "public_dns"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error for an address family with a DNS interface")
+	}
+}
+
+func TestRunConfigPrepare_NetworkInterfaceId(t *testing.T) {
+	c := testConfig()
+	c.NetworkInterfaceId = "eni-1234"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if len(c.TemporarySGSourceCidrs) != 0 {
+		t.Fatalf("bad cidrs: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c = testConfig()
+	c.NetworkInterfaceId = "eni-1234"
+	c.SubnetId = "subnet-1234"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a subnet_id")
+	}
+
+	c = testConfig()
+	c.NetworkInterfaceId = "eni-1234"
+	c.SecurityGroupIds = []string{"sg-1234"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with security groups")
+	}
+}
+
+func TestRunConfigPrepare_KeepSourceInstance(t *testing.T) {
+	c := testConfig()
+	c.KeepSourceInstance = true
+	if err := c.Prepare(nil); len(err) != 2 {
+		t.Fatalf("Should error without a key pair and security groups: %s", err)
+	}
+
+	c = testConfigFilter()
+	c.SourceInstanceId = "i-1234"
+	c.Comm.SSHPassword = "packer"
+	c.SecurityGroupIds = []string{"sg-1234"}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.SourceAmi = "abcd"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a source_ami")
+	}
+
+	c.SourceAmi = ""
+	c.SourceAmiFilter = AmiFilterOptions{Filters: map[*string]*string{aws.String("name"): aws.String("foo")}}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a source_ami_filter")
+	}
+
+	c = testConfig()
+	c.KeepSourceInstance = true
+	c.Comm.SSHPassword = "packer"
+	c.SecurityGroupIds = []string{"sg-1234"}
+	c.SpotPrice = "0.10"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a spot instance")
+	}
+
+	for _, keep := range []bool{true, false} {
+		c = testConfig()
+		c.KeepSourceInstance = keep
+		if !keep {
+			c = testConfigFilter()
+			c.SourceInstanceId = "i-1234"
+		}
+		c.Comm.SSHPassword = "packer"
+		c.SecurityGroupIds = []string{"sg-1234"}
+		c.TemporaryIamInstanceProfilePolicyDocument = &PolicyDocument{
+			Statement: []Statement{{
+				Effect:   "Allow",
+				Action:   []string{"s3:GetObject"},
+				Resource: []string{"*"},
+			}},
+		}
+		if err := c.Prepare(nil); len(err) != 1 {
+			t.Fatalf("Should error with a temporary instance profile: %s", err)
+		}
+	}
+}
+
+func TestRunConfigPrepare_EnableHibernation(t *testing.T) {
+	c := testConfig()
+	c.EnableHibernation = true
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.SpotPrice = "0.10"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a spot instance")
+	}
+}
+
+func TestRunConfigPrepare_WindowsPasswordPrivateKeyFile(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	c := testConfig()
+	c.Comm.Type = "winrm"
+	c.Comm.WinRMUser = "Administrator"
+	c.SSHKeyPairName = "packer"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error without a private key")
+	}
+
+	c = testConfig()
+	c.Comm.Type = "winrm"
+	c.Comm.WinRMUser = "Administrator"
+	c.SSHKeyPairName = "packer"
+	c.WindowsPasswordPrivateKeyFile = tf.Name()
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.SSHKeyPairName = ""
+	c.WindowsPasswordPrivateKeyFile = "/does/not/exist"
+	if err := c.Prepare(nil); len(err) != 2 {
+		t.Fatalf("Should error without ssh_keypair_name and with a missing file: %s", err)
+	}
+}
+
+func TestRunConfigPrepare_CPUCredits(t *testing.T) {
+	c := testConfig()
+	c.InstanceType = "t3.medium"
+	c.CPUCredits = "unlimited"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.CPUCredits = "boundless"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with an unknown credit option")
+	}
+
+	c = testConfig()
+	c.InstanceType = "m5.large"
+	c.CPUCredits = "standard"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a non burstable instance type")
+	}
+
+	c = testConfig()
+	c.InstanceType = "trn1.2xlarge"
+	c.CPUCredits = "standard"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a non burstable T instance type")
+	}
+
+	c = testConfig()
+	c.InstanceType = "t4g.small"
+	c.CPUCredits = "standard"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c = testConfig()
+	c.InstanceType = "t2.micro"
+	c.EnableT2Unlimited = true
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.CPUCredits != "unlimited" {
+		t.Fatalf("bad cpu_credits: %s", c.CPUCredits)
+	}
+
+	c = testConfig()
+	c.InstanceType = "t2.micro"
+	c.EnableT2Unlimited = true
+	c.CPUCredits = "standard"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with enable_t2_unlimited and standard credits")
+	}
+}
+
+func TestRunConfigPrepare_Tenancy(t *testing.T) {
+	c := testConfig()
+	c.Tenancy = "dedicated"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.Tenancy = "shared"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with an unknown tenancy")
+	}
+
+	c = testConfig()
+	c.HostId = "h-0123456789abcdef0"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.Tenancy != "host" {
+		t.Fatalf("bad tenancy: %s", c.Tenancy)
+	}
+
+	c.HostResourceGroupArn = "arn:aws:resource-groups:us-east-1:123456789012:group/mac-hosts"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with both host_id and host_resource_group_arn")
+	}
+
+	c = testConfig()
+	c.HostId = "h-0123456789abcdef0"
+	c.Tenancy = "dedicated"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a host and dedicated tenancy")
+	}
+
+	c = testConfig()
+	c.Tenancy = "host"
+	c.SpotPrice = "auto"
+	c.SpotPriceAutoProduct = "Linux/UNIX"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with host tenancy and spot instances")
+	}
+}
+
+func TestRunConfigPrepare_AllocateHost(t *testing.T) {
+	c := testConfig()
+	c.InstanceType = "mac1.metal"
+	c.AllocateHost = true
+	c.AvailabilityZone = "us-east-1a"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.Tenancy != "host" {
+		t.Fatalf("bad tenancy: %s", c.Tenancy)
+	}
+
+	c.HostId = "h-0123456789abcdef0"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with allocate_host and host_id")
+	}
+
+	c = testConfig()
+	c.AllocateHost = true
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error without an availability zone or subnet")
+	}
+
+	c = testConfig()
+	c.KeepHost = true
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with keep_host without allocate_host")
+	}
+}
+
+func TestRunConfigPrepare_CloudWatchLogs(t *testing.T) {
+	c := testConfig()
+	c.CloudWatchLogs = true
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.CloudWatchLogsRetentionDays != 30 {
+		t.Fatalf("bad retention: %d", c.CloudWatchLogsRetentionDays)
+	}
+
+	c.CloudWatchLogsRetentionDays = 10
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a retention CloudWatch Logs doesn't accept")
+	}
+
+	c = testConfig()
+	c.CloudWatchLogs = true
+	c.CloudWatchLogsGroup = "packer"
+	c.CloudWatchLogsRetentionDays = 7
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with a retention for an existing group")
+	}
+
+	c = testConfig()
+	c.CloudWatchLogsGroup = "packer"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatal("Should error with cloudwatch_logs_group without cloudwatch_logs")
+	}
+}
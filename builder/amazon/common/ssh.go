@@ -18,6 +18,11 @@ type ec2Describer interface {
 	DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error)
 }
 
+const (
+	AddressFamilyIPv4 = "ipv4"
+	AddressFamilyIPv6 = "ipv6"
+)
+
 var (
 	// modified in tests
 	sshHostSleepDuration = time.Second
@@ -25,14 +30,20 @@ var (
 
 // SSHHost returns a function that can be given to the SSH communicator
 // for determining the SSH address based on the instance DNS name.
-func SSHHost(e ec2Describer, sshInterface string) func(multistep.StateBag) (string, error) {
+//
+// The IPv6 address of the instance is used instead of its IP addresses
+// when addressFamily is ipv6, or when it isn't set and the instance only
+// has an IPv6 address, as it does in IPv6-only subnets.
+func SSHHost(e ec2Describer, sshInterface string, addressFamily string) func(multistep.StateBag) (string, error) {
 	return func(state multistep.StateBag) (string, error) {
 		const tries = 2
 		// <= with current structure to check result of describing `tries` times
 		for j := 0; j <= tries; j++ {
 			var host string
 			i := state.Get("instance").(*ec2.Instance)
-			if sshInterface != "" {
+			if useIPv6(i, sshInterface, addressFamily) {
+				host = instanceIPv6Address(i)
+			} else if sshInterface != "" {
 				switch sshInterface {
 				case "public_ip":
 					if i.PublicIpAddress != nil {
@@ -86,6 +97,36 @@ func SSHHost(e ec2Describer, sshInterface string) func(multistep.StateBag) (stri
 	}
 }
 
+func useIPv6(i *ec2.Instance, sshInterface string, addressFamily string) bool {
+	if sshInterface == "public_dns" || sshInterface == "private_dns" {
+		return false
+	}
+
+	switch addressFamily {
+	case AddressFamilyIPv6:
+		return true
+	case AddressFamilyIPv4:
+		return false
+	default:
+		return (i.PrivateIpAddress == nil || *i.PrivateIpAddress == "") &&
+			instanceIPv6Address(i) != ""
+	}
+}
+
+// instanceIPv6Address returns the first IPv6 address of the network
+// interfaces of the instance. IPv6 addresses are all public, so there is
+// no telling private and public ones apart.
+func instanceIPv6Address(i *ec2.Instance) string {
+	for _, ni := range i.NetworkInterfaces {
+		for _, addr := range ni.Ipv6Addresses {
+			if addr.Ipv6Address != nil && *addr.Ipv6Address != "" {
+				return *addr.Ipv6Address
+			}
+		}
+	}
+	return ""
+}
+
 // SSHConfig returns a function that can be used for the SSH communicator
 // config for connecting to the instance created over SSH using the private key
 // or password.
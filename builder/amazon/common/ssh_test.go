@@ -68,7 +68,7 @@ func testSSHHost(t *testing.T, allowTries int, vpcId string, sshInterface string
 		publicDNS:  publicDNS,
 	}
 
-	f := SSHHost(e, sshInterface)
+	f := SSHHost(e, sshInterface, "")
 	st := &multistep.BasicStateBag{}
 	st.Put("instance", &ec2.Instance{
 		InstanceId: aws.String("instance-id"),
@@ -128,3 +128,40 @@ func (d *fakeEC2Describer) DescribeInstances(in *ec2.DescribeInstancesInput) (*e
 
 	return out, nil
 }
+
+func TestSSHHost_IPv6(t *testing.T) {
+	const ipv6 = "2001:db8::1"
+	interfaces := []*ec2.InstanceNetworkInterface{{
+		Ipv6Addresses: []*ec2.InstanceIpv6Address{{Ipv6Address: aws.String(ipv6)}},
+	}}
+
+	var cases = []struct {
+		instance      *ec2.Instance
+		sshInterface  string
+		addressFamily string
+		wantHost      string
+	}{
+		// IPv6-only subnet
+		{&ec2.Instance{VpcId: aws.String("vpc-id"), NetworkInterfaces: interfaces}, "", "", ipv6},
+		// Dual-stack
+		{&ec2.Instance{VpcId: aws.String("vpc-id"), PrivateIpAddress: aws.String(privateIP), NetworkInterfaces: interfaces}, "", "", privateIP},
+		{&ec2.Instance{VpcId: aws.String("vpc-id"), PrivateIpAddress: aws.String(privateIP), NetworkInterfaces: interfaces}, "", "ipv6", ipv6},
+		{&ec2.Instance{VpcId: aws.String("vpc-id"), PrivateIpAddress: aws.String(privateIP), NetworkInterfaces: interfaces}, "private_ip", "ipv6", ipv6},
+		{&ec2.Instance{VpcId: aws.String("vpc-id"), PrivateIpAddress: aws.String(privateIP), NetworkInterfaces: interfaces}, "private_ip", "ipv4", privateIP},
+		{&ec2.Instance{VpcId: aws.String("vpc-id"), PrivateDnsName: aws.String(privateDNS), NetworkInterfaces: interfaces}, "private_dns", "", privateDNS},
+	}
+
+	for _, c := range cases {
+		st := &multistep.BasicStateBag{}
+		st.Put("instance", c.instance)
+
+		host, err := SSHHost(&fakeEC2Describer{}, c.sshInterface, c.addressFamily)(st)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if host != c.wantHost {
+			t.Fatalf("sshInterface=%s addressFamily=%s: got host %s, want %s",
+				c.sshInterface, c.addressFamily, host, c.wantHost)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/go-ini/ini"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/mitchellh/go-homedir"
+)
+
+// SSOProviderName is the name reported by the credentials provider used
+// for profiles configured with `aws configure sso`.
+const SSOProviderName = "PackerSSOProvider"
+
+// ssoProfile is the AWS SSO (IAM Identity Center) configuration of a
+// profile in the shared config file.
+type ssoProfile struct {
+	Name        string
+	StartURL    string
+	Region      string
+	AccountID   string
+	RoleName    string
+	SessionName string
+}
+
+// loadSSOProfile reads the profile with the given name from the shared
+// config file. It returns nil, without an error, if the profile doesn't
+// exist or isn't an SSO profile.
+func loadSSOProfile(name string) (*ssoProfile, error) {
+	path, err := sharedConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	cfg, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading AWS config file %s: %s", path, err)
+	}
+
+	sectionName := "profile " + name
+	if name == "default" {
+		sectionName = "default"
+	}
+	section, err := cfg.GetSection(sectionName)
+	if err != nil {
+		return nil, nil
+	}
+
+	p := &ssoProfile{
+		Name:        name,
+		StartURL:    section.Key("sso_start_url").String(),
+		Region:      section.Key("sso_region").String(),
+		AccountID:   section.Key("sso_account_id").String(),
+		RoleName:    section.Key("sso_role_name").String(),
+		SessionName: section.Key("sso_session").String(),
+	}
+
+	// Newer versions of the AWS CLI keep the start URL and region in a
+	// separate sso-session section shared by several profiles.
+	if p.SessionName != "" {
+		session, err := cfg.GetSection("sso-session " + p.SessionName)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Profile %q refers to sso-session %q which doesn't exist in %s",
+				name, p.SessionName, path)
+		}
+		p.StartURL = session.Key("sso_start_url").String()
+		p.Region = session.Key("sso_region").String()
+	}
+
+	if p.StartURL == "" && p.AccountID == "" && p.RoleName == "" {
+		return nil, nil
+	}
+	if p.StartURL == "" || p.Region == "" || p.AccountID == "" || p.RoleName == "" {
+		return nil, fmt.Errorf(
+			"Profile %q in %s is missing one of sso_start_url, sso_region, "+
+				"sso_account_id or sso_role_name", name, path)
+	}
+
+	return p, nil
+}
+
+func sharedConfigPath() (string, error) {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	return homedir.Expand("~/.aws/config")
+}
+
+// ssoToken is a token cached by `aws sso login`.
+type ssoToken struct {
+	StartURL              string `json:"startUrl,omitempty"`
+	Region                string `json:"region,omitempty"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+}
+
+// ssoTimeFormats are the formats that different versions of the AWS CLI
+// use for the times in the token cache.
+var ssoTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05UTC",
+}
+
+func parseSSOTime(v string) (time.Time, error) {
+	for _, format := range ssoTimeFormats {
+		if t, err := time.Parse(format, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format: %q", v)
+}
+
+func (t *ssoToken) expired(now time.Time) bool {
+	expiresAt, err := parseSSOTime(t.ExpiresAt)
+	return err != nil || !now.Before(expiresAt)
+}
+
+func (t *ssoToken) canRefresh(now time.Time) bool {
+	if t.RefreshToken == "" || t.ClientID == "" || t.ClientSecret == "" {
+		return false
+	}
+	expiresAt, err := parseSSOTime(t.RegistrationExpiresAt)
+	return err == nil && now.Before(expiresAt)
+}
+
+// ssoProvider is a credentials.Provider that exchanges the token cached by
+// `aws sso login` for role credentials. The token is refreshed, and the
+// cache updated, when it has expired and the cache has what is needed to
+// do so.
+type ssoProvider struct {
+	credentials.Expiry
+
+	profile *ssoProfile
+
+	// These are only overridden by tests.
+	cacheDir       string
+	portalEndpoint string
+	oidcEndpoint   string
+	client         *http.Client
+	now            func() time.Time
+}
+
+func newSSOProvider(profile *ssoProfile) (*ssoProvider, error) {
+	cacheDir, err := homedir.Expand("~/.aws/sso/cache")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssoProvider{
+		profile:        profile,
+		cacheDir:       cacheDir,
+		portalEndpoint: fmt.Sprintf("https://portal.sso.%s.amazonaws.com", profile.Region),
+		oidcEndpoint:   fmt.Sprintf("https://oidc.%s.amazonaws.com", profile.Region),
+		client:         cleanhttp.DefaultClient(),
+		now:            time.Now,
+	}, nil
+}
+
+func (p *ssoProvider) Retrieve() (credentials.Value, error) {
+	v := credentials.Value{ProviderName: SSOProviderName}
+
+	token, err := p.token()
+	if err != nil {
+		return v, err
+	}
+
+	query := url.Values{}
+	query.Set("account_id", p.profile.AccountID)
+	query.Set("role_name", p.profile.RoleName)
+	req, err := http.NewRequest("GET", p.portalEndpoint+"/federation/credentials?"+query.Encode(), nil)
+	if err != nil {
+		return v, err
+	}
+	req.Header.Set("x-amz-sso_bearer_token", token.AccessToken)
+
+	var out struct {
+		RoleCredentials struct {
+			AccessKeyID     string `json:"accessKeyId"`
+			SecretAccessKey string `json:"secretAccessKey"`
+			SessionToken    string `json:"sessionToken"`
+			Expiration      int64  `json:"expiration"`
+		} `json:"roleCredentials"`
+	}
+	if err := p.do(req, &out); err != nil {
+		if httpErr, ok := err.(*ssoHTTPError); ok && httpErr.StatusCode == http.StatusUnauthorized {
+			return v, p.loginRequiredError()
+		}
+		return v, fmt.Errorf("Error getting SSO role credentials for profile %q: %s", p.profile.Name, err)
+	}
+
+	creds := out.RoleCredentials
+	expiration := time.Unix(0, creds.Expiration*int64(time.Millisecond))
+	log.Printf("[INFO] Got SSO credentials for role %s in account %s, expiring at %s",
+		p.profile.RoleName, p.profile.AccountID, expiration)
+	p.SetExpiration(expiration, assumeRoleExpiryWindow)
+
+	v.AccessKeyID = creds.AccessKeyID
+	v.SecretAccessKey = creds.SecretAccessKey
+	v.SessionToken = creds.SessionToken
+	return v, nil
+}
+
+// token returns a valid token from the cache, refreshing it if needed.
+func (p *ssoProvider) token() (*ssoToken, error) {
+	path := p.cachePath()
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, p.loginRequiredError()
+	} else if err != nil {
+		return nil, fmt.Errorf("Error reading SSO token cache %s: %s", path, err)
+	}
+
+	var token ssoToken
+	if err := json.Unmarshal(raw, &token); err != nil {
+		return nil, fmt.Errorf("Error parsing SSO token cache %s: %s", path, err)
+	}
+
+	now := p.now()
+	if !token.expired(now) {
+		return &token, nil
+	}
+	if !token.canRefresh(now) {
+		return nil, p.loginRequiredError()
+	}
+
+	log.Printf("[INFO] Refreshing expired SSO token for profile %q", p.profile.Name)
+	body, err := json.Marshal(map[string]string{
+		"grantType":    "refresh_token",
+		"clientId":     token.ClientID,
+		"clientSecret": token.ClientSecret,
+		"refreshToken": token.RefreshToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", p.oidcEndpoint+"/token", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var out struct {
+		AccessToken  string `json:"accessToken"`
+		ExpiresIn    int64  `json:"expiresIn"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := p.do(req, &out); err != nil {
+		log.Printf("[WARN] Refreshing SSO token failed: %s", err)
+		return nil, p.loginRequiredError()
+	}
+
+	token.AccessToken = out.AccessToken
+	token.ExpiresAt = now.Add(time.Duration(out.ExpiresIn) * time.Second).UTC().Format(time.RFC3339)
+	if out.RefreshToken != "" {
+		token.RefreshToken = out.RefreshToken
+	}
+
+	// Failing to update the cache only means the next run refreshes again.
+	if raw, err := json.Marshal(&token); err == nil {
+		if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+			log.Printf("[WARN] Error updating SSO token cache %s: %s", path, err)
+		}
+	}
+
+	return &token, nil
+}
+
+// cachePath returns the path of the file the AWS CLI caches the token for
+// the profile in.
+func (p *ssoProvider) cachePath() string {
+	key := p.profile.StartURL
+	if p.profile.SessionName != "" {
+		key = p.profile.SessionName
+	}
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(p.cacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (p *ssoProvider) loginRequiredError() error {
+	return fmt.Errorf(
+		"The SSO session for profile %q has expired or doesn't exist. "+
+			"Run `aws sso login --profile %s` to log in again.",
+		p.profile.Name, p.profile.Name)
+}
+
+type ssoHTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ssoHTTPError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+func (p *ssoProvider) do(req *http.Request, out interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &ssoHTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,214 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSSOConfig = `
+[profile legacy]
+sso_start_url = https://example.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 123456789012
+sso_role_name = PackerBuilder
+region = us-west-2
+
+[profile modern]
+sso_session = example
+sso_account_id = 123456789012
+sso_role_name = PackerBuilder
+
+[sso-session example]
+sso_start_url = https://example.awsapps.com/start
+sso_region = eu-west-1
+
+[profile static]
+aws_access_key_id = AKID
+aws_secret_access_key = SECRET
+
+[profile incomplete]
+sso_start_url = https://example.awsapps.com/start
+`
+
+func testSSOConfigFile(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(testSSOConfig), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	old := os.Getenv("AWS_CONFIG_FILE")
+	os.Setenv("AWS_CONFIG_FILE", path)
+	return func() {
+		os.Setenv("AWS_CONFIG_FILE", old)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLoadSSOProfile(t *testing.T) {
+	defer testSSOConfigFile(t)()
+
+	p, err := loadSSOProfile("legacy")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.StartURL != "https://example.awsapps.com/start" || p.Region != "us-east-1" ||
+		p.AccountID != "123456789012" || p.RoleName != "PackerBuilder" {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	p, err = loadSSOProfile("modern")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.SessionName != "example" || p.Region != "eu-west-1" ||
+		p.StartURL != "https://example.awsapps.com/start" {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	for _, name := range []string{"static", "missing"} {
+		if p, err := loadSSOProfile(name); err != nil || p != nil {
+			t.Fatalf("%s should not be an SSO profile: %#v, %v", name, p, err)
+		}
+	}
+
+	if _, err := loadSSOProfile("incomplete"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func testSSOProvider(t *testing.T, token *ssoToken) (*ssoProvider, func()) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/federation/credentials":
+			if r.Header.Get("x-amz-sso_bearer_token") != "valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("account_id") != "123456789012" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"roleCredentials": {
+				"accessKeyId": "AKID",
+				"secretAccessKey": "SECRET",
+				"sessionToken": "TOKEN",
+				"expiration": 4102444800000}}`))
+		case "/token":
+			w.Write([]byte(`{"accessToken": "valid", "expiresIn": 3600, "refreshToken": "new"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	p := &ssoProvider{
+		profile: &ssoProfile{
+			Name:      "legacy",
+			StartURL:  "https://example.awsapps.com/start",
+			Region:    "us-east-1",
+			AccountID: "123456789012",
+			RoleName:  "PackerBuilder",
+		},
+		cacheDir:       dir,
+		portalEndpoint: server.URL,
+		oidcEndpoint:   server.URL,
+		client:         &http.Client{Transport: &http.Transport{TLSClientConfig: server.TLS}},
+		now:            time.Now,
+	}
+
+	if token != nil {
+		raw, err := json.Marshal(token)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := ioutil.WriteFile(p.cachePath(), raw, 0600); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	return p, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSSOProvider_Retrieve(t *testing.T) {
+	p, cleanup := testSSOProvider(t, &ssoToken{
+		AccessToken: "valid",
+		ExpiresAt:   time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+	defer cleanup()
+
+	v, err := p.Retrieve()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.AccessKeyID != "AKID" || v.SecretAccessKey != "SECRET" || v.SessionToken != "TOKEN" {
+		t.Fatalf("bad: %#v", v)
+	}
+	if p.IsExpired() {
+		t.Fatal("credentials should not be expired")
+	}
+}
+
+func TestSSOProvider_RetrieveRefresh(t *testing.T) {
+	p, cleanup := testSSOProvider(t, &ssoToken{
+		AccessToken:           "expired",
+		ExpiresAt:             "2018-01-01T00:00:00UTC",
+		ClientID:              "client",
+		ClientSecret:          "secret",
+		RegistrationExpiresAt: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		RefreshToken:          "old",
+	})
+	defer cleanup()
+
+	if _, err := p.Retrieve(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw, err := ioutil.ReadFile(p.cachePath())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var token ssoToken
+	if err := json.Unmarshal(raw, &token); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if token.AccessToken != "valid" || token.RefreshToken != "new" || token.expired(time.Now()) {
+		t.Fatalf("cache was not updated: %#v", token)
+	}
+}
+
+func TestSSOProvider_RetrieveLoginRequired(t *testing.T) {
+	p, cleanup := testSSOProvider(t, &ssoToken{
+		AccessToken: "expired",
+		ExpiresAt:   "2018-01-01T00:00:00Z",
+	})
+	defer cleanup()
+
+	_, err := p.Retrieve()
+	if err == nil || !strings.Contains(err.Error(), "aws sso login --profile legacy") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	os.Remove(p.cachePath())
+	_, err = p.Retrieve()
+	if err == nil || !strings.Contains(err.Error(), "aws sso login --profile legacy") {
+		t.Fatalf("bad: %v", err)
+	}
+}
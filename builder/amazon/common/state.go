@@ -97,6 +97,35 @@ func InstanceStateRefreshFunc(conn *ec2.EC2, instanceId string) StateRefreshFunc
 	}
 }
 
+// SnapshotStateRefreshFunc returns a StateRefreshFunc that is used to watch
+// an EBS snapshot until it is completed.
+func SnapshotStateRefreshFunc(conn *ec2.EC2, snapshotId string) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		resp, err := conn.DescribeSnapshots(&ec2.DescribeSnapshotsInput{
+			SnapshotIds: []*string{&snapshotId},
+		})
+		if err != nil {
+			if ec2err, ok := err.(awserr.Error); ok && ec2err.Code() == "InvalidSnapshot.NotFound" {
+				// Set this to nil as if we didn't find anything.
+				resp = nil
+			} else if isTransientNetworkError(err) {
+				// Transient network error, treat it as if we didn't find anything
+				resp = nil
+			} else {
+				log.Printf("Error on SnapshotStateRefresh: %s", err)
+				return nil, "", err
+			}
+		}
+
+		if resp == nil || len(resp.Snapshots) == 0 {
+			return nil, "", nil
+		}
+
+		i := resp.Snapshots[0]
+		return i, *i.State, nil
+	}
+}
+
 // SpotRequestStateRefreshFunc returns a StateRefreshFunc that is used to watch
 // a spot request for state changes.
 func SpotRequestStateRefreshFunc(conn *ec2.EC2, spotRequestId string) StateRefreshFunc {
@@ -0,0 +1,207 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// hostAllocationTimeTag is the tag the dedicated hosts Packer allocates are
+// marked with, holding when they were allocated. Hosts with it are reused
+// by the next builds while they aren't released.
+const hostAllocationTimeTag = "packer:host-allocation-time"
+
+// macHostMinimumAllocation is how long a host of mac instances has to be
+// allocated before it can be released.
+const macHostMinimumAllocation = 24 * time.Hour
+
+// StepAllocateHost allocates a dedicated host for the source instance to
+// run on, unless a host it allocated for an earlier build is available,
+// and releases the host once the build is done. Hosts of mac instances
+// can only be released 24 hours after they were allocated, they are left
+// allocated for the next builds until then. Without an availability zone,
+// the host is allocated in the zone of the subnet.
+//
+// Produces:
+//   hostId string - The ID of the dedicated host to run the instance on.
+type StepAllocateHost struct {
+	AllocateHost     bool
+	AvailabilityZone string
+	Ctx              interpolate.Context
+	InstanceType     string
+	KeepHost         bool
+	SubnetId         string
+	Tags             TagMap
+
+	allocationTime time.Time
+	hostId         string
+}
+
+func (s *StepAllocateHost) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.AllocateHost {
+		return multistep.ActionContinue
+	}
+
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	availabilityZone := s.AvailabilityZone
+	if availabilityZone == "" {
+		log.Printf("[INFO] Finding AZ for the given subnet '%s'", s.SubnetId)
+		resp, err := ec2conn.DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: []*string{&s.SubnetId}})
+		if err == nil && len(resp.Subnets) == 0 {
+			err = fmt.Errorf("subnet %s not found", s.SubnetId)
+		}
+		if err != nil {
+			err := fmt.Errorf("Error finding the availability zone of the dedicated host: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		availabilityZone = aws.StringValue(resp.Subnets[0].AvailabilityZone)
+		log.Printf("[INFO] AvailabilityZone found: '%s'", availabilityZone)
+	}
+
+	resp, err := ec2conn.DescribeHosts(&ec2.DescribeHostsInput{
+		Filter: []*ec2.Filter{
+			{Name: aws.String("availability-zone"), Values: []*string{&availabilityZone}},
+			{Name: aws.String("instance-type"), Values: []*string{&s.InstanceType}},
+			{Name: aws.String("state"), Values: []*string{aws.String("available")}},
+			{Name: aws.String("tag-key"), Values: []*string{aws.String(hostAllocationTimeTag)}},
+		},
+	})
+	if err != nil {
+		err := fmt.Errorf("Error describing dedicated hosts: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	for _, host := range resp.Hosts {
+		if len(host.Instances) > 0 {
+			continue
+		}
+		s.hostId = aws.StringValue(host.HostId)
+
+		// The hosts the SDK describes don't have their tags
+		tags, err := ec2conn.DescribeTags(&ec2.DescribeTagsInput{
+			Filters: []*ec2.Filter{
+				{Name: aws.String("resource-id"), Values: []*string{&s.hostId}},
+				{Name: aws.String("key"), Values: []*string{aws.String(hostAllocationTimeTag)}},
+			},
+		})
+		if err != nil {
+			err := fmt.Errorf("Error describing the tags of dedicated host %s: %s", s.hostId, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		for _, tag := range tags.Tags {
+			s.allocationTime, _ = time.Parse(time.RFC3339, aws.StringValue(tag.Value))
+		}
+		ui.Say(fmt.Sprintf("Reusing dedicated host %s allocated at %s",
+			s.hostId, s.allocationTime.Format(time.RFC3339)))
+		state.Put("hostId", s.hostId)
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Allocating a dedicated host for %s instances in %s...",
+		s.InstanceType, availabilityZone))
+	allocResp, err := ec2conn.AllocateHosts(&ec2.AllocateHostsInput{
+		AutoPlacement:    aws.String("off"),
+		AvailabilityZone: &availabilityZone,
+		InstanceType:     &s.InstanceType,
+		Quantity:         aws.Int64(1),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error allocating dedicated host: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.hostId = aws.StringValue(allocResp.HostIds[0])
+	s.allocationTime = time.Now().UTC()
+	ui.Message(fmt.Sprintf("Dedicated host: %s", s.hostId))
+	if isMacInstanceType(s.InstanceType) {
+		ui.Message(fmt.Sprintf("Hosts of mac instances can only be released 24 hours "+
+			"after they were allocated, %s is billed until at least %s.",
+			s.hostId, s.allocationTime.Add(macHostMinimumAllocation).Format(time.RFC3339)))
+	}
+	state.Put("hostId", s.hostId)
+
+	tags, err := s.Tags.EC2Tags(s.Ctx, *ec2conn.Config.Region, state)
+	if err != nil {
+		err := fmt.Errorf("Error tagging dedicated host: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	tags = append(tags, &ec2.Tag{
+		Key:   aws.String(hostAllocationTimeTag),
+		Value: aws.String(s.allocationTime.Format(time.RFC3339)),
+	})
+	_, err = ec2conn.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{&s.hostId},
+		Tags:      tags,
+	})
+	if err != nil {
+		err := fmt.Errorf("Error tagging dedicated host: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepAllocateHost) Cleanup(state multistep.StateBag) {
+	if s.hostId == "" {
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+
+	if s.KeepHost {
+		ui.Say(fmt.Sprintf("Keeping dedicated host %s for the next builds", s.hostId))
+		return
+	}
+
+	if isMacInstanceType(s.InstanceType) {
+		if releasable := s.allocationTime.Add(macHostMinimumAllocation); time.Now().Before(releasable) {
+			ui.Error(fmt.Sprintf("Dedicated host %s can't be released before %s, 24 hours "+
+				"after it was allocated. It is left allocated, and billed, until it is released: "+
+				"the next builds allocating a host reuse it, and the first one done after that "+
+				"time releases it.", s.hostId, releasable.Format(time.RFC3339)))
+			return
+		}
+	}
+
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+
+	ui.Say(fmt.Sprintf("Releasing dedicated host %s...", s.hostId))
+	resp, err := ec2conn.ReleaseHosts(&ec2.ReleaseHostsInput{
+		HostIds: []*string{&s.hostId},
+	})
+	if err == nil && len(resp.Unsuccessful) > 0 && resp.Unsuccessful[0].Error != nil {
+		err = fmt.Errorf("%s", aws.StringValue(resp.Unsuccessful[0].Error.Message))
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error releasing dedicated host %s, it may still be allocated: %s", s.hostId, err))
+		return
+	}
+	log.Printf("[INFO] Released dedicated host %s", s.hostId)
+}
+
+// isMacInstanceType returns whether instances of the type run macOS, on
+// hosts of the mac families.
+func isMacInstanceType(instanceType string) bool {
+	return strings.HasPrefix(instanceType, "mac")
+}
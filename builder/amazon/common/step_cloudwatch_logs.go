@@ -0,0 +1,297 @@
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+const (
+	logsFlushInterval   = 5 * time.Second
+	consolePollInterval = 30 * time.Second
+
+	// The limits of PutLogEvents, each event counts 26 bytes more than
+	// its message.
+	maxLogEventSize   = 256*1024 - 26
+	maxLogBatchSize   = 1024 * 1024
+	maxLogBatchEvents = 10000
+)
+
+// StepCloudWatchLogs streams the console output of the source instance,
+// and everything the build writes to the ui once the instance is running,
+// to CloudWatch Logs. Unless an existing group is given, the logs go to a
+// group created for the run, tagged with its UUID, which is kept once the
+// build completes and expires with its retention period.
+//
+// The ui in the state is replaced until the cleanup, so the steps that
+// come after this one, the provisioners included, are logged.
+type StepCloudWatchLogs struct {
+	Enable        bool
+	LogGroup      string
+	RetentionDays int
+	BuildName     string
+
+	ui         packer.Ui
+	ec2conn    *ec2.EC2
+	instanceId string
+	console    *logStream
+	output     *logStream
+	consoleLog string
+	done       chan struct{}
+	wg         sync.WaitGroup
+	err        error
+}
+
+func (s *StepCloudWatchLogs) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.Enable {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	sess := state.Get("awsSession").(*session.Session)
+	logs := newLogsClient(sess)
+
+	runUUID := os.Getenv("PACKER_RUN_UUID")
+	if runUUID == "" {
+		runUUID = uuid.TimeOrderedUUID()
+	}
+
+	group := s.LogGroup
+	if group == "" {
+		group = fmt.Sprintf("packer-%s", runUUID)
+		ui.Say(fmt.Sprintf("Creating CloudWatch Logs group: %s", group))
+		err := logs.CreateLogGroup(&logGroupInput{
+			LogGroupName: aws.String(group),
+			Tags:         map[string]*string{"packer:build-uuid": aws.String(runUUID)},
+		})
+		// The builds of a run share its group
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ResourceAlreadyExistsException" {
+			err = nil
+		}
+		if err == nil {
+			err = logs.PutRetentionPolicy(&logRetentionInput{
+				LogGroupName:    aws.String(group),
+				RetentionInDays: aws.Int64(int64(s.RetentionDays)),
+			})
+		}
+		if err != nil {
+			err := fmt.Errorf("Error creating CloudWatch Logs group: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	// Stream names can't contain colons or asterisks
+	prefix := strings.NewReplacer(":", "_", "*", "_").Replace(
+		fmt.Sprintf("%s/%s", runUUID, s.BuildName))
+	s.console = &logStream{logs: logs, group: group, name: prefix + "/console"}
+	s.output = &logStream{logs: logs, group: group, name: prefix + "/output"}
+	for _, stream := range []*logStream{s.console, s.output} {
+		err := logs.CreateLogStream(&logStreamInput{
+			LogGroupName:  aws.String(stream.group),
+			LogStreamName: aws.String(stream.name),
+		})
+		if err != nil {
+			err := fmt.Errorf("Error creating CloudWatch Logs stream %s: %s", stream.name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Say(fmt.Sprintf("Streaming the console and build output to CloudWatch Logs: %s/%s",
+		group, prefix))
+	s.ui = ui
+	s.ec2conn = state.Get("ec2").(*ec2.EC2)
+	s.instanceId = aws.StringValue(state.Get("instance").(*ec2.Instance).InstanceId)
+	state.Put("ui", &logsUi{Ui: ui, stream: s.output})
+
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.stream()
+
+	return multistep.ActionContinue
+}
+
+func (s *StepCloudWatchLogs) Cleanup(state multistep.StateBag) {
+	if s.done == nil {
+		return
+	}
+
+	close(s.done)
+	s.wg.Wait()
+	state.Put("ui", s.ui)
+
+	// Send what is left before the instance is terminated
+	s.pollConsole()
+	s.flush()
+	if s.err != nil {
+		s.ui.Error(fmt.Sprintf(
+			"Error streaming to CloudWatch Logs, the logs may be incomplete: %s", s.err))
+	}
+}
+
+func (s *StepCloudWatchLogs) stream() {
+	defer s.wg.Done()
+
+	flush := time.NewTicker(logsFlushInterval)
+	defer flush.Stop()
+	poll := time.NewTicker(consolePollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-flush.C:
+			s.flush()
+		case <-poll.C:
+			s.pollConsole()
+		}
+	}
+}
+
+func (s *StepCloudWatchLogs) flush() {
+	for _, stream := range []*logStream{s.console, s.output} {
+		if err := stream.flush(); err != nil {
+			log.Printf("[WARN] Error sending to CloudWatch Logs stream %s: %s", stream.name, err)
+			s.err = err
+		}
+	}
+}
+
+// pollConsole adds the lines of the console output that are new since the
+// last poll. The console output only holds the most recent output of the
+// instance, so it is sent whole once earlier lines have been dropped.
+func (s *StepCloudWatchLogs) pollConsole() {
+	resp, err := s.ec2conn.GetConsoleOutput(&ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(s.instanceId),
+	})
+	if err != nil {
+		log.Printf("[WARN] Error getting the console output: %s", err)
+		s.err = err
+		return
+	}
+	decoded, err := base64.StdEncoding.DecodeString(aws.StringValue(resp.Output))
+	if err != nil {
+		log.Printf("[WARN] Error decoding the console output: %s", err)
+		s.err = err
+		return
+	}
+
+	// A line is only sent once it is complete
+	output := string(decoded)
+	output = output[:strings.LastIndex(output, "\n")+1]
+	lines := output
+	if strings.HasPrefix(output, s.consoleLog) {
+		lines = output[len(s.consoleLog):]
+	}
+	s.consoleLog = output
+
+	for _, line := range strings.Split(lines, "\n") {
+		s.console.add(strings.TrimRight(line, "\r"))
+	}
+}
+
+// logStream buffers the events of a log stream until they are flushed.
+type logStream struct {
+	logs  *logsClient
+	group string
+	name  string
+	token *string
+
+	lock   sync.Mutex
+	events []*logEvent
+}
+
+func (s *logStream) add(message string) {
+	if message == "" {
+		return
+	}
+	if len(message) > maxLogEventSize {
+		end := maxLogEventSize
+		for !utf8.RuneStart(message[end]) {
+			end--
+		}
+		message = message[:end]
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events = append(s.events, &logEvent{
+		Message:   aws.String(message),
+		Timestamp: aws.Int64(time.Now().UnixNano() / int64(time.Millisecond)),
+	})
+}
+
+// flush sends the buffered events in as few batches as the limits allow.
+// The events that couldn't be sent are kept for the next flush.
+func (s *logStream) flush() error {
+	s.lock.Lock()
+	events := s.events
+	s.events = nil
+	s.lock.Unlock()
+
+	for len(events) > 0 {
+		n, size := 0, 0
+		for n < len(events) && n < maxLogBatchEvents {
+			size += len(aws.StringValue(events[n].Message)) + 26
+			if size > maxLogBatchSize {
+				break
+			}
+			n++
+		}
+
+		resp, err := s.logs.PutLogEvents(&logEventsInput{
+			LogEvents:     events[:n],
+			LogGroupName:  aws.String(s.group),
+			LogStreamName: aws.String(s.name),
+			SequenceToken: s.token,
+		})
+		if err != nil {
+			s.lock.Lock()
+			s.events = append(events, s.events...)
+			s.lock.Unlock()
+			return err
+		}
+		s.token = resp.NextSequenceToken
+		events = events[n:]
+	}
+	return nil
+}
+
+// logsUi adds the messages written to a ui to a log stream.
+type logsUi struct {
+	packer.Ui
+	stream *logStream
+}
+
+func (u *logsUi) Say(message string) {
+	u.Ui.Say(message)
+	u.stream.add(message)
+}
+
+func (u *logsUi) Message(message string) {
+	u.Ui.Message(message)
+	u.stream.add(message)
+}
+
+func (u *logsUi) Error(message string) {
+	u.Ui.Error(message)
+	u.stream.add(message)
+}
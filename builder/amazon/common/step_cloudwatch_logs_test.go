@@ -0,0 +1,26 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestLogStreamAdd(t *testing.T) {
+	s := &logStream{}
+	s.add("")
+	s.add("hello")
+	s.add(strings.Repeat("é", maxLogEventSize))
+
+	if len(s.events) != 2 {
+		t.Fatalf("bad: %d", len(s.events))
+	}
+	if aws.StringValue(s.events[0].Message) != "hello" {
+		t.Fatalf("bad: %s", aws.StringValue(s.events[0].Message))
+	}
+	truncated := aws.StringValue(s.events[1].Message)
+	if len(truncated) > maxLogEventSize || !strings.HasSuffix(truncated, "é") {
+		t.Fatalf("bad truncation: %d", len(truncated))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"time"
 
@@ -21,10 +22,11 @@ import (
 // StepGetPassword reads the password from a Windows server and sets it
 // on the WinRM config.
 type StepGetPassword struct {
-	Debug     bool
-	Comm      *communicator.Config
-	Timeout   time.Duration
-	BuildName string
+	Debug          bool
+	Comm           *communicator.Config
+	Timeout        time.Duration
+	BuildName      string
+	PrivateKeyFile string
 }
 
 func (s *StepGetPassword) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -52,7 +54,7 @@ func (s *StepGetPassword) Run(_ context.Context, state multistep.StateBag) multi
 		ui.Message(
 			"It is normal for this process to take up to 15 minutes,\n" +
 				"but it usually takes around 5. Please wait.")
-		password, err = s.waitForPassword(state, cancel)
+		password, err = s.waitForPassword(state, ui, cancel)
 		waitDone <- true
 	}()
 
@@ -104,17 +106,25 @@ func (s *StepGetPassword) Cleanup(multistep.StateBag) {
 	commonhelper.RemoveSharedStateFile("winrm_password", s.BuildName)
 }
 
-func (s *StepGetPassword) waitForPassword(state multistep.StateBag, cancel <-chan struct{}) (string, error) {
+func (s *StepGetPassword) waitForPassword(state multistep.StateBag, ui packer.Ui, cancel <-chan struct{}) (string, error) {
 	ec2conn := state.Get("ec2").(*ec2.EC2)
 	instance := state.Get("instance").(*ec2.Instance)
-	privateKey := state.Get("privateKey").(string)
 
-	for {
+	privateKey, err := s.privateKey(state)
+	if err != nil {
+		return "", err
+	}
+
+	// Poll as often as the other waiters do, AWS_POLL_DELAY_SECONDS
+	delay := time.Duration(SleepSeconds()) * time.Second
+	start := time.Now()
+	reported := start
+	for attempt := 1; ; attempt++ {
 		select {
 		case <-cancel:
 			log.Println("[INFO] Retrieve password wait cancelled. Exiting loop.")
 			return "", errors.New("Retrieve password wait cancelled")
-		case <-time.After(5 * time.Second):
+		case <-time.After(delay):
 		}
 
 		resp, err := ec2conn.GetPasswordData(&ec2.GetPasswordDataInput{
@@ -127,7 +137,7 @@ func (s *StepGetPassword) waitForPassword(state multistep.StateBag, cancel <-cha
 
 		if resp.PasswordData != nil && *resp.PasswordData != "" {
 			decryptedPassword, err := decryptPasswordDataWithPrivateKey(
-				*resp.PasswordData, []byte(privateKey))
+				*resp.PasswordData, privateKey)
 			if err != nil {
 				err := fmt.Errorf("Error decrypting auto-generated instance password: %s", err)
 				return "", err
@@ -137,9 +147,28 @@ func (s *StepGetPassword) waitForPassword(state multistep.StateBag, cancel <-cha
 		}
 
 		log.Printf("[DEBUG] Password is blank, will retry...")
+		if time.Since(reported) >= time.Minute {
+			reported = time.Now()
+			ui.Message(fmt.Sprintf("Password not available yet after %d attempts in %s...",
+				attempt, reported.Sub(start)/time.Second*time.Second))
+		}
 	}
 }
 
+// privateKey returns the private key the password is encrypted for, either
+// the one given or the one of the key pair the instance was launched with.
+func (s *StepGetPassword) privateKey(state multistep.StateBag) ([]byte, error) {
+	if s.PrivateKeyFile != "" {
+		privateKey, err := ioutil.ReadFile(s.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading windows_password_private_key_file: %s", err)
+		}
+		return privateKey, nil
+	}
+
+	return []byte(state.Get("privateKey").(string)), nil
+}
+
 func decryptPasswordDataWithPrivateKey(passwordData string, pemBytes []byte) (string, error) {
 	encryptedPasswd, err := base64.StdEncoding.DecodeString(passwordData)
 	if err != nil {
@@ -147,6 +176,9 @@ func decryptPasswordDataWithPrivateKey(passwordData string, pemBytes []byte) (st
 	}
 
 	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return "", errors.New("the private key isn't PEM encoded")
+	}
 	var asn1Bytes []byte
 	if _, ok := block.Headers["DEK-Info"]; ok {
 		return "", errors.New("encrypted private key isn't yet supported")
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+)
+
+func TestDecryptPasswordDataWithPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, &key.PublicKey, []byte("p4ssw0rd"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	passwordData := base64.StdEncoding.EncodeToString(encrypted)
+
+	password, err := decryptPasswordDataWithPrivateKey(passwordData, pemBytes)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if password != "p4ssw0rd" {
+		t.Fatalf("bad password: %s", password)
+	}
+
+	if _, err := decryptPasswordDataWithPrivateKey(passwordData, []byte("not a key")); err == nil {
+		t.Fatal("should error with a key that isn't PEM encoded")
+	}
+}
@@ -0,0 +1,178 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// EC2 doesn't accept a new instance profile until IAM has propagated it,
+// which takes a few seconds.
+const iamInstanceProfilePropagationDelay = 10 * time.Second
+
+// StepIamInstanceProfile sets up the instance profile the source instance
+// is launched with. Unless an existing profile is given, a temporary one
+// is created from the policy document, with a role of its own, and
+// deleted in the cleanup.
+//
+// Produces:
+//   iamInstanceProfile string - The name of the profile to use, if any.
+type StepIamInstanceProfile struct {
+	IamInstanceProfile                        string
+	TemporaryIamInstanceProfilePolicyDocument *PolicyDocument
+
+	createdInstanceProfileName string
+	createdRoleName            string
+	createdPolicyName          string
+	roleIsAttached             bool
+}
+
+func (s *StepIamInstanceProfile) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+
+	state.Put("iamInstanceProfile", s.IamInstanceProfile)
+	if s.IamInstanceProfile != "" || s.TemporaryIamInstanceProfilePolicyDocument == nil {
+		return multistep.ActionContinue
+	}
+
+	sess := state.Get("awsSession").(*session.Session)
+	iamsvc := newIAMClient(sess)
+
+	policy, err := json.Marshal(s.TemporaryIamInstanceProfilePolicyDocument)
+	if err != nil {
+		err := fmt.Errorf("Error encoding policy document: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	name := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	ui.Say(fmt.Sprintf("Creating temporary instance profile for this instance: %s", name))
+
+	err = iamsvc.CreateRole(&iamRoleInput{
+		RoleName:                 aws.String(name),
+		AssumeRolePolicyDocument: aws.String(ec2AssumeRolePolicy(aws.StringValue(sess.Config.Region))),
+		Description:              aws.String("Temporary role for Packer"),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error creating temporary role: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.createdRoleName = name
+
+	err = iamsvc.PutRolePolicy(&iamRolePolicyInput{
+		RoleName:       aws.String(name),
+		PolicyName:     aws.String(name),
+		PolicyDocument: aws.String(string(policy)),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error adding policy to temporary role: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.createdPolicyName = name
+
+	err = iamsvc.CreateInstanceProfile(&iamInstanceProfileInput{
+		InstanceProfileName: aws.String(name),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error creating temporary instance profile: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.createdInstanceProfileName = name
+
+	err = iamsvc.AddRoleToInstanceProfile(&iamInstanceProfileInput{
+		InstanceProfileName: aws.String(name),
+		RoleName:            aws.String(name),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error adding role to temporary instance profile: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.roleIsAttached = true
+
+	log.Printf("[INFO] Waiting %s for the instance profile to propagate", iamInstanceProfilePropagationDelay)
+	time.Sleep(iamInstanceProfilePropagationDelay)
+
+	state.Put("iamInstanceProfile", name)
+	return multistep.ActionContinue
+}
+
+func (s *StepIamInstanceProfile) Cleanup(state multistep.StateBag) {
+	if s.createdRoleName == "" {
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	iamsvc := newIAMClient(state.Get("awsSession").(*session.Session))
+
+	ui.Say("Deleting temporary instance profile...")
+	if s.roleIsAttached {
+		err := iamsvc.RemoveRoleFromInstanceProfile(&iamInstanceProfileInput{
+			InstanceProfileName: aws.String(s.createdInstanceProfileName),
+			RoleName:            aws.String(s.createdRoleName),
+		})
+		if err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error removing role %s from instance profile %s: %s",
+				s.createdRoleName, s.createdInstanceProfileName, err))
+		}
+	}
+
+	if s.createdInstanceProfileName != "" {
+		err := iamsvc.DeleteInstanceProfile(&iamInstanceProfileInput{
+			InstanceProfileName: aws.String(s.createdInstanceProfileName),
+		})
+		if err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error deleting instance profile %s: %s", s.createdInstanceProfileName, err))
+		}
+	}
+
+	if s.createdPolicyName != "" {
+		err := iamsvc.DeleteRolePolicy(&iamRolePolicyInput{
+			RoleName:   aws.String(s.createdRoleName),
+			PolicyName: aws.String(s.createdPolicyName),
+		})
+		if err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error deleting policy %s of role %s: %s", s.createdPolicyName, s.createdRoleName, err))
+		}
+	}
+
+	err := iamsvc.DeleteRole(&iamRoleInput{
+		RoleName: aws.String(s.createdRoleName),
+	})
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error deleting role %s: %s. Please delete it manually.", s.createdRoleName, err))
+	}
+}
+
+// ec2AssumeRolePolicy returns the trust policy allowing EC2 instances in
+// the region to use the role.
+func ec2AssumeRolePolicy(region string) string {
+	return fmt.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [{
+    "Effect": "Allow",
+    "Principal": {"Service": %q},
+    "Action": "sts:AssumeRole"
+  }]
+}`, servicePrincipal(region, "ec2"))
+}
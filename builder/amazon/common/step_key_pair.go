@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"runtime"
+	"strings"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -17,6 +21,8 @@ type StepKeyPair struct {
 	SSHAgentAuth         bool
 	DebugKeyPath         string
 	TemporaryKeyPairName string
+	TemporaryKeyPairType string
+	TemporaryKeyPairBits int
 	KeyPairName          string
 	PrivateKeyFile       string
 
@@ -61,18 +67,18 @@ func (s *StepKeyPair) Run(_ context.Context, state multistep.StateBag) multistep
 	ec2conn := state.Get("ec2").(*ec2.EC2)
 
 	ui.Say(fmt.Sprintf("Creating temporary keypair: %s", s.TemporaryKeyPairName))
-	keyResp, err := ec2conn.CreateKeyPair(&ec2.CreateKeyPairInput{
-		KeyName: &s.TemporaryKeyPairName})
+	privateKey, err := s.createKeyPair(ec2conn)
 	if err != nil {
 		state.Put("error", fmt.Errorf("Error creating temporary keypair: %s", err))
 		return multistep.ActionHalt
 	}
 
 	s.doCleanup = true
+	ReportTemporaryResource(ui, ec2conn, true, OrphanKeyPair, s.TemporaryKeyPairName)
 
 	// Set some state data for use in future steps
 	state.Put("keyPair", s.TemporaryKeyPairName)
-	state.Put("privateKey", *keyResp.KeyMaterial)
+	state.Put("privateKey", privateKey)
 
 	// If we're in debug mode, output the private key to the working
 	// directory.
@@ -86,7 +92,7 @@ func (s *StepKeyPair) Run(_ context.Context, state multistep.StateBag) multistep
 		defer f.Close()
 
 		// Write the key out
-		if _, err := f.Write([]byte(*keyResp.KeyMaterial)); err != nil {
+		if _, err := f.Write([]byte(privateKey)); err != nil {
 			state.Put("error", fmt.Errorf("Error saving debug key: %s", err))
 			return multistep.ActionHalt
 		}
@@ -103,6 +109,71 @@ func (s *StepKeyPair) Run(_ context.Context, state multistep.StateBag) multistep
 	return multistep.ActionContinue
 }
 
+// createKeyPair creates the temporary key pair and returns its private
+// key. EC2 generates the key, unless it doesn't support the key type or
+// a key size was asked for, in which case it is generated locally and
+// imported.
+func (s *StepKeyPair) createKeyPair(ec2conn *ec2.EC2) (string, error) {
+	if s.TemporaryKeyPairBits == 0 {
+		req, resp := ec2conn.CreateKeyPairRequest(&ec2.CreateKeyPairInput{
+			KeyName: &s.TemporaryKeyPairName,
+		})
+		if s.TemporaryKeyPairType != "" && s.TemporaryKeyPairType != KeyPairTypeRSA {
+			req.Handlers.Build.PushBackNamed(request.NamedHandler{
+				Name: "packer.KeyPairType",
+				Fn:   queryParamHandler("KeyType", s.TemporaryKeyPairType),
+			})
+		}
+
+		err := req.Send()
+		if err == nil {
+			return *resp.KeyMaterial, nil
+		}
+		if s.TemporaryKeyPairType == KeyPairTypeRSA || !isKeyTypeUnsupported(err) {
+			return "", err
+		}
+		log.Printf("[INFO] EC2 can't create %s key pairs here, importing one instead: %s",
+			s.TemporaryKeyPairType, err)
+	}
+
+	keyType := s.TemporaryKeyPairType
+	if keyType == "" {
+		keyType = KeyPairTypeRSA
+	}
+	privateKey, publicKey, err := generateKeyPair(keyType, s.TemporaryKeyPairBits)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = ec2conn.ImportKeyPair(&ec2.ImportKeyPairInput{
+		KeyName:           &s.TemporaryKeyPairName,
+		PublicKeyMaterial: publicKey,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return privateKey, nil
+}
+
+// isKeyTypeUnsupported tells whether EC2 failed to create the key pair
+// because it doesn't know about the KeyType parameter, or the type asked
+// for. Other errors, about the name of the key pair for instance, have the
+// same codes.
+func isKeyTypeUnsupported(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+
+	switch awsErr.Code() {
+	case "InvalidParameterValue", "UnknownParameter":
+		return strings.Contains(strings.ToLower(awsErr.Message()), "keytype")
+	default:
+		return false
+	}
+}
+
 func (s *StepKeyPair) Cleanup(state multistep.StateBag) {
 	if !s.doCleanup {
 		return
@@ -117,6 +188,8 @@ func (s *StepKeyPair) Cleanup(state multistep.StateBag) {
 	if err != nil {
 		ui.Error(fmt.Sprintf(
 			"Error cleaning up keypair. Please delete the key manually: %s", s.TemporaryKeyPairName))
+	} else {
+		ReportTemporaryResource(ui, ec2conn, false, OrphanKeyPair, s.TemporaryKeyPairName)
 	}
 
 	// Also remove the physical key if we're debugging.
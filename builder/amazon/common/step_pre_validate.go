@@ -16,6 +16,7 @@ import (
 type StepPreValidate struct {
 	DestAmiName     string
 	ForceDeregister bool
+	SkipCreateAMI   bool
 }
 
 func (s *StepPreValidate) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -24,6 +25,10 @@ func (s *StepPreValidate) Run(_ context.Context, state multistep.StateBag) multi
 		ui.Say("Force Deregister flag found, skipping prevalidating AMI Name")
 		return multistep.ActionContinue
 	}
+	if s.SkipCreateAMI {
+		ui.Say("skip_create_ami set, skipping prevalidating AMI Name")
+		return multistep.ActionContinue
+	}
 
 	ec2conn := state.Get("ec2").(*ec2.EC2)
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
 	retry "github.com/hashicorp/packer/common"
@@ -21,22 +22,30 @@ type StepRunSourceInstance struct {
 	AssociatePublicIpAddress          bool
 	AvailabilityZone                  string
 	BlockDevices                      BlockDevices
+	CPUCredits                        string
 	Ctx                               interpolate.Context
 	Debug                             bool
 	EbsOptimized                      bool
-	EnableT2Unlimited                 bool
+	EnableHibernation                 bool
 	ExpectedRootDevice                string
-	IamInstanceProfile                string
+	HostId                            string
+	HostResourceGroupArn              string
 	InstanceInitiatedShutdownBehavior string
 	InstanceType                      string
+	Ipv6AddressCount                  int64
+	NetworkInterfaceId                string
 	IsRestricted                      bool
+	KeepInstance                      bool
 	SourceAMI                         string
+	SourceInstanceId                  string
 	SubnetId                          string
 	Tags                              TagMap
+	Tenancy                           string
 	UserData                          string
 	UserDataFile                      string
 	VolumeTags                        TagMap
 
+	hostId     string
 	instanceId string
 }
 
@@ -47,6 +56,7 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 		keyName = name.(string)
 	}
 	securityGroupIds := aws.StringSlice(state.Get("securityGroupIds").([]string))
+	iamInstanceProfile := state.Get("iamInstanceProfile").(string)
 	ui := state.Get("ui").(packer.Ui)
 
 	userData := s.UserData
@@ -82,6 +92,10 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 		return multistep.ActionHalt
 	}
 
+	if s.SourceInstanceId != "" {
+		return s.startInstance(ctx, state)
+	}
+
 	var instanceId string
 
 	ui.Say("Adding tags to source instance")
@@ -96,6 +110,9 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
+	if !s.KeepInstance {
+		ec2Tags = append(ec2Tags, temporaryResourceTag())
+	}
 
 	volTags, err := s.VolumeTags.EC2Tags(s.Ctx, *ec2conn.Config.Region, state)
 	if err != nil {
@@ -111,15 +128,23 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 		UserData:            &userData,
 		MaxCount:            aws.Int64(1),
 		MinCount:            aws.Int64(1),
-		IamInstanceProfile:  &ec2.IamInstanceProfileSpecification{Name: &s.IamInstanceProfile},
+		IamInstanceProfile:  &ec2.IamInstanceProfileSpecification{Name: &iamInstanceProfile},
 		BlockDeviceMappings: s.BlockDevices.BuildLaunchDevices(),
 		Placement:           &ec2.Placement{AvailabilityZone: &s.AvailabilityZone},
 		EbsOptimized:        &s.EbsOptimized,
 	}
 
-	if s.EnableT2Unlimited {
-		creditOption := "unlimited"
-		runOpts.CreditSpecification = &ec2.CreditSpecificationRequest{CpuCredits: &creditOption}
+	if s.Tenancy != "" {
+		runOpts.Placement.Tenancy = &s.Tenancy
+	}
+	if s.HostId != "" {
+		runOpts.Placement.HostId = &s.HostId
+	} else if hostId, ok := state.GetOk("hostId"); ok {
+		runOpts.Placement.HostId = aws.String(hostId.(string))
+	}
+
+	if s.CPUCredits != "" {
+		runOpts.CreditSpecification = &ec2.CreditSpecificationRequest{CpuCredits: &s.CPUCredits}
 	}
 
 	// Collect tags for tagging on resource creation
@@ -154,7 +179,15 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 		runOpts.KeyName = &keyName
 	}
 
-	if s.SubnetId != "" && s.AssociatePublicIpAddress {
+	if s.NetworkInterfaceId != "" {
+		// The network interface outlives the instance, as the user created it
+		runOpts.NetworkInterfaces = []*ec2.InstanceNetworkInterfaceSpecification{
+			{
+				DeviceIndex:        aws.Int64(0),
+				NetworkInterfaceId: &s.NetworkInterfaceId,
+			},
+		}
+	} else if s.SubnetId != "" && (s.AssociatePublicIpAddress || s.Ipv6AddressCount > 0) {
 		runOpts.NetworkInterfaces = []*ec2.InstanceNetworkInterfaceSpecification{
 			{
 				DeviceIndex:              aws.Int64(0),
@@ -164,6 +197,9 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 				DeleteOnTermination:      aws.Bool(true),
 			},
 		}
+		if s.Ipv6AddressCount > 0 {
+			runOpts.NetworkInterfaces[0].Ipv6AddressCount = &s.Ipv6AddressCount
+		}
 	} else {
 		runOpts.SubnetId = &s.SubnetId
 		runOpts.SecurityGroupIds = securityGroupIds
@@ -173,7 +209,20 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 		runOpts.InstanceInitiatedShutdownBehavior = &s.InstanceInitiatedShutdownBehavior
 	}
 
-	runResp, err := ec2conn.RunInstances(runOpts)
+	runReq, runResp := ec2conn.RunInstancesRequest(runOpts)
+	if s.EnableHibernation {
+		runReq.Handlers.Build.PushBackNamed(request.NamedHandler{
+			Name: "packer.HibernationOptions",
+			Fn:   queryParamHandler("HibernationOptions.Configured", "true"),
+		})
+	}
+	if s.HostResourceGroupArn != "" {
+		runReq.Handlers.Build.PushBackNamed(request.NamedHandler{
+			Name: "packer.HostResourceGroupArn",
+			Fn:   queryParamHandler("Placement.HostResourceGroupArn", s.HostResourceGroupArn),
+		})
+	}
+	err = runReq.Send()
 	if err != nil {
 		err := fmt.Errorf("Error launching source instance: %s", err)
 		state.Put("error", err)
@@ -184,6 +233,9 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 
 	// Set the instance ID so that the cleanup works properly
 	s.instanceId = instanceId
+	if !s.KeepInstance {
+		ReportTemporaryResource(ui, ec2conn, true, OrphanInstance, instanceId)
+	}
 
 	ui.Message(fmt.Sprintf("Instance ID: %s", instanceId))
 	ui.Say(fmt.Sprintf("Waiting for instance (%v) to become ready...", instanceId))
@@ -223,6 +275,12 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 	}
 
 	state.Put("instance", instance)
+	state.Put("machine", instanceMachine(ec2conn, instance))
+
+	if instance.Placement != nil && instance.Placement.HostId != nil {
+		s.hostId = *instance.Placement.HostId
+		ui.Message(fmt.Sprintf("Dedicated host: %s", s.hostId))
+	}
 
 	// If we're in a region that doesn't support tagging on instance creation,
 	// do that now.
@@ -291,11 +349,97 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 	return multistep.ActionContinue
 }
 
+// startInstance starts the existing instance the build runs on, which
+// resumes it if it was hibernated.
+func (s *StepRunSourceInstance) startInstance(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say(fmt.Sprintf("Starting the source AWS instance (%s)...", s.SourceInstanceId))
+	if _, err := ec2conn.StartInstances(&ec2.StartInstancesInput{
+		InstanceIds: []*string{&s.SourceInstanceId},
+	}); err != nil {
+		err := fmt.Errorf("Error starting source instance: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Set the instance ID so that the cleanup stops it again
+	s.instanceId = s.SourceInstanceId
+
+	describeInstance := &ec2.DescribeInstancesInput{
+		InstanceIds: []*string{&s.SourceInstanceId},
+	}
+	if err := ec2conn.WaitUntilInstanceRunningWithContext(ctx, describeInstance); err != nil {
+		err := fmt.Errorf("Error waiting for instance (%s) to become ready: %s", s.SourceInstanceId, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	r, err := ec2conn.DescribeInstances(describeInstance)
+	if err != nil || len(r.Reservations) == 0 || len(r.Reservations[0].Instances) == 0 {
+		err := fmt.Errorf("Error finding source instance.")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("instance", r.Reservations[0].Instances[0])
+	state.Put("machine", instanceMachine(ec2conn, r.Reservations[0].Instances[0]))
+	return multistep.ActionContinue
+}
+
+// stopInstance stops the instance rather than terminating it, hibernating
+// it when it was launched with hibernation, so the next build can reuse it.
+func (s *StepRunSourceInstance) stopInstance(ec2conn *ec2.EC2, ui packer.Ui) {
+	kept := fmt.Sprintf("Kept instance %s, set source_instance_id to reuse it.", s.instanceId)
+
+	// The instance is already stopped once the AMI is created from it
+	if _, state, err := InstanceStateRefreshFunc(ec2conn, s.instanceId)(); err == nil && state == "stopped" {
+		ui.Message(kept)
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Stopping the source AWS instance (%s) to keep it...", s.instanceId))
+	req, _ := ec2conn.StopInstancesRequest(&ec2.StopInstancesInput{
+		InstanceIds: []*string{&s.instanceId},
+	})
+	if s.EnableHibernation {
+		req.Handlers.Build.PushBackNamed(request.NamedHandler{
+			Name: "packer.Hibernate",
+			Fn:   queryParamHandler("Hibernate", "true"),
+		})
+	}
+	if err := req.Send(); err != nil {
+		ui.Error(fmt.Sprintf("Error stopping instance, it may still be running: %s", err))
+		return
+	}
+
+	stateChange := StateChangeConf{
+		Pending: []string{"pending", "running", "stopping"},
+		Refresh: InstanceStateRefreshFunc(ec2conn, s.instanceId),
+		Target:  "stopped",
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	ui.Message(kept)
+}
+
 func (s *StepRunSourceInstance) Cleanup(state multistep.StateBag) {
 
 	ec2conn := state.Get("ec2").(*ec2.EC2)
 	ui := state.Get("ui").(packer.Ui)
 
+	// An instance given by its ID isn't ours to terminate
+	if s.instanceId != "" && (s.KeepInstance || s.SourceInstanceId != "") {
+		s.stopInstance(ec2conn, ui)
+		return
+	}
+
 	// Terminate the source instance if it exists
 	if s.instanceId != "" {
 		ui.Say("Terminating the source AWS instance...")
@@ -312,6 +456,30 @@ func (s *StepRunSourceInstance) Cleanup(state multistep.StateBag) {
 		_, err := WaitForState(&stateChange)
 		if err != nil {
 			ui.Error(err.Error())
+		} else {
+			ReportTemporaryResource(ui, ec2conn, false, OrphanInstance, s.instanceId)
+		}
+
+		// A host given by the template was allocated beforehand, and can
+		// take a while to be available again, for instance hosts of mac
+		// instances are scrubbed once their instance terminates. Hosts the
+		// build allocated are released by StepAllocateHost.
+		if _, allocated := state.GetOk("hostId"); s.hostId != "" && !allocated {
+			ui.Message(fmt.Sprintf("Dedicated host %s is left allocated.", s.hostId))
 		}
 	}
 }
+
+// instanceMachine identifies the instance to the provisioners reaching it
+// through SSM.
+func instanceMachine(ec2conn *ec2.EC2, instance *ec2.Instance) *packer.Machine {
+	machine := &packer.Machine{
+		Cloud:  "amazon",
+		ID:     aws.StringValue(instance.InstanceId),
+		Region: aws.StringValue(ec2conn.Config.Region),
+	}
+	if instance.Placement != nil {
+		machine.Zone = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+	return machine
+}
@@ -26,14 +26,16 @@ type StepRunSpotInstance struct {
 	Debug                             bool
 	EbsOptimized                      bool
 	ExpectedRootDevice                string
-	IamInstanceProfile                string
 	InstanceInitiatedShutdownBehavior string
 	InstanceType                      string
+	Ipv6AddressCount                  int64
+	NetworkInterfaceId                string
 	SourceAMI                         string
 	SpotPrice                         string
 	SpotPriceProduct                  string
 	SubnetId                          string
 	Tags                              TagMap
+	Tenancy                           string
 	VolumeTags                        TagMap
 	UserData                          string
 	UserDataFile                      string
@@ -50,6 +52,7 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 		keyName = name.(string)
 	}
 	securityGroupIds := aws.StringSlice(state.Get("securityGroupIds").([]string))
+	iamInstanceProfile := state.Get("iamInstanceProfile").(string)
 	ui := state.Get("ui").(packer.Ui)
 
 	userData := s.UserData
@@ -150,6 +153,7 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
+	ec2Tags = append(ec2Tags, temporaryResourceTag())
 	ec2Tags.Report(ui)
 
 	ui.Message(fmt.Sprintf(
@@ -160,7 +164,7 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 		ImageId:            &s.SourceAMI,
 		InstanceType:       &s.InstanceType,
 		UserData:           &userData,
-		IamInstanceProfile: &ec2.IamInstanceProfileSpecification{Name: &s.IamInstanceProfile},
+		IamInstanceProfile: &ec2.IamInstanceProfileSpecification{Name: &iamInstanceProfile},
 		Placement: &ec2.SpotPlacement{
 			AvailabilityZone: &availabilityZone,
 		},
@@ -168,7 +172,19 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 		EbsOptimized:        &s.EbsOptimized,
 	}
 
-	if s.SubnetId != "" && s.AssociatePublicIpAddress {
+	if s.Tenancy != "" {
+		runOpts.Placement.Tenancy = &s.Tenancy
+	}
+
+	if s.NetworkInterfaceId != "" {
+		// The network interface outlives the instance, as the user created it
+		runOpts.NetworkInterfaces = []*ec2.InstanceNetworkInterfaceSpecification{
+			{
+				DeviceIndex:        aws.Int64(0),
+				NetworkInterfaceId: &s.NetworkInterfaceId,
+			},
+		}
+	} else if s.SubnetId != "" && (s.AssociatePublicIpAddress || s.Ipv6AddressCount > 0) {
 		runOpts.NetworkInterfaces = []*ec2.InstanceNetworkInterfaceSpecification{
 			{
 				DeviceIndex:              aws.Int64(0),
@@ -178,6 +194,9 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 				DeleteOnTermination:      aws.Bool(true),
 			},
 		}
+		if s.Ipv6AddressCount > 0 {
+			runOpts.NetworkInterfaces[0].Ipv6AddressCount = &s.Ipv6AddressCount
+		}
 	} else {
 		runOpts.SubnetId = &s.SubnetId
 		runOpts.SecurityGroupIds = securityGroupIds
@@ -199,8 +218,20 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 	}
 
 	s.spotRequest = runSpotResp.SpotInstanceRequests[0]
+	ReportTemporaryResource(ui, ec2conn, true, OrphanSpotRequest, *s.spotRequest.SpotInstanceRequestId)
 
 	spotRequestId := s.spotRequest.SpotInstanceRequestId
+	_, err = ec2conn.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{spotRequestId},
+		Tags:      []*ec2.Tag{temporaryResourceTag()},
+	})
+	if err != nil {
+		err := fmt.Errorf("Error tagging spot request (%s): %s", *spotRequestId, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	ui.Message(fmt.Sprintf("Waiting for spot request (%s) to become active...", *spotRequestId))
 	stateChange := StateChangeConf{
 		Pending:   []string{"open"},
@@ -229,6 +260,7 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 
 	// Set the instance ID so that the cleanup works properly
 	s.instanceId = instanceId
+	ReportTemporaryResource(ui, ec2conn, true, OrphanInstance, instanceId)
 
 	ui.Message(fmt.Sprintf("Instance ID: %s", instanceId))
 	ui.Say(fmt.Sprintf("Waiting for instance (%v) to become ready...", instanceId))
@@ -325,6 +357,7 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 	}
 
 	state.Put("instance", instance)
+	state.Put("machine", instanceMachine(ec2conn, instance))
 
 	return multistep.ActionContinue
 }
@@ -353,6 +386,8 @@ func (s *StepRunSpotInstance) Cleanup(state multistep.StateBag) {
 		_, err := WaitForState(&stateChange)
 		if err != nil {
 			ui.Error(err.Error())
+		} else {
+			ReportTemporaryResource(ui, ec2conn, false, OrphanSpotRequest, *s.spotRequest.SpotInstanceRequestId)
 		}
 
 	}
@@ -373,6 +408,8 @@ func (s *StepRunSpotInstance) Cleanup(state multistep.StateBag) {
 		_, err := WaitForState(&stateChange)
 		if err != nil {
 			ui.Error(err.Error())
+		} else {
+			ReportTemporaryResource(ui, ec2conn, false, OrphanInstance, s.instanceId)
 		}
 	}
 }
@@ -3,23 +3,37 @@ package common
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/packer/common/uuid"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
 
+// publicIPURL is the service the public IP of the host running Packer is
+// looked up from, when the temporary security group is only open to it.
+var publicIPURL = "https://checkip.amazonaws.com"
+
 type StepSecurityGroup struct {
-	CommConfig            *communicator.Config
-	SecurityGroupIds      []string
-	VpcId                 string
-	TemporarySGSourceCidr string
+	CommConfig                *communicator.Config
+	NetworkInterfaceId        string
+	SecurityGroupIds          []string
+	SubnetId                  string
+	VpcId                     string
+	TemporarySGSourceCidrs    []string
+	TemporarySGSourceGroupId  string
+	TemporarySGSourcePublicIp bool
 
 	createdGroupId string
 }
@@ -28,6 +42,12 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 	ec2conn := state.Get("ec2").(*ec2.EC2)
 	ui := state.Get("ui").(packer.Ui)
 
+	if s.NetworkInterfaceId != "" {
+		log.Printf("Using the security groups of network interface %s", s.NetworkInterfaceId)
+		state.Put("securityGroupIds", []string{})
+		return multistep.ActionContinue
+	}
+
 	if len(s.SecurityGroupIds) > 0 {
 		_, err := ec2conn.DescribeSecurityGroups(
 			&ec2.DescribeSecurityGroupsInput{
@@ -52,6 +72,60 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 		}
 	}
 
+	// Work out who the group lets in before creating it
+	sources := s.TemporarySGSourceCidrs
+	if s.TemporarySGSourcePublicIp {
+		cidr, err := publicIPCidr()
+		if err != nil {
+			err := fmt.Errorf("Error detecting the public IP of this host: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		sources = []string{cidr}
+	}
+
+	// An instance of an IPv6-only subnet has no IPv4 address, so a group
+	// open to every IPv4 source is also open to every IPv6 one
+	if s.SubnetId != "" && openToEveryIPv4Source(sources) {
+		resp, err := ec2conn.DescribeSubnets(&ec2.DescribeSubnetsInput{
+			SubnetIds: []*string{aws.String(s.SubnetId)},
+		})
+		if err != nil {
+			err := fmt.Errorf("Error describing subnet %s: %s", s.SubnetId, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if len(resp.Subnets) > 0 && isIPv6OnlySubnet(resp.Subnets[0]) {
+			log.Printf("Subnet %s is IPv6-only, authorizing ::/0", s.SubnetId)
+			sources = append(sources, "::/0")
+		}
+	}
+
+	permission := &ec2.IpPermission{
+		IpProtocol: aws.String("tcp"),
+		FromPort:   aws.Int64(int64(port)),
+		ToPort:     aws.Int64(int64(port)),
+	}
+	for _, cidr := range sources {
+		if strings.Contains(cidr, ":") {
+			permission.Ipv6Ranges = append(permission.Ipv6Ranges, &ec2.Ipv6Range{
+				CidrIpv6: aws.String(cidr),
+			})
+			continue
+		}
+		permission.IpRanges = append(permission.IpRanges, &ec2.IpRange{
+			CidrIp: aws.String(cidr),
+		})
+	}
+	if s.TemporarySGSourceGroupId != "" {
+		permission.UserIdGroupPairs = []*ec2.UserIdGroupPair{{
+			GroupId: aws.String(s.TemporarySGSourceGroupId),
+		}}
+		sources = append(sources, s.TemporarySGSourceGroupId)
+	}
+
 	// Create the group
 	groupName := fmt.Sprintf("packer_%s", uuid.TimeOrderedUUID())
 	ui.Say(fmt.Sprintf("Creating temporary security group for this instance: %s", groupName))
@@ -73,14 +147,12 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 
 	// Set the group ID so we can delete it later
 	s.createdGroupId = *groupResp.GroupId
+	ReportTemporaryResource(ui, ec2conn, true, OrphanSecurityGroup, s.createdGroupId)
 
 	// Authorize the SSH access for the security group
 	req := &ec2.AuthorizeSecurityGroupIngressInput{
-		GroupId:    groupResp.GroupId,
-		IpProtocol: aws.String("tcp"),
-		FromPort:   aws.Int64(int64(port)),
-		ToPort:     aws.Int64(int64(port)),
-		CidrIp:     aws.String(s.TemporarySGSourceCidr),
+		GroupId:       groupResp.GroupId,
+		IpPermissions: []*ec2.IpPermission{permission},
 	}
 
 	// We loop and retry this a few times because sometimes the security
@@ -88,7 +160,7 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 	// consistent.
 	ui.Say(fmt.Sprintf(
 		"Authorizing access to port %d from %s in the temporary security group...",
-		port, s.TemporarySGSourceCidr))
+		port, strings.Join(sources, ", ")))
 	for i := 0; i < 5; i++ {
 		_, err = ec2conn.AuthorizeSecurityGroupIngress(req)
 		if err == nil {
@@ -151,7 +223,57 @@ func (s *StepSecurityGroup) Cleanup(state multistep.StateBag) {
 	if err != nil {
 		ui.Error(fmt.Sprintf(
 			"Error cleaning up security group. Please delete the group manually: %s", s.createdGroupId))
+	} else {
+		ReportTemporaryResource(ui, ec2conn, false, OrphanSecurityGroup, s.createdGroupId)
+	}
+}
+
+// openToEveryIPv4Source tells whether the sources are open to every IPv4
+// address, but to no IPv6 one.
+func openToEveryIPv4Source(sources []string) bool {
+	everyone := false
+	for _, cidr := range sources {
+		if strings.Contains(cidr, ":") {
+			return false
+		}
+		if cidr == "0.0.0.0/0" {
+			everyone = true
+		}
 	}
+	return everyone
+}
+
+// isIPv6OnlySubnet tells whether the instances of the subnet only have IPv6
+// addresses.
+func isIPv6OnlySubnet(subnet *ec2.Subnet) bool {
+	return aws.StringValue(subnet.CidrBlock) == "" && len(subnet.Ipv6CidrBlockAssociationSet) > 0
+}
+
+// publicIPCidr returns the /32 CIDR block of the public IP the host running
+// Packer reaches AWS from.
+func publicIPCidr() (string, error) {
+	client := cleanhttp.DefaultClient()
+	client.Timeout = 30 * time.Second
+	resp, err := client.Get(publicIPURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status from %s: %s", publicIPURL, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil || ip.To4() == nil {
+		return "", fmt.Errorf("%s didn't return an IPv4 address: %q", publicIPURL, body)
+	}
+
+	return fmt.Sprintf("%s/32", ip), nil
 }
 
 func waitUntilSecurityGroupExists(c *ec2.EC2, input *ec2.DescribeSecurityGroupsInput) error {
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestPublicIPCidr(t *testing.T) {
+	ip := "203.0.113.7\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ip)
+	}))
+	defer server.Close()
+
+	defer func(url string) { publicIPURL = url }(publicIPURL)
+	publicIPURL = server.URL
+
+	cidr, err := publicIPCidr()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if cidr != "203.0.113.7/32" {
+		t.Fatalf("bad: %s", cidr)
+	}
+
+	ip = "<html>rate limited</html>"
+	if _, err := publicIPCidr(); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestOpenToEveryIPv4Source(t *testing.T) {
+	cases := map[string]bool{
+		"0.0.0.0/0":               true,
+		"10.0.0.0/8,0.0.0.0/0":    true,
+		"10.0.0.0/8":              false,
+		"0.0.0.0/0,::/0":          false,
+		"0.0.0.0/0,2001:db8::/32": false,
+	}
+	for sources, expected := range cases {
+		if actual := openToEveryIPv4Source(strings.Split(sources, ",")); actual != expected {
+			t.Fatalf("bad: %s: %t", sources, actual)
+		}
+	}
+}
+
+func TestIsIPv6OnlySubnet(t *testing.T) {
+	ipv6 := []*ec2.SubnetIpv6CidrBlockAssociation{{
+		Ipv6CidrBlock: aws.String("2001:db8::/64"),
+	}}
+
+	// Good
+	if !isIPv6OnlySubnet(&ec2.Subnet{Ipv6CidrBlockAssociationSet: ipv6}) {
+		t.Fatal("should be IPv6-only")
+	}
+
+	// Bad
+	if isIPv6OnlySubnet(&ec2.Subnet{CidrBlock: aws.String("10.0.0.0/24"), Ipv6CidrBlockAssociationSet: ipv6}) {
+		t.Fatal("dual-stack subnet should not be IPv6-only")
+	}
+	if isIPv6OnlySubnet(&ec2.Subnet{CidrBlock: aws.String("10.0.0.0/24")}) {
+		t.Fatal("IPv4 subnet should not be IPv6-only")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -19,6 +20,7 @@ import (
 //   source_image *ec2.Image - the source AMI info
 type StepSourceAMIInfo struct {
 	SourceAmi                string
+	SourceInstanceId         string
 	EnableAMISriovNetSupport bool
 	EnableAMIENASupport      bool
 	AmiFilters               AmiFilterOptions
@@ -59,14 +61,45 @@ func (s *StepSourceAMIInfo) Run(_ context.Context, state multistep.StateBag) mul
 
 	params := &ec2.DescribeImagesInput{}
 
-	if s.SourceAmi != "" {
-		params.ImageIds = []*string{&s.SourceAmi}
+	sourceAmi := s.SourceAmi
+	if s.SourceInstanceId != "" {
+		// The source AMI of a reused instance is the one it was launched from
+		resp, err := ec2conn.DescribeInstances(&ec2.DescribeInstancesInput{
+			InstanceIds: []*string{&s.SourceInstanceId},
+		})
+		if err == nil && (len(resp.Reservations) == 0 || len(resp.Reservations[0].Instances) == 0) {
+			err = fmt.Errorf("instance not found")
+		}
+		if err != nil {
+			err := fmt.Errorf("Error querying source instance %s: %s", s.SourceInstanceId, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		sourceAmi = aws.StringValue(resp.Reservations[0].Instances[0].ImageId)
+		log.Printf("Source instance %s was launched from %s", s.SourceInstanceId, sourceAmi)
+	}
+
+	if sourceAmi != "" {
+		params.ImageIds = []*string{&sourceAmi}
 	}
 
 	// We have filters to apply
 	if len(s.AmiFilters.Filters) > 0 {
 		params.Filters = buildAmiFilters(s.AmiFilters.Filters)
 	}
+	if s.AmiFilters.Architecture != "" {
+		params.Filters = append(params.Filters, &ec2.Filter{
+			Name:   aws.String("architecture"),
+			Values: []*string{&s.AmiFilters.Architecture},
+		})
+	}
+	if s.AmiFilters.VirtualizationType != "" {
+		params.Filters = append(params.Filters, &ec2.Filter{
+			Name:   aws.String("virtualization-type"),
+			Values: []*string{&s.AmiFilters.VirtualizationType},
+		})
+	}
 	if len(s.AmiFilters.Owners) > 0 {
 		params.Owners = s.AmiFilters.Owners
 	}
@@ -101,12 +134,17 @@ func (s *StepSourceAMIInfo) Run(_ context.Context, state multistep.StateBag) mul
 		image = imageResp.Images[0]
 	}
 
-	ui.Message(fmt.Sprintf("Found Image ID: %s", *image.ImageId))
+	if image.CreationDate != nil {
+		ui.Message(fmt.Sprintf("Found Image ID: %s (%s, created %s)",
+			*image.ImageId, aws.StringValue(image.Name), *image.CreationDate))
+	} else {
+		ui.Message(fmt.Sprintf("Found Image ID: %s", *image.ImageId))
+	}
 
 	// Enhanced Networking can only be enabled on HVM AMIs.
 	// See http://goo.gl/icuXh5
 	if (s.EnableAMIENASupport || s.EnableAMISriovNetSupport) && *image.VirtualizationType != "hvm" {
-		err := fmt.Errorf("Cannot enable enhanced networking, source AMI '%s' is not HVM", s.SourceAmi)
+		err := fmt.Errorf("Cannot enable enhanced networking, source AMI '%s' is not HVM", *image.ImageId)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
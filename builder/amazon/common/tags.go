@@ -44,3 +44,12 @@ func (t TagMap) EC2Tags(ctx interpolate.Context, region string, state multistep.
 	}
 	return ec2Tags, nil
 }
+
+// TemporaryResourceTag marks the resources Packer creates for the time of a
+// build and deletes once it is done, so that the ones a build that crashed
+// left behind can be found with FindOrphans.
+const TemporaryResourceTag = "packer:temporary"
+
+func temporaryResourceTag() *ec2.Tag {
+	return &ec2.Tag{Key: aws.String(TemporaryResourceTag), Value: aws.String("true")}
+}
@@ -0,0 +1,259 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/mitchellh/go-homedir"
+)
+
+// VaultProviderName is the name reported by the credentials provider used
+// when `vault_aws_engine` is configured.
+const VaultProviderName = "PackerVaultProvider"
+
+const (
+	defaultVaultAddress = "https://127.0.0.1:8200"
+
+	// Access keys of IAM users created by Vault aren't accepted by AWS
+	// right away.
+	vaultIAMUserPropagationDelay = 10 * time.Second
+)
+
+// VaultAWSEngineConfig describes a role of the Vault AWS secrets engine
+// that the AWS credentials are read from. The address of Vault and the
+// token used to authenticate are read from the usual VAULT_ADDR,
+// VAULT_TOKEN and VAULT_NAMESPACE environment variables.
+type VaultAWSEngineConfig struct {
+	Name       string        `mapstructure:"name"`
+	EngineName string        `mapstructure:"engine_name"`
+	RoleARN    string        `mapstructure:"role_arn"`
+	TTL        time.Duration `mapstructure:"ttl"`
+}
+
+// Enabled reports whether credentials are to be read from Vault.
+func (c *VaultAWSEngineConfig) Enabled() bool {
+	return c.Name != ""
+}
+
+func (c *VaultAWSEngineConfig) Prepare() []error {
+	var errs []error
+
+	if !c.Enabled() {
+		if c.EngineName != "" || c.RoleARN != "" || c.TTL != 0 {
+			errs = append(errs, fmt.Errorf("vault_aws_engine: `name` must be set"))
+		}
+		return errs
+	}
+
+	if c.EngineName == "" {
+		c.EngineName = "aws"
+	}
+	c.EngineName = strings.Trim(c.EngineName, "/")
+
+	if c.TTL < 0 {
+		errs = append(errs, fmt.Errorf("vault_aws_engine: `ttl` can't be negative"))
+	}
+
+	return errs
+}
+
+// vaultSecret is the part of a Vault response for a secret that matters
+// to us.
+type vaultSecret struct {
+	LeaseID       string `json:"lease_id"`
+	LeaseDuration int64  `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+	Data          struct {
+		AccessKey     string `json:"access_key"`
+		SecretKey     string `json:"secret_key"`
+		SecurityToken string `json:"security_token"`
+	} `json:"data"`
+}
+
+// vaultProvider is a credentials.Provider that reads credentials from the
+// Vault AWS secrets engine. Because it embeds credentials.Expiry the SDK
+// calls Retrieve again shortly before the lease runs out. Renewable
+// leases, those of IAM users, are then renewed, and new credentials are
+// generated for the others, so builds outliving the lease keep working.
+type vaultProvider struct {
+	credentials.Expiry
+
+	config *VaultAWSEngineConfig
+	secret *vaultSecret
+
+	// These are only overridden by tests.
+	address          string
+	token            string
+	namespace        string
+	client           *http.Client
+	now              func() time.Time
+	propagationDelay time.Duration
+}
+
+func newVaultProvider(config *VaultAWSEngineConfig) (*vaultProvider, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		address = defaultVaultAddress
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		// This is where `vault login` stores the token.
+		path, err := homedir.Expand("~/.vault-token")
+		if err != nil {
+			return nil, err
+		}
+		raw, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("Error reading Vault token from %s: %s", path, err)
+		}
+		token = strings.TrimSpace(string(raw))
+	}
+	if token == "" {
+		return nil, fmt.Errorf(
+			"No Vault token found to read AWS credentials with. " +
+				"Set VAULT_TOKEN or log in with `vault login`.")
+	}
+
+	return &vaultProvider{
+		config:           config,
+		address:          strings.TrimRight(address, "/"),
+		token:            token,
+		namespace:        os.Getenv("VAULT_NAMESPACE"),
+		client:           cleanhttp.DefaultClient(),
+		now:              time.Now,
+		propagationDelay: vaultIAMUserPropagationDelay,
+	}, nil
+}
+
+func (p *vaultProvider) Retrieve() (credentials.Value, error) {
+	v := credentials.Value{ProviderName: VaultProviderName}
+
+	if p.secret != nil && p.secret.Renewable {
+		err := p.renew()
+		if err == nil {
+			return p.value(), nil
+		}
+		log.Printf("[WARN] Renewing Vault lease %s failed, generating new credentials: %s",
+			p.secret.LeaseID, err)
+	}
+
+	path := fmt.Sprintf("/v1/%s/creds/%s", p.config.EngineName, p.config.Name)
+	method, params := "GET", map[string]interface{}{}
+	if p.config.RoleARN != "" {
+		params["role_arn"] = p.config.RoleARN
+	}
+	if p.config.TTL != 0 {
+		params["ttl"] = fmt.Sprintf("%ds", int64(p.config.TTL/time.Second))
+	}
+	if len(params) > 0 {
+		method = "POST"
+	}
+
+	var secret vaultSecret
+	if err := p.do(method, path, params, &secret); err != nil {
+		return v, fmt.Errorf("Error reading AWS credentials from Vault at %s: %s", path, err)
+	}
+	p.secret = &secret
+	log.Printf("[INFO] Read AWS credentials from Vault at %s, lease %s expires at %s",
+		path, secret.LeaseID, p.setExpiration())
+
+	if secret.Data.SecurityToken == "" && p.propagationDelay > 0 {
+		log.Printf("[INFO] Waiting %s for the IAM user created by Vault to be usable", p.propagationDelay)
+		time.Sleep(p.propagationDelay)
+	}
+
+	return p.value(), nil
+}
+
+// renew extends the lease of the current credentials. It fails if Vault
+// didn't extend the lease past the renewal window, typically because the
+// lease reached its maximum TTL.
+func (p *vaultProvider) renew() error {
+	params := map[string]interface{}{"lease_id": p.secret.LeaseID}
+	if p.config.TTL != 0 {
+		params["increment"] = int64(p.config.TTL / time.Second)
+	}
+
+	var out vaultSecret
+	if err := p.do("PUT", "/v1/sys/leases/renew", params, &out); err != nil {
+		return err
+	}
+	if time.Duration(out.LeaseDuration)*time.Second <= assumeRoleExpiryWindow {
+		return fmt.Errorf("lease was only extended by %ds", out.LeaseDuration)
+	}
+
+	p.secret.LeaseDuration = out.LeaseDuration
+	log.Printf("[INFO] Renewed Vault lease %s until %s", p.secret.LeaseID, p.setExpiration())
+	return nil
+}
+
+// setExpiration sets when the current credentials expire, from the
+// duration of their lease, and returns that time.
+func (p *vaultProvider) setExpiration() time.Time {
+	expiration := p.now().Add(time.Duration(p.secret.LeaseDuration) * time.Second)
+	p.SetExpiration(expiration, assumeRoleExpiryWindow)
+	return expiration
+}
+
+func (p *vaultProvider) value() credentials.Value {
+	return credentials.Value{
+		AccessKeyID:     p.secret.Data.AccessKey,
+		SecretAccessKey: p.secret.Data.SecretKey,
+		SessionToken:    p.secret.Data.SecurityToken,
+		ProviderName:    VaultProviderName,
+	}
+}
+
+func (p *vaultProvider) do(method, path string, params map[string]interface{}, out interface{}) error {
+	var body io.Reader
+	if method != "GET" {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, p.address+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	req.Header.Set("X-Vault-Request", "true")
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(raw, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("%d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, ", "))
+		}
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, raw)
+	}
+
+	return json.Unmarshal(raw, out)
+}
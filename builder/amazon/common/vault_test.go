@@ -0,0 +1,166 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVaultAWSEngineConfigPrepare(t *testing.T) {
+	c := &VaultAWSEngineConfig{}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	c = &VaultAWSEngineConfig{TTL: time.Hour}
+	if errs := c.Prepare(); len(errs) == 0 {
+		t.Fatal("should have error without a name")
+	}
+
+	c = &VaultAWSEngineConfig{Name: "packer", EngineName: "/aws-prod/"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.EngineName != "aws-prod" {
+		t.Fatalf("bad engine name: %s", c.EngineName)
+	}
+
+	c = &VaultAWSEngineConfig{Name: "packer"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.EngineName != "aws" {
+		t.Fatalf("bad engine name: %s", c.EngineName)
+	}
+}
+
+// testVaultServer is a fake Vault AWS secrets engine. Every set of
+// credentials it generates gets a new access key.
+type testVaultServer struct {
+	renewable     bool
+	renewDuration int64
+	generated     int
+	renewed       int
+	requests      []string
+}
+
+func (s *testVaultServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("X-Vault-Token") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors": ["permission denied"]}`))
+		return
+	}
+
+	var params map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&params)
+
+	switch r.URL.Path {
+	case "/v1/aws/creds/packer":
+		s.generated++
+		token := "TOKEN"
+		if s.renewable {
+			token = ""
+		}
+		fmt.Fprintf(w, `{"lease_id": "aws/creds/packer/%d", "lease_duration": 900,
+			"renewable": %t, "data": {"access_key": "AKID%d", "secret_key": "SECRET",
+			"security_token": %q}}`, s.generated, s.renewable, s.generated, token)
+	case "/v1/sys/leases/renew":
+		s.renewed++
+		fmt.Fprintf(w, `{"lease_id": %q, "lease_duration": %d, "renewable": true}`,
+			params["lease_id"], s.renewDuration)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testVaultProvider(s *testVaultServer) (*vaultProvider, func()) {
+	server := httptest.NewServer(s)
+	config := &VaultAWSEngineConfig{Name: "packer"}
+	config.Prepare()
+
+	return &vaultProvider{
+		config:  config,
+		address: server.URL,
+		token:   "token",
+		client:  &http.Client{Transport: &http.Transport{TLSClientConfig: server.TLS}},
+		now:     time.Now,
+	}, server.Close
+}
+
+func TestVaultProvider_Retrieve(t *testing.T) {
+	s := &testVaultServer{}
+	p, cleanup := testVaultProvider(s)
+	defer cleanup()
+	p.config.TTL = 2 * time.Hour
+
+	v, err := p.Retrieve()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.AccessKeyID != "AKID1" || v.SecretAccessKey != "SECRET" || v.SessionToken != "TOKEN" {
+		t.Fatalf("bad: %#v", v)
+	}
+	if s.requests[0] != "POST /v1/aws/creds/packer" {
+		t.Fatalf("bad request: %s", s.requests[0])
+	}
+	if p.IsExpired() {
+		t.Fatal("credentials should not be expired")
+	}
+
+	// Credentials that can't be renewed are generated again
+	v, err = p.Retrieve()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.AccessKeyID != "AKID2" || s.renewed != 0 {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestVaultProvider_RetrieveRenew(t *testing.T) {
+	s := &testVaultServer{renewable: true, renewDuration: 900}
+	p, cleanup := testVaultProvider(s)
+	defer cleanup()
+
+	if _, err := p.Retrieve(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if s.requests[0] != "GET /v1/aws/creds/packer" {
+		t.Fatalf("bad request: %s", s.requests[0])
+	}
+
+	v, err := p.Retrieve()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.AccessKeyID != "AKID1" || s.renewed != 1 {
+		t.Fatalf("lease should have been renewed: %#v", v)
+	}
+
+	// Once the lease can't be extended anymore, new credentials are
+	// generated.
+	s.renewDuration = 30
+	v, err = p.Retrieve()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.AccessKeyID != "AKID2" || s.renewed != 2 {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestVaultProvider_RetrieveError(t *testing.T) {
+	p, cleanup := testVaultProvider(&testVaultServer{})
+	defer cleanup()
+	p.token = "bad"
+
+	_, err := p.Retrieve()
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("bad: %v", err)
+	}
+}
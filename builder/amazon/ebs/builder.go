@@ -128,14 +128,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Debug:                             b.config.PackerDebug,
 			EbsOptimized:                      b.config.EbsOptimized,
 			ExpectedRootDevice:                "ebs",
-			IamInstanceProfile:                b.config.IamInstanceProfile,
 			InstanceInitiatedShutdownBehavior: b.config.InstanceInitiatedShutdownBehavior,
 			InstanceType:                      b.config.InstanceType,
+			Ipv6AddressCount:                  int64(b.config.Ipv6AddressCount),
+			NetworkInterfaceId:                b.config.NetworkInterfaceId,
 			SourceAMI:                         b.config.SourceAmi,
 			SpotPrice:                         b.config.SpotPrice,
 			SpotPriceProduct:                  b.config.SpotPriceAutoProduct,
 			SubnetId:                          b.config.SubnetId,
 			Tags:                              b.config.RunTags,
+			Tenancy:                           b.config.Tenancy,
 			UserData:                          b.config.UserData,
 			UserDataFile:                      b.config.UserDataFile,
 			VolumeTags:                        b.config.VolumeRunTags,
@@ -145,18 +147,25 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			AssociatePublicIpAddress:          b.config.AssociatePublicIpAddress,
 			AvailabilityZone:                  b.config.AvailabilityZone,
 			BlockDevices:                      b.config.BlockDevices,
+			CPUCredits:                        b.config.CPUCredits,
 			Ctx:                               b.config.ctx,
 			Debug:                             b.config.PackerDebug,
 			EbsOptimized:                      b.config.EbsOptimized,
-			EnableT2Unlimited:                 b.config.EnableT2Unlimited,
+			EnableHibernation:                 b.config.EnableHibernation,
 			ExpectedRootDevice:                "ebs",
-			IamInstanceProfile:                b.config.IamInstanceProfile,
+			HostId:                            b.config.HostId,
+			HostResourceGroupArn:              b.config.HostResourceGroupArn,
 			InstanceInitiatedShutdownBehavior: b.config.InstanceInitiatedShutdownBehavior,
 			InstanceType:                      b.config.InstanceType,
+			Ipv6AddressCount:                  int64(b.config.Ipv6AddressCount),
+			NetworkInterfaceId:                b.config.NetworkInterfaceId,
 			IsRestricted:                      b.config.IsChinaCloud() || b.config.IsGovCloud(),
+			KeepInstance:                      b.config.KeepSourceInstance,
 			SourceAMI:                         b.config.SourceAmi,
+			SourceInstanceId:                  b.config.SourceInstanceId,
 			SubnetId:                          b.config.SubnetId,
 			Tags:                              b.config.RunTags,
+			Tenancy:                           b.config.Tenancy,
 			UserData:                          b.config.UserData,
 			UserDataFile:                      b.config.UserDataFile,
 			VolumeTags:                        b.config.VolumeRunTags,
@@ -168,9 +177,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&awscommon.StepPreValidate{
 			DestAmiName:     b.config.AMIName,
 			ForceDeregister: b.config.AMIForceDeregister,
+			SkipCreateAMI:   b.config.AMISkipCreateImage,
 		},
 		&awscommon.StepSourceAMIInfo{
 			SourceAmi:                b.config.SourceAmi,
+			SourceInstanceId:         b.config.SourceInstanceId,
 			EnableAMISriovNetSupport: b.config.AMISriovNetSupport,
 			EnableAMIENASupport:      b.config.AMIENASupport,
 			AmiFilters:               b.config.SourceAmiFilter,
@@ -181,83 +192,116 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			DebugKeyPath:         fmt.Sprintf("ec2_%s.pem", b.config.PackerBuildName),
 			KeyPairName:          b.config.SSHKeyPairName,
 			TemporaryKeyPairName: b.config.TemporaryKeyPairName,
+			TemporaryKeyPairType: b.config.TemporaryKeyPairType,
+			TemporaryKeyPairBits: b.config.TemporaryKeyPairBits,
 			PrivateKeyFile:       b.config.RunConfig.Comm.SSHPrivateKey,
 		},
 		&awscommon.StepSecurityGroup{
-			SecurityGroupIds: b.config.SecurityGroupIds,
-			CommConfig:       &b.config.RunConfig.Comm,
-			VpcId:            b.config.VpcId,
-			TemporarySGSourceCidr: b.config.TemporarySGSourceCidr,
+			SecurityGroupIds:          b.config.SecurityGroupIds,
+			CommConfig:                &b.config.RunConfig.Comm,
+			SubnetId:                  b.config.SubnetId,
+			VpcId:                     b.config.VpcId,
+			NetworkInterfaceId:        b.config.NetworkInterfaceId,
+			TemporarySGSourceCidrs:    b.config.TemporarySGSourceCidrs,
+			TemporarySGSourceGroupId:  b.config.TemporarySGSourceGroupId,
+			TemporarySGSourcePublicIp: b.config.TemporarySGSourcePublicIp,
+		},
+		&awscommon.StepIamInstanceProfile{
+			IamInstanceProfile:                        b.config.IamInstanceProfile,
+			TemporaryIamInstanceProfilePolicyDocument: b.config.TemporaryIamInstanceProfilePolicyDocument,
 		},
 		&stepCleanupVolumes{
 			BlockDevices: b.config.BlockDevices,
 		},
+		&awscommon.StepAllocateHost{
+			AllocateHost:     b.config.AllocateHost,
+			AvailabilityZone: b.config.AvailabilityZone,
+			Ctx:              b.config.ctx,
+			InstanceType:     b.config.InstanceType,
+			KeepHost:         b.config.KeepHost,
+			SubnetId:         b.config.SubnetId,
+			Tags:             b.config.RunTags,
+		},
 		instanceStep,
+		&awscommon.StepCloudWatchLogs{
+			Enable:        b.config.CloudWatchLogs,
+			LogGroup:      b.config.CloudWatchLogsGroup,
+			RetentionDays: b.config.CloudWatchLogsRetentionDays,
+			BuildName:     b.config.PackerBuildName,
+		},
 		&awscommon.StepGetPassword{
-			Debug:     b.config.PackerDebug,
-			Comm:      &b.config.RunConfig.Comm,
-			Timeout:   b.config.WindowsPasswordTimeout,
-			BuildName: b.config.PackerBuildName,
+			Debug:          b.config.PackerDebug,
+			Comm:           &b.config.RunConfig.Comm,
+			Timeout:        b.config.WindowsPasswordTimeout,
+			BuildName:      b.config.PackerBuildName,
+			PrivateKeyFile: b.config.WindowsPasswordPrivateKeyFile,
 		},
 		&communicator.StepConnect{
 			Config: &b.config.RunConfig.Comm,
 			Host: awscommon.SSHHost(
 				ec2conn,
-				b.config.SSHInterface),
+				b.config.SSHInterface,
+				b.config.TemporaryAddressFamily),
 			SSHConfig: awscommon.SSHConfig(
 				b.config.RunConfig.Comm.SSHAgentAuth,
 				b.config.RunConfig.Comm.SSHUsername,
 				b.config.RunConfig.Comm.SSHPassword),
 		},
 		&common.StepProvision{},
-		&awscommon.StepStopEBSBackedInstance{
-			Skip:                b.config.IsSpotInstance(),
-			DisableStopInstance: b.config.DisableStopInstance,
-		},
-		&awscommon.StepModifyEBSBackedInstance{
-			EnableAMISriovNetSupport: b.config.AMISriovNetSupport,
-			EnableAMIENASupport:      b.config.AMIENASupport,
-		},
-		&awscommon.StepDeregisterAMI{
-			AccessConfig:        &b.config.AccessConfig,
-			ForceDeregister:     b.config.AMIForceDeregister,
-			ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
-			AMIName:             b.config.AMIName,
-			Regions:             b.config.AMIRegions,
-		},
-		&stepCreateAMI{},
-		&awscommon.StepCreateEncryptedAMICopy{
-			KeyID:             b.config.AMIKmsKeyId,
-			EncryptBootVolume: b.config.AMIEncryptBootVolume,
-			Name:              b.config.AMIName,
-			AMIMappings:       b.config.AMIBlockDevices.AMIMappings,
-		},
-		&awscommon.StepAMIRegionCopy{
-			AccessConfig:      &b.config.AccessConfig,
-			Regions:           b.config.AMIRegions,
-			RegionKeyIds:      b.config.AMIRegionKMSKeyIDs,
-			EncryptBootVolume: b.config.AMIEncryptBootVolume,
-			Name:              b.config.AMIName,
-		},
-		&awscommon.StepModifyAMIAttributes{
-			Description:    b.config.AMIDescription,
-			Users:          b.config.AMIUsers,
-			Groups:         b.config.AMIGroups,
-			ProductCodes:   b.config.AMIProductCodes,
-			SnapshotUsers:  b.config.SnapshotUsers,
-			SnapshotGroups: b.config.SnapshotGroups,
-			Ctx:            b.config.ctx,
-		},
-		&awscommon.StepCreateTags{
-			Tags:         b.config.AMITags,
-			SnapshotTags: b.config.SnapshotTags,
-			Ctx:          b.config.ctx,
-		},
+	}
+
+	if !b.config.AMISkipCreateImage {
+		steps = append(steps,
+			&awscommon.StepStopEBSBackedInstance{
+				Skip:                b.config.IsSpotInstance(),
+				DisableStopInstance: b.config.DisableStopInstance,
+			},
+			&awscommon.StepModifyEBSBackedInstance{
+				EnableAMISriovNetSupport: b.config.AMISriovNetSupport,
+				EnableAMIENASupport:      b.config.AMIENASupport,
+			},
+			&awscommon.StepDeregisterAMI{
+				AccessConfig:        &b.config.AccessConfig,
+				ForceDeregister:     b.config.AMIForceDeregister,
+				ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
+				AMIName:             b.config.AMIName,
+				Regions:             b.config.AMIRegions,
+			},
+			&stepCreateAMI{},
+			&awscommon.StepCreateEncryptedAMICopy{
+				KeyID:             b.config.AMIKmsKeyId,
+				EncryptBootVolume: b.config.AMIEncryptBootVolume,
+				Name:              b.config.AMIName,
+				AMIMappings:       b.config.AMIBlockDevices.AMIMappings,
+			},
+			&awscommon.StepAMIRegionCopy{
+				AccessConfig:      &b.config.AccessConfig,
+				Regions:           b.config.AMIRegions,
+				RegionKeyIds:      b.config.AMIRegionKMSKeyIDs,
+				EncryptBootVolume: b.config.AMIEncryptBootVolume,
+				Name:              b.config.AMIName,
+			},
+			&awscommon.StepModifyAMIAttributes{
+				Description:    b.config.AMIDescription,
+				Users:          b.config.AMIUsers,
+				Groups:         b.config.AMIGroups,
+				ProductCodes:   b.config.AMIProductCodes,
+				SnapshotUsers:  b.config.SnapshotUsers,
+				SnapshotGroups: b.config.SnapshotGroups,
+				Ctx:            b.config.ctx,
+			},
+			&awscommon.StepCreateTags{
+				Tags:         b.config.AMITags,
+				SnapshotTags: b.config.SnapshotTags,
+				Ctx:          b.config.ctx,
+			},
+		)
 	}
 
 	// Run!
 	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
 	b.runner.Run(state)
+	b.config.ReportMockEC2(ui)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
@@ -274,6 +318,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		Amis:           state.Get("amis").(map[string]string),
 		BuilderIdValue: BuilderId,
 		Session:        session,
+		SourceImage:    state.Get("source_image").(*ec2.Image),
 	}
 
 	return artifact, nil
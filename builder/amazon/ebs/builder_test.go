@@ -1,6 +1,9 @@
 package ebs
 
 import (
+	"bytes"
+	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/packer/packer"
@@ -127,3 +130,33 @@ func TestBuilderPrepare_InvalidShutdownBehavior(t *testing.T) {
 		t.Fatal("should have error")
 	}
 }
+
+func TestBuilderRun_MockEC2(t *testing.T) {
+	b := &Builder{}
+	config := testConfig()
+	delete(config, "access_key")
+	delete(config, "secret_key")
+	config["source_ami"] = "ami-12345678"
+	config["instance_type"] = "m3.medium"
+	config["communicator"] = "none"
+	config["mock_ec2"] = true
+	config["ami_regions"] = []string{"us-west-2"}
+	config["tags"] = map[string]string{"Role": "test"}
+
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var errOut bytes.Buffer
+	ui := &packer.BasicUi{Writer: ioutil.Discard, ErrorWriter: &errOut}
+	artifact, err := b.Run(ui, &packer.MockHook{}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(artifact.Id(), "us-east-1:ami-") || !strings.Contains(artifact.Id(), "us-west-2:ami-") {
+		t.Fatalf("bad: %s", artifact.Id())
+	}
+	if errOut.Len() > 0 {
+		t.Fatalf("the build shouldn't fail or leave anything behind: %s", errOut.String())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/hashicorp/packer/packer"
 )
@@ -13,11 +14,24 @@ import (
 // map of region to list of volume IDs
 type EbsVolumes map[string][]string
 
+// Volume describes a volume of the build and its snapshot.
+type Volume struct {
+	Region                   string   `json:"region"`
+	DeviceName               string   `json:"device_name"`
+	VolumeId                 string   `json:"volume_id"`
+	SnapshotId               string   `json:"snapshot_id,omitempty"`
+	FastSnapshotRestoreZones []string `json:"fast_snapshot_restore_zones,omitempty"`
+}
+
 // Artifact is an artifact implementation that contains built AMIs.
 type Artifact struct {
 	// A map of regions to EBS Volume IDs.
 	Volumes EbsVolumes
 
+	// The volumes with their device names and snapshots. This is the
+	// "volumes" state of the artifact.
+	VolumeDetails []*Volume
+
 	// BuilderId is the unique ID for the builder that created this AMI
 	BuilderIdValue string
 
@@ -52,11 +66,34 @@ func (a *Artifact) Id() string {
 }
 
 func (a *Artifact) String() string {
-	return fmt.Sprintf("EBS Volumes were created:\n\n%s", strings.Join(a.idList(), "\n"))
+	result := fmt.Sprintf("EBS Volumes were created:\n\n%s", strings.Join(a.idList(), "\n"))
+
+	var snapshots []string
+	for _, v := range a.VolumeDetails {
+		if v.SnapshotId == "" {
+			continue
+		}
+		snapshot := fmt.Sprintf("%s:%s (%s)", v.Region, v.SnapshotId, v.VolumeId)
+		if len(v.FastSnapshotRestoreZones) > 0 {
+			snapshot += fmt.Sprintf(", fast snapshot restore in %s",
+				strings.Join(v.FastSnapshotRestoreZones, ", "))
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if len(snapshots) > 0 {
+		sort.Strings(snapshots)
+		result += fmt.Sprintf("\n\nEBS Snapshots were created:\n\n%s", strings.Join(snapshots, "\n"))
+	}
+	return result
 }
 
 func (a *Artifact) State(name string) interface{} {
-	return nil
+	switch name {
+	case "volumes":
+		return a.VolumeDetails
+	default:
+		return nil
+	}
 }
 
 func (a *Artifact) Destroy() error {
@@ -75,6 +112,19 @@ func (a *Artifact) Destroy() error {
 		}
 	}
 
+	for _, v := range a.VolumeDetails {
+		if v.SnapshotId == "" {
+			continue
+		}
+		log.Printf("Deleting Snapshot ID (%s) from region (%s)", v.SnapshotId, v.Region)
+		input := &ec2.DeleteSnapshotInput{
+			SnapshotId: aws.String(v.SnapshotId),
+		}
+		if _, err := a.Conn.DeleteSnapshot(input); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
 	if len(errors) > 0 {
 		if len(errors) == 1 {
 			return errors[0]
@@ -0,0 +1,29 @@
+package ebsvolume
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArtifactState_volumes(t *testing.T) {
+	volumes := []*Volume{
+		{Region: "us-east-1", DeviceName: "/dev/xvdf", VolumeId: "vol-1", SnapshotId: "snap-1",
+			FastSnapshotRestoreZones: []string{"us-east-1a"}},
+		{Region: "us-east-1", DeviceName: "/dev/xvdg", VolumeId: "vol-2"},
+	}
+	a := &Artifact{
+		Volumes:       EbsVolumes{"us-east-1": {"vol-1", "vol-2"}},
+		VolumeDetails: volumes,
+	}
+
+	if state := a.State("volumes"); !reflect.DeepEqual(state, volumes) {
+		t.Fatalf("bad: %#v", state)
+	}
+	if a.Id() != "us-east-1:vol-1,us-east-1:vol-2" {
+		t.Fatalf("bad: %s", a.Id())
+	}
+	if s := a.String(); !strings.Contains(s, "us-east-1:snap-1 (vol-1), fast snapshot restore in us-east-1a") {
+		t.Fatalf("bad: %s", s)
+	}
+}
@@ -1,13 +1,27 @@
 package ebsvolume
 
 import (
+	"fmt"
+
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
 type BlockDevice struct {
-	awscommon.BlockDevice `mapstructure:"-,squash"`
-	Tags                  awscommon.TagMap `mapstructure:"tags"`
+	awscommon.BlockDevice    `mapstructure:"-,squash"`
+	Tags                     awscommon.TagMap `mapstructure:"tags"`
+	SnapshotVolume           bool             `mapstructure:"snapshot_volume"`
+	SnapshotTags             awscommon.TagMap `mapstructure:"snapshot_tags"`
+	FastSnapshotRestoreZones []string         `mapstructure:"fast_snapshot_restore_zones"`
+}
+
+func (b *BlockDevice) prepareSnapshot() []error {
+	var errs []error
+	if !b.SnapshotVolume && (len(b.SnapshotTags) > 0 || len(b.FastSnapshotRestoreZones) > 0) {
+		errs = append(errs, fmt.Errorf("snapshot_tags and fast_snapshot_restore_zones "+
+			"of %s can only be set with snapshot_volume", b.DeviceName))
+	}
+	return errs
 }
 
 func commonBlockDevices(mappings []BlockDevice, ctx *interpolate.Context) (awscommon.BlockDevices, error) {
@@ -62,6 +62,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		if err := d.Prepare(&b.config.ctx); err != nil {
 			errs = packer.MultiErrorAppend(errs, fmt.Errorf("AMIMapping: %s", err.Error()))
 		}
+		errs = packer.MultiErrorAppend(errs, d.prepareSnapshot()...)
 	}
 
 	b.config.launchBlockDevices, err = commonBlockDevices(b.config.VolumeMappings, &b.config.ctx)
@@ -112,6 +113,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	state := new(multistep.BasicStateBag)
 	state.Put("config", b.config)
 	state.Put("ec2", ec2conn)
+	state.Put("awsSession", session)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 
@@ -126,14 +128,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Debug:                             b.config.PackerDebug,
 			EbsOptimized:                      b.config.EbsOptimized,
 			ExpectedRootDevice:                "ebs",
-			IamInstanceProfile:                b.config.IamInstanceProfile,
 			InstanceInitiatedShutdownBehavior: b.config.InstanceInitiatedShutdownBehavior,
 			InstanceType:                      b.config.InstanceType,
+			Ipv6AddressCount:                  int64(b.config.Ipv6AddressCount),
+			NetworkInterfaceId:                b.config.NetworkInterfaceId,
 			SourceAMI:                         b.config.SourceAmi,
 			SpotPrice:                         b.config.SpotPrice,
 			SpotPriceProduct:                  b.config.SpotPriceAutoProduct,
 			SubnetId:                          b.config.SubnetId,
 			Tags:                              b.config.RunTags,
+			Tenancy:                           b.config.Tenancy,
 			UserData:                          b.config.UserData,
 			UserDataFile:                      b.config.UserDataFile,
 		}
@@ -142,18 +146,25 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			AssociatePublicIpAddress:          b.config.AssociatePublicIpAddress,
 			AvailabilityZone:                  b.config.AvailabilityZone,
 			BlockDevices:                      b.config.launchBlockDevices,
+			CPUCredits:                        b.config.CPUCredits,
 			Ctx:                               b.config.ctx,
 			Debug:                             b.config.PackerDebug,
 			EbsOptimized:                      b.config.EbsOptimized,
-			EnableT2Unlimited:                 b.config.EnableT2Unlimited,
+			EnableHibernation:                 b.config.EnableHibernation,
 			ExpectedRootDevice:                "ebs",
-			IamInstanceProfile:                b.config.IamInstanceProfile,
+			HostId:                            b.config.HostId,
+			HostResourceGroupArn:              b.config.HostResourceGroupArn,
 			InstanceInitiatedShutdownBehavior: b.config.InstanceInitiatedShutdownBehavior,
 			InstanceType:                      b.config.InstanceType,
+			Ipv6AddressCount:                  int64(b.config.Ipv6AddressCount),
+			NetworkInterfaceId:                b.config.NetworkInterfaceId,
 			IsRestricted:                      b.config.IsChinaCloud() || b.config.IsGovCloud(),
+			KeepInstance:                      b.config.KeepSourceInstance,
 			SourceAMI:                         b.config.SourceAmi,
+			SourceInstanceId:                  b.config.SourceInstanceId,
 			SubnetId:                          b.config.SubnetId,
 			Tags:                              b.config.RunTags,
+			Tenancy:                           b.config.Tenancy,
 			UserData:                          b.config.UserData,
 			UserDataFile:                      b.config.UserDataFile,
 		}
@@ -163,6 +174,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	steps := []multistep.Step{
 		&awscommon.StepSourceAMIInfo{
 			SourceAmi:                b.config.SourceAmi,
+			SourceInstanceId:         b.config.SourceInstanceId,
 			EnableAMISriovNetSupport: b.config.AMISriovNetSupport,
 			EnableAMIENASupport:      b.config.AMIENASupport,
 			AmiFilters:               b.config.SourceAmiFilter,
@@ -173,30 +185,57 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			DebugKeyPath:         fmt.Sprintf("ec2_%s.pem", b.config.PackerBuildName),
 			KeyPairName:          b.config.SSHKeyPairName,
 			TemporaryKeyPairName: b.config.TemporaryKeyPairName,
+			TemporaryKeyPairType: b.config.TemporaryKeyPairType,
+			TemporaryKeyPairBits: b.config.TemporaryKeyPairBits,
 			PrivateKeyFile:       b.config.RunConfig.Comm.SSHPrivateKey,
 		},
 		&awscommon.StepSecurityGroup{
-			SecurityGroupIds: b.config.SecurityGroupIds,
-			CommConfig:       &b.config.RunConfig.Comm,
-			VpcId:            b.config.VpcId,
-			TemporarySGSourceCidr: b.config.TemporarySGSourceCidr,
+			SecurityGroupIds:          b.config.SecurityGroupIds,
+			CommConfig:                &b.config.RunConfig.Comm,
+			SubnetId:                  b.config.SubnetId,
+			VpcId:                     b.config.VpcId,
+			NetworkInterfaceId:        b.config.NetworkInterfaceId,
+			TemporarySGSourceCidrs:    b.config.TemporarySGSourceCidrs,
+			TemporarySGSourceGroupId:  b.config.TemporarySGSourceGroupId,
+			TemporarySGSourcePublicIp: b.config.TemporarySGSourcePublicIp,
+		},
+		&awscommon.StepIamInstanceProfile{
+			IamInstanceProfile:                        b.config.IamInstanceProfile,
+			TemporaryIamInstanceProfilePolicyDocument: b.config.TemporaryIamInstanceProfilePolicyDocument,
+		},
+		&awscommon.StepAllocateHost{
+			AllocateHost:     b.config.AllocateHost,
+			AvailabilityZone: b.config.AvailabilityZone,
+			Ctx:              b.config.ctx,
+			InstanceType:     b.config.InstanceType,
+			KeepHost:         b.config.KeepHost,
+			SubnetId:         b.config.SubnetId,
+			Tags:             b.config.RunTags,
 		},
 		instanceStep,
+		&awscommon.StepCloudWatchLogs{
+			Enable:        b.config.CloudWatchLogs,
+			LogGroup:      b.config.CloudWatchLogsGroup,
+			RetentionDays: b.config.CloudWatchLogsRetentionDays,
+			BuildName:     b.config.PackerBuildName,
+		},
 		&stepTagEBSVolumes{
 			VolumeMapping: b.config.VolumeMappings,
 			Ctx:           b.config.ctx,
 		},
 		&awscommon.StepGetPassword{
-			Debug:     b.config.PackerDebug,
-			Comm:      &b.config.RunConfig.Comm,
-			Timeout:   b.config.WindowsPasswordTimeout,
-			BuildName: b.config.PackerBuildName,
+			Debug:          b.config.PackerDebug,
+			Comm:           &b.config.RunConfig.Comm,
+			Timeout:        b.config.WindowsPasswordTimeout,
+			BuildName:      b.config.PackerBuildName,
+			PrivateKeyFile: b.config.WindowsPasswordPrivateKeyFile,
 		},
 		&communicator.StepConnect{
 			Config: &b.config.RunConfig.Comm,
 			Host: awscommon.SSHHost(
 				ec2conn,
-				b.config.SSHInterface),
+				b.config.SSHInterface,
+				b.config.TemporaryAddressFamily),
 			SSHConfig: awscommon.SSHConfig(
 				b.config.RunConfig.Comm.SSHAgentAuth,
 				b.config.RunConfig.Comm.SSHUsername,
@@ -211,11 +250,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			EnableAMISriovNetSupport: b.config.AMISriovNetSupport,
 			EnableAMIENASupport:      b.config.AMIENASupport,
 		},
+		&stepSnapshotEBSVolumes{
+			VolumeMapping: b.config.VolumeMappings,
+			Ctx:           b.config.ctx,
+		},
 	}
 
 	// Run!
 	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
 	b.runner.Run(state)
+	b.config.ReportMockEC2(ui)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
@@ -225,6 +269,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	// Build the artifact and return it
 	artifact := &Artifact{
 		Volumes:        state.Get("ebsvolumes").(EbsVolumes),
+		VolumeDetails:  state.Get("volumes").([]*Volume),
 		BuilderIdValue: BuilderId,
 		Conn:           ec2conn,
 	}
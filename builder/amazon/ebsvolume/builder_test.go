@@ -89,3 +89,26 @@ func TestBuilderPrepare_InvalidShutdownBehavior(t *testing.T) {
 		t.Fatal("should have error")
 	}
 }
+
+func TestBuilderPrepare_Snapshot(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	volume := map[string]interface{}{
+		"device_name":                 "/dev/xvdf",
+		"volume_size":                 10,
+		"snapshot_volume":             true,
+		"snapshot_tags":               map[string]string{"Name": "data"},
+		"fast_snapshot_restore_zones": []string{"us-east-1a"},
+	}
+	config["ebs_volumes"] = []map[string]interface{}{volume}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	b = Builder{}
+	volume["snapshot_volume"] = false
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with snapshot options without snapshot_volume")
+	}
+}
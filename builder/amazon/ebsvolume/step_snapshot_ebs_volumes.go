@@ -0,0 +1,183 @@
+package ebsvolume
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// stepSnapshotEBSVolumes snapshots the volumes with snapshot_volume once
+// the instance is stopped, tags the snapshots and enables fast snapshot
+// restore for them. The snapshots are deleted if the build fails.
+//
+// Uses:
+//   volumes []*Volume - The volumes of the instance, the snapshots are
+//     added to them.
+type stepSnapshotEBSVolumes struct {
+	VolumeMapping []BlockDevice
+	Ctx           interpolate.Context
+
+	snapshotIds []string
+}
+
+func (s *stepSnapshotEBSVolumes) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+	volumes := state.Get("volumes").([]*Volume)
+
+	for _, mapping := range s.VolumeMapping {
+		if !mapping.SnapshotVolume {
+			continue
+		}
+		for _, volume := range volumes {
+			if volume.DeviceName != mapping.DeviceName {
+				continue
+			}
+
+			if err := s.snapshot(ec2conn, ui, state, mapping, volume); err != nil {
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepSnapshotEBSVolumes) snapshot(ec2conn *ec2.EC2, ui packer.Ui, state multistep.StateBag, mapping BlockDevice, volume *Volume) error {
+	ui.Say(fmt.Sprintf("Creating snapshot of %s (%s)...", volume.VolumeId, volume.DeviceName))
+	snapshot, err := ec2conn.CreateSnapshot(&ec2.CreateSnapshotInput{
+		VolumeId:    aws.String(volume.VolumeId),
+		Description: aws.String(fmt.Sprintf("Packer snapshot of %s (%s)", volume.VolumeId, volume.DeviceName)),
+	})
+	if err != nil {
+		return fmt.Errorf("Error creating snapshot of %s: %s", volume.VolumeId, err)
+	}
+	snapshotId := aws.StringValue(snapshot.SnapshotId)
+	s.snapshotIds = append(s.snapshotIds, snapshotId)
+	volume.SnapshotId = snapshotId
+	ui.Message(fmt.Sprintf("Snapshot ID: %s", snapshotId))
+
+	if len(mapping.SnapshotTags) > 0 {
+		tags, err := mapping.SnapshotTags.EC2Tags(s.Ctx, *ec2conn.Config.Region, state)
+		if err != nil {
+			return fmt.Errorf("Error tagging snapshot of %s: %s", mapping.DeviceName, err)
+		}
+		tags.Report(ui)
+		_, err = ec2conn.CreateTags(&ec2.CreateTagsInput{
+			Resources: []*string{aws.String(snapshotId)},
+			Tags:      tags,
+		})
+		if err != nil {
+			return fmt.Errorf("Error tagging snapshot %s: %s", snapshotId, err)
+		}
+	}
+
+	ui.Message(fmt.Sprintf("Waiting for snapshot %s to complete...", snapshotId))
+	stateChange := awscommon.StateChangeConf{
+		Pending:   []string{"pending"},
+		Target:    "completed",
+		Refresh:   awscommon.SnapshotStateRefreshFunc(ec2conn, snapshotId),
+		StepState: state,
+	}
+	if _, err := awscommon.WaitForState(&stateChange); err != nil {
+		return fmt.Errorf("Error waiting for snapshot %s: %s", snapshotId, err)
+	}
+
+	if len(mapping.FastSnapshotRestoreZones) > 0 {
+		ui.Message(fmt.Sprintf("Enabling fast snapshot restore of %s in %v...",
+			snapshotId, mapping.FastSnapshotRestoreZones))
+		resp, err := enableFastSnapshotRestores(ec2conn, snapshotId, mapping.FastSnapshotRestoreZones)
+		if err != nil {
+			return fmt.Errorf("Error enabling fast snapshot restore of %s: %s", snapshotId, err)
+		}
+		if len(resp.Unsuccessful) > 0 {
+			return fmt.Errorf("Error enabling fast snapshot restore of %s: %s", snapshotId, resp.Unsuccessful[0])
+		}
+		volume.FastSnapshotRestoreZones = mapping.FastSnapshotRestoreZones
+	}
+
+	return nil
+}
+
+func (s *stepSnapshotEBSVolumes) Cleanup(state multistep.StateBag) {
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if len(s.snapshotIds) == 0 || (!cancelled && !halted) {
+		return
+	}
+
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deleting the snapshots of the volumes...")
+	for _, snapshotId := range s.snapshotIds {
+		_, err := ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: aws.String(snapshotId)})
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error deleting snapshot, may still be around: %s", err))
+		}
+	}
+}
+
+// The vendored EC2 client predates fast snapshot restore, so the operation
+// is sent with the query protocol of the client.
+type enableFastSnapshotRestoresInput struct {
+	_                 struct{}  `type:"structure"`
+	AvailabilityZones []*string `locationName:"AvailabilityZone" locationNameList:"AvailabilityZone" type:"list"`
+	SourceSnapshotIds []*string `locationName:"SourceSnapshotId" locationNameList:"SnapshotId" type:"list"`
+}
+
+type enableFastSnapshotRestoresOutput struct {
+	_            struct{}                    `type:"structure"`
+	Unsuccessful []*fastSnapshotRestoreError `locationName:"unsuccessful" locationNameList:"item" type:"list"`
+}
+
+type fastSnapshotRestoreError struct {
+	_          struct{}                        `type:"structure"`
+	SnapshotId *string                         `locationName:"snapshotId" type:"string"`
+	Errors     []*fastSnapshotRestoreZoneError `locationName:"fastSnapshotRestoreStateErrorSet" locationNameList:"item" type:"list"`
+}
+
+type fastSnapshotRestoreZoneError struct {
+	_                struct{} `type:"structure"`
+	AvailabilityZone *string  `locationName:"availabilityZone" type:"string"`
+	Error            *struct {
+		_       struct{} `type:"structure"`
+		Message *string  `locationName:"message" type:"string"`
+	} `locationName:"error" type:"structure"`
+}
+
+func (e *fastSnapshotRestoreError) String() string {
+	var zones []string
+	for _, err := range e.Errors {
+		message := ""
+		if err.Error != nil {
+			message = aws.StringValue(err.Error.Message)
+		}
+		zones = append(zones, fmt.Sprintf("%s: %s", aws.StringValue(err.AvailabilityZone), message))
+	}
+	return strings.Join(zones, ", ")
+}
+
+func enableFastSnapshotRestores(ec2conn *ec2.EC2, snapshotId string, zones []string) (*enableFastSnapshotRestoresOutput, error) {
+	op := &request.Operation{
+		Name:       "EnableFastSnapshotRestores",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	output := &enableFastSnapshotRestoresOutput{}
+	err := ec2conn.NewRequest(op, &enableFastSnapshotRestoresInput{
+		AvailabilityZones: aws.StringSlice(zones),
+		SourceSnapshotIds: []*string{aws.String(snapshotId)},
+	}, output).Send()
+	return output, err
+}
@@ -21,16 +21,23 @@ func (s *stepTagEBSVolumes) Run(_ context.Context, state multistep.StateBag) mul
 	ui := state.Get("ui").(packer.Ui)
 
 	volumes := make(EbsVolumes)
+	var details []*Volume
 	for _, instanceBlockDevices := range instance.BlockDeviceMappings {
 		for _, configVolumeMapping := range s.VolumeMapping {
 			if configVolumeMapping.DeviceName == *instanceBlockDevices.DeviceName {
 				volumes[*ec2conn.Config.Region] = append(
 					volumes[*ec2conn.Config.Region],
 					*instanceBlockDevices.Ebs.VolumeId)
+				details = append(details, &Volume{
+					Region:     *ec2conn.Config.Region,
+					DeviceName: *instanceBlockDevices.DeviceName,
+					VolumeId:   *instanceBlockDevices.Ebs.VolumeId,
+				})
 			}
 		}
 	}
 	state.Put("ebsvolumes", volumes)
+	state.Put("volumes", details)
 
 	if len(s.VolumeMapping) > 0 {
 		ui.Say("Tagging EBS volumes...")
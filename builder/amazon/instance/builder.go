@@ -155,6 +155,12 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, fmt.Errorf("x509_key_path points to bad file: %s", err))
 	}
 
+	// Instance store backed instances can't be stopped, only terminated
+	if b.config.KeepSourceInstance || b.config.SourceInstanceId != "" || b.config.EnableHibernation {
+		errs = packer.MultiErrorAppend(errs, errors.New("keep_source_instance, "+
+			"source_instance_id and enable_hibernation need an EBS backed instance"))
+	}
+
 	if b.config.IsSpotInstance() && (b.config.AMIENASupport || b.config.AMISriovNetSupport) {
 		errs = packer.MultiErrorAppend(errs,
 			fmt.Errorf("Spot instances do not support modification, which is required "+
@@ -212,13 +218,15 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Ctx:                      b.config.ctx,
 			Debug:                    b.config.PackerDebug,
 			EbsOptimized:             b.config.EbsOptimized,
-			IamInstanceProfile:       b.config.IamInstanceProfile,
 			InstanceType:             b.config.InstanceType,
+			Ipv6AddressCount:         int64(b.config.Ipv6AddressCount),
+			NetworkInterfaceId:       b.config.NetworkInterfaceId,
 			SourceAMI:                b.config.SourceAmi,
 			SpotPrice:                b.config.SpotPrice,
 			SpotPriceProduct:         b.config.SpotPriceAutoProduct,
 			SubnetId:                 b.config.SubnetId,
 			Tags:                     b.config.RunTags,
+			Tenancy:                  b.config.Tenancy,
 			UserData:                 b.config.UserData,
 			UserDataFile:             b.config.UserDataFile,
 		}
@@ -227,16 +235,20 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
 			AvailabilityZone:         b.config.AvailabilityZone,
 			BlockDevices:             b.config.BlockDevices,
+			CPUCredits:               b.config.CPUCredits,
 			Ctx:                      b.config.ctx,
 			Debug:                    b.config.PackerDebug,
 			EbsOptimized:             b.config.EbsOptimized,
-			EnableT2Unlimited:        b.config.EnableT2Unlimited,
-			IamInstanceProfile:       b.config.IamInstanceProfile,
+			HostId:                   b.config.HostId,
+			HostResourceGroupArn:     b.config.HostResourceGroupArn,
 			InstanceType:             b.config.InstanceType,
+			Ipv6AddressCount:         int64(b.config.Ipv6AddressCount),
+			NetworkInterfaceId:       b.config.NetworkInterfaceId,
 			IsRestricted:             b.config.IsChinaCloud() || b.config.IsGovCloud(),
 			SourceAMI:                b.config.SourceAmi,
 			SubnetId:                 b.config.SubnetId,
 			Tags:                     b.config.RunTags,
+			Tenancy:                  b.config.Tenancy,
 			UserData:                 b.config.UserData,
 			UserDataFile:             b.config.UserDataFile,
 		}
@@ -247,6 +259,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&awscommon.StepPreValidate{
 			DestAmiName:     b.config.AMIName,
 			ForceDeregister: b.config.AMIForceDeregister,
+			SkipCreateAMI:   b.config.AMISkipCreateImage,
 		},
 		&awscommon.StepSourceAMIInfo{
 			SourceAmi:                b.config.SourceAmi,
@@ -261,75 +274,108 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			KeyPairName:          b.config.SSHKeyPairName,
 			PrivateKeyFile:       b.config.RunConfig.Comm.SSHPrivateKey,
 			TemporaryKeyPairName: b.config.TemporaryKeyPairName,
+			TemporaryKeyPairType: b.config.TemporaryKeyPairType,
+			TemporaryKeyPairBits: b.config.TemporaryKeyPairBits,
 		},
 		&awscommon.StepSecurityGroup{
-			CommConfig:       &b.config.RunConfig.Comm,
-			SecurityGroupIds: b.config.SecurityGroupIds,
-			VpcId:            b.config.VpcId,
-			TemporarySGSourceCidr: b.config.TemporarySGSourceCidr,
+			CommConfig:                &b.config.RunConfig.Comm,
+			SecurityGroupIds:          b.config.SecurityGroupIds,
+			SubnetId:                  b.config.SubnetId,
+			VpcId:                     b.config.VpcId,
+			NetworkInterfaceId:        b.config.NetworkInterfaceId,
+			TemporarySGSourceCidrs:    b.config.TemporarySGSourceCidrs,
+			TemporarySGSourceGroupId:  b.config.TemporarySGSourceGroupId,
+			TemporarySGSourcePublicIp: b.config.TemporarySGSourcePublicIp,
+		},
+		&awscommon.StepIamInstanceProfile{
+			IamInstanceProfile:                        b.config.IamInstanceProfile,
+			TemporaryIamInstanceProfilePolicyDocument: b.config.TemporaryIamInstanceProfilePolicyDocument,
+		},
+		&awscommon.StepAllocateHost{
+			AllocateHost:     b.config.AllocateHost,
+			AvailabilityZone: b.config.AvailabilityZone,
+			Ctx:              b.config.ctx,
+			InstanceType:     b.config.InstanceType,
+			KeepHost:         b.config.KeepHost,
+			SubnetId:         b.config.SubnetId,
+			Tags:             b.config.RunTags,
 		},
 		instanceStep,
+		&awscommon.StepCloudWatchLogs{
+			Enable:        b.config.CloudWatchLogs,
+			LogGroup:      b.config.CloudWatchLogsGroup,
+			RetentionDays: b.config.CloudWatchLogsRetentionDays,
+			BuildName:     b.config.PackerBuildName,
+		},
 		&awscommon.StepGetPassword{
-			Debug:     b.config.PackerDebug,
-			Comm:      &b.config.RunConfig.Comm,
-			Timeout:   b.config.WindowsPasswordTimeout,
-			BuildName: b.config.PackerBuildName,
+			Debug:          b.config.PackerDebug,
+			Comm:           &b.config.RunConfig.Comm,
+			Timeout:        b.config.WindowsPasswordTimeout,
+			BuildName:      b.config.PackerBuildName,
+			PrivateKeyFile: b.config.WindowsPasswordPrivateKeyFile,
 		},
 		&communicator.StepConnect{
 			Config: &b.config.RunConfig.Comm,
 			Host: awscommon.SSHHost(
 				ec2conn,
-				b.config.SSHInterface),
+				b.config.SSHInterface,
+				b.config.TemporaryAddressFamily),
 			SSHConfig: awscommon.SSHConfig(
 				b.config.RunConfig.Comm.SSHAgentAuth,
 				b.config.RunConfig.Comm.SSHUsername,
 				b.config.RunConfig.Comm.SSHPassword),
 		},
 		&common.StepProvision{},
-		&StepUploadX509Cert{},
-		&StepBundleVolume{
-			Debug: b.config.PackerDebug,
-		},
-		&StepUploadBundle{
-			Debug: b.config.PackerDebug,
-		},
-		&awscommon.StepDeregisterAMI{
-			AccessConfig:        &b.config.AccessConfig,
-			ForceDeregister:     b.config.AMIForceDeregister,
-			ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
-			AMIName:             b.config.AMIName,
-			Regions:             b.config.AMIRegions,
-		},
-		&StepRegisterAMI{
-			EnableAMISriovNetSupport: b.config.AMISriovNetSupport,
-			EnableAMIENASupport:      b.config.AMIENASupport,
-		},
-		&awscommon.StepAMIRegionCopy{
-			AccessConfig:      &b.config.AccessConfig,
-			Regions:           b.config.AMIRegions,
-			RegionKeyIds:      b.config.AMIRegionKMSKeyIDs,
-			EncryptBootVolume: b.config.AMIEncryptBootVolume,
-			Name:              b.config.AMIName,
-		},
-		&awscommon.StepModifyAMIAttributes{
-			Description:    b.config.AMIDescription,
-			Users:          b.config.AMIUsers,
-			Groups:         b.config.AMIGroups,
-			ProductCodes:   b.config.AMIProductCodes,
-			SnapshotUsers:  b.config.SnapshotUsers,
-			SnapshotGroups: b.config.SnapshotGroups,
-			Ctx:            b.config.ctx,
-		},
-		&awscommon.StepCreateTags{
-			Tags:         b.config.AMITags,
-			SnapshotTags: b.config.SnapshotTags,
-			Ctx:          b.config.ctx,
-		},
+	}
+
+	if !b.config.AMISkipCreateImage {
+		steps = append(steps,
+			&StepUploadX509Cert{},
+			&StepBundleVolume{
+				Debug: b.config.PackerDebug,
+			},
+			&StepUploadBundle{
+				Debug: b.config.PackerDebug,
+			},
+			&awscommon.StepDeregisterAMI{
+				AccessConfig:        &b.config.AccessConfig,
+				ForceDeregister:     b.config.AMIForceDeregister,
+				ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
+				AMIName:             b.config.AMIName,
+				Regions:             b.config.AMIRegions,
+			},
+			&StepRegisterAMI{
+				EnableAMISriovNetSupport: b.config.AMISriovNetSupport,
+				EnableAMIENASupport:      b.config.AMIENASupport,
+			},
+			&awscommon.StepAMIRegionCopy{
+				AccessConfig:      &b.config.AccessConfig,
+				Regions:           b.config.AMIRegions,
+				RegionKeyIds:      b.config.AMIRegionKMSKeyIDs,
+				EncryptBootVolume: b.config.AMIEncryptBootVolume,
+				Name:              b.config.AMIName,
+			},
+			&awscommon.StepModifyAMIAttributes{
+				Description:    b.config.AMIDescription,
+				Users:          b.config.AMIUsers,
+				Groups:         b.config.AMIGroups,
+				ProductCodes:   b.config.AMIProductCodes,
+				SnapshotUsers:  b.config.SnapshotUsers,
+				SnapshotGroups: b.config.SnapshotGroups,
+				Ctx:            b.config.ctx,
+			},
+			&awscommon.StepCreateTags{
+				Tags:         b.config.AMITags,
+				SnapshotTags: b.config.SnapshotTags,
+				Ctx:          b.config.ctx,
+			},
+		)
 	}
 
 	// Run!
 	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
 	b.runner.Run(state)
+	b.config.ReportMockEC2(ui)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
@@ -346,6 +392,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		Amis:           state.Get("amis").(map[string]string),
 		BuilderIdValue: BuilderId,
 		Session:        session,
+		SourceImage:    state.Get("source_image").(*ec2.Image),
 	}
 
 	return artifact, nil
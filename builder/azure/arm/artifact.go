@@ -30,8 +30,15 @@ type Artifact struct {
 	ManagedImageName              string
 	ManagedImageLocation          string
 
+	// Shared Image Gallery
+	ManagedImageSharedImageGalleryVersion   string
+	ManagedImageSharedImageGalleryVersionID string
+
 	// Additional Disks
 	AdditionalDisks *[]AdditionalDiskArtifact
+
+	// The Marketplace plan the VMs deployed from the image must specify
+	PlanInfo *PlanInformation
 }
 
 func NewManagedImageArtifact(resourceGroup, name, location string) (*Artifact, error) {
@@ -42,6 +49,15 @@ func NewManagedImageArtifact(resourceGroup, name, location string) (*Artifact, e
 	}, nil
 }
 
+// NewSharedImageGalleryArtifact is the artifact of a build only published to
+// a Shared Image Gallery, without a managed image.
+func NewSharedImageGalleryArtifact(version, id string) (*Artifact, error) {
+	return &Artifact{
+		ManagedImageSharedImageGalleryVersion:   version,
+		ManagedImageSharedImageGalleryVersionID: id,
+	}, nil
+}
+
 func NewArtifact(template *CaptureTemplate, getSasUrl func(name string) string) (*Artifact, error) {
 	if template == nil {
 		return nil, fmt.Errorf("nil capture template")
@@ -146,6 +162,13 @@ func (a *Artifact) String() string {
 		buf.WriteString(fmt.Sprintf("ManagedImageResourceGroupName: %s\n", a.ManagedImageResourceGroupName))
 		buf.WriteString(fmt.Sprintf("ManagedImageName: %s\n", a.ManagedImageName))
 		buf.WriteString(fmt.Sprintf("ManagedImageLocation: %s\n", a.ManagedImageLocation))
+		if a.ManagedImageSharedImageGalleryVersionID != "" {
+			buf.WriteString(fmt.Sprintf("ManagedImageSharedImageGalleryVersion: %s\n", a.ManagedImageSharedImageGalleryVersion))
+			buf.WriteString(fmt.Sprintf("ManagedImageSharedImageGalleryVersionID: %s\n", a.ManagedImageSharedImageGalleryVersionID))
+		}
+	} else if a.ManagedImageSharedImageGalleryVersionID != "" {
+		buf.WriteString(fmt.Sprintf("ManagedImageSharedImageGalleryVersion: %s\n", a.ManagedImageSharedImageGalleryVersion))
+		buf.WriteString(fmt.Sprintf("ManagedImageSharedImageGalleryVersionID: %s\n", a.ManagedImageSharedImageGalleryVersionID))
 	} else {
 		buf.WriteString(fmt.Sprintf("StorageAccountLocation: %s\n", a.StorageAccountLocation))
 		buf.WriteString(fmt.Sprintf("OSDiskUri: %s\n", a.OSDiskUri))
@@ -159,6 +182,14 @@ func (a *Artifact) String() string {
 			}
 		}
 	}
+	if a.PlanInfo != nil {
+		buf.WriteString(fmt.Sprintf("PlanName: %s\n", a.PlanInfo.PlanName))
+		buf.WriteString(fmt.Sprintf("PlanProduct: %s\n", a.PlanInfo.PlanProduct))
+		buf.WriteString(fmt.Sprintf("PlanPublisher: %s\n", a.PlanInfo.PlanPublisher))
+		if a.PlanInfo.PlanPromotionCode != "" {
+			buf.WriteString(fmt.Sprintf("PlanPromotionCode: %s\n", a.PlanInfo.PlanPromotionCode))
+		}
+	}
 
 	return buf.String()
 }
@@ -174,6 +205,11 @@ func (a *Artifact) stateAtlasMetadata() interface{} {
 	metadata["OSDiskUriReadOnlySas"] = a.OSDiskUriReadOnlySas
 	metadata["TemplateUri"] = a.TemplateUri
 	metadata["TemplateUriReadOnlySas"] = a.TemplateUriReadOnlySas
+	if a.PlanInfo != nil {
+		metadata["PlanName"] = a.PlanInfo.PlanName
+		metadata["PlanProduct"] = a.PlanInfo.PlanProduct
+		metadata["PlanPublisher"] = a.PlanInfo.PlanPublisher
+	}
 
 	return metadata
 }
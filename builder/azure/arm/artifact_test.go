@@ -82,6 +82,24 @@ func TestArtifactString(t *testing.T) {
 	}
 }
 
+func TestManagedImageArtifactStringWithPlanInfo(t *testing.T) {
+	artifact, err := NewManagedImageArtifact("fakeResourceGroup", "fakeName", "fakeLocation")
+	if err != nil {
+		t.Fatalf("err=%s", err)
+	}
+	artifact.PlanInfo = &PlanInformation{PlanName: "rabbitmq", PlanProduct: "rabbitmq", PlanPublisher: "bitnami"}
+
+	testSubject := artifact.String()
+	for _, expected := range []string{"PlanName: rabbitmq", "PlanProduct: rabbitmq", "PlanPublisher: bitnami"} {
+		if !strings.Contains(testSubject, expected) {
+			t.Errorf("Expected String() output to contain %q", expected)
+		}
+	}
+	if strings.Contains(testSubject, "PlanPromotionCode") {
+		t.Errorf("Expected String() output to not contain an empty PlanPromotionCode")
+	}
+}
+
 func TestAdditionalDiskArtifactString(t *testing.T) {
 	template := CaptureTemplate{
 		Resources: []CaptureResources{
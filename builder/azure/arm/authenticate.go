@@ -1,15 +1,24 @@
 package arm
 
 import (
+	"net/url"
+
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/packer/builder/azure/common"
 )
 
+const clientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
 type Authenticate struct {
 	env          azure.Environment
 	clientID     string
 	clientSecret string
 	tenantID     string
+
+	// A managed identity or a federated token replace the client secret
+	useManagedIdentity bool
+	federatedToken     func() (string, error)
 }
 
 func NewAuthenticate(env azure.Environment, clientID, clientSecret, tenantID string) *Authenticate {
@@ -21,16 +30,53 @@ func NewAuthenticate(env azure.Environment, clientID, clientSecret, tenantID str
 	}
 }
 
+// NewManagedIdentityAuthenticate authenticates as the managed identity of
+// the machine Packer runs on, the user-assigned identity with the client ID
+// or the system-assigned one if the client ID is empty.
+func NewManagedIdentityAuthenticate(env azure.Environment, clientID string) *Authenticate {
+	return &Authenticate{
+		env:                env,
+		clientID:           clientID,
+		useManagedIdentity: true,
+	}
+}
+
+// NewWorkloadIdentityAuthenticate authenticates as the application with the
+// client ID by exchanging a token of a federated identity provider, like
+// the service account tokens of AKS or the OIDC tokens of Azure DevOps. The
+// token is read again each time the Azure token is refreshed since it
+// usually expires first.
+func NewWorkloadIdentityAuthenticate(env azure.Environment, clientID, tenantID string, federatedToken func() (string, error)) *Authenticate {
+	return &Authenticate{
+		env:            env,
+		clientID:       clientID,
+		tenantID:       tenantID,
+		federatedToken: federatedToken,
+	}
+}
+
 func (a *Authenticate) getServicePrincipalToken() (*adal.ServicePrincipalToken, error) {
 	return a.getServicePrincipalTokenWithResource(a.env.ResourceManagerEndpoint)
 }
 
 func (a *Authenticate) getServicePrincipalTokenWithResource(resource string) (*adal.ServicePrincipalToken, error) {
+	if a.useManagedIdentity {
+		return common.NewManagedIdentityToken(resource, a.clientID)
+	}
+
 	oauthConfig, err := adal.NewOAuthConfig(a.env.ActiveDirectoryEndpoint, a.tenantID)
 	if err != nil {
 		return nil, err
 	}
 
+	if a.federatedToken != nil {
+		return adal.NewServicePrincipalTokenWithSecret(
+			*oauthConfig,
+			a.clientID,
+			resource,
+			&federatedTokenSecret{token: a.federatedToken})
+	}
+
 	spt, err := adal.NewServicePrincipalToken(
 		*oauthConfig,
 		a.clientID,
@@ -39,3 +85,19 @@ func (a *Authenticate) getServicePrincipalTokenWithResource(resource string) (*a
 
 	return spt, err
 }
+
+// federatedTokenSecret authenticates with a federated token as the client
+// assertion.
+type federatedTokenSecret struct {
+	token func() (string, error)
+}
+
+func (s *federatedTokenSecret) SetAuthenticationValues(spt *adal.ServicePrincipalToken, v *url.Values) error {
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+	v.Set("client_assertion_type", clientAssertionTypeJWTBearer)
+	v.Set("client_assertion", token)
+	return nil
+}
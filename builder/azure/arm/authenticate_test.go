@@ -1,6 +1,10 @@
 package arm
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/Azure/go-autorest/autorest/azure"
@@ -38,3 +42,63 @@ func TestNewAuthenticate(t *testing.T) {
 		t.Errorf("spn.Token().Type: expected=\"\", actual=%s", spn.Token().Type)
 	}
 }
+
+func TestNewManagedIdentityAuthenticate(t *testing.T) {
+	for _, clientID := range []string{"", "clientID"} {
+		testSubject := NewManagedIdentityAuthenticate(azure.PublicCloud, clientID)
+		spn, err := testSubject.getServicePrincipalToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if spn.Token().AccessToken != "" {
+			t.Errorf("spn.Token().AccessToken: expected=\"\", actual=%s", spn.Token().AccessToken)
+		}
+	}
+}
+
+func TestNewWorkloadIdentityAuthenticateShouldExchangeTheFederatedToken(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"access_token": "token", "expires_in": "3599", "expires_on": "1506484173", "resource": "https://management.azure.com/", "token_type": "Bearer"}`))
+	}))
+	defer server.Close()
+
+	env := azure.PublicCloud
+	env.ActiveDirectoryEndpoint = server.URL + "/"
+
+	count := 0
+	testSubject := NewWorkloadIdentityAuthenticate(env, "clientID", "tenantID", func() (string, error) {
+		count++
+		return fmt.Sprintf("federated-token-%d", count), nil
+	})
+	spn, err := testSubject.getServicePrincipalToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := spn.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if form.Get("client_assertion") != "federated-token-1" {
+		t.Errorf("Expected the federated token as client_assertion, but got %q", form.Get("client_assertion"))
+	}
+	if form.Get("client_assertion_type") != clientAssertionTypeJWTBearer {
+		t.Errorf("Unexpected client_assertion_type %q", form.Get("client_assertion_type"))
+	}
+	if form.Get("client_id") != "clientID" || form.Get("grant_type") != "client_credentials" {
+		t.Errorf("Unexpected form %v", form)
+	}
+	if spn.Token().AccessToken != "token" {
+		t.Errorf("spn.Token().AccessToken: expected=\"token\", actual=%s", spn.Token().AccessToken)
+	}
+
+	if err := spn.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if form.Get("client_assertion") != "federated-token-2" {
+		t.Errorf("Expected the federated token to be read again, but got %q", form.Get("client_assertion"))
+	}
+}
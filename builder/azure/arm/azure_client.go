@@ -34,12 +34,18 @@ type AzureClient struct {
 	network.PublicIPAddressesClient
 	network.InterfacesClient
 	network.SubnetsClient
+	network.SecurityGroupsClient
+	network.SecurityRulesClient
 	network.VirtualNetworksClient
 	compute.ImagesClient
 	compute.VirtualMachinesClient
 	common.VaultClient
 	armStorage.AccountsClient
 	compute.DisksClient
+	common.GalleryImageVersionsClient
+	common.GalleryImagesClient
+	common.MarketplaceAgreementsClient
+	common.BootDiagnosticsClient
 
 	InspectorMaxLength int
 	Template           *CaptureTemplate
@@ -135,6 +141,12 @@ func NewAzureClient(subscriptionID, resourceGroupName, storageAccountName string
 	azureClient.DeploymentsClient.ResponseInspector = byConcatDecorators(byInspecting(maxlen), errorCapture(azureClient))
 	azureClient.DeploymentsClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.DeploymentsClient.UserAgent)
 
+	azureClient.BootDiagnosticsClient = common.NewBootDiagnosticsClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.BootDiagnosticsClient.Authorizer = autorest.NewBearerAuthorizer(servicePrincipalToken)
+	azureClient.BootDiagnosticsClient.RequestInspector = withInspection(maxlen)
+	azureClient.BootDiagnosticsClient.ResponseInspector = byConcatDecorators(byInspecting(maxlen), errorCapture(azureClient))
+	azureClient.BootDiagnosticsClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.BootDiagnosticsClient.UserAgent)
+
 	azureClient.DeploymentOperationsClient = resources.NewDeploymentOperationsClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
 	azureClient.DeploymentOperationsClient.Authorizer = autorest.NewBearerAuthorizer(servicePrincipalToken)
 	azureClient.DeploymentOperationsClient.RequestInspector = withInspection(maxlen)
@@ -147,6 +159,24 @@ func NewAzureClient(subscriptionID, resourceGroupName, storageAccountName string
 	azureClient.DisksClient.ResponseInspector = byConcatDecorators(byInspecting(maxlen), errorCapture(azureClient))
 	azureClient.DisksClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.DisksClient.UserAgent)
 
+	azureClient.GalleryImageVersionsClient = common.NewGalleryImageVersionsClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.GalleryImageVersionsClient.Authorizer = autorest.NewBearerAuthorizer(servicePrincipalToken)
+	azureClient.GalleryImageVersionsClient.RequestInspector = withInspection(maxlen)
+	azureClient.GalleryImageVersionsClient.ResponseInspector = byConcatDecorators(byInspecting(maxlen), errorCapture(azureClient))
+	azureClient.GalleryImageVersionsClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.GalleryImageVersionsClient.UserAgent)
+
+	azureClient.GalleryImagesClient = common.NewGalleryImagesClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.GalleryImagesClient.Authorizer = autorest.NewBearerAuthorizer(servicePrincipalToken)
+	azureClient.GalleryImagesClient.RequestInspector = withInspection(maxlen)
+	azureClient.GalleryImagesClient.ResponseInspector = byConcatDecorators(byInspecting(maxlen), errorCapture(azureClient))
+	azureClient.GalleryImagesClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.GalleryImagesClient.UserAgent)
+
+	azureClient.MarketplaceAgreementsClient = common.NewMarketplaceAgreementsClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.MarketplaceAgreementsClient.Authorizer = autorest.NewBearerAuthorizer(servicePrincipalToken)
+	azureClient.MarketplaceAgreementsClient.RequestInspector = withInspection(maxlen)
+	azureClient.MarketplaceAgreementsClient.ResponseInspector = byConcatDecorators(byInspecting(maxlen), errorCapture(azureClient))
+	azureClient.MarketplaceAgreementsClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.MarketplaceAgreementsClient.UserAgent)
+
 	azureClient.GroupsClient = resources.NewGroupsClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
 	azureClient.GroupsClient.Authorizer = autorest.NewBearerAuthorizer(servicePrincipalToken)
 	azureClient.GroupsClient.RequestInspector = withInspection(maxlen)
@@ -171,6 +201,18 @@ func NewAzureClient(subscriptionID, resourceGroupName, storageAccountName string
 	azureClient.SubnetsClient.ResponseInspector = byConcatDecorators(byInspecting(maxlen), errorCapture(azureClient))
 	azureClient.SubnetsClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.SubnetsClient.UserAgent)
 
+	azureClient.SecurityGroupsClient = network.NewSecurityGroupsClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.SecurityGroupsClient.Authorizer = autorest.NewBearerAuthorizer(servicePrincipalToken)
+	azureClient.SecurityGroupsClient.RequestInspector = withInspection(maxlen)
+	azureClient.SecurityGroupsClient.ResponseInspector = byConcatDecorators(byInspecting(maxlen), errorCapture(azureClient))
+	azureClient.SecurityGroupsClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.SecurityGroupsClient.UserAgent)
+
+	azureClient.SecurityRulesClient = network.NewSecurityRulesClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.SecurityRulesClient.Authorizer = autorest.NewBearerAuthorizer(servicePrincipalToken)
+	azureClient.SecurityRulesClient.RequestInspector = withInspection(maxlen)
+	azureClient.SecurityRulesClient.ResponseInspector = byConcatDecorators(byInspecting(maxlen), errorCapture(azureClient))
+	azureClient.SecurityRulesClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.SecurityRulesClient.UserAgent)
+
 	azureClient.VirtualNetworksClient = network.NewVirtualNetworksClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
 	azureClient.VirtualNetworksClient.Authorizer = autorest.NewBearerAuthorizer(servicePrincipalToken)
 	azureClient.VirtualNetworksClient.RequestInspector = withInspection(maxlen)
@@ -168,9 +168,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	if b.config.OSType == constants.Target_Linux {
 		steps = []multistep.Step{
 			NewStepCreateResourceGroup(azureClient, ui),
+			multistep.If(b.config.PlanInfo.AcceptTerms, NewStepAcceptMarketplaceTerms(azureClient, ui, b.config)),
 			NewStepValidateTemplate(azureClient, ui, b.config, GetVirtualMachineDeployment),
 			NewStepDeployTemplate(azureClient, ui, b.config, deploymentName, GetVirtualMachineDeployment),
+			NewStepCheckSpotEviction(azureClient, ui, b.config),
 			NewStepGetIPAddress(azureClient, ui, endpointConnectType),
+			multistep.If(b.config.ManageNsgRule, NewStepCreateNsgRule(azureClient, ui, b.config)),
+			multistep.If(b.config.BootDiagnostics, NewStepCaptureBootDiagnostics(azureClient, ui, b.config)),
 			&communicator.StepConnectSSH{
 				Config:    &b.config.Comm,
 				Host:      lin.SSHHost,
@@ -181,6 +185,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			NewStepGetAdditionalDisks(azureClient, ui),
 			NewStepPowerOffCompute(azureClient, ui),
 			NewStepCaptureImage(azureClient, ui),
+			NewStepPublishToSharedImageGallery(azureClient, ui, b.config),
 			NewStepDeleteResourceGroup(azureClient, ui),
 			NewStepDeleteOSDisk(azureClient, ui),
 			NewStepDeleteAdditionalDisks(azureClient, ui),
@@ -189,13 +194,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		keyVaultDeploymentName := b.stateBag.Get(constants.ArmKeyVaultDeploymentName).(string)
 		steps = []multistep.Step{
 			NewStepCreateResourceGroup(azureClient, ui),
+			multistep.If(b.config.PlanInfo.AcceptTerms, NewStepAcceptMarketplaceTerms(azureClient, ui, b.config)),
 			NewStepValidateTemplate(azureClient, ui, b.config, GetKeyVaultDeployment),
 			NewStepDeployTemplate(azureClient, ui, b.config, keyVaultDeploymentName, GetKeyVaultDeployment),
 			NewStepGetCertificate(azureClient, ui),
 			NewStepSetCertificate(b.config, ui),
 			NewStepValidateTemplate(azureClient, ui, b.config, GetVirtualMachineDeployment),
 			NewStepDeployTemplate(azureClient, ui, b.config, deploymentName, GetVirtualMachineDeployment),
+			NewStepCheckSpotEviction(azureClient, ui, b.config),
 			NewStepGetIPAddress(azureClient, ui, endpointConnectType),
+			multistep.If(b.config.ManageNsgRule, NewStepCreateNsgRule(azureClient, ui, b.config)),
+			multistep.If(b.config.BootDiagnostics, NewStepCaptureBootDiagnostics(azureClient, ui, b.config)),
 			&StepSaveWinRMPassword{
 				Password:  b.config.tmpAdminPassword,
 				BuildName: b.config.PackerBuildName,
@@ -217,6 +226,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			NewStepGetAdditionalDisks(azureClient, ui),
 			NewStepPowerOffCompute(azureClient, ui),
 			NewStepCaptureImage(azureClient, ui),
+			NewStepPublishToSharedImageGallery(azureClient, ui, b.config),
 			NewStepDeleteResourceGroup(azureClient, ui),
 			NewStepDeleteOSDisk(azureClient, ui),
 			NewStepDeleteAdditionalDisks(azureClient, ui),
@@ -237,8 +247,24 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		}
 	}
 
-	b.runner = packerCommon.NewRunner(steps, b.config.PackerConfig, ui)
-	b.runner.Run(b.stateBag)
+	for retry := 1; ; retry++ {
+		b.runner = packerCommon.NewRunner(steps, b.config.PackerConfig, ui)
+		b.runner.Run(b.stateBag)
+
+		if retry > b.config.Spot.MaxRetries || !b.isSpotVMEvicted() {
+			break
+		}
+
+		ui.Say(fmt.Sprintf("Retrying the build on a new Spot VM (%d of %d) ...", retry, b.config.Spot.MaxRetries))
+		setTempNames(b.config, NewTempName())
+		b.stateBag = new(multistep.BasicStateBag)
+		b.configureStateBag(b.stateBag)
+		b.stateBag.Put("hook", hook)
+		b.stateBag.Put(constants.Ui, ui)
+		b.setRuntimeParameters(b.stateBag)
+		b.setTemplateParameters(b.stateBag)
+		b.setImageParameters(b.stateBag)
+	}
 
 	// Report any errors.
 	if rawErr, ok := b.stateBag.GetOk(constants.Error); ok {
@@ -254,10 +280,22 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
-	if b.config.isManagedImage() {
-		return NewManagedImageArtifact(b.config.ManagedImageResourceGroupName, b.config.ManagedImageName, b.config.manageImageLocation)
+	if b.config.SecurityType != "" {
+		artifact, err := NewSharedImageGalleryArtifact(
+			b.stateBag.Get(constants.ArmManagedImageSharedImageGalleryVersion).(string),
+			b.stateBag.Get(constants.ArmManagedImageSharedImageGalleryVersionID).(string))
+		artifact.PlanInfo = b.config.planInfo()
+		return artifact, err
+	} else if b.config.isManagedImage() {
+		artifact, err := NewManagedImageArtifact(b.config.ManagedImageResourceGroupName, b.config.ManagedImageName, b.config.manageImageLocation)
+		if id, ok := b.stateBag.GetOk(constants.ArmManagedImageSharedImageGalleryVersionID); ok {
+			artifact.ManagedImageSharedImageGalleryVersion = b.stateBag.Get(constants.ArmManagedImageSharedImageGalleryVersion).(string)
+			artifact.ManagedImageSharedImageGalleryVersionID = id.(string)
+		}
+		artifact.PlanInfo = b.config.planInfo()
+		return artifact, err
 	} else if template, ok := b.stateBag.GetOk(constants.ArmCaptureTemplate); ok {
-		return NewArtifact(
+		artifact, err := NewArtifact(
 			template.(*CaptureTemplate),
 			func(name string) string {
 				blob := azureClient.BlobStorageClient.GetContainerReference(DefaultSasBlobContainer).GetBlobReference(name)
@@ -267,6 +305,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 				sasUrl, _ := blob.GetSASURI(options)
 				return sasUrl
 			})
+		if err != nil {
+			return nil, err
+		}
+		artifact.PlanInfo = b.config.planInfo()
+		return artifact, nil
 	}
 
 	return &Artifact{}, nil
@@ -293,6 +336,25 @@ func (b *Builder) writeSSHPrivateKey(ui packer.Ui, debugKeyPath string) {
 	}
 }
 
+// isSpotVMEvicted is true if the build failed because its Spot VM was
+// evicted, or couldn't be allocated in the first place.
+func (b *Builder) isSpotVMEvicted() bool {
+	if b.config.Spot.EvictionPolicy == "" {
+		return false
+	}
+	if _, ok := b.stateBag.GetOk(multistep.StateCancelled); ok {
+		return false
+	}
+	rawErr, ok := b.stateBag.GetOk(constants.Error)
+	if !ok {
+		return false
+	}
+	if _, ok := b.stateBag.GetOk(constants.ArmSpotVMEvicted); ok {
+		return true
+	}
+	return isSpotAllocationFailure(rawErr.(error))
+}
+
 func (b *Builder) isPublicPrivateNetworkCommunication() bool {
 	return DefaultPrivateVirtualNetworkWithPublicIp != b.config.PrivateVirtualNetworkWithPublicIp
 }
@@ -341,22 +403,32 @@ func (b *Builder) configureStateBag(stateBag multistep.StateBag) {
 	}
 	stateBag.Put(constants.ArmKeyVaultName, b.config.tmpKeyVaultName)
 	stateBag.Put(constants.ArmNicName, b.config.tmpNicName)
+	stateBag.Put(constants.ArmNsgRuleName, b.config.tmpNsgRuleName)
 	stateBag.Put(constants.ArmPublicIPAddressName, b.config.tmpPublicIPAddressName)
 	if b.config.TempResourceGroupName != "" && b.config.BuildResourceGroupName != "" {
 		stateBag.Put(constants.ArmDoubleResourceGroupNameSet, true)
 	}
-	if b.config.tmpResourceGroupName != "" {
-		stateBag.Put(constants.ArmResourceGroupName, b.config.tmpResourceGroupName)
+	resourceGroupName := b.config.tmpResourceGroupName
+	if resourceGroupName != "" {
+		stateBag.Put(constants.ArmResourceGroupName, resourceGroupName)
 		stateBag.Put(constants.ArmIsExistingResourceGroup, false)
 	} else {
-		stateBag.Put(constants.ArmResourceGroupName, b.config.BuildResourceGroupName)
+		resourceGroupName = b.config.BuildResourceGroupName
+		stateBag.Put(constants.ArmResourceGroupName, resourceGroupName)
 		stateBag.Put(constants.ArmIsExistingResourceGroup, true)
 	}
+	stateBag.Put("machine", &packer.Machine{
+		Cloud:         "azure",
+		ID:            b.config.tmpComputeName,
+		Region:        b.config.Location,
+		ResourceGroup: resourceGroupName,
+	})
 	stateBag.Put(constants.ArmStorageAccountName, b.config.StorageAccount)
 
 	stateBag.Put(constants.ArmIsManagedImage, b.config.isManagedImage())
 	stateBag.Put(constants.ArmManagedImageResourceGroupName, b.config.ManagedImageResourceGroupName)
 	stateBag.Put(constants.ArmManagedImageName, b.config.ManagedImageName)
+	stateBag.Put(constants.ArmManagedImageDiskEncryptionSetID, b.config.DiskEncryptionSetID)
 	stateBag.Put(constants.ArmAsyncResourceGroupDelete, b.config.AsyncResourceGroupDelete)
 }
 
@@ -364,6 +436,8 @@ func (b *Builder) configureStateBag(stateBag multistep.StateBag) {
 func (b *Builder) setRuntimeParameters(stateBag multistep.StateBag) {
 	stateBag.Put(constants.ArmLocation, b.config.Location)
 	stateBag.Put(constants.ArmManagedImageLocation, b.config.manageImageLocation)
+	stateBag.Put(constants.ArmManagedImageHyperVGeneration, b.config.hyperVGeneration())
+	stateBag.Put(constants.ArmManagedImageSkipCapture, b.config.SecurityType != "")
 }
 
 func (b *Builder) setTemplateParameters(stateBag multistep.StateBag) {
@@ -393,7 +467,17 @@ func (b *Builder) getServicePrincipalTokens(say func(string)) (*adal.ServicePrin
 		}
 
 	} else {
-		auth := NewAuthenticate(*b.config.cloudEnvironment, b.config.ClientID, b.config.ClientSecret, b.config.TenantID)
+		var auth *Authenticate
+		switch {
+		case b.config.UseManagedIdentity:
+			say("Getting tokens with the managed identity")
+			auth = NewManagedIdentityAuthenticate(*b.config.cloudEnvironment, b.config.ClientID)
+		case b.config.UseWorkloadIdentity:
+			say("Getting tokens with the workload identity")
+			auth = NewWorkloadIdentityAuthenticate(*b.config.cloudEnvironment, b.config.ClientID, b.config.TenantID, b.config.federatedToken)
+		default:
+			auth = NewAuthenticate(*b.config.cloudEnvironment, b.config.ClientID, b.config.ClientSecret, b.config.TenantID)
+		}
 
 		servicePrincipalToken, err = auth.getServicePrincipalToken()
 		if err != nil {
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"os"
 	"regexp"
 	"strings"
 	"time"
@@ -20,6 +21,7 @@ import (
 	"github.com/masterzen/winrm"
 
 	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/builder/azure/common/template"
 	"github.com/hashicorp/packer/builder/azure/pkcs12"
 	"github.com/hashicorp/packer/common"
 	commonhelper "github.com/hashicorp/packer/helper/common"
@@ -36,9 +38,19 @@ const (
 	DefaultImageVersion                      = "latest"
 	DefaultUserName                          = "packer"
 	DefaultPrivateVirtualNetworkWithPublicIp = false
+	DefaultSharedImageGalleryReplicaCount    = 1
+	DefaultSharedImageGalleryVersionBump     = "patch"
+	DefaultSpotMaxPrice                      = -1
+	DefaultSpotMaxRetries                    = 3
 	DefaultVMSize                            = "Standard_A1"
 )
 
+// These are the values of spot.on_eviction.
+const (
+	SpotOnEvictionFail  = "fail"
+	SpotOnEvictionRetry = "retry"
+)
+
 const (
 	// https://docs.microsoft.com/en-us/azure/architecture/best-practices/naming-conventions#naming-rules-and-restrictions
 	// Regular expressions in Go are not expressive enough, such that the regular expression returned by Azure
@@ -56,6 +68,9 @@ var (
 	reCaptureNamePrefix    = regexp.MustCompile("^[A-Za-z0-9][A-Za-z0-9_\\-\\.]{0,23}$")
 	reManagedDiskName      = regexp.MustCompile(validManagedDiskName)
 	reResourceGroupName    = regexp.MustCompile(validResourceGroupNameRe)
+
+	reSharedImageGalleryVersion = regexp.MustCompile("^[0-9]+\\.[0-9]+\\.[0-9]+$")
+	reDiskEncryptionSetID       = regexp.MustCompile("(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\\.Compute/diskEncryptionSets/[^/]+$")
 )
 
 type PlanInformation struct {
@@ -63,6 +78,42 @@ type PlanInformation struct {
 	PlanProduct       string `mapstructure:"plan_product"`
 	PlanPublisher     string `mapstructure:"plan_publisher"`
 	PlanPromotionCode string `mapstructure:"plan_promotion_code"`
+
+	// AcceptTerms accepts the terms of the plan for the subscription
+	// before the build, if they weren't already.
+	AcceptTerms bool `mapstructure:"accept_terms"`
+}
+
+type SharedImageGalleryTargetRegion struct {
+	Name                string `mapstructure:"name"`
+	ReplicaCount        int32  `mapstructure:"replica_count"`
+	StorageAccountType  string `mapstructure:"storage_account_type"`
+	DiskEncryptionSetID string `mapstructure:"disk_encryption_set_id"`
+}
+
+// SharedImageGalleryDestination is the image definition of a gallery a
+// version of the managed image is published to.
+type SharedImageGalleryDestination struct {
+	ResourceGroup      string                           `mapstructure:"resource_group"`
+	GalleryName        string                           `mapstructure:"gallery_name"`
+	ImageName          string                           `mapstructure:"image_name"`
+	ImageVersion       string                           `mapstructure:"image_version"`
+	VersionBump        string                           `mapstructure:"version_bump"`
+	ReplicaCount       int32                            `mapstructure:"replica_count"`
+	StorageAccountType string                           `mapstructure:"storage_account_type"`
+	TargetRegions      []SharedImageGalleryTargetRegion `mapstructure:"target_regions"`
+	ExcludeFromLatest  bool                             `mapstructure:"exclude_from_latest"`
+}
+
+// Spot makes the build VM a Spot VM, which Azure evicts when it needs the
+// capacity back or the price goes over the max price. A max price of -1
+// pays up to the price of a regular VM. An evicted VM either fails the
+// build, or is replaced by a new one the build is retried on.
+type Spot struct {
+	EvictionPolicy string  `mapstructure:"eviction_policy"`
+	MaxPrice       float64 `mapstructure:"max_price"`
+	OnEviction     string  `mapstructure:"on_eviction"`
+	MaxRetries     int     `mapstructure:"max_retries"`
 }
 
 type Config struct {
@@ -75,6 +126,13 @@ type Config struct {
 	TenantID       string `mapstructure:"tenant_id"`
 	SubscriptionID string `mapstructure:"subscription_id"`
 
+	// Authentication via the managed identity of the machine Packer runs
+	// on, or a federated token of a workload identity
+	UseManagedIdentity  bool   `mapstructure:"use_managed_identity"`
+	UseWorkloadIdentity bool   `mapstructure:"use_workload_identity"`
+	FederatedToken      string `mapstructure:"federated_token"`
+	FederatedTokenFile  string `mapstructure:"federated_token_file"`
+
 	// Capture
 	CaptureNamePrefix    string `mapstructure:"capture_name_prefix"`
 	CaptureContainerName string `mapstructure:"capture_container_name"`
@@ -99,6 +157,8 @@ type Config struct {
 	managedImageStorageAccountType compute.StorageAccountTypes
 	manageImageLocation            string
 
+	SharedGalleryDestination SharedImageGalleryDestination `mapstructure:"shared_image_gallery_destination"`
+
 	// Deployment
 	AzureTags                         map[string]*string `mapstructure:"azure_tags"`
 	ResourceGroupName                 string             `mapstructure:"resource_group_name"`
@@ -113,9 +173,26 @@ type Config struct {
 	VirtualNetworkName                string `mapstructure:"virtual_network_name"`
 	VirtualNetworkSubnetName          string `mapstructure:"virtual_network_subnet_name"`
 	VirtualNetworkResourceGroupName   string `mapstructure:"virtual_network_resource_group_name"`
+	ManageNsgRule                     bool   `mapstructure:"manage_nsg_rule"`
 	CustomDataFile                    string `mapstructure:"custom_data_file"`
 	customData                        string
 	PlanInfo                          PlanInformation `mapstructure:"plan_info"`
+	Spot                              Spot            `mapstructure:"spot"`
+
+	// Trusted launch and confidential VMs, secure boot and the vTPM are
+	// only set with a security type.
+	SecurityType      string `mapstructure:"security_type"`
+	SecureBootEnabled bool   `mapstructure:"secure_boot_enabled"`
+	VTpmEnabled       bool   `mapstructure:"vtpm_enabled"`
+
+	// The customer-managed keys of the disk encryption set encrypt the
+	// disks of the VM, the managed image and the replicas of the gallery
+	// image version in the location of the build.
+	DiskEncryptionSetID string `mapstructure:"disk_encryption_set_id"`
+
+	// BootDiagnostics enables the boot diagnostics of the VM, shown if the
+	// communicator can't connect to it.
+	BootDiagnostics bool `mapstructure:"boot_diagnostics"`
 
 	// OS
 	OSType       string `mapstructure:"os_type"`
@@ -132,6 +209,7 @@ type Config struct {
 	tmpResourceGroupName   string
 	tmpComputeName         string
 	tmpNicName             string
+	tmpNsgRuleName         string
 	tmpPublicIPAddressName string
 	tmpDeploymentName      string
 	tmpKeyVaultName        string
@@ -163,19 +241,44 @@ type keyVaultCertificate struct {
 }
 
 func (c *Config) toVMID() string {
-	var resourceGroupName string
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s", c.SubscriptionID, c.buildResourceGroupName(), c.tmpComputeName)
+}
+
+// toDiskID returns the ID of the managed disk of the VM with the name.
+func (c *Config) toDiskID(name string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/%s", c.SubscriptionID, c.buildResourceGroupName(), name)
+}
+
+// buildResourceGroupName is the resource group the VM is built in.
+func (c *Config) buildResourceGroupName() string {
 	if c.tmpResourceGroupName != "" {
-		resourceGroupName = c.tmpResourceGroupName
-	} else {
-		resourceGroupName = c.BuildResourceGroupName
+		return c.tmpResourceGroupName
 	}
-	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s", c.SubscriptionID, resourceGroupName, c.tmpComputeName)
+	return c.BuildResourceGroupName
 }
 
 func (c *Config) isManagedImage() bool {
 	return c.ManagedImageName != ""
 }
 
+// planInfo is the Marketplace plan of the source image, nil if it has none.
+func (c *Config) planInfo() *PlanInformation {
+	if c.PlanInfo.PlanName == "" {
+		return nil
+	}
+	return &c.PlanInfo
+}
+
+// hyperVGeneration is the Hyper-V generation of the managed image, V2 for
+// the images of trusted launch and confidential VMs which boot with UEFI.
+// The default generation of Azure is used otherwise.
+func (c *Config) hyperVGeneration() string {
+	if c.SecurityType != "" {
+		return "V2"
+	}
+	return ""
+}
+
 func (c *Config) toVirtualMachineCaptureParameters() *compute.VirtualMachineCaptureParameters {
 	return &compute.VirtualMachineCaptureParameters{
 		DestinationContainerName: &c.CaptureContainerName,
@@ -185,7 +288,7 @@ func (c *Config) toVirtualMachineCaptureParameters() *compute.VirtualMachineCapt
 }
 
 func (c *Config) toImageParameters() *compute.Image {
-	return &compute.Image{
+	image := &compute.Image{
 		ImageProperties: &compute.ImageProperties{
 			SourceVirtualMachine: &compute.SubResource{
 				ID: to.StringPtr(c.toVMID()),
@@ -194,6 +297,35 @@ func (c *Config) toImageParameters() *compute.Image {
 		Location: to.StringPtr(c.Location),
 		Tags:     c.AzureTags,
 	}
+
+	// The disk encryption set is set on the disks of the image, which are
+	// then made of the disks of the VM rather than of the VM
+	if c.DiskEncryptionSetID != "" {
+		osType := compute.Linux
+		if c.OSType == constants.Target_Windows {
+			osType = compute.Windows
+		}
+		image.SourceVirtualMachine = nil
+		image.StorageProfile = &compute.ImageStorageProfile{
+			OsDisk: &compute.ImageOSDisk{
+				OsType:      osType,
+				OsState:     compute.Generalized,
+				ManagedDisk: &compute.SubResource{ID: to.StringPtr(c.toDiskID(c.tmpOSDiskName))},
+			},
+		}
+		if len(c.AdditionalDiskSize) > 0 {
+			dataDisks := make([]compute.ImageDataDisk, len(c.AdditionalDiskSize))
+			for i := range dataDisks {
+				// The names of the data disks of the template
+				dataDisks[i].Lun = to.Int32Ptr(int32(i))
+				dataDisks[i].ManagedDisk = &compute.SubResource{
+					ID: to.StringPtr(c.toDiskID(fmt.Sprintf("datadisk-%d", i+1))),
+				}
+			}
+			image.StorageProfile.DataDisks = &dataDisks
+		}
+	}
+	return image
 }
 
 func (c *Config) createCertificate() (string, error) {
@@ -303,6 +435,7 @@ func newConfig(raws ...interface{}) (*Config, []string, error) {
 
 	assertRequiredParametersSet(&c, errs)
 	assertTagProperties(&c, errs)
+	assertSpot(&c, errs)
 	if errs != nil && len(errs.Errors) > 0 {
 		return nil, nil, errs
 	}
@@ -365,12 +498,19 @@ func setRuntimeValues(c *Config) {
 	commonhelper.SetSharedState("winrm_password", c.tmpAdminPassword, c.PackerConfig.PackerBuildName)
 
 	c.tmpCertificatePassword = tempName.CertificatePassword
+	c.tmpDeploymentName = tempName.DeploymentName
+	setTempNames(c, tempName)
+}
+
+// setTempNames sets the names of the temporary resources of the build, the
+// names of those the user didn't name are random. They are renewed when the
+// build is retried on a new Spot VM.
+func setTempNames(c *Config, tempName *TempName) {
 	if c.TempComputeName == "" {
 		c.tmpComputeName = tempName.ComputeName
 	} else {
 		c.tmpComputeName = c.TempComputeName
 	}
-	c.tmpDeploymentName = tempName.DeploymentName
 	// Only set tmpResourceGroupName if no name has been specified
 	if c.TempResourceGroupName == "" && c.BuildResourceGroupName == "" {
 		c.tmpResourceGroupName = tempName.ResourceGroupName
@@ -378,6 +518,7 @@ func setRuntimeValues(c *Config) {
 		c.tmpResourceGroupName = c.TempResourceGroupName
 	}
 	c.tmpNicName = tempName.NicName
+	c.tmpNsgRuleName = tempName.NsgRuleName
 	c.tmpPublicIPAddressName = tempName.PublicIPAddressName
 	c.tmpOSDiskName = tempName.OSDiskName
 	c.tmpSubnetName = tempName.SubnetName
@@ -463,6 +604,44 @@ func provideDefaultValues(c *Config) {
 	if c.CloudEnvironmentName == "" {
 		c.CloudEnvironmentName = DefaultCloudEnvironmentName
 	}
+
+	if c.SharedGalleryDestination.VersionBump == "" {
+		c.SharedGalleryDestination.VersionBump = DefaultSharedImageGalleryVersionBump
+	}
+
+	if c.SharedGalleryDestination.ReplicaCount == 0 {
+		c.SharedGalleryDestination.ReplicaCount = DefaultSharedImageGalleryReplicaCount
+	}
+
+	if c.SharedGalleryDestination.StorageAccountType == "" {
+		c.SharedGalleryDestination.StorageAccountType = string(compute.StorageAccountTypesStandardLRS)
+	}
+
+	// These are the variables the workload identity webhook of AKS sets in
+	// the pods of the service accounts of an identity.
+	if c.UseWorkloadIdentity {
+		if c.ClientID == "" {
+			c.ClientID = os.Getenv("AZURE_CLIENT_ID")
+		}
+		if c.TenantID == "" {
+			c.TenantID = os.Getenv("AZURE_TENANT_ID")
+		}
+		if c.FederatedToken == "" && c.FederatedTokenFile == "" {
+			c.FederatedTokenFile = os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+		}
+	}
+
+	if c.Spot.EvictionPolicy != "" {
+		if c.Spot.MaxPrice == 0 {
+			c.Spot.MaxPrice = DefaultSpotMaxPrice
+		}
+		if c.Spot.OnEviction == "" {
+			c.Spot.OnEviction = SpotOnEvictionFail
+		}
+		if c.Spot.OnEviction == SpotOnEvictionRetry && c.Spot.MaxRetries == 0 {
+			c.Spot.MaxRetries = DefaultSpotMaxRetries
+		}
+	}
 }
 
 func assertTagProperties(c *Config, errs *packer.MultiError) {
@@ -497,10 +676,16 @@ func assertRequiredParametersSet(c *Config, errs *packer.MultiError) {
 		return c.SubscriptionID != "" &&
 			c.ClientID == "" &&
 			c.ClientSecret == "" &&
-			c.TenantID == ""
+			c.TenantID == "" &&
+			!c.UseManagedIdentity &&
+			!c.UseWorkloadIdentity
 	}
 
-	if isUseDeviceLogin(c) {
+	if c.UseManagedIdentity || c.UseWorkloadIdentity {
+		assertIdentityParametersSet(c, errs)
+	} else if c.FederatedToken != "" || c.FederatedTokenFile != "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("A federated_token or federated_token_file can only be specified with use_workload_identity"))
+	} else if isUseDeviceLogin(c) {
 		c.useDeviceLogin = true
 	} else {
 		if c.ClientID == "" {
@@ -666,12 +851,15 @@ func assertRequiredParametersSet(c *Config, errs *packer.MultiError) {
 	if c.VirtualNetworkName == "" && c.VirtualNetworkSubnetName != "" {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("If virtual_network_subnet_name is specified, so must virtual_network_name"))
 	}
+	if c.ManageNsgRule && (c.VirtualNetworkName == "" || !c.PrivateVirtualNetworkWithPublicIp) {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("If manage_nsg_rule is specified, so must virtual_network_name and private_virtual_network_with_public_ip"))
+	}
 
 	/////////////////////////////////////////////
 	// Plan Info
-	if c.PlanInfo.PlanName != "" || c.PlanInfo.PlanProduct != "" || c.PlanInfo.PlanPublisher != "" || c.PlanInfo.PlanPromotionCode != "" {
+	if c.PlanInfo.PlanName != "" || c.PlanInfo.PlanProduct != "" || c.PlanInfo.PlanPublisher != "" || c.PlanInfo.PlanPromotionCode != "" || c.PlanInfo.AcceptTerms {
 		if c.PlanInfo.PlanName == "" || c.PlanInfo.PlanProduct == "" || c.PlanInfo.PlanPublisher == "" {
-			errs = packer.MultiErrorAppend(errs, fmt.Errorf("if either plan_name, plan_product, plan_publisher, or plan_promotion_code are defined then plan_name, plan_product, and plan_publisher must be defined"))
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("if either plan_name, plan_product, plan_publisher, plan_promotion_code or accept_terms are defined then plan_name, plan_product, and plan_publisher must be defined"))
 		} else {
 			if c.AzureTags == nil {
 				c.AzureTags = make(map[string]*string)
@@ -704,6 +892,169 @@ func assertRequiredParametersSet(c *Config, errs *packer.MultiError) {
 	default:
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("The managed_image_storage_account_type %q is invalid", c.ManagedImageStorageAccountType))
 	}
+
+	/////////////////////////////////////////////
+	// Shared Image Gallery
+	sig := &c.SharedGalleryDestination
+	if sig.GalleryName != "" || sig.ImageName != "" || sig.ResourceGroup != "" {
+		if !c.isManagedImage() {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A shared_image_gallery_destination is published from a managed image, managed_image_name must be specified"))
+		}
+		if sig.ResourceGroup == "" || sig.GalleryName == "" || sig.ImageName == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("The shared_image_gallery_destination resource_group, gallery_name and image_name must be specified"))
+		}
+		if sig.ImageVersion != "" && !reSharedImageGalleryVersion.MatchString(sig.ImageVersion) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("The shared_image_gallery_destination image_version %q must be of the form major.minor.patch", sig.ImageVersion))
+		}
+		switch sig.VersionBump {
+		case "major", "minor", "patch":
+		default:
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("The shared_image_gallery_destination version_bump %q must be one of major, minor or patch", sig.VersionBump))
+		}
+		if sig.ReplicaCount < 1 || sig.ReplicaCount > 10 {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("The shared_image_gallery_destination replica_count must be between 1 and 10"))
+		}
+		assertSharedImageGalleryStorageAccountType(sig.StorageAccountType, "shared_image_gallery_destination storage_account_type", errs)
+		for i, region := range sig.TargetRegions {
+			if region.Name == "" {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("The name of the shared_image_gallery_destination target region %d must be specified", i))
+			}
+			if region.ReplicaCount < 0 || region.ReplicaCount > 10 {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("The replica_count of the shared_image_gallery_destination target region %q must be between 1 and 10", region.Name))
+			}
+			if region.StorageAccountType != "" {
+				assertSharedImageGalleryStorageAccountType(region.StorageAccountType, fmt.Sprintf("storage_account_type of the shared_image_gallery_destination target region %q", region.Name), errs)
+			}
+			if region.DiskEncryptionSetID != "" && !reDiskEncryptionSetID.MatchString(region.DiskEncryptionSetID) {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("The disk_encryption_set_id of the shared_image_gallery_destination target region %q must be the resource ID of a disk encryption set", region.Name))
+			}
+		}
+	}
+
+	/////////////////////////////////////////////
+	// Security profile
+	switch c.SecurityType {
+	case "":
+		if c.SecureBootEnabled || c.VTpmEnabled {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A security_type must be specified to use secure_boot_enabled or vtpm_enabled"))
+		}
+	case template.SecurityTypeTrustedLaunch, template.SecurityTypeConfidentialVM:
+		if !c.isManagedImage() || c.ImageUrl != "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A security_type needs managed disks, managed_image_name must be specified and image_url must not be"))
+		}
+		// The VMs of a security type can't be captured to a managed image,
+		// the image version is published from the VM instead.
+		if c.SharedGalleryDestination.GalleryName == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A security_type needs a shared_image_gallery_destination, its VM can't be captured to a managed image"))
+		}
+		if c.SecurityType == template.SecurityTypeConfidentialVM && !c.VTpmEnabled {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A security_type of %q needs vtpm_enabled", c.SecurityType))
+		}
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("The security_type %q must be %s or %s", c.SecurityType, template.SecurityTypeTrustedLaunch, template.SecurityTypeConfidentialVM))
+	}
+
+	/////////////////////////////////////////////
+	// Disk encryption set
+	if c.DiskEncryptionSetID != "" {
+		if !c.isManagedImage() || c.ImageUrl != "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A disk_encryption_set_id needs managed disks, managed_image_name must be specified and image_url must not be"))
+		}
+		if !reDiskEncryptionSetID.MatchString(c.DiskEncryptionSetID) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("The disk_encryption_set_id %q must be the resource ID of a disk encryption set", c.DiskEncryptionSetID))
+		}
+	}
+}
+
+// assertIdentityParametersSet checks the settings of the authentication
+// with a managed identity, which is the user-assigned one of the client_id
+// if it is set, or with a workload identity.
+func assertIdentityParametersSet(c *Config, errs *packer.MultiError) {
+	if c.UseManagedIdentity && c.UseWorkloadIdentity {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Only one of use_managed_identity or use_workload_identity can be specified"))
+	}
+	if c.ClientSecret != "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("A client_secret can't be specified with use_managed_identity or use_workload_identity"))
+	}
+	if c.SubscriptionID == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("A subscription_id must be specified"))
+	}
+
+	if c.UseManagedIdentity {
+		if c.FederatedToken != "" || c.FederatedTokenFile != "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A federated_token or federated_token_file can only be specified with use_workload_identity"))
+		}
+		return
+	}
+
+	if c.ClientID == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("A client_id must be specified with use_workload_identity, or set in AZURE_CLIENT_ID"))
+	}
+	if c.TenantID == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("A tenant_id must be specified with use_workload_identity, or set in AZURE_TENANT_ID"))
+	}
+	if (c.FederatedToken == "") == (c.FederatedTokenFile == "") {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Either a federated_token or a federated_token_file must be specified with use_workload_identity, or AZURE_FEDERATED_TOKEN_FILE set"))
+	}
+}
+
+// federatedToken returns the token of the workload identity, read again
+// from its file each time since the file is rotated.
+func (c *Config) federatedToken() (string, error) {
+	if c.FederatedToken != "" {
+		return c.FederatedToken, nil
+	}
+	raw, err := ioutil.ReadFile(c.FederatedTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("Error reading the federated token: %s", err)
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+func assertSpot(c *Config, errs *packer.MultiError) {
+	spot := &c.Spot
+	if spot.EvictionPolicy == "" {
+		if spot.MaxPrice != 0 || spot.OnEviction != "" || spot.MaxRetries != 0 {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A spot.eviction_policy must be specified to use the other spot settings"))
+		}
+		return
+	}
+
+	switch spot.EvictionPolicy {
+	case template.SpotEvictionPolicyDeallocate, template.SpotEvictionPolicyDelete:
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("The spot.eviction_policy %q must be %s or %s", spot.EvictionPolicy, template.SpotEvictionPolicyDeallocate, template.SpotEvictionPolicyDelete))
+	}
+
+	if spot.MaxPrice != -1 && spot.MaxPrice <= 0 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("The spot.max_price must be -1 or greater than 0, not %v", spot.MaxPrice))
+	}
+
+	switch spot.OnEviction {
+	case SpotOnEvictionFail:
+		if spot.MaxRetries != 0 {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("The spot.max_retries can only be set with a spot.on_eviction of %q", SpotOnEvictionRetry))
+		}
+	case SpotOnEvictionRetry:
+		if spot.MaxRetries < 0 {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("The spot.max_retries must not be negative"))
+		}
+		// The resource group of a retry can't be created while the one of
+		// the evicted VM is still being deleted.
+		if c.TempResourceGroupName != "" && c.AsyncResourceGroupDelete {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A spot.on_eviction of %q can't be used with temp_resource_group_name and async_resourcegroup_delete", SpotOnEvictionRetry))
+		}
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("The spot.on_eviction %q must be %s or %s", spot.OnEviction, SpotOnEvictionFail, SpotOnEvictionRetry))
+	}
+}
+
+func assertSharedImageGalleryStorageAccountType(storageAccountType, setting string, errs *packer.MultiError) {
+	switch storageAccountType {
+	case string(compute.StorageAccountTypesStandardLRS), "Standard_ZRS":
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("The %s %q must be Standard_LRS or Standard_ZRS", setting, storageAccountType))
+	}
 }
 
 func assertManagedImageName(name, setting string) (bool, error) {
@@ -2,6 +2,8 @@ package arm
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
@@ -261,6 +263,40 @@ func TestConfigVirtualNetworkSubnetNameMustBeSetWithVirtualNetworkName(t *testin
 	}
 }
 
+// The rule of manage_nsg_rule lets Packer connect to the public IP address of
+// a VM in an existing virtual network.
+func TestConfigManageNsgRuleMustBeSetWithVirtualNetworkWithPublicIp(t *testing.T) {
+	config := map[string]interface{}{
+		"capture_name_prefix":    "ignore",
+		"capture_container_name": "ignore",
+		"location":               "ignore",
+		"image_url":              "ignore",
+		"storage_account":        "ignore",
+		"resource_group_name":    "ignore",
+		"subscription_id":        "ignore",
+		"os_type":                constants.Target_Linux,
+		"communicator":           "none",
+		"manage_nsg_rule":        true,
+	}
+
+	_, _, err := newConfig(config, getPackerConfiguration())
+	if err == nil {
+		t.Error("Expected Config to reject manage_nsg_rule, if virtual_network_name is not set.")
+	}
+
+	config["virtual_network_name"] = "MyVirtualNetwork"
+	_, _, err = newConfig(config, getPackerConfiguration())
+	if err == nil {
+		t.Error("Expected Config to reject manage_nsg_rule, if private_virtual_network_with_public_ip is not set.")
+	}
+
+	config["private_virtual_network_with_public_ip"] = true
+	_, _, err = newConfig(config, getPackerConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestConfigShouldDefaultToPublicCloud(t *testing.T) {
 	c, _, _ := newConfig(getArmBuilderConfiguration(), getPackerConfiguration())
 
@@ -864,6 +900,307 @@ func TestConfigShouldAcceptManagedImageStorageAccountTypes(t *testing.T) {
 	}
 }
 
+func TestConfigShouldAcceptSharedImageGalleryDestination(t *testing.T) {
+	config := map[string]interface{}{
+		"image_offer":                       "ignore",
+		"image_publisher":                   "ignore",
+		"image_sku":                         "ignore",
+		"location":                          "ignore",
+		"subscription_id":                   "ignore",
+		"communicator":                      "none",
+		"managed_image_resource_group_name": "ignore",
+		"managed_image_name":                "ignore",
+		"shared_image_gallery_destination": map[string]interface{}{
+			"resource_group": "ignore",
+			"gallery_name":   "ignore",
+			"image_name":     "ignore",
+			"target_regions": []map[string]interface{}{
+				{"name": "eastus", "replica_count": 3},
+				{"name": "westeurope", "storage_account_type": "Standard_ZRS"},
+			},
+		},
+
+		// Does not matter for this test case, just pick one.
+		"os_type": constants.Target_Linux,
+	}
+
+	c, _, err := newConfig(config, getPackerConfiguration())
+	if err != nil {
+		t.Fatalf("expected config to accept a shared_image_gallery_destination: %s", err)
+	}
+
+	sig := c.SharedGalleryDestination
+	if sig.VersionBump != DefaultSharedImageGalleryVersionBump {
+		t.Errorf("Expected 'version_bump' to default to %q, but got %q.", DefaultSharedImageGalleryVersionBump, sig.VersionBump)
+	}
+	if sig.ReplicaCount != DefaultSharedImageGalleryReplicaCount {
+		t.Errorf("Expected 'replica_count' to default to %d, but got %d.", DefaultSharedImageGalleryReplicaCount, sig.ReplicaCount)
+	}
+	if sig.StorageAccountType != "Standard_LRS" {
+		t.Errorf("Expected 'storage_account_type' to default to 'Standard_LRS', but got %q.", sig.StorageAccountType)
+	}
+	if len(sig.TargetRegions) != 2 || sig.TargetRegions[0].ReplicaCount != 3 {
+		t.Errorf("Expected the target regions to be decoded, but got %v.", sig.TargetRegions)
+	}
+}
+
+func TestConfigShouldRejectInvalidSharedImageGalleryDestination(t *testing.T) {
+	invalid := []map[string]interface{}{
+		// missing the image definition
+		{"resource_group": "ignore", "gallery_name": "ignore"},
+		{"resource_group": "ignore", "gallery_name": "ignore", "image_name": "ignore", "image_version": "latest"},
+		{"resource_group": "ignore", "gallery_name": "ignore", "image_name": "ignore", "version_bump": "build"},
+		{"resource_group": "ignore", "gallery_name": "ignore", "image_name": "ignore", "replica_count": 11},
+		{"resource_group": "ignore", "gallery_name": "ignore", "image_name": "ignore", "storage_account_type": "Premium_LRS"},
+		{"resource_group": "ignore", "gallery_name": "ignore", "image_name": "ignore", "target_regions": []map[string]interface{}{{"replica_count": 2}}},
+	}
+
+	for _, sig := range invalid {
+		config := map[string]interface{}{
+			"image_offer":                       "ignore",
+			"image_publisher":                   "ignore",
+			"image_sku":                         "ignore",
+			"location":                          "ignore",
+			"subscription_id":                   "ignore",
+			"communicator":                      "none",
+			"managed_image_resource_group_name": "ignore",
+			"managed_image_name":                "ignore",
+			"shared_image_gallery_destination":  sig,
+
+			// Does not matter for this test case, just pick one.
+			"os_type": constants.Target_Linux,
+		}
+
+		_, _, err := newConfig(config, getPackerConfiguration())
+		if err == nil {
+			t.Errorf("expected config to reject the shared_image_gallery_destination %v", sig)
+		}
+	}
+}
+
+func TestConfigShouldRejectSharedImageGalleryDestinationWithoutManagedImage(t *testing.T) {
+	config := map[string]interface{}{
+		"capture_name_prefix":    "ignore",
+		"capture_container_name": "ignore",
+		"image_offer":            "ignore",
+		"image_publisher":        "ignore",
+		"image_sku":              "ignore",
+		"location":               "ignore",
+		"storage_account":        "ignore",
+		"resource_group_name":    "ignore",
+		"subscription_id":        "ignore",
+		"communicator":           "none",
+
+		// Does not matter for this test case, just pick one.
+		"os_type": constants.Target_Linux,
+		"shared_image_gallery_destination": map[string]interface{}{
+			"resource_group": "ignore",
+			"gallery_name":   "ignore",
+			"image_name":     "ignore",
+		},
+	}
+
+	_, _, err := newConfig(config, getPackerConfiguration())
+	if err == nil {
+		t.Fatal("expected config to reject a shared_image_gallery_destination of a VHD build")
+	}
+}
+
+func TestConfigShouldAcceptSecurityTypes(t *testing.T) {
+	config := map[string]interface{}{
+		"image_offer":                       "ignore",
+		"image_publisher":                   "ignore",
+		"image_sku":                         "ignore",
+		"location":                          "ignore",
+		"subscription_id":                   "ignore",
+		"communicator":                      "none",
+		"managed_image_resource_group_name": "ignore",
+		"managed_image_name":                "ignore",
+		"vtpm_enabled":                      true,
+
+		// Does not matter for this test case, just pick one.
+		"os_type": constants.Target_Linux,
+		"shared_image_gallery_destination": map[string]interface{}{
+			"resource_group": "ignore",
+			"gallery_name":   "ignore",
+			"image_name":     "ignore",
+		},
+	}
+
+	for _, x := range []string{"TrustedLaunch", "ConfidentialVM"} {
+		config["security_type"] = x
+		c, _, err := newConfig(config, getPackerConfiguration())
+		if err != nil {
+			t.Fatalf("expected config to accept a security_type of %q: %s", x, err)
+		}
+		if c.hyperVGeneration() != "V2" {
+			t.Errorf("expected the managed image of a security_type of %q to be of the Hyper-V generation V2", x)
+		}
+	}
+}
+
+func TestConfigShouldRejectInvalidSecurityProfiles(t *testing.T) {
+	sig := map[string]interface{}{
+		"resource_group": "ignore",
+		"gallery_name":   "ignore",
+		"image_name":     "ignore",
+	}
+	invalid := []map[string]interface{}{
+		{"security_type": "Standard"},
+		{"secure_boot_enabled": true},
+		{"vtpm_enabled": true},
+		// a confidential VM needs a vTPM
+		{"security_type": "ConfidentialVM", "secure_boot_enabled": true, "shared_image_gallery_destination": sig},
+		// a trusted launch VM can't be captured to a managed image
+		{"security_type": "TrustedLaunch"},
+	}
+
+	for _, profile := range invalid {
+		config := map[string]interface{}{
+			"image_offer":                       "ignore",
+			"image_publisher":                   "ignore",
+			"image_sku":                         "ignore",
+			"location":                          "ignore",
+			"subscription_id":                   "ignore",
+			"communicator":                      "none",
+			"managed_image_resource_group_name": "ignore",
+			"managed_image_name":                "ignore",
+
+			// Does not matter for this test case, just pick one.
+			"os_type": constants.Target_Linux,
+		}
+		for k, v := range profile {
+			config[k] = v
+		}
+
+		_, _, err := newConfig(config, getPackerConfiguration())
+		if err == nil {
+			t.Errorf("expected config to reject the security profile %v", profile)
+		}
+	}
+}
+
+func TestConfigShouldRejectSecurityTypeForVhdBuild(t *testing.T) {
+	config := map[string]interface{}{
+		"capture_name_prefix":    "ignore",
+		"capture_container_name": "ignore",
+		"image_offer":            "ignore",
+		"image_publisher":        "ignore",
+		"image_sku":              "ignore",
+		"location":               "ignore",
+		"storage_account":        "ignore",
+		"resource_group_name":    "ignore",
+		"subscription_id":        "ignore",
+		"communicator":           "none",
+		"security_type":          "TrustedLaunch",
+
+		// Does not matter for this test case, just pick one.
+		"os_type": constants.Target_Linux,
+	}
+
+	_, _, err := newConfig(config, getPackerConfiguration())
+	if err == nil {
+		t.Fatal("expected config to reject a security_type of a VHD build")
+	}
+}
+
+func TestConfigShouldAcceptDiskEncryptionSet(t *testing.T) {
+	config := map[string]interface{}{
+		"image_offer":                       "ignore",
+		"image_publisher":                   "ignore",
+		"image_sku":                         "ignore",
+		"location":                          "ignore",
+		"subscription_id":                   "ignore",
+		"communicator":                      "none",
+		"managed_image_resource_group_name": "ignore",
+		"managed_image_name":                "ignore",
+		"disk_encryption_set_id":            "/subscriptions/ignore/resourceGroups/ignore/providers/Microsoft.Compute/diskEncryptionSets/ignore",
+		"os_type":                           constants.Target_Windows,
+	}
+
+	c, _, err := newConfig(config, getPackerConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image := c.toImageParameters()
+	if image.StorageProfile == nil || image.StorageProfile.OsDisk.OsType != compute.Windows || image.StorageProfile.OsDisk.OsState != compute.Generalized {
+		t.Fatalf("Expected the managed image to have a generalized Windows OS disk to encrypt, but got %#v", image.StorageProfile)
+	}
+	if image.SourceVirtualMachine != nil {
+		t.Errorf("Expected the managed image to be made of the disks of the VM, but got the VM %q", *image.SourceVirtualMachine.ID)
+	}
+	expectedOSDiskID := c.toDiskID(c.tmpOSDiskName)
+	if disk := image.StorageProfile.OsDisk.ManagedDisk; disk == nil || *disk.ID != expectedOSDiskID {
+		t.Errorf("Expected the OS disk of the managed image to be made of %q, but got %#v", expectedOSDiskID, disk)
+	}
+
+	disks := map[string][]int32{"disk_additional_size": {32, 64}}
+	c, _, err = newConfig(config, disks, getPackerConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	image = c.toImageParameters()
+	if image.StorageProfile.DataDisks == nil || len(*image.StorageProfile.DataDisks) != 2 {
+		t.Fatalf("Expected the managed image to have 2 data disks, but got %#v", image.StorageProfile.DataDisks)
+	}
+	dataDisk := (*image.StorageProfile.DataDisks)[1]
+	if *dataDisk.Lun != 1 || *dataDisk.ManagedDisk.ID != c.toDiskID("datadisk-2") {
+		t.Errorf("Expected the second data disk to be made of datadisk-2, but got %#v", dataDisk)
+	}
+}
+
+func TestConfigShouldRejectInvalidDiskEncryptionSet(t *testing.T) {
+	cases := []map[string]interface{}{
+		// Not the ID of a disk encryption set
+		{
+			"managed_image_resource_group_name": "ignore",
+			"managed_image_name":                "ignore",
+			"disk_encryption_set_id":            "/subscriptions/ignore/resourceGroups/ignore/providers/Microsoft.KeyVault/vaults/ignore",
+		},
+		// A VHD build
+		{
+			"capture_name_prefix":    "ignore",
+			"capture_container_name": "ignore",
+			"storage_account":        "ignore",
+			"resource_group_name":    "ignore",
+			"disk_encryption_set_id": "/subscriptions/ignore/resourceGroups/ignore/providers/Microsoft.Compute/diskEncryptionSets/ignore",
+		},
+		// A gallery target region with an invalid disk encryption set
+		{
+			"managed_image_resource_group_name": "ignore",
+			"managed_image_name":                "ignore",
+			"shared_image_gallery_destination": map[string]interface{}{
+				"resource_group": "ignore",
+				"gallery_name":   "ignore",
+				"image_name":     "ignore",
+				"target_regions": []map[string]interface{}{
+					{"name": "eastus", "disk_encryption_set_id": "ignore"},
+				},
+			},
+		},
+	}
+
+	for i, x := range cases {
+		config := map[string]interface{}{
+			"image_offer":     "ignore",
+			"image_publisher": "ignore",
+			"image_sku":       "ignore",
+			"location":        "ignore",
+			"subscription_id": "ignore",
+			"communicator":    "none",
+			"os_type":         constants.Target_Linux,
+		}
+		for k, v := range x {
+			config[k] = v
+		}
+
+		if _, _, err := newConfig(config, getPackerConfiguration()); err == nil {
+			t.Errorf("Case %d: expected config to reject the disk encryption set", i)
+		}
+	}
+}
+
 func TestConfigShouldRejectTempAndBuildResourceGroupName(t *testing.T) {
 	config := map[string]interface{}{
 		"capture_name_prefix":    "ignore",
@@ -1156,6 +1493,42 @@ func TestPlanInfoPromotionCode(t *testing.T) {
 	}
 }
 
+func TestPlanInfoAcceptTerms(t *testing.T) {
+	planInfo := map[string]interface{}{
+		"accept_terms": true,
+	}
+	config := map[string]interface{}{
+		"capture_name_prefix":    "ignore",
+		"capture_container_name": "ignore",
+		"image_offer":            "ignore",
+		"image_publisher":        "ignore",
+		"image_sku":              "ignore",
+		"location":               "ignore",
+		"storage_account":        "ignore",
+		"resource_group_name":    "ignore",
+		"subscription_id":        "ignore",
+		"os_type":                "linux",
+		"communicator":           "none",
+		"plan_info":              planInfo,
+	}
+
+	_, _, err := newConfig(config, getPackerConfiguration())
+	if err == nil {
+		t.Fatal("expected config to reject the use of accept_terms without a plan")
+	}
+
+	planInfo["plan_name"] = "--plan-name--"
+	planInfo["plan_product"] = "--plan-product--"
+	planInfo["plan_publisher"] = "--plan-publisher--"
+	c, _, err := newConfig(config, getPackerConfiguration())
+	if err != nil {
+		t.Fatalf("expected config to accept accept_terms with a plan: %s", err)
+	}
+	if !c.PlanInfo.AcceptTerms {
+		t.Fatal("Expected AcceptTerms to be true")
+	}
+}
+
 // plan_info defines 3 or 4 tags based on plan data.
 // The user can define up to 15 tags.  If the combination of these two
 // exceeds the max tag amount, the builder should reject the configuration.
@@ -1296,6 +1669,161 @@ func TestConfigShouldAllowAsyncResourceGroupOverrideBadValue(t *testing.T) {
 
 }
 
+func TestConfigShouldDefaultSpot(t *testing.T) {
+	cases := []struct {
+		spot       map[string]interface{}
+		maxPrice   float64
+		onEviction string
+		maxRetries int
+	}{
+		{
+			spot:       map[string]interface{}{"eviction_policy": "Deallocate"},
+			maxPrice:   DefaultSpotMaxPrice,
+			onEviction: SpotOnEvictionFail,
+			maxRetries: 0,
+		},
+		{
+			spot:       map[string]interface{}{"eviction_policy": "Delete", "max_price": 0.1, "on_eviction": "retry"},
+			maxPrice:   0.1,
+			onEviction: SpotOnEvictionRetry,
+			maxRetries: DefaultSpotMaxRetries,
+		},
+	}
+
+	for _, tc := range cases {
+		spot := map[string]interface{}{"spot": tc.spot}
+		c, _, err := newConfig(getArmBuilderConfiguration(), spot, getPackerConfiguration())
+		if err != nil {
+			t.Fatalf("expected config to accept the spot settings %v: %s", tc.spot, err)
+		}
+		if c.Spot.MaxPrice != tc.maxPrice {
+			t.Errorf("Expected MaxPrice to be %v, but got %v", tc.maxPrice, c.Spot.MaxPrice)
+		}
+		if c.Spot.OnEviction != tc.onEviction {
+			t.Errorf("Expected OnEviction to be %q, but got %q", tc.onEviction, c.Spot.OnEviction)
+		}
+		if c.Spot.MaxRetries != tc.maxRetries {
+			t.Errorf("Expected MaxRetries to be %d, but got %d", tc.maxRetries, c.Spot.MaxRetries)
+		}
+	}
+}
+
+func TestConfigShouldRejectInvalidSpot(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"spot": map[string]interface{}{"max_price": 0.1}},
+		{"spot": map[string]interface{}{"eviction_policy": "Stop"}},
+		{"spot": map[string]interface{}{"eviction_policy": "Delete", "max_price": -2}},
+		{"spot": map[string]interface{}{"eviction_policy": "Delete", "on_eviction": "wait"}},
+		{"spot": map[string]interface{}{"eviction_policy": "Delete", "max_retries": 2}},
+		{"spot": map[string]interface{}{"eviction_policy": "Delete", "on_eviction": "retry", "max_retries": -1}},
+		// The resource group of an evicted VM is deleted before retrying
+		{
+			"spot":                       map[string]interface{}{"eviction_policy": "Delete", "on_eviction": "retry"},
+			"temp_resource_group_name":   "ignore",
+			"async_resourcegroup_delete": true,
+		},
+	}
+
+	for _, settings := range cases {
+		_, _, err := newConfig(getArmBuilderConfiguration(), settings, getPackerConfiguration())
+		if err == nil {
+			t.Errorf("expected config to reject the settings %v", settings)
+		}
+	}
+}
+
+func TestConfigShouldAcceptIdentity(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"use_managed_identity": true},
+		// A user-assigned managed identity
+		{"use_managed_identity": true, "client_id": "ignore"},
+		{"use_workload_identity": true, "client_id": "ignore", "tenant_id": "ignore", "federated_token": "ignore"},
+	}
+
+	for _, settings := range cases {
+		config := getArmBuilderConfiguration()
+		delete(config, "client_id")
+		delete(config, "client_secret")
+
+		c, _, err := newConfig(config, settings, getPackerConfiguration())
+		if err != nil {
+			t.Fatalf("expected config to accept the settings %v: %s", settings, err)
+		}
+		if c.useDeviceLogin {
+			t.Errorf("Expected the settings %v to not use the device login", settings)
+		}
+	}
+}
+
+func TestConfigShouldAcceptWorkloadIdentityFromEnvironment(t *testing.T) {
+	os.Setenv("AZURE_CLIENT_ID", "client")
+	os.Setenv("AZURE_TENANT_ID", "tenant")
+	os.Setenv("AZURE_FEDERATED_TOKEN_FILE", "/var/run/secrets/azure/tokens/azure-identity-token")
+	defer os.Unsetenv("AZURE_CLIENT_ID")
+	defer os.Unsetenv("AZURE_TENANT_ID")
+	defer os.Unsetenv("AZURE_FEDERATED_TOKEN_FILE")
+
+	config := getArmBuilderConfiguration()
+	delete(config, "client_id")
+	delete(config, "client_secret")
+	identity := map[string]interface{}{"use_workload_identity": true}
+
+	c, _, err := newConfig(config, identity, getPackerConfiguration())
+	if err != nil {
+		t.Fatalf("expected config to accept a workload identity: %s", err)
+	}
+	if c.ClientID != "client" || c.TenantID != "tenant" || c.FederatedTokenFile != "/var/run/secrets/azure/tokens/azure-identity-token" {
+		t.Errorf("Expected the workload identity to be read from the environment, but got %q, %q and %q", c.ClientID, c.TenantID, c.FederatedTokenFile)
+	}
+}
+
+func TestConfigShouldRejectInvalidIdentity(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"use_managed_identity": true, "client_secret": "ignore"},
+		{"use_managed_identity": true, "use_workload_identity": true, "client_id": "ignore", "tenant_id": "ignore", "federated_token": "ignore"},
+		{"use_managed_identity": true, "federated_token": "ignore"},
+		{"use_workload_identity": true, "tenant_id": "ignore", "federated_token": "ignore"},
+		{"use_workload_identity": true, "client_id": "ignore", "federated_token": "ignore"},
+		{"use_workload_identity": true, "client_id": "ignore", "tenant_id": "ignore"},
+		{"use_workload_identity": true, "client_id": "ignore", "tenant_id": "ignore", "federated_token": "ignore", "federated_token_file": "ignore"},
+		{"client_id": "ignore", "client_secret": "ignore", "federated_token": "ignore"},
+	}
+
+	for _, settings := range cases {
+		config := getArmBuilderConfiguration()
+		delete(config, "client_id")
+		delete(config, "client_secret")
+
+		if _, _, err := newConfig(config, settings, getPackerConfiguration()); err == nil {
+			t.Errorf("expected config to reject the settings %v", settings)
+		}
+	}
+}
+
+func TestConfigFederatedTokenShouldBeReadFromFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("token\n")
+	f.Close()
+
+	c := &Config{FederatedTokenFile: f.Name()}
+	token, err := c.federatedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token" {
+		t.Errorf("Expected the token to be %q, but got %q", "token", token)
+	}
+
+	c = &Config{FederatedTokenFile: f.Name() + ".missing"}
+	if _, err := c.federatedToken(); err == nil {
+		t.Error("Expected an error reading a missing file")
+	}
+}
+
 func getArmBuilderConfiguration() map[string]string {
 	m := make(map[string]string)
 	for _, v := range requiredConfigValues {
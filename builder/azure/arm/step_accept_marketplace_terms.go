@@ -0,0 +1,87 @@
+package arm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/builder/azure/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepAcceptMarketplaceTerms accepts the terms of the plan of the
+// Marketplace image for the subscription, without which the VM can't be
+// deployed.
+type StepAcceptMarketplaceTerms struct {
+	client *AzureClient
+	config *Config
+	get    func(ctx context.Context, publisher, offer, plan string) (common.MarketplaceAgreement, error)
+	accept func(ctx context.Context, publisher, offer, plan string, agreement common.MarketplaceAgreement) error
+	say    func(message string)
+	error  func(e error)
+}
+
+func NewStepAcceptMarketplaceTerms(client *AzureClient, ui packer.Ui, config *Config) *StepAcceptMarketplaceTerms {
+	var step = &StepAcceptMarketplaceTerms{
+		client: client,
+		config: config,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.get = step.getAgreement
+	step.accept = step.acceptAgreement
+	return step
+}
+
+func (s *StepAcceptMarketplaceTerms) getAgreement(ctx context.Context, publisher, offer, plan string) (common.MarketplaceAgreement, error) {
+	agreement, err := s.client.MarketplaceAgreementsClient.Get(ctx, publisher, offer, plan)
+	if err != nil {
+		s.say(s.client.LastError.Error())
+	}
+	return agreement, err
+}
+
+func (s *StepAcceptMarketplaceTerms) acceptAgreement(ctx context.Context, publisher, offer, plan string, agreement common.MarketplaceAgreement) error {
+	_, err := s.client.MarketplaceAgreementsClient.Create(ctx, publisher, offer, plan, agreement)
+	if err != nil {
+		s.say(s.client.LastError.Error())
+	}
+	return err
+}
+
+func (s *StepAcceptMarketplaceTerms) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	plan := s.config.PlanInfo
+
+	s.say("Accepting the Marketplace terms ...")
+	s.say(fmt.Sprintf(" -> Plan Publisher : '%s'", plan.PlanPublisher))
+	s.say(fmt.Sprintf(" -> Plan Product   : '%s'", plan.PlanProduct))
+	s.say(fmt.Sprintf(" -> Plan Name      : '%s'", plan.PlanName))
+
+	agreement, err := s.get(ctx, plan.PlanPublisher, plan.PlanProduct, plan.PlanName)
+	if err != nil {
+		return processStepResult(fmt.Errorf("Error reading the Marketplace terms of the plan %s: %s", plan.PlanName, err), s.error, state)
+	}
+	if agreement.Properties == nil {
+		agreement.Properties = &common.MarketplaceAgreementProperties{}
+	}
+
+	if to.Bool(agreement.Properties.Accepted) {
+		s.say(" -> The terms are already accepted")
+		return multistep.ActionContinue
+	}
+	if agreement.Properties.LicenseTextLink != nil {
+		s.say(fmt.Sprintf(" -> License        : '%s'", *agreement.Properties.LicenseTextLink))
+	}
+
+	agreement.Properties.Accepted = to.BoolPtr(true)
+	err = s.accept(ctx, plan.PlanPublisher, plan.PlanProduct, plan.PlanName, agreement)
+	if err != nil {
+		return processStepResult(fmt.Errorf("Error accepting the Marketplace terms of the plan %s: %s", plan.PlanName, err), s.error, state)
+	}
+	return multistep.ActionContinue
+}
+
+func (*StepAcceptMarketplaceTerms) Cleanup(multistep.StateBag) {
+}
@@ -0,0 +1,107 @@
+package arm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/builder/azure/common"
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func createTestConfigStepAcceptMarketplaceTerms() *Config {
+	return &Config{
+		PlanInfo: PlanInformation{
+			PlanName:      "Unit Test: PlanName",
+			PlanProduct:   "Unit Test: PlanProduct",
+			PlanPublisher: "Unit Test: PlanPublisher",
+			AcceptTerms:   true,
+		},
+	}
+}
+
+func TestStepAcceptMarketplaceTermsShouldAcceptTheTerms(t *testing.T) {
+	var actualPublisher, actualOffer, actualPlan string
+	var actualAgreement common.MarketplaceAgreement
+
+	var testSubject = &StepAcceptMarketplaceTerms{
+		config: createTestConfigStepAcceptMarketplaceTerms(),
+		get: func(context.Context, string, string, string) (common.MarketplaceAgreement, error) {
+			return common.MarketplaceAgreement{
+				Properties: &common.MarketplaceAgreementProperties{
+					Signature: to.StringPtr("Unit Test: Signature"),
+					Accepted:  to.BoolPtr(false),
+				},
+			}, nil
+		},
+		accept: func(_ context.Context, publisher, offer, plan string, agreement common.MarketplaceAgreement) error {
+			actualPublisher, actualOffer, actualPlan = publisher, offer, plan
+			actualAgreement = agreement
+			return nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := new(multistep.BasicStateBag)
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+
+	if actualPublisher != "Unit Test: PlanPublisher" || actualOffer != "Unit Test: PlanProduct" || actualPlan != "Unit Test: PlanName" {
+		t.Fatalf("Expected the terms of the plan to be accepted, but got %q, %q and %q.", actualPublisher, actualOffer, actualPlan)
+	}
+	if !to.Bool(actualAgreement.Properties.Accepted) || to.String(actualAgreement.Properties.Signature) != "Unit Test: Signature" {
+		t.Fatalf("Expected the terms as they were read to be accepted, but got %#v.", actualAgreement.Properties)
+	}
+}
+
+func TestStepAcceptMarketplaceTermsShouldNotAcceptTheTermsTwice(t *testing.T) {
+	var testSubject = &StepAcceptMarketplaceTerms{
+		config: createTestConfigStepAcceptMarketplaceTerms(),
+		get: func(context.Context, string, string, string) (common.MarketplaceAgreement, error) {
+			return common.MarketplaceAgreement{
+				Properties: &common.MarketplaceAgreementProperties{Accepted: to.BoolPtr(true)},
+			}, nil
+		},
+		accept: func(context.Context, string, string, string, common.MarketplaceAgreement) error {
+			t.Fatal("Expected the step to not accept the terms again, but it did.")
+			return nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := new(multistep.BasicStateBag)
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+}
+
+func TestStepAcceptMarketplaceTermsShouldFailIfAcceptFails(t *testing.T) {
+	var testSubject = &StepAcceptMarketplaceTerms{
+		config: createTestConfigStepAcceptMarketplaceTerms(),
+		get: func(context.Context, string, string, string) (common.MarketplaceAgreement, error) {
+			return common.MarketplaceAgreement{}, nil
+		},
+		accept: func(context.Context, string, string, string, common.MarketplaceAgreement) error {
+			return fmt.Errorf("!! Unit Test FAIL !!")
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := new(multistep.BasicStateBag)
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionHalt {
+		t.Fatalf("Expected the step to return 'ActionHalt', but got '%d'.", result)
+	}
+
+	if _, ok := stateBag.GetOk(constants.Error); ok == false {
+		t.Fatalf("Expected the step to set stateBag['%s'], but it was not.", constants.Error)
+	}
+}
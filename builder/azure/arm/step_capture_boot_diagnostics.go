@@ -0,0 +1,182 @@
+package arm
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The number of lines of the end of the serial log shown in the build
+// output, the whole log is saved to a file.
+const bootDiagnosticsLogLines = 50
+
+type bootDiagnostics struct {
+	screenshot []byte
+	serialLog  []byte
+}
+
+// StepCaptureBootDiagnostics does nothing on run. When the communicator
+// never connected to the VM, it shows the end of its serial log and saves
+// the serial log and the screenshot of the console on cleanup, before the VM
+// is deleted.
+type StepCaptureBootDiagnostics struct {
+	client  *AzureClient
+	config  *Config
+	get     func(ctx context.Context, resourceGroupName, computeName string) (*bootDiagnostics, error)
+	write   func(path string, data []byte) error
+	say     func(message string)
+	message func(message string)
+	error   func(e error)
+}
+
+func NewStepCaptureBootDiagnostics(client *AzureClient, ui packer.Ui, config *Config) *StepCaptureBootDiagnostics {
+	var step = &StepCaptureBootDiagnostics{
+		client: client,
+		config: config,
+		write: func(path string, data []byte) error {
+			return ioutil.WriteFile(path, data, 0644)
+		},
+		say:     func(message string) { ui.Say(message) },
+		message: func(message string) { ui.Message(message) },
+		error:   func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.get = step.getBootDiagnostics
+	return step
+}
+
+// getBootDiagnostics reads the boot diagnostics from the storage account of
+// the build, or from the storage managed by Azure for a managed build.
+func (s *StepCaptureBootDiagnostics) getBootDiagnostics(ctx context.Context, resourceGroupName, computeName string) (*bootDiagnostics, error) {
+	if s.config.isManagedImage() {
+		data, err := s.client.BootDiagnosticsClient.RetrieveBootDiagnosticsData(ctx, resourceGroupName, computeName)
+		if err != nil {
+			s.say(s.client.LastError.Error())
+			return nil, err
+		}
+
+		var diagnostics bootDiagnostics
+		if diagnostics.screenshot, err = downloadSasUri(to.String(data.ConsoleScreenshotBlobURI)); err != nil {
+			return nil, err
+		}
+		if diagnostics.serialLog, err = downloadSasUri(to.String(data.SerialConsoleLogBlobURI)); err != nil {
+			return nil, err
+		}
+		return &diagnostics, nil
+	}
+
+	instanceView, err := s.client.VirtualMachinesClient.InstanceView(ctx, resourceGroupName, computeName)
+	if err != nil {
+		s.say(s.client.LastError.Error())
+		return nil, err
+	}
+	if instanceView.BootDiagnostics == nil {
+		return nil, fmt.Errorf("The VM has no boot diagnostics")
+	}
+
+	var diagnostics bootDiagnostics
+	if diagnostics.screenshot, err = s.downloadBlob(to.String(instanceView.BootDiagnostics.ConsoleScreenshotBlobURI)); err != nil {
+		return nil, err
+	}
+	if diagnostics.serialLog, err = s.downloadBlob(to.String(instanceView.BootDiagnostics.SerialConsoleLogBlobURI)); err != nil {
+		return nil, err
+	}
+	return &diagnostics, nil
+}
+
+func (s *StepCaptureBootDiagnostics) downloadBlob(uri string) ([]byte, error) {
+	if uri == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	xs := strings.Split(u.Path, "/")
+	if len(xs) < 3 {
+		return nil, fmt.Errorf("Failed to parse the boot diagnostics URI %s", uri)
+	}
+	blob := s.client.BlobStorageClient.GetContainerReference(xs[1]).GetBlobReference(strings.Join(xs[2:], "/"))
+	r, err := blob.Get(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func downloadSasUri(uri string) ([]byte, error) {
+	if uri == "" {
+		return nil, nil
+	}
+	resp, err := http.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error downloading the boot diagnostics: %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (*StepCaptureBootDiagnostics) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (s *StepCaptureBootDiagnostics) Cleanup(state multistep.StateBag) {
+	if _, ok := state.GetOk(constants.Error); !ok {
+		return
+	}
+	if _, ok := state.GetOk("communicator"); ok {
+		return
+	}
+
+	var resourceGroupName = state.Get(constants.ArmResourceGroupName).(string)
+	var computeName = state.Get(constants.ArmComputeName).(string)
+
+	s.say("Capturing the boot diagnostics of the VM, which could not be connected to ...")
+	diagnostics, err := s.get(context.TODO(), resourceGroupName, computeName)
+	if err != nil {
+		s.error(fmt.Errorf("Error capturing the boot diagnostics: %s", err))
+		return
+	}
+
+	prefix := fmt.Sprintf("%s-%s", s.config.PackerBuildName, computeName)
+	if len(diagnostics.serialLog) > 0 {
+		path := prefix + "-serial.log"
+		if err := s.write(path, diagnostics.serialLog); err != nil {
+			s.error(fmt.Errorf("Error saving the serial log: %s", err))
+		} else {
+			s.say(fmt.Sprintf(" -> Serial log : '%s'", path))
+		}
+
+		s.message(fmt.Sprintf("Last lines of the serial log:\n%s", lastLines(string(diagnostics.serialLog), bootDiagnosticsLogLines)))
+	}
+	if len(diagnostics.screenshot) > 0 {
+		path := prefix + "-screenshot.bmp"
+		if err := s.write(path, diagnostics.screenshot); err != nil {
+			s.error(fmt.Errorf("Error saving the screenshot: %s", err))
+		} else {
+			s.say(fmt.Sprintf(" -> Screenshot : '%s'", path))
+		}
+	}
+}
+
+// lastLines returns at most the n last lines of the text.
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
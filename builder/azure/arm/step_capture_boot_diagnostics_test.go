@@ -0,0 +1,82 @@
+package arm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func createTestStateBagStepCaptureBootDiagnostics() multistep.StateBag {
+	stateBag := new(multistep.BasicStateBag)
+	stateBag.Put(constants.ArmResourceGroupName, "Unit Test: ResourceGroupName")
+	stateBag.Put(constants.ArmComputeName, "vm")
+	return stateBag
+}
+
+func TestStepCaptureBootDiagnosticsShouldCaptureIfTheCommunicatorDidNotConnect(t *testing.T) {
+	written := make(map[string]string)
+	var messages []string
+	var testSubject = &StepCaptureBootDiagnostics{
+		config: &Config{},
+		get: func(_ context.Context, resourceGroupName, computeName string) (*bootDiagnostics, error) {
+			if resourceGroupName != "Unit Test: ResourceGroupName" || computeName != "vm" {
+				t.Fatalf("Unexpected VM %s in %s", computeName, resourceGroupName)
+			}
+			return &bootDiagnostics{
+				screenshot: []byte("BMP"),
+				serialLog:  []byte(strings.Repeat("line\n", 60) + "Kernel panic\n"),
+			}, nil
+		},
+		write: func(path string, data []byte) error {
+			written[path] = string(data)
+			return nil
+		},
+		say:     func(message string) {},
+		message: func(message string) { messages = append(messages, message) },
+		error:   func(e error) {},
+	}
+	testSubject.config.PackerBuildName = "build"
+
+	stateBag := createTestStateBagStepCaptureBootDiagnostics()
+	if result := testSubject.Run(context.Background(), stateBag); result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+	stateBag.Put(constants.Error, fmt.Errorf("Timeout waiting for SSH."))
+	testSubject.Cleanup(stateBag)
+
+	if written["build-vm-screenshot.bmp"] != "BMP" {
+		t.Errorf("Expected the screenshot to be saved, but got %v", written)
+	}
+	if !strings.HasSuffix(written["build-vm-serial.log"], "Kernel panic\n") {
+		t.Errorf("Expected the serial log to be saved, but got %v", written)
+	}
+	if len(messages) != 1 || !strings.HasSuffix(messages[0], "Kernel panic") || strings.Count(messages[0], "\nline") != bootDiagnosticsLogLines-1 {
+		t.Errorf("Expected the last lines of the serial log to be shown, but got %v", messages)
+	}
+}
+
+func TestStepCaptureBootDiagnosticsShouldNotCaptureOtherwise(t *testing.T) {
+	var testSubject = &StepCaptureBootDiagnostics{
+		config: &Config{},
+		get: func(context.Context, string, string) (*bootDiagnostics, error) {
+			t.Fatal("Expected the step to not capture the boot diagnostics, but it did.")
+			return nil, nil
+		},
+		say:     func(message string) {},
+		message: func(message string) {},
+		error:   func(e error) {},
+	}
+
+	// The build succeeded
+	stateBag := createTestStateBagStepCaptureBootDiagnostics()
+	testSubject.Cleanup(stateBag)
+
+	// The build failed after the communicator connected
+	stateBag.Put(constants.Error, fmt.Errorf("!! Unit Test FAIL !!"))
+	stateBag.Put("communicator", "Unit Test: Communicator")
+	testSubject.Cleanup(stateBag)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/hashicorp/packer/builder/azure/common"
 	"github.com/hashicorp/packer/builder/azure/common/constants"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -14,7 +15,7 @@ type StepCaptureImage struct {
 	client              *AzureClient
 	generalizeVM        func(resourceGroupName, computeName string) error
 	captureVhd          func(ctx context.Context, resourceGroupName string, computeName string, parameters *compute.VirtualMachineCaptureParameters) error
-	captureManagedImage func(ctx context.Context, resourceGroupName string, computeName string, parameters *compute.Image) error
+	captureManagedImage func(ctx context.Context, resourceGroupName string, computeName string, parameters *compute.Image, hyperVGeneration, diskEncryptionSetID string) error
 	get                 func(client *AzureClient) *CaptureTemplate
 	say                 func(message string)
 	error               func(e error)
@@ -49,10 +50,30 @@ func (s *StepCaptureImage) generalize(resourceGroupName string, computeName stri
 	return err
 }
 
-func (s *StepCaptureImage) captureImageFromVM(ctx context.Context, resourceGroupName string, imageName string, image *compute.Image) error {
-	f, err := s.client.ImagesClient.CreateOrUpdate(ctx, resourceGroupName, imageName, *image)
+func (s *StepCaptureImage) captureImageFromVM(ctx context.Context, resourceGroupName string, imageName string, image *compute.Image, hyperVGeneration, diskEncryptionSetID string) error {
+	if hyperVGeneration == "" && diskEncryptionSetID == "" {
+		f, err := s.client.ImagesClient.CreateOrUpdate(ctx, resourceGroupName, imageName, *image)
+		if err != nil {
+			s.say(s.client.LastError.Error())
+		}
+		return f.WaitForCompletion(ctx, s.client.ImagesClient.Client)
+	}
+
+	req, err := s.client.ImagesClient.CreateOrUpdatePreparer(ctx, resourceGroupName, imageName, *image)
+	if err == nil && hyperVGeneration != "" {
+		err = common.SetImageHyperVGeneration(req, hyperVGeneration)
+	}
+	if err == nil && diskEncryptionSetID != "" {
+		err = common.SetImageDiskEncryptionSet(req, diskEncryptionSetID)
+	}
+	if err != nil {
+		return err
+	}
+
+	f, err := s.client.ImagesClient.CreateOrUpdateSender(req)
 	if err != nil {
 		s.say(s.client.LastError.Error())
+		return err
 	}
 	return f.WaitForCompletion(ctx, s.client.ImagesClient.Client)
 }
@@ -78,6 +99,9 @@ func (s *StepCaptureImage) Run(ctx context.Context, state multistep.StateBag) mu
 	var targetManagedImageResourceGroupName = state.Get(constants.ArmManagedImageResourceGroupName).(string)
 	var targetManagedImageName = state.Get(constants.ArmManagedImageName).(string)
 	var targetManagedImageLocation = state.Get(constants.ArmManagedImageLocation).(string)
+	var targetManagedImageHyperVGeneration = state.Get(constants.ArmManagedImageHyperVGeneration).(string)
+	var targetManagedImageDiskEncryptionSetID = state.Get(constants.ArmManagedImageDiskEncryptionSetID).(string)
+	var skipManagedImageCapture, _ = state.Get(constants.ArmManagedImageSkipCapture).(bool)
 
 	s.say(fmt.Sprintf(" -> Compute ResourceGroupName : '%s'", resourceGroupName))
 	s.say(fmt.Sprintf(" -> Compute Name              : '%s'", computeName))
@@ -86,11 +110,21 @@ func (s *StepCaptureImage) Run(ctx context.Context, state multistep.StateBag) mu
 	err := s.generalizeVM(resourceGroupName, computeName)
 
 	if err == nil {
-		if isManagedImage {
+		if isManagedImage && skipManagedImageCapture {
+			// The VMs of a security type can't be captured to a managed
+			// image, the image version is published from the VM.
+			s.say(" -> Skipping the managed image, the image version is published from the VM")
+		} else if isManagedImage {
 			s.say(fmt.Sprintf(" -> Image ResourceGroupName   : '%s'", targetManagedImageResourceGroupName))
 			s.say(fmt.Sprintf(" -> Image Name                : '%s'", targetManagedImageName))
 			s.say(fmt.Sprintf(" -> Image Location            : '%s'", targetManagedImageLocation))
-			err = s.captureManagedImage(ctx, targetManagedImageResourceGroupName, targetManagedImageName, imageParameters)
+			if targetManagedImageHyperVGeneration != "" {
+				s.say(fmt.Sprintf(" -> Image Hyper-V Generation  : '%s'", targetManagedImageHyperVGeneration))
+			}
+			if targetManagedImageDiskEncryptionSetID != "" {
+				s.say(fmt.Sprintf(" -> Image Encryption Set      : '%s'", targetManagedImageDiskEncryptionSetID))
+			}
+			err = s.captureManagedImage(ctx, targetManagedImageResourceGroupName, targetManagedImageName, imageParameters, targetManagedImageHyperVGeneration, targetManagedImageDiskEncryptionSetID)
 		} else {
 			err = s.captureVhd(ctx, resourceGroupName, computeName, vmCaptureParameters)
 		}
@@ -120,6 +120,66 @@ func TestStepCaptureImageShouldTakeStepArgumentsFromStateBag(t *testing.T) {
 	}
 }
 
+func TestStepCaptureImageShouldPassTheDiskEncryptionSetOfTheManagedImage(t *testing.T) {
+	var actualDiskEncryptionSetID string
+	var testSubject = &StepCaptureImage{
+		captureManagedImage: func(_ context.Context, _ string, _ string, _ *compute.Image, _ string, diskEncryptionSetID string) error {
+			actualDiskEncryptionSetID = diskEncryptionSetID
+			return nil
+		},
+		generalizeVM: func(string, string) error {
+			return nil
+		},
+		get: func(client *AzureClient) *CaptureTemplate {
+			return nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepCaptureImage()
+	stateBag.Put(constants.ArmIsManagedImage, true)
+	stateBag.Put(constants.ArmManagedImageDiskEncryptionSetID, "des")
+
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+	if actualDiskEncryptionSetID != "des" {
+		t.Fatalf("Expected the disk encryption set 'des', but got %q.", actualDiskEncryptionSetID)
+	}
+}
+
+func TestStepCaptureImageShouldNotCaptureAManagedImageIfSkipped(t *testing.T) {
+	var testSubject = &StepCaptureImage{
+		captureManagedImage: func(context.Context, string, string, *compute.Image, string, string) error {
+			t.Fatal("Expected the step to not capture a managed image, but it did.")
+			return nil
+		},
+		captureVhd: func(context.Context, string, string, *compute.VirtualMachineCaptureParameters) error {
+			t.Fatal("Expected the step to not capture a VHD, but it did.")
+			return nil
+		},
+		generalizeVM: func(string, string) error {
+			return nil
+		},
+		get: func(client *AzureClient) *CaptureTemplate {
+			return nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepCaptureImage()
+	stateBag.Put(constants.ArmIsManagedImage, true)
+	stateBag.Put(constants.ArmManagedImageSkipCapture, true)
+
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+}
+
 func createTestStateBagStepCaptureImage() multistep.StateBag {
 	stateBag := new(multistep.BasicStateBag)
 
@@ -132,6 +192,8 @@ func createTestStateBagStepCaptureImage() multistep.StateBag {
 	stateBag.Put(constants.ArmManagedImageResourceGroupName, "")
 	stateBag.Put(constants.ArmManagedImageName, "")
 	stateBag.Put(constants.ArmManagedImageLocation, "")
+	stateBag.Put(constants.ArmManagedImageHyperVGeneration, "")
+	stateBag.Put(constants.ArmManagedImageDiskEncryptionSetID, "")
 	stateBag.Put(constants.ArmImageParameters, &compute.Image{})
 
 	return stateBag
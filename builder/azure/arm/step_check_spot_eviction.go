@@ -0,0 +1,118 @@
+package arm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCheckSpotEviction tells an eviction of the Spot VM apart from the
+// other reasons the build failed. The VM is only looked at in the cleanup,
+// before it is deleted along with the other resources of the build.
+type StepCheckSpotEviction struct {
+	client  *AzureClient
+	config  *Config
+	evicted func(ctx context.Context, resourceGroupName string, computeName string) (bool, error)
+	say     func(message string)
+	error   func(e error)
+}
+
+func NewStepCheckSpotEviction(client *AzureClient, ui packer.Ui, config *Config) *StepCheckSpotEviction {
+	var step = &StepCheckSpotEviction{
+		client: client,
+		config: config,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.evicted = step.isSpotVMEvicted
+	return step
+}
+
+// isSpotVMEvicted is true when the VM is gone or deallocated, which is what
+// becomes of a Spot VM evicted with the Delete or Deallocate policy.
+func (s *StepCheckSpotEviction) isSpotVMEvicted(ctx context.Context, resourceGroupName string, computeName string) (bool, error) {
+	view, err := s.client.VirtualMachinesClient.InstanceView(ctx, resourceGroupName, computeName)
+	if view.Response.Response != nil && view.StatusCode == http.StatusNotFound {
+		return true, nil
+	}
+	if err != nil {
+		s.say(s.client.LastError.Error())
+		return false, err
+	}
+
+	if view.Statuses != nil {
+		for _, status := range *view.Statuses {
+			if status.Code == nil {
+				continue
+			}
+			switch *status.Code {
+			case "PowerState/deallocating", "PowerState/deallocated":
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (s *StepCheckSpotEviction) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (s *StepCheckSpotEviction) Cleanup(state multistep.StateBag) {
+	if s.config.Spot.EvictionPolicy == "" {
+		return
+	}
+	rawErr, ok := state.GetOk(constants.Error)
+	if !ok {
+		return
+	}
+	// The VM is deallocated on purpose once it is provisioned
+	if _, ok := state.GetOk(constants.ArmOSDiskVhd); ok {
+		return
+	}
+
+	var resourceGroupName = state.Get(constants.ArmResourceGroupName).(string)
+	var computeName = state.Get(constants.ArmComputeName).(string)
+
+	evicted, err := s.evicted(context.TODO(), resourceGroupName, computeName)
+	if err != nil {
+		log.Printf("[WARN] Could not tell whether the Spot VM %s was evicted: %s", computeName, err)
+		return
+	}
+	if !evicted {
+		return
+	}
+
+	err = fmt.Errorf("The Spot VM %s was evicted by Azure before it was provisioned: %s", computeName, rawErr)
+	s.error(err)
+	state.Put(constants.Error, err)
+	state.Put(constants.ArmSpotVMEvicted, true)
+}
+
+// spotAllocationFailureCodes are the error codes of the deployments of
+// Spot VMs Azure has no capacity for.
+var spotAllocationFailureCodes = []string{
+	"AllocationFailed",
+	"OverconstrainedAllocationRequest",
+	"OverconstrainedZonalAllocationRequest",
+	"ZonalAllocationFailed",
+}
+
+// isSpotAllocationFailure is true if the error is the failure of a
+// deployment for a lack of Spot capacity, which is retried like an
+// eviction.
+func isSpotAllocationFailure(err error) bool {
+	for _, code := range spotAllocationFailureCodes {
+		if strings.Contains(err.Error(), code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package arm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepCheckSpotEvictionShouldReportEviction(t *testing.T) {
+	var actualComputeName string
+	var testSubject = &StepCheckSpotEviction{
+		config: &Config{Spot: Spot{EvictionPolicy: "Deallocate"}},
+		evicted: func(ctx context.Context, resourceGroupName string, computeName string) (bool, error) {
+			actualComputeName = computeName
+			return true, nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepCheckSpotEviction()
+	stateBag.Put(constants.Error, fmt.Errorf("connection reset by peer"))
+
+	testSubject.Cleanup(stateBag)
+
+	if actualComputeName != "Unit Test: ComputeName" {
+		t.Fatalf("Expected the step to check the compute 'Unit Test: ComputeName', but got %q.", actualComputeName)
+	}
+	if _, ok := stateBag.GetOk(constants.ArmSpotVMEvicted); !ok {
+		t.Fatalf("Expected the step to set stateBag['%s'], but it was not.", constants.ArmSpotVMEvicted)
+	}
+	err := stateBag.Get(constants.Error).(error)
+	if !strings.Contains(err.Error(), "was evicted") || !strings.Contains(err.Error(), "connection reset by peer") {
+		t.Fatalf("Expected the error to tell about the eviction, but got %q.", err)
+	}
+}
+
+func TestStepCheckSpotEvictionShouldKeepOtherErrors(t *testing.T) {
+	var testSubject = &StepCheckSpotEviction{
+		config:  &Config{Spot: Spot{EvictionPolicy: "Delete"}},
+		evicted: func(context.Context, string, string) (bool, error) { return false, nil },
+		say:     func(message string) {},
+		error:   func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepCheckSpotEviction()
+	stateBag.Put(constants.Error, fmt.Errorf("provisioner failed"))
+
+	testSubject.Cleanup(stateBag)
+
+	if _, ok := stateBag.GetOk(constants.ArmSpotVMEvicted); ok {
+		t.Fatalf("Expected the step to not set stateBag['%s'], but it was.", constants.ArmSpotVMEvicted)
+	}
+	if err := stateBag.Get(constants.Error).(error); err.Error() != "provisioner failed" {
+		t.Fatalf("Expected the error to be kept, but got %q.", err)
+	}
+}
+
+func TestStepCheckSpotEvictionShouldNotCheckUnlessAProvisionedSpotVMFailed(t *testing.T) {
+	cases := []struct {
+		name   string
+		spot   Spot
+		err    error
+		osDisk bool
+	}{
+		{"regular VM", Spot{}, errors.New("!! Unit Test FAIL !!"), false},
+		{"successful build", Spot{EvictionPolicy: "Delete"}, nil, false},
+		{"provisioned VM", Spot{EvictionPolicy: "Deallocate"}, errors.New("!! Unit Test FAIL !!"), true},
+	}
+
+	for _, tc := range cases {
+		var testSubject = &StepCheckSpotEviction{
+			config: &Config{Spot: tc.spot},
+			evicted: func(context.Context, string, string) (bool, error) {
+				t.Errorf("%s: Expected the step to not check the VM.", tc.name)
+				return true, nil
+			},
+			say:   func(message string) {},
+			error: func(e error) {},
+		}
+
+		stateBag := createTestStateBagStepCheckSpotEviction()
+		if tc.err != nil {
+			stateBag.Put(constants.Error, tc.err)
+		}
+		if tc.osDisk {
+			stateBag.Put(constants.ArmOSDiskVhd, "subscriptions/123-456-789/resourceGroups/existingresourcegroup/providers/Microsoft.Compute/disks/osdisk")
+		}
+
+		testSubject.Cleanup(stateBag)
+
+		if _, ok := stateBag.GetOk(constants.ArmSpotVMEvicted); ok {
+			t.Errorf("%s: Expected the step to not set stateBag['%s'], but it was.", tc.name, constants.ArmSpotVMEvicted)
+		}
+	}
+}
+
+func TestIsSpotAllocationFailure(t *testing.T) {
+	if !isSpotAllocationFailure(errors.New(`Code="OverconstrainedAllocationRequest" Message="Allocation failed."`)) {
+		t.Fatal("Expected an allocation failure.")
+	}
+	if isSpotAllocationFailure(errors.New(`Code="InvalidParameter" Message="The value of parameter adminUsername is invalid."`)) {
+		t.Fatal("Expected no allocation failure.")
+	}
+}
+
+func createTestStateBagStepCheckSpotEviction() multistep.StateBag {
+	stateBag := new(multistep.BasicStateBag)
+
+	stateBag.Put(constants.ArmResourceGroupName, "Unit Test: ResourceGroupName")
+	stateBag.Put(constants.ArmComputeName, "Unit Test: ComputeName")
+
+	return stateBag
+}
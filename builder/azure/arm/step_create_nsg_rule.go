@@ -0,0 +1,263 @@
+package arm
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-01-01/network"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/helper/retry"
+	"github.com/hashicorp/packer/packer"
+)
+
+const (
+	// The service returning the public IP address Packer connects from
+	publicIPAddressLookupURL = "https://api.ipify.org"
+
+	// The range of priorities of the rules of a network security group
+	nsgRuleMinPriority = 100
+	nsgRuleMaxPriority = 4096
+
+	// How many times the rule is created with a new priority when another
+	// rule took it in the meantime
+	nsgRuleCreateTries = 5
+)
+
+// nsgRuleRetryBackoff is how long to wait before creating the rule again
+// after a priority conflict. It is modified in tests.
+var nsgRuleRetryBackoff = 2 * time.Second
+
+// StepCreateNsgRule lets the communicator through the network security group
+// of an existing subnet, by creating a rule that only allows its port from
+// the public IP address Packer connects from to the VM. The rule is deleted
+// in the cleanup.
+type StepCreateNsgRule struct {
+	client       *AzureClient
+	config       *Config
+	getSourceIP  func(ctx context.Context) (string, error)
+	getSubnet    func(ctx context.Context, resourceGroupName, virtualNetworkName, subnetName string) (network.Subnet, error)
+	getNsg       func(ctx context.Context, resourceGroupName, nsgName string) (network.SecurityGroup, error)
+	getPrivateIP func(ctx context.Context, resourceGroupName, nicName string) (string, error)
+	create       func(ctx context.Context, resourceGroupName, nsgName, ruleName string, rule network.SecurityRule) error
+	delete       func(ctx context.Context, resourceGroupName, nsgName, ruleName string) error
+	say          func(message string)
+	error        func(e error)
+
+	nsgResourceGroupName string
+	nsgName              string
+	ruleName             string
+}
+
+func NewStepCreateNsgRule(client *AzureClient, ui packer.Ui, config *Config) *StepCreateNsgRule {
+	var step = &StepCreateNsgRule{
+		client: client,
+		config: config,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.getSourceIP = step.lookupPublicIP
+	step.getSubnet = step.getSubnetFromAzure
+	step.getNsg = step.getNsgFromAzure
+	step.getPrivateIP = step.getNicPrivateIP
+	step.create = step.createRule
+	step.delete = step.deleteRule
+	return step
+}
+
+func (s *StepCreateNsgRule) lookupPublicIP(ctx context.Context) (string, error) {
+	req, err := http.NewRequest("GET", publicIPAddressLookupURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", publicIPAddressLookupURL, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	address := strings.TrimSpace(string(body))
+	if net.ParseIP(address) == nil {
+		return "", fmt.Errorf("%s returned %q, which isn't an IP address", publicIPAddressLookupURL, address)
+	}
+	return address, nil
+}
+
+func (s *StepCreateNsgRule) getSubnetFromAzure(ctx context.Context, resourceGroupName, virtualNetworkName, subnetName string) (network.Subnet, error) {
+	return s.client.SubnetsClient.Get(ctx, resourceGroupName, virtualNetworkName, subnetName, "")
+}
+
+func (s *StepCreateNsgRule) getNsgFromAzure(ctx context.Context, resourceGroupName, nsgName string) (network.SecurityGroup, error) {
+	return s.client.SecurityGroupsClient.Get(ctx, resourceGroupName, nsgName, "")
+}
+
+func (s *StepCreateNsgRule) getNicPrivateIP(ctx context.Context, resourceGroupName, nicName string) (string, error) {
+	nic, err := s.client.InterfacesClient.Get(ctx, resourceGroupName, nicName, "")
+	if err != nil {
+		return "", err
+	}
+	if nic.InterfacePropertiesFormat == nil || nic.IPConfigurations == nil || len(*nic.IPConfigurations) == 0 {
+		return "", fmt.Errorf("The network interface %s has no IP configuration", nicName)
+	}
+	return to.String((*nic.IPConfigurations)[0].PrivateIPAddress), nil
+}
+
+func (s *StepCreateNsgRule) createRule(ctx context.Context, resourceGroupName, nsgName, ruleName string, rule network.SecurityRule) error {
+	f, err := s.client.SecurityRulesClient.CreateOrUpdate(ctx, resourceGroupName, nsgName, ruleName, rule)
+	if err == nil {
+		err = f.WaitForCompletion(ctx, s.client.SecurityRulesClient.Client)
+	}
+	if err != nil {
+		s.say(s.client.LastError.Error())
+	}
+	return err
+}
+
+func (s *StepCreateNsgRule) deleteRule(ctx context.Context, resourceGroupName, nsgName, ruleName string) error {
+	f, err := s.client.SecurityRulesClient.Delete(ctx, resourceGroupName, nsgName, ruleName)
+	if err == nil {
+		err = f.WaitForCompletion(ctx, s.client.SecurityRulesClient.Client)
+	}
+	return err
+}
+
+// freeNsgRulePriority returns the highest priority, the lowest number, no
+// inbound rule of the network security group uses.
+func freeNsgRulePriority(nsg network.SecurityGroup) (int32, error) {
+	used := make(map[int32]bool)
+	if nsg.SecurityGroupPropertiesFormat != nil && nsg.SecurityRules != nil {
+		for _, rule := range *nsg.SecurityRules {
+			if rule.SecurityRulePropertiesFormat != nil && rule.Direction == network.SecurityRuleDirectionInbound && rule.Priority != nil {
+				used[*rule.Priority] = true
+			}
+		}
+	}
+
+	for priority := int32(nsgRuleMinPriority); priority <= nsgRuleMaxPriority; priority++ {
+		if !used[priority] {
+			return priority, nil
+		}
+	}
+	return 0, fmt.Errorf("The network security group %s has no free priority left", to.String(nsg.Name))
+}
+
+// isNsgRulePriorityConflict tells whether the rule couldn't be created
+// because another inbound rule has its priority.
+func isNsgRulePriorityConflict(err error) bool {
+	return strings.Contains(err.Error(), "SecurityRuleConflict")
+}
+
+func (s *StepCreateNsgRule) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	s.say("Allowing the communicator through the network security group ...")
+
+	var resourceGroupName = state.Get(constants.ArmResourceGroupName).(string)
+	var nicName = state.Get(constants.ArmNicName).(string)
+	var ruleName = state.Get(constants.ArmNsgRuleName).(string)
+
+	subnet, err := s.getSubnet(ctx, s.config.VirtualNetworkResourceGroupName, s.config.VirtualNetworkName, s.config.VirtualNetworkSubnetName)
+	if err != nil {
+		err = fmt.Errorf("Error reading the subnet %s of %s: %s", s.config.VirtualNetworkSubnetName, s.config.VirtualNetworkName, err)
+		return processStepResult(err, s.error, state)
+	}
+	if subnet.SubnetPropertiesFormat == nil || subnet.NetworkSecurityGroup == nil || subnet.NetworkSecurityGroup.ID == nil {
+		s.say(fmt.Sprintf(" -> The subnet %s has no network security group, skipping", s.config.VirtualNetworkSubnetName))
+		return multistep.ActionContinue
+	}
+
+	nsgID, err := azure.ParseResourceID(*subnet.NetworkSecurityGroup.ID)
+	if err != nil {
+		return processStepResult(err, s.error, state)
+	}
+	sourceIP, err := s.getSourceIP(ctx)
+	if err != nil {
+		err = fmt.Errorf("Error detecting the public IP address Packer connects from: %s", err)
+		return processStepResult(err, s.error, state)
+	}
+	destinationIP, err := s.getPrivateIP(ctx, resourceGroupName, nicName)
+	if err != nil {
+		err = fmt.Errorf("Error reading the private IP address of the VM: %s", err)
+		return processStepResult(err, s.error, state)
+	}
+	port := strconv.Itoa(s.config.Comm.Port())
+
+	s.say(fmt.Sprintf(" -> NetworkSecurityGroup : '%s'", nsgID.ResourceName))
+	s.say(fmt.Sprintf(" -> RuleName             : '%s'", ruleName))
+	s.say(fmt.Sprintf(" -> Source               : '%s'", sourceIP))
+	s.say(fmt.Sprintf(" -> Destination          : '%s:%s'", destinationIP, port))
+
+	rule := network.SecurityRule{
+		SecurityRulePropertiesFormat: &network.SecurityRulePropertiesFormat{
+			Description:              to.StringPtr(fmt.Sprintf("Packer communicator of %s", s.config.PackerBuildName)),
+			Protocol:                 network.SecurityRuleProtocolTCP,
+			SourceAddressPrefix:      to.StringPtr(sourceIP),
+			SourcePortRange:          to.StringPtr("*"),
+			DestinationAddressPrefix: to.StringPtr(destinationIP),
+			DestinationPortRange:     to.StringPtr(port),
+			Access:                   network.SecurityRuleAccessAllow,
+			Direction:                network.SecurityRuleDirectionInbound,
+		},
+	}
+
+	// The priority is the first free one, which another rule created at
+	// the same time, by another build for instance, may take first.
+	err = retry.Config{
+		Tries:          nsgRuleCreateTries,
+		InitialBackoff: nsgRuleRetryBackoff,
+		ShouldRetry:    isNsgRulePriorityConflict,
+	}.Run(ctx, func(ctx context.Context) error {
+		nsg, err := s.getNsg(ctx, nsgID.ResourceGroup, nsgID.ResourceName)
+		if err != nil {
+			return retry.Abort(fmt.Errorf("Error reading the network security group %s: %s", nsgID.ResourceName, err))
+		}
+		priority, err := freeNsgRulePriority(nsg)
+		if err != nil {
+			return retry.Abort(err)
+		}
+
+		s.say(fmt.Sprintf(" -> Priority             : '%d'", priority))
+		rule.Priority = to.Int32Ptr(priority)
+		if err := s.create(ctx, nsgID.ResourceGroup, nsgID.ResourceName, ruleName, rule); err != nil {
+			return fmt.Errorf("Error creating the rule %s: %s", ruleName, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return processStepResult(err, s.error, state)
+	}
+
+	s.nsgResourceGroupName = nsgID.ResourceGroup
+	s.nsgName = nsgID.ResourceName
+	s.ruleName = ruleName
+	return multistep.ActionContinue
+}
+
+func (s *StepCreateNsgRule) Cleanup(state multistep.StateBag) {
+	if s.ruleName == "" {
+		return
+	}
+
+	s.say(fmt.Sprintf("Deleting the rule %s of the network security group %s ...", s.ruleName, s.nsgName))
+	err := s.delete(context.TODO(), s.nsgResourceGroupName, s.nsgName, s.ruleName)
+	if err != nil {
+		s.error(fmt.Errorf("Error deleting the rule %s of the network security group %s, delete it manually: %s", s.ruleName, s.nsgName, err))
+		return
+	}
+	s.ruleName = ""
+}
@@ -0,0 +1,178 @@
+package arm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-01-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+const testNsgID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/nsg-rg/providers/Microsoft.Network/networkSecurityGroups/nsg"
+
+func createTestStateBagStepCreateNsgRule() multistep.StateBag {
+	stateBag := new(multistep.BasicStateBag)
+	stateBag.Put(constants.ArmResourceGroupName, "Unit Test: ResourceGroupName")
+	stateBag.Put(constants.ArmNicName, "Unit Test: NicName")
+	stateBag.Put(constants.ArmNsgRuleName, "Unit Test: NsgRuleName")
+	return stateBag
+}
+
+func createTestStepCreateNsgRule(nsgID *string) *StepCreateNsgRule {
+	config := &Config{
+		VirtualNetworkName:              "vnet",
+		VirtualNetworkSubnetName:        "subnet",
+		VirtualNetworkResourceGroupName: "vnet-rg",
+		Comm:                            communicator.Config{Type: "ssh", SSHPort: 22},
+	}
+
+	return &StepCreateNsgRule{
+		config:      config,
+		getSourceIP: func(context.Context) (string, error) { return "203.0.113.10", nil },
+		getSubnet: func(context.Context, string, string, string) (network.Subnet, error) {
+			return network.Subnet{
+				SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
+					NetworkSecurityGroup: &network.SecurityGroup{ID: nsgID},
+				},
+			}, nil
+		},
+		getNsg: func(context.Context, string, string) (network.SecurityGroup, error) {
+			return network.SecurityGroup{
+				SecurityGroupPropertiesFormat: &network.SecurityGroupPropertiesFormat{
+					SecurityRules: &[]network.SecurityRule{
+						{SecurityRulePropertiesFormat: &network.SecurityRulePropertiesFormat{
+							Priority: to.Int32Ptr(100), Direction: network.SecurityRuleDirectionInbound}},
+						{SecurityRulePropertiesFormat: &network.SecurityRulePropertiesFormat{
+							Priority: to.Int32Ptr(101), Direction: network.SecurityRuleDirectionOutbound}},
+					},
+				},
+			}, nil
+		},
+		getPrivateIP: func(context.Context, string, string) (string, error) { return "10.0.0.4", nil },
+		say:          func(message string) {},
+		error:        func(e error) {},
+	}
+}
+
+func TestStepCreateNsgRuleShouldCreateAndDeleteTheRule(t *testing.T) {
+	var createdRule network.SecurityRule
+	var created, deleted string
+
+	var testSubject = createTestStepCreateNsgRule(to.StringPtr(testNsgID))
+	testSubject.create = func(_ context.Context, resourceGroupName, nsgName, ruleName string, rule network.SecurityRule) error {
+		created = fmt.Sprintf("%s/%s/%s", resourceGroupName, nsgName, ruleName)
+		createdRule = rule
+		return nil
+	}
+	testSubject.delete = func(_ context.Context, resourceGroupName, nsgName, ruleName string) error {
+		deleted = fmt.Sprintf("%s/%s/%s", resourceGroupName, nsgName, ruleName)
+		return nil
+	}
+
+	stateBag := createTestStateBagStepCreateNsgRule()
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+
+	if created != "nsg-rg/nsg/Unit Test: NsgRuleName" {
+		t.Errorf("Expected the rule to be created in nsg-rg/nsg, but got %q", created)
+	}
+	properties := createdRule.SecurityRulePropertiesFormat
+	if to.String(properties.SourceAddressPrefix) != "203.0.113.10" ||
+		to.String(properties.DestinationAddressPrefix) != "10.0.0.4" ||
+		to.String(properties.DestinationPortRange) != "22" {
+		t.Errorf("Expected the rule to only allow 203.0.113.10 to 10.0.0.4:22, but got %s to %s:%s",
+			to.String(properties.SourceAddressPrefix), to.String(properties.DestinationAddressPrefix), to.String(properties.DestinationPortRange))
+	}
+	if *properties.Priority != 101 {
+		t.Errorf("Expected the first free inbound priority 101, but got %d", *properties.Priority)
+	}
+
+	testSubject.Cleanup(stateBag)
+	if deleted != created {
+		t.Errorf("Expected the rule %q to be deleted, but got %q", created, deleted)
+	}
+}
+
+func TestStepCreateNsgRuleShouldSkipSubnetsWithoutNsg(t *testing.T) {
+	var testSubject = createTestStepCreateNsgRule(nil)
+	testSubject.create = func(context.Context, string, string, string, network.SecurityRule) error {
+		t.Fatal("Expected no rule to be created, but one was.")
+		return nil
+	}
+	testSubject.delete = func(context.Context, string, string, string) error {
+		t.Fatal("Expected no rule to be deleted, but one was.")
+		return nil
+	}
+
+	stateBag := createTestStateBagStepCreateNsgRule()
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+	testSubject.Cleanup(stateBag)
+}
+
+func TestStepCreateNsgRuleShouldFailIfTheRuleCannotBeCreated(t *testing.T) {
+	var testSubject = createTestStepCreateNsgRule(to.StringPtr(testNsgID))
+	testSubject.create = func(context.Context, string, string, string, network.SecurityRule) error {
+		return fmt.Errorf("!! Unit Test FAIL !!")
+	}
+	testSubject.delete = func(context.Context, string, string, string) error {
+		t.Fatal("Expected no rule to be deleted, but one was.")
+		return nil
+	}
+
+	stateBag := createTestStateBagStepCreateNsgRule()
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionHalt {
+		t.Fatalf("Expected the step to return 'ActionHalt', but got '%d'.", result)
+	}
+	if _, ok := stateBag.GetOk(constants.Error); ok == false {
+		t.Fatalf("Expected the step to set stateBag['%s'], but it was not.", constants.Error)
+	}
+	testSubject.Cleanup(stateBag)
+}
+
+func TestStepCreateNsgRuleShouldRetryOnPriorityConflicts(t *testing.T) {
+	defer func(backoff time.Duration) { nsgRuleRetryBackoff = backoff }(nsgRuleRetryBackoff)
+	nsgRuleRetryBackoff = time.Millisecond
+
+	var priorities []int32
+	var testSubject = createTestStepCreateNsgRule(to.StringPtr(testNsgID))
+	getNsg := testSubject.getNsg
+	testSubject.getNsg = func(ctx context.Context, resourceGroupName, nsgName string) (network.SecurityGroup, error) {
+		nsg, err := getNsg(ctx, resourceGroupName, nsgName)
+		if len(priorities) > 0 {
+			// Another rule took the priority in the meantime
+			rules := append(*nsg.SecurityRules, network.SecurityRule{
+				SecurityRulePropertiesFormat: &network.SecurityRulePropertiesFormat{
+					Priority: to.Int32Ptr(101), Direction: network.SecurityRuleDirectionInbound}})
+			nsg.SecurityRules = &rules
+		}
+		return nsg, err
+	}
+	testSubject.create = func(_ context.Context, _, _, _ string, rule network.SecurityRule) error {
+		priorities = append(priorities, *rule.Priority)
+		if len(priorities) == 1 {
+			return fmt.Errorf("Code=\"SecurityRuleConflict\" Message=\"Security rule conflicts with Rule other.\"")
+		}
+		return nil
+	}
+	testSubject.delete = func(context.Context, string, string, string) error { return nil }
+
+	stateBag := createTestStateBagStepCreateNsgRule()
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+	if len(priorities) != 2 || priorities[0] != 101 || priorities[1] != 102 {
+		t.Fatalf("Expected the rule to be created again with the next free priority, but got %v", priorities)
+	}
+}
@@ -0,0 +1,358 @@
+package arm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/builder/azure/common"
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/builder/azure/common/template"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type StepPublishToSharedImageGallery struct {
+	client       *AzureClient
+	config       *Config
+	listVersions func(ctx context.Context, resourceGroupName, galleryName, imageName string) ([]string, error)
+	getImage     func(ctx context.Context, resourceGroupName, galleryName, imageName string) (common.GalleryImage, error)
+	publish      func(ctx context.Context, resourceGroupName, galleryName, imageName, version string, imageVersion common.GalleryImageVersion) (string, error)
+	say          func(message string)
+	error        func(e error)
+}
+
+func NewStepPublishToSharedImageGallery(client *AzureClient, ui packer.Ui, config *Config) *StepPublishToSharedImageGallery {
+	var step = &StepPublishToSharedImageGallery{
+		client: client,
+		config: config,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.listVersions = step.listGalleryImageVersions
+	step.getImage = step.getGalleryImage
+	step.publish = step.publishGalleryImageVersion
+	return step
+}
+
+func (s *StepPublishToSharedImageGallery) listGalleryImageVersions(ctx context.Context, resourceGroupName, galleryName, imageName string) ([]string, error) {
+	versions, err := s.client.GalleryImageVersionsClient.ListByGalleryImage(ctx, resourceGroupName, galleryName, imageName)
+	if err != nil {
+		s.say(s.client.LastError.Error())
+		return nil, err
+	}
+
+	var names []string
+	for _, version := range versions {
+		if version.Name != nil {
+			names = append(names, *version.Name)
+		}
+	}
+	return names, nil
+}
+
+func (s *StepPublishToSharedImageGallery) getGalleryImage(ctx context.Context, resourceGroupName, galleryName, imageName string) (common.GalleryImage, error) {
+	image, err := s.client.GalleryImagesClient.Get(ctx, resourceGroupName, galleryName, imageName)
+	if err != nil {
+		s.say(s.client.LastError.Error())
+	}
+	return image, err
+}
+
+func (s *StepPublishToSharedImageGallery) publishGalleryImageVersion(ctx context.Context, resourceGroupName, galleryName, imageName, version string, imageVersion common.GalleryImageVersion) (string, error) {
+	result, err := s.client.GalleryImageVersionsClient.CreateOrUpdate(ctx, resourceGroupName, galleryName, imageName, version, imageVersion)
+	if err != nil {
+		s.say(s.client.LastError.Error())
+		return "", err
+	}
+	return to.String(result.ID), nil
+}
+
+func (s *StepPublishToSharedImageGallery) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	sig := s.config.SharedGalleryDestination
+	if sig.GalleryName == "" {
+		return multistep.ActionContinue
+	}
+
+	s.say("Publishing to Shared Image Gallery ...")
+
+	var location = state.Get(constants.ArmManagedImageLocation).(string)
+	var managedImageResourceGroupName = state.Get(constants.ArmManagedImageResourceGroupName).(string)
+	var managedImageName = state.Get(constants.ArmManagedImageName).(string)
+	var managedImageID = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/images/%s",
+		s.config.SubscriptionID, managedImageResourceGroupName, managedImageName)
+
+	// The image definition of the version of a trusted launch or
+	// confidential VM image, or of a Marketplace image with a plan, must say
+	// so, it can't be changed afterwards.
+	image, err := s.getImage(ctx, sig.ResourceGroup, sig.GalleryName, sig.ImageName)
+	if err == nil && s.config.SecurityType != "" {
+		err = assertGalleryImageSecurityType(image, s.config.SecurityType)
+	}
+	if err == nil && s.config.PlanInfo.PlanName != "" {
+		err = assertGalleryImagePurchasePlan(image, &s.config.PlanInfo)
+	}
+	if err != nil {
+		return processStepResult(err, s.error, state)
+	}
+
+	// The version is created in the location of the gallery, which may not
+	// be the one of the build
+	galleryLocation := to.String(image.Location)
+	if galleryLocation == "" {
+		galleryLocation = location
+	}
+
+	version := sig.ImageVersion
+	if version == "" {
+		versions, err := s.listVersions(ctx, sig.ResourceGroup, sig.GalleryName, sig.ImageName)
+		if err == nil {
+			version, err = nextGalleryImageVersion(versions, sig.VersionBump)
+		}
+		if err != nil {
+			return processStepResult(err, s.error, state)
+		}
+	}
+
+	s.say(fmt.Sprintf(" -> Gallery ResourceGroupName : '%s'", sig.ResourceGroup))
+	s.say(fmt.Sprintf(" -> Gallery Name              : '%s'", sig.GalleryName))
+	s.say(fmt.Sprintf(" -> Gallery Image Name        : '%s'", sig.ImageName))
+	s.say(fmt.Sprintf(" -> Gallery Location          : '%s'", galleryLocation))
+	s.say(fmt.Sprintf(" -> Gallery Image Version     : '%s'", version))
+
+	targetRegions := galleryTargetRegions(&sig, location, s.config.DiskEncryptionSetID, len(s.config.AdditionalDiskSize))
+	for _, region := range targetRegions {
+		s.say(fmt.Sprintf(" -> Replicating to '%s' (%d replicas, %s)",
+			*region.Name, *region.RegionalReplicaCount, region.StorageAccountType))
+	}
+
+	properties := &common.GalleryImageVersionProperties{
+		PublishingProfile: &common.GalleryImageVersionPublishingProfile{
+			TargetRegions:      &targetRegions,
+			ReplicaCount:       to.Int32Ptr(sig.ReplicaCount),
+			ExcludeFromLatest:  to.BoolPtr(sig.ExcludeFromLatest),
+			StorageAccountType: sig.StorageAccountType,
+		},
+	}
+	if s.config.SecurityType != "" {
+		// The VMs of a security type can't be captured to a managed image,
+		// the version is published from the generalized VM.
+		var computeName = state.Get(constants.ArmComputeName).(string)
+		var resourceGroupName = state.Get(constants.ArmResourceGroupName).(string)
+		vmID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s",
+			s.config.SubscriptionID, resourceGroupName, computeName)
+		s.say(fmt.Sprintf(" -> Source VM                 : '%s'", vmID))
+		properties.StorageProfile = &common.GalleryImageVersionStorageProfile{
+			Source: &common.GalleryArtifactVersionSource{
+				ID: to.StringPtr(vmID),
+			},
+		}
+	} else {
+		s.say(fmt.Sprintf(" -> Source Managed Image      : '%s'", managedImageID))
+		properties.PublishingProfile.Source = &common.GalleryArtifactSource{
+			ManagedImage: &common.ManagedArtifact{
+				ID: to.StringPtr(managedImageID),
+			},
+		}
+	}
+
+	imageVersion := common.GalleryImageVersion{
+		Location:   to.StringPtr(galleryLocation),
+		Tags:       s.config.AzureTags,
+		Properties: properties,
+	}
+
+	id, err := s.publish(ctx, sig.ResourceGroup, sig.GalleryName, sig.ImageName, version, imageVersion)
+	if err != nil {
+		return processStepResult(err, s.error, state)
+	}
+
+	state.Put(constants.ArmManagedImageSharedImageGalleryVersion, version)
+	state.Put(constants.ArmManagedImageSharedImageGalleryVersionID, id)
+	return multistep.ActionContinue
+}
+
+func (*StepPublishToSharedImageGallery) Cleanup(multistep.StateBag) {
+}
+
+// galleryTargetRegions returns the regions of the destination with their
+// defaults. The image version has to be replicated to the location of the
+// managed image, it is listed first if it isn't among the regions. The
+// replicas in the location are encrypted with the disk encryption set of the
+// build, unless the region has its own, since a disk encryption set only
+// encrypts the disks of its region.
+func galleryTargetRegions(sig *SharedImageGalleryDestination, location, diskEncryptionSetID string, dataDisks int) []common.GalleryTargetRegion {
+	var regions []common.GalleryTargetRegion
+
+	hasLocation := false
+	for _, region := range sig.TargetRegions {
+		hasLocation = hasLocation || normalizeLocation(region.Name) == normalizeLocation(location)
+	}
+	if !hasLocation {
+		regions = append(regions, common.GalleryTargetRegion{
+			Name:                 to.StringPtr(location),
+			RegionalReplicaCount: to.Int32Ptr(sig.ReplicaCount),
+			StorageAccountType:   sig.StorageAccountType,
+			Encryption:           galleryEncryption(diskEncryptionSetID, dataDisks),
+		})
+	}
+
+	for _, region := range sig.TargetRegions {
+		replicaCount := region.ReplicaCount
+		if replicaCount == 0 {
+			replicaCount = sig.ReplicaCount
+		}
+		storageAccountType := region.StorageAccountType
+		if storageAccountType == "" {
+			storageAccountType = sig.StorageAccountType
+		}
+		regionDiskEncryptionSetID := region.DiskEncryptionSetID
+		if regionDiskEncryptionSetID == "" && normalizeLocation(region.Name) == normalizeLocation(location) {
+			regionDiskEncryptionSetID = diskEncryptionSetID
+		}
+		regions = append(regions, common.GalleryTargetRegion{
+			Name:                 to.StringPtr(region.Name),
+			RegionalReplicaCount: to.Int32Ptr(replicaCount),
+			StorageAccountType:   storageAccountType,
+			Encryption:           galleryEncryption(regionDiskEncryptionSetID, dataDisks),
+		})
+	}
+	return regions
+}
+
+// galleryEncryption encrypts the OS disk image and the images of the data
+// disks of a replica with the disk encryption set, if there is one.
+func galleryEncryption(diskEncryptionSetID string, dataDisks int) *common.GalleryEncryptionImages {
+	if diskEncryptionSetID == "" {
+		return nil
+	}
+
+	encryption := &common.GalleryEncryptionImages{
+		OsDiskImage: &common.GalleryDiskImageEncryption{
+			DiskEncryptionSetID: to.StringPtr(diskEncryptionSetID),
+		},
+	}
+	if dataDisks > 0 {
+		dataDiskImages := make([]common.GalleryDataDiskImageEncryption, dataDisks)
+		for i := range dataDiskImages {
+			dataDiskImages[i].Lun = to.Int32Ptr(int32(i))
+			dataDiskImages[i].DiskEncryptionSetID = to.StringPtr(diskEncryptionSetID)
+		}
+		encryption.DataDiskImages = &dataDiskImages
+	}
+	return encryption
+}
+
+// galleryImageSecurityTypes are the values of the SecurityType feature of
+// the image definitions the versions of a security type can be published
+// to.
+var galleryImageSecurityTypes = map[string][]string{
+	template.SecurityTypeTrustedLaunch:  {"TrustedLaunch", "TrustedLaunchSupported", "TrustedLaunchAndConfidentialVmSupported"},
+	template.SecurityTypeConfidentialVM: {"ConfidentialVM", "ConfidentialVmSupported", "TrustedLaunchAndConfidentialVmSupported"},
+}
+
+// assertGalleryImageSecurityType checks the image definition is of the
+// Hyper-V generation 2, and has a SecurityType feature of the security
+// type.
+func assertGalleryImageSecurityType(image common.GalleryImage, securityType string) error {
+	var generation, feature string
+	if image.Properties != nil {
+		generation = image.Properties.HyperVGeneration
+		if image.Properties.Features != nil {
+			for _, f := range *image.Properties.Features {
+				if strings.EqualFold(to.String(f.Name), "SecurityType") {
+					feature = to.String(f.Value)
+				}
+			}
+		}
+	}
+
+	if generation != "V2" {
+		return fmt.Errorf("The image definition %q must be of the Hyper-V generation V2 to publish a %s image, not %q", to.String(image.Name), securityType, generation)
+	}
+	for _, v := range galleryImageSecurityTypes[securityType] {
+		if strings.EqualFold(feature, v) {
+			return nil
+		}
+	}
+	return fmt.Errorf("The SecurityType feature of the image definition %q must be one of %s to publish a %s image, not %q",
+		to.String(image.Name), strings.Join(galleryImageSecurityTypes[securityType], ", "), securityType, feature)
+}
+
+// assertGalleryImagePurchasePlan checks the image definition has the plan
+// of the source image, the versions of a definition without it can't be
+// deployed.
+func assertGalleryImagePurchasePlan(image common.GalleryImage, plan *PlanInformation) error {
+	var purchasePlan common.GalleryImagePurchasePlan
+	if image.Properties != nil && image.Properties.PurchasePlan != nil {
+		purchasePlan = *image.Properties.PurchasePlan
+	}
+
+	if !strings.EqualFold(to.String(purchasePlan.Name), plan.PlanName) ||
+		!strings.EqualFold(to.String(purchasePlan.Product), plan.PlanProduct) ||
+		!strings.EqualFold(to.String(purchasePlan.Publisher), plan.PlanPublisher) {
+		return fmt.Errorf("The purchase plan of the image definition %q must be the plan_info of the source image, name %q, product %q and publisher %q, not %q, %q and %q",
+			to.String(image.Name), plan.PlanName, plan.PlanProduct, plan.PlanPublisher,
+			to.String(purchasePlan.Name), to.String(purchasePlan.Product), to.String(purchasePlan.Publisher))
+	}
+	return nil
+}
+
+// "West US 2" and "westus2" are the same location.
+func normalizeLocation(location string) string {
+	return strings.ToLower(strings.Replace(location, " ", "", -1))
+}
+
+// nextGalleryImageVersion bumps the major, minor or patch number of the
+// highest of the versions, which aren't all semantic versions if they were
+// published by other tools. The first version is 1.0.0.
+func nextGalleryImageVersion(versions []string, bump string) (string, error) {
+	var highest []int
+	for _, version := range versions {
+		parts := strings.Split(version, ".")
+		if len(parts) != 3 {
+			continue
+		}
+		numbers := make([]int, 3)
+		valid := true
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			valid = valid && err == nil && n >= 0
+			numbers[i] = n
+		}
+		if !valid {
+			continue
+		}
+		if highest == nil || compareVersions(numbers, highest) > 0 {
+			highest = numbers
+		}
+	}
+
+	if highest == nil {
+		return "1.0.0", nil
+	}
+
+	switch bump {
+	case "major":
+		highest = []int{highest[0] + 1, 0, 0}
+	case "minor":
+		highest = []int{highest[0], highest[1] + 1, 0}
+	case "patch":
+		highest = []int{highest[0], highest[1], highest[2] + 1}
+	default:
+		return "", fmt.Errorf("Unknown version bump %q", bump)
+	}
+	return fmt.Sprintf("%d.%d.%d", highest[0], highest[1], highest[2]), nil
+}
+
+func compareVersions(a, b []int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,403 @@
+package arm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/builder/azure/common"
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepPublishToSharedImageGalleryShouldNotPublishIfNotSet(t *testing.T) {
+	var testSubject = &StepPublishToSharedImageGallery{
+		config: &Config{},
+		publish: func(context.Context, string, string, string, string, common.GalleryImageVersion) (string, error) {
+			t.Fatal("Expected the step to not publish, but it did.")
+			return "", nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepPublishToSharedImageGallery()
+
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+
+	if _, ok := stateBag.GetOk(constants.ArmManagedImageSharedImageGalleryVersion); ok == true {
+		t.Fatalf("Expected the step to not set stateBag['%s'], but it was.", constants.ArmManagedImageSharedImageGalleryVersion)
+	}
+}
+
+func TestStepPublishToSharedImageGalleryShouldFailIfPublishFails(t *testing.T) {
+	var testSubject = &StepPublishToSharedImageGallery{
+		config:   createTestConfigStepPublishToSharedImageGallery("1.2.3"),
+		getImage: getTestGalleryImage,
+		publish: func(context.Context, string, string, string, string, common.GalleryImageVersion) (string, error) {
+			return "", fmt.Errorf("!! Unit Test FAIL !!")
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepPublishToSharedImageGallery()
+
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionHalt {
+		t.Fatalf("Expected the step to return 'ActionHalt', but got '%d'.", result)
+	}
+
+	if _, ok := stateBag.GetOk(constants.Error); ok == false {
+		t.Fatalf("Expected the step to set stateBag['%s'], but it was not.", constants.Error)
+	}
+}
+
+func TestStepPublishToSharedImageGalleryShouldFailIfListVersionsFails(t *testing.T) {
+	var testSubject = &StepPublishToSharedImageGallery{
+		config:   createTestConfigStepPublishToSharedImageGallery(""),
+		getImage: getTestGalleryImage,
+		listVersions: func(context.Context, string, string, string) ([]string, error) {
+			return nil, fmt.Errorf("!! Unit Test FAIL !!")
+		},
+		publish: func(context.Context, string, string, string, string, common.GalleryImageVersion) (string, error) {
+			t.Fatal("Expected the step to not publish, but it did.")
+			return "", nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepPublishToSharedImageGallery()
+
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionHalt {
+		t.Fatalf("Expected the step to return 'ActionHalt', but got '%d'.", result)
+	}
+}
+
+func TestStepPublishToSharedImageGalleryShouldBumpVersionIfNotSet(t *testing.T) {
+	var actualVersion string
+	var actualImageVersion common.GalleryImageVersion
+
+	var testSubject = &StepPublishToSharedImageGallery{
+		config: createTestConfigStepPublishToSharedImageGallery(""),
+		listVersions: func(_ context.Context, resourceGroupName, galleryName, imageName string) ([]string, error) {
+			if resourceGroupName != "Unit Test: GalleryResourceGroupName" || galleryName != "Unit Test: GalleryName" || imageName != "Unit Test: GalleryImageName" {
+				t.Fatalf("Expected the versions of the destination to be listed, but got %q, %q, %q.", resourceGroupName, galleryName, imageName)
+			}
+			return []string{"1.0.9", "1.0.10", "latest"}, nil
+		},
+		getImage: getTestGalleryImage,
+		publish: func(_ context.Context, _, _, _, version string, imageVersion common.GalleryImageVersion) (string, error) {
+			actualVersion = version
+			actualImageVersion = imageVersion
+			return "Unit Test: GalleryImageVersionID", nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepPublishToSharedImageGallery()
+
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+
+	if actualVersion != "1.0.11" {
+		t.Fatalf("Expected the version to be bumped to '1.0.11', but got '%s'.", actualVersion)
+	}
+
+	expectedManagedImageID := "/subscriptions/Unit Test: SubscriptionID/resourceGroups/Unit Test: ManagedImageResourceGroupName/providers/Microsoft.Compute/images/Unit Test: ManagedImageName"
+	if id := *actualImageVersion.Properties.PublishingProfile.Source.ManagedImage.ID; id != expectedManagedImageID {
+		t.Fatalf("Expected the managed image ID to be '%s', but got '%s'.", expectedManagedImageID, id)
+	}
+
+	if location := to.String(actualImageVersion.Location); location != "Unit Test: GalleryLocation" {
+		t.Fatalf("Expected the version to be created in the location of the gallery, but got '%s'.", location)
+	}
+
+	if version := stateBag.Get(constants.ArmManagedImageSharedImageGalleryVersion).(string); version != "1.0.11" {
+		t.Fatalf("Expected stateBag['%s'] to be '1.0.11', but got '%s'.", constants.ArmManagedImageSharedImageGalleryVersion, version)
+	}
+
+	if id := stateBag.Get(constants.ArmManagedImageSharedImageGalleryVersionID).(string); id != "Unit Test: GalleryImageVersionID" {
+		t.Fatalf("Expected stateBag['%s'] to be the ID of the version, but got '%s'.", constants.ArmManagedImageSharedImageGalleryVersionID, id)
+	}
+}
+
+func TestStepPublishToSharedImageGalleryShouldFailIfImageDefinitionIsNotTrustedLaunch(t *testing.T) {
+	config := createTestConfigStepPublishToSharedImageGallery("1.2.3")
+	config.SecurityType = "TrustedLaunch"
+
+	var testSubject = &StepPublishToSharedImageGallery{
+		config: config,
+		getImage: func(context.Context, string, string, string) (common.GalleryImage, error) {
+			return common.GalleryImage{
+				Name:       to.StringPtr("Unit Test: GalleryImageName"),
+				Properties: &common.GalleryImageProperties{HyperVGeneration: "V2"},
+			}, nil
+		},
+		publish: func(context.Context, string, string, string, string, common.GalleryImageVersion) (string, error) {
+			t.Fatal("Expected the step to not publish, but it did.")
+			return "", nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepPublishToSharedImageGallery()
+
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionHalt {
+		t.Fatalf("Expected the step to return 'ActionHalt', but got '%d'.", result)
+	}
+}
+
+func TestStepPublishToSharedImageGalleryShouldPublishTheVMOfASecurityType(t *testing.T) {
+	config := createTestConfigStepPublishToSharedImageGallery("1.2.3")
+	config.SecurityType = "TrustedLaunch"
+
+	var actualImageVersion common.GalleryImageVersion
+	var testSubject = &StepPublishToSharedImageGallery{
+		config: config,
+		getImage: func(context.Context, string, string, string) (common.GalleryImage, error) {
+			return common.GalleryImage{
+				Name: to.StringPtr("Unit Test: GalleryImageName"),
+				Properties: &common.GalleryImageProperties{
+					HyperVGeneration: "V2",
+					Features: &[]common.GalleryImageFeature{
+						{Name: to.StringPtr("SecurityType"), Value: to.StringPtr("TrustedLaunch")},
+					},
+				},
+			}, nil
+		},
+		publish: func(_ context.Context, _, _, _, _ string, imageVersion common.GalleryImageVersion) (string, error) {
+			actualImageVersion = imageVersion
+			return "Unit Test: GalleryImageVersionID", nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepPublishToSharedImageGallery()
+	stateBag.Put(constants.ArmComputeName, "Unit Test: ComputeName")
+	stateBag.Put(constants.ArmResourceGroupName, "Unit Test: ResourceGroupName")
+
+	var result = testSubject.Run(context.Background(), stateBag)
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+
+	if actualImageVersion.Properties.PublishingProfile.Source != nil {
+		t.Fatal("Expected the version to not be published from a managed image.")
+	}
+	expectedVMID := "/subscriptions/Unit Test: SubscriptionID/resourceGroups/Unit Test: ResourceGroupName/providers/Microsoft.Compute/virtualMachines/Unit Test: ComputeName"
+	if id := *actualImageVersion.Properties.StorageProfile.Source.ID; id != expectedVMID {
+		t.Fatalf("Expected the source to be the VM '%s', but got '%s'.", expectedVMID, id)
+	}
+}
+
+func TestAssertGalleryImageSecurityType(t *testing.T) {
+	image := func(generation, securityType string) common.GalleryImage {
+		return common.GalleryImage{
+			Name: to.StringPtr("image"),
+			Properties: &common.GalleryImageProperties{
+				HyperVGeneration: generation,
+				Features: &[]common.GalleryImageFeature{
+					{Name: to.StringPtr("SecurityType"), Value: to.StringPtr(securityType)},
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		image        common.GalleryImage
+		securityType string
+		valid        bool
+	}{
+		{image("V2", "TrustedLaunch"), "TrustedLaunch", true},
+		{image("V2", "TrustedLaunchSupported"), "TrustedLaunch", true},
+		{image("V2", "TrustedLaunchAndConfidentialVmSupported"), "ConfidentialVM", true},
+		{image("V2", "TrustedLaunch"), "ConfidentialVM", false},
+		{image("V1", "TrustedLaunch"), "TrustedLaunch", false},
+		{common.GalleryImage{Name: to.StringPtr("image")}, "TrustedLaunch", false},
+	}
+
+	for i, x := range cases {
+		err := assertGalleryImageSecurityType(x.image, x.securityType)
+		if (err == nil) != x.valid {
+			t.Errorf("Case %d: expected the image definition to be valid: %t, but got %v.", i, x.valid, err)
+		}
+	}
+}
+
+func TestAssertGalleryImagePurchasePlan(t *testing.T) {
+	plan := &PlanInformation{PlanName: "rabbitmq", PlanProduct: "rabbitmq", PlanPublisher: "bitnami"}
+	image := func(name, product, publisher string) common.GalleryImage {
+		return common.GalleryImage{
+			Name: to.StringPtr("image"),
+			Properties: &common.GalleryImageProperties{
+				PurchasePlan: &common.GalleryImagePurchasePlan{
+					Name:      to.StringPtr(name),
+					Product:   to.StringPtr(product),
+					Publisher: to.StringPtr(publisher),
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		image common.GalleryImage
+		valid bool
+	}{
+		{image("rabbitmq", "rabbitmq", "bitnami"), true},
+		{image("RabbitMQ", "rabbitmq", "Bitnami"), true},
+		{image("rabbitmq", "rabbitmq", "other"), false},
+		{common.GalleryImage{Name: to.StringPtr("image"), Properties: &common.GalleryImageProperties{}}, false},
+	}
+
+	for i, x := range cases {
+		err := assertGalleryImagePurchasePlan(x.image, plan)
+		if (err == nil) != x.valid {
+			t.Errorf("Case %d: expected the image definition to be valid: %t, but got %v.", i, x.valid, err)
+		}
+	}
+}
+
+func TestGalleryTargetRegionsShouldIncludeManagedImageLocation(t *testing.T) {
+	sig := &SharedImageGalleryDestination{
+		ReplicaCount:       2,
+		StorageAccountType: "Standard_LRS",
+		TargetRegions: []SharedImageGalleryTargetRegion{
+			{Name: "eastus", ReplicaCount: 5},
+			{Name: "westeurope", StorageAccountType: "Standard_ZRS"},
+		},
+	}
+
+	regions := galleryTargetRegions(sig, "West US 2", "", 0)
+	if len(regions) != 3 {
+		t.Fatalf("Expected 3 target regions, but got %d.", len(regions))
+	}
+
+	expected := []struct {
+		name               string
+		replicaCount       int32
+		storageAccountType string
+	}{
+		{"West US 2", 2, "Standard_LRS"},
+		{"eastus", 5, "Standard_LRS"},
+		{"westeurope", 2, "Standard_ZRS"},
+	}
+	for i, x := range expected {
+		if *regions[i].Name != x.name || *regions[i].RegionalReplicaCount != x.replicaCount || regions[i].StorageAccountType != x.storageAccountType {
+			t.Errorf("Expected target region %d to be %v, but got %s, %d, %s.", i, x,
+				*regions[i].Name, *regions[i].RegionalReplicaCount, regions[i].StorageAccountType)
+		}
+	}
+
+	sig.TargetRegions = append(sig.TargetRegions, SharedImageGalleryTargetRegion{Name: "westus2"})
+	regions = galleryTargetRegions(sig, "West US 2", "", 0)
+	if len(regions) != 3 || *regions[0].Name != "eastus" {
+		t.Fatalf("Expected the managed image location to not be added twice, but got %d target regions.", len(regions))
+	}
+	for _, region := range regions {
+		if region.Encryption != nil {
+			t.Errorf("Expected the target region %s to not be encrypted", *region.Name)
+		}
+	}
+}
+
+func TestGalleryTargetRegionsShouldEncryptWithTheDiskEncryptionSetOfTheirRegion(t *testing.T) {
+	sig := &SharedImageGalleryDestination{
+		ReplicaCount: 1,
+		TargetRegions: []SharedImageGalleryTargetRegion{
+			{Name: "eastus", DiskEncryptionSetID: "eastus-des"},
+			{Name: "westeurope"},
+		},
+	}
+
+	regions := galleryTargetRegions(sig, "West US 2", "westus2-des", 2)
+	if len(regions) != 3 {
+		t.Fatalf("Expected 3 target regions, but got %d.", len(regions))
+	}
+
+	for i, expected := range []string{"westus2-des", "eastus-des"} {
+		encryption := regions[i].Encryption
+		if encryption == nil || *encryption.OsDiskImage.DiskEncryptionSetID != expected {
+			t.Fatalf("Expected the OS disk image of %s to be encrypted with %s, but got %v.", *regions[i].Name, expected, encryption)
+		}
+		dataDiskImages := *encryption.DataDiskImages
+		if len(dataDiskImages) != 2 || *dataDiskImages[1].Lun != 1 || *dataDiskImages[1].DiskEncryptionSetID != expected {
+			t.Errorf("Expected the 2 data disk images of %s to be encrypted with %s, but got %v.", *regions[i].Name, expected, dataDiskImages)
+		}
+	}
+	if regions[2].Encryption != nil {
+		t.Errorf("Expected westeurope without a disk encryption set to not be encrypted, but got %v.", regions[2].Encryption)
+	}
+}
+
+func TestNextGalleryImageVersion(t *testing.T) {
+	cases := []struct {
+		versions []string
+		bump     string
+		expected string
+	}{
+		{nil, "patch", "1.0.0"},
+		{[]string{"latest", "1.0"}, "major", "1.0.0"},
+		{[]string{"1.2.3", "1.10.0", "1.9.9"}, "patch", "1.10.1"},
+		{[]string{"1.2.3", "1.10.0", "1.9.9"}, "minor", "1.11.0"},
+		{[]string{"1.2.3", "1.10.0", "1.9.9"}, "major", "2.0.0"},
+	}
+
+	for _, x := range cases {
+		version, err := nextGalleryImageVersion(x.versions, x.bump)
+		if err != nil {
+			t.Fatalf("Expected no error bumping %v, but got %s.", x.versions, err)
+		}
+		if version != x.expected {
+			t.Errorf("Expected the %s bump of %v to be '%s', but got '%s'.", x.bump, x.versions, x.expected, version)
+		}
+	}
+
+	if _, err := nextGalleryImageVersion([]string{"1.0.0"}, "build"); err == nil {
+		t.Fatal("Expected an unknown version bump to fail, but it did not.")
+	}
+}
+
+func createTestConfigStepPublishToSharedImageGallery(version string) *Config {
+	return &Config{
+		SubscriptionID: "Unit Test: SubscriptionID",
+		SharedGalleryDestination: SharedImageGalleryDestination{
+			ResourceGroup:      "Unit Test: GalleryResourceGroupName",
+			GalleryName:        "Unit Test: GalleryName",
+			ImageName:          "Unit Test: GalleryImageName",
+			ImageVersion:       version,
+			VersionBump:        DefaultSharedImageGalleryVersionBump,
+			ReplicaCount:       DefaultSharedImageGalleryReplicaCount,
+			StorageAccountType: "Standard_LRS",
+		},
+	}
+}
+
+func getTestGalleryImage(context.Context, string, string, string) (common.GalleryImage, error) {
+	return common.GalleryImage{
+		Name:     to.StringPtr("Unit Test: GalleryImageName"),
+		Location: to.StringPtr("Unit Test: GalleryLocation"),
+	}, nil
+}
+
+func createTestStateBagStepPublishToSharedImageGallery() multistep.StateBag {
+	stateBag := new(multistep.BasicStateBag)
+
+	stateBag.Put(constants.ArmManagedImageLocation, "Unit Test: ManagedImageLocation")
+	stateBag.Put(constants.ArmManagedImageResourceGroupName, "Unit Test: ManagedImageResourceGroupName")
+	stateBag.Put(constants.ArmManagedImageName, "Unit Test: ManagedImageName")
+
+	return stateBag
+}
@@ -92,6 +92,34 @@ func GetVirtualMachineDeployment(config *Config) (*resources.Deployment, error)
 		builder.SetPlanInfo(config.PlanInfo.PlanName, config.PlanInfo.PlanProduct, config.PlanInfo.PlanPublisher, config.PlanInfo.PlanPromotionCode)
 	}
 
+	if config.Spot.EvictionPolicy != "" {
+		err = builder.SetSpot(config.Spot.EvictionPolicy, config.Spot.MaxPrice)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.SecurityType != "" {
+		err = builder.SetSecurityProfile(config.SecurityType, config.SecureBootEnabled, config.VTpmEnabled)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.BootDiagnostics {
+		err = builder.SetBootDiagnostics(config.isManagedImage())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.DiskEncryptionSetID != "" {
+		err = builder.SetDiskEncryptionSet(config.DiskEncryptionSetID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if config.VirtualNetworkName != "" && DefaultPrivateVirtualNetworkWithPublicIp != config.PrivateVirtualNetworkWithPublicIp {
 		builder.SetPrivateVirtualNetworkWithPublicIp(
 			config.VirtualNetworkResourceGroupName,
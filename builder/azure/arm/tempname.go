@@ -20,6 +20,7 @@ type TempName struct {
 	ResourceGroupName   string
 	OSDiskName          string
 	NicName             string
+	NsgRuleName         string
 	SubnetName          string
 	PublicIPAddressName string
 	VirtualNetworkName  string
@@ -34,6 +35,7 @@ func NewTempName() *TempName {
 	tempName.KeyVaultName = fmt.Sprintf("pkrkv%s", suffix)
 	tempName.OSDiskName = fmt.Sprintf("pkros%s", suffix)
 	tempName.NicName = fmt.Sprintf("pkrni%s", suffix)
+	tempName.NsgRuleName = fmt.Sprintf("pkrsr%s", suffix)
 	tempName.PublicIPAddressName = fmt.Sprintf("pkrip%s", suffix)
 	tempName.SubnetName = fmt.Sprintf("pkrsn%s", suffix)
 	tempName.VirtualNetworkName = fmt.Sprintf("pkrvn%s", suffix)
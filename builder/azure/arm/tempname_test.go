@@ -24,6 +24,10 @@ func TestTempNameShouldCreatePrefixedRandomNames(t *testing.T) {
 		t.Errorf("Expected NicName to begin with 'pkrni', but got '%s'!", tempName.NicName)
 	}
 
+	if strings.Index(tempName.NsgRuleName, "pkrsr") != 0 {
+		t.Errorf("Expected NsgRuleName to begin with 'pkrsr', but got '%s'!", tempName.NsgRuleName)
+	}
+
 	if strings.Index(tempName.PublicIPAddressName, "pkrip") != 0 {
 		t.Errorf("Expected PublicIPAddressName to begin with 'pkrip', but got '%s'!", tempName.PublicIPAddressName)
 	}
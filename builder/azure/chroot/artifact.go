@@ -0,0 +1,46 @@
+package chroot
+
+import (
+	"context"
+	"fmt"
+)
+
+// Artifact is the managed image the build created.
+type Artifact struct {
+	ResourceID        string
+	ResourceGroupName string
+	Name              string
+	Location          string
+
+	client *AzureClient
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return a.ResourceID
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Azure managed image:\n\nManagedImageResourceGroupName: %s\nManagedImageName: %s\nManagedImageId: %s\nManagedImageLocation: %s\n",
+		a.ResourceGroupName, a.Name, a.ResourceID, a.Location)
+}
+
+func (*Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	ctx := context.TODO()
+	f, err := a.client.ImagesClient.Delete(ctx, a.ResourceGroupName, a.Name)
+	if err == nil {
+		err = f.WaitForCompletion(ctx, a.client.ImagesClient.Client)
+	}
+	return err
+}
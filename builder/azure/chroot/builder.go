@@ -0,0 +1,156 @@
+// The chroot package is able to create an Azure managed image without
+// requiring the launch of a new VM for every build. It does this by
+// attaching a new managed disk, copied from the source image, to the Azure
+// VM Packer runs on, and chrooting into where it is mounted. It then
+// creates a managed image from that disk.
+package chroot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	packerAzureCommon "github.com/hashicorp/packer/builder/azure/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The unique ID for this builder
+const BuilderId = "azure.chroot"
+
+type wrappedCommandTemplate struct {
+	Command string
+}
+
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, errs := NewConfig(raws...)
+	if errs != nil {
+		return warnings, errs
+	}
+	b.config = c
+
+	return warnings, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("The azure-chroot builder only works on Linux environments.")
+	}
+
+	instance, err := GetInstanceMetadata()
+	if err != nil {
+		return nil, err
+	}
+	if b.config.SubscriptionID != "" {
+		instance.SubscriptionID = b.config.SubscriptionID
+	}
+	log.Printf("Building on the VM %s in %s", instance.ResourceID(), instance.Location)
+
+	env, err := azure.EnvironmentFromName(instance.AzEnvironment)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := b.getServicePrincipalToken(&env)
+	if err != nil {
+		return nil, err
+	}
+	client := NewAzureClient(instance.SubscriptionID, &env, token)
+
+	wrappedCommand := func(command string) (string, error) {
+		ctx := b.config.ctx
+		ctx.Data = &wrappedCommandTemplate{Command: command}
+		return interpolate.Render(b.config.CommandWrapper, &ctx)
+	}
+
+	// Setup the state bag and initial state for the steps
+	state := new(multistep.BasicStateBag)
+	state.Put("config", b.config)
+	state.Put("azureclient", client)
+	state.Put("instance", instance)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+	state.Put("wrappedCommand", chroot.CommandWrapper(wrappedCommand))
+
+	// Build the steps
+	steps := []multistep.Step{
+		NewStepCheckExistingImage(client, ui, b.config),
+		NewStepCreateNewDisk(client, ui, b.config),
+		NewStepAttachDisk(client, ui, b.config),
+		&chroot.StepPreMountCommands{
+			Commands: b.config.PreMountCommands,
+		},
+		&StepMountDevice{},
+		&chroot.StepPostMountCommands{
+			Commands: b.config.PostMountCommands,
+		},
+		&chroot.StepMountExtra{
+			ChrootMounts: b.config.ChrootMounts,
+		},
+		&chroot.StepCopyFiles{
+			Files: b.config.CopyFiles,
+		},
+		&chroot.StepChrootProvision{},
+		&chroot.StepEarlyCleanup{},
+		NewStepCreateImage(client, ui, b.config),
+	}
+
+	// Run!
+	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	// If we were interrupted or cancelled, then just exit.
+	if _, ok := state.GetOk(multistep.StateCancelled); ok {
+		return nil, errors.New("Build was cancelled.")
+	}
+
+	if _, ok := state.GetOk(multistep.StateHalted); ok {
+		return nil, errors.New("Build was halted.")
+	}
+
+	return &Artifact{
+		ResourceID:        state.Get("image_id").(string),
+		ResourceGroupName: b.config.ManagedImageResourceGroupName,
+		Name:              b.config.ManagedImageName,
+		Location:          instance.Location,
+		client:            client,
+	}, nil
+}
+
+// getServicePrincipalToken authenticates as the service principal if there
+// is a client secret, or else as the managed identity of the VM.
+func (b *Builder) getServicePrincipalToken(env *azure.Environment) (*adal.ServicePrincipalToken, error) {
+	if b.config.ClientSecret == "" {
+		log.Print("Authenticating with the managed identity of the VM")
+		return packerAzureCommon.NewManagedIdentityToken(env.ResourceManagerEndpoint, b.config.ClientID)
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, b.config.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("Error authenticating the service principal %s: %s", b.config.ClientID, err)
+	}
+	return adal.NewServicePrincipalToken(*oauthConfig, b.config.ClientID, b.config.ClientSecret, env.ResourceManagerEndpoint)
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
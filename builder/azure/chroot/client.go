@@ -0,0 +1,42 @@
+package chroot
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/packer/helper/useragent"
+)
+
+// AzureClient is the set of Azure clients the steps use.
+type AzureClient struct {
+	compute.DisksClient
+	compute.ImagesClient
+	compute.VirtualMachinesClient
+	compute.VirtualMachineImagesClient
+}
+
+func NewAzureClient(subscriptionID string, cloud *azure.Environment, servicePrincipalToken *adal.ServicePrincipalToken) *AzureClient {
+	var azureClient = &AzureClient{}
+	authorizer := autorest.NewBearerAuthorizer(servicePrincipalToken)
+
+	azureClient.DisksClient = compute.NewDisksClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.DisksClient.Authorizer = authorizer
+	azureClient.DisksClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.DisksClient.UserAgent)
+
+	azureClient.ImagesClient = compute.NewImagesClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.ImagesClient.Authorizer = authorizer
+	azureClient.ImagesClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.ImagesClient.UserAgent)
+
+	azureClient.VirtualMachinesClient = compute.NewVirtualMachinesClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.VirtualMachinesClient.Authorizer = authorizer
+	azureClient.VirtualMachinesClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.VirtualMachinesClient.UserAgent)
+
+	azureClient.VirtualMachineImagesClient = compute.NewVirtualMachineImagesClientWithBaseURI(cloud.ResourceManagerEndpoint, subscriptionID)
+	azureClient.VirtualMachineImagesClient.Authorizer = authorizer
+	azureClient.VirtualMachineImagesClient.UserAgent = fmt.Sprintf("%s %s", useragent.String(), azureClient.VirtualMachineImagesClient.UserAgent)
+
+	return azureClient
+}
@@ -0,0 +1,192 @@
+package chroot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// Config is the configuration that is chained through the steps and
+// settable from the template.
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The service principal to authenticate with. Without a client secret,
+	// the managed identity of the VM is used.
+	ClientID       string `mapstructure:"client_id"`
+	ClientSecret   string `mapstructure:"client_secret"`
+	TenantID       string `mapstructure:"tenant_id"`
+	SubscriptionID string `mapstructure:"subscription_id"`
+
+	// Source is the URN of a Marketplace image or the resource ID of a
+	// managed disk.
+	Source string `mapstructure:"source"`
+
+	TemporaryOSDiskName      string `mapstructure:"temporary_os_disk_name"`
+	OSDiskSizeGB             int32  `mapstructure:"os_disk_size_gb"`
+	OSDiskStorageAccountType string `mapstructure:"os_disk_storage_account_type"`
+	OSDiskCacheType          string `mapstructure:"os_disk_cache_type"`
+	OSDiskSkipCleanup        bool   `mapstructure:"os_disk_skip_cleanup"`
+
+	ManagedImageName              string            `mapstructure:"managed_image_name"`
+	ManagedImageResourceGroupName string            `mapstructure:"managed_image_resource_group_name"`
+	ImageHyperVGeneration         string            `mapstructure:"image_hyperv_generation"`
+	AzureTags                     map[string]string `mapstructure:"azure_tags"`
+
+	ChrootMounts      [][]string `mapstructure:"chroot_mounts"`
+	CommandWrapper    string     `mapstructure:"command_wrapper"`
+	CopyFiles         []string   `mapstructure:"copy_files"`
+	MountOptions      []string   `mapstructure:"mount_options"`
+	MountPartition    string     `mapstructure:"mount_partition"`
+	MountPath         string     `mapstructure:"mount_path"`
+	PostMountCommands []string   `mapstructure:"post_mount_commands"`
+	PreMountCommands  []string   `mapstructure:"pre_mount_commands"`
+
+	ctx interpolate.Context
+}
+
+// GetContext is the interpolation context of the configuration, the mount
+// commands are interpolated with.
+func (c *Config) GetContext() interpolate.Context {
+	return c.ctx
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"command_wrapper",
+				"post_mount_commands",
+				"pre_mount_commands",
+				"mount_path",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if len(c.ChrootMounts) == 0 {
+		c.ChrootMounts = [][]string{
+			{"proc", "proc", "/proc"},
+			{"sysfs", "sysfs", "/sys"},
+			{"bind", "/dev", "/dev"},
+			{"devpts", "devpts", "/dev/pts"},
+			{"binfmt_misc", "binfmt_misc", "/proc/sys/fs/binfmt_misc"},
+		}
+	}
+
+	if c.CopyFiles == nil {
+		c.CopyFiles = []string{"/etc/resolv.conf"}
+	}
+
+	if c.CommandWrapper == "" {
+		c.CommandWrapper = "{{.Command}}"
+	}
+
+	if c.MountPath == "" {
+		c.MountPath = "/mnt/packer-azure-chroot-disks/{{.Device}}"
+	}
+
+	if c.MountPartition == "" {
+		c.MountPartition = "1"
+	}
+
+	if c.ImageHyperVGeneration == "" {
+		c.ImageHyperVGeneration = "V1"
+	}
+
+	if c.OSDiskStorageAccountType == "" {
+		c.OSDiskStorageAccountType = string(compute.StorageAccountTypesStandardLRS)
+	}
+
+	if c.OSDiskCacheType == "" {
+		c.OSDiskCacheType = string(compute.CachingTypesReadOnly)
+	}
+
+	// Accumulate any errors or warnings
+	var errs *packer.MultiError
+	var warns []string
+
+	if c.TemporaryOSDiskName == "" {
+		c.TemporaryOSDiskName, err = interpolate.Render("PackerTemp-osdisk-{{timestamp}}", &c.ctx)
+		if err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+
+	if c.ClientSecret != "" && (c.ClientID == "" || c.TenantID == "") {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("client_id and tenant_id must be set with client_secret."))
+	}
+
+	if c.Source == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("source is required."))
+	} else if !isManagedDiskID(c.Source) {
+		if _, err := ParsePlatformImage(c.Source); err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"source must be a Publisher:Offer:Sku:Version image URN or the resource ID of a managed disk: %s", err))
+		}
+	}
+
+	if c.ManagedImageName == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("managed_image_name is required."))
+	}
+	if c.ManagedImageResourceGroupName == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("managed_image_resource_group_name is required."))
+	}
+
+	if c.ImageHyperVGeneration != "V1" && c.ImageHyperVGeneration != "V2" {
+		errs = packer.MultiErrorAppend(errs, errors.New("image_hyperv_generation must be V1 or V2."))
+	}
+
+	if c.OSDiskSizeGB < 0 {
+		errs = packer.MultiErrorAppend(errs, errors.New("os_disk_size_gb must not be negative."))
+	}
+
+	switch compute.StorageAccountTypes(c.OSDiskStorageAccountType) {
+	case compute.StorageAccountTypesStandardLRS, compute.StorageAccountTypesPremiumLRS:
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"os_disk_storage_account_type must be %s or %s.",
+			compute.StorageAccountTypesStandardLRS, compute.StorageAccountTypesPremiumLRS))
+	}
+
+	switch compute.CachingTypes(c.OSDiskCacheType) {
+	case compute.CachingTypesNone, compute.CachingTypesReadOnly, compute.CachingTypesReadWrite:
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"os_disk_cache_type must be %s, %s or %s.",
+			compute.CachingTypesNone, compute.CachingTypesReadOnly, compute.CachingTypesReadWrite))
+	}
+
+	for _, mounts := range c.ChrootMounts {
+		if len(mounts) != 3 {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("Each chroot_mounts entry should be three elements."))
+			break
+		}
+	}
+
+	if c.OSDiskSkipCleanup {
+		warns = append(warns, fmt.Sprintf(
+			"os_disk_skip_cleanup is set, the temporary disk %s will not be deleted.", c.TemporaryOSDiskName))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, warns, errs
+	}
+
+	log.Println(common.ScrubConfig(c, c.ClientSecret))
+	return c, warns, nil
+}
@@ -0,0 +1,131 @@
+package chroot
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"source":                            "Canonical:UbuntuServer:18.04-LTS:latest",
+		"managed_image_name":                "ignore",
+		"managed_image_resource_group_name": "ignore",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestNewConfig_defaults(t *testing.T) {
+	c, warns, err := NewConfig(testConfig())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+
+	if len(c.ChrootMounts) != 5 {
+		t.Errorf("bad chroot_mounts: %#v", c.ChrootMounts)
+	}
+	if len(c.CopyFiles) != 1 || c.CopyFiles[0] != "/etc/resolv.conf" {
+		t.Errorf("bad copy_files: %#v", c.CopyFiles)
+	}
+	if c.MountPartition != "1" {
+		t.Errorf("bad mount_partition: %s", c.MountPartition)
+	}
+	if c.OSDiskStorageAccountType != "Standard_LRS" || c.OSDiskCacheType != "ReadOnly" {
+		t.Errorf("bad os disk: %s, %s", c.OSDiskStorageAccountType, c.OSDiskCacheType)
+	}
+	if c.ImageHyperVGeneration != "V1" {
+		t.Errorf("bad image_hyperv_generation: %s", c.ImageHyperVGeneration)
+	}
+	if c.TemporaryOSDiskName == "" || c.TemporaryOSDiskName == "PackerTemp-osdisk-{{timestamp}}" {
+		t.Errorf("bad temporary_os_disk_name: %s", c.TemporaryOSDiskName)
+	}
+}
+
+func TestNewConfig_source(t *testing.T) {
+	for _, source := range []string{
+		"Canonical:UbuntuServer:18.04-LTS:18.04.201908131",
+		"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/disks/disk",
+	} {
+		config := testConfig()
+		config["source"] = source
+		if _, _, err := NewConfig(config); err != nil {
+			t.Errorf("source %s: %s", source, err)
+		}
+	}
+
+	for _, source := range []string{
+		"",
+		"Canonical:UbuntuServer:18.04-LTS",
+		"Canonical::18.04-LTS:latest",
+		"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/images/image",
+	} {
+		config := testConfig()
+		config["source"] = source
+		if _, _, err := NewConfig(config); err == nil {
+			t.Errorf("source %q should be rejected", source)
+		}
+	}
+}
+
+func TestNewConfig_reject(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"managed_image_name": ""},
+		{"managed_image_resource_group_name": ""},
+		{"client_secret": "secret", "client_id": "id"},
+		{"client_secret": "secret", "tenant_id": "tenant"},
+		{"os_disk_size_gb": -1},
+		{"os_disk_storage_account_type": "Ultra_LRS"},
+		{"os_disk_cache_type": "WriteOnly"},
+		{"image_hyperv_generation": "V3"},
+		{"chroot_mounts": [][]string{{"proc", "/proc"}}},
+	}
+
+	for _, settings := range cases {
+		config := testConfig()
+		for k, v := range settings {
+			config[k] = v
+		}
+		if _, _, err := NewConfig(config); err == nil {
+			t.Errorf("expected the settings %v to be rejected", settings)
+		}
+	}
+}
+
+func TestNewConfig_authentication(t *testing.T) {
+	config := testConfig()
+	config["client_id"] = "id"
+	config["client_secret"] = "secret"
+	config["tenant_id"] = "tenant"
+	if _, _, err := NewConfig(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A user-assigned managed identity
+	delete(config, "client_secret")
+	delete(config, "tenant_id")
+	if _, _, err := NewConfig(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestNewConfig_skipCleanupWarns(t *testing.T) {
+	config := testConfig()
+	config["os_disk_skip_cleanup"] = true
+	_, warns, err := NewConfig(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+}
@@ -0,0 +1,65 @@
+package chroot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// instanceMetadataEndpoint is where the Azure Instance Metadata Service
+// describes the VM Packer runs on.
+var instanceMetadataEndpoint = "http://169.254.169.254/metadata/instance/compute?api-version=2018-10-01"
+
+// InstanceMetadata is the part of the metadata of the VM Packer runs on the
+// builder needs, to attach disks to it.
+type InstanceMetadata struct {
+	SubscriptionID    string `json:"subscriptionId"`
+	ResourceGroupName string `json:"resourceGroupName"`
+	Name              string `json:"name"`
+	Location          string `json:"location"`
+	Zone              string `json:"zone"`
+	AzEnvironment     string `json:"azEnvironment"`
+}
+
+// ResourceID is the resource ID of the VM.
+func (m *InstanceMetadata) ResourceID() string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s",
+		m.SubscriptionID, m.ResourceGroupName, m.Name)
+}
+
+// GetInstanceMetadata asks the Instance Metadata Service which VM Packer
+// runs on. It fails quickly outside of Azure, where there is no such
+// service.
+func GetInstanceMetadata() (*InstanceMetadata, error) {
+	req, err := http.NewRequest(http.MethodGet, instanceMetadataEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the metadata of the Azure VM, the azure-chroot builder only runs on Azure VMs: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error reading the metadata of the Azure VM: %s: %s", resp.Status, body)
+	}
+
+	var m InstanceMetadata
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("Error parsing the metadata of the Azure VM: %s", err)
+	}
+	if m.AzEnvironment == "" {
+		m.AzEnvironment = "AzurePublicCloud"
+	}
+	return &m, nil
+}
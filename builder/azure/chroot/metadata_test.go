@@ -0,0 +1,49 @@
+package chroot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetInstanceMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"location": "westeurope", "name": "builder", "resourceGroupName": "rg", "subscriptionId": "sub", "zone": "2", "vmSize": "Standard_D2s_v3"}`))
+	}))
+	defer server.Close()
+
+	defer func(endpoint string) { instanceMetadataEndpoint = endpoint }(instanceMetadataEndpoint)
+	instanceMetadataEndpoint = server.URL
+
+	m, err := GetInstanceMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Location != "westeurope" || m.Name != "builder" || m.ResourceGroupName != "rg" || m.SubscriptionID != "sub" || m.Zone != "2" {
+		t.Fatalf("bad metadata: %#v", m)
+	}
+	if m.AzEnvironment != "AzurePublicCloud" {
+		t.Fatalf("bad environment: %s", m.AzEnvironment)
+	}
+	if m.ResourceID() != "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/builder" {
+		t.Fatalf("bad resource ID: %s", m.ResourceID())
+	}
+}
+
+func TestGetInstanceMetadata_error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	defer func(endpoint string) { instanceMetadataEndpoint = endpoint }(instanceMetadataEndpoint)
+	instanceMetadataEndpoint = server.URL
+
+	if _, err := GetInstanceMetadata(); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,91 @@
+package chroot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+)
+
+// PlatformImage is a Marketplace image, as the URN
+// Publisher:Offer:Sku:Version the Azure CLI uses.
+type PlatformImage struct {
+	Publisher string
+	Offer     string
+	Sku       string
+	Version   string
+}
+
+// ParsePlatformImage parses the URN of a Marketplace image. The version may
+// be "latest".
+func ParsePlatformImage(urn string) (*PlatformImage, error) {
+	parts := strings.Split(urn, ":")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("%q is not a Publisher:Offer:Sku:Version image URN", urn)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("%q is not a Publisher:Offer:Sku:Version image URN", urn)
+		}
+	}
+	return &PlatformImage{
+		Publisher: parts[0],
+		Offer:     parts[1],
+		Sku:       parts[2],
+		Version:   parts[3],
+	}, nil
+}
+
+func (pi *PlatformImage) String() string {
+	return fmt.Sprintf("%s:%s:%s:%s", pi.Publisher, pi.Offer, pi.Sku, pi.Version)
+}
+
+// isManagedDiskID is true for the resource IDs of managed disks.
+func isManagedDiskID(source string) bool {
+	parts := strings.Split(strings.Trim(source, "/"), "/")
+	return len(parts) == 8 &&
+		strings.EqualFold(parts[0], "subscriptions") &&
+		strings.EqualFold(parts[2], "resourceGroups") &&
+		strings.EqualFold(parts[4], "providers") &&
+		strings.EqualFold(parts[5], "Microsoft.Compute") &&
+		strings.EqualFold(parts[6], "disks")
+}
+
+// latestImageVersion returns the image version with the highest version
+// number. The versions compare number by number, so 1.10 comes after 1.9.
+func latestImageVersion(versions []compute.VirtualMachineImageResource) (*compute.VirtualMachineImageResource, error) {
+	var latest *compute.VirtualMachineImageResource
+	for i := range versions {
+		if versions[i].Name == nil {
+			continue
+		}
+		if latest == nil || compareVersions(*versions[i].Name, *latest.Name) > 0 {
+			latest = &versions[i]
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no image versions")
+	}
+	return latest, nil
+}
+
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
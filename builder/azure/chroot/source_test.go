@@ -0,0 +1,62 @@
+package chroot
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func TestParsePlatformImage(t *testing.T) {
+	image, err := ParsePlatformImage("Canonical:UbuntuServer:18.04-LTS:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.Publisher != "Canonical" || image.Offer != "UbuntuServer" || image.Sku != "18.04-LTS" || image.Version != "latest" {
+		t.Fatalf("bad image: %#v", image)
+	}
+	if image.String() != "Canonical:UbuntuServer:18.04-LTS:latest" {
+		t.Fatalf("bad string: %s", image)
+	}
+}
+
+func TestIsManagedDiskID(t *testing.T) {
+	if !isManagedDiskID("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/disks/disk") {
+		t.Error("should be a managed disk")
+	}
+	if !isManagedDiskID("/Subscriptions/sub/resourcegroups/rg/providers/microsoft.compute/Disks/disk") {
+		t.Error("should be a managed disk regardless of case")
+	}
+	if isManagedDiskID("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/snapshots/snapshot") {
+		t.Error("should not be a managed disk")
+	}
+}
+
+func TestLatestImageVersion(t *testing.T) {
+	versions := []compute.VirtualMachineImageResource{
+		{Name: to.StringPtr("18.04.201908131"), ID: to.StringPtr("a")},
+		{Name: to.StringPtr("18.04.201910030"), ID: to.StringPtr("b")},
+		{Name: to.StringPtr("18.04.201909091"), ID: to.StringPtr("c")},
+	}
+	latest, err := latestImageVersion(versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *latest.ID != "b" {
+		t.Fatalf("bad latest version: %s", *latest.Name)
+	}
+
+	// 1.10 comes after 1.9
+	versions = []compute.VirtualMachineImageResource{
+		{Name: to.StringPtr("1.10.0")},
+		{Name: to.StringPtr("1.9.0")},
+	}
+	latest, _ = latestImageVersion(versions)
+	if *latest.Name != "1.10.0" {
+		t.Fatalf("bad latest version: %s", *latest.Name)
+	}
+
+	if _, err := latestImageVersion(nil); err == nil {
+		t.Fatal("expected an error without versions")
+	}
+}
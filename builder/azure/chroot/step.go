@@ -0,0 +1,11 @@
+package chroot
+
+import (
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func halt(state multistep.StateBag, sayError func(error), err error) multistep.StepAction {
+	state.Put("error", err)
+	sayError(err)
+	return multistep.ActionHalt
+}
@@ -0,0 +1,223 @@
+package chroot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepAttachDisk attaches the temporary OS disk to the VM Packer runs on,
+// as a data disk with the first free LUN.
+//
+// Produces:
+//   device string - The block device the disk is attached as.
+//   attach_cleanup Cleanup - To detach the disk early.
+type StepAttachDisk struct {
+	client        *AzureClient
+	config        *Config
+	lock          func() (unlock func() error, err error)
+	get           func(ctx context.Context, instance *InstanceMetadata) (compute.VirtualMachine, error)
+	update        func(ctx context.Context, instance *InstanceMetadata, vm compute.VirtualMachine) error
+	waitForDevice func(lun int32) (string, error)
+	say           func(message string)
+	error         func(e error)
+
+	instance *InstanceMetadata
+	diskID   string
+}
+
+func NewStepAttachDisk(client *AzureClient, ui packer.Ui, config *Config) *StepAttachDisk {
+	var step = &StepAttachDisk{
+		client: client,
+		config: config,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.lock = step.lockVM
+	step.get = step.getVM
+	step.update = step.updateVM
+	step.waitForDevice = step.waitForLunDevice
+	return step
+}
+
+// lockVM obtains the lock of the chroot builds running on the VM. The data
+// disks are changed with a read and an update of the whole VM, which would
+// lose the changes another build makes in between.
+func (s *StepAttachDisk) lockVM() (func() error, error) {
+	f, err := chroot.Lock()
+	if err != nil {
+		return nil, err
+	}
+	return func() error { return chroot.Unlock(f) }, nil
+}
+
+func (s *StepAttachDisk) getVM(ctx context.Context, instance *InstanceMetadata) (compute.VirtualMachine, error) {
+	return s.client.VirtualMachinesClient.Get(ctx, instance.ResourceGroupName, instance.Name, "")
+}
+
+func (s *StepAttachDisk) updateVM(ctx context.Context, instance *InstanceMetadata, vm compute.VirtualMachine) error {
+	// The extensions of the VM can't be updated along with its disks
+	vm.Resources = nil
+
+	f, err := s.client.VirtualMachinesClient.CreateOrUpdate(ctx, instance.ResourceGroupName, instance.Name, vm)
+	if err == nil {
+		err = f.WaitForCompletion(ctx, s.client.VirtualMachinesClient.Client)
+	}
+	return err
+}
+
+// waitForLunDevice waits for the udev rules of the Azure Linux agent to
+// link the disk with the LUN, and returns the block device it links to.
+func (s *StepAttachDisk) waitForLunDevice(lun int32) (string, error) {
+	link := fmt.Sprintf("/dev/disk/azure/scsi1/lun%d", lun)
+	deadline := time.Now().Add(2 * time.Minute)
+	for {
+		if _, err := os.Stat(link); err == nil {
+			return filepath.EvalSymlinks(link)
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("Timeout waiting for %s to appear", link)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// freeLun returns the lowest LUN no data disk of the VM uses.
+func freeLun(vm compute.VirtualMachine) int32 {
+	used := make(map[int32]bool)
+	if vm.VirtualMachineProperties != nil && vm.StorageProfile != nil && vm.StorageProfile.DataDisks != nil {
+		for _, disk := range *vm.StorageProfile.DataDisks {
+			if disk.Lun != nil {
+				used[*disk.Lun] = true
+			}
+		}
+	}
+
+	var lun int32
+	for used[lun] {
+		lun++
+	}
+	return lun
+}
+
+func (s *StepAttachDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	instance := state.Get("instance").(*InstanceMetadata)
+	diskID := state.Get("os_disk_resource_id").(string)
+
+	s.say(fmt.Sprintf("Attaching the temporary OS disk to %s ...", instance.Name))
+
+	unlock, err := s.lock()
+	if err != nil {
+		return halt(state, s.error, err)
+	}
+	lun, err := s.attachDisk(ctx, instance, diskID)
+	if unlockErr := unlock(); err == nil && unlockErr != nil {
+		err = fmt.Errorf("Error unlocking: %s", unlockErr)
+	}
+	if err != nil {
+		return halt(state, s.error, err)
+	}
+	s.instance = instance
+	s.diskID = diskID
+
+	device, err := s.waitForDevice(lun)
+	if err != nil {
+		return halt(state, s.error, err)
+	}
+	s.say(fmt.Sprintf(" -> Attached as LUN %d, device %s", lun, device))
+
+	state.Put("device", device)
+	state.Put("attach_cleanup", s)
+	return multistep.ActionContinue
+}
+
+// attachDisk adds the disk to the data disks of the VM, with the first free
+// LUN it returns.
+func (s *StepAttachDisk) attachDisk(ctx context.Context, instance *InstanceMetadata, diskID string) (int32, error) {
+	vm, err := s.get(ctx, instance)
+	if err != nil {
+		return 0, fmt.Errorf("Error reading the VM %s: %s", instance.Name, err)
+	}
+	if vm.VirtualMachineProperties == nil {
+		vm.VirtualMachineProperties = &compute.VirtualMachineProperties{}
+	}
+	if vm.StorageProfile == nil {
+		vm.StorageProfile = &compute.StorageProfile{}
+	}
+
+	lun := freeLun(vm)
+	var dataDisks []compute.DataDisk
+	if vm.StorageProfile.DataDisks != nil {
+		dataDisks = *vm.StorageProfile.DataDisks
+	}
+	dataDisks = append(dataDisks, compute.DataDisk{
+		Lun:          to.Int32Ptr(lun),
+		Name:         to.StringPtr(s.config.TemporaryOSDiskName),
+		CreateOption: compute.DiskCreateOptionTypesAttach,
+		Caching:      compute.CachingTypes(s.config.OSDiskCacheType),
+		ManagedDisk:  &compute.ManagedDiskParameters{ID: to.StringPtr(diskID)},
+	})
+	vm.StorageProfile.DataDisks = &dataDisks
+
+	if err := s.update(ctx, instance, vm); err != nil {
+		return 0, fmt.Errorf("Error attaching the disk to %s: %s", instance.Name, err)
+	}
+	return lun, nil
+}
+
+func (s *StepAttachDisk) Cleanup(state multistep.StateBag) {
+	if err := s.CleanupFunc(state); err != nil {
+		s.error(err)
+	}
+}
+
+// CleanupFunc detaches the disk. It does nothing if the disk isn't
+// attached anymore, so it is safe to call more than once.
+func (s *StepAttachDisk) CleanupFunc(state multistep.StateBag) error {
+	if s.diskID == "" {
+		return nil
+	}
+
+	s.say(fmt.Sprintf("Detaching the temporary OS disk from %s ...", s.instance.Name))
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ctx := context.TODO()
+	vm, err := s.get(ctx, s.instance)
+	if err != nil {
+		return fmt.Errorf("Error reading the VM %s: %s", s.instance.Name, err)
+	}
+
+	if vm.VirtualMachineProperties != nil && vm.StorageProfile != nil && vm.StorageProfile.DataDisks != nil {
+		var dataDisks []compute.DataDisk
+		for _, disk := range *vm.StorageProfile.DataDisks {
+			if disk.ManagedDisk != nil && strings.EqualFold(to.String(disk.ManagedDisk.ID), s.diskID) {
+				continue
+			}
+			dataDisks = append(dataDisks, disk)
+		}
+		vm.StorageProfile.DataDisks = &dataDisks
+	}
+
+	if err := s.update(ctx, s.instance, vm); err != nil {
+		return fmt.Errorf("Error detaching the disk from %s: %s", s.instance.Name, err)
+	}
+	log.Printf("Detached %s from %s", s.diskID, s.instance.Name)
+
+	s.diskID = ""
+	return nil
+}
@@ -0,0 +1,103 @@
+package chroot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepAttachDiskShouldAttachAndDetach(t *testing.T) {
+	vm := compute.VirtualMachine{
+		Resources: &[]compute.VirtualMachineExtension{{}},
+		VirtualMachineProperties: &compute.VirtualMachineProperties{
+			StorageProfile: &compute.StorageProfile{
+				DataDisks: &[]compute.DataDisk{
+					{Lun: to.Int32Ptr(0), ManagedDisk: &compute.ManagedDiskParameters{ID: to.StringPtr("data0")}},
+					{Lun: to.Int32Ptr(2), ManagedDisk: &compute.ManagedDiskParameters{ID: to.StringPtr("data2")}},
+				},
+			},
+		},
+	}
+
+	var waitedFor int32 = -1
+	locked := false
+	testSubject := &StepAttachDisk{
+		config: testStepConfig(),
+		lock: func() (func() error, error) {
+			locked = true
+			return func() error { locked = false; return nil }, nil
+		},
+		get: func(context.Context, *InstanceMetadata) (compute.VirtualMachine, error) {
+			return vm, nil
+		},
+		update: func(_ context.Context, _ *InstanceMetadata, updated compute.VirtualMachine) error {
+			if !locked {
+				t.Fatal("Expected the VM to be locked while its disks are updated")
+			}
+			vm = updated
+			return nil
+		},
+		waitForDevice: func(lun int32) (string, error) {
+			waitedFor = lun
+			return "/dev/sdd", nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	state := testState()
+	state.Put("os_disk_resource_id", "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/disks/PackerTemp-osdisk")
+	if action := testSubject.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d': %s", action, state.Get("error"))
+	}
+
+	disks := *vm.StorageProfile.DataDisks
+	if len(disks) != 3 || *disks[2].Lun != 1 || disks[2].CreateOption != compute.DiskCreateOptionTypesAttach || disks[2].Caching != compute.CachingTypesReadOnly {
+		t.Fatalf("Expected the disk to be attached with the first free LUN, but got %#v", disks)
+	}
+	if locked {
+		t.Error("Expected the VM to be unlocked once the disk is attached")
+	}
+	if waitedFor != 1 {
+		t.Errorf("Expected to wait for the device of LUN 1, but waited for %d", waitedFor)
+	}
+	if state.Get("device").(string) != "/dev/sdd" {
+		t.Errorf("Unexpected device %s", state.Get("device"))
+	}
+
+	// The disk is detached early, not again on cleanup
+	if err := state.Get("attach_cleanup").(chroot.Cleanup).CleanupFunc(state); err != nil {
+		t.Fatal(err)
+	}
+	disks = *vm.StorageProfile.DataDisks
+	if len(disks) != 2 || *disks[0].ManagedDisk.ID != "data0" || *disks[1].ManagedDisk.ID != "data2" {
+		t.Fatalf("Expected the disk to be detached, but got %#v", disks)
+	}
+
+	testSubject.update = func(context.Context, *InstanceMetadata, compute.VirtualMachine) error {
+		t.Fatal("The disk is detached already")
+		return nil
+	}
+	testSubject.Cleanup(state)
+}
+
+func TestFreeLun(t *testing.T) {
+	if lun := freeLun(compute.VirtualMachine{}); lun != 0 {
+		t.Errorf("Expected LUN 0, but got %d", lun)
+	}
+
+	vm := compute.VirtualMachine{
+		VirtualMachineProperties: &compute.VirtualMachineProperties{
+			StorageProfile: &compute.StorageProfile{
+				DataDisks: &[]compute.DataDisk{{Lun: to.Int32Ptr(1)}, {Lun: to.Int32Ptr(0)}},
+			},
+		},
+	}
+	if lun := freeLun(vm); lun != 2 {
+		t.Errorf("Expected LUN 2, but got %d", lun)
+	}
+}
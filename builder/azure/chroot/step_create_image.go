@@ -0,0 +1,151 @@
+package chroot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	packerAzureCommon "github.com/hashicorp/packer/builder/azure/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCheckExistingImage fails the build early if the managed image exists
+// already, unless it is forced.
+type StepCheckExistingImage struct {
+	client *AzureClient
+	config *Config
+	exists func(ctx context.Context, resourceGroupName string, imageName string) (bool, error)
+	say    func(message string)
+	error  func(e error)
+}
+
+func NewStepCheckExistingImage(client *AzureClient, ui packer.Ui, config *Config) *StepCheckExistingImage {
+	var step = &StepCheckExistingImage{
+		client: client,
+		config: config,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.exists = step.imageExists
+	return step
+}
+
+func (s *StepCheckExistingImage) imageExists(ctx context.Context, resourceGroupName string, imageName string) (bool, error) {
+	image, err := s.client.ImagesClient.Get(ctx, resourceGroupName, imageName, "")
+	if image.Response.Response != nil && image.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *StepCheckExistingImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	exists, err := s.exists(ctx, s.config.ManagedImageResourceGroupName, s.config.ManagedImageName)
+	if err != nil {
+		return halt(state, s.error, fmt.Errorf("Error checking for the managed image %s: %s", s.config.ManagedImageName, err))
+	}
+	if exists {
+		if !s.config.PackerForce {
+			return halt(state, s.error, fmt.Errorf(
+				"The managed image %s exists in the resource group %s, use -force to replace it",
+				s.config.ManagedImageName, s.config.ManagedImageResourceGroupName))
+		}
+		s.say(fmt.Sprintf("The managed image %s will be replaced (-force)", s.config.ManagedImageName))
+	}
+	return multistep.ActionContinue
+}
+
+func (s *StepCheckExistingImage) Cleanup(multistep.StateBag) {}
+
+// StepCreateImage creates the managed image from the provisioned disk,
+// once it is detached.
+//
+// Produces:
+//   image_id string - The resource ID of the image.
+type StepCreateImage struct {
+	client *AzureClient
+	config *Config
+	create func(ctx context.Context, resourceGroupName string, imageName string, image compute.Image, hyperVGeneration string) (string, error)
+	say    func(message string)
+	error  func(e error)
+}
+
+func NewStepCreateImage(client *AzureClient, ui packer.Ui, config *Config) *StepCreateImage {
+	var step = &StepCreateImage{
+		client: client,
+		config: config,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.create = step.createImage
+	return step
+}
+
+// createImage creates the image, of the Hyper-V generation the vendored SDK
+// doesn't know about yet.
+func (s *StepCreateImage) createImage(ctx context.Context, resourceGroupName string, imageName string, image compute.Image, hyperVGeneration string) (string, error) {
+	req, err := s.client.ImagesClient.CreateOrUpdatePreparer(ctx, resourceGroupName, imageName, image)
+	if err == nil {
+		err = packerAzureCommon.SetImageHyperVGeneration(req, hyperVGeneration)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	f, err := s.client.ImagesClient.CreateOrUpdateSender(req)
+	if err == nil {
+		err = f.WaitForCompletion(ctx, s.client.ImagesClient.Client)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	created, err := f.Result(s.client.ImagesClient)
+	if err != nil {
+		return "", err
+	}
+	return to.String(created.ID), nil
+}
+
+func (s *StepCreateImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	instance := state.Get("instance").(*InstanceMetadata)
+	diskID := state.Get("os_disk_resource_id").(string)
+
+	s.say(fmt.Sprintf("Creating the managed image %s in the resource group %s ...",
+		s.config.ManagedImageName, s.config.ManagedImageResourceGroupName))
+	s.say(fmt.Sprintf(" -> Hyper-V Generation: %s", s.config.ImageHyperVGeneration))
+
+	image := compute.Image{
+		Location: to.StringPtr(instance.Location),
+		Tags:     make(map[string]*string, len(s.config.AzureTags)),
+		ImageProperties: &compute.ImageProperties{
+			StorageProfile: &compute.ImageStorageProfile{
+				OsDisk: &compute.ImageOSDisk{
+					OsType:             compute.Linux,
+					OsState:            compute.Generalized,
+					ManagedDisk:        &compute.SubResource{ID: to.StringPtr(diskID)},
+					Caching:            compute.CachingTypes(s.config.OSDiskCacheType),
+					StorageAccountType: compute.StorageAccountTypes(s.config.OSDiskStorageAccountType),
+				},
+			},
+		},
+	}
+	for k, v := range s.config.AzureTags {
+		image.Tags[k] = to.StringPtr(v)
+	}
+
+	id, err := s.create(ctx, s.config.ManagedImageResourceGroupName, s.config.ManagedImageName, image, s.config.ImageHyperVGeneration)
+	if err != nil {
+		return halt(state, s.error, fmt.Errorf("Error creating the managed image: %s", err))
+	}
+	s.say(fmt.Sprintf(" -> Image ID: %s", id))
+
+	state.Put("image_id", id)
+	return multistep.ActionContinue
+}
+
+func (s *StepCreateImage) Cleanup(multistep.StateBag) {}
@@ -0,0 +1,76 @@
+package chroot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepCheckExistingImage(t *testing.T) {
+	cases := []struct {
+		exists bool
+		force  bool
+		action multistep.StepAction
+	}{
+		{false, false, multistep.ActionContinue},
+		{true, false, multistep.ActionHalt},
+		{true, true, multistep.ActionContinue},
+	}
+
+	for _, tc := range cases {
+		config := testStepConfig()
+		config.PackerForce = tc.force
+		testSubject := &StepCheckExistingImage{
+			config: config,
+			exists: func(context.Context, string, string) (bool, error) { return tc.exists, nil },
+			say:    func(message string) {},
+			error:  func(e error) {},
+		}
+
+		if action := testSubject.Run(context.Background(), testState()); action != tc.action {
+			t.Errorf("exists=%t, force=%t: expected the action %d, but got %d", tc.exists, tc.force, tc.action, action)
+		}
+	}
+}
+
+func TestStepCreateImageShouldCreateTheImageFromTheDisk(t *testing.T) {
+	var created compute.Image
+	var resourceGroup, name, generation string
+	config := testStepConfig()
+	config.AzureTags = map[string]string{"os": "ubuntu"}
+	config.ImageHyperVGeneration = "V2"
+	testSubject := &StepCreateImage{
+		config: config,
+		create: func(_ context.Context, resourceGroupName string, imageName string, image compute.Image, hyperVGeneration string) (string, error) {
+			resourceGroup, name, created, generation = resourceGroupName, imageName, image, hyperVGeneration
+			return "/subscriptions/sub/resourceGroups/images/providers/Microsoft.Compute/images/image", nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	state := testState()
+	state.Put("os_disk_resource_id", "disk")
+	if action := testSubject.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d': %s", action, state.Get("error"))
+	}
+
+	if resourceGroup != "images" || name != "image" {
+		t.Errorf("Unexpected image %s in %s", name, resourceGroup)
+	}
+	osDisk := created.StorageProfile.OsDisk
+	if *osDisk.ManagedDisk.ID != "disk" || osDisk.OsType != compute.Linux || osDisk.OsState != compute.Generalized {
+		t.Errorf("Unexpected OS disk %#v", osDisk)
+	}
+	if generation != "V2" {
+		t.Errorf("Expected the image to be of the Hyper-V generation V2, but got %q", generation)
+	}
+	if *created.Location != "westeurope" || *created.Tags["os"] != "ubuntu" {
+		t.Errorf("Unexpected location %s or tags %v", *created.Location, created.Tags)
+	}
+	if state.Get("image_id").(string) != "/subscriptions/sub/resourceGroups/images/providers/Microsoft.Compute/images/image" {
+		t.Errorf("Unexpected image ID %s", state.Get("image_id"))
+	}
+}
@@ -0,0 +1,167 @@
+package chroot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCreateNewDisk creates the temporary OS disk from the source, in the
+// resource group and zone of the VM so that it can be attached to it.
+//
+// Produces:
+//   os_disk_resource_id string - The resource ID of the disk.
+type StepCreateNewDisk struct {
+	client           *AzureClient
+	config           *Config
+	create           func(ctx context.Context, resourceGroupName string, diskName string, disk compute.Disk) (string, error)
+	delete           func(ctx context.Context, resourceGroupName string, diskName string) error
+	getImageVersions func(ctx context.Context, location string, image *PlatformImage) ([]compute.VirtualMachineImageResource, error)
+	say              func(message string)
+	error            func(e error)
+
+	resourceGroupName string
+}
+
+func NewStepCreateNewDisk(client *AzureClient, ui packer.Ui, config *Config) *StepCreateNewDisk {
+	var step = &StepCreateNewDisk{
+		client: client,
+		config: config,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.create = step.createDisk
+	step.delete = step.deleteDisk
+	step.getImageVersions = step.listImageVersions
+	return step
+}
+
+func (s *StepCreateNewDisk) createDisk(ctx context.Context, resourceGroupName string, diskName string, disk compute.Disk) (string, error) {
+	f, err := s.client.DisksClient.CreateOrUpdate(ctx, resourceGroupName, diskName, disk)
+	if err == nil {
+		err = f.WaitForCompletion(ctx, s.client.DisksClient.Client)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	created, err := f.Result(s.client.DisksClient)
+	if err != nil {
+		return "", err
+	}
+	return to.String(created.ID), nil
+}
+
+func (s *StepCreateNewDisk) deleteDisk(ctx context.Context, resourceGroupName string, diskName string) error {
+	f, err := s.client.DisksClient.Delete(ctx, resourceGroupName, diskName)
+	if err == nil {
+		err = f.WaitForCompletion(ctx, s.client.DisksClient.Client)
+	}
+	return err
+}
+
+func (s *StepCreateNewDisk) listImageVersions(ctx context.Context, location string, image *PlatformImage) ([]compute.VirtualMachineImageResource, error) {
+	result, err := s.client.VirtualMachineImagesClient.List(ctx, location, image.Publisher, image.Offer, image.Sku, "", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if result.Value == nil {
+		return nil, nil
+	}
+	return *result.Value, nil
+}
+
+// creationData tells Azure to copy the source disk, or to create the disk
+// from the version of the Marketplace image.
+func (s *StepCreateNewDisk) creationData(ctx context.Context, instance *InstanceMetadata) (*compute.CreationData, error) {
+	if isManagedDiskID(s.config.Source) {
+		return &compute.CreationData{
+			CreateOption:     compute.Copy,
+			SourceResourceID: to.StringPtr(s.config.Source),
+		}, nil
+	}
+
+	image, err := ParsePlatformImage(s.config.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	imageID := fmt.Sprintf(
+		"/Subscriptions/%s/Providers/Microsoft.Compute/Locations/%s/Publishers/%s/ArtifactTypes/VMImage/Offers/%s/Skus/%s/Versions/%s",
+		instance.SubscriptionID, instance.Location, image.Publisher, image.Offer, image.Sku, image.Version)
+	if image.Version == "latest" {
+		versions, err := s.getImageVersions(ctx, instance.Location, image)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing the versions of %s: %s", image, err)
+		}
+		latest, err := latestImageVersion(versions)
+		if err != nil {
+			return nil, fmt.Errorf("Error finding the latest version of %s: %s", image, err)
+		}
+		s.say(fmt.Sprintf(" -> Latest version: %s", to.String(latest.Name)))
+		imageID = to.String(latest.ID)
+	}
+
+	return &compute.CreationData{
+		CreateOption:   compute.FromImage,
+		ImageReference: &compute.ImageDiskReference{ID: to.StringPtr(imageID)},
+	}, nil
+}
+
+func (s *StepCreateNewDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	instance := state.Get("instance").(*InstanceMetadata)
+
+	s.say(fmt.Sprintf("Creating the temporary OS disk %s from %s ...", s.config.TemporaryOSDiskName, s.config.Source))
+
+	creationData, err := s.creationData(ctx, instance)
+	if err != nil {
+		return halt(state, s.error, err)
+	}
+
+	disk := compute.Disk{
+		Location: to.StringPtr(instance.Location),
+		Sku: &compute.DiskSku{
+			Name: compute.StorageAccountTypes(s.config.OSDiskStorageAccountType),
+		},
+		DiskProperties: &compute.DiskProperties{
+			OsType:       compute.Linux,
+			CreationData: creationData,
+		},
+	}
+	if s.config.OSDiskSizeGB > 0 {
+		disk.DiskProperties.DiskSizeGB = to.Int32Ptr(s.config.OSDiskSizeGB)
+	}
+	if instance.Zone != "" {
+		disk.Zones = &[]string{instance.Zone}
+	}
+
+	id, err := s.create(ctx, instance.ResourceGroupName, s.config.TemporaryOSDiskName, disk)
+	if err != nil {
+		return halt(state, s.error, fmt.Errorf("Error creating the temporary OS disk: %s", err))
+	}
+	s.resourceGroupName = instance.ResourceGroupName
+	s.say(fmt.Sprintf(" -> Disk ID: %s", id))
+
+	state.Put("os_disk_resource_id", id)
+	return multistep.ActionContinue
+}
+
+func (s *StepCreateNewDisk) Cleanup(state multistep.StateBag) {
+	if s.resourceGroupName == "" {
+		return
+	}
+	if s.config.OSDiskSkipCleanup {
+		s.say(fmt.Sprintf("Keeping the temporary OS disk %s ...", s.config.TemporaryOSDiskName))
+		return
+	}
+
+	s.say(fmt.Sprintf("Deleting the temporary OS disk %s ...", s.config.TemporaryOSDiskName))
+	if err := s.delete(context.TODO(), s.resourceGroupName, s.config.TemporaryOSDiskName); err != nil {
+		s.error(fmt.Errorf("Error deleting the temporary OS disk %s, delete it manually: %s", s.config.TemporaryOSDiskName, err))
+	}
+}
@@ -0,0 +1,121 @@
+package chroot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepCreateNewDiskShouldCreateFromTheLatestImageVersion(t *testing.T) {
+	var created compute.Disk
+	var deleted string
+	config := testStepConfig()
+	config.OSDiskSizeGB = 64
+	testSubject := &StepCreateNewDisk{
+		config: config,
+		create: func(_ context.Context, resourceGroupName string, diskName string, disk compute.Disk) (string, error) {
+			created = disk
+			return fmt.Sprintf("/subscriptions/sub/resourceGroups/%s/providers/Microsoft.Compute/disks/%s", resourceGroupName, diskName), nil
+		},
+		delete: func(_ context.Context, resourceGroupName string, diskName string) error {
+			deleted = diskName
+			return nil
+		},
+		getImageVersions: func(_ context.Context, location string, image *PlatformImage) ([]compute.VirtualMachineImageResource, error) {
+			return []compute.VirtualMachineImageResource{
+				{Name: to.StringPtr("18.04.1"), ID: to.StringPtr("/version/18.04.1")},
+				{Name: to.StringPtr("18.04.2"), ID: to.StringPtr("/version/18.04.2")},
+			}, nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	state := testState()
+	if action := testSubject.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d': %s", action, state.Get("error"))
+	}
+
+	if id := state.Get("os_disk_resource_id").(string); id != "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/disks/PackerTemp-osdisk" {
+		t.Errorf("Unexpected disk ID %s", id)
+	}
+	if created.CreationData.CreateOption != compute.FromImage || *created.CreationData.ImageReference.ID != "/version/18.04.2" {
+		t.Errorf("Expected the disk to be created from the latest version, but got %#v", created.CreationData)
+	}
+	if *created.Location != "westeurope" || (*created.Zones)[0] != "2" {
+		t.Errorf("Expected the disk in the location and zone of the VM, but got %s and %v", *created.Location, *created.Zones)
+	}
+	if *created.DiskSizeGB != 64 || created.Sku.Name != compute.StorageAccountTypesPremiumLRS {
+		t.Errorf("Unexpected size %d or SKU %s", *created.DiskSizeGB, created.Sku.Name)
+	}
+
+	testSubject.Cleanup(state)
+	if deleted != "PackerTemp-osdisk" {
+		t.Errorf("Expected the disk to be deleted, but got %q", deleted)
+	}
+}
+
+func TestStepCreateNewDiskShouldCopyAManagedDisk(t *testing.T) {
+	var created compute.Disk
+	config := testStepConfig()
+	config.Source = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/disks/source"
+	config.OSDiskSkipCleanup = true
+	testSubject := &StepCreateNewDisk{
+		config: config,
+		create: func(_ context.Context, resourceGroupName string, diskName string, disk compute.Disk) (string, error) {
+			created = disk
+			return "id", nil
+		},
+		delete: func(_ context.Context, resourceGroupName string, diskName string) error {
+			t.Fatal("The disk should be kept")
+			return nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	state := testState()
+	if action := testSubject.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d': %s", action, state.Get("error"))
+	}
+	if created.CreationData.CreateOption != compute.Copy || *created.CreationData.SourceResourceID != config.Source {
+		t.Errorf("Expected the disk to be copied, but got %#v", created.CreationData)
+	}
+	if created.DiskSizeGB != nil {
+		t.Errorf("Expected the size of the source, but got %d", *created.DiskSizeGB)
+	}
+
+	testSubject.Cleanup(state)
+}
+
+func TestStepCreateNewDiskShouldHaltOnError(t *testing.T) {
+	testSubject := &StepCreateNewDisk{
+		config: testStepConfig(),
+		create: func(context.Context, string, string, compute.Disk) (string, error) {
+			return "", fmt.Errorf("!! Unit Test FAIL !!")
+		},
+		delete: func(context.Context, string, string) error {
+			t.Fatal("No disk should be deleted")
+			return nil
+		},
+		getImageVersions: func(context.Context, string, *PlatformImage) ([]compute.VirtualMachineImageResource, error) {
+			return []compute.VirtualMachineImageResource{{Name: to.StringPtr("1"), ID: to.StringPtr("1")}}, nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	state := testState()
+	if action := testSubject.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("Expected the step to return 'ActionHalt', but got '%d'.", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("Expected the step to set stateBag['error'], but it was not.")
+	}
+
+	testSubject.Cleanup(state)
+}
@@ -0,0 +1,96 @@
+package chroot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type mountPathData struct {
+	Device string
+}
+
+// StepMountDevice mounts the partition of the attached disk.
+//
+// Produces:
+//   mount_path string - The location where the disk was mounted.
+//   mount_device_cleanup Cleanup - To unmount the disk early.
+type StepMountDevice struct {
+	mountPath string
+}
+
+func (s *StepMountDevice) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	device := state.Get("device").(string)
+	wrappedCommand := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	sayError := func(e error) { ui.Error(e.Error()) }
+
+	ctx := config.ctx
+	ctx.Data = &mountPathData{Device: filepath.Base(device)}
+	mountPath, err := interpolate.Render(config.MountPath, &ctx)
+	if err == nil {
+		mountPath, err = filepath.Abs(mountPath)
+	}
+	if err != nil {
+		return halt(state, sayError, fmt.Errorf("Error preparing mount directory: %s", err))
+	}
+	log.Printf("Mount path: %s", mountPath)
+
+	if err := os.MkdirAll(mountPath, 0755); err != nil {
+		return halt(state, sayError, fmt.Errorf("Error creating mount directory: %s", err))
+	}
+
+	deviceMount := device
+	if config.MountPartition != "0" {
+		deviceMount = device + config.MountPartition
+	}
+
+	ui.Say("Mounting the root device...")
+	opts := ""
+	if len(config.MountOptions) > 0 {
+		opts = "-o " + strings.Join(config.MountOptions, " -o ")
+	}
+	if err := chroot.RunWrapped(wrappedCommand, fmt.Sprintf("mount %s %s %s", opts, deviceMount, mountPath)); err != nil {
+		return halt(state, sayError, fmt.Errorf("Error mounting root volume: %s", err))
+	}
+	s.mountPath = mountPath
+
+	state.Put("mount_path", mountPath)
+	state.Put("mount_device_cleanup", s)
+	return multistep.ActionContinue
+}
+
+func (s *StepMountDevice) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	if err := s.CleanupFunc(state); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+// CleanupFunc unmounts the disk. It does nothing if the disk isn't mounted
+// anymore, so it is safe to call more than once.
+func (s *StepMountDevice) CleanupFunc(state multistep.StateBag) error {
+	if s.mountPath == "" {
+		return nil
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	wrappedCommand := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	ui.Say("Unmounting the root device...")
+	if err := chroot.RunWrapped(wrappedCommand, fmt.Sprintf("umount %s", s.mountPath)); err != nil {
+		return fmt.Errorf("Error unmounting root device: %s", err)
+	}
+
+	s.mountPath = ""
+	return nil
+}
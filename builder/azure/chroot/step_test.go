@@ -0,0 +1,29 @@
+package chroot
+
+import (
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func testState() multistep.StateBag {
+	state := new(multistep.BasicStateBag)
+	state.Put("instance", &InstanceMetadata{
+		SubscriptionID:    "sub",
+		ResourceGroupName: "rg",
+		Name:              "builder",
+		Location:          "westeurope",
+		Zone:              "2",
+	})
+	return state
+}
+
+func testStepConfig() *Config {
+	return &Config{
+		Source:                        "Canonical:UbuntuServer:18.04-LTS:latest",
+		TemporaryOSDiskName:           "PackerTemp-osdisk",
+		OSDiskStorageAccountType:      "Premium_LRS",
+		OSDiskCacheType:               "ReadOnly",
+		ManagedImageName:              "image",
+		ManagedImageResourceGroupName: "images",
+		ImageHyperVGeneration:         "V1",
+	}
+}
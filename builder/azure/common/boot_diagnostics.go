@@ -0,0 +1,80 @@
+// NOTE: the managed boot diagnostics of virtual machines do not yet exist in
+// the vendored SDK, but once they do this code should be removed.
+
+package common
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+const (
+	AzureBootDiagnosticsApiVersion = "2020-06-01"
+)
+
+type BootDiagnosticsClient struct {
+	autorest.Client
+	BaseURI        string
+	SubscriptionID string
+}
+
+func NewBootDiagnosticsClientWithBaseURI(baseURI, subscriptionID string) BootDiagnosticsClient {
+	return BootDiagnosticsClient{
+		Client:         autorest.NewClientWithUserAgent(""),
+		BaseURI:        baseURI,
+		SubscriptionID: subscriptionID,
+	}
+}
+
+// BootDiagnosticsData are the SAS URIs of the screenshot and the serial log
+// of a virtual machine.
+type BootDiagnosticsData struct {
+	ConsoleScreenshotBlobURI *string `json:"consoleScreenshotBlobUri,omitempty"`
+	SerialConsoleLogBlobURI  *string `json:"serialConsoleLogBlobUri,omitempty"`
+}
+
+// RetrieveBootDiagnosticsData retrieves the SAS URIs of the boot
+// diagnostics of a virtual machine, which expire after an hour.
+func (client *BootDiagnosticsClient) RetrieveBootDiagnosticsData(ctx context.Context, resourceGroupName, vmName string) (result BootDiagnosticsData, err error) {
+	pathParameters := map[string]interface{}{
+		"resourceGroupName": autorest.Encode("path", resourceGroupName),
+		"subscriptionId":    autorest.Encode("path", client.SubscriptionID),
+		"vmName":            autorest.Encode("path", vmName),
+	}
+
+	queryParameters := map[string]interface{}{
+		"api-version":                   AzureBootDiagnosticsApiVersion,
+		"sasUriExpirationTimeInMinutes": 60,
+	}
+
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx),
+		autorest.AsPost(),
+		autorest.WithBaseURL(client.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Compute/virtualMachines/{vmName}/retrieveBootDiagnosticsData", pathParameters),
+		autorest.WithQueryParameters(queryParameters))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.VirtualMachinesClient", "RetrieveBootDiagnosticsData", nil, "Failure preparing request")
+		return
+	}
+
+	resp, err := autorest.SendWithSender(client, req,
+		azure.DoRetryWithRegistration(client.Client))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.VirtualMachinesClient", "RetrieveBootDiagnosticsData", resp, "Failure sending request")
+		return
+	}
+
+	err = autorest.Respond(
+		resp,
+		client.ByInspecting(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result),
+		autorest.ByClosing())
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.VirtualMachinesClient", "RetrieveBootDiagnosticsData", resp, "Failure responding to request")
+	}
+	return
+}
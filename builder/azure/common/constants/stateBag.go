@@ -18,6 +18,7 @@ const (
 	ArmKeyVaultDeploymentName          string = "arm.KeyVaultDeploymentName"
 	ArmDeploymentName                  string = "arm.DeploymentName"
 	ArmNicName                         string = "arm.NicName"
+	ArmNsgRuleName                     string = "arm.NsgRuleName"
 	ArmKeyVaultName                    string = "arm.KeyVaultName"
 	ArmLocation                        string = "arm.Location"
 	ArmOSDiskVhd                       string = "arm.OSDiskVhd"
@@ -36,4 +37,11 @@ const (
 	ArmManagedImageLocation          string = "arm.ManagedImageLocation"
 	ArmManagedImageName              string = "arm.ManagedImageName"
 	ArmAsyncResourceGroupDelete      string = "arm.AsyncResourceGroupDelete"
+	ArmSpotVMEvicted                 string = "arm.SpotVMEvicted"
+
+	ArmManagedImageHyperVGeneration            string = "arm.ManagedImageHyperVGeneration"
+	ArmManagedImageSkipCapture                 string = "arm.ManagedImageSkipCapture"
+	ArmManagedImageDiskEncryptionSetID         string = "arm.ManagedImageDiskEncryptionSetID"
+	ArmManagedImageSharedImageGalleryVersion   string = "arm.ManagedImageSharedImageGalleryVersion"
+	ArmManagedImageSharedImageGalleryVersionID string = "arm.ManagedImageSharedImageGalleryVersionID"
 )
@@ -61,7 +61,7 @@ func (s *walker) formatValue(name, value string) string {
 
 func (s *walker) isMaskable(name string) bool {
 	up := strings.ToUpper(name)
-	return strings.Contains(up, "SECRET") || strings.Contains(up, "PASSWORD")
+	return strings.Contains(up, "SECRET") || strings.Contains(up, "PASSWORD") || strings.Contains(up, "TOKEN")
 }
 
 func DumpConfig(config interface{}, say func(string)) {
@@ -59,6 +59,7 @@ func TestDumpShouldMaskSensitiveFieldValues(t *testing.T) {
 		MySecretValue   string
 		MyPassword      string
 		MyPasswordValue string
+		MyToken         string
 	}
 
 	data := &S{
@@ -67,13 +68,14 @@ func TestDumpShouldMaskSensitiveFieldValues(t *testing.T) {
 		MySecretValue:   "s3cr3t-value",
 		MyPassword:      "p@ssw0rd",
 		MyPasswordValue: "p@ssw0rd-value",
+		MyToken:         "t0k3n",
 	}
 
-	dumps := make([]string, 0, 5)
+	dumps := make([]string, 0, 6)
 	DumpConfig(data, func(s string) { dumps = append(dumps, s) })
 
-	if len(dumps) != 5 {
-		t.Fatalf("Expected len(dumps) to be 5, but got %d", len(dumps))
+	if len(dumps) != 6 {
+		t.Fatalf("Expected len(dumps) to be 6, but got %d", len(dumps))
 
 	}
 	if dumps[0] != "MyString=my-string" {
@@ -91,6 +93,9 @@ func TestDumpShouldMaskSensitiveFieldValues(t *testing.T) {
 	if dumps[4] != "MyPasswordValue=**************" {
 		t.Errorf("Expected dumps[4] to be 'MyPasswordValue=**************' but got %s", dumps[4])
 	}
+	if dumps[5] != "MyToken=*****" {
+		t.Errorf("Expected dumps[5] to be 'MyToken=*****' but got %s", dumps[5])
+	}
 }
 
 func TestDumpConfigShouldDumpTopLevelValuesOnly(t *testing.T) {
@@ -0,0 +1,344 @@
+// NOTE: shared image gallery APIs do not yet exist in the vendored SDK, but
+// once they do this code should be removed.
+
+package common
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+const (
+	// The encryption of image versions with disk encryption sets came with
+	// this API version.
+	AzureGalleryApiVersion = "2019-07-01"
+
+	// The features of image definitions, such as their security type, and
+	// the image versions published from a VM came with a later API version.
+	AzureGalleryImageApiVersion = "2021-10-01"
+)
+
+type GalleryImageVersionsClient struct {
+	autorest.Client
+	BaseURI        string
+	SubscriptionID string
+}
+
+func NewGalleryImageVersionsClientWithBaseURI(baseURI, subscriptionID string) GalleryImageVersionsClient {
+	return GalleryImageVersionsClient{
+		Client:         autorest.NewClientWithUserAgent(""),
+		BaseURI:        baseURI,
+		SubscriptionID: subscriptionID,
+	}
+}
+
+type GalleryImagesClient struct {
+	autorest.Client
+	BaseURI        string
+	SubscriptionID string
+}
+
+func NewGalleryImagesClientWithBaseURI(baseURI, subscriptionID string) GalleryImagesClient {
+	return GalleryImagesClient{
+		Client:         autorest.NewClientWithUserAgent(""),
+		BaseURI:        baseURI,
+		SubscriptionID: subscriptionID,
+	}
+}
+
+type GalleryImage struct {
+	ID         *string                 `json:"id,omitempty"`
+	Name       *string                 `json:"name,omitempty"`
+	Location   *string                 `json:"location,omitempty"`
+	Properties *GalleryImageProperties `json:"properties,omitempty"`
+}
+
+type GalleryImageProperties struct {
+	OsType           string                    `json:"osType,omitempty"`
+	HyperVGeneration string                    `json:"hyperVGeneration,omitempty"`
+	Features         *[]GalleryImageFeature    `json:"features,omitempty"`
+	PurchasePlan     *GalleryImagePurchasePlan `json:"purchasePlan,omitempty"`
+}
+
+// GalleryImagePurchasePlan is the Marketplace plan of the versions of an
+// image definition, which the VMs deployed from them must specify.
+type GalleryImagePurchasePlan struct {
+	Name      *string `json:"name,omitempty"`
+	Publisher *string `json:"publisher,omitempty"`
+	Product   *string `json:"product,omitempty"`
+}
+
+type GalleryImageFeature struct {
+	Name  *string `json:"name,omitempty"`
+	Value *string `json:"value,omitempty"`
+}
+
+type GalleryImageVersion struct {
+	ID         *string                        `json:"id,omitempty"`
+	Name       *string                        `json:"name,omitempty"`
+	Location   *string                        `json:"location,omitempty"`
+	Tags       map[string]*string             `json:"tags,omitempty"`
+	Properties *GalleryImageVersionProperties `json:"properties,omitempty"`
+}
+
+type GalleryImageVersionProperties struct {
+	PublishingProfile *GalleryImageVersionPublishingProfile `json:"publishingProfile,omitempty"`
+	StorageProfile    *GalleryImageVersionStorageProfile    `json:"storageProfile,omitempty"`
+	ProvisioningState *string                               `json:"provisioningState,omitempty"`
+}
+
+type GalleryImageVersionPublishingProfile struct {
+	Source             *GalleryArtifactSource `json:"source,omitempty"`
+	TargetRegions      *[]GalleryTargetRegion `json:"targetRegions,omitempty"`
+	ReplicaCount       *int32                 `json:"replicaCount,omitempty"`
+	ExcludeFromLatest  *bool                  `json:"excludeFromLatest,omitempty"`
+	StorageAccountType string                 `json:"storageAccountType,omitempty"`
+}
+
+// GalleryImageVersionStorageProfile is the source of an image version
+// published from a VM, rather than from a managed image.
+type GalleryImageVersionStorageProfile struct {
+	Source *GalleryArtifactVersionSource `json:"source,omitempty"`
+}
+
+type GalleryArtifactVersionSource struct {
+	ID *string `json:"id,omitempty"`
+}
+
+type GalleryArtifactSource struct {
+	ManagedImage *ManagedArtifact `json:"managedImage,omitempty"`
+}
+
+type ManagedArtifact struct {
+	ID *string `json:"id,omitempty"`
+}
+
+type GalleryTargetRegion struct {
+	Name                 *string                  `json:"name,omitempty"`
+	RegionalReplicaCount *int32                   `json:"regionalReplicaCount,omitempty"`
+	StorageAccountType   string                   `json:"storageAccountType,omitempty"`
+	Encryption           *GalleryEncryptionImages `json:"encryption,omitempty"`
+}
+
+// GalleryEncryptionImages are the disk encryption sets the disk images of
+// the replicas of a region are encrypted with. A disk encryption set can
+// only encrypt the replicas of its own region.
+type GalleryEncryptionImages struct {
+	OsDiskImage    *GalleryDiskImageEncryption       `json:"osDiskImage,omitempty"`
+	DataDiskImages *[]GalleryDataDiskImageEncryption `json:"dataDiskImages,omitempty"`
+}
+
+type GalleryDiskImageEncryption struct {
+	DiskEncryptionSetID *string `json:"diskEncryptionSetId,omitempty"`
+}
+
+type GalleryDataDiskImageEncryption struct {
+	Lun                 *int32  `json:"lun"`
+	DiskEncryptionSetID *string `json:"diskEncryptionSetId,omitempty"`
+}
+
+type galleryImageVersionList struct {
+	Value    []GalleryImageVersion `json:"value"`
+	NextLink *string               `json:"nextLink,omitempty"`
+}
+
+// ListByGalleryImage lists the versions of an image definition of a gallery.
+//
+// resourceGroupName is the name of the resource group of the gallery. galleryName is the name of the gallery.
+// galleryImageName is the name of the image definition the versions are listed of.
+func (client *GalleryImageVersionsClient) ListByGalleryImage(ctx context.Context, resourceGroupName, galleryName, galleryImageName string) ([]GalleryImageVersion, error) {
+	pathParameters := map[string]interface{}{
+		"galleryImageName":  autorest.Encode("path", galleryImageName),
+		"galleryName":       autorest.Encode("path", galleryName),
+		"resourceGroupName": autorest.Encode("path", resourceGroupName),
+		"subscriptionId":    autorest.Encode("path", client.SubscriptionID),
+	}
+
+	queryParameters := map[string]interface{}{
+		"api-version": AzureGalleryApiVersion,
+	}
+
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx),
+		autorest.AsGet(),
+		autorest.WithBaseURL(client.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Compute/galleries/{galleryName}/images/{galleryImageName}/versions", pathParameters),
+		autorest.WithQueryParameters(queryParameters))
+	if err != nil {
+		return nil, autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "ListByGalleryImage", nil, "Failure preparing request")
+	}
+
+	var versions []GalleryImageVersion
+	for {
+		resp, err := autorest.SendWithSender(client, req,
+			azure.DoRetryWithRegistration(client.Client))
+		if err != nil {
+			return nil, autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "ListByGalleryImage", resp, "Failure sending request")
+		}
+
+		var page galleryImageVersionList
+		err = autorest.Respond(
+			resp,
+			client.ByInspecting(),
+			azure.WithErrorUnlessStatusCode(http.StatusOK),
+			autorest.ByUnmarshallingJSON(&page),
+			autorest.ByClosing())
+		if err != nil {
+			return nil, autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "ListByGalleryImage", resp, "Failure responding to request")
+		}
+		versions = append(versions, page.Value...)
+
+		if page.NextLink == nil || *page.NextLink == "" {
+			return versions, nil
+		}
+		req, err = autorest.Prepare((&http.Request{}).WithContext(ctx),
+			autorest.AsGet(),
+			autorest.WithBaseURL(*page.NextLink))
+		if err != nil {
+			return nil, autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "ListByGalleryImage", nil, "Failure preparing next results request")
+		}
+	}
+}
+
+// CreateOrUpdate creates a version of an image definition of a gallery, and
+// waits for it to be replicated to its target regions.
+//
+// resourceGroupName is the name of the resource group of the gallery. galleryName is the name of the gallery.
+// galleryImageName is the name of the image definition. galleryImageVersionName is the semantic version of the
+// image version to create.
+func (client *GalleryImageVersionsClient) CreateOrUpdate(ctx context.Context, resourceGroupName, galleryName, galleryImageName, galleryImageVersionName string, galleryImageVersion GalleryImageVersion) (result GalleryImageVersion, err error) {
+	pathParameters := map[string]interface{}{
+		"galleryImageName":        autorest.Encode("path", galleryImageName),
+		"galleryImageVersionName": autorest.Encode("path", galleryImageVersionName),
+		"galleryName":             autorest.Encode("path", galleryName),
+		"resourceGroupName":       autorest.Encode("path", resourceGroupName),
+		"subscriptionId":          autorest.Encode("path", client.SubscriptionID),
+	}
+
+	queryParameters := map[string]interface{}{
+		"api-version": AzureGalleryApiVersion,
+	}
+	if galleryImageVersion.Properties != nil && galleryImageVersion.Properties.StorageProfile != nil {
+		queryParameters["api-version"] = AzureGalleryImageApiVersion
+	}
+
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx),
+		autorest.AsContentType("application/json; charset=utf-8"),
+		autorest.AsPut(),
+		autorest.WithBaseURL(client.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Compute/galleries/{galleryName}/images/{galleryImageName}/versions/{galleryImageVersionName}", pathParameters),
+		autorest.WithJSON(galleryImageVersion),
+		autorest.WithQueryParameters(queryParameters))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "CreateOrUpdate", nil, "Failure preparing request")
+		return
+	}
+
+	resp, err := autorest.SendWithSender(client, req,
+		azure.DoRetryWithRegistration(client.Client),
+		azure.DoPollForAsynchronous(client.PollingDelay))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "CreateOrUpdate", resp, "Failure sending request")
+		return
+	}
+
+	err = autorest.Respond(
+		resp,
+		client.ByInspecting(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK, http.StatusCreated),
+		autorest.ByClosing())
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "CreateOrUpdate", resp, "Failure responding to request")
+		return
+	}
+
+	// The last response is the one of the operation, not of the version
+	return client.Get(ctx, resourceGroupName, galleryName, galleryImageName, galleryImageVersionName)
+}
+
+// Get retrieves a version of an image definition of a gallery.
+func (client *GalleryImageVersionsClient) Get(ctx context.Context, resourceGroupName, galleryName, galleryImageName, galleryImageVersionName string) (result GalleryImageVersion, err error) {
+	pathParameters := map[string]interface{}{
+		"galleryImageName":        autorest.Encode("path", galleryImageName),
+		"galleryImageVersionName": autorest.Encode("path", galleryImageVersionName),
+		"galleryName":             autorest.Encode("path", galleryName),
+		"resourceGroupName":       autorest.Encode("path", resourceGroupName),
+		"subscriptionId":          autorest.Encode("path", client.SubscriptionID),
+	}
+
+	queryParameters := map[string]interface{}{
+		"api-version": AzureGalleryApiVersion,
+	}
+
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx),
+		autorest.AsGet(),
+		autorest.WithBaseURL(client.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Compute/galleries/{galleryName}/images/{galleryImageName}/versions/{galleryImageVersionName}", pathParameters),
+		autorest.WithQueryParameters(queryParameters))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "Get", nil, "Failure preparing request")
+		return
+	}
+
+	resp, err := autorest.SendWithSender(client, req,
+		azure.DoRetryWithRegistration(client.Client))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "Get", resp, "Failure sending request")
+		return
+	}
+
+	err = autorest.Respond(
+		resp,
+		client.ByInspecting(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result),
+		autorest.ByClosing())
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.GalleryImageVersionsClient", "Get", resp, "Failure responding to request")
+	}
+	return
+}
+
+// Get retrieves an image definition of a gallery.
+func (client *GalleryImagesClient) Get(ctx context.Context, resourceGroupName, galleryName, galleryImageName string) (result GalleryImage, err error) {
+	pathParameters := map[string]interface{}{
+		"galleryImageName":  autorest.Encode("path", galleryImageName),
+		"galleryName":       autorest.Encode("path", galleryName),
+		"resourceGroupName": autorest.Encode("path", resourceGroupName),
+		"subscriptionId":    autorest.Encode("path", client.SubscriptionID),
+	}
+
+	queryParameters := map[string]interface{}{
+		"api-version": AzureGalleryImageApiVersion,
+	}
+
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx),
+		autorest.AsGet(),
+		autorest.WithBaseURL(client.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Compute/galleries/{galleryName}/images/{galleryImageName}", pathParameters),
+		autorest.WithQueryParameters(queryParameters))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.GalleryImagesClient", "Get", nil, "Failure preparing request")
+		return
+	}
+
+	resp, err := autorest.SendWithSender(client, req,
+		azure.DoRetryWithRegistration(client.Client))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.GalleryImagesClient", "Get", resp, "Failure sending request")
+		return
+	}
+
+	err = autorest.Respond(
+		resp,
+		client.ByInspecting(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result),
+		autorest.ByClosing())
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "compute.GalleryImagesClient", "Get", resp, "Failure responding to request")
+	}
+	return
+}
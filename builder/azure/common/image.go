@@ -0,0 +1,90 @@
+// NOTE: the Hyper-V generation and the disk encryption set of managed images do not yet exist in the
+// vendored SDK, but once it does this code should be removed.
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	AzureImageApiVersion = "2019-03-01"
+
+	// The disk encryption sets of managed images came with a later API
+	// version.
+	AzureImageDiskEncryptionApiVersion = "2019-07-01"
+)
+
+// SetImageHyperVGeneration sets the Hyper-V generation of the managed image
+// a request prepared by ImagesClient.CreateOrUpdatePreparer creates, and
+// the first API version knowing about it.
+func SetImageHyperVGeneration(req *http.Request, generation string) error {
+	return setImageProperties(req, AzureImageApiVersion, func(properties map[string]interface{}) error {
+		properties["hyperVGeneration"] = generation
+		return nil
+	})
+}
+
+// SetImageDiskEncryptionSet encrypts the OS disk and the data disks of the
+// managed image a request prepared by ImagesClient.CreateOrUpdatePreparer
+// creates with the disk encryption set. The image must have a storage
+// profile with an OS disk.
+func SetImageDiskEncryptionSet(req *http.Request, diskEncryptionSetID string) error {
+	return setImageProperties(req, AzureImageDiskEncryptionApiVersion, func(properties map[string]interface{}) error {
+		storageProfile, _ := properties["storageProfile"].(map[string]interface{})
+		osDisk, ok := storageProfile["osDisk"].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("the image has no OS disk to encrypt")
+		}
+		osDisk["diskEncryptionSet"] = map[string]interface{}{"id": diskEncryptionSetID}
+
+		dataDisks, _ := storageProfile["dataDisks"].([]interface{})
+		for _, d := range dataDisks {
+			if dataDisk, ok := d.(map[string]interface{}); ok {
+				dataDisk["diskEncryptionSet"] = map[string]interface{}{"id": diskEncryptionSetID}
+			}
+		}
+		return nil
+	})
+}
+
+// setImageProperties rewrites the properties of the image of the request,
+// and makes it use at least the API version.
+func setImageProperties(req *http.Request, apiVersion string, set func(properties map[string]interface{}) error) error {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+
+	var image map[string]interface{}
+	if err := json.Unmarshal(body, &image); err != nil {
+		return err
+	}
+	properties, ok := image["properties"].(map[string]interface{})
+	if !ok {
+		properties = make(map[string]interface{})
+		image["properties"] = properties
+	}
+	if err := set(properties); err != nil {
+		return err
+	}
+
+	body, err = json.Marshal(image)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	query := req.URL.Query()
+	if query.Get("api-version") < apiVersion {
+		query.Set("api-version", apiVersion)
+	}
+	req.URL.RawQuery = query.Encode()
+	return nil
+}
@@ -0,0 +1,116 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func TestSetImageHyperVGeneration(t *testing.T) {
+	client := compute.NewImagesClientWithBaseURI("https://management.azure.com", "subscription")
+	req, err := client.CreateOrUpdatePreparer(context.TODO(), "group", "image", compute.Image{
+		Location: to.StringPtr("westus2"),
+		ImageProperties: &compute.ImageProperties{
+			SourceVirtualMachine: &compute.SubResource{ID: to.StringPtr("vm")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetImageHyperVGeneration(req, "V2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := req.URL.Query().Get("api-version"); v != AzureImageApiVersion {
+		t.Errorf("Expected the api-version to be %q, but got %q", AzureImageApiVersion, v)
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	if int64(len(body)) != req.ContentLength {
+		t.Errorf("Expected the content length to be %d, but got %d", len(body), req.ContentLength)
+	}
+
+	var image struct {
+		Location   string
+		Properties struct {
+			HyperVGeneration     string
+			SourceVirtualMachine struct{ ID string }
+		}
+	}
+	if err := json.Unmarshal(body, &image); err != nil {
+		t.Fatal(err)
+	}
+	if image.Properties.HyperVGeneration != "V2" || image.Properties.SourceVirtualMachine.ID != "vm" || image.Location != "westus2" {
+		t.Errorf("Unexpected image: %s", body)
+	}
+}
+
+func TestSetImageDiskEncryptionSet(t *testing.T) {
+	client := compute.NewImagesClientWithBaseURI("https://management.azure.com", "subscription")
+	image := compute.Image{
+		Location: to.StringPtr("westus2"),
+		ImageProperties: &compute.ImageProperties{
+			SourceVirtualMachine: &compute.SubResource{ID: to.StringPtr("vm")},
+		},
+	}
+	req, err := client.CreateOrUpdatePreparer(context.TODO(), "group", "image", image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetImageDiskEncryptionSet(req, "des"); err == nil {
+		t.Fatal("Expected an error for an image without an OS disk")
+	}
+
+	image.SourceVirtualMachine = nil
+	image.StorageProfile = &compute.ImageStorageProfile{
+		OsDisk: &compute.ImageOSDisk{OsType: compute.Linux, OsState: compute.Generalized},
+		DataDisks: &[]compute.ImageDataDisk{
+			{Lun: to.Int32Ptr(0)},
+		},
+	}
+	req, err = client.CreateOrUpdatePreparer(context.TODO(), "group", "image", image)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The later API version wins, whatever the order
+	if err := SetImageDiskEncryptionSet(req, "des"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetImageHyperVGeneration(req, "V2"); err != nil {
+		t.Fatal(err)
+	}
+	if v := req.URL.Query().Get("api-version"); v != AzureImageDiskEncryptionApiVersion {
+		t.Errorf("Expected the api-version to be %q, but got %q", AzureImageDiskEncryptionApiVersion, v)
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	var result struct {
+		Properties struct {
+			HyperVGeneration string
+			StorageProfile   struct {
+				OsDisk struct {
+					OsType            string
+					DiskEncryptionSet struct{ ID string }
+				}
+				DataDisks []struct {
+					DiskEncryptionSet struct{ ID string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	osDisk := result.Properties.StorageProfile.OsDisk
+	dataDisks := result.Properties.StorageProfile.DataDisks
+	if osDisk.DiskEncryptionSet.ID != "des" || osDisk.OsType != "Linux" || result.Properties.HyperVGeneration != "V2" ||
+		len(dataDisks) != 1 || dataDisks[0].DiskEncryptionSet.ID != "des" {
+		t.Errorf("Unexpected image: %s", body)
+	}
+}
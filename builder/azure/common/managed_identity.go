@@ -0,0 +1,75 @@
+package common
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+const (
+	// The token endpoint of the Azure Instance Metadata Service, where the
+	// VMs, the scale set agents of Azure DevOps and the AKS nodes get the
+	// tokens of their managed identities.
+	imdsTokenEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+	imdsApiVersion    = "2018-02-01"
+)
+
+// NewManagedIdentityToken returns the token of the managed identity of the
+// machine Packer runs on for the resource, the user-assigned identity with
+// the client ID or the system-assigned one if the client ID is empty.
+//
+// NOTE: The MSI support of the vendored adal posts to the endpoint of the
+// deprecated MSI VM extension. The token is requested from the Instance
+// Metadata Service instead, which only answers GET requests.
+func NewManagedIdentityToken(resource, clientID string) (*adal.ServicePrincipalToken, error) {
+	var spt *adal.ServicePrincipalToken
+	var err error
+	if clientID == "" {
+		spt, err = adal.NewServicePrincipalTokenFromMSI(imdsTokenEndpoint, resource)
+	} else {
+		spt, err = adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(imdsTokenEndpoint, resource, clientID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	spt.SetSender(&imdsSender{sender: http.DefaultClient})
+	return spt, nil
+}
+
+// imdsSender turns the token requests adal posts to the MSI endpoint into
+// the GET requests of the Instance Metadata Service.
+type imdsSender struct {
+	sender adal.Sender
+}
+
+func (s *imdsSender) Do(req *http.Request) (*http.Response, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	form, err := url.ParseQuery(string(bytes.TrimSpace(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("api-version", imdsApiVersion)
+	query.Set("resource", form.Get("resource"))
+	if clientID := form.Get("client_id"); clientID != "" {
+		query.Set("client_id", clientID)
+	}
+
+	u := *req.URL
+	u.RawQuery = query.Encode()
+	imdsReq, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	imdsReq.Header.Set("Metadata", "true")
+	return s.sender.Do(imdsReq)
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIMDSSenderShouldGetTheToken(t *testing.T) {
+	var actual *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual = r
+		w.Write([]byte(`{"access_token": "token", "expires_in": "3599", "expires_on": "1506484173", "resource": "https://management.azure.com/", "token_type": "Bearer"}`))
+	}))
+	defer server.Close()
+
+	body := "client_id=clientID&grant_type=client_credentials&resource=https%3A%2F%2Fmanagement.azure.com%2F"
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/metadata/identity/oauth2/token", strings.NewReader(body))
+
+	testSubject := &imdsSender{sender: http.DefaultClient}
+	resp, err := testSubject.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if actual.Method != http.MethodGet {
+		t.Errorf("Expected a GET request, but got %s", actual.Method)
+	}
+	if actual.Header.Get("Metadata") != "true" {
+		t.Errorf("Expected the Metadata header to be true, but got %q", actual.Header.Get("Metadata"))
+	}
+	query := actual.URL.Query()
+	if query.Get("api-version") != imdsApiVersion || query.Get("client_id") != "clientID" || query.Get("resource") != "https://management.azure.com/" {
+		t.Errorf("Unexpected query %q", actual.URL.RawQuery)
+	}
+}
@@ -0,0 +1,132 @@
+// NOTE: the marketplace ordering APIs do not yet exist in the vendored SDK,
+// but once they do this code should be removed.
+
+package common
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+const (
+	AzureMarketplaceApiVersion = "2021-01-01"
+)
+
+type MarketplaceAgreementsClient struct {
+	autorest.Client
+	BaseURI        string
+	SubscriptionID string
+}
+
+func NewMarketplaceAgreementsClientWithBaseURI(baseURI, subscriptionID string) MarketplaceAgreementsClient {
+	return MarketplaceAgreementsClient{
+		Client:         autorest.NewClientWithUserAgent(""),
+		BaseURI:        baseURI,
+		SubscriptionID: subscriptionID,
+	}
+}
+
+// MarketplaceAgreement are the terms of the plan of a Marketplace image, and
+// whether the subscription accepted them. The terms are accepted by
+// creating them again as they were read, with Accepted set: the signature
+// ties the acceptance to the terms that were read.
+type MarketplaceAgreement struct {
+	ID         *string                         `json:"id,omitempty"`
+	Name       *string                         `json:"name,omitempty"`
+	Type       *string                         `json:"type,omitempty"`
+	Properties *MarketplaceAgreementProperties `json:"properties,omitempty"`
+}
+
+type MarketplaceAgreementProperties struct {
+	Publisher            *string `json:"publisher,omitempty"`
+	Product              *string `json:"product,omitempty"`
+	Plan                 *string `json:"plan,omitempty"`
+	LicenseTextLink      *string `json:"licenseTextLink,omitempty"`
+	PrivacyPolicyLink    *string `json:"privacyPolicyLink,omitempty"`
+	MarketplaceTermsLink *string `json:"marketplaceTermsLink,omitempty"`
+	RetrieveDatetime     *string `json:"retrieveDatetime,omitempty"`
+	Signature            *string `json:"signature,omitempty"`
+	Accepted             *bool   `json:"accepted,omitempty"`
+}
+
+// Get retrieves the terms of the plan of a Marketplace image.
+//
+// publisherID is the publisher of the image. offerID is the offer of the image, the product of its plan. planID
+// is the name of the plan.
+func (client *MarketplaceAgreementsClient) Get(ctx context.Context, publisherID, offerID, planID string) (result MarketplaceAgreement, err error) {
+	req, err := client.prepare(ctx, publisherID, offerID, planID, autorest.AsGet())
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "marketplaceordering.MarketplaceAgreementsClient", "Get", nil, "Failure preparing request")
+		return
+	}
+
+	resp, err := autorest.SendWithSender(client, req,
+		azure.DoRetryWithRegistration(client.Client))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "marketplaceordering.MarketplaceAgreementsClient", "Get", resp, "Failure sending request")
+		return
+	}
+
+	err = autorest.Respond(
+		resp,
+		client.ByInspecting(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result),
+		autorest.ByClosing())
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "marketplaceordering.MarketplaceAgreementsClient", "Get", resp, "Failure responding to request")
+	}
+	return
+}
+
+// Create saves the terms of the plan of a Marketplace image, to accept them.
+func (client *MarketplaceAgreementsClient) Create(ctx context.Context, publisherID, offerID, planID string, agreement MarketplaceAgreement) (result MarketplaceAgreement, err error) {
+	req, err := client.prepare(ctx, publisherID, offerID, planID,
+		autorest.AsContentType("application/json; charset=utf-8"),
+		autorest.AsPut(),
+		autorest.WithJSON(agreement))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "marketplaceordering.MarketplaceAgreementsClient", "Create", nil, "Failure preparing request")
+		return
+	}
+
+	resp, err := autorest.SendWithSender(client, req,
+		azure.DoRetryWithRegistration(client.Client))
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "marketplaceordering.MarketplaceAgreementsClient", "Create", resp, "Failure sending request")
+		return
+	}
+
+	err = autorest.Respond(
+		resp,
+		client.ByInspecting(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result),
+		autorest.ByClosing())
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "marketplaceordering.MarketplaceAgreementsClient", "Create", resp, "Failure responding to request")
+	}
+	return
+}
+
+func (client *MarketplaceAgreementsClient) prepare(ctx context.Context, publisherID, offerID, planID string, decorators ...autorest.PrepareDecorator) (*http.Request, error) {
+	pathParameters := map[string]interface{}{
+		"offerId":        autorest.Encode("path", offerID),
+		"planId":         autorest.Encode("path", planID),
+		"publisherId":    autorest.Encode("path", publisherID),
+		"subscriptionId": autorest.Encode("path", client.SubscriptionID),
+	}
+
+	queryParameters := map[string]interface{}{
+		"api-version": AzureMarketplaceApiVersion,
+	}
+
+	decorators = append(decorators,
+		autorest.WithBaseURL(client.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/providers/Microsoft.MarketplaceOrdering/offerTypes/virtualmachine/publishers/{publisherId}/offers/{offerId}/plans/{planId}/agreements/current", pathParameters),
+		autorest.WithQueryParameters(queryParameters))
+	return autorest.Prepare((&http.Request{}).WithContext(ctx), decorators...)
+}
@@ -53,7 +53,7 @@ type OSDiskUnion struct {
 	Caching      compute.CachingTypes              `json:"caching,omitempty"`
 	CreateOption compute.DiskCreateOptionTypes     `json:"createOption,omitempty"`
 	DiskSizeGB   *int32                            `json:"diskSizeGB,omitempty"`
-	ManagedDisk  *compute.ManagedDiskParameters    `json:"managedDisk,omitempty"`
+	ManagedDisk  *ManagedDiskUnion                 `json:"managedDisk,omitempty"`
 }
 
 type DataDiskUnion struct {
@@ -65,7 +65,38 @@ type DataDiskUnion struct {
 	Caching      compute.CachingTypes           `json:"caching,omitempty"`
 	CreateOption compute.DiskCreateOptionTypes  `json:"createOption,omitempty"`
 	DiskSizeGB   *int32                         `json:"diskSizeGB,omitempty"`
-	ManagedDisk  *compute.ManagedDiskParameters `json:"managedDisk,omitempty"`
+	ManagedDisk  *ManagedDiskUnion              `json:"managedDisk,omitempty"`
+}
+
+// Union of the ManagedDiskParameters type, the security profile of the
+// OS disk of confidential VMs and the disk encryption set of the disk.
+type ManagedDiskUnion struct {
+	ID                 *string                     `json:"id,omitempty"`
+	StorageAccountType compute.StorageAccountTypes `json:"storageAccountType,omitempty"`
+	SecurityProfile    *DiskSecurityProfile        `json:"securityProfile,omitempty"`
+	DiskEncryptionSet  *DiskEncryptionSet          `json:"diskEncryptionSet,omitempty"`
+}
+
+type DiskEncryptionSet struct {
+	ID *string `json:"id,omitempty"`
+}
+
+type DiskSecurityProfile struct {
+	SecurityEncryptionType string `json:"securityEncryptionType,omitempty"`
+}
+
+type BillingProfile struct {
+	MaxPrice *float64 `json:"maxPrice,omitempty"`
+}
+
+type SecurityProfile struct {
+	SecurityType string        `json:"securityType,omitempty"`
+	UefiSettings *UefiSettings `json:"uefiSettings,omitempty"`
+}
+
+type UefiSettings struct {
+	SecureBootEnabled *bool `json:"secureBootEnabled,omitempty"`
+	VTpmEnabled       *bool `json:"vTpmEnabled,omitempty"`
 }
 
 // Union of the StorageProfile and ImageStorageProfile types.
@@ -80,15 +111,19 @@ type StorageProfileUnion struct {
 type Properties struct {
 	AccessPolicies               *[]AccessPolicies                   `json:"accessPolicies,omitempty"`
 	AddressSpace                 *network.AddressSpace               `json:"addressSpace,omitempty"`
+	BillingProfile               *BillingProfile                     `json:"billingProfile,omitempty"`
 	DiagnosticsProfile           *compute.DiagnosticsProfile         `json:"diagnosticsProfile,omitempty"`
 	DNSSettings                  *network.PublicIPAddressDNSSettings `json:"dnsSettings,omitempty"`
 	EnabledForDeployment         *string                             `json:"enabledForDeployment,omitempty"`
 	EnabledForTemplateDeployment *string                             `json:"enabledForTemplateDeployment,omitempty"`
+	EvictionPolicy               *string                             `json:"evictionPolicy,omitempty"`
 	HardwareProfile              *compute.HardwareProfile            `json:"hardwareProfile,omitempty"`
 	IPConfigurations             *[]network.IPConfiguration          `json:"ipConfigurations,omitempty"`
 	NetworkProfile               *compute.NetworkProfile             `json:"networkProfile,omitempty"`
 	OsProfile                    *compute.OSProfile                  `json:"osProfile,omitempty"`
+	Priority                     *string                             `json:"priority,omitempty"`
 	PublicIPAllocatedMethod      *network.IPAllocationMethod         `json:"publicIPAllocationMethod,omitempty"`
+	SecurityProfile              *SecurityProfile                    `json:"securityProfile,omitempty"`
 	Sku                          *Sku                                `json:"sku,omitempty"`
 	//StorageProfile3              *compute.StorageProfile             `json:"storageProfile,omitempty"`
 	StorageProfile *StorageProfileUnion `json:"storageProfile,omitempty"`
@@ -21,6 +21,30 @@ const (
 	resourceVirtualNetworks   = "Microsoft.Network/virtualNetworks"
 
 	variableSshKeyPath = "sshKeyPath"
+
+	// The security types of trusted launch and confidential VMs.
+	SecurityTypeTrustedLaunch  = "TrustedLaunch"
+	SecurityTypeConfidentialVM = "ConfidentialVM"
+
+	// securityProfileApiVersion is the first API version of virtual
+	// machines supporting both security types.
+	securityProfileApiVersion = "2021-11-01"
+
+	// The eviction policies of Spot VMs.
+	SpotEvictionPolicyDeallocate = "Deallocate"
+	SpotEvictionPolicyDelete     = "Delete"
+
+	// spotApiVersion is the first API version of virtual machines
+	// supporting Spot VMs.
+	spotApiVersion = "2019-03-01"
+
+	// diskEncryptionSetApiVersion is the first API version of virtual
+	// machines supporting disk encryption sets.
+	diskEncryptionSetApiVersion = "2019-07-01"
+
+	// managedBootDiagnosticsApiVersion is the first API version of virtual
+	// machines supporting boot diagnostics without a storage account.
+	managedBootDiagnosticsApiVersion = "2020-06-01"
 )
 
 type TemplateBuilder struct {
@@ -114,7 +138,7 @@ func (s *TemplateBuilder) SetManagedDiskUrl(managedImageId string, storageAccoun
 	profile.OsDisk.OsType = s.osType
 	profile.OsDisk.CreateOption = compute.DiskCreateOptionTypesFromImage
 	profile.OsDisk.Vhd = nil
-	profile.OsDisk.ManagedDisk = &compute.ManagedDiskParameters{
+	profile.OsDisk.ManagedDisk = &ManagedDiskUnion{
 		StorageAccountType: storageAccountType,
 	}
 
@@ -138,7 +162,7 @@ func (s *TemplateBuilder) SetManagedMarketplaceImage(location, publisher, offer,
 	profile.OsDisk.OsType = s.osType
 	profile.OsDisk.CreateOption = compute.DiskCreateOptionTypesFromImage
 	profile.OsDisk.Vhd = nil
-	profile.OsDisk.ManagedDisk = &compute.ManagedDiskParameters{
+	profile.OsDisk.ManagedDisk = &ManagedDiskUnion{
 		StorageAccountType: storageAccountType,
 	}
 
@@ -226,7 +250,10 @@ func (s *TemplateBuilder) SetAdditionalDisks(diskSizeGB []int32, isManaged bool)
 		dataDisks[i].Caching = "ReadWrite"
 		if isManaged {
 			dataDisks[i].Vhd = nil
-			dataDisks[i].ManagedDisk = profile.OsDisk.ManagedDisk
+			dataDisks[i].ManagedDisk = &ManagedDiskUnion{
+				ID:                 profile.OsDisk.ManagedDisk.ID,
+				StorageAccountType: profile.OsDisk.ManagedDisk.StorageAccountType,
+			}
 		} else {
 			dataDisks[i].Vhd = &compute.VirtualHardDisk{
 				URI: to.StringPtr(fmt.Sprintf("[concat(parameters('storageAccountBlobEndpoint'),variables('vmStorageAccountContainerName'),'/datadisk-', '%d','.vhd')]", i+1)),
@@ -238,6 +265,127 @@ func (s *TemplateBuilder) SetAdditionalDisks(diskSizeGB []int32, isManaged bool)
 	return nil
 }
 
+// SetSecurityProfile makes the VM a trusted launch or a confidential VM,
+// which needs a managed OS disk. The guest state of the OS disk of a
+// confidential VM is encrypted.
+func (s *TemplateBuilder) SetSecurityProfile(securityType string, secureBootEnabled, vTpmEnabled bool) error {
+	resource, err := s.getResourceByType(resourceVirtualMachine)
+	if err != nil {
+		return err
+	}
+
+	s.setVirtualMachineApiVersion(securityProfileApiVersion)
+
+	resource.Properties.SecurityProfile = &SecurityProfile{
+		SecurityType: securityType,
+		UefiSettings: &UefiSettings{
+			SecureBootEnabled: to.BoolPtr(secureBootEnabled),
+			VTpmEnabled:       to.BoolPtr(vTpmEnabled),
+		},
+	}
+
+	if securityType == SecurityTypeConfidentialVM {
+		disk := resource.Properties.StorageProfile.OsDisk
+		if disk.ManagedDisk == nil {
+			return fmt.Errorf("a confidential VM needs a managed OS disk")
+		}
+		disk.ManagedDisk.SecurityProfile = &DiskSecurityProfile{
+			SecurityEncryptionType: "VMGuestStateOnly",
+		}
+	}
+
+	return nil
+}
+
+// SetSpot makes the VM a Spot VM, evicted with the policy when its price
+// goes over the max price.
+func (s *TemplateBuilder) SetSpot(evictionPolicy string, maxPrice float64) error {
+	resource, err := s.getResourceByType(resourceVirtualMachine)
+	if err != nil {
+		return err
+	}
+
+	s.setVirtualMachineApiVersion(spotApiVersion)
+
+	resource.Properties.Priority = to.StringPtr("Spot")
+	resource.Properties.EvictionPolicy = to.StringPtr(evictionPolicy)
+	resource.Properties.BillingProfile = &BillingProfile{
+		MaxPrice: to.Float64Ptr(maxPrice),
+	}
+
+	return nil
+}
+
+// SetDiskEncryptionSet encrypts the managed OS disk and data disks of the
+// VM with the customer-managed keys of the disk encryption set. Set the
+// additional disks first.
+func (s *TemplateBuilder) SetDiskEncryptionSet(diskEncryptionSetID string) error {
+	resource, err := s.getResourceByType(resourceVirtualMachine)
+	if err != nil {
+		return err
+	}
+
+	profile := resource.Properties.StorageProfile
+	if profile.OsDisk.ManagedDisk == nil {
+		return fmt.Errorf("a disk encryption set needs a managed OS disk")
+	}
+
+	s.setVirtualMachineApiVersion(diskEncryptionSetApiVersion)
+
+	profile.OsDisk.ManagedDisk.DiskEncryptionSet = &DiskEncryptionSet{
+		ID: to.StringPtr(diskEncryptionSetID),
+	}
+	if profile.DataDisks != nil {
+		for i := range *profile.DataDisks {
+			if disk := (*profile.DataDisks)[i].ManagedDisk; disk != nil {
+				disk.DiskEncryptionSet = &DiskEncryptionSet{
+					ID: to.StringPtr(diskEncryptionSetID),
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetBootDiagnostics enables the boot diagnostics of the VM, stored in the
+// storage account of the build, or in a storage account managed by Azure
+// for a managed build, which has none.
+func (s *TemplateBuilder) SetBootDiagnostics(isManaged bool) error {
+	resource, err := s.getResourceByType(resourceVirtualMachine)
+	if err != nil {
+		return err
+	}
+
+	bootDiagnostics := &compute.BootDiagnostics{
+		Enabled: to.BoolPtr(true),
+	}
+	if isManaged {
+		s.setVirtualMachineApiVersion(managedBootDiagnosticsApiVersion)
+	} else {
+		bootDiagnostics.StorageURI = to.StringPtr("[parameters('storageAccountBlobEndpoint')]")
+	}
+	resource.Properties.DiagnosticsProfile = &compute.DiagnosticsProfile{
+		BootDiagnostics: bootDiagnostics,
+	}
+
+	return nil
+}
+
+// setVirtualMachineApiVersion makes the VM use at least the API version,
+// an API version of a date, newer than the variable of the template.
+func (s *TemplateBuilder) setVirtualMachineApiVersion(apiVersion string) {
+	for i, x := range *s.template.Resources {
+		if !strings.EqualFold(*x.Type, resourceVirtualMachine) {
+			continue
+		}
+		current := x.ApiVersion
+		if current == nil || strings.HasPrefix(*current, "[") || *current < apiVersion {
+			(*s.template.Resources)[i].ApiVersion = to.StringPtr(apiVersion)
+		}
+	}
+}
+
 func (s *TemplateBuilder) SetCustomData(customData string) error {
 	resource, err := s.getResourceByType(resourceVirtualMachine)
 	if err != nil {
@@ -62,6 +62,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			HTTPDir:     b.config.HTTPDir,
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
+			TLS:         b.config.HTTPTLS,
+			TLSCertFile: b.config.HTTPTLSCertFile,
+			TLSKeyFile:  b.config.HTTPTLSKeyFile,
+			Username:    b.config.HTTPUsername,
+			Password:    b.config.HTTPPassword,
+			Templates:   b.config.HTTPTemplates,
+			Ctx:         b.config.ctx,
 		},
 		&stepKeypair{
 			Debug:                b.config.PackerDebug,
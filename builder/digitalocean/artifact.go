@@ -42,6 +42,19 @@ func (a *Artifact) String() string {
 }
 
 func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "SnapshotName":
+		return a.snapshotName
+	case "SnapshotId":
+		return a.snapshotId
+	case "RegionSnapshotIds":
+		// A snapshot keeps its ID in the regions it is transferred to
+		ids := make(map[string]string, len(a.regionNames))
+		for _, region := range a.regionNames {
+			ids[region] = strconv.Itoa(a.snapshotId)
+		}
+		return ids
+	}
 	return nil
 }
 
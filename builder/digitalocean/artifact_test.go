@@ -1,6 +1,7 @@
 package digitalocean
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/packer/packer"
@@ -49,3 +50,12 @@ func TestArtifactStringWithoutMultipleRegions(t *testing.T) {
 		t.Fatalf("artifact string should match: %v", expected)
 	}
 }
+
+func TestArtifactState_RegionSnapshotIds(t *testing.T) {
+	a := &Artifact{"packer-foobar", 42, []string{"sfo", "tor1"}, nil}
+	expected := map[string]string{"sfo": "42", "tor1": "42"}
+
+	if ids := a.State("RegionSnapshotIds"); !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("artifact region snapshot IDs should match: %v, got %v", expected, ids)
+	}
+}
@@ -1,6 +1,7 @@
 package digitalocean
 
 import (
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -226,6 +227,25 @@ func TestBuilderPrepare_PrivateNetworking(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_SnapshotTags(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test set
+	config["snapshot_tags"] = []string{"packer", "web"}
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if !reflect.DeepEqual(b.config.SnapshotTags, []string{"packer", "web"}) {
+		t.Errorf("invalid: %#v", b.config.SnapshotTags)
+	}
+}
+
 func TestBuilderPrepare_SnapshotName(t *testing.T) {
 	var b Builder
 	config := testConfig()
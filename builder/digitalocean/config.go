@@ -31,6 +31,7 @@ type Config struct {
 	IPv6              bool          `mapstructure:"ipv6"`
 	SnapshotName      string        `mapstructure:"snapshot_name"`
 	SnapshotRegions   []string      `mapstructure:"snapshot_regions"`
+	SnapshotTags      []string      `mapstructure:"snapshot_tags"`
 	StateTimeout      time.Duration `mapstructure:"state_timeout"`
 	DropletName       string        `mapstructure:"droplet_name"`
 	UserData          string        `mapstructure:"user_data"`
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/digitalocean/godo"
@@ -61,6 +64,16 @@ func (s *stepSnapshot) Run(_ context.Context, state multistep.StateBag) multiste
 		return multistep.ActionHalt
 	}
 
+	var imageId int
+	if len(images) == 1 {
+		imageId = images[0].ID
+	} else {
+		err := errors.New("Couldn't find snapshot to get the image ID. Bug?")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	if len(c.SnapshotRegions) > 0 {
 		regionSet := make(map[string]struct{})
 		regions := make([]string, 0, len(c.SnapshotRegions))
@@ -78,40 +91,22 @@ func (s *stepSnapshot) Run(_ context.Context, state multistep.StateBag) multiste
 		}
 		snapshotRegions = regions
 
-		for transfer := range snapshotRegions {
-			transferRequest := &godo.ActionRequest{
-				"type":   "transfer",
-				"region": snapshotRegions[transfer],
-			}
-			imageTransfer, _, err := client.ImageActions.Transfer(context.TODO(), images[0].ID, transferRequest)
-			if err != nil {
-				err := fmt.Errorf("Error transferring snapshot: %s", err)
-				state.Put("error", err)
-				ui.Error(err.Error())
-				return multistep.ActionHalt
-			}
-			ui.Say(fmt.Sprintf("transferring Snapshot ID: %d", imageTransfer.ID))
-			if err := waitForImageState(godo.ActionCompleted, imageTransfer.ID, action.ID,
-				client, 20*time.Minute); err != nil {
-				// If we get an error the first time, actually report it
-				err := fmt.Errorf("Error waiting for snapshot transfer: %s", err)
-				state.Put("error", err)
-				ui.Error(err.Error())
-				return multistep.ActionHalt
-			}
+		if err := transferSnapshot(client, ui, imageId, snapshotRegions); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
 		}
 	}
+	snapshotRegions = append(snapshotRegions, c.Region)
 
-	var imageId int
-	if len(images) == 1 {
-		imageId = images[0].ID
-	} else {
-		err := errors.New("Couldn't find snapshot to get the image ID. Bug?")
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+	if len(c.SnapshotTags) > 0 {
+		if err := tagSnapshot(client, ui, imageId, c.SnapshotTags); err != nil {
+			err := fmt.Errorf("Error tagging snapshot: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
 	}
-	snapshotRegions = append(snapshotRegions, c.Region)
 
 	log.Printf("Snapshot image ID: %d", imageId)
 	state.Put("snapshot_image_id", imageId)
@@ -121,6 +116,70 @@ func (s *stepSnapshot) Run(_ context.Context, state multistep.StateBag) multiste
 	return multistep.ActionContinue
 }
 
+// transferSnapshot transfers the snapshot to the regions in parallel, and
+// waits for all the transfers to complete.
+func transferSnapshot(client *godo.Client, ui packer.Ui, imageId int, regions []string) error {
+	errs := make(chan error, len(regions))
+	var wg sync.WaitGroup
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			transferRequest := &godo.ActionRequest{
+				"type":   "transfer",
+				"region": region,
+			}
+			imageTransfer, _, err := client.ImageActions.Transfer(context.TODO(), imageId, transferRequest)
+			if err != nil {
+				errs <- fmt.Errorf("Error transferring snapshot to %s: %s", region, err)
+				return
+			}
+			ui.Say(fmt.Sprintf("Transferring snapshot to %s (action ID: %d)", region, imageTransfer.ID))
+			if err := waitForImageState(godo.ActionCompleted, imageId, imageTransfer.ID,
+				client, 20*time.Minute); err != nil {
+				errs <- fmt.Errorf("Error waiting for snapshot transfer to %s: %s", region, err)
+				return
+			}
+			ui.Message(fmt.Sprintf("Snapshot transferred to %s", region))
+		}(region)
+	}
+	wg.Wait()
+	close(errs)
+
+	// Report the first failed transfer, if any
+	return <-errs
+}
+
+// The type of the images in the resources of tags. The vendored client only
+// knows droplets.
+const imageResourceType godo.ResourceType = "image"
+
+// tagSnapshot applies the tags to the snapshot, creating the ones that
+// don't exist yet.
+func tagSnapshot(client *godo.Client, ui packer.Ui, imageId int, tags []string) error {
+	for _, tag := range tags {
+		ui.Say(fmt.Sprintf("Tagging snapshot with %s", tag))
+		_, resp, err := client.Tags.Get(context.TODO(), tag)
+		if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+			_, _, err = client.Tags.Create(context.TODO(), &godo.TagCreateRequest{Name: tag})
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = client.Tags.TagResources(context.TODO(), tag, &godo.TagResourcesRequest{
+			Resources: []godo.Resource{
+				{ID: strconv.Itoa(imageId), Type: imageResourceType},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *stepSnapshot) Cleanup(state multistep.StateBag) {
 	// no cleanup
 }
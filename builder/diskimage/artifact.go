@@ -0,0 +1,37 @@
+package diskimage
+
+import (
+	"fmt"
+	"os"
+)
+
+// Artifact is the image built by the disk-image builder.
+type Artifact struct {
+	dir   string
+	f     []string
+	state map[string]interface{}
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return a.f
+}
+
+func (*Artifact) Id() string {
+	return "Image"
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Image files in directory: %s", a.dir)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return a.state[name]
+}
+
+func (a *Artifact) Destroy() error {
+	return os.RemoveAll(a.dir)
+}
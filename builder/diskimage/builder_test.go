@@ -0,0 +1,251 @@
+package diskimage
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"rootfs_source":           "debootstrap",
+		"debootstrap_suite":       "buster",
+		packer.BuildNameConfigKey: "foo",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Error("Builder must implement builder.")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	warns, err := b.Prepare(testConfig())
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.DiskSize != 4096 {
+		t.Errorf("bad disk size: %d", b.config.DiskSize)
+	}
+	if b.config.PartitionTable != "gpt" {
+		t.Errorf("bad partition table: %s", b.config.PartitionTable)
+	}
+	if !reflect.DeepEqual(b.config.Partitions, defaultPartitions("gpt")) {
+		t.Errorf("bad partitions: %#v", b.config.Partitions)
+	}
+	if b.config.Bootloader != BootloaderGrub {
+		t.Errorf("bad bootloader: %s", b.config.Bootloader)
+	}
+	if b.config.Format != "raw" {
+		t.Errorf("bad format: %s", b.config.Format)
+	}
+	if b.config.OutputDir != "output-foo" {
+		t.Errorf("bad output dir: %s", b.config.OutputDir)
+	}
+	if b.config.ImageName != "packer-foo" {
+		t.Errorf("bad image name: %s", b.config.ImageName)
+	}
+	if b.config.diskPath() != b.config.imagePath() {
+		t.Errorf("bad disk path: %s", b.config.diskPath())
+	}
+}
+
+func TestBuilderPrepare_RootfsSource(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad
+	config["rootfs_source"] = "yum"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad
+	config["rootfs_source"] = "debootstrap"
+	delete(config, "debootstrap_suite")
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad
+	config["rootfs_source"] = "dnf"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["dnf_releasever"] = "32"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !reflect.DeepEqual(b.config.Packages, []string{"@core"}) {
+		t.Fatalf("bad: %#v", b.config.Packages)
+	}
+}
+
+func TestBuilderPrepare_RootfsTarball(t *testing.T) {
+	f, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	var b Builder
+	config := testConfig()
+	config["rootfs_source"] = "tarball"
+
+	// Bad
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad
+	config["rootfs_tarball"] = "/i/dont/exist"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["rootfs_tarball"] = f.Name()
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Bad
+	config["packages"] = []string{"vim"}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Partitions(t *testing.T) {
+	cases := []struct {
+		table      string
+		bootloader string
+		partitions []map[string]interface{}
+		ok         bool
+	}{
+		{"gpt", "grub", []map[string]interface{}{
+			{"size": 512, "filesystem": "vfat", "mountpoint": "/boot/efi", "flags": []string{"esp"}},
+			{"size": 1024, "filesystem": "swap"},
+			{"filesystem": "ext4", "mountpoint": "/"},
+		}, true},
+		{"gpt", "grub", []map[string]interface{}{
+			{"filesystem": "ext4", "mountpoint": "/"},
+		}, false},
+		{"gpt", "none", []map[string]interface{}{
+			{"filesystem": "ext4", "mountpoint": "/"},
+		}, true},
+		{"msdos", "grub", []map[string]interface{}{
+			{"filesystem": "xfs", "mountpoint": "/"},
+		}, true},
+		{"msdos", "none", []map[string]interface{}{
+			{"filesystem": "ext4", "mountpoint": "/"},
+			{"size": 1024, "filesystem": "ext4", "mountpoint": "/srv"},
+		}, false},
+		{"gpt", "none", []map[string]interface{}{
+			{"size": 1024, "filesystem": "ext4", "mountpoint": "/srv"},
+		}, false},
+		{"gpt", "none", []map[string]interface{}{
+			{"size": 1024, "filesystem": "ext4", "mountpoint": "/"},
+			{"filesystem": "ext4", "mountpoint": "/"},
+		}, false},
+		{"gpt", "none", []map[string]interface{}{
+			{"size": 1024, "filesystem": "swap", "mountpoint": "/swap"},
+			{"filesystem": "ext4", "mountpoint": "/"},
+		}, false},
+		{"gpt", "none", []map[string]interface{}{
+			{"filesystem": "zfs", "mountpoint": "/"},
+		}, false},
+		{"gpt", "none", []map[string]interface{}{
+			{"size": 4096, "filesystem": "ext4", "mountpoint": "/"},
+		}, false},
+	}
+
+	for i, tc := range cases {
+		config := testConfig()
+		config["partition_table"] = tc.table
+		config["bootloader"] = tc.bootloader
+		config["partitions"] = tc.partitions
+
+		var b Builder
+		_, err := b.Prepare(config)
+		if tc.ok && err != nil {
+			t.Fatalf("%d: should not have error: %s", i, err)
+		}
+		if !tc.ok && err == nil {
+			t.Fatalf("%d: should have error", i)
+		}
+	}
+}
+
+func TestBuilderPrepare_Format(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["format"] = "vmdk"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["format"] = "qcow2"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.diskPath() != "output-foo/packer-foo.raw" {
+		t.Fatalf("bad disk path: %s", b.config.diskPath())
+	}
+}
+
+func TestRootfsCommand(t *testing.T) {
+	cases := []struct {
+		config   Config
+		expected string
+	}{
+		{
+			Config{RootfsSource: "debootstrap", DebootstrapSuite: "buster"},
+			"debootstrap 'buster' '/mnt'",
+		},
+		{
+			Config{
+				RootfsSource:      "debootstrap",
+				DebootstrapSuite:  "buster",
+				DebootstrapMirror: "http://deb.debian.org/debian",
+				Packages:          []string{"linux-image-amd64", "grub-pc"},
+			},
+			"debootstrap --include='linux-image-amd64,grub-pc' 'buster' '/mnt' 'http://deb.debian.org/debian'",
+		},
+		{
+			Config{RootfsSource: "dnf", DnfReleasever: "32", Packages: []string{"@core", "kernel"}},
+			"dnf --assumeyes --installroot='/mnt' --releasever='32' install '@core' 'kernel'",
+		},
+		{
+			Config{RootfsSource: "tarball", RootfsTarball: "rootfs.tar.gz"},
+			"tar --extract --preserve-permissions --numeric-owner --file='rootfs.tar.gz' --directory='/mnt'",
+		},
+	}
+
+	for _, tc := range cases {
+		if command := rootfsCommand(&tc.config, "/mnt"); command != tc.expected {
+			t.Fatalf("bad: %s", command)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package diskimage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+const (
+	RootfsSourceDebootstrap = "debootstrap"
+	RootfsSourceDnf         = "dnf"
+	RootfsSourceTarball     = "tarball"
+)
+
+const (
+	BootloaderGrub = "grub"
+	BootloaderNone = "none"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	DiskSize       uint        `mapstructure:"disk_size"`
+	PartitionTable string      `mapstructure:"partition_table"`
+	Partitions     []Partition `mapstructure:"partitions"`
+
+	RootfsSource      string   `mapstructure:"rootfs_source"`
+	RootfsTarball     string   `mapstructure:"rootfs_tarball"`
+	DebootstrapSuite  string   `mapstructure:"debootstrap_suite"`
+	DebootstrapMirror string   `mapstructure:"debootstrap_mirror"`
+	DnfReleasever     string   `mapstructure:"dnf_releasever"`
+	Packages          []string `mapstructure:"packages"`
+
+	Bootloader string `mapstructure:"bootloader"`
+
+	Format             string `mapstructure:"format"`
+	OutputDir          string `mapstructure:"output_directory"`
+	ImageName          string `mapstructure:"image_name"`
+	HostCommandWrapper string `mapstructure:"host_command_wrapper"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	var c Config
+	err := config.Decode(&c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"host_command_wrapper",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var errs *packer.MultiError
+
+	if c.DiskSize == 0 {
+		c.DiskSize = 4096
+	}
+
+	if c.PartitionTable == "" {
+		c.PartitionTable = "gpt"
+	}
+	if c.PartitionTable != "gpt" && c.PartitionTable != "msdos" {
+		errs = packer.MultiErrorAppend(errs, errors.New("partition_table must be 'gpt' or 'msdos'"))
+	}
+
+	if c.Bootloader == "" {
+		c.Bootloader = BootloaderGrub
+	}
+	if c.Bootloader != BootloaderGrub && c.Bootloader != BootloaderNone {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("bootloader must be %q or %q", BootloaderGrub, BootloaderNone))
+	}
+
+	if len(c.Partitions) == 0 {
+		c.Partitions = defaultPartitions(c.PartitionTable)
+	}
+	errs = packer.MultiErrorAppend(errs, c.preparePartitions()...)
+
+	switch c.RootfsSource {
+	case RootfsSourceDebootstrap:
+		if c.DebootstrapSuite == "" {
+			errs = packer.MultiErrorAppend(errs,
+				errors.New("debootstrap_suite is required with the debootstrap rootfs_source"))
+		}
+	case RootfsSourceDnf:
+		if c.DnfReleasever == "" {
+			errs = packer.MultiErrorAppend(errs,
+				errors.New("dnf_releasever is required with the dnf rootfs_source"))
+		}
+		if len(c.Packages) == 0 {
+			c.Packages = []string{"@core"}
+		}
+	case RootfsSourceTarball:
+		if c.RootfsTarball == "" {
+			errs = packer.MultiErrorAppend(errs,
+				errors.New("rootfs_tarball is required with the tarball rootfs_source"))
+		} else if _, err := os.Stat(c.RootfsTarball); err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("rootfs_tarball is invalid: %s", err))
+		}
+		if len(c.Packages) > 0 {
+			errs = packer.MultiErrorAppend(errs,
+				errors.New("packages can't be installed with the tarball rootfs_source"))
+		}
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"rootfs_source must be %q, %q or %q",
+			RootfsSourceDebootstrap, RootfsSourceDnf, RootfsSourceTarball))
+	}
+
+	if c.Format == "" {
+		c.Format = "raw"
+	}
+	if c.Format != "qcow2" && c.Format != "raw" {
+		errs = packer.MultiErrorAppend(errs, errors.New("format must be 'qcow2' or 'raw'"))
+	}
+
+	if c.OutputDir == "" {
+		c.OutputDir = fmt.Sprintf("output-%s", c.PackerBuildName)
+	}
+	if !c.PackerForce {
+		if _, err := os.Stat(c.OutputDir); err == nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Output directory '%s' already exists. It must not exist.", c.OutputDir))
+		}
+	}
+
+	if c.ImageName == "" {
+		c.ImageName = fmt.Sprintf("packer-%s", c.PackerBuildName)
+	}
+
+	if c.HostCommandWrapper == "" {
+		c.HostCommandWrapper = "{{.Command}}"
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	return &c, nil, nil
+}
+
+// imagePath is the path of the image the builder produces.
+func (c *Config) imagePath() string {
+	return filepath.Join(c.OutputDir, c.ImageName)
+}
+
+// diskPath is the path of the raw disk the image is built on, which is
+// the image itself unless it is converted to another format at the end.
+func (c *Config) diskPath() string {
+	if c.Format == "raw" {
+		return c.imagePath()
+	}
+	return c.imagePath() + ".raw"
+}
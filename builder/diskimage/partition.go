@@ -0,0 +1,230 @@
+package diskimage
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Partition is a partition of the disk, created in order.
+type Partition struct {
+	// Label is the name of the partition, on GPT disks, and the label of
+	// its file system.
+	Label string `mapstructure:"label"`
+	// Size is the size of the partition, in MB. The last partition may have
+	// no size, to take the rest of the disk.
+	Size       uint     `mapstructure:"size"`
+	Filesystem string   `mapstructure:"filesystem"`
+	Mountpoint string   `mapstructure:"mountpoint"`
+	Options    string   `mapstructure:"mount_options"`
+	Flags      []string `mapstructure:"flags"`
+}
+
+// partedFilesystems are the file systems partitions can be formatted with,
+// and their names for parted.
+var partedFilesystems = map[string]string{
+	"btrfs": "btrfs",
+	"ext2":  "ext2",
+	"ext3":  "ext3",
+	"ext4":  "ext4",
+	"swap":  "linux-swap",
+	"vfat":  "fat32",
+	"xfs":   "xfs",
+}
+
+// defaultPartitions is a single root partition, after the partition GRUB
+// needs to boot a GPT disk on BIOS machines.
+func defaultPartitions(table string) []Partition {
+	root := Partition{Filesystem: "ext4", Mountpoint: "/"}
+	if table == "msdos" {
+		root.Flags = []string{"boot"}
+		return []Partition{root}
+	}
+	return []Partition{
+		{Label: "bios", Size: 1, Flags: []string{"bios_grub"}},
+		root,
+	}
+}
+
+func (c *Config) preparePartitions() []error {
+	var errs []error
+
+	if c.PartitionTable == "msdos" && len(c.Partitions) > 4 {
+		errs = append(errs, errors.New("msdos disks can't have more than 4 partitions"))
+	}
+
+	var size uint
+	var root, bootable bool
+	mountpoints := make(map[string]bool)
+	for i, p := range c.Partitions {
+		if p.Size == 0 && i != len(c.Partitions)-1 {
+			errs = append(errs, fmt.Errorf("partitions %d: size is required for all but the last partition", i+1))
+		}
+		size += p.Size
+
+		if p.Filesystem != "" {
+			if _, ok := partedFilesystems[p.Filesystem]; !ok {
+				errs = append(errs, fmt.Errorf("partitions %d: unknown filesystem %q", i+1, p.Filesystem))
+			}
+		}
+
+		if p.Mountpoint != "" {
+			if p.Filesystem == "" || p.Filesystem == "swap" {
+				errs = append(errs, fmt.Errorf("partitions %d: can't mount a partition without filesystem", i+1))
+			}
+			if !path.IsAbs(p.Mountpoint) {
+				errs = append(errs, fmt.Errorf("partitions %d: mountpoint must be an absolute path", i+1))
+			}
+			mountpoint := path.Clean(p.Mountpoint)
+			if mountpoints[mountpoint] {
+				errs = append(errs, fmt.Errorf("partitions %d: mountpoint %s is already used", i+1, mountpoint))
+			}
+			mountpoints[mountpoint] = true
+			c.Partitions[i].Mountpoint = mountpoint
+			root = root || mountpoint == "/"
+		} else if p.Options != "" {
+			errs = append(errs, fmt.Errorf("partitions %d: mount_options requires a mountpoint", i+1))
+		}
+
+		for _, flag := range p.Flags {
+			if flag == "bios_grub" || flag == "esp" || (flag == "boot" && c.PartitionTable == "msdos") {
+				bootable = true
+			}
+		}
+	}
+
+	if !root {
+		errs = append(errs, errors.New("a partition must be mounted on /"))
+	}
+
+	// The partitions start after the first MB of the disk, and GPT disks
+	// keep a copy of the partition table in their last MB.
+	if size+2 > c.DiskSize {
+		errs = append(errs, fmt.Errorf("the partitions don't fit in a disk of %d MB", c.DiskSize))
+	}
+
+	if c.Bootloader == BootloaderGrub && c.PartitionTable == "gpt" && !bootable {
+		errs = append(errs, errors.New("GRUB requires a partition with the bios_grub or esp flag on GPT disks"))
+	}
+
+	return errs
+}
+
+// partedCommand is the parted command creating the partitions on the disk.
+func partedCommand(disk string, table string, partitions []Partition) string {
+	args := []string{"parted", "--script", "--align", "optimal", shellQuote(disk), "mklabel", table}
+
+	start := uint(1)
+	for i, p := range partitions {
+		end := "100%"
+		if p.Size != 0 {
+			end = fmt.Sprintf("%dMiB", start+p.Size)
+		}
+
+		args = append(args, "mkpart")
+		if table == "gpt" {
+			name := p.Label
+			if name == "" {
+				name = fmt.Sprintf("part%d", i+1)
+			}
+			args = append(args, shellQuote(name))
+		} else {
+			args = append(args, "primary")
+		}
+		if fs, ok := partedFilesystems[p.Filesystem]; ok {
+			args = append(args, fs)
+		}
+		args = append(args, fmt.Sprintf("%dMiB", start), end)
+
+		for _, flag := range p.Flags {
+			args = append(args, "set", fmt.Sprint(i+1), flag, "on")
+		}
+		start += p.Size
+	}
+
+	return strings.Join(args, " ")
+}
+
+// partitionDevice is the device of the i-th partition of the disk attached
+// to the loop device.
+func partitionDevice(device string, i int) string {
+	return fmt.Sprintf("%sp%d", device, i+1)
+}
+
+// mkfsCommand is the command formatting the partition on the device, or
+// an empty string for partitions without file system.
+func mkfsCommand(p Partition, device string) string {
+	var command, label string
+	switch p.Filesystem {
+	case "ext2", "ext3", "ext4":
+		command, label = "mkfs."+p.Filesystem+" -F", "-L"
+	case "btrfs", "xfs":
+		command, label = "mkfs."+p.Filesystem+" -f", "-L"
+	case "vfat":
+		command, label = "mkfs.vfat -F 32", "-n"
+	case "swap":
+		command, label = "mkswap", "-L"
+	default:
+		return ""
+	}
+
+	if p.Label != "" {
+		command += fmt.Sprintf(" %s %s", label, shellQuote(p.Label))
+	}
+	return fmt.Sprintf("%s %s", command, device)
+}
+
+// mountOrder are the indexes of the partitions to mount, parents first.
+func mountOrder(partitions []Partition) []int {
+	var order []int
+	for i, p := range partitions {
+		if p.Mountpoint != "" {
+			order = append(order, i)
+		}
+	}
+
+	depth := func(mountpoint string) int {
+		if mountpoint == "/" {
+			return 0
+		}
+		return strings.Count(mountpoint, "/")
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return depth(partitions[order[i]].Mountpoint) < depth(partitions[order[j]].Mountpoint)
+	})
+	return order
+}
+
+// fstab is the /etc/fstab of the image, mounting the partitions by the
+// UUIDs of their file systems.
+func fstab(partitions []Partition, uuids []string) string {
+	var lines []string
+	for _, i := range mountOrder(partitions) {
+		p := partitions[i]
+		options := p.Options
+		if options == "" {
+			options = "defaults"
+		}
+		// xfs and btrfs are never checked at boot.
+		pass := 2
+		if p.Filesystem == "xfs" || p.Filesystem == "btrfs" {
+			pass = 0
+		} else if p.Mountpoint == "/" {
+			pass = 1
+		}
+		lines = append(lines, fmt.Sprintf("UUID=%s %s %s %s 0 %d",
+			uuids[i], p.Mountpoint, p.Filesystem, options, pass))
+	}
+	for i, p := range partitions {
+		if p.Filesystem == "swap" {
+			lines = append(lines, fmt.Sprintf("UUID=%s none swap sw 0 0", uuids[i]))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
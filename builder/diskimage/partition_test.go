@@ -0,0 +1,88 @@
+package diskimage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testPartitions() []Partition {
+	return []Partition{
+		{Label: "EFI", Size: 512, Filesystem: "vfat", Mountpoint: "/boot/efi", Flags: []string{"esp"}},
+		{Size: 1024, Filesystem: "swap"},
+		{Size: 2048, Filesystem: "xfs", Mountpoint: "/var", Options: "noatime"},
+		{Label: "root", Filesystem: "ext4", Mountpoint: "/"},
+	}
+}
+
+func TestPartedCommand(t *testing.T) {
+	command := partedCommand("/tmp/disk", "gpt", testPartitions())
+	expected := "parted --script --align optimal '/tmp/disk' mklabel gpt " +
+		"mkpart 'EFI' fat32 1MiB 513MiB set 1 esp on " +
+		"mkpart 'part2' linux-swap 513MiB 1537MiB " +
+		"mkpart 'part3' xfs 1537MiB 3585MiB " +
+		"mkpart 'root' ext4 3585MiB 100%"
+	if command != expected {
+		t.Fatalf("bad: %s", command)
+	}
+
+	command = partedCommand("/tmp/disk", "msdos", defaultPartitions("msdos"))
+	expected = "parted --script --align optimal '/tmp/disk' mklabel msdos " +
+		"mkpart primary ext4 1MiB 100% set 1 boot on"
+	if command != expected {
+		t.Fatalf("bad: %s", command)
+	}
+}
+
+func TestMkfsCommand(t *testing.T) {
+	partitions := append(testPartitions(), defaultPartitions("gpt")[0])
+	expected := []string{
+		"mkfs.vfat -F 32 -n 'EFI' /dev/loop0p1",
+		"mkswap /dev/loop0p2",
+		"mkfs.xfs -f /dev/loop0p3",
+		"mkfs.ext4 -F -L 'root' /dev/loop0p4",
+		"",
+	}
+
+	for i, p := range partitions {
+		if command := mkfsCommand(p, partitionDevice("/dev/loop0", i)); command != expected[i] {
+			t.Fatalf("%d: bad: %s", i, command)
+		}
+	}
+}
+
+func TestMountOrder(t *testing.T) {
+	if order := mountOrder(testPartitions()); !reflect.DeepEqual(order, []int{3, 2, 0}) {
+		t.Fatalf("bad: %#v", order)
+	}
+}
+
+func TestFstab(t *testing.T) {
+	content := fstab(testPartitions(), []string{"efi", "swap", "var", "root"})
+	expected := "UUID=root / ext4 defaults 0 1\n" +
+		"UUID=var /var xfs noatime 0 0\n" +
+		"UUID=efi /boot/efi vfat defaults 0 2\n" +
+		"UUID=swap none swap sw 0 0\n"
+	if content != expected {
+		t.Fatalf("bad: %s", content)
+	}
+}
+
+func TestGrubScript(t *testing.T) {
+	script := grubScript("gpt", testPartitions(), "/dev/loop0")
+	expected := "set -e\n" +
+		"if command -v grub2-install >/dev/null 2>&1; then grub=grub2; else grub=grub; fi\n" +
+		"${grub}-install --target=x86_64-efi --efi-directory=/boot/efi --removable --no-nvram\n" +
+		"${grub}-mkconfig -o /boot/${grub}/grub.cfg"
+	if script != expected {
+		t.Fatalf("bad: %s", script)
+	}
+
+	script = grubScript("gpt", defaultPartitions("gpt"), "/dev/loop0")
+	expected = "set -e\n" +
+		"if command -v grub2-install >/dev/null 2>&1; then grub=grub2; else grub=grub; fi\n" +
+		"${grub}-install --target=i386-pc /dev/loop0\n" +
+		"${grub}-mkconfig -o /boot/${grub}/grub.cfg"
+	if script != expected {
+		t.Fatalf("bad: %s", script)
+	}
+}
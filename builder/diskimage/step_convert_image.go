@@ -0,0 +1,47 @@
+package diskimage
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepConvertImage converts the raw disk to the output format, unless it
+// is raw.
+type stepConvertImage struct{}
+
+func (s *stepConvertImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	if config.Format == "raw" {
+		return multistep.ActionContinue
+	}
+
+	command := fmt.Sprintf("qemu-img convert -f raw -O %s %s %s",
+		config.Format, shellQuote(config.diskPath()), shellQuote(config.imagePath()))
+
+	ui.Say(fmt.Sprintf("Converting the disk to %s...", config.Format))
+	if err := chroot.RunWrapped(wrapper, command); err != nil {
+		err := fmt.Errorf("Error converting disk: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if err := os.Remove(config.diskPath()); err != nil {
+		err := fmt.Errorf("Error removing raw disk: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConvertImage) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,102 @@
+package diskimage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/common/imagemount"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepCreateDisk creates the raw disk, partitions it, and attaches it to a
+// loop device, with a device for each of its partitions.
+//
+// Produces:
+//   device string - The loop device the disk is attached to.
+//   attach_cleanup Cleanup - To detach the disk before the end of the
+//     build, with stepEarlyCleanup.
+type stepCreateDisk struct {
+	device string
+}
+
+func (s *stepCreateDisk) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	state.Put("attach_cleanup", s)
+
+	disk := config.diskPath()
+	ui.Say(fmt.Sprintf("Creating a %d MB disk at %s...", config.DiskSize, disk))
+	f, err := os.Create(disk)
+	if err == nil {
+		err = f.Truncate(int64(config.DiskSize) * 1024 * 1024)
+		f.Close()
+	}
+	if err != nil {
+		err := fmt.Errorf("Error creating disk: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Partitioning the disk...")
+	command := partedCommand(disk, config.PartitionTable, config.Partitions)
+	if err := chroot.RunWrapped(wrapper, command); err != nil {
+		err := fmt.Errorf("Error partitioning disk: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	device, err := chroot.RunWrappedOutput(wrapper,
+		fmt.Sprintf("losetup --find --show --partscan %s", shellQuote(disk)))
+	if err != nil {
+		err := fmt.Errorf("Error attaching disk to a loop device: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.device = device
+	ui.Say(fmt.Sprintf("Attached the disk to %s", device))
+
+	for i := range config.Partitions {
+		if err := imagemount.WaitForDevice(partitionDevice(device, i), 10*time.Second); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	state.Put("device", device)
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateDisk) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	if err := s.CleanupFunc(state); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+// CleanupFunc detaches the disk from the loop device.
+func (s *stepCreateDisk) CleanupFunc(state multistep.StateBag) error {
+	if s.device == "" {
+		return nil
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	ui.Say(fmt.Sprintf("Detaching the disk from %s...", s.device))
+	if err := chroot.RunWrapped(wrapper, fmt.Sprintf("losetup --detach %s", s.device)); err != nil {
+		return fmt.Errorf("Error detaching disk: %s", err)
+	}
+
+	s.device = ""
+	return nil
+}
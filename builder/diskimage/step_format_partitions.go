@@ -0,0 +1,42 @@
+package diskimage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepFormatPartitions creates the file systems of the partitions.
+//
+// Uses:
+//   device string
+type stepFormatPartitions struct{}
+
+func (s *stepFormatPartitions) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	device := state.Get("device").(string)
+
+	for i, p := range config.Partitions {
+		command := mkfsCommand(p, partitionDevice(device, i))
+		if command == "" {
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Formatting partition %d with %s...", i+1, p.Filesystem))
+		if err := chroot.RunWrapped(wrapper, command); err != nil {
+			err := fmt.Errorf("Error formatting partition %d: %s", i+1, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepFormatPartitions) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,78 @@
+package diskimage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepInstallBootloader installs GRUB on the disk, from the root file
+// system, which must have it installed.
+//
+// Uses:
+//   device string
+//   mount_path string
+type stepInstallBootloader struct{}
+
+func (s *stepInstallBootloader) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	device := state.Get("device").(string)
+	mountPath := state.Get("mount_path").(string)
+
+	if config.Bootloader == BootloaderNone {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Installing GRUB...")
+	command := fmt.Sprintf("chroot %s /bin/sh -c %s",
+		shellQuote(mountPath), shellQuote(grubScript(config.PartitionTable, config.Partitions, device)))
+	if err := chroot.RunWrapped(wrapper, command); err != nil {
+		err := fmt.Errorf("Error installing GRUB: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepInstallBootloader) Cleanup(state multistep.StateBag) {}
+
+// grubScript is the script installing GRUB on the disk attached to the
+// device, for BIOS machines if the partitions allow it, and for UEFI ones
+// if there is an EFI system partition. It uses the grub2 tools of Red Hat
+// based distributions if they exist.
+func grubScript(table string, partitions []Partition, device string) string {
+	bios := table == "msdos"
+	var esp string
+	for _, p := range partitions {
+		for _, flag := range p.Flags {
+			switch flag {
+			case "bios_grub":
+				bios = true
+			case "esp":
+				esp = p.Mountpoint
+			}
+		}
+	}
+
+	lines := []string{
+		"set -e",
+		"if command -v grub2-install >/dev/null 2>&1; then grub=grub2; else grub=grub; fi",
+	}
+	if bios {
+		lines = append(lines, fmt.Sprintf("${grub}-install --target=i386-pc %s", device))
+	}
+	if esp != "" {
+		lines = append(lines, fmt.Sprintf(
+			"${grub}-install --target=x86_64-efi --efi-directory=%s --removable --no-nvram", esp))
+	}
+	lines = append(lines, "${grub}-mkconfig -o /boot/${grub}/grub.cfg")
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,110 @@
+package diskimage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepInstallRootfs fills the root file system, with debootstrap or dnf,
+// or from a tarball, then writes its /etc/fstab.
+//
+// Uses:
+//   device string
+//   mount_path string
+type stepInstallRootfs struct{}
+
+func (s *stepInstallRootfs) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	device := state.Get("device").(string)
+	mountPath := state.Get("mount_path").(string)
+
+	ui.Say(fmt.Sprintf("Installing the root file system with %s...", config.RootfsSource))
+	if err := chroot.RunWrapped(wrapper, rootfsCommand(config, mountPath)); err != nil {
+		err := fmt.Errorf("Error installing the root file system: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Writing /etc/fstab...")
+	if err := writeFstab(config, wrapper, device, mountPath); err != nil {
+		err := fmt.Errorf("Error writing /etc/fstab: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepInstallRootfs) Cleanup(state multistep.StateBag) {}
+
+// rootfsCommand is the command filling the root file system mounted at
+// root.
+func rootfsCommand(config *Config, root string) string {
+	switch config.RootfsSource {
+	case RootfsSourceDebootstrap:
+		command := "debootstrap"
+		if len(config.Packages) > 0 {
+			command += " --include=" + shellQuote(strings.Join(config.Packages, ","))
+		}
+		command += fmt.Sprintf(" %s %s", shellQuote(config.DebootstrapSuite), shellQuote(root))
+		if config.DebootstrapMirror != "" {
+			command += " " + shellQuote(config.DebootstrapMirror)
+		}
+		return command
+	case RootfsSourceDnf:
+		packages := make([]string, len(config.Packages))
+		for i, p := range config.Packages {
+			packages[i] = shellQuote(p)
+		}
+		return fmt.Sprintf("dnf --assumeyes --installroot=%s --releasever=%s install %s",
+			shellQuote(root), shellQuote(config.DnfReleasever), strings.Join(packages, " "))
+	default:
+		return fmt.Sprintf("tar --extract --preserve-permissions --numeric-owner --file=%s --directory=%s",
+			shellQuote(config.RootfsTarball), shellQuote(root))
+	}
+}
+
+// writeFstab writes the /etc/fstab of the root file system, with the UUIDs
+// the file systems of the partitions got when they were formatted.
+func writeFstab(config *Config, wrapper chroot.CommandWrapper, device string, root string) error {
+	uuids := make([]string, len(config.Partitions))
+	for i, p := range config.Partitions {
+		if p.Filesystem == "" {
+			continue
+		}
+		uuid, err := chroot.RunWrappedOutput(wrapper,
+			fmt.Sprintf("blkid -s UUID -o value %s", partitionDevice(device, i)))
+		if err != nil {
+			return fmt.Errorf("Error reading UUID of partition %d: %s", i+1, err)
+		}
+		uuids[i] = uuid
+	}
+
+	f, err := ioutil.TempFile("", "packer-fstab")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(fstab(config.Partitions, uuids))
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := chroot.RunWrapped(wrapper, fmt.Sprintf("mkdir -p %s", shellQuote(root+"/etc"))); err != nil {
+		return err
+	}
+	return chroot.RunWrapped(wrapper,
+		fmt.Sprintf("cp %s %s", shellQuote(f.Name()), shellQuote(root+"/etc/fstab")))
+}
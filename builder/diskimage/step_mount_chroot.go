@@ -0,0 +1,63 @@
+package diskimage
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/common/imagemount"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepMountChroot mounts the file systems the usual tools need in the
+// root file system, such as /proc, so that provisioners can run chrooted
+// into it.
+//
+// Uses:
+//   mount_path string
+//
+// Produces:
+//   communicator packer.Communicator - Runs commands in the root file system.
+//   mount_chroot_cleanup Cleanup - To unmount the file systems before the
+//     end of the build, with stepEarlyCleanup.
+type stepMountChroot struct {
+	mounted []string
+}
+
+func (s *stepMountChroot) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	mountPath := state.Get("mount_path").(string)
+
+	state.Put("mount_chroot_cleanup", s)
+
+	mounted, err := imagemount.MountChrootFilesystems(wrapper, mountPath)
+	s.mounted = mounted
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("communicator", &chroot.Communicator{
+		Chroot:     mountPath,
+		CmdWrapper: wrapper,
+	})
+	return multistep.ActionContinue
+}
+
+func (s *stepMountChroot) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	if err := s.CleanupFunc(state); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+// CleanupFunc unmounts the file systems mounted in the root file system.
+func (s *stepMountChroot) CleanupFunc(state multistep.StateBag) error {
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	var err error
+	s.mounted, err = imagemount.UnmountChrootFilesystems(wrapper, s.mounted)
+	return err
+}
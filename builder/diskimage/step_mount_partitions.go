@@ -0,0 +1,112 @@
+package diskimage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepMountPartitions mounts the partitions of the disk on the host, on
+// their mountpoints under a temporary directory.
+//
+// Uses:
+//   device string
+//
+// Produces:
+//   mount_path string - The location where the root file system is mounted.
+//   mount_partitions_cleanup Cleanup - To unmount the partitions before the
+//     end of the build, with stepEarlyCleanup.
+type stepMountPartitions struct {
+	mountPath string
+	mounted   []string
+}
+
+func (s *stepMountPartitions) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	device := state.Get("device").(string)
+
+	state.Put("mount_partitions_cleanup", s)
+
+	mountPath, err := ioutil.TempDir("", "packer-disk-image")
+	if err != nil {
+		err := fmt.Errorf("Error creating mount directory: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.mountPath = mountPath
+
+	ui.Say("Mounting the partitions...")
+	for _, i := range mountOrder(config.Partitions) {
+		p := config.Partitions[i]
+		target := filepath.Join(mountPath, p.Mountpoint)
+
+		if err := chroot.RunWrapped(wrapper, fmt.Sprintf("mkdir -p %s", shellQuote(target))); err != nil {
+			err := fmt.Errorf("Error creating mountpoint %s: %s", p.Mountpoint, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		command := "mount"
+		if p.Options != "" {
+			command += " -o " + shellQuote(p.Options)
+		}
+		command += fmt.Sprintf(" %s %s", partitionDevice(device, i), shellQuote(target))
+
+		if err := chroot.RunWrapped(wrapper, command); err != nil {
+			err := fmt.Errorf("Error mounting partition %d on %s: %s", i+1, p.Mountpoint, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.mounted = append(s.mounted, target)
+	}
+
+	state.Put("mount_path", mountPath)
+	return multistep.ActionContinue
+}
+
+func (s *stepMountPartitions) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	if err := s.CleanupFunc(state); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+// CleanupFunc unmounts the partitions, children first. It does nothing if
+// they aren't mounted anymore, so it is safe to call more than once.
+func (s *stepMountPartitions) CleanupFunc(state multistep.StateBag) error {
+	if s.mountPath == "" {
+		return nil
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	if len(s.mounted) > 0 {
+		ui.Say("Unmounting the partitions...")
+	}
+	for len(s.mounted) > 0 {
+		target := s.mounted[len(s.mounted)-1]
+		if err := chroot.RunWrapped(wrapper, fmt.Sprintf("umount %s", shellQuote(target))); err != nil {
+			return fmt.Errorf("Error unmounting %s: %s", target, err)
+		}
+		s.mounted = s.mounted[:len(s.mounted)-1]
+	}
+
+	if err := os.Remove(s.mountPath); err != nil {
+		log.Printf("Error removing mount directory %s: %s", s.mountPath, err)
+	}
+	s.mountPath = ""
+	return nil
+}
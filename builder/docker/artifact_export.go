@@ -8,7 +8,8 @@ import (
 // ExportArtifact is an Artifact implementation for when a container is
 // exported from docker into a single flat file.
 type ExportArtifact struct {
-	path string
+	path    string
+	runtime string
 }
 
 func (*ExportArtifact) BuilderId() string {
@@ -28,6 +29,9 @@ func (a *ExportArtifact) String() string {
 }
 
 func (a *ExportArtifact) State(name string) interface{} {
+	if name == "runtime" {
+		return a.runtime
+	}
 	return nil
 }
 
@@ -10,6 +10,7 @@ type ImportArtifact struct {
 	BuilderIdValue string
 	Driver         Driver
 	IdValue        string
+	Runtime        string
 }
 
 func (a *ImportArtifact) BuilderId() string {
@@ -28,7 +29,10 @@ func (a *ImportArtifact) String() string {
 	return fmt.Sprintf("Imported Docker image: %s", a.Id())
 }
 
-func (*ImportArtifact) State(name string) interface{} {
+func (a *ImportArtifact) State(name string) interface{} {
+	if name == "runtime" {
+		return a.Runtime
+	}
 	return nil
 }
 
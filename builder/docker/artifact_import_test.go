@@ -32,6 +32,28 @@ func TestImportArtifactId(t *testing.T) {
 	}
 }
 
+func TestImportArtifactState(t *testing.T) {
+	a := &ImportArtifact{Runtime: RuntimePodman}
+	if a.State("runtime") != RuntimePodman {
+		t.Fatalf("bad: %#v", a.State("runtime"))
+	}
+}
+
+func TestArtifactRuntime(t *testing.T) {
+	artifact := &packer.MockArtifact{
+		StateValues: map[string]interface{}{"runtime": RuntimePodman},
+	}
+	if runtime := ArtifactRuntime(artifact, ""); runtime != RuntimePodman {
+		t.Fatalf("bad: %s", runtime)
+	}
+	if runtime := ArtifactRuntime(artifact, RuntimeDocker); runtime != RuntimeDocker {
+		t.Fatalf("bad: %s", runtime)
+	}
+	if runtime := ArtifactRuntime(new(packer.MockArtifact), ""); runtime != "" {
+		t.Fatalf("bad: %s", runtime)
+	}
+}
+
 func TestImportArtifactDestroy(t *testing.T) {
 	d := new(MockDriver)
 	a := &ImportArtifact{
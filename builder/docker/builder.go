@@ -30,7 +30,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
-	driver := &DockerDriver{Ctx: &b.config.ctx, Ui: ui}
+	driver := &DockerDriver{Ctx: &b.config.ctx, Ui: ui, Runtime: b.config.Runtime}
 	if err := driver.Verify(); err != nil {
 		return nil, err
 	}
@@ -41,8 +41,22 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 	log.Printf("[DEBUG] Docker version: %s", version.String())
 
+	platform, err := driver.Platform()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] Docker platform: %s %s", platform.OS, platform.KernelVersion)
+	if err := b.config.usePlatform(platform); err != nil {
+		return nil, err
+	}
+
 	steps := []multistep.Step{
 		&StepTempDir{},
+	}
+	if b.config.Checkpoints {
+		steps = append(steps, &StepCheckpoint{})
+	}
+	steps = append(steps,
 		&StepPull{},
 		&StepRun{},
 		&communicator.StepConnect{
@@ -54,7 +68,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			},
 		},
 		&common.StepProvision{},
-	}
+	)
 
 	if b.config.Discard {
 		log.Print("[DEBUG] Container will be discarded")
@@ -98,9 +112,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			IdValue:        state.Get("image_id").(string),
 			BuilderIdValue: BuilderIdImport,
 			Driver:         driver,
+			Runtime:        b.config.Runtime,
 		}
 	} else {
-		artifact = &ExportArtifact{path: b.config.ExportPath}
+		artifact = &ExportArtifact{path: b.config.ExportPath, runtime: b.config.Runtime}
 	}
 
 	return artifact, nil
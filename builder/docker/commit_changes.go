@@ -0,0 +1,245 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// commitInstructions are the Dockerfile instructions the changes of a
+// commit can have.
+var commitInstructions = map[string]bool{
+	"CMD":         true,
+	"ENTRYPOINT":  true,
+	"ENV":         true,
+	"EXPOSE":      true,
+	"HEALTHCHECK": true,
+	"LABEL":       true,
+	"MAINTAINER":  true,
+	"ONBUILD":     true,
+	"STOPSIGNAL":  true,
+	"USER":        true,
+	"VOLUME":      true,
+	"WORKDIR":     true,
+}
+
+// HealthcheckConfig is the HEALTHCHECK of the committed image. The test is
+// in the form of Docker Compose: ["CMD", args...] runs the command,
+// ["CMD-SHELL", command] runs it with the shell, and ["NONE"] disables the
+// health check of the base image.
+type HealthcheckConfig struct {
+	Test        []string      `mapstructure:"test"`
+	Interval    time.Duration `mapstructure:"interval"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	StartPeriod time.Duration `mapstructure:"start_period"`
+	Retries     int           `mapstructure:"retries"`
+}
+
+func (h *HealthcheckConfig) Prepare() []error {
+	var errs []error
+	if len(h.Test) == 0 {
+		errs = append(errs, fmt.Errorf("healthcheck test is required"))
+	} else {
+		switch h.Test[0] {
+		case "NONE":
+		case "CMD", "CMD-SHELL":
+			if len(h.Test) < 2 {
+				errs = append(errs, fmt.Errorf("healthcheck test %s requires a command", h.Test[0]))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("healthcheck test must start with CMD, CMD-SHELL or NONE, not %q", h.Test[0]))
+		}
+	}
+	if h.Interval < 0 || h.Timeout < 0 || h.StartPeriod < 0 || h.Retries < 0 {
+		errs = append(errs, fmt.Errorf("healthcheck durations and retries can't be negative"))
+	}
+	return errs
+}
+
+func (h *HealthcheckConfig) change() string {
+	return healthcheckChange(h.Test, h.Interval, h.Timeout, h.StartPeriod, h.Retries)
+}
+
+func healthcheckChange(test []string, interval, timeout, startPeriod time.Duration, retries int) string {
+	if len(test) == 0 || test[0] == "NONE" {
+		return "HEALTHCHECK NONE"
+	}
+
+	parts := []string{"HEALTHCHECK"}
+	if interval > 0 {
+		parts = append(parts, "--interval="+interval.String())
+	}
+	if timeout > 0 {
+		parts = append(parts, "--timeout="+timeout.String())
+	}
+	if startPeriod > 0 {
+		parts = append(parts, "--start-period="+startPeriod.String())
+	}
+	if retries > 0 {
+		parts = append(parts, fmt.Sprintf("--retries=%d", retries))
+	}
+
+	if test[0] == "CMD-SHELL" {
+		parts = append(parts, "CMD", strings.Join(test[1:], " "))
+	} else {
+		parts = append(parts, "CMD", execForm(test[1:]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// validateChange validates the instruction of a change, and the JSON of
+// the exec form of CMD and ENTRYPOINT, which Docker would otherwise run
+// with the shell when it is invalid.
+func validateChange(change string) error {
+	change = strings.TrimSpace(change)
+	instruction, arg := change, ""
+	if i := strings.IndexAny(change, " \t"); i >= 0 {
+		instruction, arg = change[:i], strings.TrimSpace(change[i:])
+	}
+
+	instruction = strings.ToUpper(instruction)
+	if !commitInstructions[instruction] {
+		return fmt.Errorf("change %q: %s can't be changed on commit", change, instruction)
+	}
+	if arg == "" {
+		return fmt.Errorf("change %q: %s requires an argument", change, instruction)
+	}
+
+	if (instruction == "CMD" || instruction == "ENTRYPOINT") && strings.HasPrefix(arg, "[") {
+		var args []string
+		if err := json.Unmarshal([]byte(arg), &args); err != nil {
+			return fmt.Errorf("change %q: the exec form of %s must be a JSON array of strings: %s",
+				change, instruction, err)
+		}
+	}
+	return nil
+}
+
+// commitChanges are the changes of the commit: the changes of the
+// configuration, then the ones of the structured options, which override
+// them.
+func (c *Config) commitChanges(now time.Time) []string {
+	changes := append([]string{}, c.Changes...)
+
+	if len(c.Entrypoint) > 0 {
+		changes = append(changes, "ENTRYPOINT "+execForm(c.Entrypoint))
+	}
+	if len(c.Cmd) > 0 {
+		changes = append(changes, "CMD "+execForm(c.Cmd))
+	}
+	if c.Healthcheck != nil {
+		changes = append(changes, c.Healthcheck.change())
+	}
+
+	labels := make(map[string]string)
+	if c.OCILabels {
+		labels["org.opencontainers.image.created"] = now.UTC().Format(time.RFC3339)
+		labels["org.opencontainers.image.base.name"] = c.Image
+	}
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	return append(changes, labelChanges(labels)...)
+}
+
+// ImageConfig is the configuration of an image, as docker inspect reports
+// it.
+type ImageConfig struct {
+	User         string
+	ExposedPorts map[string]struct{}
+	Env          []string
+	Cmd          []string
+	Healthcheck  *struct {
+		Test        []string
+		Interval    time.Duration
+		Timeout     time.Duration
+		StartPeriod time.Duration
+		Retries     int
+	}
+	Volumes    map[string]struct{}
+	WorkingDir string
+	Entrypoint []string
+	OnBuild    []string
+	Labels     map[string]string
+	StopSignal string
+}
+
+// changes are the changes that set up the configuration on an imported
+// image, which has none.
+func (c *ImageConfig) changes() []string {
+	var changes []string
+	for _, env := range c.Env {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) == 2 {
+			changes = append(changes, fmt.Sprintf("ENV %s=%s", kv[0], dockerfileQuote(kv[1])))
+		}
+	}
+	for _, port := range sortedKeys(c.ExposedPorts) {
+		changes = append(changes, "EXPOSE "+port)
+	}
+	if volumes := sortedKeys(c.Volumes); len(volumes) > 0 {
+		changes = append(changes, "VOLUME "+execForm(volumes))
+	}
+	if c.WorkingDir != "" {
+		changes = append(changes, "WORKDIR "+c.WorkingDir)
+	}
+	if c.User != "" {
+		changes = append(changes, "USER "+c.User)
+	}
+	if c.StopSignal != "" {
+		changes = append(changes, "STOPSIGNAL "+c.StopSignal)
+	}
+	for _, onBuild := range c.OnBuild {
+		changes = append(changes, "ONBUILD "+onBuild)
+	}
+	if h := c.Healthcheck; h != nil && len(h.Test) > 0 {
+		changes = append(changes, healthcheckChange(h.Test, h.Interval, h.Timeout, h.StartPeriod, h.Retries))
+	}
+	if len(c.Entrypoint) > 0 {
+		changes = append(changes, "ENTRYPOINT "+execForm(c.Entrypoint))
+	}
+	if len(c.Cmd) > 0 {
+		changes = append(changes, "CMD "+execForm(c.Cmd))
+	}
+	return append(changes, labelChanges(c.Labels)...)
+}
+
+func labelChanges(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	changes := make([]string, len(keys))
+	for i, k := range keys {
+		changes[i] = fmt.Sprintf("LABEL %s=%s", dockerfileQuote(k), dockerfileQuote(labels[k]))
+	}
+	return changes
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// execForm is the JSON array of the exec form of an instruction.
+func execForm(args []string) string {
+	raw, _ := json.Marshal(args)
+	return string(raw)
+}
+
+// dockerfileQuote quotes the string for a Dockerfile instruction, without
+// expanding the variables in it.
+func dockerfileQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, `$`, `\$`, -1)
+	return `"` + s + `"`
+}
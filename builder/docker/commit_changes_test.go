@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateChange(t *testing.T) {
+	good := []string{
+		"CMD nginx -g 'daemon off;'",
+		`ENTRYPOINT ["/docker-entrypoint.sh"]`,
+		"expose 8080",
+		"HEALTHCHECK NONE",
+		`LABEL "maintainer"="ops"`,
+	}
+	for _, change := range good {
+		if err := validateChange(change); err != nil {
+			t.Fatalf("%s: %s", change, err)
+		}
+	}
+
+	bad := []string{
+		"RUN apt-get update",
+		"CMD",
+		`ENTRYPOINT ['/docker-entrypoint.sh']`,
+		`CMD ["nginx", 42]`,
+	}
+	for _, change := range bad {
+		if err := validateChange(change); err == nil {
+			t.Fatalf("%s: should error", change)
+		}
+	}
+}
+
+func TestConfig_commitChanges(t *testing.T) {
+	c := &Config{
+		Image:      "nginx:1.19",
+		Changes:    []string{"EXPOSE 80"},
+		Entrypoint: []string{"/docker-entrypoint.sh"},
+		Cmd:        []string{"nginx", "-g", "daemon off;"},
+		Healthcheck: &HealthcheckConfig{
+			Test:     []string{"CMD-SHELL", "curl -f http://localhost/ || exit 1"},
+			Interval: 30 * time.Second,
+			Retries:  3,
+		},
+		Labels:    map[string]string{"org.opencontainers.image.title": "web", "maintainer": `"ops"`},
+		OCILabels: true,
+	}
+
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	expected := []string{
+		"EXPOSE 80",
+		`ENTRYPOINT ["/docker-entrypoint.sh"]`,
+		`CMD ["nginx","-g","daemon off;"]`,
+		"HEALTHCHECK --interval=30s --retries=3 CMD curl -f http://localhost/ || exit 1",
+		`LABEL "maintainer"="\"ops\""`,
+		`LABEL "org.opencontainers.image.base.name"="nginx:1.19"`,
+		`LABEL "org.opencontainers.image.created"="2020-10-01T12:00:00Z"`,
+		`LABEL "org.opencontainers.image.title"="web"`,
+	}
+	if changes := c.commitChanges(now); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("bad: %#v", changes)
+	}
+}
+
+func TestHealthcheckConfigPrepare(t *testing.T) {
+	// Good
+	for _, test := range [][]string{{"NONE"}, {"CMD", "/healthcheck"}, {"CMD-SHELL", "exit 0"}} {
+		h := &HealthcheckConfig{Test: test}
+		if errs := h.Prepare(); len(errs) > 0 {
+			t.Fatalf("%v: %v", test, errs)
+		}
+	}
+
+	// Bad
+	for _, test := range [][]string{nil, {"CMD"}, {"curl", "http://localhost/"}} {
+		h := &HealthcheckConfig{Test: test}
+		if errs := h.Prepare(); len(errs) == 0 {
+			t.Fatalf("%v: should error", test)
+		}
+	}
+}
+
+func TestImageConfig_changes(t *testing.T) {
+	c := &ImageConfig{
+		Env:          []string{"PATH=/usr/local/bin:/usr/bin", "PRICE=$5"},
+		ExposedPorts: map[string]struct{}{"80/tcp": {}, "443/tcp": {}},
+		WorkingDir:   "/app",
+		User:         "www-data",
+		Cmd:          []string{"nginx"},
+		Labels:       map[string]string{"maintainer": "ops"},
+	}
+
+	expected := []string{
+		`ENV PATH="/usr/local/bin:/usr/bin"`,
+		`ENV PRICE="\$5"`,
+		"EXPOSE 443/tcp",
+		"EXPOSE 80/tcp",
+		"WORKDIR /app",
+		"USER www-data",
+		`CMD ["nginx"]`,
+		`LABEL "maintainer"="ops"`,
+	}
+	if changes := c.changes(); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("bad: %#v", changes)
+	}
+}
@@ -46,7 +46,7 @@ func (c *Communicator) Start(remote *packer.RemoteCmd) error {
 			append([]string{"-u", c.Config.ExecUser}, dockerArgs[2:]...)...)
 	}
 
-	cmd := exec.Command("docker", dockerArgs...)
+	cmd := c.command(dockerArgs...)
 
 	var (
 		stdin_w io.WriteCloser
@@ -74,6 +74,15 @@ func (c *Communicator) Start(remote *packer.RemoteCmd) error {
 	return nil
 }
 
+// command is the command of the container runtime with the arguments.
+func (c *Communicator) command(args ...string) *exec.Cmd {
+	runtime := c.Config.Runtime
+	if runtime == "" {
+		runtime = RuntimeDocker
+	}
+	return exec.Command(runtime, args...)
+}
+
 // Upload uploads a file to the docker container
 func (c *Communicator) Upload(dst string, src io.Reader, fi *os.FileInfo) error {
 	if fi == nil {
@@ -108,7 +117,7 @@ func (c *Communicator) uploadFile(dst string, src io.Reader, fi *os.FileInfo) er
 	// command format: docker cp /path/to/infile containerid:/path/to/outfile
 	log.Printf("Copying to %s on container %s.", dst, c.ContainerID)
 
-	localCmd := exec.Command("docker", "cp", "-",
+	localCmd := c.command("cp", "-",
 		fmt.Sprintf("%s:%s", c.ContainerID, filepath.Dir(dst)))
 
 	stderrP, err := localCmd.StderrPipe()
@@ -192,7 +201,7 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 	}
 
 	// Make the directory, then copy into it
-	localCmd := exec.Command("docker", "cp", dockerSource, fmt.Sprintf("%s:%s", c.ContainerID, dst))
+	localCmd := c.command("cp", dockerSource, fmt.Sprintf("%s:%s", c.ContainerID, dst))
 
 	stderrP, err := localCmd.StderrPipe()
 	if err != nil {
@@ -223,7 +232,7 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 // cp to write to stdout, and then copy the stream to our destination io.Writer.
 func (c *Communicator) Download(src string, dst io.Writer) error {
 	log.Printf("Downloading file from container: %s:%s", c.ContainerID, src)
-	localCmd := exec.Command("docker", "cp", fmt.Sprintf("%s:%s", c.ContainerID, src), "-")
+	localCmd := c.command("cp", fmt.Sprintf("%s:%s", c.ContainerID, src), "-")
 
 	pipe, err := localCmd.StdoutPipe()
 	if err != nil {
@@ -331,10 +340,10 @@ func (c *Communicator) fixDestinationOwner(destination string) error {
 	}
 
 	chownArgs := []string{
-		"docker", "exec", "--user", "root", c.ContainerID, "/bin/sh", "-c",
+		"exec", "--user", "root", c.ContainerID, "/bin/sh", "-c",
 		fmt.Sprintf("chown -R %s %s", owner, destination),
 	}
-	if output, err := exec.Command(chownArgs[0], chownArgs[1:]...).CombinedOutput(); err != nil {
+	if output, err := c.command(chownArgs...).CombinedOutput(); err != nil {
 		return fmt.Errorf("Failed to set owner of the uploaded file: %s, %s", err, output)
 	}
 
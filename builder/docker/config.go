@@ -2,6 +2,7 @@ package docker
 
 import (
 	"fmt"
+	"log"
 	"os"
 
 	"github.com/hashicorp/packer/common"
@@ -17,6 +18,9 @@ var (
 	errArtifactUseConflict = fmt.Errorf("Cannot specify more than one of commit, discard, and export_path")
 	errExportPathNotFile   = fmt.Errorf("export_path must be a file, not a directory")
 	errImageNotSpecified   = fmt.Errorf("Image must be specified")
+	errWindowsExport       = fmt.Errorf("Windows containers can't be exported, use commit instead of export_path")
+	errWindowsSquash       = fmt.Errorf("Windows containers can't be squashed")
+	errCommitOptions       = fmt.Errorf("cmd, entrypoint, healthcheck, labels, oci_labels and squash require commit")
 )
 
 type Config struct {
@@ -39,6 +43,29 @@ type Config struct {
 	Volumes        map[string]string
 	FixUploadOwner bool `mapstructure:"fix_upload_owner"`
 
+	// The changes of the commit besides Changes, and whether the image is
+	// squashed into a single layer.
+	Cmd         []string           `mapstructure:"cmd"`
+	Entrypoint  []string           `mapstructure:"entrypoint"`
+	Healthcheck *HealthcheckConfig `mapstructure:"healthcheck"`
+	Labels      map[string]string  `mapstructure:"labels"`
+	OCILabels   bool               `mapstructure:"oci_labels"`
+	Squash      bool               `mapstructure:"squash"`
+
+	// The container is committed to a checkpoint image after the
+	// provisioners, which the next builds start from while the template
+	// before them doesn't change.
+	Checkpoints          bool   `mapstructure:"checkpoints"`
+	CheckpointRepository string `mapstructure:"checkpoint_repository"`
+
+	// The container runtime to build with, RuntimeDocker or RuntimePodman.
+	Runtime string `mapstructure:"runtime"`
+
+	// The container is a Windows container, with the defaults and the
+	// communicator of Windows. Unless it is set, it is detected from the
+	// platform of Docker when the build starts.
+	WindowsContainer bool `mapstructure:"windows_container"`
+
 	// This is used to login to dockerhub to pull a private base container. For
 	// pushing to dockerhub, see the docker post-processors
 	Login           bool
@@ -49,6 +76,10 @@ type Config struct {
 	AwsAccessConfig `mapstructure:",squash"`
 
 	ctx interpolate.Context
+
+	detectPlatform      bool
+	defaultRunCommand   bool
+	defaultContainerDir bool
 }
 
 func NewConfig(raws ...interface{}) (*Config, []string, error) {
@@ -72,16 +103,19 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	}
 
 	// Defaults
-	if len(c.RunCommand) == 0 {
-		c.RunCommand = []string{"-d", "-i", "-t", "{{.Image}}", "/bin/bash"}
-	}
+	c.defaultRunCommand = len(c.RunCommand) == 0
+	c.defaultContainerDir = c.ContainerDir == ""
+	c.setPlatformDefaults()
 
-	// Default Pull if it wasn't set
+	// Default Pull if it wasn't set, and detect the platform unless it is
 	hasPull := false
+	c.detectPlatform = true
 	for _, k := range md.Keys {
-		if k == "Pull" {
+		switch k {
+		case "Pull":
 			hasPull = true
-			break
+		case "windows_container":
+			c.detectPlatform = false
 		}
 	}
 
@@ -110,14 +144,38 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		errs = packer.MultiErrorAppend(errs, errArtifactNotUsed)
 	}
 
+	if !c.Commit && (len(c.Cmd) > 0 || len(c.Entrypoint) > 0 ||
+		c.Healthcheck != nil || len(c.Labels) > 0 || c.OCILabels || c.Squash) {
+		errs = packer.MultiErrorAppend(errs, errCommitOptions)
+	}
+	for _, change := range c.Changes {
+		if err := validateChange(change); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+	if c.Healthcheck != nil {
+		errs = packer.MultiErrorAppend(errs, c.Healthcheck.Prepare()...)
+	}
+
 	if c.ExportPath != "" {
 		if fi, err := os.Stat(c.ExportPath); err == nil && fi.IsDir() {
 			errs = packer.MultiErrorAppend(errs, errExportPathNotFile)
 		}
 	}
 
-	if c.ContainerDir == "" {
-		c.ContainerDir = "/packer-files"
+	if c.Runtime == "" {
+		c.Runtime = RuntimeDocker
+	}
+	if err := CheckRuntime(c.Runtime); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
+	if err := c.validatePlatform(); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
+	if c.CheckpointRepository == "" {
+		c.CheckpointRepository = "packer-checkpoints"
 	}
 
 	if c.EcrLogin && c.LoginServer == "" {
@@ -130,3 +188,61 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 
 	return c, nil, nil
 }
+
+// setPlatformDefaults defaults the run command and the directory of the
+// container to the ones of its platform.
+func (c *Config) setPlatformDefaults() {
+	if c.defaultRunCommand {
+		if c.WindowsContainer {
+			c.RunCommand = []string{"-d", "-i", "-t", "--entrypoint=powershell", "{{.Image}}"}
+		} else {
+			c.RunCommand = []string{"-d", "-i", "-t", "{{.Image}}", "/bin/bash"}
+		}
+	}
+	if c.defaultContainerDir {
+		if c.WindowsContainer {
+			c.ContainerDir = "c:/packer-files"
+		} else {
+			c.ContainerDir = "/packer-files"
+		}
+	}
+}
+
+func (c *Config) validatePlatform() error {
+	if c.WindowsContainer && c.ExportPath != "" {
+		return errWindowsExport
+	}
+	if c.WindowsContainer && c.Squash {
+		return errWindowsSquash
+	}
+	return nil
+}
+
+// usePlatform sets the configuration up for the platform of Docker: the
+// platform of the container is detected unless it is set, and Windows
+// containers run the image for the Windows build of the host.
+func (c *Config) usePlatform(p *Platform) error {
+	if c.detectPlatform {
+		c.WindowsContainer = p.OS == "windows"
+		c.setPlatformDefaults()
+		if err := c.validatePlatform(); err != nil {
+			return err
+		}
+	}
+	if !c.WindowsContainer {
+		return nil
+	}
+
+	if p.OS != "windows" {
+		return fmt.Errorf("%s runs %s containers, not Windows containers", c.Runtime, p.OS)
+	}
+	image, err := windowsImage(c.Image, p.KernelVersion)
+	if err != nil {
+		return err
+	}
+	if image != c.Image {
+		log.Printf("Using image %s for the Windows version of the host", image)
+		c.Image = image
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 )
 
 func testConfig() map[string]interface{} {
@@ -141,3 +142,55 @@ func TestConfigPrepare_pull(t *testing.T) {
 		t.Fatal("should not pull")
 	}
 }
+
+func TestConfigPrepare_runtime(t *testing.T) {
+	raw := testConfig()
+
+	// Default
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.Runtime != RuntimeDocker {
+		t.Fatalf("bad: %s", c.Runtime)
+	}
+
+	// Good
+	raw["runtime"] = "podman"
+	c, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.Runtime != RuntimePodman {
+		t.Fatalf("bad: %s", c.Runtime)
+	}
+
+	// Bad
+	raw["runtime"] = "containerd"
+	_, warns, errs = NewConfig(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_commitOptions(t *testing.T) {
+	raw := testConfig()
+
+	// Not committed
+	raw["squash"] = true
+	_, warns, errs := NewConfig(raw)
+	testConfigErr(t, warns, errs)
+
+	// Committed
+	delete(raw, "export_path")
+	raw["commit"] = true
+	raw["labels"] = map[string]string{"maintainer": "ops"}
+	raw["healthcheck"] = map[string]interface{}{
+		"test":     []string{"CMD", "/healthcheck"},
+		"interval": "10s",
+	}
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.Healthcheck.Interval != 10*time.Second {
+		t.Fatalf("bad: %s", c.Healthcheck.Interval)
+	}
+
+	// Bad change
+	raw["changes"] = []string{"RUN rm -rf /"}
+	_, warns, errs = NewConfig(raw)
+	testConfigErr(t, warns, errs)
+}
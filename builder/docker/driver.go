@@ -1,11 +1,44 @@
 package docker
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/packer"
 )
 
+// The container runtimes the driver can run, whose command line interfaces
+// are compatible.
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// CheckRuntime returns an error unless the runtime is one the driver can
+// run, or empty for the default.
+func CheckRuntime(runtime string) error {
+	switch runtime {
+	case "", RuntimeDocker, RuntimePodman:
+		return nil
+	default:
+		return fmt.Errorf("runtime must be %s or %s, not %q", RuntimeDocker, RuntimePodman, runtime)
+	}
+}
+
+// ArtifactRuntime returns the runtime, or unless it is set the runtime the
+// artifact was built with, so that post-processors run the same runtime as
+// the builder.
+func ArtifactRuntime(artifact packer.Artifact, runtime string) string {
+	if runtime != "" {
+		return runtime
+	}
+	if runtime, ok := artifact.State("runtime").(string); ok {
+		return runtime
+	}
+	return ""
+}
+
 // Driver is the interface that has to be implemented to communicate with
 // Docker. The Driver interface also allows the steps to be tested since
 // a mock driver can be shimmed in.
@@ -19,6 +52,13 @@ type Driver interface {
 	// Export exports the container with the given ID to the given writer.
 	Export(id string, dst io.Writer) error
 
+	// InspectImage returns the configuration of the image with the given ID.
+	InspectImage(id string) (*ImageConfig, error)
+
+	// ImageExists returns whether the image with the given name or ID is
+	// in Docker.
+	ImageExists(image string) (bool, error)
+
 	// Import imports a container from a tar file
 	Import(path, repo string) (string, error)
 
@@ -33,6 +73,9 @@ type Driver interface {
 	// Logout. This can only be called if Login succeeded.
 	Logout(repo string) error
 
+	// Platform returns the platform of the containers Docker runs.
+	Platform() (*Platform, error)
+
 	// Pull should pull down the given image.
 	Pull(image string) error
 
@@ -42,6 +85,11 @@ type Driver interface {
 	// Save an image with the given ID to the given writer.
 	SaveImage(id string, dst io.Writer) error
 
+	// SquashContainer imports the filesystem of the container with the
+	// given ID into an image with a single layer, with the given author,
+	// changes and message, and returns the ID of the image.
+	SquashContainer(id string, author string, changes []string, message string) (string, error)
+
 	// StartContainer starts a container and returns the ID for that container,
 	// along with a potential error.
 	StartContainer(*ContainerConfig) (string, error)
@@ -59,6 +107,16 @@ type Driver interface {
 	Version() (*version.Version, error)
 }
 
+// Platform is the platform of the containers Docker runs.
+type Platform struct {
+	// OS is the operating system of the containers, linux or windows.
+	OS string
+
+	// KernelVersion is the version of the kernel of the host, with the
+	// Windows build Windows images must be compatible with.
+	KernelVersion string
+}
+
 // ContainerConfig is the configuration used to start a container.
 type ContainerConfig struct {
 	Image      string
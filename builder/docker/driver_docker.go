@@ -2,6 +2,7 @@ package docker
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -21,12 +22,27 @@ type DockerDriver struct {
 	Ui  packer.Ui
 	Ctx *interpolate.Context
 
+	// The container runtime to run, RuntimeDocker or RuntimePodman. The
+	// default is RuntimeDocker.
+	Runtime string
+
 	l sync.Mutex
 }
 
+func (d *DockerDriver) executable() string {
+	if d.Runtime == "" {
+		return RuntimeDocker
+	}
+	return d.Runtime
+}
+
+func (d *DockerDriver) command(args ...string) *exec.Cmd {
+	return exec.Command(d.executable(), args...)
+}
+
 func (d *DockerDriver) DeleteImage(id string) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command("docker", "rmi", id)
+	cmd := d.command("rmi", id)
 	cmd.Stderr = &stderr
 
 	log.Printf("Deleting image: %s", id)
@@ -60,7 +76,7 @@ func (d *DockerDriver) Commit(id string, author string, changes []string, messag
 	args = append(args, id)
 
 	log.Printf("Committing container with args: %v", args)
-	cmd := exec.Command("docker", args...)
+	cmd := d.command(args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
@@ -79,7 +95,7 @@ func (d *DockerDriver) Commit(id string, author string, changes []string, messag
 
 func (d *DockerDriver) Export(id string, dst io.Writer) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command("docker", "export", id)
+	cmd := d.command("export", id)
 	cmd.Stdout = dst
 	cmd.Stderr = &stderr
 
@@ -99,7 +115,7 @@ func (d *DockerDriver) Export(id string, dst io.Writer) error {
 
 func (d *DockerDriver) Import(path string, repo string) (string, error) {
 	var stdout, stderr bytes.Buffer
-	cmd := exec.Command("docker", "import", "-", repo)
+	cmd := d.command("import", "-", repo)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	stdin, err := cmd.StdinPipe()
@@ -130,10 +146,39 @@ func (d *DockerDriver) Import(path string, repo string) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+func (d *DockerDriver) InspectImage(id string) (*ImageConfig, error) {
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("inspect", "--type=image", "--format", "{{ json .Config }}", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error inspecting image: %s\nStderr: %s", err, stderr.String())
+	}
+
+	var config ImageConfig
+	if err := json.Unmarshal(stdout.Bytes(), &config); err != nil {
+		return nil, fmt.Errorf("Error reading the configuration of image %s: %s", id, err)
+	}
+	return &config, nil
+}
+
+func (d *DockerDriver) ImageExists(image string) (bool, error) {
+	var stderr bytes.Buffer
+	cmd := d.command("inspect", "--type=image", "--format", "{{ .Id }}", image)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(strings.ToLower(stderr.String()), "no such") {
+			return false, nil
+		}
+		return false, fmt.Errorf("Error inspecting image: %s\nStderr: %s", err, stderr.String())
+	}
+
+	return true, nil
+}
+
 func (d *DockerDriver) IPAddress(id string) (string, error) {
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		"docker",
+	cmd := d.command(
 		"inspect",
 		"--format",
 		"{{ .NetworkSettings.IPAddress }}",
@@ -160,13 +205,14 @@ func (d *DockerDriver) Login(repo, user, pass string) error {
 	// `--password-stdin` option which can be used to offer
 	// password via the standard input, rather than passing
 	// the password and/or token using a command line switch.
+	// Podman has always supported it.
 	constraint, err := version.NewConstraint(">= 17.07.0")
 	if err != nil {
 		d.l.Unlock()
 		return err
 	}
 
-	cmd := exec.Command("docker")
+	cmd := d.command()
 	cmd.Args = append(cmd.Args, "login")
 
 	if user != "" {
@@ -174,7 +220,7 @@ func (d *DockerDriver) Login(repo, user, pass string) error {
 	}
 
 	if pass != "" {
-		if constraint.Check(version_running) {
+		if d.Runtime == RuntimePodman || constraint.Check(version_running) {
 			cmd.Args = append(cmd.Args, "--password-stdin")
 
 			stdin, err := cmd.StdinPipe()
@@ -208,25 +254,48 @@ func (d *DockerDriver) Logout(repo string) error {
 		args = append(args, repo)
 	}
 
-	cmd := exec.Command("docker", args...)
+	cmd := d.command(args...)
 	err := runAndStream(cmd, d.Ui)
 	d.l.Unlock()
 	return err
 }
 
+func (d *DockerDriver) Platform() (*Platform, error) {
+	// Podman reports the platform of the host, whose containers it runs
+	format := "{{ .OSType }} {{ .KernelVersion }}"
+	if d.Runtime == RuntimePodman {
+		format = "{{ .Host.OS }} {{ .Host.Kernel }}"
+	}
+
+	var stderr, stdout bytes.Buffer
+	cmd := d.command("info", "--format", format)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error reading the %s platform: %s\nStderr: %s", d.executable(), err, stderr.String())
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(stdout.String()), " ", 2)
+	p := &Platform{OS: parts[0]}
+	if len(parts) > 1 {
+		p.KernelVersion = parts[1]
+	}
+	return p, nil
+}
+
 func (d *DockerDriver) Pull(image string) error {
-	cmd := exec.Command("docker", "pull", image)
+	cmd := d.command("pull", image)
 	return runAndStream(cmd, d.Ui)
 }
 
 func (d *DockerDriver) Push(name string) error {
-	cmd := exec.Command("docker", "push", name)
+	cmd := d.command("push", name)
 	return runAndStream(cmd, d.Ui)
 }
 
 func (d *DockerDriver) SaveImage(id string, dst io.Writer) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command("docker", "save", id)
+	cmd := d.command("save", id)
 	cmd.Stdout = dst
 	cmd.Stderr = &stderr
 
@@ -244,6 +313,71 @@ func (d *DockerDriver) SaveImage(id string, dst io.Writer) error {
 	return nil
 }
 
+func (d *DockerDriver) SquashContainer(id string, author string, changes []string, message string) (string, error) {
+	var stdout, exportStderr, importStderr bytes.Buffer
+
+	args := []string{"import"}
+	for _, change := range changes {
+		args = append(args, "--change", change)
+	}
+	if message != "" {
+		args = append(args, "--message", message)
+	}
+	args = append(args, "-")
+
+	export := d.command("export", id)
+	export.Stderr = &exportStderr
+	imp := d.command(args...)
+	imp.Stdout = &stdout
+	imp.Stderr = &importStderr
+
+	pipe, err := export.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	imp.Stdin = pipe
+
+	log.Printf("Squashing container %s with args: %v", id, args)
+	if err := export.Start(); err != nil {
+		return "", err
+	}
+	if err := imp.Start(); err != nil {
+		export.Process.Kill()
+		export.Wait()
+		return "", err
+	}
+
+	exportErr := export.Wait()
+	importErr := imp.Wait()
+	if exportErr != nil {
+		return "", fmt.Errorf("Error exporting container: %s\nStderr: %s", exportErr, exportStderr.String())
+	}
+	if importErr != nil {
+		return "", fmt.Errorf("Error importing container: %s\nStderr: %s", importErr, importStderr.String())
+	}
+
+	imageId := strings.TrimSpace(stdout.String())
+	if author == "" {
+		return imageId, nil
+	}
+
+	// Import can't set the author, a commit of a container of the image
+	// does. The container needs a command, so the image a CMD or an
+	// ENTRYPOINT.
+	var createStdout, createStderr bytes.Buffer
+	create := d.command("create", imageId)
+	create.Stdout = &createStdout
+	create.Stderr = &createStderr
+	if err := create.Run(); err != nil {
+		d.DeleteImage(imageId)
+		return "", fmt.Errorf("Error creating a container of the squashed image to set its author: %s\nStderr: %s", err, createStderr.String())
+	}
+	containerId := strings.TrimSpace(createStdout.String())
+	defer d.command("rm", containerId).Run()
+
+	return d.Commit(containerId, author, nil, "")
+}
+
 func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 	// Build up the template data
 	var tplData startContainerTemplate
@@ -277,7 +411,7 @@ func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 
 	// Start the container
 	var stdout, stderr bytes.Buffer
-	cmd := exec.Command("docker", args...)
+	cmd := d.command(args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
@@ -301,11 +435,11 @@ func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 }
 
 func (d *DockerDriver) StopContainer(id string) error {
-	if err := exec.Command("docker", "kill", id).Run(); err != nil {
+	if err := d.command("kill", id).Run(); err != nil {
 		return err
 	}
 
-	return exec.Command("docker", "rm", id).Run()
+	return d.command("rm", id).Run()
 }
 
 func (d *DockerDriver) TagImage(id string, repo string, force bool) error {
@@ -342,7 +476,7 @@ func (d *DockerDriver) TagImage(id string, repo string, force bool) error {
 	args = append(args, id, repo)
 
 	var stderr bytes.Buffer
-	cmd := exec.Command("docker", args...)
+	cmd := d.command(args...)
 	cmd.Stderr = &stderr
 
 	if err := cmd.Start(); err != nil {
@@ -359,7 +493,7 @@ func (d *DockerDriver) TagImage(id string, repo string, force bool) error {
 }
 
 func (d *DockerDriver) Verify() error {
-	if _, err := exec.LookPath("docker"); err != nil {
+	if _, err := exec.LookPath(d.executable()); err != nil {
 		return err
 	}
 
@@ -367,7 +501,7 @@ func (d *DockerDriver) Verify() error {
 }
 
 func (d *DockerDriver) Version() (*version.Version, error) {
-	output, err := exec.Command("docker", "-v").Output()
+	output, err := d.command("-v").Output()
 	if err != nil {
 		return nil, err
 	}
@@ -5,3 +5,15 @@ import "testing"
 func TestDockerDriver_impl(t *testing.T) {
 	var _ Driver = new(DockerDriver)
 }
+
+func TestDockerDriver_command(t *testing.T) {
+	d := new(DockerDriver)
+	if args := d.command("ps").Args; args[0] != "docker" || args[1] != "ps" {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	d.Runtime = RuntimePodman
+	if args := d.command("ps").Args; args[0] != "podman" {
+		t.Fatalf("bad: %#v", args)
+	}
+}
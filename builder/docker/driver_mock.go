@@ -17,6 +17,15 @@ type MockDriver struct {
 	DeleteImageId     string
 	DeleteImageErr    error
 
+	InspectImageCalled bool
+	InspectImageId     string
+	InspectImageResult *ImageConfig
+	InspectImageErr    error
+
+	ImageExistsImages []string
+	ImageExistsResult map[string]bool
+	ImageExistsErr    error
+
 	ImportCalled bool
 	ImportPath   string
 	ImportRepo   string
@@ -38,10 +47,21 @@ type MockDriver struct {
 	LogoutRepo   string
 	LogoutErr    error
 
+	PlatformCalled bool
+	PlatformResult *Platform
+	PlatformErr    error
+
 	PushCalled bool
 	PushName   string
 	PushErr    error
 
+	SquashCalled      bool
+	SquashContainerId string
+	SquashAuthor      string
+	SquashChanges     []string
+	SquashImageId     string
+	SquashErr         error
+
 	SaveImageCalled bool
 	SaveImageId     string
 	SaveImageReader io.Reader
@@ -101,6 +121,20 @@ func (d *MockDriver) Export(id string, dst io.Writer) error {
 	return d.ExportError
 }
 
+func (d *MockDriver) InspectImage(id string) (*ImageConfig, error) {
+	d.InspectImageCalled = true
+	d.InspectImageId = id
+	if d.InspectImageResult == nil && d.InspectImageErr == nil {
+		return new(ImageConfig), nil
+	}
+	return d.InspectImageResult, d.InspectImageErr
+}
+
+func (d *MockDriver) ImageExists(image string) (bool, error) {
+	d.ImageExistsImages = append(d.ImageExistsImages, image)
+	return d.ImageExistsResult[image], d.ImageExistsErr
+}
+
 func (d *MockDriver) Import(path, repo string) (string, error) {
 	d.ImportCalled = true
 	d.ImportPath = path
@@ -128,6 +162,14 @@ func (d *MockDriver) Logout(r string) error {
 	return d.LogoutErr
 }
 
+func (d *MockDriver) Platform() (*Platform, error) {
+	d.PlatformCalled = true
+	if d.PlatformResult == nil && d.PlatformErr == nil {
+		return &Platform{OS: "linux"}, nil
+	}
+	return d.PlatformResult, d.PlatformErr
+}
+
 func (d *MockDriver) Pull(image string) error {
 	d.PullCalled = true
 	d.PullImage = image
@@ -154,6 +196,14 @@ func (d *MockDriver) SaveImage(id string, dst io.Writer) error {
 	return d.SaveImageError
 }
 
+func (d *MockDriver) SquashContainer(id string, author string, changes []string, message string) (string, error) {
+	d.SquashCalled = true
+	d.SquashContainerId = id
+	d.SquashAuthor = author
+	d.SquashChanges = changes
+	return d.SquashImageId, d.SquashErr
+}
+
 func (d *MockDriver) StartContainer(config *ContainerConfig) (string, error) {
 	d.StartCalled = true
 	d.StartConfig = config
@@ -0,0 +1,77 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCheckpoint looks for the checkpoint image of the last provisioner
+// whose configuration, and the configuration before it, is unchanged, for
+// the container to start from. The provisioners after it run, and the
+// container is committed to a new checkpoint image after each of them.
+//
+// Produces:
+//   checkpoint_image      string - The image to start from, if any.
+//   provision_checkpoints *common.ProvisionCheckpoints
+type StepCheckpoint struct{}
+
+func (s *StepCheckpoint) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	keys := config.PackerProvisionerKeys
+	if len(keys) == 0 {
+		return multistep.ActionContinue
+	}
+
+	image := func(key string) string {
+		return config.CheckpointRepository + ":" + key
+	}
+
+	ui.Say("Looking for checkpoint images...")
+	start, err := common.LastCheckpoint(keys, func(key string) (bool, error) {
+		return driver.ImageExists(image(key))
+	})
+	if err != nil {
+		err := fmt.Errorf("Error looking for checkpoint images: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if start > 0 {
+		checkpoint := image(keys[start-1])
+		ui.Message(fmt.Sprintf(
+			"Starting from checkpoint image %s, skipping %d of %d provisioners",
+			checkpoint, start, len(keys)))
+		state.Put("checkpoint_image", checkpoint)
+	} else {
+		ui.Message("No checkpoint images, running all the provisioners")
+	}
+
+	state.Put("provision_checkpoints", &common.ProvisionCheckpoints{
+		Keys:  keys,
+		Start: start,
+		Save: func(ui packer.Ui, key string) error {
+			containerId := state.Get("container_id").(string)
+
+			ui.Say(fmt.Sprintf("Committing the container to checkpoint image %s", image(key)))
+			imageId, err := driver.Commit(containerId, config.Author, nil, "")
+			if err != nil {
+				return fmt.Errorf("Error committing the checkpoint image: %s", err)
+			}
+			if err := driver.TagImage(imageId, image(key), true); err != nil {
+				return fmt.Errorf("Error tagging the checkpoint image: %s", err)
+			}
+			return nil
+		},
+	})
+	return multistep.ActionContinue
+}
+
+func (s *StepCheckpoint) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestStepCheckpoint_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCheckpoint)
+}
+
+func TestStepCheckpoint(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckpoint)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PackerProvisionerKeys = []string{"a", "", "c", "d"}
+	config.CheckpointRepository = "checkpoints"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsResult = map[string]bool{"checkpoints:c": true}
+	driver.CommitImageId = "sha256:abc"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if !reflect.DeepEqual(driver.ImageExistsImages, []string{"checkpoints:d", "checkpoints:c"}) {
+		t.Fatalf("bad: %#v", driver.ImageExistsImages)
+	}
+	if image := state.Get("checkpoint_image"); image != "checkpoints:c" {
+		t.Fatalf("bad: %#v", image)
+	}
+
+	checkpoints := state.Get("provision_checkpoints").(*common.ProvisionCheckpoints)
+	if checkpoints.Start != 3 {
+		t.Fatalf("bad: %d", checkpoints.Start)
+	}
+
+	state.Put("container_id", "foo")
+	if err := checkpoints.Save(state.Get("ui").(packer.Ui), "d"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if driver.CommitContainerId != "foo" {
+		t.Fatalf("bad: %#v", driver.CommitContainerId)
+	}
+	if driver.TagImageImageId != "sha256:abc" || driver.TagImageRepo != "checkpoints:d" {
+		t.Fatalf("bad: %#v %#v", driver.TagImageImageId, driver.TagImageRepo)
+	}
+
+	// The container starts from the checkpoint, which isn't pulled
+	pull := new(StepPull)
+	if action := pull.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.PullCalled {
+		t.Fatal("shouldn't pull")
+	}
+}
+
+func TestStepCheckpoint_error(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckpoint)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PackerProvisionerKeys = []string{"a"}
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsErr = errors.New("foo")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
@@ -3,12 +3,16 @@ package docker
 import (
 	"context"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
 
-// StepCommit commits the container to a image.
+// StepCommit commits the container to a image. A squashed image is
+// imported from the filesystem of the container instead, with the
+// configuration of the commit, which is removed.
 type StepCommit struct {
 	imageId string
 }
@@ -20,13 +24,24 @@ func (s *StepCommit) Run(_ context.Context, state multistep.StateBag) multistep.
 	ui := state.Get("ui").(packer.Ui)
 
 	ui.Say("Committing the container")
-	imageId, err := driver.Commit(containerId, config.Author, config.Changes, config.Message)
+	imageId, err := driver.Commit(containerId, config.Author, config.commitChanges(time.Now()), config.Message)
 	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
+	if config.Squash {
+		ui.Say("Squashing the image")
+		squashedId, err := s.squash(driver, ui, containerId, imageId, config.Author, config.Message)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		imageId = squashedId
+	}
+
 	// Save the container ID
 	s.imageId = imageId
 	state.Put("image_id", s.imageId)
@@ -35,4 +50,29 @@ func (s *StepCommit) Run(_ context.Context, state multistep.StateBag) multistep.
 	return multistep.ActionContinue
 }
 
+func (s *StepCommit) squash(driver Driver, ui packer.Ui, containerId string, commitId string, author string, message string) (string, error) {
+	imageConfig, err := driver.InspectImage(commitId)
+	if err != nil {
+		return "", err
+	}
+
+	// The author is set by a commit of a container of the squashed image,
+	// and Docker can't create one without a command.
+	if author != "" && len(imageConfig.Cmd) == 0 && len(imageConfig.Entrypoint) == 0 {
+		ui.Error("Warning: the image has no CMD or ENTRYPOINT, so the author " +
+			"of the squashed image can't be set. Set one with `changes` to keep it.")
+		author = ""
+	}
+
+	imageId, err := driver.SquashContainer(containerId, author, imageConfig.changes(), message)
+	if err != nil {
+		return "", fmt.Errorf("Error squashing the image: %s", err)
+	}
+
+	if err := driver.DeleteImage(commitId); err != nil {
+		log.Printf("[WARN] Error removing the commit %s of the squashed image: %s", commitId, err)
+	}
+	return imageId, nil
+}
+
 func (s *StepCommit) Cleanup(state multistep.StateBag) {}
@@ -3,6 +3,7 @@ package docker
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/packer/helper/multistep"
@@ -66,3 +67,68 @@ func TestStepCommit_error(t *testing.T) {
 		t.Fatal("shouldn't save image ID")
 	}
 }
+
+func TestStepCommit_squash(t *testing.T) {
+	state := testStepCommitState(t)
+	step := new(StepCommit)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Squash = true
+	config.Author = "Packer <packer@example.com>"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.CommitImageId = "bar"
+	driver.InspectImageResult = &ImageConfig{Cmd: []string{"/bin/sh"}}
+	driver.SquashImageId = "baz"
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the container is squashed with the configuration of the commit
+	if driver.InspectImageId != "bar" {
+		t.Fatalf("bad: %#v", driver.InspectImageId)
+	}
+	if driver.SquashContainerId != "foo" || !reflect.DeepEqual(driver.SquashChanges, []string{`CMD ["/bin/sh"]`}) {
+		t.Fatalf("bad: %#v %#v", driver.SquashContainerId, driver.SquashChanges)
+	}
+	if driver.SquashAuthor != config.Author {
+		t.Fatalf("bad: %#v", driver.SquashAuthor)
+	}
+	if !driver.DeleteImageCalled || driver.DeleteImageId != "bar" {
+		t.Fatal("should remove the commit")
+	}
+	if id := state.Get("image_id"); id != "baz" {
+		t.Fatalf("bad: %#v", id)
+	}
+}
+
+func TestStepCommit_squashAuthorNoCommand(t *testing.T) {
+	state := testStepCommitState(t)
+	step := new(StepCommit)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Squash = true
+	config.Author = "Packer <packer@example.com>"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.CommitImageId = "bar"
+	driver.InspectImageResult = &ImageConfig{}
+	driver.SquashImageId = "baz"
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the image is squashed without the author, which can't be set
+	if !driver.SquashCalled || driver.SquashAuthor != "" {
+		t.Fatalf("bad: %#v", driver.SquashAuthor)
+	}
+	if id := state.Get("image_id"); id != "baz" {
+		t.Fatalf("bad: %#v", id)
+	}
+}
@@ -24,7 +24,22 @@ func (s *StepConnectDocker) Run(_ context.Context, state multistep.StateBag) mul
 		return multistep.ActionHalt
 	}
 
-	containerUser, err := getContainerUser(containerId)
+	// Windows containers run PowerShell, and files are copied through
+	// the directory of the host mounted in the container
+	if config.WindowsContainer {
+		comm := &WindowsContainerCommunicator{Communicator{
+			ContainerID:  containerId,
+			HostDir:      tempDir,
+			ContainerDir: config.ContainerDir,
+			Version:      version,
+			Config:       config,
+		}}
+
+		state.Put("communicator", comm)
+		return multistep.ActionContinue
+	}
+
+	containerUser, err := getContainerUser(config.Runtime, containerId)
 	if err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
@@ -47,8 +62,8 @@ func (s *StepConnectDocker) Run(_ context.Context, state multistep.StateBag) mul
 
 func (s *StepConnectDocker) Cleanup(state multistep.StateBag) {}
 
-func getContainerUser(containerId string) (string, error) {
-	inspectArgs := []string{"docker", "inspect", "--format", "{{.Config.User}}", containerId}
+func getContainerUser(runtime string, containerId string) (string, error) {
+	inspectArgs := []string{runtime, "inspect", "--format", "{{.Config.User}}", containerId}
 	stdout, err := exec.Command(inspectArgs[0], inspectArgs[1:]...).Output()
 	if err != nil {
 		errStr := fmt.Sprintf("Failed to inspect the container: %s", err)
@@ -21,6 +21,11 @@ func (s *StepPull) Run(_ context.Context, state multistep.StateBag) multistep.St
 		return multistep.ActionContinue
 	}
 
+	if _, ok := state.GetOk("checkpoint_image"); ok {
+		log.Println("Starting from a checkpoint image, won't docker pull")
+		return multistep.ActionContinue
+	}
+
 	ui.Say(fmt.Sprintf("Pulling Docker image: %s", config.Image))
 
 	if config.EcrLogin {
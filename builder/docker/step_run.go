@@ -18,8 +18,15 @@ func (s *StepRun) Run(_ context.Context, state multistep.StateBag) multistep.Ste
 	tempDir := state.Get("temp_dir").(string)
 	ui := state.Get("ui").(packer.Ui)
 
+	// Start from the checkpoint image of the last provisioner that is
+	// unchanged, if there is one
+	image := config.Image
+	if checkpoint, ok := state.GetOk("checkpoint_image"); ok {
+		image = checkpoint.(string)
+	}
+
 	runConfig := ContainerConfig{
-		Image:      config.Image,
+		Image:      image,
 		RunCommand: config.RunCommand,
 		Volumes:    make(map[string]string),
 		Privileged: config.Privileged,
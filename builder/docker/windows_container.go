@@ -0,0 +1,55 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// windowsImageTags are the tags of the Windows base images for the builds
+// of Windows they are compatible with, as the images of process isolated
+// containers must be of the build of the host.
+var windowsImageTags = map[int]string{
+	14393: "ltsc2016",
+	17763: "1809",
+	18362: "1903",
+	18363: "1909",
+	19041: "2004",
+	19042: "20H2",
+	20348: "ltsc2022",
+}
+
+// windowsBuild returns the Windows build of the kernel version Docker
+// reports, like "10.0 17763 (17763.1.amd64fre.rs5_release.180914-1434)".
+func windowsBuild(kernelVersion string) (int, error) {
+	fields := strings.Fields(kernelVersion)
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("Unknown Windows kernel version: %q", kernelVersion)
+	}
+
+	build, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, fmt.Errorf("Unknown Windows kernel version: %q", kernelVersion)
+	}
+	return build, nil
+}
+
+// windowsImage returns the image, tagged for the Windows build of the host
+// unless it has a tag or a digest already.
+func windowsImage(image string, kernelVersion string) (string, error) {
+	name := image[strings.LastIndex(image, "/")+1:]
+	if strings.ContainsAny(name, ":@") {
+		return image, nil
+	}
+
+	build, err := windowsBuild(kernelVersion)
+	if err != nil {
+		return "", err
+	}
+	tag, ok := windowsImageTags[build]
+	if !ok {
+		return "", fmt.Errorf(
+			"No known image tag for the Windows build %d of the host, the image must have a tag", build)
+	}
+	return image + ":" + tag, nil
+}
@@ -0,0 +1,205 @@
+package docker
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// WindowsContainerCommunicator is the communicator of Windows containers,
+// which runs commands with PowerShell. Files can't be copied in and out of
+// running Windows containers with docker cp, so they are copied through
+// the directory of the host mounted in the container instead.
+type WindowsContainerCommunicator struct {
+	Communicator
+}
+
+func (c *WindowsContainerCommunicator) Start(remote *packer.RemoteCmd) error {
+	dockerArgs := []string{"exec", "-i"}
+	if c.Config.Pty {
+		dockerArgs = append(dockerArgs, "-t")
+	}
+	if c.Config.ExecUser != "" {
+		dockerArgs = append(dockerArgs, "-u", c.Config.ExecUser)
+	}
+	dockerArgs = append(dockerArgs, c.ContainerID, "powershell", fmt.Sprintf("(%s)", remote.Command))
+
+	cmd := c.command(dockerArgs...)
+
+	stdin_w, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr_r, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	stdout_r, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	// Run the actual command in a goroutine so that Start doesn't block
+	go c.run(cmd, remote, stdin_w, stdout_r, stderr_r)
+
+	return nil
+}
+
+// Upload writes the file to the host directory, and copies it from there
+// to its destination in the container.
+func (c *WindowsContainerCommunicator) Upload(dst string, src io.Reader, fi *os.FileInfo) error {
+	tempfile, err := ioutil.TempFile(c.HostDir, "upload")
+	if err != nil {
+		return fmt.Errorf("Failed to open temp file for writing: %s", err)
+	}
+	defer os.Remove(tempfile.Name())
+	defer tempfile.Close()
+
+	if _, err := io.Copy(tempfile, src); err != nil {
+		return fmt.Errorf("Failed to copy upload file to tempfile: %s", err)
+	}
+	if err := tempfile.Close(); err != nil {
+		return fmt.Errorf("Failed to close tempfile: %s", err)
+	}
+
+	log.Printf("Copying to %s on container %s.", dst, c.ContainerID)
+	script := fmt.Sprintf("Copy-Item -Path %s -Destination %s -Force",
+		psQuote(c.containerPath(tempfile.Name())), psQuote(dst))
+	if err := c.runPowershell(script); err != nil {
+		return fmt.Errorf("Failed to upload to '%s' in container: %s", dst, err)
+	}
+	return nil
+}
+
+// UploadDir copies the directory to the host directory, and from there to
+// its destination in the container, with the semantics of docker cp: the
+// contents of a source ending with a slash are copied in the destination.
+func (c *WindowsContainerCommunicator) UploadDir(dst string, src string, exclude []string) error {
+	tempdir, err := ioutil.TempDir(c.HostDir, "upload")
+	if err != nil {
+		return fmt.Errorf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	local := filepath.Join(tempdir, filepath.Base(src))
+	if err := copyDir(src, local); err != nil {
+		return fmt.Errorf("Failed to copy '%s' to the host directory: %s", src, err)
+	}
+
+	var script string
+	if strings.HasSuffix(src, "/") || strings.HasSuffix(src, "\\") {
+		script = fmt.Sprintf(
+			"New-Item -ItemType Directory -Force -Path %s | Out-Null; Copy-Item -Path %s -Destination %s -Recurse -Force",
+			psQuote(dst), psQuote(c.containerPath(local)+"/*"), psQuote(dst))
+	} else {
+		script = fmt.Sprintf("Copy-Item -Path %s -Destination %s -Recurse -Force",
+			psQuote(c.containerPath(local)), psQuote(dst))
+	}
+	if err := c.runPowershell(script); err != nil {
+		return fmt.Errorf("Failed to upload to '%s' in container: %s", dst, err)
+	}
+	return nil
+}
+
+// Download copies the file to the host directory in the container, and
+// reads it from there.
+func (c *WindowsContainerCommunicator) Download(src string, dst io.Writer) error {
+	tempfile, err := ioutil.TempFile(c.HostDir, "download")
+	if err != nil {
+		return fmt.Errorf("Failed to open temp file: %s", err)
+	}
+	tempfile.Close()
+	defer os.Remove(tempfile.Name())
+
+	log.Printf("Downloading file from container: %s:%s", c.ContainerID, src)
+	script := fmt.Sprintf("Copy-Item -Path %s -Destination %s -Force",
+		psQuote(src), psQuote(c.containerPath(tempfile.Name())))
+	if err := c.runPowershell(script); err != nil {
+		return fmt.Errorf("Failed to download '%s' from container: %s", src, err)
+	}
+
+	f, err := os.Open(tempfile.Name())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	numBytes, err := io.Copy(dst, f)
+	if err != nil {
+		return fmt.Errorf("Failed to pipe download: %s", err)
+	}
+	log.Printf("Copied %d bytes for %s", numBytes, src)
+	return nil
+}
+
+// containerPath is the path in the container of a path of the host
+// directory.
+func (c *WindowsContainerCommunicator) containerPath(hostPath string) string {
+	rel, err := filepath.Rel(c.HostDir, hostPath)
+	if err != nil {
+		rel = filepath.Base(hostPath)
+	}
+	return c.ContainerDir + "/" + filepath.ToSlash(rel)
+}
+
+// runPowershell runs the script in the container, and waits for it.
+func (c *WindowsContainerCommunicator) runPowershell(script string) error {
+	args := []string{"exec", c.ContainerID, "powershell", "-Command", script}
+	log.Printf("Executing %s", strings.Join(args, " "))
+	if output, err := c.command(args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, output)
+	}
+	return nil
+}
+
+// psQuote quotes the string for PowerShell.
+func psQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// copyDir copies the tree of the directory to dst.
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			log.Printf("Skipping %s, which isn't a regular file", path)
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
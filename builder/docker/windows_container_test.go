@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestWindowsContainerCommunicator_impl(t *testing.T) {
+	var _ packer.Communicator = new(WindowsContainerCommunicator)
+}
+
+func TestWindowsImage(t *testing.T) {
+	const ltsc2019 = "10.0 17763 (17763.1.amd64fre.rs5_release.180914-1434)"
+
+	cases := []struct {
+		image    string
+		kernel   string
+		expected string
+	}{
+		{"mcr.microsoft.com/windows/servercore", ltsc2019, "mcr.microsoft.com/windows/servercore:1809"},
+		{"mcr.microsoft.com/windows/servercore:ltsc2022", ltsc2019, "mcr.microsoft.com/windows/servercore:ltsc2022"},
+		{"localhost:5000/base@sha256:abc", ltsc2019, "localhost:5000/base@sha256:abc"},
+		{"localhost:5000/base", "10.0 20348 (20348.1.amd64fre.fe_release.210507-1500)", "localhost:5000/base:ltsc2022"},
+	}
+	for _, tc := range cases {
+		image, err := windowsImage(tc.image, tc.kernel)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.image, err)
+		}
+		if image != tc.expected {
+			t.Fatalf("bad: %s, expected %s", image, tc.expected)
+		}
+	}
+
+	// Bad
+	if _, err := windowsImage("base", "10.0 99999 (99999.1)"); err == nil {
+		t.Fatal("should error with an unknown build")
+	}
+	if _, err := windowsImage("base", "4.19.0"); err == nil {
+		t.Fatal("should error with a kernel version that isn't of Windows")
+	}
+}
+
+func TestConfigUsePlatform(t *testing.T) {
+	raw := testConfig()
+	delete(raw, "export_path")
+	raw["commit"] = true
+	raw["image"] = "mcr.microsoft.com/windows/nanoserver"
+
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.ContainerDir != "/packer-files" {
+		t.Fatalf("bad: %s", c.ContainerDir)
+	}
+
+	err := c.usePlatform(&Platform{OS: "windows", KernelVersion: "10.0 17763 (17763.1)"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !c.WindowsContainer {
+		t.Fatal("should detect a Windows container")
+	}
+	if c.ContainerDir != "c:/packer-files" {
+		t.Fatalf("bad: %s", c.ContainerDir)
+	}
+	if c.RunCommand[3] != "--entrypoint=powershell" {
+		t.Fatalf("bad: %#v", c.RunCommand)
+	}
+	if c.Image != "mcr.microsoft.com/windows/nanoserver:1809" {
+		t.Fatalf("bad: %s", c.Image)
+	}
+
+	// Forced, on a Linux platform
+	raw["windows_container"] = true
+	c, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if err := c.usePlatform(&Platform{OS: "linux"}); err == nil {
+		t.Fatal("should error")
+	}
+
+	// Forced off, on a Windows platform
+	raw["windows_container"] = false
+	c, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if err := c.usePlatform(&Platform{OS: "windows", KernelVersion: "10.0 17763"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.WindowsContainer {
+		t.Fatal("shouldn't be a Windows container")
+	}
+
+	// Windows containers can't be exported
+	raw = testConfig()
+	raw["windows_container"] = true
+	_, warns, errs = NewConfig(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestWindowsContainerCommunicator_containerPath(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	comm := &WindowsContainerCommunicator{Communicator{HostDir: td, ContainerDir: "c:/packer-files"}}
+	path := comm.containerPath(filepath.Join(td, "upload123", "scripts"))
+	if path != "c:/packer-files/upload123/scripts" {
+		t.Fatalf("bad: %s", path)
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	src := filepath.Join(td, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "sub", "file"), []byte("foo"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dst := filepath.Join(td, "dst")
+	if err := copyDir(src, dst); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dst, "sub", "file"))
+	if err != nil || string(contents) != "foo" {
+		t.Fatalf("bad: %q %s", contents, err)
+	}
+}
@@ -0,0 +1,36 @@
+package equinixmetal
+
+import (
+	"fmt"
+	"os"
+)
+
+// Artifact is the custom image captured from the device.
+type Artifact struct {
+	dir   string
+	files []string
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return a.files
+}
+
+func (a *Artifact) Id() string {
+	return a.dir
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Custom image files in directory: %s", a.dir)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	return os.RemoveAll(a.dir)
+}
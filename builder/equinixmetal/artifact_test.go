@@ -0,0 +1,23 @@
+package equinixmetal
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestArtifactString(t *testing.T) {
+	a := &Artifact{dir: "output-foo", files: []string{"output-foo/image.tar.gz"}}
+	expected := "Custom image files in directory: output-foo"
+
+	if a.String() != expected {
+		t.Fatalf("artifact string should match: %v", expected)
+	}
+	if len(a.Files()) != 1 {
+		t.Fatalf("bad: %#v", a.Files())
+	}
+}
@@ -0,0 +1,93 @@
+// The equinixmetal package contains a packer.Builder implementation
+// that provisions Equinix Metal (formerly Packet) bare metal devices, and
+// captures them into custom images.
+
+package equinixmetal
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The unique id for the builder
+const BuilderId = "packer.equinix-metal"
+
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, errs := NewConfig(raws...)
+	if errs != nil {
+		return warnings, errs
+	}
+	b.config = c
+
+	return nil, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	client := &Client{
+		BaseURL:   b.config.APIURL,
+		AuthToken: b.config.AuthToken,
+	}
+
+	// Set up the state
+	state := new(multistep.BasicStateBag)
+	state.Put("config", b.config)
+	state.Put("client", client)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	// Build the steps
+	steps := []multistep.Step{
+		new(stepPrepareOutputDir),
+		&stepCreateSSHKey{
+			Debug:        b.config.PackerDebug,
+			DebugKeyPath: fmt.Sprintf("metal_%s.pem", b.config.PackerBuildName),
+		},
+		new(stepCreateDevice),
+		new(stepDeviceInfo),
+		&communicator.StepConnect{
+			Config:    &b.config.Comm,
+			Host:      commHost,
+			SSHConfig: sshConfig,
+		},
+		new(common.StepProvision),
+		new(stepCapture),
+	}
+
+	// Run the steps
+	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	if _, ok := state.GetOk("files"); !ok {
+		log.Println("Failed to find files in state. Bug?")
+		return nil, nil
+	}
+
+	artifact := &Artifact{
+		dir:   b.config.OutputDir,
+		files: state.Get("files").([]string),
+	}
+
+	return artifact, nil
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
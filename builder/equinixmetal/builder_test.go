@@ -0,0 +1,199 @@
+package equinixmetal
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"auth_token":       "bar",
+		"project_id":       "foo",
+		"metro":            "sv",
+		"plan":             "c3.small.x86",
+		"operating_system": "ubuntu_20_04",
+		"output_directory": "packer-equinix-metal-test-output",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare_InvalidKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Add a random key
+	config["i_should_not_be_valid"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.APIURL != DefaultAPIURL {
+		t.Errorf("bad api url: %s", b.config.APIURL)
+	}
+	if !strings.HasPrefix(b.config.Hostname, "packer-") {
+		t.Errorf("bad hostname: %s", b.config.Hostname)
+	}
+	if b.config.BillingCycle != "hourly" {
+		t.Errorf("bad billing cycle: %s", b.config.BillingCycle)
+	}
+	if b.config.StateTimeout != 20*time.Minute {
+		t.Errorf("bad state timeout: %s", b.config.StateTimeout)
+	}
+	if b.config.Comm.SSHUsername != "root" {
+		t.Errorf("bad ssh username: %s", b.config.Comm.SSHUsername)
+	}
+}
+
+func TestBuilderPrepare_Required(t *testing.T) {
+	for _, key := range []string{"auth_token", "project_id", "metro", "plan", "operating_system"} {
+		var b Builder
+		config := testConfig()
+		delete(config, key)
+
+		old := os.Getenv("METAL_AUTH_TOKEN")
+		os.Setenv("METAL_AUTH_TOKEN", "")
+		_, err := b.Prepare(config)
+		os.Setenv("METAL_AUTH_TOKEN", old)
+		if err == nil {
+			t.Fatalf("should have error without %s", key)
+		}
+	}
+}
+
+func TestBuilderPrepare_AuthTokenEnv(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	delete(config, "auth_token")
+
+	old := os.Getenv("METAL_AUTH_TOKEN")
+	os.Setenv("METAL_AUTH_TOKEN", "env")
+	defer os.Setenv("METAL_AUTH_TOKEN", old)
+
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.AuthToken != "env" {
+		t.Fatalf("bad: %s", b.config.AuthToken)
+	}
+}
+
+func TestBuilderPrepare_Facility(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	delete(config, "metro")
+	config["facility"] = "sjc1"
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Both is ambiguous
+	config["metro"] = "sv"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_IPXE(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["operating_system"] = "custom_ipxe"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without ipxe_script_url")
+	}
+
+	config["ipxe_script_url"] = "https://example.com/boot.ipxe"
+	config["always_pxe"] = true
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["operating_system"] = "ubuntu_20_04"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with ipxe_script_url on another operating system")
+	}
+}
+
+func TestBuilderPrepare_BillingCycle(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["billing_cycle"] = "weekly"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Communicator(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["communicator"] = "none"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_OutputDir(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config["output_directory"] = dir
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with an existing output directory")
+	}
+
+	config["packer_force"] = true
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestCaptureScript(t *testing.T) {
+	script := captureScriptFor([]string{"/var/cache/apt/", "home/*"})
+	for _, exclude := range []string{
+		"--exclude='./proc/*'",
+		"--exclude='./var/tmp/packer-capture'",
+		"--exclude='./var/cache/apt'",
+		"--exclude='./home/*'",
+	} {
+		if !strings.Contains(script, exclude) {
+			t.Errorf("should contain %s:\n%s", exclude, script)
+		}
+	}
+}
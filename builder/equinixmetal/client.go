@@ -0,0 +1,159 @@
+package equinixmetal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultAPIURL is the Equinix Metal API endpoint.
+const DefaultAPIURL = "https://api.equinix.com/metal/v1/"
+
+// NOTE: there is no Equinix Metal SDK vendored, so the few calls the builder
+// makes are done with the client here.
+
+// Client is a client of the Equinix Metal API.
+type Client struct {
+	BaseURL   string
+	AuthToken string
+
+	HTTPClient *http.Client
+}
+
+// Device is an Equinix Metal server.
+type Device struct {
+	ID          string      `json:"id"`
+	Hostname    string      `json:"hostname"`
+	State       string      `json:"state"`
+	IPAddresses []IPAddress `json:"ip_addresses"`
+}
+
+// IPAddress is an address assigned to a device.
+type IPAddress struct {
+	Address       string `json:"address"`
+	AddressFamily int    `json:"address_family"`
+	Public        bool   `json:"public"`
+}
+
+// PublicIPv4 returns the public IPv4 address of the device, if it has one.
+func (d *Device) PublicIPv4() string {
+	for _, ip := range d.IPAddresses {
+		if ip.Public && ip.AddressFamily == 4 {
+			return ip.Address
+		}
+	}
+	return ""
+}
+
+// SSHKey is an SSH key of a project, set up on the devices of the project
+// that are created with it.
+type SSHKey struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Key   string `json:"key"`
+}
+
+// DeviceCreateRequest is the request of a new device.
+type DeviceCreateRequest struct {
+	Hostname        string   `json:"hostname"`
+	Plan            string   `json:"plan"`
+	Metro           string   `json:"metro,omitempty"`
+	Facility        []string `json:"facility,omitempty"`
+	OperatingSystem string   `json:"operating_system"`
+	BillingCycle    string   `json:"billing_cycle"`
+	UserData        string   `json:"userdata,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	IPXEScriptURL   string   `json:"ipxe_script_url,omitempty"`
+	AlwaysPXE       bool     `json:"always_pxe,omitempty"`
+	ProjectSSHKeys  []string `json:"project_ssh_keys,omitempty"`
+}
+
+// ErrorResponse is the error returned by the API.
+type ErrorResponse struct {
+	StatusCode int
+	Errors     []string `json:"errors"`
+}
+
+func (e *ErrorResponse) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("Equinix Metal API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("Equinix Metal API error: %d %s", e.StatusCode, strings.Join(e.Errors, ", "))
+}
+
+// CreateSSHKey adds an SSH key to the project.
+func (c *Client) CreateSSHKey(projectID string, label string, key string) (*SSHKey, error) {
+	result := new(SSHKey)
+	err := c.do("POST", fmt.Sprintf("projects/%s/ssh-keys", projectID), &SSHKey{Label: label, Key: key}, result)
+	return result, err
+}
+
+// DeleteSSHKey deletes an SSH key.
+func (c *Client) DeleteSSHKey(id string) error {
+	return c.do("DELETE", fmt.Sprintf("ssh-keys/%s", id), nil, nil)
+}
+
+// CreateDevice provisions a device in the project.
+func (c *Client) CreateDevice(projectID string, req *DeviceCreateRequest) (*Device, error) {
+	result := new(Device)
+	err := c.do("POST", fmt.Sprintf("projects/%s/devices", projectID), req, result)
+	return result, err
+}
+
+// GetDevice returns a device.
+func (c *Client) GetDevice(id string) (*Device, error) {
+	result := new(Device)
+	err := c.do("GET", fmt.Sprintf("devices/%s", id), nil, result)
+	return result, err
+}
+
+// DeleteDevice deprovisions a device.
+func (c *Client) DeleteDevice(id string) error {
+	return c.do("DELETE", fmt.Sprintf("devices/%s", id), nil, nil)
+}
+
+func (c *Client) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.BaseURL, "/")+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", c.AuthToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &ErrorResponse{StatusCode: resp.StatusCode}
+		// The errors are best effort, the status is enough to fail
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
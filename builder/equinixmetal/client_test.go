@@ -0,0 +1,78 @@
+package equinixmetal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_CreateDevice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/projects/foo/devices" {
+			t.Errorf("bad request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("X-Auth-Token") != "secret" {
+			t.Errorf("bad token: %s", r.Header.Get("X-Auth-Token"))
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if body["metro"] != "sv" || body["billing_cycle"] != "hourly" {
+			t.Errorf("bad body: %#v", body)
+		}
+		if _, ok := body["facility"]; ok {
+			t.Errorf("facility should be omitted: %#v", body)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "device", "state": "queued"}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL + "/", AuthToken: "secret"}
+	device, err := client.CreateDevice("foo", &DeviceCreateRequest{
+		Hostname:        "packer",
+		Plan:            "c3.small.x86",
+		Metro:           "sv",
+		OperatingSystem: "ubuntu_20_04",
+		BillingCycle:    "hourly",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if device.ID != "device" || device.State != "queued" {
+		t.Fatalf("bad: %#v", device)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"errors": ["Plan is not available"]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL, AuthToken: "secret"}
+	_, err := client.GetDevice("device")
+	apiErr, ok := err.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if apiErr.StatusCode != http.StatusUnprocessableEntity || len(apiErr.Errors) != 1 {
+		t.Fatalf("bad: %#v", apiErr)
+	}
+}
+
+func TestDevice_PublicIPv4(t *testing.T) {
+	device := &Device{IPAddresses: []IPAddress{
+		{Address: "10.0.0.1", AddressFamily: 4},
+		{Address: "2604::1", AddressFamily: 6, Public: true},
+		{Address: "147.75.0.1", AddressFamily: 4, Public: true},
+	}}
+	if ip := device.PublicIPv4(); ip != "147.75.0.1" {
+		t.Fatalf("bad: %s", ip)
+	}
+}
@@ -0,0 +1,172 @@
+package equinixmetal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// customIPXE is the operating system of the devices that boot an iPXE script.
+const customIPXE = "custom_ipxe"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	Comm                communicator.Config `mapstructure:",squash"`
+
+	AuthToken string `mapstructure:"auth_token"`
+	APIURL    string `mapstructure:"api_url"`
+	ProjectID string `mapstructure:"project_id"`
+
+	Metro           string `mapstructure:"metro"`
+	Facility        string `mapstructure:"facility"`
+	Plan            string `mapstructure:"plan"`
+	OperatingSystem string `mapstructure:"operating_system"`
+	IPXEScriptURL   string `mapstructure:"ipxe_script_url"`
+	AlwaysPXE       bool   `mapstructure:"always_pxe"`
+
+	Hostname        string        `mapstructure:"hostname"`
+	Tags            []string      `mapstructure:"tags"`
+	BillingCycle    string        `mapstructure:"billing_cycle"`
+	UserData        string        `mapstructure:"user_data"`
+	UserDataFile    string        `mapstructure:"user_data_file"`
+	StateTimeout    time.Duration `mapstructure:"state_timeout"`
+	OutputDir       string        `mapstructure:"output_directory"`
+	CaptureExcludes []string      `mapstructure:"capture_excludes"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.AuthToken == "" {
+		c.AuthToken = os.Getenv("METAL_AUTH_TOKEN")
+	}
+	if c.AuthToken == "" {
+		// The API is still served to the tokens of Packet
+		c.AuthToken = os.Getenv("PACKET_AUTH_TOKEN")
+	}
+	if c.APIURL == "" {
+		c.APIURL = os.Getenv("METAL_API_URL")
+	}
+	if c.APIURL == "" {
+		c.APIURL = DefaultAPIURL
+	}
+	if c.ProjectID == "" {
+		c.ProjectID = os.Getenv("METAL_PROJECT_ID")
+	}
+
+	if c.Hostname == "" {
+		// Default to packer-[time-ordered-uuid]
+		c.Hostname = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	}
+
+	if c.BillingCycle == "" {
+		c.BillingCycle = "hourly"
+	}
+
+	if c.StateTimeout == 0 {
+		// Bare metal takes much longer than a virtual machine to be
+		// provisioned
+		c.StateTimeout = 20 * time.Minute
+	}
+
+	if c.OutputDir == "" {
+		c.OutputDir = fmt.Sprintf("output-%s", c.PackerBuildName)
+	}
+
+	if c.Comm.SSHUsername == "" {
+		c.Comm.SSHUsername = "root"
+	}
+
+	var errs *packer.MultiError
+	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+	if c.Comm.Type != "ssh" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("the communicator must be ssh to capture the image"))
+	}
+
+	if c.AuthToken == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("auth_token for auth must be specified"))
+	}
+
+	if c.ProjectID == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("project_id is required"))
+	}
+
+	if c.Metro == "" && c.Facility == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("one of metro or facility is required"))
+	} else if c.Metro != "" && c.Facility != "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("only one of metro or facility can be specified"))
+	}
+
+	if c.Plan == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("plan is required"))
+	}
+
+	if c.OperatingSystem == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("operating_system is required"))
+	}
+
+	if c.OperatingSystem == customIPXE && c.IPXEScriptURL == "" {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("ipxe_script_url is required with the %s operating system", customIPXE))
+	} else if c.OperatingSystem != customIPXE && (c.IPXEScriptURL != "" || c.AlwaysPXE) {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("ipxe_script_url and always_pxe require the %s operating system", customIPXE))
+	}
+
+	if c.BillingCycle != "hourly" && c.BillingCycle != "daily" && c.BillingCycle != "monthly" {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("billing_cycle must be one of hourly, daily or monthly: %s", c.BillingCycle))
+	}
+
+	if c.UserData != "" && c.UserDataFile != "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("only one of user_data or user_data_file can be specified"))
+	} else if c.UserDataFile != "" {
+		if _, err := os.Stat(c.UserDataFile); err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("user_data_file not found: %s", c.UserDataFile))
+		}
+	}
+
+	if !c.PackerForce {
+		if _, err := os.Stat(c.OutputDir); err == nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("Output directory '%s' already exists. It must not exist.", c.OutputDir))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	common.ScrubConfig(c, c.AuthToken)
+	return c, nil, nil
+}
@@ -0,0 +1,130 @@
+package equinixmetal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// captureDir is the directory of the device the image is captured in.
+const captureDir = "/var/tmp/packer-capture"
+
+// captureFiles are the files of a custom image, as Equinix Metal expects
+// them to boot it with iPXE or to upload it.
+var captureFiles = []string{"image.tar.gz", "kernel.tar.gz", "initrd.tar.gz", "modules.tar.gz"}
+
+// defaultCaptureExcludes are the paths of the root file system that are not
+// part of the image.
+var defaultCaptureExcludes = []string{"/proc/*", "/sys/*", "/dev/*", "/run/*", "/tmp/*", captureDir}
+
+// captureScript archives the root file system, and the latest kernel with its
+// initrd and modules. tar exits with 1 when files change while they are read,
+// which the running system does all the time.
+const captureScript = `set -e
+dir=%[1]s
+rm -rf "$dir"
+mkdir -p "$dir"
+cd "$dir"
+
+kernel=$(ls -1 /boot/vmlinuz-* | sort -V | tail -n 1)
+version=${kernel#/boot/vmlinuz-}
+for initrd in "/boot/initrd.img-$version" "/boot/initramfs-$version.img"; do
+  if [ -f "$initrd" ]; then
+    cp "$initrd" initrd
+  fi
+done
+if [ ! -f initrd ]; then
+  echo "No initrd found for the kernel $version" >&2
+  exit 1
+fi
+cp "$kernel" vmlinuz
+
+tar -czf kernel.tar.gz vmlinuz
+tar -czf initrd.tar.gz initrd
+rm vmlinuz initrd
+tar -czf modules.tar.gz -C / "lib/modules/$version"
+tar --numeric-owner --one-file-system %[2]s -czf image.tar.gz -C / . || [ $? -eq 1 ]
+`
+
+// stepCapture archives the device into the files of a custom image, and
+// downloads them to the output directory.
+type stepCapture struct{}
+
+func (s *stepCapture) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	comm := state.Get("communicator").(packer.Communicator)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	ui.Say("Capturing the device into an image...")
+
+	script := captureScriptFor(c.CaptureExcludes)
+	scriptPath := captureDir + ".sh"
+	if err := comm.Upload(scriptPath, strings.NewReader(script), nil); err != nil {
+		err := fmt.Errorf("Error uploading the capture script: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	cmd := &packer.RemoteCmd{Command: fmt.Sprintf("sh %s", scriptPath)}
+	if err := cmd.StartWithUi(comm, ui); err != nil {
+		err := fmt.Errorf("Error capturing the device: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if cmd.ExitStatus != 0 {
+		err := fmt.Errorf("Error capturing the device: the capture exited with status %d", cmd.ExitStatus)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	var files []string
+	for _, name := range captureFiles {
+		dst := filepath.Join(c.OutputDir, name)
+		ui.Message(fmt.Sprintf("Downloading %s...", name))
+		if err := download(comm, path.Join(captureDir, name), dst); err != nil {
+			err := fmt.Errorf("Error downloading %s: %s", name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		files = append(files, dst)
+	}
+
+	state.Put("files", files)
+	return multistep.ActionContinue
+}
+
+func (s *stepCapture) Cleanup(state multistep.StateBag) {
+	// The capture is deleted with the device
+}
+
+// captureScriptFor returns the capture script, excluding the default paths
+// and the given ones from the image.
+func captureScriptFor(excludes []string) string {
+	var args bytes.Buffer
+	for _, exclude := range append(defaultCaptureExcludes, excludes...) {
+		// The archive is of the root directory, with paths relative to it
+		fmt.Fprintf(&args, " --exclude='.%s'", path.Clean("/"+exclude))
+	}
+	return fmt.Sprintf(captureScript, captureDir, strings.TrimSpace(args.String()))
+}
+
+func download(comm packer.Communicator, src string, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return comm.Download(src, f)
+}
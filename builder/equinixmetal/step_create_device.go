@@ -0,0 +1,84 @@
+package equinixmetal
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepCreateDevice struct {
+	deviceId string
+}
+
+func (s *stepCreateDevice) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	sshKeyId := state.Get("ssh_key_id").(string)
+
+	ui.Say("Creating device...")
+
+	userData := c.UserData
+	if c.UserDataFile != "" {
+		contents, err := ioutil.ReadFile(c.UserDataFile)
+		if err != nil {
+			state.Put("error", fmt.Errorf("Problem reading user data file: %s", err))
+			return multistep.ActionHalt
+		}
+
+		userData = string(contents)
+	}
+
+	req := &DeviceCreateRequest{
+		Hostname:        c.Hostname,
+		Plan:            c.Plan,
+		Metro:           c.Metro,
+		OperatingSystem: c.OperatingSystem,
+		BillingCycle:    c.BillingCycle,
+		UserData:        userData,
+		Tags:            c.Tags,
+		IPXEScriptURL:   c.IPXEScriptURL,
+		AlwaysPXE:       c.AlwaysPXE,
+		ProjectSSHKeys:  []string{sshKeyId},
+	}
+	if c.Facility != "" {
+		req.Facility = []string{c.Facility}
+	}
+
+	device, err := client.CreateDevice(c.ProjectID, req)
+	if err != nil {
+		err := fmt.Errorf("Error creating device: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this in cleanup
+	s.deviceId = device.ID
+
+	// Store the device id for later
+	state.Put("device_id", device.ID)
+	ui.Message(fmt.Sprintf("Device ID: %s", device.ID))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateDevice) Cleanup(state multistep.StateBag) {
+	// If the device id isn't there, we probably never created it
+	if s.deviceId == "" {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+
+	// Deprovision the device we just created
+	ui.Say("Deleting device...")
+	if err := client.DeleteDevice(s.deviceId); err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error deleting device. Please delete it manually: %s", err))
+	}
+}
@@ -0,0 +1,123 @@
+package equinixmetal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"golang.org/x/crypto/ssh"
+)
+
+type stepCreateSSHKey struct {
+	Debug        bool
+	DebugKeyPath string
+
+	keyId string
+}
+
+func (s *stepCreateSSHKey) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	ui.Say("Creating temporary ssh key for device...")
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		err := fmt.Errorf("Error generating temporary SSH key: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// ASN.1 DER encoded form
+	privBlk := pem.Block{
+		Type:    "RSA PRIVATE KEY",
+		Headers: nil,
+		Bytes:   x509.MarshalPKCS1PrivateKey(priv),
+	}
+
+	// Set the private key in the statebag for later
+	state.Put("privateKey", string(pem.EncodeToMemory(&privBlk)))
+
+	// Marshal the public key into SSH compatible format
+	pub, err := ssh.NewPublicKey(&priv.PublicKey)
+	if err != nil {
+		err := fmt.Errorf("Error generating temporary SSH key: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// The label of the key in the project
+	name := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+
+	key, err := client.CreateSSHKey(c.ProjectID, name, string(ssh.MarshalAuthorizedKey(pub)))
+	if err != nil {
+		err := fmt.Errorf("Error creating temporary SSH key: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this to check cleanup
+	s.keyId = key.ID
+
+	log.Printf("temporary ssh key name: %s", name)
+
+	// Remember some state for the future
+	state.Put("ssh_key_id", key.ID)
+
+	// If we're in debug mode, output the private key to the working directory.
+	if s.Debug {
+		ui.Message(fmt.Sprintf("Saving key for debug purposes: %s", s.DebugKeyPath))
+		f, err := os.Create(s.DebugKeyPath)
+		if err != nil {
+			state.Put("error", fmt.Errorf("Error saving debug key: %s", err))
+			return multistep.ActionHalt
+		}
+		defer f.Close()
+
+		// Write the key out
+		if _, err := f.Write(pem.EncodeToMemory(&privBlk)); err != nil {
+			state.Put("error", fmt.Errorf("Error saving debug key: %s", err))
+			return multistep.ActionHalt
+		}
+
+		// Chmod it so that it is SSH ready
+		if runtime.GOOS != "windows" {
+			if err := f.Chmod(0600); err != nil {
+				state.Put("error", fmt.Errorf("Error setting permissions of debug key: %s", err))
+				return multistep.ActionHalt
+			}
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateSSHKey) Cleanup(state multistep.StateBag) {
+	// If no key id is set, then we never created it, so just return
+	if s.keyId == "" {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deleting temporary ssh key...")
+	if err := client.DeleteSSHKey(s.keyId); err != nil {
+		log.Printf("Error cleaning up ssh key: %s", err)
+		ui.Error(fmt.Sprintf(
+			"Error cleaning up ssh key. Please delete the key manually: %s", err))
+	}
+}
@@ -0,0 +1,43 @@
+package equinixmetal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepDeviceInfo struct{}
+
+func (s *stepDeviceInfo) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	deviceID := state.Get("device_id").(string)
+
+	ui.Say("Waiting for device to become active...")
+
+	device, err := waitForDeviceState("active", deviceID, client, c.StateTimeout)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for device to become active: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ip := device.PublicIPv4()
+	if ip == "" {
+		err := fmt.Errorf("Could not find a public IPv4 address for this device")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	state.Put("device_ip", ip)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepDeviceInfo) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
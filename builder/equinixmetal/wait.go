@@ -0,0 +1,66 @@
+package equinixmetal
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// waitForDeviceState waits for the device to be in the desired state, and
+// returns it.
+func waitForDeviceState(
+	desiredState string, deviceId string,
+	client *Client, timeout time.Duration) (*Device, error) {
+	done := make(chan struct{})
+	defer close(done)
+
+	type deviceResult struct {
+		device *Device
+		err    error
+	}
+	result := make(chan deviceResult, 1)
+	go func() {
+		attempts := 0
+		for {
+			attempts += 1
+
+			log.Printf("Checking device status... (attempt: %d)", attempts)
+			device, err := client.GetDevice(deviceId)
+			if err != nil {
+				result <- deviceResult{nil, err}
+				return
+			}
+
+			if device.State == desiredState {
+				result <- deviceResult{device, nil}
+				return
+			}
+
+			// A failed provisioning never becomes active
+			if device.State == "failed" {
+				result <- deviceResult{nil, fmt.Errorf("device provisioning failed")}
+				return
+			}
+
+			// Wait 10 seconds in between, bare metal is slow to provision
+			time.Sleep(10 * time.Second)
+
+			// Verify we shouldn't exit
+			select {
+			case <-done:
+				// We finished, so just exit the goroutine
+				return
+			default:
+				// Keep going
+			}
+		}
+	}()
+
+	log.Printf("Waiting for up to %d seconds for device to become %s", timeout/time.Second, desiredState)
+	select {
+	case r := <-result:
+		return r.device, r.err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("Timeout while waiting for device to become '%s'", desiredState)
+	}
+}
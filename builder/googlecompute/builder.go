@@ -3,22 +3,37 @@
 package googlecompute
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/helper/retry"
 	"github.com/hashicorp/packer/packer"
 )
 
 // The unique ID for this builder.
 const BuilderId = "packer.googlecompute"
 
+// How long to wait before running a build again after its instance was
+// preempted, at first and at most, so that Compute Engine has time to get
+// the capacity back.
+const (
+	preemptionRetryInitialBackoff = 30 * time.Second
+	preemptionRetryMaxBackoff     = 5 * time.Minute
+)
+
 // Builder represents a Packer Builder.
 type Builder struct {
 	config *Config
 	runner multistep.Runner
+
+	l           sync.Mutex
+	cancelRetry context.CancelFunc
 }
 
 // Prepare processes the build configuration parameters.
@@ -40,6 +55,64 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, err
 	}
 
+	// Cancelling the build also stops waiting to run it again
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.l.Lock()
+	b.cancelRetry = cancel
+	b.l.Unlock()
+
+	// A preempted instance fails the build, which is run again with a new
+	// instance, up to preemption_retries times.
+	var state multistep.StateBag
+	runs := 0
+	err = retry.Config{
+		Tries:          1 + b.config.PreemptionRetries,
+		InitialBackoff: preemptionRetryInitialBackoff,
+		MaxBackoff:     preemptionRetryMaxBackoff,
+	}.Run(ctx, func(context.Context) error {
+		if runs > 0 {
+			ui.Say(fmt.Sprintf("The instance was preempted, retrying the build (%d/%d)...",
+				runs, b.config.PreemptionRetries))
+		}
+		runs++
+
+		state = b.runSteps(ui, hook, driver)
+		rawErr, failed := state.GetOk("error")
+		if !failed {
+			return nil
+		}
+		_, preempted := state.GetOk("instance_preempted")
+		_, cancelled := state.GetOk(multistep.StateCancelled)
+		if !preempted || cancelled {
+			return retry.Abort(rawErr.(error))
+		}
+		return rawErr.(error)
+	})
+
+	// Report any errors, the one of the last build rather than the one of
+	// giving up.
+	if err != nil {
+		if rawErr, ok := state.GetOk("error"); ok {
+			return nil, rawErr.(error)
+		}
+		return nil, err
+	}
+	if _, ok := state.GetOk("image"); !ok {
+		log.Println("Failed to find image in state. Bug?")
+		return nil, nil
+	}
+
+	artifact := &Artifact{
+		image:  state.Get("image").(*Image),
+		driver: driver,
+		config: b.config,
+	}
+	return artifact, nil
+}
+
+// runSteps runs the steps of a build with a new state, and returns it.
+func (b *Builder) runSteps(ui packer.Ui, hook packer.Hook, driver Driver) multistep.StateBag {
 	// Set up the state.
 	state := new(multistep.BasicStateBag)
 	state.Put("config", b.config)
@@ -48,6 +121,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	state.Put("ui", ui)
 
 	// Build the steps.
+	_, hasStartupScript := b.config.Metadata[StartupScriptKey]
+	hasStartupScript = hasStartupScript || b.config.StartupScriptFile != ""
+
 	steps := []multistep.Step{
 		new(StepCheckExistingImage),
 		&StepCreateSSHKey{
@@ -55,6 +131,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			DebugKeyPath:   fmt.Sprintf("gce_%s.pem", b.config.PackerBuildName),
 			PrivateKeyFile: b.config.Comm.SSHPrivateKey,
 		},
+		multistep.If(b.config.UseOSLogin, new(StepImportOSLoginSSHKey)),
 		&StepCreateInstance{
 			Debug: b.config.PackerDebug,
 		},
@@ -65,6 +142,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&StepInstanceInfo{
 			Debug: b.config.PackerDebug,
 		},
+		multistep.If(b.config.UseIAP, new(StepStartTunnel)),
 		&communicator.StepConnect{
 			Config:      &b.config.Comm,
 			Host:        commHost,
@@ -72,35 +150,27 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			WinRMConfig: winrmConfig,
 		},
 		new(common.StepProvision),
+		multistep.If(hasStartupScript, new(StepWaitStartupScript)),
+		new(StepTeardownInstance),
+		new(StepCreateImage),
+		multistep.If(b.config.ImageFamilyDeprecatePrevious || b.config.ImageFamilyObsoleteAfter > 0,
+			new(StepDeprecateFamilyImages)),
 	}
-	if _, exists := b.config.Metadata[StartupScriptKey]; exists || b.config.StartupScriptFile != "" {
-		steps = append(steps, new(StepWaitStartupScript))
-	}
-	steps = append(steps, new(StepTeardownInstance), new(StepCreateImage))
 
 	// Run the steps.
 	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
 	b.runner.Run(state)
-
-	// Report any errors.
-	if rawErr, ok := state.GetOk("error"); ok {
-		return nil, rawErr.(error)
-	}
-	if _, ok := state.GetOk("image"); !ok {
-		log.Println("Failed to find image in state. Bug?")
-		return nil, nil
-	}
-
-	artifact := &Artifact{
-		image:  state.Get("image").(*Image),
-		driver: driver,
-		config: b.config,
-	}
-	return artifact, nil
+	return state
 }
 
 // Cancel.
 func (b *Builder) Cancel() {
+	b.l.Lock()
+	if b.cancelRetry != nil {
+		b.cancelRetry()
+	}
+	b.l.Unlock()
+
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
 		b.runner.Cancel()
@@ -16,6 +16,10 @@ import (
 
 var reImageFamily = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
 
+// maxPreemptionRetries is how many times a build may run again when its
+// instance is preempted.
+const maxPreemptionRetries = 10
+
 // Config is the configuration structure for the GCE builder. It stores
 // both the publicly settable state as well as the privately generated
 // state of the config object.
@@ -29,13 +33,21 @@ type Config struct {
 	AcceleratorType              string            `mapstructure:"accelerator_type"`
 	AcceleratorCount             int64             `mapstructure:"accelerator_count"`
 	Address                      string            `mapstructure:"address"`
+	ConfidentialInstanceType     string            `mapstructure:"confidential_instance_type"`
 	DisableDefaultServiceAccount bool              `mapstructure:"disable_default_service_account"`
+	IAPLocalhostPort             int               `mapstructure:"iap_localhost_port"`
+	IAPTunnelLaunchWait          int               `mapstructure:"iap_tunnel_launch_wait"`
 	DiskName                     string            `mapstructure:"disk_name"`
 	DiskSizeGb                   int64             `mapstructure:"disk_size"`
 	DiskType                     string            `mapstructure:"disk_type"`
+	EnableIntegrityMonitoring    bool              `mapstructure:"enable_integrity_monitoring"`
+	EnableSecureBoot             bool              `mapstructure:"enable_secure_boot"`
+	EnableVtpm                   bool              `mapstructure:"enable_vtpm"`
 	ImageName                    string            `mapstructure:"image_name"`
 	ImageDescription             string            `mapstructure:"image_description"`
 	ImageFamily                  string            `mapstructure:"image_family"`
+	ImageFamilyDeprecatePrevious bool              `mapstructure:"image_family_deprecate_previous"`
+	ImageFamilyObsoleteAfter     int               `mapstructure:"image_family_obsolete_after"`
 	ImageLabels                  map[string]string `mapstructure:"image_labels"`
 	ImageLicenses                []string          `mapstructure:"image_licenses"`
 	InstanceName                 string            `mapstructure:"instance_name"`
@@ -47,6 +59,8 @@ type Config struct {
 	OmitExternalIP               bool              `mapstructure:"omit_external_ip"`
 	OnHostMaintenance            string            `mapstructure:"on_host_maintenance"`
 	Preemptible                  bool              `mapstructure:"preemptible"`
+	PreemptionRetries            int               `mapstructure:"preemption_retries"`
+	ProvisioningModel            string            `mapstructure:"provisioning_model"`
 	RawStateTimeout              string            `mapstructure:"state_timeout"`
 	Region                       string            `mapstructure:"region"`
 	Scopes                       []string          `mapstructure:"scopes"`
@@ -57,12 +71,15 @@ type Config struct {
 	StartupScriptFile            string            `mapstructure:"startup_script_file"`
 	Subnetwork                   string            `mapstructure:"subnetwork"`
 	Tags                         []string          `mapstructure:"tags"`
+	UseIAP                       bool              `mapstructure:"use_iap"`
+	UseOSLogin                   bool              `mapstructure:"use_os_login"`
 	UseInternalIP                bool              `mapstructure:"use_internal_ip"`
 	Zone                         string            `mapstructure:"zone"`
 
 	Account            AccountFile
 	stateTimeout       time.Duration
 	imageAlreadyExists bool
+	iapFirewallRule    string
 	ctx                interpolate.Context
 }
 
@@ -105,21 +122,63 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.ImageDescription = "Created by Packer"
 	}
 
-	if c.OnHostMaintenance == "MIGRATE" && c.Preemptible {
+	switch c.ProvisioningModel {
+	case "", "STANDARD", "SPOT":
+	default:
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("provisioning_model must be one of STANDARD or SPOT."))
+	}
+
+	if c.Preemptible && c.ProvisioningModel == "SPOT" {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("preemptible can't be used with the SPOT provisioning_model, Spot VMs replace preemptible VMs."))
+	}
+
+	if c.OnHostMaintenance == "MIGRATE" && c.isPreemptible() {
 		errs = packer.MultiErrorAppend(errs,
-			errors.New("on_host_maintenance must be TERMINATE when using preemptible instances."))
+			errors.New("on_host_maintenance must be TERMINATE when using preemptible or Spot instances."))
 	}
 	// Setting OnHostMaintenance Correct Defaults
 	//   "MIGRATE" : Possible and default if Preemptible is false
-	//   "TERMINATE": Required if Preemptible is true
-	if c.Preemptible {
+	//   "TERMINATE": Required if Preemptible is true or for Spot instances
+	//   "TERMINATE": Required and default for confidential instances
+	if c.isPreemptible() {
 		c.OnHostMaintenance = "TERMINATE"
 	} else {
-		if c.OnHostMaintenance == "" {
+		if c.OnHostMaintenance == "" && c.ConfidentialInstanceType != "" {
+			c.OnHostMaintenance = "TERMINATE"
+		} else if c.OnHostMaintenance == "" {
 			c.OnHostMaintenance = "MIGRATE"
 		}
 	}
 
+	switch c.ConfidentialInstanceType {
+	case "", "SEV", "SEV_SNP", "TDX":
+	default:
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("confidential_instance_type must be one of SEV, SEV_SNP or TDX."))
+	}
+
+	if c.ConfidentialInstanceType != "" && c.OnHostMaintenance == "MIGRATE" {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("on_host_maintenance must be TERMINATE when using confidential instances."))
+	}
+
+	if c.PreemptionRetries < 0 || c.PreemptionRetries > maxPreemptionRetries {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("preemption_retries must be between 0 and %d.", maxPreemptionRetries))
+	}
+
+	if c.PreemptionRetries > 0 && !c.isPreemptible() {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("preemption_retries requires preemptible or the SPOT provisioning_model."))
+	}
+
+	if c.EnableIntegrityMonitoring && !c.EnableVtpm {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("enable_vtpm must be true when using enable_integrity_monitoring."))
+	}
+
 	// Make sure user sets a valid value for on_host_maintenance option
 	if !(c.OnHostMaintenance == "MIGRATE" || c.OnHostMaintenance == "TERMINATE") {
 		errs = packer.MultiErrorAppend(errs,
@@ -149,6 +208,16 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 
 	}
 
+	if c.ImageFamily == "" && (c.ImageFamilyDeprecatePrevious || c.ImageFamilyObsoleteAfter != 0) {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("image_family must be set when using image_family_deprecate_previous or image_family_obsolete_after."))
+	}
+
+	if c.ImageFamilyObsoleteAfter < 0 {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("image_family_obsolete_after must not be negative."))
+	}
+
 	if c.InstanceName == "" {
 		c.InstanceName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
 	}
@@ -161,10 +230,21 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.MachineType = "n1-standard-1"
 	}
 
+	// The rest of the limits of custom machine types depend on the zone
+	if _, err := parseCustomMachineType(c.MachineType); err != nil {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("Invalid machine_type: %s", err))
+	}
+
 	if c.RawStateTimeout == "" {
 		c.RawStateTimeout = "5m"
 	}
 
+	// The username of OS Login is the one of the user's profile
+	if c.UseOSLogin && c.Comm.SSHUsername == "" {
+		c.Comm.SSHUsername = "packer"
+	}
+
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packer.MultiErrorAppend(errs, es...)
 	}
@@ -210,24 +290,43 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	}
 
 	if c.OmitExternalIP && c.Address != "" {
-		errs = packer.MultiErrorAppend(fmt.Errorf("you can not specify an external address when 'omit_external_ip' is true"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("you can not specify an external address when 'omit_external_ip' is true"))
+	}
+
+	if c.UseIAP {
+		if c.Comm.Type == "none" {
+			errs = packer.MultiErrorAppend(errs, errors.New("'use_iap' requires a communicator"))
+		}
+		if c.IAPLocalhostPort < 0 || c.IAPLocalhostPort > 65535 {
+			errs = packer.MultiErrorAppend(errs, errors.New("'iap_localhost_port' must be a TCP port"))
+		}
+		if c.IAPTunnelLaunchWait == 0 {
+			c.IAPTunnelLaunchWait = 30
+		}
+		// The name of the firewall rule is also the network tag of the
+		// instance it allows
+		c.iapFirewallRule = fmt.Sprintf("packer-iap-%s", uuid.TimeOrderedUUID())
+	}
+
+	if c.UseOSLogin && c.Comm.Type != "ssh" {
+		errs = packer.MultiErrorAppend(errs, errors.New("'use_os_login' requires the ssh communicator"))
 	}
 
-	if c.OmitExternalIP && !c.UseInternalIP {
-		errs = packer.MultiErrorAppend(fmt.Errorf("'use_internal_ip' must be true if 'omit_external_ip' is true"))
+	if c.OmitExternalIP && !c.UseInternalIP && !c.UseIAP {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("'use_internal_ip' or 'use_iap' must be true if 'omit_external_ip' is true"))
 	}
 
 	if c.AcceleratorCount > 0 && len(c.AcceleratorType) == 0 {
-		errs = packer.MultiErrorAppend(fmt.Errorf("'accelerator_type' must be set when 'accelerator_count' is more than 0"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("'accelerator_type' must be set when 'accelerator_count' is more than 0"))
 	}
 
 	if c.AcceleratorCount > 0 && c.OnHostMaintenance != "TERMINATE" {
-		errs = packer.MultiErrorAppend(fmt.Errorf("'on_host_maintenance' must be set to 'TERMINATE' when 'accelerator_count' is more than 0"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("'on_host_maintenance' must be set to 'TERMINATE' when 'accelerator_count' is more than 0"))
 	}
 
 	// If DisableDefaultServiceAccount is provided, don't allow a value for ServiceAccountEmail
 	if c.DisableDefaultServiceAccount && c.ServiceAccountEmail != "" {
-		errs = packer.MultiErrorAppend(fmt.Errorf("you may not specify a 'service_account_email' when 'disable_default_service_account' is true"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("you may not specify a 'service_account_email' when 'disable_default_service_account' is true"))
 	}
 
 	// Check for any errors.
@@ -238,6 +337,35 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	return c, nil, nil
 }
 
+// isPreemptible is true if Compute Engine may stop the instance, when it is
+// preemptible or a Spot VM.
+func (c *Config) isPreemptible() bool {
+	return c.Preemptible || c.ProvisioningModel == "SPOT"
+}
+
+// isShieldedVM is true if any of the Shielded VM options is set.
+func (c *Config) isShieldedVM() bool {
+	return c.EnableSecureBoot || c.EnableVtpm || c.EnableIntegrityMonitoring
+}
+
+// imageGuestOsFeatures returns the guest OS features the image needs to
+// boot Shielded VMs and Confidential VMs like the build instance.
+func (c *Config) imageGuestOsFeatures() []string {
+	var features []string
+	if c.isShieldedVM() || c.ConfidentialInstanceType != "" {
+		features = append(features, "UEFI_COMPATIBLE")
+	}
+	switch c.ConfidentialInstanceType {
+	case "SEV":
+		features = append(features, "SEV_CAPABLE")
+	case "SEV_SNP":
+		features = append(features, "SEV_SNP_CAPABLE")
+	case "TDX":
+		features = append(features, "TDX_CAPABLE")
+	}
+	return features
+}
+
 func (c *Config) CalcTimeout() error {
 	stateTimeout, err := time.ParseDuration(c.RawStateTimeout)
 	if err != nil {
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/hashicorp/packer/packer"
 )
 
 func TestConfigPrepare(t *testing.T) {
@@ -187,6 +190,16 @@ func TestConfigPrepare(t *testing.T) {
 			false,
 			false,
 		},
+		{
+			"image_family_obsolete_after",
+			-1,
+			true,
+		},
+		{
+			"image_family_obsolete_after",
+			3,
+			false,
+		},
 		{
 			"disable_default_service_account",
 			true,
@@ -329,6 +342,253 @@ func TestConfigPrepareServiceAccount(t *testing.T) {
 	}
 }
 
+func TestConfigPrepareShieldedVM(t *testing.T) {
+	cases := []struct {
+		Keys   []string
+		Values []interface{}
+		Err    bool
+	}{
+		{
+			[]string{"enable_secure_boot", "enable_vtpm", "enable_integrity_monitoring"},
+			[]interface{}{true, true, true},
+			false,
+		},
+		{
+			[]string{"enable_secure_boot", "enable_vtpm", "enable_integrity_monitoring"},
+			[]interface{}{true, false, true},
+			true,
+		},
+		{
+			[]string{"confidential_instance_type", "on_host_maintenance"},
+			[]interface{}{"SEV_SNP", nil},
+			false,
+		},
+		{
+			[]string{"confidential_instance_type", "on_host_maintenance"},
+			[]interface{}{"SEV", "MIGRATE"},
+			true,
+		},
+		{
+			[]string{"confidential_instance_type", "on_host_maintenance"},
+			[]interface{}{"SGX", "TERMINATE"},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		raw, tempfile := testConfig(t)
+		defer os.Remove(tempfile)
+
+		errStr := ""
+		for k := range tc.Keys {
+
+			// Create the string for error reporting
+			// convert value to string if it can be converted
+			errStr += fmt.Sprintf("%s:%v, ", tc.Keys[k], tc.Values[k])
+			if tc.Values[k] == nil {
+				delete(raw, tc.Keys[k])
+			} else {
+				raw[tc.Keys[k]] = tc.Values[k]
+			}
+		}
+
+		_, warns, errs := NewConfig(raw)
+
+		if tc.Err {
+			testConfigErr(t, warns, errs, strings.TrimRight(errStr, ", "))
+		} else {
+			testConfigOk(t, warns, errs)
+		}
+	}
+}
+
+func TestConfigPreparePreemption(t *testing.T) {
+	cases := []struct {
+		Keys   []string
+		Values []interface{}
+		Err    bool
+	}{
+		{
+			[]string{"provisioning_model", "on_host_maintenance"},
+			[]interface{}{"SPOT", nil},
+			false,
+		},
+		{
+			[]string{"provisioning_model", "on_host_maintenance"},
+			[]interface{}{"SPOT", "MIGRATE"},
+			true,
+		},
+		{
+			[]string{"provisioning_model", "preemptible"},
+			[]interface{}{"SPOT", true},
+			true,
+		},
+		{
+			[]string{"provisioning_model", "preemptible"},
+			[]interface{}{"RESERVED", false},
+			true,
+		},
+		{
+			[]string{"preemptible", "preemption_retries"},
+			[]interface{}{true, 3},
+			false,
+		},
+		{
+			[]string{"provisioning_model", "preemption_retries"},
+			[]interface{}{"SPOT", -1},
+			true,
+		},
+		{
+			[]string{"provisioning_model", "preemption_retries"},
+			[]interface{}{"SPOT", 11},
+			true,
+		},
+		{
+			[]string{"provisioning_model", "preemption_retries"},
+			[]interface{}{"STANDARD", 3},
+			true,
+		},
+		{
+			[]string{"machine_type"},
+			[]interface{}{"n2-custom-4-16384"},
+			false,
+		},
+		{
+			[]string{"machine_type"},
+			[]interface{}{"custom-3-3840"},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		raw, tempfile := testConfig(t)
+		defer os.Remove(tempfile)
+
+		errStr := ""
+		for k := range tc.Keys {
+
+			// Create the string for error reporting
+			// convert value to string if it can be converted
+			errStr += fmt.Sprintf("%s:%v, ", tc.Keys[k], tc.Values[k])
+			if tc.Values[k] == nil {
+				delete(raw, tc.Keys[k])
+			} else {
+				raw[tc.Keys[k]] = tc.Values[k]
+			}
+		}
+
+		c, warns, errs := NewConfig(raw)
+
+		if tc.Err {
+			testConfigErr(t, warns, errs, strings.TrimRight(errStr, ", "))
+		} else {
+			testConfigOk(t, warns, errs)
+			if c.isPreemptible() && c.OnHostMaintenance != "TERMINATE" {
+				t.Errorf("on_host_maintenance should default to TERMINATE with %s", errStr)
+			}
+		}
+	}
+}
+
+func TestConfigPrepareIAPAndOSLogin(t *testing.T) {
+	cases := []struct {
+		Keys   []string
+		Values []interface{}
+		Err    bool
+	}{
+		{
+			[]string{"use_iap", "omit_external_ip", "use_internal_ip"},
+			[]interface{}{true, true, false},
+			false,
+		},
+		{
+			[]string{"use_iap", "omit_external_ip", "use_internal_ip"},
+			[]interface{}{false, true, false},
+			true,
+		},
+		{
+			[]string{"use_iap", "iap_localhost_port"},
+			[]interface{}{true, 70000},
+			true,
+		},
+		{
+			[]string{"use_iap", "communicator"},
+			[]interface{}{true, "none"},
+			true,
+		},
+		{
+			[]string{"use_os_login", "ssh_username"},
+			[]interface{}{true, nil},
+			false,
+		},
+		{
+			[]string{"use_os_login", "communicator"},
+			[]interface{}{true, "winrm"},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		raw, tempfile := testConfig(t)
+		defer os.Remove(tempfile)
+
+		errStr := ""
+		for k := range tc.Keys {
+
+			// Create the string for error reporting
+			// convert value to string if it can be converted
+			errStr += fmt.Sprintf("%s:%v, ", tc.Keys[k], tc.Values[k])
+			if tc.Values[k] == nil {
+				delete(raw, tc.Keys[k])
+			} else {
+				raw[tc.Keys[k]] = tc.Values[k]
+			}
+		}
+
+		_, warns, errs := NewConfig(raw)
+
+		if tc.Err {
+			testConfigErr(t, warns, errs, strings.TrimRight(errStr, ", "))
+		} else {
+			testConfigOk(t, warns, errs)
+		}
+	}
+}
+
+func TestConfigPrepareKeepsErrors(t *testing.T) {
+	raw, tempfile := testConfig(t)
+	defer os.Remove(tempfile)
+
+	// The error about the tunnel must not replace the one about the zone
+	delete(raw, "zone")
+	raw["omit_external_ip"] = true
+
+	_, _, errs := NewConfig(raw)
+	if errs == nil {
+		t.Fatal("should have error")
+	}
+	if len(errs.(*packer.MultiError).Errors) != 2 {
+		t.Fatalf("bad: %s", errs)
+	}
+}
+
+func TestConfigImageGuestOsFeatures(t *testing.T) {
+	c := testConfigStruct(t)
+	if features := c.imageGuestOsFeatures(); len(features) != 0 {
+		t.Fatalf("Expected no guest OS features, but got %v", features)
+	}
+
+	c.EnableVtpm = true
+	if features := c.imageGuestOsFeatures(); !reflect.DeepEqual(features, []string{"UEFI_COMPATIBLE"}) {
+		t.Fatalf("Expected the guest OS features of Shielded VMs, but got %v", features)
+	}
+
+	c.ConfidentialInstanceType = "SEV_SNP"
+	if features := c.imageGuestOsFeatures(); !reflect.DeepEqual(features, []string{"UEFI_COMPATIBLE", "SEV_SNP_CAPABLE"}) {
+		t.Fatalf("Expected the guest OS features of Confidential VMs, but got %v", features)
+	}
+}
+
 func TestConfigDefaults(t *testing.T) {
 	cases := []struct {
 		Read  func(c *Config) interface{}
@@ -11,11 +11,27 @@ import (
 type Driver interface {
 	// CreateImage creates an image from the given disk in Google Compute
 	// Engine.
-	CreateImage(name, description, family, zone, disk string, image_labels map[string]string, image_licenses []string) (<-chan *Image, <-chan error)
+	CreateImage(name, description, family, zone, disk string, image_labels map[string]string, image_licenses []string, image_guest_os_features []string) (<-chan *Image, <-chan error)
+
+	// CreateFirewallRule creates a rule in the network, that allows TCP
+	// traffic to the port of the instances with the target tag from the
+	// source ranges.
+	CreateFirewallRule(name, network string, sourceRanges []string, targetTag string, port int) (<-chan error, error)
+
+	// DeleteFirewallRule deletes the rule of the network.
+	DeleteFirewallRule(name, network string) (<-chan error, error)
+
+	// DeleteOSLoginSSHKey deletes the SSH key with the fingerprint from the
+	// OS Login profile of the user.
+	DeleteOSLoginSSHKey(user, fingerprint string) error
 
 	// DeleteImage deletes the image with the given name.
 	DeleteImage(name string) <-chan error
 
+	// DeprecateImage sets the deprecation state of the image, DEPRECATED,
+	// OBSOLETE or DELETED, with the self link of the image replacing it.
+	DeprecateImage(name, state, replacement string) <-chan error
+
 	// DeleteInstance deletes the given instance, keeping the boot disk.
 	DeleteInstance(zone, name string) (<-chan error, error)
 
@@ -34,6 +50,13 @@ type Driver interface {
 	// GetInstanceMetadata gets a metadata variable for the instance, name.
 	GetInstanceMetadata(zone, name, key string) (string, error)
 
+	// GetInstanceNetwork gets the URL of the network of the instance.
+	GetInstanceNetwork(zone, name string) (string, error)
+
+	// GetTokenUserEmail gets the email of the user or service account that
+	// Packer authenticates as.
+	GetTokenUserEmail() (string, error)
+
 	// GetInternalIP gets the GCE-internal IP address for the instance.
 	GetInternalIP(zone, name string) (string, error)
 
@@ -43,6 +66,19 @@ type Driver interface {
 	// GetSerialPortOutput gets the Serial Port contents for the instance.
 	GetSerialPortOutput(zone, name string) (string, error)
 
+	// ListFamilyImages lists the images of the family in the project, the
+	// newest first.
+	ListFamilyImages(family string) ([]*Image, error)
+
+	// ImportOSLoginSSHKey imports the SSH public key to the OS Login profile
+	// of the user, and returns the username the user logs in as and the
+	// fingerprint of the key.
+	ImportOSLoginSSHKey(user, sshPublicKey string) (string, string, error)
+
+	// InstancePreempted returns true if Compute Engine preempted the
+	// instance.
+	InstancePreempted(zone, name string) (bool, error)
+
 	// ImageExists returns true if the specified image exists. If an error
 	// occurs calling the API, this method returns false.
 	ImageExists(name string) bool
@@ -61,10 +97,14 @@ type InstanceConfig struct {
 	AcceleratorType              string
 	AcceleratorCount             int64
 	Address                      string
+	ConfidentialInstanceType     string
 	Description                  string
 	DisableDefaultServiceAccount bool
 	DiskSizeGb                   int64
 	DiskType                     string
+	EnableIntegrityMonitoring    bool
+	EnableSecureBoot             bool
+	EnableVtpm                   bool
 	Image                        *Image
 	Labels                       map[string]string
 	MachineType                  string
@@ -75,6 +115,7 @@ type InstanceConfig struct {
 	OmitExternalIP               bool
 	OnHostMaintenance            string
 	Preemptible                  bool
+	ProvisioningModel            string
 	Region                       string
 	ServiceAccountEmail          string
 	Scopes                       []string
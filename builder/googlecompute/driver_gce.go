@@ -1,6 +1,8 @@
 package googlecompute
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
@@ -8,12 +10,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/useragent"
@@ -28,13 +34,17 @@ import (
 // Create an instance using NewDriverGCE.
 type driverGCE struct {
 	projectId string
+	client    *http.Client
 	service   *compute.Service
 	ui        packer.Ui
 }
 
-var DriverScopes = []string{"https://www.googleapis.com/auth/compute", "https://www.googleapis.com/auth/devstorage.full_control"}
+var DriverScopes = []string{"https://www.googleapis.com/auth/compute", "https://www.googleapis.com/auth/devstorage.full_control", "https://www.googleapis.com/auth/userinfo.email"}
 
-func NewDriverGCE(ui packer.Ui, p string, a *AccountFile) (Driver, error) {
+// NewClientGCE returns an HTTP client authorized with the scopes, as the
+// account of the account file if it has a private key, or else with the
+// application default credentials.
+func NewClientGCE(a *AccountFile, scopes []string) (*http.Client, error) {
 	var err error
 
 	var client *http.Client
@@ -43,13 +53,13 @@ func NewDriverGCE(ui packer.Ui, p string, a *AccountFile) (Driver, error) {
 	if a.PrivateKey != "" {
 		log.Printf("[INFO] Requesting Google token via AccountFile...")
 		log.Printf("[INFO]   -- Email: %s", a.ClientEmail)
-		log.Printf("[INFO]   -- Scopes: %s", DriverScopes)
+		log.Printf("[INFO]   -- Scopes: %s", scopes)
 		log.Printf("[INFO]   -- Private Key Length: %d", len(a.PrivateKey))
 
 		conf := jwt.Config{
 			Email:      a.ClientEmail,
 			PrivateKey: []byte(a.PrivateKey),
-			Scopes:     DriverScopes,
+			Scopes:     scopes,
 			TokenURL:   "https://accounts.google.com/o/oauth2/token",
 		}
 
@@ -59,7 +69,7 @@ func NewDriverGCE(ui packer.Ui, p string, a *AccountFile) (Driver, error) {
 		client = conf.Client(oauth2.NoContext)
 	} else {
 		log.Printf("[INFO] Requesting Google token via GCE API Default Client Token Source...")
-		client, err = google.DefaultClient(oauth2.NoContext, DriverScopes...)
+		client, err = google.DefaultClient(oauth2.NoContext, scopes...)
 		// The DefaultClient uses the DefaultTokenSource of the google lib.
 		// The DefaultTokenSource uses the "Application Default Credentials"
 		// It looks for credentials in the following places, preferring the first location found:
@@ -74,6 +84,14 @@ func NewDriverGCE(ui packer.Ui, p string, a *AccountFile) (Driver, error) {
 		//    (In this final case any provided scopes are ignored.)
 	}
 
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func NewDriverGCE(ui packer.Ui, p string, a *AccountFile) (Driver, error) {
+	client, err := NewClientGCE(a, DriverScopes)
 	if err != nil {
 		return nil, err
 	}
@@ -89,20 +107,27 @@ func NewDriverGCE(ui packer.Ui, p string, a *AccountFile) (Driver, error) {
 
 	return &driverGCE{
 		projectId: p,
+		client:    client,
 		service:   service,
 		ui:        ui,
 	}, nil
 }
 
-func (d *driverGCE) CreateImage(name, description, family, zone, disk string, image_labels map[string]string, image_licenses []string) (<-chan *Image, <-chan error) {
+func (d *driverGCE) CreateImage(name, description, family, zone, disk string, image_labels map[string]string, image_licenses []string, image_guest_os_features []string) (<-chan *Image, <-chan error) {
+	var guestOsFeatures []*compute.GuestOsFeature
+	for _, feature := range image_guest_os_features {
+		guestOsFeatures = append(guestOsFeatures, &compute.GuestOsFeature{Type: feature})
+	}
+
 	gce_image := &compute.Image{
-		Description: description,
-		Name:        name,
-		Family:      family,
-		Labels:      image_labels,
-		Licenses:    image_licenses,
-		SourceDisk:  fmt.Sprintf("%s%s/zones/%s/disks/%s", d.service.BasePath, d.projectId, zone, disk),
-		SourceType:  "RAW",
+		Description:     description,
+		Name:            name,
+		Family:          family,
+		GuestOsFeatures: guestOsFeatures,
+		Labels:          image_labels,
+		Licenses:        image_licenses,
+		SourceDisk:      fmt.Sprintf("%s%s/zones/%s/disks/%s", d.service.BasePath, d.projectId, zone, disk),
+		SourceType:      "RAW",
 	}
 
 	imageCh := make(chan *Image, 1)
@@ -133,6 +158,56 @@ func (d *driverGCE) CreateImage(name, description, family, zone, disk string, im
 	return imageCh, errCh
 }
 
+// networkProject returns the project of the network URL, which differs from
+// the project of the build with a shared VPC.
+func (d *driverGCE) networkProject(network string) string {
+	parts := strings.Split(network, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "projects" {
+			return parts[i+1]
+		}
+	}
+	return d.projectId
+}
+
+func (d *driverGCE) CreateFirewallRule(name, network string, sourceRanges []string, targetTag string, port int) (<-chan error, error) {
+	project := d.networkProject(network)
+	firewall := &compute.Firewall{
+		Name:        name,
+		Description: "Firewall rule created by Packer",
+		Network:     network,
+		Allowed: []*compute.FirewallAllowed{
+			{
+				IPProtocol: "tcp",
+				Ports:      []string{strconv.Itoa(port)},
+			},
+		},
+		SourceRanges: sourceRanges,
+		TargetTags:   []string{targetTag},
+	}
+
+	op, err := d.service.Firewalls.Insert(project, firewall).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 1)
+	go waitForState(errCh, "DONE", d.refreshGlobalOpInProject(project, op))
+	return errCh, nil
+}
+
+func (d *driverGCE) DeleteFirewallRule(name, network string) (<-chan error, error) {
+	project := d.networkProject(network)
+	op, err := d.service.Firewalls.Delete(project, name).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 1)
+	go waitForState(errCh, "DONE", d.refreshGlobalOpInProject(project, op))
+	return errCh, nil
+}
+
 func (d *driverGCE) DeleteImage(name string) <-chan error {
 	errCh := make(chan error, 1)
 	op, err := d.service.Images.Delete(d.projectId, name).Do()
@@ -145,6 +220,21 @@ func (d *driverGCE) DeleteImage(name string) <-chan error {
 	return errCh
 }
 
+func (d *driverGCE) DeprecateImage(name, state, replacement string) <-chan error {
+	errCh := make(chan error, 1)
+	op, err := d.service.Images.Deprecate(d.projectId, name, &compute.DeprecationStatus{
+		State:       state,
+		Replacement: replacement,
+	}).Do()
+	if err != nil {
+		errCh <- err
+	} else {
+		go waitForState(errCh, "DONE", d.refreshGlobalOp(op))
+	}
+
+	return errCh
+}
+
 func (d *driverGCE) DeleteInstance(zone, name string) (<-chan error, error) {
 	op, err := d.service.Instances.Delete(d.projectId, zone, name).Do()
 	if err != nil {
@@ -227,6 +317,39 @@ func (d *driverGCE) GetInstanceMetadata(zone, name, key string) (string, error)
 	return "", fmt.Errorf("Instance metadata key, %s, not found.", key)
 }
 
+func (d *driverGCE) GetInstanceNetwork(zone, name string) (string, error) {
+	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
+	if err != nil {
+		return "", err
+	}
+
+	if len(instance.NetworkInterfaces) == 0 {
+		return "", fmt.Errorf("Instance, %s, has no network interface.", name)
+	}
+	return instance.NetworkInterfaces[0].Network, nil
+}
+
+func (d *driverGCE) InstancePreempted(zone, name string) (bool, error) {
+	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
+	if err != nil {
+		return false, err
+	}
+
+	// Compute Engine stops a preempted instance with a system operation
+	preempted := false
+	err = d.service.ZoneOperations.List(d.projectId, zone).
+		Filter(`operationType="compute.instances.preempted"`).
+		Pages(context.TODO(), func(list *compute.OperationList) error {
+			for _, op := range list.Items {
+				if op.TargetId == instance.Id {
+					preempted = true
+				}
+			}
+			return nil
+		})
+	return preempted, err
+}
+
 func (d *driverGCE) GetNatIP(zone, name string) (string, error) {
 	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
 	if err != nil {
@@ -272,6 +395,41 @@ func (d *driverGCE) GetSerialPortOutput(zone, name string) (string, error) {
 	return output.Contents, nil
 }
 
+func (d *driverGCE) ListFamilyImages(family string) ([]*Image, error) {
+	var images []*compute.Image
+	err := d.service.Images.List(d.projectId).Filter(fmt.Sprintf("family = %s", family)).Pages(
+		context.TODO(), func(list *compute.ImageList) error {
+			images = append(images, list.Items...)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// The creation timestamps are RFC3339 in the same time zone
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].CreationTimestamp > images[j].CreationTimestamp
+	})
+
+	result := make([]*Image, 0, len(images))
+	for _, image := range images {
+		var state string
+		if image.Deprecated != nil {
+			state = image.Deprecated.State
+		}
+		result = append(result, &Image{
+			Labels:           image.Labels,
+			Licenses:         image.Licenses,
+			Name:             image.Name,
+			ProjectId:        d.projectId,
+			SelfLink:         image.SelfLink,
+			SizeGb:           image.DiskSizeGb,
+			DeprecationState: state,
+		})
+	}
+	return result, nil
+}
+
 func (d *driverGCE) ImageExists(name string) bool {
 	_, err := d.GetImageFromProject(d.projectId, name, false)
 	// The API may return an error for reasons other than the image not
@@ -289,8 +447,7 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 
 	// Get the machine type
 	d.ui.Message(fmt.Sprintf("Loading machine type: %s", c.MachineType))
-	machineType, err := d.service.MachineTypes.Get(
-		d.projectId, zone.Name, c.MachineType).Do()
+	machineType, err := d.getMachineType(zone.Name, c.MachineType)
 	if err != nil {
 		return nil, err
 	}
@@ -373,7 +530,7 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 		},
 		GuestAccelerators: guestAccelerators,
 		Labels:            c.Labels,
-		MachineType:       machineType.SelfLink,
+		MachineType:       machineType,
 		Metadata: &compute.Metadata{
 			Items: metadata,
 		},
@@ -398,7 +555,12 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 	}
 
 	d.ui.Message("Requesting instance creation...")
-	op, err := d.service.Instances.Insert(d.projectId, zone.Name, &instance).Do()
+	var op *compute.Operation
+	if c.EnableSecureBoot || c.EnableVtpm || c.EnableIntegrityMonitoring || c.ConfidentialInstanceType != "" || c.ProvisioningModel != "" {
+		op, err = d.insertInstance(zone.Name, &instance, c)
+	} else {
+		op, err = d.service.Instances.Insert(d.projectId, zone.Name, &instance).Do()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -408,6 +570,120 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 	return errCh, nil
 }
 
+// getMachineType returns the URL of the machine type in the zone. Custom
+// machine types aren't listed, so they are checked against the limits of
+// the predefined machine types of their family instead.
+func (d *driverGCE) getMachineType(zone, name string) (string, error) {
+	custom, err := parseCustomMachineType(name)
+	if err != nil {
+		return "", err
+	}
+	if custom == nil {
+		machineType, err := d.service.MachineTypes.Get(d.projectId, zone, name).Do()
+		if err != nil {
+			return "", err
+		}
+		return machineType.SelfLink, nil
+	}
+
+	var machineTypes []*compute.MachineType
+	err = d.service.MachineTypes.List(d.projectId, zone).Pages(context.TODO(), func(list *compute.MachineTypeList) error {
+		machineTypes = append(machineTypes, list.Items...)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := custom.checkZoneLimits(zone, machineTypes); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("zones/%s/machineTypes/%s", zone, name), nil
+}
+
+// instanceWithExtraConfig returns the JSON of the instance, along with the
+// Shielded VM, Confidential VM and provisioning model configuration of c.
+// The vendored compute API doesn't have them yet.
+func instanceWithExtraConfig(instance *compute.Instance, c *InstanceConfig) ([]byte, error) {
+	body, err := json.Marshal(instance)
+	if err != nil {
+		return nil, err
+	}
+	var properties map[string]interface{}
+	if err := json.Unmarshal(body, &properties); err != nil {
+		return nil, err
+	}
+
+	if c.EnableSecureBoot || c.EnableVtpm || c.EnableIntegrityMonitoring {
+		properties["shieldedInstanceConfig"] = map[string]bool{
+			"enableSecureBoot":          c.EnableSecureBoot,
+			"enableVtpm":                c.EnableVtpm,
+			"enableIntegrityMonitoring": c.EnableIntegrityMonitoring,
+		}
+	}
+	if c.ConfidentialInstanceType != "" {
+		properties["confidentialInstanceConfig"] = map[string]string{
+			"confidentialInstanceType": c.ConfidentialInstanceType,
+		}
+	}
+	if c.ProvisioningModel != "" {
+		scheduling, _ := properties["scheduling"].(map[string]interface{})
+		if scheduling == nil {
+			scheduling = make(map[string]interface{})
+			properties["scheduling"] = scheduling
+		}
+		scheduling["provisioningModel"] = c.ProvisioningModel
+	}
+	return json.Marshal(properties)
+}
+
+// insertInstance creates the instance like Instances.Insert, with the
+// configuration of instanceWithExtraConfig.
+func (d *driverGCE) insertInstance(zone string, instance *compute.Instance, c *InstanceConfig) (*compute.Operation, error) {
+	body, err := instanceWithExtraConfig(instance, c)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s%s/zones/%s/instances?alt=json", d.service.BasePath, d.projectId, zone)
+	op := &compute.Operation{}
+	if err := d.doJSON("POST", url, body, op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// doJSON sends the JSON body to the Google API and decodes its JSON response
+// into result, for the APIs missing from the vendored client libraries.
+// Either may be nil.
+func (d *driverGCE) doJSON(method, url string, body []byte, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", d.service.UserAgent)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(resp)
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
 func (d *driverGCE) CreateOrResetWindowsPassword(instance, zone string, c *WindowsPasswordConfig) (<-chan error, error) {
 
 	errCh := make(chan error, 1)
@@ -531,8 +807,12 @@ func (d *driverGCE) refreshInstanceState(zone, name string) stateRefreshFunc {
 }
 
 func (d *driverGCE) refreshGlobalOp(op *compute.Operation) stateRefreshFunc {
+	return d.refreshGlobalOpInProject(d.projectId, op)
+}
+
+func (d *driverGCE) refreshGlobalOpInProject(project string, op *compute.Operation) stateRefreshFunc {
 	return func() (string, error) {
-		newOp, err := d.service.GlobalOperations.Get(d.projectId, op.Name).Do()
+		newOp, err := d.service.GlobalOperations.Get(project, op.Name).Do()
 		if err != nil {
 			return "", err
 		}
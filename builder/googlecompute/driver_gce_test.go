@@ -0,0 +1,60 @@
+package googlecompute
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestInstanceWithExtraConfig(t *testing.T) {
+	instance := &compute.Instance{Name: "packer"}
+	body, err := instanceWithExtraConfig(instance, &InstanceConfig{
+		ConfidentialInstanceType: "SEV",
+		EnableSecureBoot:         true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var properties map[string]interface{}
+	if err := json.Unmarshal(body, &properties); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "packer", properties["name"], "The instance properties should be kept.")
+	assert.Equal(t, map[string]interface{}{
+		"enableSecureBoot":          true,
+		"enableVtpm":                false,
+		"enableIntegrityMonitoring": false,
+	}, properties["shieldedInstanceConfig"], "Incorrect Shielded VM configuration.")
+	assert.Equal(t, map[string]interface{}{
+		"confidentialInstanceType": "SEV",
+	}, properties["confidentialInstanceConfig"], "Incorrect Confidential VM configuration.")
+}
+
+func TestInstanceWithExtraConfig_spot(t *testing.T) {
+	instance := &compute.Instance{
+		Name: "packer",
+		Scheduling: &compute.Scheduling{
+			OnHostMaintenance: "TERMINATE",
+		},
+	}
+	body, err := instanceWithExtraConfig(instance, &InstanceConfig{
+		ProvisioningModel: "SPOT",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var properties map[string]interface{}
+	if err := json.Unmarshal(body, &properties); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"onHostMaintenance": "TERMINATE",
+		"provisioningModel": "SPOT",
+	}, properties["scheduling"], "Incorrect scheduling.")
+	_, ok := properties["shieldedInstanceConfig"]
+	assert.False(t, ok, "There should be no Shielded VM configuration.")
+}
@@ -10,6 +10,7 @@ type DriverMock struct {
 	CreateImageFamily          string
 	CreateImageLabels          map[string]string
 	CreateImageLicenses        []string
+	CreateImageGuestOsFeatures []string
 	CreateImageZone            string
 	CreateImageDisk            string
 	CreateImageResultProjectId string
@@ -18,9 +19,30 @@ type DriverMock struct {
 	CreateImageErrCh           <-chan error
 	CreateImageResultCh        <-chan *Image
 
+	CreateFirewallRuleName         string
+	CreateFirewallRuleNetwork      string
+	CreateFirewallRuleSourceRanges []string
+	CreateFirewallRuleTargetTag    string
+	CreateFirewallRulePort         int
+	CreateFirewallRuleErrCh        <-chan error
+	CreateFirewallRuleErr          error
+
+	DeleteFirewallRuleName    string
+	DeleteFirewallRuleNetwork string
+	DeleteFirewallRuleErrCh   <-chan error
+	DeleteFirewallRuleErr     error
+
+	DeleteOSLoginSSHKeyUser        string
+	DeleteOSLoginSSHKeyFingerprint string
+	DeleteOSLoginSSHKeyErr         error
+
 	DeleteImageName  string
 	DeleteImageErrCh <-chan error
 
+	DeprecateImageStates      map[string]string
+	DeprecateImageReplacement string
+	DeprecateImageErrCh       <-chan error
+
 	DeleteInstanceZone  string
 	DeleteInstanceName  string
 	DeleteInstanceErrCh <-chan error
@@ -53,6 +75,14 @@ type DriverMock struct {
 	GetNatIPResult string
 	GetNatIPErr    error
 
+	GetInstanceNetworkZone   string
+	GetInstanceNetworkName   string
+	GetInstanceNetworkResult string
+	GetInstanceNetworkErr    error
+
+	GetTokenUserEmailResult string
+	GetTokenUserEmailErr    error
+
 	GetInternalIPZone   string
 	GetInternalIPName   string
 	GetInternalIPResult string
@@ -66,6 +96,21 @@ type DriverMock struct {
 	ImageExistsName   string
 	ImageExistsResult bool
 
+	InstancePreemptedZone   string
+	InstancePreemptedName   string
+	InstancePreemptedResult bool
+	InstancePreemptedErr    error
+
+	ImportOSLoginSSHKeyUser              string
+	ImportOSLoginSSHKeyKey               string
+	ImportOSLoginSSHKeyResultUsername    string
+	ImportOSLoginSSHKeyResultFingerprint string
+	ImportOSLoginSSHKeyErr               error
+
+	ListFamilyImagesFamily string
+	ListFamilyImagesResult []*Image
+	ListFamilyImagesErr    error
+
 	RunInstanceConfig *InstanceConfig
 	RunInstanceErrCh  <-chan error
 	RunInstanceErr    error
@@ -82,12 +127,13 @@ type DriverMock struct {
 	WaitForInstanceErrCh <-chan error
 }
 
-func (d *DriverMock) CreateImage(name, description, family, zone, disk string, image_labels map[string]string, image_licenses []string) (<-chan *Image, <-chan error) {
+func (d *DriverMock) CreateImage(name, description, family, zone, disk string, image_labels map[string]string, image_licenses []string, image_guest_os_features []string) (<-chan *Image, <-chan error) {
 	d.CreateImageName = name
 	d.CreateImageDesc = description
 	d.CreateImageFamily = family
 	d.CreateImageLabels = image_labels
 	d.CreateImageLicenses = image_licenses
+	d.CreateImageGuestOsFeatures = image_guest_os_features
 	d.CreateImageZone = zone
 	d.CreateImageDisk = disk
 	if d.CreateImageResultProjectId == "" {
@@ -127,6 +173,43 @@ func (d *DriverMock) CreateImage(name, description, family, zone, disk string, i
 	return resultCh, errCh
 }
 
+func (d *DriverMock) CreateFirewallRule(name, network string, sourceRanges []string, targetTag string, port int) (<-chan error, error) {
+	d.CreateFirewallRuleName = name
+	d.CreateFirewallRuleNetwork = network
+	d.CreateFirewallRuleSourceRanges = sourceRanges
+	d.CreateFirewallRuleTargetTag = targetTag
+	d.CreateFirewallRulePort = port
+
+	resultCh := d.CreateFirewallRuleErrCh
+	if resultCh == nil {
+		ch := make(chan error)
+		close(ch)
+		resultCh = ch
+	}
+
+	return resultCh, d.CreateFirewallRuleErr
+}
+
+func (d *DriverMock) DeleteFirewallRule(name, network string) (<-chan error, error) {
+	d.DeleteFirewallRuleName = name
+	d.DeleteFirewallRuleNetwork = network
+
+	resultCh := d.DeleteFirewallRuleErrCh
+	if resultCh == nil {
+		ch := make(chan error)
+		close(ch)
+		resultCh = ch
+	}
+
+	return resultCh, d.DeleteFirewallRuleErr
+}
+
+func (d *DriverMock) DeleteOSLoginSSHKey(user, fingerprint string) error {
+	d.DeleteOSLoginSSHKeyUser = user
+	d.DeleteOSLoginSSHKeyFingerprint = fingerprint
+	return d.DeleteOSLoginSSHKeyErr
+}
+
 func (d *DriverMock) DeleteImage(name string) <-chan error {
 	d.DeleteImageName = name
 
@@ -140,6 +223,23 @@ func (d *DriverMock) DeleteImage(name string) <-chan error {
 	return resultCh
 }
 
+func (d *DriverMock) DeprecateImage(name, state, replacement string) <-chan error {
+	if d.DeprecateImageStates == nil {
+		d.DeprecateImageStates = make(map[string]string)
+	}
+	d.DeprecateImageStates[name] = state
+	d.DeprecateImageReplacement = replacement
+
+	resultCh := d.DeprecateImageErrCh
+	if resultCh == nil {
+		ch := make(chan error)
+		close(ch)
+		resultCh = ch
+	}
+
+	return resultCh
+}
+
 func (d *DriverMock) DeleteInstance(zone, name string) (<-chan error, error) {
 	d.DeleteInstanceZone = zone
 	d.DeleteInstanceName = name
@@ -194,6 +294,16 @@ func (d *DriverMock) GetNatIP(zone, name string) (string, error) {
 	return d.GetNatIPResult, d.GetNatIPErr
 }
 
+func (d *DriverMock) GetInstanceNetwork(zone, name string) (string, error) {
+	d.GetInstanceNetworkZone = zone
+	d.GetInstanceNetworkName = name
+	return d.GetInstanceNetworkResult, d.GetInstanceNetworkErr
+}
+
+func (d *DriverMock) GetTokenUserEmail() (string, error) {
+	return d.GetTokenUserEmailResult, d.GetTokenUserEmailErr
+}
+
 func (d *DriverMock) GetInternalIP(zone, name string) (string, error) {
 	d.GetInternalIPZone = zone
 	d.GetInternalIPName = name
@@ -206,11 +316,28 @@ func (d *DriverMock) GetSerialPortOutput(zone, name string) (string, error) {
 	return d.GetSerialPortOutputResult, d.GetSerialPortOutputErr
 }
 
+func (d *DriverMock) InstancePreempted(zone, name string) (bool, error) {
+	d.InstancePreemptedZone = zone
+	d.InstancePreemptedName = name
+	return d.InstancePreemptedResult, d.InstancePreemptedErr
+}
+
+func (d *DriverMock) ImportOSLoginSSHKey(user, sshPublicKey string) (string, string, error) {
+	d.ImportOSLoginSSHKeyUser = user
+	d.ImportOSLoginSSHKeyKey = sshPublicKey
+	return d.ImportOSLoginSSHKeyResultUsername, d.ImportOSLoginSSHKeyResultFingerprint, d.ImportOSLoginSSHKeyErr
+}
+
 func (d *DriverMock) ImageExists(name string) bool {
 	d.ImageExistsName = name
 	return d.ImageExistsResult
 }
 
+func (d *DriverMock) ListFamilyImages(family string) ([]*Image, error) {
+	d.ListFamilyImagesFamily = family
+	return d.ListFamilyImagesResult, d.ListFamilyImagesErr
+}
+
 func (d *DriverMock) RunInstance(c *InstanceConfig) (<-chan error, error) {
 	d.RunInstanceConfig = c
 
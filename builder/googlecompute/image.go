@@ -11,6 +11,10 @@ type Image struct {
 	ProjectId string
 	SelfLink  string
 	SizeGb    int64
+
+	// DeprecationState is DEPRECATED, OBSOLETE or DELETED, or empty if the
+	// image is active.
+	DeprecationState string
 }
 
 func (i *Image) IsWindows() bool {
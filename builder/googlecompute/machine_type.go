@@ -0,0 +1,101 @@
+package googlecompute
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+// Custom machine types are named [<family>-]custom-<vCPUs>-<memory in MB>,
+// with an -ext suffix for extended memory. Without a family they are N1.
+var reCustomMachineType = regexp.MustCompile(`^(?:([a-z][a-z0-9]*)-)?custom-([0-9]+)-([0-9]+)(-ext)?$`)
+
+// customMachineType is a machine type with custom vCPUs and memory, see
+// https://cloud.google.com/compute/docs/instances/creating-instance-with-custom-machine-type
+type customMachineType struct {
+	Family         string
+	CPUs           int64
+	MemoryMb       int64
+	ExtendedMemory bool
+}
+
+// parseCustomMachineType parses the name of a custom machine type. It
+// returns nil if the name is the one of a predefined machine type.
+func parseCustomMachineType(name string) (*customMachineType, error) {
+	if !strings.Contains(name, "custom-") {
+		return nil, nil
+	}
+	matches := reCustomMachineType.FindStringSubmatch(name)
+	if matches == nil {
+		return nil, fmt.Errorf("%s isn't a custom machine type, they are named [FAMILY-]custom-CPUS-MEMORY[-ext]", name)
+	}
+
+	m := &customMachineType{
+		Family:         matches[1],
+		ExtendedMemory: matches[4] != "",
+	}
+	if m.Family == "" {
+		m.Family = "n1"
+	}
+	m.CPUs, _ = strconv.ParseInt(matches[2], 10, 64)
+	m.MemoryMb, _ = strconv.ParseInt(matches[3], 10, 64)
+	return m, m.validate()
+}
+
+// validate checks the vCPUs and memory of the machine type against the
+// rules of custom machine types that don't depend on the zone.
+func (m *customMachineType) validate() error {
+	if m.CPUs < 1 {
+		return fmt.Errorf("a custom machine type needs at least 1 vCPU")
+	}
+	if m.CPUs > 1 && m.CPUs%2 != 0 {
+		return fmt.Errorf("a custom machine type with more than 1 vCPU needs an even number of them, got %d", m.CPUs)
+	}
+	if m.MemoryMb%256 != 0 {
+		return fmt.Errorf("the memory of a custom machine type must be a multiple of 256 MB, got %d", m.MemoryMb)
+	}
+
+	// The memory per vCPU, in MB, of the N1 family and of the newer ones
+	minMemory, maxMemory := int64(922), int64(6656)
+	if m.Family != "n1" {
+		minMemory, maxMemory = 512, 8192
+	}
+	if m.MemoryMb < minMemory*m.CPUs {
+		return fmt.Errorf("a %s custom machine type needs at least %d MB of memory per vCPU", m.Family, minMemory)
+	}
+	if !m.ExtendedMemory && m.MemoryMb > maxMemory*m.CPUs {
+		return fmt.Errorf("a %s custom machine type has at most %d MB of memory per vCPU, use the -ext suffix for extended memory", m.Family, maxMemory)
+	}
+	return nil
+}
+
+// checkZoneLimits checks the machine type against the largest predefined
+// machine types of its family in the zone.
+func (m *customMachineType) checkZoneLimits(zone string, machineTypes []*compute.MachineType) error {
+	var maxCPUs, maxMemoryMb int64
+	for _, machineType := range machineTypes {
+		if !strings.HasPrefix(machineType.Name, m.Family+"-") {
+			continue
+		}
+		if machineType.GuestCpus > maxCPUs {
+			maxCPUs = machineType.GuestCpus
+		}
+		if machineType.MemoryMb > maxMemoryMb {
+			maxMemoryMb = machineType.MemoryMb
+		}
+	}
+
+	if maxCPUs == 0 {
+		return fmt.Errorf("the %s machine family isn't available in %s", m.Family, zone)
+	}
+	if m.CPUs > maxCPUs {
+		return fmt.Errorf("the %s machine family has at most %d vCPUs in %s, got %d", m.Family, maxCPUs, zone, m.CPUs)
+	}
+	if m.MemoryMb > maxMemoryMb {
+		return fmt.Errorf("the %s machine family has at most %d MB of memory in %s, got %d", m.Family, maxMemoryMb, zone, m.MemoryMb)
+	}
+	return nil
+}
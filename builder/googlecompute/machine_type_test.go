@@ -0,0 +1,58 @@
+package googlecompute
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestParseCustomMachineType(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected *customMachineType
+		err      bool
+	}{
+		{"n1-standard-1", nil, false},
+		{"custom-2-4096", &customMachineType{Family: "n1", CPUs: 2, MemoryMb: 4096}, false},
+		{"custom-1-1024", &customMachineType{Family: "n1", CPUs: 1, MemoryMb: 1024}, false},
+		{"n2-custom-8-65536-ext", &customMachineType{Family: "n2", CPUs: 8, MemoryMb: 65536, ExtendedMemory: true}, false},
+		{"custom-2-4000", nil, true},
+		{"custom-3-3072", nil, true},
+		{"custom-0-1024", nil, true},
+		{"custom-2-1024", nil, true},
+		{"custom-2-16384", nil, true},
+		{"n2-custom-2-16384", &customMachineType{Family: "n2", CPUs: 2, MemoryMb: 16384}, false},
+		{"custom-2", nil, true},
+	}
+
+	for _, tc := range cases {
+		m, err := parseCustomMachineType(tc.name)
+		if tc.err {
+			assert.Error(t, err, "%s should be invalid.", tc.name)
+			continue
+		}
+		assert.NoError(t, err, "%s should be valid.", tc.name)
+		assert.Equal(t, tc.expected, m, "Incorrect machine type parsed from %s.", tc.name)
+	}
+}
+
+func TestCustomMachineTypeCheckZoneLimits(t *testing.T) {
+	machineTypes := []*compute.MachineType{
+		{Name: "n1-standard-96", GuestCpus: 96, MemoryMb: 368640},
+		{Name: "n1-highmem-64", GuestCpus: 64, MemoryMb: 425984},
+		{Name: "n2-standard-32", GuestCpus: 32, MemoryMb: 131072},
+	}
+
+	m := &customMachineType{Family: "n1", CPUs: 96, MemoryMb: 425984, ExtendedMemory: true}
+	assert.NoError(t, m.checkZoneLimits("us-east1-a", machineTypes), "The largest machine type should fit.")
+
+	m = &customMachineType{Family: "n2", CPUs: 48, MemoryMb: 196608}
+	assert.Error(t, m.checkZoneLimits("us-east1-a", machineTypes), "There should be too many vCPUs.")
+
+	m = &customMachineType{Family: "n2", CPUs: 32, MemoryMb: 262144, ExtendedMemory: true}
+	assert.Error(t, m.checkZoneLimits("us-east1-a", machineTypes), "There should be too much memory.")
+
+	m = &customMachineType{Family: "e2", CPUs: 2, MemoryMb: 4096}
+	assert.Error(t, m.checkZoneLimits("us-east1-a", machineTypes), "The family should not be available.")
+}
@@ -0,0 +1,103 @@
+package googlecompute
+
+// NOTE: The OS Login API does not yet exist in the vendored client libraries,
+// the OS Login methods of driverGCE call its REST API directly.
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// EnableOSLoginKey is the instance metadata key enabling OS Login.
+const EnableOSLoginKey string = "enable-oslogin"
+
+const (
+	osLoginBasePath  = "https://oslogin.googleapis.com/v1/"
+	userInfoEndpoint = "https://www.googleapis.com/oauth2/v2/userinfo"
+)
+
+// osLoginProfile is the OS Login profile of a user, with the POSIX accounts
+// it logs in to the instances as and its SSH public keys by fingerprint.
+type osLoginProfile struct {
+	Name          string                         `json:"name"`
+	PosixAccounts []osLoginPosixAccount          `json:"posixAccounts"`
+	SSHPublicKeys map[string]osLoginSSHPublicKey `json:"sshPublicKeys"`
+}
+
+type osLoginPosixAccount struct {
+	Primary  bool   `json:"primary"`
+	Username string `json:"username"`
+}
+
+type osLoginSSHPublicKey struct {
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// username returns the username of the primary POSIX account of the profile.
+func (p *osLoginProfile) username() (string, error) {
+	for _, account := range p.PosixAccounts {
+		if account.Primary {
+			return account.Username, nil
+		}
+	}
+	return "", fmt.Errorf("The OS Login profile %s has no primary POSIX account", p.Name)
+}
+
+// fingerprint returns the fingerprint of the SSH public key in the profile.
+func (p *osLoginProfile) fingerprint(sshPublicKey string) (string, error) {
+	sshPublicKey = strings.TrimSpace(sshPublicKey)
+	for fingerprint, key := range p.SSHPublicKeys {
+		if strings.TrimSpace(key.Key) == sshPublicKey {
+			return fingerprint, nil
+		}
+	}
+	return "", fmt.Errorf("The OS Login profile %s doesn't have the imported SSH key", p.Name)
+}
+
+func (d *driverGCE) ImportOSLoginSSHKey(user, sshPublicKey string) (string, string, error) {
+	body, err := json.Marshal(map[string]string{"key": sshPublicKey})
+	if err != nil {
+		return "", "", err
+	}
+
+	profile := &osLoginProfile{}
+	u := fmt.Sprintf("%susers/%s:importSshPublicKey?projectId=%s",
+		osLoginBasePath, url.PathEscape(user), url.QueryEscape(d.projectId))
+	if err := d.doJSON("POST", u, body, &struct {
+		LoginProfile *osLoginProfile `json:"loginProfile"`
+	}{profile}); err != nil {
+		return "", "", err
+	}
+
+	username, err := profile.username()
+	if err != nil {
+		return "", "", err
+	}
+	fingerprint, err := profile.fingerprint(sshPublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	return username, fingerprint, nil
+}
+
+func (d *driverGCE) DeleteOSLoginSSHKey(user, fingerprint string) error {
+	u := fmt.Sprintf("%susers/%s/sshPublicKeys/%s",
+		osLoginBasePath, url.PathEscape(user), url.PathEscape(fingerprint))
+	return d.doJSON("DELETE", u, nil, nil)
+}
+
+func (d *driverGCE) GetTokenUserEmail() (string, error) {
+	var info struct {
+		Email string `json:"email"`
+	}
+	if err := d.doJSON("GET", userInfoEndpoint, nil, &info); err != nil {
+		return "", err
+	}
+	if info.Email == "" {
+		return "", fmt.Errorf("The credentials have no email, they need the userinfo.email scope")
+	}
+	return info.Email, nil
+}
@@ -19,6 +19,7 @@ func TestStepCreateImage(t *testing.T) {
 	defer step.Cleanup(state)
 
 	c := state.Get("config").(*Config)
+	c.EnableSecureBoot = true
 	d := state.Get("driver").(*DriverMock)
 
 	// These are the values of the image the driver will return.
@@ -47,6 +48,7 @@ func TestStepCreateImage(t *testing.T) {
 	assert.Equal(t, d.CreateImageDisk, c.DiskName, "Incorrect disk passed to driver.")
 	assert.Equal(t, d.CreateImageLabels, c.ImageLabels, "Incorrect image_labels passed to driver.")
 	assert.Equal(t, d.CreateImageLicenses, c.ImageLicenses, "Incorrect image_licenses passed to driver.")
+	assert.Equal(t, d.CreateImageGuestOsFeatures, []string{"UEFI_COMPATIBLE"}, "Incorrect guest OS features passed to driver.")
 }
 
 func TestStepCreateImage_errorOnChannel(t *testing.T) {
@@ -28,7 +28,10 @@ func (c *Config) createInstanceMetadata(sourceImage *Image, sshPublicKey string)
 	// Merge any existing ssh keys with our public key, unless there is no
 	// supplied public key. This is possible if a private_key_file was
 	// specified.
-	if sshPublicKey != "" {
+	if c.UseOSLogin {
+		// The key is in the OS Login profile instead
+		instanceMetadata[EnableOSLoginKey] = "TRUE"
+	} else if sshPublicKey != "" {
 		sshMetaKey := "sshKeys"
 		sshKeys := fmt.Sprintf("%s:%s", c.Comm.SSHUsername, sshPublicKey)
 		if confSshKeys, exists := instanceMetadata[sshMetaKey]; exists {
@@ -58,6 +61,17 @@ func (c *Config) createInstanceMetadata(sourceImage *Image, sshPublicKey string)
 	return instanceMetadata, err
 }
 
+// instanceTags returns the network tags of the instance, with the tag of
+// the IAP firewall rule.
+func (c *Config) instanceTags() []string {
+	if !c.UseIAP {
+		return c.Tags
+	}
+	tags := make([]string, len(c.Tags), len(c.Tags)+1)
+	copy(tags, c.Tags)
+	return append(tags, c.iapFirewallRule)
+}
+
 func getImage(c *Config, d Driver) (*Image, error) {
 	name := c.SourceImageFamily
 	fromFamily := true
@@ -103,10 +117,14 @@ func (s *StepCreateInstance) Run(_ context.Context, state multistep.StateBag) mu
 		AcceleratorType:              c.AcceleratorType,
 		AcceleratorCount:             c.AcceleratorCount,
 		Address:                      c.Address,
+		ConfidentialInstanceType:     c.ConfidentialInstanceType,
 		Description:                  "New instance created by Packer",
 		DisableDefaultServiceAccount: c.DisableDefaultServiceAccount,
 		DiskSizeGb:                   c.DiskSizeGb,
 		DiskType:                     c.DiskType,
+		EnableIntegrityMonitoring:    c.EnableIntegrityMonitoring,
+		EnableSecureBoot:             c.EnableSecureBoot,
+		EnableVtpm:                   c.EnableVtpm,
 		Image:                        sourceImage,
 		Labels:                       c.Labels,
 		MachineType:                  c.MachineType,
@@ -117,11 +135,12 @@ func (s *StepCreateInstance) Run(_ context.Context, state multistep.StateBag) mu
 		OmitExternalIP:               c.OmitExternalIP,
 		OnHostMaintenance:            c.OnHostMaintenance,
 		Preemptible:                  c.Preemptible,
+		ProvisioningModel:            c.ProvisioningModel,
 		Region:                       c.Region,
 		ServiceAccountEmail:          c.ServiceAccountEmail,
 		Scopes:                       c.Scopes,
 		Subnetwork:                   c.Subnetwork,
-		Tags:                         c.Tags,
+		Tags:                         c.instanceTags(),
 		Zone:                         c.Zone,
 	})
 
@@ -151,6 +170,12 @@ func (s *StepCreateInstance) Run(_ context.Context, state multistep.StateBag) mu
 
 	// Things succeeded, store the name so we can remove it later
 	state.Put("instance_name", name)
+	state.Put("machine", &packer.Machine{
+		Cloud:     "googlecompute",
+		ID:        name,
+		Zone:      c.Zone,
+		ProjectID: c.ProjectId,
+	})
 
 	return multistep.ActionContinue
 }
@@ -170,6 +195,18 @@ func (s *StepCreateInstance) Cleanup(state multistep.StateBag) {
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 
+	// The builder retries the build if the instance failed it by being
+	// preempted
+	if _, failed := state.GetOk("error"); failed && config.isPreemptible() {
+		preempted, err := driver.InstancePreempted(config.Zone, name)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error checking whether the instance was preempted: %s", err))
+		} else if preempted {
+			ui.Message("The instance was preempted.")
+			state.Put("instance_preempted", true)
+		}
+	}
+
 	ui.Say("Deleting instance...")
 	errCh, err := driver.DeleteInstance(config.Zone, name)
 	if err == nil {
@@ -248,6 +248,42 @@ func TestStepCreateInstance_errorTimeout(t *testing.T) {
 	assert.False(t, ok, "State should not have an instance name.")
 }
 
+func TestStepCreateInstance_preempted(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+
+	c := state.Get("config").(*Config)
+	c.Preemptible = true
+	d := state.Get("driver").(*DriverMock)
+	d.InstancePreemptedResult = true
+
+	// a failed build with a preempted instance is retried
+	state.Put("instance_name", "foo")
+	state.Put("error", errors.New("error"))
+	step.Cleanup(state)
+
+	assert.Equal(t, d.InstancePreemptedName, "foo", "Incorrect instance name passed to driver.")
+	_, ok := state.GetOk("instance_preempted")
+	assert.True(t, ok, "State should have the preemption.")
+	assert.Equal(t, d.DeleteInstanceName, "foo", "The instance should have been deleted.")
+}
+
+func TestStepCreateInstance_notPreemptible(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+
+	d := state.Get("driver").(*DriverMock)
+	d.InstancePreemptedResult = true
+
+	state.Put("instance_name", "foo")
+	state.Put("error", errors.New("error"))
+	step.Cleanup(state)
+
+	assert.Equal(t, d.InstancePreemptedName, "", "Standard instances should not be checked for preemption.")
+	_, ok := state.GetOk("instance_preempted")
+	assert.False(t, ok, "State should not have a preemption.")
+}
+
 func TestStepCreateInstance_noServiceAccount(t *testing.T) {
 	state := testState(t)
 	step := new(StepCreateInstance)
@@ -325,3 +361,20 @@ func TestCreateInstanceMetadata_noPublicKey(t *testing.T) {
 	// ensure the ssh metadata hasn't changed
 	assert.Equal(t, metadata["sshKeys"], sshKeys, "Instance metadata should not have been modified")
 }
+
+func TestCreateInstanceMetadata_osLogin(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	c.UseOSLogin = true
+	image := StubImage("test-image", "test-project", []string{}, 100)
+	key := "abcdefgh12345678"
+
+	// create our metadata
+	metadata, err := c.createInstanceMetadata(image, key)
+
+	assert.True(t, err == nil, "Metadata creation should have succeeded.")
+
+	// ensure OS Login is enabled instead of listing our key
+	assert.Equal(t, metadata[EnableOSLoginKey], "TRUE", "Instance metadata should enable OS Login")
+	assert.False(t, strings.Contains(metadata["sshKeys"], key), "Instance metadata should not contain provided key")
+}
@@ -0,0 +1,97 @@
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The deprecation states of an image, from the least to the most severe.
+var deprecationStateSeverity = map[string]int{
+	"":           0,
+	"DEPRECATED": 1,
+	"OBSOLETE":   2,
+	"DELETED":    3,
+}
+
+// StepDeprecateFamilyImages represents a Packer build step that rolls the
+// image family over to the new image: the images it replaces are marked
+// DEPRECATED, and OBSOLETE once the family has enough newer images.
+type StepDeprecateFamilyImages int
+
+// familyImageStates returns the deprecation state of each image of the
+// family, the newest first, that should be raised with the new image. The
+// new image itself and the images already in a more severe state are left
+// alone.
+func (c *Config) familyImageStates(images []*Image) map[string]string {
+	states := make(map[string]string)
+	newer := 0
+	for _, image := range images {
+		if image.Name == c.ImageName {
+			continue
+		}
+		newer++
+
+		var state string
+		if c.ImageFamilyObsoleteAfter > 0 && newer >= c.ImageFamilyObsoleteAfter {
+			state = "OBSOLETE"
+		} else if c.ImageFamilyDeprecatePrevious {
+			state = "DEPRECATED"
+		}
+		if deprecationStateSeverity[state] > deprecationStateSeverity[image.DeprecationState] {
+			states[image.Name] = state
+		}
+	}
+	return states
+}
+
+// Run executes the Packer build step that deprecates the previous images of
+// the family.
+func (s *StepDeprecateFamilyImages) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	image := state.Get("image").(*Image)
+
+	ui.Say(fmt.Sprintf("Deprecating the previous images of the family %s...", config.ImageFamily))
+
+	images, err := driver.ListFamilyImages(config.ImageFamily)
+	if err != nil {
+		err := fmt.Errorf("Error listing the images of the family: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Deprecate in the order of the family, the newest first
+	states := config.familyImageStates(images)
+	for _, familyImage := range images {
+		newState, ok := states[familyImage.Name]
+		if !ok {
+			continue
+		}
+		ui.Message(fmt.Sprintf("Marking %s %s", familyImage.Name, newState))
+
+		errCh := driver.DeprecateImage(familyImage.Name, newState, image.SelfLink)
+		select {
+		case err = <-errCh:
+		case <-time.After(config.stateTimeout):
+			err = errors.New("time out while waiting for the image to be deprecated")
+		}
+		if err != nil {
+			err := fmt.Errorf("Error deprecating the image %s: %s", familyImage.Name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// Cleanup.
+func (s *StepDeprecateFamilyImages) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,81 @@
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepDeprecateFamilyImages_impl(t *testing.T) {
+	var _ multistep.Step = new(StepDeprecateFamilyImages)
+}
+
+func testFamilyImages(newImage string) []*Image {
+	return []*Image{
+		{Name: newImage},
+		{Name: "image-3"},
+		{Name: "image-2", DeprecationState: "DEPRECATED"},
+		{Name: "image-1", DeprecationState: "OBSOLETE"},
+	}
+}
+
+func TestStepDeprecateFamilyImages(t *testing.T) {
+	state := testState(t)
+	step := new(StepDeprecateFamilyImages)
+	defer step.Cleanup(state)
+
+	c := state.Get("config").(*Config)
+	c.ImageFamilyDeprecatePrevious = true
+	c.ImageFamilyObsoleteAfter = 2
+	d := state.Get("driver").(*DriverMock)
+	d.ListFamilyImagesResult = testFamilyImages(c.ImageName)
+	state.Put("image", &Image{Name: c.ImageName, SelfLink: "https://selflink/new-image"})
+
+	// run the step
+	action := step.Run(context.Background(), state)
+	assert.Equal(t, action, multistep.ActionContinue, "Step did not pass.")
+
+	// Verify proper args passed to driver.
+	assert.Equal(t, d.ListFamilyImagesFamily, c.ImageFamily, "Incorrect family passed to driver.")
+	assert.Equal(t, d.DeprecateImageStates, map[string]string{
+		"image-3": "DEPRECATED",
+		"image-2": "OBSOLETE",
+	}, "Incorrect images deprecated.")
+	assert.Equal(t, d.DeprecateImageReplacement, "https://selflink/new-image", "Incorrect replacement passed to driver.")
+}
+
+func TestStepDeprecateFamilyImages_obsoleteOnly(t *testing.T) {
+	c := testConfigStruct(t)
+	c.ImageFamilyObsoleteAfter = 1
+
+	states := c.familyImageStates(testFamilyImages(c.ImageName))
+	assert.Equal(t, states, map[string]string{
+		"image-3": "OBSOLETE",
+		"image-2": "OBSOLETE",
+	}, "Incorrect images deprecated.")
+}
+
+func TestStepDeprecateFamilyImages_errorOnChannel(t *testing.T) {
+	state := testState(t)
+	step := new(StepDeprecateFamilyImages)
+	defer step.Cleanup(state)
+
+	errCh := make(chan error, 1)
+	errCh <- errors.New("error")
+
+	c := state.Get("config").(*Config)
+	c.ImageFamilyDeprecatePrevious = true
+	d := state.Get("driver").(*DriverMock)
+	d.ListFamilyImagesResult = testFamilyImages(c.ImageName)
+	d.DeprecateImageErrCh = errCh
+	state.Put("image", &Image{Name: c.ImageName})
+
+	// run the step
+	action := step.Run(context.Background(), state)
+	assert.Equal(t, action, multistep.ActionHalt, "Step should not have passed.")
+	_, ok := state.GetOk("error")
+	assert.True(t, ok, "State should have an error.")
+}
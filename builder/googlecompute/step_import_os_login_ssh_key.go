@@ -0,0 +1,93 @@
+package googlecompute
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"golang.org/x/crypto/ssh"
+)
+
+// StepImportOSLoginSSHKey represents a Packer build step that imports the SSH
+// public key to the OS Login profile of the account Packer authenticates as,
+// instead of adding it to the metadata of the instance. The key is deleted
+// from the profile in the cleanup.
+type StepImportOSLoginSSHKey struct {
+	accountEmail string
+	fingerprint  string
+}
+
+// Run executes the Packer build step that imports the SSH key. The username
+// of the communicator is set to the one of the OS Login profile.
+func (s *StepImportOSLoginSSHKey) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	halt := func(err error) multistep.StepAction {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// The public key of a private_key_file isn't in the state
+	sshPublicKey := state.Get("ssh_public_key").(string)
+	if sshPublicKey == "" {
+		signer, err := ssh.ParsePrivateKey([]byte(state.Get("ssh_private_key").(string)))
+		if err != nil {
+			return halt(fmt.Errorf("Error reading the SSH private key: %s", err))
+		}
+		sshPublicKey = string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	}
+
+	accountEmail := config.Account.ClientEmail
+	if accountEmail == "" {
+		var err error
+		accountEmail, err = driver.GetTokenUserEmail()
+		if err != nil {
+			return halt(fmt.Errorf("Error getting the account of the credentials: %s", err))
+		}
+	}
+
+	// Service accounts are exempt from the 2-step verification of OS Login,
+	// but Packer can't log in as a user who has to verify.
+	if !strings.HasSuffix(accountEmail, ".gserviceaccount.com") {
+		ui.Message(fmt.Sprintf(
+			"%s isn't a service account, the build fails if OS Login requires it to use 2-step verification.", accountEmail))
+	}
+
+	ui.Say(fmt.Sprintf("Importing the SSH key to the OS Login profile of %s...", accountEmail))
+	username, fingerprint, err := driver.ImportOSLoginSSHKey(accountEmail, sshPublicKey)
+	if err != nil {
+		return halt(fmt.Errorf("Error importing the SSH key to OS Login: %s", err))
+	}
+	s.accountEmail = accountEmail
+	s.fingerprint = fingerprint
+
+	ui.Message(fmt.Sprintf("OS Login username: %s", username))
+	config.Comm.SSHUsername = username
+	return multistep.ActionContinue
+}
+
+// Cleanup deletes the SSH key from the OS Login profile.
+func (s *StepImportOSLoginSSHKey) Cleanup(state multistep.StateBag) {
+	if s.fingerprint == "" {
+		return
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deleting the SSH key from the OS Login profile...")
+	if err := driver.DeleteOSLoginSSHKey(s.accountEmail, s.fingerprint); err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error deleting the SSH key from the OS Login profile. Please delete it manually.\n\n"+
+				"Account: %s\n"+
+				"Fingerprint: %s\n"+
+				"Error: %s", s.accountEmail, s.fingerprint, err))
+		return
+	}
+	s.fingerprint = ""
+}
@@ -0,0 +1,100 @@
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepImportOSLoginSSHKey_impl(t *testing.T) {
+	var _ multistep.Step = new(StepImportOSLoginSSHKey)
+}
+
+func TestStepImportOSLoginSSHKey(t *testing.T) {
+	state := testState(t)
+	step := new(StepImportOSLoginSSHKey)
+	defer step.Cleanup(state)
+
+	state.Put("ssh_public_key", "ssh-rsa key")
+	c := state.Get("config").(*Config)
+	c.Account.ClientEmail = "packer@hashicorp.iam.gserviceaccount.com"
+	d := state.Get("driver").(*DriverMock)
+	d.ImportOSLoginSSHKeyResultUsername = "sa_1234"
+	d.ImportOSLoginSSHKeyResultFingerprint = "fingerprint"
+
+	// run the step
+	assert.Equal(t, step.Run(context.Background(), state), multistep.ActionContinue, "Step should have passed and continued.")
+
+	// Verify the key was imported for the account, and the username of the profile is used.
+	assert.Equal(t, d.ImportOSLoginSSHKeyUser, c.Account.ClientEmail, "Incorrect user passed to driver.")
+	assert.Equal(t, d.ImportOSLoginSSHKeyKey, "ssh-rsa key", "Incorrect key passed to driver.")
+	assert.Equal(t, c.Comm.SSHUsername, "sa_1234", "The SSH username should be the one of the OS Login profile.")
+
+	// cleanup
+	step.Cleanup(state)
+	assert.Equal(t, d.DeleteOSLoginSSHKeyUser, c.Account.ClientEmail, "Incorrect user passed to driver.")
+	assert.Equal(t, d.DeleteOSLoginSSHKeyFingerprint, "fingerprint", "Incorrect fingerprint passed to driver.")
+}
+
+func TestStepImportOSLoginSSHKey_defaultCredentials(t *testing.T) {
+	state := testState(t)
+	step := new(StepImportOSLoginSSHKey)
+	defer step.Cleanup(state)
+
+	state.Put("ssh_public_key", "ssh-rsa key")
+	c := state.Get("config").(*Config)
+	c.Account.ClientEmail = ""
+	d := state.Get("driver").(*DriverMock)
+	d.GetTokenUserEmailResult = "user@example.com"
+
+	// run the step
+	assert.Equal(t, step.Run(context.Background(), state), multistep.ActionContinue, "Step should have passed and continued.")
+	assert.Equal(t, d.ImportOSLoginSSHKeyUser, "user@example.com", "Incorrect user passed to driver.")
+}
+
+func TestStepImportOSLoginSSHKey_error(t *testing.T) {
+	state := testState(t)
+	step := new(StepImportOSLoginSSHKey)
+	defer step.Cleanup(state)
+
+	state.Put("ssh_public_key", "ssh-rsa key")
+	d := state.Get("driver").(*DriverMock)
+	d.ImportOSLoginSSHKeyErr = errors.New("error")
+
+	// run the step
+	assert.Equal(t, step.Run(context.Background(), state), multistep.ActionHalt, "Step should have failed and halted.")
+	_, ok := state.GetOk("error")
+	assert.True(t, ok, "State should have an error.")
+
+	// cleanup has nothing to delete
+	step.Cleanup(state)
+	assert.Equal(t, d.DeleteOSLoginSSHKeyFingerprint, "", "No key should have been deleted.")
+}
+
+func TestOSLoginProfile(t *testing.T) {
+	profile := &osLoginProfile{
+		Name: "users/packer@hashicorp.iam.gserviceaccount.com",
+		PosixAccounts: []osLoginPosixAccount{
+			{Username: "other"},
+			{Primary: true, Username: "sa_1234"},
+		},
+		SSHPublicKeys: map[string]osLoginSSHPublicKey{
+			"abc": {Key: "ssh-rsa other"},
+			"def": {Key: "ssh-rsa key\n"},
+		},
+	}
+
+	username, err := profile.username()
+	assert.Nil(t, err, "The profile should have a primary account.")
+	assert.Equal(t, username, "sa_1234", "Incorrect username.")
+
+	fingerprint, err := profile.fingerprint("ssh-rsa key\n")
+	assert.Nil(t, err, "The profile should have the key.")
+	assert.Equal(t, fingerprint, "def", "Incorrect fingerprint.")
+
+	_, err = profile.fingerprint("ssh-rsa missing")
+	assert.NotNil(t, err, "The profile should not have the key.")
+}
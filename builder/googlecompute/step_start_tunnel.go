@@ -0,0 +1,204 @@
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The range IAP forwards TCP traffic from.
+// See https://cloud.google.com/iap/docs/using-tcp-forwarding
+var iapSourceRanges = []string{"35.235.240.0/20"}
+
+// StepStartTunnel represents a Packer build step that forwards a local port
+// to the communicator port of the instance through Identity-Aware Proxy, so
+// that instances without an external IP can be reached. It lets IAP through
+// the firewall of the network while the tunnel is open.
+//
+// The communicator is then pointed to the local port by overriding
+// "instance_ip" and the port of the communicator config, which is restored
+// in the cleanup.
+type StepStartTunnel struct {
+	// startTunnel starts the tunnel listening on the local port, and returns
+	// a function stopping it. It defaults to running gcloud.
+	startTunnel func(args []string, env []string, localPort int, wait time.Duration) (func(), error)
+
+	network    string
+	remotePort int
+	stopTunnel func()
+}
+
+// iapTunnelArgs returns the gcloud arguments forwarding the local port to the
+// remote port of the instance.
+func (c *Config) iapTunnelArgs(instanceName string, localPort, remotePort int) []string {
+	return []string{
+		"compute", "start-iap-tunnel", instanceName, strconv.Itoa(remotePort),
+		fmt.Sprintf("--local-host-port=localhost:%d", localPort),
+		fmt.Sprintf("--zone=%s", c.Zone),
+		fmt.Sprintf("--project=%s", c.ProjectId),
+	}
+}
+
+// freeLocalPort returns a TCP port nothing listens on.
+func freeLocalPort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// startGcloudTunnel runs gcloud and waits for it to listen on the local port.
+func startGcloudTunnel(args []string, env []string, localPort int, wait time.Duration) (func(), error) {
+	cmd := exec.Command("gcloud", args...)
+	cmd.Env = append(os.Environ(), env...)
+	log.Printf("Starting the IAP tunnel: gcloud %v", args)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Error running gcloud: %s", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	stop := func() {
+		cmd.Process.Kill()
+		<-exited
+	}
+
+	address := fmt.Sprintf("localhost:%d", localPort)
+	deadline := time.Now().Add(wait)
+	for {
+		select {
+		case err := <-exited:
+			return nil, fmt.Errorf("gcloud exited before the tunnel was open: %v", err)
+		default:
+		}
+
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			conn.Close()
+			return stop, nil
+		}
+		if time.Now().After(deadline) {
+			stop()
+			return nil, errors.New("time out while waiting for the IAP tunnel to open")
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// Run executes the Packer build step that opens the IAP tunnel.
+func (s *StepStartTunnel) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	instanceName := state.Get("instance_name").(string)
+	remotePort := config.Comm.Port()
+
+	halt := func(err error) multistep.StepAction {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Allowing IAP through the firewall...")
+	network, err := driver.GetInstanceNetwork(config.Zone, instanceName)
+	if err != nil {
+		return halt(fmt.Errorf("Error getting the network of the instance: %s", err))
+	}
+	errCh, err := driver.CreateFirewallRule(config.iapFirewallRule, network, iapSourceRanges, config.iapFirewallRule, remotePort)
+	if err == nil {
+		select {
+		case err = <-errCh:
+		case <-time.After(config.stateTimeout):
+			err = errors.New("time out while waiting for the firewall rule to be created")
+		}
+	}
+	if err != nil {
+		return halt(fmt.Errorf("Error creating the firewall rule: %s", err))
+	}
+	s.network = network
+
+	localPort := config.IAPLocalhostPort
+	if localPort == 0 {
+		localPort, err = freeLocalPort()
+		if err != nil {
+			return halt(fmt.Errorf("Error finding a free local port: %s", err))
+		}
+	}
+
+	ui.Say(fmt.Sprintf("Opening the IAP tunnel from localhost:%d to port %d...", localPort, remotePort))
+	var env []string
+	if config.AccountFile != "" {
+		env = append(env, fmt.Sprintf("CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE=%s", config.AccountFile))
+	}
+	startTunnel := s.startTunnel
+	if startTunnel == nil {
+		startTunnel = startGcloudTunnel
+	}
+	stop, err := startTunnel(
+		config.iapTunnelArgs(instanceName, localPort, remotePort), env,
+		localPort, time.Duration(config.IAPTunnelLaunchWait)*time.Second)
+	if err != nil {
+		return halt(fmt.Errorf("Error opening the IAP tunnel: %s", err))
+	}
+	s.stopTunnel = stop
+	s.remotePort = remotePort
+
+	state.Put("instance_ip", "localhost")
+	if config.Comm.Type == "winrm" {
+		config.Comm.WinRMPort = localPort
+	} else {
+		config.Comm.SSHPort = localPort
+	}
+	return multistep.ActionContinue
+}
+
+// Cleanup closes the tunnel and deletes the firewall rule.
+func (s *StepStartTunnel) Cleanup(state multistep.StateBag) {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	if s.stopTunnel != nil {
+		s.stopTunnel()
+		s.stopTunnel = nil
+
+		if config.Comm.Type == "winrm" {
+			config.Comm.WinRMPort = s.remotePort
+		} else {
+			config.Comm.SSHPort = s.remotePort
+		}
+	}
+
+	if s.network == "" {
+		return
+	}
+	ui.Say("Deleting the IAP firewall rule...")
+	errCh, err := driver.DeleteFirewallRule(config.iapFirewallRule, s.network)
+	if err == nil {
+		select {
+		case err = <-errCh:
+		case <-time.After(config.stateTimeout):
+			err = errors.New("time out while waiting for the firewall rule to be deleted")
+		}
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error deleting the firewall rule. Please delete it manually.\n\n"+
+				"Name: %s\n"+
+				"Error: %s", config.iapFirewallRule, err))
+		return
+	}
+	s.network = ""
+}
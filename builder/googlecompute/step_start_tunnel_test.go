@@ -0,0 +1,101 @@
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepStartTunnel_impl(t *testing.T) {
+	var _ multistep.Step = new(StepStartTunnel)
+}
+
+func testStateStepStartTunnel(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	state.Put("instance_name", "foo")
+	state.Put("instance_ip", "10.0.0.2")
+
+	c := state.Get("config").(*Config)
+	c.UseIAP = true
+	c.IAPLocalhostPort = 8022
+	c.iapFirewallRule = "packer-iap-rule"
+	c.Comm.SSHPort = 22
+	return state
+}
+
+func TestStepStartTunnel(t *testing.T) {
+	state := testStateStepStartTunnel(t)
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(*DriverMock)
+	d.GetInstanceNetworkResult = "https://www.googleapis.com/compute/v1/projects/hashicorp/global/networks/default"
+
+	var args []string
+	stopped := false
+	step := &StepStartTunnel{
+		startTunnel: func(a []string, env []string, localPort int, wait time.Duration) (func(), error) {
+			args = a
+			return func() { stopped = true }, nil
+		},
+	}
+
+	// run the step
+	assert.Equal(t, step.Run(context.Background(), state), multistep.ActionContinue, "Step should have passed and continued.")
+
+	// Verify the tunnel and the firewall rule.
+	assert.Equal(t, args, []string{
+		"compute", "start-iap-tunnel", "foo", "22",
+		"--local-host-port=localhost:8022", "--zone=us-east1-a", "--project=hashicorp",
+	}, "Incorrect gcloud arguments.")
+	assert.Equal(t, d.CreateFirewallRuleName, "packer-iap-rule", "Incorrect firewall rule name passed to driver.")
+	assert.Equal(t, d.CreateFirewallRuleNetwork, d.GetInstanceNetworkResult, "Incorrect network passed to driver.")
+	assert.Equal(t, d.CreateFirewallRuleSourceRanges, iapSourceRanges, "Incorrect source ranges passed to driver.")
+	assert.Equal(t, d.CreateFirewallRuleTargetTag, "packer-iap-rule", "Incorrect target tag passed to driver.")
+	assert.Equal(t, d.CreateFirewallRulePort, 22, "Incorrect port passed to driver.")
+
+	// Verify the communicator goes through the tunnel.
+	assert.Equal(t, state.Get("instance_ip"), "localhost", "The communicator should connect to localhost.")
+	assert.Equal(t, c.Comm.Port(), 8022, "The communicator should connect to the local port.")
+
+	// cleanup
+	step.Cleanup(state)
+	assert.True(t, stopped, "The tunnel should have been stopped.")
+	assert.Equal(t, d.DeleteFirewallRuleName, "packer-iap-rule", "Incorrect firewall rule name passed to driver.")
+	assert.Equal(t, d.DeleteFirewallRuleNetwork, d.GetInstanceNetworkResult, "Incorrect network passed to driver.")
+}
+
+func TestStepStartTunnel_errorStartingTunnel(t *testing.T) {
+	state := testStateStepStartTunnel(t)
+	d := state.Get("driver").(*DriverMock)
+	d.GetInstanceNetworkResult = "global/networks/default"
+
+	step := &StepStartTunnel{
+		startTunnel: func([]string, []string, int, time.Duration) (func(), error) {
+			return nil, errors.New("error")
+		},
+	}
+
+	// run the step
+	assert.Equal(t, step.Run(context.Background(), state), multistep.ActionHalt, "Step should have failed and halted.")
+	_, ok := state.GetOk("error")
+	assert.True(t, ok, "State should have an error.")
+	assert.Equal(t, state.Get("instance_ip"), "10.0.0.2", "The instance IP should not be overridden.")
+
+	// cleanup still deletes the firewall rule
+	step.Cleanup(state)
+	assert.Equal(t, d.DeleteFirewallRuleName, "packer-iap-rule", "Incorrect firewall rule name passed to driver.")
+}
+
+func TestConfigInstanceTags(t *testing.T) {
+	c := testConfigStruct(t)
+	c.Tags = []string{"foo"}
+	assert.Equal(t, c.instanceTags(), []string{"foo"}, "Incorrect tags without IAP.")
+
+	c.UseIAP = true
+	c.iapFirewallRule = "packer-iap-rule"
+	assert.Equal(t, c.instanceTags(), []string{"foo", "packer-iap-rule"}, "Incorrect tags with IAP.")
+	assert.Equal(t, c.Tags, []string{"foo"}, "The configured tags should not change.")
+}
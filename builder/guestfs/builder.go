@@ -0,0 +1,95 @@
+// The guestfs package contains a packer.Builder implementation that
+// customizes an existing disk image with libguestfs, without ever booting
+// it.
+package guestfs
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/imagemount"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The unique ID for this builder
+const BuilderId = "packer.guestfs"
+
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, errs := NewConfig(raws...)
+	if errs != nil {
+		return warnings, errs
+	}
+	b.config = c
+
+	return warnings, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	steps := []multistep.Step{
+		new(stepPrepareOutputDir),
+		new(stepConvertImage),
+		new(stepCustomize),
+		&imagemount.StepMountImage{
+			Config: &b.config.ImageMount,
+			Ctx:    b.config.ctx,
+			Image:  b.imagePath(),
+			Format: b.config.Format,
+		},
+		new(common.StepProvision),
+		new(imagemount.StepUnmountImage),
+	}
+
+	// Setup the state bag
+	state := new(multistep.BasicStateBag)
+	state.Put("config", b.config)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+	state.Put("wrappedCommand", b.config.ImageMount.CommandWrapper(b.config.ctx))
+
+	// Run
+	b.runner = common.NewRunnerWithPauseFn(steps, b.config.PackerConfig, ui, state)
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	// If we were interrupted or cancelled, then just exit.
+	if _, ok := state.GetOk(multistep.StateCancelled); ok {
+		return nil, errors.New("Build was cancelled.")
+	}
+
+	if _, ok := state.GetOk(multistep.StateHalted); ok {
+		return nil, errors.New("Build was halted.")
+	}
+
+	artifact := &Artifact{
+		dir:   b.config.OutputDir,
+		f:     []string{b.imagePath()},
+		state: make(map[string]interface{}),
+	}
+	artifact.state["diskName"] = b.config.ImageName
+	artifact.state["diskType"] = b.config.Format
+
+	return artifact, nil
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
+
+func (b *Builder) imagePath() string {
+	return filepath.Join(b.config.OutputDir, b.config.ImageName)
+}
@@ -0,0 +1,160 @@
+package guestfs
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig(t *testing.T) (map[string]interface{}, func()) {
+	f, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+
+	return map[string]interface{}{
+		"source_image":            f.Name(),
+		packer.BuildNameConfigKey: "foo",
+	}, func() { os.Remove(f.Name()) }
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Error("Builder must implement builder.")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	config, cleanup := testConfig(t)
+	defer cleanup()
+
+	var b Builder
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Format != "qcow2" {
+		t.Errorf("bad format: %s", b.config.Format)
+	}
+	if b.config.OutputDir != "output-foo" {
+		t.Errorf("bad output dir: %s", b.config.OutputDir)
+	}
+	if b.config.ImageName != "packer-foo" {
+		t.Errorf("bad image name: %s", b.config.ImageName)
+	}
+	if !b.config.ImageMount.HostChroot() {
+		t.Errorf("bad provision mode: %s", b.config.ImageMount.ProvisionMode)
+	}
+}
+
+func TestBuilderPrepare_SourceImage(t *testing.T) {
+	config, cleanup := testConfig(t)
+	defer cleanup()
+
+	var b Builder
+	config["source_image"] = "/i/dont/exist"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	delete(config, "source_image")
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Format(t *testing.T) {
+	config, cleanup := testConfig(t)
+	defer cleanup()
+
+	var b Builder
+	config["format"] = "vmdk"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["format"] = "raw"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_ProvisionMode(t *testing.T) {
+	config, cleanup := testConfig(t)
+	defer cleanup()
+
+	var b Builder
+	config["provision_mode"] = "guest"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_UploadFiles(t *testing.T) {
+	config, cleanup := testConfig(t)
+	defer cleanup()
+
+	var b Builder
+	config["upload_files"] = []map[string]interface{}{
+		{"source": config["source_image"], "destination": "/etc/foo"},
+	}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["upload_files"] = []map[string]interface{}{
+		{"source": config["source_image"]},
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepCustomize_Args(t *testing.T) {
+	config, cleanup := testConfig(t)
+	defer cleanup()
+	config["hostname"] = "box"
+	config["install_packages"] = []string{"nginx", "curl"}
+	config["root_password"] = "secret"
+	config["run_commands"] = []string{"echo 'hi'"}
+
+	var b Builder
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	s := new(stepCustomize)
+	args, err := s.customizeArgs(b.config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer s.Cleanup(nil)
+
+	command := strings.Join(args, " ")
+	if strings.Contains(command, "secret") {
+		t.Fatalf("password should not be on the command line: %s", command)
+	}
+	if len(s.passwordFiles) != 1 {
+		t.Fatalf("bad: %#v", s.passwordFiles)
+	}
+
+	expected := "--hostname 'box' --install 'nginx,curl' " +
+		"--root-password 'file:" + s.passwordFiles[0] + "' " +
+		`--run-command 'echo '"'"'hi'"'"''`
+	if command != expected {
+		t.Fatalf("bad: %s", command)
+	}
+}
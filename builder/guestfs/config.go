@@ -0,0 +1,110 @@
+package guestfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/imagemount"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// Upload is a file copied from the host into the image.
+type Upload struct {
+	Source      string `mapstructure:"source"`
+	Destination string `mapstructure:"destination"`
+}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	ImageMount          imagemount.Config `mapstructure:",squash"`
+
+	SourceImage  string `mapstructure:"source_image"`
+	SourceFormat string `mapstructure:"source_format"`
+	Format       string `mapstructure:"format"`
+	OutputDir    string `mapstructure:"output_directory"`
+	ImageName    string `mapstructure:"image_name"`
+
+	Hostname        string            `mapstructure:"hostname"`
+	InstallPackages []string          `mapstructure:"install_packages"`
+	UploadFiles     []Upload          `mapstructure:"upload_files"`
+	RootPassword    string            `mapstructure:"root_password"`
+	Passwords       map[string]string `mapstructure:"passwords"`
+	RunCommands     []string          `mapstructure:"run_commands"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	var c Config
+	err := config.Decode(&c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"host_command_wrapper",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var errs *packer.MultiError
+
+	if c.SourceImage == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("source_image is required"))
+	} else if _, err := os.Stat(c.SourceImage); err != nil {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_image is invalid: %s", err))
+	}
+
+	if c.Format == "" {
+		c.Format = "qcow2"
+	}
+	if c.Format != "qcow2" && c.Format != "raw" {
+		errs = packer.MultiErrorAppend(errs, errors.New("format must be 'qcow2' or 'raw'"))
+	}
+
+	if c.OutputDir == "" {
+		c.OutputDir = fmt.Sprintf("output-%s", c.PackerBuildName)
+	}
+	if !c.PackerForce {
+		if _, err := os.Stat(c.OutputDir); err == nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Output directory '%s' already exists. It must not exist.", c.OutputDir))
+		}
+	}
+
+	if c.ImageName == "" {
+		c.ImageName = fmt.Sprintf("packer-%s", c.PackerBuildName)
+	}
+
+	for i, upload := range c.UploadFiles {
+		if upload.Source == "" || upload.Destination == "" {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("upload_files %d: source and destination are required", i+1))
+		} else if _, err := os.Stat(upload.Source); err != nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("upload_files %d: source is invalid: %s", i+1, err))
+		}
+	}
+
+	// Provisioners always run in the mounted image, as it's never booted.
+	if c.ImageMount.ProvisionMode == "" {
+		c.ImageMount.ProvisionMode = imagemount.ProvisionModeHostChroot
+	}
+	if c.ImageMount.ProvisionMode != imagemount.ProvisionModeHostChroot {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("provision_mode can only be %q", imagemount.ProvisionModeHostChroot))
+	}
+	errs = packer.MultiErrorAppend(errs, c.ImageMount.Prepare(&c.ctx)...)
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	return &c, nil, nil
+}
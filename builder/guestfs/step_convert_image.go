@@ -0,0 +1,47 @@
+package guestfs
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepConvertImage copies the source image into the output directory,
+// converting it to the output format, so that the source image itself is
+// never modified.
+//
+// Produces:
+//   image_path string - The path of the image being built.
+type stepConvertImage struct{}
+
+func (s *stepConvertImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	imagePath := filepath.Join(config.OutputDir, config.ImageName)
+
+	command := "qemu-img convert"
+	if config.SourceFormat != "" {
+		command += fmt.Sprintf(" -f %s", config.SourceFormat)
+	}
+	command += fmt.Sprintf(" -O %s %s %s",
+		config.Format, shellQuote(config.SourceImage), shellQuote(imagePath))
+
+	ui.Say(fmt.Sprintf("Copying source image to %s...", imagePath))
+	if err := chroot.RunWrapped(wrapper, command); err != nil {
+		err := fmt.Errorf("Error copying source image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("image_path", imagePath)
+	return multistep.ActionContinue
+}
+
+func (s *stepConvertImage) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,127 @@
+package guestfs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepCustomize applies the customizations of the configuration to the
+// image with virt-customize. Passwords are written to temporary files
+// rather than passed on the command line, so they don't end up in logs
+// or in the process list.
+//
+// Uses:
+//   image_path string
+type stepCustomize struct {
+	passwordFiles []string
+}
+
+func (s *stepCustomize) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	imagePath := state.Get("image_path").(string)
+
+	args, err := s.customizeArgs(config)
+	if err != nil {
+		err := fmt.Errorf("Error preparing customizations: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if len(args) == 0 {
+		return multistep.ActionContinue
+	}
+
+	command := fmt.Sprintf("virt-customize -a %s --format %s %s",
+		shellQuote(imagePath), config.Format, strings.Join(args, " "))
+
+	ui.Say("Customizing image...")
+	if err := chroot.RunWrapped(wrapper, command); err != nil {
+		err := fmt.Errorf("Error customizing image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCustomize) Cleanup(state multistep.StateBag) {
+	for _, path := range s.passwordFiles {
+		os.Remove(path)
+	}
+	s.passwordFiles = nil
+}
+
+// customizeArgs returns the virt-customize arguments for the
+// customizations, which virt-customize applies in order.
+func (s *stepCustomize) customizeArgs(config *Config) ([]string, error) {
+	var args []string
+
+	if config.Hostname != "" {
+		args = append(args, "--hostname", shellQuote(config.Hostname))
+	}
+
+	if len(config.InstallPackages) > 0 {
+		args = append(args, "--install", shellQuote(strings.Join(config.InstallPackages, ",")))
+	}
+
+	for _, upload := range config.UploadFiles {
+		args = append(args, "--upload", shellQuote(upload.Source+":"+upload.Destination))
+	}
+
+	if config.RootPassword != "" {
+		path, err := s.passwordFile(config.RootPassword)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--root-password", shellQuote("file:"+path))
+	}
+
+	users := make([]string, 0, len(config.Passwords))
+	for user := range config.Passwords {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	for _, user := range users {
+		path, err := s.passwordFile(config.Passwords[user])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--password", shellQuote(user+":file:"+path))
+	}
+
+	for _, command := range config.RunCommands {
+		args = append(args, "--run-command", shellQuote(command))
+	}
+
+	return args, nil
+}
+
+func (s *stepCustomize) passwordFile(password string) (string, error) {
+	f, err := ioutil.TempFile("", "packer-password")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	s.passwordFiles = append(s.passwordFiles, f.Name())
+
+	if _, err := f.WriteString(password); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// shellQuote quotes s so that /bin/sh passes it as a single argument.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
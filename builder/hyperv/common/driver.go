@@ -96,6 +96,8 @@ type Driver interface {
 
 	CompactDisks(string, string) error
 
+	MergeDifferencingDisks(string, string) error
+
 	CopyExportedVirtualMachine(string, string, string, string) error
 
 	RestartVirtualMachine(string) error
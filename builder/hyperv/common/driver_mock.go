@@ -191,6 +191,11 @@ type DriverMock struct {
 	CompactDisks_VhdDir  string
 	CompactDisks_Err     error
 
+	MergeDifferencingDisks_Called  bool
+	MergeDifferencingDisks_ExpPath string
+	MergeDifferencingDisks_VhdDir  string
+	MergeDifferencingDisks_Err     error
+
 	CopyExportedVirtualMachine_Called     bool
 	CopyExportedVirtualMachine_ExpPath    string
 	CopyExportedVirtualMachine_OutputPath string
@@ -496,6 +501,13 @@ func (d *DriverMock) CompactDisks(expPath string, vhdDir string) error {
 	return d.CompactDisks_Err
 }
 
+func (d *DriverMock) MergeDifferencingDisks(expPath string, vhdDir string) error {
+	d.MergeDifferencingDisks_Called = true
+	d.MergeDifferencingDisks_ExpPath = expPath
+	d.MergeDifferencingDisks_VhdDir = vhdDir
+	return d.MergeDifferencingDisks_Err
+}
+
 func (d *DriverMock) CopyExportedVirtualMachine(expPath string, outputPath string, vhdDir string, vmDir string) error {
 	d.CopyExportedVirtualMachine_Called = true
 	d.CopyExportedVirtualMachine_ExpPath = expPath
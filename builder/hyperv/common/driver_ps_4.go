@@ -223,6 +223,10 @@ func (d *HypervPS4Driver) CompactDisks(expPath string, vhdDir string) error {
 	return hyperv.CompactDisks(expPath, vhdDir)
 }
 
+func (d *HypervPS4Driver) MergeDifferencingDisks(expPath string, vhdDir string) error {
+	return hyperv.MergeDifferencingDisks(expPath, vhdDir)
+}
+
 func (d *HypervPS4Driver) CopyExportedVirtualMachine(expPath string, outputPath string, vhdDir string, vmDir string) error {
 	return hyperv.CopyExportedVirtualMachine(expPath, outputPath, vhdDir, vmDir)
 }
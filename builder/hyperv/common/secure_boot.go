@@ -0,0 +1,31 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SecureBootTemplates are the secure boot templates Hyper-V ships with.
+var SecureBootTemplates = []string{
+	"MicrosoftWindows",
+	"MicrosoftUEFICertificateAuthority",
+	"OpenSourceShieldedVM",
+}
+
+var templateIdRe = regexp.MustCompile(`^\{?[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}\}?$`)
+
+// CheckSecureBootTemplate validates a secure_boot_template, which is either
+// the name of one of the SecureBootTemplates or the ID of a custom template.
+func CheckSecureBootTemplate(template string) error {
+	if template == "" || templateIdRe.MatchString(template) {
+		return nil
+	}
+	for _, t := range SecureBootTemplates {
+		if template == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("secure_boot_template must be one of %s, or the ID of a custom template: %s",
+		strings.Join(SecureBootTemplates, ", "), template)
+}
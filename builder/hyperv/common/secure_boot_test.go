@@ -0,0 +1,32 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestCheckSecureBootTemplate(t *testing.T) {
+	good := []string{
+		"",
+		"MicrosoftWindows",
+		"MicrosoftUEFICertificateAuthority",
+		"OpenSourceShieldedVM",
+		"1734c6e8-3154-4dda-ba5f-a874cc483422",
+		"{1734C6E8-3154-4DDA-BA5F-A874CC483422}",
+	}
+	for _, template := range good {
+		if err := CheckSecureBootTemplate(template); err != nil {
+			t.Fatalf("bad: %s: %s", template, err)
+		}
+	}
+
+	bad := []string{
+		"microsoftwindows",
+		"Linux",
+		"1734c6e8-3154-4dda-ba5f",
+	}
+	for _, template := range bad {
+		if err := CheckSecureBootTemplate(template); err == nil {
+			t.Fatalf("should have error: %s", template)
+		}
+	}
+}
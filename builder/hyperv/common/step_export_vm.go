@@ -19,6 +19,10 @@ type StepExportVm struct {
 	OutputDir      string
 	SkipCompaction bool
 	SkipExport     bool
+
+	// Convert the differencing disks into standalone disks, instead of
+	// keeping the chain of their parents.
+	MergeDifferencingDisks bool
 }
 
 func (s *StepExportVm) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -56,6 +60,18 @@ func (s *StepExportVm) Run(_ context.Context, state multistep.StateBag) multiste
 		expPath = filepath.Join(vmExportPath, vmName)
 	}
 
+	if s.MergeDifferencingDisks {
+		ui.Say("Merging differencing disks...")
+		err = driver.MergeDifferencingDisks(expPath, vhdDir)
+		if err != nil {
+			errorMsg = "Error merging differencing disks: %s"
+			err := fmt.Errorf(errorMsg, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	if s.SkipCompaction {
 		ui.Say("Skipping disk compaction...")
 	} else {
@@ -34,7 +34,13 @@ func (s *StepMountSecondaryDvdImages) Run(_ context.Context, state multistep.Sta
 	// For IDE, there are only 2 controllers (0,1) with 2 locations each (0,1)
 	var dvdProperties []DvdControllerProperties
 
-	for _, isoPath := range s.IsoPaths {
+	// The CD created from cd_files and cd_content is mounted last
+	isoPaths := append([]string{}, s.IsoPaths...)
+	if cdPath, ok := state.GetOk("cd_path"); ok {
+		isoPaths = append(isoPaths, cdPath.(string))
+	}
+
+	for _, isoPath := range isoPaths {
 		var properties DvdControllerProperties
 
 		controllerNumber, controllerLocation, err := driver.CreateDvdDrive(vmName, isoPath, s.Generation)
@@ -53,6 +53,7 @@ type Config struct {
 	common.HTTPConfig           `mapstructure:",squash"`
 	common.ISOConfig            `mapstructure:",squash"`
 	common.FloppyConfig         `mapstructure:",squash"`
+	common.CDConfig             `mapstructure:",squash"`
 	bootcommand.BootConfig      `mapstructure:",squash"`
 	hypervcommon.OutputConfig   `mapstructure:",squash"`
 	hypervcommon.SSHConfig      `mapstructure:",squash"`
@@ -94,6 +95,7 @@ type Config struct {
 	EnableSecureBoot               bool   `mapstructure:"enable_secure_boot"`
 	SecureBootTemplate             string `mapstructure:"secure_boot_template"`
 	EnableVirtualizationExtensions bool   `mapstructure:"enable_virtualization_extensions"`
+	EnableNestedVirtualization     bool   `mapstructure:"enable_nested_virtualization"`
 	TempPath                       string `mapstructure:"temp_path"`
 
 	// A separate path can be used for storing the VM's disk image. The purpose is to enable
@@ -112,6 +114,10 @@ type Config struct {
 	// Use differencing disk
 	DifferencingDisk bool `mapstructure:"differencing_disk"`
 
+	// Merge the differencing disk into a standalone disk before exporting,
+	// instead of keeping the chain of its parent
+	MergeDifferencingDisk bool `mapstructure:"merge_differencing_disk"`
+
 	// Create the VM with a Fixed VHD format disk instead of Dynamic VHDX
 	FixedVHD bool `mapstructure:"use_fixed_vhd_format"`
 
@@ -145,12 +151,13 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 	errs = packer.MultiErrorAppend(errs, b.config.BootConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CDConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.HTTPConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.OutputConfig.Prepare(&b.config.ctx, &b.config.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, b.config.SSHConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.ShutdownConfig.Prepare(&b.config.ctx)...)
 
-	if len(b.config.ISOConfig.ISOUrls) < 1 || (strings.ToLower(filepath.Ext(b.config.ISOConfig.ISOUrls[0])) != ".vhd" && strings.ToLower(filepath.Ext(b.config.ISOConfig.ISOUrls[0])) != ".vhdx") {
+	if !b.isVHDSource() {
 		//We only create a new hard drive if an existing one to copy from does not exist
 		err = b.checkDiskSize()
 		if err != nil {
@@ -240,6 +247,9 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	}
 
 	numberOfIsos := len(b.config.SecondaryDvdImages)
+	if b.config.CDConfig.HasCD() {
+		numberOfIsos = numberOfIsos + 1
+	}
 
 	if b.config.GuestAdditionsMode == "attach" {
 		if _, err := os.Stat(b.config.GuestAdditionsPath); os.IsNotExist(err) {
@@ -266,6 +276,14 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	if b.config.EnableNestedVirtualization {
+		b.config.EnableVirtualizationExtensions = true
+	}
+
+	if err := hypervcommon.CheckSecureBootTemplate(b.config.SecureBootTemplate); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	if b.config.EnableVirtualizationExtensions {
 		hasVirtualMachineVirtualizationExtensions, err := powershell.HasVirtualMachineVirtualizationExtensions()
 		if err != nil {
@@ -292,6 +310,16 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		errs = packer.MultiErrorAppend(errs, err)
 	}
 
+	if b.config.DifferencingDisk && !b.isVHDSource() {
+		err = errors.New("Differencing disks require a VHD or VHDX iso_url as their parent.")
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
+	if b.config.MergeDifferencingDisk && !b.config.DifferencingDisk {
+		err = errors.New("merge_differencing_disk requires differencing_disk.")
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	// Warnings
 
 	if b.config.ShutdownCommand == "" {
@@ -300,6 +328,10 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 				"will forcibly halt the virtual machine, which may result in data loss.")
 	}
 
+	if b.config.Generation < 2 && (b.config.EnableSecureBoot || b.config.SecureBootTemplate != "") {
+		warnings = appendWarnings(warnings, "Secure boot is only supported on Generation 2 virtual machines, it is ignored.")
+	}
+
 	warning := b.checkHostAvailableMemory()
 	if warning != "" {
 		warnings = appendWarnings(warnings, warning)
@@ -364,6 +396,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Path:  b.config.OutputDir,
 		},
 		&common.StepDownload{
+			Chunks:       b.config.DownloadChunks,
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
 			Description:  "ISO",
@@ -376,10 +409,22 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
 		},
+		&common.StepCreateCD{
+			Files:   b.config.CDConfig.CDFiles,
+			Content: b.config.CDConfig.CDContent,
+			Label:   b.config.CDConfig.CDLabel,
+		},
 		&common.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
+			TLS:         b.config.HTTPTLS,
+			TLSCertFile: b.config.HTTPTLSCertFile,
+			TLSKeyFile:  b.config.HTTPTLSKeyFile,
+			Username:    b.config.HTTPUsername,
+			Password:    b.config.HTTPPassword,
+			Templates:   b.config.HTTPTemplates,
+			Ctx:         b.config.ctx,
 		},
 		&hypervcommon.StepCreateSwitch{
 			SwitchName: b.config.SwitchName,
@@ -434,10 +479,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 
 		&hypervcommon.StepTypeBootCommand{
-			BootCommand: b.config.FlatBootCommand(),
-			BootWait:    b.config.BootWait,
-			SwitchName:  b.config.SwitchName,
-			Ctx:         b.config.ctx,
+			BootCommand:    b.config.FlatBootCommand(),
+			BootWait:       b.config.BootWait,
+			SwitchName:     b.config.SwitchName,
+			KeyboardLayout: b.config.BootKeyboardLayout,
+			Ctx:            b.config.ctx,
 		},
 
 		// configure the communicator ssh, winrm
@@ -467,9 +513,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Generation: b.config.Generation,
 		},
 		&hypervcommon.StepExportVm{
-			OutputDir:      b.config.OutputDir,
-			SkipCompaction: b.config.SkipCompaction,
-			SkipExport:     b.config.SkipExport,
+			OutputDir:              b.config.OutputDir,
+			SkipCompaction:         b.config.SkipCompaction,
+			SkipExport:             b.config.SkipExport,
+			MergeDifferencingDisks: b.config.MergeDifferencingDisk,
 		},
 
 		// the clean up actions for each step will be executed reverse order
@@ -518,6 +565,16 @@ func appendWarnings(slice []string, data ...string) []string {
 	return slice
 }
 
+// isVHDSource tells whether the iso_url is an existing virtual hard disk,
+// which the disk of the VM is copied from, or is a differencing disk of.
+func (b *Builder) isVHDSource() bool {
+	if len(b.config.ISOConfig.ISOUrls) < 1 {
+		return false
+	}
+	extension := strings.ToLower(filepath.Ext(b.config.ISOConfig.ISOUrls[0]))
+	return extension == ".vhd" || extension == ".vhdx"
+}
+
 func (b *Builder) checkDiskSize() error {
 	if b.config.DiskSize == 0 {
 		b.config.DiskSize = DefaultDiskSize
@@ -256,6 +256,30 @@ func TestBuilderPrepare_InvalidKey(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_CDContent(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["generation"] = 1
+	config["secondary_iso_images"] = []string{"builder.go", "builder_test.go"}
+
+	// Good
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Bad, the CD needs a third ide controller location
+	config["cd_content"] = map[string]string{"meta-data": "instance-id: packer"}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestBuilderPrepare_ISOChecksum(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -625,3 +649,78 @@ func TestUserVariablesInBootCommand(t *testing.T) {
 		t.Fatalf("should not have error: %#v", ret)
 	}
 }
+
+func TestBuilderPrepare_DifferencingDisk(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad, the parent is not a virtual hard disk
+	config["differencing_disk"] = true
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["iso_url"] = "http://www.packer.io/parent.vhdx"
+	config["merge_differencing_disk"] = true
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Bad, nothing to merge
+	config["differencing_disk"] = false
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_SecureBoot(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Good
+	config["generation"] = 2
+	config["enable_secure_boot"] = true
+	config["secure_boot_template"] = "MicrosoftUEFICertificateAuthority"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Bad
+	config["secure_boot_template"] = "Linux"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Secure boot is ignored on generation 1
+	config["generation"] = 1
+	config["secure_boot_template"] = "MicrosoftWindows"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
@@ -43,6 +43,7 @@ type Config struct {
 	common.HTTPConfig           `mapstructure:",squash"`
 	common.ISOConfig            `mapstructure:",squash"`
 	common.FloppyConfig         `mapstructure:",squash"`
+	common.CDConfig             `mapstructure:",squash"`
 	bootcommand.BootConfig      `mapstructure:",squash"`
 	hypervcommon.OutputConfig   `mapstructure:",squash"`
 	hypervcommon.SSHConfig      `mapstructure:",squash"`
@@ -91,6 +92,7 @@ type Config struct {
 	EnableSecureBoot               bool   `mapstructure:"enable_secure_boot"`
 	SecureBootTemplate             string `mapstructure:"secure_boot_template"`
 	EnableVirtualizationExtensions bool   `mapstructure:"enable_virtualization_extensions"`
+	EnableNestedVirtualization     bool   `mapstructure:"enable_nested_virtualization"`
 
 	Communicator string `mapstructure:"communicator"`
 
@@ -130,6 +132,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 	errs = packer.MultiErrorAppend(errs, b.config.BootConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CDConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.HTTPConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.OutputConfig.Prepare(&b.config.ctx, &b.config.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, b.config.SSHConfig.Prepare(&b.config.ctx)...)
@@ -262,6 +265,9 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	}
 
 	numberOfIsos := len(b.config.SecondaryDvdImages)
+	if b.config.CDConfig.HasCD() {
+		numberOfIsos = numberOfIsos + 1
+	}
 
 	if b.config.GuestAdditionsMode == "attach" {
 		if _, err := os.Stat(b.config.GuestAdditionsPath); os.IsNotExist(err) {
@@ -288,6 +294,14 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	if b.config.EnableNestedVirtualization {
+		b.config.EnableVirtualizationExtensions = true
+	}
+
+	if err := hypervcommon.CheckSecureBootTemplate(b.config.SecureBootTemplate); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	if b.config.EnableVirtualizationExtensions {
 		hasVirtualMachineVirtualizationExtensions, err := powershell.HasVirtualMachineVirtualizationExtensions()
 		if err != nil {
@@ -372,6 +386,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	if b.config.RawSingleISOUrl != "" || len(b.config.ISOUrls) > 0 {
 		steps = append(steps,
 			&common.StepDownload{
+				Chunks:       b.config.DownloadChunks,
 				Checksum:     b.config.ISOChecksum,
 				ChecksumType: b.config.ISOChecksumType,
 				Description:  "ISO",
@@ -388,10 +403,22 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Files:       b.config.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
 		},
+		&common.StepCreateCD{
+			Files:   b.config.CDConfig.CDFiles,
+			Content: b.config.CDConfig.CDContent,
+			Label:   b.config.CDConfig.CDLabel,
+		},
 		&common.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
+			TLS:         b.config.HTTPTLS,
+			TLSCertFile: b.config.HTTPTLSCertFile,
+			TLSKeyFile:  b.config.HTTPTLSKeyFile,
+			Username:    b.config.HTTPUsername,
+			Password:    b.config.HTTPPassword,
+			Templates:   b.config.HTTPTemplates,
+			Ctx:         b.config.ctx,
 		},
 		&hypervcommon.StepCreateSwitch{
 			SwitchName: b.config.SwitchName,
@@ -443,10 +470,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 
 		&hypervcommon.StepTypeBootCommand{
-			BootCommand: b.config.FlatBootCommand(),
-			BootWait:    b.config.BootWait,
-			SwitchName:  b.config.SwitchName,
-			Ctx:         b.config.ctx,
+			BootCommand:    b.config.FlatBootCommand(),
+			BootWait:       b.config.BootWait,
+			SwitchName:     b.config.SwitchName,
+			KeyboardLayout: b.config.BootKeyboardLayout,
+			Ctx:            b.config.ctx,
 		},
 
 		// configure the communicator ssh, winrm
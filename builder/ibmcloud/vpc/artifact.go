@@ -0,0 +1,54 @@
+package vpc
+
+import (
+	"fmt"
+	"log"
+)
+
+type Artifact struct {
+	// The ID of the image
+	imageId string
+
+	// The name of the image
+	imageName string
+
+	// The region of the image
+	region string
+
+	// The client for making API calls
+	client *Client
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	// No files with IBM Cloud
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return fmt.Sprintf("%s:%s", a.region, a.imageId)
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("An image was created: '%v' (ID: %v) in region '%v'", a.imageName, a.imageId, a.region)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "ImageId":
+		return a.imageId
+	case "ImageName":
+		return a.imageName
+	case "Region":
+		return a.region
+	}
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying image: %s (%s)", a.imageId, a.imageName)
+	return a.client.DeleteImage(a.imageId)
+}
@@ -0,0 +1,23 @@
+package vpc
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestArtifactString(t *testing.T) {
+	a := &Artifact{imageId: "abc", imageName: "packer-foobar", region: "us-south"}
+	expected := "An image was created: 'packer-foobar' (ID: abc) in region 'us-south'"
+
+	if a.String() != expected {
+		t.Fatalf("artifact string should match: %v", expected)
+	}
+	if a.Id() != "us-south:abc" {
+		t.Fatalf("bad: %s", a.Id())
+	}
+}
@@ -0,0 +1,96 @@
+// The vpc package contains a packer.Builder implementation
+// that builds custom images of IBM Cloud VPC.
+
+package vpc
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The unique id for the builder
+const BuilderId = "packer.ibmcloud-vpc"
+
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, errs := NewConfig(raws...)
+	if errs != nil {
+		return warnings, errs
+	}
+	b.config = c
+
+	return nil, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	client := &Client{
+		URL:    b.config.Endpoint,
+		IAMURL: b.config.IAMURL,
+		APIKey: b.config.APIKey,
+	}
+
+	// Set up the state
+	state := new(multistep.BasicStateBag)
+	state.Put("config", b.config)
+	state.Put("client", client)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	// Build the steps
+	steps := []multistep.Step{
+		&stepCreateSSHKey{
+			Debug:        b.config.PackerDebug,
+			DebugKeyPath: fmt.Sprintf("ibmcloud_%s.pem", b.config.PackerBuildName),
+		},
+		new(stepImportImage),
+		new(stepCreateInstance),
+		new(stepFloatingIP),
+		&communicator.StepConnect{
+			Config:    &b.config.Comm,
+			Host:      commHost,
+			SSHConfig: sshConfig,
+		},
+		new(common.StepProvision),
+		new(stepStopInstance),
+		new(stepCreateImage),
+	}
+
+	// Run the steps
+	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	if _, ok := state.GetOk("image_id"); !ok {
+		log.Println("Failed to find image_id in state. Bug?")
+		return nil, nil
+	}
+
+	artifact := &Artifact{
+		imageId:   state.Get("image_id").(string),
+		imageName: state.Get("image_name").(string),
+		region:    b.config.Region,
+		client:    client,
+	}
+
+	return artifact, nil
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
@@ -0,0 +1,149 @@
+package vpc
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"api_key":             "bar",
+		"region":              "us-south",
+		"subnet_id":           "0717-5e9b3a6e-b8c4-4d33-a9a8-6cfb3ee2fa28",
+		"vsi_profile":         "bx2-2x8",
+		"vsi_base_image_name": "ibm-ubuntu-20-04-minimal-amd64-2",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare_InvalidKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Add a random key
+	config["i_should_not_be_valid"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	warnings, err := b.Prepare(testConfig())
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Endpoint != "https://us-south.iaas.cloud.ibm.com/v1" {
+		t.Errorf("bad endpoint: %s", b.config.Endpoint)
+	}
+	if b.config.IAMURL != DefaultIAMURL {
+		t.Errorf("bad iam url: %s", b.config.IAMURL)
+	}
+	if !strings.HasPrefix(b.config.InstanceName, "packer-") {
+		t.Errorf("bad instance name: %s", b.config.InstanceName)
+	}
+	if !strings.HasPrefix(b.config.ImageName, "packer-") {
+		t.Errorf("bad image name: %s", b.config.ImageName)
+	}
+	if b.config.Interface != "public" {
+		t.Errorf("bad interface: %s", b.config.Interface)
+	}
+	if b.config.StateTimeout != 10*time.Minute {
+		t.Errorf("bad state timeout: %s", b.config.StateTimeout)
+	}
+	if b.config.resourceGroup() != nil {
+		t.Errorf("bad resource group: %#v", b.config.resourceGroup())
+	}
+}
+
+func TestBuilderPrepare_Required(t *testing.T) {
+	for _, env := range []string{"IBMCLOUD_API_KEY", "IC_API_KEY"} {
+		old := os.Getenv(env)
+		os.Setenv(env, "")
+		defer os.Setenv(env, old)
+	}
+
+	for _, key := range []string{"api_key", "region", "subnet_id", "vsi_profile", "vsi_base_image_name"} {
+		var b Builder
+		config := testConfig()
+		delete(config, key)
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error without %s", key)
+		}
+	}
+}
+
+func TestBuilderPrepare_COSImage(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	delete(config, "vsi_base_image_name")
+	config["image_cos_url"] = "cos://us-south/images/ubuntu.qcow2"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without image_os_name")
+	}
+
+	config["image_os_name"] = "ubuntu-20-04-amd64"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// Only one source
+	config["vsi_base_image_id"] = "r006-ed3f775f-ad7e-4e37-ae62-7199b4988b00"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with two sources")
+	}
+
+	delete(config, "vsi_base_image_id")
+	config["image_cos_url"] = "https://example.com/ubuntu.qcow2"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with a URL that isn't of COS")
+	}
+}
+
+func TestBuilderPrepare_Names(t *testing.T) {
+	for _, key := range []string{"image_name", "vsi_name"} {
+		var b Builder
+		config := testConfig()
+		config[key] = "Packer_Image"
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error with an invalid %s", key)
+		}
+	}
+}
+
+func TestBuilderPrepare_Interface(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["vsi_interface"] = "private"
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["vsi_interface"] = "both"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
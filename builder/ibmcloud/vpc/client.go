@@ -0,0 +1,354 @@
+package vpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultIAMURL is the endpoint of the IAM tokens.
+const DefaultIAMURL = "https://iam.cloud.ibm.com/identity/token"
+
+// apiVersion is the date of the version of the VPC API the client speaks.
+const apiVersion = "2021-06-01"
+
+// NOTE: there is no IBM Cloud SDK vendored, so the few calls of the VPC API
+// the builder makes are done with the client here.
+
+// Client is a client of the VPC API of a region, authenticated with an IAM
+// API key.
+type Client struct {
+	// URL is the endpoint of the region, like
+	// https://us-south.iaas.cloud.ibm.com/v1.
+	URL    string
+	IAMURL string
+	APIKey string
+
+	HTTPClient *http.Client
+
+	tokenLock sync.Mutex
+	token     string
+	expires   time.Time
+}
+
+// Ref is a reference to a resource, by ID or by name.
+type Ref struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Instance is a virtual server instance.
+type Instance struct {
+	ID                      string `json:"id"`
+	Status                  string `json:"status"`
+	PrimaryNetworkInterface struct {
+		ID                 string `json:"id"`
+		PrimaryIPv4Address string `json:"primary_ipv4_address"`
+	} `json:"primary_network_interface"`
+	BootVolumeAttachment struct {
+		Volume Ref `json:"volume"`
+	} `json:"boot_volume_attachment"`
+}
+
+// Image is an image, custom or not.
+type Image struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// FloatingIP is a public address bound to a network interface.
+type FloatingIP struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+	Status  string `json:"status"`
+}
+
+// Subnet is a subnet of a VPC, in a zone.
+type Subnet struct {
+	ID   string `json:"id"`
+	VPC  Ref    `json:"vpc"`
+	Zone Ref    `json:"zone"`
+}
+
+// KeyCreateRequest is the request of a new SSH key.
+type KeyCreateRequest struct {
+	Name          string `json:"name"`
+	PublicKey     string `json:"public_key"`
+	Type          string `json:"type"`
+	ResourceGroup *Ref   `json:"resource_group,omitempty"`
+}
+
+// InstanceCreateRequest is the request of a new instance.
+type InstanceCreateRequest struct {
+	Name                    string                  `json:"name"`
+	Profile                 Ref                     `json:"profile"`
+	Zone                    Ref                     `json:"zone"`
+	VPC                     Ref                     `json:"vpc"`
+	Image                   Ref                     `json:"image"`
+	Keys                    []Ref                   `json:"keys"`
+	PrimaryNetworkInterface NetworkInterfaceRequest `json:"primary_network_interface"`
+	ResourceGroup           *Ref                    `json:"resource_group,omitempty"`
+	UserData                string                  `json:"user_data,omitempty"`
+}
+
+// NetworkInterfaceRequest is the network interface of a new instance.
+type NetworkInterfaceRequest struct {
+	Subnet Ref `json:"subnet"`
+}
+
+// FloatingIPCreateRequest is the request of a new floating IP.
+type FloatingIPCreateRequest struct {
+	Name          string `json:"name"`
+	Target        Ref    `json:"target"`
+	ResourceGroup *Ref   `json:"resource_group,omitempty"`
+}
+
+// ImageCreateRequest is the request of a new custom image. It is created
+// from the SourceVolume, or imported from the File in Cloud Object Storage.
+type ImageCreateRequest struct {
+	Name            string     `json:"name"`
+	SourceVolume    *Ref       `json:"source_volume,omitempty"`
+	File            *ImageFile `json:"file,omitempty"`
+	OperatingSystem *Ref       `json:"operating_system,omitempty"`
+	ResourceGroup   *Ref       `json:"resource_group,omitempty"`
+}
+
+// ImageFile is the file of an image to import, by its cos:// URL.
+type ImageFile struct {
+	Href string `json:"href"`
+}
+
+// ErrorResponse is the error returned by the API.
+type ErrorResponse struct {
+	StatusCode int
+	Errors     []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *ErrorResponse) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("IBM Cloud VPC API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", err.Code, err.Message))
+	}
+	return fmt.Sprintf("IBM Cloud VPC API error: %d %s", e.StatusCode, strings.Join(messages, ", "))
+}
+
+// IsNotFound returns whether the error is of a resource that doesn't exist.
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*ErrorResponse)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// CreateKey adds an SSH key.
+func (c *Client) CreateKey(req *KeyCreateRequest) (*Ref, error) {
+	result := new(Ref)
+	err := c.do("POST", "keys", nil, req, result)
+	return result, err
+}
+
+// DeleteKey deletes an SSH key.
+func (c *Client) DeleteKey(id string) error {
+	return c.do("DELETE", "keys/"+id, nil, nil, nil)
+}
+
+// GetSubnet returns a subnet.
+func (c *Client) GetSubnet(id string) (*Subnet, error) {
+	result := new(Subnet)
+	err := c.do("GET", "subnets/"+id, nil, nil, result)
+	return result, err
+}
+
+// CreateInstance provisions an instance.
+func (c *Client) CreateInstance(req *InstanceCreateRequest) (*Instance, error) {
+	result := new(Instance)
+	err := c.do("POST", "instances", nil, req, result)
+	return result, err
+}
+
+// GetInstance returns an instance.
+func (c *Client) GetInstance(id string) (*Instance, error) {
+	result := new(Instance)
+	err := c.do("GET", "instances/"+id, nil, nil, result)
+	return result, err
+}
+
+// StopInstance stops an instance.
+func (c *Client) StopInstance(id string) error {
+	body := map[string]string{"type": "stop"}
+	return c.do("POST", fmt.Sprintf("instances/%s/actions", id), nil, body, nil)
+}
+
+// DeleteInstance deletes an instance, and its boot volume.
+func (c *Client) DeleteInstance(id string) error {
+	return c.do("DELETE", "instances/"+id, nil, nil, nil)
+}
+
+// CreateFloatingIP reserves a floating IP, bound to the target.
+func (c *Client) CreateFloatingIP(req *FloatingIPCreateRequest) (*FloatingIP, error) {
+	result := new(FloatingIP)
+	err := c.do("POST", "floating_ips", nil, req, result)
+	return result, err
+}
+
+// GetFloatingIP returns a floating IP.
+func (c *Client) GetFloatingIP(id string) (*FloatingIP, error) {
+	result := new(FloatingIP)
+	err := c.do("GET", "floating_ips/"+id, nil, nil, result)
+	return result, err
+}
+
+// DeleteFloatingIP releases a floating IP.
+func (c *Client) DeleteFloatingIP(id string) error {
+	return c.do("DELETE", "floating_ips/"+id, nil, nil, nil)
+}
+
+// CreateImage creates a custom image, from a volume or from a file in
+// Cloud Object Storage.
+func (c *Client) CreateImage(req *ImageCreateRequest) (*Image, error) {
+	result := new(Image)
+	err := c.do("POST", "images", nil, req, result)
+	return result, err
+}
+
+// GetImage returns an image.
+func (c *Client) GetImage(id string) (*Image, error) {
+	result := new(Image)
+	err := c.do("GET", "images/"+id, nil, nil, result)
+	return result, err
+}
+
+// FindImage returns the image with the name.
+func (c *Client) FindImage(name string) (*Image, error) {
+	var result struct {
+		Images []*Image `json:"images"`
+	}
+	if err := c.do("GET", "images", url.Values{"name": {name}}, nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Images) == 0 {
+		return nil, fmt.Errorf("no image named %s", name)
+	}
+	return result.Images[0], nil
+}
+
+// DeleteImage deletes a custom image.
+func (c *Client) DeleteImage(id string) error {
+	return c.do("DELETE", "images/"+id, nil, nil, nil)
+}
+
+// accessToken returns an IAM access token of the API key, which is renewed
+// a while before it expires, as builds outlast it.
+func (c *Client) accessToken() (string, error) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	iamURL := c.IAMURL
+	if iamURL == "" {
+		iamURL = DefaultIAMURL
+	}
+	form := url.Values{
+		"grant_type": {"urn:ibm:params:oauth:grant-type:apikey"},
+		"apikey":     {c.APIKey},
+	}
+	req, err := http.NewRequest("POST", iamURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Error getting an IAM token: %s", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	c.token = token.AccessToken
+	c.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - 5*time.Minute)
+	return c.token, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+func (c *Client) do(method string, path string, query url.Values, body interface{}, result interface{}) error {
+	token, err := c.accessToken()
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("version", apiVersion)
+	query.Set("generation", "2")
+
+	u := strings.TrimSuffix(c.URL, "/") + "/" + path + "?" + query.Encode()
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &ErrorResponse{StatusCode: resp.StatusCode}
+		// The errors are best effort, the status is enough to fail
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,112 @@
+package vpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testServer serves the IAM tokens on /token, and the API with the handler.
+func testServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *Client) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("apikey") != "secret" {
+			t.Errorf("bad api key: %s", r.FormValue("apikey"))
+		}
+		w.Write([]byte(`{"access_token": "token", "expires_in": 3600}`))
+	})
+	mux.HandleFunc("/v1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("bad authorization: %s", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("generation") != "2" || r.URL.Query().Get("version") == "" {
+			t.Errorf("bad query: %s", r.URL.RawQuery)
+		}
+		handler(w, r)
+	})
+
+	server := httptest.NewServer(mux)
+	client := &Client{
+		URL:    server.URL + "/v1",
+		IAMURL: server.URL + "/token",
+		APIKey: "secret",
+	}
+	return server, client
+}
+
+func TestClient_CreateImage(t *testing.T) {
+	server, client := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/images" {
+			t.Errorf("bad request: %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		file, _ := body["file"].(map[string]interface{})
+		if file["href"] != "cos://us-south/images/ubuntu.qcow2" {
+			t.Errorf("bad file: %#v", body)
+		}
+		group, _ := body["resource_group"].(map[string]interface{})
+		if group["id"] != "group" {
+			t.Errorf("bad resource group: %#v", body)
+		}
+		if _, ok := body["source_volume"]; ok {
+			t.Errorf("source_volume should be omitted: %#v", body)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "image", "name": "packer", "status": "pending"}`))
+	})
+	defer server.Close()
+
+	image, err := client.CreateImage(&ImageCreateRequest{
+		Name:            "packer",
+		File:            &ImageFile{Href: "cos://us-south/images/ubuntu.qcow2"},
+		OperatingSystem: &Ref{Name: "ubuntu-20-04-amd64"},
+		ResourceGroup:   &Ref{ID: "group"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if image.ID != "image" || image.Status != "pending" {
+		t.Fatalf("bad: %#v", image)
+	}
+}
+
+func TestClient_FindImage(t *testing.T) {
+	server, client := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") != "ubuntu" {
+			t.Errorf("bad query: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"images": [{"id": "image", "name": "ubuntu", "status": "available"}]}`))
+	})
+	defer server.Close()
+
+	image, err := client.FindImage("ubuntu")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if image.ID != "image" {
+		t.Fatalf("bad: %#v", image)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	server, client := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors": [{"code": "not_found", "message": "Instance not found"}]}`))
+	})
+	defer server.Close()
+
+	_, err := client.GetInstance("instance")
+	if !IsNotFound(err) {
+		t.Fatalf("bad: %#v", err)
+	}
+	apiErr := err.(*ErrorResponse)
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0].Message != "Instance not found" {
+		t.Fatalf("bad: %#v", apiErr)
+	}
+}
@@ -0,0 +1,190 @@
+package vpc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// reName is the format of the names of the VPC resources.
+var reName = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	Comm                communicator.Config `mapstructure:",squash"`
+
+	APIKey          string `mapstructure:"api_key"`
+	Region          string `mapstructure:"region"`
+	Endpoint        string `mapstructure:"endpoint"`
+	IAMURL          string `mapstructure:"iam_url"`
+	ResourceGroupID string `mapstructure:"resource_group_id"`
+
+	SubnetID      string `mapstructure:"subnet_id"`
+	Profile       string `mapstructure:"vsi_profile"`
+	BaseImageID   string `mapstructure:"vsi_base_image_id"`
+	BaseImageName string `mapstructure:"vsi_base_image_name"`
+	ImageCOSURL   string `mapstructure:"image_cos_url"`
+	ImageOSName   string `mapstructure:"image_os_name"`
+
+	InstanceName string        `mapstructure:"vsi_name"`
+	Interface    string        `mapstructure:"vsi_interface"`
+	UserData     string        `mapstructure:"vsi_user_data"`
+	UserDataFile string        `mapstructure:"vsi_user_data_file"`
+	ImageName    string        `mapstructure:"image_name"`
+	StateTimeout time.Duration `mapstructure:"state_timeout"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.APIKey == "" {
+		c.APIKey = os.Getenv("IBMCLOUD_API_KEY")
+	}
+	if c.APIKey == "" {
+		c.APIKey = os.Getenv("IC_API_KEY")
+	}
+	if c.Endpoint == "" && c.Region != "" {
+		c.Endpoint = fmt.Sprintf("https://%s.iaas.cloud.ibm.com/v1", c.Region)
+	}
+	if c.IAMURL == "" {
+		c.IAMURL = DefaultIAMURL
+	}
+
+	if c.ImageName == "" {
+		def, err := interpolate.Render("packer-{{timestamp}}", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		// Default to packer-{{ unix timestamp (utc) }}
+		c.ImageName = def
+	}
+
+	if c.InstanceName == "" {
+		// Default to packer-[time-ordered-uuid]
+		c.InstanceName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	}
+
+	if c.Interface == "" {
+		c.Interface = "public"
+	}
+
+	if c.StateTimeout == 0 {
+		c.StateTimeout = 10 * time.Minute
+	}
+
+	if c.Comm.SSHUsername == "" {
+		c.Comm.SSHUsername = "root"
+	}
+
+	var errs *packer.MultiError
+	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if c.APIKey == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("api_key for auth must be specified"))
+	}
+
+	if c.Region == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("region is required"))
+	}
+
+	if c.SubnetID == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("subnet_id is required"))
+	}
+
+	if c.Profile == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("vsi_profile is required"))
+	}
+
+	sources := 0
+	for _, set := range []bool{c.BaseImageID != "", c.BaseImageName != "", c.ImageCOSURL != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("exactly one of vsi_base_image_id, vsi_base_image_name or image_cos_url is required"))
+	}
+
+	if c.ImageCOSURL != "" {
+		if !strings.HasPrefix(c.ImageCOSURL, "cos://") {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("image_cos_url must be a cos:// URL: %s", c.ImageCOSURL))
+		}
+		if c.ImageOSName == "" {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("image_os_name is required to import image_cos_url"))
+		}
+	} else if c.ImageOSName != "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("image_os_name can only be specified with image_cos_url"))
+	}
+
+	if c.Interface != "public" && c.Interface != "private" {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("vsi_interface must be one of public or private: %s", c.Interface))
+	}
+
+	if !reName.MatchString(c.ImageName) {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("image_name must be lowercase letters, digits and dashes, starting with a letter: %s", c.ImageName))
+	}
+	if !reName.MatchString(c.InstanceName) {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("vsi_name must be lowercase letters, digits and dashes, starting with a letter: %s", c.InstanceName))
+	}
+
+	if c.UserData != "" && c.UserDataFile != "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("only one of vsi_user_data or vsi_user_data_file can be specified"))
+	} else if c.UserDataFile != "" {
+		if _, err := os.Stat(c.UserDataFile); err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("vsi_user_data_file not found: %s", c.UserDataFile))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	common.ScrubConfig(c, c.APIKey)
+	return c, nil, nil
+}
+
+// resourceGroup returns the reference of the resource group, or nil for
+// the default resource group of the account.
+func (c *Config) resourceGroup() *Ref {
+	if c.ResourceGroupID == "" {
+		return nil
+	}
+	return &Ref{ID: c.ResourceGroupID}
+}
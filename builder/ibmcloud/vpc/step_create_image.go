@@ -0,0 +1,53 @@
+package vpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepCreateImage creates the custom image from the boot volume of the
+// stopped instance.
+type stepCreateImage struct{}
+
+func (s *stepCreateImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	volumeId := state.Get("boot_volume_id").(string)
+
+	ui.Say(fmt.Sprintf("Creating image: %s", c.ImageName))
+	image, err := client.CreateImage(&ImageCreateRequest{
+		Name:          c.ImageName,
+		SourceVolume:  &Ref{ID: volumeId},
+		ResourceGroup: c.resourceGroup(),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error creating image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Images of large volumes take a while to be created
+	ui.Say("Waiting for image to become available...")
+	err = waitForState("available", imageStatus(client, image.ID), 10*time.Second, 60*time.Minute)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for image to become available: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("image_id", image.ID)
+	state.Put("image_name", c.ImageName)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateImage) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
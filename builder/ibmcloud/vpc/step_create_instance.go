@@ -0,0 +1,131 @@
+package vpc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepCreateInstance struct {
+	instanceId string
+}
+
+func (s *stepCreateInstance) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	sshKeyId := state.Get("ssh_key_id").(string)
+
+	// The VPC and the zone of the instance are the ones of the subnet
+	subnet, err := client.GetSubnet(c.SubnetID)
+	if err != nil {
+		err := fmt.Errorf("Error getting subnet %s: %s", c.SubnetID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	imageId := c.BaseImageID
+	if id, ok := state.GetOk("base_image_id"); ok {
+		imageId = id.(string)
+	} else if c.BaseImageName != "" {
+		image, err := client.FindImage(c.BaseImageName)
+		if err != nil {
+			err := fmt.Errorf("Error finding the base image: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		imageId = image.ID
+	}
+
+	userData := c.UserData
+	if c.UserDataFile != "" {
+		contents, err := ioutil.ReadFile(c.UserDataFile)
+		if err != nil {
+			state.Put("error", fmt.Errorf("Problem reading user data file: %s", err))
+			return multistep.ActionHalt
+		}
+
+		userData = string(contents)
+	}
+
+	ui.Say("Creating instance...")
+	instance, err := client.CreateInstance(&InstanceCreateRequest{
+		Name:    c.InstanceName,
+		Profile: Ref{Name: c.Profile},
+		Zone:    Ref{Name: subnet.Zone.Name},
+		VPC:     Ref{ID: subnet.VPC.ID},
+		Image:   Ref{ID: imageId},
+		Keys:    []Ref{{ID: sshKeyId}},
+		PrimaryNetworkInterface: NetworkInterfaceRequest{
+			Subnet: Ref{ID: c.SubnetID},
+		},
+		ResourceGroup: c.resourceGroup(),
+		UserData:      userData,
+	})
+	if err != nil {
+		err := fmt.Errorf("Error creating instance: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this in cleanup
+	s.instanceId = instance.ID
+	ui.Message(fmt.Sprintf("Instance ID: %s", instance.ID))
+
+	ui.Say("Waiting for instance to become running...")
+	err = waitForState("running", instanceStatus(client, instance.ID), 5*time.Second, c.StateTimeout)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for instance to become running: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	instance, err = client.GetInstance(instance.ID)
+	if err != nil {
+		err := fmt.Errorf("Error retrieving instance: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Store the instance for later
+	state.Put("instance_id", instance.ID)
+	state.Put("network_interface_id", instance.PrimaryNetworkInterface.ID)
+	state.Put("boot_volume_id", instance.BootVolumeAttachment.Volume.ID)
+	state.Put("instance_ip", instance.PrimaryNetworkInterface.PrimaryIPv4Address)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateInstance) Cleanup(state multistep.StateBag) {
+	// If the instance id isn't there, we probably never created it
+	if s.instanceId == "" {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	ui.Say("Destroying instance...")
+	if err := client.DeleteInstance(s.instanceId); err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error destroying instance. Please destroy it manually: %s", err))
+		return
+	}
+
+	// The base image can't be deleted while the instance uses it
+	err := waitForState("deleted", instanceDeleted(client, s.instanceId), 5*time.Second, c.StateTimeout)
+	if err != nil {
+		log.Printf("Error waiting for instance to be destroyed: %s", err)
+	}
+}
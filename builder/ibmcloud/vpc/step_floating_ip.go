@@ -0,0 +1,72 @@
+package vpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepFloatingIP reserves a floating IP for the instance, to connect to it
+// from outside of the VPC. With the private interface, the instance is
+// connected to on its address in the subnet.
+type stepFloatingIP struct {
+	floatingIPId string
+}
+
+func (s *stepFloatingIP) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	interfaceId := state.Get("network_interface_id").(string)
+
+	if c.Interface != "public" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Reserving a floating IP for the instance...")
+	ip, err := client.CreateFloatingIP(&FloatingIPCreateRequest{
+		Name:          c.InstanceName,
+		Target:        Ref{ID: interfaceId},
+		ResourceGroup: c.resourceGroup(),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error reserving floating IP: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this in cleanup
+	s.floatingIPId = ip.ID
+
+	err = waitForState("available", floatingIPStatus(client, ip.ID), 2*time.Second, c.StateTimeout)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for floating IP to become available: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Floating IP: %s", ip.Address))
+	state.Put("instance_ip", ip.Address)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepFloatingIP) Cleanup(state multistep.StateBag) {
+	if s.floatingIPId == "" {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Releasing floating IP...")
+	if err := client.DeleteFloatingIP(s.floatingIPId); err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error releasing floating IP. Please release it manually: %s", err))
+	}
+}
@@ -0,0 +1,73 @@
+package vpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepImportImage imports the image in Cloud Object Storage to provision the
+// instance from, when the build doesn't start from an existing image. The
+// imported image is deleted with the build.
+type stepImportImage struct {
+	imageId string
+}
+
+func (s *stepImportImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	if c.ImageCOSURL == "" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Importing the base image from %s...", c.ImageCOSURL))
+	image, err := client.CreateImage(&ImageCreateRequest{
+		Name:            c.InstanceName,
+		File:            &ImageFile{Href: c.ImageCOSURL},
+		OperatingSystem: &Ref{Name: c.ImageOSName},
+		ResourceGroup:   c.resourceGroup(),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error importing the base image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this in cleanup
+	s.imageId = image.ID
+
+	// Imports take a while, as the whole image is copied
+	err = waitForState("available", imageStatus(client, image.ID), 10*time.Second, 60*time.Minute)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for the base image to be imported: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("base_image_id", image.ID)
+	ui.Message(fmt.Sprintf("Base image ID: %s", image.ID))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepImportImage) Cleanup(state multistep.StateBag) {
+	if s.imageId == "" {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deleting the imported base image...")
+	if err := client.DeleteImage(s.imageId); err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error deleting the imported base image. Please delete it manually: %s", err))
+	}
+}
@@ -0,0 +1,41 @@
+package vpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepStopInstance struct{}
+
+func (s *stepStopInstance) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	instanceId := state.Get("instance_id").(string)
+
+	ui.Say("Stopping instance...")
+	if err := client.StopInstance(instanceId); err != nil {
+		err := fmt.Errorf("Error stopping instance: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	err := waitForState("stopped", instanceStatus(client, instanceId), 5*time.Second, c.StateTimeout)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for instance to stop: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepStopInstance) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,105 @@
+package vpc
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// waitForState polls refresh until it returns the desired state, with the
+// given pause in between.
+func waitForState(
+	desiredState string, refresh func() (string, error),
+	pause time.Duration, timeout time.Duration) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	result := make(chan error, 1)
+	go func() {
+		attempts := 0
+		for {
+			attempts += 1
+
+			log.Printf("Checking state... (attempt: %d)", attempts)
+			state, err := refresh()
+			if err != nil {
+				result <- err
+				return
+			}
+
+			if state == desiredState {
+				result <- nil
+				return
+			}
+
+			time.Sleep(pause)
+
+			// Verify we shouldn't exit
+			select {
+			case <-done:
+				// We finished, so just exit the goroutine
+				return
+			default:
+				// Keep going
+			}
+		}
+	}()
+
+	log.Printf("Waiting for up to %d seconds for state to become: %s", timeout/time.Second, desiredState)
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("Timeout while waiting to for state to become '%s'", desiredState)
+	}
+}
+
+func instanceStatus(client *Client, id string) func() (string, error) {
+	return func() (string, error) {
+		instance, err := client.GetInstance(id)
+		if err != nil {
+			return "", err
+		}
+		return instance.Status, nil
+	}
+}
+
+func floatingIPStatus(client *Client, id string) func() (string, error) {
+	return func() (string, error) {
+		ip, err := client.GetFloatingIP(id)
+		if err != nil {
+			return "", err
+		}
+		return ip.Status, nil
+	}
+}
+
+// imageStatus is the status of an image, which fails as soon as the image
+// does, rather than waiting for the timeout.
+func imageStatus(client *Client, id string) func() (string, error) {
+	return func() (string, error) {
+		image, err := client.GetImage(id)
+		if err != nil {
+			return "", err
+		}
+		if image.Status == "failed" {
+			return "", fmt.Errorf("image %s failed", id)
+		}
+		return image.Status, nil
+	}
+}
+
+// instanceDeleted waits for the instance to be gone, as the resources it
+// holds, such as its image, can't be deleted before it is.
+func instanceDeleted(client *Client, id string) func() (string, error) {
+	return func() (string, error) {
+		instance, err := client.GetInstance(id)
+		if IsNotFound(err) {
+			return "deleted", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return instance.Status, nil
+	}
+}
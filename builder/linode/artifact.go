@@ -0,0 +1,46 @@
+package linode
+
+import (
+	"fmt"
+	"log"
+)
+
+type Artifact struct {
+	// The private image
+	image *Image
+
+	// The client for making API calls
+	client *Client
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	// No files with Linode
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return a.image.ID
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("A private image was created: '%v' (ID: %v)", a.image.Label, a.image.ID)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "ImageLabel":
+		return a.image.Label
+	case "ImageDescription":
+		return a.image.Description
+	}
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying image: %s (%s)", a.image.ID, a.image.Label)
+	return a.client.DeleteImage(a.image.ID)
+}
@@ -0,0 +1,23 @@
+package linode
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestArtifactString(t *testing.T) {
+	a := &Artifact{image: &Image{ID: "private/42", Label: "packer-foobar"}}
+	expected := "A private image was created: 'packer-foobar' (ID: private/42)"
+
+	if a.String() != expected {
+		t.Fatalf("artifact string should match: %v", expected)
+	}
+	if a.Id() != "private/42" {
+		t.Fatalf("bad: %s", a.Id())
+	}
+}
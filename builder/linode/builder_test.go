@@ -0,0 +1,139 @@
+package linode
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"linode_token":  "bar",
+		"region":        "us-east",
+		"instance_type": "g6-nanode-1",
+		"image":         "linode/debian10",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare_InvalidKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Add a random key
+	config["i_should_not_be_valid"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	warnings, err := b.Prepare(testConfig())
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if !strings.HasPrefix(b.config.ImageLabel, "packer-") {
+		t.Errorf("bad image label: %s", b.config.ImageLabel)
+	}
+	if !strings.HasPrefix(b.config.InstanceLabel, "packer-") {
+		t.Errorf("bad instance label: %s", b.config.InstanceLabel)
+	}
+	if b.config.Comm.SSHUsername != "root" {
+		t.Errorf("bad ssh username: %s", b.config.Comm.SSHUsername)
+	}
+}
+
+func TestBuilderPrepare_Required(t *testing.T) {
+	old := os.Getenv("LINODE_TOKEN")
+	os.Setenv("LINODE_TOKEN", "")
+	defer os.Setenv("LINODE_TOKEN", old)
+
+	for _, key := range []string{"linode_token", "region", "instance_type", "image"} {
+		var b Builder
+		config := testConfig()
+		delete(config, key)
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error without %s", key)
+		}
+	}
+}
+
+func TestBuilderPrepare_StackScript(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["stackscript_data"] = map[string]string{"hostname": "packer"}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without stackscript_id")
+	}
+
+	config["stackscript_id"] = 1234
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.StackScriptData["hostname"] != "packer" {
+		t.Fatalf("bad: %#v", b.config.StackScriptData)
+	}
+}
+
+func TestBuilderPrepare_ImageLabel(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["image_label"] = "{{build_name}}-image"
+	config["image_description"] = "Built by {{build_type}}"
+	config["packer_build_name"] = "debian"
+	config["packer_builder_type"] = "linode"
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.ImageLabel != "debian-image" {
+		t.Errorf("bad image label: %s", b.config.ImageLabel)
+	}
+	if b.config.ImageDescription != "Built by linode" {
+		t.Errorf("bad image description: %s", b.config.ImageDescription)
+	}
+
+	config["image_label"] = strings.Repeat("a", 51)
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with a long label")
+	}
+}
+
+func TestPublicIPv4(t *testing.T) {
+	instance := &Instance{IPv4: []string{"192.168.135.10", "45.79.0.1"}}
+	if ip := publicIPv4(instance); ip != "45.79.0.1" {
+		t.Fatalf("bad: %s", ip)
+	}
+}
+
+func TestImageDisk(t *testing.T) {
+	disks := []Disk{
+		{ID: 1, Filesystem: "swap"},
+		{ID: 2, Filesystem: "ext4"},
+	}
+	if disk := imageDisk(disks); disk == nil || disk.ID != 2 {
+		t.Fatalf("bad: %#v", disk)
+	}
+	if disk := imageDisk(disks[:1]); disk != nil {
+		t.Fatalf("bad: %#v", disk)
+	}
+}
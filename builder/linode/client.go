@@ -0,0 +1,190 @@
+package linode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultAPIURL is the Linode API endpoint.
+const DefaultAPIURL = "https://api.linode.com/v4/"
+
+// NOTE: there is no Linode SDK vendored, so the few calls the builder makes
+// are done with the client here.
+
+// Client is a client of the Linode API.
+type Client struct {
+	BaseURL string
+	Token   string
+
+	HTTPClient *http.Client
+}
+
+// Instance is a Linode.
+type Instance struct {
+	ID     int      `json:"id"`
+	Label  string   `json:"label"`
+	Status string   `json:"status"`
+	IPv4   []string `json:"ipv4"`
+}
+
+// InstanceCreateRequest is the request of a new Linode, deployed from an
+// image and booted.
+type InstanceCreateRequest struct {
+	Region          string            `json:"region"`
+	Type            string            `json:"type"`
+	Image           string            `json:"image"`
+	Label           string            `json:"label,omitempty"`
+	Tags            []string          `json:"tags,omitempty"`
+	RootPass        string            `json:"root_pass"`
+	AuthorizedKeys  []string          `json:"authorized_keys,omitempty"`
+	StackScriptID   int               `json:"stackscript_id,omitempty"`
+	StackScriptData map[string]string `json:"stackscript_data,omitempty"`
+	PrivateIP       bool              `json:"private_ip,omitempty"`
+	Booted          bool              `json:"booted"`
+}
+
+// Disk is a disk of a Linode.
+type Disk struct {
+	ID         int    `json:"id"`
+	Label      string `json:"label"`
+	Filesystem string `json:"filesystem"`
+	Status     string `json:"status"`
+}
+
+// Image is a private image, created from a disk.
+type Image struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+// ErrorResponse is the error returned by the API.
+type ErrorResponse struct {
+	StatusCode int
+	Errors     []struct {
+		Field  string `json:"field"`
+		Reason string `json:"reason"`
+	} `json:"errors"`
+}
+
+func (e *ErrorResponse) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("Linode API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	reasons := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		if err.Field != "" {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", err.Field, err.Reason))
+		} else {
+			reasons = append(reasons, err.Reason)
+		}
+	}
+	return fmt.Sprintf("Linode API error: %d %s", e.StatusCode, strings.Join(reasons, ", "))
+}
+
+// CreateInstance deploys a Linode.
+func (c *Client) CreateInstance(req *InstanceCreateRequest) (*Instance, error) {
+	result := new(Instance)
+	err := c.do("POST", "linode/instances", req, result)
+	return result, err
+}
+
+// GetInstance returns a Linode.
+func (c *Client) GetInstance(id int) (*Instance, error) {
+	result := new(Instance)
+	err := c.do("GET", fmt.Sprintf("linode/instances/%d", id), nil, result)
+	return result, err
+}
+
+// ShutdownInstance shuts a Linode down.
+func (c *Client) ShutdownInstance(id int) error {
+	return c.do("POST", fmt.Sprintf("linode/instances/%d/shutdown", id), nil, nil)
+}
+
+// DeleteInstance deletes a Linode, and its disks.
+func (c *Client) DeleteInstance(id int) error {
+	return c.do("DELETE", fmt.Sprintf("linode/instances/%d", id), nil, nil)
+}
+
+// ListDisks returns the disks of a Linode.
+func (c *Client) ListDisks(id int) ([]Disk, error) {
+	var result struct {
+		Data []Disk `json:"data"`
+	}
+	err := c.do("GET", fmt.Sprintf("linode/instances/%d/disks", id), nil, &result)
+	return result.Data, err
+}
+
+// CreateImage starts a private image of a disk.
+func (c *Client) CreateImage(diskID int, label string, description string) (*Image, error) {
+	body := map[string]interface{}{
+		"disk_id":     diskID,
+		"label":       label,
+		"description": description,
+	}
+	result := new(Image)
+	err := c.do("POST", "images", body, result)
+	return result, err
+}
+
+// GetImage returns an image. The IDs of private images are like
+// private/1234.
+func (c *Client) GetImage(id string) (*Image, error) {
+	result := new(Image)
+	err := c.do("GET", fmt.Sprintf("images/%s", id), nil, result)
+	return result, err
+}
+
+// DeleteImage deletes a private image.
+func (c *Client) DeleteImage(id string) error {
+	return c.do("DELETE", fmt.Sprintf("images/%s", id), nil, nil)
+}
+
+func (c *Client) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.BaseURL, "/")+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &ErrorResponse{StatusCode: resp.StatusCode}
+		// The errors are best effort, the status is enough to fail
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,72 @@
+package linode
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_CreateInstance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/linode/instances" {
+			t.Errorf("bad request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("bad authorization: %s", r.Header.Get("Authorization"))
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if body["stackscript_id"] != float64(1234) {
+			t.Errorf("bad stackscript: %#v", body)
+		}
+		if !reflect.DeepEqual(body["stackscript_data"], map[string]interface{}{"hostname": "packer"}) {
+			t.Errorf("bad stackscript data: %#v", body)
+		}
+		if body["booted"] != true {
+			t.Errorf("should be booted: %#v", body)
+		}
+
+		w.Write([]byte(`{"id": 42, "status": "provisioning", "ipv4": ["45.79.0.1"]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL + "/", Token: "secret"}
+	instance, err := client.CreateInstance(&InstanceCreateRequest{
+		Region:          "us-east",
+		Type:            "g6-nanode-1",
+		Image:           "linode/debian10",
+		RootPass:        "pass",
+		StackScriptID:   1234,
+		StackScriptData: map[string]string{"hostname": "packer"},
+		Booted:          true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if instance.ID != 42 || instance.Status != "provisioning" {
+		t.Fatalf("bad: %#v", instance)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors": [{"field": "label", "reason": "Label must be unique"}]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL, Token: "secret"}
+	_, err := client.GetImage("private/42")
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	expected := "Linode API error: 400 label: Label must be unique"
+	if err.Error() != expected {
+		t.Fatalf("bad: %s", err)
+	}
+}
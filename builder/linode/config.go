@@ -0,0 +1,129 @@
+package linode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	Comm                communicator.Config `mapstructure:",squash"`
+
+	Token  string `mapstructure:"linode_token"`
+	APIURL string `mapstructure:"api_url"`
+
+	Region       string `mapstructure:"region"`
+	InstanceType string `mapstructure:"instance_type"`
+	Image        string `mapstructure:"image"`
+
+	StackScriptID   int               `mapstructure:"stackscript_id"`
+	StackScriptData map[string]string `mapstructure:"stackscript_data"`
+
+	InstanceLabel    string        `mapstructure:"instance_label"`
+	InstanceTags     []string      `mapstructure:"instance_tags"`
+	RootPass         string        `mapstructure:"root_pass"`
+	PrivateIP        bool          `mapstructure:"private_ip"`
+	ImageLabel       string        `mapstructure:"image_label"`
+	ImageDescription string        `mapstructure:"image_description"`
+	StateTimeout     time.Duration `mapstructure:"state_timeout"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.Token == "" {
+		// Default to environment variable for linode_token, if it exists
+		c.Token = os.Getenv("LINODE_TOKEN")
+	}
+	if c.APIURL == "" {
+		c.APIURL = DefaultAPIURL
+	}
+
+	if c.ImageLabel == "" {
+		def, err := interpolate.Render("packer-{{timestamp}}", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		// Default to packer-{{ unix timestamp (utc) }}
+		c.ImageLabel = def
+	}
+
+	if c.InstanceLabel == "" {
+		// Default to packer-[time-ordered-uuid]
+		c.InstanceLabel = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	}
+
+	if c.StateTimeout == 0 {
+		// Default to 5 minute timeouts waiting for
+		// desired state. i.e waiting for linode to be running
+		c.StateTimeout = 5 * time.Minute
+	}
+
+	if c.Comm.SSHUsername == "" {
+		c.Comm.SSHUsername = "root"
+	}
+
+	var errs *packer.MultiError
+	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+	if c.Token == "" {
+		// Required configurations that will display errors if not set
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("linode_token for auth must be specified"))
+	}
+
+	if c.Region == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("region is required"))
+	}
+
+	if c.InstanceType == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("instance_type is required"))
+	}
+
+	if c.Image == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("image is required"))
+	}
+
+	if len(c.StackScriptData) > 0 && c.StackScriptID == 0 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("stackscript_data requires stackscript_id"))
+	}
+
+	// The limit of the labels of the images
+	if len(c.ImageLabel) > 50 {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("image_label must be 50 characters or less: %s", c.ImageLabel))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	common.ScrubConfig(c, c.Token, c.RootPass)
+	return c, nil, nil
+}
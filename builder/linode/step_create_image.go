@@ -0,0 +1,75 @@
+package linode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepCreateImage struct{}
+
+func (s *stepCreateImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	linodeID := state.Get("linode_id").(int)
+
+	disks, err := client.ListDisks(linodeID)
+	if err != nil {
+		err := fmt.Errorf("Error listing the disks of the linode: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	disk := imageDisk(disks)
+	if disk == nil {
+		err := errors.New("Couldn't find the disk of the linode to create the image from")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Creating image: %v", c.ImageLabel))
+	image, err := client.CreateImage(disk.ID, c.ImageLabel, c.ImageDescription)
+	if err != nil {
+		err := fmt.Errorf("Error creating image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Images take a while, depending on the size of the disk, so we
+	// hardcode this to 30 minutes.
+	ui.Say("Waiting for image to become available...")
+	if err := waitForState("available", imageStatus(client, image.ID), 30*time.Minute); err != nil {
+		err := fmt.Errorf("Error waiting for image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	log.Printf("Image ID: %s", image.ID)
+	state.Put("image", image)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateImage) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// imageDisk returns the disk the image is created from, the first one that
+// isn't swap.
+func imageDisk(disks []Disk) *Disk {
+	for i := range disks {
+		if disks[i].Filesystem != "swap" {
+			return &disks[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package linode
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepCreateLinode struct {
+	instanceId int
+}
+
+func (s *stepCreateLinode) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	publicKey := state.Get("publicKey").(string)
+
+	ui.Say("Creating linode...")
+
+	// Linode requires a root password, which the builder never uses with
+	// the temporary key
+	rootPass := c.RootPass
+	if rootPass == "" {
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			state.Put("error", fmt.Errorf("Error generating root password: %s", err))
+			return multistep.ActionHalt
+		}
+		rootPass = base64.StdEncoding.EncodeToString(b)
+	}
+
+	if c.StackScriptID != 0 {
+		ui.Message(fmt.Sprintf("Using the StackScript: %d", c.StackScriptID))
+	}
+	instance, err := client.CreateInstance(&InstanceCreateRequest{
+		Region:          c.Region,
+		Type:            c.InstanceType,
+		Image:           c.Image,
+		Label:           c.InstanceLabel,
+		Tags:            c.InstanceTags,
+		RootPass:        rootPass,
+		AuthorizedKeys:  []string{publicKey},
+		StackScriptID:   c.StackScriptID,
+		StackScriptData: c.StackScriptData,
+		PrivateIP:       c.PrivateIP,
+		Booted:          true,
+	})
+	if err != nil {
+		err := fmt.Errorf("Error creating linode: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this in cleanup
+	s.instanceId = instance.ID
+
+	// Store the linode id for later
+	state.Put("linode_id", instance.ID)
+	ui.Message(fmt.Sprintf("Linode ID: %d", instance.ID))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateLinode) Cleanup(state multistep.StateBag) {
+	// If the linode id isn't there, we probably never created it
+	if s.instanceId == 0 {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+
+	// Destroy the linode we just created
+	ui.Say("Destroying linode...")
+	if err := client.DeleteInstance(s.instanceId); err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error destroying linode. Please destroy it manually: %s", err))
+	}
+}
@@ -0,0 +1,68 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepLinodeInfo struct{}
+
+// privateNetwork is the range of the private addresses of the linodes.
+var privateNetwork = &net.IPNet{IP: net.IPv4(192, 168, 0, 0), Mask: net.CIDRMask(16, 32)}
+
+func (s *stepLinodeInfo) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	linodeID := state.Get("linode_id").(int)
+
+	ui.Say("Waiting for linode to become running...")
+
+	err := waitForState("running", instanceStatus(client, linodeID), c.StateTimeout)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for linode to become running: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Set the IP on the state for later
+	instance, err := client.GetInstance(linodeID)
+	if err != nil {
+		err := fmt.Errorf("Error retrieving linode: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ip := publicIPv4(instance)
+	if ip == "" {
+		err := fmt.Errorf("Could not find a public IPv4 address for this linode")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	state.Put("linode_ip", ip)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepLinodeInfo) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// publicIPv4 returns the first IPv4 address of the linode that isn't its
+// private one.
+func publicIPv4(instance *Instance) string {
+	for _, address := range instance.IPv4 {
+		ip := net.ParseIP(address)
+		if ip != nil && !privateNetwork.Contains(ip) {
+			return address
+		}
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepShutdown struct{}
+
+func (s *stepShutdown) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	linodeID := state.Get("linode_id").(int)
+
+	ui.Say("Shutting down linode...")
+	if err := client.ShutdownInstance(linodeID); err != nil {
+		err := fmt.Errorf("Error shutting down linode: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	err := waitForState("offline", instanceStatus(client, linodeID), c.StateTimeout)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for linode to shut down: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepShutdown) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,74 @@
+package linode
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// waitForState polls refresh until it returns the desired state.
+func waitForState(
+	desiredState string, refresh func() (string, error), timeout time.Duration) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	result := make(chan error, 1)
+	go func() {
+		attempts := 0
+		for {
+			attempts += 1
+
+			log.Printf("Checking state... (attempt: %d)", attempts)
+			state, err := refresh()
+			if err != nil {
+				result <- err
+				return
+			}
+
+			if state == desiredState {
+				result <- nil
+				return
+			}
+
+			// Wait 3 seconds in between
+			time.Sleep(3 * time.Second)
+
+			// Verify we shouldn't exit
+			select {
+			case <-done:
+				// We finished, so just exit the goroutine
+				return
+			default:
+				// Keep going
+			}
+		}
+	}()
+
+	log.Printf("Waiting for up to %d seconds for state to become: %s", timeout/time.Second, desiredState)
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("Timeout while waiting to for state to become '%s'", desiredState)
+	}
+}
+
+func instanceStatus(client *Client, id int) func() (string, error) {
+	return func() (string, error) {
+		instance, err := client.GetInstance(id)
+		if err != nil {
+			return "", err
+		}
+		return instance.Status, nil
+	}
+}
+
+func imageStatus(client *Client, id string) func() (string, error) {
+	return func() (string, error) {
+		image, err := client.GetImage(id)
+		if err != nil {
+			return "", err
+		}
+		return image.Status, nil
+	}
+}
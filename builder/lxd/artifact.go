@@ -2,10 +2,16 @@ package lxd
 
 import (
 	"fmt"
+	"os"
 )
 
 type Artifact struct {
 	id string
+
+	// The remote of the image, and the path of its export if it was
+	// exported.
+	remote string
+	file   string
 }
 
 func (*Artifact) BuilderId() string {
@@ -13,7 +19,10 @@ func (*Artifact) BuilderId() string {
 }
 
 func (a *Artifact) Files() []string {
-	return nil
+	if a.file == "" {
+		return nil
+	}
+	return []string{a.file}
 }
 
 func (a *Artifact) Id() string {
@@ -21,7 +30,10 @@ func (a *Artifact) Id() string {
 }
 
 func (a *Artifact) String() string {
-	return fmt.Sprintf("image: %s", a.id)
+	if a.file != "" {
+		return fmt.Sprintf("image: %s, exported to %s", remoteName(a.remote, a.id), a.file)
+	}
+	return fmt.Sprintf("image: %s", remoteName(a.remote, a.id))
 }
 
 func (a *Artifact) State(name string) interface{} {
@@ -29,6 +41,11 @@ func (a *Artifact) State(name string) interface{} {
 }
 
 func (a *Artifact) Destroy() error {
-	_, err := LXDCommand("image", "delete", a.id)
+	if a.file != "" {
+		if err := os.Remove(a.file); err != nil {
+			return err
+		}
+	}
+	_, err := LXDCommand("image", "delete", remoteName(a.remote, a.id))
 	return err
 }
@@ -61,7 +61,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 
 	artifact := &Artifact{
-		id: state.Get("imageFingerprint").(string),
+		id:     state.Get("imageFingerprint").(string),
+		remote: b.config.PublishRemote,
+	}
+	if path, ok := state.GetOk("exportPath"); ok {
+		artifact.file = path.(string)
 	}
 
 	return artifact, nil
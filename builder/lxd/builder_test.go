@@ -2,6 +2,7 @@ package lxd
 
 import (
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/packer/packer"
@@ -68,6 +69,85 @@ func TestBuilderPrepare_ConfigFile(t *testing.T) {
 
 }
 
+func TestBuilderPrepare_Profiles(t *testing.T) {
+	var b Builder
+
+	// Default
+	config := testConfig()
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !reflect.DeepEqual(b.config.Profiles, []string{"default"}) {
+		t.Fatalf("bad: %#v", b.config.Profiles)
+	}
+
+	// Good, a single profile
+	config["profile"] = "web"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !reflect.DeepEqual(b.config.Profiles, []string{"web"}) {
+		t.Fatalf("bad: %#v", b.config.Profiles)
+	}
+
+	// Bad, both
+	config["profiles"] = []string{"default", "web"}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatalf("should have error")
+	}
+}
+
+func TestLaunchArgs(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["container_name"] = "build"
+	config["profiles"] = []string{"default", "web"}
+	config["storage_pool"] = "fast"
+	config["remote"] = "cluster"
+	config["target"] = "node2"
+	config["launch_config"] = map[string]string{"security.nesting": "true", "limits.cpu": "2"}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	expected := []string{
+		"launch", "--ephemeral=false", "--profile=default", "--profile=web",
+		"--storage", "fast", "--target", "node2",
+		"--config", "limits.cpu=2", "--config", "security.nesting=true",
+		"bar", "cluster:build",
+	}
+	if args := launchArgs(b.config); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	// Published where the container is
+	expected = []string{"publish", "cluster:build", "cluster:", "--alias", "foo"}
+	if args := publishArgs(b.config); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
+
+func TestPublishArgs(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["container_name"] = "build"
+	config["publish_remote"] = "images"
+	config["publish_aliases"] = []string{"foo/latest"}
+	config["publish_properties"] = map[string]string{"description": "web"}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	expected := []string{
+		"publish", "build", "images:", "--alias", "foo", "--alias", "foo/latest", "description=web",
+	}
+	if args := publishArgs(b.config); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
+
 func TestBuilder_ImplementsBuilder(t *testing.T) {
 	var raw interface{}
 	raw = &Builder{}
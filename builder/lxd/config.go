@@ -2,6 +2,7 @@ package lxd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/config"
@@ -17,8 +18,15 @@ type Config struct {
 	CommandWrapper      string            `mapstructure:"command_wrapper"`
 	Image               string            `mapstructure:"image"`
 	Profile             string            `mapstructure:"profile"`
+	Profiles            []string          `mapstructure:"profiles"`
+	StoragePool         string            `mapstructure:"storage_pool"`
+	Remote              string            `mapstructure:"remote"`
+	Target              string            `mapstructure:"target"`
 	InitSleep           string            `mapstructure:"init_sleep"`
 	PublishProperties   map[string]string `mapstructure:"publish_properties"`
+	PublishRemote       string            `mapstructure:"publish_remote"`
+	PublishAliases      []string          `mapstructure:"publish_aliases"`
+	ExportPath          string            `mapstructure:"export_path"`
 	LaunchConfig        map[string]string `mapstructure:"launch_config"`
 
 	ctx interpolate.Context
@@ -55,8 +63,26 @@ func NewConfig(raws ...interface{}) (*Config, error) {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("`image` is a required parameter for LXD. Please specify an image by alias or fingerprint. e.g. `ubuntu-daily:x`"))
 	}
 
-	if c.Profile == "" {
-		c.Profile = "default"
+	if c.Profile != "" && len(c.Profiles) > 0 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Only one of `profile` and `profiles` can be specified."))
+	}
+	if c.Profile != "" {
+		c.Profiles = []string{c.Profile}
+	}
+	if len(c.Profiles) == 0 {
+		c.Profiles = []string{"default"}
+	}
+
+	// The image is published where the container is, unless it is
+	// published to another remote
+	if c.PublishRemote == "" {
+		c.PublishRemote = c.Remote
+	}
+
+	if c.ExportPath != "" {
+		if fi, err := os.Stat(c.ExportPath); err == nil && fi.IsDir() {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("`export_path` must be a file, not a directory."))
+		}
 	}
 
 	// Sadly we have to wait a few seconds for /tmp to be intialized and networking
@@ -71,3 +97,17 @@ func NewConfig(raws ...interface{}) (*Config, error) {
 
 	return &c, nil
 }
+
+// remoteName is the name on the remote, which is the default remote unless
+// one is given.
+func remoteName(remote string, name string) string {
+	if remote == "" {
+		return name
+	}
+	return remote + ":" + name
+}
+
+// container is the name of the container, on its remote.
+func (c *Config) container() string {
+	return remoteName(c.Remote, c.ContainerName)
+}
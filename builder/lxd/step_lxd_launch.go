@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 	"time"
 
@@ -17,20 +18,8 @@ func (s *stepLxdLaunch) Run(_ context.Context, state multistep.StateBag) multist
 	config := state.Get("config").(*Config)
 	ui := state.Get("ui").(packer.Ui)
 
-	name := config.ContainerName
-	image := config.Image
-	profile := fmt.Sprintf("--profile=%s", config.Profile)
-
-	launch_args := []string{
-		"launch", "--ephemeral=false", profile, image, name,
-	}
-
-	for k, v := range config.LaunchConfig {
-		launch_args = append(launch_args, fmt.Sprintf("--config %s=%s", k, v))
-	}
-
 	ui.Say("Creating container...")
-	_, err := LXDCommand(launch_args...)
+	_, err := LXDCommand(launchArgs(config)...)
 	if err != nil {
 		err := fmt.Errorf("Error creating container: %s", err)
 		state.Put("error", err)
@@ -58,7 +47,7 @@ func (s *stepLxdLaunch) Cleanup(state multistep.StateBag) {
 	ui := state.Get("ui").(packer.Ui)
 
 	cleanup_args := []string{
-		"delete", "--force", config.ContainerName,
+		"delete", "--force", config.container(),
 	}
 
 	ui.Say("Unregistering and deleting deleting container...")
@@ -66,3 +55,29 @@ func (s *stepLxdLaunch) Cleanup(state multistep.StateBag) {
 		ui.Error(fmt.Sprintf("Error deleting container: %s", err))
 	}
 }
+
+// launchArgs are the arguments of lxc launch for the container, with its
+// profiles, storage pool and cluster member.
+func launchArgs(config *Config) []string {
+	args := []string{"launch", "--ephemeral=false"}
+	for _, profile := range config.Profiles {
+		args = append(args, fmt.Sprintf("--profile=%s", profile))
+	}
+	if config.StoragePool != "" {
+		args = append(args, "--storage", config.StoragePool)
+	}
+	if config.Target != "" {
+		args = append(args, "--target", config.Target)
+	}
+
+	keys := make([]string, 0, len(config.LaunchConfig))
+	for k := range config.LaunchConfig {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--config", fmt.Sprintf("%s=%s", k, config.LaunchConfig[k]))
+	}
+
+	return append(args, config.Image, config.container())
+}
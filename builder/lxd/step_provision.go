@@ -19,7 +19,7 @@ func (s *StepProvision) Run(_ context.Context, state multistep.StateBag) multist
 
 	// Create our communicator
 	comm := &Communicator{
-		ContainerName: config.ContainerName,
+		ContainerName: config.container(),
 		CmdWrapper:    wrappedCommand,
 	}
 
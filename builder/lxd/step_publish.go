@@ -3,7 +3,10 @@ package lxd
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -15,7 +18,7 @@ func (s *stepPublish) Run(_ context.Context, state multistep.StateBag) multistep
 	config := state.Get("config").(*Config)
 	ui := state.Get("ui").(packer.Ui)
 
-	name := config.ContainerName
+	name := config.container()
 	stop_args := []string{
 		// We created the container with "--ephemeral=false" so we know it is safe to stop.
 		"stop", name,
@@ -30,16 +33,8 @@ func (s *stepPublish) Run(_ context.Context, state multistep.StateBag) multistep
 		return multistep.ActionHalt
 	}
 
-	publish_args := []string{
-		"publish", name, "--alias", config.OutputImage,
-	}
-
-	for k, v := range config.PublishProperties {
-		publish_args = append(publish_args, fmt.Sprintf("%s=%s", k, v))
-	}
-
 	ui.Say("Publishing container...")
-	stdoutString, err := LXDCommand(publish_args...)
+	stdoutString, err := LXDCommand(publishArgs(config)...)
 	if err != nil {
 		err := fmt.Errorf("Error publishing container: %s", err)
 		state.Put("error", err)
@@ -54,7 +49,61 @@ func (s *stepPublish) Run(_ context.Context, state multistep.StateBag) multistep
 
 	state.Put("imageFingerprint", fingerprint)
 
+	if config.ExportPath != "" {
+		ui.Say(fmt.Sprintf("Exporting image to %s...", config.ExportPath))
+		path, err := exportImage(remoteName(config.PublishRemote, fingerprint), config.ExportPath)
+		if err != nil {
+			err := fmt.Errorf("Error exporting image: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		state.Put("exportPath", path)
+	}
+
 	return multistep.ActionContinue
 }
 
+// publishArgs are the arguments of lxc publish for the container, with the
+// aliases and properties of the image, on its remote.
+func publishArgs(config *Config) []string {
+	args := []string{"publish", config.container()}
+	if config.PublishRemote != "" {
+		args = append(args, config.PublishRemote+":")
+	}
+
+	args = append(args, "--alias", config.OutputImage)
+	for _, alias := range config.PublishAliases {
+		args = append(args, "--alias", alias)
+	}
+
+	keys := make([]string, 0, len(config.PublishProperties))
+	for k := range config.PublishProperties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, fmt.Sprintf("%s=%s", k, config.PublishProperties[k]))
+	}
+	return args
+}
+
+// exportImage exports the image as a unified tarball, and returns its path,
+// which lxc suffixes with the extension of the compression of the image
+// unless it already has it.
+func exportImage(image string, path string) (string, error) {
+	if _, err := LXDCommand("image", "export", image, path); err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil || len(matches) != 1 {
+		return "", fmt.Errorf("The export of image %s to %s wasn't found", image, path)
+	}
+	return matches[0], nil
+}
+
 func (s *stepPublish) Cleanup(state multistep.StateBag) {}
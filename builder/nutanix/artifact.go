@@ -0,0 +1,48 @@
+package nutanix
+
+import (
+	"fmt"
+	"log"
+)
+
+type Artifact struct {
+	// The UUID of the image
+	imageUUID string
+
+	// The name of the image
+	imageName string
+
+	// The client for making API calls
+	client *Client
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	// No files with Nutanix
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return a.imageUUID
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("An image was created: '%v' (UUID: %v)", a.imageName, a.imageUUID)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "ImageName":
+		return a.imageName
+	}
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying image: %s (%s)", a.imageUUID, a.imageName)
+	_, err := a.client.DeleteImage(a.imageUUID)
+	return err
+}
@@ -0,0 +1,20 @@
+package nutanix
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestArtifactString(t *testing.T) {
+	a := &Artifact{imageUUID: "abc", imageName: "packer-foobar"}
+	expected := "An image was created: 'packer-foobar' (UUID: abc)"
+
+	if a.String() != expected {
+		t.Fatalf("artifact string should match: %v", expected)
+	}
+}
@@ -0,0 +1,86 @@
+// The nutanix package contains a packer.Builder implementation
+// that builds Nutanix AHV images with Prism Central.
+
+package nutanix
+
+import (
+	"log"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The unique id for the builder
+const BuilderId = "packer.nutanix"
+
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, errs := NewConfig(raws...)
+	if errs != nil {
+		return warnings, errs
+	}
+	b.config = c
+
+	return nil, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	client := NewClient(b.config.prismURL(), b.config.Username, b.config.Password, b.config.Insecure)
+
+	// Set up the state
+	state := new(multistep.BasicStateBag)
+	state.Put("config", b.config)
+	state.Put("client", client)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	// Build the steps
+	steps := []multistep.Step{
+		new(stepResolveReferences),
+		new(stepCreateVM),
+		new(stepVMInfo),
+		&communicator.StepConnect{
+			Config:    &b.config.Comm,
+			Host:      commHost,
+			SSHConfig: sshConfig(&b.config.Comm),
+		},
+		new(common.StepProvision),
+		new(stepPowerOff),
+		new(stepCreateImage),
+	}
+
+	// Run the steps
+	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	if _, ok := state.GetOk("image_uuid"); !ok {
+		log.Println("Failed to find image_uuid in state. Bug?")
+		return nil, nil
+	}
+
+	artifact := &Artifact{
+		imageUUID: state.Get("image_uuid").(string),
+		imageName: b.config.ImageName,
+		client:    client,
+	}
+
+	return artifact, nil
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
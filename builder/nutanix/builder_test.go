@@ -0,0 +1,96 @@
+package nutanix
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"prism_endpoint":    "prism.example.com",
+		"prism_username":    "admin",
+		"prism_password":    "secret",
+		"cluster_name":      "cluster",
+		"subnet_name":       "vlan0",
+		"source_image_name": "centos7",
+		"ssh_username":      "centos",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare_InvalidKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Add a random key
+	config["i_should_not_be_valid"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	warnings, err := b.Prepare(testConfig())
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.prismURL() != "https://prism.example.com:9440" {
+		t.Errorf("bad url: %s", b.config.prismURL())
+	}
+	if !strings.HasPrefix(b.config.ImageName, "packer-") {
+		t.Errorf("bad image name: %s", b.config.ImageName)
+	}
+	if b.config.CPUs != 1 || b.config.MemoryMB != 2048 {
+		t.Errorf("bad size: %d %d", b.config.CPUs, b.config.MemoryMB)
+	}
+}
+
+func TestBuilderPrepare_Required(t *testing.T) {
+	for _, key := range []string{"prism_endpoint", "prism_password", "cluster_name", "subnet_name", "source_image_name"} {
+		var b Builder
+		config := testConfig()
+		delete(config, key)
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error without %s", key)
+		}
+	}
+}
+
+func TestBuilderPrepare_ISO(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["iso_image_name"] = "centos7-iso"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with both sources")
+	}
+
+	delete(config, "source_image_name")
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without disk_size_gb")
+	}
+
+	config["disk_size_gb"] = 40
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
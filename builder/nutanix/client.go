@@ -0,0 +1,285 @@
+package nutanix
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NOTE: there is no Nutanix SDK vendored, so the few calls of the Prism
+// Central v3 API the builder makes are done with the client here.
+
+// Client is a client of the Prism Central v3 API.
+type Client struct {
+	// URL is the base URL of Prism Central, like https://prism:9440.
+	URL      string
+	Username string
+	Password string
+
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client of the Prism Central at the URL, which may not
+// verify the certificate if it is self signed.
+func NewClient(url string, username string, password string, insecure bool) *Client {
+	transport := http.DefaultTransport.(*http.Transport)
+	if insecure {
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return &Client{
+		URL:      url,
+		Username: username,
+		Password: password,
+		HTTPClient: &http.Client{
+			Transport: transport,
+			Timeout:   time.Minute,
+		},
+	}
+}
+
+// Reference is a reference to an entity of a kind.
+type Reference struct {
+	Kind string `json:"kind"`
+	UUID string `json:"uuid"`
+}
+
+// Metadata are the metadata of an entity, with its project and categories.
+type Metadata struct {
+	Kind             string            `json:"kind"`
+	UUID             string            `json:"uuid,omitempty"`
+	ProjectReference *Reference        `json:"project_reference,omitempty"`
+	Categories       map[string]string `json:"categories,omitempty"`
+}
+
+// VMDisk is a disk or a CD-ROM of a VM.
+type VMDisk struct {
+	UUID                string            `json:"uuid,omitempty"`
+	DataSourceReference *Reference        `json:"data_source_reference,omitempty"`
+	DiskSizeMib         int64             `json:"disk_size_mib,omitempty"`
+	DeviceProperties    *deviceProperties `json:"device_properties,omitempty"`
+}
+
+type deviceProperties struct {
+	DeviceType  string `json:"device_type"`
+	DiskAddress struct {
+		AdapterType string `json:"adapter_type"`
+		DeviceIndex int    `json:"device_index"`
+	} `json:"disk_address"`
+}
+
+// VMNic is a network interface of a VM.
+type VMNic struct {
+	SubnetReference *Reference `json:"subnet_reference,omitempty"`
+	IPEndpointList  []struct {
+		IP string `json:"ip"`
+	} `json:"ip_endpoint_list,omitempty"`
+}
+
+// VM is a virtual machine, as it is returned.
+type VM struct {
+	Spec     json.RawMessage `json:"spec"`
+	Metadata json.RawMessage `json:"metadata"`
+	Status   struct {
+		State     string `json:"state"`
+		Resources struct {
+			PowerState string   `json:"power_state"`
+			DiskList   []VMDisk `json:"disk_list"`
+			NicList    []VMNic  `json:"nic_list"`
+		} `json:"resources"`
+	} `json:"status"`
+}
+
+// Task is an asynchronous operation of Prism Central.
+type Task struct {
+	UUID        string `json:"uuid"`
+	Status      string `json:"status"`
+	ErrorDetail string `json:"error_detail"`
+}
+
+// ErrorResponse is the error returned by the API.
+type ErrorResponse struct {
+	StatusCode  int
+	MessageList []struct {
+		Message string `json:"message"`
+		Reason  string `json:"reason"`
+	} `json:"message_list"`
+}
+
+func (e *ErrorResponse) Error() string {
+	if len(e.MessageList) == 0 {
+		return fmt.Sprintf("Prism Central API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	messages := make([]string, 0, len(e.MessageList))
+	for _, m := range e.MessageList {
+		messages = append(messages, m.Message)
+	}
+	return fmt.Sprintf("Prism Central API error: %d %s", e.StatusCode, strings.Join(messages, ", "))
+}
+
+// intentResponse is the response of the calls that change an entity.
+type intentResponse struct {
+	Status struct {
+		ExecutionContext struct {
+			TaskUUID string `json:"task_uuid"`
+		} `json:"execution_context"`
+	} `json:"status"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// FindUUID returns the UUID of the entity of the kind, such as cluster,
+// with the name.
+func (c *Client) FindUUID(kind string, name string) (string, error) {
+	body := map[string]interface{}{
+		"kind":   kind,
+		"filter": fmt.Sprintf("name==%s", name),
+	}
+	var result struct {
+		Entities []struct {
+			Metadata Metadata `json:"metadata"`
+		} `json:"entities"`
+	}
+	if err := c.do("POST", kind+"s/list", body, &result); err != nil {
+		return "", err
+	}
+
+	switch len(result.Entities) {
+	case 0:
+		return "", fmt.Errorf("no %s named %s", kind, name)
+	case 1:
+		return result.Entities[0].Metadata.UUID, nil
+	default:
+		return "", fmt.Errorf("more than one %s named %s", kind, name)
+	}
+}
+
+// CreateVM creates a VM, and returns its UUID and the UUID of the task
+// creating it.
+func (c *Client) CreateVM(spec interface{}, metadata *Metadata) (string, string, error) {
+	return c.create("vms", spec, metadata)
+}
+
+// GetVM returns a VM.
+func (c *Client) GetVM(uuid string) (*VM, error) {
+	result := new(VM)
+	err := c.do("GET", "vms/"+uuid, nil, result)
+	return result, err
+}
+
+// PowerOffVM shuts the guest of a VM down, and returns the UUID of the task.
+func (c *Client) PowerOffVM(uuid string) (string, error) {
+	vm, err := c.GetVM(uuid)
+	if err != nil {
+		return "", err
+	}
+
+	// The VM is updated with its whole spec, as it is returned
+	var spec map[string]interface{}
+	if err := json.Unmarshal(vm.Spec, &spec); err != nil {
+		return "", err
+	}
+	resources, ok := spec["resources"].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("VM %s has no resources", uuid)
+	}
+	resources["power_state"] = "OFF"
+	resources["power_state_mechanism"] = map[string]interface{}{"mechanism": "ACPI"}
+
+	body := map[string]interface{}{
+		"spec":     spec,
+		"metadata": vm.Metadata,
+	}
+	var result intentResponse
+	err = c.do("PUT", "vms/"+uuid, body, &result)
+	return result.Status.ExecutionContext.TaskUUID, err
+}
+
+// DeleteVM deletes a VM, and returns the UUID of the task.
+func (c *Client) DeleteVM(uuid string) (string, error) {
+	var result intentResponse
+	err := c.do("DELETE", "vms/"+uuid, nil, &result)
+	return result.Status.ExecutionContext.TaskUUID, err
+}
+
+// CreateImage creates an image, and returns its UUID and the UUID of the
+// task creating it.
+func (c *Client) CreateImage(spec interface{}, metadata *Metadata) (string, string, error) {
+	return c.create("images", spec, metadata)
+}
+
+// DeleteImage deletes an image, and returns the UUID of the task.
+func (c *Client) DeleteImage(uuid string) (string, error) {
+	var result intentResponse
+	err := c.do("DELETE", "images/"+uuid, nil, &result)
+	return result.Status.ExecutionContext.TaskUUID, err
+}
+
+// GetTask returns a task.
+func (c *Client) GetTask(uuid string) (*Task, error) {
+	result := new(Task)
+	err := c.do("GET", "tasks/"+uuid, nil, result)
+	return result, err
+}
+
+func (c *Client) create(path string, spec interface{}, metadata *Metadata) (string, string, error) {
+	body := map[string]interface{}{
+		"spec":     spec,
+		"metadata": metadata,
+	}
+	var result intentResponse
+	if err := c.do("POST", path, body, &result); err != nil {
+		return "", "", err
+	}
+	return result.Metadata.UUID, result.Status.ExecutionContext.TaskUUID, nil
+}
+
+func (c *Client) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.URL, "/")+"/api/nutanix/v3/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &ErrorResponse{StatusCode: resp.StatusCode}
+		// The messages are best effort, the status is enough to fail
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,82 @@
+package nutanix
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_FindUUID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/nutanix/v3/clusters/list" {
+			t.Errorf("bad request: %s %s", r.Method, r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			t.Errorf("bad auth: %s %s", user, pass)
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body["filter"] {
+		case "name==cluster":
+			w.Write([]byte(`{"entities": [{"metadata": {"kind": "cluster", "uuid": "abc"}}]}`))
+		case "name==twice":
+			w.Write([]byte(`{"entities": [{"metadata": {"uuid": "abc"}}, {"metadata": {"uuid": "def"}}]}`))
+		default:
+			w.Write([]byte(`{"entities": []}`))
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL, Username: "admin", Password: "secret"}
+	uuid, err := client.FindUUID("cluster", "cluster")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if uuid != "abc" {
+		t.Fatalf("bad: %s", uuid)
+	}
+
+	if _, err := client.FindUUID("cluster", "twice"); err == nil {
+		t.Fatal("should have error with an ambiguous name")
+	}
+	if _, err := client.FindUUID("cluster", "missing"); err == nil {
+		t.Fatal("should have error with a missing name")
+	}
+}
+
+func TestClient_PowerOffVM(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`{"spec": {"name": "packer", "resources": {"power_state": "ON", "num_sockets": 1}}, "metadata": {"kind": "vm", "uuid": "vm", "spec_version": 3}}`))
+		case "PUT":
+			var body struct {
+				Spec struct {
+					Resources map[string]interface{} `json:"resources"`
+				} `json:"spec"`
+				Metadata map[string]interface{} `json:"metadata"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Spec.Resources["power_state"] != "OFF" || body.Spec.Resources["num_sockets"] != float64(1) {
+				t.Errorf("bad spec: %#v", body.Spec)
+			}
+			if body.Metadata["spec_version"] != float64(3) {
+				t.Errorf("the metadata should be kept: %#v", body.Metadata)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"status": {"execution_context": {"task_uuid": "task"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL}
+	taskUUID, err := client.PowerOffVM("vm")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if taskUUID != "task" {
+		t.Fatalf("bad: %s", taskUUID)
+	}
+}
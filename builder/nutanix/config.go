@@ -0,0 +1,152 @@
+package nutanix
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	Comm                communicator.Config `mapstructure:",squash"`
+
+	Endpoint string `mapstructure:"prism_endpoint"`
+	Port     int    `mapstructure:"prism_port"`
+	Username string `mapstructure:"prism_username"`
+	Password string `mapstructure:"prism_password"`
+	Insecure bool   `mapstructure:"prism_insecure"`
+
+	ClusterName     string `mapstructure:"cluster_name"`
+	SubnetName      string `mapstructure:"subnet_name"`
+	SourceImageName string `mapstructure:"source_image_name"`
+	ISOImageName    string `mapstructure:"iso_image_name"`
+	DiskSizeGB      int64  `mapstructure:"disk_size_gb"`
+
+	VMName   string `mapstructure:"vm_name"`
+	CPUs     int    `mapstructure:"cpus"`
+	MemoryMB int64  `mapstructure:"memory_mb"`
+	UserData string `mapstructure:"user_data"`
+
+	ImageName        string            `mapstructure:"image_name"`
+	ImageDescription string            `mapstructure:"image_description"`
+	ProjectName      string            `mapstructure:"project_name"`
+	ImageCategories  map[string]string `mapstructure:"image_categories"`
+
+	StateTimeout time.Duration `mapstructure:"state_timeout"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.Endpoint == "" {
+		c.Endpoint = os.Getenv("NUTANIX_ENDPOINT")
+	}
+	if c.Username == "" {
+		c.Username = os.Getenv("NUTANIX_USERNAME")
+	}
+	if c.Password == "" {
+		c.Password = os.Getenv("NUTANIX_PASSWORD")
+	}
+	if c.Port == 0 {
+		c.Port = 9440
+	}
+
+	if c.ImageName == "" {
+		def, err := interpolate.Render("packer-{{timestamp}}", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		// Default to packer-{{ unix timestamp (utc) }}
+		c.ImageName = def
+	}
+
+	if c.VMName == "" {
+		// Default to packer-[time-ordered-uuid]
+		c.VMName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	}
+
+	if c.CPUs == 0 {
+		c.CPUs = 1
+	}
+	if c.MemoryMB == 0 {
+		c.MemoryMB = 2048
+	}
+
+	if c.StateTimeout == 0 {
+		// Installing from an ISO takes a while before the guest gets
+		// an IP address
+		c.StateTimeout = 15 * time.Minute
+	}
+
+	var errs *packer.MultiError
+	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if c.Endpoint == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("prism_endpoint is required"))
+	}
+	if c.Username == "" || c.Password == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("prism_username and prism_password for auth must be specified"))
+	}
+
+	if c.ClusterName == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("cluster_name is required"))
+	}
+	if c.SubnetName == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("subnet_name is required"))
+	}
+
+	if (c.SourceImageName == "") == (c.ISOImageName == "") {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("exactly one of source_image_name or iso_image_name is required"))
+	}
+	if c.ISOImageName != "" && c.DiskSizeGB == 0 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("disk_size_gb is required to install from an ISO"))
+	}
+	if c.DiskSizeGB < 0 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("disk_size_gb must be positive"))
+	}
+	if c.ISOImageName != "" && c.UserData != "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("user_data can't be used to install from an ISO"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	common.ScrubConfig(c, c.Password)
+	return c, nil, nil
+}
+
+// prismURL is the base URL of Prism Central.
+func (c *Config) prismURL() string {
+	return fmt.Sprintf("https://%s:%d", c.Endpoint, c.Port)
+}
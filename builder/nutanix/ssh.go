@@ -0,0 +1,38 @@
+package nutanix
+
+import (
+	commonssh "github.com/hashicorp/packer/common/ssh"
+	"github.com/hashicorp/packer/communicator/ssh"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func commHost(state multistep.StateBag) (string, error) {
+	return state.Get("vm_ip").(string), nil
+}
+
+func sshConfig(comm *communicator.Config) func(multistep.StateBag) (*gossh.ClientConfig, error) {
+	return func(state multistep.StateBag) (*gossh.ClientConfig, error) {
+		auth := []gossh.AuthMethod{
+			gossh.Password(comm.SSHPassword),
+			gossh.KeyboardInteractive(
+				ssh.PasswordKeyboardInteractive(comm.SSHPassword)),
+		}
+
+		if comm.SSHPrivateKey != "" {
+			signer, err := commonssh.FileSigner(comm.SSHPrivateKey)
+			if err != nil {
+				return nil, err
+			}
+
+			auth = append(auth, gossh.PublicKeys(signer))
+		}
+
+		return &gossh.ClientConfig{
+			User:            comm.SSHUsername,
+			Auth:            auth,
+			HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+		}, nil
+	}
+}
@@ -0,0 +1,80 @@
+package nutanix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepCreateImage publishes the disk of the VM as an image, in the project
+// and with the categories.
+type stepCreateImage struct{}
+
+func (s *stepCreateImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	vmUUID := state.Get("vm_uuid").(string)
+
+	vm, err := client.GetVM(vmUUID)
+	if err != nil {
+		err := fmt.Errorf("Error retrieving VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	diskUUID := vmDiskUUID(vm)
+	if diskUUID == "" {
+		err := errors.New("Couldn't find the disk of the VM to create the image from")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	metadata := projectMetadata("image", state)
+	metadata.Categories = c.ImageCategories
+	spec := map[string]interface{}{
+		"name":        c.ImageName,
+		"description": c.ImageDescription,
+		"resources": map[string]interface{}{
+			"image_type":            "DISK_IMAGE",
+			"data_source_reference": &Reference{Kind: "vm_disk", UUID: diskUUID},
+		},
+	}
+
+	ui.Say(fmt.Sprintf("Creating image: %s", c.ImageName))
+	uuid, taskUUID, err := client.CreateImage(spec, metadata)
+	if err == nil {
+		err = waitForTask(client, taskUUID, c.StateTimeout)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error creating image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	log.Printf("Image UUID: %s", uuid)
+	state.Put("image_uuid", uuid)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateImage) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// vmDiskUUID returns the UUID of the first disk of the VM, which isn't a
+// CD-ROM.
+func vmDiskUUID(vm *VM) string {
+	for _, disk := range vm.Status.Resources.DiskList {
+		if disk.DeviceProperties != nil && disk.DeviceProperties.DeviceType == "DISK" {
+			return disk.UUID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package nutanix
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepCreateVM struct {
+	vmUUID string
+}
+
+func (s *stepCreateVM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	ui.Say(fmt.Sprintf("Creating VM: %s", c.VMName))
+	uuid, taskUUID, err := client.CreateVM(vmSpec(c, state), projectMetadata("vm", state))
+	if err != nil {
+		err := fmt.Errorf("Error creating VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this in cleanup
+	s.vmUUID = uuid
+
+	if err := waitForTask(client, taskUUID, c.StateTimeout); err != nil {
+		err := fmt.Errorf("Error waiting for VM to be created: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Store the VM UUID for later
+	state.Put("vm_uuid", uuid)
+	ui.Message(fmt.Sprintf("VM UUID: %s", uuid))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateVM) Cleanup(state multistep.StateBag) {
+	// If the VM UUID isn't there, we probably never created it
+	if s.vmUUID == "" {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	// Destroy the VM we just created
+	ui.Say("Deleting VM...")
+	taskUUID, err := client.DeleteVM(s.vmUUID)
+	if err == nil {
+		err = waitForTask(client, taskUUID, c.StateTimeout)
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error deleting VM. Please delete it manually: %s", err))
+	}
+}
+
+// vmSpec is the spec of the VM, which boots either a clone of the source
+// image, or the ISO with an empty disk to install to.
+func vmSpec(c *Config, state multistep.StateBag) map[string]interface{} {
+	var disks []*VMDisk
+	resources := map[string]interface{}{
+		"num_sockets":          c.CPUs,
+		"num_vcpus_per_socket": 1,
+		"memory_size_mib":      c.MemoryMB,
+		"power_state":          "ON",
+		"nic_list": []*VMNic{
+			{SubnetReference: &Reference{Kind: "subnet", UUID: state.Get("subnet_uuid").(string)}},
+		},
+	}
+
+	if uuid, ok := state.GetOk("source_image_uuid"); ok {
+		disk := newDisk("DISK", "SCSI", 0)
+		disk.DataSourceReference = &Reference{Kind: "image", UUID: uuid.(string)}
+		disk.DiskSizeMib = c.DiskSizeGB * 1024
+		disks = append(disks, disk)
+	} else {
+		cdrom := newDisk("CDROM", "IDE", 0)
+		cdrom.DataSourceReference = &Reference{Kind: "image", UUID: state.Get("iso_image_uuid").(string)}
+		disk := newDisk("DISK", "SCSI", 0)
+		disk.DiskSizeMib = c.DiskSizeGB * 1024
+		disks = append(disks, cdrom, disk)
+
+		resources["boot_config"] = map[string]interface{}{
+			"boot_device_order_list": []string{"CDROM", "DISK"},
+		}
+	}
+	resources["disk_list"] = disks
+
+	if c.UserData != "" {
+		resources["guest_customization"] = map[string]interface{}{
+			"cloud_init": map[string]interface{}{
+				"user_data": base64.StdEncoding.EncodeToString([]byte(c.UserData)),
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"name":              c.VMName,
+		"cluster_reference": &Reference{Kind: "cluster", UUID: state.Get("cluster_uuid").(string)},
+		"resources":         resources,
+	}
+}
+
+func newDisk(deviceType string, adapterType string, index int) *VMDisk {
+	disk := &VMDisk{DeviceProperties: &deviceProperties{DeviceType: deviceType}}
+	disk.DeviceProperties.DiskAddress.AdapterType = adapterType
+	disk.DeviceProperties.DiskAddress.DeviceIndex = index
+	return disk
+}
+
+// projectMetadata are the metadata of an entity of the kind, in the project
+// if there is one.
+func projectMetadata(kind string, state multistep.StateBag) *Metadata {
+	metadata := &Metadata{Kind: kind}
+	if uuid, ok := state.GetOk("project_uuid"); ok {
+		metadata.ProjectReference = &Reference{Kind: "project", UUID: uuid.(string)}
+	}
+	return metadata
+}
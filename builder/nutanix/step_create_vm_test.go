@@ -0,0 +1,49 @@
+package nutanix
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestVMSpec_Clone(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("cluster_uuid", "cluster")
+	state.Put("subnet_uuid", "subnet")
+	state.Put("source_image_uuid", "image")
+
+	spec := vmSpec(&Config{VMName: "packer", CPUs: 2, MemoryMB: 4096, UserData: "#cloud-config"}, state)
+	b, _ := json.Marshal(spec)
+	expected := `{"cluster_reference":{"kind":"cluster","uuid":"cluster"},"name":"packer","resources":{` +
+		`"disk_list":[{"data_source_reference":{"kind":"image","uuid":"image"},"device_properties":{"device_type":"DISK","disk_address":{"adapter_type":"SCSI","device_index":0}}}],` +
+		`"guest_customization":{"cloud_init":{"user_data":"I2Nsb3VkLWNvbmZpZw=="}},` +
+		`"memory_size_mib":4096,"nic_list":[{"subnet_reference":{"kind":"subnet","uuid":"subnet"}}],` +
+		`"num_sockets":2,"num_vcpus_per_socket":1,"power_state":"ON"}}`
+	if string(b) != expected {
+		t.Fatalf("bad:\n%s", b)
+	}
+}
+
+func TestVMSpec_ISO(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("cluster_uuid", "cluster")
+	state.Put("subnet_uuid", "subnet")
+	state.Put("iso_image_uuid", "iso")
+
+	spec := vmSpec(&Config{VMName: "packer", CPUs: 1, MemoryMB: 2048, DiskSizeGB: 40}, state)
+	resources := spec["resources"].(map[string]interface{})
+	disks := resources["disk_list"].([]*VMDisk)
+	if len(disks) != 2 {
+		t.Fatalf("bad: %#v", disks)
+	}
+	if disks[0].DeviceProperties.DeviceType != "CDROM" || disks[0].DataSourceReference.UUID != "iso" {
+		t.Fatalf("bad cdrom: %#v", disks[0])
+	}
+	if disks[1].DeviceProperties.DeviceType != "DISK" || disks[1].DiskSizeMib != 40*1024 || disks[1].DataSourceReference != nil {
+		t.Fatalf("bad disk: %#v", disks[1])
+	}
+	if _, ok := resources["boot_config"]; !ok {
+		t.Fatal("should boot the cdrom first")
+	}
+}
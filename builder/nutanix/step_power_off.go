@@ -0,0 +1,60 @@
+package nutanix
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepPowerOff struct{}
+
+func (s *stepPowerOff) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	vmUUID := state.Get("vm_uuid").(string)
+
+	ui.Say("Shutting down VM...")
+	taskUUID, err := client.PowerOffVM(vmUUID)
+	if err == nil {
+		err = waitForTask(client, taskUUID, c.StateTimeout)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error shutting down VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// The task completes when the shutdown is requested, not when the
+	// guest is off
+	deadline := time.Now().Add(c.StateTimeout)
+	for {
+		vm, err := client.GetVM(vmUUID)
+		if err != nil {
+			err := fmt.Errorf("Error retrieving VM: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if vm.Status.Resources.PowerState == "OFF" {
+			break
+		}
+		if time.Now().After(deadline) {
+			err := fmt.Errorf("Timeout while waiting for VM to shut down")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		time.Sleep(5 * time.Second)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepPowerOff) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,53 @@
+package nutanix
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepResolveReferences looks the UUIDs of the entities named in the
+// configuration up.
+type stepResolveReferences struct{}
+
+func (s *stepResolveReferences) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	ui.Say("Looking up the cluster, subnet and images...")
+
+	names := []struct {
+		kind string
+		name string
+		key  string
+	}{
+		{"cluster", c.ClusterName, "cluster_uuid"},
+		{"subnet", c.SubnetName, "subnet_uuid"},
+		{"image", c.SourceImageName, "source_image_uuid"},
+		{"image", c.ISOImageName, "iso_image_uuid"},
+		{"project", c.ProjectName, "project_uuid"},
+	}
+	for _, n := range names {
+		if n.name == "" {
+			continue
+		}
+
+		uuid, err := client.FindUUID(n.kind, n.name)
+		if err != nil {
+			err := fmt.Errorf("Error looking up the %s %s: %s", n.kind, n.name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		state.Put(n.key, uuid)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepResolveReferences) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,69 @@
+package nutanix
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepVMInfo waits for the guest of the VM to get an IP address.
+type stepVMInfo struct{}
+
+func (s *stepVMInfo) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	vmUUID := state.Get("vm_uuid").(string)
+
+	ui.Say("Waiting for VM to get an IP address...")
+	var ip string
+	deadline := time.Now().Add(c.StateTimeout)
+	for ip == "" {
+		if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			return multistep.ActionHalt
+		}
+		if time.Now().After(deadline) {
+			err := fmt.Errorf("Timeout while waiting for VM to get an IP address")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		vm, err := client.GetVM(vmUUID)
+		if err != nil {
+			err := fmt.Errorf("Error retrieving VM: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ip = vmIP(vm)
+		if ip == "" {
+			log.Printf("VM has no IP address yet, waiting...")
+			time.Sleep(5 * time.Second)
+		}
+	}
+
+	ui.Message(fmt.Sprintf("IP address: %s", ip))
+	state.Put("vm_ip", ip)
+	return multistep.ActionContinue
+}
+
+func (s *stepVMInfo) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// vmIP returns the first IP address of the VM.
+func vmIP(vm *VM) string {
+	for _, nic := range vm.Status.Resources.NicList {
+		for _, endpoint := range nic.IPEndpointList {
+			if endpoint.IP != "" {
+				return endpoint.IP
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package nutanix
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// waitForTask waits for the task to succeed.
+func waitForTask(client *Client, uuid string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		task, err := client.GetTask(uuid)
+		if err != nil {
+			return err
+		}
+
+		switch task.Status {
+		case "SUCCEEDED":
+			return nil
+		case "FAILED", "ABORTED":
+			return fmt.Errorf("task %s %s: %s", uuid, task.Status, task.ErrorDetail)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timeout while waiting for task %s", uuid)
+		}
+		log.Printf("Task %s is %s, waiting...", uuid, task.Status)
+		time.Sleep(3 * time.Second)
+	}
+}
@@ -33,6 +33,12 @@ type AccessConfig struct {
 	Token            string `mapstructure:"token"`
 	Cloud            string `mapstructure:"cloud"`
 
+	// An application credential replaces the password, by its ID or by its
+	// name and user
+	ApplicationCredentialID     string `mapstructure:"application_credential_id"`
+	ApplicationCredentialName   string `mapstructure:"application_credential_name"`
+	ApplicationCredentialSecret string `mapstructure:"application_credential_secret"`
+
 	osClient *gophercloud.ProviderClient
 }
 
@@ -76,6 +82,19 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 		c.ClientKeyFile = os.Getenv("OS_KEY")
 	}
 
+	if c.ApplicationCredentialID == "" {
+		c.ApplicationCredentialID = os.Getenv("OS_APPLICATION_CREDENTIAL_ID")
+	}
+	if c.ApplicationCredentialName == "" {
+		c.ApplicationCredentialName = os.Getenv("OS_APPLICATION_CREDENTIAL_NAME")
+	}
+	if c.ApplicationCredentialSecret == "" {
+		c.ApplicationCredentialSecret = os.Getenv("OS_APPLICATION_CREDENTIAL_SECRET")
+	}
+	if c.IdentityEndpoint == "" && c.isApplicationCredential() {
+		c.IdentityEndpoint = os.Getenv("OS_AUTH_URL")
+	}
+
 	clientOpts := new(clientconfig.ClientOpts)
 
 	// If a cloud entry was given, base AuthOptions on a clouds.yaml file.
@@ -90,6 +109,10 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 		if c.Region == "" && cloud.RegionName != "" {
 			c.Region = cloud.RegionName
 		}
+
+		if err := c.applicationCredentialFromCloud(); err != nil {
+			return []error{err}
+		}
 	} else {
 		authInfo := &clientconfig.AuthInfo{
 			AuthURL:     c.IdentityEndpoint,
@@ -105,36 +128,33 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 		clientOpts.AuthInfo = authInfo
 	}
 
-	ao, err := clientconfig.AuthOptions(clientOpts)
-	if err != nil {
-		return []error{err}
-	}
-
-	// Make sure we reauth as needed
-	ao.AllowReauth = true
-
-	// Override values if we have them in our config
-	overrides := []struct {
-		From, To *string
-	}{
-		{&c.Username, &ao.Username},
-		{&c.UserID, &ao.UserID},
-		{&c.Password, &ao.Password},
-		{&c.IdentityEndpoint, &ao.IdentityEndpoint},
-		{&c.TenantID, &ao.TenantID},
-		{&c.TenantName, &ao.TenantName},
-		{&c.DomainID, &ao.DomainID},
-		{&c.DomainName, &ao.DomainName},
-		{&c.Token, &ao.TokenID},
-	}
-	for _, s := range overrides {
-		if *s.From != "" {
-			*s.To = *s.From
+	var ao *gophercloud.AuthOptions
+	var appCredential *applicationCredentialAuth
+	identityEndpoint := c.IdentityEndpoint
+	if c.isApplicationCredential() {
+		if identityEndpoint == "" {
+			return []error{fmt.Errorf("identity_endpoint must be set with application credentials")}
 		}
+		appCredential = &applicationCredentialAuth{
+			ID:             c.ApplicationCredentialID,
+			Name:           c.ApplicationCredentialName,
+			Secret:         c.ApplicationCredentialSecret,
+			UserID:         c.UserID,
+			Username:       c.Username,
+			UserDomainID:   c.DomainID,
+			UserDomainName: c.DomainName,
+		}
+	} else {
+		var err error
+		ao, err = c.authOptions(clientOpts)
+		if err != nil {
+			return []error{err}
+		}
+		identityEndpoint = ao.IdentityEndpoint
 	}
 
 	// Build the client itself
-	client, err := openstack.NewClient(ao.IdentityEndpoint)
+	client, err := openstack.NewClient(identityEndpoint)
 	if err != nil {
 		return []error{err}
 	}
@@ -171,7 +191,12 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 	client.HTTPClient.Transport = transport
 
 	// Auth
-	err = openstack.Authenticate(client, *ao)
+	if appCredential != nil {
+		// Application credentials only exist in the v3 API
+		err = openstack.AuthenticateV3(client, appCredential, gophercloud.EndpointOpts{})
+	} else {
+		err = openstack.Authenticate(client, *ao)
+	}
 	if err != nil {
 		return []error{err}
 	}
@@ -180,6 +205,74 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 	return nil
 }
 
+// authOptions returns the options to authenticate with a password or a
+// token, from the client options and the overrides of the config.
+func (c *AccessConfig) authOptions(clientOpts *clientconfig.ClientOpts) (*gophercloud.AuthOptions, error) {
+	ao, err := clientconfig.AuthOptions(clientOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure we reauth as needed
+	ao.AllowReauth = true
+
+	// Override values if we have them in our config
+	overrides := []struct {
+		From, To *string
+	}{
+		{&c.Username, &ao.Username},
+		{&c.UserID, &ao.UserID},
+		{&c.Password, &ao.Password},
+		{&c.IdentityEndpoint, &ao.IdentityEndpoint},
+		{&c.TenantID, &ao.TenantID},
+		{&c.TenantName, &ao.TenantName},
+		{&c.DomainID, &ao.DomainID},
+		{&c.DomainName, &ao.DomainName},
+		{&c.Token, &ao.TokenID},
+	}
+	for _, s := range overrides {
+		if *s.From != "" {
+			*s.To = *s.From
+		}
+	}
+	return ao, nil
+}
+
+// isApplicationCredential is true if the config authenticates with an
+// application credential.
+func (c *AccessConfig) isApplicationCredential() bool {
+	return c.ApplicationCredentialID != "" || c.ApplicationCredentialName != ""
+}
+
+// applicationCredentialFromCloud fills the application credential of the
+// config with the one of the clouds.yaml cloud, if it has one.
+func (c *AccessConfig) applicationCredentialFromCloud() error {
+	auth, err := cloudApplicationCredential(c.Cloud)
+	if err != nil || auth == nil {
+		return err
+	}
+
+	overrides := []struct {
+		From, To *string
+	}{
+		{&auth.ApplicationCredentialID, &c.ApplicationCredentialID},
+		{&auth.ApplicationCredentialName, &c.ApplicationCredentialName},
+		{&auth.ApplicationCredentialSecret, &c.ApplicationCredentialSecret},
+		{&auth.AuthURL, &c.IdentityEndpoint},
+		{&auth.UserID, &c.UserID},
+		{&auth.Username, &c.Username},
+		{&auth.UserDomainID, &c.DomainID},
+		{&auth.UserDomainName, &c.DomainName},
+	}
+	for _, s := range overrides {
+		// The values of the config take precedence
+		if *s.To == "" {
+			*s.To = *s.From
+		}
+	}
+	return nil
+}
+
 func (c *AccessConfig) computeV2Client() (*gophercloud.ServiceClient, error) {
 	return openstack.NewComputeV2(c.osClient, gophercloud.EndpointOpts{
 		Region:       c.Region,
@@ -194,6 +287,13 @@ func (c *AccessConfig) imageV2Client() (*gophercloud.ServiceClient, error) {
 	})
 }
 
+func (c *AccessConfig) blockStorageV3Client() (*gophercloud.ServiceClient, error) {
+	return openstack.NewBlockStorageV3(c.osClient, gophercloud.EndpointOpts{
+		Region:       c.Region,
+		Availability: c.getEndpointType(),
+	})
+}
+
 func (c *AccessConfig) getEndpointType() gophercloud.Availability {
 	if c.EndpointType == "internal" || c.EndpointType == "internalURL" {
 		return gophercloud.AvailabilityInternal
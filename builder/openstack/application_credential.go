@@ -0,0 +1,142 @@
+package openstack
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NOTE: the vendored gophercloud doesn't support the application credentials
+// of Keystone yet, so they are authenticated with this builder of the body of
+// the token request, and read from clouds.yaml here.
+
+// applicationCredentialAuth authenticates with an application credential,
+// by its ID, or by its name and its user. The token is scoped to the project
+// of the application credential.
+type applicationCredentialAuth struct {
+	ID     string
+	Name   string
+	Secret string
+
+	UserID         string
+	Username       string
+	UserDomainID   string
+	UserDomainName string
+}
+
+func (o *applicationCredentialAuth) ToTokenV3CreateMap(map[string]interface{}) (map[string]interface{}, error) {
+	if o.Secret == "" {
+		return nil, errors.New("application_credential_secret must be set with application credentials")
+	}
+	credential := map[string]interface{}{
+		"secret": o.Secret,
+	}
+
+	if o.ID != "" {
+		credential["id"] = o.ID
+	} else {
+		user := make(map[string]interface{})
+		switch {
+		case o.UserID != "":
+			user["id"] = o.UserID
+		case o.Username != "" && o.UserDomainID != "":
+			user["name"] = o.Username
+			user["domain"] = map[string]interface{}{"id": o.UserDomainID}
+		case o.Username != "" && o.UserDomainName != "":
+			user["name"] = o.Username
+			user["domain"] = map[string]interface{}{"name": o.UserDomainName}
+		default:
+			return nil, errors.New("user_id, or username and domain_id or domain_name, must be set with application_credential_name")
+		}
+		credential["name"] = o.Name
+		credential["user"] = user
+	}
+
+	return map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods":                []string{"application_credential"},
+				"application_credential": credential,
+			},
+		},
+	}, nil
+}
+
+// ToTokenV3ScopeMap returns no scope, an application credential can't
+// request one.
+func (o *applicationCredentialAuth) ToTokenV3ScopeMap() (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (o *applicationCredentialAuth) CanReauth() bool {
+	return true
+}
+
+// cloudsYAMLAuth is the authentication of a cloud of clouds.yaml, with the
+// application credential fields gophercloud doesn't read.
+type cloudsYAMLAuth struct {
+	AuthURL                     string `yaml:"auth_url"`
+	ApplicationCredentialID     string `yaml:"application_credential_id"`
+	ApplicationCredentialName   string `yaml:"application_credential_name"`
+	ApplicationCredentialSecret string `yaml:"application_credential_secret"`
+	Username                    string `yaml:"username"`
+	UserID                      string `yaml:"user_id"`
+	UserDomainID                string `yaml:"user_domain_id"`
+	UserDomainName              string `yaml:"user_domain_name"`
+}
+
+type cloudsYAML struct {
+	Clouds map[string]struct {
+		Auth     cloudsYAMLAuth `yaml:"auth"`
+		AuthType string         `yaml:"auth_type"`
+	} `yaml:"clouds"`
+}
+
+// cloudApplicationCredential reads the authentication of the cloud from
+// clouds.yaml, found where gophercloud finds it. It returns nil if the cloud
+// doesn't authenticate with an application credential.
+func cloudApplicationCredential(cloud string) (*cloudsYAMLAuth, error) {
+	content, err := readCloudsYAML()
+	if err != nil {
+		return nil, err
+	}
+	var clouds cloudsYAML
+	if err := yaml.Unmarshal(content, &clouds); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml: %v", err)
+	}
+
+	entry, ok := clouds.Clouds[cloud]
+	if !ok {
+		return nil, fmt.Errorf("cloud %s does not exist in clouds.yaml", cloud)
+	}
+	if entry.AuthType != "v3applicationcredential" &&
+		entry.Auth.ApplicationCredentialID == "" && entry.Auth.ApplicationCredentialName == "" {
+		return nil, nil
+	}
+	return &entry.Auth, nil
+}
+
+// readCloudsYAML reads clouds.yaml from OS_CLIENT_CONFIG_FILE, the current
+// directory, the user config directory or the site config directory.
+func readCloudsYAML() ([]byte, error) {
+	paths := []string{os.Getenv("OS_CLIENT_CONFIG_FILE"), "clouds.yaml"}
+	if u, err := user.Current(); err == nil && u.HomeDir != "" {
+		paths = append(paths, filepath.Join(u.HomeDir, ".config/openstack/clouds.yaml"))
+	}
+	paths = append(paths, "/etc/openstack/clouds.yaml")
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return ioutil.ReadFile(path)
+		}
+	}
+	return nil, errors.New("no clouds.yaml file found")
+}
@@ -0,0 +1,116 @@
+package openstack
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestApplicationCredentialAuth_ID(t *testing.T) {
+	auth := &applicationCredentialAuth{ID: "foo", Secret: "bar"}
+	body, err := auth.ToTokenV3CreateMap(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods": []string{"application_credential"},
+				"application_credential": map[string]interface{}{
+					"id":     "foo",
+					"secret": "bar",
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(body, expected) {
+		t.Fatalf("bad: %#v", body)
+	}
+}
+
+func TestApplicationCredentialAuth_Name(t *testing.T) {
+	auth := &applicationCredentialAuth{Name: "foo", Secret: "bar", Username: "packer", UserDomainName: "Default"}
+	body, err := auth.ToTokenV3CreateMap(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	credential := body["auth"].(map[string]interface{})["identity"].(map[string]interface{})["application_credential"]
+	expected := map[string]interface{}{
+		"name":   "foo",
+		"secret": "bar",
+		"user": map[string]interface{}{
+			"name":   "packer",
+			"domain": map[string]interface{}{"name": "Default"},
+		},
+	}
+	if !reflect.DeepEqual(credential, expected) {
+		t.Fatalf("bad: %#v", credential)
+	}
+
+	// The name is only unique for a user
+	auth.Username = ""
+	if _, err := auth.ToTokenV3CreateMap(nil); err == nil {
+		t.Fatal("should have error without user")
+	}
+
+	auth = &applicationCredentialAuth{ID: "foo"}
+	if _, err := auth.ToTokenV3CreateMap(nil); err == nil {
+		t.Fatal("should have error without secret")
+	}
+}
+
+func TestCloudApplicationCredential(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Write([]byte(`clouds:
+  appcred:
+    auth_type: v3applicationcredential
+    auth:
+      auth_url: https://keystone.example.com:5000/v3
+      application_credential_id: foo
+      application_credential_secret: bar
+  password:
+    auth:
+      auth_url: https://keystone.example.com:5000/v3
+      username: packer
+      password: secret
+`))
+	tf.Close()
+
+	old := os.Getenv("OS_CLIENT_CONFIG_FILE")
+	os.Setenv("OS_CLIENT_CONFIG_FILE", tf.Name())
+	defer os.Setenv("OS_CLIENT_CONFIG_FILE", old)
+
+	c := &AccessConfig{Cloud: "appcred", ApplicationCredentialSecret: "override"}
+	if err := c.applicationCredentialFromCloud(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.ApplicationCredentialID != "foo" {
+		t.Fatalf("bad: %s", c.ApplicationCredentialID)
+	}
+	if c.ApplicationCredentialSecret != "override" {
+		t.Fatalf("the config should take precedence: %s", c.ApplicationCredentialSecret)
+	}
+	if c.IdentityEndpoint != "https://keystone.example.com:5000/v3" {
+		t.Fatalf("bad: %s", c.IdentityEndpoint)
+	}
+
+	c = &AccessConfig{Cloud: "password"}
+	if err := c.applicationCredentialFromCloud(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.isApplicationCredential() || c.IdentityEndpoint != "" {
+		t.Fatalf("a password cloud should not be changed: %#v", c)
+	}
+
+	c = &AccessConfig{Cloud: "missing"}
+	if err := c.applicationCredentialFromCloud(); err == nil {
+		t.Fatal("should have error with a missing cloud")
+	}
+}
@@ -34,6 +34,8 @@ type Builder struct {
 }
 
 func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	b.config.VolumeDeleteOnTermination = true
+
 	err := config.Decode(&b.config, &config.DecodeOpts{
 		Interpolate:        true,
 		InterpolateContext: &b.config.ctx,
@@ -56,6 +58,9 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	if b.config.InstanceName == "" {
 		b.config.InstanceName = b.config.ImageName
 	}
+	if b.config.UseBlockStorageVolume && b.config.VolumeName == "" {
+		b.config.VolumeName = b.config.InstanceName
+	}
 
 	log.Println(common.ScrubConfig(b.config, b.config.Password))
 	return nil, nil
@@ -86,6 +91,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			PrivateKeyFile:       b.config.RunConfig.Comm.SSHPrivateKey,
 			SSHAgentAuth:         b.config.RunConfig.Comm.SSHAgentAuth,
 		},
+		&stepCreateVolume{},
 		&StepRunSourceServer{
 			Name:             b.config.InstanceName,
 			SourceImage:      b.config.SourceImage,
@@ -123,6 +129,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&common.StepProvision{},
 		&StepStopServer{},
+		&stepDetachVolume{},
 		&stepCreateImage{},
 		&stepUpdateImageVisibility{},
 		&stepAddImageMembers{},
@@ -35,6 +35,14 @@ type RunConfig struct {
 
 	ConfigDrive bool `mapstructure:"config_drive"`
 
+	// Boot the server from a new volume, for the flavors without a disk
+	UseBlockStorageVolume     bool   `mapstructure:"use_blockstorage_volume"`
+	VolumeName                string `mapstructure:"volume_name"`
+	VolumeType                string `mapstructure:"volume_type"`
+	VolumeSize                int    `mapstructure:"volume_size"`
+	VolumeAvailabilityZone    string `mapstructure:"volume_availability_zone"`
+	VolumeDeleteOnTermination bool   `mapstructure:"volume_delete_on_termination"`
+
 	// Not really used, but here for BC
 	OpenstackProvider string `mapstructure:"openstack_provider"`
 	UseFloatingIp     bool   `mapstructure:"use_floating_ip"`
@@ -55,6 +63,10 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		c.FloatingIpPool = "public"
 	}
 
+	if c.UseBlockStorageVolume && c.VolumeAvailabilityZone == "" {
+		c.VolumeAvailabilityZone = c.AvailabilityZone
+	}
+
 	// Validation
 	errs := c.Comm.Prepare(ctx)
 
@@ -80,6 +92,14 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		errs = append(errs, errors.New("SSH IP version must be either 4 or 6"))
 	}
 
+	if c.VolumeSize < 0 {
+		errs = append(errs, errors.New("volume_size must be positive"))
+	}
+	if !c.UseBlockStorageVolume && (c.VolumeName != "" || c.VolumeType != "" || c.VolumeSize != 0 ||
+		c.VolumeAvailabilityZone != "") {
+		errs = append(errs, errors.New("The volume options can only be set with use_blockstorage_volume"))
+	}
+
 	for key, value := range c.InstanceMetadata {
 		if len(key) > 255 {
 			errs = append(errs, fmt.Errorf("Instance metadata key too long (max 255 bytes): %s", key))
@@ -70,3 +70,28 @@ func TestRunConfigPrepare_SSHPort(t *testing.T) {
 		t.Fatalf("invalid value: %d", c.Comm.SSHPort)
 	}
 }
+
+func TestRunConfigPrepare_BlockStorage(t *testing.T) {
+	c := testRunConfig()
+	c.UseBlockStorageVolume = true
+	c.VolumeType = "fast"
+	c.AvailabilityZone = "RegionTwo"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	if c.VolumeAvailabilityZone != "RegionTwo" {
+		t.Fatalf("invalid value: %s", c.VolumeAvailabilityZone)
+	}
+
+	c.VolumeSize = -1
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c = testRunConfig()
+	c.VolumeSize = 10
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("the volume options should require use_blockstorage_volume: %s", err)
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/images"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	imageservice "github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -30,10 +32,15 @@ func (s *stepCreateImage) Run(_ context.Context, state multistep.StateBag) multi
 
 	// Create the image
 	ui.Say(fmt.Sprintf("Creating the image: %s", config.ImageName))
-	imageId, err := servers.CreateImage(client, server.ID, servers.CreateImageOpts{
-		Name:     config.ImageName,
-		Metadata: config.ImageMetadata,
-	}).ExtractImageID()
+	var imageId string
+	if config.UseBlockStorageVolume {
+		imageId, err = createVolumeImage(config, state.Get("volume_id").(string))
+	} else {
+		imageId, err = servers.CreateImage(client, server.ID, servers.CreateImageOpts{
+			Name:     config.ImageName,
+			Metadata: config.ImageMetadata,
+		}).ExtractImageID()
+	}
 	if err != nil {
 		err := fmt.Errorf("Error creating image: %s", err)
 		state.Put("error", err)
@@ -54,6 +61,17 @@ func (s *stepCreateImage) Run(_ context.Context, state multistep.StateBag) multi
 		return multistep.ActionHalt
 	}
 
+	// The image uploaded from the volume gets the metadata of the config
+	// once it exists
+	if config.UseBlockStorageVolume && len(config.ImageMetadata) > 0 {
+		if err := setImageMetadata(config, imageId); err != nil {
+			err := fmt.Errorf("Error setting the image metadata: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	return multistep.ActionContinue
 }
 
@@ -61,6 +79,53 @@ func (s *stepCreateImage) Cleanup(multistep.StateBag) {
 	// No cleanup...
 }
 
+// createVolumeImage uploads the volume to a new image, and returns its ID.
+func createVolumeImage(config Config, volumeID string) (string, error) {
+	blockStorageClient, err := config.blockStorageV3Client()
+	if err != nil {
+		return "", err
+	}
+	return uploadVolumeImage(blockStorageClient, volumeID, uploadImageOpts{
+		ImageName: config.ImageName,
+	})
+}
+
+// setImageMetadata sets the metadata of the config as properties of the
+// image.
+func setImageMetadata(config Config, imageId string) error {
+	imageClient, err := config.imageV2Client()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(config.ImageMetadata))
+	for key := range config.ImageMetadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	opts := make(imageservice.UpdateOpts, len(keys))
+	for i, key := range keys {
+		opts[i] = addImageProperty{Name: key, Value: config.ImageMetadata[key]}
+	}
+	_, err = imageservice.Update(imageClient, imageId, opts).Extract()
+	return err
+}
+
+// addImageProperty adds, or replaces, a property of an image.
+type addImageProperty struct {
+	Name  string
+	Value string
+}
+
+func (p addImageProperty) ToImagePatchMap() map[string]interface{} {
+	return map[string]interface{}{
+		"op":    "add",
+		"path":  "/" + p.Name,
+		"value": p.Value,
+	}
+}
+
 // WaitForImage waits for the given Image ID to become ready.
 func WaitForImage(client *gophercloud.ServiceClient, imageId string) error {
 	maxNumErrors := 10
@@ -0,0 +1,159 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/images"
+	imageservice "github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepCreateVolume creates the bootable volume of the server from the source
+// image, if the server boots from a volume.
+type stepCreateVolume struct {
+	volumeID string
+}
+
+func (s *stepCreateVolume) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !config.UseBlockStorageVolume {
+		return multistep.ActionContinue
+	}
+
+	blockStorageClient, err := config.blockStorageV3Client()
+	if err != nil {
+		err = fmt.Errorf("Error initializing block storage client: %s", err)
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	sourceImage, err := sourceImageID(config)
+	if err != nil {
+		err = fmt.Errorf("Error finding the source image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	size := config.VolumeSize
+	if size == 0 {
+		size, err = sourceImageVolumeSize(config, sourceImage)
+		if err != nil {
+			err = fmt.Errorf("Error getting the size of the source image: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Say("Creating volume...")
+	volume, err := createVolume(blockStorageClient, createVolumeOpts{
+		Name:             config.VolumeName,
+		Size:             size,
+		VolumeType:       config.VolumeType,
+		AvailabilityZone: config.VolumeAvailabilityZone,
+		ImageRef:         sourceImage,
+	})
+	if err != nil {
+		err = fmt.Errorf("Error creating volume: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.volumeID = volume.ID
+	ui.Message(fmt.Sprintf("Volume ID: %s (%d GB)", volume.ID, size))
+
+	ui.Say("Waiting for volume to become available...")
+	stateChange := StateChangeConf{
+		Pending:   []string{"creating", "downloading"},
+		Target:    []string{"available"},
+		Refresh:   VolumeStateRefreshFunc(blockStorageClient, volume.ID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Error waiting for volume (%s) to become available: %s", volume.ID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("volume_id", volume.ID)
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateVolume) Cleanup(state multistep.StateBag) {
+	if s.volumeID == "" {
+		return
+	}
+
+	config := state.Get("config").(Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !config.VolumeDeleteOnTermination {
+		ui.Say(fmt.Sprintf("Keeping the volume: %s", s.volumeID))
+		return
+	}
+
+	blockStorageClient, err := config.blockStorageV3Client()
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error deleting volume, may still be around: %s", err))
+		return
+	}
+
+	// The volume is detached from the terminated server, or uploaded to
+	// the image, before it can be deleted
+	stateChange := StateChangeConf{
+		Pending: []string{"in-use", "detaching", "uploading", "creating", "downloading"},
+		Target:  []string{"available", "error", "deleted"},
+		Refresh: VolumeStateRefreshFunc(blockStorageClient, s.volumeID),
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting volume, may still be around: %s", err))
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Deleting the volume: %s ...", s.volumeID))
+	if err := deleteVolume(blockStorageClient, s.volumeID); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting volume, may still be around: %s", err))
+	}
+}
+
+// sourceImageID returns the ID of the source image, looking it up by name
+// if needed.
+func sourceImageID(config Config) (string, error) {
+	if config.SourceImage != "" {
+		return config.SourceImage, nil
+	}
+
+	computeClient, err := config.computeV2Client()
+	if err != nil {
+		return "", err
+	}
+	return images.IDFromName(computeClient, config.SourceImageName)
+}
+
+// sourceImageVolumeSize returns the size in GB of the smallest volume the
+// source image fits in.
+func sourceImageVolumeSize(config Config, id string) (int, error) {
+	imageClient, err := config.imageV2Client()
+	if err != nil {
+		return 0, err
+	}
+	image, err := imageservice.Get(imageClient, id).Extract()
+	if err != nil {
+		return 0, err
+	}
+
+	size := int((image.SizeBytes + (1 << 30) - 1) >> 30)
+	if image.MinDiskGigabytes > size {
+		size = image.MinDiskGigabytes
+	}
+	if size == 0 {
+		size = 1
+	}
+	return size, nil
+}
@@ -0,0 +1,80 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepDetachVolume terminates the stopped server that boots from a volume,
+// since its boot volume can't be detached, so that the image can be created
+// from the available volume.
+type stepDetachVolume struct{}
+
+func (s *stepDetachVolume) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !config.UseBlockStorageVolume {
+		return multistep.ActionContinue
+	}
+
+	server := state.Get("server").(*servers.Server)
+	volumeID := state.Get("volume_id").(string)
+
+	computeClient, err := config.computeV2Client()
+	if err != nil {
+		err = fmt.Errorf("Error initializing compute client: %s", err)
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	blockStorageClient, err := config.blockStorageV3Client()
+	if err != nil {
+		err = fmt.Errorf("Error initializing block storage client: %s", err)
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Terminating the server to detach the volume: %s ...", server.ID))
+	if err := servers.Delete(computeClient, server.ID).ExtractErr(); err != nil {
+		err = fmt.Errorf("Error terminating server: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	stateChange := StateChangeConf{
+		Pending:   []string{"ACTIVE", "BUILD", "REBUILD", "SUSPENDED", "SHUTOFF", "STOPPED"},
+		Refresh:   ServerStateRefreshFunc(computeClient, server),
+		Target:    []string{"DELETED"},
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err = fmt.Errorf("Error waiting for server (%s) to terminate: %s", server.ID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	state.Put("server_terminated", true)
+
+	ui.Message(fmt.Sprintf("Waiting for volume to be detached: %s ...", volumeID))
+	stateChange = StateChangeConf{
+		Pending:   []string{"in-use", "detaching"},
+		Target:    []string{"available"},
+		Refresh:   VolumeStateRefreshFunc(blockStorageClient, volumeID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err = fmt.Errorf("Error waiting for volume (%s) to be detached: %s", volumeID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepDetachVolume) Cleanup(multistep.StateBag) {}
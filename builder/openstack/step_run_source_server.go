@@ -70,6 +70,13 @@ func (s *StepRunSourceServer) Run(_ context.Context, state multistep.StateBag) m
 		Metadata:         s.InstanceMetadata,
 	}
 
+	// The source image is on the volume
+	volumeID, bootFromVolume := state.GetOk("volume_id")
+	if bootFromVolume {
+		serverOpts.ImageRef = ""
+		serverOpts.ImageName = ""
+	}
+
 	var serverOptsExt servers.CreateOptsBuilder
 	keyName, hasKey := state.GetOk("keyPair")
 	if hasKey {
@@ -80,6 +87,12 @@ func (s *StepRunSourceServer) Run(_ context.Context, state multistep.StateBag) m
 	} else {
 		serverOptsExt = serverOpts
 	}
+	if bootFromVolume {
+		serverOptsExt = bootFromVolumeOpts{
+			CreateOptsBuilder: serverOptsExt,
+			VolumeID:          volumeID.(string),
+		}
+	}
 
 	s.server, err = servers.Create(computeClient, serverOptsExt).Extract()
 	if err != nil {
@@ -118,6 +131,11 @@ func (s *StepRunSourceServer) Cleanup(state multistep.StateBag) {
 		return
 	}
 
+	// The server was already terminated to detach its volume
+	if _, ok := state.GetOk("server_terminated"); ok {
+		return
+	}
+
 	config := state.Get("config").(Config)
 	ui := state.Get("ui").(packer.Ui)
 
@@ -0,0 +1,119 @@
+package openstack
+
+import (
+	"log"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+)
+
+// NOTE: the vendored gophercloud doesn't have the block storage API, nor the
+// block device mappings of the servers, so the few calls the builder makes
+// are done with the service clients here.
+
+// Volume is a block storage volume.
+type Volume struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	Status string `json:"status"`
+}
+
+type createVolumeOpts struct {
+	Name             string `json:"name,omitempty"`
+	Size             int    `json:"size"`
+	VolumeType       string `json:"volume_type,omitempty"`
+	AvailabilityZone string `json:"availability_zone,omitempty"`
+	ImageRef         string `json:"imageRef,omitempty"`
+}
+
+// createVolume creates a volume, bootable if it is created from an image.
+func createVolume(client *gophercloud.ServiceClient, opts createVolumeOpts) (*Volume, error) {
+	var result struct {
+		Volume *Volume `json:"volume"`
+	}
+	_, err := client.Post(client.ServiceURL("volumes"), map[string]interface{}{"volume": opts}, &result, &gophercloud.RequestOpts{
+		OkCodes: []int{202},
+	})
+	return result.Volume, err
+}
+
+func getVolume(client *gophercloud.ServiceClient, id string) (*Volume, error) {
+	var result struct {
+		Volume *Volume `json:"volume"`
+	}
+	_, err := client.Get(client.ServiceURL("volumes", id), &result, nil)
+	return result.Volume, err
+}
+
+func deleteVolume(client *gophercloud.ServiceClient, id string) error {
+	_, err := client.Delete(client.ServiceURL("volumes", id), nil)
+	return err
+}
+
+type uploadImageOpts struct {
+	ImageName       string `json:"image_name"`
+	ContainerFormat string `json:"container_format,omitempty"`
+	DiskFormat      string `json:"disk_format,omitempty"`
+	Force           bool   `json:"force"`
+}
+
+// uploadVolumeImage creates an image in the image service from a volume that
+// is available, and returns its ID.
+func uploadVolumeImage(client *gophercloud.ServiceClient, id string, opts uploadImageOpts) (string, error) {
+	var result struct {
+		Upload struct {
+			ImageID string `json:"image_id"`
+		} `json:"os-volume_upload_image"`
+	}
+	_, err := client.Post(client.ServiceURL("volumes", id, "action"), map[string]interface{}{"os-volume_upload_image": opts}, &result, &gophercloud.RequestOpts{
+		OkCodes: []int{202},
+	})
+	return result.Upload.ImageID, err
+}
+
+// VolumeStateRefreshFunc returns a StateRefreshFunc that is used to watch
+// a block storage volume.
+func VolumeStateRefreshFunc(client *gophercloud.ServiceClient, id string) StateRefreshFunc {
+	return func() (interface{}, string, int, error) {
+		volume, err := getVolume(client, id)
+		if err != nil {
+			if _, ok := err.(gophercloud.ErrDefault404); ok {
+				log.Printf("[INFO] 404 on VolumeStateRefresh, returning deleted")
+				return nil, "deleted", 0, nil
+			}
+			log.Printf("[ERROR] Error on VolumeStateRefresh: %s", err)
+			return nil, "", 0, err
+		}
+
+		return volume, volume.Status, 0, nil
+	}
+}
+
+// bootFromVolumeOpts boots a server from an existing volume, instead of an
+// image. The volume outlives the server, for the image to be created from it.
+type bootFromVolumeOpts struct {
+	servers.CreateOptsBuilder
+
+	VolumeID string
+}
+
+func (opts bootFromVolumeOpts) ToServerCreateMap() (map[string]interface{}, error) {
+	base, err := opts.CreateOptsBuilder.ToServerCreateMap()
+	if err != nil {
+		return nil, err
+	}
+
+	server := base["server"].(map[string]interface{})
+	delete(server, "imageRef")
+	server["block_device_mapping_v2"] = []map[string]interface{}{
+		{
+			"uuid":                  opts.VolumeID,
+			"source_type":           "volume",
+			"destination_type":      "volume",
+			"boot_index":            0,
+			"delete_on_termination": false,
+		},
+	}
+	return base, nil
+}
@@ -0,0 +1,48 @@
+package openstack
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/keypairs"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+)
+
+func TestBootFromVolumeOpts(t *testing.T) {
+	opts := bootFromVolumeOpts{
+		CreateOptsBuilder: keypairs.CreateOptsExt{
+			CreateOptsBuilder: servers.CreateOpts{
+				Name:      "packer",
+				FlavorRef: "m1.small",
+			},
+			KeyName: "packer_key",
+		},
+		VolumeID: "abcd",
+	}
+
+	body, err := opts.ToServerCreateMap()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	server := body["server"].(map[string]interface{})
+	if _, ok := server["imageRef"]; ok {
+		t.Fatalf("a server booting from a volume should have no image: %#v", server)
+	}
+	if server["key_name"] != "packer_key" {
+		t.Fatalf("the key pair should be kept: %#v", server)
+	}
+
+	expected := []map[string]interface{}{
+		{
+			"uuid":                  "abcd",
+			"source_type":           "volume",
+			"destination_type":      "volume",
+			"boot_index":            0,
+			"delete_on_termination": false,
+		},
+	}
+	if !reflect.DeepEqual(server["block_device_mapping_v2"], expected) {
+		t.Fatalf("bad: %#v", server["block_device_mapping_v2"])
+	}
+}
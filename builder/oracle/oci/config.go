@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/communicator"
@@ -19,6 +20,12 @@ import (
 	"github.com/mitchellh/go-homedir"
 )
 
+// FlexShapeConfig sets the resources of an instance of a flexible shape.
+type FlexShapeConfig struct {
+	Ocpus       float32 `mapstructure:"ocpus"`
+	MemoryInGBs float32 `mapstructure:"memory_in_gbs"`
+}
+
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 	Comm                communicator.Config `mapstructure:",squash"`
@@ -41,9 +48,10 @@ type Config struct {
 	CompartmentID      string `mapstructure:"compartment_ocid"`
 
 	// Image
-	BaseImageID string `mapstructure:"base_image_ocid"`
-	Shape       string `mapstructure:"shape"`
-	ImageName   string `mapstructure:"image_name"`
+	BaseImageID string          `mapstructure:"base_image_ocid"`
+	Shape       string          `mapstructure:"shape"`
+	ShapeConfig FlexShapeConfig `mapstructure:"shape_config"`
+	ImageName   string          `mapstructure:"image_name"`
 	// UserData and UserDataFile file are both optional and mutually exclusive.
 	UserData     string `mapstructure:"user_data"`
 	UserDataFile string `mapstructure:"user_data_file"`
@@ -159,6 +167,20 @@ func NewConfig(raws ...interface{}) (*Config, error) {
 			errs, errors.New("'shape' must be specified"))
 	}
 
+	if strings.HasSuffix(c.Shape, ".Flex") {
+		if c.ShapeConfig.Ocpus <= 0 {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("'shape_config.ocpus' must be specified with a flexible shape"))
+		}
+		if c.ShapeConfig.MemoryInGBs < 0 {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("'shape_config.memory_in_gbs' must be positive"))
+		}
+	} else if c.ShapeConfig.Ocpus != 0 || c.ShapeConfig.MemoryInGBs != 0 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("'shape_config' can only be specified with a flexible shape"))
+	}
+
 	if c.SubnetID == "" {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("'subnet_ocid' must be specified"))
@@ -172,6 +172,36 @@ func TestConfig(t *testing.T) {
 		}
 	})
 
+	t.Run("FlexShapeConfig", func(t *testing.T) {
+		raw := testConfig(cfgFile)
+		raw["shape"] = "VM.Standard.E3.Flex"
+		raw["shape_config"] = map[string]interface{}{
+			"ocpus":         2,
+			"memory_in_gbs": 16,
+		}
+
+		c, errs := NewConfig(raw)
+		if errs != nil {
+			t.Fatalf("Unexpected error in configuration %+v", errs)
+		}
+		if c.ShapeConfig.Ocpus != 2 || c.ShapeConfig.MemoryInGBs != 16 {
+			t.Errorf("Unexpected shape config %+v", c.ShapeConfig)
+		}
+
+		delete(raw, "shape_config")
+		_, errs = NewConfig(raw)
+		if errs == nil || !strings.Contains(errs.Error(), "'shape_config.ocpus'") {
+			t.Errorf("Expected an error about the ocpus of the flexible shape, got %v", errs)
+		}
+
+		raw = testConfig(cfgFile)
+		raw["shape_config"] = map[string]interface{}{"ocpus": 2}
+		_, errs = NewConfig(raw)
+		if errs == nil || !strings.Contains(errs.Error(), "'shape_config'") {
+			t.Errorf("Expected an error about the shape config of a fixed shape, got %v", errs)
+		}
+	})
+
 	t.Run("user_ocid_overridden", func(t *testing.T) {
 		expected := "override"
 		raw := testConfig(cfgFile)
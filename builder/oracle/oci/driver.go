@@ -1,12 +1,21 @@
 package oci
 
-import "github.com/oracle/oci-go-sdk/core"
+import (
+	"errors"
+
+	"github.com/oracle/oci-go-sdk/core"
+)
+
+// errOutOfCapacity is returned by CreateInstance when the availability
+// domain has no capacity left for the shape.
+var errOutOfCapacity = errors.New("Out of host capacity")
 
 // Driver interfaces between the builder steps and the OCI SDK.
 type Driver interface {
-	CreateInstance(publicKey string) (string, error)
+	CreateInstance(publicKey, availabilityDomain string) (string, error)
 	CreateImage(id string) (core.Image, error)
 	DeleteImage(id string) error
+	GetAvailabilityDomains() ([]string, error)
 	GetInstanceIP(id string) (string, error)
 	TerminateInstance(id string) error
 	WaitForImageCreation(id string) error
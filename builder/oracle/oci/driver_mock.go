@@ -5,8 +5,10 @@ import "github.com/oracle/oci-go-sdk/core"
 // driverMock implements the Driver interface and communicates with Oracle
 // OCI.
 type driverMock struct {
-	CreateInstanceID  string
-	CreateInstanceErr error
+	CreateInstanceID                  string
+	CreateInstanceAvailabilityDomains []string
+	CreateInstanceOutOfCapacity       []string
+	CreateInstanceErr                 error
 
 	CreateImageID  string
 	CreateImageErr error
@@ -14,6 +16,9 @@ type driverMock struct {
 	DeleteImageID  string
 	DeleteImageErr error
 
+	GetAvailabilityDomainsResult []string
+	GetAvailabilityDomainsErr    error
+
 	GetInstanceIPErr error
 
 	TerminateInstanceID  string
@@ -27,10 +32,14 @@ type driverMock struct {
 }
 
 // CreateInstance creates a new compute instance.
-func (d *driverMock) CreateInstance(publicKey string) (string, error) {
+func (d *driverMock) CreateInstance(publicKey, availabilityDomain string) (string, error) {
+	d.CreateInstanceAvailabilityDomains = append(d.CreateInstanceAvailabilityDomains, availabilityDomain)
 	if d.CreateInstanceErr != nil {
 		return "", d.CreateInstanceErr
 	}
+	if stringSliceContains(d.CreateInstanceOutOfCapacity, availabilityDomain) {
+		return "", errOutOfCapacity
+	}
 
 	d.CreateInstanceID = "ocid1..."
 
@@ -57,6 +66,18 @@ func (d *driverMock) DeleteImage(id string) error {
 	return nil
 }
 
+// GetAvailabilityDomains mocks listing the availability domains of the
+// instance, the configured one by default.
+func (d *driverMock) GetAvailabilityDomains() ([]string, error) {
+	if d.GetAvailabilityDomainsErr != nil {
+		return nil, d.GetAvailabilityDomainsErr
+	}
+	if d.GetAvailabilityDomainsResult != nil {
+		return d.GetAvailabilityDomainsResult, nil
+	}
+	return []string{d.cfg.AvailabilityDomain}, nil
+}
+
 // GetInstanceIP returns the public or private IP corresponding to the given instance id.
 func (d *driverMock) GetInstanceIP(id string) (string, error) {
 	if d.GetInstanceIPErr != nil {
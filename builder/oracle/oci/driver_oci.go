@@ -2,19 +2,26 @@ package oci
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/oracle/oci-go-sdk/common"
 	core "github.com/oracle/oci-go-sdk/core"
+	"github.com/oracle/oci-go-sdk/identity"
 )
 
 // driverOCI implements the Driver interface and communicates with Oracle
 // OCI.
 type driverOCI struct {
-	computeClient core.ComputeClient
-	vcnClient     core.VirtualNetworkClient
-	cfg           *Config
+	computeClient  core.ComputeClient
+	vcnClient      core.VirtualNetworkClient
+	identityClient identity.IdentityClient
+	cfg            *Config
 }
 
 // NewDriverOCI Creates a new driverOCI with a connected compute client and a connected vcn client.
@@ -29,15 +36,27 @@ func NewDriverOCI(cfg *Config) (Driver, error) {
 		return nil, err
 	}
 
+	identityClient, err := identity.NewIdentityClientWithConfigurationProvider(cfg.ConfigProvider)
+	if err != nil {
+		return nil, err
+	}
+
 	return &driverOCI{
-		computeClient: coreClient,
-		vcnClient:     vcnClient,
-		cfg:           cfg,
+		computeClient:  coreClient,
+		vcnClient:      vcnClient,
+		identityClient: identityClient,
+		cfg:            cfg,
 	}, nil
 }
 
-// CreateInstance creates a new compute instance.
-func (d *driverOCI) CreateInstance(publicKey string) (string, error) {
+// launchInstanceRequest launches an instance with the details of the SDK, and
+// the shape config it doesn't have yet for flexible shapes.
+type launchInstanceRequest struct {
+	LaunchInstanceDetails map[string]interface{} `contributesTo:"body"`
+}
+
+// CreateInstance creates a new compute instance in the availability domain.
+func (d *driverOCI) CreateInstance(publicKey, availabilityDomain string) (string, error) {
 	metadata := map[string]string{
 		"ssh_authorized_keys": publicKey,
 	}
@@ -45,22 +64,83 @@ func (d *driverOCI) CreateInstance(publicKey string) (string, error) {
 		metadata["user_data"] = d.cfg.UserData
 	}
 
-	instance, err := d.computeClient.LaunchInstance(context.TODO(), core.LaunchInstanceRequest{LaunchInstanceDetails: core.LaunchInstanceDetails{
-		AvailabilityDomain: &d.cfg.AvailabilityDomain,
+	details, err := launchInstanceDetails(core.LaunchInstanceDetails{
+		AvailabilityDomain: &availabilityDomain,
 		CompartmentId:      &d.cfg.CompartmentID,
 		ImageId:            &d.cfg.BaseImageID,
 		Shape:              &d.cfg.Shape,
 		SubnetId:           &d.cfg.SubnetID,
 		Metadata:           metadata,
-	}})
+	}, d.cfg.ShapeConfig)
+	if err != nil {
+		return "", err
+	}
+
+	httpRequest, err := common.MakeDefaultHTTPRequestWithTaggedStruct(http.MethodPost, "/instances/",
+		launchInstanceRequest{LaunchInstanceDetails: details})
+	if err != nil {
+		return "", err
+	}
 
+	httpResponse, err := d.computeClient.Call(context.TODO(), &httpRequest)
+	defer common.CloseBodyIfValid(httpResponse)
 	if err != nil {
+		if isOutOfCapacity(err) {
+			log.Printf("[DEBUG] Launching the instance in %s: %s", availabilityDomain, err)
+			return "", errOutOfCapacity
+		}
+		return "", err
+	}
+
+	var instance core.LaunchInstanceResponse
+	if err := common.UnmarshalResponse(httpResponse, &instance); err != nil {
 		return "", err
 	}
 
 	return *instance.Id, nil
 }
 
+// launchInstanceDetails returns the body of the request launching the
+// instance, with its shape config if the shape is flexible.
+func launchInstanceDetails(details core.LaunchInstanceDetails, shapeConfig FlexShapeConfig) (map[string]interface{}, error) {
+	raw, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+	body := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, err
+	}
+
+	// The optional details that aren't set are left out, like the SDK does
+	for key, value := range body {
+		if value == nil {
+			delete(body, key)
+		}
+	}
+
+	if shapeConfig.Ocpus > 0 {
+		config := map[string]interface{}{
+			"ocpus": shapeConfig.Ocpus,
+		}
+		if shapeConfig.MemoryInGBs > 0 {
+			config["memoryInGBs"] = shapeConfig.MemoryInGBs
+		}
+		body["shapeConfig"] = config
+	}
+	return body, nil
+}
+
+// isOutOfCapacity is true if the error of a launch is the lack of capacity
+// for the shape in the availability domain.
+func isOutOfCapacity(err error) bool {
+	failure, ok := common.IsServiceError(err)
+	if !ok {
+		return false
+	}
+	return strings.Contains(strings.ToLower(failure.GetMessage()), "out of host capacity")
+}
+
 // CreateImage creates a new custom image.
 func (d *driverOCI) CreateImage(id string) (core.Image, error) {
 	res, err := d.computeClient.CreateImage(context.TODO(), core.CreateImageRequest{CreateImageDetails: core.CreateImageDetails{
@@ -82,6 +162,39 @@ func (d *driverOCI) DeleteImage(id string) error {
 	return err
 }
 
+// GetAvailabilityDomains returns the availability domains the instance can
+// be launched in, the configured one first. The instance can only be
+// launched in the other ones of the region if its subnet is regional.
+func (d *driverOCI) GetAvailabilityDomains() ([]string, error) {
+	availabilityDomains := []string{d.cfg.AvailabilityDomain}
+
+	subnet, err := d.vcnClient.GetSubnet(context.TODO(), core.GetSubnetRequest{SubnetId: &d.cfg.SubnetID})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting subnet details: %s", err)
+	}
+	if subnet.AvailabilityDomain != nil && *subnet.AvailabilityDomain != "" {
+		return availabilityDomains, nil
+	}
+
+	tenancyID, err := d.cfg.ConfigProvider.TenancyOCID()
+	if err != nil {
+		return nil, err
+	}
+	res, err := d.identityClient.ListAvailabilityDomains(context.TODO(), identity.ListAvailabilityDomainsRequest{
+		CompartmentId: &tenancyID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing availability domains: %s", err)
+	}
+
+	for _, item := range res.Items {
+		if item.Name != nil && *item.Name != d.cfg.AvailabilityDomain {
+			availabilityDomains = append(availabilityDomains, *item.Name)
+		}
+	}
+	return availabilityDomains, nil
+}
+
 // GetInstanceIP returns the public or private IP corresponding to the given instance id.
 func (d *driverOCI) GetInstanceIP(id string) (string, error) {
 	vnics, err := d.computeClient.ListVnicAttachments(context.TODO(), core.ListVnicAttachmentsRequest{
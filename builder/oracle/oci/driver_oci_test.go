@@ -0,0 +1,41 @@
+package oci
+
+import (
+	"reflect"
+	"testing"
+
+	core "github.com/oracle/oci-go-sdk/core"
+)
+
+func TestLaunchInstanceDetails(t *testing.T) {
+	ad := "aaaa:US-ASHBURN-AD-1"
+	shape := "VM.Standard.E3.Flex"
+	details := core.LaunchInstanceDetails{
+		AvailabilityDomain: &ad,
+		Shape:              &shape,
+	}
+
+	body, err := launchInstanceDetails(details, FlexShapeConfig{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]interface{}{
+		"availabilityDomain": ad,
+		"shape":              shape,
+	}
+	if !reflect.DeepEqual(body, expected) {
+		t.Fatalf("bad: %#v", body)
+	}
+
+	body, err = launchInstanceDetails(details, FlexShapeConfig{Ocpus: 2, MemoryInGBs: 32})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expectedShapeConfig := map[string]interface{}{
+		"ocpus":       float32(2),
+		"memoryInGBs": float32(32),
+	}
+	if !reflect.DeepEqual(body["shapeConfig"], expectedShapeConfig) {
+		t.Fatalf("bad: %#v", body["shapeConfig"])
+	}
+}
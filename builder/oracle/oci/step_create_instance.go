@@ -19,7 +19,24 @@ func (s *stepCreateInstance) Run(_ context.Context, state multistep.StateBag) mu
 
 	ui.Say("Creating instance...")
 
-	instanceID, err := driver.CreateInstance(publicKey)
+	availabilityDomains, err := driver.GetAvailabilityDomains()
+	if err != nil {
+		err = fmt.Errorf("Problem getting availability domains: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// The next availability domain is tried while the shape is out of
+	// capacity
+	var instanceID string
+	for _, availabilityDomain := range availabilityDomains {
+		instanceID, err = driver.CreateInstance(publicKey, availabilityDomain)
+		if err != errOutOfCapacity {
+			break
+		}
+		ui.Message(fmt.Sprintf("Out of capacity for the shape in %s.", availabilityDomain))
+	}
 	if err != nil {
 		err = fmt.Errorf("Problem creating instance: %s", err)
 		ui.Error(err.Error())
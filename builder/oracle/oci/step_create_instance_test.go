@@ -3,6 +3,7 @@ package oci
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/packer/helper/multistep"
@@ -35,6 +36,41 @@ func TestStepCreateInstance(t *testing.T) {
 	}
 }
 
+func TestStepCreateInstance_OutOfCapacity(t *testing.T) {
+	state := testState()
+	state.Put("publicKey", "key")
+
+	step := new(stepCreateInstance)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*driverMock)
+	driver.GetAvailabilityDomainsResult = []string{"aaaa:US-ASHBURN-AD-1", "aaaa:US-ASHBURN-AD-2", "aaaa:US-ASHBURN-AD-3"}
+	driver.CreateInstanceOutOfCapacity = []string{"aaaa:US-ASHBURN-AD-1"}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	expected := []string{"aaaa:US-ASHBURN-AD-1", "aaaa:US-ASHBURN-AD-2"}
+	if !reflect.DeepEqual(driver.CreateInstanceAvailabilityDomains, expected) {
+		t.Fatalf("bad availability domains: %v", driver.CreateInstanceAvailabilityDomains)
+	}
+
+	// No availability domain has capacity left
+	state = testState()
+	state.Put("publicKey", "key")
+	driver = state.Get("driver").(*driverMock)
+	driver.GetAvailabilityDomainsResult = []string{"aaaa:US-ASHBURN-AD-1", "aaaa:US-ASHBURN-AD-2"}
+	driver.CreateInstanceOutOfCapacity = driver.GetAvailabilityDomainsResult
+
+	if action := new(stepCreateInstance).Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("instance_id"); ok {
+		t.Fatalf("should NOT have instance_id")
+	}
+}
+
 func TestStepCreateInstance_CreateInstanceErr(t *testing.T) {
 	state := testState()
 	state.Put("publicKey", "key")
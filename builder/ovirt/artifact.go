@@ -0,0 +1,55 @@
+package ovirt
+
+import (
+	"fmt"
+	"log"
+)
+
+type Artifact struct {
+	// The ID of the template, if the VM is exported as one
+	templateID   string
+	templateName string
+
+	// The host and path of the OVA, if the VM is exported as one
+	ovaHost string
+	ovaPath string
+
+	// The client for making API calls
+	client *Client
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	// The OVA is on the host, not here
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	if a.templateID != "" {
+		return a.templateID
+	}
+	return fmt.Sprintf("%s:%s", a.ovaHost, a.ovaPath)
+}
+
+func (a *Artifact) String() string {
+	if a.templateID != "" {
+		return fmt.Sprintf("A template was created: '%v' (ID: %v)", a.templateName, a.templateID)
+	}
+	return fmt.Sprintf("An OVA was exported to %v on %v", a.ovaPath, a.ovaHost)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	if a.templateID == "" {
+		return fmt.Errorf("The OVA must be deleted from %s manually: %s", a.ovaHost, a.ovaPath)
+	}
+
+	log.Printf("Destroying template: %s (%s)", a.templateID, a.templateName)
+	return a.client.DeleteTemplate(a.templateID)
+}
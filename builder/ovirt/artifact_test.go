@@ -0,0 +1,31 @@
+package ovirt
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestArtifactString(t *testing.T) {
+	a := &Artifact{templateID: "abc", templateName: "packer-foobar"}
+	expected := "A template was created: 'packer-foobar' (ID: abc)"
+	if a.String() != expected {
+		t.Fatalf("artifact string should match: %v", expected)
+	}
+
+	a = &Artifact{ovaHost: "host1", ovaPath: "/exports/packer.ova"}
+	expected = "An OVA was exported to /exports/packer.ova on host1"
+	if a.String() != expected {
+		t.Fatalf("artifact string should match: %v", expected)
+	}
+	if a.Id() != "host1:/exports/packer.ova" {
+		t.Fatalf("bad: %s", a.Id())
+	}
+	if err := a.Destroy(); err == nil {
+		t.Fatal("an OVA can't be destroyed")
+	}
+}
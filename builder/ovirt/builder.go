@@ -0,0 +1,87 @@
+// The ovirt package contains a packer.Builder implementation
+// that builds oVirt and RHV templates, or OVAs.
+
+package ovirt
+
+import (
+	"log"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The unique id for the builder
+const BuilderId = "packer.ovirt"
+
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, errs := NewConfig(raws...)
+	if errs != nil {
+		return warnings, errs
+	}
+	b.config = c
+
+	return nil, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	client := NewClient(b.config.URL, b.config.Username, b.config.Password, b.config.Insecure)
+
+	// Set up the state
+	state := new(multistep.BasicStateBag)
+	state.Put("config", b.config)
+	state.Put("client", client)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	// Build the steps
+	steps := []multistep.Step{
+		new(stepCreateVM),
+		new(stepStartVM),
+		new(stepVMInfo),
+		&communicator.StepConnect{
+			Config:    &b.config.Comm,
+			Host:      commHost(&b.config.Comm),
+			SSHConfig: sshConfig(&b.config.Comm),
+		},
+		new(common.StepProvision),
+		new(stepShutdown),
+		new(stepExport),
+	}
+
+	// Run the steps
+	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	artifact := &Artifact{client: client}
+	if id, ok := state.GetOk("template_id"); ok {
+		artifact.templateID = id.(string)
+		artifact.templateName = b.config.TemplateName
+	} else if path, ok := state.GetOk("ova_path"); ok {
+		artifact.ovaHost = b.config.OVAHost
+		artifact.ovaPath = path.(string)
+	} else {
+		log.Println("Failed to find the export in state. Bug?")
+		return nil, nil
+	}
+
+	return artifact, nil
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
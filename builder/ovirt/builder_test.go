@@ -0,0 +1,123 @@
+package ovirt
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"ovirt_url":       "https://engine.example.com/ovirt-engine/api",
+		"username":        "admin@internal",
+		"password":        "secret",
+		"cluster":         "Default",
+		"source_template": "centos7",
+		"ssh_username":    "root",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare_InvalidKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Add a random key
+	config["i_should_not_be_valid"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	warnings, err := b.Prepare(testConfig())
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Output != outputTemplate {
+		t.Errorf("bad output: %s", b.config.Output)
+	}
+	if b.config.OVAFilename != b.config.TemplateName+".ova" {
+		t.Errorf("bad ova filename: %s", b.config.OVAFilename)
+	}
+}
+
+func TestBuilderPrepare_Required(t *testing.T) {
+	for _, key := range []string{"ovirt_url", "password", "cluster", "source_template"} {
+		var b Builder
+		config := testConfig()
+		delete(config, key)
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error without %s", key)
+		}
+	}
+}
+
+func TestBuilderPrepare_ISO(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	delete(config, "source_template")
+	config["iso_file"] = "CentOS-7-x86_64-Minimal.iso"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without the disk and network")
+	}
+
+	config["disk_size_gb"] = 20
+	config["storage_domain"] = "data"
+	config["vnic_profile_id"] = "0000000a-000a-000a-000a-000000000398"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_Output(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["output"] = "ova"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without the host")
+	}
+
+	config["ova_host"] = "host1"
+	config["ova_directory"] = "/exports"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["output"] = "qcow2"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStartParams(t *testing.T) {
+	if params := startParams(&Config{}); params != nil {
+		t.Fatalf("a VM from a template should start as is: %#v", params)
+	}
+
+	params := startParams(&Config{ISOFile: "boot.iso"})
+	vm := params["vm"].(map[string]interface{})
+	cdrom := vm["cdroms"].(map[string]interface{})["cdrom"].([]map[string]interface{})[0]
+	if cdrom["file"] != (Ref{ID: "boot.iso"}) {
+		t.Fatalf("bad: %#v", cdrom)
+	}
+}
@@ -0,0 +1,261 @@
+package ovirt
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NOTE: there is no oVirt SDK vendored, so the few calls of the REST API
+// the builder makes are done with the client here, in JSON.
+
+// Client is a client of the oVirt engine REST API.
+type Client struct {
+	// URL is the URL of the API, like https://engine/ovirt-engine/api.
+	URL      string
+	Username string
+	Password string
+
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client of the engine at the URL, which may not verify
+// the certificate if it is self signed.
+func NewClient(url string, username string, password string, insecure bool) *Client {
+	transport := http.DefaultTransport.(*http.Transport)
+	if insecure {
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return &Client{
+		URL:      url,
+		Username: username,
+		Password: password,
+		HTTPClient: &http.Client{
+			Transport: transport,
+			Timeout:   5 * time.Minute,
+		},
+	}
+}
+
+// Ref is a reference to an entity, by ID or by name.
+type Ref struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Entity is the ID and status of a VM, a disk, a template or a job.
+type Entity struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// ErrorResponse is the fault returned by the API.
+type ErrorResponse struct {
+	StatusCode int
+	Reason     string `json:"reason"`
+	Detail     string `json:"detail"`
+}
+
+func (e *ErrorResponse) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("oVirt API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("oVirt API error: %d %s: %s", e.StatusCode, e.Reason, e.Detail)
+}
+
+// CreateVM creates a VM.
+func (c *Client) CreateVM(vm interface{}) (*Entity, error) {
+	result := new(Entity)
+	err := c.do("POST", "vms", vm, result)
+	return result, err
+}
+
+// GetVM returns a VM.
+func (c *Client) GetVM(id string) (*Entity, error) {
+	result := new(Entity)
+	err := c.do("GET", "vms/"+id, nil, result)
+	return result, err
+}
+
+// DeleteVM removes a VM and its disks.
+func (c *Client) DeleteVM(id string) error {
+	return c.do("DELETE", "vms/"+id, nil, nil)
+}
+
+// AddDisk adds a disk to a VM, and returns the ID of the disk.
+func (c *Client) AddDisk(vmID string, attachment interface{}) (string, error) {
+	var result struct {
+		Disk Ref `json:"disk"`
+	}
+	err := c.do("POST", fmt.Sprintf("vms/%s/diskattachments", vmID), attachment, &result)
+	return result.Disk.ID, err
+}
+
+// GetDisk returns a disk.
+func (c *Client) GetDisk(id string) (*Entity, error) {
+	result := new(Entity)
+	err := c.do("GET", "disks/"+id, nil, result)
+	return result, err
+}
+
+// AddNic adds a network interface to a VM.
+func (c *Client) AddNic(vmID string, nic interface{}) error {
+	return c.do("POST", fmt.Sprintf("vms/%s/nics", vmID), nic, nil)
+}
+
+// VMAction runs an action, such as start, on a VM, and returns the ID of
+// the job running it, if any.
+func (c *Client) VMAction(id string, action string, params interface{}) (string, error) {
+	return c.VMActionCorrelated(id, action, params, "")
+}
+
+// VMActionCorrelated runs an action on a VM like VMAction, marking the
+// events of the action with the correlation ID, if not empty.
+func (c *Client) VMActionCorrelated(id string, action string, params interface{}, correlationID string) (string, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	path := fmt.Sprintf("vms/%s/%s", id, action)
+	if correlationID != "" {
+		path += "?correlation_id=" + url.QueryEscape(correlationID)
+	}
+	var result struct {
+		Job Ref `json:"job"`
+	}
+	err := c.do("POST", path, params, &result)
+	return result.Job.ID, err
+}
+
+// GetJob returns a job, whose status is started until it is finished or
+// failed.
+func (c *Client) GetJob(id string) (*Entity, error) {
+	result := new(Entity)
+	err := c.do("GET", "jobs/"+id, nil, result)
+	return result, err
+}
+
+// Event is an entry of the audit log of the engine.
+type Event struct {
+	Code        EventCode `json:"code"`
+	Severity    string    `json:"severity"`
+	Description string    `json:"description"`
+}
+
+// EventCode is the code of an event, telling what happened, which the API
+// may return as a number or as a string.
+type EventCode string
+
+func (c *EventCode) UnmarshalJSON(b []byte) error {
+	*c = EventCode(strings.Trim(string(b), `"`))
+	return nil
+}
+
+// Events returns the events marked with the correlation ID.
+func (c *Client) Events(correlationID string) ([]Event, error) {
+	var result struct {
+		Event []Event `json:"event"`
+	}
+	search := url.QueryEscape("correlation_id=" + correlationID)
+	err := c.do("GET", "events?search="+search, nil, &result)
+	return result.Event, err
+}
+
+// ReportedIPs returns the IP addresses the guest agent of a VM reports.
+func (c *Client) ReportedIPs(vmID string) ([]string, error) {
+	var result struct {
+		ReportedDevice []struct {
+			IPs struct {
+				IP []struct {
+					Address string `json:"address"`
+					Version string `json:"version"`
+				} `json:"ip"`
+			} `json:"ips"`
+		} `json:"reported_device"`
+	}
+	if err := c.do("GET", fmt.Sprintf("vms/%s/reporteddevices", vmID), nil, &result); err != nil {
+		return nil, err
+	}
+
+	var ips []string
+	for _, device := range result.ReportedDevice {
+		for _, ip := range device.IPs.IP {
+			if ip.Version == "v4" {
+				ips = append(ips, ip.Address)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// CreateTemplate creates a template from a VM.
+func (c *Client) CreateTemplate(template interface{}) (*Entity, error) {
+	result := new(Entity)
+	err := c.do("POST", "templates", template, result)
+	return result, err
+}
+
+// GetTemplate returns a template.
+func (c *Client) GetTemplate(id string) (*Entity, error) {
+	result := new(Entity)
+	err := c.do("GET", "templates/"+id, nil, result)
+	return result, err
+}
+
+// DeleteTemplate removes a template.
+func (c *Client) DeleteTemplate(id string) error {
+	return c.do("DELETE", "templates/"+id, nil, nil)
+}
+
+func (c *Client) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.URL, "/")+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+	req.Header.Set("Version", "4")
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &ErrorResponse{StatusCode: resp.StatusCode}
+		// The fault is best effort, the status is enough to fail
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,67 @@
+package ovirt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_ReportedIPs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ovirt-engine/api/vms/vm/reporteddevices" {
+			t.Errorf("bad request: %s", r.URL.Path)
+		}
+		if r.Header.Get("Accept") != "application/json" || r.Header.Get("Version") != "4" {
+			t.Errorf("bad headers: %#v", r.Header)
+		}
+		w.Write([]byte(`{"reported_device": [{"ips": {"ip": [
+			{"address": "fe80::1", "version": "v6"},
+			{"address": "10.0.0.5", "version": "v4"}
+		]}}]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL + "/ovirt-engine/api"}
+	ips, err := client.ReportedIPs("vm")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(ips, []string{"10.0.0.5"}) {
+		t.Fatalf("bad: %#v", ips)
+	}
+}
+
+func TestClient_VMAction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/vms/vm/exporttopathonhost" {
+			t.Errorf("bad request: %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"status": "complete", "job": {"id": "job"}}`))
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL}
+	jobID, err := client.VMAction("vm", "exporttopathonhost", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if jobID != "job" {
+		t.Fatalf("bad: %s", jobID)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"reason": "Operation Failed", "detail": "[Cannot add VM. The given name is already in use.]"}`))
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL}
+	_, err := client.CreateVM(nil)
+	expected := "oVirt API error: 409 Operation Failed: [Cannot add VM. The given name is already in use.]"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("bad: %v", err)
+	}
+}
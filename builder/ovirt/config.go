@@ -0,0 +1,164 @@
+package ovirt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The outputs of the builder.
+const (
+	outputTemplate = "template"
+	outputOVA      = "ova"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	Comm                communicator.Config `mapstructure:",squash"`
+
+	URL      string `mapstructure:"ovirt_url"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Insecure bool   `mapstructure:"tls_insecure"`
+
+	Cluster        string `mapstructure:"cluster"`
+	SourceTemplate string `mapstructure:"source_template"`
+	ISOFile        string `mapstructure:"iso_file"`
+	DiskSizeGB     int64  `mapstructure:"disk_size_gb"`
+	StorageDomain  string `mapstructure:"storage_domain"`
+	VNICProfileID  string `mapstructure:"vnic_profile_id"`
+
+	VMName   string `mapstructure:"vm_name"`
+	CPUs     int    `mapstructure:"cpus"`
+	MemoryMB int64  `mapstructure:"memory_mb"`
+
+	Output              string `mapstructure:"output"`
+	TemplateName        string `mapstructure:"template_name"`
+	TemplateDescription string `mapstructure:"template_description"`
+	OVAHost             string `mapstructure:"ova_host"`
+	OVADirectory        string `mapstructure:"ova_directory"`
+	OVAFilename         string `mapstructure:"ova_filename"`
+
+	StateTimeout time.Duration `mapstructure:"state_timeout"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.URL == "" {
+		c.URL = os.Getenv("OVIRT_URL")
+	}
+	if c.Username == "" {
+		c.Username = os.Getenv("OVIRT_USERNAME")
+	}
+	if c.Password == "" {
+		c.Password = os.Getenv("OVIRT_PASSWORD")
+	}
+
+	if c.VMName == "" {
+		// Default to packer-[time-ordered-uuid]
+		c.VMName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	}
+	if c.CPUs == 0 {
+		c.CPUs = 1
+	}
+	if c.MemoryMB == 0 {
+		c.MemoryMB = 2048
+	}
+
+	if c.Output == "" {
+		c.Output = outputTemplate
+	}
+	if c.TemplateName == "" {
+		def, err := interpolate.Render("packer-{{timestamp}}", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		// Default to packer-{{ unix timestamp (utc) }}
+		c.TemplateName = def
+	}
+	if c.OVAFilename == "" {
+		c.OVAFilename = c.TemplateName + ".ova"
+	}
+
+	if c.StateTimeout == 0 {
+		c.StateTimeout = 20 * time.Minute
+	}
+
+	var errs *packer.MultiError
+	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if c.URL == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("ovirt_url is required"))
+	}
+	if c.Username == "" || c.Password == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("username and password for auth must be specified"))
+	}
+
+	if c.Cluster == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("cluster is required"))
+	}
+
+	if (c.SourceTemplate == "") == (c.ISOFile == "") {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("exactly one of source_template or iso_file is required"))
+	}
+	if c.ISOFile != "" {
+		if c.DiskSizeGB <= 0 {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("disk_size_gb is required to install from an ISO"))
+		}
+		if c.StorageDomain == "" {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("storage_domain is required to install from an ISO"))
+		}
+		if c.VNICProfileID == "" {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("vnic_profile_id is required to install from an ISO"))
+		}
+	}
+
+	switch c.Output {
+	case outputTemplate:
+	case outputOVA:
+		if c.OVAHost == "" || c.OVADirectory == "" {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("ova_host and ova_directory are required to export an OVA"))
+		}
+	default:
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("output must be %s or %s: %s", outputTemplate, outputOVA, c.Output))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	common.ScrubConfig(c, c.Password)
+	return c, nil, nil
+}
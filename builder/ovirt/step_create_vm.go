@@ -0,0 +1,133 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// blankTemplate is the template of the VMs that don't have one.
+const blankTemplate = "Blank"
+
+type stepCreateVM struct {
+	vmID string
+}
+
+func (s *stepCreateVM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	template := c.SourceTemplate
+	if template == "" {
+		template = blankTemplate
+	}
+
+	ui.Say(fmt.Sprintf("Creating VM %s from the template %s...", c.VMName, template))
+	vm, err := client.CreateVM(map[string]interface{}{
+		"name":     c.VMName,
+		"cluster":  Ref{Name: c.Cluster},
+		"template": Ref{Name: template},
+		"memory":   c.MemoryMB << 20,
+		"cpu": map[string]interface{}{
+			"topology": map[string]interface{}{
+				"sockets": c.CPUs,
+				"cores":   1,
+			},
+		},
+	})
+	if err != nil {
+		err := fmt.Errorf("Error creating VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this in cleanup
+	s.vmID = vm.ID
+	state.Put("vm_id", vm.ID)
+	ui.Message(fmt.Sprintf("VM ID: %s", vm.ID))
+
+	// The disks of the template are copied while the VM is locked
+	if err := waitForStatus("down", vmStatus(client, vm.ID), c.StateTimeout); err != nil {
+		err := fmt.Errorf("Error waiting for VM to be created: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if c.ISOFile != "" {
+		if err := s.addDevices(client, ui, c); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// addDevices adds the disk to install to, and the network interface, to a
+// blank VM.
+func (s *stepCreateVM) addDevices(client *Client, ui packer.Ui, c *Config) error {
+	ui.Message(fmt.Sprintf("Adding a %d GB disk...", c.DiskSizeGB))
+	diskID, err := client.AddDisk(s.vmID, map[string]interface{}{
+		"bootable":  true,
+		"active":    true,
+		"interface": "virtio_scsi",
+		"disk": map[string]interface{}{
+			"name":             c.VMName,
+			"format":           "cow",
+			"provisioned_size": c.DiskSizeGB << 30,
+			"storage_domains": map[string]interface{}{
+				"storage_domain": []Ref{{Name: c.StorageDomain}},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Error adding disk: %s", err)
+	}
+	err = waitForStatus("ok", func() (*Entity, error) { return client.GetDisk(diskID) }, c.StateTimeout)
+	if err != nil {
+		return fmt.Errorf("Error waiting for disk to be created: %s", err)
+	}
+
+	err = client.AddNic(s.vmID, map[string]interface{}{
+		"name":         "nic1",
+		"vnic_profile": Ref{ID: c.VNICProfileID},
+	})
+	if err != nil {
+		return fmt.Errorf("Error adding network interface: %s", err)
+	}
+	return nil
+}
+
+func (s *stepCreateVM) Cleanup(state multistep.StateBag) {
+	// If the VM ID isn't there, we probably never created it
+	if s.vmID == "" {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	// A VM is removed once it is down
+	if vm, err := client.GetVM(s.vmID); err == nil && vm.Status != "down" {
+		ui.Say("Stopping VM...")
+		if _, err := client.VMAction(s.vmID, "stop", nil); err != nil {
+			ui.Error(fmt.Sprintf("Error stopping VM: %s", err))
+		}
+		if err := waitForStatus("down", vmStatus(client, s.vmID), c.StateTimeout); err != nil {
+			ui.Error(fmt.Sprintf("Error waiting for VM to stop: %s", err))
+		}
+	}
+
+	ui.Say("Removing VM...")
+	if err := client.DeleteVM(s.vmID); err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error removing VM. Please remove it manually: %s", err))
+	}
+}
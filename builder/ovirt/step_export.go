@@ -0,0 +1,126 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The codes of the events the engine logs once a VM is exported as an OVA.
+const (
+	eventOVAExported     = "1223"
+	eventOVAExportFailed = "1225"
+)
+
+// stepExport exports the stopped VM as a template, or as an OVA on a host.
+type stepExport struct{}
+
+func (s *stepExport) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	vmID := state.Get("vm_id").(string)
+
+	var err error
+	switch c.Output {
+	case outputTemplate:
+		err = exportTemplate(client, ui, c, vmID, state)
+	case outputOVA:
+		err = exportOVA(client, ui, c, vmID, state)
+	}
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepExport) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+func exportTemplate(client *Client, ui packer.Ui, c *Config, vmID string, state multistep.StateBag) error {
+	body := map[string]interface{}{
+		"name":        c.TemplateName,
+		"description": c.TemplateDescription,
+		"vm":          Ref{ID: vmID},
+	}
+	if c.StorageDomain != "" {
+		body["storage_domain"] = Ref{Name: c.StorageDomain}
+	}
+
+	ui.Say(fmt.Sprintf("Creating template: %s", c.TemplateName))
+	template, err := client.CreateTemplate(body)
+	if err != nil {
+		return fmt.Errorf("Error creating template: %s", err)
+	}
+
+	// The disks of the VM are copied while the template is locked
+	get := func() (*Entity, error) { return client.GetTemplate(template.ID) }
+	if err := waitForStatus("ok", get, c.StateTimeout); err != nil {
+		return fmt.Errorf("Error waiting for template to be created: %s", err)
+	}
+
+	log.Printf("Template ID: %s", template.ID)
+	state.Put("template_id", template.ID)
+	return nil
+}
+
+func exportOVA(client *Client, ui packer.Ui, c *Config, vmID string, state multistep.StateBag) error {
+	params := map[string]interface{}{
+		"host":      Ref{Name: c.OVAHost},
+		"directory": c.OVADirectory,
+		"filename":  c.OVAFilename,
+	}
+
+	ova := path.Join(c.OVADirectory, c.OVAFilename)
+	ui.Say(fmt.Sprintf("Exporting OVA to %s on %s...", ova, c.OVAHost))
+	correlationID := "packer-" + uuid.TimeOrderedUUID()
+	jobID, err := client.VMActionCorrelated(vmID, "exporttopathonhost", params, correlationID)
+	if err != nil {
+		return fmt.Errorf("Error exporting OVA: %s", err)
+	}
+
+	get := func() (*Entity, error) { return client.GetJob(jobID) }
+	if jobID == "" {
+		// Without a job to follow, the export is done once the engine logs
+		// its outcome in the events of the action.
+		log.Printf("No job for the OVA export, waiting for the events of %s", correlationID)
+		get = ovaExportStatus(client, correlationID)
+	}
+	if err := waitForStatus("finished", get, c.StateTimeout); err != nil {
+		return fmt.Errorf("Error waiting for OVA to be exported: %s", err)
+	}
+
+	state.Put("ova_path", ova)
+	return nil
+}
+
+// ovaExportStatus returns the status of the OVA export marked with the
+// correlation ID, from its events, like the status of a job.
+func ovaExportStatus(client *Client, correlationID string) func() (*Entity, error) {
+	return func() (*Entity, error) {
+		events, err := client.Events(correlationID)
+		if err != nil {
+			return nil, err
+		}
+
+		export := &Entity{ID: "OVA export", Status: "started"}
+		for _, event := range events {
+			switch {
+			case event.Code == eventOVAExported:
+				export.Status = "finished"
+			case event.Code == eventOVAExportFailed, event.Severity == "error":
+				return nil, fmt.Errorf("OVA export failed: %s", event.Description)
+			}
+		}
+		return export, nil
+	}
+}
@@ -0,0 +1,71 @@
+package ovirt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestExportOVA_noJob(t *testing.T) {
+	var correlationID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vms/vm/exporttopathonhost":
+			correlationID = r.URL.Query().Get("correlation_id")
+			w.Write([]byte(`{"status": "complete"}`))
+		case "/events":
+			if r.URL.Query().Get("search") != "correlation_id="+correlationID {
+				t.Errorf("bad search: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"event": [
+				{"code": "1222", "severity": "normal", "description": "Starting to export Vm vm"},
+				{"code": 1223, "severity": "normal", "description": "Vm vm was exported successfully"}
+			]}`))
+		default:
+			t.Errorf("bad request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL}
+	config := &Config{OVAHost: "host", OVADirectory: "/exports", OVAFilename: "vm.ova", StateTimeout: time.Minute}
+	state := new(multistep.BasicStateBag)
+	if err := exportOVA(client, packer.TestUi(t), config, "vm", state); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if correlationID == "" {
+		t.Fatal("should have a correlation ID")
+	}
+	if state.Get("ova_path") != "/exports/vm.ova" {
+		t.Fatalf("bad: %#v", state.Get("ova_path"))
+	}
+}
+
+func TestOVAExportStatus(t *testing.T) {
+	events := `{"event": []}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(events))
+	}))
+	defer server.Close()
+
+	get := ovaExportStatus(&Client{URL: server.URL}, "packer")
+
+	// Good
+	export, err := get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if export.Status != "started" {
+		t.Fatalf("bad: %s", export.Status)
+	}
+
+	// Bad
+	events = `{"event": [{"code": 1225, "severity": "error", "description": "Failed to export Vm vm"}]}`
+	if _, err := get(); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,39 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepShutdown struct{}
+
+func (s *stepShutdown) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	vmID := state.Get("vm_id").(string)
+
+	ui.Say("Shutting down VM...")
+	if _, err := client.VMAction(vmID, "shutdown", nil); err != nil {
+		err := fmt.Errorf("Error shutting down VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if err := waitForStatus("down", vmStatus(client, vmID), c.StateTimeout); err != nil {
+		err := fmt.Errorf("Error waiting for VM to shut down: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepShutdown) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
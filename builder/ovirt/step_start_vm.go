@@ -0,0 +1,64 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepStartVM struct{}
+
+func (s *stepStartVM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	vmID := state.Get("vm_id").(string)
+
+	ui.Say("Starting VM...")
+	if _, err := client.VMAction(vmID, "start", startParams(c)); err != nil {
+		err := fmt.Errorf("Error starting VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if err := waitForStatus("up", vmStatus(client, vmID), c.StateTimeout); err != nil {
+		err := fmt.Errorf("Error waiting for VM to start: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepStartVM) Cleanup(state multistep.StateBag) {
+	// The VM is stopped before it is removed
+}
+
+// startParams are the parameters of the start of the VM, which boots the
+// ISO once, with the disk to install to after it.
+func startParams(c *Config) map[string]interface{} {
+	if c.ISOFile == "" {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"vm": map[string]interface{}{
+			"os": map[string]interface{}{
+				"boot": map[string]interface{}{
+					"devices": map[string]interface{}{
+						"device": []string{"cdrom", "hd"},
+					},
+				},
+			},
+			"cdroms": map[string]interface{}{
+				"cdrom": []map[string]interface{}{
+					{"file": Ref{ID: c.ISOFile}},
+				},
+			},
+		},
+	}
+}
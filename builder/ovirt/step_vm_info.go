@@ -0,0 +1,60 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepVMInfo waits for the guest agent of the VM to report an IP address,
+// unless the host of the communicator is configured.
+type stepVMInfo struct{}
+
+func (s *stepVMInfo) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	vmID := state.Get("vm_id").(string)
+
+	if c.Comm.Type == "none" || c.Comm.Host() != "" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Waiting for the guest agent to report an IP address...")
+	deadline := time.Now().Add(c.StateTimeout)
+	for {
+		if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			return multistep.ActionHalt
+		}
+
+		ips, err := client.ReportedIPs(vmID)
+		if err != nil {
+			err := fmt.Errorf("Error retrieving the IP addresses of the VM: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if len(ips) > 0 {
+			ui.Message(fmt.Sprintf("IP address: %s", ips[0]))
+			state.Put("vm_ip", ips[0])
+			return multistep.ActionContinue
+		}
+
+		if time.Now().After(deadline) {
+			err := fmt.Errorf("Timeout while waiting for an IP address. Is the guest agent installed?")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		log.Printf("VM has no IP address yet, waiting...")
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func (s *stepVMInfo) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,38 @@
+package ovirt
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// waitForStatus polls get until the status of the entity is the desired one.
+func waitForStatus(
+	desiredStatus string, get func() (*Entity, error), timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		entity, err := get()
+		if err != nil {
+			return err
+		}
+
+		if entity.Status == desiredStatus {
+			return nil
+		}
+		if entity.Status == "failed" || entity.Status == "illegal" {
+			return fmt.Errorf("%s is %s", entity.ID, entity.Status)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timeout while waiting for %s to become %s", entity.ID, desiredStatus)
+		}
+		log.Printf("%s is %s, waiting to become %s...", entity.ID, entity.Status, desiredStatus)
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func vmStatus(client *Client, id string) func() (*Entity, error) {
+	return func() (*Entity, error) {
+		return client.GetVM(id)
+	}
+}
@@ -3,11 +3,25 @@ package common
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
+	"os/exec"
+	"runtime"
+	"strings"
 
 	"github.com/hashicorp/packer/helper/multistep"
 )
 
+// isAppleSilicon tells whether the host is a Mac with an Apple silicon
+// processor, even when Packer runs under Rosetta.
+var isAppleSilicon = func() bool {
+	if runtime.GOOS != "darwin" {
+		return false
+	}
+	out, err := exec.Command("sysctl", "-n", "hw.optional.arm64").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "1"
+}
+
 // StepPrepareParallelsTools is a step that prepares parameters related
 // to Parallels Tools.
 //
@@ -36,11 +50,24 @@ func (s *StepPrepareParallelsTools) Run(_ context.Context, state multistep.State
 		return multistep.ActionHalt
 	}
 
+	// ARM guests, the only ones on Apple silicon, have their own Parallels
+	// Tools, whose flavors end with "-arm"
+	if isAppleSilicon() && !strings.HasSuffix(s.ParallelsToolsFlavor, "-arm") {
+		armPath, err := driver.ToolsISOPath(s.ParallelsToolsFlavor + "-arm")
+		if err == nil {
+			if _, err := os.Stat(armPath); err == nil {
+				log.Printf("Using the ARM Parallels Tools: %s", armPath)
+				path = armPath
+			}
+		}
+	}
+
 	if _, err := os.Stat(path); err != nil {
 		state.Put("error", fmt.Errorf(
 			"Couldn't find Parallels Tools for the '%s' flavor! Please, check the\n"+
 				"value of 'parallels_tools_flavor'. Valid flavors are: 'win', 'lin',\n"+
-				"'mac', 'os2' and 'other'", s.ParallelsToolsFlavor))
+				"'mac', 'os2' and 'other', and 'win-arm', 'lin-arm' and 'mac-arm'\n"+
+				"on Apple silicon", s.ParallelsToolsFlavor))
 		return multistep.ActionHalt
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/packer/helper/multistep"
@@ -114,3 +115,46 @@ func TestStepPrepareParallelsTools_nonExist(t *testing.T) {
 		t.Fatal("should NOT have parallels_tools_path")
 	}
 }
+
+func TestStepPrepareParallelsTools_appleSilicon(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	armPath := filepath.Join(td, "prl-tools-lin-arm.iso")
+	if err := ioutil.WriteFile(armPath, []byte("foo"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	defer func(f func() bool) { isAppleSilicon = f }(isAppleSilicon)
+	isAppleSilicon = func() bool { return true }
+
+	state := testState(t)
+	step := &StepPrepareParallelsTools{
+		ParallelsToolsFlavor: "lin",
+		ParallelsToolsMode:   "",
+	}
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Mock results
+	driver.ToolsISOPathResult = armPath
+
+	// Test the run
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test the driver
+	if driver.ToolsISOPathFlavor != "lin-arm" {
+		t.Fatalf("bad: %#v", driver.ToolsISOPathFlavor)
+	}
+	if path := state.Get("parallels_tools_path"); path != armPath {
+		t.Fatalf("bad: %#v", path)
+	}
+}
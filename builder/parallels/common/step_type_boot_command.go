@@ -25,6 +25,7 @@ type StepTypeBootCommand struct {
 	BootWait       time.Duration
 	HostInterfaces []string
 	VMName         string
+	KeyboardLayout string
 	Ctx            interpolate.Context
 }
 
@@ -79,7 +80,7 @@ func (s *StepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 	sendCodes := func(codes []string) error {
 		return driver.SendKeyScanCodes(s.VMName, codes...)
 	}
-	d := bootcommand.NewPCXTDriver(sendCodes, -1)
+	d := bootcommand.NewPCXTDriver(sendCodes, -1, s.KeyboardLayout)
 
 	ui.Say("Typing the boot command...")
 	command, err := interpolate.Render(s.BootCommand, &s.Ctx)
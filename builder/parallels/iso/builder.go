@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
+	"strings"
 
 	parallelscommon "github.com/hashicorp/packer/builder/parallels/common"
 	"github.com/hashicorp/packer/common"
@@ -98,6 +100,19 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 	if b.config.GuestOSType == "" {
 		b.config.GuestOSType = "other"
+		if b.config.isIPSW() {
+			b.config.GuestOSType = "macos"
+		}
+	}
+
+	if b.config.isIPSW() {
+		if b.config.TargetExtension == "iso" {
+			b.config.TargetExtension = "ipsw"
+		}
+		if len(b.config.FloppyFiles) > 0 || len(b.config.FloppyDirectories) > 0 {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("macOS guests installed from an IPSW don't support floppies"))
+		}
 	}
 
 	if len(b.config.HostInterfaces) == 0 {
@@ -152,6 +167,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			ParallelsToolsMode:   b.config.ParallelsToolsMode,
 		},
 		&common.StepDownload{
+			Chunks:       b.config.DownloadChunks,
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
 			Description:  "ISO",
@@ -172,11 +188,28 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			HTTPDir:     b.config.HTTPDir,
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
+			TLS:         b.config.HTTPTLS,
+			TLSCertFile: b.config.HTTPTLSCertFile,
+			TLSKeyFile:  b.config.HTTPTLSKeyFile,
+			Username:    b.config.HTTPUsername,
+			Password:    b.config.HTTPPassword,
+			Templates:   b.config.HTTPTemplates,
+			Ctx:         b.config.ctx,
 		},
 		new(stepCreateVM),
-		new(stepCreateDisk),
-		new(stepSetBootOrder),
-		new(stepAttachISO),
+	}
+
+	// A macOS guest is installed from its IPSW when it is created, on a disk
+	// of its own
+	if !b.config.isIPSW() {
+		steps = append(steps,
+			new(stepCreateDisk),
+			new(stepSetBootOrder),
+			new(stepAttachISO),
+		)
+	}
+
+	steps = append(steps,
 		&parallelscommon.StepAttachParallelsTools{
 			ParallelsToolsMode: b.config.ParallelsToolsMode,
 		},
@@ -191,6 +224,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			BootCommand:    b.config.FlatBootCommand(),
 			HostInterfaces: b.config.HostInterfaces,
 			VMName:         b.config.VMName,
+			KeyboardLayout: b.config.BootKeyboardLayout,
 			Ctx:            b.config.ctx,
 		},
 		&communicator.StepConnect{
@@ -219,7 +253,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&parallelscommon.StepCompactDisk{
 			Skip: b.config.SkipCompaction,
 		},
-	}
+	)
 
 	// Setup the state bag
 	state := new(multistep.BasicStateBag)
@@ -251,6 +285,15 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	return parallelscommon.NewArtifact(b.config.OutputDir)
 }
 
+// isIPSW tells whether the iso_url is the IPSW restore image of macOS, which
+// Parallels installs macOS guests on Apple silicon from.
+func (c *Config) isIPSW() bool {
+	if len(c.ISOUrls) < 1 {
+		return false
+	}
+	return strings.ToLower(filepath.Ext(c.ISOUrls[0])) == ".ipsw"
+}
+
 func (b *Builder) Cancel() {
 	if b.runner != nil {
 		log.Println("Cancelling the step runner...")
@@ -240,3 +240,33 @@ func TestBuilderPrepare_InvalidKey(t *testing.T) {
 		t.Fatal("should have error")
 	}
 }
+
+func TestBuilderPrepare_IPSW(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["iso_url"] = "http://www.google.com/UniversalMac_Restore.ipsw"
+
+	// Good
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.GuestOSType != "macos" {
+		t.Errorf("bad guest OS type: %s", b.config.GuestOSType)
+	}
+	if b.config.TargetExtension != "ipsw" {
+		t.Errorf("bad target extension: %s", b.config.TargetExtension)
+	}
+
+	// Bad
+	config["floppy_files"] = []string{"foo"}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -31,7 +31,18 @@ func (s *stepCreateVM) Run(_ context.Context, state multistep.StateBag) multiste
 		"--no-hdd",
 	}
 
-	ui.Say("Creating virtual machine...")
+	// Parallels installs macOS from the IPSW while it creates the VM
+	if config.isIPSW() {
+		command = []string{
+			"create", name,
+			"-o", config.GuestOSType,
+			"--restore-image", state.Get("iso_path").(string),
+			"--dst", config.OutputDir,
+		}
+		ui.Say("Creating virtual machine, installing macOS from the IPSW...")
+	} else {
+		ui.Say("Creating virtual machine...")
+	}
 	if err := driver.Prlctl(command...); err != nil {
 		err := fmt.Errorf("Error creating VM: %s", err)
 		state.Put("error", err)
@@ -47,6 +58,16 @@ func (s *stepCreateVM) Run(_ context.Context, state multistep.StateBag) multiste
 		return multistep.ActionHalt
 	}
 
+	// macOS does not boot with the default memory
+	if config.isIPSW() {
+		if err := driver.Prlctl("set", name, "--cpus", "2", "--memsize", "4096"); err != nil {
+			err := fmt.Errorf("Error VM configuration: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	// Set the VM name property on the first command
 	if s.vmName == "" {
 		s.vmName = name
@@ -80,6 +80,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			BootWait:       b.config.BootWait,
 			HostInterfaces: []string{},
 			VMName:         b.config.VMName,
+			KeyboardLayout: b.config.BootKeyboardLayout,
 			Ctx:            b.config.ctx,
 		},
 		&communicator.StepConnect{
@@ -0,0 +1,105 @@
+// The clone package contains a packer.Builder implementation
+// that builds Proxmox VE templates, cloning them from an existing one.
+
+package clone
+
+import (
+	"fmt"
+	"log"
+
+	proxmoxcommon "github.com/hashicorp/packer/builder/proxmox/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, errs := NewConfig(raws...)
+	if errs != nil {
+		return warnings, errs
+	}
+	b.config = c
+
+	return nil, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	client := proxmoxcommon.NewClient(b.config.ProxmoxURL, b.config.Username, b.config.Password, b.config.Token, b.config.Insecure)
+
+	source := fmt.Sprintf("Clone of: %s", b.config.CloneVM)
+	if b.config.CloneVM == "" {
+		source = fmt.Sprintf("Clone of: ID %d", b.config.CloneVMID)
+	}
+
+	// Set up the state
+	state := new(multistep.BasicStateBag)
+	state.Put("config", b.config)
+	state.Put("client", client)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	// Build the steps
+	steps := []multistep.Step{
+		new(stepCloneVM),
+		new(stepConfigureVM),
+		&proxmoxcommon.StepStartVM{
+			Node:    b.config.Node,
+			Timeout: b.config.StateTimeout,
+		},
+		&communicator.StepConnect{
+			Config:    &b.config.Comm,
+			Host:      proxmoxcommon.CommHost(&b.config.Comm, b.config.Node),
+			SSHConfig: proxmoxcommon.SSHConfig(&b.config.Comm),
+		},
+		new(common.StepProvision),
+		&proxmoxcommon.StepShutdown{
+			Node:    b.config.Node,
+			Timeout: b.config.StateTimeout,
+		},
+		&proxmoxcommon.StepFinalize{
+			Node:         b.config.Node,
+			Template:     &b.config.TemplateConfig,
+			PackerConfig: &b.config.PackerConfig,
+			Source:       source,
+		},
+		&proxmoxcommon.StepConvertToTemplate{
+			Node:     b.config.Node,
+			Template: &b.config.TemplateConfig,
+			Timeout:  b.config.StateTimeout,
+		},
+	}
+
+	// Run the steps
+	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	vmID, ok := state.GetOk("vm_id")
+	if !ok {
+		log.Println("Failed to find vm_id in state. Bug?")
+		return nil, nil
+	}
+
+	name := b.config.TemplateName
+	if b.config.SkipConvertToTemplate {
+		name = b.config.VMName
+	}
+	return proxmoxcommon.NewArtifact(client, b.config.Node, vmID.(int), name, !b.config.SkipConvertToTemplate), nil
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
@@ -0,0 +1,144 @@
+package clone
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"proxmox_url":  "https://pve.example.com:8006/api2/json",
+		"username":     "root@pam",
+		"password":     "secret",
+		"node":         "pve",
+		"clone_vm":     "debian-10",
+		"ssh_username": "root",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare_InvalidKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Add a random key
+	config["i_should_not_be_valid"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	warnings, err := b.Prepare(testConfig())
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.TemplateName != b.config.VMName {
+		t.Errorf("bad template name: %s", b.config.TemplateName)
+	}
+	if b.config.LinkedClone {
+		t.Error("clones should be full clones")
+	}
+}
+
+func TestBuilderPrepare_Required(t *testing.T) {
+	for _, key := range []string{"proxmox_url", "password", "node", "clone_vm"} {
+		var b Builder
+		config := testConfig()
+		delete(config, key)
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error without %s", key)
+		}
+	}
+}
+
+func TestBuilderPrepare_Source(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["clone_vm_id"] = 9000
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with a name and an ID")
+	}
+
+	delete(config, "clone_vm")
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_LinkedClone(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["linked_clone"] = true
+	config["clone_storage_pool"] = "ceph"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with a storage pool")
+	}
+
+	delete(config, "clone_storage_pool")
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestCloneParams(t *testing.T) {
+	c := &Config{VMName: "packer", CloneStoragePool: "ceph"}
+	c.Node = "pve2"
+	c.Pool = "builds"
+
+	params := cloneParams(c, 101)
+	expected := map[string]string{
+		"newid":   "101",
+		"name":    "packer",
+		"target":  "pve2",
+		"full":    "1",
+		"storage": "ceph",
+		"pool":    "builds",
+	}
+	for key, value := range expected {
+		if params.Get(key) != value {
+			t.Errorf("bad %s: %q", key, params.Get(key))
+		}
+	}
+
+	c = &Config{LinkedClone: true}
+	if params := cloneParams(c, 101); params.Get("full") != "0" {
+		t.Fatalf("bad full: %q", params.Get("full"))
+	}
+}
+
+func TestConfigParams(t *testing.T) {
+	if params := configParams(&Config{}); len(params) > 0 {
+		t.Fatalf("the clone should be left as is: %#v", params)
+	}
+
+	c := &Config{Memory: 4096}
+	c.CloudInit = true
+	c.CloudInitUser = "debian"
+	params := configParams(c)
+	if params.Get("memory") != "4096" || params.Get("ciuser") != "debian" {
+		t.Fatalf("bad: %#v", params)
+	}
+	if _, ok := params["cores"]; ok {
+		t.Fatalf("the cores of the template should be kept: %#v", params)
+	}
+}
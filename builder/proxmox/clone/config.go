@@ -0,0 +1,95 @@
+package clone
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	proxmoxcommon "github.com/hashicorp/packer/builder/proxmox/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig           `mapstructure:",squash"`
+	proxmoxcommon.ConnectConfig   `mapstructure:",squash"`
+	proxmoxcommon.CloudInitConfig `mapstructure:",squash"`
+	proxmoxcommon.TemplateConfig  `mapstructure:",squash"`
+	Comm                          communicator.Config `mapstructure:",squash"`
+
+	CloneVM          string `mapstructure:"clone_vm"`
+	CloneVMID        int    `mapstructure:"clone_vm_id"`
+	LinkedClone      bool   `mapstructure:"linked_clone"`
+	CloneStoragePool string `mapstructure:"clone_storage_pool"`
+
+	VMID    int    `mapstructure:"vm_id"`
+	VMName  string `mapstructure:"vm_name"`
+	Memory  int    `mapstructure:"memory"`
+	Cores   int    `mapstructure:"cores"`
+	Sockets int    `mapstructure:"sockets"`
+
+	StateTimeout time.Duration `mapstructure:"state_timeout"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.VMName == "" {
+		// Default to packer-[time-ordered-uuid]
+		c.VMName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	}
+
+	if c.StateTimeout == 0 {
+		// Full clones copy the disks of the template
+		c.StateTimeout = 20 * time.Minute
+	}
+
+	var errs *packer.MultiError
+	errs = packer.MultiErrorAppend(errs, c.ConnectConfig.Prepare(&c.ctx)...)
+	// The clone has the cloud-init drive of the template, if it has one
+	errs = packer.MultiErrorAppend(errs, c.CloudInitConfig.Prepare(&c.ctx, false)...)
+	errs = packer.MultiErrorAppend(errs, c.TemplateConfig.Prepare(&c.ctx, c.VMName)...)
+	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if (c.CloneVM == "") == (c.CloneVMID == 0) {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("exactly one of clone_vm or clone_vm_id is required"))
+	}
+	if c.LinkedClone && c.CloneStoragePool != "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("clone_storage_pool can't be used with linked_clone, a linked clone is in the storage of the template"))
+	}
+
+	if c.VMID < 0 {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("vm_id must be positive: %d", c.VMID))
+	}
+	if c.Memory < 0 || c.Cores < 0 || c.Sockets < 0 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("memory, cores and sockets must be positive"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	common.ScrubConfig(c, c.Password, c.Token, c.CloudInitPassword)
+	return c, nil, nil
+}
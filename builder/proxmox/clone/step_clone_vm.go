@@ -0,0 +1,104 @@
+package clone
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	proxmoxcommon "github.com/hashicorp/packer/builder/proxmox/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepCloneVM struct {
+	vmID int
+}
+
+func (s *stepCloneVM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*proxmoxcommon.Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	var source *proxmoxcommon.Resource
+	var err error
+	if c.CloneVM != "" {
+		source, err = client.FindVM(c.CloneVM)
+	} else {
+		source, err = client.GetVM(c.CloneVMID)
+	}
+	if err == nil && c.LinkedClone && source.Template == 0 {
+		err = fmt.Errorf("%s (ID: %d) is not a template, only templates have linked clones", source.Name, source.VMID)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error finding the VM to clone: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	vmID := c.VMID
+	if vmID == 0 {
+		id, err := client.NextID()
+		if err != nil {
+			err := fmt.Errorf("Error getting a free VM ID: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		vmID = id
+	}
+
+	kind := "full"
+	if c.LinkedClone {
+		kind = "linked"
+	}
+	ui.Say(fmt.Sprintf("Creating VM %s (ID: %d) on %s, a %s clone of %s (ID: %d)...",
+		c.VMName, vmID, c.Node, kind, source.Name, source.VMID))
+	// The template is cloned on its node, to the node of the build
+	upid, err := client.CloneVM(source.Node, source.VMID, cloneParams(c, vmID))
+	if err == nil {
+		// We use this in cleanup
+		s.vmID = vmID
+		err = proxmoxcommon.WaitForTask(client, source.Node, upid, c.StateTimeout)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error cloning VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("vm_id", vmID)
+	return multistep.ActionContinue
+}
+
+func (s *stepCloneVM) Cleanup(state multistep.StateBag) {
+	// If the VM ID isn't there, we probably never created it
+	if s.vmID == 0 {
+		return
+	}
+
+	c := state.Get("config").(*Config)
+	proxmoxcommon.RemoveVM(state, c.Node, s.vmID, c.StateTimeout)
+}
+
+// cloneParams are the parameters of the clone of the VM.
+func cloneParams(c *Config, vmID int) url.Values {
+	params := url.Values{
+		"newid":  {strconv.Itoa(vmID)},
+		"name":   {c.VMName},
+		"target": {c.Node},
+		"full":   {"1"},
+	}
+	if c.LinkedClone {
+		params.Set("full", "0")
+	}
+	if c.CloneStoragePool != "" {
+		params.Set("storage", c.CloneStoragePool)
+	}
+	if c.Pool != "" {
+		params.Set("pool", c.Pool)
+	}
+	return params
+}
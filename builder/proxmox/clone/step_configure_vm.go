@@ -0,0 +1,58 @@
+package clone
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	proxmoxcommon "github.com/hashicorp/packer/builder/proxmox/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepConfigureVM changes the hardware of the clone, and sets up cloud-init
+// before the clone boots for the first time.
+type stepConfigureVM struct{}
+
+func (s *stepConfigureVM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*proxmoxcommon.Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	vmID := state.Get("vm_id").(int)
+
+	params := configParams(c)
+	if len(params) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Configuring VM...")
+	if err := client.SetVMConfig(c.Node, vmID, params); err != nil {
+		err := fmt.Errorf("Error configuring VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConfigureVM) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// configParams are the parameters of the clone that differ from the
+// template.
+func configParams(c *Config) url.Values {
+	params := c.CloudInitConfig.Params()
+	if c.Memory != 0 {
+		params.Set("memory", strconv.Itoa(c.Memory))
+	}
+	if c.Cores != 0 {
+		params.Set("cores", strconv.Itoa(c.Cores))
+	}
+	if c.Sockets != 0 {
+		params.Set("sockets", strconv.Itoa(c.Sockets))
+	}
+	return params
+}
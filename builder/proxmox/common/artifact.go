@@ -0,0 +1,74 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// This is the common builder ID to all of these artifacts.
+const BuilderId = "packer.proxmox"
+
+// defaultTaskTimeout is the time to wait for the tasks that aren't run by the
+// build, such as the removal of the artifact.
+const defaultTaskTimeout = 10 * time.Minute
+
+type artifact struct {
+	// The node and ID of the VM, which is a template unless the conversion
+	// is skipped
+	node     string
+	vmID     int
+	name     string
+	template bool
+
+	// The client for making API calls
+	client *Client
+}
+
+// NewArtifact returns the artifact of the VM, or of the template it is
+// converted into.
+func NewArtifact(client *Client, node string, vmID int, name string, template bool) packer.Artifact {
+	return &artifact{
+		node:     node,
+		vmID:     vmID,
+		name:     name,
+		template: template,
+		client:   client,
+	}
+}
+
+func (*artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*artifact) Files() []string {
+	// The disks are in the storage pools of the cluster, not here
+	return nil
+}
+
+func (a *artifact) Id() string {
+	return strconv.Itoa(a.vmID)
+}
+
+func (a *artifact) String() string {
+	if a.template {
+		return fmt.Sprintf("A template was created: '%v' (ID: %v) on %v", a.name, a.vmID, a.node)
+	}
+	return fmt.Sprintf("A VM was created: '%v' (ID: %v) on %v", a.name, a.vmID, a.node)
+}
+
+func (a *artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *artifact) Destroy() error {
+	log.Printf("Destroying VM: %d (%s)", a.vmID, a.name)
+	upid, err := a.client.DeleteVM(a.node, a.vmID)
+	if err != nil {
+		return err
+	}
+	return WaitForTask(a.client, a.node, upid, defaultTaskTimeout)
+}
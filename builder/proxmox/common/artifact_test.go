@@ -0,0 +1,28 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packer.Artifact = new(artifact)
+}
+
+func TestArtifactString(t *testing.T) {
+	a := &artifact{node: "pve", vmID: 100, name: "debian-10", template: true}
+	expected := "A template was created: 'debian-10' (ID: 100) on pve"
+	if a.String() != expected {
+		t.Fatalf("artifact string should match: %v", expected)
+	}
+	if a.Id() != "100" {
+		t.Fatalf("bad: %s", a.Id())
+	}
+
+	a.template = false
+	expected = "A VM was created: 'debian-10' (ID: 100) on pve"
+	if a.String() != expected {
+		t.Fatalf("artifact string should match: %v", expected)
+	}
+}
@@ -0,0 +1,339 @@
+package common
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NOTE: there is no Proxmox SDK vendored, so the few calls of the VE API the
+// builder makes are done with the client here. The API takes form encoded
+// parameters, and wraps its results in a data object.
+
+// Client is a client of the Proxmox VE API.
+type Client struct {
+	// URL is the URL of the API, like https://pve:8006/api2/json.
+	URL string
+
+	// Username is the user with its realm, like root@pam. With a Token, it
+	// is the ID of the token, like root@pam!packer.
+	Username string
+	Password string
+	Token    string
+
+	HTTPClient *http.Client
+
+	ticket string
+	csrf   string
+}
+
+// NewClient returns a client of the API at the URL, which may not verify the
+// certificate if it is self signed.
+func NewClient(url string, username string, password string, token string, insecure bool) *Client {
+	transport := http.DefaultTransport.(*http.Transport)
+	if insecure {
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return &Client{
+		URL:      url,
+		Username: username,
+		Password: password,
+		Token:    token,
+		HTTPClient: &http.Client{
+			Transport: transport,
+			Timeout:   5 * time.Minute,
+		},
+	}
+}
+
+// VMStatus is the current status of a VM.
+type VMStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Resource is a VM or a template of the cluster, on the node it is on.
+type Resource struct {
+	VMID     int    `json:"vmid"`
+	Name     string `json:"name"`
+	Node     string `json:"node"`
+	Template int    `json:"template"`
+}
+
+// TaskStatus is the status of a task, which is running until it is stopped,
+// with an exit status of OK if it succeeded.
+type TaskStatus struct {
+	Status     string `json:"status"`
+	ExitStatus string `json:"exitstatus"`
+}
+
+// ErrorResponse is the error returned by the API, with the parameters in
+// error if it rejected some.
+type ErrorResponse struct {
+	StatusCode int
+	Status     string
+	Errors     map[string]string `json:"errors"`
+}
+
+func (e *ErrorResponse) Error() string {
+	msg := fmt.Sprintf("Proxmox API error: %s", e.Status)
+	if len(e.Errors) == 0 {
+		return msg
+	}
+
+	params := make([]string, 0, len(e.Errors))
+	for param := range e.Errors {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for i, param := range params {
+		params[i] = fmt.Sprintf("%s: %s", param, strings.TrimSpace(e.Errors[param]))
+	}
+	return fmt.Sprintf("%s (%s)", msg, strings.Join(params, ", "))
+}
+
+// NextID returns a free VM ID of the cluster.
+func (c *Client) NextID() (int, error) {
+	var result json.Number
+	if err := c.do("GET", "cluster/nextid", nil, &result); err != nil {
+		return 0, err
+	}
+	id, err := result.Int64()
+	return int(id), err
+}
+
+// CreateVM creates a VM on a node, and returns the UPID of the task creating
+// it.
+func (c *Client) CreateVM(node string, params url.Values) (string, error) {
+	var upid string
+	err := c.do("POST", fmt.Sprintf("nodes/%s/qemu", node), params, &upid)
+	return upid, err
+}
+
+// FindVM returns the VM or template with a name in the cluster, which must
+// be the only one with that name.
+func (c *Client) FindVM(name string) (*Resource, error) {
+	vms, err := c.listVMs()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Resource
+	for _, vm := range vms {
+		if vm.Name == name {
+			found = append(found, vm)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no VM named %s", name)
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, fmt.Errorf("%d VMs are named %s, the ID of the one to use is needed", len(found), name)
+	}
+}
+
+// GetVM returns the VM or template with an ID in the cluster.
+func (c *Client) GetVM(vmID int) (*Resource, error) {
+	vms, err := c.listVMs()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range vms {
+		if vms[i].VMID == vmID {
+			return &vms[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no VM with the ID %d", vmID)
+}
+
+func (c *Client) listVMs() ([]Resource, error) {
+	var result []Resource
+	err := c.do("GET", "cluster/resources?type=vm", nil, &result)
+	return result, err
+}
+
+// CloneVM clones a VM or a template, on the node it is on, and returns the
+// UPID of the task cloning it.
+func (c *Client) CloneVM(node string, vmID int, params url.Values) (string, error) {
+	var upid string
+	err := c.do("POST", fmt.Sprintf("nodes/%s/qemu/%d/clone", node, vmID), params, &upid)
+	return upid, err
+}
+
+// GetVMStatus returns the current status of a VM.
+func (c *Client) GetVMStatus(node string, vmID int) (*VMStatus, error) {
+	result := new(VMStatus)
+	err := c.do("GET", fmt.Sprintf("nodes/%s/qemu/%d/status/current", node, vmID), nil, result)
+	return result, err
+}
+
+// VMAction runs an action on the status of a VM, such as start, shutdown or
+// stop, and returns the UPID of the task running it.
+func (c *Client) VMAction(node string, vmID int, action string) (string, error) {
+	var upid string
+	err := c.do("POST", fmt.Sprintf("nodes/%s/qemu/%d/status/%s", node, vmID, action), url.Values{}, &upid)
+	return upid, err
+}
+
+// SetVMConfig updates the configuration of a VM.
+func (c *Client) SetVMConfig(node string, vmID int, params url.Values) error {
+	return c.do("PUT", fmt.Sprintf("nodes/%s/qemu/%d/config", node, vmID), params, nil)
+}
+
+// SendKey sends a key, in the key syntax of QEMU like shift-a, to a VM.
+func (c *Client) SendKey(node string, vmID int, key string) error {
+	return c.do("PUT", fmt.Sprintf("nodes/%s/qemu/%d/sendkey", node, vmID), url.Values{"key": {key}}, nil)
+}
+
+// AgentIPs returns the IPv4 addresses the guest agent of a VM reports, but
+// the loopback ones.
+func (c *Client) AgentIPs(node string, vmID int) ([]string, error) {
+	var result struct {
+		Result []struct {
+			Name        string `json:"name"`
+			IPAddresses []struct {
+				Address string `json:"ip-address"`
+				Type    string `json:"ip-address-type"`
+			} `json:"ip-addresses"`
+		} `json:"result"`
+	}
+	err := c.do("GET", fmt.Sprintf("nodes/%s/qemu/%d/agent/network-get-interfaces", node, vmID), nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []string
+	for _, iface := range result.Result {
+		for _, ip := range iface.IPAddresses {
+			if ip.Type == "ipv4" && !strings.HasPrefix(ip.Address, "127.") {
+				ips = append(ips, ip.Address)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// ConvertToTemplate converts a stopped VM into a template, and returns the
+// UPID of the task converting it, if there is one.
+func (c *Client) ConvertToTemplate(node string, vmID int) (string, error) {
+	var upid string
+	err := c.do("POST", fmt.Sprintf("nodes/%s/qemu/%d/template", node, vmID), url.Values{}, &upid)
+	return upid, err
+}
+
+// DeleteVM removes a stopped VM with its disks, and returns the UPID of the
+// task removing it.
+func (c *Client) DeleteVM(node string, vmID int) (string, error) {
+	var upid string
+	err := c.do("DELETE", fmt.Sprintf("nodes/%s/qemu/%d?purge=1", node, vmID), nil, &upid)
+	return upid, err
+}
+
+// GetTaskStatus returns the status of a task.
+func (c *Client) GetTaskStatus(node string, upid string) (*TaskStatus, error) {
+	result := new(TaskStatus)
+	err := c.do("GET", fmt.Sprintf("nodes/%s/tasks/%s/status", node, url.PathEscape(upid)), nil, result)
+	return result, err
+}
+
+// login gets a ticket for the user and password, which is valid for two
+// hours.
+func (c *Client) login() error {
+	var result struct {
+		Ticket string `json:"ticket"`
+		CSRF   string `json:"CSRFPreventionToken"`
+	}
+	params := url.Values{"username": {c.Username}, "password": {c.Password}}
+	if err := c.request("POST", "access/ticket", params, &result); err != nil {
+		return err
+	}
+	c.ticket = result.Ticket
+	c.csrf = result.CSRF
+	return nil
+}
+
+func (c *Client) do(method string, path string, params url.Values, result interface{}) error {
+	if c.Token == "" && c.ticket == "" {
+		if err := c.login(); err != nil {
+			return err
+		}
+	}
+
+	err := c.request(method, path, params, result)
+	if apiErr, ok := err.(*ErrorResponse); ok && apiErr.StatusCode == http.StatusUnauthorized && c.Token == "" {
+		// The ticket expired during a long build, a new one is needed
+		if err := c.login(); err != nil {
+			return err
+		}
+		err = c.request(method, path, params, result)
+	}
+	return err
+}
+
+func (c *Client) request(method string, path string, params url.Values, result interface{}) error {
+	var reader io.Reader
+	if params != nil {
+		reader = strings.NewReader(params.Encode())
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.URL, "/")+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if params != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", c.Username, c.Token))
+	} else if c.ticket != "" {
+		req.AddCookie(&http.Cookie{Name: "PVEAuthCookie", Value: c.ticket})
+		if method != "GET" {
+			req.Header.Set("CSRFPreventionToken", c.csrf)
+		}
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		// The reason of the status is the message of the error
+		apiErr := &ErrorResponse{StatusCode: resp.StatusCode, Status: resp.Status}
+		// The errors are best effort, the status is enough to fail
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	if result == nil {
+		return nil
+	}
+	var data struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return err
+	}
+	if len(data.Data) == 0 || string(data.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data.Data, result)
+}
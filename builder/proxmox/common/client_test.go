@@ -0,0 +1,142 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Ticket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/access/ticket":
+			if r.FormValue("username") != "root@pam" || r.FormValue("password") != "secret" {
+				t.Errorf("bad login: %v", r.Form)
+			}
+			w.Write([]byte(`{"data": {"ticket": "PVE:root@pam:ticket", "CSRFPreventionToken": "csrf"}}`))
+		case "/api2/json/nodes/pve/qemu":
+			cookie, err := r.Cookie("PVEAuthCookie")
+			if err != nil || cookie.Value != "PVE:root@pam:ticket" || r.Header.Get("CSRFPreventionToken") != "csrf" {
+				t.Errorf("bad auth: %#v", r.Header)
+			}
+			if r.FormValue("vmid") != "100" {
+				t.Errorf("bad params: %v", r.Form)
+			}
+			w.Write([]byte(`{"data": "UPID:pve:00001234:00005678:5F000000:qmcreate:100:root@pam:"}`))
+		default:
+			t.Errorf("bad request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL + "/api2/json", Username: "root@pam", Password: "secret"}
+	upid, err := client.CreateVM("pve", map[string][]string{"vmid": {"100"}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if upid != "UPID:pve:00001234:00005678:5F000000:qmcreate:100:root@pam:" {
+		t.Fatalf("bad: %s", upid)
+	}
+}
+
+func TestClient_Token(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cluster/nextid" {
+			t.Errorf("bad request: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "PVEAPIToken=root@pam!packer=secret" {
+			t.Errorf("bad auth: %#v", r.Header)
+		}
+		w.Write([]byte(`{"data": "104"}`))
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL, Username: "root@pam!packer", Token: "secret"}
+	id, err := client.NextID()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if id != 104 {
+		t.Fatalf("bad: %d", id)
+	}
+}
+
+func TestClient_AgentIPs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nodes/pve/qemu/100/agent/network-get-interfaces" {
+			t.Errorf("bad request: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"data": {"result": [
+			{"name": "lo", "ip-addresses": [{"ip-address": "127.0.0.1", "ip-address-type": "ipv4"}]},
+			{"name": "eth0", "ip-addresses": [
+				{"ip-address": "fe80::1", "ip-address-type": "ipv6"},
+				{"ip-address": "10.0.0.5", "ip-address-type": "ipv4"}
+			]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL, Token: "secret"}
+	ips, err := client.AgentIPs("pve", 100)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(ips, []string{"10.0.0.5"}) {
+		t.Fatalf("bad: %#v", ips)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"data": null, "errors": {"memory": "value must have a minimum value of 16\n"}}`))
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL, Token: "secret"}
+	_, err := client.CreateVM("pve", nil)
+	expected := "Proxmox API error: 400 Bad Request (memory: value must have a minimum value of 16)"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestClient_FindVM(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cluster/resources" || r.URL.Query().Get("type") != "vm" {
+			t.Errorf("bad request: %s", r.URL)
+		}
+		w.Write([]byte(`{"data": [
+			{"vmid": 100, "name": "web", "node": "pve", "template": 0},
+			{"vmid": 9000, "name": "debian-10", "node": "pve2", "template": 1},
+			{"vmid": 9001, "name": "debian-9", "node": "pve", "template": 1},
+			{"vmid": 9002, "name": "debian-9", "node": "pve2", "template": 1}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL, Token: "secret"}
+	vm, err := client.FindVM("debian-10")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if *vm != (Resource{VMID: 9000, Name: "debian-10", Node: "pve2", Template: 1}) {
+		t.Fatalf("bad: %#v", vm)
+	}
+
+	if _, err := client.FindVM("debian-9"); err == nil {
+		t.Fatal("should have error with two VMs of the name")
+	}
+	if _, err := client.FindVM("debian-8"); err == nil {
+		t.Fatal("should have error without a VM of the name")
+	}
+
+	vm, err = client.GetVM(100)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if vm.Name != "web" {
+		t.Fatalf("bad: %#v", vm)
+	}
+}
@@ -0,0 +1,149 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/template/interpolate"
+	"github.com/hashicorp/packer/version"
+)
+
+// CloudInitDrive is the drive of the cloud-init drive the builders create,
+// after the drive of the ISO on ide2.
+const CloudInitDrive = "ide3"
+
+// ConnectConfig is the configuration of the access to the API, and of the
+// node and pool of the VM.
+type ConnectConfig struct {
+	ProxmoxURL string `mapstructure:"proxmox_url"`
+	Username   string `mapstructure:"username"`
+	Password   string `mapstructure:"password"`
+	Token      string `mapstructure:"token"`
+	Insecure   bool   `mapstructure:"insecure_skip_tls_verify"`
+
+	Node string `mapstructure:"node"`
+	Pool string `mapstructure:"pool"`
+}
+
+func (c *ConnectConfig) Prepare(ctx *interpolate.Context) []error {
+	if c.ProxmoxURL == "" {
+		c.ProxmoxURL = os.Getenv("PROXMOX_URL")
+	}
+	if c.Username == "" {
+		c.Username = os.Getenv("PROXMOX_USERNAME")
+	}
+	if c.Password == "" {
+		c.Password = os.Getenv("PROXMOX_PASSWORD")
+	}
+	if c.Token == "" {
+		c.Token = os.Getenv("PROXMOX_TOKEN")
+	}
+
+	var errs []error
+	if c.ProxmoxURL == "" {
+		errs = append(errs, errors.New("proxmox_url is required"))
+	}
+	if c.Username == "" {
+		errs = append(errs, errors.New("username is required"))
+	}
+	if (c.Password == "") == (c.Token == "") {
+		errs = append(errs, errors.New("exactly one of password or token for auth must be specified"))
+	}
+	if c.Node == "" {
+		errs = append(errs, errors.New("node is required"))
+	}
+	return errs
+}
+
+// CloudInitConfig is the configuration cloud-init provides to the guest,
+// from a cloud-init drive.
+type CloudInitConfig struct {
+	CloudInit            bool     `mapstructure:"cloud_init"`
+	CloudInitStoragePool string   `mapstructure:"cloud_init_storage_pool"`
+	CloudInitUser        string   `mapstructure:"cloud_init_user"`
+	CloudInitPassword    string   `mapstructure:"cloud_init_password"`
+	CloudInitSSHKeys     []string `mapstructure:"cloud_init_ssh_keys"`
+	CloudInitIPConfig    []string `mapstructure:"cloud_init_ipconfig"`
+}
+
+// Prepare validates the configuration, whose storage pool is required if
+// the VM has no cloud-init drive yet.
+func (c *CloudInitConfig) Prepare(ctx *interpolate.Context, poolRequired bool) []error {
+	var errs []error
+	if c.CloudInit {
+		if poolRequired && c.CloudInitStoragePool == "" {
+			errs = append(errs, errors.New("cloud_init_storage_pool is required with cloud_init"))
+		}
+	} else if c.CloudInitStoragePool != "" || c.CloudInitUser != "" || c.CloudInitPassword != "" ||
+		len(c.CloudInitSSHKeys) > 0 || len(c.CloudInitIPConfig) > 0 {
+		errs = append(errs, errors.New("the cloud_init_* options require cloud_init"))
+	}
+	return errs
+}
+
+// Params are the parameters of the cloud-init drive of the VM, if it is
+// created in a storage pool, and of the configuration it provides.
+func (c *CloudInitConfig) Params() url.Values {
+	params := url.Values{}
+	if !c.CloudInit {
+		return params
+	}
+
+	if c.CloudInitStoragePool != "" {
+		params.Set(CloudInitDrive, c.CloudInitStoragePool+":cloudinit")
+	}
+	if c.CloudInitUser != "" {
+		params.Set("ciuser", c.CloudInitUser)
+	}
+	if c.CloudInitPassword != "" {
+		params.Set("cipassword", c.CloudInitPassword)
+	}
+	if len(c.CloudInitSSHKeys) > 0 {
+		// The keys are URL encoded in the parameter, once more than the
+		// other parameters, with all the reserved characters escaped and
+		// spaces as %20, as Proxmox doesn't decode a + into a space
+		keys := make([]string, len(c.CloudInitSSHKeys))
+		for i, key := range c.CloudInitSSHKeys {
+			keys[i] = strings.TrimSpace(key)
+		}
+		escaped := url.QueryEscape(strings.Join(keys, "\n"))
+		params.Set("sshkeys", strings.Replace(escaped, "+", "%20", -1))
+	}
+	for i, ipconfig := range c.CloudInitIPConfig {
+		params.Set(fmt.Sprintf("ipconfig%d", i), ipconfig)
+	}
+	return params
+}
+
+// TemplateConfig is the configuration of the template the VM is converted
+// into once it is built.
+type TemplateConfig struct {
+	TemplateName          string `mapstructure:"template_name"`
+	TemplateDescription   string `mapstructure:"template_description"`
+	SkipConvertToTemplate bool   `mapstructure:"skip_convert_to_template"`
+}
+
+// Prepare defaults the name of the template to the name of the VM.
+func (c *TemplateConfig) Prepare(ctx *interpolate.Context, vmName string) []error {
+	if c.TemplateName == "" {
+		c.TemplateName = vmName
+	}
+	return nil
+}
+
+// Description is the description of the template, generated from the
+// metadata of the build and the source of the VM unless there is one.
+func (c *TemplateConfig) Description(pc *common.PackerConfig, source string, now time.Time) string {
+	if c.TemplateDescription != "" {
+		return c.TemplateDescription
+	}
+
+	return fmt.Sprintf("Built by Packer %s on %s\n\nBuild: %s (%s)\n%s\n",
+		version.FormattedVersion(), now.UTC().Format(time.RFC3339),
+		pc.PackerBuildName, pc.PackerBuilderType, source)
+}
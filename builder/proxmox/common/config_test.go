@@ -0,0 +1,98 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+)
+
+func TestConnectConfigPrepare(t *testing.T) {
+	c := &ConnectConfig{
+		ProxmoxURL: "https://pve.example.com:8006/api2/json",
+		Username:   "root@pam",
+		Password:   "secret",
+		Node:       "pve",
+	}
+	if errs := c.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("should not have error: %v", errs)
+	}
+
+	c.Token = "e2d5b7f8-1f2b-4f5e-9f0a-000000000000"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("should have error with a password and a token: %v", errs)
+	}
+}
+
+func TestCloudInitConfigPrepare(t *testing.T) {
+	c := &CloudInitConfig{CloudInitUser: "debian"}
+	if errs := c.Prepare(nil, false); len(errs) != 1 {
+		t.Fatalf("should have error without cloud_init: %v", errs)
+	}
+
+	c.CloudInit = true
+	if errs := c.Prepare(nil, true); len(errs) != 1 {
+		t.Fatalf("should have error without the storage pool: %v", errs)
+	}
+	if errs := c.Prepare(nil, false); len(errs) > 0 {
+		t.Fatalf("should not have error: %v", errs)
+	}
+}
+
+func TestCloudInitConfigParams(t *testing.T) {
+	c := &CloudInitConfig{
+		CloudInit:            true,
+		CloudInitStoragePool: "local-lvm",
+		CloudInitUser:        "debian",
+		CloudInitSSHKeys:     []string{"ssh-ed25519 AAAA+key1 a@example.com\n", "ssh-rsa AAAA/key2== b@example.com"},
+		CloudInitIPConfig:    []string{"ip=dhcp", "ip=10.0.0.10/24,gw=10.0.0.1"},
+	}
+
+	params := c.Params()
+	expected := map[string]string{
+		"ide3":      "local-lvm:cloudinit",
+		"ciuser":    "debian",
+		"sshkeys":   "ssh-ed25519%20AAAA%2Bkey1%20a%40example.com%0Assh-rsa%20AAAA%2Fkey2%3D%3D%20b%40example.com",
+		"ipconfig0": "ip=dhcp",
+		"ipconfig1": "ip=10.0.0.10/24,gw=10.0.0.1",
+	}
+	for key, value := range expected {
+		if params.Get(key) != value {
+			t.Errorf("bad %s: %q", key, params.Get(key))
+		}
+	}
+	if _, ok := params["cipassword"]; ok {
+		t.Error("the password should not be set")
+	}
+
+	c.CloudInitStoragePool = ""
+	if _, ok := c.Params()["ide3"]; ok {
+		t.Error("the drive of the VM should be used")
+	}
+
+	if params := (&CloudInitConfig{}).Params(); len(params) > 0 {
+		t.Fatalf("bad: %#v", params)
+	}
+}
+
+func TestTemplateConfigDescription(t *testing.T) {
+	pc := &common.PackerConfig{PackerBuildName: "debian", PackerBuilderType: "proxmox-iso"}
+	c := &TemplateConfig{}
+	if errs := c.Prepare(nil, "packer-vm"); len(errs) > 0 || c.TemplateName != "packer-vm" {
+		t.Fatalf("bad template name: %s", c.TemplateName)
+	}
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := c.Description(pc, "ISO: local:iso/debian-10.iso", now)
+	for _, s := range []string{"2021-06-01T12:00:00Z", "Build: debian (proxmox-iso)", "ISO: local:iso/debian-10.iso"} {
+		if !strings.Contains(d, s) {
+			t.Errorf("description should contain %q: %s", s, d)
+		}
+	}
+
+	c.TemplateDescription = "Debian 10"
+	if d := c.Description(pc, "", now); d != "Debian 10" {
+		t.Fatalf("bad: %s", d)
+	}
+}
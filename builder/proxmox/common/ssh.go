@@ -0,0 +1,59 @@
+package common
+
+import (
+	"errors"
+
+	commonssh "github.com/hashicorp/packer/common/ssh"
+	"github.com/hashicorp/packer/communicator/ssh"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// CommHost returns the first IPv4 address the guest agent of the VM reports,
+// unless the host of the communicator is configured. The agent may only run
+// once the system is installed or booted, the connection retries until then.
+func CommHost(comm *communicator.Config, node string) func(multistep.StateBag) (string, error) {
+	return func(state multistep.StateBag) (string, error) {
+		if host := comm.Host(); host != "" {
+			return host, nil
+		}
+
+		client := state.Get("client").(*Client)
+		vmID := state.Get("vm_id").(int)
+
+		ips, err := client.AgentIPs(node, vmID)
+		if err != nil {
+			return "", err
+		}
+		if len(ips) == 0 {
+			return "", errors.New("the guest agent reports no IP address yet")
+		}
+		return ips[0], nil
+	}
+}
+
+func SSHConfig(comm *communicator.Config) func(multistep.StateBag) (*gossh.ClientConfig, error) {
+	return func(state multistep.StateBag) (*gossh.ClientConfig, error) {
+		auth := []gossh.AuthMethod{
+			gossh.Password(comm.SSHPassword),
+			gossh.KeyboardInteractive(
+				ssh.PasswordKeyboardInteractive(comm.SSHPassword)),
+		}
+
+		if comm.SSHPrivateKey != "" {
+			signer, err := commonssh.FileSigner(comm.SSHPrivateKey)
+			if err != nil {
+				return nil, err
+			}
+
+			auth = append(auth, gossh.PublicKeys(signer))
+		}
+
+		return &gossh.ClientConfig{
+			User:            comm.SSHUsername,
+			Auth:            auth,
+			HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+		}, nil
+	}
+}
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type StepConvertToTemplate struct {
+	Node     string
+	Template *TemplateConfig
+	Timeout  time.Duration
+}
+
+func (s *StepConvertToTemplate) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	vmID := state.Get("vm_id").(int)
+
+	if s.Template.SkipConvertToTemplate {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Converting VM to the template %s...", s.Template.TemplateName))
+	upid, err := client.ConvertToTemplate(s.Node, vmID)
+	if err == nil {
+		err = WaitForTask(client, s.Node, upid, s.Timeout)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error converting VM to a template: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepConvertToTemplate) Cleanup(state multistep.StateBag) {
+	// The template is removed with the VM
+}
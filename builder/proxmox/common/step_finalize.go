@@ -0,0 +1,58 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepFinalize sets up the stopped VM the way the VMs created from it will
+// be, with its name and description, and the parameters of the builder.
+type StepFinalize struct {
+	Node         string
+	Template     *TemplateConfig
+	PackerConfig *common.PackerConfig
+	// Source is the line of the generated description about the source of
+	// the VM.
+	Source string
+	Params url.Values
+}
+
+func (s *StepFinalize) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	vmID := state.Get("vm_id").(int)
+
+	ui.Say("Finalizing VM...")
+	if err := client.SetVMConfig(s.Node, vmID, s.params(time.Now())); err != nil {
+		err := fmt.Errorf("Error finalizing VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepFinalize) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// params are the parameters of the update of the VM once it is built.
+func (s *StepFinalize) params(now time.Time) url.Values {
+	params := url.Values{
+		"description": {s.Template.Description(s.PackerConfig, s.Source, now)},
+	}
+	if !s.Template.SkipConvertToTemplate {
+		params.Set("name", s.Template.TemplateName)
+	}
+	for key, values := range s.Params {
+		params[key] = values
+	}
+	return params
+}
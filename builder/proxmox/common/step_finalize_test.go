@@ -0,0 +1,34 @@
+package common
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+)
+
+func TestStepFinalize_params(t *testing.T) {
+	s := &StepFinalize{
+		Template:     &TemplateConfig{TemplateName: "debian-10", TemplateDescription: "Debian 10"},
+		PackerConfig: &common.PackerConfig{},
+		Params:       url.Values{"ide2": {"none,media=cdrom"}},
+	}
+
+	params := s.params(time.Now())
+	expected := map[string]string{
+		"name":        "debian-10",
+		"description": "Debian 10",
+		"ide2":        "none,media=cdrom",
+	}
+	for key, value := range expected {
+		if params.Get(key) != value {
+			t.Errorf("bad %s: %q", key, params.Get(key))
+		}
+	}
+
+	s.Template.SkipConvertToTemplate = true
+	if _, ok := s.params(time.Now())["name"]; ok {
+		t.Error("the VM should keep its name")
+	}
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepShutdown shuts the VM down with ACPI, for the guest to stop cleanly.
+type StepShutdown struct {
+	Node    string
+	Timeout time.Duration
+}
+
+func (s *StepShutdown) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	vmID := state.Get("vm_id").(int)
+
+	ui.Say("Shutting down VM...")
+	if _, err := client.VMAction(s.Node, vmID, "shutdown"); err != nil {
+		err := fmt.Errorf("Error shutting down VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// The guest may take longer than the task to shut down, the status of
+	// the VM is the one to wait for
+	if err := WaitForStatus(client, s.Node, vmID, "stopped", s.Timeout); err != nil {
+		err := fmt.Errorf("Error waiting for VM to shut down: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepShutdown) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type StepStartVM struct {
+	Node    string
+	Timeout time.Duration
+}
+
+func (s *StepStartVM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	vmID := state.Get("vm_id").(int)
+
+	ui.Say("Starting VM...")
+	upid, err := client.VMAction(s.Node, vmID, "start")
+	if err == nil {
+		err = WaitForTask(client, s.Node, upid, s.Timeout)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error starting VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepStartVM) Cleanup(state multistep.StateBag) {
+	// The VM is stopped before it is removed
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// RemoveVM stops and removes the VM of a build that failed or was
+// cancelled, in the cleanup of the step that created it. The VM is the
+// artifact of the builds that succeed.
+func RemoveVM(state multistep.StateBag, node string, vmID int, timeout time.Duration) {
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+
+	// A VM is removed once it is stopped
+	if vm, err := client.GetVMStatus(node, vmID); err == nil && vm.Status != "stopped" {
+		ui.Say("Stopping VM...")
+		upid, err := client.VMAction(node, vmID, "stop")
+		if err == nil {
+			err = WaitForTask(client, node, upid, timeout)
+		}
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error stopping VM: %s", err))
+		}
+	}
+
+	ui.Say("Removing VM...")
+	upid, err := client.DeleteVM(node, vmID)
+	if err == nil {
+		err = WaitForTask(client, node, upid, timeout)
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error removing VM. Please remove it manually: %s", err))
+	}
+}
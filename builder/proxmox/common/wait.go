@@ -0,0 +1,58 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// WaitForTask polls the status of a task until it stops, and returns an
+// error unless it succeeded. Calls that are done synchronously by the node
+// return no task, there is nothing to wait for then.
+func WaitForTask(client *Client, node string, upid string, timeout time.Duration) error {
+	if upid == "" {
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		task, err := client.GetTaskStatus(node, upid)
+		if err != nil {
+			return err
+		}
+
+		if task.Status == "stopped" {
+			if task.ExitStatus != "OK" {
+				return fmt.Errorf("task %s failed: %s", upid, task.ExitStatus)
+			}
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timeout while waiting for task %s to finish", upid)
+		}
+		log.Printf("Task %s is %s, waiting to finish...", upid, task.Status)
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// WaitForStatus polls the status of a VM until it is the desired one.
+func WaitForStatus(client *Client, node string, vmID int, desiredStatus string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		vm, err := client.GetVMStatus(node, vmID)
+		if err != nil {
+			return err
+		}
+
+		if vm.Status == desiredStatus {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timeout while waiting for VM %d to become %s", vmID, desiredStatus)
+		}
+		log.Printf("VM %d is %s, waiting to become %s...", vmID, vm.Status, desiredStatus)
+		time.Sleep(5 * time.Second)
+	}
+}
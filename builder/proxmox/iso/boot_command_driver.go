@@ -0,0 +1,193 @@
+package iso
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/bootcommand"
+)
+
+// qemuSpecialKeys are the names of the special keys of boot commands in the
+// key syntax of QEMU, which the sendkey call of the API takes.
+var qemuSpecialKeys = map[string]string{
+	"bs":         "backspace",
+	"del":        "delete",
+	"down":       "down",
+	"end":        "end",
+	"enter":      "ret",
+	"esc":        "esc",
+	"f1":         "f1",
+	"f2":         "f2",
+	"f3":         "f3",
+	"f4":         "f4",
+	"f5":         "f5",
+	"f6":         "f6",
+	"f7":         "f7",
+	"f8":         "f8",
+	"f9":         "f9",
+	"f10":        "f10",
+	"f11":        "f11",
+	"f12":        "f12",
+	"home":       "home",
+	"insert":     "insert",
+	"left":       "left",
+	"leftalt":    "alt",
+	"leftctrl":   "ctrl",
+	"leftshift":  "shift",
+	"leftsuper":  "meta_l",
+	"menu":       "menu",
+	"pagedown":   "pgdn",
+	"pageup":     "pgup",
+	"return":     "ret",
+	"right":      "right",
+	"rightalt":   "alt_r",
+	"rightctrl":  "ctrl_r",
+	"rightshift": "shift_r",
+	"rightsuper": "meta_r",
+	"spacebar":   "spc",
+	"tab":        "tab",
+	"up":         "up",
+}
+
+// qemuCharKeys are the keys of the characters that aren't typed with a
+// letter or a digit, with the characters typed with shift after them.
+var qemuCharKeys = map[rune]string{
+	'-':  "minus",
+	'=':  "equal",
+	'[':  "bracket_left",
+	']':  "bracket_right",
+	'\\': "backslash",
+	';':  "semicolon",
+	'\'': "apostrophe",
+	'`':  "grave_accent",
+	',':  "comma",
+	'.':  "dot",
+	'/':  "slash",
+	' ':  "spc",
+	'\n': "ret",
+	'\t': "tab",
+
+	'_': "shift-minus",
+	'+': "shift-equal",
+	'{': "shift-bracket_left",
+	'}': "shift-bracket_right",
+	'|': "shift-backslash",
+	':': "shift-semicolon",
+	'"': "shift-apostrophe",
+	'~': "shift-grave_accent",
+	'<': "shift-comma",
+	'>': "shift-dot",
+	'?': "shift-slash",
+	'!': "shift-1",
+	'@': "shift-2",
+	'#': "shift-3",
+	'$': "shift-4",
+	'%': "shift-5",
+	'^': "shift-6",
+	'&': "shift-7",
+	'*': "shift-8",
+	'(': "shift-9",
+	')': "shift-0",
+}
+
+// sendKeyDriver types boot commands with the sendkey call of the API, which
+// presses and releases a combination of keys at once. The keys that are held
+// down with <fooOn> are typed in combination with the keys after them, until
+// they are released with <fooOff>.
+type sendKeyDriver struct {
+	sendKey  func(key string) error
+	interval time.Duration
+
+	held   []string
+	buffer []string
+}
+
+func newSendKeyDriver(sendKey func(key string) error) *sendKeyDriver {
+	// We delay (default 100ms) between each key to allow for CPU or network
+	// latency. See PackerKeyEnv for tuning.
+	interval := common.PackerKeyDefault
+	if delay, err := time.ParseDuration(os.Getenv(common.PackerKeyEnv)); err == nil {
+		interval = delay
+	}
+	return &sendKeyDriver{sendKey: sendKey, interval: interval}
+}
+
+func (d *sendKeyDriver) SendKey(key rune, action bootcommand.KeyAction) error {
+	// A character is typed when it is pressed, and can't be held down
+	if action == bootcommand.KeyOff {
+		return nil
+	}
+
+	name, ok := qemuCharKeys[key]
+	switch {
+	case ok:
+	case key < unicode.MaxASCII && (unicode.IsLower(key) || unicode.IsDigit(key)):
+		name = string(key)
+	case key < unicode.MaxASCII && unicode.IsUpper(key):
+		name = "shift-" + string(unicode.ToLower(key))
+	default:
+		return fmt.Errorf("the character %q can't be typed", key)
+	}
+
+	d.send(name)
+	return nil
+}
+
+func (d *sendKeyDriver) SendSpecial(special string, action bootcommand.KeyAction) error {
+	name, ok := qemuSpecialKeys[special]
+	if !ok {
+		return fmt.Errorf("special %s not found.", special)
+	}
+
+	switch action {
+	case bootcommand.KeyOn:
+		d.held = append(d.held, name)
+	case bootcommand.KeyOff:
+		for i, held := range d.held {
+			if held == name {
+				d.held = append(d.held[:i], d.held[i+1:]...)
+				break
+			}
+		}
+	case bootcommand.KeyPress:
+		d.send(name)
+	}
+	return nil
+}
+
+// send stores the key, in combination with the keys that are held down, in
+// an internal buffer. Use Flush to send them.
+func (d *sendKeyDriver) send(name string) {
+	keys := append(append([]string{}, d.held...), strings.Split(name, "-")...)
+
+	// shift may be held down for an upper case letter already
+	var combination []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			combination = append(combination, key)
+		}
+	}
+	d.buffer = append(d.buffer, strings.Join(combination, "-"))
+}
+
+// Flush sends the keys that are buffered.
+func (d *sendKeyDriver) Flush() error {
+	defer func() {
+		d.buffer = nil
+	}()
+	for _, key := range d.buffer {
+		log.Printf("Sending key %s", key)
+		if err := d.sendKey(key); err != nil {
+			return err
+		}
+		time.Sleep(d.interval)
+	}
+	return nil
+}
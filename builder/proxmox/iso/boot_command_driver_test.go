@@ -0,0 +1,34 @@
+package iso
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/common/bootcommand"
+)
+
+func TestSendKeyDriver(t *testing.T) {
+	var keys []string
+	d := newSendKeyDriver(func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	d.interval = 0
+
+	seq, err := bootcommand.GenerateExpressionSequence("aB:/ <enter><leftCtrlOn><leftAltOn><del><leftAltOff>c<leftCtrlOff><leftShiftOn>D<leftShiftOff>")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := seq.Do(context.Background(), d); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"a", "shift-b", "shift-semicolon", "slash", "spc", "ret",
+		"ctrl-alt-delete", "ctrl-c", "shift-d",
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
@@ -0,0 +1,206 @@
+package iso
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"proxmox_url":  "https://pve.example.com:8006/api2/json",
+		"username":     "root@pam",
+		"password":     "secret",
+		"node":         "pve",
+		"iso_file":     "local:iso/debian-10.iso",
+		"ssh_username": "root",
+		"disks": []map[string]interface{}{
+			{"storage_pool": "local-lvm"},
+		},
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare_InvalidKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Add a random key
+	config["i_should_not_be_valid"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	warnings, err := b.Prepare(testConfig())
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.TemplateName != b.config.VMName {
+		t.Errorf("bad template name: %s", b.config.TemplateName)
+	}
+	disk := b.config.Disks[0]
+	if disk.Type != "scsi" || disk.SizeGB != 20 {
+		t.Errorf("bad disk: %#v", disk)
+	}
+	if len(b.config.NICs) != 1 || b.config.NICs[0] != (NICConfig{Model: "virtio", Bridge: "vmbr0"}) {
+		t.Errorf("bad network adapters: %#v", b.config.NICs)
+	}
+}
+
+func TestBuilderPrepare_Required(t *testing.T) {
+	for _, key := range []string{"proxmox_url", "username", "password", "node", "iso_file", "disks"} {
+		var b Builder
+		config := testConfig()
+		delete(config, key)
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error without %s", key)
+		}
+	}
+}
+
+func TestBuilderPrepare_Auth(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["token"] = "e2d5b7f8-1f2b-4f5e-9f0a-000000000000"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with a password and a token")
+	}
+
+	delete(config, "password")
+	config["username"] = "root@pam!packer"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_Disks(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["disks"] = []map[string]interface{}{
+		{"type": "nvme", "storage_pool": "local-lvm"},
+	}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with a bad type")
+	}
+
+	config["disks"] = []map[string]interface{}{
+		{"type": "ide", "storage_pool": "local-lvm"},
+		{"type": "ide", "storage_pool": "local-lvm"},
+		{"type": "ide", "storage_pool": "local-lvm"},
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with an ide disk on the drive of the ISO")
+	}
+}
+
+func TestBuilderPrepare_CloudInit(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["cloud_init_user"] = "debian"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without cloud_init")
+	}
+
+	config["cloud_init"] = true
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without the storage pool")
+	}
+
+	config["cloud_init_storage_pool"] = "local-lvm"
+	config["cloud_init_ipconfig"] = []string{"ip=dhcp", "ip=dhcp"}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with more ipconfig than network adapters")
+	}
+
+	config["cloud_init_ipconfig"] = []string{"ip=dhcp"}
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestVMParams(t *testing.T) {
+	c := &Config{
+		VMName:         "packer",
+		Memory:         2048,
+		Cores:          2,
+		Sockets:        1,
+		OS:             "l26",
+		SCSIController: "virtio-scsi-pci",
+		Pool:           "templates",
+		ISOFile:        "local:iso/debian-10.iso",
+		Disks: []DiskConfig{
+			{Type: "virtio", StoragePool: "local-lvm", SizeGB: 20, Format: "raw"},
+			{Type: "scsi", StoragePool: "ceph", SizeGB: 50, CacheMode: "writeback"},
+			{Type: "virtio", StoragePool: "local-lvm", SizeGB: 10},
+		},
+		NICs: []NICConfig{
+			{Model: "virtio", Bridge: "vmbr0"},
+			{Model: "e1000", Bridge: "vmbr1", VLANTag: 10, MACAddress: "AA:BB:CC:DD:EE:FF", Firewall: true},
+		},
+	}
+
+	params := vmParams(c, 100)
+	expected := map[string]string{
+		"vmid":    "100",
+		"pool":    "templates",
+		"ide2":    "local:iso/debian-10.iso,media=cdrom",
+		"virtio0": "local-lvm:20,format=raw",
+		"scsi0":   "ceph:50,cache=writeback",
+		"virtio1": "local-lvm:10",
+		"net0":    "virtio,bridge=vmbr0",
+		"net1":    "e1000=AA:BB:CC:DD:EE:FF,bridge=vmbr1,tag=10,firewall=1",
+		"boot":    "order=virtio0;ide2;net0",
+	}
+	for key, value := range expected {
+		if params.Get(key) != value {
+			t.Errorf("bad %s: %q", key, params.Get(key))
+		}
+	}
+	if _, ok := params["cpu"]; ok {
+		t.Errorf("the CPU type should be the default one: %s", params.Get("cpu"))
+	}
+}
+
+func TestFinalParams(t *testing.T) {
+	c := &Config{UnmountISO: true}
+	c.CloudInit = true
+	c.CloudInitStoragePool = "local-lvm"
+
+	params := finalParams(c)
+	expected := map[string]string{
+		"ide2": "none,media=cdrom",
+		"ide3": "local-lvm:cloudinit",
+	}
+	for key, value := range expected {
+		if params.Get(key) != value {
+			t.Errorf("bad %s: %q", key, params.Get(key))
+		}
+	}
+
+	if params := finalParams(&Config{}); len(params) > 0 {
+		t.Fatalf("the VM should be left as is: %#v", params)
+	}
+}
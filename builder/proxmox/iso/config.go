@@ -0,0 +1,189 @@
+package iso
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	proxmoxcommon "github.com/hashicorp/packer/builder/proxmox/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// DiskConfig is a disk of the VM, created empty in a storage pool.
+type DiskConfig struct {
+	Type        string `mapstructure:"type"`
+	StoragePool string `mapstructure:"storage_pool"`
+	SizeGB      int    `mapstructure:"disk_size_gb"`
+	Format      string `mapstructure:"format"`
+	CacheMode   string `mapstructure:"cache_mode"`
+}
+
+// NICConfig is a network adapter of the VM, on a bridge of the node.
+type NICConfig struct {
+	Model      string `mapstructure:"model"`
+	Bridge     string `mapstructure:"bridge"`
+	VLANTag    int    `mapstructure:"vlan_tag"`
+	MACAddress string `mapstructure:"mac_address"`
+	Firewall   bool   `mapstructure:"firewall"`
+}
+
+type Config struct {
+	common.PackerConfig           `mapstructure:",squash"`
+	common.HTTPConfig             `mapstructure:",squash"`
+	bootcommand.BootConfig        `mapstructure:",squash"`
+	proxmoxcommon.ConnectConfig   `mapstructure:",squash"`
+	proxmoxcommon.CloudInitConfig `mapstructure:",squash"`
+	proxmoxcommon.TemplateConfig  `mapstructure:",squash"`
+	Comm                          communicator.Config `mapstructure:",squash"`
+
+	VMID           int          `mapstructure:"vm_id"`
+	VMName         string       `mapstructure:"vm_name"`
+	Memory         int          `mapstructure:"memory"`
+	Cores          int          `mapstructure:"cores"`
+	Sockets        int          `mapstructure:"sockets"`
+	CPUType        string       `mapstructure:"cpu_type"`
+	OS             string       `mapstructure:"os"`
+	SCSIController string       `mapstructure:"scsi_controller"`
+	Disks          []DiskConfig `mapstructure:"disks"`
+	NICs           []NICConfig  `mapstructure:"network_adapters"`
+	ISOFile        string       `mapstructure:"iso_file"`
+	UnmountISO     bool         `mapstructure:"unmount_iso"`
+
+	StateTimeout time.Duration `mapstructure:"state_timeout"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"boot_command",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.VMName == "" {
+		// Default to packer-[time-ordered-uuid]
+		c.VMName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	}
+	if c.Memory == 0 {
+		c.Memory = 2048
+	}
+	if c.Cores == 0 {
+		c.Cores = 1
+	}
+	if c.Sockets == 0 {
+		c.Sockets = 1
+	}
+	if c.OS == "" {
+		c.OS = "l26"
+	}
+	if c.SCSIController == "" {
+		c.SCSIController = "virtio-scsi-pci"
+	}
+	for i := range c.Disks {
+		if c.Disks[i].Type == "" {
+			c.Disks[i].Type = "scsi"
+		}
+		if c.Disks[i].SizeGB == 0 {
+			c.Disks[i].SizeGB = 20
+		}
+	}
+	if len(c.NICs) == 0 {
+		c.NICs = []NICConfig{{}}
+	}
+	for i := range c.NICs {
+		if c.NICs[i].Model == "" {
+			c.NICs[i].Model = "virtio"
+		}
+		if c.NICs[i].Bridge == "" {
+			c.NICs[i].Bridge = "vmbr0"
+		}
+	}
+
+	if c.StateTimeout == 0 {
+		c.StateTimeout = 10 * time.Minute
+	}
+
+	var errs *packer.MultiError
+	errs = packer.MultiErrorAppend(errs, c.HTTPConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.BootConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.ConnectConfig.Prepare(&c.ctx)...)
+	// The template gets a new drive
+	errs = packer.MultiErrorAppend(errs, c.CloudInitConfig.Prepare(&c.ctx, true)...)
+	errs = packer.MultiErrorAppend(errs, c.TemplateConfig.Prepare(&c.ctx, c.VMName)...)
+	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if c.BootKeyboardLayout != "" && c.BootKeyboardLayout != bootcommand.DefaultKeyboardLayout {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("boot_keyboard_layout is not supported by this builder: %s", c.BootKeyboardLayout))
+	}
+
+	if c.VMID < 0 {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("vm_id must be positive: %d", c.VMID))
+	}
+
+	if c.ISOFile == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("iso_file is required"))
+	}
+
+	if len(c.Disks) == 0 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("at least one disk is required"))
+	}
+	ideDisks := 0
+	for i, disk := range c.Disks {
+		switch disk.Type {
+		case "ide":
+			ideDisks++
+		case "sata", "scsi", "virtio":
+		default:
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("disks[%d]: type must be one of ide, sata, scsi or virtio: %s", i, disk.Type))
+		}
+		if disk.StoragePool == "" {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("disks[%d]: storage_pool is required", i))
+		}
+		if disk.SizeGB < 0 {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("disks[%d]: disk_size_gb must be positive: %d", i, disk.SizeGB))
+		}
+	}
+	// The ISO and the cloud-init drive are on ide2 and ide3
+	if ideDisks > 2 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("at most two disks can be of type ide"))
+	}
+
+	if len(c.CloudInitIPConfig) > len(c.NICs) {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("cloud_init_ipconfig can't have more entries than network_adapters"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	common.ScrubConfig(c, c.Password, c.Token, c.CloudInitPassword)
+	return c, nil, nil
+}
@@ -0,0 +1,139 @@
+package iso
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	proxmoxcommon "github.com/hashicorp/packer/builder/proxmox/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// isoDrive is the drive of the ISO, which ide disks are numbered not to clash
+// with, like the cloud-init drive.
+const isoDrive = "ide2"
+
+type stepCreateVM struct {
+	vmID int
+}
+
+func (s *stepCreateVM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*proxmoxcommon.Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+
+	vmID := c.VMID
+	if vmID == 0 {
+		id, err := client.NextID()
+		if err != nil {
+			err := fmt.Errorf("Error getting a free VM ID: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		vmID = id
+	}
+
+	ui.Say(fmt.Sprintf("Creating VM %s (ID: %d) on %s...", c.VMName, vmID, c.Node))
+	upid, err := client.CreateVM(c.Node, vmParams(c, vmID))
+	if err == nil {
+		// We use this in cleanup
+		s.vmID = vmID
+		err = proxmoxcommon.WaitForTask(client, c.Node, upid, c.StateTimeout)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error creating VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("vm_id", vmID)
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateVM) Cleanup(state multistep.StateBag) {
+	// If the VM ID isn't there, we probably never created it
+	if s.vmID == 0 {
+		return
+	}
+
+	c := state.Get("config").(*Config)
+	proxmoxcommon.RemoveVM(state, c.Node, s.vmID, c.StateTimeout)
+}
+
+// vmParams are the parameters of the creation of the VM, which boots the ISO
+// until an operating system is installed on its first disk.
+func vmParams(c *Config, vmID int) url.Values {
+	params := url.Values{
+		"vmid":    {strconv.Itoa(vmID)},
+		"name":    {c.VMName},
+		"memory":  {strconv.Itoa(c.Memory)},
+		"cores":   {strconv.Itoa(c.Cores)},
+		"sockets": {strconv.Itoa(c.Sockets)},
+		"ostype":  {c.OS},
+		"scsihw":  {c.SCSIController},
+		"agent":   {"1"},
+		isoDrive:  {c.ISOFile + ",media=cdrom"},
+	}
+	if c.CPUType != "" {
+		params.Set("cpu", c.CPUType)
+	}
+	if c.Pool != "" {
+		params.Set("pool", c.Pool)
+	}
+
+	var firstDisk string
+	indexes := make(map[string]int)
+	for _, disk := range c.Disks {
+		// At most two ide disks are allowed, on ide0 and ide1
+		index := indexes[disk.Type]
+		indexes[disk.Type] = index + 1
+
+		name := fmt.Sprintf("%s%d", disk.Type, index)
+		if firstDisk == "" {
+			firstDisk = name
+		}
+		params.Set(name, diskParam(disk))
+	}
+
+	for i, nic := range c.NICs {
+		params.Set(fmt.Sprintf("net%d", i), nicParam(nic))
+	}
+
+	// The empty disk doesn't boot, until the installation
+	params.Set("boot", fmt.Sprintf("order=%s;%s;net0", firstDisk, isoDrive))
+	return params
+}
+
+// diskParam is the parameter of a new disk, allocated in its storage pool.
+func diskParam(disk DiskConfig) string {
+	opts := []string{fmt.Sprintf("%s:%d", disk.StoragePool, disk.SizeGB)}
+	if disk.Format != "" {
+		opts = append(opts, "format="+disk.Format)
+	}
+	if disk.CacheMode != "" {
+		opts = append(opts, "cache="+disk.CacheMode)
+	}
+	return strings.Join(opts, ",")
+}
+
+// nicParam is the parameter of a network adapter, with a generated MAC
+// address unless there is one.
+func nicParam(nic NICConfig) string {
+	model := nic.Model
+	if nic.MACAddress != "" {
+		model += "=" + nic.MACAddress
+	}
+	opts := []string{model, "bridge=" + nic.Bridge}
+	if nic.VLANTag != 0 {
+		opts = append(opts, fmt.Sprintf("tag=%d", nic.VLANTag))
+	}
+	if nic.Firewall {
+		opts = append(opts, "firewall=1")
+	}
+	return strings.Join(opts, ",")
+}
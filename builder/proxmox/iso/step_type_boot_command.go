@@ -0,0 +1,128 @@
+package iso
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	proxmoxcommon "github.com/hashicorp/packer/builder/proxmox/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type bootCommandTemplateData struct {
+	HTTPIP   string
+	HTTPPort uint
+	Name     string
+}
+
+type stepTypeBootCommand struct {
+	BootCommand string
+	BootWait    time.Duration
+	Ctx         interpolate.Context
+}
+
+func (s *stepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*proxmoxcommon.Client)
+	c := state.Get("config").(*Config)
+	debug := state.Get("debug").(bool)
+	httpPort := state.Get("http_port").(uint)
+	ui := state.Get("ui").(packer.Ui)
+	vmID := state.Get("vm_id").(int)
+
+	if s.BootCommand == "" {
+		return multistep.ActionContinue
+	}
+
+	// Wait the for the vm to boot.
+	if int64(s.BootWait) > 0 {
+		ui.Say(fmt.Sprintf("Waiting %s for boot...", s.BootWait.String()))
+		select {
+		case <-time.After(s.BootWait):
+			break
+		case <-ctx.Done():
+			return multistep.ActionHalt
+		}
+	}
+
+	var pauseFn multistep.DebugPauseFn
+	if debug {
+		pauseFn = state.Get("pauseFn").(multistep.DebugPauseFn)
+	}
+
+	hostIP, err := localIP(c.ProxmoxURL)
+	if err != nil {
+		err := fmt.Errorf("Error finding the IP address of the HTTP server: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	common.SetHTTPIP(hostIP)
+	s.Ctx.Data = &bootCommandTemplateData{
+		hostIP,
+		httpPort,
+		c.VMName,
+	}
+
+	d := newSendKeyDriver(func(key string) error {
+		return client.SendKey(c.Node, vmID, key)
+	})
+
+	ui.Say("Typing the boot command...")
+	command, err := interpolate.Render(s.BootCommand, &s.Ctx)
+	if err != nil {
+		err := fmt.Errorf("Error preparing boot command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	seq, err := bootcommand.GenerateExpressionSequence(command)
+	if err != nil {
+		err := fmt.Errorf("Error generating boot command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if err := seq.Do(ctx, d); err != nil {
+		err := fmt.Errorf("Error running boot command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if pauseFn != nil {
+		pauseFn(multistep.DebugLocationAfterRun, fmt.Sprintf("boot_command: %s", command), state)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (*stepTypeBootCommand) Cleanup(multistep.StateBag) {}
+
+// localIP returns the IP address Packer reaches the Proxmox host from, which
+// the VMs on its bridges most likely reach the HTTP server at.
+func localIP(proxmoxURL string) (string, error) {
+	u, err := url.Parse(proxmoxURL)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "8006"
+	}
+
+	// UDP doesn't send anything to connect
+	conn, err := net.Dial("udp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
@@ -0,0 +1,82 @@
+package qemu
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// hostAccelerator returns the hardware accelerator available on the host,
+// or "" if there is none, with the reason why. It is a variable so that
+// tests can pretend to run on another host.
+var hostAccelerator = func() (string, string) {
+	switch runtime.GOOS {
+	case "linux":
+		// /dev/kvm is a kernel module that may be loaded if kvm is
+		// installed and the host supports VT-x extensions. To make sure
+		// this will actually work we need to os.Open() it. If os.Open fails
+		// the kernel module was not installed or loaded correctly.
+		fp, err := os.Open("/dev/kvm")
+		if err != nil {
+			return "", fmt.Sprintf("/dev/kvm can't be opened: %s", err)
+		}
+		fp.Close()
+		return "kvm", "/dev/kvm is available"
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "kern.hv_support").Output()
+		if err != nil || strings.TrimSpace(string(out)) != "1" {
+			return "", "the Hypervisor framework isn't supported by this Mac"
+		}
+		return "hvf", "the Hypervisor framework is supported"
+	case "windows":
+		// The library is only installed with the Windows feature
+		dll := filepath.Join(os.Getenv("SystemRoot"), "System32", "WinHvPlatform.dll")
+		if _, err := os.Stat(dll); err != nil {
+			return "", "the Windows Hypervisor Platform feature isn't enabled"
+		}
+		return "whpx", "the Windows Hypervisor Platform feature is enabled"
+	}
+	return "", fmt.Sprintf("there is no known hardware accelerator on %s", runtime.GOOS)
+}
+
+// binaryAccelerators returns the accelerators a qemu binary was built with,
+// or nil if it can't tell, as when the binary isn't installed.
+func binaryAccelerators(binary string) map[string]bool {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil
+	}
+	out, err := exec.Command(path, "-accel", "help").Output()
+	if err != nil {
+		return nil
+	}
+
+	// The accelerators are listed one per line after a header
+	accels := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasSuffix(line, ":") {
+			accels[line] = true
+		}
+	}
+	return accels
+}
+
+// detectAccelerator returns the best accelerator to run guests of the host
+// architecture with, falling back to tcg, with the reason it was chosen.
+func detectAccelerator(binary string) (string, string) {
+	accel, reason := hostAccelerator()
+	if accel == "" {
+		return "tcg", reason
+	}
+	if accels := binaryAccelerators(binary); accels != nil && !accels[accel] {
+		return "tcg", fmt.Sprintf("%s, but %s wasn't built with the %s accelerator", reason, binary, accel)
+	}
+	return accel, reason
+}
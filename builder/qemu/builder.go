@@ -7,11 +7,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"time"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/common/guestip"
+	"github.com/hashicorp/packer/common/imagemount"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/helper/multistep"
@@ -28,6 +29,7 @@ var accels = map[string]struct{}{
 	"xen":  {},
 	"hax":  {},
 	"hvf":  {},
+	"whpx": {},
 }
 
 var netDevice = map[string]bool{
@@ -57,6 +59,7 @@ var netDevice = map[string]bool{
 
 var diskInterface = map[string]bool{
 	"ide":         true,
+	"nvme":        true,
 	"scsi":        true,
 	"virtio":      true,
 	"virtio-scsi": true,
@@ -75,18 +78,50 @@ var diskDiscard = map[string]bool{
 	"ignore": true,
 }
 
+// AdditionalDisk is a disk attached to the VM after the main one, blank or
+// copied from an image.
+type AdditionalDisk struct {
+	Size      uint   `mapstructure:"size"`
+	Format    string `mapstructure:"format"`
+	Interface string `mapstructure:"interface"`
+	Source    string `mapstructure:"source"`
+}
+
+// ConvertFormat is a format the disk is also converted to once it is built.
+type ConvertFormat struct {
+	Format   string   `mapstructure:"format"`
+	Compress bool     `mapstructure:"compress"`
+	Options  []string `mapstructure:"options"`
+	Name     string   `mapstructure:"name"`
+}
+
+// convertExtensions are the formats the disk can be converted to, with the
+// extension of their files.
+var convertExtensions = map[string]string{
+	"qcow2": "qcow2",
+	"raw":   "raw",
+	"vmdk":  "vmdk",
+	"vhdx":  "vhdx",
+	"vpc":   "vhd",
+}
+
 type Builder struct {
 	config Config
 	runner multistep.Runner
 }
 
 type Config struct {
-	common.PackerConfig   `mapstructure:",squash"`
-	common.HTTPConfig     `mapstructure:",squash"`
-	common.ISOConfig      `mapstructure:",squash"`
-	bootcommand.VNCConfig `mapstructure:",squash"`
-	Comm                  communicator.Config `mapstructure:",squash"`
-	common.FloppyConfig   `mapstructure:",squash"`
+	common.PackerConfig          `mapstructure:",squash"`
+	common.HTTPConfig            `mapstructure:",squash"`
+	common.ISOConfig             `mapstructure:",squash"`
+	bootcommand.VNCConfig        `mapstructure:",squash"`
+	Comm                         communicator.Config `mapstructure:",squash"`
+	common.FloppyConfig          `mapstructure:",squash"`
+	common.CDConfig              `mapstructure:",squash"`
+	common.IsolatedNetworkConfig `mapstructure:",squash"`
+	common.DeltaLayerConfig      `mapstructure:",squash"`
+	GuestIPConfig                guestip.Config    `mapstructure:",squash"`
+	ImageMount                   imagemount.Config `mapstructure:",squash"`
 
 	ISOSkipCache      bool       `mapstructure:"iso_skip_cache"`
 	Accelerator       string     `mapstructure:"accelerator"`
@@ -99,9 +134,14 @@ type Config struct {
 	Format            string     `mapstructure:"format"`
 	Headless          bool       `mapstructure:"headless"`
 	DiskImage         bool       `mapstructure:"disk_image"`
+	CPUModel          string     `mapstructure:"cpu_model"`
+	EFIBoot           bool       `mapstructure:"efi_boot"`
+	EFIFirmwareCode   string     `mapstructure:"efi_firmware_code"`
+	EFIFirmwareVars   string     `mapstructure:"efi_firmware_vars"`
 	MachineType       string     `mapstructure:"machine_type"`
 	NetDevice         string     `mapstructure:"net_device"`
 	OutputDir         string     `mapstructure:"output_directory"`
+	QemuArch          string     `mapstructure:"qemu_arch"`
 	QemuArgs          [][]string `mapstructure:"qemuargs"`
 	QemuBinary        string     `mapstructure:"qemu_binary"`
 	ShutdownCommand   string     `mapstructure:"shutdown_command"`
@@ -112,6 +152,10 @@ type Config struct {
 	VNCPortMin        uint       `mapstructure:"vnc_port_min"`
 	VNCPortMax        uint       `mapstructure:"vnc_port_max"`
 	VMName            string     `mapstructure:"vm_name"`
+	VTPM              bool       `mapstructure:"vtpm"`
+
+	AdditionalDisks []AdditionalDisk `mapstructure:"additional_disks"`
+	ConvertFormats  []ConvertFormat  `mapstructure:"convert_formats"`
 
 	// These are deprecated, but we keep them around for BC
 	// TODO(@mitchellh): remove
@@ -133,6 +177,8 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"boot_command",
+				"delta_layer_command_wrapper",
+				"host_command_wrapper",
 				"qemuargs",
 			},
 		},
@@ -156,36 +202,95 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.DiskDiscard = "ignore"
 	}
 
+	if b.config.QemuArch == "" {
+		b.config.QemuArch = "x86_64"
+		if b.config.QemuBinary != "" {
+			// A binary whose name doesn't tell, like qemu-kvm, runs guests
+			// of the host
+			b.config.QemuArch = guestArch(b.config.QemuBinary)
+			if b.config.QemuArch == "" {
+				b.config.QemuArch = nativeArch()
+			}
+		}
+	}
+	if _, ok := qemuArchs[b.config.QemuArch]; !ok {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("unrecognized qemu_arch: %s", b.config.QemuArch))
+	}
+
+	if b.config.QemuBinary == "" {
+		b.config.QemuBinary = "qemu-system-" + b.config.QemuArch
+	} else if arch := guestArch(b.config.QemuBinary); arch != "" && arch != b.config.QemuArch {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("qemu_binary %s doesn't run %s guests", b.config.QemuBinary, b.config.QemuArch))
+	}
+
+	emulated := isEmulated(b.config.QemuArch)
 	if b.config.Accelerator == "" {
-		if runtime.GOOS == "windows" {
+		if emulated {
 			b.config.Accelerator = "tcg"
+			warnings = append(warnings, fmt.Sprintf(
+				"%s guests can't be accelerated on this %s host, they are emulated with the "+
+					"tcg accelerator, which is much slower. The timeouts that aren't set "+
+					"are lengthened accordingly.", b.config.QemuArch, hostArch))
 		} else {
-			// /dev/kvm is a kernel module that may be loaded if kvm is
-			// installed and the host supports VT-x extensions. To make sure
-			// this will actually work we need to os.Open() it. If os.Open fails
-			// the kernel module was not installed or loaded correctly.
-			if fp, err := os.Open("/dev/kvm"); err != nil {
-				b.config.Accelerator = "tcg"
-			} else {
-				fp.Close()
-				b.config.Accelerator = "kvm"
-			}
+			var reason string
+			b.config.Accelerator, reason = detectAccelerator(b.config.QemuBinary)
+			log.Printf("%s, the %s accelerator is used", reason, b.config.Accelerator)
 		}
-		log.Printf("use detected accelerator: %s", b.config.Accelerator)
+	} else if emulated && hardwareAccels[b.config.Accelerator] {
+		warnings = append(warnings, fmt.Sprintf(
+			"The %s accelerator can't run %s guests on this %s host, the tcg accelerator "+
+				"is used instead, which is much slower. The timeouts that aren't set are "+
+				"lengthened accordingly.", b.config.Accelerator, b.config.QemuArch, hostArch))
+		b.config.Accelerator = "tcg"
 	} else {
 		log.Printf("use specified accelerator: %s", b.config.Accelerator)
 	}
 
+	if emulated && b.config.Accelerator == "tcg" {
+		b.config.prepareTCG()
+	}
+
 	if b.config.MachineType == "" {
 		b.config.MachineType = "pc"
+		switch b.config.QemuArch {
+		case "aarch64", "arm", "riscv64":
+			// These have no pc machine, virt is their generic one
+			b.config.MachineType = "virt"
+		}
 	}
 
-	if b.config.OutputDir == "" {
-		b.config.OutputDir = fmt.Sprintf("output-%s", b.config.PackerBuildName)
+	if b.config.CPUModel == "" && b.config.QemuArch == "aarch64" {
+		// The default CPU of the virt machine is a 32 bit one
+		b.config.CPUModel = "max"
+		if hardwareAccels[b.config.Accelerator] {
+			b.config.CPUModel = "host"
+		}
 	}
 
-	if b.config.QemuBinary == "" {
-		b.config.QemuBinary = "qemu-system-x86_64"
+	// The virt machines of these have no firmware booting from a CD, so ISOs
+	// boot with UEFI unless qemuargs gives them another firmware
+	if !b.config.EFIBoot && !b.config.DiskImage && b.config.MachineType == "virt" {
+		switch b.config.QemuArch {
+		case "aarch64", "riscv64":
+			firmware := false
+			for _, args := range b.config.QemuArgs {
+				firmware = firmware || (len(args) > 0 && (args[0] == "-bios" || args[0] == "-pflash"))
+			}
+			b.config.EFIBoot = !firmware
+		}
+	}
+
+	errs = packer.MultiErrorAppend(errs, b.config.prepareEFI()...)
+
+	if _, ok := tpmDevices[b.config.QemuArch]; b.config.VTPM && !ok {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("vtpm isn't supported for %s guests", b.config.QemuArch))
+	}
+
+	if b.config.OutputDir == "" {
+		b.config.OutputDir = fmt.Sprintf("output-%s", b.config.PackerBuildName)
 	}
 
 	if b.config.SSHHostPortMin == 0 {
@@ -217,6 +322,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	}
 
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CDConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.VNCConfig.Prepare(&b.config.ctx)...)
 
 	if b.config.NetDevice == "" {
@@ -241,10 +347,53 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, isoErrs...)
 
 	errs = packer.MultiErrorAppend(errs, b.config.HTTPConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.IsolatedNetworkConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.DeltaLayerConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.ImageMount.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.GuestIPConfig.Prepare(guestip.QemuGuestAgent, guestip.ARP)...)
+
+	// User mode networking can't be reached from the host, the guest has
+	// to be attached to another network with qemuargs
+	if len(b.config.GuestIPConfig.GuestIPStrategies) > 0 {
+		netdev := false
+		for _, args := range b.config.QemuArgs {
+			netdev = netdev || (len(args) > 0 && args[0] == "-netdev")
+		}
+		if !netdev {
+			errs = packer.MultiErrorAppend(errs,
+				errors.New("guest_ip_strategies requires a -netdev in qemuargs with the id user.0, "+
+					"attaching the VM to a network reachable from the host"))
+		}
+	}
+
+	if b.config.ImageMount.HostChroot() {
+		// The machine never boots, so there is nothing to connect to.
+		if b.config.Comm.Type == "" {
+			b.config.Comm.Type = "none"
+		}
+		if b.config.Comm.Type != "none" {
+			errs = packer.MultiErrorAppend(errs,
+				errors.New("communicator must be 'none' when provision_mode is 'host-chroot'"))
+		}
+		if !b.config.DiskImage {
+			errs = packer.MultiErrorAppend(errs,
+				errors.New("disk_image must be true when provision_mode is 'host-chroot'"))
+		}
+	}
+
 	if es := b.config.Comm.Prepare(&b.config.ctx); len(es) > 0 {
 		errs = packer.MultiErrorAppend(errs, es...)
 	}
 
+	// The root filesystem is listed with find and stat on the machine
+	if b.config.DeltaLayer {
+		if b.config.Comm.Type == "winrm" ||
+			(b.config.Comm.Type == "none" && !b.config.ImageMount.HostChroot()) {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("delta_layer can't be used with the %s communicator", b.config.Comm.Type))
+		}
+	}
+
 	if !(b.config.Format == "qcow2" || b.config.Format == "raw") {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("invalid format, only 'qcow2' or 'raw' are allowed"))
@@ -257,7 +406,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 	if _, ok := accels[b.config.Accelerator]; !ok {
 		errs = packer.MultiErrorAppend(
-			errs, errors.New("invalid accelerator, only 'kvm', 'tcg', 'xen', 'hax', 'hvf', or 'none' are allowed"))
+			errs, errors.New("invalid accelerator, only 'kvm', 'tcg', 'xen', 'hax', 'hvf', 'whpx', or 'none' are allowed"))
 	}
 
 	if _, ok := netDevice[b.config.NetDevice]; !ok {
@@ -270,6 +419,57 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, errors.New("unrecognized disk interface type"))
 	}
 
+	for i, disk := range b.config.AdditionalDisks {
+		if disk.Format == "" {
+			b.config.AdditionalDisks[i].Format = b.config.Format
+		}
+		if disk.Interface == "" {
+			b.config.AdditionalDisks[i].Interface = b.config.DiskInterface
+		}
+		disk = b.config.AdditionalDisks[i]
+
+		if disk.Size == 0 && disk.Source == "" {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("additional_disks[%d]: size or source is required", i))
+		}
+		if disk.Source != "" {
+			if _, err := os.Stat(disk.Source); err != nil {
+				errs = packer.MultiErrorAppend(errs,
+					fmt.Errorf("additional_disks[%d]: source is invalid: %s", i, err))
+			}
+		}
+		if !(disk.Format == "qcow2" || disk.Format == "raw") {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("additional_disks[%d]: invalid format, only 'qcow2' or 'raw' are allowed", i))
+		}
+		if _, ok := diskInterface[disk.Interface]; !ok {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("additional_disks[%d]: unrecognized disk interface type", i))
+		}
+	}
+
+	names := map[string]bool{b.config.VMName: true}
+	for i, format := range b.config.ConvertFormats {
+		ext, ok := convertExtensions[format.Format]
+		if !ok {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("convert_formats[%d]: format must be one of qcow2, raw, vmdk, vhdx or vpc", i))
+		}
+		if format.Compress && format.Format != "qcow2" {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("convert_formats[%d]: only qcow2 can be compressed", i))
+		}
+		if format.Name == "" {
+			b.config.ConvertFormats[i].Name = fmt.Sprintf("%s.%s", b.config.VMName, ext)
+		}
+		if name := b.config.ConvertFormats[i].Name; names[name] {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("convert_formats[%d]: the name %s is already used, set another one", i, name))
+		} else {
+			names[name] = true
+		}
+	}
+
 	if _, ok := diskCache[b.config.DiskCache]; !ok {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("unrecognized disk cache type"))
@@ -338,6 +538,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	steps := []multistep.Step{}
 	if !b.config.ISOSkipCache {
 		steps = append(steps, &common.StepDownload{
+			Chunks:       b.config.DownloadChunks,
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
 			Description:  "ISO",
@@ -360,13 +561,50 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
 		},
+		&common.StepCreateCD{
+			Files:   b.config.CDConfig.CDFiles,
+			Content: b.config.CDConfig.CDContent,
+			Label:   b.config.CDConfig.CDLabel,
+		},
 		new(stepCreateDisk),
 		new(stepCopyDisk),
 		new(stepResizeDisk),
+		new(stepCreateAdditionalDisks),
+	)
+
+	if b.config.ImageMount.HostChroot() {
+		// Provision the image mounted on the host instead of booting it
+		steps = append(steps,
+			&imagemount.StepMountImage{
+				Config: &b.config.ImageMount,
+				Ctx:    b.config.ctx,
+				Image:  filepath.Join(b.config.OutputDir, b.config.VMName),
+				Format: b.config.Format,
+			},
+			new(common.StepProvision),
+			new(imagemount.StepUnmountImage),
+			new(stepConvertDisk),
+			new(stepConvertFormats),
+		)
+		return b.run(steps, driver, ui, hook, cache)
+	}
+
+	if b.config.EFIBoot {
+		steps = append(steps, new(stepCopyEFIVars))
+	}
+
+	steps = append(steps,
 		&common.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
+			TLS:         b.config.HTTPTLS,
+			TLSCertFile: b.config.HTTPTLSCertFile,
+			TLSKeyFile:  b.config.HTTPTLSKeyFile,
+			Username:    b.config.HTTPUsername,
+			Password:    b.config.HTTPPassword,
+			Templates:   b.config.HTTPTemplates,
+			Ctx:         b.config.ctx,
 		},
 	)
 
@@ -378,6 +616,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	steps = append(steps,
 		new(stepConfigureVNC),
+	)
+
+	if b.config.VTPM {
+		steps = append(steps, new(stepStartTPM))
+	}
+
+	steps = append(steps,
 		steprun,
 		&stepTypeBootCommand{},
 	)
@@ -403,8 +648,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	steps = append(steps,
 		new(stepConvertDisk),
+		new(stepConvertFormats),
 	)
 
+	return b.run(steps, driver, ui, hook, cache)
+}
+
+// run runs the steps and returns the artifact they produced.
+func (b *Builder) run(steps []multistep.Step, driver Driver, ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
 	// Setup the state bag
 	state := new(multistep.BasicStateBag)
 	state.Put("cache", cache)
@@ -414,6 +665,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 
+	deltaLayerPath := ""
+	if b.config.DeltaLayer {
+		deltaLayerPath = filepath.Join(b.config.OutputDir, b.config.VMName+"-delta.tar")
+		state.Put("delta_layer_path", deltaLayerPath)
+		state.Put("delta_layer_exclude", b.config.DeltaLayerExclude)
+		state.Put("delta_layer_command_wrapper", b.config.DeltaLayerCommandWrapper)
+	}
+
 	// Run
 	b.runner = common.NewRunnerWithPauseFn(steps, b.config.PackerConfig, ui, state)
 	b.runner.Run(state)
@@ -438,7 +697,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
+		if !info.IsDir() && path != deltaLayerPath {
 			files = append(files, path)
 		}
 
@@ -460,6 +719,15 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	artifact.state["diskSize"] = uint64(b.config.DiskSize)
 	artifact.state["domainType"] = b.config.Accelerator
 
+	if deltaLayerPath != "" {
+		return &packer.ArtifactSet{
+			Artifact: artifact,
+			Named: map[string]packer.Artifact{
+				"delta": &common.DeltaLayerArtifact{BuilderIdValue: BuilderId, Path: deltaLayerPath},
+			},
+		}, nil
+	}
+
 	return artifact, nil
 }
 
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer/packer"
 )
@@ -525,3 +527,574 @@ func TestBuilderPrepare_QemuArgs(t *testing.T) {
 		t.Fatalf("bad: %#v", b.config.QemuArgs)
 	}
 }
+
+func TestBuilderPrepare_ProvisionMode(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test a bad mode
+	config["provision_mode"] = "nope"
+	_, err := b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test host-chroot without a disk image
+	config["provision_mode"] = "host-chroot"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test host-chroot with a communicator
+	config["disk_image"] = true
+	config["communicator"] = "ssh"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test a good one
+	delete(config, "communicator")
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.Comm.Type != "none" {
+		t.Fatalf("bad communicator: %s", b.config.Comm.Type)
+	}
+}
+
+func TestBuilderPrepare_EmulatedGuest(t *testing.T) {
+	defer func(arch string) { hostArch = arch }(hostArch)
+	hostArch = "arm64"
+
+	// Test an x86 guest, without an accelerator
+	var b Builder
+	config := testConfig()
+	warns, err := b.Prepare(config)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.Accelerator != "tcg" {
+		t.Fatalf("bad accelerator: %s", b.config.Accelerator)
+	}
+	if b.config.BootWait != 40*time.Second {
+		t.Fatalf("bad boot_wait: %s", b.config.BootWait)
+	}
+	if b.config.Comm.SSHTimeout != 20*time.Minute {
+		t.Fatalf("bad ssh_timeout: %s", b.config.Comm.SSHTimeout)
+	}
+	if b.config.shutdownTimeout != 20*time.Minute {
+		t.Fatalf("bad shutdown_timeout: %s", b.config.shutdownTimeout)
+	}
+
+	// Test with a hardware accelerator and set timeouts
+	config["accelerator"] = "hvf"
+	config["boot_wait"] = "5s"
+	config["ssh_timeout"] = "1m"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.Accelerator != "tcg" {
+		t.Fatalf("bad accelerator: %s", b.config.Accelerator)
+	}
+	if b.config.BootWait != 5*time.Second {
+		t.Fatalf("bad boot_wait: %s", b.config.BootWait)
+	}
+	if b.config.Comm.SSHTimeout != time.Minute {
+		t.Fatalf("bad ssh_timeout: %s", b.config.Comm.SSHTimeout)
+	}
+
+	// Test a native guest
+	config["qemu_binary"] = "qemu-system-aarch64"
+	config["qemuargs"] = [][]interface{}{{"-bios", "QEMU_EFI.fd"}}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.Accelerator != "hvf" {
+		t.Fatalf("bad accelerator: %s", b.config.Accelerator)
+	}
+	if b.config.MachineType != "virt" {
+		t.Fatalf("bad machine_type: %s", b.config.MachineType)
+	}
+	if b.config.CPUModel != "host" {
+		t.Fatalf("bad cpu_model: %s", b.config.CPUModel)
+	}
+}
+
+func TestBuilderPrepare_NativeTCG(t *testing.T) {
+	defer func(arch string) { hostArch = arch }(hostArch)
+	hostArch = "amd64"
+
+	var b Builder
+	config := testConfig()
+	config["accelerator"] = "tcg"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.BootWait != 10*time.Second {
+		t.Fatalf("bad boot_wait: %s", b.config.BootWait)
+	}
+	if b.config.Comm.SSHTimeout != 5*time.Minute {
+		t.Fatalf("bad ssh_timeout: %s", b.config.Comm.SSHTimeout)
+	}
+	if b.config.Comm.WinRMTimeout != 0 {
+		t.Fatalf("bad winrm_timeout: %s", b.config.Comm.WinRMTimeout)
+	}
+	if b.config.shutdownTimeout != 5*time.Minute {
+		t.Fatalf("bad shutdown_timeout: %s", b.config.shutdownTimeout)
+	}
+}
+
+func TestBuilderPrepare_QemuArch(t *testing.T) {
+	defer func(arch string) { hostArch = arch }(hostArch)
+	hostArch = "amd64"
+
+	// Test the default
+	var b Builder
+	config := testConfig()
+	_, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.QemuArch != "x86_64" {
+		t.Fatalf("bad qemu_arch: %s", b.config.QemuArch)
+	}
+	if b.config.CPUModel != "" {
+		t.Fatalf("bad cpu_model: %s", b.config.CPUModel)
+	}
+
+	// Test the binary of the architecture
+	config["qemu_arch"] = "aarch64"
+	config["qemuargs"] = [][]interface{}{{"-bios", "QEMU_EFI.fd"}}
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.QemuBinary != "qemu-system-aarch64" {
+		t.Fatalf("bad qemu_binary: %s", b.config.QemuBinary)
+	}
+	if b.config.MachineType != "virt" {
+		t.Fatalf("bad machine_type: %s", b.config.MachineType)
+	}
+	if b.config.CPUModel != "max" {
+		t.Fatalf("bad cpu_model: %s", b.config.CPUModel)
+	}
+
+	// Test a binary of another architecture
+	config["qemu_binary"] = "qemu-system-riscv64"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test a binary that doesn't tell its architecture
+	delete(config, "qemu_arch")
+	config["qemu_binary"] = "qemu-kvm"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.QemuArch != "x86_64" {
+		t.Fatalf("bad qemu_arch: %s", b.config.QemuArch)
+	}
+
+	// Test an unknown architecture
+	config["qemu_arch"] = "mips"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_EFIBoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	code := filepath.Join(dir, "code.fd")
+	vars := filepath.Join(dir, "vars.fd")
+	for _, path := range []string{code, vars} {
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	defer func(firmwares []efiFirmware) { efiFirmwares["x86_64"] = firmwares }(efiFirmwares["x86_64"])
+	efiFirmwares["x86_64"] = []efiFirmware{
+		{filepath.Join(dir, "missing-code.fd"), vars},
+		{code, vars},
+	}
+
+	// Test the firmware without efi_boot
+	var b Builder
+	config := testConfig()
+	config["efi_firmware_code"] = code
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test the code without the vars
+	config["efi_boot"] = true
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test a missing file
+	config["efi_firmware_vars"] = filepath.Join(dir, "missing-vars.fd")
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test the default firmware
+	delete(config, "efi_firmware_code")
+	delete(config, "efi_firmware_vars")
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.EFIFirmwareCode != code {
+		t.Fatalf("bad efi_firmware_code: %s", b.config.EFIFirmwareCode)
+	}
+	if b.config.EFIFirmwareVars != vars {
+		t.Fatalf("bad efi_firmware_vars: %s", b.config.EFIFirmwareVars)
+	}
+
+	// Test without a default firmware
+	efiFirmwares["x86_64"] = nil
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_EFIBootVirt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	code := filepath.Join(dir, "code.fd")
+	vars := filepath.Join(dir, "vars.fd")
+	for _, path := range []string{code, vars} {
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	defer func(firmwares []efiFirmware) { efiFirmwares["aarch64"] = firmwares }(efiFirmwares["aarch64"])
+	efiFirmwares["aarch64"] = []efiFirmware{{code, vars}}
+
+	// Test the default of an ISO
+	var b Builder
+	config := testConfig()
+	config["qemu_arch"] = "aarch64"
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !b.config.EFIBoot {
+		t.Fatal("should boot with UEFI")
+	}
+	if b.config.EFIFirmwareCode != code {
+		t.Fatalf("bad efi_firmware_code: %s", b.config.EFIFirmwareCode)
+	}
+
+	// Test the firmware of qemuargs
+	config["qemuargs"] = [][]interface{}{{"-bios", "u-boot.bin"}}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.EFIBoot {
+		t.Fatal("should not boot with UEFI")
+	}
+
+	// Test another machine
+	delete(config, "qemuargs")
+	config["machine_type"] = "sbsa-ref"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.EFIBoot {
+		t.Fatal("should not boot with UEFI")
+	}
+
+	// Test a disk image
+	delete(config, "machine_type")
+	config["disk_image"] = true
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.EFIBoot {
+		t.Fatal("should not boot with UEFI")
+	}
+}
+
+func TestBuilderPrepare_GuestIPStrategies(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad, user mode networking can't be reached
+	config["guest_ip_strategies"] = []string{"qemu_guest_agent", "arp"}
+	_, err := b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad
+	config["guest_ip_strategies"] = []string{"guest_properties"}
+	config["qemuargs"] = [][]interface{}{
+		{"-netdev", "bridge,id=user.0,br=br0"},
+	}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["guest_ip_strategies"] = []string{"qemu_guest_agent", "arp"}
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_DeltaLayer(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad
+	config["delta_layer"] = true
+	config["communicator"] = "none"
+	_, err := b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad
+	config["communicator"] = "ssh"
+	config["delta_layer_exclude"] = []string{"var/cache"}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["delta_layer_exclude"] = []string{"/var/cache"}
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.DeltaLayer {
+		t.Fatal("delta_layer should be set")
+	}
+}
+
+func TestBuilderPrepare_VTPM(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["vtpm"] = true
+	_, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Bad, riscv has no TPM device
+	config["qemu_arch"] = "riscv64"
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_AdditionalDisks(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	// Good, with the defaults of the main disk
+	var b Builder
+	config := testConfig()
+	config["format"] = "raw"
+	config["additional_disks"] = []map[string]interface{}{
+		{"size": 1024},
+		{"source": tf.Name(), "format": "qcow2", "interface": "nvme"},
+	}
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []AdditionalDisk{
+		{Size: 1024, Format: "raw", Interface: "virtio"},
+		{Source: tf.Name(), Format: "qcow2", Interface: "nvme"},
+	}
+	if !reflect.DeepEqual(b.config.AdditionalDisks, expected) {
+		t.Fatalf("bad: %#v", b.config.AdditionalDisks)
+	}
+
+	// Bad, no size nor source
+	config["additional_disks"] = []map[string]interface{}{{"interface": "virtio-scsi"}}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad, missing source
+	config["additional_disks"] = []map[string]interface{}{{"source": tf.Name() + ".missing"}}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad interface
+	config["additional_disks"] = []map[string]interface{}{{"size": 1024, "interface": "sata"}}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestDetectAccelerator(t *testing.T) {
+	defer func(f func() (string, string)) { hostAccelerator = f }(hostAccelerator)
+
+	// The binary isn't installed, so nothing is known of its accelerators
+	binary := "packer-qemu-system-missing"
+
+	hostAccelerator = func() (string, string) { return "hvf", "supported" }
+	if accel, _ := detectAccelerator(binary); accel != "hvf" {
+		t.Fatalf("bad accelerator: %s", accel)
+	}
+
+	hostAccelerator = func() (string, string) { return "", "unsupported" }
+	accel, reason := detectAccelerator(binary)
+	if accel != "tcg" {
+		t.Fatalf("bad accelerator: %s", accel)
+	}
+	if reason != "unsupported" {
+		t.Fatalf("bad reason: %s", reason)
+	}
+
+	// Test the detected accelerator in the config
+	hostAccelerator = func() (string, string) { return "whpx", "enabled" }
+	var b Builder
+	config := testConfig()
+	config["qemu_binary"] = binary
+	_, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.Accelerator != "whpx" {
+		t.Fatalf("bad accelerator: %s", b.config.Accelerator)
+	}
+}
+
+func TestBuilderPrepare_ConvertFormats(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["vm_name"] = "disk"
+	config["convert_formats"] = []map[string]interface{}{
+		{"format": "qcow2", "compress": true, "name": "disk-compressed.qcow2"},
+		{"format": "vmdk", "options": []string{"subformat=streamOptimized"}},
+		{"format": "vpc"},
+	}
+	_, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []ConvertFormat{
+		{Format: "qcow2", Compress: true, Name: "disk-compressed.qcow2"},
+		{Format: "vmdk", Options: []string{"subformat=streamOptimized"}, Name: "disk.vmdk"},
+		{Format: "vpc", Name: "disk.vhd"},
+	}
+	if !reflect.DeepEqual(b.config.ConvertFormats, expected) {
+		t.Fatalf("bad: %#v", b.config.ConvertFormats)
+	}
+
+	// Bad, only qcow2 is compressed
+	config["convert_formats"] = []map[string]interface{}{{"format": "vhdx", "compress": true}}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad, the same name twice
+	config["convert_formats"] = []map[string]interface{}{{"format": "raw"}, {"format": "raw"}}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad format
+	config["convert_formats"] = []map[string]interface{}{{"format": "vdi"}}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,189 @@
+package qemu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// hostArch is the architecture Packer runs on. It is a variable so that
+// tests can pretend to run on another one.
+var hostArch = runtime.GOARCH
+
+// qemuArchs maps the architectures of the qemu-system-* binaries to the
+// host architectures that can run their guests with a hardware
+// accelerator.
+var qemuArchs = map[string][]string{
+	"x86_64":  {"amd64"},
+	"i386":    {"386", "amd64"},
+	"aarch64": {"arm64"},
+	"arm":     {"arm"},
+	"riscv64": {"riscv64"},
+	"ppc64":   {"ppc64", "ppc64le"},
+	"s390x":   {"s390x"},
+}
+
+// hardwareAccels are the accelerators that run the guest on the host CPU,
+// which can only run guests of its own architecture.
+var hardwareAccels = map[string]bool{
+	"kvm":  true,
+	"xen":  true,
+	"hax":  true,
+	"hvf":  true,
+	"whpx": true,
+}
+
+// tcgTimeoutFactor is how much longer than the defaults the timeouts are
+// when a guest of another architecture is emulated with tcg, which
+// translates every instruction.
+const tcgTimeoutFactor = 4
+
+// guestArch returns the architecture a qemu binary emulates, or "" if it
+// can't be told from its name, as with qemu-kvm.
+func guestArch(binary string) string {
+	name := strings.TrimSuffix(filepath.Base(binary), ".exe")
+	if !strings.HasPrefix(name, "qemu-system-") {
+		return ""
+	}
+	return strings.TrimPrefix(name, "qemu-system-")
+}
+
+// isEmulated returns whether guests of the architecture are of another
+// architecture than the host, so that no hardware accelerator can run them.
+func isEmulated(arch string) bool {
+	archs, ok := qemuArchs[arch]
+	if !ok {
+		return false
+	}
+	for _, a := range archs {
+		if a == hostArch {
+			return false
+		}
+	}
+	return true
+}
+
+// nativeArch returns the architecture of the guests the host runs with a
+// hardware accelerator.
+func nativeArch() string {
+	for arch, archs := range qemuArchs {
+		// amd64 hosts also run i386 guests, but not primarily
+		if archs[0] == hostArch {
+			return arch
+		}
+	}
+	return ""
+}
+
+// prepareTCG lengthens the timeouts that weren't set for an emulated guest,
+// as the defaults are meant for a guest of the host architecture.
+func (c *Config) prepareTCG() {
+	if c.RawBootWait == "" {
+		c.RawBootWait = (tcgTimeoutFactor * 10 * time.Second).String()
+	}
+	if c.Comm.SSHTimeout == 0 && c.SSHWaitTimeout == 0 {
+		c.Comm.SSHTimeout = tcgTimeoutFactor * 5 * time.Minute
+	}
+	if c.Comm.WinRMTimeout == 0 {
+		c.Comm.WinRMTimeout = tcgTimeoutFactor * 30 * time.Minute
+	}
+	if c.RawShutdownTimeout == "" {
+		c.RawShutdownTimeout = (tcgTimeoutFactor * 5 * time.Minute).String()
+	}
+}
+
+// efiFirmware is an edk2 UEFI firmware, with the code flashed read-only and
+// the template of the variables each VM gets a copy of.
+type efiFirmware struct {
+	code string
+	vars string
+}
+
+// qemuDataDirs are where QEMU installs the edk2 firmwares it ships with.
+var qemuDataDirs = []string{
+	"/usr/share/qemu",
+	"/usr/local/share/qemu",
+	"/opt/homebrew/share/qemu",
+}
+
+// efiFirmwares are the edk2 firmwares of each architecture, in the order
+// they are looked for: those of the distribution packages, then those of
+// QEMU.
+var efiFirmwares = map[string][]efiFirmware{
+	"x86_64": append([]efiFirmware{
+		{"/usr/share/OVMF/OVMF_CODE.fd", "/usr/share/OVMF/OVMF_VARS.fd"},
+		{"/usr/share/edk2/ovmf/OVMF_CODE.fd", "/usr/share/edk2/ovmf/OVMF_VARS.fd"},
+	}, qemuFirmwares("x86_64", "i386")...),
+	"aarch64": append([]efiFirmware{
+		{"/usr/share/AAVMF/AAVMF_CODE.fd", "/usr/share/AAVMF/AAVMF_VARS.fd"},
+		{"/usr/share/edk2/aarch64/QEMU_EFI-pflash.raw", "/usr/share/edk2/aarch64/vars-template-pflash.raw"},
+	}, qemuFirmwares("aarch64", "arm")...),
+	"arm": append([]efiFirmware{
+		{"/usr/share/AAVMF/AAVMF32_CODE.fd", "/usr/share/AAVMF/AAVMF32_VARS.fd"},
+		{"/usr/share/edk2/arm/QEMU_EFI-pflash.raw", "/usr/share/edk2/arm/vars-template-pflash.raw"},
+	}, qemuFirmwares("arm", "arm")...),
+	"riscv64": append([]efiFirmware{
+		{"/usr/share/edk2/riscv/RISCV_VIRT_CODE.fd", "/usr/share/edk2/riscv/RISCV_VIRT_VARS.fd"},
+	}, qemuFirmwares("riscv", "riscv")...),
+}
+
+// qemuFirmwares returns the edk2 firmwares QEMU ships with, whose code and
+// variables are named after different architectures.
+func qemuFirmwares(codeArch, varsArch string) []efiFirmware {
+	firmwares := make([]efiFirmware, len(qemuDataDirs))
+	for i, dir := range qemuDataDirs {
+		firmwares[i] = efiFirmware{
+			code: filepath.Join(dir, fmt.Sprintf("edk2-%s-code.fd", codeArch)),
+			vars: filepath.Join(dir, fmt.Sprintf("edk2-%s-vars.fd", varsArch)),
+		}
+	}
+	return firmwares
+}
+
+// prepareEFI validates the firmware the VM boots with efi_boot, defaulting
+// to the first edk2 firmware of the guest architecture found on the host.
+func (c *Config) prepareEFI() []error {
+	if !c.EFIBoot {
+		if c.EFIFirmwareCode != "" || c.EFIFirmwareVars != "" {
+			return []error{fmt.Errorf("efi_firmware_code and efi_firmware_vars require efi_boot")}
+		}
+		return nil
+	}
+
+	if c.EFIFirmwareCode == "" && c.EFIFirmwareVars == "" {
+		for _, firmware := range efiFirmwares[c.QemuArch] {
+			if _, err := os.Stat(firmware.code); err != nil {
+				continue
+			}
+			if _, err := os.Stat(firmware.vars); err != nil {
+				continue
+			}
+			c.EFIFirmwareCode = firmware.code
+			c.EFIFirmwareVars = firmware.vars
+			break
+		}
+		if c.EFIFirmwareCode == "" {
+			return []error{fmt.Errorf(
+				"no edk2 firmware for %s guests found, efi_firmware_code and "+
+					"efi_firmware_vars must be specified", c.QemuArch)}
+		}
+		return nil
+	}
+
+	var errs []error
+	if c.EFIFirmwareCode == "" || c.EFIFirmwareVars == "" {
+		errs = append(errs, fmt.Errorf("efi_firmware_code and efi_firmware_vars must be specified together"))
+	}
+	for _, path := range []string{c.EFIFirmwareCode, c.EFIFirmwareVars} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("EFI firmware file %s is invalid: %s", path, err))
+		}
+	}
+	return errs
+}
@@ -1,6 +1,9 @@
 package qemu
 
 import (
+	"net"
+
+	"github.com/hashicorp/packer/common/guestip"
 	commonssh "github.com/hashicorp/packer/common/ssh"
 	"github.com/hashicorp/packer/communicator/ssh"
 	"github.com/hashicorp/packer/helper/multistep"
@@ -8,10 +11,34 @@ import (
 )
 
 func commHost(state multistep.StateBag) (string, error) {
-	return "127.0.0.1", nil
+	config := state.Get("config").(*Config)
+	if len(config.GuestIPConfig.GuestIPStrategies) == 0 {
+		return "127.0.0.1", nil
+	}
+
+	mac, err := net.ParseMAC(state.Get("guest_mac").(string))
+	if err != nil {
+		return "", err
+	}
+	strategies := map[string]guestip.Strategy{
+		guestip.ARP: &guestip.ARPStrategy{MAC: mac},
+	}
+	if socket, ok := state.GetOk("qga_socket"); ok {
+		strategies[guestip.QemuGuestAgent] = &guestip.QemuGuestAgentStrategy{
+			Socket: socket.(string),
+			MAC:    mac,
+		}
+	}
+	return guestip.Discover(config.GuestIPConfig.GuestIPStrategies, strategies)
 }
 
 func commPort(state multistep.StateBag) (int, error) {
+	// The guest is reached directly on its own address
+	config := state.Get("config").(*Config)
+	if len(config.GuestIPConfig.GuestIPStrategies) > 0 {
+		return config.Comm.Port(), nil
+	}
+
 	sshHostPort := state.Get("sshHostPort").(uint)
 	return int(sshHostPort), nil
 }
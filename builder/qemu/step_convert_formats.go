@@ -0,0 +1,46 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step converts the built disk into each of the additional formats,
+// next to it in the output directory.
+type stepConvertFormats struct{}
+
+func (s *stepConvertFormats) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	diskName := state.Get("disk_filename").(string)
+	ui := state.Get("ui").(packer.Ui)
+
+	sourcePath := filepath.Join(config.OutputDir, diskName)
+	for _, format := range config.ConvertFormats {
+		command := []string{"convert", "-f", config.Format, "-O", format.Format}
+		if format.Compress {
+			command = append(command, "-c")
+		}
+		if len(format.Options) > 0 {
+			command = append(command, "-o", strings.Join(format.Options, ","))
+		}
+		command = append(command, sourcePath, filepath.Join(config.OutputDir, format.Name))
+
+		ui.Say(fmt.Sprintf("Converting hard drive to %s...", format.Name))
+		if err := driver.QemuImg(command...); err != nil {
+			err := fmt.Errorf("Error converting hard drive to %s: %s", format.Format, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConvertFormats) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,54 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step copies the template of the UEFI variables into the output
+// directory, so that the VM has its own NVRAM to keep its boot entries in.
+type stepCopyEFIVars struct{}
+
+func (s *stepCopyEFIVars) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	path := filepath.Join(config.OutputDir, fmt.Sprintf("%s-efivars.fd", config.VMName))
+
+	ui.Say("Copying UEFI variables...")
+	if err := copyFile(config.EFIFirmwareVars, path); err != nil {
+		err := fmt.Errorf("Error copying UEFI variables: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("efivars_path", path)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCopyEFIVars) Cleanup(state multistep.StateBag) {}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,54 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step creates the additional disks of the virtual machine, blank or
+// copied from their source image.
+type stepCreateAdditionalDisks struct{}
+
+func (s *stepCreateAdditionalDisks) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	for i, disk := range config.AdditionalDisks {
+		path := additionalDiskPath(config, i)
+
+		var commands [][]string
+		if disk.Source != "" {
+			commands = append(commands, []string{"convert", "-O", disk.Format, disk.Source, path})
+			if disk.Size != 0 {
+				commands = append(commands, []string{"resize", "-f", disk.Format, path, fmt.Sprintf("%vM", disk.Size)})
+			}
+		} else {
+			commands = append(commands, []string{"create", "-f", disk.Format, path, fmt.Sprintf("%vM", disk.Size)})
+		}
+
+		ui.Say(fmt.Sprintf("Creating additional hard drive %d...", i+1))
+		for _, command := range commands {
+			if err := driver.QemuImg(command...); err != nil {
+				err := fmt.Errorf("Error creating additional hard drive %d: %s", i+1, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateAdditionalDisks) Cleanup(state multistep.StateBag) {}
+
+// additionalDiskPath is the path of an additional disk, named after the
+// main one with its number.
+func additionalDiskPath(config *Config, i int) string {
+	return filepath.Join(config.OutputDir, fmt.Sprintf("%s-%d", config.VMName, i+1))
+}
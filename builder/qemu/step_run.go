@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math/rand"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/hashicorp/packer/common/guestip"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -57,6 +60,10 @@ func (s *stepRun) Cleanup(state multistep.StateBag) {
 	if err := driver.Stop(); err != nil {
 		ui.Error(fmt.Sprintf("Error shutting down VM: %s", err))
 	}
+
+	if socket, ok := state.GetOk("qga_socket"); ok {
+		os.Remove(socket.(string))
+	}
 }
 
 func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error) {
@@ -72,18 +79,25 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 	imgPath := filepath.Join(config.OutputDir, vmName)
 
 	defaultArgs := make(map[string]interface{})
+	var chardevArgs []string
 	var deviceArgs []string
 	var driveArgs []string
 	var sshHostPort uint
 
 	defaultArgs["-name"] = vmName
 	defaultArgs["-machine"] = fmt.Sprintf("type=%s", config.MachineType)
+	netdev := "user,id=user.0"
+	if config.IsolatedNetwork {
+		// User mode networking is private to the VM, only the addresses
+		// of its network are set
+		netdev += fmt.Sprintf(",net=%s,dhcpstart=%s",
+			config.IsolatedNetworkCIDR, config.IsolatedNetworkConfig.GuestIP())
+	}
 	if config.Comm.Type != "none" {
 		sshHostPort = state.Get("sshHostPort").(uint)
-		defaultArgs["-netdev"] = fmt.Sprintf("user,id=user.0,hostfwd=tcp::%v-:%d", sshHostPort, config.Comm.Port())
-	} else {
-		defaultArgs["-netdev"] = fmt.Sprintf("user,id=user.0")
+		netdev += fmt.Sprintf(",hostfwd=tcp::%v-:%d", sshHostPort, config.Comm.Port())
 	}
+	defaultArgs["-netdev"] = netdev
 
 	qemuVersion, err := driver.Version()
 	if err != nil {
@@ -94,17 +108,58 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 	if err != nil {
 		return nil, err
 	}
-	if qemuMajor >= 2 {
-		if config.DiskInterface == "virtio-scsi" {
-			deviceArgs = append(deviceArgs, "virtio-scsi-pci,id=scsi0", "scsi-hd,bus=scsi0.0,drive=drive0")
-			driveArgs = append(driveArgs, fmt.Sprintf("if=none,file=%s,id=drive0,cache=%s,discard=%s,format=%s", imgPath, config.DiskCache, config.DiskDiscard, config.Format))
-		} else {
-			driveArgs = append(driveArgs, fmt.Sprintf("file=%s,if=%s,cache=%s,discard=%s,format=%s", imgPath, config.DiskInterface, config.DiskCache, config.DiskDiscard, config.Format))
+	disks := []AdditionalDisk{{Format: config.Format, Interface: config.DiskInterface}}
+	disks = append(disks, config.AdditionalDisks...)
+	scsi := false
+	for i, disk := range disks {
+		path := imgPath
+		if i > 0 {
+			path = additionalDiskPath(config, i-1)
+		}
+		id := fmt.Sprintf("drive%d", i)
+
+		if qemuMajor < 2 {
+			driveArgs = append(driveArgs, fmt.Sprintf("file=%s,if=%s,cache=%s,format=%s", path, disk.Interface, config.DiskCache, disk.Format))
+			continue
+		}
+
+		switch disk.Interface {
+		case "virtio-scsi":
+			// The disks share a controller
+			if !scsi {
+				deviceArgs = append(deviceArgs, "virtio-scsi-pci,id=scsi0")
+				scsi = true
+			}
+			deviceArgs = append(deviceArgs, fmt.Sprintf("scsi-hd,bus=scsi0.0,drive=%s", id))
+			driveArgs = append(driveArgs, fmt.Sprintf("if=none,file=%s,id=%s,cache=%s,discard=%s,format=%s", path, id, config.DiskCache, config.DiskDiscard, disk.Format))
+		case "nvme":
+			// Each disk is a controller, which requires a serial number
+			deviceArgs = append(deviceArgs, fmt.Sprintf("nvme,serial=%s,drive=%s", id, id))
+			driveArgs = append(driveArgs, fmt.Sprintf("if=none,file=%s,id=%s,cache=%s,discard=%s,format=%s", path, id, config.DiskCache, config.DiskDiscard, disk.Format))
+		default:
+			driveArgs = append(driveArgs, fmt.Sprintf("file=%s,if=%s,cache=%s,discard=%s,format=%s", path, disk.Interface, config.DiskCache, config.DiskDiscard, disk.Format))
+		}
+	}
+	netDeviceArgs := fmt.Sprintf("%s,netdev=user.0", config.NetDevice)
+	if strategies := config.GuestIPConfig.GuestIPStrategies; len(strategies) > 0 {
+		// A MAC address of the QEMU range, to find the guest with
+		mac := fmt.Sprintf("52:54:00:%02x:%02x:%02x", rand.Intn(256), rand.Intn(256), rand.Intn(256))
+		netDeviceArgs += ",mac=" + mac
+		state.Put("guest_mac", mac)
+
+		for _, strategy := range strategies {
+			if strategy != guestip.QemuGuestAgent {
+				continue
+			}
+			// Unix socket paths are short, the output directory may be
+			// too deep for one.
+			socket := filepath.Join(os.TempDir(), fmt.Sprintf("packer-qga-%d.sock", rand.Int63()))
+			chardevArgs = append(chardevArgs, fmt.Sprintf("socket,path=%s,server,nowait,id=qga0", socket))
+			deviceArgs = append(deviceArgs, "virtio-serial", "virtserialport,chardev=qga0,name=org.qemu.guest_agent.0")
+			state.Put("qga_socket", socket)
 		}
-	} else {
-		driveArgs = append(driveArgs, fmt.Sprintf("file=%s,if=%s,cache=%s,format=%s", imgPath, config.DiskInterface, config.DiskCache, config.Format))
 	}
-	deviceArgs = append(deviceArgs, fmt.Sprintf("%s,netdev=user.0", config.NetDevice))
+	deviceArgs = append(deviceArgs, netDeviceArgs)
 
 	if config.Headless == true {
 		vncIpRaw, vncIpOk := state.GetOk("vnc_ip")
@@ -134,12 +189,49 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 		}
 	}
 
-	defaultArgs["-device"] = deviceArgs
-	defaultArgs["-drive"] = driveArgs
+	if config.EFIBoot {
+		driveArgs = append(driveArgs,
+			fmt.Sprintf("if=pflash,unit=0,format=raw,readonly=on,file=%s", config.EFIFirmwareCode),
+			fmt.Sprintf("if=pflash,unit=1,format=raw,file=%s", state.Get("efivars_path").(string)))
+	}
 
 	if !config.DiskImage {
-		defaultArgs["-cdrom"] = isoPath
+		if config.MachineType == "virt" {
+			// The virt machines have no IDE bus for -cdrom
+			driveArgs = append(driveArgs, fmt.Sprintf("if=none,file=%s,id=cdrom0,media=cdrom,readonly=on", isoPath))
+			deviceArgs = append(deviceArgs, "virtio-scsi-pci,id=scsi1", "scsi-cd,bus=scsi1.0,drive=cdrom0")
+		} else {
+			defaultArgs["-cdrom"] = isoPath
+		}
+	}
+
+	// Determine if we have a CD, from cd_files and cd_content, to attach
+	if cdPathRaw, ok := state.GetOk("cd_path"); ok {
+		cdPath := cdPathRaw.(string)
+		if config.MachineType == "virt" {
+			driveArgs = append(driveArgs, fmt.Sprintf("if=none,file=%s,id=cdrom1,media=cdrom,readonly=on", cdPath))
+			deviceArgs = append(deviceArgs, "virtio-scsi-pci,id=scsi2", "scsi-cd,bus=scsi2.0,drive=cdrom1")
+		} else {
+			driveArgs = append(driveArgs, fmt.Sprintf("file=%s,media=cdrom", cdPath))
+		}
+	}
+
+	if socket, ok := state.GetOk("tpm_socket"); ok {
+		chardevArgs = append(chardevArgs, fmt.Sprintf("socket,id=chrtpm,path=%s", socket.(string)))
+		defaultArgs["-tpmdev"] = "emulator,id=tpm0,chardev=chrtpm"
+		deviceArgs = append(deviceArgs, fmt.Sprintf("%s,tpmdev=tpm0", tpmDevices[config.QemuArch]))
+	}
+
+	if config.CPUModel != "" {
+		defaultArgs["-cpu"] = config.CPUModel
+	}
+
+	if len(chardevArgs) > 0 {
+		defaultArgs["-chardev"] = chardevArgs
 	}
+	defaultArgs["-device"] = deviceArgs
+	defaultArgs["-drive"] = driveArgs
+
 	defaultArgs["-boot"] = bootDrive
 	defaultArgs["-m"] = "512M"
 	defaultArgs["-vnc"] = vnc
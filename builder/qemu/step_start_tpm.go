@@ -0,0 +1,107 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// tpmDevices are the TPM devices QEMU attaches the emulator to on each
+// architecture of guests.
+var tpmDevices = map[string]string{
+	"x86_64":  "tpm-tis",
+	"i386":    "tpm-tis",
+	"aarch64": "tpm-tis-device",
+	"ppc64":   "tpm-spapr",
+}
+
+// This step starts swtpm, a TPM 2.0 emulator the VM connects to through the
+// unix socket it puts in tpm_socket.
+type stepStartTPM struct {
+	dir string
+	cmd *exec.Cmd
+}
+
+func (s *stepStartTPM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Starting the TPM emulator...")
+	socket, err := s.start()
+	if err != nil {
+		err := fmt.Errorf("Error starting swtpm: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("tpm_socket", socket)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepStartTPM) start() (string, error) {
+	path, err := exec.LookPath("swtpm")
+	if err != nil {
+		return "", err
+	}
+
+	// Unix socket paths are short, the output directory may be too deep
+	// for one. The state of the TPM doesn't outlive the build.
+	s.dir, err = ioutil.TempDir("", "packer-swtpm")
+	if err != nil {
+		return "", err
+	}
+	socket := filepath.Join(s.dir, "swtpm.sock")
+
+	args := []string{
+		"socket", "--tpm2",
+		"--tpmstate", "dir=" + s.dir,
+		"--ctrl", "type=unixio,path=" + socket,
+		// Exit once QEMU disconnects
+		"--terminate",
+	}
+	log.Printf("Executing %s: %#v", path, args)
+	s.cmd = exec.Command(path, args...)
+	out, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	s.cmd.Stderr = s.cmd.Stdout
+	if err := s.cmd.Start(); err != nil {
+		s.cmd = nil
+		return "", err
+	}
+	go logReader("swtpm", out)
+
+	// QEMU fails to start if the socket isn't there yet
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(socket); err == nil {
+			return socket, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return "", fmt.Errorf("timeout waiting for the socket %s", socket)
+}
+
+func (s *stepStartTPM) Cleanup(state multistep.StateBag) {
+	if s.cmd != nil {
+		// It exits by itself once QEMU disconnects, unless QEMU never
+		// connected
+		if err := s.cmd.Process.Kill(); err != nil {
+			log.Printf("Error killing swtpm, it may have exited already: %s", err)
+		}
+		s.cmd.Wait()
+	}
+
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
@@ -25,6 +25,10 @@ type Driver interface {
 	// Delete a VM by name
 	Delete(string) error
 
+	// GuestProperty reads a guest property of the VM, or returns an empty
+	// string if the guest hasn't set it.
+	GuestProperty(vm string, property string) (string, error)
+
 	// Import a VM
 	Import(string, string, []string) error
 
@@ -34,6 +38,9 @@ type Driver interface {
 	// Checks if the VM with the given name is running.
 	IsRunning(string) (bool, error)
 
+	// MACAddress reads the MAC address of the first NIC of the VM.
+	MACAddress(string) (string, error)
+
 	// Stop stops a running machine, forcefully.
 	Stop(string) error
 
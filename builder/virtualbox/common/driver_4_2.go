@@ -63,6 +63,23 @@ func (d *VBox42Driver) Delete(name string) error {
 	})
 }
 
+func (d *VBox42Driver) GuestProperty(vmName string, property string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(d.VBoxManagePath, "guestproperty", "get", vmName, property)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	// The output is "No value set!" until the guest sets the property
+	value := strings.TrimSpace(stdout.String())
+	if !strings.HasPrefix(value, "Value: ") {
+		return "", nil
+	}
+	return strings.TrimPrefix(value, "Value: "), nil
+}
+
 func (d *VBox42Driver) Iso() (string, error) {
 	var stdout bytes.Buffer
 
@@ -137,6 +154,36 @@ func (d *VBox42Driver) IsRunning(name string) (bool, error) {
 	return false, nil
 }
 
+func (d *VBox42Driver) MACAddress(name string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(d.VBoxManagePath, "showvminfo", name, "--machinereadable")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		// Need to trim off CR character when running in windows
+		line = strings.TrimRight(line, "\r")
+
+		// The address is printed without separators, as 080027AB12CD
+		if strings.HasPrefix(line, "macaddress1=") {
+			mac := strings.Trim(strings.TrimPrefix(line, "macaddress1="), `"`)
+			if len(mac) != 12 {
+				break
+			}
+			parts := make([]string, 0, 6)
+			for i := 0; i < len(mac); i += 2 {
+				parts = append(parts, mac[i:i+2])
+			}
+			return strings.ToLower(strings.Join(parts, ":")), nil
+		}
+	}
+
+	return "", fmt.Errorf("couldn't find the MAC address of the first NIC of %s", name)
+}
+
 func (d *VBox42Driver) Stop(name string) error {
 	if err := d.VBoxManage("controlvm", name, "poweroff"); err != nil {
 		return err
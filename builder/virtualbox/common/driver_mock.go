@@ -17,6 +17,11 @@ type DriverMock struct {
 	DeleteName   string
 	DeleteErr    error
 
+	GuestPropertyVM       string
+	GuestPropertyProperty string
+	GuestPropertyResult   string
+	GuestPropertyErr      error
+
 	ImportCalled bool
 	ImportName   string
 	ImportPath   string
@@ -30,6 +35,10 @@ type DriverMock struct {
 	IsRunningReturn bool
 	IsRunningErr    error
 
+	MACAddressName   string
+	MACAddressResult string
+	MACAddressErr    error
+
 	StopName string
 	StopErr  error
 
@@ -65,6 +74,12 @@ func (d *DriverMock) Delete(name string) error {
 	return d.DeleteErr
 }
 
+func (d *DriverMock) GuestProperty(vm string, property string) (string, error) {
+	d.GuestPropertyVM = vm
+	d.GuestPropertyProperty = property
+	return d.GuestPropertyResult, d.GuestPropertyErr
+}
+
 func (d *DriverMock) Import(name string, path string, flags []string) error {
 	d.ImportCalled = true
 	d.ImportName = name
@@ -86,6 +101,11 @@ func (d *DriverMock) IsRunning(name string) (bool, error) {
 	return d.IsRunningReturn, d.IsRunningErr
 }
 
+func (d *DriverMock) MACAddress(name string) (string, error) {
+	d.MACAddressName = name
+	return d.MACAddressResult, d.MACAddressErr
+}
+
 func (d *DriverMock) Stop(name string) error {
 	d.StopName = name
 	return d.StopErr
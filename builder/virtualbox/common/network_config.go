@@ -0,0 +1,111 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// maxNetworkAdapters is the number of NICs of a VM, the first one being
+// the one Packer connects to the guest through.
+const maxNetworkAdapters = 8
+
+var macAddressRe = regexp.MustCompile(`^[0-9a-f]{12}$`)
+
+// NetworkAdapter is a NIC attached to the VM after the first one.
+type NetworkAdapter struct {
+	Type          string `mapstructure:"type"`
+	Model         string `mapstructure:"model"`
+	HostInterface string `mapstructure:"host_interface"`
+	Network       string `mapstructure:"network"`
+	MACAddress    string `mapstructure:"mac_address"`
+}
+
+// PortForward is a rule forwarding a port of the host to a port of the
+// guest, through the NAT of the first NIC. The host port is the first
+// free one from HostPort to HostPortMax.
+type PortForward struct {
+	Name        string `mapstructure:"name"`
+	Protocol    string `mapstructure:"protocol"`
+	HostIP      string `mapstructure:"host_ip"`
+	HostPort    uint   `mapstructure:"host_port"`
+	HostPortMax uint   `mapstructure:"host_port_max"`
+	GuestPort   uint   `mapstructure:"guest_port"`
+}
+
+type NetworkConfig struct {
+	NetworkAdapters []NetworkAdapter `mapstructure:"network_adapters"`
+	PortForwards    []PortForward    `mapstructure:"port_forwards"`
+}
+
+func (c *NetworkConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if len(c.NetworkAdapters) > maxNetworkAdapters-1 {
+		errs = append(errs, fmt.Errorf("network_adapters can't have more than %d adapters", maxNetworkAdapters-1))
+	}
+	for i := range c.NetworkAdapters {
+		adapter := &c.NetworkAdapters[i]
+		switch adapter.Type {
+		case "nat", "intnet":
+			if adapter.HostInterface != "" {
+				errs = append(errs, fmt.Errorf("network_adapters[%d]: host_interface requires the hostonly or bridged type", i))
+			}
+		case "hostonly", "bridged":
+			if adapter.HostInterface == "" {
+				errs = append(errs, fmt.Errorf("network_adapters[%d]: host_interface is required with the %s type", i, adapter.Type))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("network_adapters[%d]: type must be one of nat, hostonly, bridged or intnet", i))
+		}
+		if adapter.Network != "" && adapter.Type != "intnet" {
+			errs = append(errs, fmt.Errorf("network_adapters[%d]: network requires the intnet type", i))
+		}
+		if adapter.MACAddress != "" {
+			// VBoxManage takes the address without separators
+			adapter.MACAddress = strings.ToLower(strings.NewReplacer(":", "", "-", "").Replace(adapter.MACAddress))
+			if !macAddressRe.MatchString(adapter.MACAddress) {
+				errs = append(errs, fmt.Errorf("network_adapters[%d]: mac_address is invalid", i))
+			}
+		}
+	}
+
+	names := make(map[string]bool)
+	for i := range c.PortForwards {
+		rule := &c.PortForwards[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("packer%d", i)
+		}
+		if rule.Protocol == "" {
+			rule.Protocol = "tcp"
+		}
+		if rule.HostIP == "" {
+			rule.HostIP = "127.0.0.1"
+		}
+		if rule.HostPortMax == 0 {
+			rule.HostPortMax = rule.HostPort
+		}
+
+		if names[rule.Name] || rule.Name == "packercomm" {
+			errs = append(errs, fmt.Errorf("port_forwards[%d]: the name %s is already used", i, rule.Name))
+		}
+		names[rule.Name] = true
+		if rule.Protocol != "tcp" && rule.Protocol != "udp" {
+			errs = append(errs, fmt.Errorf("port_forwards[%d]: protocol must be tcp or udp", i))
+		}
+		if net.ParseIP(rule.HostIP) == nil {
+			errs = append(errs, fmt.Errorf("port_forwards[%d]: host_ip is invalid", i))
+		}
+		if rule.HostPort == 0 || rule.GuestPort == 0 {
+			errs = append(errs, fmt.Errorf("port_forwards[%d]: host_port and guest_port are required", i))
+		}
+		if rule.HostPortMax < rule.HostPort {
+			errs = append(errs, fmt.Errorf("port_forwards[%d]: host_port_max must be greater than host_port", i))
+		}
+	}
+
+	return errs
+}
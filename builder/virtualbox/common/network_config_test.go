@@ -0,0 +1,91 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNetworkConfigPrepare_NetworkAdapters(t *testing.T) {
+	var c *NetworkConfig
+	var errs []error
+
+	// Good
+	c = &NetworkConfig{
+		NetworkAdapters: []NetworkAdapter{
+			{Type: "hostonly", HostInterface: "vboxnet0", MACAddress: "08:00:27:AB:CD:EF"},
+			{Type: "intnet", Network: "backend"},
+		},
+	}
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.NetworkAdapters[0].MACAddress != "080027abcdef" {
+		t.Fatalf("bad: %s", c.NetworkAdapters[0].MACAddress)
+	}
+
+	// Bad
+	for _, adapter := range []NetworkAdapter{
+		{Type: "nat", HostInterface: "eth0"},
+		{Type: "bridged"},
+		{Type: "nat", Network: "backend"},
+		{Type: "natnetwork"},
+		{Type: "nat", MACAddress: "08:00:27"},
+	} {
+		c = &NetworkConfig{NetworkAdapters: []NetworkAdapter{adapter}}
+		errs = c.Prepare(testConfigTemplate(t))
+		if len(errs) == 0 {
+			t.Fatalf("should have error: %#v", adapter)
+		}
+	}
+
+	// Bad, too many adapters
+	c = &NetworkConfig{NetworkAdapters: make([]NetworkAdapter, 8)}
+	for i := range c.NetworkAdapters {
+		c.NetworkAdapters[i].Type = "nat"
+	}
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatalf("should have error")
+	}
+}
+
+func TestNetworkConfigPrepare_PortForwards(t *testing.T) {
+	var c *NetworkConfig
+	var errs []error
+
+	// Good, with the defaults
+	c = &NetworkConfig{
+		PortForwards: []PortForward{
+			{HostPort: 8080, GuestPort: 80},
+			{Name: "dns", Protocol: "udp", HostIP: "0.0.0.0", HostPort: 5353, HostPortMax: 5360, GuestPort: 53},
+		},
+	}
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	expected := []PortForward{
+		{Name: "packer0", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: 8080, HostPortMax: 8080, GuestPort: 80},
+		{Name: "dns", Protocol: "udp", HostIP: "0.0.0.0", HostPort: 5353, HostPortMax: 5360, GuestPort: 53},
+	}
+	if !reflect.DeepEqual(c.PortForwards, expected) {
+		t.Fatalf("bad: %#v", c.PortForwards)
+	}
+
+	// Bad
+	for _, rules := range [][]PortForward{
+		{{Name: "packercomm", HostPort: 8080, GuestPort: 80}},
+		{{Name: "web", HostPort: 8080, GuestPort: 80}, {Name: "web", HostPort: 8443, GuestPort: 443}},
+		{{Protocol: "icmp", HostPort: 8080, GuestPort: 80}},
+		{{HostIP: "localhost", HostPort: 8080, GuestPort: 80}},
+		{{GuestPort: 80}},
+		{{HostPort: 8080, HostPortMax: 8000, GuestPort: 80}},
+	} {
+		c = &NetworkConfig{PortForwards: rules}
+		errs = c.Prepare(testConfigTemplate(t))
+		if len(errs) == 0 {
+			t.Fatalf("should have error: %#v", rules)
+		}
+	}
+}
@@ -1,15 +1,45 @@
 package common
 
 import (
+	"net"
+
+	"github.com/hashicorp/packer/common/guestip"
 	commonssh "github.com/hashicorp/packer/common/ssh"
 	"github.com/hashicorp/packer/communicator/ssh"
 	"github.com/hashicorp/packer/helper/multistep"
 	gossh "golang.org/x/crypto/ssh"
 )
 
-func CommHost(host string) func(multistep.StateBag) (string, error) {
+func CommHost(config *SSHConfig) func(multistep.StateBag) (string, error) {
 	return func(state multistep.StateBag) (string, error) {
-		return host, nil
+		if config.Comm.SSHHost != "" {
+			return config.Comm.SSHHost, nil
+		}
+
+		driver := state.Get("driver").(Driver)
+		vmName := state.Get("vmName").(string)
+		return guestip.Discover(config.GuestIPConfig.GuestIPStrategies, guestIPStrategies(driver, vmName))
+	}
+}
+
+func guestIPStrategies(driver Driver, vmName string) map[string]guestip.Strategy {
+	return map[string]guestip.Strategy{
+		// Set by the Guest Additions
+		guestip.GuestProperties: guestip.StrategyFunc(func() (string, error) {
+			return driver.GuestProperty(vmName, "/VirtualBox/GuestInfo/Net/0/V4/IP")
+		}),
+		guestip.ARP: guestip.StrategyFunc(func() (string, error) {
+			address, err := driver.MACAddress(vmName)
+			if err != nil {
+				return "", err
+			}
+			mac, err := net.ParseMAC(address)
+			if err != nil {
+				return "", err
+			}
+			s := &guestip.ARPStrategy{MAC: mac}
+			return s.GuestIP()
+		}),
 	}
 }
 
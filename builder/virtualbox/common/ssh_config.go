@@ -4,12 +4,14 @@ import (
 	"errors"
 	"time"
 
+	"github.com/hashicorp/packer/common/guestip"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
 type SSHConfig struct {
-	Comm communicator.Config `mapstructure:",squash"`
+	Comm          communicator.Config `mapstructure:",squash"`
+	GuestIPConfig guestip.Config      `mapstructure:",squash"`
 
 	SSHHostPortMin    uint `mapstructure:"ssh_host_port_min"`
 	SSHHostPortMax    uint `mapstructure:"ssh_host_port_max"`
@@ -21,7 +23,8 @@ type SSHConfig struct {
 }
 
 func (c *SSHConfig) Prepare(ctx *interpolate.Context) []error {
-	if c.Comm.SSHHost == "" {
+	// The guest is reached on its own address when it's discovered
+	if c.Comm.SSHHost == "" && len(c.GuestIPConfig.GuestIPStrategies) == 0 {
 		c.Comm.SSHHost = "127.0.0.1"
 	}
 
@@ -44,5 +47,13 @@ func (c *SSHConfig) Prepare(ctx *interpolate.Context) []error {
 			errors.New("ssh_host_port_min must be less than ssh_host_port_max"))
 	}
 
+	errs = append(errs, c.GuestIPConfig.Prepare(guestip.GuestProperties, guestip.ARP)...)
+	// The NAT network of the VM can't be reached from the host
+	if len(c.GuestIPConfig.GuestIPStrategies) > 0 && !c.SSHSkipNatMapping {
+		errs = append(errs,
+			errors.New("guest_ip_strategies requires ssh_skip_nat_mapping, "+
+				"and the VM attached to a network reachable from the host"))
+	}
+
 	return errs
 }
@@ -59,6 +59,40 @@ func TestSSHConfigPrepare_SSHHostPort(t *testing.T) {
 	}
 }
 
+func TestSSHConfigPrepare_GuestIPStrategies(t *testing.T) {
+	var c *SSHConfig
+	var errs []error
+
+	// Bad, the guest is behind NAT
+	c = testSSHConfig()
+	c.GuestIPConfig.GuestIPStrategies = []string{"guest_properties"}
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Bad
+	c = testSSHConfig()
+	c.GuestIPConfig.GuestIPStrategies = []string{"dhcp_leases"}
+	c.SSHSkipNatMapping = true
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Good
+	c = testSSHConfig()
+	c.GuestIPConfig.GuestIPStrategies = []string{"guest_properties", "arp"}
+	c.SSHSkipNatMapping = true
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.Comm.SSHHost != "" {
+		t.Fatalf("bad: %s", c.Comm.SSHHost)
+	}
+}
+
 func TestSSHConfigPrepare_SSHPrivateKey(t *testing.T) {
 	var c *SSHConfig
 	var errs []error
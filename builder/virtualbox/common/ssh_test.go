@@ -0,0 +1,52 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCommHost(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "foo")
+
+	config := testSSHConfig()
+	config.Comm.SSHHost = "127.0.0.1"
+	host, err := CommHost(config)(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if host != "127.0.0.1" {
+		t.Fatalf("bad: %s", host)
+	}
+}
+
+func TestCommHost_GuestIPStrategies(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "foo")
+	driver := state.Get("driver").(*DriverMock)
+
+	config := testSSHConfig()
+	config.GuestIPConfig.GuestIPStrategies = []string{"guest_properties", "arp"}
+
+	driver.GuestPropertyResult = "192.168.1.23"
+	host, err := CommHost(config)(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if host != "192.168.1.23" {
+		t.Fatalf("bad: %s", host)
+	}
+	if driver.GuestPropertyVM != "foo" || driver.GuestPropertyProperty != "/VirtualBox/GuestInfo/Net/0/V4/IP" {
+		t.Fatalf("bad: %s %s", driver.GuestPropertyVM, driver.GuestPropertyProperty)
+	}
+
+	// No address until the Guest Additions run, nor in the ARP cache
+	driver.GuestPropertyResult = ""
+	driver.MACAddressErr = errors.New("foo")
+	if _, err := CommHost(config)(state); err == nil {
+		t.Fatal("should have error")
+	}
+	if driver.MACAddressName != "foo" {
+		t.Fatalf("bad: %s", driver.MACAddressName)
+	}
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step attaches the CD created from cd_files and cd_content as an
+// inserted CD onto the virtual machine.
+//
+// Uses:
+//   cd_path string
+//   driver Driver
+//   ui packer.Ui
+//   vmName string
+//
+// Produces:
+//   cd_attached bool
+type StepAttachCD struct {
+	attachedPath string
+}
+
+func (s *StepAttachCD) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	// If we don't have a CD, then just return
+	cdPathRaw, ok := state.GetOk("cd_path")
+	if !ok {
+		log.Println("No CD to attach.")
+		return multistep.ActionContinue
+	}
+	cdPath := cdPathRaw.(string)
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+
+	// The port and device the installation ISO and the guest additions
+	// don't use
+	log.Println("Attaching CD onto IDE controller...")
+	command := []string{
+		"storageattach", vmName,
+		"--storagectl", "IDE Controller",
+		"--port", "1",
+		"--device", "1",
+		"--type", "dvddrive",
+		"--medium", cdPath,
+	}
+	if err := driver.VBoxManage(command...); err != nil {
+		err := fmt.Errorf("Error attaching CD: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Track the path so that we can unregister it from VirtualBox later
+	s.attachedPath = cdPath
+	state.Put("cd_attached", true)
+
+	return multistep.ActionContinue
+}
+
+func (s *StepAttachCD) Cleanup(state multistep.StateBag) {
+	if s.attachedPath == "" {
+		return
+	}
+
+	driver := state.Get("driver").(Driver)
+	vmName := state.Get("vmName").(string)
+
+	command := []string{
+		"storageattach", vmName,
+		"--storagectl", "IDE Controller",
+		"--port", "1",
+		"--device", "1",
+		"--medium", "none",
+	}
+
+	// Remove the CD. Note that this will probably fail since
+	// stepRemoveDevices does this as well. No big deal.
+	driver.VBoxManage(command...)
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepAttachCD_impl(t *testing.T) {
+	var _ multistep.Step = new(StepAttachCD)
+}
+
+func TestStepAttachCD(t *testing.T) {
+	state := testState(t)
+	step := new(StepAttachCD)
+
+	state.Put("cd_path", "/tmp/packer.iso")
+	state.Put("vmName", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+	if _, ok := state.GetOk("cd_attached"); !ok {
+		t.Fatal("should be attached")
+	}
+
+	if len(driver.VBoxManageCalls) != 1 {
+		t.Fatal("not enough calls to VBoxManage")
+	}
+	call := driver.VBoxManageCalls[0]
+	if call[0] != "storageattach" || call[len(call)-1] != "/tmp/packer.iso" {
+		t.Fatalf("bad call: %#v", call)
+	}
+
+	// Test the cleanup
+	step.Cleanup(state)
+	if driver.VBoxManageCalls[1][0] != "storageattach" {
+		t.Fatal("bad call")
+	}
+}
+
+func TestStepAttachCD_noCD(t *testing.T) {
+	state := testState(t)
+	step := new(StepAttachCD)
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	if len(driver.VBoxManageCalls) > 0 {
+		t.Fatal("should not call vboxmanage")
+	}
+}
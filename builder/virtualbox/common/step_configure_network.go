@@ -0,0 +1,144 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step attaches the additional NICs of the config to the VM, and adds
+// its NAT port forwarding rules. When the VM is on an isolated network, the
+// rules are added to the NAT network instead.
+//
+// Uses:
+//   driver Driver
+//   natNetwork string (optional)
+//   natNetworkGuestIP string (optional)
+//   sshHostPort int
+//   ui packer.Ui
+//   vmName string
+type StepConfigureNetwork struct {
+	Config *NetworkConfig
+}
+
+func (s *StepConfigureNetwork) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+
+	for i, adapter := range s.Config.NetworkAdapters {
+		nic := i + 2
+		ui.Say(fmt.Sprintf("Attaching network adapter %d (%s)...", nic, adapter.Type))
+		if err := driver.VBoxManage(networkAdapterCommand(vmName, nic, adapter)...); err != nil {
+			err := fmt.Errorf("Error attaching network adapter %d: %s", nic, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	// The ports are kept open until all are chosen, so that no two rules
+	// get the same one
+	used := make(map[string]bool)
+	if port, ok := state.GetOk("sshHostPort"); ok {
+		used[fmt.Sprintf("tcp:127.0.0.1:%d", port)] = true
+	}
+	var listeners []interface{ Close() error }
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+
+	for _, rule := range s.Config.PortForwards {
+		hostPort, l, err := freeHostPort(rule, used)
+		if err != nil {
+			err := fmt.Errorf("Error creating port forwarding rule %s: %s", rule.Name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		listeners = append(listeners, l)
+
+		ui.Say(fmt.Sprintf("Creating forwarded port mapping %s (host port %d to guest port %d)",
+			rule.Name, hostPort, rule.GuestPort))
+		command := []string{
+			"modifyvm", vmName,
+			"--natpf1",
+			fmt.Sprintf("%s,%s,%s,%d,,%d", rule.Name, rule.Protocol, rule.HostIP, hostPort, rule.GuestPort),
+		}
+		if natNetwork, ok := state.GetOk("natNetwork"); ok {
+			command = []string{
+				"natnetwork", "modify",
+				"--netname", natNetwork.(string),
+				"--port-forward-4",
+				fmt.Sprintf("%s:%s:[%s]:%d:[%s]:%d", rule.Name, rule.Protocol, rule.HostIP,
+					hostPort, state.Get("natNetworkGuestIP").(string), rule.GuestPort),
+			}
+		}
+		if err := driver.VBoxManage(command...); err != nil {
+			err := fmt.Errorf("Error creating port forwarding rule %s: %s", rule.Name, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepConfigureNetwork) Cleanup(state multistep.StateBag) {}
+
+func networkAdapterCommand(vmName string, nic int, adapter NetworkAdapter) []string {
+	command := []string{"modifyvm", vmName, fmt.Sprintf("--nic%d", nic), adapter.Type}
+	switch adapter.Type {
+	case "hostonly":
+		command = append(command, fmt.Sprintf("--hostonlyadapter%d", nic), adapter.HostInterface)
+	case "bridged":
+		command = append(command, fmt.Sprintf("--bridgeadapter%d", nic), adapter.HostInterface)
+	case "intnet":
+		if adapter.Network != "" {
+			command = append(command, fmt.Sprintf("--intnet%d", nic), adapter.Network)
+		}
+	}
+	if adapter.Model != "" {
+		command = append(command, fmt.Sprintf("--nictype%d", nic), adapter.Model)
+	}
+	if adapter.MACAddress != "" {
+		command = append(command, fmt.Sprintf("--macaddress%d", nic), adapter.MACAddress)
+	}
+	return command
+}
+
+// freeHostPort returns the first port of the range of the rule that is
+// neither used by another rule nor by another program, with the listener
+// holding it.
+func freeHostPort(rule PortForward, used map[string]bool) (uint, interface{ Close() error }, error) {
+	for port := rule.HostPort; port <= rule.HostPortMax; port++ {
+		key := fmt.Sprintf("%s:%s:%d", rule.Protocol, rule.HostIP, port)
+		if used[key] {
+			continue
+		}
+
+		address := net.JoinHostPort(rule.HostIP, fmt.Sprint(port))
+		var l interface{ Close() error }
+		var err error
+		if rule.Protocol == "udp" {
+			l, err = net.ListenPacket("udp", address)
+		} else {
+			l, err = net.Listen("tcp", address)
+		}
+		if err != nil {
+			log.Printf("Host port %d is not available: %s", port, err)
+			continue
+		}
+
+		used[key] = true
+		return port, l, nil
+	}
+	return 0, nil, fmt.Errorf("no host port is available between %d and %d", rule.HostPort, rule.HostPortMax)
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepConfigureNetwork_impl(t *testing.T) {
+	var _ multistep.Step = new(StepConfigureNetwork)
+}
+
+func TestStepConfigureNetwork(t *testing.T) {
+	// Hold a port, so that the rule skips it
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer l.Close()
+	port := uint(l.Addr().(*net.TCPAddr).Port)
+
+	state := testState(t)
+	config := &NetworkConfig{
+		NetworkAdapters: []NetworkAdapter{
+			{Type: "bridged", HostInterface: "eth0", Model: "virtio"},
+		},
+		PortForwards: []PortForward{
+			{HostPort: port, HostPortMax: port + 10, GuestPort: 80},
+		},
+	}
+	if errs := config.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("bad: %s", errs)
+	}
+	step := &StepConfigureNetwork{Config: config}
+
+	state.Put("vmName", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test driver
+	if len(driver.VBoxManageCalls) != 2 {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+	expected := []string{"modifyvm", "foo", "--nic2", "bridged", "--bridgeadapter2", "eth0", "--nictype2", "virtio"}
+	if !reflect.DeepEqual(driver.VBoxManageCalls[0], expected) {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls[0])
+	}
+	rule := driver.VBoxManageCalls[1][3]
+	if rule == fmt.Sprintf("packer0,tcp,127.0.0.1,%d,,80", port) {
+		t.Fatalf("should skip the port in use: %s", rule)
+	}
+	found := false
+	for p := port + 1; p <= port+10; p++ {
+		found = found || rule == fmt.Sprintf("packer0,tcp,127.0.0.1,%d,,80", p)
+	}
+	if !found {
+		t.Fatalf("bad: %s", rule)
+	}
+}
+
+func TestStepConfigureNetwork_unavailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer l.Close()
+	port := uint(l.Addr().(*net.TCPAddr).Port)
+
+	state := testState(t)
+	config := &NetworkConfig{
+		PortForwards: []PortForward{{HostPort: port, GuestPort: 80}},
+	}
+	if errs := config.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("bad: %s", errs)
+	}
+	step := &StepConfigureNetwork{Config: config}
+
+	state.Put("vmName", "foo")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
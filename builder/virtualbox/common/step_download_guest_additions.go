@@ -31,10 +31,11 @@ type guestAdditionsUrlTemplate struct {
 // Produces:
 //   guest_additions_path string - Path to the guest additions.
 type StepDownloadGuestAdditions struct {
-	GuestAdditionsMode   string
-	GuestAdditionsURL    string
-	GuestAdditionsSHA256 string
-	Ctx                  interpolate.Context
+	GuestAdditionsMode     string
+	GuestAdditionsURL      string
+	GuestAdditionsSHA256   string
+	GuestAdditionsLocalISO bool
+	Ctx                    interpolate.Context
 }
 
 func (s *StepDownloadGuestAdditions) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -84,20 +85,25 @@ func (s *StepDownloadGuestAdditions) Run(ctx context.Context, state multistep.St
 		return multistep.ActionHalt
 	}
 
-	// If this resulted in an empty url, then ask the driver about it.
-	if url == "" {
+	// If this resulted in an empty url, then download the guest additions
+	// of the version of VirtualBox, which the ISO installed with it may not
+	// be, unless it is asked for.
+	if url == "" && s.GuestAdditionsLocalISO {
 		log.Printf("guest_additions_url is blank; querying driver for iso.")
 		url, err = driver.Iso()
-
-		if err == nil {
-			checksumType = "none"
-		} else {
+		if err != nil {
+			err := fmt.Errorf("Error finding the guest additions installed with VirtualBox: %s", err)
+			state.Put("error", err)
 			ui.Error(err.Error())
-			url = fmt.Sprintf(
-				"http://download.virtualbox.org/virtualbox/%s/%s",
-				version,
-				additionsName)
+			return multistep.ActionHalt
 		}
+		checksumType = "none"
+	} else if url == "" {
+		ui.Message(fmt.Sprintf("Using the guest additions of VirtualBox %s", version))
+		url = fmt.Sprintf(
+			"https://download.virtualbox.org/virtualbox/%s/%s",
+			version,
+			additionsName)
 	}
 
 	// The driver couldn't even figure it out, so fail hard.
@@ -150,7 +156,7 @@ func (s *StepDownloadGuestAdditions) downloadAdditionsSHA256(ctx context.Context
 	// First things first, we get the list of checksums for the files available
 	// for this version.
 	checksumsUrl := fmt.Sprintf(
-		"http://download.virtualbox.org/virtualbox/%s/SHA256SUMS",
+		"https://download.virtualbox.org/virtualbox/%s/SHA256SUMS",
 		additionsVersion)
 
 	checksumsFile, err := ioutil.TempFile("", "packer")
@@ -184,34 +190,36 @@ func (s *StepDownloadGuestAdditions) downloadAdditionsSHA256(ctx context.Context
 	}
 	defer checksumsF.Close()
 
-	// We copy the contents of the file into memory. In general this file
-	// is quite small so that is okay. In the future, we probably want to
-	// use bufio and iterate line by line.
+	checksum := additionsChecksum(checksumsF, additionsName)
+	log.Printf("Guest additions checksum: %s", checksum)
+
+	if checksum == "" {
+		state.Put("error", fmt.Errorf(
+			"The checksum for the file '%s' could not be found.", additionsName))
+		return "", multistep.ActionHalt
+	}
+
+	return checksum, multistep.ActionContinue
+}
+
+// additionsChecksum returns the checksum of a file from a SHA256SUMS file,
+// or "" if it isn't listed.
+func additionsChecksum(r io.Reader, name string) string {
+	// In general this file is quite small, so it is read into memory.
 	var contents bytes.Buffer
-	io.Copy(&contents, checksumsF)
+	io.Copy(&contents, r)
 
-	checksum := ""
 	for _, line := range strings.Split(contents.String(), "\n") {
 		parts := strings.Fields(line)
-		log.Printf("Checksum file parts: %#v", parts)
 		if len(parts) != 2 {
 			// Bogus line
 			continue
 		}
 
-		if strings.HasSuffix(parts[1], additionsName) {
-			checksum = parts[0]
-			log.Printf("Guest additions checksum: %s", checksum)
-			break
+		// Files checksummed in binary mode are prefixed with a star
+		if strings.TrimPrefix(parts[1], "*") == name {
+			return parts[0]
 		}
 	}
-
-	if checksum == "" {
-		state.Put("error", fmt.Errorf(
-			"The checksum for the file '%s' could not be found.", additionsName))
-		return "", multistep.ActionHalt
-	}
-
-	return checksum, multistep.ActionContinue
-
+	return ""
 }
@@ -0,0 +1,31 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdditionsChecksum(t *testing.T) {
+	sums := `4a7c6b9e2a1bfd0b4edf9e8d1d8e0e10d8e3f67b1ea8c8c0a4bd4e3c7f6f0d9e *VirtualBox-6.1.16.tar.bz2
+7d2e44e4d8d8a2a9c3d8ac2e6f5b1a0f4c0d5e6f7a8b9c0d1e2f3a4b5c6d7e8f *VBoxGuestAdditions_6.1.16.iso
+bogus line
+9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a0  VBoxGuestAdditions_6.1.1.iso
+`
+
+	checksum := additionsChecksum(strings.NewReader(sums), "VBoxGuestAdditions_6.1.16.iso")
+	if checksum != "7d2e44e4d8d8a2a9c3d8ac2e6f5b1a0f4c0d5e6f7a8b9c0d1e2f3a4b5c6d7e8f" {
+		t.Fatalf("bad: %s", checksum)
+	}
+
+	// Files checksummed in text mode
+	checksum = additionsChecksum(strings.NewReader(sums), "VBoxGuestAdditions_6.1.1.iso")
+	if checksum != "9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a0" {
+		t.Fatalf("bad: %s", checksum)
+	}
+
+	// Not a suffix match
+	checksum = additionsChecksum(strings.NewReader(sums), "Additions_6.1.16.iso")
+	if checksum != "" {
+		t.Fatalf("bad: %s", checksum)
+	}
+}
@@ -42,9 +42,22 @@ func (s *StepExport) Run(_ context.Context, state multistep.StateBag) multistep.
 	time.Sleep(1 * time.Second)
 	ui.Say("Preparing to export machine...")
 
+	// Attach the VM back to NAT, the isolated network is deleted with the
+	// forwarding rule once the build completes
+	_, isolated := state.GetOk("natNetwork")
+	if isolated {
+		ui.Message("Attaching the VM back to NAT")
+		if err := driver.VBoxManage("modifyvm", vmName, "--nic1", "nat"); err != nil {
+			err := fmt.Errorf("Error attaching the VM back to NAT: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	// Clear out the Packer-created forwarding rule
 	sshPort := state.Get("sshHostPort")
-	if !s.SkipNatMapping && sshPort != 0 {
+	if !s.SkipNatMapping && sshPort != 0 && !isolated {
 		ui.Message(fmt.Sprintf(
 			"Deleting forwarded port mapping for the communicator (SSH, WinRM, etc) (host port %d)", sshPort))
 		command := []string{"modifyvm", vmName, "--natpf1", "delete", "packercomm"}
@@ -13,10 +13,13 @@ import (
 )
 
 // This step adds a NAT port forwarding definition so that SSH is available
-// on the guest machine.
+// on the guest machine. When the VM is on an isolated network, the rule is
+// added to the NAT network instead.
 //
 // Uses:
 //   driver Driver
+//   natNetwork string (optional)
+//   natNetworkGuestIP string (optional)
 //   ui packer.Ui
 //   vmName string
 //
@@ -69,6 +72,15 @@ func (s *StepForwardSSH) Run(_ context.Context, state multistep.StateBag) multis
 			"--natpf1",
 			fmt.Sprintf("packercomm,tcp,127.0.0.1,%d,,%d", sshHostPort, guestPort),
 		}
+		if natNetwork, ok := state.GetOk("natNetwork"); ok {
+			command = []string{
+				"natnetwork", "modify",
+				"--netname", natNetwork.(string),
+				"--port-forward-4",
+				fmt.Sprintf("packercomm:tcp:[127.0.0.1]:%d:[%s]:%d",
+					sshHostPort, state.Get("natNetworkGuestIP").(string), guestPort),
+			}
+		}
 		if err := driver.VBoxManage(command...); err != nil {
 			err := fmt.Errorf("Error creating port forwarding rule: %s", err)
 			state.Put("error", err)
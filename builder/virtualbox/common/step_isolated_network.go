@@ -0,0 +1,111 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step creates a NAT network for the build, with a DHCP server for
+// the range of the config, and attaches the first NIC of the VM to it.
+// The NIC is attached back to NAT before the VM is exported.
+//
+// Uses:
+//   driver Driver
+//   ui packer.Ui
+//   vmName string
+//
+// Produces:
+//   natNetwork string - The name of the NAT network.
+//   natNetworkGuestIP string - The address the guest gets on the network.
+type StepIsolatedNetwork struct {
+	Config *common.IsolatedNetworkConfig
+
+	name       string
+	dhcpServer bool
+}
+
+func (s *StepIsolatedNetwork) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.Config.IsolatedNetwork {
+		return multistep.ActionContinue
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+
+	name := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	ui.Say(fmt.Sprintf("Creating isolated network %s (%s)...", name, s.Config.IsolatedNetworkCIDR))
+	command := []string{
+		"natnetwork", "add",
+		"--netname", name,
+		"--network", s.Config.IsolatedNetworkCIDR,
+		"--enable",
+		"--dhcp", "off",
+	}
+	if err := driver.VBoxManage(command...); err != nil {
+		err := fmt.Errorf("Error creating isolated network: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.name = name
+
+	lower, upper := s.Config.DHCPRange()
+	command = []string{
+		"dhcpserver", "add",
+		"--netname", name,
+		"--ip", s.Config.DHCPServer().String(),
+		"--netmask", s.Config.Netmask().String(),
+		"--lowerip", lower.String(),
+		"--upperip", upper.String(),
+		"--enable",
+	}
+	if err := driver.VBoxManage(command...); err != nil {
+		err := fmt.Errorf("Error creating the DHCP server of the isolated network: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.dhcpServer = true
+
+	command = []string{
+		"modifyvm", vmName,
+		"--nic1", "natnetwork",
+		"--nat-network1", name,
+	}
+	if err := driver.VBoxManage(command...); err != nil {
+		err := fmt.Errorf("Error attaching the VM to the isolated network: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("natNetwork", name)
+	state.Put("natNetworkGuestIP", s.Config.GuestIP().String())
+
+	return multistep.ActionContinue
+}
+
+func (s *StepIsolatedNetwork) Cleanup(state multistep.StateBag) {
+	if s.name == "" {
+		return
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say(fmt.Sprintf("Deleting isolated network %s...", s.name))
+	if s.dhcpServer {
+		if err := driver.VBoxManage("dhcpserver", "remove", "--netname", s.name); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting the DHCP server of the isolated network: %s", err))
+		}
+	}
+	if err := driver.VBoxManage("natnetwork", "remove", "--netname", s.name); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting isolated network: %s", err))
+	}
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepIsolatedNetwork_impl(t *testing.T) {
+	var _ multistep.Step = new(StepIsolatedNetwork)
+}
+
+func TestStepIsolatedNetwork(t *testing.T) {
+	state := testState(t)
+	config := &common.IsolatedNetworkConfig{
+		IsolatedNetwork:     true,
+		IsolatedNetworkCIDR: "10.10.0.0/24",
+	}
+	if errs := config.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("bad: %s", errs)
+	}
+	step := &StepIsolatedNetwork{Config: config}
+
+	state.Put("vmName", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test output state
+	name, ok := state.GetOk("natNetwork")
+	if !ok {
+		t.Fatal("should set natNetwork")
+	}
+	if ip := state.Get("natNetworkGuestIP").(string); ip != "10.10.0.10" {
+		t.Fatalf("bad: %s", ip)
+	}
+
+	// Test driver
+	if len(driver.VBoxManageCalls) != 3 {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+	if driver.VBoxManageCalls[0][0] != "natnetwork" || driver.VBoxManageCalls[0][3] != name {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls[0])
+	}
+	if driver.VBoxManageCalls[1][0] != "dhcpserver" {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls[1])
+	}
+	if driver.VBoxManageCalls[2][0] != "modifyvm" {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls[2])
+	}
+
+	// Test the cleanup
+	step.Cleanup(state)
+	if len(driver.VBoxManageCalls) != 5 {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+	if driver.VBoxManageCalls[4][0] != "natnetwork" || driver.VBoxManageCalls[4][1] != "remove" {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls[4])
+	}
+}
+
+func TestStepIsolatedNetwork_disabled(t *testing.T) {
+	state := testState(t)
+	step := &StepIsolatedNetwork{Config: &common.IsolatedNetworkConfig{}}
+
+	driver := state.Get("driver").(*DriverMock)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	step.Cleanup(state)
+	if len(driver.VBoxManageCalls) != 0 {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+}
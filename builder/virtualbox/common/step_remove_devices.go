@@ -111,6 +111,23 @@ func (s *StepRemoveDevices) Run(_ context.Context, state multistep.StateBag) mul
 		}
 	}
 
+	if _, ok := state.GetOk("cd_attached"); ok {
+		ui.Message("Removing CD drive...")
+		command := []string{
+			"storageattach", vmName,
+			"--storagectl", "IDE Controller",
+			"--port", "1",
+			"--device", "1",
+			"--medium", "none",
+		}
+		if err := driver.VBoxManage(command...); err != nil {
+			err := fmt.Errorf("Error removing CD: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	return multistep.ActionContinue
 }
 
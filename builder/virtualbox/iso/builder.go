@@ -28,9 +28,12 @@ type Config struct {
 	common.HTTPConfig               `mapstructure:",squash"`
 	common.ISOConfig                `mapstructure:",squash"`
 	common.FloppyConfig             `mapstructure:",squash"`
+	common.CDConfig                 `mapstructure:",squash"`
+	common.IsolatedNetworkConfig    `mapstructure:",squash"`
 	bootcommand.BootConfig          `mapstructure:",squash"`
 	vboxcommon.ExportConfig         `mapstructure:",squash"`
 	vboxcommon.ExportOpts           `mapstructure:",squash"`
+	vboxcommon.NetworkConfig        `mapstructure:",squash"`
 	vboxcommon.OutputConfig         `mapstructure:",squash"`
 	vboxcommon.RunConfig            `mapstructure:",squash"`
 	vboxcommon.ShutdownConfig       `mapstructure:",squash"`
@@ -40,6 +43,7 @@ type Config struct {
 	vboxcommon.VBoxVersionConfig    `mapstructure:",squash"`
 
 	DiskSize               uint   `mapstructure:"disk_size"`
+	GuestAdditionsLocalISO bool   `mapstructure:"guest_additions_local_iso"`
 	GuestAdditionsMode     string `mapstructure:"guest_additions_mode"`
 	GuestAdditionsPath     string `mapstructure:"guest_additions_path"`
 	GuestAdditionsSHA256   string `mapstructure:"guest_additions_sha256"`
@@ -86,12 +90,19 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, b.config.ExportConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.ExportOpts.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CDConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(
 		errs, b.config.OutputConfig.Prepare(&b.config.ctx, &b.config.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, b.config.HTTPConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.IsolatedNetworkConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.NetworkConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.ShutdownConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.SSHConfig.Prepare(&b.config.ctx)...)
+	if b.config.IsolatedNetwork && b.config.SSHSkipNatMapping {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("ssh_skip_nat_mapping can't be used with isolated_network"))
+	}
 	errs = packer.MultiErrorAppend(errs, b.config.VBoxManageConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.VBoxManagePostConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.VBoxVersionConfig.Prepare(&b.config.ctx)...)
@@ -168,6 +179,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.GuestAdditionsSHA256 = strings.ToLower(b.config.GuestAdditionsSHA256)
 	}
 
+	if b.config.GuestAdditionsLocalISO && b.config.GuestAdditionsURL != "" {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("only one of guest_additions_local_iso or guest_additions_url can be specified"))
+	}
+
 	// Warnings
 	if b.config.ShutdownCommand == "" {
 		warnings = append(warnings,
@@ -191,12 +207,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	steps := []multistep.Step{
 		&vboxcommon.StepDownloadGuestAdditions{
-			GuestAdditionsMode:   b.config.GuestAdditionsMode,
-			GuestAdditionsURL:    b.config.GuestAdditionsURL,
-			GuestAdditionsSHA256: b.config.GuestAdditionsSHA256,
-			Ctx:                  b.config.ctx,
+			GuestAdditionsMode:     b.config.GuestAdditionsMode,
+			GuestAdditionsURL:      b.config.GuestAdditionsURL,
+			GuestAdditionsSHA256:   b.config.GuestAdditionsSHA256,
+			GuestAdditionsLocalISO: b.config.GuestAdditionsLocalISO,
+			Ctx:                    b.config.ctx,
 		},
 		&common.StepDownload{
+			Chunks:       b.config.DownloadChunks,
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
 			Description:  "ISO",
@@ -213,10 +231,22 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
 		},
+		&common.StepCreateCD{
+			Files:   b.config.CDConfig.CDFiles,
+			Content: b.config.CDConfig.CDContent,
+			Label:   b.config.CDConfig.CDLabel,
+		},
 		&common.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
+			TLS:         b.config.HTTPTLS,
+			TLSCertFile: b.config.HTTPTLSCertFile,
+			TLSKeyFile:  b.config.HTTPTLSKeyFile,
+			Username:    b.config.HTTPUsername,
+			Password:    b.config.HTTPPassword,
+			Templates:   b.config.HTTPTemplates,
+			Ctx:         b.config.ctx,
 		},
 		new(vboxcommon.StepSuppressMessages),
 		new(stepCreateVM),
@@ -231,12 +261,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			VRDPPortMax:     b.config.VRDPPortMax,
 		},
 		new(vboxcommon.StepAttachFloppy),
+		new(vboxcommon.StepAttachCD),
+		&vboxcommon.StepIsolatedNetwork{
+			Config: &b.config.IsolatedNetworkConfig,
+		},
 		&vboxcommon.StepForwardSSH{
 			CommConfig:     &b.config.SSHConfig.Comm,
 			HostPortMin:    b.config.SSHHostPortMin,
 			HostPortMax:    b.config.SSHHostPortMax,
 			SkipNatMapping: b.config.SSHSkipNatMapping,
 		},
+		&vboxcommon.StepConfigureNetwork{
+			Config: &b.config.NetworkConfig,
+		},
 		&vboxcommon.StepVBoxManage{
 			Commands: b.config.VBoxManage,
 			Ctx:      b.config.ctx,
@@ -245,14 +282,15 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Headless: b.config.Headless,
 		},
 		&vboxcommon.StepTypeBootCommand{
-			BootWait:    b.config.BootWait,
-			BootCommand: b.config.FlatBootCommand(),
-			VMName:      b.config.VMName,
-			Ctx:         b.config.ctx,
+			BootWait:       b.config.BootWait,
+			BootCommand:    b.config.FlatBootCommand(),
+			VMName:         b.config.VMName,
+			KeyboardLayout: b.config.BootKeyboardLayout,
+			Ctx:            b.config.ctx,
 		},
 		&communicator.StepConnect{
 			Config:    &b.config.SSHConfig.Comm,
-			Host:      vboxcommon.CommHost(b.config.SSHConfig.Comm.SSHHost),
+			Host:      vboxcommon.CommHost(&b.config.SSHConfig),
 			SSHConfig: vboxcommon.SSHConfigFunc(b.config.SSHConfig),
 			SSHPort:   vboxcommon.SSHPort,
 			WinRMPort: vboxcommon.SSHPort,
@@ -367,3 +367,32 @@ func TestBuilderPrepare_ISOInterface(t *testing.T) {
 		t.Fatalf("should not have error: %s", err)
 	}
 }
+
+func TestBuilderPrepare_IsolatedNetwork(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["isolated_network"] = true
+	config["isolated_network_cidr"] = "10.10.0.0/24"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.IsolatedNetworkDHCPRange != "10.10.0.10-10.10.0.254" {
+		t.Fatalf("bad: %s", b.config.IsolatedNetworkDHCPRange)
+	}
+
+	// Test with the NAT mapping skipped
+	config["ssh_skip_nat_mapping"] = true
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -59,30 +59,58 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
 		},
+		&common.StepCreateCD{
+			Files:   b.config.CDConfig.CDFiles,
+			Content: b.config.CDConfig.CDContent,
+			Label:   b.config.CDConfig.CDLabel,
+		},
 		&common.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
+			TLS:         b.config.HTTPTLS,
+			TLSCertFile: b.config.HTTPTLSCertFile,
+			TLSKeyFile:  b.config.HTTPTLSKeyFile,
+			Username:    b.config.HTTPUsername,
+			Password:    b.config.HTTPPassword,
+			Templates:   b.config.HTTPTemplates,
+			Ctx:         b.config.ctx,
 		},
 		&vboxcommon.StepDownloadGuestAdditions{
-			GuestAdditionsMode:   b.config.GuestAdditionsMode,
-			GuestAdditionsURL:    b.config.GuestAdditionsURL,
-			GuestAdditionsSHA256: b.config.GuestAdditionsSHA256,
-			Ctx:                  b.config.ctx,
-		},
-		&common.StepDownload{
-			Checksum:     b.config.Checksum,
-			ChecksumType: b.config.ChecksumType,
-			Description:  "OVF/OVA",
-			Extension:    "ova",
-			ResultKey:    "vm_path",
-			TargetPath:   b.config.TargetPath,
-			Url:          []string{b.config.SourcePath},
-		},
-		&StepImport{
-			Name:        b.config.VMName,
-			ImportFlags: b.config.ImportFlags,
+			GuestAdditionsMode:     b.config.GuestAdditionsMode,
+			GuestAdditionsURL:      b.config.GuestAdditionsURL,
+			GuestAdditionsSHA256:   b.config.GuestAdditionsSHA256,
+			GuestAdditionsLocalISO: b.config.GuestAdditionsLocalISO,
+			Ctx:                    b.config.ctx,
 		},
+	}
+
+	if b.config.SourceVM != "" {
+		steps = append(steps, &StepCloneVM{
+			Source:   b.config.SourceVM,
+			Snapshot: b.config.SourceSnapshot,
+			Name:     b.config.VMName,
+			Linked:   b.config.LinkedClone,
+		})
+	} else {
+		steps = append(steps,
+			&common.StepDownload{
+				Checksum:     b.config.Checksum,
+				ChecksumType: b.config.ChecksumType,
+				Description:  "OVF/OVA",
+				Extension:    "ova",
+				ResultKey:    "vm_path",
+				TargetPath:   b.config.TargetPath,
+				Url:          []string{b.config.SourcePath},
+			},
+			&StepImport{
+				Name:        b.config.VMName,
+				ImportFlags: b.config.ImportFlags,
+			},
+		)
+	}
+
+	steps = append(steps,
 		&vboxcommon.StepAttachGuestAdditions{
 			GuestAdditionsMode: b.config.GuestAdditionsMode,
 		},
@@ -92,12 +120,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			VRDPPortMax:     b.config.VRDPPortMax,
 		},
 		new(vboxcommon.StepAttachFloppy),
+		new(vboxcommon.StepAttachCD),
+		&vboxcommon.StepIsolatedNetwork{
+			Config: &b.config.IsolatedNetworkConfig,
+		},
 		&vboxcommon.StepForwardSSH{
 			CommConfig:     &b.config.SSHConfig.Comm,
 			HostPortMin:    b.config.SSHHostPortMin,
 			HostPortMax:    b.config.SSHHostPortMax,
 			SkipNatMapping: b.config.SSHSkipNatMapping,
 		},
+		&vboxcommon.StepConfigureNetwork{
+			Config: &b.config.NetworkConfig,
+		},
 		&vboxcommon.StepVBoxManage{
 			Commands: b.config.VBoxManage,
 			Ctx:      b.config.ctx,
@@ -106,14 +141,15 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Headless: b.config.Headless,
 		},
 		&vboxcommon.StepTypeBootCommand{
-			BootWait:    b.config.BootWait,
-			BootCommand: b.config.FlatBootCommand(),
-			VMName:      b.config.VMName,
-			Ctx:         b.config.ctx,
+			BootWait:       b.config.BootWait,
+			BootCommand:    b.config.FlatBootCommand(),
+			VMName:         b.config.VMName,
+			KeyboardLayout: b.config.BootKeyboardLayout,
+			Ctx:            b.config.ctx,
 		},
 		&communicator.StepConnect{
 			Config:    &b.config.SSHConfig.Comm,
-			Host:      vboxcommon.CommHost(b.config.SSHConfig.Comm.SSHHost),
+			Host:      vboxcommon.CommHost(&b.config.SSHConfig),
 			SSHConfig: vboxcommon.SSHConfigFunc(b.config.SSHConfig),
 			SSHPort:   vboxcommon.SSHPort,
 			WinRMPort: vboxcommon.SSHPort,
@@ -137,6 +173,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Commands: b.config.VBoxManagePost,
 			Ctx:      b.config.ctx,
 		},
+	)
+
+	if b.config.FlattenClone {
+		steps = append(steps, new(StepFlattenClone))
+	}
+
+	steps = append(steps,
 		&vboxcommon.StepExport{
 			Format:         b.config.Format,
 			OutputDir:      b.config.OutputDir,
@@ -144,7 +187,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			SkipNatMapping: b.config.SSHSkipNatMapping,
 			SkipExport:     b.config.SkipExport,
 		},
-	}
+	)
 
 	// Run the steps.
 	b.runner = common.NewRunnerWithPauseFn(steps, b.config.PackerConfig, ui, state)
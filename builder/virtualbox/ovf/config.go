@@ -17,9 +17,12 @@ type Config struct {
 	common.PackerConfig             `mapstructure:",squash"`
 	common.HTTPConfig               `mapstructure:",squash"`
 	common.FloppyConfig             `mapstructure:",squash"`
+	common.CDConfig                 `mapstructure:",squash"`
+	common.IsolatedNetworkConfig    `mapstructure:",squash"`
 	bootcommand.BootConfig          `mapstructure:",squash"`
 	vboxcommon.ExportConfig         `mapstructure:",squash"`
 	vboxcommon.ExportOpts           `mapstructure:",squash"`
+	vboxcommon.NetworkConfig        `mapstructure:",squash"`
 	vboxcommon.OutputConfig         `mapstructure:",squash"`
 	vboxcommon.RunConfig            `mapstructure:",squash"`
 	vboxcommon.SSHConfig            `mapstructure:",squash"`
@@ -28,19 +31,24 @@ type Config struct {
 	vboxcommon.VBoxManagePostConfig `mapstructure:",squash"`
 	vboxcommon.VBoxVersionConfig    `mapstructure:",squash"`
 
-	Checksum             string   `mapstructure:"checksum"`
-	ChecksumType         string   `mapstructure:"checksum_type"`
-	GuestAdditionsMode   string   `mapstructure:"guest_additions_mode"`
-	GuestAdditionsPath   string   `mapstructure:"guest_additions_path"`
-	GuestAdditionsSHA256 string   `mapstructure:"guest_additions_sha256"`
-	GuestAdditionsURL    string   `mapstructure:"guest_additions_url"`
-	ImportFlags          []string `mapstructure:"import_flags"`
-	ImportOpts           string   `mapstructure:"import_opts"`
-	SourcePath           string   `mapstructure:"source_path"`
-	TargetPath           string   `mapstructure:"target_path"`
-	VMName               string   `mapstructure:"vm_name"`
-	KeepRegistered       bool     `mapstructure:"keep_registered"`
-	SkipExport           bool     `mapstructure:"skip_export"`
+	Checksum               string   `mapstructure:"checksum"`
+	ChecksumType           string   `mapstructure:"checksum_type"`
+	GuestAdditionsLocalISO bool     `mapstructure:"guest_additions_local_iso"`
+	GuestAdditionsMode     string   `mapstructure:"guest_additions_mode"`
+	GuestAdditionsPath     string   `mapstructure:"guest_additions_path"`
+	GuestAdditionsSHA256   string   `mapstructure:"guest_additions_sha256"`
+	GuestAdditionsURL      string   `mapstructure:"guest_additions_url"`
+	ImportFlags            []string `mapstructure:"import_flags"`
+	ImportOpts             string   `mapstructure:"import_opts"`
+	SourcePath             string   `mapstructure:"source_path"`
+	SourceVM               string   `mapstructure:"source_vm"`
+	SourceSnapshot         string   `mapstructure:"source_snapshot"`
+	LinkedClone            bool     `mapstructure:"linked_clone"`
+	FlattenClone           bool     `mapstructure:"flatten_clone"`
+	TargetPath             string   `mapstructure:"target_path"`
+	VMName                 string   `mapstructure:"vm_name"`
+	KeepRegistered         bool     `mapstructure:"keep_registered"`
+	SkipExport             bool     `mapstructure:"skip_export"`
 
 	ctx interpolate.Context
 }
@@ -83,11 +91,18 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs = packer.MultiErrorAppend(errs, c.ExportConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ExportOpts.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.FloppyConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.CDConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.HTTPConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.IsolatedNetworkConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.NetworkConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.OutputConfig.Prepare(&c.ctx, &c.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, c.RunConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ShutdownConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.SSHConfig.Prepare(&c.ctx)...)
+	if c.IsolatedNetwork && c.SSHSkipNatMapping {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("ssh_skip_nat_mapping can't be used with isolated_network"))
+	}
 	errs = packer.MultiErrorAppend(errs, c.VBoxManageConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VBoxManagePostConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VBoxVersionConfig.Prepare(&c.ctx)...)
@@ -96,8 +111,21 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	c.ChecksumType = strings.ToLower(c.ChecksumType)
 	c.Checksum = strings.ToLower(c.Checksum)
 
-	if c.SourcePath == "" {
-		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is required"))
+	if c.SourceVM != "" {
+		if c.SourcePath != "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("only one of source_path or source_vm can be specified"))
+		}
+		if c.LinkedClone && c.SourceSnapshot == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_snapshot is required with linked_clone"))
+		}
+		if c.FlattenClone && !c.LinkedClone {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("flatten_clone requires linked_clone"))
+		}
+	} else if c.SourceSnapshot != "" || c.LinkedClone || c.FlattenClone {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("source_snapshot, linked_clone and flatten_clone require source_vm"))
+	} else if c.SourcePath == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("one of source_path or source_vm is required"))
 	} else {
 		c.SourcePath, err = common.ValidatedURL(c.SourcePath)
 		if err != nil {
@@ -134,6 +162,11 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.GuestAdditionsSHA256 = strings.ToLower(c.GuestAdditionsSHA256)
 	}
 
+	if c.GuestAdditionsLocalISO && c.GuestAdditionsURL != "" {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("only one of guest_additions_local_iso or guest_additions_url can be specified"))
+	}
+
 	// Warnings
 	var warnings []string
 	if c.ShutdownCommand == "" {
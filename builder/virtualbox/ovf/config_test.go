@@ -128,3 +128,69 @@ func TestNewConfig_shutdown_timeout(t *testing.T) {
 		t.Fatalf("bad: %s", err)
 	}
 }
+
+func TestNewConfig_sourceVM(t *testing.T) {
+	// Good
+	c := testConfig(t)
+	delete(c, "source_path")
+	c["source_vm"] = "base"
+	c["source_snapshot"] = "golden"
+	c["linked_clone"] = true
+	c["flatten_clone"] = true
+	_, _, err := NewConfig(c)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// Bad, both sources
+	c = testConfig(t)
+	c["source_vm"] = "base"
+	_, _, err = NewConfig(c)
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	// Bad, linked clones are of snapshots
+	c = testConfig(t)
+	delete(c, "source_path")
+	c["source_vm"] = "base"
+	c["linked_clone"] = true
+	_, _, err = NewConfig(c)
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	// Bad, only linked clones are flattened
+	c = testConfig(t)
+	delete(c, "source_path")
+	c["source_vm"] = "base"
+	c["flatten_clone"] = true
+	_, _, err = NewConfig(c)
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	// Bad, a snapshot of an OVF
+	c = testConfig(t)
+	c["source_snapshot"] = "golden"
+	_, _, err = NewConfig(c)
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestNewConfig_guestAdditionsLocalISO(t *testing.T) {
+	c := testConfig(t)
+	c["guest_additions_local_iso"] = true
+	_, _, err := NewConfig(c)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// Bad, the URL is of another ISO
+	c["guest_additions_url"] = "https://example.com/VBoxGuestAdditions.iso"
+	_, _, err = NewConfig(c)
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
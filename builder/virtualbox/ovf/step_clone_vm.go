@@ -0,0 +1,73 @@
+package ovf
+
+import (
+	"context"
+	"fmt"
+
+	vboxcommon "github.com/hashicorp/packer/builder/virtualbox/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step clones a VM registered with VirtualBox, from its current state
+// or from one of its snapshots, instead of importing an OVF.
+type StepCloneVM struct {
+	Source   string
+	Snapshot string
+	Name     string
+	Linked   bool
+
+	vmName string
+}
+
+func (s *StepCloneVM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(vboxcommon.Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	args := []string{"clonevm", s.Source, "--name", s.Name, "--register"}
+	if s.Snapshot != "" {
+		args = append(args, "--snapshot", s.Snapshot)
+	}
+	if s.Linked {
+		// The disks of the clone are differencing images on top of the
+		// ones of the snapshot
+		args = append(args, "--options", "link")
+		ui.Say(fmt.Sprintf("Creating a linked clone of VM: %s (snapshot %s)", s.Source, s.Snapshot))
+	} else {
+		ui.Say(fmt.Sprintf("Cloning VM: %s", s.Source))
+	}
+
+	if err := driver.VBoxManage(args...); err != nil {
+		err := fmt.Errorf("Error cloning VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	s.vmName = s.Name
+	state.Put("vmName", s.Name)
+	return multistep.ActionContinue
+}
+
+func (s *StepCloneVM) Cleanup(state multistep.StateBag) {
+	if s.vmName == "" {
+		return
+	}
+
+	driver := state.Get("driver").(vboxcommon.Driver)
+	ui := state.Get("ui").(packer.Ui)
+	config := state.Get("config").(*Config)
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if (config.KeepRegistered) && (!cancelled && !halted) {
+		ui.Say("Keeping virtual machine registered with VirtualBox host (keep_registered = true)")
+		return
+	}
+
+	// Only the disks of the clone are deleted, not the ones of the source
+	ui.Say("Deregistering and deleting cloned VM...")
+	if err := driver.Delete(s.vmName); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting VM: %s", err))
+	}
+}
@@ -0,0 +1,102 @@
+package ovf
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	vboxcommon "github.com/hashicorp/packer/builder/virtualbox/common"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepCloneVM_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCloneVM)
+}
+
+func TestStepCloneVM(t *testing.T) {
+	state := testState(t)
+	c := testConfig(t)
+	config, _, _ := NewConfig(c)
+	state.Put("config", config)
+	step := &StepCloneVM{
+		Source:   "base",
+		Snapshot: "golden",
+		Name:     "bar",
+		Linked:   true,
+	}
+
+	driver := state.Get("driver").(*vboxcommon.DriverMock)
+
+	// Test the run
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test driver
+	expected := [][]string{
+		{"clonevm", "base", "--name", "bar", "--register", "--snapshot", "golden", "--options", "link"},
+	}
+	if !reflect.DeepEqual(driver.VBoxManageCalls, expected) {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+
+	// Test output state
+	if name, ok := state.GetOk("vmName"); !ok {
+		t.Fatal("vmName should be set")
+	} else if name != "bar" {
+		t.Fatalf("bad: %#v", name)
+	}
+
+	// Test cleanup
+	config.KeepRegistered = true
+	step.Cleanup(state)
+
+	if driver.DeleteCalled {
+		t.Fatal("delete should not be called")
+	}
+
+	config.KeepRegistered = false
+	step.Cleanup(state)
+	if !driver.DeleteCalled {
+		t.Fatal("delete should be called")
+	}
+	if driver.DeleteName != "bar" {
+		t.Fatalf("bad: %#v", driver.DeleteName)
+	}
+}
+
+func TestStepFlattenClone(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "bar")
+	step := new(StepFlattenClone)
+
+	driver := state.Get("driver").(*vboxcommon.DriverMock)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	expected := [][]string{
+		{"clonevm", "bar", "--name", "bar-flat", "--register"},
+		{"modifyvm", "bar-flat", "--name", "bar"},
+	}
+	if !reflect.DeepEqual(driver.VBoxManageCalls, expected) {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+	if driver.DeleteName != "bar" {
+		t.Fatalf("bad: %#v", driver.DeleteName)
+	}
+
+	// The flattened clone replaced the linked one, it isn't deleted
+	driver.DeleteCalled = false
+	step.Cleanup(state)
+	if driver.DeleteCalled {
+		t.Fatal("delete should not be called")
+	}
+}
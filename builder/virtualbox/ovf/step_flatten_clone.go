@@ -0,0 +1,65 @@
+package ovf
+
+import (
+	"context"
+	"fmt"
+
+	vboxcommon "github.com/hashicorp/packer/builder/virtualbox/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step replaces a linked clone by a full clone of it, whose disks no
+// longer depend on the snapshot it was cloned from.
+type StepFlattenClone struct {
+	flatName string
+}
+
+func (s *StepFlattenClone) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(vboxcommon.Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+
+	ui.Say("Flattening the linked clone...")
+	flatName := vmName + "-flat"
+	if err := driver.VBoxManage("clonevm", vmName, "--name", flatName, "--register"); err != nil {
+		err := fmt.Errorf("Error flattening the linked clone: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.flatName = flatName
+
+	if err := driver.Delete(vmName); err != nil {
+		err := fmt.Errorf("Error deleting the linked clone: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// The full clone takes the place of the linked one, whose cleanup then
+	// deletes it unless it is kept registered
+	if err := driver.VBoxManage("modifyvm", flatName, "--name", vmName); err != nil {
+		err := fmt.Errorf("Error renaming the flattened clone: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.flatName = ""
+
+	return multistep.ActionContinue
+}
+
+func (s *StepFlattenClone) Cleanup(state multistep.StateBag) {
+	if s.flatName == "" {
+		return
+	}
+
+	driver := state.Get("driver").(vboxcommon.Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deregistering and deleting flattened clone...")
+	if err := driver.Delete(s.flatName); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting VM: %s", err))
+	}
+}
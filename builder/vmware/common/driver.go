@@ -13,8 +13,8 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
-	"time"
 
+	"github.com/hashicorp/packer/common/guestip"
 	"github.com/hashicorp/packer/helper/multistep"
 )
 
@@ -278,6 +278,21 @@ type VmwareDriver struct {
 	/// This method returns an object with the NetworkNameMapper interface
 	/// that maps network to device and vice-versa.
 	NetworkMapper func() (NetworkNameMapper, error)
+
+	/// This method asks VMware Tools in the guest for its address. The
+	/// vmware_tools strategy is skipped by drivers that don't set it.
+	ToolsGuestIP func(vmxPath string) (string, error)
+
+	/// The order of the strategies used by GuestIP to find the address
+	/// of the guest, DefaultGuestIPStrategies if empty.
+	GuestIPStrategies []string
+}
+
+// The strategies of guest_ip_strategies, in their default order.
+var DefaultGuestIPStrategies = []string{
+	guestip.DHCPLeases,
+	guestip.VMwareTools,
+	guestip.ARP,
 }
 
 func (d *VmwareDriver) GuestAddress(state multistep.StateBag) (string, error) {
@@ -308,10 +323,49 @@ func (d *VmwareDriver) GuestAddress(state multistep.StateBag) (string, error) {
 
 func (d *VmwareDriver) GuestIP(state multistep.StateBag) (string, error) {
 
+	// figure out our MAC address for looking up the guest address
+	MACAddress, err := d.GuestAddress(state)
+	if err != nil {
+		return "", err
+	}
+	mac, err := net.ParseMAC(MACAddress)
+	if err != nil {
+		return "", err
+	}
+
+	strategies := map[string]guestip.Strategy{
+		guestip.DHCPLeases: guestip.StrategyFunc(func() (string, error) {
+			paths, err := d.dhcpLeasesPaths(state)
+			if err != nil {
+				return "", err
+			}
+			s := &guestip.DHCPLeasesStrategy{Paths: paths, MAC: mac}
+			return s.GuestIP()
+		}),
+		guestip.ARP: &guestip.ARPStrategy{MAC: mac},
+	}
+	if d.ToolsGuestIP != nil {
+		vmxPath := state.Get("vmx_path").(string)
+		strategies[guestip.VMwareTools] = guestip.StrategyFunc(func() (string, error) {
+			return d.ToolsGuestIP(vmxPath)
+		})
+	}
+
+	names := d.GuestIPStrategies
+	if len(names) == 0 {
+		names = DefaultGuestIPStrategies
+	}
+	return guestip.Discover(names, strategies)
+}
+
+// dhcpLeasesPaths returns the leases files of the DHCP servers of the
+// devices of the network of the VM.
+func (d *VmwareDriver) dhcpLeasesPaths(state multistep.StateBag) ([]string, error) {
+
 	// grab network mapper
 	netmap, err := d.NetworkMapper()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// convert the stashed network to a device
@@ -329,85 +383,44 @@ func (d *VmwareDriver) GuestIP(state multistep.StateBag) (string, error) {
 		vmxPath := state.Get("vmx_path").(string)
 		vmxData, err := readVMXConfig(vmxPath)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		var device string
 		device, err = readCustomDeviceName(vmxData)
 		devices = append(devices, device)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		log.Printf("GuestIP discovered custom device matching %s: %s", network, device)
 	}
 
-	// figure out our MAC address for looking up the guest address
-	MACAddress, err := d.GuestAddress(state)
-	if err != nil {
-		return "", err
-	}
-
+	var paths []string
 	for _, device := range devices {
 		// figure out the correct dhcp leases
 		dhcpLeasesPath := d.DhcpLeasesPath(device)
-		log.Printf("Trying DHCP leases path: %s", dhcpLeasesPath)
 		if dhcpLeasesPath == "" {
-			return "", fmt.Errorf("no DHCP leases path found for device %s", device)
-		}
-
-		// open up the lease and read its contents
-		fh, err := os.Open(dhcpLeasesPath)
-		if err != nil {
-			return "", err
+			return nil, fmt.Errorf("no DHCP leases path found for device %s", device)
 		}
-		defer fh.Close()
-
-		dhcpBytes, err := ioutil.ReadAll(fh)
-		if err != nil {
-			return "", err
-		}
-
-		// start grepping through the file looking for fields that we care about
-		var lastIp string
-		var lastLeaseEnd time.Time
-
-		var curIp string
-		var curLeaseEnd time.Time
-
-		ipLineRe := regexp.MustCompile(`^lease (.+?) {$`)
-		endTimeLineRe := regexp.MustCompile(`^\s*ends \d (.+?);$`)
-		macLineRe := regexp.MustCompile(`^\s*hardware ethernet (.+?);$`)
-
-		for _, line := range strings.Split(string(dhcpBytes), "\n") {
-			// Need to trim off CR character when running in windows
-			line = strings.TrimRight(line, "\r")
-
-			matches := ipLineRe.FindStringSubmatch(line)
-			if matches != nil {
-				lastIp = matches[1]
-				continue
-			}
-
-			matches = endTimeLineRe.FindStringSubmatch(line)
-			if matches != nil {
-				lastLeaseEnd, _ = time.Parse("2006/01/02 15:04:05", matches[1])
-				continue
-			}
+		paths = append(paths, dhcpLeasesPath)
+	}
+	return paths, nil
+}
 
-			// If the mac address matches and this lease ends farther in the
-			// future than the last match we might have, then choose it.
-			matches = macLineRe.FindStringSubmatch(line)
-			if matches != nil && strings.EqualFold(matches[1], MACAddress) && curLeaseEnd.Before(lastLeaseEnd) {
-				curIp = lastIp
-				curLeaseEnd = lastLeaseEnd
-			}
-		}
-		if curIp != "" {
-			return curIp, nil
-		}
+// vmrunGuestIP asks VMware Tools in the guest for its address with vmrun.
+func vmrunGuestIP(vmrunPath, hostType, vmxPath string) (string, error) {
+	cmd := exec.Command(vmrunPath, "-T", hostType, "getGuestIPAddress", vmxPath)
+	stdout, _, err := runAndLog(cmd)
+	if err != nil {
+		return "", err
 	}
 
-	return "", fmt.Errorf("None of the found device(s) %v has a DHCP lease for MAC %s", devices, MACAddress)
+	// vmrun prints "unknown" until the tools are running
+	ip := net.ParseIP(strings.TrimSpace(stdout))
+	if ip == nil {
+		return "", fmt.Errorf("VMware Tools didn't report an address: %s", strings.TrimSpace(stdout))
+	}
+	return ip.String(), nil
 }
 
 func (d *VmwareDriver) HostAddress(state multistep.StateBag) (string, error) {
@@ -169,6 +169,11 @@ func (d *Fusion5Driver) Verify() error {
 		return ReadNetworkingConfig(fd)
 	}
 
+	d.VmwareDriver.ToolsGuestIP = func(vmxPath string) (string, error) {
+		return vmrunGuestIP(d.vmrunPath(), "fusion", vmxPath)
+	}
+	d.VmwareDriver.GuestIPStrategies = d.SSHConfig.GuestIPConfig.GuestIPStrategies
+
 	return nil
 }
 
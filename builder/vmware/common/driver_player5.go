@@ -205,6 +205,11 @@ func (d *Player5Driver) Verify() error {
 
 		return ReadNetmapConfig(pathNetmap)
 	}
+
+	d.VmwareDriver.ToolsGuestIP = func(vmxPath string) (string, error) {
+		return vmrunGuestIP(d.VmrunPath, "player", vmxPath)
+	}
+	d.VmwareDriver.GuestIPStrategies = d.SSHConfig.GuestIPConfig.GuestIPStrategies
 	return nil
 }
 
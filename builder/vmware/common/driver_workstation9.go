@@ -166,6 +166,11 @@ func (d *Workstation9Driver) Verify() error {
 
 		return ReadNetmapConfig(pathNetmap)
 	}
+
+	d.VmwareDriver.ToolsGuestIP = func(vmxPath string) (string, error) {
+		return vmrunGuestIP(d.VmrunPath, "ws", vmxPath)
+	}
+	d.VmwareDriver.GuestIPStrategies = d.SSHConfig.GuestIPConfig.GuestIPStrategies
 	return nil
 }
 
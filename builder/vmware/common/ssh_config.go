@@ -3,12 +3,14 @@ package common
 import (
 	"time"
 
+	"github.com/hashicorp/packer/common/guestip"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
 type SSHConfig struct {
-	Comm communicator.Config `mapstructure:",squash"`
+	Comm          communicator.Config `mapstructure:",squash"`
+	GuestIPConfig guestip.Config      `mapstructure:",squash"`
 
 	// These are deprecated, but we keep them around for BC
 	// TODO(@mitchellh): remove
@@ -25,5 +27,7 @@ func (c *SSHConfig) Prepare(ctx *interpolate.Context) []error {
 		c.Comm.SSHPty = false
 	}
 
-	return c.Comm.Prepare(ctx)
+	errs := c.Comm.Prepare(ctx)
+	errs = append(errs, c.GuestIPConfig.Prepare(DefaultGuestIPStrategies...)...)
+	return errs
 }
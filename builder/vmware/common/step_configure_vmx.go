@@ -12,9 +12,12 @@ import (
 )
 
 // This step configures a VMX by setting some default settings as well
-// as taking in custom data to set, attaching a floppy if it exists, etc.
+// as taking in custom data to set, attaching a floppy and a CD if they
+// exist, etc.
 //
 // Uses:
+//   cd_path string
+//   floppy_path string
 //   vmx_path string
 type StepConfigureVMX struct {
 	CustomData map[string]string
@@ -55,7 +58,7 @@ func (s *StepConfigureVMX) Run(_ context.Context, state multistep.StateBag) mult
 		vmxData[k] = v
 	}
 
-	// Set a floppy disk, but only if we should
+	// Set a floppy disk and a CD, but only if we should
 	if !s.SkipFloppy {
 		// Set a floppy disk if we have one
 		if floppyPathRaw, ok := state.GetOk("floppy_path"); ok {
@@ -64,6 +67,21 @@ func (s *StepConfigureVMX) Run(_ context.Context, state multistep.StateBag) mult
 			vmxData["floppy0.filetype"] = "file"
 			vmxData["floppy0.filename"] = floppyPathRaw.(string)
 		}
+
+		// Set a CD on a free IDE device if we have one
+		if cdPathRaw, ok := state.GetOk("cd_path"); ok {
+			device := freeIDEDevice(vmxData)
+			if device == "" {
+				err := fmt.Errorf("Error attaching CD: no free IDE device in the VMX")
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+			log.Printf("CD path present, setting in VMX on %s", device)
+			vmxData[device+".present"] = "TRUE"
+			vmxData[device+".devicetype"] = "cdrom-image"
+			vmxData[device+".filename"] = cdPathRaw.(string)
+		}
 	}
 
 	if err := WriteVMX(vmxPath, vmxData); err != nil {
@@ -78,3 +96,14 @@ func (s *StepConfigureVMX) Run(_ context.Context, state multistep.StateBag) mult
 
 func (s *StepConfigureVMX) Cleanup(state multistep.StateBag) {
 }
+
+// freeIDEDevice returns the first IDE device of the VMX that isn't present,
+// or an empty string if all of them are.
+func freeIDEDevice(vmxData map[string]string) string {
+	for _, device := range []string{"ide0:0", "ide0:1", "ide1:0", "ide1:1"} {
+		if strings.ToUpper(vmxData[device+".present"]) != "TRUE" {
+			return device
+		}
+	}
+	return ""
+}
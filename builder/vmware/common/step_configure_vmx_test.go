@@ -125,6 +125,55 @@ func TestStepConfigureVMX_floppyPath(t *testing.T) {
 
 }
 
+func TestStepConfigureVMX_cdPath(t *testing.T) {
+	state := testState(t)
+	step := new(StepConfigureVMX)
+
+	vmxPath := testVMXFile(t)
+	defer os.Remove(vmxPath)
+
+	err := WriteVMX(vmxPath, map[string]string{
+		"ide0:0.present":    "TRUE",
+		"ide0:0.devicetype": "cdrom-image",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state.Put("cd_path", "packer.iso")
+	state.Put("vmx_path", vmxPath)
+
+	// Test the run
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test the resulting data
+	vmxContents, err := ioutil.ReadFile(vmxPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	vmxData := ParseVMX(string(vmxContents))
+
+	cases := []struct {
+		Key   string
+		Value string
+	}{
+		{"ide0:1.present", "TRUE"},
+		{"ide0:1.devicetype", "cdrom-image"},
+		{"ide0:1.filename", "packer.iso"},
+	}
+
+	for _, tc := range cases {
+		if vmxData[tc.Key] != tc.Value {
+			t.Fatalf("bad: %s %#v", tc.Key, vmxData[tc.Key])
+		}
+	}
+}
+
 func TestStepConfigureVMX_generatedAddresses(t *testing.T) {
 	state := testState(t)
 	step := new(StepConfigureVMX)
@@ -25,11 +25,12 @@ import (
 // Produces:
 //   <nothing>
 type StepTypeBootCommand struct {
-	BootCommand string
-	VNCEnabled  bool
-	BootWait    time.Duration
-	VMName      string
-	Ctx         interpolate.Context
+	BootCommand    string
+	VNCEnabled     bool
+	BootWait       time.Duration
+	VMName         string
+	KeyboardLayout string
+	Ctx            interpolate.Context
 }
 type bootCommandTemplateData struct {
 	HTTPIP   string
@@ -115,7 +116,7 @@ func (s *StepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 		s.VMName,
 	}
 
-	d := bootcommand.NewVNCDriver(c)
+	d := bootcommand.NewVNCDriver(c, s.KeyboardLayout)
 
 	ui.Say("Typing the boot command over VNC...")
 	command, err := interpolate.Render(s.BootCommand, &s.Ctx)
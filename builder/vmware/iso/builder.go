@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	vmwcommon "github.com/hashicorp/packer/builder/vmware/common"
@@ -31,6 +32,7 @@ type Config struct {
 	common.HTTPConfig        `mapstructure:",squash"`
 	common.ISOConfig         `mapstructure:",squash"`
 	common.FloppyConfig      `mapstructure:",squash"`
+	common.CDConfig          `mapstructure:",squash"`
 	bootcommand.VNCConfig    `mapstructure:",squash"`
 	vmwcommon.DriverConfig   `mapstructure:",squash"`
 	vmwcommon.OutputConfig   `mapstructure:",squash"`
@@ -41,12 +43,13 @@ type Config struct {
 	vmwcommon.VMXConfig      `mapstructure:",squash"`
 
 	// disk drives
-	AdditionalDiskSize []uint `mapstructure:"disk_additional_size"`
-	DiskAdapterType    string `mapstructure:"disk_adapter_type"`
-	DiskName           string `mapstructure:"vmdk_name"`
-	DiskSize           uint   `mapstructure:"disk_size"`
-	DiskTypeId         string `mapstructure:"disk_type_id"`
-	Format             string `mapstructure:"format"`
+	AdditionalDiskSize []uint           `mapstructure:"disk_additional_size"`
+	AdditionalDisks    []AdditionalDisk `mapstructure:"additional_disks"`
+	DiskAdapterType    string           `mapstructure:"disk_adapter_type"`
+	DiskName           string           `mapstructure:"vmdk_name"`
+	DiskSize           uint             `mapstructure:"disk_size"`
+	DiskTypeId         string           `mapstructure:"disk_type_id"`
+	Format             string           `mapstructure:"format"`
 
 	// cdrom drive
 	CdromAdapterType string `mapstructure:"cdrom_adapter_type"`
@@ -56,9 +59,13 @@ type Config struct {
 	Version     string `mapstructure:"version"`
 	VMName      string `mapstructure:"vm_name"`
 
+	// firmware
+	Firmware string `mapstructure:"firmware"`
+
 	// Network adapter and type
-	NetworkAdapterType string `mapstructure:"network_adapter_type"`
-	Network            string `mapstructure:"network"`
+	NetworkAdapterType string              `mapstructure:"network_adapter_type"`
+	Network            string              `mapstructure:"network"`
+	AdditionalNetworks []AdditionalNetwork `mapstructure:"additional_networks"`
 
 	// device presence
 	Sound bool `mapstructure:"sound"`
@@ -76,6 +83,9 @@ type Config struct {
 	VMXDiskTemplatePath string   `mapstructure:"vmx_disk_template_path"`
 	VMXTemplatePath     string   `mapstructure:"vmx_template_path"`
 
+	// vApp of the exported OVF
+	VApp VAppConfig `mapstructure:"vapp"`
+
 	// remote vsphere
 	RemoteType           string `mapstructure:"remote_type"`
 	RemoteDatastore      string `mapstructure:"remote_datastore"`
@@ -92,6 +102,20 @@ type Config struct {
 	ctx interpolate.Context
 }
 
+// AdditionalDisk is a disk added to the VM on a controller of its adapter
+// type, next to the main one.
+type AdditionalDisk struct {
+	Size        uint   `mapstructure:"size"`
+	AdapterType string `mapstructure:"adapter_type"`
+}
+
+// AdditionalNetwork is a network adapter added to the VM, after the one of
+// network.
+type AdditionalNetwork struct {
+	Network     string `mapstructure:"network"`
+	AdapterType string `mapstructure:"adapter_type"`
+}
+
 func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	err := config.Decode(&b.config, &config.DecodeOpts{
 		Interpolate:        true,
@@ -124,6 +148,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, b.config.ToolsConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.VMXConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CDConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.VNCConfig.Prepare(&b.config.ctx)...)
 
 	if b.config.DiskName == "" {
@@ -160,6 +185,17 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.Version = "9"
 	}
 
+	if b.config.Firmware == "" {
+		b.config.Firmware = "bios"
+	}
+
+	switch b.config.Firmware {
+	case "bios", "efi", "efi-secure":
+	default:
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("firmware must be one of bios, efi or efi-secure"))
+	}
+
 	if b.config.RemoteUser == "" {
 		b.config.RemoteUser = "root"
 	}
@@ -192,6 +228,37 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.Network = "nat"
 	}
 
+	if len(b.config.AdditionalDisks) > 0 && len(b.config.AdditionalDiskSize) > 0 {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("only one of disk_additional_size or additional_disks can be specified"))
+	}
+	for i, disk := range b.config.AdditionalDisks {
+		if disk.AdapterType == "" {
+			b.config.AdditionalDisks[i].AdapterType = "lsilogic"
+		}
+		if disk.Size == 0 {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("additional_disks[%d]: size is required", i))
+		}
+		switch strings.ToLower(disk.AdapterType) {
+		case "", "scsi", "lsilogic", "lsisas1068", "buslogic", "pvscsi", "sata", "nvme":
+		default:
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("additional_disks[%d]: adapter_type must be one of scsi, lsilogic, "+
+					"lsisas1068, buslogic, pvscsi, sata or nvme", i))
+		}
+	}
+
+	for i, network := range b.config.AdditionalNetworks {
+		if network.AdapterType == "" {
+			b.config.AdditionalNetworks[i].AdapterType = "e1000"
+		}
+		if network.Network == "" {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("additional_networks[%d]: network is required", i))
+		}
+	}
+
 	if !b.config.Sound {
 		b.config.Sound = false
 	}
@@ -210,6 +277,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs = packer.MultiErrorAppend(errs,
 				fmt.Errorf("Only 'esx5' value is accepted for remote_type"))
 		}
+		// ESXi reports the address of the guest itself
+		if len(b.config.GuestIPConfig.GuestIPStrategies) > 0 {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("guest_ip_strategies isn't supported with remote_type"))
+		}
 	}
 
 	if b.config.Format == "" {
@@ -221,6 +293,12 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			fmt.Errorf("format must be one of ova, ovf, or vmx"))
 	}
 
+	errs = packer.MultiErrorAppend(errs, b.config.VApp.Prepare()...)
+	if !b.config.VApp.empty() && (b.config.SkipExport || b.config.Format == "vmx") {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("vapp requires to export the VM as an ovf or an ova"))
+	}
+
 	// Warnings
 	if b.config.ShutdownCommand == "" {
 		warnings = append(warnings,
@@ -279,6 +357,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			ToolsUploadFlavor: b.config.ToolsUploadFlavor,
 		},
 		&common.StepDownload{
+			Chunks:       b.config.DownloadChunks,
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
 			Description:  "ISO",
@@ -299,9 +378,20 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Message:   "Uploading Floppy to remote machine...",
 			DoCleanup: true,
 		},
+		&common.StepCreateCD{
+			Files:   b.config.CDConfig.CDFiles,
+			Content: b.config.CDConfig.CDContent,
+			Label:   b.config.CDConfig.CDLabel,
+		},
 		&stepRemoteUpload{
-			Key:     "iso_path",
-			Message: "Uploading ISO to remote machine...",
+			Key:       "cd_path",
+			Message:   "Uploading CD to remote machine...",
+			DoCleanup: true,
+		},
+		&stepRemoteUpload{
+			Key:      "iso_path",
+			Message:  "Uploading ISO to remote machine...",
+			Checksum: true,
 		},
 		&stepCreateDisk{},
 		&stepCreateVMX{},
@@ -313,6 +403,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			HTTPDir:     b.config.HTTPDir,
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
+			TLS:         b.config.HTTPTLS,
+			TLSCertFile: b.config.HTTPTLSCertFile,
+			TLSKeyFile:  b.config.HTTPTLSKeyFile,
+			Username:    b.config.HTTPUsername,
+			Password:    b.config.HTTPPassword,
+			Templates:   b.config.HTTPTemplates,
+			Ctx:         b.config.ctx,
 		},
 		&vmwcommon.StepConfigureVNC{
 			Enabled:            !b.config.DisableVNC,
@@ -329,11 +426,12 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Headless:           b.config.Headless,
 		},
 		&vmwcommon.StepTypeBootCommand{
-			BootWait:    b.config.BootWait,
-			VNCEnabled:  !b.config.DisableVNC,
-			BootCommand: b.config.FlatBootCommand(),
-			VMName:      b.config.VMName,
-			Ctx:         b.config.ctx,
+			BootWait:       b.config.BootWait,
+			VNCEnabled:     !b.config.DisableVNC,
+			BootCommand:    b.config.FlatBootCommand(),
+			VMName:         b.config.VMName,
+			KeyboardLayout: b.config.BootKeyboardLayout,
+			Ctx:            b.config.ctx,
 		},
 		&communicator.StepConnect{
 			Config:    &b.config.SSHConfig.Comm,
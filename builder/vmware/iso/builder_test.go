@@ -56,6 +56,10 @@ func TestBuilderPrepare_Defaults(t *testing.T) {
 	if b.config.VMName != "packer-foo" {
 		t.Errorf("bad vm name: %s", b.config.VMName)
 	}
+
+	if b.config.Firmware != "bios" {
+		t.Errorf("bad firmware: %s", b.config.Firmware)
+	}
 }
 
 func TestBuilderPrepare_DiskSize(t *testing.T) {
@@ -221,6 +225,115 @@ func TestBuilderPrepare_Format(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_Firmware(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad
+	config["firmware"] = "uefi"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	for _, firmware := range []string{"bios", "efi", "efi-secure"} {
+		// Good
+		config["firmware"] = firmware
+		b = Builder{}
+		if _, err := b.Prepare(config); err != nil {
+			t.Fatalf("should not have error with %s: %s", firmware, err)
+		}
+	}
+}
+
+func TestBuilderPrepare_VTPM(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// VMware only powers on VMs with a virtual TPM once they are encrypted,
+	// which the builder doesn't do.
+	config["firmware"] = "efi-secure"
+	config["version"] = "14"
+	config["vtpm"] = true
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_AdditionalDisks(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["additional_disks"] = []map[string]interface{}{
+		{"size": 1024},
+		{"size": 2048, "adapter_type": "pvscsi"},
+	}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.AdditionalDisks[0].AdapterType != "lsilogic" {
+		t.Errorf("bad adapter type: %s", b.config.AdditionalDisks[0].AdapterType)
+	}
+
+	config["disk_additional_size"] = []uint{1024}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with disk_additional_size")
+	}
+
+	delete(config, "disk_additional_size")
+	config["additional_disks"] = []map[string]interface{}{
+		{"size": 1024, "adapter_type": "floppy"},
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with a bad adapter type")
+	}
+}
+
+func TestBuilderPrepare_AdditionalNetworks(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["additional_networks"] = []map[string]interface{}{
+		{"network": "VM Network 2"},
+	}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.AdditionalNetworks[0].AdapterType != "e1000" {
+		t.Errorf("bad adapter type: %s", b.config.AdditionalNetworks[0].AdapterType)
+	}
+
+	config["additional_networks"] = []map[string]interface{}{
+		{"adapter_type": "vmxnet3"},
+	}
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without network")
+	}
+}
+
+func TestBuilderPrepare_VApp(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["vapp"] = map[string]interface{}{
+		"product": "Appliance",
+		"properties": []map[string]interface{}{
+			{"key": "guestinfo.hostname", "user_configurable": true},
+		},
+	}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.VApp.Properties[0].UserConfigurable {
+		t.Errorf("bad: %#v", b.config.VApp)
+	}
+
+	config["format"] = "vmx"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error with the vmx format")
+	}
+}
+
 func TestBuilderPrepare_InvalidKey(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -465,3 +578,40 @@ func TestBuilderPrepare_CommConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestBuilderPrepare_IsolatedNetwork(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["isolated_network"] = true
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_GuestIPStrategies(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["guest_ip_strategies"] = []string{"vmware_tools", "arp"}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	// ESXi reports the address itself
+	b = Builder{}
+	config["remote_type"] = "esx5"
+	config["remote_host"] = "foobar.example.com"
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	vmwcommon "github.com/hashicorp/packer/builder/vmware/common"
@@ -39,8 +41,27 @@ type ESX5Driver struct {
 	comm      packer.Communicator
 	outputDir string
 	vmId      string
+
+	// The bytes of the current upload that are on the datastore, and its
+	// size, accessed atomically.
+	uploaded    int64
+	uploadTotal int64
+
+	// The size of the chunks of the uploads, uploadChunkSize by default.
+	chunkSize int64
 }
 
+const (
+	// uploadChunkSize is the size of the chunks the uploads are appended
+	// to the datastore in, so an interrupted upload resumes from the bytes
+	// the datastore received instead of from zero.
+	uploadChunkSize = 64 * 1024 * 1024
+
+	// uploadRetries is how many times an upload is resumed in a row
+	// before giving up.
+	uploadRetries = 5
+)
+
 func (d *ESX5Driver) Clone(dst, src string) error {
 	return errors.New("Cloning is not supported with the ESX driver.")
 }
@@ -126,7 +147,7 @@ func (d *ESX5Driver) UploadISO(localPath string, checksum string, checksumType s
 	}
 
 	log.Printf("Verifying checksum of %s", finalPath)
-	if d.verifyChecksum(checksumType, checksum, finalPath) {
+	if d.verifyChecksum(checksumType, checksum, finalPath, localPath) {
 		log.Println("Initial checksum matched, no upload needed.")
 		return finalPath, nil
 	}
@@ -135,9 +156,24 @@ func (d *ESX5Driver) UploadISO(localPath string, checksum string, checksumType s
 		return "", err
 	}
 
+	if !d.verifyChecksum(checksumType, checksum, finalPath, localPath) {
+		d.sh("rm", "-f", finalPath)
+		return "", fmt.Errorf("Checksum of the uploaded %s does not match", finalPath)
+	}
+
 	return finalPath, nil
 }
 
+// UploadProgress is the percentage of the current upload that is on the
+// datastore, or -1 if there is none.
+func (d *ESX5Driver) UploadProgress() int {
+	total := atomic.LoadInt64(&d.uploadTotal)
+	if total <= 0 {
+		return -1
+	}
+	return int(atomic.LoadInt64(&d.uploaded) * 100 / total)
+}
+
 func (d *ESX5Driver) RemoveCache(localPath string) error {
 	finalPath := d.cachePath(localPath)
 	log.Printf("Removing remote cache path %s (local %s)", finalPath, localPath)
@@ -575,18 +611,89 @@ func (d *ESX5Driver) mkdir(path string) error {
 	return d.sh("mkdir", "-p", path)
 }
 
+// upload appends src to a partial dst on the datastore, chunk by chunk,
+// resuming from the size of the partial file, which is left over when an
+// upload is interrupted, and moves it to dst once it is complete.
 func (d *ESX5Driver) upload(dst, src string) error {
 	f, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	return d.comm.Upload(dst, f, nil)
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	total := fi.Size()
+
+	chunkSize := d.chunkSize
+	if chunkSize <= 0 {
+		chunkSize = uploadChunkSize
+	}
+
+	atomic.StoreInt64(&d.uploaded, 0)
+	atomic.StoreInt64(&d.uploadTotal, total)
+	defer atomic.StoreInt64(&d.uploadTotal, 0)
+
+	partPath := dst + ".part"
+	for retries := 0; ; {
+		offset, err := d.remoteSize(partPath)
+		if err == nil && offset > total {
+			log.Printf("Removing %s, larger than %s", partPath, src)
+			err = d.sh("rm", "-f", partPath)
+			offset = 0
+		}
+		if err == nil {
+			atomic.StoreInt64(&d.uploaded, offset)
+			if offset == total {
+				break
+			}
+			if offset > 0 && retries == 0 {
+				log.Printf("Resuming upload of %s at %d bytes", src, offset)
+			}
+
+			size := chunkSize
+			if total-offset < size {
+				size = total - offset
+			}
+			_, err = d.run(io.NewSectionReader(f, offset, size), "cat", ">>", strconv.Quote(partPath))
+			if err == nil {
+				retries = 0
+				continue
+			}
+		}
+
+		retries++
+		if retries > uploadRetries {
+			return fmt.Errorf("Error uploading %s: %s", src, err)
+		}
+		log.Printf("Error uploading %s, resuming (%d/%d): %s", src, retries, uploadRetries, err)
+		time.Sleep(time.Duration(retries) * time.Second)
+	}
+
+	return d.sh("mv", "-f", strconv.Quote(partPath), strconv.Quote(dst))
 }
 
-func (d *ESX5Driver) verifyChecksum(ctype string, hash string, file string) bool {
+// remoteSize is the size of path on the datastore, 0 if it doesn't exist.
+func (d *ESX5Driver) remoteSize(path string) (int64, error) {
+	stdout, err := d.run(nil, "stat", "-c", "%s", strconv.Quote(path), "2>/dev/null", "||", "echo", "0")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(stdout), 10, 64)
+}
+
+// verifyChecksum tells whether file on the datastore matches the checksum,
+// or, without a checksum, has the size of the local file.
+func (d *ESX5Driver) verifyChecksum(ctype string, hash string, file string, localPath string) bool {
 	if ctype == "none" {
-		if err := d.sh("stat", file); err != nil {
+		fi, err := os.Stat(localPath)
+		if err != nil {
+			return false
+		}
+		size, err := d.remoteSize(file)
+		if err != nil || size != fi.Size() {
 			return false
 		}
 	} else {
@@ -2,11 +2,17 @@ package iso
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
 
 	vmwcommon "github.com/hashicorp/packer/builder/vmware/common"
 	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
 )
 
 func TestESX5Driver_implDriver(t *testing.T) {
@@ -90,3 +96,83 @@ func TestESX5Driver_CommHost(t *testing.T) {
 		t.Errorf("bad host name: %s", host)
 	}
 }
+
+// datastoreComm runs the commands of the uploads on files in memory, and
+// interrupts the next cat after failAfter bytes if it is set.
+type datastoreComm struct {
+	packer.MockCommunicator
+
+	files     map[string]string
+	failAfter int
+}
+
+func (c *datastoreComm) Start(rc *packer.RemoteCmd) error {
+	args := strings.Fields(rc.Command)
+	for i, arg := range args {
+		if unquoted, err := strconv.Unquote(arg); err == nil {
+			args[i] = unquoted
+		}
+	}
+
+	status := 0
+	switch args[0] {
+	case "stat":
+		fmt.Fprintf(rc.Stdout, "%d\n", len(c.files[args[3]]))
+	case "cat":
+		data, _ := ioutil.ReadAll(rc.Stdin)
+		if c.failAfter > 0 {
+			data = data[:c.failAfter]
+			c.failAfter = 0
+			status = 1
+		}
+		c.files[args[2]] += string(data)
+	case "mv":
+		c.files[args[3]] = c.files[args[2]]
+		delete(c.files, args[2])
+	case "rm":
+		delete(c.files, args[2])
+	default:
+		status = 255
+	}
+
+	go rc.SetExited(status)
+	return nil
+}
+
+func TestESX5Driver_upload(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	io.WriteString(tf, "0123456789")
+	tf.Close()
+
+	// Resume an interrupted upload, and a chunk interrupted again
+	comm := &datastoreComm{
+		files:     map[string]string{"/vmfs/volumes/ds/foo.iso.part": "012"},
+		failAfter: 2,
+	}
+	driver := ESX5Driver{comm: comm, chunkSize: 4}
+	if err := driver.upload("/vmfs/volumes/ds/foo.iso", tf.Name()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(comm.files) != 1 || comm.files["/vmfs/volumes/ds/foo.iso"] != "0123456789" {
+		t.Fatalf("bad: %#v", comm.files)
+	}
+	if driver.UploadProgress() != -1 {
+		t.Fatalf("bad: %d", driver.UploadProgress())
+	}
+
+	// A partial file larger than the source is uploaded again
+	comm = &datastoreComm{
+		files: map[string]string{"/vmfs/volumes/ds/foo.iso.part": "0123456789abc"},
+	}
+	driver = ESX5Driver{comm: comm, chunkSize: 4}
+	if err := driver.upload("/vmfs/volumes/ds/foo.iso", tf.Name()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(comm.files) != 1 || comm.files["/vmfs/volumes/ds/foo.iso"] != "0123456789" {
+		t.Fatalf("bad: %#v", comm.files)
+	}
+}
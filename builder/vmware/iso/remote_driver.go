@@ -12,6 +12,10 @@ type RemoteDriver interface {
 	// exists.
 	UploadISO(string, string, string) (string, error)
 
+	// UploadProgress is the percentage of the current upload that is done,
+	// or -1 if there is none.
+	UploadProgress() int
+
 	// RemoveCache deletes localPath from the remote cache.
 	RemoveCache(localPath string) error
 
@@ -12,6 +12,8 @@ type RemoteDriverMock struct {
 	UploadISOResult string
 	UploadISOErr    error
 
+	UploadProgressResult int
+
 	RegisterCalled bool
 	RegisterPath   string
 	RegisterErr    error
@@ -38,6 +40,10 @@ func (d *RemoteDriverMock) UploadISO(path string, checksum string, checksumType
 	return d.UploadISOResult, d.UploadISOErr
 }
 
+func (d *RemoteDriverMock) UploadProgress() int {
+	return d.UploadProgressResult
+}
+
 func (d *RemoteDriverMock) Register(path string) error {
 	d.RegisterCalled = true
 	d.RegisterPath = path
@@ -45,12 +45,24 @@ func (stepCreateDisk) Run(_ context.Context, state multistep.StateBag) multistep
 		}
 	}
 
+	// The disks of disk_additional_size use the adapter type of the main
+	// disk, the ones of additional_disks have their own
+	diskAdapterTypes := make([]string, len(diskFullPaths))
+	for i := range diskAdapterTypes {
+		diskAdapterTypes[i] = config.DiskAdapterType
+	}
+	for i, disk := range config.AdditionalDisks {
+		path := filepath.Join(config.OutputDir, fmt.Sprintf("%s-%d.vmdk", config.DiskName, i+1))
+		diskFullPaths = append(diskFullPaths, path)
+		diskSizes = append(diskSizes, fmt.Sprintf("%dM", uint64(disk.Size)))
+		diskAdapterTypes = append(diskAdapterTypes, diskAdapter(disk.AdapterType))
+	}
+
 	// Create all required disks
 	for i, diskFullPath := range diskFullPaths {
 		log.Printf("[INFO] Creating disk with Path: %s and Size: %s", diskFullPath, diskSizes[i])
-		// Additional disks currently use the same adapter type and disk
-		// type as specified for the main disk
-		if err := driver.CreateDisk(diskFullPath, diskSizes[i], config.DiskAdapterType, config.DiskTypeId); err != nil {
+		// The disk type is the one specified for the main disk
+		if err := driver.CreateDisk(diskFullPath, diskSizes[i], diskAdapterTypes[i], config.DiskTypeId); err != nil {
 			err := fmt.Errorf("Error creating disk: %s", err)
 			state.Put("error", err)
 			ui.Error(err.Error())
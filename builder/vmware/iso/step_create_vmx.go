@@ -464,10 +464,12 @@ func (s *stepCreateVMX) Run(_ context.Context, state multistep.StateBag) multist
 
 	// check to see if the driver implements a network mapper for mapping
 	// the network-type to its device-name.
+	var netmap vmwcommon.NetworkNameMapper
 	if driver.NetworkMapper != nil {
 
 		// read network map configuration into a NetworkNameMapper.
-		netmap, err := driver.NetworkMapper()
+		var err error
+		netmap, err = driver.NetworkMapper()
 		if err != nil {
 			state.Put("error", err)
 			ui.Error(err.Error())
@@ -617,8 +619,28 @@ func (s *stepCreateVMX) Run(_ context.Context, state multistep.StateBag) multist
 		s.tempDir = vmxDir
 	}
 
+	// The firmware and the additional devices are set on top of the
+	// template, custom ones included
+	disksData, err := additionalDisksVMXData(config, &templateData)
+	if err != nil {
+		err := fmt.Errorf("Error adding the additional disks: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	vmxData := vmwcommon.ParseVMX(vmxContents)
+	for _, data := range []map[string]string{
+		firmwareVMXData(config),
+		disksData,
+		additionalNetworksVMXData(config, netmap),
+	} {
+		for k, v := range data {
+			vmxData[k] = v
+		}
+	}
+
 	vmxPath := filepath.Join(vmxDir, config.VMName+".vmx")
-	if err := vmwcommon.WriteVMX(vmxPath, vmwcommon.ParseVMX(vmxContents)); err != nil {
+	if err := vmwcommon.WriteVMX(vmxPath, vmxData); err != nil {
 		err := fmt.Errorf("Error creating VMX file: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -630,6 +652,113 @@ func (s *stepCreateVMX) Run(_ context.Context, state multistep.StateBag) multist
 	return multistep.ActionContinue
 }
 
+// firmwareVMXData returns the VMX keys of the firmware of the VM, with
+// Secure Boot for efi-secure, and of its virtual TPM. A virtual TPM only works
+// on a VM that's encrypted.
+func firmwareVMXData(config *Config) map[string]string {
+	data := make(map[string]string)
+	switch config.Firmware {
+	case "efi":
+		data["firmware"] = "efi"
+	case "efi-secure":
+		data["firmware"] = "efi"
+		data["uefi.secureboot.enabled"] = "TRUE"
+	}
+	return data
+}
+
+// diskAdapter returns the adapter of the type of a disk, scsi being the
+// default SCSI adapter.
+func diskAdapter(adapterType string) string {
+	adapter := strings.ToLower(adapterType)
+	if adapter == "scsi" {
+		return "lsilogic"
+	}
+	return adapter
+}
+
+// additionalDisksVMXData returns the VMX keys of the additional disks, each on
+// the first free unit of the controller of its adapter type. The SCSI
+// adapters each get their own controller, the one of the main disk included.
+func additionalDisksVMXData(config *Config, data *vmxTemplateData) (map[string]string, error) {
+	vmx := make(map[string]string)
+	used := map[string]bool{
+		fmt.Sprintf("%s0:0", data.DiskType):                                true,
+		fmt.Sprintf("%s0:%s", data.CDROMType, data.CDROMType_MasterSlave): true,
+	}
+	scsi := make(map[string]int)
+	if data.SCSI_Present == "TRUE" {
+		scsi[data.SCSI_diskAdapterType] = 0
+	}
+
+	for i, disk := range config.AdditionalDisks {
+		var bus string
+		var units int
+		switch adapter := diskAdapter(disk.AdapterType); adapter {
+		case "sata":
+			bus, units = "sata0", 30
+		case "nvme":
+			bus, units = "nvme0", 15
+		default:
+			index, ok := scsi[adapter]
+			if !ok {
+				index = len(scsi)
+				if index > 3 {
+					return nil, fmt.Errorf("a VM has at most 4 SCSI controllers, %s can't have one", adapter)
+				}
+				scsi[adapter] = index
+				vmx[fmt.Sprintf("scsi%d.virtualdev", index)] = adapter
+			}
+			bus, units = fmt.Sprintf("scsi%d", index), 16
+		}
+		vmx[bus+".present"] = "TRUE"
+
+		slot := ""
+		for unit := 0; unit < units; unit++ {
+			// The unit 7 of a SCSI bus is the one of its controller
+			if strings.HasPrefix(bus, "scsi") && unit == 7 {
+				continue
+			}
+			if s := fmt.Sprintf("%s:%d", bus, unit); !used[s] {
+				slot = s
+				break
+			}
+		}
+		if slot == "" {
+			return nil, fmt.Errorf("no unit left on %s", bus)
+		}
+		used[slot] = true
+
+		vmx[slot+".present"] = "TRUE"
+		vmx[slot+".filename"] = fmt.Sprintf("%s-%d.vmdk", config.DiskName, i+1)
+	}
+
+	return vmx, nil
+}
+
+// additionalNetworksVMXData returns the VMX keys of the additional network
+// adapters, from ethernet1 on. Without a network mapper, as on ESXi, the
+// network is the name of a port group.
+func additionalNetworksVMXData(config *Config, netmap vmwcommon.NetworkNameMapper) map[string]string {
+	vmx := make(map[string]string)
+	for i, network := range config.AdditionalNetworks {
+		ethernet := fmt.Sprintf("ethernet%d", i+1)
+		vmx[ethernet+".present"] = "TRUE"
+		vmx[ethernet+".addresstype"] = "generated"
+		vmx[ethernet+".virtualdev"] = strings.ToLower(network.AdapterType)
+
+		if netmap == nil {
+			vmx[ethernet+".networkname"] = network.Network
+		} else if devices, err := netmap.NameIntoDevices(network.Network); err == nil && len(devices) > 0 {
+			vmx[ethernet+".connectiontype"] = network.Network
+		} else {
+			vmx[ethernet+".connectiontype"] = "custom"
+			vmx[ethernet+".vnet"] = network.Network
+		}
+	}
+	return vmx
+}
+
 func (s *stepCreateVMX) Cleanup(multistep.StateBag) {
 	if s.tempDir != "" {
 		os.RemoveAll(s.tempDir)
@@ -8,12 +8,14 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
 
 	"testing"
 
+	vmwcommon "github.com/hashicorp/packer/builder/vmware/common"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/provisioner/shell"
 	"github.com/hashicorp/packer/template"
@@ -408,3 +410,127 @@ func TestStepCreateVmx_Sound(t *testing.T) {
 		t.Errorf("Soundcard not detected : %v", data)
 	}
 }
+
+func TestFirmwareVMXData(t *testing.T) {
+	cases := []struct {
+		config   Config
+		expected map[string]string
+	}{
+		{Config{Firmware: "bios"}, map[string]string{}},
+		{Config{Firmware: "efi"}, map[string]string{"firmware": "efi"}},
+		{
+			Config{Firmware: "efi-secure"},
+			map[string]string{
+				"firmware":                "efi",
+				"uefi.secureboot.enabled": "TRUE",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		data := firmwareVMXData(&tc.config)
+		if !reflect.DeepEqual(data, tc.expected) {
+			t.Errorf("bad data for %s: %#v", tc.config.Firmware, data)
+		}
+	}
+}
+
+func TestAdditionalDisksVMXData(t *testing.T) {
+	config := &Config{
+		DiskName: "disk",
+		AdditionalDisks: []AdditionalDisk{
+			{Size: 1024, AdapterType: "lsilogic"},
+			{Size: 1024, AdapterType: "pvscsi"},
+			{Size: 1024, AdapterType: "nvme"},
+			{Size: 1024, AdapterType: "sata"},
+		},
+	}
+	// The main disk on scsi0, with the cdrom on sata0:0
+	templateData := &vmxTemplateData{
+		SCSI_Present:          "TRUE",
+		SCSI_diskAdapterType:  "lsilogic",
+		DiskType:              "scsi",
+		CDROMType:             "sata",
+		CDROMType_MasterSlave: "0",
+	}
+
+	data, err := additionalDisksVMXData(config, templateData)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]string{
+		"scsi0.present":    "TRUE",
+		"scsi0:1.present":  "TRUE",
+		"scsi0:1.filename": "disk-1.vmdk",
+		"scsi1.present":    "TRUE",
+		"scsi1.virtualdev": "pvscsi",
+		"scsi1:0.present":  "TRUE",
+		"scsi1:0.filename": "disk-2.vmdk",
+		"nvme0.present":    "TRUE",
+		"nvme0:0.present":  "TRUE",
+		"nvme0:0.filename": "disk-3.vmdk",
+		"sata0.present":    "TRUE",
+		"sata0:1.present":  "TRUE",
+		"sata0:1.filename": "disk-4.vmdk",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("bad data: %#v", data)
+	}
+}
+
+func TestAdditionalDisksVMXData_SkipsControllerUnit(t *testing.T) {
+	config := &Config{DiskName: "disk"}
+	for i := 0; i < 7; i++ {
+		config.AdditionalDisks = append(config.AdditionalDisks, AdditionalDisk{Size: 1024, AdapterType: "scsi"})
+	}
+	templateData := &vmxTemplateData{
+		SCSI_Present:          "TRUE",
+		SCSI_diskAdapterType:  "lsilogic",
+		DiskType:              "scsi",
+		CDROMType:             "ide",
+		CDROMType_MasterSlave: "0",
+	}
+
+	data, err := additionalDisksVMXData(config, templateData)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := data["scsi0:7.present"]; ok {
+		t.Fatalf("the unit of the controller should be skipped: %#v", data)
+	}
+	if data["scsi0:8.filename"] != "disk-7.vmdk" {
+		t.Fatalf("bad data: %#v", data)
+	}
+}
+
+func TestAdditionalNetworksVMXData(t *testing.T) {
+	config := &Config{
+		AdditionalNetworks: []AdditionalNetwork{
+			{Network: "hostonly", AdapterType: "e1000"},
+			{Network: "vmnet5", AdapterType: "VMXNET3"},
+		},
+	}
+	netmap := vmwcommon.NetworkMap{{"name": "hostonly", "device": "vmnet1"}}
+
+	data := additionalNetworksVMXData(config, netmap)
+	expected := map[string]string{
+		"ethernet1.present":        "TRUE",
+		"ethernet1.addresstype":    "generated",
+		"ethernet1.virtualdev":     "e1000",
+		"ethernet1.connectiontype": "hostonly",
+		"ethernet2.present":        "TRUE",
+		"ethernet2.addresstype":    "generated",
+		"ethernet2.virtualdev":     "vmxnet3",
+		"ethernet2.connectiontype": "custom",
+		"ethernet2.vnet":           "vmnet5",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("bad data: %#v", data)
+	}
+
+	// Without a network mapper, the networks are port groups
+	data = additionalNetworksVMXData(config, nil)
+	if data["ethernet1.networkname"] != "hostonly" || data["ethernet2.networkname"] != "vmnet5" {
+		t.Fatalf("bad data: %#v", data)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 
@@ -72,20 +74,76 @@ func (s *StepExport) Run(_ context.Context, state multistep.StateBag) multistep.
 	}
 
 	ui.Say("Exporting virtual machine...")
-	ui.Message(fmt.Sprintf("Executing: %s %s", ovftool, strings.Join(s.generateArgs(c, true), " ")))
-	var out bytes.Buffer
-	cmd := exec.Command(ovftool, s.generateArgs(c, false)...)
-	cmd.Stdout = &out
-	if err := cmd.Run(); err != nil {
-		err := fmt.Errorf("Error exporting virtual machine: %s\n%s\n", err, out.String())
+	if c.VApp.empty() {
+		if err := runOVFTool(ui, ovftool, s.generateArgs(c, false), s.generateArgs(c, true)); err != nil {
+			err := fmt.Errorf("Error exporting virtual machine: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		return multistep.ActionContinue
+	}
+
+	// The vApp is added to the OVF descriptor, so an OVA is exported as an
+	// OVF first, and packed once the descriptor has the vApp
+	ovfExport := &StepExport{Format: "ovf", OutputDir: s.OutputDir}
+	if s.Format == "ova" {
+		tempDir, err := ioutil.TempDir(filepath.Dir(s.OutputDir), "packer-ovf")
+		if err != nil {
+			err := fmt.Errorf("Error exporting virtual machine: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		defer os.RemoveAll(tempDir)
+		ovfExport.OutputDir = tempDir
+	}
+
+	if err := runOVFTool(ui, ovftool, ovfExport.generateArgs(c, false), ovfExport.generateArgs(c, true)); err != nil {
+		err := fmt.Errorf("Error exporting virtual machine: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	ui.Message(fmt.Sprintf("%s", out.String()))
+	ui.Say("Adding the vApp to the OVF descriptor...")
+	ovfPath, err := findOVF(ovfExport.OutputDir)
+	if err == nil {
+		err = injectVApp(ovfPath, &c.VApp)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error adding the vApp: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if s.Format == "ova" {
+		args := []string{"-tt=ova", ovfPath, s.OutputDir}
+		if err := runOVFTool(ui, ovftool, args, args); err != nil {
+			err := fmt.Errorf("Error packing the OVA: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
 
 	return multistep.ActionContinue
 }
 
+// runOVFTool runs ovftool with the arguments, displaying the ones with the
+// password hidden.
+func runOVFTool(ui packer.Ui, ovftool string, args []string, displayArgs []string) error {
+	ui.Message(fmt.Sprintf("Executing: %s %s", ovftool, strings.Join(displayArgs, " ")))
+	var out bytes.Buffer
+	cmd := exec.Command(ovftool, args...)
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s\n%s\n", err, out.String())
+	}
+
+	ui.Message(fmt.Sprintf("%s", out.String()))
+	return nil
+}
+
 func (s *StepExport) Cleanup(state multistep.StateBag) {}
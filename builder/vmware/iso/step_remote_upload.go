@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	vmwcommon "github.com/hashicorp/packer/builder/vmware/common"
 	"github.com/hashicorp/packer/helper/multistep"
@@ -16,6 +17,10 @@ type stepRemoteUpload struct {
 	Key       string
 	Message   string
 	DoCleanup bool
+
+	// Verify the upload against the checksum of the ISO, instead of its
+	// size only.
+	Checksum bool
 }
 
 func (s *stepRemoteUpload) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -32,13 +37,40 @@ func (s *stepRemoteUpload) Run(_ context.Context, state multistep.StateBag) mult
 		return multistep.ActionContinue
 	}
 
-	config := state.Get("config").(*Config)
-	checksum := config.ISOChecksum
-	checksumType := config.ISOChecksumType
+	checksum := ""
+	checksumType := "none"
+	if s.Checksum {
+		config := state.Get("config").(*Config)
+		checksum = config.ISOChecksum
+		checksumType = config.ISOChecksumType
+	}
 
 	ui.Say(s.Message)
 	log.Printf("Remote uploading: %s", path)
-	newPath, err := remote.UploadISO(path, checksum, checksumType)
+
+	var newPath string
+	uploadCompleteCh := make(chan error, 1)
+	go func() {
+		var err error
+		newPath, err = remote.UploadISO(path, checksum, checksumType)
+		uploadCompleteCh <- err
+	}()
+
+	progressTicker := time.NewTicker(5 * time.Second)
+	defer progressTicker.Stop()
+
+	var err error
+	for uploading := true; uploading; {
+		select {
+		case err = <-uploadCompleteCh:
+			uploading = false
+		case <-progressTicker.C:
+			progress := remote.UploadProgress()
+			if progress >= 0 {
+				ui.Message(fmt.Sprintf("Upload progress: %d%%", progress))
+			}
+		}
+	}
 	if err != nil {
 		err := fmt.Errorf("Error uploading file: %s", err)
 		state.Put("error", err)
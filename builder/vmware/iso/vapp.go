@@ -0,0 +1,198 @@
+package iso
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// VAppConfig is the product and the properties of the vApp of the exported
+// VM, which the tools deploying the OVF ask for and pass to the guest.
+type VAppConfig struct {
+	Product    string         `mapstructure:"product"`
+	Vendor     string         `mapstructure:"vendor"`
+	Version    string         `mapstructure:"version"`
+	Properties []VAppProperty `mapstructure:"properties"`
+}
+
+// VAppProperty is a property of a vApp.
+type VAppProperty struct {
+	Key              string `mapstructure:"key"`
+	Type             string `mapstructure:"type"`
+	Value            string `mapstructure:"value"`
+	Label            string `mapstructure:"label"`
+	Description      string `mapstructure:"description"`
+	UserConfigurable bool   `mapstructure:"user_configurable"`
+}
+
+func (c *VAppConfig) empty() bool {
+	return c.Product == "" && c.Vendor == "" && c.Version == "" && len(c.Properties) == 0
+}
+
+func (c *VAppConfig) Prepare() []error {
+	var errs []error
+	keys := make(map[string]bool)
+	for i := range c.Properties {
+		p := &c.Properties[i]
+		if p.Key == "" {
+			errs = append(errs, fmt.Errorf("vapp.properties[%d]: key is required", i))
+		} else if keys[p.Key] {
+			errs = append(errs, fmt.Errorf("vapp.properties[%d]: duplicate key %s", i, p.Key))
+		}
+		keys[p.Key] = true
+
+		if p.Type == "" {
+			p.Type = "string"
+		}
+		switch p.Type {
+		case "string", "boolean", "int", "real", "password":
+		default:
+			errs = append(errs, fmt.Errorf("vapp.properties[%d]: type must be one of string, "+
+				"boolean, int, real or password", i))
+		}
+	}
+	return errs
+}
+
+// productSection returns the ProductSection of the vApp in an OVF descriptor.
+func (c *VAppConfig) productSection() string {
+	var b bytes.Buffer
+	b.WriteString("    <ProductSection ovf:required=\"false\">\n")
+	b.WriteString("      <Info>Information about the installed software</Info>\n")
+	for _, e := range []struct{ name, value string }{
+		{"Product", c.Product},
+		{"Vendor", c.Vendor},
+		{"Version", c.Version},
+	} {
+		if e.value != "" {
+			fmt.Fprintf(&b, "      <%s>%s</%s>\n", e.name, xmlEscape(e.value), e.name)
+		}
+	}
+	for _, p := range c.Properties {
+		// Passwords are strings that aren't displayed
+		typ, password := p.Type, ""
+		if typ == "password" {
+			typ, password = "string", ` ovf:password="true"`
+		}
+		fmt.Fprintf(&b, "      <Property ovf:key=\"%s\" ovf:type=\"%s\" ovf:userConfigurable=\"%t\" ovf:value=\"%s\"%s>\n",
+			xmlEscape(p.Key), typ, p.UserConfigurable, xmlEscape(p.Value), password)
+		if p.Label != "" {
+			fmt.Fprintf(&b, "        <Label>%s</Label>\n", xmlEscape(p.Label))
+		}
+		if p.Description != "" {
+			fmt.Fprintf(&b, "        <Description>%s</Description>\n", xmlEscape(p.Description))
+		}
+		b.WriteString("      </Property>\n")
+	}
+	b.WriteString("    </ProductSection>\n")
+	return b.String()
+}
+
+var reVirtualHardwareSection = regexp.MustCompile(`<VirtualHardwareSection\b[^>]*>`)
+
+// injectVApp adds the vApp to the OVF descriptor, with the transport of the
+// properties to the guest through VMware Tools, and updates the digest of
+// the descriptor in its manifest, if there is one.
+func injectVApp(ovfPath string, vapp *VAppConfig) error {
+	contents, err := ioutil.ReadFile(ovfPath)
+	if err != nil {
+		return err
+	}
+	ovf := string(contents)
+
+	end := strings.LastIndex(ovf, "</VirtualSystem>")
+	if end < 0 {
+		return fmt.Errorf("no VirtualSystem in %s", ovfPath)
+	}
+	ovf = ovf[:end] + vapp.productSection() + "  " + ovf[end:]
+
+	if len(vapp.Properties) > 0 {
+		ovf = reVirtualHardwareSection.ReplaceAllStringFunc(ovf, func(section string) string {
+			if strings.Contains(section, "ovf:transport=") {
+				return section
+			}
+			return strings.Replace(section, "<VirtualHardwareSection", `<VirtualHardwareSection ovf:transport="com.vmware.guestInfo"`, 1)
+		})
+	}
+
+	if err := ioutil.WriteFile(ovfPath, []byte(ovf), 0644); err != nil {
+		return err
+	}
+
+	mfPath := strings.TrimSuffix(ovfPath, filepath.Ext(ovfPath)) + ".mf"
+	if _, err := os.Stat(mfPath); os.IsNotExist(err) {
+		return nil
+	}
+	return updateManifest(mfPath, filepath.Base(ovfPath), []byte(ovf))
+}
+
+var reManifestLine = regexp.MustCompile(`^(SHA1|SHA256|SHA512)\((.+)\)\s*=\s*[0-9a-fA-F]+$`)
+
+// updateManifest sets the digest of the file in the manifest, with the
+// algorithm the manifest already uses for it.
+func updateManifest(mfPath string, name string, contents []byte) error {
+	mf, err := ioutil.ReadFile(mfPath)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(mf), "\n")
+	for i, line := range lines {
+		m := reManifestLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil || m[2] != name {
+			continue
+		}
+
+		var h hash.Hash
+		switch m[1] {
+		case "SHA1":
+			h = sha1.New()
+		case "SHA256":
+			h = sha256.New()
+		case "SHA512":
+			h = sha512.New()
+		}
+		h.Write(contents)
+		lines[i] = fmt.Sprintf("%s(%s)= %s", m[1], name, hex.EncodeToString(h.Sum(nil)))
+	}
+
+	return ioutil.WriteFile(mfPath, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+// findOVF returns the path of the OVF descriptor ovftool exported in the
+// directory, which may be in a subdirectory named after the VM.
+func findOVF(dir string) (string, error) {
+	var ovfPath string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if ovfPath == "" && !info.IsDir() && strings.EqualFold(filepath.Ext(path), ".ovf") {
+			ovfPath = path
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if ovfPath == "" {
+		return "", fmt.Errorf("no OVF descriptor exported in %s", dir)
+	}
+	return ovfPath, nil
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
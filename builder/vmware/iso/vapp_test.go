@@ -0,0 +1,107 @@
+package iso
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOVF = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <VirtualSystem ovf:id="packer">
+    <Info>A virtual machine</Info>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements</Info>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+
+func TestVAppConfigPrepare(t *testing.T) {
+	c := &VAppConfig{
+		Properties: []VAppProperty{{Key: "guestinfo.hostname"}},
+	}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.Properties[0].Type != "string" {
+		t.Fatalf("bad type: %s", c.Properties[0].Type)
+	}
+
+	for _, properties := range [][]VAppProperty{
+		{{Type: "string"}},
+		{{Key: "a"}, {Key: "a"}},
+		{{Key: "a", Type: "uint128"}},
+	} {
+		c := &VAppConfig{Properties: properties}
+		if errs := c.Prepare(); len(errs) == 0 {
+			t.Fatalf("should have error with %#v", properties)
+		}
+	}
+}
+
+func TestInjectVApp(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	ovfPath := filepath.Join(td, "packer", "packer.ovf")
+	os.MkdirAll(filepath.Dir(ovfPath), 0755)
+	if err := ioutil.WriteFile(ovfPath, []byte(testOVF), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	mfPath := filepath.Join(td, "packer", "packer.mf")
+	mf := "SHA256(packer.ovf)= 00\nSHA256(packer-disk1.vmdk)= 01\n"
+	if err := ioutil.WriteFile(mfPath, []byte(mf), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	found, err := findOVF(td)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if found != ovfPath {
+		t.Fatalf("bad ovf: %s", found)
+	}
+
+	vapp := &VAppConfig{
+		Product: "Appliance",
+		Version: "1.0",
+		Properties: []VAppProperty{
+			{Key: "guestinfo.hostname", Type: "string", Label: "Hostname", UserConfigurable: true},
+			{Key: "guestinfo.password", Type: "password", Value: "a<b"},
+		},
+	}
+	if err := injectVApp(ovfPath, vapp); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	contents, _ := ioutil.ReadFile(ovfPath)
+	ovf := string(contents)
+	for _, expected := range []string{
+		`<VirtualHardwareSection ovf:transport="com.vmware.guestInfo">`,
+		`<Product>Appliance</Product>`,
+		`<Version>1.0</Version>`,
+		`<Property ovf:key="guestinfo.hostname" ovf:type="string" ovf:userConfigurable="true" ovf:value="">`,
+		`<Label>Hostname</Label>`,
+		`<Property ovf:key="guestinfo.password" ovf:type="string" ovf:userConfigurable="false" ovf:value="a&lt;b" ovf:password="true">`,
+		"</ProductSection>\n  </VirtualSystem>",
+	} {
+		if !strings.Contains(ovf, expected) {
+			t.Errorf("ovf should contain %s:\n%s", expected, ovf)
+		}
+	}
+
+	sum := sha256.Sum256(contents)
+	contents, _ = ioutil.ReadFile(mfPath)
+	expected := "SHA256(packer.ovf)= " + hex.EncodeToString(sum[:]) + "\nSHA256(packer-disk1.vmdk)= 01\n"
+	if string(contents) != expected {
+		t.Fatalf("bad manifest: %s", contents)
+	}
+}
@@ -0,0 +1,49 @@
+package vultr
+
+import (
+	"fmt"
+	"log"
+)
+
+type Artifact struct {
+	// The ID of the snapshot
+	snapshotId string
+
+	// The description of the snapshot
+	description string
+
+	// The client for making API calls
+	client *Client
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	// No files with Vultr
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return a.snapshotId
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("A snapshot was created: '%v' (ID: %v)", a.description, a.snapshotId)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "SnapshotId":
+		return a.snapshotId
+	case "SnapshotDescription":
+		return a.description
+	}
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying snapshot: %s (%s)", a.snapshotId, a.description)
+	return a.client.DeleteSnapshot(a.snapshotId)
+}
@@ -0,0 +1,23 @@
+package vultr
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestArtifactString(t *testing.T) {
+	a := &Artifact{snapshotId: "abc", description: "packer-foobar"}
+	expected := "A snapshot was created: 'packer-foobar' (ID: abc)"
+
+	if a.String() != expected {
+		t.Fatalf("artifact string should match: %v", expected)
+	}
+	if a.Id() != "abc" {
+		t.Fatalf("bad: %s", a.Id())
+	}
+}
@@ -0,0 +1,139 @@
+package vultr
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"api_key":   "bar",
+		"region_id": "ewr",
+		"plan_id":   "vc2-1c-1gb",
+		"os_id":     387,
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare_InvalidKey(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Add a random key
+	config["i_should_not_be_valid"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	warnings, err := b.Prepare(testConfig())
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.APIURL != DefaultAPIURL {
+		t.Errorf("bad api url: %s", b.config.APIURL)
+	}
+	if !strings.HasPrefix(b.config.InstanceLabel, "packer-") {
+		t.Errorf("bad instance label: %s", b.config.InstanceLabel)
+	}
+	if !strings.HasPrefix(b.config.SnapshotDescription, "packer-") {
+		t.Errorf("bad snapshot description: %s", b.config.SnapshotDescription)
+	}
+	if b.config.StateTimeout != 10*time.Minute {
+		t.Errorf("bad state timeout: %s", b.config.StateTimeout)
+	}
+	if b.config.Comm.SSHUsername != "root" {
+		t.Errorf("bad ssh username: %s", b.config.Comm.SSHUsername)
+	}
+}
+
+func TestBuilderPrepare_Required(t *testing.T) {
+	old := os.Getenv("VULTR_API_KEY")
+	os.Setenv("VULTR_API_KEY", "")
+	defer os.Setenv("VULTR_API_KEY", old)
+
+	for _, key := range []string{"api_key", "region_id", "plan_id", "os_id"} {
+		var b Builder
+		config := testConfig()
+		delete(config, key)
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error without %s", key)
+		}
+	}
+}
+
+func TestBuilderPrepare_Source(t *testing.T) {
+	for _, source := range []map[string]interface{}{
+		{"app_id": 37},
+		{"image_id": "openlitespeed-wordpress"},
+		{"iso_id": "3b67f3e1-0d83-4d0b-8b7e-bc4c5e7c2ab1"},
+		{"snapshot_id": "5359435d-abcd-1234-abcd-bd6a2b9a6059"},
+	} {
+		var b Builder
+		config := testConfig()
+		delete(config, "os_id")
+		for k, v := range source {
+			config[k] = v
+		}
+		if _, err := b.Prepare(config); err != nil {
+			t.Fatalf("should not have error with %v: %s", source, err)
+		}
+
+		// Only one source
+		config["os_id"] = 387
+		b = Builder{}
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error with os_id and %v", source)
+		}
+	}
+}
+
+func TestBuilderPrepare_AppVariables(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["app_variables"] = map[string]string{"domain": "example.com"}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error without a marketplace app")
+	}
+
+	delete(config, "os_id")
+	config["image_id"] = "openlitespeed-wordpress"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.AppVariables["domain"] != "example.com" {
+		t.Fatalf("bad: %#v", b.config.AppVariables)
+	}
+}
+
+func TestBuilderPrepare_UserData(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["user_data"] = "foo"
+	config["user_data_file"] = "bar"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,198 @@
+package vultr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultAPIURL is the Vultr API endpoint.
+const DefaultAPIURL = "https://api.vultr.com/v2/"
+
+// NOTE: there is no Vultr SDK vendored, so the few calls the builder makes
+// are done with the client here.
+
+// Client is a client of the Vultr API.
+type Client struct {
+	BaseURL string
+	APIKey  string
+
+	HTTPClient *http.Client
+}
+
+// Instance is a Vultr virtual machine.
+type Instance struct {
+	ID              string `json:"id"`
+	Label           string `json:"label"`
+	MainIP          string `json:"main_ip"`
+	Status          string `json:"status"`
+	PowerStatus     string `json:"power_status"`
+	ServerStatus    string `json:"server_status"`
+	DefaultPassword string `json:"default_password"`
+}
+
+// InstanceCreateRequest is the request of a new instance. Exactly one of the
+// sources, an OS, an application, a marketplace image, an ISO or a snapshot,
+// is set.
+type InstanceCreateRequest struct {
+	Region       string            `json:"region"`
+	Plan         string            `json:"plan"`
+	OSID         int               `json:"os_id,omitempty"`
+	AppID        int               `json:"app_id,omitempty"`
+	ImageID      string            `json:"image_id,omitempty"`
+	ISOID        string            `json:"iso_id,omitempty"`
+	SnapshotID   string            `json:"snapshot_id,omitempty"`
+	AppVariables map[string]string `json:"app_variables,omitempty"`
+	Label        string            `json:"label,omitempty"`
+	Hostname     string            `json:"hostname,omitempty"`
+	SSHKeyIDs    []string          `json:"sshkey_id,omitempty"`
+	ScriptID     string            `json:"script_id,omitempty"`
+	EnableIPv6   bool              `json:"enable_ipv6,omitempty"`
+	AttachVPC    []string          `json:"attach_vpc,omitempty"`
+	UserData     string            `json:"user_data,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+}
+
+// SSHKey is an SSH key of the account, set up on the instances that are
+// created with it.
+type SSHKey struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	SSHKey string `json:"ssh_key"`
+}
+
+// Snapshot is a snapshot of an instance.
+type Snapshot struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Size        int64  `json:"size"`
+}
+
+// ErrorResponse is the error returned by the API.
+type ErrorResponse struct {
+	StatusCode int
+	Message    string `json:"error"`
+}
+
+func (e *ErrorResponse) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Vultr API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("Vultr API error: %d %s", e.StatusCode, e.Message)
+}
+
+// CreateSSHKey adds an SSH key to the account.
+func (c *Client) CreateSSHKey(name string, key string) (*SSHKey, error) {
+	var result struct {
+		SSHKey *SSHKey `json:"ssh_key"`
+	}
+	err := c.do("POST", "ssh-keys", &SSHKey{Name: name, SSHKey: key}, &result)
+	return result.SSHKey, err
+}
+
+// DeleteSSHKey deletes an SSH key.
+func (c *Client) DeleteSSHKey(id string) error {
+	return c.do("DELETE", fmt.Sprintf("ssh-keys/%s", id), nil, nil)
+}
+
+// CreateInstance deploys an instance.
+func (c *Client) CreateInstance(req *InstanceCreateRequest) (*Instance, error) {
+	var result struct {
+		Instance *Instance `json:"instance"`
+	}
+	err := c.do("POST", "instances", req, &result)
+	return result.Instance, err
+}
+
+// GetInstance returns an instance.
+func (c *Client) GetInstance(id string) (*Instance, error) {
+	var result struct {
+		Instance *Instance `json:"instance"`
+	}
+	err := c.do("GET", fmt.Sprintf("instances/%s", id), nil, &result)
+	return result.Instance, err
+}
+
+// HaltInstance powers the instance off.
+func (c *Client) HaltInstance(id string) error {
+	return c.do("POST", fmt.Sprintf("instances/%s/halt", id), nil, nil)
+}
+
+// DeleteInstance destroys an instance.
+func (c *Client) DeleteInstance(id string) error {
+	return c.do("DELETE", fmt.Sprintf("instances/%s", id), nil, nil)
+}
+
+// CreateSnapshot starts a snapshot of an instance.
+func (c *Client) CreateSnapshot(instanceID string, description string) (*Snapshot, error) {
+	body := map[string]string{
+		"instance_id": instanceID,
+		"description": description,
+	}
+	var result struct {
+		Snapshot *Snapshot `json:"snapshot"`
+	}
+	err := c.do("POST", "snapshots", body, &result)
+	return result.Snapshot, err
+}
+
+// GetSnapshot returns a snapshot.
+func (c *Client) GetSnapshot(id string) (*Snapshot, error) {
+	var result struct {
+		Snapshot *Snapshot `json:"snapshot"`
+	}
+	err := c.do("GET", fmt.Sprintf("snapshots/%s", id), nil, &result)
+	return result.Snapshot, err
+}
+
+// DeleteSnapshot deletes a snapshot.
+func (c *Client) DeleteSnapshot(id string) error {
+	return c.do("DELETE", fmt.Sprintf("snapshots/%s", id), nil, nil)
+}
+
+func (c *Client) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.BaseURL, "/")+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &ErrorResponse{StatusCode: resp.StatusCode}
+		// The message is best effort, the status is enough to fail
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
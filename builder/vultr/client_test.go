@@ -0,0 +1,71 @@
+package vultr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_CreateInstance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/instances" {
+			t.Errorf("bad request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("bad authorization: %s", r.Header.Get("Authorization"))
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !reflect.DeepEqual(body["attach_vpc"], []interface{}{"vpc"}) {
+			t.Errorf("bad vpcs: %#v", body)
+		}
+		if !reflect.DeepEqual(body["app_variables"], map[string]interface{}{"domain": "example.com"}) {
+			t.Errorf("bad app variables: %#v", body)
+		}
+		if _, ok := body["os_id"]; ok {
+			t.Errorf("os_id should be omitted: %#v", body)
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"instance": {"id": "instance", "status": "pending", "default_password": "pass"}}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL + "/", APIKey: "secret"}
+	instance, err := client.CreateInstance(&InstanceCreateRequest{
+		Region:       "ewr",
+		Plan:         "vc2-1c-1gb",
+		ImageID:      "openlitespeed-wordpress",
+		AppVariables: map[string]string{"domain": "example.com"},
+		AttachVPC:    []string{"vpc"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if instance.ID != "instance" || instance.DefaultPassword != "pass" {
+		t.Fatalf("bad: %#v", instance)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid plan", "status": 400}`))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL, APIKey: "secret"}
+	_, err := client.GetSnapshot("snapshot")
+	apiErr, ok := err.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != "Invalid plan" {
+		t.Fatalf("bad: %#v", apiErr)
+	}
+}
@@ -0,0 +1,144 @@
+package vultr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	Comm                communicator.Config `mapstructure:",squash"`
+
+	APIKey string `mapstructure:"api_key"`
+	APIURL string `mapstructure:"api_url"`
+
+	RegionID     string            `mapstructure:"region_id"`
+	PlanID       string            `mapstructure:"plan_id"`
+	OSID         int               `mapstructure:"os_id"`
+	AppID        int               `mapstructure:"app_id"`
+	ImageID      string            `mapstructure:"image_id"`
+	ISOID        string            `mapstructure:"iso_id"`
+	SnapshotID   string            `mapstructure:"snapshot_id"`
+	AppVariables map[string]string `mapstructure:"app_variables"`
+
+	VPCIDs              []string      `mapstructure:"vpc_ids"`
+	EnableIPv6          bool          `mapstructure:"enable_ipv6"`
+	InstanceLabel       string        `mapstructure:"instance_label"`
+	Hostname            string        `mapstructure:"hostname"`
+	Tags                []string      `mapstructure:"tags"`
+	ScriptID            string        `mapstructure:"script_id"`
+	UserData            string        `mapstructure:"user_data"`
+	UserDataFile        string        `mapstructure:"user_data_file"`
+	SnapshotDescription string        `mapstructure:"snapshot_description"`
+	StateTimeout        time.Duration `mapstructure:"state_timeout"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.APIKey == "" {
+		// Default to environment variable for api_key, if it exists
+		c.APIKey = os.Getenv("VULTR_API_KEY")
+	}
+	if c.APIURL == "" {
+		c.APIURL = DefaultAPIURL
+	}
+
+	if c.SnapshotDescription == "" {
+		def, err := interpolate.Render("packer-{{timestamp}}", nil)
+		if err != nil {
+			panic(err)
+		}
+
+		// Default to packer-{{ unix timestamp (utc) }}
+		c.SnapshotDescription = def
+	}
+
+	if c.InstanceLabel == "" {
+		// Default to packer-[time-ordered-uuid]
+		c.InstanceLabel = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	}
+
+	if c.StateTimeout == 0 {
+		// Default to 10 minute timeouts waiting for
+		// desired state. i.e waiting for instance to become active
+		c.StateTimeout = 10 * time.Minute
+	}
+
+	if c.Comm.SSHUsername == "" {
+		c.Comm.SSHUsername = "root"
+	}
+
+	var errs *packer.MultiError
+	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+	if c.APIKey == "" {
+		// Required configurations that will display errors if not set
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("api_key for auth must be specified"))
+	}
+
+	if c.RegionID == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("region_id is required"))
+	}
+
+	if c.PlanID == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("plan_id is required"))
+	}
+
+	sources := 0
+	for _, set := range []bool{c.OSID != 0, c.AppID != 0, c.ImageID != "", c.ISOID != "", c.SnapshotID != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("exactly one of os_id, app_id, image_id, iso_id or snapshot_id is required"))
+	}
+
+	if len(c.AppVariables) > 0 && c.AppID == 0 && c.ImageID == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("app_variables require a marketplace app_id or image_id"))
+	}
+
+	if c.UserData != "" && c.UserDataFile != "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("only one of user_data or user_data_file can be specified"))
+	} else if c.UserDataFile != "" {
+		if _, err := os.Stat(c.UserDataFile); err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("user_data_file not found: %s", c.UserDataFile))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	common.ScrubConfig(c, c.APIKey)
+	return c, nil, nil
+}
@@ -0,0 +1,44 @@
+package vultr
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func commHost(state multistep.StateBag) (string, error) {
+	ipAddress := state.Get("instance_ip").(string)
+	return ipAddress, nil
+}
+
+func sshConfig(state multistep.StateBag) (*ssh.ClientConfig, error) {
+	config := state.Get("config").(*Config)
+	privateKey := state.Get("privateKey").(string)
+
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("Error setting up SSH config: %s", err)
+	}
+
+	auth := []ssh.AuthMethod{
+		ssh.PublicKeys(signer),
+	}
+
+	// Instances that don't take the temporary key, such as the ones booted
+	// from an ISO, may take the password generated for them
+	password := config.Comm.SSHPassword
+	if password == "" {
+		password = state.Get("default_password").(string)
+	}
+	if password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+
+	return &ssh.ClientConfig{
+		User:            config.Comm.SSHUsername,
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}, nil
+}
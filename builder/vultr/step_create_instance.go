@@ -0,0 +1,92 @@
+package vultr
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepCreateInstance struct {
+	instanceId string
+}
+
+func (s *stepCreateInstance) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	sshKeyId := state.Get("ssh_key_id").(string)
+
+	// Create the instance based on configuration
+	ui.Say("Creating instance...")
+
+	userData := c.UserData
+	if c.UserDataFile != "" {
+		contents, err := ioutil.ReadFile(c.UserDataFile)
+		if err != nil {
+			state.Put("error", fmt.Errorf("Problem reading user data file: %s", err))
+			return multistep.ActionHalt
+		}
+
+		userData = string(contents)
+	}
+	if userData != "" {
+		// The API takes the user data encoded
+		userData = base64.StdEncoding.EncodeToString([]byte(userData))
+	}
+
+	instance, err := client.CreateInstance(&InstanceCreateRequest{
+		Region:       c.RegionID,
+		Plan:         c.PlanID,
+		OSID:         c.OSID,
+		AppID:        c.AppID,
+		ImageID:      c.ImageID,
+		ISOID:        c.ISOID,
+		SnapshotID:   c.SnapshotID,
+		AppVariables: c.AppVariables,
+		Label:        c.InstanceLabel,
+		Hostname:     c.Hostname,
+		SSHKeyIDs:    []string{sshKeyId},
+		ScriptID:     c.ScriptID,
+		EnableIPv6:   c.EnableIPv6,
+		AttachVPC:    c.VPCIDs,
+		UserData:     userData,
+		Tags:         c.Tags,
+	})
+	if err != nil {
+		err := fmt.Errorf("Error creating instance: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// We use this in cleanup
+	s.instanceId = instance.ID
+
+	// Store the instance for later
+	state.Put("instance_id", instance.ID)
+	state.Put("default_password", instance.DefaultPassword)
+	ui.Message(fmt.Sprintf("Instance ID: %s", instance.ID))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateInstance) Cleanup(state multistep.StateBag) {
+	// If the instance id isn't there, we probably never created it
+	if s.instanceId == "" {
+		return
+	}
+
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+
+	// Destroy the instance we just created
+	ui.Say("Destroying instance...")
+	if err := client.DeleteInstance(s.instanceId); err != nil {
+		ui.Error(fmt.Sprintf(
+			"Error destroying instance. Please destroy it manually: %s", err))
+	}
+}
@@ -0,0 +1,53 @@
+package vultr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepInstanceInfo struct{}
+
+func (s *stepInstanceInfo) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	instanceID := state.Get("instance_id").(string)
+
+	ui.Say("Waiting for instance to become active...")
+
+	err := waitForState("active/running", instanceRunning(client, instanceID), 5*time.Second, c.StateTimeout)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for instance to become active: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Set the IP on the state for later
+	instance, err := client.GetInstance(instanceID)
+	if err != nil {
+		err := fmt.Errorf("Error retrieving instance: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// The main IP is only set once the instance is active
+	if instance.MainIP == "" || instance.MainIP == "0.0.0.0" {
+		err := fmt.Errorf("Could not find the main IP address of this instance")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	state.Put("instance_ip", instance.MainIP)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepInstanceInfo) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,41 @@
+package vultr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepShutdown struct{}
+
+func (s *stepShutdown) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	instanceID := state.Get("instance_id").(string)
+
+	ui.Say("Halting instance...")
+	if err := client.HaltInstance(instanceID); err != nil {
+		err := fmt.Errorf("Error halting instance: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	err := waitForState("stopped", instancePowerStatus(client, instanceID), 5*time.Second, c.StateTimeout)
+	if err != nil {
+		err := fmt.Errorf("Error waiting for instance to stop: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepShutdown) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,49 @@
+package vultr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type stepSnapshot struct{}
+
+func (s *stepSnapshot) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*Client)
+	ui := state.Get("ui").(packer.Ui)
+	c := state.Get("config").(*Config)
+	instanceID := state.Get("instance_id").(string)
+
+	ui.Say(fmt.Sprintf("Creating snapshot: %v", c.SnapshotDescription))
+	snapshot, err := client.CreateSnapshot(instanceID, c.SnapshotDescription)
+	if err != nil {
+		err := fmt.Errorf("Error creating snapshot: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Snapshots take a long time, depending on the size of the disk, so
+	// we hardcode this to 60 minutes.
+	ui.Say("Waiting for snapshot to complete...")
+	if err := waitForState("complete", snapshotStatus(client, snapshot.ID), 20*time.Second, 60*time.Minute); err != nil {
+		err := fmt.Errorf("Error waiting for snapshot: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	log.Printf("Snapshot ID: %s", snapshot.ID)
+	state.Put("snapshot_id", snapshot.ID)
+	state.Put("snapshot_description", c.SnapshotDescription)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepSnapshot) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,86 @@
+package vultr
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// waitForState polls refresh until it returns the desired state, with the
+// given pause in between.
+func waitForState(
+	desiredState string, refresh func() (string, error),
+	pause time.Duration, timeout time.Duration) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	result := make(chan error, 1)
+	go func() {
+		attempts := 0
+		for {
+			attempts += 1
+
+			log.Printf("Checking state... (attempt: %d)", attempts)
+			state, err := refresh()
+			if err != nil {
+				result <- err
+				return
+			}
+
+			if state == desiredState {
+				result <- nil
+				return
+			}
+
+			time.Sleep(pause)
+
+			// Verify we shouldn't exit
+			select {
+			case <-done:
+				// We finished, so just exit the goroutine
+				return
+			default:
+				// Keep going
+			}
+		}
+	}()
+
+	log.Printf("Waiting for up to %d seconds for state to become: %s", timeout/time.Second, desiredState)
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("Timeout while waiting to for state to become '%s'", desiredState)
+	}
+}
+
+// instanceRunning is the state of an instance that is active and powered on.
+func instanceRunning(client *Client, id string) func() (string, error) {
+	return func() (string, error) {
+		instance, err := client.GetInstance(id)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s/%s", instance.Status, instance.PowerStatus), nil
+	}
+}
+
+func instancePowerStatus(client *Client, id string) func() (string, error) {
+	return func() (string, error) {
+		instance, err := client.GetInstance(id)
+		if err != nil {
+			return "", err
+		}
+		return instance.PowerStatus, nil
+	}
+}
+
+func snapshotStatus(client *Client, id string) func() (string, error) {
+	return func() (string, error) {
+		snapshot, err := client.GetSnapshot(id)
+		if err != nil {
+			return "", err
+		}
+		return snapshot.Status, nil
+	}
+}
@@ -0,0 +1,38 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+)
+
+// Artifact is the tarball of a WSL distribution, with its manifest.
+type Artifact struct {
+	name  string
+	dir   string
+	f     []string
+	state map[string]interface{}
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return a.f
+}
+
+func (a *Artifact) Id() string {
+	return a.name
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("WSL distribution '%s' in directory: %s", a.name, a.dir)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return a.state[name]
+}
+
+func (a *Artifact) Destroy() error {
+	return os.RemoveAll(a.dir)
+}
@@ -0,0 +1,88 @@
+// The wsl package contains a packer.Builder implementation that builds a
+// distribution for the Windows Subsystem for Linux: a root file system
+// tarball WSL 2 can import, from a container image or a rootfs archive,
+// provisioned in a chroot on the host.
+package wsl
+
+import (
+	"errors"
+	"log"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/imagemount"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The unique ID for this builder
+const BuilderId = "packer.wsl"
+
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, errs := NewConfig(raws...)
+	if errs != nil {
+		return warnings, errs
+	}
+	b.config = c
+
+	return warnings, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	steps := []multistep.Step{
+		new(stepPrepareOutputDir),
+		new(stepCreateRootfs),
+		new(stepMountChroot),
+		new(common.StepProvision),
+		new(stepConfigureWSL),
+		new(stepExportTarball),
+		new(stepWriteManifest),
+	}
+
+	// Setup the state bag
+	state := new(multistep.BasicStateBag)
+	state.Put("config", b.config)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+	state.Put("wrappedCommand", imagemount.HostCommandWrapper(b.config.HostCommandWrapper, b.config.ctx))
+
+	// Run
+	b.runner = common.NewRunnerWithPauseFn(steps, b.config.PackerConfig, ui, state)
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	// If we were interrupted or cancelled, then just exit.
+	if _, ok := state.GetOk(multistep.StateCancelled); ok {
+		return nil, errors.New("Build was cancelled.")
+	}
+
+	if _, ok := state.GetOk(multistep.StateHalted); ok {
+		return nil, errors.New("Build was halted.")
+	}
+
+	artifact := &Artifact{
+		name:  b.config.DistributionName,
+		dir:   b.config.OutputDir,
+		f:     []string{b.config.tarballPath(), b.config.manifestPath()},
+		state: make(map[string]interface{}),
+	}
+	artifact.state["tarball"] = b.config.tarballPath()
+	artifact.state["manifest"] = b.config.manifestPath()
+
+	return artifact, nil
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
@@ -0,0 +1,157 @@
+package wsl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"source_image":            "ubuntu:20.04",
+		packer.BuildNameConfigKey: "foo",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Error("Builder must implement builder.")
+	}
+}
+
+func TestBuilderPrepare_Defaults(t *testing.T) {
+	var b Builder
+	warns, err := b.Prepare(testConfig())
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Runtime != "docker" {
+		t.Errorf("bad runtime: %s", b.config.Runtime)
+	}
+	if b.config.DistributionName != "packer-foo" {
+		t.Errorf("bad distribution name: %s", b.config.DistributionName)
+	}
+	if b.config.OutputDir != "output-foo" {
+		t.Errorf("bad output dir: %s", b.config.OutputDir)
+	}
+	if b.config.tarballPath() != filepath.Join("output-foo", "packer-foo.tar.gz") {
+		t.Errorf("bad tarball: %s", b.config.tarballPath())
+	}
+	if b.config.manifestPath() != filepath.Join("output-foo", "packer-foo.json") {
+		t.Errorf("bad manifest: %s", b.config.manifestPath())
+	}
+}
+
+func TestBuilderPrepare_Source(t *testing.T) {
+	f, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	var b Builder
+	config := testConfig()
+
+	// Bad
+	config["source_rootfs"] = f.Name()
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad
+	delete(config, "source_image")
+	delete(config, "source_rootfs")
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad
+	config["source_rootfs"] = "/i/dont/exist"
+	b = Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["source_rootfs"] = f.Name()
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_Runtime(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["runtime"] = "rkt"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["runtime"] = "podman"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_Compression(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["compression"] = "xz"
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["compression"] = "none"
+	b = Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.tarballPath() != filepath.Join("output-foo", "packer-foo.tar") {
+		t.Fatalf("bad tarball: %s", b.config.tarballPath())
+	}
+}
+
+func TestArchiveCommand(t *testing.T) {
+	command := archiveCommand("/tmp/rootfs", "out/foo.tar.gz", true)
+	expected := "tar --create --numeric-owner --xattrs --gzip --file='out/foo.tar.gz' --directory='/tmp/rootfs' ."
+	if command != expected {
+		t.Fatalf("bad: %s", command)
+	}
+}
+
+func TestNewManifest(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["distribution_name"] = "web"
+	config["default_user"] = "dev"
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	m := newManifest(b.config, "abcd")
+	expected := Manifest{
+		Name:          "web",
+		Tarball:       "web.tar.gz",
+		SHA256:        "abcd",
+		Version:       2,
+		DefaultUser:   "dev",
+		Source:        "ubuntu:20.04",
+		ImportCommand: `wsl.exe --import web "$env:LOCALAPPDATA\web" web.tar.gz --version 2`,
+	}
+	if *m != expected {
+		t.Fatalf("bad: %#v", m)
+	}
+}
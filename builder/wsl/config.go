@@ -0,0 +1,111 @@
+package wsl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/builder/docker"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	SourceImage  string `mapstructure:"source_image"`
+	SourceRootfs string `mapstructure:"source_rootfs"`
+	Runtime      string `mapstructure:"runtime"`
+
+	DistributionName string `mapstructure:"distribution_name"`
+	DefaultUser      string `mapstructure:"default_user"`
+
+	Compression        string `mapstructure:"compression"`
+	OutputDir          string `mapstructure:"output_directory"`
+	HostCommandWrapper string `mapstructure:"host_command_wrapper"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	var c Config
+	err := config.Decode(&c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"host_command_wrapper",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var errs *packer.MultiError
+
+	if (c.SourceImage == "") == (c.SourceRootfs == "") {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("exactly one of source_image or source_rootfs must be specified"))
+	}
+	if c.SourceRootfs != "" {
+		if _, err := os.Stat(c.SourceRootfs); err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_rootfs is invalid: %s", err))
+		}
+	}
+
+	if c.Runtime == "" {
+		c.Runtime = docker.RuntimeDocker
+	}
+	if err := docker.CheckRuntime(c.Runtime); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
+	if c.DistributionName == "" {
+		c.DistributionName = fmt.Sprintf("packer-%s", c.PackerBuildName)
+	}
+
+	if c.Compression == "" {
+		c.Compression = "gzip"
+	}
+	if c.Compression != "gzip" && c.Compression != "none" {
+		errs = packer.MultiErrorAppend(errs, errors.New("compression must be 'gzip' or 'none'"))
+	}
+
+	if c.OutputDir == "" {
+		c.OutputDir = fmt.Sprintf("output-%s", c.PackerBuildName)
+	}
+	if !c.PackerForce {
+		if _, err := os.Stat(c.OutputDir); err == nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Output directory '%s' already exists. It must not exist.", c.OutputDir))
+		}
+	}
+
+	if c.HostCommandWrapper == "" {
+		c.HostCommandWrapper = "{{.Command}}"
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	return &c, nil, nil
+}
+
+// tarballPath is the path of the tarball of the distribution.
+func (c *Config) tarballPath() string {
+	name := c.DistributionName + ".tar"
+	if c.Compression == "gzip" {
+		name += ".gz"
+	}
+	return filepath.Join(c.OutputDir, name)
+}
+
+// manifestPath is the path of the manifest describing the distribution.
+func (c *Config) manifestPath() string {
+	return filepath.Join(c.OutputDir, c.DistributionName+".json")
+}
@@ -0,0 +1,78 @@
+package wsl
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepConfigureWSL sets the default user of the distribution in its
+// /etc/wsl.conf, once provisioners had the chance to create it.
+//
+// Uses:
+//   rootfs_path string
+type stepConfigureWSL struct{}
+
+func (s *stepConfigureWSL) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	rootfsPath := state.Get("rootfs_path").(string)
+
+	if config.DefaultUser == "" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Setting the default user to %s...", config.DefaultUser))
+	if err := chroot.RunWrapped(wrapper, fmt.Sprintf("chroot %s id -u %s",
+		shellQuote(rootfsPath), shellQuote(config.DefaultUser))); err != nil {
+		err := fmt.Errorf("The default user %s doesn't exist in the distribution: %s",
+			config.DefaultUser, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if err := appendWSLConf(wrapper, rootfsPath, wslConf(config.DefaultUser)); err != nil {
+		err := fmt.Errorf("Error writing /etc/wsl.conf: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConfigureWSL) Cleanup(state multistep.StateBag) {}
+
+// wslConf is the section of /etc/wsl.conf setting the default user.
+func wslConf(user string) string {
+	return fmt.Sprintf("\n[user]\ndefault=%s\n", user)
+}
+
+// appendWSLConf appends the content to the /etc/wsl.conf of the root file
+// system, keeping what the source or the provisioners configured there.
+func appendWSLConf(wrapper chroot.CommandWrapper, rootfsPath string, content string) error {
+	f, err := ioutil.TempFile("", "packer-wsl-conf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(content)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	// The file is appended to by a shell run with the command wrapper, as
+	// it usually belongs to root.
+	script := fmt.Sprintf("cat %s >> %s",
+		shellQuote(f.Name()), shellQuote(filepath.Join(rootfsPath, "etc", "wsl.conf")))
+	return chroot.RunWrapped(wrapper, "sh -c "+shellQuote(script))
+}
@@ -0,0 +1,119 @@
+package wsl
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepCreateRootfs extracts the root file system of the distribution into
+// a temporary directory, from the source rootfs archive, or from a
+// container created from the source image.
+//
+// Produces:
+//   rootfs_path string - The directory of the root file system.
+type stepCreateRootfs struct {
+	rootfsPath string
+}
+
+func (s *stepCreateRootfs) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	rootfsPath, err := ioutil.TempDir("", "packer-wsl")
+	if err != nil {
+		err := fmt.Errorf("Error creating rootfs directory: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.rootfsPath = rootfsPath
+
+	archive := config.SourceRootfs
+	if config.SourceImage != "" {
+		ui.Say(fmt.Sprintf("Exporting the file system of %s...", config.SourceImage))
+		archive = rootfsPath + ".tar"
+		defer chroot.RunWrapped(wrapper, fmt.Sprintf("rm -f %s", shellQuote(archive)))
+
+		if err := exportImage(wrapper, config.Runtime, config.SourceImage, archive); err != nil {
+			err := fmt.Errorf("Error exporting %s: %s", config.SourceImage, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	// The temporary directory becomes the root of the distribution, which
+	// must belong to root, and be readable by everyone.
+	for _, command := range []string{"chown 0:0 %s", "chmod 0755 %s"} {
+		if err := chroot.RunWrapped(wrapper, fmt.Sprintf(command, shellQuote(rootfsPath))); err != nil {
+			err := fmt.Errorf("Error preparing rootfs directory: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Say("Extracting the root file system...")
+	if err := chroot.RunWrapped(wrapper, extractCommand(archive, rootfsPath)); err != nil {
+		err := fmt.Errorf("Error extracting the root file system: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("rootfs_path", rootfsPath)
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateRootfs) Cleanup(state multistep.StateBag) {
+	if s.rootfsPath == "" {
+		return
+	}
+
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	// The rootfs is removed without crossing file systems, in case
+	// something is still mounted in it.
+	if err := chroot.RunWrapped(wrapper,
+		fmt.Sprintf("rm -rf --one-file-system %s", shellQuote(s.rootfsPath))); err != nil {
+		log.Printf("Error removing rootfs directory %s: %s", s.rootfsPath, err)
+	}
+	os.Remove(s.rootfsPath)
+}
+
+// exportImage exports the file system of the image to the archive, from a
+// container created, but never started, with the container runtime.
+func exportImage(wrapper chroot.CommandWrapper, runtime string, image string, archive string) error {
+	// Images without command can't be created without one, even though
+	// the container never runs.
+	id, err := chroot.RunWrappedOutput(wrapper,
+		fmt.Sprintf("%s create %s /bin/sh", runtime, shellQuote(image)))
+	if err != nil {
+		return err
+	}
+	defer chroot.RunWrapped(wrapper, fmt.Sprintf("%s rm %s", runtime, id))
+
+	return chroot.RunWrapped(wrapper,
+		fmt.Sprintf("%s export --output=%s %s", runtime, shellQuote(archive), id))
+}
+
+// extractCommand is the command extracting the archive of a root file
+// system into the directory, keeping the owners and permissions of its
+// files.
+func extractCommand(archive string, dir string) string {
+	return fmt.Sprintf("tar --extract --preserve-permissions --numeric-owner --file=%s --directory=%s",
+		shellQuote(archive), shellQuote(dir))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
@@ -0,0 +1,56 @@
+package wsl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepExportTarball unmounts the file systems mounted in the root file
+// system, and archives it into the tarball WSL imports.
+//
+// Uses:
+//   rootfs_path string
+//   chroot_unmount *stepMountChroot
+type stepExportTarball struct{}
+
+func (s *stepExportTarball) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	rootfsPath := state.Get("rootfs_path").(string)
+
+	if mount, ok := state.Get("chroot_unmount").(*stepMountChroot); ok {
+		if err := mount.CleanupFunc(state); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Say(fmt.Sprintf("Exporting the distribution to %s...", config.tarballPath()))
+	command := archiveCommand(rootfsPath, config.tarballPath(), config.Compression == "gzip")
+	if err := chroot.RunWrapped(wrapper, command); err != nil {
+		err := fmt.Errorf("Error exporting the distribution: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepExportTarball) Cleanup(state multistep.StateBag) {}
+
+// archiveCommand is the command archiving the root file system in the
+// directory into the tarball, with numeric owners as WSL expects.
+func archiveCommand(dir string, tarball string, gzip bool) string {
+	command := "tar --create --numeric-owner --xattrs"
+	if gzip {
+		command += " --gzip"
+	}
+	return fmt.Sprintf("%s --file=%s --directory=%s .", command, shellQuote(tarball), shellQuote(dir))
+}
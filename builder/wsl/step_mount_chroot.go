@@ -0,0 +1,92 @@
+package wsl
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/common/imagemount"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepMountChroot mounts the file systems the usual tools need in the root
+// file system, such as /proc, and copies the DNS configuration of the host
+// in it, so that provisioners can run chrooted into it.
+//
+// Uses:
+//   rootfs_path string
+//
+// Produces:
+//   communicator packer.Communicator - Runs commands in the root file system.
+//   chroot_unmount *stepMountChroot - To unmount the file systems before
+//     the end of the build, with stepExportTarball.
+type stepMountChroot struct {
+	rootfsPath string
+	mounted    []string
+}
+
+func (s *stepMountChroot) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	rootfsPath := state.Get("rootfs_path").(string)
+
+	s.rootfsPath = rootfsPath
+	state.Put("chroot_unmount", s)
+
+	if err := chroot.RunWrapped(wrapper, fmt.Sprintf(
+		"cp -L --remove-destination /etc/resolv.conf %s",
+		shellQuote(filepath.Join(rootfsPath, "etc", "resolv.conf")))); err != nil {
+		err := fmt.Errorf("Error copying /etc/resolv.conf: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	mounted, err := imagemount.MountChrootFilesystems(wrapper, rootfsPath)
+	s.mounted = mounted
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("communicator", &chroot.Communicator{
+		Chroot:     rootfsPath,
+		CmdWrapper: wrapper,
+	})
+	return multistep.ActionContinue
+}
+
+func (s *stepMountChroot) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	if err := s.CleanupFunc(state); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+// CleanupFunc unmounts the file systems mounted in the root file system,
+// and removes the DNS configuration of the host, as WSL generates its own.
+// It does nothing if it already ran, so it is safe to call more than once.
+func (s *stepMountChroot) CleanupFunc(state multistep.StateBag) error {
+	if s.rootfsPath == "" {
+		return nil
+	}
+
+	wrapper := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	var err error
+	s.mounted, err = imagemount.UnmountChrootFilesystems(wrapper, s.mounted)
+	if err != nil {
+		return err
+	}
+
+	if err := chroot.RunWrapped(wrapper, fmt.Sprintf("rm -f %s",
+		shellQuote(filepath.Join(s.rootfsPath, "etc", "resolv.conf")))); err != nil {
+		return fmt.Errorf("Error removing /etc/resolv.conf: %s", err)
+	}
+
+	s.rootfsPath = ""
+	return nil
+}
@@ -0,0 +1,91 @@
+package wsl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// Manifest describes the distribution, so that it can be registered with
+// WSL on Windows, with the import command it contains.
+type Manifest struct {
+	Name          string `json:"name"`
+	Tarball       string `json:"tarball"`
+	SHA256        string `json:"sha256"`
+	Version       int    `json:"version"`
+	DefaultUser   string `json:"default_user,omitempty"`
+	Source        string `json:"source"`
+	ImportCommand string `json:"import_command"`
+}
+
+// newManifest is the manifest of the distribution of the configuration,
+// whose tarball has the checksum.
+func newManifest(config *Config, checksum string) *Manifest {
+	source := config.SourceImage
+	if source == "" {
+		source = filepath.Base(config.SourceRootfs)
+	}
+	tarball := filepath.Base(config.tarballPath())
+
+	return &Manifest{
+		Name:        config.DistributionName,
+		Tarball:     tarball,
+		SHA256:      checksum,
+		Version:     2,
+		DefaultUser: config.DefaultUser,
+		Source:      source,
+		ImportCommand: fmt.Sprintf(`wsl.exe --import %s "$env:LOCALAPPDATA\%s" %s --version 2`,
+			config.DistributionName, config.DistributionName, tarball),
+	}
+}
+
+// stepWriteManifest writes the manifest of the distribution next to its
+// tarball.
+type stepWriteManifest struct{}
+
+func (s *stepWriteManifest) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	checksum, err := fileChecksum(config.tarballPath())
+	if err == nil {
+		var data []byte
+		data, err = json.MarshalIndent(newManifest(config, checksum), "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(config.manifestPath(), append(data, '\n'), 0644)
+		}
+	}
+	if err != nil {
+		err := fmt.Errorf("Error writing manifest: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepWriteManifest) Cleanup(state multistep.StateBag) {}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
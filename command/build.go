@@ -10,7 +10,10 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/hashicorp/packer/common/remotelog"
+	"github.com/hashicorp/packer/common/uuid"
 	"github.com/hashicorp/packer/helper/enumflag"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template"
@@ -23,16 +26,22 @@ type BuildCommand struct {
 }
 
 func (c *BuildCommand) Run(args []string) int {
-	var cfgColor, cfgDebug, cfgForce, cfgParallel bool
-	var cfgOnError string
+	var cfgColor, cfgDebug, cfgForce, cfgParallel, cfgProvisionerDryRun bool
+	var cfgOnError, cfgShipLogs string
+	var cfgOnErrorGracePeriod time.Duration
+	var cfgParallelBuilds int
 	flags := c.Meta.FlagSet("build", FlagSetBuildFilter|FlagSetVars)
 	flags.Usage = func() { c.Ui.Say(c.Help()) }
 	flags.BoolVar(&cfgColor, "color", true, "")
 	flags.BoolVar(&cfgDebug, "debug", false, "")
 	flags.BoolVar(&cfgForce, "force", false, "")
-	flagOnError := enumflag.New(&cfgOnError, "cleanup", "abort", "ask")
+	flagOnError := enumflag.New(&cfgOnError, "cleanup", "abort", "ask", "inspect")
 	flags.Var(flagOnError, "on-error", "")
+	flags.DurationVar(&cfgOnErrorGracePeriod, "on-error-grace-period", 30*time.Minute, "")
 	flags.BoolVar(&cfgParallel, "parallel", true, "")
+	flags.IntVar(&cfgParallelBuilds, "parallel-builds", c.Meta.ParallelBuilds, "")
+	flags.BoolVar(&cfgProvisionerDryRun, "provisioner-dry-run", false, "")
+	flags.StringVar(&cfgShipLogs, "ship-logs", c.Meta.ShipLogs, "")
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
@@ -49,14 +58,14 @@ func (c *BuildCommand) Run(args []string) int {
 	tpl, err = template.ParseFile(args[0])
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to parse template: %s", err))
-		return 1
+		return exitCode(packer.ErrorClassConfig)
 	}
 
 	// Get the core
 	core, err := c.Meta.Core(tpl)
 	if err != nil {
 		c.Ui.Error(err.Error())
-		return 1
+		return exitCode(packer.ErrorClassConfig)
 	}
 
 	// Get the builds we care about
@@ -74,9 +83,37 @@ func (c *BuildCommand) Run(args []string) int {
 		builds = append(builds, b)
 	}
 
+	// Builds run once the builds they depend on finished, so those must
+	// be part of the run too.
+	selected := make(map[string]bool)
+	for _, b := range builds {
+		selected[b.Name()] = true
+	}
+	dependencies := make(map[string][]string)
+	for _, b := range builds {
+		deps, err := core.BuildDependencies(b.Name())
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return exitCode(packer.ErrorClassConfig)
+		}
+		for _, dep := range deps {
+			if !selected[dep] {
+				c.Ui.Error(fmt.Sprintf(
+					"Build '%s' depends on build '%s', which isn't part of this run",
+					b.Name(), dep))
+				return exitCode(packer.ErrorClassConfig)
+			}
+		}
+		dependencies[b.Name()] = deps
+	}
+	builds = sortBuildsByDependencies(builds, dependencies)
+
 	if cfgDebug {
 		c.Ui.Say("Debug mode enabled. Builds will not be parallelized.")
 	}
+	if cfgProvisionerDryRun {
+		c.Ui.Say("Provisioner dry run enabled. No machine will be built or connected to.")
+	}
 
 	// Compile all the UIs for the builds
 	colors := [5]packer.UiColor{
@@ -107,9 +144,44 @@ func (c *BuildCommand) Run(args []string) int {
 		buildUis[b] = ui
 	}
 
+	// Ship the output of the builds as they run, so it survives the
+	// machine Packer runs on.
+	var shipper *remotelog.Shipper
+	if cfgShipLogs != "" {
+		store, err := remotelog.NewStore(cfgShipLogs)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error setting up -ship-logs: %s", err))
+			return exitCode(packer.ErrorClassConfig)
+		}
+
+		runUUID := os.Getenv("PACKER_RUN_UUID")
+		if runUUID == "" {
+			runUUID = uuid.TimeOrderedUUID()
+		}
+		names := make([]string, 0, len(builds))
+		for _, b := range builds {
+			names = append(names, b.Name())
+		}
+		shipper = remotelog.NewShipper(store, runUUID, names)
+		for name, ui := range buildUis {
+			buildUis[name] = shipper.Ui(name, ui)
+		}
+
+		c.Ui.Say(fmt.Sprintf("Shipping the logs to %s/%s", strings.TrimRight(cfgShipLogs, "/"), runUUID))
+		shipper.Start()
+		defer func() {
+			if err := shipper.Close(); err != nil {
+				c.Ui.Error(fmt.Sprintf("Error shipping the logs: %s", err))
+			}
+		}()
+	}
+
 	log.Printf("Build debug mode: %v", cfgDebug)
 	log.Printf("Force build: %v", cfgForce)
 	log.Printf("On error: %v", cfgOnError)
+	log.Printf("On error grace period: %v", cfgOnErrorGracePeriod)
+	log.Printf("Provisioner dry run: %v", cfgProvisionerDryRun)
+	log.Printf("Parallel builds: %d", cfgParallelBuilds)
 
 	// Set the debug and force mode and prepare all the builds
 	for _, b := range builds {
@@ -117,19 +189,27 @@ func (c *BuildCommand) Run(args []string) int {
 		b.SetDebug(cfgDebug)
 		b.SetForce(cfgForce)
 		b.SetOnError(cfgOnError)
+		b.SetOnErrorGracePeriod(cfgOnErrorGracePeriod)
+		b.SetProvisionerDryRun(cfgProvisionerDryRun)
 
-		warnings, err := b.Prepare()
-		if err != nil {
-			c.Ui.Error(err.Error())
-			return 1
+		// Builds depending on others use their outputs, so they are only
+		// prepared once those finished.
+		if len(dependencies[b.Name()]) > 0 {
+			continue
 		}
-		if len(warnings) > 0 {
-			ui := buildUis[b.Name()]
-			ui.Say(fmt.Sprintf("Warnings for build '%s':\n", b.Name()))
-			for _, warning := range warnings {
-				ui.Say(fmt.Sprintf("* %s", warning))
+
+		if err := prepareBuild(b, buildUis[b.Name()]); err != nil {
+			if shipper != nil {
+				shipper.BuildFinished(b.Name(), nil, err)
+			}
+			class := packer.ClassifyError(err)
+			ui := &packer.TargetedUI{
+				Target: b.Name(),
+				Ui:     c.Ui,
 			}
-			ui.Say("")
+			ui.Machine("error-class", string(class))
+			c.Ui.Error(err.Error())
+			return exitCode(class)
 		}
 	}
 
@@ -140,7 +220,20 @@ func (c *BuildCommand) Run(args []string) int {
 		sync.RWMutex
 		m map[string][]packer.Artifact
 	}{m: make(map[string][]packer.Artifact)}
-	errors := make(map[string]error)
+	var errors = struct {
+		sync.RWMutex
+		m map[string]error
+	}{m: make(map[string]error)}
+	done := make(map[string]chan struct{})
+	for _, b := range builds {
+		done[b.Name()] = make(chan struct{})
+	}
+	// A build takes a slot once the builds it depends on finished, so
+	// waiting doesn't keep the others from running.
+	var slots chan struct{}
+	if cfgParallelBuilds > 0 {
+		slots = make(chan struct{}, cfgParallelBuilds)
+	}
 	// ctx := context.Background()
 	for _, b := range builds {
 		// Increment the waitgroup so we wait for this item to finish properly
@@ -168,13 +261,69 @@ func (c *BuildCommand) Run(args []string) int {
 			defer wg.Done()
 
 			name := b.Name()
-			log.Printf("Starting build run: %s", name)
+			defer close(done[name])
 			ui := buildUis[name]
+
+			if deps := dependencies[name]; len(deps) > 0 {
+				log.Printf("Waiting for the builds %s depends on: %s", name, strings.Join(deps, ", "))
+				for _, dep := range deps {
+					<-done[dep]
+				}
+
+				errors.RLock()
+				var failed []string
+				for _, dep := range deps {
+					if _, ok := errors.m[dep]; ok {
+						failed = append(failed, dep)
+					}
+				}
+				errors.RUnlock()
+				if len(failed) > 0 {
+					err := fmt.Errorf("Build '%s' skipped because builds it depends on failed: %s",
+						name, strings.Join(failed, ", "))
+					ui.Error(err.Error())
+					errors.Lock()
+					err = packer.NewClassifiedError(packer.ClassifyError(errors.m[failed[0]]), err)
+					errors.m[name] = err
+					errors.Unlock()
+					if shipper != nil {
+						shipper.BuildFinished(name, nil, err)
+					}
+					return
+				}
+
+				log.Printf("Preparing build: %s", name)
+				if err := prepareBuild(b, ui); err != nil {
+					ui.Error(err.Error())
+					errors.Lock()
+					errors.m[name] = err
+					errors.Unlock()
+					if shipper != nil {
+						shipper.BuildFinished(name, nil, err)
+					}
+					return
+				}
+			}
+
+			if slots != nil {
+				slots <- struct{}{}
+				defer func() { <-slots }()
+			}
+
+			log.Printf("Starting build run: %s", name)
+			if shipper != nil {
+				shipper.BuildStarted(name)
+			}
 			runArtifacts, err := b.Run(ui, c.Cache)
+			if shipper != nil {
+				shipper.BuildFinished(name, runArtifacts, err)
+			}
 
 			if err != nil {
 				ui.Error(fmt.Sprintf("Build '%s' errored: %s", name, err))
-				errors[name] = err
+				errors.Lock()
+				errors.m[name] = err
+				errors.Unlock()
 			} else {
 				ui.Say(fmt.Sprintf("Build '%s' finished.", name))
 				artifacts.Lock()
@@ -212,11 +361,11 @@ func (c *BuildCommand) Run(args []string) int {
 		return 1
 	}
 
-	if len(errors) > 0 {
-		c.Ui.Machine("error-count", strconv.FormatInt(int64(len(errors)), 10))
+	if len(errors.m) > 0 {
+		c.Ui.Machine("error-count", strconv.FormatInt(int64(len(errors.m)), 10))
 
 		c.Ui.Error("\n==> Some builds didn't complete successfully and had errors:")
-		for name, err := range errors {
+		for name, err := range errors.m {
 			// Create a UI for the machine readable stuff to be targeted
 			ui := &packer.TargetedUI{
 				Target: name,
@@ -224,6 +373,7 @@ func (c *BuildCommand) Run(args []string) int {
 			}
 
 			ui.Machine("error", err.Error())
+			ui.Machine("error-class", string(packer.ClassifyError(err)))
 
 			c.Ui.Error(fmt.Sprintf("--> %s: %s", name, err))
 		}
@@ -269,6 +419,20 @@ func (c *BuildCommand) Run(args []string) int {
 					ui.Machine("artifact", iStr, "nil")
 				}
 
+				// Artifacts of builds that produce several ones also list
+				// the others by name.
+				if named, ok := artifact.(packer.NamedArtifacts); ok {
+					for _, artifactName := range named.ArtifactNames() {
+						namedArtifact := named.NamedArtifact(artifactName)
+						if namedArtifact == nil {
+							continue
+						}
+
+						ui.Machine("artifact", iStr, "named-artifact", artifactName, namedArtifact.Id())
+						fmt.Fprintf(&message, "\n--> %s (%s): %s", name, artifactName, namedArtifact.String())
+					}
+				}
+
 				ui.Machine("artifact", iStr, "end")
 				c.Ui.Say(message.String())
 			}
@@ -277,14 +441,59 @@ func (c *BuildCommand) Run(args []string) int {
 		c.Ui.Say("\n==> Builds finished but no artifacts were created.")
 	}
 
-	if len(errors) > 0 {
-		// If any errors occurred, exit with a non-zero exit status
-		return 1
+	if len(errors.m) > 0 {
+		// If any errors occurred, exit with a non-zero exit status that
+		// tells what kind of errors they were
+		return exitCode(errorsClass(errors.m))
 	}
 
 	return 0
 }
 
+// prepareBuild prepares the build, showing its warnings on the UI. Prepare
+// errors are configuration errors unless they were classified as something
+// else.
+func prepareBuild(b packer.Build, ui packer.Ui) error {
+	warnings, err := b.Prepare()
+	if err != nil {
+		return packer.NewClassifiedError(packer.ErrorClassConfig, err)
+	}
+	if len(warnings) > 0 {
+		ui.Say(fmt.Sprintf("Warnings for build '%s':\n", b.Name()))
+		for _, warning := range warnings {
+			ui.Say(fmt.Sprintf("* %s", warning))
+		}
+		ui.Say("")
+	}
+	return nil
+}
+
+// sortBuildsByDependencies orders the builds so that each one comes after
+// the builds it depends on.
+func sortBuildsByDependencies(builds []packer.Build, dependencies map[string][]string) []packer.Build {
+	result := make([]packer.Build, 0, len(builds))
+	added := make(map[string]bool)
+	for len(result) < len(builds) {
+		for _, b := range builds {
+			if added[b.Name()] {
+				continue
+			}
+
+			ready := true
+			for _, dep := range dependencies[b.Name()] {
+				if !added[dep] {
+					ready = false
+				}
+			}
+			if ready {
+				result = append(result, b)
+				added[b.Name()] = true
+			}
+		}
+	}
+	return result
+}
+
 func (*BuildCommand) Help() string {
 	helpText := `
 Usage: packer build [options] TEMPLATE
@@ -300,10 +509,27 @@ Options:
   -only=foo,bar,baz          Build only the specified builds
   -force                     Force a build to continue if artifacts exist, deletes existing artifacts
   -machine-readable          Machine-readable output
-  -on-error=[cleanup|abort|ask] If the build fails do: clean up (default), abort, or ask
+  -on-error=[cleanup|abort|ask|inspect] If the build fails do: clean up (default), abort, ask,
+                             or keep the machine to inspect it for a grace period, then clean up
+  -on-error-grace-period=30m How long to keep the machine with -on-error=inspect
   -parallel=false            Disable parallelization (on by default)
+  -parallel-builds=0         Number of builds to run at once, 0 for no limit (the default)
+  -provisioner-dry-run       Show what the provisioners would do, without building anything
+  -ship-logs=s3://bucket/dir Ship the logs and a manifest of the builds as they run, to
+                             s3://, gs:// or azblob:// (an Azure container)
   -var 'key=value'           Variable for templates, can be used multiple times.
   -var-file=path             JSON file containing user variables.
+
+Exit codes:
+
+  0  All builds succeeded
+  1  Builds failed for unclassified or different reasons, or were cancelled
+  2  Invalid template or configuration
+  3  Missing, invalid or unauthorized credentials
+  4  Out of capacity or quota
+  5  Rate limited
+  6  A provisioner failed
+  7  Timed out
 `
 
 	return strings.TrimSpace(helpText)
@@ -319,15 +545,18 @@ func (*BuildCommand) AutocompleteArgs() complete.Predictor {
 
 func (*BuildCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-color":            complete.PredictNothing,
-		"-debug":            complete.PredictNothing,
-		"-except":           complete.PredictNothing,
-		"-only":             complete.PredictNothing,
-		"-force":            complete.PredictNothing,
-		"-machine-readable": complete.PredictNothing,
-		"-on-error":         complete.PredictNothing,
-		"-parallel":         complete.PredictNothing,
-		"-var":              complete.PredictNothing,
-		"-var-file":         complete.PredictNothing,
+		"-color":                 complete.PredictNothing,
+		"-debug":                 complete.PredictNothing,
+		"-except":                complete.PredictNothing,
+		"-only":                  complete.PredictNothing,
+		"-force":                 complete.PredictNothing,
+		"-machine-readable":      complete.PredictNothing,
+		"-on-error":              complete.PredictNothing,
+		"-on-error-grace-period": complete.PredictNothing,
+		"-parallel":              complete.PredictNothing,
+		"-parallel-builds":       complete.PredictNothing,
+		"-ship-logs":             complete.PredictNothing,
+		"-var":                   complete.PredictNothing,
+		"-var-file":              complete.PredictNothing,
 	}
 }
@@ -2,6 +2,7 @@ package command
 
 import (
 	"bytes"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -122,6 +123,73 @@ func TestBuildExceptFileCommaFlags(t *testing.T) {
 	}
 }
 
+func TestBuildDependsOn(t *testing.T) {
+	c := &BuildCommand{
+		Meta: testMetaFile(t),
+	}
+
+	args := []string{
+		filepath.Join(testFixture("build-depends"), "template.json"),
+	}
+
+	defer cleanup()
+
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	content, err := ioutil.ReadFile("vanilla.txt")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(content) != "File" {
+		t.Fatalf("bad: %s", content)
+	}
+}
+
+func TestBuildDependsOnNotInRun(t *testing.T) {
+	c := &BuildCommand{
+		Meta: testMetaFile(t),
+	}
+
+	args := []string{
+		"-only=vanilla",
+		filepath.Join(testFixture("build-depends"), "template.json"),
+	}
+
+	defer cleanup()
+
+	if code := c.Run(args); code == 0 {
+		t.Fatal("should fail")
+	}
+	if fileExists("vanilla.txt") {
+		t.Error("Expected NOT to find vanilla.txt")
+	}
+}
+
+func TestBuildProvisionerDryRun(t *testing.T) {
+	c := &BuildCommand{
+		Meta: testMetaFile(t),
+	}
+
+	args := []string{
+		"-provisioner-dry-run",
+		filepath.Join(testFixture("build-only"), "template.json"),
+	}
+
+	defer cleanup()
+
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	for _, f := range []string{"chocolate.txt", "vanilla.txt", "cherry.txt"} {
+		if fileExists(f) {
+			t.Errorf("Expected NOT to find %s", f)
+		}
+	}
+}
+
 // fileExists returns true if the filename is found
 func fileExists(filename string) bool {
 	if _, err := os.Stat(filename); err == nil {
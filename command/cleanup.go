@@ -0,0 +1,135 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/helper/flag-slice"
+	"github.com/hashicorp/packer/packer"
+
+	"github.com/posener/complete"
+)
+
+type CleanupCommand struct {
+	Meta
+}
+
+func (c *CleanupCommand) Run(args []string) int {
+	var dryRun bool
+	var olderThan time.Duration
+	var profile string
+	var regions []string
+	flags := c.Meta.FlagSet("cleanup", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.BoolVar(&dryRun, "dry-run", false, "")
+	flags.DurationVar(&olderThan, "older-than", 24*time.Hour, "")
+	flags.StringVar(&profile, "profile", "", "")
+	flags.Var((*sliceflag.StringFlag)(&regions), "region", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		flags.Usage()
+		return 1
+	}
+	if args[0] != "amazon" {
+		c.Ui.Error(fmt.Sprintf("Can't clean up '%s', only amazon is supported", args[0]))
+		return 1
+	}
+	if len(regions) == 0 {
+		// The region of the environment
+		regions = []string{""}
+	}
+
+	before := time.Now().Add(-olderThan)
+	failed := false
+	for _, region := range regions {
+		access := &awscommon.AccessConfig{ProfileName: profile, RawRegion: region}
+		session, err := access.Session()
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to connect to AWS: %s", err))
+			return 1
+		}
+		ui := &packer.TargetedUI{Target: access.SessionRegion(), Ui: c.Ui}
+		ec2conn := ec2.New(session)
+
+		ui.Say(fmt.Sprintf("Looking for temporary resources created before %s...",
+			before.UTC().Format(time.RFC3339)))
+		orphans, err := awscommon.FindOrphans(ec2conn, before)
+		if err != nil {
+			ui.Error(err.Error())
+			failed = true
+			continue
+		}
+		if len(orphans) == 0 {
+			ui.Say("Nothing to clean up")
+			continue
+		}
+		for _, orphan := range orphans {
+			created := orphan.Created.UTC().Format(time.RFC3339)
+			ui.Machine("cleanup-resource", orphan.Type, orphan.Id, created)
+			ui.Message(fmt.Sprintf("%s, created %s", orphan, created))
+		}
+		if dryRun {
+			continue
+		}
+
+		if err := awscommon.DeleteOrphans(ec2conn, ui, orphans); err != nil {
+			ui.Error(err.Error())
+			failed = true
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func (*CleanupCommand) Help() string {
+	helpText := `
+Usage: packer cleanup [options] amazon
+
+  Deletes the temporary resources builds left behind when they crashed
+  or were killed: the instances, spot requests and volumes Packer tagged
+  as temporary, and the key pairs and security groups whose name Packer
+  generated. Only the resources created before -older-than are deleted,
+  so that the builds still running keep theirs.
+
+  AWS credentials are found as they are for the amazon builders.
+
+Options:
+
+  -dry-run             List the resources without deleting them
+  -older-than=24h      Only clean up the resources older than this
+  -profile=name        The AWS profile to use
+  -region=name         The region to clean up, may be repeated. The
+                       region of the environment by default
+  -machine-readable    Machine-readable output
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*CleanupCommand) Synopsis() string {
+	return "delete the temporary resources builds left behind"
+}
+
+func (*CleanupCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("amazon")
+}
+
+func (*CleanupCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-dry-run":          complete.PredictNothing,
+		"-older-than":       complete.PredictNothing,
+		"-profile":          complete.PredictNothing,
+		"-region":           complete.PredictNothing,
+		"-machine-readable": complete.PredictNothing,
+	}
+}
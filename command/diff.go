@@ -0,0 +1,276 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/post-processor/manifest"
+
+	"github.com/posener/complete"
+)
+
+type DiffCommand struct {
+	Meta
+}
+
+func (c *DiffCommand) Run(args []string) int {
+	var buildName string
+	flags := c.Meta.FlagSet("diff", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.StringVar(&buildName, "build", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 2 {
+		flags.Usage()
+		return 1
+	}
+
+	var latest [2]map[string]manifest.Artifact
+	for i, path := range args {
+		builds, err := readLatestBuilds(path)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to read manifest: %s", err))
+			return 1
+		}
+		latest[i] = builds
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, builds := range latest {
+		for name := range builds {
+			if seen[name] || (buildName != "" && name != buildName) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		if buildName != "" {
+			c.Ui.Error(fmt.Sprintf("No build named '%s' in the manifests", buildName))
+			return 1
+		}
+		c.Ui.Say("No builds in the manifests")
+		return 0
+	}
+
+	for _, name := range names {
+		before, hasBefore := latest[0][name]
+		after, hasAfter := latest[1][name]
+
+		switch {
+		case !hasBefore:
+			c.Ui.Say(fmt.Sprintf("==> %s: only in %s", name, args[1]))
+			c.Ui.Machine("diff-build", name, "added")
+			continue
+		case !hasAfter:
+			c.Ui.Say(fmt.Sprintf("==> %s: only in %s", name, args[0]))
+			c.Ui.Machine("diff-build", name, "removed")
+			continue
+		}
+
+		c.Ui.Say(fmt.Sprintf("==> %s (%s -> %s)", name, formatBuildTime(before), formatBuildTime(after)))
+		changes := diffBuilds(&before, &after)
+		if len(changes) == 0 {
+			c.Ui.Say("  No changes")
+		}
+		for _, change := range changes {
+			c.Ui.Machine("diff", name, change.field, change.before, change.after)
+			c.Ui.Say(change.String())
+		}
+	}
+
+	return 0
+}
+
+func (*DiffCommand) Help() string {
+	helpText := `
+Usage: packer diff [options] MANIFEST1 MANIFEST2
+
+  Compares the builds recorded in two manifests, as the manifest
+  post-processor wrote them, and reports what changed between them:
+  the artifacts, the custom data and the metadata of the builds.
+  The latest build of each name is compared.
+
+Options:
+
+  -build=name        Only compare the builds with this name
+  -machine-readable  Machine-readable output
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*DiffCommand) Synopsis() string {
+	return "compare the builds of two manifests"
+}
+
+func (*DiffCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*.json")
+}
+
+func (*DiffCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-build":            complete.PredictNothing,
+		"-machine-readable": complete.PredictNothing,
+	}
+}
+
+// readLatestBuilds returns the latest build of each name recorded in the
+// manifest at path.
+func readLatestBuilds(path string) (map[string]manifest.Artifact, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file manifest.ManifestFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("Error parsing manifest %s: %s", path, err)
+	}
+
+	builds := make(map[string]manifest.Artifact)
+	for _, build := range file.Builds {
+		if latest, ok := builds[build.BuildName]; ok && latest.BuildTime > build.BuildTime {
+			continue
+		}
+		builds[build.BuildName] = build
+	}
+	return builds, nil
+}
+
+func formatBuildTime(build manifest.Artifact) string {
+	return time.Unix(build.BuildTime, 0).UTC().Format(time.RFC3339)
+}
+
+// buildChange is a field of a build that differs between two builds. The
+// before or the after value is empty when the field was added or removed.
+type buildChange struct {
+	field  string
+	before string
+	after  string
+}
+
+func (c buildChange) String() string {
+	switch {
+	case c.before == "":
+		return fmt.Sprintf("  + %s: %s", c.field, c.after)
+	case c.after == "":
+		return fmt.Sprintf("  - %s: %s", c.field, c.before)
+	default:
+		return fmt.Sprintf("  ~ %s: %s -> %s", c.field, c.before, c.after)
+	}
+}
+
+// diffBuilds returns the fields that differ between the builds. The build
+// time and the run UUID always do, so they aren't compared.
+func diffBuilds(before, after *manifest.Artifact) []buildChange {
+	var changes []buildChange
+	field := func(name, o, n string) {
+		if o != n {
+			changes = append(changes, buildChange{name, o, n})
+		}
+	}
+
+	field("builder_type", before.BuilderType, after.BuilderType)
+	field("artifact_id", before.ArtifactId, after.ArtifactId)
+	field("lineage", before.Lineage, after.Lineage)
+	if before.LineageVersion != after.LineageVersion {
+		field("lineage_version", strconv.Itoa(before.LineageVersion), strconv.Itoa(after.LineageVersion))
+	}
+
+	beforeFiles := make(map[string]string)
+	for _, f := range before.ArtifactFiles {
+		beforeFiles[f.Name] = strconv.FormatInt(f.Size, 10)
+	}
+	afterFiles := make(map[string]string)
+	for _, f := range after.ArtifactFiles {
+		afterFiles[f.Name] = strconv.FormatInt(f.Size, 10)
+	}
+	for _, name := range mapKeys(beforeFiles, afterFiles) {
+		o, n := beforeFiles[name], afterFiles[name]
+		if o != "" {
+			o += " bytes"
+		}
+		if n != "" {
+			n += " bytes"
+		}
+		field("files."+name, o, n)
+	}
+
+	for _, key := range mapKeys(before.CustomData, after.CustomData) {
+		field("custom_data."+key, before.CustomData[key], after.CustomData[key])
+	}
+
+	var metadata []string
+	for key := range before.Metadata {
+		metadata = append(metadata, key)
+	}
+	for key := range after.Metadata {
+		if _, ok := before.Metadata[key]; !ok {
+			metadata = append(metadata, key)
+		}
+	}
+	sort.Strings(metadata)
+	for _, key := range metadata {
+		removed, added := diffLines(before.Metadata[key], after.Metadata[key])
+		for _, line := range removed {
+			changes = append(changes, buildChange{"metadata." + key, line, ""})
+		}
+		for _, line := range added {
+			changes = append(changes, buildChange{"metadata." + key, "", line})
+		}
+	}
+
+	return changes
+}
+
+// diffLines returns the lines only in before and the lines only in after, in
+// the order they come in. Lines are compared as a set, as in package
+// inventories the order doesn't matter.
+func diffLines(before, after []string) (removed, added []string) {
+	count := make(map[string]int)
+	for _, line := range before {
+		count[line]++
+	}
+	for _, line := range after {
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		added = append(added, line)
+	}
+	for _, line := range before {
+		if count[line] > 0 {
+			count[line]--
+			removed = append(removed, line)
+		}
+	}
+	return removed, added
+}
+
+// mapKeys returns the keys of both maps, sorted.
+func mapKeys(a, b map[string]string) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
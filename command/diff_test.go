@@ -0,0 +1,54 @@
+package command
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffCommand(t *testing.T) {
+	c := &DiffCommand{
+		Meta: testMeta(t),
+	}
+	args := []string{
+		filepath.Join(testFixture("diff"), "v1.json"),
+		filepath.Join(testFixture("diff"), "v2.json"),
+	}
+
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	stdout, _ := outputCommand(t, c.Meta)
+	expected := `==> amazon-ebs (2018-09-15T08:26:40Z -> 2018-09-26T22:13:20Z)
+  ~ artifact_id: us-east-1:ami-1 -> us-east-1:ami-2
+  ~ custom_data.source_ami: ami-base-1 -> ami-base-2
+  - metadata.packages: curl 7.58.0-2ubuntu3
+  + metadata.packages: curl 7.58.0-2ubuntu3.5
+==> docker (2018-09-15T08:26:40Z -> 2018-09-26T22:13:20Z)
+  No changes
+`
+	if stdout != expected {
+		t.Fatalf("bad output:\n%s", stdout)
+	}
+}
+
+func TestDiffCommand_unknownBuild(t *testing.T) {
+	c := &DiffCommand{
+		Meta: testMeta(t),
+	}
+	args := []string{
+		"-build", "foo",
+		filepath.Join(testFixture("diff"), "v1.json"),
+		filepath.Join(testFixture("diff"), "v2.json"),
+	}
+
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad exit code: %d", code)
+	}
+
+	_, stderr := outputCommand(t, c.Meta)
+	if !strings.Contains(stderr, "No build named 'foo'") {
+		t.Fatalf("bad output: %s", stderr)
+	}
+}
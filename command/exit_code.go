@@ -0,0 +1,46 @@
+package command
+
+import (
+	"github.com/hashicorp/packer/packer"
+)
+
+// errorClassExitCodes maps the class of a failure to the exit code of the
+// build command, so that callers can tell failures apart without parsing
+// the output. 1 is kept for failures that couldn't be classified, as that
+// is what it always has been.
+var errorClassExitCodes = map[packer.ErrorClass]int{
+	packer.ErrorClassInternal:    1,
+	packer.ErrorClassConfig:      2,
+	packer.ErrorClassCredential:  3,
+	packer.ErrorClassCapacity:    4,
+	packer.ErrorClassThrottle:    5,
+	packer.ErrorClassProvisioner: 6,
+	packer.ErrorClassTimeout:     7,
+}
+
+// exitCode returns the exit code for a failure of the given class.
+func exitCode(class packer.ErrorClass) int {
+	if code, ok := errorClassExitCodes[class]; ok {
+		return code
+	}
+
+	return errorClassExitCodes[packer.ErrorClassInternal]
+}
+
+// errorsClass returns the class shared by all the errors, or
+// ErrorClassInternal if their classes differ.
+func errorsClass(errs map[string]error) packer.ErrorClass {
+	var class packer.ErrorClass
+	for _, err := range errs {
+		c := packer.ClassifyError(err)
+		if class != "" && c != class {
+			return packer.ErrorClassInternal
+		}
+		class = c
+	}
+
+	if class == "" {
+		return packer.ErrorClassInternal
+	}
+	return class
+}
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestErrorsClass(t *testing.T) {
+	capacity := packer.NewClassifiedError(packer.ErrorClassCapacity, errors.New("foo"))
+	timeout := packer.NewClassifiedError(packer.ErrorClassTimeout, errors.New("bar"))
+
+	cases := []struct {
+		errs     map[string]error
+		expected packer.ErrorClass
+	}{
+		{map[string]error{}, packer.ErrorClassInternal},
+		{map[string]error{"a": capacity}, packer.ErrorClassCapacity},
+		{map[string]error{"a": capacity, "b": capacity}, packer.ErrorClassCapacity},
+		{map[string]error{"a": capacity, "b": timeout}, packer.ErrorClassInternal},
+		{map[string]error{"a": errors.New("baz")}, packer.ErrorClassInternal},
+	}
+
+	for _, tc := range cases {
+		if actual := errorsClass(tc.errs); actual != tc.expected {
+			t.Fatalf("bad class for %v: %s, expected %s", tc.errs, actual, tc.expected)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	seen := make(map[int]packer.ErrorClass)
+	for _, class := range packer.ErrorClasses {
+		code := exitCode(class)
+		if code == 0 {
+			t.Fatalf("class %s has exit code 0", class)
+		}
+		if other, ok := seen[code]; ok {
+			t.Fatalf("classes %s and %s share exit code %d", class, other, code)
+		}
+		seen[code] = class
+	}
+
+	if code := exitCode("unknown"); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+
+	"github.com/posener/complete"
+)
+
+type LineageCommand struct {
+	Meta
+}
+
+func (c *LineageCommand) Run(args []string) int {
+	var manifest string
+	flags := c.Meta.FlagSet("lineage", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.StringVar(&manifest, "manifest", packer.DefaultLineageManifest, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) > 1 {
+		flags.Usage()
+		return 1
+	}
+
+	history, err := packer.ReadLineageHistory(manifest)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to read the lineage history: %s", err))
+		return 1
+	}
+
+	var lineage string
+	if len(args) == 1 {
+		lineage = args[0]
+	}
+
+	found := false
+	current := ""
+	for _, entry := range history {
+		if lineage != "" && entry.Lineage != lineage {
+			continue
+		}
+		found = true
+
+		if entry.Lineage != current {
+			current = entry.Lineage
+			c.Ui.Say(fmt.Sprintf("==> %s", current))
+		}
+
+		buildTime := time.Unix(entry.BuildTime, 0).UTC().Format(time.RFC3339)
+		c.Ui.Machine("lineage-build",
+			entry.Lineage,
+			strconv.Itoa(entry.Version),
+			entry.BuildName,
+			entry.BuilderType,
+			entry.ArtifactId,
+			strconv.FormatInt(entry.BuildTime, 10))
+		c.Ui.Say(fmt.Sprintf("  v%d  %s  %s  %s",
+			entry.Version, entry.BuildName, entry.ArtifactId, buildTime))
+	}
+
+	if !found {
+		if lineage != "" {
+			c.Ui.Error(fmt.Sprintf("No versions of lineage '%s' in %s", lineage, manifest))
+			return 1
+		}
+		c.Ui.Say(fmt.Sprintf("No lineages in %s", manifest))
+	}
+
+	return 0
+}
+
+func (*LineageCommand) Help() string {
+	helpText := `
+Usage: packer lineage [options] [LINEAGE]
+
+  Lists the versions of lineages built so far, as the manifest
+  post-processor recorded them, along with the artifacts of each.
+  Only the given lineage is listed if there is one.
+
+Options:
+
+  -manifest=path     The manifest to read, packer-manifest.json by default
+  -machine-readable  Machine-readable output
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*LineageCommand) Synopsis() string {
+	return "list the versions of image lineages"
+}
+
+func (*LineageCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*LineageCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-manifest":         complete.PredictNothing,
+		"-machine-readable": complete.PredictNothing,
+	}
+}
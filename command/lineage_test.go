@@ -0,0 +1,49 @@
+package command
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineageCommand(t *testing.T) {
+	c := &LineageCommand{
+		Meta: testMeta(t),
+	}
+	args := []string{
+		"-manifest", filepath.Join(testFixture("lineage"), "packer-manifest.json"),
+		"ubuntu-base",
+	}
+
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	stdout, _ := outputCommand(t, c.Meta)
+	expected := `==> ubuntu-base
+  v1  amazon-ebs  us-east-1:ami-1  2018-09-15T08:26:40Z
+  v2  amazon-ebs  us-east-1:ami-2  2018-09-26T22:13:20Z
+`
+	if stdout != expected {
+		t.Fatalf("bad output:\n%s", stdout)
+	}
+}
+
+func TestLineageCommand_unknown(t *testing.T) {
+	c := &LineageCommand{
+		Meta: testMeta(t),
+	}
+	args := []string{
+		"-manifest", filepath.Join(testFixture("lineage"), "packer-manifest.json"),
+		"foo",
+	}
+
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad exit code: %d", code)
+	}
+
+	_, stderr := outputCommand(t, c.Meta)
+	if !strings.Contains(stderr, "No versions of lineage 'foo'") {
+		t.Fatalf("bad output: %s", stderr)
+	}
+}
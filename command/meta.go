@@ -30,6 +30,14 @@ type Meta struct {
 	Ui         packer.Ui
 	Version    string
 
+	// ParallelBuilds is the default of the -parallel-builds flag of
+	// build, from the config files.
+	ParallelBuilds int
+
+	// ShipLogs is the default of the -ship-logs flag of build, from the
+	// config files.
+	ShipLogs string
+
 	// These are set by command-line flags
 	flagBuildExcept []string
 	flagBuildOnly   []string
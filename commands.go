@@ -23,6 +23,18 @@ func init() {
 			}, nil
 		},
 
+		"cleanup": func() (cli.Command, error) {
+			return &command.CleanupCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"diff": func() (cli.Command, error) {
+			return &command.DiffCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"fix": func() (cli.Command, error) {
 			return &command.FixCommand{
 				Meta: *CommandMeta,
@@ -35,6 +47,12 @@ func init() {
 			}, nil
 		},
 
+		"lineage": func() (cli.Command, error) {
+			return &command.LineageCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"push": func() (cli.Command, error) {
 			return &command.PushCommand{
 				Meta: *CommandMeta,
@@ -121,19 +121,52 @@ func (w *waitExpression) String() string {
 type specialExpression struct {
 	s      string
 	action KeyAction
+	// modifiers are held down while the special key is pressed, such as
+	// ctrl and alt in <ctrlAltDel>.
+	modifiers []string
+}
+
+// modifierKeys are the special keys held down for the modifiers.
+var modifierKeys = map[string]string{
+	"ctrl":  "leftctrl",
+	"alt":   "leftalt",
+	"shift": "leftshift",
+	"super": "leftsuper",
 }
 
 // Do sends the special command to the driver, along with the key action.
+// Modifiers are pressed before, and released after it in reverse order.
 func (s *specialExpression) Do(ctx context.Context, driver BCDriver) error {
-	return driver.SendSpecial(s.s, s.action)
+	for _, m := range s.modifiers {
+		if err := driver.SendSpecial(modifierKeys[m], KeyOn); err != nil {
+			return err
+		}
+	}
+	if err := driver.SendSpecial(s.s, s.action); err != nil {
+		return err
+	}
+	for i := len(s.modifiers) - 1; i >= 0; i-- {
+		if err := driver.SendSpecial(modifierKeys[s.modifiers[i]], KeyOff); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// Validate always passes
+// Validate returns an error if modifiers are used to hold or release the
+// key, which only a press of it can be combined with.
 func (s *specialExpression) Validate() error {
+	if len(s.modifiers) > 0 && s.action != KeyPress {
+		return fmt.Errorf("Modifiers %s can't be used with %s: %s",
+			strings.Join(s.modifiers, ", "), s.action, s.s)
+	}
 	return nil
 }
 
 func (s *specialExpression) String() string {
+	if len(s.modifiers) > 0 {
+		return fmt.Sprintf("Spec-%s(%s+%s)", s.action, strings.Join(s.modifiers, "+"), s.s)
+	}
 	return fmt.Sprintf("Spec-%s(%s)", s.action, s.s)
 }
 
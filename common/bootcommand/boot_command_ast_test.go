@@ -77,6 +77,18 @@ func Test_special(t *testing.T) {
 			"<enteroff><enterOFF><eNtErOfF><ENTEROFF>",
 			"Spec-Off(enter)",
 		},
+		{
+			"<ctrlAltDel><CTRLALTDEL><ctrlaltdel>",
+			"Spec-Press(ctrl+alt+del)",
+		},
+		{
+			"<shiftF10><SHIFTf10>",
+			"Spec-Press(shift+f10)",
+		},
+		{
+			"<menu><MENU>",
+			"Spec-Press(menu)",
+		},
 	}
 	for _, tt := range specials {
 		seq, err := GenerateExpressionSequence(tt.in)
@@ -110,6 +122,14 @@ func Test_validation(t *testing.T) {
 			"<",
 			true,
 		},
+		{
+			"<altF4>",
+			true,
+		},
+		{
+			"<ctrlDelOn>",
+			false,
+		},
 	}
 	for _, tt := range expressions {
 		exp, err := GenerateExpressionSequence(tt.in)
@@ -11,6 +11,9 @@ import (
 type BootConfig struct {
 	RawBootWait string   `mapstructure:"boot_wait"`
 	BootCommand []string `mapstructure:"boot_command"`
+	// BootKeyboardLayout is the keyboard layout of the guest the boot
+	// command is typed with.
+	BootKeyboardLayout string `mapstructure:"boot_keyboard_layout"`
 
 	BootWait time.Duration ``
 }
@@ -34,6 +37,10 @@ func (c *BootConfig) Prepare(ctx *interpolate.Context) (errs []error) {
 		}
 	}
 
+	if err := CheckKeyboardLayout(c.BootKeyboardLayout); err != nil {
+		errs = append(errs, err)
+	}
+
 	if c.BootCommand != nil {
 		expSeq, err := GenerateExpressionSequence(c.FlatBootCommand())
 		if err != nil {
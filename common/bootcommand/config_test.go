@@ -35,6 +35,22 @@ func TestConfigPrepare(t *testing.T) {
 	if len(errs) > 0 {
 		t.Fatalf("bad: %#v", errs)
 	}
+
+	// Test with a bad keyboard layout
+	c = new(BootConfig)
+	c.BootKeyboardLayout = "klingon"
+	errs = c.Prepare(&interpolate.Context{})
+	if len(errs) == 0 {
+		t.Fatal("should error")
+	}
+
+	// Test with a good one
+	c = new(BootConfig)
+	c.BootKeyboardLayout = "de"
+	errs = c.Prepare(&interpolate.Context{})
+	if len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
 }
 
 func TestVNCConfigPrepare(t *testing.T) {
@@ -0,0 +1,143 @@
+package bootcommand
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultKeyboardLayout is the layout boot commands are typed with unless
+// another one is configured.
+const DefaultKeyboardLayout = "us"
+
+// keyboardRows are the scancodes, in the PC-XT set 1, of the first key of
+// each row of keys typing characters, whose other keys follow with
+// consecutive scancodes. The last row is the single key left of Z on ISO
+// keyboards, which US keyboards don't have.
+var keyboardRows = []byte{0x02, 0x10, 0x1e, 0x2b, 0x56}
+
+// layoutRows are the characters each key of the rows types in a layout,
+// one string per row, with a space for the keys typing nothing.
+type layoutRows [5]string
+
+// layoutLevels are the characters typed without modifier, with shift, and
+// with AltGr, the right Alt key.
+type layoutLevels struct {
+	base, shift, altGr layoutRows
+}
+
+var layoutDefinitions = map[string]layoutLevels{
+	"us": {
+		base:  layoutRows{"1234567890-=", "qwertyuiop[]", "asdfghjkl;'`", `\zxcvbnm,./`, ""},
+		shift: layoutRows{"!@#$%^&*()_+", "QWERTYUIOP{}", `ASDFGHJKL:"~`, "|ZXCVBNM<>?", ""},
+	},
+	"uk": {
+		base:  layoutRows{"1234567890-=", "qwertyuiop[]", "asdfghjkl;'`", "#zxcvbnm,./", `\`},
+		shift: layoutRows{`!"£$%^&*()_+`, "QWERTYUIOP{}", "ASDFGHJKL:@¬", "~ZXCVBNM<>?", "|"},
+		altGr: layoutRows{"   €        ", "", "", "", "¦"},
+	},
+	"de": {
+		base:  layoutRows{"1234567890ß´", "qwertzuiopü+", "asdfghjklöä^", "#yxcvbnm,.-", "<"},
+		shift: layoutRows{`!"§$%&/()=?` + "`", "QWERTZUIOPÜ*", "ASDFGHJKLÖÄ°", "'YXCVBNM;:_", ">"},
+		altGr: layoutRows{" ²³   {[]}\\ ", "@ €        ~", "", "       µ", "|"},
+	},
+	"fr": {
+		base:  layoutRows{`&é"'(-è_çà)=`, "azertyuiop^$", "qsdfghjklmù²", "*wxcvbn,;:!", "<"},
+		shift: layoutRows{"1234567890°+", "AZERTYUIOP¨£", "QSDFGHJKLM% ", "µWXCVBN?./§", ">"},
+		altGr: layoutRows{" ~#{[|`\\^@]}", "  €        ¤", "", "", ""},
+	},
+	"dvorak": {
+		base:  layoutRows{"1234567890[]", "',.pyfgcrl/=", "aoeuidhtns-`", `\;qjkxbmwvz`, ""},
+		shift: layoutRows{"!@#$%^&*(){}", `"<>PYFGCRL?+`, "AOEUIDHTNS_~", "|:QJKXBMWVZ", ""},
+	},
+}
+
+// keystroke is how a character is typed: the key with the scancode, in the
+// PC-XT set 1, pressed while holding the modifiers.
+type keystroke struct {
+	code  byte
+	shift bool
+	altGr bool
+}
+
+// keyboardLayout are the keystrokes typing the characters of a layout.
+type keyboardLayout map[rune]keystroke
+
+var keyboardLayouts = make(map[string]keyboardLayout)
+
+func init() {
+	for name, levels := range layoutDefinitions {
+		keyboardLayouts[name] = newKeyboardLayout(levels)
+	}
+}
+
+// newKeyboardLayout maps the characters of the layout to the keystrokes
+// typing them, with as few modifiers as possible.
+func newKeyboardLayout(levels layoutLevels) keyboardLayout {
+	layout := keyboardLayout{' ': {code: 0x39}}
+	for _, level := range []struct {
+		rows         layoutRows
+		shift, altGr bool
+	}{
+		{levels.base, false, false},
+		{levels.shift, true, false},
+		{levels.altGr, false, true},
+	} {
+		for i, row := range level.rows {
+			code := keyboardRows[i]
+			for len(row) > 0 {
+				r, size := utf8.DecodeRuneInString(row)
+				row = row[size:]
+				if _, ok := layout[r]; !ok && r != ' ' {
+					layout[r] = keystroke{code, level.shift, level.altGr}
+				}
+				code++
+			}
+		}
+	}
+	return layout
+}
+
+// CheckKeyboardLayout returns an error unless boot commands can be typed
+// with the layout, or it's empty for the default one.
+func CheckKeyboardLayout(name string) error {
+	if _, ok := keyboardLayouts[name]; ok || name == "" {
+		return nil
+	}
+
+	var names []string
+	for name := range keyboardLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown keyboard layout %q, must be one of %s", name, strings.Join(names, ", "))
+}
+
+// lookupKeyboardLayout returns the layout with the name, or the default
+// one if the name is empty.
+func lookupKeyboardLayout(name string) keyboardLayout {
+	if name == "" {
+		name = DefaultKeyboardLayout
+	}
+	return keyboardLayouts[name]
+}
+
+// usKeysym returns the character the keystroke types on a US keyboard,
+// regardless of AltGr, which VNC servers translate back into the key of
+// the keystroke. The key US keyboards don't have types < and > on most
+// other layouts.
+func usKeysym(k keystroke) (rune, bool) {
+	if k.code == 0x56 {
+		if k.shift {
+			return '>', true
+		}
+		return '<', true
+	}
+	for r, us := range keyboardLayouts[DefaultKeyboardLayout] {
+		if us.code == k.code && us.shift == k.shift {
+			return r, true
+		}
+	}
+	return 0, false
+}
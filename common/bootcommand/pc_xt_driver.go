@@ -6,8 +6,6 @@ import (
 	"os"
 	"strings"
 	"time"
-	"unicode"
-	"unicode/utf8"
 
 	"github.com/hashicorp/packer/common"
 )
@@ -17,11 +15,11 @@ type SendCodeFunc func([]string) error
 type scMap map[string]*scancode
 
 type pcXTDriver struct {
-	interval    time.Duration
-	sendImpl    SendCodeFunc
-	specialMap  scMap
-	scancodeMap map[rune]byte
-	buffer      [][]string
+	interval   time.Duration
+	sendImpl   SendCodeFunc
+	specialMap scMap
+	layout     keyboardLayout
+	buffer     [][]string
 	// TODO: set from env
 	scancodeChunkSize int
 }
@@ -38,7 +36,8 @@ func (sc *scancode) makeBreak() []string {
 // NewPCXTDriver creates a new boot command driver for VMs that expect PC-XT
 // keyboard codes. `send` should send its argument to the VM. `chunkSize` should
 // be the maximum number of keyboard codes to send to `send` at one time.
-func NewPCXTDriver(send SendCodeFunc, chunkSize int) *pcXTDriver {
+// `layout` is the keyboard layout of the guest, the default one if empty.
+func NewPCXTDriver(send SendCodeFunc, chunkSize int, layout string) *pcXTDriver {
 	// We delay (default 100ms) between each input event to allow for CPU or
 	// network latency. See PackerKeyEnv for tuning.
 	keyInterval := common.PackerKeyDefault
@@ -90,33 +89,11 @@ func NewPCXTDriver(send SendCodeFunc, chunkSize int) *pcXTDriver {
 	sMap["tab"] = &scancode{[]string{"0f"}, []string{"8f"}}
 	sMap["up"] = &scancode{[]string{"e0", "48"}, []string{"e0", "c8"}}
 
-	scancodeIndex := make(map[string]byte)
-	scancodeIndex["1234567890-="] = 0x02
-	scancodeIndex["!@#$%^&*()_+"] = 0x02
-	scancodeIndex["qwertyuiop[]"] = 0x10
-	scancodeIndex["QWERTYUIOP{}"] = 0x10
-	scancodeIndex["asdfghjkl;'`"] = 0x1e
-	scancodeIndex[`ASDFGHJKL:"~`] = 0x1e
-	scancodeIndex[`\zxcvbnm,./`] = 0x2b
-	scancodeIndex["|ZXCVBNM<>?"] = 0x2b
-	scancodeIndex[" "] = 0x39
-
-	scancodeMap := make(map[rune]byte)
-	for chars, start := range scancodeIndex {
-		var i byte = 0
-		for len(chars) > 0 {
-			r, size := utf8.DecodeRuneInString(chars)
-			chars = chars[size:]
-			scancodeMap[r] = start + i
-			i += 1
-		}
-	}
-
 	return &pcXTDriver{
 		interval:          keyInterval,
 		sendImpl:          send,
 		specialMap:        sMap,
-		scancodeMap:       scancodeMap,
+		layout:            lookupKeyboardLayout(layout),
 		scancodeChunkSize: chunkSize,
 	}
 }
@@ -140,28 +117,35 @@ func (d *pcXTDriver) Flush() error {
 }
 
 func (d *pcXTDriver) SendKey(key rune, action KeyAction) error {
-	keyShift := unicode.IsUpper(key) || strings.ContainsRune(shiftedChars, key)
+	k, ok := d.layout[key]
+	if !ok {
+		return fmt.Errorf("char '%c' can't be typed with the keyboard layout", key)
+	}
 
 	var sc []string
 
 	if action&(KeyOn|KeyPress) != 0 {
-		scInt := d.scancodeMap[key]
-		if keyShift {
+		if k.shift {
 			sc = append(sc, "2a")
 		}
-		sc = append(sc, fmt.Sprintf("%02x", scInt))
+		if k.altGr {
+			sc = append(sc, "e0", "38")
+		}
+		sc = append(sc, fmt.Sprintf("%02x", k.code))
 	}
 
 	if action&(KeyOff|KeyPress) != 0 {
-		scInt := d.scancodeMap[key] + 0x80
-		if keyShift {
+		if k.shift {
 			sc = append(sc, "aa")
 		}
-		sc = append(sc, fmt.Sprintf("%02x", scInt))
+		if k.altGr {
+			sc = append(sc, "e0", "b8")
+		}
+		sc = append(sc, fmt.Sprintf("%02x", k.code+0x80))
 	}
 
-	log.Printf("Sending char '%c', code '%s', shift %v",
-		key, strings.Join(sc, ""), keyShift)
+	log.Printf("Sending char '%c', code '%s', shift %v, altgr %v",
+		key, strings.Join(sc, ""), k.shift, k.altGr)
 
 	d.send(sc)
 	return nil
@@ -79,7 +79,7 @@ func Test_pcxtSpecialOnOff(t *testing.T) {
 		codes = c
 		return nil
 	}
-	d := NewPCXTDriver(sendCodes, -1)
+	d := NewPCXTDriver(sendCodes, -1, "")
 	seq, err := GenerateExpressionSequence(in)
 	assert.NoError(t, err)
 	err = seq.Do(context.Background(), d)
@@ -95,7 +95,7 @@ func Test_pcxtSpecial(t *testing.T) {
 		codes = c
 		return nil
 	}
-	d := NewPCXTDriver(sendCodes, -1)
+	d := NewPCXTDriver(sendCodes, -1, "")
 	seq, err := GenerateExpressionSequence(in)
 	assert.NoError(t, err)
 	err = seq.Do(context.Background(), d)
@@ -114,10 +114,63 @@ func Test_flushes(t *testing.T) {
 		actual = append(actual, c)
 		return nil
 	}
-	d := NewPCXTDriver(sendCodes, -1)
+	d := NewPCXTDriver(sendCodes, -1, "")
 	seq, err := GenerateExpressionSequence(in)
 	assert.NoError(t, err)
 	err = seq.Do(context.Background(), d)
 	assert.NoError(t, err)
 	assert.Equal(t, expected, actual)
 }
+
+func Test_pcxtModifiers(t *testing.T) {
+	in := "<ctrlAltDel>"
+	expected := []string{"1d", "38", "e0", "53", "e0", "d3", "b8", "9d"}
+	var codes []string
+	sendCodes := func(c []string) error {
+		codes = c
+		return nil
+	}
+	d := NewPCXTDriver(sendCodes, -1, "")
+	seq, err := GenerateExpressionSequence(in)
+	assert.NoError(t, err)
+	err = seq.Do(context.Background(), d)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, codes)
+}
+
+func Test_pcxtKeyboardLayout(t *testing.T) {
+	var layouttests = []struct {
+		layout string
+		in     string
+		out    []string
+	}{
+		{"", "y/", []string{"15", "95", "35", "b5"}},
+		{"us", "Y", []string{"2a", "15", "aa", "95"}},
+		{"de", "y/", []string{"2c", "ac", "2a", "08", "aa", "88"}},
+		{"de", "@", []string{"e0", "38", "10", "e0", "b8", "90"}},
+		{"fr", "a1", []string{"10", "90", "2a", "02", "aa", "82"}},
+		{"uk", `"\`, []string{"2a", "03", "aa", "83", "56", "d6"}},
+	}
+
+	for _, tt := range layouttests {
+		var codes []string
+		sendCodes := func(c []string) error {
+			codes = c
+			return nil
+		}
+		d := NewPCXTDriver(sendCodes, -1, tt.layout)
+		seq, err := GenerateExpressionSequence(tt.in)
+		assert.NoError(t, err)
+		err = seq.Do(context.Background(), d)
+		assert.NoError(t, err)
+		assert.Equalf(t, tt.out, codes, "typing %s with layout %q.", tt.in, tt.layout)
+	}
+}
+
+func Test_pcxtKeyboardLayoutError(t *testing.T) {
+	d := NewPCXTDriver(func([]string) error { return nil }, -1, "us")
+	seq, err := GenerateExpressionSequence("ü")
+	assert.NoError(t, err)
+	err = seq.Do(context.Background(), d)
+	assert.Error(t, err)
+}
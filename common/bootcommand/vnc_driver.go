@@ -11,7 +11,10 @@ import (
 	"github.com/hashicorp/packer/common"
 )
 
-const KeyLeftShift uint32 = 0xFFE1
+const (
+	KeyLeftShift uint32 = 0xFFE1
+	KeyRightAlt  uint32 = 0xFFEA
+)
 
 type VNCKeyEvent interface {
 	KeyEvent(uint32, bool) error
@@ -21,11 +24,16 @@ type vncDriver struct {
 	c          VNCKeyEvent
 	interval   time.Duration
 	specialMap map[string]uint32
+	// layout is nil for the default layout, whose characters are sent as
+	// they are.
+	layout keyboardLayout
 	// keyEvent can set this error which will prevent it from continuing
 	err error
 }
 
-func NewVNCDriver(c VNCKeyEvent) *vncDriver {
+// NewVNCDriver creates a new boot command driver for VMs typed to over VNC.
+// `layout` is the keyboard layout of the guest, the default one if empty.
+func NewVNCDriver(c VNCKeyEvent, layout string) *vncDriver {
 	// We delay (default 100ms) between each key event to allow for CPU or
 	// network latency. See PackerKeyEnv for tuning.
 	keyInterval := common.PackerKeyDefault
@@ -73,11 +81,15 @@ func NewVNCDriver(c VNCKeyEvent) *vncDriver {
 	sMap["tab"] = 0xFF09
 	sMap["up"] = 0xFF52
 
-	return &vncDriver{
+	d := &vncDriver{
 		c:          c,
 		interval:   keyInterval,
 		specialMap: sMap,
 	}
+	if layout != "" && layout != DefaultKeyboardLayout {
+		d.layout = lookupKeyboardLayout(layout)
+	}
+	return d
 }
 
 func (d *vncDriver) keyEvent(k uint32, down bool) error {
@@ -99,33 +111,53 @@ func (d *vncDriver) Flush() error {
 
 func (d *vncDriver) SendKey(key rune, action KeyAction) error {
 	keyShift := unicode.IsUpper(key) || strings.ContainsRune(shiftedChars, key)
+	keyAltGr := false
 	keyCode := uint32(key)
-	log.Printf("Sending char '%c', code 0x%X, shift %v", key, keyCode, keyShift)
+	if d.layout != nil {
+		// VNC servers type the keysyms with a US layout, so this sends
+		// those of the keys typing the character with the guest layout.
+		k, ok := d.layout[key]
+		if !ok {
+			return fmt.Errorf("char '%c' can't be typed with the keyboard layout", key)
+		}
+		sym, ok := usKeysym(k)
+		if !ok {
+			return fmt.Errorf("char '%c' has no keysym with the keyboard layout", key)
+		}
+		keyShift, keyAltGr, keyCode = k.shift, k.altGr, uint32(sym)
+	}
+	log.Printf("Sending char '%c', code 0x%X, shift %v, altgr %v", key, keyCode, keyShift, keyAltGr)
+
+	var modifiers []uint32
+	if keyShift {
+		modifiers = append(modifiers, KeyLeftShift)
+	}
+	if keyAltGr {
+		modifiers = append(modifiers, KeyRightAlt)
+	}
 
 	switch action {
 	case KeyOn:
-		if keyShift {
-			d.keyEvent(KeyLeftShift, true)
-		}
+		d.modifierEvents(modifiers, true)
 		d.keyEvent(keyCode, true)
 	case KeyOff:
-		if keyShift {
-			d.keyEvent(KeyLeftShift, false)
-		}
+		d.modifierEvents(modifiers, false)
 		d.keyEvent(keyCode, false)
 	case KeyPress:
-		if keyShift {
-			d.keyEvent(KeyLeftShift, true)
-		}
+		d.modifierEvents(modifiers, true)
 		d.keyEvent(keyCode, true)
 		d.keyEvent(keyCode, false)
-		if keyShift {
-			d.keyEvent(KeyLeftShift, false)
-		}
+		d.modifierEvents(modifiers, false)
 	}
 	return d.err
 }
 
+func (d *vncDriver) modifierEvents(modifiers []uint32, down bool) {
+	for _, k := range modifiers {
+		d.keyEvent(k, down)
+	}
+}
+
 func (d *vncDriver) SendSpecial(special string, action KeyAction) error {
 	keyCode, ok := d.specialMap[special]
 	if !ok {
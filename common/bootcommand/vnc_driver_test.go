@@ -30,7 +30,30 @@ func Test_vncSpecialLookup(t *testing.T) {
 		{0xFFE2, true},
 	}
 	s := &sender{}
-	d := NewVNCDriver(s)
+	d := NewVNCDriver(s, "")
+	seq, err := GenerateExpressionSequence(in)
+	assert.NoError(t, err)
+	err = seq.Do(context.Background(), d)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, s.e)
+}
+
+func Test_vncKeyboardLayout(t *testing.T) {
+	in := "zY@"
+	expected := []event{
+		{'y', true},
+		{'y', false},
+		{KeyLeftShift, true},
+		{'Z', true},
+		{'Z', false},
+		{KeyLeftShift, false},
+		{KeyRightAlt, true},
+		{'q', true},
+		{'q', false},
+		{KeyRightAlt, false},
+	}
+	s := &sender{}
+	d := NewVNCDriver(s, "de")
 	seq, err := GenerateExpressionSequence(in)
 	assert.NoError(t, err)
 	err = seq.Do(context.Background(), d)
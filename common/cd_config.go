@@ -0,0 +1,73 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// DefaultCDLabel is the volume label of the CD created from cd_files and
+// cd_content unless another one is configured.
+const DefaultCDLabel = "packer"
+
+type CDConfig struct {
+	CDFiles   []string          `mapstructure:"cd_files"`
+	CDContent map[string]string `mapstructure:"cd_content"`
+	CDLabel   string            `mapstructure:"cd_label"`
+}
+
+func (c *CDConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+	var err error
+
+	if c.CDFiles == nil {
+		c.CDFiles = make([]string, 0)
+	}
+
+	for _, path := range c.CDFiles {
+		if strings.ContainsAny(path, "*?[") {
+			_, err = filepath.Glob(path)
+		} else {
+			_, err = os.Stat(path)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Bad CD disk file '%s': %s", path, err))
+		}
+	}
+
+	for path := range c.CDContent {
+		if cdContentPath(path) == "" {
+			errs = append(errs, fmt.Errorf("Bad CD disk content path '%s'", path))
+		}
+	}
+
+	if c.CDLabel == "" {
+		c.CDLabel = DefaultCDLabel
+	}
+
+	// The volume identifier of ISO 9660 file systems is at most 32
+	// characters long.
+	if len(c.CDLabel) > 32 {
+		errs = append(errs, fmt.Errorf("cd_label must be at most 32 characters long"))
+	}
+
+	return errs
+}
+
+// HasCD tells whether a CD is created from cd_files and cd_content.
+func (c *CDConfig) HasCD() bool {
+	return len(c.CDFiles) > 0 || len(c.CDContent) > 0
+}
+
+// cdContentPath returns the path, relative to the root of the CD, of a
+// cd_content entry, or an empty string if it points outside of the CD.
+func cdContentPath(path string) string {
+	path = filepath.Clean(filepath.FromSlash(strings.TrimLeft(path, `/\`)))
+	if path == "." || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestCDConfigPrepare(t *testing.T) {
+	// Good
+	c := CDConfig{}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.CDLabel != DefaultCDLabel {
+		t.Fatalf("bad label: %s", c.CDLabel)
+	}
+	if c.HasCD() {
+		t.Fatal("should not have a CD")
+	}
+
+	// Good
+	c = CDConfig{
+		CDFiles:   []string{"cd_config.go", "test-fixtures/floppies/*"},
+		CDContent: map[string]string{"user-data": "#cloud-config"},
+		CDLabel:   "cidata",
+	}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if !c.HasCD() {
+		t.Fatal("should have a CD")
+	}
+
+	// Bad
+	c = CDConfig{CDFiles: []string{"i/dont/exist"}}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Bad
+	c = CDConfig{CDContent: map[string]string{"../user-data": ""}}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Bad
+	c = CDConfig{CDLabel: "a-label-longer-than-thirty-two-chars"}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
@@ -0,0 +1,63 @@
+// Package chroot contains the steps, and the communicator, the chroot
+// builders share to provision a device chrooted into where it is mounted.
+package chroot
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// CommandWrapper is a type that given a command, will possibly modify that
+// command in-flight. This might return an error.
+type CommandWrapper func(string) (string, error)
+
+// ShellCommand takes a command string and returns an *exec.Cmd to execute
+// it within the context of a shell (/bin/sh).
+func ShellCommand(command string) *exec.Cmd {
+	return exec.Command("/bin/sh", "-c", command)
+}
+
+// shellQuote quotes the string as a single word of a shell command.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
+
+// RunWrapped runs the command, wrapped with the wrapper, in a shell. The
+// error has the standard error of the command.
+func RunWrapped(wrapper CommandWrapper, command string) error {
+	wrapped, err := wrapper(command)
+	if err != nil {
+		return fmt.Errorf("Error wrapping command %q: %s", command, err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := ShellCommand(wrapped)
+	cmd.Stderr = &stderr
+	log.Printf("Executing: %s", wrapped)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+// RunWrappedOutput runs the command like RunWrapped, and returns its output
+// without surrounding whitespace.
+func RunWrappedOutput(wrapper CommandWrapper, command string) (string, error) {
+	wrapped, err := wrapper(command)
+	if err != nil {
+		return "", fmt.Errorf("Error wrapping command %q: %s", command, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := ShellCommand(wrapped)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	log.Printf("Executing: %s", wrapped)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s\nStderr: %s", err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
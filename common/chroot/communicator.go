@@ -24,7 +24,7 @@ type Communicator struct {
 
 func (c *Communicator) Start(cmd *packer.RemoteCmd) error {
 	command, err := c.CmdWrapper(
-		fmt.Sprintf("chroot %s /bin/sh -c \"%s\"", c.Chroot, cmd.Command))
+		fmt.Sprintf("chroot %s /bin/sh -c %s", shellQuote(c.Chroot), shellQuote(cmd.Command)))
 	if err != nil {
 		return err
 	}
@@ -64,7 +64,7 @@ func (c *Communicator) Start(cmd *packer.RemoteCmd) error {
 func (c *Communicator) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
 	dst = filepath.Join(c.Chroot, dst)
 	log.Printf("Uploading to chroot dir: %s", dst)
-	tf, err := ioutil.TempFile("", "packer-amazon-chroot")
+	tf, err := ioutil.TempFile("", "packer-chroot")
 	if err != nil {
 		return fmt.Errorf("Error preparing shell script: %s", err)
 	}
@@ -74,7 +74,7 @@ func (c *Communicator) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
 		return err
 	}
 
-	cpCmd, err := c.CmdWrapper(fmt.Sprintf("cp %s %s", tf.Name(), dst))
+	cpCmd, err := c.CmdWrapper(fmt.Sprintf("cp %s %s", shellQuote(tf.Name()), shellQuote(dst)))
 	if err != nil {
 		return err
 	}
@@ -95,7 +95,7 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 	chrootDest := filepath.Join(c.Chroot, dst)
 
 	log.Printf("Uploading directory '%s' to '%s'", src, chrootDest)
-	cpCmd, err := c.CmdWrapper(fmt.Sprintf("cp -R '%s' %s", src, chrootDest))
+	cpCmd, err := c.CmdWrapper(fmt.Sprintf("cp -R %s %s", shellQuote(src), shellQuote(chrootDest)))
 	if err != nil {
 		return err
 	}
@@ -119,7 +119,7 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 }
 
 func (c *Communicator) DownloadDir(src string, dst string, exclude []string) error {
-	return fmt.Errorf("DownloadDir is not implemented for amazon-chroot")
+	return fmt.Errorf("DownloadDir is not implemented for chroot builds")
 }
 
 func (c *Communicator) Download(src string, w io.Writer) error {
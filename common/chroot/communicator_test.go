@@ -0,0 +1,47 @@
+package chroot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestCommunicator_ImplementsCommunicator(t *testing.T) {
+	var raw interface{}
+	raw = &Communicator{}
+	if _, ok := raw.(packer.Communicator); !ok {
+		t.Fatalf("Communicator should be a communicator")
+	}
+}
+
+func TestCommunicatorStart(t *testing.T) {
+	var wrapped string
+	c := &Communicator{
+		Chroot: "/mnt/packer amazon",
+		CmdWrapper: func(command string) (string, error) {
+			wrapped = command
+			// Run the command on the host, without the chroot
+			return strings.TrimPrefix(command, "chroot '/mnt/packer amazon' "), nil
+		},
+	}
+
+	var stdout bytes.Buffer
+	cmd := &packer.RemoteCmd{
+		Command: `echo "it's" $((1 + 1)) \$HOME`,
+		Stdout:  &stdout,
+	}
+	if err := c.Start(cmd); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd.Wait()
+
+	expected := `chroot '/mnt/packer amazon' /bin/sh -c 'echo "it'"'"'s" $((1 + 1)) \$HOME'`
+	if wrapped != expected {
+		t.Fatalf("bad: %s", wrapped)
+	}
+	if cmd.ExitStatus != 0 || stdout.String() != "it's 2 $HOME\n" {
+		t.Fatalf("bad: %d %q", cmd.ExitStatus, stdout.String())
+	}
+}
@@ -0,0 +1,85 @@
+package chroot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// lockPath is the file the chroot builds running on the same host lock, so
+// that they don't change its devices at the same time.
+const lockPath = "/var/lock/packer-chroot/lock"
+
+// Lock obtains the lock the chroot builds running on the same host share. It
+// blocks until the lock is released, with Unlock, by any other build.
+func Lock() (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, fmt.Errorf("Error creating lock: %s", err)
+	}
+
+	log.Printf("Obtaining lock: %s", lockPath)
+	f, err := os.Create(lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating lock: %s", err)
+	}
+
+	// LOCK!
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Error obtaining lock: %s", err)
+	}
+	return f, nil
+}
+
+// Unlock releases the lock obtained with Lock.
+func Unlock(f *os.File) error {
+	log.Printf("Unlocking: %s", f.Name())
+	defer f.Close()
+	return unlockFile(f)
+}
+
+// StepFlock obtains the lock of the chroot builds, while the device is
+// prepared and attached.
+//
+// Produces:
+//   flock_cleanup Cleanup - To perform early cleanup
+type StepFlock struct {
+	fh *os.File
+}
+
+func (s *StepFlock) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+
+	f, err := Lock()
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Set the file handle, we can't close it because we need to hold
+	// the lock.
+	s.fh = f
+
+	state.Put("flock_cleanup", s)
+	return multistep.ActionContinue
+}
+
+func (s *StepFlock) Cleanup(state multistep.StateBag) {
+	s.CleanupFunc(state)
+}
+
+func (s *StepFlock) CleanupFunc(state multistep.StateBag) error {
+	if s.fh == nil {
+		return nil
+	}
+
+	fh := s.fh
+	s.fh = nil
+	return Unlock(fh)
+}
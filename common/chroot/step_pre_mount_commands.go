@@ -5,8 +5,15 @@ import (
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
 )
 
+// interpolateContextProvider is the configuration of a build, in the
+// "config" of the state, the mount commands are interpolated with.
+type interpolateContextProvider interface {
+	GetContext() interpolate.Context
+}
+
 type preMountCommandsData struct {
 	Device string
 }
@@ -17,7 +24,7 @@ type StepPreMountCommands struct {
 }
 
 func (s *StepPreMountCommands) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
-	config := state.Get("config").(*Config)
+	config := state.Get("config").(interpolateContextProvider)
 	device := state.Get("device").(string)
 	ui := state.Get("ui").(packer.Ui)
 	wrappedCommand := state.Get("wrappedCommand").(CommandWrapper)
@@ -26,7 +33,7 @@ func (s *StepPreMountCommands) Run(_ context.Context, state multistep.StateBag)
 		return multistep.ActionContinue
 	}
 
-	ctx := config.ctx
+	ctx := config.GetContext()
 	ctx.Data = &preMountCommandsData{Device: device}
 
 	ui.Say("Running device setup commands...")
@@ -0,0 +1,271 @@
+package common
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// deltaLayerDir is where the listings of the root filesystem and the
+// changes are kept on the machine while it is provisioned.
+const deltaLayerDir = "/var/tmp/packer-delta"
+
+// deltaLayerPrune are the paths never part of the layer, they are either
+// not on the root filesystem of a booted machine or never imaged.
+var deltaLayerPrune = []string{"/dev", "/proc", "/run", "/sys", "/tmp", deltaLayerDir}
+
+// deltaLayer exports the changes the provisioners make to the root
+// filesystem of the machine. The filesystem is listed before and after
+// they run, and the files whose type, mode, owner, size or modification
+// time changed are archived in a tar layer. The deleted files are whiteout
+// entries of the layer, in the format of OCI image layers: an empty
+// ".wh.<name>" file next to where the deleted file was.
+type deltaLayer struct {
+	path    string
+	exclude []string
+
+	// commandWrapper is the template the scripts listing and archiving
+	// the root filesystem, which root usually has to run, are wrapped in.
+	commandWrapper string
+}
+
+type deltaLayerCommandTemplate struct {
+	Command string
+}
+
+// deltaLayerFromState returns the layer the builder was configured to
+// export, or nil if there is none.
+func deltaLayerFromState(state multistep.StateBag) *deltaLayer {
+	raw, ok := state.GetOk("delta_layer_path")
+	if !ok {
+		return nil
+	}
+
+	d := &deltaLayer{path: raw.(string)}
+	if raw, ok := state.GetOk("delta_layer_exclude"); ok {
+		d.exclude = raw.([]string)
+	}
+	if raw, ok := state.GetOk("delta_layer_command_wrapper"); ok {
+		d.commandWrapper = raw.(string)
+	}
+	return d
+}
+
+// record lists the root filesystem before the machine is provisioned.
+func (d *deltaLayer) record(ui packer.Ui, comm packer.Communicator) error {
+	ui.Say("Recording the root filesystem for the delta layer...")
+	script := fmt.Sprintf("set -e\nmkdir -p %s\n%s > %s/base\n",
+		deltaLayerDir, d.listCommand(), deltaLayerDir)
+	if err := d.runScript(ui, comm, script); err != nil {
+		return fmt.Errorf("Error recording the root filesystem: %s", err)
+	}
+	return nil
+}
+
+// export archives the changes since the root filesystem was recorded, and
+// writes the layer.
+func (d *deltaLayer) export(ui packer.Ui, comm packer.Communicator) error {
+	ui.Say(fmt.Sprintf("Exporting the delta layer to %s...", d.path))
+	if err := d.runScript(ui, comm, d.exportScript()); err != nil {
+		return fmt.Errorf("Error archiving the changes of the root filesystem: %s", err)
+	}
+
+	changes, err := ioutil.TempFile("", "packer-delta")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(changes.Name())
+	defer changes.Close()
+
+	var deleted bytes.Buffer
+	if err := comm.Download(deltaLayerDir+"/changes.tar", changes); err != nil {
+		return fmt.Errorf("Error downloading the changes of the root filesystem: %s", err)
+	}
+	if err := comm.Download(deltaLayerDir+"/deleted", &deleted); err != nil {
+		return fmt.Errorf("Error downloading the deleted files of the root filesystem: %s", err)
+	}
+
+	var deletedPaths []string
+	scanner := bufio.NewScanner(&deleted)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			deletedPaths = append(deletedPaths, line)
+		}
+	}
+
+	if _, err := changes.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := writeDeltaLayer(d.path, changes, deletedPaths); err != nil {
+		return fmt.Errorf("Error writing the delta layer: %s", err)
+	}
+
+	// The listings must not end up in the image
+	if err := d.runScript(ui, comm, "rm -rf "+deltaLayerDir); err != nil {
+		return fmt.Errorf("Error removing %s: %s", deltaLayerDir, err)
+	}
+	return nil
+}
+
+// listCommand lists the files of the root filesystem, one per line with
+// their type and mode, owner, group, size, modification time and path.
+func (d *deltaLayer) listCommand() string {
+	prune := make([]string, 0, len(deltaLayerPrune)+len(d.exclude))
+	for _, p := range append(append([]string{}, deltaLayerPrune...), d.exclude...) {
+		prune = append(prune, "-path "+shellQuote(path.Clean(p)))
+	}
+
+	return fmt.Sprintf(
+		"find / -xdev \\( %s \\) -prune -o -exec stat -c '%%f %%u %%g %%s %%Y %%n' {} + | sort",
+		strings.Join(prune, " -o "))
+}
+
+// exportScript archives the files whose listing changed, and lists the
+// deleted ones.
+func (d *deltaLayer) exportScript() string {
+	return fmt.Sprintf(`set -e
+cd %[1]s
+%[2]s > after
+comm -13 base after | cut -d' ' -f6- | sed -e 's|^/||' -e '/^$/d' > changed
+cut -d' ' -f6- base | sort > base.paths
+cut -d' ' -f6- after | sort > after.paths
+comm -23 base.paths after.paths > deleted
+if [ -s changed ]; then
+  tar -C / --no-recursion -cf changes.tar -T changed
+else
+  : > changes.tar
+fi
+`, deltaLayerDir, d.listCommand())
+}
+
+// runScript runs the script on the machine, wrapped in the command wrapper.
+func (d *deltaLayer) runScript(ui packer.Ui, comm packer.Communicator, script string) error {
+	log.Printf("[DEBUG] Running delta layer script:\n%s", script)
+	command := "sh -c " + shellQuote(script)
+	if d.commandWrapper != "" {
+		ctx := &interpolate.Context{Data: &deltaLayerCommandTemplate{Command: command}}
+		wrapped, err := interpolate.Render(d.commandWrapper, ctx)
+		if err != nil {
+			return fmt.Errorf("Error wrapping the command: %s", err)
+		}
+		command = wrapped
+	}
+
+	cmd := &packer.RemoteCmd{Command: command}
+	if err := cmd.StartWithUi(comm, ui); err != nil {
+		return err
+	}
+	if cmd.ExitStatus != 0 {
+		return fmt.Errorf("script exited with non-zero exit status: %d", cmd.ExitStatus)
+	}
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
+
+// writeDeltaLayer writes the layer made of the entries of the changes tar
+// and of the whiteout entries of the deleted paths. The files under a
+// deleted directory only need the whiteout of the directory.
+func writeDeltaLayer(dst string, changes io.Reader, deleted []string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	tr := tar.NewReader(changes)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+
+	sort.Strings(deleted)
+	isDeleted := make(map[string]bool, len(deleted))
+	for _, p := range deleted {
+		isDeleted[path.Clean("/"+p)] = true
+	}
+
+WhiteoutLoop:
+	for _, p := range deleted {
+		p = path.Clean("/" + p)
+		for dir := path.Dir(p); dir != "/"; dir = path.Dir(dir) {
+			if isDeleted[dir] {
+				continue WhiteoutLoop
+			}
+		}
+
+		name := strings.TrimPrefix(path.Join(path.Dir(p), ".wh."+path.Base(p)), "/")
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// DeltaLayerArtifact is the delta layer a build exported, besides the
+// full image of its builder.
+type DeltaLayerArtifact struct {
+	// BuilderIdValue is the ID of the builder of the full image.
+	BuilderIdValue string
+
+	// Path is the path of the layer.
+	Path string
+}
+
+func (a *DeltaLayerArtifact) BuilderId() string {
+	return a.BuilderIdValue
+}
+
+func (a *DeltaLayerArtifact) Files() []string {
+	return []string{a.Path}
+}
+
+func (*DeltaLayerArtifact) Id() string {
+	return "DeltaLayer"
+}
+
+func (a *DeltaLayerArtifact) String() string {
+	return fmt.Sprintf("Delta layer: %s", a.Path)
+}
+
+func (*DeltaLayerArtifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *DeltaLayerArtifact) Destroy() error {
+	return os.Remove(a.Path)
+}
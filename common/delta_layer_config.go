@@ -0,0 +1,45 @@
+package common
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// DeltaLayerConfig configures the export of the changes the provisioners
+// made to the root filesystem of the machine, as a tar layer next to the
+// full image. Updates of machines running an image built from the same
+// source can then be shipped as the layer alone.
+type DeltaLayerConfig struct {
+	DeltaLayer               bool     `mapstructure:"delta_layer"`
+	DeltaLayerExclude        []string `mapstructure:"delta_layer_exclude"`
+	DeltaLayerCommandWrapper string   `mapstructure:"delta_layer_command_wrapper"`
+}
+
+func (c *DeltaLayerConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if !c.DeltaLayer {
+		if len(c.DeltaLayerExclude) > 0 {
+			errs = append(errs, fmt.Errorf("delta_layer_exclude requires delta_layer"))
+		}
+		if c.DeltaLayerCommandWrapper != "" {
+			errs = append(errs, fmt.Errorf("delta_layer_command_wrapper requires delta_layer"))
+		}
+		return errs
+	}
+
+	if c.DeltaLayerCommandWrapper == "" {
+		c.DeltaLayerCommandWrapper = "{{.Command}}"
+	}
+
+	for _, p := range c.DeltaLayerExclude {
+		if !path.IsAbs(p) {
+			errs = append(errs, fmt.Errorf(
+				"delta_layer_exclude paths must be absolute: %s", p))
+		}
+	}
+
+	return errs
+}
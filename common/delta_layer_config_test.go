@@ -0,0 +1,30 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestDeltaLayerConfigPrepare(t *testing.T) {
+	c := &DeltaLayerConfig{DeltaLayerExclude: []string{"/var/cache"}}
+	if errs := c.Prepare(nil); len(errs) == 0 {
+		t.Fatal("should error without delta_layer")
+	}
+
+	c = &DeltaLayerConfig{DeltaLayer: true, DeltaLayerExclude: []string{"/var/cache"}}
+	if errs := c.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.DeltaLayerCommandWrapper != "{{.Command}}" {
+		t.Fatalf("bad: %s", c.DeltaLayerCommandWrapper)
+	}
+
+	c = &DeltaLayerConfig{DeltaLayerCommandWrapper: "sudo {{.Command}}"}
+	if errs := c.Prepare(nil); len(errs) == 0 {
+		t.Fatal("should error without delta_layer")
+	}
+
+	c = &DeltaLayerConfig{DeltaLayer: true, DeltaLayerExclude: []string{"var/cache"}}
+	if errs := c.Prepare(nil); len(errs) == 0 {
+		t.Fatal("should error with a relative path")
+	}
+}
@@ -0,0 +1,163 @@
+package common
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// deltaLayerCommunicator downloads the given contents of the files.
+type deltaLayerCommunicator struct {
+	packer.MockCommunicator
+	files map[string]string
+}
+
+func (c *deltaLayerCommunicator) Download(path string, w io.Writer) error {
+	_, err := io.WriteString(w, c.files[path])
+	return err
+}
+
+func testDeltaLayerChanges(t *testing.T, names ...string) string {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		if strings.HasSuffix(name, "/") {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			continue
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(name)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err := io.WriteString(tw, name); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return buf.String()
+}
+
+func testDeltaLayerEntries(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		names = append(names, hdr.Name)
+	}
+}
+
+func TestWriteDeltaLayer(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	dst := filepath.Join(td, "layer.tar")
+
+	changes := testDeltaLayerChanges(t, "etc/", "etc/motd")
+	deleted := []string{"/opt/app/bin", "/etc/old.conf", "/opt/app"}
+	if err := writeDeltaLayer(dst, strings.NewReader(changes), deleted); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"etc/", "etc/motd", "etc/.wh.old.conf", "opt/.wh.app"}
+	if names := testDeltaLayerEntries(t, dst); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: %#v", names)
+	}
+}
+
+func TestWriteDeltaLayer_noChanges(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	dst := filepath.Join(td, "layer.tar")
+
+	if err := writeDeltaLayer(dst, strings.NewReader(""), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if names := testDeltaLayerEntries(t, dst); len(names) != 0 {
+		t.Fatalf("bad: %#v", names)
+	}
+}
+
+func TestDeltaLayer_listCommand(t *testing.T) {
+	d := &deltaLayer{exclude: []string{"/var/cache/", "/home/o'brien"}}
+	command := d.listCommand()
+	for _, part := range []string{"-path '/proc'", "-path '/var/cache'", `-path '/home/o'"'"'brien'`, "stat -c '%f %u %g %s %Y %n'"} {
+		if !strings.Contains(command, part) {
+			t.Fatalf("%q not in %s", part, command)
+		}
+	}
+}
+
+func TestStepProvision_deltaLayer(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	dst := filepath.Join(td, "layer.tar")
+
+	comm := &deltaLayerCommunicator{
+		files: map[string]string{
+			deltaLayerDir + "/changes.tar": testDeltaLayerChanges(t, "etc/motd"),
+			deltaLayerDir + "/deleted":     "/etc/old.conf\n",
+		},
+	}
+	hook := &packer.MockHook{
+		RunFunc: func() error {
+			if !strings.Contains(comm.StartCmd.Command, "> "+deltaLayerDir+"/base") {
+				t.Errorf("root filesystem should be recorded: %s", comm.StartCmd.Command)
+			}
+			return nil
+		},
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("communicator", comm)
+	state.Put("hook", hook)
+	state.Put("ui", &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)})
+	state.Put("delta_layer_path", dst)
+	state.Put("delta_layer_command_wrapper", "sudo {{.Command}}")
+
+	step := new(StepProvision)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+
+	if comm.StartCmd.Command != "sudo sh -c 'rm -rf "+deltaLayerDir+"'" {
+		t.Fatalf("listings should be removed: %s", comm.StartCmd.Command)
+	}
+
+	expected := []string{"etc/motd", "etc/.wh.old.conf"}
+	if names := testDeltaLayerEntries(t, dst); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: %#v", names)
+	}
+}
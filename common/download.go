@@ -16,6 +16,9 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // imports related to each Downloader implementation
@@ -54,6 +57,10 @@ type DownloadConfig struct {
 	// What to use for the user agent for HTTP requests. If set to "", use the
 	// default user agent provided by Go.
 	UserAgent string
+
+	// The number of parts of the file to download in parallel, from servers
+	// supporting range requests. If less than 2, it's downloaded in one part.
+	Chunks int
 }
 
 // A DownloadClient helps download, verify checksums, etc.
@@ -88,8 +95,8 @@ func NewDownloadClient(c *DownloadConfig) *DownloadClient {
 	if c.DownloaderMap == nil {
 		c.DownloaderMap = map[string]Downloader{
 			"file":  &FileDownloader{bufferSize: nil},
-			"http":  &HTTPDownloader{userAgent: c.UserAgent},
-			"https": &HTTPDownloader{userAgent: c.UserAgent},
+			"http":  &HTTPDownloader{userAgent: c.UserAgent, chunks: c.Chunks},
+			"https": &HTTPDownloader{userAgent: c.UserAgent, chunks: c.Chunks},
 			"smb":   &SMBDownloader{bufferSize: nil},
 		}
 	}
@@ -232,12 +239,19 @@ func (d *DownloadClient) VerifyChecksum(path string) (bool, error) {
 	return bytes.Equal(d.config.Hash.Sum(nil), d.config.Checksum), nil
 }
 
+// httpDownloadRetries is how many times an HTTP download interrupted by a
+// network error is resumed, after httpRetryDelay.
+const httpDownloadRetries = 5
+
+var httpRetryDelay = 2 * time.Second
+
 // HTTPDownloader is an implementation of Downloader that downloads
 // files over HTTP.
 type HTTPDownloader struct {
 	current   uint64
 	total     uint64
 	userAgent string
+	chunks    int
 }
 
 func (d *HTTPDownloader) Cancel() {
@@ -251,25 +265,8 @@ func (d *HTTPDownloader) Resume() {
 func (d *HTTPDownloader) Download(dst *os.File, src *url.URL) error {
 	log.Printf("Starting download over HTTP: %s", src.String())
 
-	// Seek to the beginning by default
-	if _, err := dst.Seek(0, 0); err != nil {
-		return err
-	}
-
 	// Reset our progress
-	d.current = 0
-
-	// Make the request. We first make a HEAD request so we can check
-	// if the server supports range queries. If the server/URL doesn't
-	// support HEAD requests, we just fall back to GET.
-	req, err := http.NewRequest("HEAD", src.String(), nil)
-	if err != nil {
-		return err
-	}
-
-	if d.userAgent != "" {
-		req.Header.Set("User-Agent", d.userAgent)
-	}
+	atomic.StoreUint64(&d.current, 0)
 
 	httpClient := &http.Client{
 		Transport: &http.Transport{
@@ -277,63 +274,219 @@ func (d *HTTPDownloader) Download(dst *os.File, src *url.URL) error {
 		},
 	}
 
-	resp, err := httpClient.Do(req)
+	// We first make a HEAD request so we can check if the server supports
+	// range queries, and the size of the file. If the server/URL doesn't
+	// support HEAD requests, we just fall back to a GET of the whole file.
+	ranges := false
+	size := int64(-1)
+	resp, err := d.do(httpClient, "HEAD", src, "")
 	if err != nil {
 		log.Printf("[DEBUG] (download) Error making HTTP HEAD request: %s", err.Error())
 	} else {
+		resp.Body.Close()
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			// If the HEAD request succeeded, then attempt to set the range
-			// query if we can.
-			if resp.Header.Get("Accept-Ranges") == "bytes" {
-				if fi, err := dst.Stat(); err == nil {
-					if _, err = dst.Seek(0, os.SEEK_END); err == nil {
-						req.Header.Set("Range", fmt.Sprintf("bytes=%d-", fi.Size()))
-
-						d.current = uint64(fi.Size())
-					}
-				}
-			}
+			ranges = resp.Header.Get("Accept-Ranges") == "bytes"
+			size = resp.ContentLength
 		} else {
 			log.Printf("[DEBUG] (download) Unexpected HTTP response during HEAD request: %s", resp.Status)
 		}
 	}
 
-	// Set the request to GET now, and redo the query to download
-	req.Method = "GET"
+	// Resume what a previous download left in the file, unless it's
+	// already as big as the whole file, which then didn't match.
+	var offset int64
+	if ranges {
+		if fi, err := dst.Stat(); err == nil {
+			offset = fi.Size()
+		}
+		if size > 0 && offset >= size {
+			offset = 0
+		}
+	}
 
-	resp, err = httpClient.Do(req)
-	if err != nil {
-		return err
-	} else {
-		if resp.StatusCode >= 400 && resp.StatusCode < 600 {
-			return fmt.Errorf("Error making HTTP GET request: %s", resp.Status)
+	if d.chunks > 1 && ranges && size > 0 && offset == 0 {
+		return d.downloadChunks(httpClient, dst, src, size)
+	}
+
+	for retry := 0; ; retry++ {
+		var retryable bool
+		offset, retryable, err = d.downloadFrom(httpClient, dst, src, offset)
+		if err == nil || !retryable || retry == httpDownloadRetries {
+			return err
 		}
+		if !ranges {
+			offset = 0
+		}
+		log.Printf("[DEBUG] (download) Resuming download at byte %d after error: %s", offset, err)
+		time.Sleep(httpRetryDelay)
+	}
+}
+
+// downloadFrom downloads the file from offset to the end. It returns the
+// offset reached, whether the error is worth resuming the download, and
+// the error.
+func (d *HTTPDownloader) downloadFrom(client *http.Client, dst *os.File, src *url.URL, offset int64) (int64, bool, error) {
+	var byteRange string
+	if offset > 0 {
+		byteRange = fmt.Sprintf("bytes=%d-", offset)
+	}
+
+	resp, err := d.do(client, "GET", src, byteRange)
+	if err != nil {
+		return offset, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 600 {
+		return offset, resp.StatusCode >= 500, fmt.Errorf("Error making HTTP GET request: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		// The server sent the whole file
+		offset = 0
+	}
+
+	// Drop anything after the offset, left by a previous download
+	if err := dst.Truncate(offset); err != nil {
+		return offset, false, err
+	}
+	if _, err := dst.Seek(offset, io.SeekStart); err != nil {
+		return offset, false, err
 	}
 
-	d.total = d.current + uint64(resp.ContentLength)
+	atomic.StoreUint64(&d.current, uint64(offset))
+	if resp.ContentLength >= 0 {
+		d.total = uint64(offset + resp.ContentLength)
+	}
 
 	var buffer [4096]byte
 	for {
 		n, err := resp.Body.Read(buffer[:])
-		if err != nil && err != io.EOF {
-			return err
+		if _, werr := dst.Write(buffer[:n]); werr != nil {
+			return offset, false, werr
 		}
+		offset += int64(n)
+		atomic.AddUint64(&d.current, uint64(n))
 
-		d.current += uint64(n)
+		if err == io.EOF {
+			return offset, false, nil
+		}
+		if err != nil {
+			return offset, true, err
+		}
+	}
+}
 
-		if _, werr := dst.Write(buffer[:n]); werr != nil {
-			return werr
+// downloadChunks downloads the file of the size in d.chunks parts in
+// parallel. If one fails, the file is truncated after the part downloaded
+// from its start, so a later download can resume from there.
+func (d *HTTPDownloader) downloadChunks(client *http.Client, dst *os.File, src *url.URL, size int64) error {
+	if err := dst.Truncate(size); err != nil {
+		return err
+	}
+	d.total = uint64(size)
+
+	chunkSize := (size + int64(d.chunks) - 1) / int64(d.chunks)
+	written := make([]int64, d.chunks)
+	errs := make([]error, d.chunks)
+
+	var wg sync.WaitGroup
+	for i := range written {
+		start := int64(i) * chunkSize
+		end := start + chunkSize
+		if end > size {
+			end = size
+		}
+		if start >= end {
+			continue
 		}
 
-		if err == io.EOF {
-			break
+		wg.Add(1)
+		go func(i int, start, end int64) {
+			defer wg.Done()
+			for retry := 0; ; retry++ {
+				var retryable bool
+				retryable, errs[i] = d.downloadRange(client, dst, src, start+written[i], end, &written[i])
+				if errs[i] == nil || !retryable || retry == httpDownloadRetries {
+					return
+				}
+				log.Printf("[DEBUG] (download) Resuming download at byte %d after error: %s", start+written[i], errs[i])
+				time.Sleep(httpRetryDelay)
+			}
+		}(i, start, end)
+	}
+	wg.Wait()
+
+	var prefix int64
+	for i, err := range errs {
+		if err != nil {
+			if terr := dst.Truncate(prefix + written[i]); terr != nil {
+				log.Printf("[DEBUG] (download) Error truncating download: %s", terr)
+			}
+			return err
 		}
+		prefix += written[i]
 	}
 	return nil
 }
 
+// downloadRange downloads the bytes of the file from start to end, adding
+// the number downloaded to written. It returns whether the error is worth
+// resuming the download, and the error.
+func (d *HTTPDownloader) downloadRange(client *http.Client, dst *os.File, src *url.URL, start, end int64, written *int64) (bool, error) {
+	resp, err := d.do(client, "GET", src, fmt.Sprintf("bytes=%d-%d", start, end-1))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 600 {
+		return resp.StatusCode >= 500, fmt.Errorf("Error making HTTP GET request: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return false, fmt.Errorf("Unexpected HTTP response to a range request: %s", resp.Status)
+	}
+
+	var buffer [4096]byte
+	for start < end {
+		n, err := resp.Body.Read(buffer[:])
+		if int64(n) > end-start {
+			n = int(end - start)
+		}
+		if _, werr := dst.WriteAt(buffer[:n], start); werr != nil {
+			return false, werr
+		}
+		start += int64(n)
+		*written += int64(n)
+		atomic.AddUint64(&d.current, uint64(n))
+
+		if err == io.EOF && start < end {
+			return true, io.ErrUnexpectedEOF
+		}
+		if err != nil && err != io.EOF {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// do makes an HTTP request for the file, of the byte range if it isn't
+// empty.
+func (d *HTTPDownloader) do(client *http.Client, method string, src *url.URL, byteRange string) (*http.Response, error) {
+	req, err := http.NewRequest(method, src.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	return client.Do(req)
+}
+
 func (d *HTTPDownloader) Progress() uint64 {
-	return d.current
+	return atomic.LoadUint64(&d.current)
 }
 
 func (d *HTTPDownloader) Total() uint64 {
@@ -9,9 +9,12 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestDownloadClientVerifyChecksum(t *testing.T) {
@@ -228,6 +231,180 @@ func TestDownloadClient_resume(t *testing.T) {
 	}
 }
 
+func TestDownloadClient_resumeAfterError(t *testing.T) {
+	defer func(delay time.Duration) { httpRetryDelay = delay }(httpRetryDelay)
+	httpRetryDelay = 0
+
+	tf, _ := ioutil.TempFile("", "packer")
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	content := strings.Repeat("packer", 1000)
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+		if r.Method == "GET" && len(ranges) == 1 {
+			// Drop the connection halfway through
+			rw.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
+			rw.Write([]byte(content[:len(content)/2]))
+			return
+		}
+		http.ServeContent(rw, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+
+	client := NewDownloadClient(&DownloadConfig{
+		Url:        ts.URL,
+		TargetPath: tf.Name(),
+		CopyFile:   true,
+	})
+
+	path, err := client.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(raw) != content {
+		t.Fatalf("bad: %d bytes", len(raw))
+	}
+	if len(ranges) != 2 || ranges[1] != fmt.Sprintf("bytes=%d-", len(content)/2) {
+		t.Fatalf("bad: %#v", ranges)
+	}
+}
+
+func TestDownloadClient_resumeIgnored(t *testing.T) {
+	tf, _ := ioutil.TempFile("", "packer")
+	tf.Write([]byte("stale content"))
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			rw.Header().Set("Accept-Ranges", "bytes")
+			rw.WriteHeader(204)
+			return
+		}
+
+		// The range is ignored and the whole file is sent
+		rw.Write([]byte("hello\n"))
+	}))
+	defer ts.Close()
+
+	client := NewDownloadClient(&DownloadConfig{
+		Url:        ts.URL,
+		TargetPath: tf.Name(),
+		CopyFile:   true,
+	})
+
+	path, err := client.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(raw) != "hello\n" {
+		t.Fatalf("bad: %s", string(raw))
+	}
+}
+
+func TestDownloadClient_chunks(t *testing.T) {
+	tf, _ := ioutil.TempFile("", "packer")
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	content := strings.Repeat("0123456789", 1000)
+	var l sync.Mutex
+	ranges := make(map[string]bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			l.Lock()
+			ranges[r.Header.Get("Range")] = true
+			l.Unlock()
+		}
+		http.ServeContent(rw, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+
+	client := NewDownloadClient(&DownloadConfig{
+		Url:        ts.URL,
+		TargetPath: tf.Name(),
+		CopyFile:   true,
+		Chunks:     3,
+	})
+
+	path, err := client.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(raw) != content {
+		t.Fatalf("bad: %d bytes", len(raw))
+	}
+	expected := map[string]bool{
+		"bytes=0-3333":    true,
+		"bytes=3334-6667": true,
+		"bytes=6668-9999": true,
+	}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Fatalf("bad: %#v", ranges)
+	}
+}
+
+func TestDownloadClient_chunksError(t *testing.T) {
+	defer func(delay time.Duration) { httpRetryDelay = delay }(httpRetryDelay)
+	httpRetryDelay = 0
+
+	tf, _ := ioutil.TempFile("", "packer")
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	content := strings.Repeat("0123456789", 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Range"), "bytes=5000-") {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.ServeContent(rw, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+
+	client := NewDownloadClient(&DownloadConfig{
+		Url:        ts.URL,
+		TargetPath: tf.Name(),
+		CopyFile:   true,
+		Chunks:     2,
+	})
+
+	if _, err := client.Get(); err == nil {
+		t.Fatal("should error")
+	}
+
+	// What was downloaded from the start is kept to be resumed
+	raw, err := ioutil.ReadFile(tf.Name())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(raw) != content[:5000] {
+		t.Fatalf("bad: %d bytes", len(raw))
+	}
+}
+
 func TestDownloadClient_usesDefaultUserAgent(t *testing.T) {
 	tf, err := ioutil.TempFile("", "packer")
 	if err != nil {
@@ -0,0 +1,60 @@
+package guestip
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ARPStrategy finds the address of the MAC address of the guest in the ARP
+// cache of the host. The guest is only in the cache once the host has
+// exchanged packets with it, so this is a fallback for bridged networks
+// with a DHCP server the host can't see the leases of.
+type ARPStrategy struct {
+	MAC net.HardwareAddr
+}
+
+func (s *ARPStrategy) GuestIP() (string, error) {
+	var table []byte
+	var err error
+	switch runtime.GOOS {
+	case "linux":
+		table, err = ioutil.ReadFile("/proc/net/arp")
+	case "windows":
+		table, err = exec.Command("arp", "-a").Output()
+	default:
+		table, err = exec.Command("arp", "-a", "-n").Output()
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error reading the ARP cache: %s", err)
+	}
+
+	if ip := arpIP(string(table), s.MAC); ip != "" {
+		return ip, nil
+	}
+	return "", fmt.Errorf("MAC %s isn't in the ARP cache", s.MAC)
+}
+
+// arpIP returns the address of the MAC address in an ARP table, in the
+// formats of /proc/net/arp, of arp -an on macOS or BSD and of arp -a on
+// Windows. The incomplete entries of /proc/net/arp have a zero MAC.
+func arpIP(table string, mac net.HardwareAddr) string {
+	for _, line := range strings.Split(table, "\n") {
+		var ip string
+		found := false
+		for _, field := range strings.Fields(line) {
+			if parsed := net.ParseIP(strings.Trim(field, "()")); parsed != nil && parsed.To4() != nil {
+				ip = parsed.String()
+			} else if sameMAC(field, mac) {
+				found = true
+			}
+		}
+		if found && ip != "" {
+			return ip
+		}
+	}
+	return ""
+}
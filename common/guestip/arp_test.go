@@ -0,0 +1,36 @@
+package guestip
+
+import (
+	"net"
+	"testing"
+)
+
+func TestArpIP(t *testing.T) {
+	mac, _ := net.ParseMAC("52:54:00:0a:0b:0c")
+
+	cases := map[string]string{
+		"linux": `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        br0
+192.168.1.23     0x1         0x2         52:54:00:0a:0b:0c     *        br0
+`,
+		"darwin": `? (192.168.1.1) at 0:11:22:33:44:55 on en0 ifscope [ethernet]
+? (192.168.1.23) at 52:54:0:a:b:c on en0 ifscope [ethernet]
+`,
+		"windows": `
+Interface: 192.168.1.10 --- 0x4
+  Internet Address      Physical Address      Type
+  192.168.1.1           00-11-22-33-44-55     dynamic
+  192.168.1.23          52-54-00-0a-0b-0c     dynamic
+`,
+	}
+	for name, table := range cases {
+		if ip := arpIP(table, mac); ip != "192.168.1.23" {
+			t.Fatalf("%s: bad: %s", name, ip)
+		}
+	}
+
+	other, _ := net.ParseMAC("52:54:00:ff:ff:ff")
+	if ip := arpIP(cases["linux"], other); ip != "" {
+		t.Fatalf("bad: %s", ip)
+	}
+}
@@ -0,0 +1,121 @@
+package guestip
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DHCPLeasesStrategy finds the address leased to the MAC address of the
+// guest in the leases files of DHCP servers. It reads the files of ISC
+// dhcpd, used by VMware, and of the bootpd of macOS.
+type DHCPLeasesStrategy struct {
+	Paths []string
+	MAC   net.HardwareAddr
+}
+
+func (s *DHCPLeasesStrategy) GuestIP() (string, error) {
+	for _, path := range s.Paths {
+		log.Printf("Trying DHCP leases path: %s", path)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		leases := string(data)
+		ip := iscLeasesIP(leases, s.MAC)
+		if ip == "" {
+			ip = bootpdLeasesIP(leases, s.MAC)
+		}
+		if ip != "" {
+			return ip, nil
+		}
+	}
+	return "", fmt.Errorf("none of %v has a DHCP lease for MAC %s", s.Paths, s.MAC)
+}
+
+var (
+	iscIPLineRe      = regexp.MustCompile(`^lease (.+?) {$`)
+	iscEndTimeLineRe = regexp.MustCompile(`^\s*ends \d (.+?);$`)
+	iscMACLineRe     = regexp.MustCompile(`^\s*hardware ethernet (.+?);$`)
+)
+
+// iscLeasesIP returns the address of the lease of the MAC address that
+// ends the latest.
+func iscLeasesIP(leases string, mac net.HardwareAddr) string {
+	var lastIp string
+	var lastLeaseEnd time.Time
+
+	var curIp string
+	var curLeaseEnd time.Time
+
+	for _, line := range strings.Split(leases, "\n") {
+		// Need to trim off CR character when running in windows
+		line = strings.TrimRight(line, "\r")
+
+		matches := iscIPLineRe.FindStringSubmatch(line)
+		if matches != nil {
+			lastIp = matches[1]
+			continue
+		}
+
+		matches = iscEndTimeLineRe.FindStringSubmatch(line)
+		if matches != nil {
+			lastLeaseEnd, _ = time.Parse("2006/01/02 15:04:05", matches[1])
+			continue
+		}
+
+		// If the mac address matches and this lease ends farther in the
+		// future than the last match we might have, then choose it.
+		matches = iscMACLineRe.FindStringSubmatch(line)
+		if matches != nil && sameMAC(matches[1], mac) && curLeaseEnd.Before(lastLeaseEnd) {
+			curIp = lastIp
+			curLeaseEnd = lastLeaseEnd
+		}
+	}
+	return curIp
+}
+
+// bootpdLeasesIP returns the address of the most recent lease of the MAC
+// address, in leases such as:
+//
+//	{
+//		name=vm
+//		ip_address=192.168.64.2
+//		hw_address=1,52:54:0:12:34:56
+//		lease=0x5c8f8f0b
+//	}
+func bootpdLeasesIP(leases string, mac net.HardwareAddr) string {
+	var ip string
+	var latest int64 = -1
+
+	var curIp, curMAC string
+	var curLease int64
+	for _, line := range strings.Split(leases, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "{":
+			curIp, curMAC, curLease = "", "", 0
+		case strings.HasPrefix(line, "ip_address="):
+			curIp = strings.TrimPrefix(line, "ip_address=")
+		case strings.HasPrefix(line, "hw_address="):
+			// The hardware type comes first
+			curMAC = strings.TrimPrefix(line, "hw_address=")
+			if i := strings.Index(curMAC, ","); i > -1 {
+				curMAC = curMAC[i+1:]
+			}
+		case strings.HasPrefix(line, "lease="):
+			curLease, _ = strconv.ParseInt(strings.TrimPrefix(line, "lease="), 0, 64)
+		case line == "}":
+			if sameMAC(curMAC, mac) && curLease > latest {
+				ip, latest = curIp, curLease
+			}
+		}
+	}
+	return ip
+}
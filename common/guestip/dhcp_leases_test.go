@@ -0,0 +1,43 @@
+package guestip
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestDHCPLeasesStrategy(t *testing.T) {
+	mac, _ := net.ParseMAC("00:0c:29:ab:cd:ef")
+
+	cases := map[string]string{
+		"dhcpd.leases": "192.168.122.131",
+		"dhcpd_leases": "192.168.64.3",
+	}
+	for file, expected := range cases {
+		s := &DHCPLeasesStrategy{
+			Paths: []string{filepath.Join("test-fixtures", file)},
+			MAC:   mac,
+		}
+		ip, err := s.GuestIP()
+		if err != nil {
+			t.Fatalf("%s: %s", file, err)
+		}
+		if ip != expected {
+			t.Fatalf("%s: bad: %s", file, ip)
+		}
+	}
+}
+
+func TestDHCPLeasesStrategy_noLease(t *testing.T) {
+	mac, _ := net.ParseMAC("00:0c:29:00:00:01")
+	s := &DHCPLeasesStrategy{
+		Paths: []string{
+			filepath.Join("test-fixtures", "dhcpd.leases"),
+			filepath.Join("test-fixtures", "dhcpd_leases"),
+		},
+		MAC: mac,
+	}
+	if _, err := s.GuestIP(); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,121 @@
+// Package guestip finds the IP address of the guest of a local VM builder.
+// Each way of finding it is a strategy, and the strategies are tried one
+// after the other until one of them finds the address, as each of them
+// only works with some hosts, networks and guests.
+package guestip
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// The names of the strategies in guest_ip_strategies.
+const (
+	ARP             = "arp"
+	DHCPLeases      = "dhcp_leases"
+	GuestProperties = "guest_properties"
+	QemuGuestAgent  = "qemu_guest_agent"
+	VMwareTools     = "vmware_tools"
+)
+
+// A Strategy finds the IP address of the guest, or returns an empty
+// address if it hasn't found it yet.
+type Strategy interface {
+	GuestIP() (string, error)
+}
+
+// StrategyFunc is a function finding the IP address of the guest.
+type StrategyFunc func() (string, error)
+
+func (f StrategyFunc) GuestIP() (string, error) {
+	return f()
+}
+
+// Discover returns the address found by the first of the strategies, in
+// the order of the names, that finds one. The names without a strategy
+// are skipped.
+func Discover(names []string, strategies map[string]Strategy) (string, error) {
+	var errs []string
+	for _, name := range names {
+		strategy, ok := strategies[name]
+		if !ok {
+			continue
+		}
+
+		ip, err := strategy.GuestIP()
+		if err == nil && ip == "" {
+			err = errors.New("no address yet")
+		}
+		if err != nil {
+			log.Printf("Guest IP not found with %s: %s", name, err)
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+
+		log.Printf("Guest IP found with %s: %s", name, ip)
+		return ip, nil
+	}
+
+	if len(errs) == 0 {
+		return "", errors.New("no strategy to find the IP address of the guest")
+	}
+	return "", fmt.Errorf("couldn't find the IP address of the guest: %s", strings.Join(errs, "; "))
+}
+
+// Config is the order of the strategies tried to find the IP address of
+// the guest.
+type Config struct {
+	GuestIPStrategies []string `mapstructure:"guest_ip_strategies"`
+}
+
+// Prepare checks the strategies are among the ones the builder supports.
+func (c *Config) Prepare(supported ...string) []error {
+	var errs []error
+
+	seen := make(map[string]bool)
+	for _, name := range c.GuestIPStrategies {
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("guest_ip_strategies: %s is listed twice", name))
+		}
+		seen[name] = true
+
+		valid := false
+		for _, s := range supported {
+			valid = valid || s == name
+		}
+		if !valid {
+			errs = append(errs, fmt.Errorf(
+				"guest_ip_strategies: %s is not one of %s", name, strings.Join(supported, ", ")))
+		}
+	}
+
+	return errs
+}
+
+// parseMAC parses a MAC address the way the different tools print them,
+// with dashes or colons, and with the leading zeros of the bytes dropped
+// as on macOS.
+func parseMAC(s string) (net.HardwareAddr, error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == '-' })
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("invalid MAC address: %s", s)
+	}
+	mac := make(net.HardwareAddr, len(parts))
+	for i, part := range parts {
+		b, err := strconv.ParseUint(part, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAC address: %s", s)
+		}
+		mac[i] = byte(b)
+	}
+	return mac, nil
+}
+
+func sameMAC(s string, mac net.HardwareAddr) bool {
+	parsed, err := parseMAC(s)
+	return err == nil && parsed.String() == mac.String()
+}
@@ -0,0 +1,85 @@
+package guestip
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	var tried []string
+	strategy := func(name, ip string, err error) Strategy {
+		return StrategyFunc(func() (string, error) {
+			tried = append(tried, name)
+			return ip, err
+		})
+	}
+	strategies := map[string]Strategy{
+		"a": strategy("a", "", errors.New("failed")),
+		"b": strategy("b", "", nil),
+		"c": strategy("c", "10.0.0.5", nil),
+		"d": strategy("d", "10.0.0.6", nil),
+	}
+
+	ip, err := Discover([]string{"missing", "a", "b", "c", "d"}, strategies)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ip != "10.0.0.5" {
+		t.Fatalf("bad: %s", ip)
+	}
+	if len(tried) != 3 || tried[0] != "a" || tried[2] != "c" {
+		t.Fatalf("bad: %#v", tried)
+	}
+
+	if _, err := Discover([]string{"a", "b"}, strategies); err == nil {
+		t.Fatal("should have error")
+	}
+	if _, err := Discover(nil, strategies); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestConfigPrepare(t *testing.T) {
+	c := &Config{}
+	if errs := c.Prepare(ARP, DHCPLeases); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	c = &Config{GuestIPStrategies: []string{DHCPLeases, ARP}}
+	if errs := c.Prepare(ARP, DHCPLeases); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	c = &Config{GuestIPStrategies: []string{QemuGuestAgent}}
+	if errs := c.Prepare(ARP, DHCPLeases); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	c = &Config{GuestIPStrategies: []string{ARP, ARP}}
+	if errs := c.Prepare(ARP, DHCPLeases); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestParseMAC(t *testing.T) {
+	cases := map[string]string{
+		"00:0c:29:ab:cd:ef": "00:0c:29:ab:cd:ef",
+		"00-0C-29-AB-CD-EF": "00:0c:29:ab:cd:ef",
+		"0:c:29:ab:cd:ef":   "00:0c:29:ab:cd:ef",
+	}
+	for input, expected := range cases {
+		mac, err := parseMAC(input)
+		if err != nil {
+			t.Fatalf("%s: %s", input, err)
+		}
+		if mac.String() != expected {
+			t.Fatalf("%s: %s", input, mac)
+		}
+	}
+
+	for _, input := range []string{"", "00:0c:29:ab:cd", "00:0c:29:ab:cd:xx", "incomplete"} {
+		if _, err := parseMAC(input); err == nil {
+			t.Fatalf("%s: should have error", input)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package guestip
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// QemuGuestAgentStrategy asks the qemu-guest-agent running in the guest for
+// the addresses of its interfaces, through the unix socket the virtio
+// serial port of the agent is attached to. It returns the first IPv4
+// address of the interface with the MAC address, or of any interface but
+// the loopback one if the MAC address is nil.
+type QemuGuestAgentStrategy struct {
+	Socket string
+	MAC    net.HardwareAddr
+
+	// Timeout of a request to the agent, 5 seconds if zero.
+	Timeout time.Duration
+}
+
+type qgaRequest struct {
+	Execute   string      `json:"execute"`
+	Arguments interface{} `json:"arguments,omitempty"`
+}
+
+type qgaInterface struct {
+	Name            string `json:"name"`
+	HardwareAddress string `json:"hardware-address"`
+	IPAddresses     []struct {
+		Type    string `json:"ip-address-type"`
+		Address string `json:"ip-address"`
+	} `json:"ip-addresses"`
+}
+
+func (s *QemuGuestAgentStrategy) GuestIP() (string, error) {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	conn, err := net.DialTimeout("unix", s.Socket, timeout)
+	if err != nil {
+		return "", fmt.Errorf("Error connecting to the guest agent: %s", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	// The agent may have answers to the requests of an earlier connection
+	// queued, skip them until the answer to the sync with our id.
+	id := time.Now().UnixNano() & 0x7fffffff
+	if err := enc.Encode(qgaRequest{"guest-sync", map[string]int64{"id": id}}); err != nil {
+		return "", fmt.Errorf("Error syncing with the guest agent: %s", err)
+	}
+	for {
+		var resp struct {
+			Return *int64 `json:"return"`
+		}
+		if err := dec.Decode(&resp); err != nil {
+			return "", fmt.Errorf("Error syncing with the guest agent: %s", err)
+		}
+		if resp.Return != nil && *resp.Return == id {
+			break
+		}
+	}
+
+	if err := enc.Encode(qgaRequest{Execute: "guest-network-get-interfaces"}); err != nil {
+		return "", fmt.Errorf("Error asking the guest agent for the interfaces: %s", err)
+	}
+	var resp struct {
+		Return []qgaInterface `json:"return"`
+		Error  *struct {
+			Desc string `json:"desc"`
+		} `json:"error"`
+	}
+	if err := dec.Decode(&resp); err != nil {
+		return "", fmt.Errorf("Error reading the interfaces from the guest agent: %s", err)
+	}
+	if resp.Error != nil {
+		return "", fmt.Errorf("Error from the guest agent: %s", resp.Error.Desc)
+	}
+
+	return qgaInterfacesIP(resp.Return, s.MAC), nil
+}
+
+func qgaInterfacesIP(ifaces []qgaInterface, mac net.HardwareAddr) string {
+	for _, iface := range ifaces {
+		if mac != nil && !sameMAC(iface.HardwareAddress, mac) {
+			continue
+		}
+		for _, addr := range iface.IPAddresses {
+			if addr.Type != "ipv4" {
+				continue
+			}
+			ip := net.ParseIP(addr.Address)
+			if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || strings.HasPrefix(iface.Name, "lo") {
+				continue
+			}
+			return ip.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package guestip
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+const testInterfaces = `[
+	{"name": "lo", "hardware-address": "00:00:00:00:00:00",
+	 "ip-addresses": [{"ip-address-type": "ipv4", "ip-address": "127.0.0.1", "prefix": 8}]},
+	{"name": "eth0", "hardware-address": "52:54:00:0a:0b:0c",
+	 "ip-addresses": [
+		{"ip-address-type": "ipv6", "ip-address": "fe80::5054:ff:fe0a:b0c", "prefix": 64},
+		{"ip-address-type": "ipv4", "ip-address": "192.168.122.15", "prefix": 24}]}
+]`
+
+// testAgent answers the requests the way qemu-guest-agent does, after a
+// stale answer of an earlier connection.
+func testAgent(t *testing.T, l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req struct {
+			Execute   string `json:"execute"`
+			Arguments struct {
+				ID int64 `json:"id"`
+			} `json:"arguments"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			t.Errorf("bad request: %s", scanner.Text())
+			return
+		}
+
+		switch req.Execute {
+		case "guest-sync":
+			conn.Write([]byte(`{"return": 1}` + "\n"))
+			resp, _ := json.Marshal(map[string]int64{"return": req.Arguments.ID})
+			conn.Write(append(resp, '\n'))
+		case "guest-network-get-interfaces":
+			conn.Write([]byte(`{"return": ` + testInterfaces + "}\n"))
+		}
+	}
+}
+
+func TestQemuGuestAgentStrategy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	socket := filepath.Join(td, "qga.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer l.Close()
+	go testAgent(t, l)
+
+	s := &QemuGuestAgentStrategy{Socket: socket}
+	ip, err := s.GuestIP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ip != "192.168.122.15" {
+		t.Fatalf("bad: %s", ip)
+	}
+}
+
+func TestQemuGuestAgentStrategy_noAgent(t *testing.T) {
+	s := &QemuGuestAgentStrategy{Socket: filepath.Join(os.TempDir(), "packer-missing-qga.sock")}
+	if _, err := s.GuestIP(); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestQgaInterfacesIP(t *testing.T) {
+	var ifaces []qgaInterface
+	if err := json.Unmarshal([]byte(testInterfaces), &ifaces); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	mac, _ := net.ParseMAC("52:54:00:0a:0b:0c")
+	if ip := qgaInterfacesIP(ifaces, mac); ip != "192.168.122.15" {
+		t.Fatalf("bad: %s", ip)
+	}
+
+	other, _ := net.ParseMAC("52:54:00:ff:ff:ff")
+	if ip := qgaInterfacesIP(ifaces, other); ip != "" {
+		t.Fatalf("bad: %s", ip)
+	}
+}
@@ -2,6 +2,8 @@ package common
 
 import (
 	"errors"
+	"fmt"
+	"path"
 
 	"github.com/hashicorp/packer/template/interpolate"
 )
@@ -11,6 +13,19 @@ type HTTPConfig struct {
 	HTTPDir     string `mapstructure:"http_directory"`
 	HTTPPortMin uint   `mapstructure:"http_port_min"`
 	HTTPPortMax uint   `mapstructure:"http_port_max"`
+
+	// Serve over HTTPS, with the certificate and key in the files, or a
+	// self-signed certificate generated for the build if they're empty.
+	HTTPTLS         bool   `mapstructure:"http_tls"`
+	HTTPTLSCertFile string `mapstructure:"http_tls_cert_file"`
+	HTTPTLSKeyFile  string `mapstructure:"http_tls_key_file"`
+
+	// The credentials of the basic authentication required by the server.
+	HTTPUsername string `mapstructure:"http_username"`
+	HTTPPassword string `mapstructure:"http_password"`
+
+	// The patterns of the paths of the files rendered as templates.
+	HTTPTemplates []string `mapstructure:"http_templates"`
 }
 
 func (c *HTTPConfig) Prepare(ctx *interpolate.Context) []error {
@@ -30,5 +45,24 @@ func (c *HTTPConfig) Prepare(ctx *interpolate.Context) []error {
 			errors.New("http_port_min must be less than http_port_max"))
 	}
 
+	if (c.HTTPTLSCertFile == "") != (c.HTTPTLSKeyFile == "") {
+		errs = append(errs,
+			errors.New("http_tls_cert_file and http_tls_key_file must be specified together"))
+	} else if c.HTTPTLSCertFile != "" {
+		c.HTTPTLS = true
+	}
+
+	if (c.HTTPUsername == "") != (c.HTTPPassword == "") {
+		errs = append(errs,
+			errors.New("http_username and http_password must be specified together"))
+	}
+
+	for _, pattern := range c.HTTPTemplates {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs,
+				fmt.Errorf("Bad http_templates pattern %q: %s", pattern, err))
+		}
+	}
+
 	return errs
 }
@@ -43,3 +43,68 @@ func TestHTTPConfigPrepare_Bounds(t *testing.T) {
 		t.Fatalf("should not have error: %s", err)
 	}
 }
+
+func TestHTTPConfigPrepare_TLS(t *testing.T) {
+	// Test bad
+	h := HTTPConfig{
+		HTTPTLSCertFile: "cert.pem",
+	}
+	err := h.Prepare(nil)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test good
+	h = HTTPConfig{
+		HTTPTLSCertFile: "cert.pem",
+		HTTPTLSKeyFile:  "key.pem",
+	}
+	err = h.Prepare(nil)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !h.HTTPTLS {
+		t.Fatal("should serve over TLS")
+	}
+}
+
+func TestHTTPConfigPrepare_Credentials(t *testing.T) {
+	// Test bad
+	h := HTTPConfig{
+		HTTPUsername: "packer",
+	}
+	err := h.Prepare(nil)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test good
+	h = HTTPConfig{
+		HTTPUsername: "packer",
+		HTTPPassword: "secret",
+	}
+	err = h.Prepare(nil)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestHTTPConfigPrepare_Templates(t *testing.T) {
+	// Test bad
+	h := HTTPConfig{
+		HTTPTemplates: []string{"preseed/[.cfg"},
+	}
+	err := h.Prepare(nil)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test good
+	h = HTTPConfig{
+		HTTPTemplates: []string{"ks.cfg", "preseed/*.cfg"},
+	}
+	err = h.Prepare(nil)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
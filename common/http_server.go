@@ -0,0 +1,159 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// httpTemplateData is the data of the files of the HTTP server rendered as
+// templates.
+type httpTemplateData struct {
+	// The IP of the host the file was requested from, and the port of the
+	// server.
+	HTTPIP   string
+	HTTPPort uint
+}
+
+// handler returns the handler of the requests to the HTTP server listening
+// on the port.
+func (s *StepHTTPServer) handler(port uint) http.Handler {
+	var h http.Handler = http.FileServer(http.Dir(s.HTTPDir))
+	if len(s.Templates) > 0 {
+		h = &httpTemplateHandler{
+			next:     h,
+			dir:      s.HTTPDir,
+			patterns: s.Templates,
+			port:     port,
+			ctx:      s.Ctx,
+		}
+	}
+	if s.Username != "" {
+		h = &httpAuthHandler{next: h, username: s.Username, password: s.Password}
+	}
+	return h
+}
+
+// certificate returns the certificate of the HTTPS server, from the files
+// or generated.
+func (s *StepHTTPServer) certificate() (tls.Certificate, error) {
+	if s.TLSCertFile != "" {
+		return tls.LoadX509KeyPair(s.TLSCertFile, s.TLSKeyFile)
+	}
+	return selfSignedCertificate()
+}
+
+// selfSignedCertificate generates a self-signed certificate, valid during
+// a week.
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "packer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(7 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// httpAuthHandler requires basic authentication with the credentials.
+type httpAuthHandler struct {
+	next               http.Handler
+	username, password string
+}
+
+func (h *httpAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	username, password, ok := r.BasicAuth()
+	if !ok ||
+		subtle.ConstantTimeCompare([]byte(username), []byte(h.username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(h.password)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="packer"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// httpTemplateHandler renders the files matching the patterns as templates,
+// and serves the other ones with the next handler.
+type httpTemplateHandler struct {
+	next     http.Handler
+	dir      string
+	patterns []string
+	port     uint
+	ctx      interpolate.Context
+}
+
+func (h *httpTemplateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if !h.isTemplate(name) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(h.dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("Error reading %s: %s", name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := &httpTemplateData{HTTPPort: h.port}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		data.HTTPIP, _, _ = net.SplitHostPort(addr.String())
+	}
+	ctx := h.ctx
+	ctx.Data = data
+	rendered, err := interpolate.Render(string(content), &ctx)
+	if err != nil {
+		log.Printf("Error rendering %s: %s", name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeContent(w, r, name, time.Time{}, strings.NewReader(rendered))
+}
+
+// isTemplate returns whether the file with the slash separated path,
+// relative to the directory, is rendered as a template.
+func (h *httpTemplateHandler) isTemplate(name string) bool {
+	for _, pattern := range h.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
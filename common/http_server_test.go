@@ -0,0 +1,85 @@
+package common
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+func TestStepHTTPServer_handler(t *testing.T) {
+	s := &StepHTTPServer{
+		HTTPDir:   "./test-fixtures/http",
+		Username:  "packer",
+		Password:  "secret",
+		Templates: []string{"*.cfg"},
+		Ctx: interpolate.Context{
+			UserVariables: map[string]string{"root_password": "hunter2"},
+		},
+	}
+	ts := httptest.NewServer(s.handler(8080))
+	defer ts.Close()
+
+	get := func(path, username, password string) (int, string) {
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	// Bad credentials
+	if code, _ := get("/ks.cfg", "", ""); code != http.StatusUnauthorized {
+		t.Fatalf("bad: %d", code)
+	}
+	if code, _ := get("/ks.cfg", "packer", "hunter2"); code != http.StatusUnauthorized {
+		t.Fatalf("bad: %d", code)
+	}
+
+	// Rendered template
+	code, body := get("/ks.cfg", "packer", "secret")
+	expected := "url --url http://127.0.0.1:8080/os\nrootpw hunter2\n"
+	if code != http.StatusOK || body != expected {
+		t.Fatalf("bad: %d %q", code, body)
+	}
+
+	// Static file
+	code, body = get("/static.txt", "packer", "secret")
+	if code != http.StatusOK || body != "static {{ .HTTPIP }}\n" {
+		t.Fatalf("bad: %d %q", code, body)
+	}
+
+	// Missing template
+	if code, _ := get("/missing.cfg", "packer", "secret"); code != http.StatusNotFound {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestSelfSignedCertificate(t *testing.T) {
+	cert, err := selfSignedCertificate()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := c.CheckSignature(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature); err != nil {
+		t.Fatalf("should be self-signed: %s", err)
+	}
+}
@@ -0,0 +1,96 @@
+package imagemount
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+const (
+	// ProvisionModeGuest runs provisioners inside the booted machine, the
+	// way builders always have.
+	ProvisionModeGuest = "guest"
+
+	// ProvisionModeHostChroot runs provisioners on the host, chrooted into
+	// the image mounted there, without ever booting it.
+	ProvisionModeHostChroot = "host-chroot"
+)
+
+const (
+	MountToolGuestmount = "guestmount"
+	MountToolNBD        = "nbd"
+)
+
+// Config is the configuration of builders that can provision the image
+// they produce by mounting it on the host. It is meant to be squashed into
+// the configuration of the builder.
+type Config struct {
+	ProvisionMode      string `mapstructure:"provision_mode"`
+	HostMountTool      string `mapstructure:"host_mount_tool"`
+	HostMountPartition string `mapstructure:"host_mount_partition"`
+	HostNBDDevice      string `mapstructure:"host_nbd_device"`
+	HostCommandWrapper string `mapstructure:"host_command_wrapper"`
+}
+
+type wrappedCommandTemplate struct {
+	Command string
+}
+
+func (c *Config) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.ProvisionMode == "" {
+		c.ProvisionMode = ProvisionModeGuest
+	}
+	if c.HostMountTool == "" {
+		c.HostMountTool = MountToolGuestmount
+	}
+	if c.HostNBDDevice == "" {
+		c.HostNBDDevice = "/dev/nbd0"
+	}
+	if c.HostCommandWrapper == "" {
+		c.HostCommandWrapper = "{{.Command}}"
+	}
+
+	switch c.ProvisionMode {
+	case ProvisionModeGuest, ProvisionModeHostChroot:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"provision_mode must be %q or %q", ProvisionModeGuest, ProvisionModeHostChroot))
+	}
+
+	switch c.HostMountTool {
+	case MountToolGuestmount:
+	case MountToolNBD:
+		if c.HostMountPartition == "" {
+			c.HostMountPartition = "1"
+		}
+	default:
+		errs = append(errs, fmt.Errorf(
+			"host_mount_tool must be %q or %q", MountToolGuestmount, MountToolNBD))
+	}
+
+	return errs
+}
+
+// HostChroot reports whether provisioners run on the host.
+func (c *Config) HostChroot() bool {
+	return c.ProvisionMode == ProvisionModeHostChroot
+}
+
+// CommandWrapper returns a function wrapping commands run on the host
+// according to host_command_wrapper.
+func (c *Config) CommandWrapper(ctx interpolate.Context) chroot.CommandWrapper {
+	return HostCommandWrapper(c.HostCommandWrapper, ctx)
+}
+
+// HostCommandWrapper returns a function wrapping commands run on the host
+// according to the host_command_wrapper template of a builder, which is
+// typically used to run them with sudo.
+func HostCommandWrapper(wrapper string, ctx interpolate.Context) chroot.CommandWrapper {
+	return func(command string) (string, error) {
+		ctx.Data = &wrappedCommandTemplate{Command: command}
+		return interpolate.Render(wrapper, &ctx)
+	}
+}
@@ -0,0 +1,54 @@
+package imagemount
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+func TestConfigPrepare(t *testing.T) {
+	c := new(Config)
+	if errs := c.Prepare(&interpolate.Context{}); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.ProvisionMode != ProvisionModeGuest || c.HostChroot() {
+		t.Fatalf("bad provision mode: %s", c.ProvisionMode)
+	}
+	if c.HostMountTool != MountToolGuestmount {
+		t.Fatalf("bad mount tool: %s", c.HostMountTool)
+	}
+	if c.HostMountPartition != "" {
+		t.Fatalf("bad partition: %s", c.HostMountPartition)
+	}
+
+	c = &Config{ProvisionMode: ProvisionModeHostChroot, HostMountTool: MountToolNBD}
+	if errs := c.Prepare(&interpolate.Context{}); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if !c.HostChroot() {
+		t.Fatal("should provision in a chroot")
+	}
+	if c.HostMountPartition != "1" || c.HostNBDDevice != "/dev/nbd0" {
+		t.Fatalf("bad: %#v", c)
+	}
+
+	c = &Config{ProvisionMode: "nope", HostMountTool: "nope"}
+	if errs := c.Prepare(&interpolate.Context{}); len(errs) != 2 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestConfigCommandWrapper(t *testing.T) {
+	c := &Config{HostCommandWrapper: "sudo {{.Command}}"}
+	if errs := c.Prepare(&interpolate.Context{}); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	command, err := c.CommandWrapper(interpolate.Context{})("umount /mnt")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if command != "sudo umount /mnt" {
+		t.Fatalf("bad: %s", command)
+	}
+}
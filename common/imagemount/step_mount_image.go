@@ -0,0 +1,234 @@
+package imagemount
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// chrootMounts are the file systems mounted inside the image so that the
+// usual tools work in the chroot, as [type, source, target].
+var chrootMounts = [][]string{
+	{"proc", "proc", "/proc"},
+	{"sysfs", "sysfs", "/sys"},
+	{"bind", "/dev", "/dev"},
+}
+
+// StepMountImage mounts a disk image on the host with libguestfs or
+// qemu-nbd, so that provisioners can run chrooted into it.
+//
+// Uses:
+//   ui packer.Ui
+//
+// Produces:
+//   communicator packer.Communicator - Runs commands in the mounted image.
+//   mount_path string - The location where the image was mounted.
+//   image_unmount *StepMountImage - To unmount the image before the end of
+//     the build, with StepUnmountImage.
+type StepMountImage struct {
+	Config *Config
+	Ctx    interpolate.Context
+
+	// Image is the path to the disk image and Format its format, as
+	// understood by qemu-img.
+	Image  string
+	Format string
+
+	mountPath     string
+	nbdConnected  bool
+	mainMounted   bool
+	chrootMounted []string
+}
+
+func (s *StepMountImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := s.Config.CommandWrapper(s.Ctx)
+
+	mountPath, err := ioutil.TempDir("", "packer-host-chroot")
+	if err != nil {
+		return halt(state, fmt.Errorf("Error creating mount directory: %s", err))
+	}
+	s.mountPath = mountPath
+
+	ui.Say(fmt.Sprintf("Mounting %s on the host with %s...", s.Image, s.Config.HostMountTool))
+	switch s.Config.HostMountTool {
+	case MountToolGuestmount:
+		args := fmt.Sprintf("guestmount -a '%s' --format=%s --rw", s.Image, s.Format)
+		if s.Config.HostMountPartition != "" {
+			args += fmt.Sprintf(" -m /dev/sda%s", s.Config.HostMountPartition)
+		} else {
+			args += " -i"
+		}
+		if err := chroot.RunWrapped(wrapper, fmt.Sprintf("%s '%s'", args, mountPath)); err != nil {
+			return halt(state, fmt.Errorf("Error mounting image: %s", err))
+		}
+	case MountToolNBD:
+		device := s.Config.HostNBDDevice
+		if err := chroot.RunWrapped(wrapper, fmt.Sprintf(
+			"qemu-nbd --connect=%s --format=%s '%s'", device, s.Format, s.Image)); err != nil {
+			return halt(state, fmt.Errorf("Error connecting image to %s: %s", device, err))
+		}
+		s.nbdConnected = true
+
+		partition := fmt.Sprintf("%sp%s", device, s.Config.HostMountPartition)
+		if err := WaitForDevice(partition, 10*time.Second); err != nil {
+			return halt(state, err)
+		}
+		if err := chroot.RunWrapped(wrapper, fmt.Sprintf("mount %s '%s'", partition, mountPath)); err != nil {
+			return halt(state, fmt.Errorf("Error mounting %s: %s", partition, err))
+		}
+	}
+	s.mainMounted = true
+
+	s.chrootMounted, err = MountChrootFilesystems(wrapper, mountPath)
+	if err != nil {
+		return halt(state, err)
+	}
+
+	state.Put("mount_path", mountPath)
+	state.Put("image_unmount", s)
+	state.Put("communicator", &chroot.Communicator{
+		Chroot:     mountPath,
+		CmdWrapper: wrapper,
+	})
+
+	return multistep.ActionContinue
+}
+
+func (s *StepMountImage) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	if err := s.CleanupFunc(state); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+// CleanupFunc unmounts the image. It does nothing if the image isn't
+// mounted anymore, so it is safe to call more than once.
+func (s *StepMountImage) CleanupFunc(state multistep.StateBag) error {
+	if s.mountPath == "" {
+		return nil
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	wrapper := s.Config.CommandWrapper(s.Ctx)
+
+	var err error
+	s.chrootMounted, err = UnmountChrootFilesystems(wrapper, s.chrootMounted)
+	if err != nil {
+		return err
+	}
+
+	if s.mainMounted {
+		ui.Say("Unmounting the image...")
+		command := fmt.Sprintf("umount '%s'", s.mountPath)
+		if s.Config.HostMountTool == MountToolGuestmount {
+			command = fmt.Sprintf("guestunmount '%s'", s.mountPath)
+		}
+		if err := chroot.RunWrapped(wrapper, command); err != nil {
+			return fmt.Errorf("Error unmounting image: %s", err)
+		}
+		s.mainMounted = false
+	}
+
+	if s.nbdConnected {
+		if err := chroot.RunWrapped(wrapper, fmt.Sprintf(
+			"qemu-nbd --disconnect %s", s.Config.HostNBDDevice)); err != nil {
+			return fmt.Errorf("Error disconnecting %s: %s", s.Config.HostNBDDevice, err)
+		}
+		s.nbdConnected = false
+	}
+
+	if err := os.Remove(s.mountPath); err != nil {
+		log.Printf("Error removing mount directory %s: %s", s.mountPath, err)
+	}
+	s.mountPath = ""
+	return nil
+}
+
+// StepUnmountImage unmounts the image mounted by StepMountImage, so that
+// the steps following it can use the image file.
+type StepUnmountImage struct{}
+
+func (s *StepUnmountImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	mount, ok := state.Get("image_unmount").(*StepMountImage)
+	if !ok {
+		return multistep.ActionContinue
+	}
+
+	if err := mount.CleanupFunc(state); err != nil {
+		return halt(state, err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepUnmountImage) Cleanup(multistep.StateBag) {}
+
+// MountChrootFilesystems mounts the file systems the usual tools need in a
+// chroot, such as /proc, inside the root file system mounted at root. It
+// returns the targets it mounted, even if it fails to mount the others, so
+// that they can be unmounted with UnmountChrootFilesystems.
+func MountChrootFilesystems(wrapper chroot.CommandWrapper, root string) ([]string, error) {
+	var mounted []string
+	for _, m := range chrootMounts {
+		target := filepath.Join(root, m[2])
+		if err := chroot.RunWrapped(wrapper, fmt.Sprintf("mkdir -p '%s'", target)); err != nil {
+			return mounted, fmt.Errorf("Error creating %s in the image: %s", m[2], err)
+		}
+
+		command := fmt.Sprintf("mount -t %s %s '%s'", m[0], m[1], target)
+		if m[0] == "bind" {
+			command = fmt.Sprintf("mount --bind %s '%s'", m[1], target)
+		}
+		if err := chroot.RunWrapped(wrapper, command); err != nil {
+			return mounted, fmt.Errorf("Error mounting %s in the image: %s", m[2], err)
+		}
+		mounted = append(mounted, target)
+	}
+	return mounted, nil
+}
+
+// UnmountChrootFilesystems unmounts the targets mounted by
+// MountChrootFilesystems, in reverse order. It returns the targets still
+// mounted if it fails.
+func UnmountChrootFilesystems(wrapper chroot.CommandWrapper, mounted []string) ([]string, error) {
+	for len(mounted) > 0 {
+		target := mounted[len(mounted)-1]
+		if err := chroot.RunWrapped(wrapper, fmt.Sprintf("umount '%s'", target)); err != nil {
+			return mounted, fmt.Errorf("Error unmounting %s: %s", target, err)
+		}
+		mounted = mounted[:len(mounted)-1]
+	}
+	return nil, nil
+}
+
+func halt(state multistep.StateBag, err error) multistep.StepAction {
+	state.Put("error", err)
+	state.Get("ui").(packer.Ui).Error(err.Error())
+	return multistep.ActionHalt
+}
+
+// WaitForDevice waits for the device node of a partition to appear, which
+// happens asynchronously after a disk image is connected with qemu-nbd or
+// attached to a loop device.
+func WaitForDevice(device string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := os.Stat(device); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timeout waiting for %s to appear", device)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
@@ -2,11 +2,12 @@ package common
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -23,6 +24,12 @@ type ISOConfig struct {
 	TargetPath      string   `mapstructure:"iso_target_path"`
 	TargetExtension string   `mapstructure:"iso_target_extension"`
 	RawSingleISOUrl string   `mapstructure:"iso_url"`
+	DownloadChunks  int      `mapstructure:"iso_download_chunks"`
+
+	// The detached GPG signature of the checksum file, and the public keys
+	// trusted to sign it.
+	ISOChecksumSignatureURL string   `mapstructure:"iso_checksum_signature_url"`
+	ISOChecksumKeys         []string `mapstructure:"iso_checksum_keys"`
 }
 
 func (c *ISOConfig) Prepare(ctx *interpolate.Context) (warnings []string, errs []error) {
@@ -38,6 +45,39 @@ func (c *ISOConfig) Prepare(ctx *interpolate.Context) (warnings []string, errs [
 		c.ISOUrls = []string{c.RawSingleISOUrl}
 	}
 
+	// iso_checksum can also be the URL of the checksum file
+	if strings.HasPrefix(c.ISOChecksum, "file:") {
+		if c.ISOChecksumURL != "" {
+			errs = append(
+				errs, errors.New("Only one of iso_checksum_url or a file: iso_checksum may be specified."))
+			return
+		}
+		c.ISOChecksumURL = strings.TrimPrefix(c.ISOChecksum, "file:")
+		c.ISOChecksum = ""
+	}
+
+	if c.ISOChecksumSignatureURL != "" && c.ISOChecksumURL == "" {
+		errs = append(
+			errs, errors.New("iso_checksum_signature_url requires the checksum file URL in iso_checksum_url."))
+		return
+	}
+	// The signature is of the checksum file, which isn't read otherwise
+	if c.ISOChecksumSignatureURL != "" && c.ISOChecksum != "" {
+		errs = append(
+			errs, errors.New("iso_checksum_signature_url can't verify iso_checksum, only the checksum file in iso_checksum_url."))
+		return
+	}
+	if c.ISOChecksumSignatureURL != "" && strings.ToLower(c.ISOChecksumType) == "none" {
+		errs = append(
+			errs, errors.New("iso_checksum_signature_url can't be specified with an iso_checksum_type of none."))
+		return
+	}
+	if (c.ISOChecksumSignatureURL == "") != (len(c.ISOChecksumKeys) == 0) {
+		errs = append(
+			errs, errors.New("iso_checksum_signature_url and iso_checksum_keys must be specified together."))
+		return
+	}
+
 	if c.ISOChecksumType == "" {
 		errs = append(
 			errs, errors.New("The iso_checksum_type must be specified."))
@@ -64,40 +104,24 @@ func (c *ISOConfig) Prepare(ctx *interpolate.Context) (warnings []string, errs [
 						return warnings, errs
 					}
 					switch u.Scheme {
-					case "http", "https":
-						res, err := http.Get(c.ISOChecksumURL)
-						c.ISOChecksum = ""
+					case "http", "https", "file", "":
+						checksums, err := readURL(u)
 						if err != nil {
 							errs = append(errs,
-								fmt.Errorf("Error getting checksum from url: %s", c.ISOChecksumURL))
+								fmt.Errorf("Error getting checksum from url: %s: %s", c.ISOChecksumURL, err))
 							return warnings, errs
 						}
-						defer res.Body.Close()
-						err = c.parseCheckSumFile(bufio.NewReader(res.Body))
-						if err != nil {
-							errs = append(errs, err)
-							return warnings, errs
-						}
-					case "file":
-						path := u.Path
-
-						if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
-							path = strings.TrimLeft(path, "/")
-						}
-
-						file, err := os.Open(path)
-						if err != nil {
-							errs = append(errs, err)
-							return warnings, errs
+						if c.ISOChecksumSignatureURL != "" {
+							if err := c.verifyChecksumSignature(checksums); err != nil {
+								errs = append(errs, err)
+								return warnings, errs
+							}
 						}
-						err = c.parseCheckSumFile(bufio.NewReader(file))
+						err = c.parseCheckSumFile(bufio.NewReader(bytes.NewReader(checksums)))
 						if err != nil {
 							errs = append(errs, err)
 							return warnings, errs
 						}
-
-					case "":
-						break
 					default:
 						errs = append(errs,
 							fmt.Errorf("Error parsing checksum url: %s, scheme not supported: %s", c.ISOChecksumURL, u.Scheme))
@@ -120,6 +144,13 @@ func (c *ISOConfig) Prepare(ctx *interpolate.Context) (warnings []string, errs [
 		}
 	}
 
+	if c.DownloadChunks < 0 {
+		errs = append(
+			errs, fmt.Errorf("iso_download_chunks must be positive: %d", c.DownloadChunks))
+	} else if c.DownloadChunks == 0 {
+		c.DownloadChunks = 1
+	}
+
 	if c.TargetExtension == "" {
 		c.TargetExtension = "iso"
 	}
@@ -184,3 +215,47 @@ func (c *ISOConfig) parseCheckSumFile(rd *bufio.Reader) error {
 	}
 	return errNotFound
 }
+
+// verifyChecksumSignature returns an error unless the checksum file is
+// signed by one of the keys of iso_checksum_keys.
+func (c *ISOConfig) verifyChecksumSignature(checksums []byte) error {
+	u, err := url.Parse(c.ISOChecksumSignatureURL)
+	if err != nil {
+		return fmt.Errorf("Error parsing checksum signature url: %s", err)
+	}
+	signature, err := readURL(u)
+	if err != nil {
+		return fmt.Errorf("Error getting checksum signature from url: %s: %s", c.ISOChecksumSignatureURL, err)
+	}
+	if err := verifySignature(checksums, signature, c.ISOChecksumKeys); err != nil {
+		return fmt.Errorf("Error verifying the signature of %s: %s", c.ISOChecksumURL, err)
+	}
+	return nil
+}
+
+// readURL returns the content of the file at the http, https or file URL,
+// or the path.
+func readURL(u *url.URL) ([]byte, error) {
+	switch u.Scheme {
+	case "http", "https":
+		res, err := http.Get(u.String())
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode >= 400 {
+			return nil, fmt.Errorf("unexpected HTTP response: %s", res.Status)
+		}
+		return ioutil.ReadAll(res.Body)
+	case "file", "":
+		path := u.Path
+
+		if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+			path = strings.TrimLeft(path, "/")
+		}
+
+		return ioutil.ReadFile(path)
+	default:
+		return nil, fmt.Errorf("scheme not supported: %s", u.Scheme)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"testing"
@@ -401,3 +403,159 @@ func TestISOConfigPrepare_TargetExtension(t *testing.T) {
 		t.Fatalf("should've lowercased: %s", i.TargetExtension)
 	}
 }
+
+func TestISOConfigPrepare_DownloadChunks(t *testing.T) {
+	i := testISOConfig()
+
+	// Test the default value
+	warns, err := i.Prepare(nil)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if i.DownloadChunks != 1 {
+		t.Fatalf("bad: %d", i.DownloadChunks)
+	}
+
+	// Test a bad value
+	i = testISOConfig()
+	i.DownloadChunks = -1
+	_, err = i.Prepare(nil)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test a good value
+	i = testISOConfig()
+	i.DownloadChunks = 4
+	_, err = i.Prepare(nil)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if i.DownloadChunks != 4 {
+		t.Fatalf("bad: %d", i.DownloadChunks)
+	}
+}
+
+func TestISOConfigPrepare_ISOChecksumFile(t *testing.T) {
+	cs_file, _ := ioutil.TempFile("", "packer-test-")
+	defer os.Remove(cs_file.Name())
+	defer cs_file.Close()
+	ioutil.WriteFile(cs_file.Name(), []byte(cs_gnu_style), 0666)
+
+	// Test good
+	i := testISOConfig()
+	i.ISOChecksum = "file:" + cs_file.Name()
+	_, err := i.Prepare(nil)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if i.ISOChecksum != "bar0" {
+		t.Fatalf("should've found \"bar0\" got: %s", i.ISOChecksum)
+	}
+
+	// Test good, the path in iso_checksum_url
+	i = testISOConfig()
+	i.ISOChecksum = ""
+	i.ISOChecksumURL = cs_file.Name()
+	_, err = i.Prepare(nil)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if i.ISOChecksum != "bar0" {
+		t.Fatalf("should've found \"bar0\" got: %s", i.ISOChecksum)
+	}
+
+	// Test bad, with iso_checksum_url too
+	i = testISOConfig()
+	i.ISOChecksum = "file:" + cs_file.Name()
+	i.ISOChecksumURL = "file://" + cs_file.Name()
+	_, err = i.Prepare(nil)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestISOConfigPrepare_ISOChecksumSignatureSkipped(t *testing.T) {
+	// Bad, the checksum in iso_checksum isn't signed
+	i := testISOConfig()
+	i.ISOChecksumURL = "file:///SHA256SUMS"
+	i.ISOChecksumSignatureURL = "file:///SHA256SUMS.sig"
+	i.ISOChecksumKeys = []string{"key.asc"}
+	_, errs := i.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad, no checksum is verified
+	i = testISOConfig()
+	i.ISOChecksum = ""
+	i.ISOChecksumType = "none"
+	i.ISOChecksumURL = "file:///SHA256SUMS"
+	i.ISOChecksumSignatureURL = "file:///SHA256SUMS.sig"
+	i.ISOChecksumKeys = []string{"key.asc"}
+	_, errs = i.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestISOConfigPrepare_ISOChecksumSignature(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not found")
+	}
+
+	fixtures, err := filepath.Abs("./test-fixtures/gpg")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	filePrefix := "file://"
+	if runtime.GOOS == "windows" {
+		filePrefix += "/"
+	}
+	config := func() ISOConfig {
+		i := testISOConfig()
+		i.ISOChecksum = "file:" + filePrefix + filepath.Join(fixtures, "SHA256SUMS")
+		i.ISOChecksumType = "sha256"
+		i.ISOChecksumSignatureURL = filePrefix + filepath.Join(fixtures, "SHA256SUMS.sig")
+		i.ISOChecksumKeys = []string{filepath.Join(fixtures, "key.asc")}
+		return i
+	}
+
+	// Test good
+	i := config()
+	_, errs := i.Prepare(nil)
+	if errs != nil {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if i.ISOChecksum != "bar0" {
+		t.Fatalf("should've found \"bar0\" got: %s", i.ISOChecksum)
+	}
+
+	// Test bad, signed by another key
+	i = config()
+	i.ISOChecksumKeys = []string{filepath.Join(fixtures, "other.asc")}
+	_, errs = i.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test bad, the signature of another file
+	i = config()
+	i.ISOChecksum = "file:" + filePrefix + filepath.Join(fixtures, "SHA256SUMS.sig")
+	_, errs = i.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test bad, no keys
+	i = config()
+	i.ISOChecksumKeys = nil
+	_, errs = i.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should have error")
+	}
+}
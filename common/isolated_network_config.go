@@ -0,0 +1,162 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// IsolatedNetworkConfig configures a private network created for a build
+// of a local VM builder and deleted once it completes, so that builds
+// running next to each other on the same host never share addresses.
+//
+// The first three addresses of the network are kept for the gateway, the
+// DNS proxy and the DHCP server, the guest gets the first address of the
+// DHCP range.
+type IsolatedNetworkConfig struct {
+	IsolatedNetwork          bool   `mapstructure:"isolated_network"`
+	IsolatedNetworkCIDR      string `mapstructure:"isolated_network_cidr"`
+	IsolatedNetworkDHCPRange string `mapstructure:"isolated_network_dhcp_range"`
+
+	network   *net.IPNet
+	dhcpLower net.IP
+	dhcpUpper net.IP
+}
+
+func (c *IsolatedNetworkConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if !c.IsolatedNetwork {
+		if c.IsolatedNetworkCIDR != "" || c.IsolatedNetworkDHCPRange != "" {
+			errs = append(errs, fmt.Errorf(
+				"isolated_network_cidr and isolated_network_dhcp_range require isolated_network"))
+		}
+		return errs
+	}
+
+	if c.IsolatedNetworkCIDR == "" {
+		network, err := freeNetwork()
+		if err != nil {
+			return append(errs, err)
+		}
+		c.IsolatedNetworkCIDR = network.String()
+	}
+
+	_, network, err := net.ParseCIDR(c.IsolatedNetworkCIDR)
+	if err != nil {
+		return append(errs, fmt.Errorf("isolated_network_cidr is invalid: %s", err))
+	}
+	if network.IP.To4() == nil {
+		return append(errs, fmt.Errorf("isolated_network_cidr must be an IPv4 network"))
+	}
+	if ones, _ := network.Mask.Size(); ones > 28 {
+		return append(errs, fmt.Errorf("isolated_network_cidr must be a /28 or larger"))
+	}
+	c.network = network
+	c.IsolatedNetworkCIDR = network.String()
+
+	if c.IsolatedNetworkDHCPRange == "" {
+		c.IsolatedNetworkDHCPRange = fmt.Sprintf("%s-%s",
+			addIP(network.IP, 10), addIP(broadcast(network), -1))
+	}
+
+	parts := strings.Split(c.IsolatedNetworkDHCPRange, "-")
+	if len(parts) == 2 {
+		c.dhcpLower = net.ParseIP(strings.TrimSpace(parts[0])).To4()
+		c.dhcpUpper = net.ParseIP(strings.TrimSpace(parts[1])).To4()
+	}
+	if c.dhcpLower == nil || c.dhcpUpper == nil {
+		return append(errs, fmt.Errorf(
+			"isolated_network_dhcp_range must be two IPv4 addresses separated by a dash"))
+	}
+	first, last := ipInt(addIP(network.IP, 4)), ipInt(addIP(broadcast(network), -1))
+	lower, upper := ipInt(c.dhcpLower), ipInt(c.dhcpUpper)
+	if lower < first || upper > last {
+		errs = append(errs, fmt.Errorf(
+			"isolated_network_dhcp_range must be within %s-%s", addIP(network.IP, 4), addIP(broadcast(network), -1)))
+	}
+	if lower > upper {
+		errs = append(errs, fmt.Errorf(
+			"isolated_network_dhcp_range must start with its lowest address"))
+	}
+
+	return errs
+}
+
+// Gateway is the address of the host on the isolated network.
+func (c *IsolatedNetworkConfig) Gateway() net.IP {
+	return addIP(c.network.IP, 1)
+}
+
+// DHCPServer is the address of the DHCP server of the isolated network.
+func (c *IsolatedNetworkConfig) DHCPServer() net.IP {
+	return addIP(c.network.IP, 3)
+}
+
+// Netmask is the mask of the isolated network in dotted form.
+func (c *IsolatedNetworkConfig) Netmask() net.IP {
+	return net.IP(c.network.Mask).To4()
+}
+
+// DHCPRange returns the first and last address the DHCP server leases.
+func (c *IsolatedNetworkConfig) DHCPRange() (net.IP, net.IP) {
+	return c.dhcpLower, c.dhcpUpper
+}
+
+// GuestIP is the address the guest gets, the first one of the DHCP range
+// since the guest is the only one on the network.
+func (c *IsolatedNetworkConfig) GuestIP() net.IP {
+	return c.dhcpLower
+}
+
+// freeNetwork picks a random /24 in 172.16.0.0/12 that doesn't overlap the
+// networks of the interfaces of the host.
+func freeNetwork() (*net.IPNet, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing the addresses of the host: %s", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		network := &net.IPNet{
+			IP:   net.IPv4(172, byte(16+rand.Intn(16)), byte(rand.Intn(256)), 0).To4(),
+			Mask: net.CIDRMask(24, 32),
+		}
+		overlaps := false
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				if ipNet.Contains(network.IP) || network.Contains(ipNet.IP) {
+					overlaps = true
+					break
+				}
+			}
+		}
+		if !overlaps {
+			return network, nil
+		}
+	}
+	return nil, fmt.Errorf("Couldn't find a free network for isolated_network, set isolated_network_cidr")
+}
+
+func ipInt(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func addIP(ip net.IP, n int) net.IP {
+	result := make(net.IP, 4)
+	binary.BigEndian.PutUint32(result, uint32(int64(ipInt(ip))+int64(n)))
+	return result
+}
+
+func broadcast(network *net.IPNet) net.IP {
+	result := make(net.IP, 4)
+	ip, mask := network.IP.To4(), net.IP(network.Mask).To4()
+	for i := range result {
+		result[i] = ip[i] | ^mask[i]
+	}
+	return result
+}
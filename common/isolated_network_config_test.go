@@ -0,0 +1,83 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestIsolatedNetworkConfigPrepare(t *testing.T) {
+	// Test disabled
+	c := IsolatedNetworkConfig{}
+	if errs := c.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+
+	c = IsolatedNetworkConfig{IsolatedNetworkCIDR: "10.10.0.0/24"}
+	if errs := c.Prepare(nil); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+
+	// Test defaults
+	c = IsolatedNetworkConfig{IsolatedNetwork: true}
+	if errs := c.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.IsolatedNetworkCIDR == "" {
+		t.Fatal("should pick a network")
+	}
+
+	c = IsolatedNetworkConfig{
+		IsolatedNetwork:     true,
+		IsolatedNetworkCIDR: "10.10.0.7/24",
+	}
+	if errs := c.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.IsolatedNetworkCIDR != "10.10.0.0/24" {
+		t.Fatalf("bad: %s", c.IsolatedNetworkCIDR)
+	}
+	if c.IsolatedNetworkDHCPRange != "10.10.0.10-10.10.0.254" {
+		t.Fatalf("bad: %s", c.IsolatedNetworkDHCPRange)
+	}
+	if c.Gateway().String() != "10.10.0.1" {
+		t.Fatalf("bad: %s", c.Gateway())
+	}
+	if c.DHCPServer().String() != "10.10.0.3" {
+		t.Fatalf("bad: %s", c.DHCPServer())
+	}
+	if c.Netmask().String() != "255.255.255.0" {
+		t.Fatalf("bad: %s", c.Netmask())
+	}
+	if c.GuestIP().String() != "10.10.0.10" {
+		t.Fatalf("bad: %s", c.GuestIP())
+	}
+}
+
+func TestIsolatedNetworkConfigPrepare_Invalid(t *testing.T) {
+	cases := []IsolatedNetworkConfig{
+		{IsolatedNetworkCIDR: "10.10.0.0"},
+		{IsolatedNetworkCIDR: "fd00::/64"},
+		{IsolatedNetworkCIDR: "10.10.0.0/30"},
+		{IsolatedNetworkCIDR: "10.10.0.0/24", IsolatedNetworkDHCPRange: "10.10.0.100"},
+		{IsolatedNetworkCIDR: "10.10.0.0/24", IsolatedNetworkDHCPRange: "10.10.0.2-10.10.0.200"},
+		{IsolatedNetworkCIDR: "10.10.0.0/24", IsolatedNetworkDHCPRange: "10.10.0.100-10.10.1.100"},
+		{IsolatedNetworkCIDR: "10.10.0.0/24", IsolatedNetworkDHCPRange: "10.10.0.200-10.10.0.100"},
+	}
+	for _, c := range cases {
+		c.IsolatedNetwork = true
+		if errs := c.Prepare(nil); len(errs) == 0 {
+			t.Fatalf("should have error: %#v", c)
+		}
+	}
+
+	c := IsolatedNetworkConfig{
+		IsolatedNetwork:          true,
+		IsolatedNetworkCIDR:      "10.10.0.0/24",
+		IsolatedNetworkDHCPRange: "10.10.0.100 - 10.10.0.120",
+	}
+	if errs := c.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if lower, upper := c.DHCPRange(); lower.String() != "10.10.0.100" || upper.String() != "10.10.0.120" {
+		t.Fatalf("bad: %s-%s", lower, upper)
+	}
+}
@@ -3,10 +3,12 @@ package common
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
@@ -24,13 +26,23 @@ func newRunner(steps []multistep.Step, config PackerConfig, ui packer.Ui) (multi
 		for i, step := range steps {
 			steps[i] = askStep{step, ui}
 		}
+	case "inspect":
+		session := &inspectSession{
+			buildName:   config.PackerBuildName,
+			gracePeriod: config.PackerOnErrorGracePeriod,
+			ui:          ui,
+		}
+		for i, step := range steps {
+			steps[i] = inspectStep{step, session}
+		}
 	}
 
+	hooks := []multistep.StepHook{&stepTrace{ui: ui, debug: config.PackerDebug}}
 	if config.PackerDebug {
 		pauseFn := MultistepDebugFn(ui)
-		return &multistep.DebugRunner{Steps: steps, PauseFn: pauseFn}, pauseFn
+		return &multistep.DebugRunner{Steps: steps, PauseFn: pauseFn, Hooks: hooks}, pauseFn
 	} else {
-		return &multistep.BasicRunner{Steps: steps}, nil
+		return &multistep.BasicRunner{Steps: steps, Hooks: hooks}, nil
 	}
 }
 
@@ -66,6 +78,10 @@ func (s abortStep) InnerStepName() string {
 	return typeName(s.step)
 }
 
+func (s abortStep) ShouldRun(state multistep.StateBag) bool {
+	return multistep.ShouldRun(s.step, state)
+}
+
 func (s abortStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	return s.step.Run(ctx, state)
 }
@@ -95,6 +111,10 @@ func (s askStep) InnerStepName() string {
 	return typeName(s.step)
 }
 
+func (s askStep) ShouldRun(state multistep.StateBag) bool {
+	return multistep.ShouldRun(s.step, state)
+}
+
 func (s askStep) Run(ctx context.Context, state multistep.StateBag) (action multistep.StepAction) {
 	for {
 		action = s.step.Run(ctx, state)
@@ -123,6 +143,125 @@ func (s askStep) Cleanup(state multistep.StateBag) {
 	s.step.Cleanup(state)
 }
 
+type inspectStep struct {
+	step    multistep.Step
+	session *inspectSession
+}
+
+func (s inspectStep) InnerStepName() string {
+	return typeName(s.step)
+}
+
+func (s inspectStep) ShouldRun(state multistep.StateBag) bool {
+	return multistep.ShouldRun(s.step, state)
+}
+
+func (s inspectStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	return s.step.Run(ctx, state)
+}
+
+func (s inspectStep) Cleanup(state multistep.StateBag) {
+	if _, ok := state.GetOk(multistep.StateHalted); ok {
+		s.session.once.Do(func() { s.session.wait(typeName(s.step), state) })
+	}
+	s.step.Cleanup(state)
+}
+
+// inspectSession keeps the machine of a failed build around for the grace
+// period, before the steps are cleaned up, so it can be inspected. The
+// first step cleaned up once the build halted waits for it.
+type inspectSession struct {
+	buildName   string
+	gracePeriod time.Duration
+	ui          packer.Ui
+
+	once sync.Once
+}
+
+func (s *inspectSession) wait(name string, state multistep.StateBag) {
+	ui := s.ui
+	if err, ok := state.GetOk("error"); ok {
+		ui.Error(fmt.Sprintf("%s", err))
+	}
+	if _, ok := state.GetOk(multistep.StateCancelled); ok {
+		return
+	}
+
+	info, ok := state.GetOk("connection_info")
+	if !ok {
+		ui.Say(fmt.Sprintf("Step %q failed before connecting to the machine, cleaning up...", name))
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Step %q failed, keeping the machine for %s to inspect it. "+
+		"Interrupt to clean up right away.", name, s.gracePeriod))
+	for _, line := range info.([]string) {
+		ui.Message(line)
+	}
+	if key, ok := state.GetOk("privateKey"); ok && key.(string) != "" {
+		path := fmt.Sprintf("inspect-%s.pem", s.buildName)
+		if err := ioutil.WriteFile(path, []byte(key.(string)), 0600); err != nil {
+			ui.Error(fmt.Sprintf("Error saving the private key: %s", err))
+		} else {
+			ui.Message(fmt.Sprintf("Private key saved to: %s", path))
+			defer os.Remove(path)
+		}
+	}
+
+	deadline := time.Now().Add(s.gracePeriod)
+	reminder := time.Now().Add(inspectReminderInterval)
+	for time.Now().Before(deadline) {
+		if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			ui.Say("Interrupted, cleaning up...")
+			return
+		}
+		if time.Now().After(reminder) {
+			ui.Message(fmt.Sprintf("Cleaning up in %s...", roundDuration(deadline.Sub(time.Now()), time.Second)))
+			reminder = reminder.Add(inspectReminderInterval)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	ui.Say("Grace period over, cleaning up...")
+}
+
+// roundDuration rounds the positive duration to the nearest multiple of m,
+// like Duration.Round of Go 1.9.
+func roundDuration(d, m time.Duration) time.Duration {
+	return (d + m/2) / m * m
+}
+
+// inspectReminderInterval is how often the time left to inspect the
+// machine is shown.
+const inspectReminderInterval = 5 * time.Minute
+
+// stepTrace logs which steps ran and how long they took, and shows it with
+// -debug, so every builder reports its steps the same way.
+type stepTrace struct {
+	ui    packer.Ui
+	debug bool
+}
+
+func (t *stepTrace) BeforeStep(name string, _ multistep.StateBag) {
+	log.Printf("Running step %q", name)
+}
+
+func (t *stepTrace) AfterStep(name string, result multistep.StepResult, _ multistep.StateBag) {
+	var message string
+	switch {
+	case result.Skipped:
+		message = fmt.Sprintf("Step %q skipped", name)
+	case result.Action == multistep.ActionHalt:
+		message = fmt.Sprintf("Step %q halted after %s", name, roundDuration(result.Duration, time.Millisecond))
+	default:
+		message = fmt.Sprintf("Step %q took %s", name, roundDuration(result.Duration, time.Millisecond))
+	}
+
+	log.Print(message)
+	if t.debug {
+		t.ui.Message(message)
+	}
+}
+
 type askResponse int
 
 const (
@@ -0,0 +1,88 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type haltStep struct {
+	cleaned bool
+}
+
+func (s *haltStep) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	state.Put("connection_info", []string{"Host: 10.0.0.5:22"})
+	return multistep.ActionHalt
+}
+
+func (s *haltStep) Cleanup(multistep.StateBag) {
+	s.cleaned = true
+}
+
+func TestNewRunner_inspect(t *testing.T) {
+	out := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: out, ErrorWriter: out}
+	step := new(haltStep)
+	config := PackerConfig{
+		PackerBuildName:          "test",
+		PackerOnError:            "inspect",
+		PackerOnErrorGracePeriod: 10 * time.Millisecond,
+	}
+
+	state := new(multistep.BasicStateBag)
+	NewRunner([]multistep.Step{step}, config, ui).Run(state)
+
+	if !step.cleaned {
+		t.Fatal("step should be cleaned up after the grace period")
+	}
+	for _, expected := range []string{"keeping the machine for 10ms", "Host: 10.0.0.5:22", "Grace period over"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("%q not in output:\n%s", expected, out.String())
+		}
+	}
+}
+
+type continueStep struct{}
+
+func (continueStep) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (continueStep) Cleanup(multistep.StateBag) {}
+
+func TestStepTrace(t *testing.T) {
+	out := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: out, ErrorWriter: out}
+	runner := &multistep.BasicRunner{
+		Steps: []multistep.Step{
+			multistep.If(false, new(haltStep)),
+			continueStep{},
+		},
+		Hooks: []multistep.StepHook{&stepTrace{ui: ui, debug: true}},
+	}
+	runner.Run(new(multistep.BasicStateBag))
+
+	for _, expected := range []string{`Step "haltStep" skipped`, `Step "continueStep" took`} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("%q not in output:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestRoundDuration(t *testing.T) {
+	cases := map[time.Duration]time.Duration{
+		1499 * time.Microsecond: time.Millisecond,
+		1500 * time.Microsecond: 2 * time.Millisecond,
+		250 * time.Microsecond:  0,
+	}
+	for d, expected := range cases {
+		if actual := roundDuration(d, time.Millisecond); actual != expected {
+			t.Fatalf("bad: %s: %s", d, actual)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// buildHostsMarker tags the lines added to /etc/hosts, so that only these
+// are removed when the overrides are reverted.
+const buildHostsMarker = "# packer build_hosts"
+
+// resolvConfBackup is where /etc/resolv.conf is kept while it is replaced.
+const resolvConfBackup = "/etc/resolv.conf.packer"
+
+// nameResolution overrides name resolution on the build machine while it
+// is provisioned: entries are added to /etc/hosts and the name servers of
+// /etc/resolv.conf are replaced. Both are reverted before the machine is
+// imaged. The files are written in place, as they may be symlinks or bind
+// mounts.
+type nameResolution struct {
+	hosts      map[string]string
+	dnsServers []string
+}
+
+// nameResolutionFromState returns the overrides the communicator was
+// configured with, or nil if there are none.
+func nameResolutionFromState(state multistep.StateBag) *nameResolution {
+	n := new(nameResolution)
+	if raw, ok := state.GetOk("build_hosts"); ok {
+		n.hosts = raw.(map[string]string)
+	}
+	if raw, ok := state.GetOk("build_dns_servers"); ok {
+		n.dnsServers = raw.([]string)
+	}
+	if len(n.hosts) == 0 && len(n.dnsServers) == 0 {
+		return nil
+	}
+	return n
+}
+
+func (n *nameResolution) applyScript() string {
+	var script bytes.Buffer
+	script.WriteString("set -e\n")
+	script.WriteString(`[ "$(id -u)" -eq 0 ] || SUDO="sudo -n"` + "\n")
+
+	if len(n.hosts) > 0 {
+		names := make([]string, 0, len(n.hosts))
+		for name := range n.hosts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		script.WriteString("printf '%s\\n'")
+		for _, name := range names {
+			fmt.Fprintf(&script, " '%s %s %s'", n.hosts[name], name, buildHostsMarker)
+		}
+		script.WriteString(" | $SUDO tee -a /etc/hosts > /dev/null\n")
+	}
+
+	if len(n.dnsServers) > 0 {
+		fmt.Fprintf(&script, "$SUDO cp /etc/resolv.conf %s 2>/dev/null || $SUDO touch %s\n",
+			resolvConfBackup, resolvConfBackup)
+		fmt.Fprintf(&script, "{ printf 'nameserver %%s\\n' %s; grep -v '^nameserver' %s || true; } | $SUDO tee /etc/resolv.conf > /dev/null\n",
+			strings.Join(n.dnsServers, " "), resolvConfBackup)
+	}
+
+	return script.String()
+}
+
+func (n *nameResolution) revertScript() string {
+	var script bytes.Buffer
+	script.WriteString("set -e\n")
+	script.WriteString(`[ "$(id -u)" -eq 0 ] || SUDO="sudo -n"` + "\n")
+
+	if len(n.hosts) > 0 {
+		fmt.Fprintf(&script, "hosts=\"$(grep -v ' %s$' /etc/hosts || true)\"\n", buildHostsMarker)
+		script.WriteString("printf '%s\\n' \"$hosts\" | $SUDO tee /etc/hosts > /dev/null\n")
+	}
+
+	if len(n.dnsServers) > 0 {
+		fmt.Fprintf(&script, "$SUDO sh -c 'cat %s > /etc/resolv.conf && rm -f %s'\n",
+			resolvConfBackup, resolvConfBackup)
+	}
+
+	return script.String()
+}
+
+// apply overrides name resolution on the machine.
+func (n *nameResolution) apply(ui packer.Ui, comm packer.Communicator) error {
+	ui.Say("Overriding name resolution for the build...")
+	if err := n.run(ui, comm, n.applyScript()); err != nil {
+		return fmt.Errorf("Error overriding name resolution: %s", err)
+	}
+	return nil
+}
+
+// revert restores the name resolution the machine had.
+func (n *nameResolution) revert(ui packer.Ui, comm packer.Communicator) error {
+	ui.Say("Reverting the name resolution overrides...")
+	if err := n.run(ui, comm, n.revertScript()); err != nil {
+		return fmt.Errorf("Error reverting the name resolution overrides: %s", err)
+	}
+	return nil
+}
+
+func (n *nameResolution) run(ui packer.Ui, comm packer.Communicator, script string) error {
+	log.Printf("[DEBUG] Running name resolution script:\n%s", script)
+	cmd := &packer.RemoteCmd{Command: fmt.Sprintf("sh -c '%s'", strings.Replace(script, "'", `'"'"'`, -1))}
+	if err := cmd.StartWithUi(comm, ui); err != nil {
+		return err
+	}
+	if cmd.ExitStatus != 0 {
+		return fmt.Errorf("script exited with non-zero exit status: %d", cmd.ExitStatus)
+	}
+	return nil
+}
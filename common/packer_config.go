@@ -1,13 +1,20 @@
 package common
 
+import "time"
+
 // PackerConfig is a struct that contains the configuration keys that
 // are sent by packer, properly tagged already so mapstructure can load
 // them. Embed this structure into your configuration class to get it.
 type PackerConfig struct {
-	PackerBuildName   string            `mapstructure:"packer_build_name"`
-	PackerBuilderType string            `mapstructure:"packer_builder_type"`
-	PackerDebug       bool              `mapstructure:"packer_debug"`
-	PackerForce       bool              `mapstructure:"packer_force"`
-	PackerOnError     string            `mapstructure:"packer_on_error"`
-	PackerUserVars    map[string]string `mapstructure:"packer_user_variables"`
+	PackerBuildName          string            `mapstructure:"packer_build_name"`
+	PackerBuilderType        string            `mapstructure:"packer_builder_type"`
+	PackerDebug              bool              `mapstructure:"packer_debug"`
+	PackerForce              bool              `mapstructure:"packer_force"`
+	PackerOnError            string            `mapstructure:"packer_on_error"`
+	PackerOnErrorGracePeriod time.Duration     `mapstructure:"packer_on_error_grace_period"`
+	PackerProvisionerDryRun  bool              `mapstructure:"packer_provisioner_dry_run"`
+	PackerUserVars           map[string]string `mapstructure:"packer_user_variables"`
+	PackerLineage            string            `mapstructure:"packer_lineage"`
+	PackerLineageVersion     int               `mapstructure:"packer_lineage_version"`
+	PackerProvisionerKeys    []string          `mapstructure:"packer_provisioner_keys"`
 }
@@ -518,8 +518,17 @@ Hyper-V\Set-VMNetworkAdapter -VMName $vmName -MacAddressSpoofing $enableMacSpoof
 
 func SetVirtualMachineSecureBoot(vmName string, enableSecureBoot bool, templateName string) error {
 	var script = `
-param([string]$vmName, $enableSecureBoot)
-Hyper-V\Set-VMFirmware -VMName $vmName -EnableSecureBoot $enableSecureBoot
+param([string]$vmName, $enableSecureBoot, [string]$templateName)
+if ($enableSecureBoot -ne "On") {
+  Hyper-V\Set-VMFirmware -VMName $vmName -EnableSecureBoot $enableSecureBoot
+  return
+}
+$templateId = [guid]::Empty
+if ([guid]::TryParse($templateName, [ref]$templateId)) {
+  Hyper-V\Set-VMFirmware -VMName $vmName -EnableSecureBoot $enableSecureBoot -SecureBootTemplateId $templateId
+} else {
+  Hyper-V\Set-VMFirmware -VMName $vmName -EnableSecureBoot $enableSecureBoot -SecureBootTemplate $templateName
+}
 `
 
 	var ps powershell.PowerShellCmd
@@ -672,6 +681,34 @@ Get-ChildItem "$srcPath/$vhdDirName" -Filter *.vhd* | %{
 	return err
 }
 
+// MergeDifferencingDisks converts the differencing disks in the vhdDir
+// directory of expPath into standalone dynamic disks, under the same names,
+// and removes the parents exported along with them.
+func MergeDifferencingDisks(expPath string, vhdDir string) error {
+	var script = `
+param([string]$srcPath, [string]$vhdDirName)
+$vhdPath = Join-Path (Get-Item $srcPath).FullName $vhdDirName
+$disks = @(Get-ChildItem $vhdPath -Filter *.vhd* | %{ Hyper-V\Get-VHD -Path $_.FullName } | ?{ $_.ParentPath })
+$parents = @()
+foreach ($disk in $disks) {
+    $merged = Join-Path $vhdPath ('merged-' + (Split-Path $disk.Path -Leaf))
+    Hyper-V\Convert-VHD -Path $disk.Path -DestinationPath $merged -VHDType Dynamic
+    Remove-Item -Path $disk.Path
+    Move-Item -Path $merged -Destination $disk.Path
+    $parents += $disk.ParentPath
+}
+foreach ($parent in $parents) {
+    if ((Split-Path $parent -Parent) -eq $vhdPath -and (Test-Path $parent)) {
+        Remove-Item -Path $parent
+    }
+}
+`
+
+	var ps powershell.PowerShellCmd
+	err := ps.Run(script, expPath, vhdDir)
+	return err
+}
+
 func CopyExportedVirtualMachine(expPath string, outputPath string, vhdDir string, vmDir string) error {
 
 	var script = `
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"errors"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// ProvisionCheckpoints are the checkpoints of the machine StepProvision
+// saves for a builder, with Save, after each provisioner with a key. The
+// keys are the PackerProvisionerKeys of the configuration. StepProvision
+// skips the provisioners before Start, whose changes the machine starts
+// with, from the last checkpoint that exists.
+type ProvisionCheckpoints struct {
+	Keys  []string
+	Start int
+	Save  func(ui packer.Ui, key string) error
+}
+
+// LastCheckpoint returns the number of provisioners up to the last one with
+// a checkpoint that exists, which is 0 if there are none.
+func LastCheckpoint(keys []string, exists func(key string) (bool, error)) (int, error) {
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i] == "" {
+			continue
+		}
+
+		ok, err := exists(keys[i])
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func provisionCheckpointsFromState(state multistep.StateBag) *ProvisionCheckpoints {
+	c, ok := state.Get("provision_checkpoints").(*ProvisionCheckpoints)
+	if !ok || len(c.Keys) == 0 {
+		return nil
+	}
+	return c
+}
+
+// run runs the provisioners from Start, saving a checkpoint after each one
+// with a key, and stops between them once the build is cancelled.
+func (c *ProvisionCheckpoints) run(state multistep.StateBag, hook packer.Hook, ui packer.Ui, comm packer.Communicator) error {
+	machine, _ := state.Get("machine").(*packer.Machine)
+	from := c.Start
+	for i := c.Start; i < len(c.Keys); i++ {
+		if c.Keys[i] == "" && i < len(c.Keys)-1 {
+			continue
+		}
+
+		r := &packer.ProvisionRange{From: from, To: i + 1, Machine: machine}
+		if err := hook.Run(packer.HookProvision, ui, comm, r); err != nil {
+			return err
+		}
+		if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			return errors.New("Build was cancelled")
+		}
+		from = i + 1
+
+		if c.Keys[i] != "" {
+			if err := c.Save(ui, c.Keys[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
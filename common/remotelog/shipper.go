@@ -0,0 +1,282 @@
+// Package remotelog ships the output of a run and a manifest of its builds
+// to a bucket while the builds run, so they outlive the machine Packer runs
+// on, such as a CI agent reaped in the middle of a build.
+package remotelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// DefaultInterval is how often the output is shipped while builds run.
+const DefaultInterval = 10 * time.Second
+
+// The statuses of the builds in the manifest.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Manifest describes the builds of a run, with the temporary resources
+// they haven't deleted yet, for tooling to clean up after a run that
+// didn't complete.
+type Manifest struct {
+	RunUUID  string           `json:"packer_run_uuid"`
+	Started  time.Time        `json:"started"`
+	Finished *time.Time       `json:"finished,omitempty"`
+	Builds   []*ManifestBuild `json:"builds"`
+}
+
+type ManifestBuild struct {
+	Name      string              `json:"name"`
+	Status    string              `json:"status"`
+	Error     string              `json:"error,omitempty"`
+	Artifacts []*ManifestArtifact `json:"artifacts,omitempty"`
+	Resources []*Resource         `json:"resources"`
+}
+
+type ManifestArtifact struct {
+	BuilderId string   `json:"builder_id"`
+	Id        string   `json:"id"`
+	Files     []string `json:"files"`
+	String    string   `json:"string"`
+}
+
+// Resource is a temporary resource a builder reported creating, with the
+// "temporary-resource" machine-readable message:
+//
+//   temporary-resource,created,<type>,<id>[,<region>]
+//   temporary-resource,deleted,<type>,<id>[,<region>]
+type Resource struct {
+	Type   string `json:"type"`
+	Id     string `json:"id"`
+	Region string `json:"region,omitempty"`
+}
+
+// Shipper ships the output of the builds to the objects <run uuid>/build.log
+// and <run uuid>/manifest.json of a store.
+type Shipper struct {
+	Store    Store
+	Interval time.Duration
+
+	lock     sync.Mutex
+	log      bytes.Buffer
+	manifest Manifest
+	builds   map[string]*ManifestBuild
+	changed  bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	err  error
+}
+
+// NewShipper returns a shipper for the builds of the run.
+func NewShipper(store Store, runUUID string, builds []string) *Shipper {
+	s := &Shipper{
+		Store:    store,
+		Interval: DefaultInterval,
+		manifest: Manifest{
+			RunUUID: runUUID,
+			Started: time.Now().UTC(),
+		},
+		builds:  make(map[string]*ManifestBuild),
+		changed: true,
+	}
+	for _, name := range builds {
+		build := &ManifestBuild{Name: name, Status: StatusPending, Resources: []*Resource{}}
+		s.manifest.Builds = append(s.manifest.Builds, build)
+		s.builds[name] = build
+	}
+	return s
+}
+
+// Start ships the output every interval until Close.
+func (s *Shipper) Start() {
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				if err := s.ship(); err != nil {
+					log.Printf("[WARN] Error shipping the logs: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops shipping and ships the final output and manifest. It
+// returns the last error shipping them.
+func (s *Shipper) Close() error {
+	if s.done != nil {
+		close(s.done)
+		s.wg.Wait()
+	}
+
+	s.lock.Lock()
+	now := time.Now().UTC()
+	s.manifest.Finished = &now
+	s.changed = true
+	s.lock.Unlock()
+
+	if err := s.ship(); err != nil {
+		return err
+	}
+	return s.err
+}
+
+// Ui returns a ui adding the output of a build to the log.
+func (s *Shipper) Ui(build string, ui packer.Ui) packer.Ui {
+	return &shipperUi{Ui: ui, shipper: s, build: build}
+}
+
+// BuildStarted marks the build running in the manifest.
+func (s *Shipper) BuildStarted(name string) {
+	s.update(name, func(b *ManifestBuild) {
+		b.Status = StatusRunning
+	})
+}
+
+// BuildFinished records the result of the build in the manifest.
+func (s *Shipper) BuildFinished(name string, artifacts []packer.Artifact, err error) {
+	s.update(name, func(b *ManifestBuild) {
+		if err != nil {
+			b.Status = StatusFailed
+			b.Error = err.Error()
+		} else {
+			b.Status = StatusSucceeded
+		}
+		for _, a := range artifacts {
+			b.Artifacts = append(b.Artifacts, &ManifestArtifact{
+				BuilderId: a.BuilderId(),
+				Id:        a.Id(),
+				Files:     a.Files(),
+				String:    a.String(),
+			})
+		}
+	})
+}
+
+func (s *Shipper) update(name string, f func(*ManifestBuild)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if b, ok := s.builds[name]; ok {
+		f(b)
+		s.changed = true
+	}
+}
+
+func (s *Shipper) write(line string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, l := range strings.Split(strings.TrimRight(line, "\n"), "\n") {
+		fmt.Fprintf(&s.log, "%s %s\n", time.Now().UTC().Format(time.RFC3339), l)
+	}
+	s.changed = true
+}
+
+func (s *Shipper) resource(build string, args []string) {
+	if len(args) < 3 {
+		return
+	}
+	r := &Resource{Type: args[1], Id: args[2]}
+	if len(args) > 3 {
+		r.Region = args[3]
+	}
+
+	s.update(build, func(b *ManifestBuild) {
+		for i, existing := range b.Resources {
+			if *existing == *r {
+				b.Resources = append(b.Resources[:i], b.Resources[i+1:]...)
+				break
+			}
+		}
+		if args[0] == "created" {
+			b.Resources = append(b.Resources, r)
+		}
+	})
+}
+
+// ship uploads the log and the manifest if they changed since they were
+// last shipped.
+func (s *Shipper) ship() error {
+	s.lock.Lock()
+	if !s.changed {
+		s.lock.Unlock()
+		return nil
+	}
+	logData := append([]byte(nil), s.log.Bytes()...)
+	manifest, err := json.MarshalIndent(&s.manifest, "", "  ")
+	s.changed = false
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = s.Store.Put(path.Join(s.manifest.RunUUID, "build.log"), "text/plain", logData)
+	if err == nil {
+		err = s.Store.Put(path.Join(s.manifest.RunUUID, "manifest.json"), "application/json", manifest)
+	}
+	if err != nil {
+		// Ship everything again the next time
+		s.lock.Lock()
+		s.changed = true
+		s.lock.Unlock()
+		s.err = err
+	}
+	return err
+}
+
+// shipperUi adds the messages of a build to the log of a shipper, and
+// tracks the temporary resources it reports.
+type shipperUi struct {
+	packer.Ui
+	shipper *Shipper
+	build   string
+}
+
+func (u *shipperUi) Say(message string) {
+	u.Ui.Say(message)
+	u.shipper.write(message)
+}
+
+func (u *shipperUi) Message(message string) {
+	u.Ui.Message(message)
+	u.shipper.write(message)
+}
+
+func (u *shipperUi) Error(message string) {
+	u.Ui.Error(message)
+	u.shipper.write(message)
+}
+
+func (u *shipperUi) Machine(category string, args ...string) {
+	u.Ui.Machine(category, args...)
+
+	// The builder uis prefix the category with the name of the build
+	if idx := strings.Index(category, ","); idx > -1 {
+		category = category[idx+1:]
+	}
+	if category == "temporary-resource" {
+		u.shipper.resource(u.build, args)
+	}
+}
@@ -0,0 +1,91 @@
+package remotelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+type memoryStore struct {
+	sync.Mutex
+	objects map[string][]byte
+	err     error
+}
+
+func (s *memoryStore) Put(name, contentType string, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.objects[name] = data
+	return nil
+}
+
+func TestShipper(t *testing.T) {
+	store := &memoryStore{objects: make(map[string][]byte)}
+	s := NewShipper(store, "uuid", []string{"foo", "bar"})
+
+	ui := s.Ui("foo", packer.TestUi(t))
+	s.BuildStarted("foo")
+	ui.Say("==> foo: Creating temporary keypair")
+	ui.Machine("foo,temporary-resource", "created", "key-pair", "packer_1", "us-east-1")
+	ui.Machine("foo,temporary-resource", "created", "instance", "i-1", "us-east-1")
+	ui.Machine("foo,temporary-resource", "deleted", "key-pair", "packer_1", "us-east-1")
+	s.BuildFinished("foo", nil, fmt.Errorf("bad"))
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if log := string(store.objects["uuid/build.log"]); !strings.Contains(log, "==> foo: Creating temporary keypair\n") {
+		t.Fatalf("bad log: %s", log)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(store.objects["uuid/manifest.json"], &manifest); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if manifest.Finished == nil || len(manifest.Builds) != 2 {
+		t.Fatalf("bad manifest: %#v", manifest)
+	}
+	foo, bar := manifest.Builds[0], manifest.Builds[1]
+	if foo.Status != StatusFailed || foo.Error != "bad" {
+		t.Fatalf("bad status: %s %s", foo.Status, foo.Error)
+	}
+	if len(foo.Resources) != 1 || foo.Resources[0].Id != "i-1" || foo.Resources[0].Region != "us-east-1" {
+		t.Fatalf("bad resources: %#v", foo.Resources)
+	}
+	if bar.Status != StatusPending {
+		t.Fatalf("bad status: %s", bar.Status)
+	}
+}
+
+func TestShipper_error(t *testing.T) {
+	store := &memoryStore{objects: make(map[string][]byte), err: fmt.Errorf("denied")}
+	s := NewShipper(store, "uuid", []string{"foo"})
+	if err := s.Close(); err == nil {
+		t.Fatal("should error")
+	}
+
+	// What failed to ship is shipped the next time
+	store.err = nil
+	if err := s.ship(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := store.objects["uuid/manifest.json"]; !ok {
+		t.Fatal("should ship the manifest")
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	for _, u := range []string{"ftp://bucket/logs", "s3:///logs", "azblob://container"} {
+		if _, err := NewStore(u); err == nil {
+			t.Fatalf("should error: %s", u)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package remotelog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"golang.org/x/oauth2/google"
+)
+
+// A Store keeps the objects the logs are shipped as, overwriting them as
+// they grow.
+type Store interface {
+	Put(name string, contentType string, data []byte) error
+}
+
+// NewStore returns the store for the URL of a bucket or container, and
+// the prefix of the objects in it:
+//
+//   s3://bucket/prefix          the AWS credentials of the environment
+//   gs://bucket/prefix          the Google application default credentials
+//   azblob://container/prefix   AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_KEY
+//
+// The region of an S3 bucket is looked up unless given with ?region=.
+func NewStore(rawurl string) (Store, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s doesn't name a bucket or container", rawurl)
+	}
+	prefix := strings.Trim(u.Path, "/")
+
+	switch u.Scheme {
+	case "s3":
+		return newS3Store(u.Host, prefix, u.Query().Get("region"))
+	case "gs":
+		return newGCSStore(u.Host, prefix)
+	case "azblob":
+		return newAzureStore(u.Host, prefix)
+	default:
+		return nil, fmt.Errorf("%s: the scheme must be one of s3, gs or azblob", rawurl)
+	}
+}
+
+type s3Store struct {
+	svc    *s3.S3
+	bucket string
+	prefix string
+}
+
+func newS3Store(bucket, prefix, region string) (Store, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		region, err = s3manager.GetBucketRegion(aws.BackgroundContext(), sess, bucket, "us-east-1")
+		if err != nil {
+			return nil, fmt.Errorf("Error finding the region of bucket %s: %s", bucket, err)
+		}
+	}
+
+	return &s3Store{
+		svc:    s3.New(sess, aws.NewConfig().WithRegion(region)),
+		bucket: bucket,
+		prefix: prefix,
+	}, nil
+}
+
+func (s *s3Store) Put(name, contentType string, data []byte) error {
+	_, err := s.svc.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(path.Join(s.prefix, name)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+// gcsStore uploads with the JSON API of Cloud Storage, there is no client
+// for it among the Google APIs Packer uses.
+type gcsStore struct {
+	client *http.Client
+	bucket string
+	prefix string
+}
+
+func newGCSStore(bucket, prefix string) (Store, error) {
+	client, err := google.DefaultClient(context.Background(),
+		"https://www.googleapis.com/auth/devstorage.read_write")
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *gcsStore) Put(name, contentType string, data []byte) error {
+	u := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		url.PathEscape(s.bucket), url.QueryEscape(path.Join(s.prefix, name)))
+	resp, err := s.client.Post(u, contentType, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Error uploading to gs://%s: %s: %s", s.bucket, resp.Status, body)
+	}
+	return nil
+}
+
+type azureStore struct {
+	container *storage.Container
+	prefix    string
+}
+
+func newAzureStore(container, prefix string) (Store, error) {
+	account, key := os.Getenv("AZURE_STORAGE_ACCOUNT"), os.Getenv("AZURE_STORAGE_KEY")
+	if account == "" || key == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_KEY must be set to ship logs to Azure")
+	}
+	client, err := storage.NewBasicClient(account, key)
+	if err != nil {
+		return nil, err
+	}
+	blobs := client.GetBlobService()
+	return &azureStore{container: blobs.GetContainerReference(container), prefix: prefix}, nil
+}
+
+func (s *azureStore) Put(name, contentType string, data []byte) error {
+	blob := s.container.GetBlobReference(path.Join(s.prefix, name))
+	blob.Properties.ContentType = contentType
+	return blob.CreateBlockBlobFromReader(bytes.NewReader(data), nil)
+}
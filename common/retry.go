@@ -23,6 +23,8 @@ If maxInterval == 0, maxInterval is set to +Infinity.
 Intervals are in seconds.
 Returns an error if initial > max intervals, if retries are exhausted, or if the passed function returns
 an error.
+New code should use the helper/retry package, which is jittered and can be
+cancelled.
 */
 func Retry(initialInterval float64, maxInterval float64, numTries uint, function RetryableFunc) error {
 	if maxInterval == 0 {
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// verifySignature returns an error unless the detached GPG signature of the
+// data was made by one of the public keys in the files. The keys are
+// imported in a keyring of its own, so only they are trusted.
+func verifySignature(data, signature []byte, keys []string) error {
+	dir, err := ioutil.TempDir("", "packer-gpg")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	home := filepath.Join(dir, "gnupg")
+	if err := os.Mkdir(home, 0700); err != nil {
+		return err
+	}
+	dataPath := filepath.Join(dir, "data")
+	if err := ioutil.WriteFile(dataPath, data, 0600); err != nil {
+		return err
+	}
+	signaturePath := filepath.Join(dir, "data.sig")
+	if err := ioutil.WriteFile(signaturePath, signature, 0600); err != nil {
+		return err
+	}
+
+	args := append([]string{"--homedir", home, "--batch", "--import"}, keys...)
+	if _, err := runGPG(args...); err != nil {
+		return fmt.Errorf("Error importing keys: %s", err)
+	}
+
+	status, err := runGPG("--homedir", home, "--batch", "--status-fd", "1",
+		"--verify", signaturePath, dataPath)
+	if err != nil {
+		return fmt.Errorf("Bad signature: %s", err)
+	}
+	if !strings.Contains(status, "[GNUPG:] VALIDSIG ") {
+		return fmt.Errorf("Bad signature: %s", status)
+	}
+	return nil
+}
+
+// runGPG runs gpg with the arguments and returns its output.
+func runGPG(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("gpg", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	log.Printf("Executing: gpg %s", strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,214 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// isoTools are the commands StepCreateCD creates ISO images with, in the
+// order they are looked up on the PATH.
+var isoTools = []string{"xorriso", "mkisofs", "genisoimage", "hdiutil", "oscdimg"}
+
+// StepCreateCD will create an ISO image with the given files and content,
+// to be attached as a CD to the machine, such as a cloud-init NoCloud seed.
+//
+// Produces:
+//   cd_path string - The path to the ISO image
+type StepCreateCD struct {
+	Files   []string
+	Content map[string]string
+	Label   string
+
+	rootDir string
+}
+
+func (s *StepCreateCD) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	if len(s.Files) == 0 && len(s.Content) == 0 {
+		log.Println("No CD files specified. CD disk will not be made.")
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	ui.Say("Creating CD disk...")
+
+	tool, err := lookupISOTool()
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	rootDir, err := ioutil.TempDir("", "packer-cd")
+	if err != nil {
+		err := fmt.Errorf("Error creating temporary directory for CD: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.rootDir = rootDir
+
+	// The files are staged in a directory the ISO image is made of
+	contentDir := filepath.Join(rootDir, "content")
+	if err := s.stage(contentDir); err != nil {
+		err := fmt.Errorf("Error creating CD: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	label := s.Label
+	if label == "" {
+		label = DefaultCDLabel
+	}
+	cdPath := filepath.Join(rootDir, "packer.iso")
+	log.Printf("CD path: %s", cdPath)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(tool, isoToolArgs(tool, label, contentDir, cdPath)...)
+	cmd.Stdout = &stderr
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		err := fmt.Errorf("Error creating CD with %s: %s\n\n%s",
+			tool, err, strings.TrimSpace(stderr.String()))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("cd_path", cdPath)
+	return multistep.ActionContinue
+}
+
+func (s *StepCreateCD) Cleanup(multistep.StateBag) {
+	if s.rootDir != "" {
+		log.Printf("Deleting CD disk: %s", s.rootDir)
+		os.RemoveAll(s.rootDir)
+	}
+}
+
+// stage copies the files, and writes the content, in the directory. Files
+// are copied at its root, and directories with their contents under their
+// name.
+func (s *StepCreateCD) stage(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, pattern := range s.Files {
+		paths := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return err
+			}
+			if len(matches) == 0 {
+				log.Printf("No CD files matching %s", pattern)
+			}
+			paths = matches
+		}
+
+		for _, path := range paths {
+			log.Printf("Adding to CD: %s", path)
+			if err := copyToCD(path, filepath.Join(dir, filepath.Base(path))); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Content is written after the files, in a deterministic order, so it
+	// overrides any file with the same path.
+	var paths []string
+	for path := range s.Content {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		target := cdContentPath(path)
+		if target == "" {
+			return fmt.Errorf("bad CD content path '%s'", path)
+		}
+		target = filepath.Join(dir, target)
+		log.Printf("Writing CD content: %s", path)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(target, []byte(s.Content[path]), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyToCD copies the file, or the directory with its contents, to dst.
+func copyToCD(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// lookupISOTool returns the first command creating ISO images found on the
+// PATH.
+func lookupISOTool() (string, error) {
+	for _, tool := range isoTools {
+		if _, err := exec.LookPath(tool); err == nil {
+			return tool, nil
+		}
+	}
+	return "", fmt.Errorf("Error creating CD: none of %s was found on the PATH",
+		strings.Join(isoTools, ", "))
+}
+
+// isoToolArgs are the arguments to the tool creating an ISO image at output
+// with the files in dir, with Joliet and Rock Ridge extensions so that long
+// and lowercase file names, such as those cloud-init expects, are kept.
+func isoToolArgs(tool string, label string, dir string, output string) []string {
+	switch tool {
+	case "xorriso":
+		return []string{"-as", "mkisofs", "-output", output, "-volid", label, "-joliet", "-rock", dir}
+	case "hdiutil":
+		return []string{"makehybrid", "-o", output, "-hfs", "-joliet", "-iso", "-default-volume-name", label, dir}
+	case "oscdimg":
+		return []string{"-j1", "-o", "-m", "-l" + label, dir, output}
+	default:
+		return []string{"-output", output, "-volid", label, "-joliet", "-rock", dir}
+	}
+}
@@ -0,0 +1,135 @@
+package common
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepCreateCD_Impl(t *testing.T) {
+	var raw interface{}
+	raw = new(StepCreateCD)
+	if _, ok := raw.(multistep.Step); !ok {
+		t.Fatalf("StepCreateCD should be a step")
+	}
+}
+
+func TestStepCreateCD_stage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	step := &StepCreateCD{
+		Files: []string{
+			filepath.Join(TestFixtures, "floppies", "bar.bat"),
+			filepath.Join(TestFixtures, "floppy-hier", "test-0"),
+			filepath.Join(TestFixtures, "floppies", "f*.ps1"),
+		},
+		Content: map[string]string{
+			"meta-data":         "instance-id: packer\n",
+			"/openstack/latest": "{}",
+			"bar.bat":           "echo override",
+		},
+	}
+	if err := step.stage(dir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var files []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	expected := []string{
+		"bar.bat",
+		"foo.ps1",
+		"meta-data",
+		"openstack/latest",
+		"test-0/file1",
+		"test-0/file2",
+		"test-0/file3",
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "bar.bat"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(content) != "echo override" {
+		t.Fatalf("bad: %s", content)
+	}
+
+	// Bad
+	step = &StepCreateCD{Content: map[string]string{"../escape": ""}}
+	if err := step.stage(dir); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepCreateCD(t *testing.T) {
+	if _, err := lookupISOTool(); err != nil {
+		t.Skip(err)
+	}
+
+	state := testStepCreateFloppyState(t)
+	step := &StepCreateCD{
+		Content: map[string]string{
+			"user-data": "#cloud-config\n",
+			"meta-data": "instance-id: packer\n",
+		},
+		Label: "cidata",
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatalf("state should be ok")
+	}
+
+	cdPath := state.Get("cd_path").(string)
+	if _, err := os.Stat(cdPath); err != nil {
+		t.Fatalf("file not found: %s", cdPath)
+	}
+
+	step.Cleanup(state)
+	if _, err := os.Stat(cdPath); err == nil {
+		t.Fatalf("file found: %s", cdPath)
+	}
+}
+
+func TestStepCreateCD_noFiles(t *testing.T) {
+	state := testStepCreateFloppyState(t)
+	step := new(StepCreateCD)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("cd_path"); ok {
+		t.Fatal("should not have a CD")
+	}
+}
+
+func TestISOToolArgs(t *testing.T) {
+	args := isoToolArgs("genisoimage", "cidata", "dir", "out.iso")
+	expected := []string{"-output", "out.iso", "-volid", "cidata", "-joliet", "-rock", "dir"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	args = isoToolArgs("oscdimg", "cidata", "dir", "out.iso")
+	expected = []string{"-j1", "-o", "-m", "-lcidata", "dir", "out.iso"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
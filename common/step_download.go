@@ -37,9 +37,14 @@ type StepDownload struct {
 	// cache directory.
 	TargetPath string
 
-	// A list of URLs to attempt to download this thing.
+	// A list of URLs to attempt to download this thing. With a checksum,
+	// they are mirrors of the same file, which is downloaded from the next
+	// one where the previous one failed.
 	Url []string
 
+	// The number of parts of the file to download in parallel.
+	Chunks int
+
 	// Extension is the extension to force for the file that is downloaded.
 	// Some systems require a certain extension. If this isn't set, the
 	// extension on the URL is used. Otherwise, this will be forced
@@ -70,7 +75,11 @@ func (s *StepDownload) Run(_ context.Context, state multistep.StateBag) multiste
 	var finalPath string
 	for i, url := range s.Url {
 		targetPath := s.TargetPath
-		if targetPath == "" {
+		if targetPath == "" && checksum != nil && i > 0 {
+			// Mirrors share the file, to resume what the previous ones
+			// downloaded.
+			targetPath = downloadConfigs[0].TargetPath
+		} else if targetPath == "" {
 			// Determine a cache key. This is normally just the URL but
 			// if we force a certain extension we hash the URL and add
 			// the extension to force it.
@@ -93,9 +102,15 @@ func (s *StepDownload) Run(_ context.Context, state multistep.StateBag) multiste
 			Hash:       HashForType(s.ChecksumType),
 			Checksum:   checksum,
 			UserAgent:  useragent.String(),
+			Chunks:     s.Chunks,
 		}
 		downloadConfigs[i] = config
 
+		// The file was already checked for a previous URL
+		if i > 0 && targetPath == downloadConfigs[i-1].TargetPath {
+			continue
+		}
+
 		if match, _ := NewDownloadClient(config).VerifyChecksum(config.TargetPath); match {
 			ui.Message(fmt.Sprintf("Found already downloaded, initial checksum matched, no download needed: %s", url))
 			finalPath = config.TargetPath
@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"math/rand"
@@ -11,6 +12,7 @@ import (
 	"github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
 )
 
 // This step creates and runs the HTTP server that is serving files from the
@@ -27,6 +29,21 @@ type StepHTTPServer struct {
 	HTTPPortMin uint
 	HTTPPortMax uint
 
+	// Serve over HTTPS, with the certificate and key in the files, or a
+	// self-signed certificate generated for the build if they're empty.
+	TLS         bool
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Require basic authentication with the credentials, unless empty.
+	Username string
+	Password string
+
+	// The patterns of the paths of the files rendered as templates with
+	// the context.
+	Templates []string
+	Ctx       interpolate.Context
+
 	l net.Listener
 }
 
@@ -61,11 +78,22 @@ func (s *StepHTTPServer) Run(_ context.Context, state multistep.StateBag) multis
 		}
 	}
 
-	ui.Say(fmt.Sprintf("Starting HTTP server on port %d", httpPort))
+	if s.TLS {
+		cert, err := s.certificate()
+		if err != nil {
+			err := fmt.Errorf("Error setting up TLS for the HTTP server: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.l = tls.NewListener(s.l, &tls.Config{Certificates: []tls.Certificate{cert}})
+		ui.Say(fmt.Sprintf("Starting HTTPS server on port %d", httpPort))
+	} else {
+		ui.Say(fmt.Sprintf("Starting HTTP server on port %d", httpPort))
+	}
 
 	// Start the HTTP server and run it in the background
-	fileServer := http.FileServer(http.Dir(s.HTTPDir))
-	server := &http.Server{Addr: httpAddr, Handler: fileServer}
+	server := &http.Server{Addr: httpAddr, Handler: s.handler(httpPort)}
 	go server.Serve(s.l)
 
 	// Save the address into the state so it can be accessed in the future
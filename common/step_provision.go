@@ -9,12 +9,24 @@ import (
 	"github.com/hashicorp/packer/packer"
 )
 
-// StepProvision runs the provisioners.
+// StepProvision runs the provisioners. The name resolution overrides of
+// the communicator are applied beforehand and reverted afterwards. When
+// the builder exports a delta layer, the root filesystem is recorded
+// before anything else and the layer is written last. When the builder
+// saves checkpoints, the provisioners run from the last one and a new one
+// is saved after each provisioner with a key. The machine the builder
+// identified is given to the provisioners.
 //
 // Uses:
-//   communicator packer.Communicator
-//   hook         packer.Hook
-//   ui           packer.Ui
+//   build_dns_servers     []string (optional)
+//   build_hosts           map[string]string (optional)
+//   delta_layer_exclude   []string (optional)
+//   delta_layer_path      string (optional)
+//   machine               *packer.Machine (optional)
+//   provision_checkpoints *ProvisionCheckpoints (optional)
+//   communicator          packer.Communicator
+//   hook                  packer.Hook
+//   ui                    packer.Ui
 //
 // Produces:
 //   <nothing>
@@ -33,17 +45,58 @@ func (s *StepProvision) Run(_ context.Context, state multistep.StateBag) multist
 	hook := state.Get("hook").(packer.Hook)
 	ui := state.Get("ui").(packer.Ui)
 
+	// Record the root filesystem the delta layer is the changes of
+	delta := deltaLayerFromState(state)
+	if delta != nil {
+		if err := delta.record(ui, comm); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	// Override name resolution on the machine while it is provisioned
+	names := nameResolutionFromState(state)
+	if names != nil {
+		if err := names.apply(ui, comm); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	// Run the provisioner in a goroutine so we can continually check
 	// for cancellations...
 	log.Println("Running the provision hook")
+	checkpoints := provisionCheckpointsFromState(state)
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- hook.Run(packer.HookProvision, ui, comm, nil)
+		if checkpoints != nil {
+			errCh <- checkpoints.run(state, hook, ui, comm)
+			return
+		}
+		var data interface{}
+		if machine, ok := state.Get("machine").(*packer.Machine); ok {
+			data = machine
+		}
+		errCh <- hook.Run(packer.HookProvision, ui, comm, data)
 	}()
 
 	for {
 		select {
 		case err := <-errCh:
+			if names != nil {
+				if revertErr := names.revert(ui, comm); revertErr != nil {
+					if err != nil {
+						log.Printf("[ERROR] %s", revertErr)
+					} else {
+						err = revertErr
+					}
+				}
+			}
+			if err == nil && delta != nil {
+				err = delta.export(ui, comm)
+			}
 			if err != nil {
 				state.Put("error", err)
 				return multistep.ActionHalt
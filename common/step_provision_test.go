@@ -1,9 +1,14 @@
 package common
 
 import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
 )
 
 func TestStepProvision_Impl(t *testing.T) {
@@ -13,3 +18,89 @@ func TestStepProvision_Impl(t *testing.T) {
 		t.Fatalf("provision should be a step")
 	}
 }
+
+func TestStepProvision_nameResolution(t *testing.T) {
+	comm := new(packer.MockCommunicator)
+	hook := &packer.MockHook{
+		RunFunc: func() error {
+			if !strings.Contains(comm.StartCmd.Command, "10.0.0.5 mirror.internal "+buildHostsMarker) {
+				t.Errorf("hosts should be overridden: %s", comm.StartCmd.Command)
+			}
+			return nil
+		},
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("communicator", comm)
+	state.Put("hook", hook)
+	state.Put("ui", &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)})
+	state.Put("build_hosts", map[string]string{"mirror.internal": "10.0.0.5"})
+	state.Put("build_dns_servers", []string{"10.0.0.2"})
+
+	step := new(StepProvision)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+	if !hook.RunCalled {
+		t.Fatal("provision hook should be run")
+	}
+
+	script := strings.Replace(comm.StartCmd.Command, `'"'"'`, "'", -1)
+	for _, part := range []string{"grep -v ' " + buildHostsMarker, "cat " + resolvConfBackup + " > /etc/resolv.conf"} {
+		if !strings.Contains(script, part) {
+			t.Fatalf("overrides should be reverted, %q not in %s", part, script)
+		}
+	}
+}
+
+func TestStepProvision_checkpoints(t *testing.T) {
+	var ranges []packer.ProvisionRange
+	hook := new(packer.MockHook)
+	hook.RunFunc = func() error {
+		ranges = append(ranges, *hook.RunData.(*packer.ProvisionRange))
+		return nil
+	}
+
+	var saved []string
+	state := new(multistep.BasicStateBag)
+	state.Put("communicator", new(packer.MockCommunicator))
+	state.Put("hook", hook)
+	state.Put("ui", &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)})
+	state.Put("provision_checkpoints", &ProvisionCheckpoints{
+		Keys:  []string{"a", "", "c", "", ""},
+		Start: 1,
+		Save: func(_ packer.Ui, key string) error {
+			saved = append(saved, key)
+			return nil
+		},
+	})
+
+	machine := &packer.Machine{Cloud: "amazon", ID: "i-12345"}
+	state.Put("machine", machine)
+
+	step := new(StepProvision)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+
+	expected := []packer.ProvisionRange{{From: 1, To: 3, Machine: machine}, {From: 3, To: 5, Machine: machine}}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Fatalf("bad: %#v", ranges)
+	}
+	if !reflect.DeepEqual(saved, []string{"c"}) {
+		t.Fatalf("bad: %#v", saved)
+	}
+}
+
+func TestLastCheckpoint(t *testing.T) {
+	exists := func(key string) (bool, error) {
+		return key == "a" || key == "c", nil
+	}
+
+	if n, err := LastCheckpoint([]string{"a", "", "c", "d"}, exists); err != nil || n != 3 {
+		t.Fatalf("bad: %d %s", n, err)
+	}
+	if n, err := LastCheckpoint([]string{"b", "d"}, exists); err != nil || n != 0 {
+		t.Fatalf("bad: %d %s", n, err)
+	}
+}
@@ -1,6 +1,7 @@
 package winrm
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -13,7 +14,6 @@ import (
 
 	"github.com/hashicorp/packer/packer"
 	"github.com/masterzen/winrm"
-	"github.com/packer-community/winrmcp/winrmcp"
 )
 
 // Communicator represents the WinRM communicator
@@ -21,6 +21,7 @@ type Communicator struct {
 	config   *Config
 	client   *winrm.Client
 	endpoint *winrm.Endpoint
+	shells   *shellPool
 }
 
 // New creates a new communicator implementation over WinRM.
@@ -53,45 +54,38 @@ func New(config *Config) (*Communicator, error) {
 		return nil, err
 	}
 
-	// Create the shell to verify the connection
+	// Create the shell to verify the connection, it is kept to run the
+	// first commands in.
 	log.Printf("[DEBUG] connecting to remote shell using WinRM")
-	shell, err := client.CreateShell()
+	shells := newShellPool(client, config.MaxOperationsPerShell)
+	shell, _, err := shells.get()
 	if err != nil {
 		log.Printf("[ERROR] connection error: %s", err)
 		return nil, err
 	}
-
-	if err := shell.Close(); err != nil {
-		log.Printf("[ERROR] error closing connection: %s", err)
-		return nil, err
-	}
+	shells.idle = append(shells.idle, shell)
 
 	return &Communicator{
 		config:   config,
 		client:   client,
 		endpoint: endpoint,
+		shells:   shells,
 	}, nil
 }
 
 // Start implementation of communicator.Communicator interface
 func (c *Communicator) Start(rc *packer.RemoteCmd) error {
-	shell, err := c.client.CreateShell()
-	if err != nil {
-		return err
-	}
-
 	log.Printf("[INFO] starting remote command: %s", rc.Command)
-	cmd, err := shell.Execute(rc.Command)
+	shell, cmd, err := c.shells.execute(rc.Command)
 	if err != nil {
 		return err
 	}
 
-	go runCommand(shell, cmd, rc)
+	go c.runCommand(shell, cmd, rc)
 	return nil
 }
 
-func runCommand(shell *winrm.Shell, cmd *winrm.Command, rc *packer.RemoteCmd) {
-	defer shell.Close()
+func (c *Communicator) runCommand(shell *pooledShell, cmd *winrm.Command, rc *packer.RemoteCmd) {
 	var wg sync.WaitGroup
 
 	copyFunc := func(w io.Writer, r io.Reader) {
@@ -115,6 +109,7 @@ func runCommand(shell *winrm.Shell, cmd *winrm.Command, rc *packer.RemoteCmd) {
 
 	cmd.Wait()
 	wg.Wait()
+	c.shells.release(shell, cmd)
 
 	code := cmd.ExitCode()
 	log.Printf("[INFO] command '%s' exited with code: %d", rc.Command, code)
@@ -123,27 +118,19 @@ func runCommand(shell *winrm.Shell, cmd *winrm.Command, rc *packer.RemoteCmd) {
 
 // Upload implementation of communicator.Communicator interface
 func (c *Communicator) Upload(path string, input io.Reader, fi *os.FileInfo) error {
-	wcp, err := c.newCopyClient()
-	if err != nil {
-		return err
-	}
-
-	if err != nil {
-		return fmt.Errorf("Was unable to create winrm client: %s", err)
-	}
-	client, err := c.newWinRMClient()
-	stdout, _, _, err := client.RunWithString(fmt.Sprintf("powershell -Command \"(Get-Item %s) -is [System.IO.DirectoryInfo]\"", path), "")
+	var stdout bytes.Buffer
+	_, err := c.shells.run(fmt.Sprintf("powershell -Command \"(Get-Item %s) -is [System.IO.DirectoryInfo]\"", path), &stdout, ioutil.Discard)
 	if err != nil {
 		return fmt.Errorf("Couldn't determine whether destination was a folder or file: %s", err)
 	}
-	if strings.Contains(stdout, "True") {
+	if strings.Contains(stdout.String(), "True") {
 		// The path exists and is a directory.
 		// Upload file into the directory instead of overwriting.
 		path = filepath.Join(path, filepath.Base((*fi).Name()))
 	}
 
 	log.Printf("Uploading file to '%s'", path)
-	return wcp.Write(path, input)
+	return c.upload(path, input)
 }
 
 // UploadDir implementation of communicator.Communicator interface
@@ -152,25 +139,36 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 		dst = fmt.Sprintf("%s\\%s", dst, filepath.Base(src))
 	}
 	log.Printf("Uploading dir '%s' to '%s'", src, dst)
-	wcp, err := c.newCopyClient()
-	if err != nil {
-		return err
-	}
-	return wcp.Copy(src, dst)
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Ignore dir entries and OS X special hidden file
+		if info.IsDir() || info.Name() == ".DS_Store" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("Couldn't read file %s: %v", path, err)
+		}
+		defer f.Close()
+
+		return c.upload(filepath.Join(dst, rel), f)
+	})
 }
 
 func (c *Communicator) Download(src string, dst io.Writer) error {
-	client, err := c.newWinRMClient()
-	if err != nil {
-		return err
-	}
-
 	encodeScript := `$file=[System.IO.File]::ReadAllBytes("%s"); Write-Output $([System.Convert]::ToBase64String($file))`
 
 	base64DecodePipe := &Base64Pipe{w: dst}
 
 	cmd := winrm.Powershell(fmt.Sprintf(encodeScript, src))
-	_, err = client.Run(cmd, base64DecodePipe, ioutil.Discard)
+	_, err := c.shells.run(cmd, base64DecodePipe, ioutil.Discard)
 
 	return err
 }
@@ -179,58 +177,6 @@ func (c *Communicator) DownloadDir(src string, dst string, exclude []string) err
 	return fmt.Errorf("WinRM doesn't support download dir.")
 }
 
-func (c *Communicator) getClientConfig() *winrmcp.Config {
-	return &winrmcp.Config{
-		Auth: winrmcp.Auth{
-			User:     c.config.Username,
-			Password: c.config.Password,
-		},
-		Https:                 c.config.Https,
-		Insecure:              c.config.Insecure,
-		OperationTimeout:      c.config.Timeout,
-		MaxOperationsPerShell: 15, // lowest common denominator
-		TransportDecorator:    c.config.TransportDecorator,
-	}
-}
-
-func (c *Communicator) newCopyClient() (*winrmcp.Winrmcp, error) {
-	addr := fmt.Sprintf("%s:%d", c.endpoint.Host, c.endpoint.Port)
-	clientConfig := c.getClientConfig()
-	return winrmcp.New(addr, clientConfig)
-}
-
-func (c *Communicator) newWinRMClient() (*winrm.Client, error) {
-	conf := c.getClientConfig()
-
-	// Shamelessly borrowed from the winrmcp client to ensure
-	// that the client is configured using the same defaulting behaviors that
-	// winrmcp uses even we we aren't using winrmcp. This ensures similar
-	// behavior between upload, download, and copy functions. We can't use the
-	// one generated by winrmcp because it isn't exported.
-	var endpoint *winrm.Endpoint
-	endpoint = &winrm.Endpoint{
-		Host:          c.endpoint.Host,
-		Port:          c.endpoint.Port,
-		HTTPS:         conf.Https,
-		Insecure:      conf.Insecure,
-		TLSServerName: conf.TLSServerName,
-		CACert:        conf.CACertBytes,
-		Timeout:       conf.ConnectTimeout,
-	}
-	params := winrm.NewParameters(
-		winrm.DefaultParameters.Timeout,
-		winrm.DefaultParameters.Locale,
-		winrm.DefaultParameters.EnvelopeSize,
-	)
-
-	params.TransportDecorator = conf.TransportDecorator
-	params.Timeout = "PT3M"
-
-	client, err := winrm.NewClientWithParameters(
-		endpoint, conf.Auth.User, conf.Auth.Password, params)
-	return client, err
-}
-
 type Base64Pipe struct {
 	w io.Writer // underlying writer (file, buffer)
 }
@@ -16,4 +16,10 @@ type Config struct {
 	Https              bool
 	Insecure           bool
 	TransportDecorator func() winrm.Transporter
+
+	// MaxOperationsPerShell is how many commands run in a shell before it
+	// is closed, 15 when unset. UploadStreams is how many chunks of a file
+	// are uploaded in parallel, 1 when unset.
+	MaxOperationsPerShell int
+	UploadStreams         int
 }
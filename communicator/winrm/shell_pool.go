@@ -0,0 +1,117 @@
+package winrm
+
+import (
+	"io"
+	"log"
+	"sync"
+
+	"github.com/masterzen/winrm"
+)
+
+// pooledShell is a remote shell along with the number of commands it ran.
+type pooledShell struct {
+	*winrm.Shell
+	operations int
+}
+
+// shellPool keeps the remote shells commands ran in open to run the next
+// commands in, rather than opening a shell for every command. A shell is
+// closed once it ran as many commands as the server lets it run.
+type shellPool struct {
+	client        *winrm.Client
+	maxOperations int
+
+	lock sync.Mutex
+	idle []*pooledShell
+}
+
+func newShellPool(client *winrm.Client, maxOperations int) *shellPool {
+	if maxOperations <= 0 {
+		maxOperations = 15 // lowest common denominator
+	}
+	return &shellPool{
+		client:        client,
+		maxOperations: maxOperations,
+	}
+}
+
+// get returns an idle shell, or a new one when there is none.
+func (p *shellPool) get() (*pooledShell, bool, error) {
+	p.lock.Lock()
+	if n := len(p.idle); n > 0 {
+		shell := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.lock.Unlock()
+		return shell, true, nil
+	}
+	p.lock.Unlock()
+
+	shell, err := p.client.CreateShell()
+	if err != nil {
+		return nil, false, err
+	}
+	return &pooledShell{Shell: shell}, false, nil
+}
+
+// release closes the completed command and gives its shell back to the
+// pool, unless it can't run more commands.
+func (p *shellPool) release(shell *pooledShell, cmd *winrm.Command) {
+	if err := cmd.Close(); err != nil || shell.operations >= p.maxOperations {
+		shell.Close()
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.idle = append(p.idle, shell)
+}
+
+// execute starts the command in a shell of the pool. A shell that was kept
+// open may be gone, for instance when the machine restarted, in which case
+// the command is started in a new shell.
+func (p *shellPool) execute(command string) (*pooledShell, *winrm.Command, error) {
+	for {
+		shell, reused, err := p.get()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		cmd, err := shell.Execute(command)
+		if err != nil {
+			shell.Close()
+			if reused {
+				log.Printf("[DEBUG] kept WinRM shell is gone, opening a new one: %s", err)
+				continue
+			}
+			return nil, nil, err
+		}
+
+		shell.operations++
+		return shell, cmd, nil
+	}
+}
+
+// run runs the command in a shell of the pool, copying its output to
+// stdout and stderr, and returns its exit code.
+func (p *shellPool) run(command string, stdout, stderr io.Writer) (int, error) {
+	shell, cmd, err := p.execute(command)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	copyFunc := func(w io.Writer, r io.Reader) {
+		defer wg.Done()
+		io.Copy(w, r)
+	}
+
+	wg.Add(2)
+	go copyFunc(stdout, cmd.Stdout)
+	go copyFunc(stderr, cmd.Stderr)
+
+	cmd.Wait()
+	wg.Wait()
+	p.release(shell, cmd)
+
+	return cmd.ExitCode(), nil
+}
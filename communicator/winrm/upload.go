@@ -0,0 +1,170 @@
+package winrm
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/masterzen/winrm"
+)
+
+// uploadChunkSize is the size of the chunks files are uploaded in. Each
+// chunk is base64 encoded and appended to a file on the remote with echo,
+// which has to fit the 8191 characters cmd.exe allows a command line.
+const uploadChunkSize = 5700
+
+// restoreScript puts the chunks appended to the part files of an upload
+// back together, in the order they were distributed to the parts in, and
+// decompresses them to the destination. It is kept short as it has to fit
+// a command line too once encoded.
+const restoreScript = `$ErrorActionPreference = "Stop"
+$dest = [System.IO.Path]::GetFullPath("%s".Trim("'"))
+New-Item -ItemType Directory -Force -ErrorAction SilentlyContinue -Path ([System.IO.Path]::GetDirectoryName($dest)) | Out-Null
+$paths = @(%s) | ForEach-Object { Join-Path $env:TEMP $_ }
+$gz = Join-Path $env:TEMP "%s.gz"
+$parts = $paths | ForEach-Object { [System.IO.File]::OpenText($_) }
+$writer = [System.IO.File]::Create($gz)
+try {
+	$done = $false
+	while (-not $done) {
+		foreach ($part in $parts) {
+			$line = $part.ReadLine()
+			if ($line -eq $null) { $done = $true; break }
+			$bytes = [System.Convert]::FromBase64String($line)
+			$writer.Write($bytes, 0, $bytes.Length)
+		}
+	}
+} finally {
+	$writer.Close()
+	$parts | ForEach-Object { $_.Close() }
+}
+$in = New-Object System.IO.Compression.GZipStream([System.IO.File]::OpenRead($gz), [System.IO.Compression.CompressionMode]::Decompress)
+$out = [System.IO.File]::Create($dest)
+try {
+	$buffer = New-Object byte[] 65536
+	while (($n = $in.Read($buffer, 0, $buffer.Length)) -gt 0) { $out.Write($buffer, 0, $n) }
+} finally {
+	$in.Close()
+	$out.Close()
+}
+Remove-Item -Force $gz
+$paths | Remove-Item -Force`
+
+// upload copies the input to the path on the remote. The input is
+// compressed, cut in chunks, and the chunks are spread over the upload
+// streams, which each append theirs to a part file of their own in
+// parallel. The part files are then put back together on the remote.
+func (c *Communicator) upload(path string, input io.Reader) error {
+	name := fmt.Sprintf("packer-upload-%s", uuid.TimeOrderedUUID())
+	streams := c.config.UploadStreams
+	if streams <= 0 {
+		streams = 1
+	}
+
+	compressed, w := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(w)
+		_, err := io.Copy(gz, input)
+		if err == nil {
+			err = gz.Close()
+		}
+		w.CloseWithError(err)
+	}()
+	defer compressed.Close()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var uploadErr error
+	failed := make(chan struct{})
+	fail := func(err error) {
+		once.Do(func() {
+			uploadErr = err
+			close(failed)
+		})
+	}
+
+	chunks := make([]chan string, streams)
+	for i := range chunks {
+		chunks[i] = make(chan string, 1)
+		part := fmt.Sprintf("%%TEMP%%\\%s-%d.tmp", name, i)
+
+		wg.Add(1)
+		go func(chunks <-chan string) {
+			defer wg.Done()
+			for content := range chunks {
+				select {
+				case <-failed:
+					continue
+				default:
+				}
+				if err := c.run(fmt.Sprintf("echo %s >> \"%s\"", content, part)); err != nil {
+					fail(fmt.Errorf("upload operation failed: %s", err))
+				}
+			}
+		}(chunks[i])
+	}
+
+	// The part files the chunks were appended to, in order
+	var parts []string
+	buf := make([]byte, uploadChunkSize)
+	for i := 0; ; i++ {
+		n, err := io.ReadFull(compressed, buf)
+		if n > 0 {
+			if i < streams {
+				parts = append(parts, fmt.Sprintf("'%s-%d.tmp'", name, i))
+			}
+			select {
+			case chunks[i%streams] <- base64.StdEncoding.EncodeToString(buf[:n]):
+			case <-failed:
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			fail(err)
+			break
+		}
+	}
+	for _, ch := range chunks {
+		close(ch)
+	}
+	wg.Wait()
+
+	if uploadErr != nil {
+		return fmt.Errorf("Error uploading file to %s: %s", path, uploadErr)
+	}
+
+	log.Printf("[DEBUG] restoring %s from %d part files", path, len(parts))
+	script := fmt.Sprintf(restoreScript, winPath(path), strings.Join(parts, ","), name)
+	if err := c.run(winrm.Powershell(script)); err != nil {
+		return fmt.Errorf("Error restoring file to %s: %s", path, err)
+	}
+	return nil
+}
+
+// run runs the command on the remote, failing unless it exits with 0.
+func (c *Communicator) run(command string) error {
+	code, err := c.shells.run(command, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("command returned code=%d", code)
+	}
+	return nil
+}
+
+// winPath turns the path into a Windows one, quoting it when it has spaces.
+func winPath(path string) string {
+	if strings.Contains(path, " ") {
+		path = fmt.Sprintf("'%s'", strings.Trim(path, "'\""))
+	}
+	return strings.Replace(path, "/", "\\", -1)
+}
@@ -0,0 +1,112 @@
+package winrm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dylanmei/winrmtest"
+)
+
+func TestUpload_Streams(t *testing.T) {
+	wrm := winrmtest.NewRemote()
+	defer wrm.Close()
+
+	// Record the chunks appended to each part file
+	var lock sync.Mutex
+	parts := make(map[string][]string)
+	echo := regexp.MustCompile(`^echo ([A-Za-z0-9+/=]+) >> "(.*)"$`)
+	wrm.CommandFunc(
+		func(text string) bool {
+			m := echo.FindStringSubmatch(text)
+			if m == nil {
+				return false
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			parts[m[2]] = append(parts[m[2]], m[1])
+			return true
+		},
+		func(out, err io.Writer) int {
+			return 0
+		})
+	wrm.CommandFunc(
+		winrmtest.MatchPattern(`^powershell.exe -EncodedCommand .*$`),
+		func(out, err io.Writer) int {
+			return 0
+		})
+
+	c, err := New(&Config{
+		Host:          wrm.Host,
+		Port:          wrm.Port,
+		Username:      "user",
+		Password:      "pass",
+		Timeout:       30 * time.Second,
+		UploadStreams: 3,
+	})
+	if err != nil {
+		t.Fatalf("error creating communicator: %s", err)
+	}
+
+	// Random data doesn't compress, so it takes several chunks
+	payload := make([]byte, 4*uploadChunkSize)
+	rand.New(rand.NewSource(0)).Read(payload)
+	if err := c.upload("C:/Temp/payload.bin", bytes.NewReader(payload)); err != nil {
+		t.Fatalf("error uploading file: %s", err)
+	}
+
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 part files, got %d", len(parts))
+	}
+	var names []string
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Put the chunks back together the way the restore script does
+	var compressed []byte
+	for i := 0; ; i++ {
+		part := parts[names[i%len(names)]]
+		if i/len(names) >= len(part) {
+			break
+		}
+		chunk, err := base64.StdEncoding.DecodeString(part[i/len(names)])
+		if err != nil {
+			t.Fatalf("bad chunk: %s", err)
+		}
+		compressed = append(compressed, chunk...)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("bad compressed content: %s", err)
+	}
+	restored, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("bad compressed content: %s", err)
+	}
+	if !bytes.Equal(restored, payload) {
+		t.Fatalf("restored content differs from the payload")
+	}
+}
+
+func TestWinPath(t *testing.T) {
+	cases := map[string]string{
+		"C:/Temp/packer.cmd":     `C:\Temp\packer.cmd`,
+		"C:/Program Files/a.txt": `'C:\Program Files\a.txt'`,
+	}
+	for path, expected := range cases {
+		if actual := winPath(path); actual != expected {
+			t.Fatalf("bad path for %s: %s", path, actual)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/packer/command"
@@ -27,11 +28,32 @@ type config struct {
 	PluginMinPort              uint
 	PluginMaxPort              uint
 
+	// The defaults of the settings that are otherwise only set through
+	// environment variables, see setEnv.
+	AWSPolling     awsPollingConfig `json:"aws_polling"`
+	CacheDir       string           `json:"cache_directory"`
+	ParallelBuilds int              `json:"parallel_builds"`
+	PluginDir      string           `json:"plugin_directory"`
+	Proxy          proxyConfig      `json:"proxy"`
+	ShipLogs       string           `json:"ship_logs"`
+	TmpDir         string           `json:"tmp_directory"`
+
 	Builders       map[string]string
 	PostProcessors map[string]string `json:"post-processors"`
 	Provisioners   map[string]string
 }
 
+type awsPollingConfig struct {
+	DelaySeconds   int `json:"delay_seconds"`
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+type proxyConfig struct {
+	HTTP    string `json:"http"`
+	HTTPS   string `json:"https"`
+	NoProxy string `json:"no_proxy"`
+}
+
 // Decodes configuration in JSON format from the given io.Reader into
 // the config object pointed to.
 func decodeConfig(r io.Reader, c *config) error {
@@ -39,10 +61,79 @@ func decodeConfig(r io.Reader, c *config) error {
 	return decoder.Decode(c)
 }
 
+// loadFile decodes a config file over the config, so the settings it has
+// override the ones of the files loaded before it. The directories it sets
+// are relative to the directory of the file. A file that doesn't exist is
+// ignored.
+func (c *config) loadFile(path string) error {
+	log.Printf("Attempting to open config file: %s", path)
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		log.Printf("[WARN] Config file doesn't exist: %s", path)
+		return nil
+	}
+	defer f.Close()
+
+	if err := decodeConfig(f, c); err != nil {
+		return fmt.Errorf("Error decoding %s: %s", path, err)
+	}
+
+	for _, dir := range []*string{&c.CacheDir, &c.PluginDir, &c.TmpDir} {
+		if *dir != "" && !filepath.IsAbs(*dir) {
+			*dir = filepath.Join(filepath.Dir(path), *dir)
+		}
+	}
+	return nil
+}
+
+// setEnv sets the environment variables the settings of the config files
+// are defaults for. Variables that are already set take precedence. The
+// plugins inherit the environment, so this is how they get the settings.
+func (c *config) setEnv() {
+	vars := [][]string{
+		{c.CacheDir, "PACKER_CACHE_DIR"},
+		{c.TmpDir, "PACKER_TMP_DIR"},
+		{c.Proxy.HTTP, "HTTP_PROXY", "http_proxy"},
+		{c.Proxy.HTTPS, "HTTPS_PROXY", "https_proxy"},
+		{c.Proxy.NoProxy, "NO_PROXY", "no_proxy"},
+	}
+	if c.AWSPolling.DelaySeconds > 0 {
+		vars = append(vars, []string{
+			strconv.Itoa(c.AWSPolling.DelaySeconds), "AWS_POLL_DELAY_SECONDS"})
+	}
+	if c.AWSPolling.TimeoutSeconds > 0 {
+		vars = append(vars, []string{
+			strconv.Itoa(c.AWSPolling.TimeoutSeconds), "AWS_TIMEOUT_SECONDS"})
+	}
+
+	for _, v := range vars {
+		value, keys := v[0], v[1:]
+		if value == "" {
+			continue
+		}
+
+		set := false
+		for _, key := range keys {
+			if _, ok := os.LookupEnv(key); ok {
+				set = true
+			}
+		}
+		if !set {
+			log.Printf("Setting %s from the config file: %s", keys[0], value)
+			os.Setenv(keys[0], value)
+		}
+	}
+}
+
 // Discover discovers plugins.
 //
-// Search the directory of the executable, then the plugins directory, and
-// finally the CWD, in that order. Any conflicts will overwrite previously
+// Search the directory of the executable, then the plugins directory (the
+// plugin_directory of the config, or the "plugins" directory of the config
+// directory), and finally the CWD, in that order. Any conflicts will overwrite previously
 // found plugins, in that order.
 // Hence, the priority order is the reverse of the search order - i.e., the
 // CWD has the highest priority.
@@ -64,8 +155,11 @@ func (c *config) Discover() error {
 	}
 
 	// Next, look in the plugins directory.
-	dir, err := packer.ConfigDir()
-	if err != nil {
+	if c.PluginDir != "" {
+		if err := c.discover(c.PluginDir); err != nil {
+			return err
+		}
+	} else if dir, err := packer.ConfigDir(); err != nil {
 		log.Printf("[ERR] Error loading config directory: %s", err)
 	} else {
 		if err := c.discover(filepath.Join(dir, "plugins")); err != nil {
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	global := filepath.Join(dir, "global")
+	project := filepath.Join(dir, "project", ".packerconfig")
+	os.Mkdir(filepath.Dir(project), 0755)
+	ioutil.WriteFile(global, []byte(`{
+		"cache_directory": "/var/cache/packer",
+		"parallel_builds": 2,
+		"builders": {"foo": "packer-builder-foo"}
+	}`), 0644)
+	ioutil.WriteFile(project, []byte(`{
+		"plugin_directory": "plugins",
+		"parallel_builds": 4,
+		"builders": {"bar": "packer-builder-bar"}
+	}`), 0644)
+
+	var c config
+	for _, path := range []string{global, project, filepath.Join(dir, "missing")} {
+		if err := c.loadFile(path); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if c.CacheDir != "/var/cache/packer" {
+		t.Fatalf("bad cache directory: %s", c.CacheDir)
+	}
+	if c.PluginDir != filepath.Join(dir, "project", "plugins") {
+		t.Fatalf("bad plugin directory: %s", c.PluginDir)
+	}
+	if c.ParallelBuilds != 4 {
+		t.Fatalf("bad parallel builds: %d", c.ParallelBuilds)
+	}
+	if len(c.Builders) != 2 {
+		t.Fatalf("bad builders: %#v", c.Builders)
+	}
+}
+
+func TestConfigSetEnv(t *testing.T) {
+	for _, key := range []string{"AWS_POLL_DELAY_SECONDS", "PACKER_TMP_DIR"} {
+		if value, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, value)
+		} else {
+			defer os.Unsetenv(key)
+		}
+	}
+	os.Unsetenv("AWS_POLL_DELAY_SECONDS")
+	os.Setenv("PACKER_TMP_DIR", "/tmp/packer")
+
+	c := config{TmpDir: "/var/tmp/packer"}
+	c.AWSPolling.DelaySeconds = 10
+	c.setEnv()
+
+	if v := os.Getenv("AWS_POLL_DELAY_SECONDS"); v != "10" {
+		t.Fatalf("bad poll delay: %s", v)
+	}
+	if v := os.Getenv("PACKER_TMP_DIR"); v != "/tmp/packer" {
+		t.Fatalf("the environment should take precedence: %s", v)
+	}
+}
@@ -3,13 +3,18 @@ package communicator
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"regexp"
 	"time"
 
 	"github.com/hashicorp/packer/template/interpolate"
 	"github.com/masterzen/winrm"
 )
 
+// hostnameRegexp matches the hostnames build_hosts can map to an address.
+var hostnameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+
 // Config is the common configuration that communicators allow within
 // a builder.
 type Config struct {
@@ -41,15 +46,21 @@ type Config struct {
 	SSHReadWriteTimeout       time.Duration `mapstructure:"ssh_read_write_timeout"`
 
 	// WinRM
-	WinRMUser               string        `mapstructure:"winrm_username"`
-	WinRMPassword           string        `mapstructure:"winrm_password"`
-	WinRMHost               string        `mapstructure:"winrm_host"`
-	WinRMPort               int           `mapstructure:"winrm_port"`
-	WinRMTimeout            time.Duration `mapstructure:"winrm_timeout"`
-	WinRMUseSSL             bool          `mapstructure:"winrm_use_ssl"`
-	WinRMInsecure           bool          `mapstructure:"winrm_insecure"`
-	WinRMUseNTLM            bool          `mapstructure:"winrm_use_ntlm"`
-	WinRMTransportDecorator func() winrm.Transporter
+	WinRMUser                  string        `mapstructure:"winrm_username"`
+	WinRMPassword              string        `mapstructure:"winrm_password"`
+	WinRMHost                  string        `mapstructure:"winrm_host"`
+	WinRMPort                  int           `mapstructure:"winrm_port"`
+	WinRMTimeout               time.Duration `mapstructure:"winrm_timeout"`
+	WinRMUseSSL                bool          `mapstructure:"winrm_use_ssl"`
+	WinRMInsecure              bool          `mapstructure:"winrm_insecure"`
+	WinRMUseNTLM               bool          `mapstructure:"winrm_use_ntlm"`
+	WinRMMaxOperationsPerShell int           `mapstructure:"winrm_max_operations_per_shell"`
+	WinRMUploadStreams         int           `mapstructure:"winrm_upload_streams"`
+	WinRMTransportDecorator    func() winrm.Transporter
+
+	// Name resolution on the machine while it is provisioned
+	BuildHosts      map[string]string `mapstructure:"build_hosts"`
+	BuildDNSServers []string          `mapstructure:"build_dns_servers"`
 }
 
 // Port returns the port that will be used for access based on config.
@@ -121,6 +132,39 @@ func (c *Config) Prepare(ctx *interpolate.Context) []error {
 		return []error{fmt.Errorf("Communicator type %s is invalid", c.Type)}
 	}
 
+	if es := c.prepareNameResolution(); len(es) > 0 {
+		errs = append(errs, es...)
+	}
+
+	return errs
+}
+
+func (c *Config) prepareNameResolution() []error {
+	if len(c.BuildHosts) == 0 && len(c.BuildDNSServers) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if c.Type == "winrm" || c.Type == "none" {
+		errs = append(errs, fmt.Errorf(
+			"build_hosts and build_dns_servers can't be used with the %s communicator", c.Type))
+	}
+
+	for host, ip := range c.BuildHosts {
+		if !hostnameRegexp.MatchString(host) {
+			errs = append(errs, fmt.Errorf("build_hosts: invalid hostname '%s'", host))
+		}
+		if net.ParseIP(ip) == nil {
+			errs = append(errs, fmt.Errorf("build_hosts: invalid IP address '%s' for %s", ip, host))
+		}
+	}
+
+	for _, ip := range c.BuildDNSServers {
+		if net.ParseIP(ip) == nil {
+			errs = append(errs, fmt.Errorf("build_dns_servers: invalid IP address '%s'", ip))
+		}
+	}
+
 	return errs
 }
 
@@ -208,6 +252,14 @@ func (c *Config) prepareWinRM(ctx *interpolate.Context) []error {
 		c.WinRMTimeout = 30 * time.Minute
 	}
 
+	if c.WinRMMaxOperationsPerShell == 0 {
+		c.WinRMMaxOperationsPerShell = 15
+	}
+
+	if c.WinRMUploadStreams == 0 {
+		c.WinRMUploadStreams = 4
+	}
+
 	if c.WinRMUseNTLM == true {
 		c.WinRMTransportDecorator = func() winrm.Transporter { return &winrm.ClientNTLM{} }
 	}
@@ -217,5 +269,13 @@ func (c *Config) prepareWinRM(ctx *interpolate.Context) []error {
 		errs = append(errs, errors.New("winrm_username must be specified."))
 	}
 
+	if c.WinRMMaxOperationsPerShell < 0 {
+		errs = append(errs, errors.New("winrm_max_operations_per_shell must be positive."))
+	}
+
+	if c.WinRMUploadStreams < 0 {
+		errs = append(errs, errors.New("winrm_upload_streams must be positive."))
+	}
+
 	return errs
 }
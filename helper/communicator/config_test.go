@@ -139,3 +139,48 @@ func TestConfig_winrm(t *testing.T) {
 func testContext(t *testing.T) *interpolate.Context {
 	return nil
 }
+
+func TestConfig_winrm_shells(t *testing.T) {
+	c := &Config{
+		Type:      "winrm",
+		WinRMUser: "admin",
+	}
+	if err := c.Prepare(testContext(t)); len(err) > 0 {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	if c.WinRMMaxOperationsPerShell != 15 || c.WinRMUploadStreams != 4 {
+		t.Fatalf("bad defaults: %d, %d", c.WinRMMaxOperationsPerShell, c.WinRMUploadStreams)
+	}
+
+	c.WinRMUploadStreams = -1
+	if err := c.Prepare(testContext(t)); len(err) != 1 {
+		t.Fatalf("should error with negative upload streams")
+	}
+}
+
+func TestConfig_buildNameResolution(t *testing.T) {
+	c := &Config{
+		Type:            "ssh",
+		SSHUsername:     "root",
+		BuildHosts:      map[string]string{"mirror.internal": "10.0.0.5"},
+		BuildDNSServers: []string{"10.0.0.2", "fd00::2"},
+	}
+	if err := c.Prepare(testContext(t)); len(err) > 0 {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	c.BuildHosts = map[string]string{"mirror internal": "10.0.0"}
+	if err := c.Prepare(testContext(t)); len(err) != 2 {
+		t.Fatalf("should error with invalid hosts: %#v", err)
+	}
+
+	c = &Config{
+		Type:            "winrm",
+		WinRMUser:       "admin",
+		BuildDNSServers: []string{"10.0.0.2"},
+	}
+	if err := c.Prepare(testContext(t)); len(err) != 1 {
+		t.Fatalf("should error with winrm: %#v", err)
+	}
+}
@@ -64,6 +64,15 @@ func (s *StepConnect) Run(ctx context.Context, state multistep.StateBag) multist
 		typeMap[k] = v
 	}
 
+	// The name resolution overrides are applied while the machine is
+	// provisioned, see common.StepProvision.
+	if len(s.Config.BuildHosts) > 0 {
+		state.Put("build_hosts", s.Config.BuildHosts)
+	}
+	if len(s.Config.BuildDNSServers) > 0 {
+		state.Put("build_dns_servers", s.Config.BuildDNSServers)
+	}
+
 	step, ok := typeMap[s.Config.Type]
 	if !ok {
 		state.Put("error", fmt.Errorf("unknown communicator type: %s", s.Config.Type))
@@ -85,7 +94,43 @@ func (s *StepConnect) Run(ctx context.Context, state multistep.StateBag) multist
 	}
 
 	s.substep = step
-	return s.substep.Run(ctx, state)
+	action := s.substep.Run(ctx, state)
+	if action == multistep.ActionContinue {
+		state.Put("connection_info", s.connectionInfo(state))
+	}
+	return action
+}
+
+// connectionInfo describes how to connect to the machine, for it to be
+// inspected when the build fails.
+func (s *StepConnect) connectionInfo(state multistep.StateBag) []string {
+	info := []string{fmt.Sprintf("Communicator: %s", s.Config.Type)}
+
+	port := s.Config.Port()
+	portFunc := s.SSHPort
+	if s.Config.Type == "winrm" {
+		portFunc = s.WinRMPort
+	}
+	if portFunc != nil {
+		if p, err := portFunc(state); err == nil {
+			port = p
+		}
+	}
+	if s.Host != nil {
+		if host, err := s.Host(state); err == nil && host != "" {
+			info = append(info, fmt.Sprintf("Host: %s:%d", host, port))
+		}
+	}
+	if s.Config.SSHBastionHost != "" {
+		info = append(info, fmt.Sprintf("Bastion host: %s:%d", s.Config.SSHBastionHost, s.Config.SSHBastionPort))
+	}
+	if user := s.Config.User(); user != "" {
+		info = append(info, fmt.Sprintf("Username: %s", user))
+	}
+	if s.Config.Type == "ssh" && s.Config.SSHPrivateKey != "" {
+		info = append(info, fmt.Sprintf("Private key: %s", s.Config.SSHPrivateKey))
+	}
+	return info
 }
 
 func (s *StepConnect) Cleanup(state multistep.StateBag) {
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -62,7 +63,8 @@ WaitLoop:
 			state.Put("communicator", comm)
 			break WaitLoop
 		case <-timeout:
-			err := fmt.Errorf("Timeout waiting for SSH.")
+			err := packer.NewClassifiedError(packer.ErrorClassTimeout,
+				fmt.Errorf("Timeout waiting for SSH."))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			close(cancel)
@@ -155,7 +157,7 @@ func (s *StepConnectSSH) waitForSSH(state multistep.StateBag, cancel <-chan stru
 
 		// Attempt to connect to SSH port
 		var connFunc func() (net.Conn, error)
-		address := fmt.Sprintf("%s:%d", host, port)
+		address := net.JoinHostPort(host, strconv.Itoa(port))
 		if bAddr != "" {
 			// We're using a bastion host, so use the bastion connfunc
 			connFunc = ssh.BastionConnectFunc(
@@ -64,7 +64,8 @@ WaitLoop:
 			state.Put("communicator", comm)
 			break WaitLoop
 		case <-timeout:
-			err := fmt.Errorf("Timeout waiting for WinRM.")
+			err := packer.NewClassifiedError(packer.ErrorClassTimeout,
+				fmt.Errorf("Timeout waiting for WinRM."))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			close(cancel)
@@ -138,6 +139,9 @@ func (s *StepConnectWinRM) waitForWinRM(state multistep.StateBag, cancel <-chan
 			Https:              s.Config.WinRMUseSSL,
 			Insecure:           s.Config.WinRMInsecure,
 			TransportDecorator: s.Config.WinRMTransportDecorator,
+
+			MaxOperationsPerShell: s.Config.WinRMMaxOperationsPerShell,
+			UploadStreams:         s.Config.WinRMUploadStreams,
 		})
 		if err != nil {
 			log.Printf("[ERROR] WinRM connection err: %s", err)
@@ -46,6 +46,9 @@ func Decode(target interface{}, config *DecodeOpts, raws ...interface{}) error {
 			config.InterpolateContext.BuildType = ctx.BuildType
 			config.InterpolateContext.TemplatePath = ctx.TemplatePath
 			config.InterpolateContext.UserVariables = ctx.UserVariables
+			config.InterpolateContext.BuildOutputs = ctx.BuildOutputs
+			config.InterpolateContext.Lineage = ctx.Lineage
+			config.InterpolateContext.LineageVersion = ctx.LineageVersion
 		}
 		ctx = config.InterpolateContext
 
@@ -108,10 +111,13 @@ func Decode(target interface{}, config *DecodeOpts, raws ...interface{}) error {
 // detecting things like user variables from the raw configuration params.
 func DetectContext(raws ...interface{}) (*interpolate.Context, error) {
 	var s struct {
-		BuildName    string            `mapstructure:"packer_build_name"`
-		BuildType    string            `mapstructure:"packer_builder_type"`
-		TemplatePath string            `mapstructure:"packer_template_path"`
-		Vars         map[string]string `mapstructure:"packer_user_variables"`
+		BuildName      string            `mapstructure:"packer_build_name"`
+		BuildType      string            `mapstructure:"packer_builder_type"`
+		TemplatePath   string            `mapstructure:"packer_template_path"`
+		Vars           map[string]string `mapstructure:"packer_user_variables"`
+		BuildOutputs   map[string]string `mapstructure:"packer_build_outputs"`
+		Lineage        string            `mapstructure:"packer_lineage"`
+		LineageVersion int               `mapstructure:"packer_lineage_version"`
 	}
 
 	for _, r := range raws {
@@ -121,10 +127,13 @@ func DetectContext(raws ...interface{}) (*interpolate.Context, error) {
 	}
 
 	return &interpolate.Context{
-		BuildName:     s.BuildName,
-		BuildType:     s.BuildType,
-		TemplatePath:  s.TemplatePath,
-		UserVariables: s.Vars,
+		BuildName:      s.BuildName,
+		BuildType:      s.BuildType,
+		TemplatePath:   s.TemplatePath,
+		UserVariables:  s.Vars,
+		BuildOutputs:   s.BuildOutputs,
+		Lineage:        s.Lineage,
+		LineageVersion: s.LineageVersion,
 	}, nil
 }
 
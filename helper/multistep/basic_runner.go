@@ -4,6 +4,7 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type runState int32
@@ -20,6 +21,9 @@ type BasicRunner struct {
 	// modified.
 	Steps []Step
 
+	// Hooks are called around each step, in order.
+	Hooks []StepHook
+
 	cancel context.CancelFunc
 	doneCh chan struct{}
 	state  runState
@@ -69,8 +73,37 @@ func (b *BasicRunner) Run(state StateBag) {
 			break
 		}
 
+		// The pauses of the debug runner are not steps of their own
+		_, traced := step.(*debugStepPause)
+		traced = !traced && len(b.Hooks) > 0
+		name := ""
+		if traced {
+			name = StepName(step)
+		}
+
+		if !ShouldRun(step, state) {
+			if traced {
+				for _, hook := range b.Hooks {
+					hook.AfterStep(name, StepResult{Action: ActionContinue, Skipped: true}, state)
+				}
+			}
+			continue
+		}
+
+		if traced {
+			for _, hook := range b.Hooks {
+				hook.BeforeStep(name, state)
+			}
+		}
+		start := time.Now()
 		action := step.Run(ctx, state)
 		defer step.Cleanup(state)
+		if traced {
+			result := StepResult{Action: action, Duration: time.Since(start)}
+			for _, hook := range b.Hooks {
+				hook.AfterStep(name, result, state)
+			}
+		}
 
 		if _, ok := state.GetOk(StateCancelled); ok {
 			break
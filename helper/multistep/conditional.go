@@ -0,0 +1,64 @@
+package multistep
+
+import "context"
+
+// ConditionalStep is a step that is only run if its condition holds when
+// the runner gets to it. A skipped step is neither run nor cleaned up.
+type ConditionalStep interface {
+	Step
+
+	// ShouldRun is called right before the step would run.
+	ShouldRun(StateBag) bool
+}
+
+// ShouldRun is true unless the step is a ConditionalStep whose condition
+// does not hold. Steps wrapping other steps can use it to forward the
+// condition of the wrapped step.
+func ShouldRun(step Step, state StateBag) bool {
+	if c, ok := step.(ConditionalStep); ok {
+		return c.ShouldRun(state)
+	}
+	return true
+}
+
+// StepIf runs Step only if Condition returns true. Build the steps of a
+// builder with it instead of checking the configuration at the start of
+// Run, so that the runners show the step as skipped.
+type StepIf struct {
+	Step      Step
+	Condition func(StateBag) bool
+}
+
+// If returns a step that only runs the step if the condition is true, for
+// the conditions known before the steps run, like configuration settings.
+func If(condition bool, step Step) *StepIf {
+	return &StepIf{
+		Step:      step,
+		Condition: func(StateBag) bool { return condition },
+	}
+}
+
+// IfFunc returns a step that only runs the step if the condition returns
+// true, for the conditions on the state left by the previous steps.
+func IfFunc(condition func(StateBag) bool, step Step) *StepIf {
+	return &StepIf{
+		Step:      step,
+		Condition: condition,
+	}
+}
+
+func (s *StepIf) InnerStepName() string {
+	return StepName(s.Step)
+}
+
+func (s *StepIf) ShouldRun(state StateBag) bool {
+	return s.Condition(state) && ShouldRun(s.Step, state)
+}
+
+func (s *StepIf) Run(ctx context.Context, state StateBag) StepAction {
+	return s.Step.Run(ctx, state)
+}
+
+func (s *StepIf) Cleanup(state StateBag) {
+	s.Step.Cleanup(state)
+}
@@ -0,0 +1,132 @@
+package multistep
+
+import (
+	"reflect"
+	"testing"
+)
+
+// A hook for testing that records the steps it is called around.
+type testHook struct {
+	calls []string
+}
+
+func (h *testHook) BeforeStep(name string, _ StateBag) {
+	h.calls = append(h.calls, "before "+name)
+}
+
+func (h *testHook) AfterStep(name string, result StepResult, _ StateBag) {
+	switch {
+	case result.Skipped:
+		h.calls = append(h.calls, "skipped "+name)
+	case result.Action == ActionHalt:
+		h.calls = append(h.calls, "halted "+name)
+	default:
+		h.calls = append(h.calls, "after "+name)
+	}
+}
+
+func TestStepIf_Impl(t *testing.T) {
+	var _ ConditionalStep = new(StepIf)
+	var _ StepWrapper = new(StepIf)
+}
+
+func TestStepIf_ShouldRun(t *testing.T) {
+	state := new(BasicStateBag)
+	step := &TestStepAcc{Data: "a"}
+
+	if !If(true, step).ShouldRun(state) {
+		t.Fatal("should run")
+	}
+	if If(false, step).ShouldRun(state) {
+		t.Fatal("should not run")
+	}
+	// The condition of a wrapped conditional step holds as well
+	if If(true, If(false, step)).ShouldRun(state) {
+		t.Fatal("should not run")
+	}
+
+	cond := IfFunc(func(state StateBag) bool {
+		_, ok := state.GetOk("run")
+		return ok
+	}, step)
+	if cond.ShouldRun(state) {
+		t.Fatal("should not run")
+	}
+	state.Put("run", true)
+	if !cond.ShouldRun(state) {
+		t.Fatal("should run")
+	}
+
+	if name := StepName(cond); name != "TestStepAcc" {
+		t.Fatalf("bad name: %s", name)
+	}
+}
+
+func TestBasicRunner_Run_Conditional(t *testing.T) {
+	data := new(BasicStateBag)
+	hook := new(testHook)
+	stepA := &TestStepAcc{Data: "a"}
+	stepB := If(false, &TestStepAcc{Data: "b"})
+	stepC := IfFunc(func(state StateBag) bool {
+		_, ok := state.GetOk("data")
+		return ok
+	}, &TestStepAcc{Data: "c", Halt: true})
+
+	r := &BasicRunner{Steps: []Step{stepA, stepB, stepC}, Hooks: []StepHook{hook}}
+	r.Run(data)
+
+	expected := []string{"a", "c"}
+	results := data.Get("data").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected result: %#v", results)
+	}
+
+	expected = []string{"c", "a"}
+	results = data.Get("cleanup").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected result: %#v", results)
+	}
+
+	expected = []string{
+		"before TestStepAcc", "after TestStepAcc",
+		"skipped TestStepAcc",
+		"before TestStepAcc", "halted TestStepAcc",
+	}
+	if !reflect.DeepEqual(hook.calls, expected) {
+		t.Errorf("unexpected hook calls: %#v", hook.calls)
+	}
+}
+
+func TestDebugRunner_Run_Conditional(t *testing.T) {
+	data := new(BasicStateBag)
+	hook := new(testHook)
+	var pauses []string
+	pauseFn := func(loc DebugLocation, name string, state StateBag) {
+		pauses = append(pauses, name)
+	}
+
+	r := &DebugRunner{
+		Steps: []Step{
+			If(false, &TestStepAcc{Data: "a"}),
+			&TestStepAcc{Data: "b"},
+		},
+		PauseFn: pauseFn,
+		Hooks:   []StepHook{hook},
+	}
+	r.Run(data)
+
+	// Neither the skipped step nor its pause ran
+	expected := []string{"b"}
+	results := data.Get("data").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected result: %#v", results)
+	}
+	if len(pauses) != 2 {
+		t.Errorf("unexpected pauses: %#v", pauses)
+	}
+
+	expected = []string{"skipped TestStepAcc", "before TestStepAcc", "after TestStepAcc"}
+	if !reflect.DeepEqual(hook.calls, expected) {
+		t.Errorf("unexpected hook calls: %#v", hook.calls)
+	}
+}
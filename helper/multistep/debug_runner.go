@@ -3,7 +3,6 @@ package multistep
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"sync"
 )
 
@@ -41,6 +40,10 @@ type DebugRunner struct {
 	// The function is given the state so that the state can be inspected.
 	PauseFn DebugPauseFn
 
+	// Hooks are called around each step, in order, but not around the
+	// pauses.
+	Hooks []StepHook
+
 	l      sync.Mutex
 	runner *BasicRunner
 }
@@ -50,7 +53,7 @@ func (r *DebugRunner) Run(state StateBag) {
 	if r.runner != nil {
 		panic("already running")
 	}
-	r.runner = new(BasicRunner)
+	r.runner = &BasicRunner{Hooks: r.Hooks}
 	r.l.Unlock()
 
 	pauseFn := r.PauseFn
@@ -63,17 +66,16 @@ func (r *DebugRunner) Run(state StateBag) {
 	// Rebuild the steps so that we insert the pause step after each
 	steps := make([]Step, len(r.Steps)*2)
 	for i, step := range r.Steps {
-		steps[i*2] = step
-		name := ""
-		if wrapped, ok := step.(StepWrapper); ok {
-			name = wrapped.InnerStepName()
-		} else {
-			name = reflect.Indirect(reflect.ValueOf(step)).Type().Name()
+		pause := &debugStepPause{
+			StepName: StepName(step),
+			PauseFn:  pauseFn,
 		}
-		steps[(i*2)+1] = &debugStepPause{
-			name,
-			pauseFn,
+		// There is no pause after a step that was skipped
+		if c, ok := step.(ConditionalStep); ok {
+			step = &debugConditionalStep{c, pause}
 		}
+		steps[i*2] = step
+		steps[(i*2)+1] = pause
 	}
 
 	// Then just use a basic runner to run it
@@ -112,6 +114,12 @@ func DebugPauseDefault(loc DebugLocation, name string, state StateBag) {
 type debugStepPause struct {
 	StepName string
 	PauseFn  DebugPauseFn
+
+	skipped bool
+}
+
+func (s *debugStepPause) ShouldRun(StateBag) bool {
+	return !s.skipped
 }
 
 func (s *debugStepPause) Run(_ context.Context, state StateBag) StepAction {
@@ -122,3 +130,18 @@ func (s *debugStepPause) Run(_ context.Context, state StateBag) StepAction {
 func (s *debugStepPause) Cleanup(state StateBag) {
 	s.PauseFn(DebugLocationBeforeCleanup, s.StepName, state)
 }
+
+// debugConditionalStep skips the pause after the step along with the step.
+type debugConditionalStep struct {
+	ConditionalStep
+	pause *debugStepPause
+}
+
+func (s *debugConditionalStep) InnerStepName() string {
+	return StepName(s.ConditionalStep)
+}
+
+func (s *debugConditionalStep) ShouldRun(state StateBag) bool {
+	s.pause.skipped = !s.ConditionalStep.ShouldRun(state)
+	return !s.pause.skipped
+}
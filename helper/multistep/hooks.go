@@ -0,0 +1,39 @@
+package multistep
+
+import (
+	"reflect"
+	"time"
+)
+
+// StepResult is how a step the runner got to went.
+type StepResult struct {
+	// Action is what the step returned, ActionContinue if it was skipped.
+	Action StepAction
+
+	// Skipped is true if the step is a ConditionalStep whose condition
+	// did not hold. BeforeStep is not called for skipped steps.
+	Skipped bool
+
+	// Duration is how long the step ran.
+	Duration time.Duration
+}
+
+// StepHook is called by the runners around each step of the sequence, with
+// the name of the step. Use it to trace the steps, instead of having each
+// step report itself.
+type StepHook interface {
+	// BeforeStep is called right before the step runs.
+	BeforeStep(name string, state StateBag)
+
+	// AfterStep is called once the step ran or was skipped.
+	AfterStep(name string, result StepResult, state StateBag)
+}
+
+// StepName is the human readable name of the step: the name of the step it
+// wraps if it is a StepWrapper, or else the name of its type.
+func StepName(step Step) string {
+	if wrapped, ok := step.(StepWrapper); ok {
+		return wrapped.InnerStepName()
+	}
+	return reflect.Indirect(reflect.ValueOf(step)).Type().Name()
+}
@@ -0,0 +1,137 @@
+// Package retry calls a function until it succeeds, waiting longer after
+// each failure. Builders, provisioners and post-processors, built in or
+// plugins, should use it rather than their own retry loops, so that they
+// all back off, give up and get cancelled the same way.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultInitialBackoff is how long Run waits after the first failure
+	// if Config.InitialBackoff isn't set.
+	DefaultInitialBackoff = time.Second
+
+	// DefaultMaxBackoff is the longest Run waits between two tries if
+	// Config.MaxBackoff isn't set.
+	DefaultMaxBackoff = 30 * time.Second
+
+	// DefaultMultiplier is how much longer each wait is than the previous
+	// one if Config.Multiplier isn't set.
+	DefaultMultiplier = 2
+)
+
+// Config says how a function is retried. The zero value retries every error
+// until the context is done, with the default backoff.
+type Config struct {
+	// Tries is how many times the function is called at most. 0 means it
+	// is called until it succeeds or the context is done.
+	Tries int
+
+	// InitialBackoff is how long to wait after the first failure.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the longest to wait between two tries.
+	MaxBackoff time.Duration
+
+	// Multiplier is how much longer each wait is than the previous one.
+	Multiplier float64
+
+	// ShouldRetry returns whether the function is worth calling again after
+	// it failed with the error. Every error is retried if it is nil. See
+	// packer.RetryOnErrorClass.
+	ShouldRetry func(error) bool
+}
+
+// ExhaustedError is returned by Run when the function failed as many times
+// as it could be called, or when the context was done before it succeeded.
+// Its cause is the last error of the function, which packer.ClassifyError
+// classifies it like, so that a build that gave up retrying a throttled call
+// still fails as throttled.
+type ExhaustedError struct {
+	// Tries is how many times the function was called.
+	Tries int
+
+	// Err is the last error of the function.
+	Err error
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("gave up after %d tries: %s", e.Tries, e.Err)
+}
+
+func (e *ExhaustedError) Cause() error {
+	return e.Err
+}
+
+type abortError struct {
+	err error
+}
+
+func (e *abortError) Error() string { return e.err.Error() }
+
+// Abort wraps an error of the function to make Run return it right away,
+// whatever Config.ShouldRetry says about it.
+func Abort(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &abortError{err: err}
+}
+
+// Run calls fn until it returns nil, waiting between the tries. Each wait
+// is longer than the previous one, by Config.Multiplier up to
+// Config.MaxBackoff, and its second half is random so that the builds
+// failing at the same time don't all try again at the same time.
+//
+// Run returns the error of fn if it shouldn't be retried or was wrapped
+// with Abort, and an *ExhaustedError if fn was called Config.Tries times or
+// the context was done first. The context is passed to fn, which should
+// give up when it is done.
+func (c Config) Run(ctx context.Context, fn func(context.Context) error) error {
+	backoff := c.InitialBackoff
+	if backoff <= 0 {
+		backoff = DefaultInitialBackoff
+	}
+	maxBackoff := c.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	multiplier := c.Multiplier
+	if multiplier < 1 {
+		multiplier = DefaultMultiplier
+	}
+
+	var err error
+	for try := 1; ; try++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if abort, ok := err.(*abortError); ok {
+			return abort.err
+		}
+		if c.ShouldRetry != nil && !c.ShouldRetry(err) {
+			return err
+		}
+		if c.Tries > 0 && try >= c.Tries {
+			return &ExhaustedError{Tries: try, Err: err}
+		}
+
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		log.Printf("[DEBUG] Try %d failed, retrying in %s: %s", try, wait, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return &ExhaustedError{Tries: try, Err: err}
+		}
+		backoff = time.Duration(float64(backoff) * multiplier)
+	}
+}
@@ -0,0 +1,116 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tries := 0
+	err := Config{InitialBackoff: time.Millisecond}.Run(context.Background(), func(context.Context) error {
+		tries++
+		if tries < 3 {
+			return fmt.Errorf("fail")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if tries != 3 {
+		t.Fatalf("bad: %d", tries)
+	}
+}
+
+func TestRun_Exhausted(t *testing.T) {
+	tries := 0
+	err := Config{Tries: 3, InitialBackoff: time.Millisecond}.Run(context.Background(), func(context.Context) error {
+		tries++
+		return fmt.Errorf("RequestLimitExceeded")
+	})
+	exhausted, ok := err.(*ExhaustedError)
+	if !ok {
+		t.Fatalf("should be exhausted: %#v", err)
+	}
+	if tries != 3 || exhausted.Tries != 3 {
+		t.Fatalf("bad: %d %d", tries, exhausted.Tries)
+	}
+	if exhausted.Cause().Error() != "RequestLimitExceeded" {
+		t.Fatalf("bad: %s", exhausted.Cause())
+	}
+}
+
+func TestRun_ShouldRetry(t *testing.T) {
+	tries := 0
+	config := Config{
+		InitialBackoff: time.Millisecond,
+		ShouldRetry: func(err error) bool {
+			return err.Error() != "AccessDenied"
+		},
+	}
+	err := config.Run(context.Background(), func(context.Context) error {
+		tries++
+		if tries == 1 {
+			return fmt.Errorf("Throttling")
+		}
+		return fmt.Errorf("AccessDenied")
+	})
+	if err == nil || err.Error() != "AccessDenied" {
+		t.Fatalf("bad: %s", err)
+	}
+	if tries != 2 {
+		t.Fatalf("bad: %d", tries)
+	}
+}
+
+func TestRun_Abort(t *testing.T) {
+	tries := 0
+	err := Config{InitialBackoff: time.Millisecond}.Run(context.Background(), func(context.Context) error {
+		tries++
+		return Abort(fmt.Errorf("fatal"))
+	})
+	if err == nil || err.Error() != "fatal" {
+		t.Fatalf("bad: %s", err)
+	}
+	if tries != 1 {
+		t.Fatalf("bad: %d", tries)
+	}
+}
+
+func TestRun_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tries := 0
+	err := Config{InitialBackoff: time.Hour}.Run(ctx, func(context.Context) error {
+		tries++
+		cancel()
+		return fmt.Errorf("fail")
+	})
+	if _, ok := err.(*ExhaustedError); !ok {
+		t.Fatalf("should be exhausted: %#v", err)
+	}
+	if tries != 1 {
+		t.Fatalf("bad: %d", tries)
+	}
+}
+
+func TestRun_Backoff(t *testing.T) {
+	var times []time.Time
+	config := Config{
+		Tries:          4,
+		InitialBackoff: 20 * time.Millisecond,
+		MaxBackoff:     40 * time.Millisecond,
+	}
+	config.Run(context.Background(), func(context.Context) error {
+		times = append(times, time.Now())
+		return fmt.Errorf("fail")
+	})
+
+	// The waits are at least half the backoff: 20ms, 40ms, then capped at 40ms
+	for i, min := range []time.Duration{10, 20, 20} {
+		if wait := times[i+1].Sub(times[i]); wait < min*time.Millisecond {
+			t.Fatalf("wait %d too short: %s", i, wait)
+		}
+	}
+}
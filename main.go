@@ -204,8 +204,10 @@ func wrappedMain() int {
 			},
 			Version: version.Version,
 		},
-		Cache: cache,
-		Ui:    ui,
+		Cache:          cache,
+		ParallelBuilds: config.ParallelBuilds,
+		ShipLogs:       config.ShipLogs,
+		Ui:             ui,
 	}
 
 	cli := &cli.CLI{
@@ -275,9 +277,6 @@ func loadConfig() (*config, error) {
 	var config config
 	config.PluginMinPort = 10000
 	config.PluginMaxPort = 25000
-	if err := config.Discover(); err != nil {
-		return nil, err
-	}
 
 	configFilePath := os.Getenv("PACKER_CONFIG")
 	if configFilePath != "" {
@@ -291,26 +290,40 @@ func loadConfig() (*config, error) {
 		}
 	}
 
-	if configFilePath == "" {
-		return &config, nil
+	// The config of the project overrides the global one
+	paths := []string{configFilePath}
+	if projectFilePath, err := packer.ProjectConfigFile(); err != nil {
+		log.Printf("Error detecting project config file path: %s", err)
+	} else if projectFilePath != configFilePath {
+		paths = append(paths, projectFilePath)
 	}
 
-	log.Printf("Attempting to open config file: %s", configFilePath)
-	f, err := os.Open(configFilePath)
-	if err != nil {
-		if !os.IsNotExist(err) {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := config.loadFile(path); err != nil {
 			return nil, err
 		}
-
-		log.Printf("[WARN] Config file doesn't exist: %s", configFilePath)
-		return &config, nil
 	}
-	defer f.Close()
 
-	if err := decodeConfig(f, &config); err != nil {
+	// The plugins set in the config files take precedence over the
+	// discovered ones.
+	configured := []map[string]string{config.Builders, config.PostProcessors, config.Provisioners}
+	config.Builders, config.PostProcessors, config.Provisioners = nil, nil, nil
+	if err := config.Discover(); err != nil {
 		return nil, err
 	}
+	for i, m := range []*map[string]string{&config.Builders, &config.PostProcessors, &config.Provisioners} {
+		if *m == nil {
+			*m = make(map[string]string)
+		}
+		for name, path := range configured[i] {
+			(*m)[name] = path
+		}
+	}
 
+	config.setEnv()
 	return &config, nil
 }
 
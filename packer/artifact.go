@@ -1,5 +1,10 @@
 package packer
 
+import (
+	"fmt"
+	"sort"
+)
+
 // An Artifact is the result of a build, and is the metadata that documents
 // what a builder actually created. The exact meaning of the contents is
 // specific to each builder, but this interface is used to communicate back
@@ -34,3 +39,64 @@ type Artifact interface {
 	// no longer needed.
 	Destroy() error
 }
+
+// NamedArtifacts is implemented by the artifact of a build that produces
+// several artifacts, for example a machine image along with an export of
+// its disk. The artifact itself describes the main result of the build,
+// and is what post-processors get unless they ask for one of the others
+// by name.
+type NamedArtifacts interface {
+	// ArtifactNames returns the names of the other artifacts of the build.
+	ArtifactNames() []string
+
+	// NamedArtifact returns the artifact with the given name, or nil if
+	// there is none.
+	NamedArtifact(name string) Artifact
+
+	// MainArtifact returns the main artifact alone, which is destroyed
+	// without the named ones.
+	MainArtifact() Artifact
+}
+
+// ArtifactSet is an Artifact made of a main artifact and other named ones,
+// for builders that produce several artifacts from a single build.
+// Destroying the set destroys all of its artifacts.
+type ArtifactSet struct {
+	Artifact
+
+	Named map[string]Artifact
+}
+
+func (a *ArtifactSet) ArtifactNames() []string {
+	names := make([]string, 0, len(a.Named))
+	for name := range a.Named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *ArtifactSet) NamedArtifact(name string) Artifact {
+	return a.Named[name]
+}
+
+func (a *ArtifactSet) MainArtifact() Artifact {
+	return a.Artifact
+}
+
+func (a *ArtifactSet) Destroy() error {
+	var errs *MultiError
+	for _, name := range a.ArtifactNames() {
+		if err := a.Named[name].Destroy(); err != nil {
+			errs = MultiErrorAppend(errs, fmt.Errorf("Error destroying artifact '%s': %s", name, err))
+		}
+	}
+	if err := a.Artifact.Destroy(); err != nil {
+		errs = MultiErrorAppend(errs, err)
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
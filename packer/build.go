@@ -1,9 +1,22 @@
 package packer
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/hashicorp/packer/helper/retry"
+	"github.com/hashicorp/packer/template"
+	"github.com/hashicorp/packer/template/interpolate"
 )
 
 const (
@@ -28,14 +41,36 @@ const (
 	// - "cleanup" - run cleanup steps
 	// - "abort" - exit without cleanup
 	// - "ask" - ask the user
+	// - "inspect" - keep the machine for a grace period, then clean up
 	OnErrorConfigKey = "packer_on_error"
 
+	// This is the key in configurations that is set to how long the machine
+	// of a failed build is kept with the "inspect" on error mode.
+	OnErrorGracePeriodConfigKey = "packer_on_error_grace_period"
+
+	// This is the key in configurations that is set to "true" when the
+	// provisioners are only run dry, without any machine to run on.
+	ProvisionerDryRunConfigKey = "packer_provisioner_dry_run"
+
 	// TemplatePathKey is the path to the template that configured this build
 	TemplatePathKey = "packer_template_path"
 
 	// This key contains a map[string]string of the user variables for
 	// template processing.
 	UserVariablesConfigKey = "packer_user_variables"
+
+	// This key contains a map[string]string of the outputs of the builds
+	// this one depends on, keyed by "<build>.<output>".
+	BuildOutputsConfigKey = "packer_build_outputs"
+
+	// These keys are set to the image family the template builds versions
+	// of, and to the version being built, when the template has a lineage.
+	LineageConfigKey        = "packer_lineage"
+	LineageVersionConfigKey = "packer_lineage_version"
+
+	// This key is set to the checkpoint keys of the provisioners, for the
+	// builders that save a checkpoint of the machine after them.
+	ProvisionerKeysConfigKey = "packer_provisioner_keys"
 )
 
 // A Build represents a single job within Packer that is responsible for
@@ -78,7 +113,20 @@ type Build interface {
 	// - "cleanup" - run cleanup steps
 	// - "abort" - exit without cleanup
 	// - "ask" - ask the user
+	// - "inspect" - keep the machine for a grace period, then clean up
 	SetOnError(string)
+
+	// SetOnErrorGracePeriod sets how long the machine of a failed build is
+	// kept to be inspected with the "inspect" on error mode, before it is
+	// cleaned up. This must be called prior to Prepare.
+	SetOnErrorGracePeriod(time.Duration)
+
+	// SetProvisionerDryRun will enable/disable the dry run of the
+	// provisioners. The builder isn't run then: the provisioners run
+	// against a communicator showing the commands they run and the files
+	// they upload, and the build produces no artifact. This must be called
+	// prior to Prepare.
+	SetProvisionerDryRun(bool)
 }
 
 // A build struct represents a single build job, the result of which should
@@ -93,16 +141,46 @@ type coreBuild struct {
 	hooks          map[string][]Hook
 	postProcessors [][]coreBuildPostProcessor
 	provisioners   []coreBuildProvisioner
+	preBuild       []string
+	postBuild      []string
+	retry          *template.BuildRetry
 	templatePath   string
 	variables      map[string]string
+	lineage        string
+	lineageVersion int
+
+	// rawName is the name of the build in the template, the one
+	// dependencies and outputs refer to.
+	rawName      string
+	dependsOn    []string
+	outputs      map[string]string
+	buildOutputs *buildOutputs
+
+	debug              bool
+	force              bool
+	onError            string
+	onErrorGracePeriod time.Duration
+	provisionerDryRun  bool
+	l                  sync.Mutex
+	prepareCalled      bool
+	cancelled          bool
+	cancelRetry        context.CancelFunc
+}
 
-	debug         bool
-	force         bool
-	onError       string
-	l             sync.Mutex
-	prepareCalled bool
+// defaultBuildRetryOn are the error classes a build is retried on when the
+// build_retry policy of the template doesn't list any.
+var defaultBuildRetryOn = []string{
+	string(ErrorClassCapacity),
+	string(ErrorClassThrottle),
 }
 
+// The backoff between the runs of a build retried by its build_retry
+// policy. They are modified in tests.
+var (
+	buildRetryInitialBackoff = 30 * time.Second
+	buildRetryMaxBackoff     = 5 * time.Minute
+)
+
 // Keeps track of the post-processor and the configuration of the
 // post-processor used within a build.
 type coreBuildPostProcessor struct {
@@ -110,6 +188,10 @@ type coreBuildPostProcessor struct {
 	processorType     string
 	config            map[string]interface{}
 	keepInputArtifact bool
+
+	// artifactName is the name of the build artifact the chain starting
+	// with this post-processor runs on, the main one if it's empty.
+	artifactName string
 }
 
 // Keeps track of the provisioner and the configuration of the provisioner
@@ -118,6 +200,165 @@ type coreBuildProvisioner struct {
 	pType       string
 	provisioner Provisioner
 	config      []interface{}
+	checkpoint  string
+}
+
+// provisionerFileKeys are the keys of provisioner configurations naming the
+// local files and directories the provisioners upload or run.
+var provisionerFileKeys = map[string]bool{
+	"config_template":                true,
+	"cookbook_paths":                 true,
+	"data_bags_path":                 true,
+	"encrypted_data_bag_secret_path": true,
+	"environments_path":              true,
+	"galaxy_file":                    true,
+	"grains_file":                    true,
+	"group_vars":                     true,
+	"hiera_config_path":              true,
+	"host_vars":                      true,
+	"inventory_file":                 true,
+	"local_pillar_roots":             true,
+	"local_state_tree":               true,
+	"manifest_dir":                   true,
+	"manifest_file":                  true,
+	"minion_config":                  true,
+	"module_paths":                   true,
+	"playbook_dir":                   true,
+	"playbook_file":                  true,
+	"playbook_files":                 true,
+	"playbook_paths":                 true,
+	"role_paths":                     true,
+	"roles_path":                     true,
+	"script":                         true,
+	"scripts":                        true,
+	"source":                         true,
+	"sources":                        true,
+}
+
+// provisionerKeys are the checkpoint keys of the provisioners of the build,
+// each a hash of the user variables, of the name, type and configuration of
+// the builder, and of the type, configuration and local files of the
+// provisioners up to it, so a key changes with any of them. Only the last
+// provisioner of a checkpoint group has a key. There are no keys if a
+// configuration can't be hashed.
+func (b *coreBuild) provisionerKeys() []string {
+	provisioners := b.provisioners
+	if len(provisioners) == 0 {
+		return nil
+	}
+
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	if err := enc.Encode(b.variables); err != nil {
+		log.Printf("Error hashing the user variables: %s", err)
+		return nil
+	}
+	if err := enc.Encode([]interface{}{b.name, b.builderType, b.builderConfig}); err != nil {
+		log.Printf("Error hashing the configuration of the builder: %s", err)
+		return nil
+	}
+
+	ctx := &interpolate.Context{
+		UserVariables: b.variables,
+		BuildName:     b.name,
+		BuildType:     b.builderType,
+		TemplatePath:  b.templatePath,
+	}
+	keys := make([]string, len(provisioners))
+	for i, p := range provisioners {
+		if err := enc.Encode([]interface{}{p.pType, p.config}); err != nil {
+			log.Printf("Error hashing the configuration of provisioner %d: %s", i+1, err)
+			return nil
+		}
+		if err := hashProvisionerFiles(h, ctx, p.config); err != nil {
+			log.Printf("Error hashing the files of provisioner %d: %s", i+1, err)
+			return nil
+		}
+
+		next := i + 1
+		if p.checkpoint != "" && next < len(provisioners) && provisioners[next].checkpoint == p.checkpoint {
+			continue
+		}
+		keys[i] = hex.EncodeToString(h.Sum(nil))
+	}
+	return keys
+}
+
+// hashProvisionerFiles writes the paths and contents of the local files and
+// directories the provisioner configurations name to the hash, in the order
+// of their keys. The paths that don't exist locally, such as those of files
+// downloaded from the machine, are skipped.
+func hashProvisionerFiles(h io.Writer, ctx *interpolate.Context, configs []interface{}) error {
+	for _, raw := range configs {
+		config, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var keys []string
+		for key := range config {
+			if provisionerFileKeys[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			var paths []interface{}
+			switch v := config[key].(type) {
+			case []interface{}:
+				paths = v
+			case []string:
+				for _, path := range v {
+					paths = append(paths, path)
+				}
+			default:
+				paths = []interface{}{v}
+			}
+
+			for _, rawPath := range paths {
+				path, ok := rawPath.(string)
+				if !ok {
+					continue
+				}
+				path, err := interpolate.Render(path, ctx)
+				if err != nil {
+					return fmt.Errorf("%s: %s", key, err)
+				}
+				if err := hashPath(h, path); err != nil {
+					return fmt.Errorf("%s: %s", key, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hashPath writes the path, and the contents of the file or of the files in
+// the directory, to the hash.
+func hashPath(h io.Writer, root string) error {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(path))
+		_, err = io.Copy(h, f)
+		return err
+	})
 }
 
 // Returns the name of the build.
@@ -139,13 +380,25 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 	b.prepareCalled = true
 
 	packerConfig := map[string]interface{}{
-		BuildNameConfigKey:     b.name,
-		BuilderTypeConfigKey:   b.builderType,
-		DebugConfigKey:         b.debug,
-		ForceConfigKey:         b.force,
-		OnErrorConfigKey:       b.onError,
-		TemplatePathKey:        b.templatePath,
-		UserVariablesConfigKey: b.variables,
+		BuildNameConfigKey:          b.name,
+		BuilderTypeConfigKey:        b.builderType,
+		DebugConfigKey:              b.debug,
+		ForceConfigKey:              b.force,
+		OnErrorConfigKey:            b.onError,
+		OnErrorGracePeriodConfigKey: b.onErrorGracePeriod.String(),
+		ProvisionerDryRunConfigKey:  b.provisionerDryRun,
+		TemplatePathKey:             b.templatePath,
+		UserVariablesConfigKey:      b.variables,
+	}
+	if len(b.dependsOn) > 0 {
+		packerConfig[BuildOutputsConfigKey] = b.buildOutputs.Config(b.dependsOn)
+	}
+	if b.lineage != "" {
+		packerConfig[LineageConfigKey] = b.lineage
+		packerConfig[LineageVersionConfigKey] = b.lineageVersion
+	}
+	if keys := b.provisionerKeys(); len(keys) > 0 {
+		packerConfig[ProvisionerKeysConfigKey] = keys
 	}
 
 	// Prepare the builder
@@ -185,6 +438,43 @@ func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 		panic("Prepare must be called first")
 	}
 
+	// The build hooks aren't run for a dry run, nothing is built
+	if b.provisionerDryRun {
+		return b.run(originalUi, cache)
+	}
+
+	hookUi := &TargetedUI{
+		Target: b.Name(),
+		Ui:     originalUi,
+	}
+
+	if err := b.runBuildHooks(hookUi, "pre_build", b.preBuild, b.buildHookEnv("", nil, nil)); err != nil {
+		return nil, err
+	}
+
+	artifacts, err := b.run(originalUi, cache)
+
+	b.l.Lock()
+	cancelled := b.cancelled
+	b.l.Unlock()
+	status := BuildHookStatusSuccess
+	switch {
+	case cancelled:
+		status = BuildHookStatusCancelled
+	case err != nil:
+		status = BuildHookStatusFailure
+	}
+
+	env := b.buildHookEnv(status, artifacts, err)
+	if hookErr := b.runBuildHooks(hookUi, "post_build", b.postBuild, env); hookErr != nil {
+		err = MultiErrorAppend(err, hookErr)
+	}
+
+	return artifacts, err
+}
+
+// run runs the builder and the post-processors of the build.
+func (b *coreBuild) run(originalUi Ui, cache Cache) ([]Artifact, error) {
 	// Copy the hooks
 	hooks := make(map[string][]Hook)
 	for hookName, hookList := range b.hooks {
@@ -233,12 +523,49 @@ func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 		Ui:     originalUi,
 	}
 
-	log.Printf("Running builder: %s", b.builderType)
-	ts := CheckpointReporter.AddSpan(b.builderType, "builder", b.builderConfig)
-	builderArtifact, err := b.builder.Run(builderUi, hook, cache)
-	ts.End(err)
+	if b.provisionerDryRun {
+		builderUi.Say("Dry run of the provisioners, the builder isn't run")
+		comm := &DryRunCommunicator{Ui: builderUi}
+		return nil, hook.Run(HookProvision, builderUi, comm, nil)
+	}
+
+	// Cancelling the build also stops waiting to run it again
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.l.Lock()
+	b.cancelRetry = cancel
+	b.l.Unlock()
+
+	tries := 1
+	if b.retry != nil {
+		tries += b.retry.Attempts
+	}
+
+	var builderArtifact Artifact
+	var buildErr error
+	runs := 0
+	err := retry.Config{
+		Tries:          tries,
+		InitialBackoff: buildRetryInitialBackoff,
+		MaxBackoff:     buildRetryMaxBackoff,
+		ShouldRetry:    b.shouldRetry,
+	}.Run(ctx, func(context.Context) error {
+		if runs > 0 {
+			builderUi.Error(fmt.Sprintf(
+				"Build failed with a %s error, running it again (retry %d of %d): %s",
+				ClassifyError(buildErr), runs, b.retry.Attempts, buildErr))
+		}
+		runs++
+
+		log.Printf("Running builder: %s", b.builderType)
+		ts := CheckpointReporter.AddSpan(b.builderType, "builder", b.builderConfig)
+		builderArtifact, buildErr = b.builder.Run(builderUi, hook, cache)
+		ts.End(buildErr)
+		return buildErr
+	})
 	if err != nil {
-		return nil, err
+		// The error of the last run, rather than the one of giving up
+		return nil, buildErr
 	}
 
 	// If there was no result, don't worry about running post-processors
@@ -250,10 +577,25 @@ func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 	errors := make([]error, 0)
 	keepOriginalArtifact := len(b.postProcessors) == 0
 
+	// The named artifacts the post-processors use, and whether one of them
+	// asked to keep them
+	keepNamedArtifacts := make(map[string]bool)
+
 	// Run the post-processors
 PostProcessorRunSeqLoop:
 	for _, ppSeq := range b.postProcessors {
 		priorArtifact := builderArtifact
+		name := ppSeq[0].artifactName
+		if name != "" {
+			priorArtifact = namedArtifact(builderArtifact, name)
+			if priorArtifact == nil {
+				errors = append(errors, fmt.Errorf(
+					"Post-processor %s can't run: the build has no artifact named '%s'",
+					ppSeq[0].processorType, name))
+				continue PostProcessorRunSeqLoop
+			}
+		}
+
 		for i, corePP := range ppSeq {
 			ppUi := &TargetedUI{
 				Target: fmt.Sprintf("%s (%s)", b.Name(), corePP.processorType),
@@ -275,7 +617,16 @@ PostProcessorRunSeqLoop:
 			}
 
 			keep = keep || corePP.keepInputArtifact
-			if i == 0 {
+			if i == 0 && name != "" {
+				// The chain only keeps the named artifact it was given,
+				// and not the other artifacts of the build.
+				if keep && !keepNamedArtifacts[name] {
+					log.Printf(
+						"Flagging to keep artifact '%s' from post-processor '%s'",
+						name, corePP.processorType)
+				}
+				keepNamedArtifacts[name] = keepNamedArtifacts[name] || keep
+			} else if i == 0 {
 				// This is the first post-processor. We handle deleting
 				// previous artifacts a bit different because multiple
 				// post-processors may be using the original and need it.
@@ -307,15 +658,14 @@ PostProcessorRunSeqLoop:
 		}
 	}
 
-	if keepOriginalArtifact {
-		artifacts = append(artifacts, nil)
-		copy(artifacts[1:], artifacts)
-		artifacts[0] = builderArtifact
-	} else {
-		log.Printf("Deleting original artifact for build '%s'", b.name)
-		if err := builderArtifact.Destroy(); err != nil {
-			errors = append(errors, fmt.Errorf("Error destroying builder artifact: %s", err))
-		}
+	kept, err := b.keepBuilderArtifacts(builderArtifact, keepOriginalArtifact, keepNamedArtifacts)
+	if err != nil {
+		errors = append(errors, err)
+	}
+	artifacts = append(kept, artifacts...)
+
+	if err := b.setOutputs(builderArtifact); err != nil {
+		errors = append(errors, err)
 	}
 
 	if len(errors) > 0 {
@@ -325,6 +675,45 @@ PostProcessorRunSeqLoop:
 	return artifacts, err
 }
 
+// outputsData is the data the outputs of a build are rendered with.
+type outputsData struct {
+	ArtifactId string
+	BuilderId  string
+}
+
+// setOutputs renders the outputs of the build from its artifact, and
+// records them for the builds depending on it.
+func (b *coreBuild) setOutputs(artifact Artifact) error {
+	if len(b.outputs) == 0 {
+		return nil
+	}
+
+	ctx := &interpolate.Context{
+		Data: &outputsData{
+			ArtifactId: artifact.Id(),
+			BuilderId:  artifact.BuilderId(),
+		},
+		UserVariables:  b.variables,
+		BuildName:      b.name,
+		BuildType:      b.builderType,
+		TemplatePath:   b.templatePath,
+		Lineage:        b.lineage,
+		LineageVersion: b.lineageVersion,
+	}
+
+	values := make(map[string]string, len(b.outputs))
+	for name, v := range b.outputs {
+		value, err := interpolate.Render(v, ctx)
+		if err != nil {
+			return fmt.Errorf("Error rendering output '%s': %s", name, err)
+		}
+		values[name] = value
+	}
+
+	b.buildOutputs.Set(b.rawName, values)
+	return nil
+}
+
 func (b *coreBuild) SetDebug(val bool) {
 	if b.prepareCalled {
 		panic("prepare has already been called")
@@ -349,7 +738,135 @@ func (b *coreBuild) SetOnError(val string) {
 	b.onError = val
 }
 
+func (b *coreBuild) SetOnErrorGracePeriod(val time.Duration) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.onErrorGracePeriod = val
+}
+
+func (b *coreBuild) SetProvisionerDryRun(val bool) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.provisionerDryRun = val
+}
+
+// keepBuilderArtifacts destroys the artifacts of the builder that aren't
+// kept, and returns those that are. The main artifact is kept if keepMain
+// is set. A named artifact is kept if a post-processor using it asked to,
+// or if none used it and the main artifact is kept.
+func (b *coreBuild) keepBuilderArtifacts(a Artifact, keepMain bool, keepNamed map[string]bool) ([]Artifact, error) {
+	var names []string
+	if named, ok := a.(NamedArtifacts); ok {
+		names = named.ArtifactNames()
+	}
+	destroy := make(map[string]bool)
+	for _, name := range names {
+		keep, used := keepNamed[name]
+		if !keep && (used || !keepMain) {
+			destroy[name] = true
+		}
+	}
+
+	// All the artifacts are kept, or destroyed, together
+	if keepMain && len(destroy) == 0 {
+		return []Artifact{a}, nil
+	}
+	if !keepMain && len(destroy) == len(names) {
+		log.Printf("Deleting original artifact for build '%s'", b.name)
+		if err := a.Destroy(); err != nil {
+			return nil, fmt.Errorf("Error destroying builder artifact: %s", err)
+		}
+		return nil, nil
+	}
+
+	var kept []Artifact
+	var errs *MultiError
+	main := a.(NamedArtifacts).MainArtifact()
+	if main == nil {
+		// The set can't be split, so none of it is destroyed
+		log.Printf("Keeping all the artifacts of build '%s', its main artifact can't be had alone", b.name)
+		return []Artifact{a}, nil
+	}
+	if keepMain {
+		kept = append(kept, main)
+	} else {
+		log.Printf("Deleting original artifact for build '%s'", b.name)
+		if err := main.Destroy(); err != nil {
+			errs = MultiErrorAppend(errs, fmt.Errorf("Error destroying builder artifact: %s", err))
+		}
+	}
+
+	for _, name := range names {
+		artifact := namedArtifact(a, name)
+		if artifact == nil {
+			continue
+		}
+		if !destroy[name] {
+			kept = append(kept, artifact)
+			continue
+		}
+
+		log.Printf("Deleting artifact '%s' for build '%s'", name, b.name)
+		if err := artifact.Destroy(); err != nil {
+			errs = MultiErrorAppend(errs, fmt.Errorf("Error destroying builder artifact '%s': %s", name, err))
+		}
+	}
+
+	if errs != nil {
+		return kept, errs
+	}
+	return kept, nil
+}
+
+// namedArtifact returns the artifact of the build with the given name, or
+// nil if the build didn't produce it.
+func namedArtifact(a Artifact, name string) Artifact {
+	named, ok := a.(NamedArtifacts)
+	if !ok {
+		return nil
+	}
+
+	return named.NamedArtifact(name)
+}
+
+// shouldRetry reports whether the build should be run again after the
+// builder failed with err.
+func (b *coreBuild) shouldRetry(err error) bool {
+	if b.retry == nil {
+		return false
+	}
+
+	b.l.Lock()
+	cancelled := b.cancelled
+	b.l.Unlock()
+	if cancelled {
+		return false
+	}
+
+	retryOn := b.retry.On
+	if len(retryOn) == 0 {
+		retryOn = defaultBuildRetryOn
+	}
+
+	classes := make([]ErrorClass, len(retryOn))
+	for i, on := range retryOn {
+		classes[i] = ErrorClass(on)
+	}
+	return RetryOnErrorClass(classes...)(err)
+}
+
 // Cancels the build if it is running.
 func (b *coreBuild) Cancel() {
+	b.l.Lock()
+	b.cancelled = true
+	if b.cancelRetry != nil {
+		b.cancelRetry()
+	}
+	b.l.Unlock()
+
 	b.builder.Cancel()
 }
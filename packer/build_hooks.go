@@ -0,0 +1,99 @@
+package packer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/packer/template"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// These are the values of PACKER_BUILD_STATUS in the environment of the
+// post_build hooks.
+const (
+	BuildHookStatusSuccess   = "success"
+	BuildHookStatusFailure   = "failure"
+	BuildHookStatusCancelled = "cancelled"
+)
+
+// buildHookCommands renders the commands of the hooks that run for the
+// build named rawName in the template.
+func buildHookCommands(section string, hooks []*template.BuildHook, rawName string, ctx *interpolate.Context) ([]string, error) {
+	var commands []string
+	for i, h := range hooks {
+		if h.Skip(rawName) {
+			continue
+		}
+
+		command, err := interpolate.Render(h.Command, ctx)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%s %d: error rendering command: %s", section, i+1, err)
+		}
+		commands = append(commands, command)
+	}
+
+	return commands, nil
+}
+
+// buildHookEnv returns the environment variables describing the build to
+// its hooks. The status, artifacts and error of the build are only known
+// to the post_build hooks. The IDs of the artifacts are separated by
+// newlines since an ID may itself be a list, like the AMIs of
+// amazon-ebs.
+func (b *coreBuild) buildHookEnv(status string, artifacts []Artifact, err error) []string {
+	env := []string{
+		"PACKER_BUILD_NAME=" + b.name,
+		"PACKER_BUILDER_TYPE=" + b.builderType,
+		"PACKER_TEMPLATE_PATH=" + b.templatePath,
+	}
+	if status == "" {
+		return env
+	}
+
+	ids := make([]string, 0, len(artifacts))
+	for _, a := range artifacts {
+		if a != nil {
+			ids = append(ids, a.Id())
+		}
+	}
+	env = append(env,
+		"PACKER_BUILD_STATUS="+status,
+		"PACKER_ARTIFACT_IDS="+strings.Join(ids, "\n"))
+	if err != nil {
+		env = append(env, "PACKER_BUILD_ERROR="+err.Error())
+	}
+
+	return env
+}
+
+// runBuildHooks runs the commands of the hooks one after the other with
+// the environment of Packer and env, stopping at the first failing one.
+func (b *coreBuild) runBuildHooks(ui Ui, section string, commands []string, env []string) error {
+	for _, command := range commands {
+		ui.Say(fmt.Sprintf("Running %s hook: %s", section, command))
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("/bin/sh", "-c", command)
+		}
+		cmd.Env = append(os.Environ(), env...)
+
+		output, err := cmd.CombinedOutput()
+		if out := strings.TrimRight(string(output), "\r\n"); out != "" {
+			ui.Message(out)
+		}
+		if err != nil {
+			log.Printf("%s hook of build '%s' failed: %s", section, b.name, err)
+			return fmt.Errorf("%s hook failed: %s: %s", section, command, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package packer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/template"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+func TestBuildHookCommands(t *testing.T) {
+	hooks := []*template.BuildHook{
+		{Command: "echo {{build_name}}"},
+		{OnlyExcept: template.OnlyExcept{Only: []string{"other"}}, Command: "echo other"},
+		{OnlyExcept: template.OnlyExcept{Except: []string{"other"}}, Command: "echo {{user `foo`}}"},
+	}
+	ctx := &interpolate.Context{
+		BuildName:     "test",
+		UserVariables: map[string]string{"foo": "bar"},
+	}
+
+	commands, err := buildHookCommands("pre_build", hooks, "test", ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"echo test", "echo bar"}
+	if strings.Join(commands, ",") != strings.Join(expected, ",") {
+		t.Fatalf("bad: %#v", commands)
+	}
+}
+
+func TestBuild_RunBuildHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook commands are sh commands")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	pre := filepath.Join(td, "pre")
+	post := filepath.Join(td, "post")
+
+	build := testBuild()
+	build.preBuild = []string{"echo $PACKER_BUILD_NAME $PACKER_BUILD_STATUS > " + pre}
+	build.postBuild = []string{"echo $PACKER_BUILD_NAME $PACKER_BUILD_STATUS $PACKER_ARTIFACT_IDS > " + post}
+	build.Prepare()
+
+	if _, err := build.Run(testUi(), &TestCache{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if data, _ := ioutil.ReadFile(pre); string(data) != "test\n" {
+		t.Fatalf("bad pre_build environment: %q", data)
+	}
+	if data, _ := ioutil.ReadFile(post); string(data) != "test success b pp\n" {
+		t.Fatalf("bad post_build environment: %q", data)
+	}
+}
+
+func TestBuild_RunBuildHooksPreFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook commands are sh commands")
+	}
+
+	build := testBuild()
+	build.preBuild = []string{"exit 1"}
+	build.postBuild = []string{"exit 1"}
+	build.Prepare()
+
+	if _, err := build.Run(testUi(), &TestCache{}); err == nil {
+		t.Fatal("should have error")
+	}
+	if build.builder.(*MockBuilder).RunCalled {
+		t.Fatal("should not run the builder")
+	}
+}
+
+func TestBuild_RunBuildHooksPostFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook commands are sh commands")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	post := filepath.Join(td, "post")
+
+	build := testBuild()
+	build.builder = &flakyBuilder{
+		MockBuilder: MockBuilder{ArtifactId: "b"},
+		errs:        []error{errors.New("builder failed")},
+	}
+	build.postBuild = []string{"echo $PACKER_BUILD_STATUS $PACKER_BUILD_ERROR > " + post}
+	build.Prepare()
+
+	if _, err := build.Run(testUi(), &TestCache{}); err == nil {
+		t.Fatal("should have error")
+	}
+	if data, _ := ioutil.ReadFile(post); string(data) != "failure builder failed\n" {
+		t.Fatalf("bad post_build environment: %q", data)
+	}
+}
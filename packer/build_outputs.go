@@ -0,0 +1,54 @@
+package packer
+
+import (
+	"fmt"
+	"sync"
+)
+
+// buildOutputs keeps the outputs of the builds of a run, so that the builds
+// depending on them can use them.
+type buildOutputs struct {
+	// declared are the names of the outputs of each build of the template.
+	declared map[string][]string
+
+	l      sync.Mutex
+	values map[string]map[string]string
+}
+
+func newBuildOutputs(declared map[string][]string) *buildOutputs {
+	return &buildOutputs{
+		declared: declared,
+		values:   make(map[string]map[string]string),
+	}
+}
+
+// Set records the outputs of the given build.
+func (o *buildOutputs) Set(build string, values map[string]string) {
+	o.l.Lock()
+	defer o.l.Unlock()
+	o.values[build] = values
+}
+
+// Config returns the outputs of the given builds, keyed by
+// "<build>.<output>", as they are passed to the components of a build.
+// The outputs of builds that haven't run, which is the case when templates
+// are only validated, are placeholders.
+func (o *buildOutputs) Config(builds []string) map[string]string {
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	result := make(map[string]string)
+	for _, build := range builds {
+		for _, name := range o.declared[build] {
+			key := fmt.Sprintf("%s.%s", build, name)
+			if values, ok := o.values[build]; ok {
+				if v, ok := values[name]; ok {
+					result[key] = v
+				}
+				continue
+			}
+			result[key] = fmt.Sprintf("<%s>", key)
+		}
+	}
+	return result
+}
@@ -1,8 +1,15 @@
 package packer
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/template"
 )
 
 func testBuild() *coreBuild {
@@ -15,11 +22,11 @@ func testBuild() *coreBuild {
 			"foo": {&MockHook{}},
 		},
 		provisioners: []coreBuildProvisioner{
-			{"mock-provisioner", &MockProvisioner{}, []interface{}{42}},
+			{"mock-provisioner", &MockProvisioner{}, []interface{}{42}, ""},
 		},
 		postProcessors: [][]coreBuildPostProcessor{
 			{
-				{&MockPostProcessor{ArtifactId: "pp"}, "testPP", make(map[string]interface{}), true},
+				{&MockPostProcessor{ArtifactId: "pp"}, "testPP", make(map[string]interface{}), true, ""},
 			},
 		},
 		variables: make(map[string]string),
@@ -29,11 +36,16 @@ func testBuild() *coreBuild {
 
 func testDefaultPackerConfig() map[string]interface{} {
 	return map[string]interface{}{
-		BuildNameConfigKey:     "test",
-		BuilderTypeConfigKey:   "foo",
-		DebugConfigKey:         false,
-		ForceConfigKey:         false,
-		OnErrorConfigKey:       "cleanup",
+		BuildNameConfigKey:          "test",
+		BuilderTypeConfigKey:        "foo",
+		DebugConfigKey:              false,
+		ForceConfigKey:              false,
+		OnErrorConfigKey:            "cleanup",
+		OnErrorGracePeriodConfigKey: "0s",
+		ProvisionerDryRunConfigKey:  false,
+		ProvisionerKeysConfigKey: []string{
+			"a5c2a5ce96ddf6b1242325b4b835b225a03d5193375cab54fe7106137e9822c7",
+		},
 		TemplatePathKey:        "",
 		UserVariablesConfigKey: make(map[string]string),
 	}
@@ -149,6 +161,9 @@ func TestBuildPrepare_variables_default(t *testing.T) {
 	packerConfig[UserVariablesConfigKey] = map[string]string{
 		"foo": "bar",
 	}
+	packerConfig[ProvisionerKeysConfigKey] = []string{
+		"c49ebd225939054b11ef5f3f876b04d847262a10c14a18d8e2bd3c02276bacb0",
+	}
 
 	build := testBuild()
 	build.variables["foo"] = "bar"
@@ -171,6 +186,118 @@ func TestBuildPrepare_variables_default(t *testing.T) {
 	}
 }
 
+func TestBuild_provisionerKeys(t *testing.T) {
+	provisioners := []coreBuildProvisioner{
+		{"shell", &MockProvisioner{}, []interface{}{map[string]interface{}{"inline": "a"}}, ""},
+		{"shell", &MockProvisioner{}, []interface{}{map[string]interface{}{"inline": "b"}}, "deps"},
+		{"shell", &MockProvisioner{}, []interface{}{map[string]interface{}{"inline": "c"}}, "deps"},
+		{"file", &MockProvisioner{}, []interface{}{map[string]interface{}{"source": "d"}}, ""},
+	}
+	build := testBuild()
+	build.builderConfig = map[string]interface{}{"image": "ubuntu"}
+	build.provisioners = provisioners
+	build.variables = map[string]string{"foo": "bar"}
+
+	keys := build.provisionerKeys()
+	if len(keys) != 4 {
+		t.Fatalf("bad: %#v", keys)
+	}
+	if keys[0] == "" || keys[1] != "" || keys[2] == "" || keys[3] == "" {
+		t.Fatalf("only the last provisioner of a group should have a key: %#v", keys)
+	}
+
+	// Same configuration, same keys
+	if again := build.provisionerKeys(); !reflect.DeepEqual(again, keys) {
+		t.Fatalf("bad: %#v", again)
+	}
+
+	// A change invalidates the keys from the provisioner
+	provisioners[2].config = []interface{}{map[string]interface{}{"inline": "changed"}}
+	changed := build.provisionerKeys()
+	if changed[0] != keys[0] || changed[2] == keys[2] || changed[3] == keys[3] {
+		t.Fatalf("bad: %#v", changed)
+	}
+	keys = changed
+
+	// A change of the variables invalidates all of them
+	build.variables = map[string]string{"foo": "baz"}
+	changed = build.provisionerKeys()
+	if changed[0] == keys[0] || changed[3] == keys[3] {
+		t.Fatalf("bad: %#v", changed)
+	}
+	keys = changed
+
+	// So does a change of the builder configuration
+	build.builderConfig = map[string]interface{}{"image": "debian"}
+	changed = build.provisionerKeys()
+	if changed[0] == keys[0] || changed[3] == keys[3] {
+		t.Fatalf("bad: %#v", changed)
+	}
+	keys = changed
+
+	// Or of the builder
+	build.builderType = "bar"
+	changed = build.provisionerKeys()
+	if changed[0] == keys[0] || changed[3] == keys[3] {
+		t.Fatalf("bad: %#v", changed)
+	}
+
+	build.provisioners = nil
+	if keys := build.provisionerKeys(); keys != nil {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
+
+func TestBuild_provisionerKeysFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "script.sh")
+	if err := ioutil.WriteFile(script, []byte("echo a"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "files"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	file := filepath.Join(dir, "files", "file")
+	if err := ioutil.WriteFile(file, []byte("a"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	build := testBuild()
+	build.templatePath = filepath.Join(dir, "template.json")
+	build.provisioners = []coreBuildProvisioner{
+		{"shell", &MockProvisioner{}, []interface{}{map[string]interface{}{"scripts": []interface{}{"{{template_dir}}/script.sh"}}}, ""},
+		{"file", &MockProvisioner{}, []interface{}{map[string]interface{}{"source": filepath.Join(dir, "files")}}, ""},
+	}
+	keys := build.provisionerKeys()
+	if len(keys) != 2 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	// A change of a script invalidates the keys from its provisioner
+	if err := ioutil.WriteFile(script, []byte("echo b"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	changed := build.provisionerKeys()
+	if changed[0] == keys[0] || changed[1] == keys[1] {
+		t.Fatalf("bad: %#v", changed)
+	}
+	keys = changed
+
+	// So does a change of a file in a directory
+	if err := ioutil.WriteFile(file, []byte("b"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	changed = build.provisionerKeys()
+	if changed[0] != keys[0] || changed[1] == keys[1] {
+		t.Fatalf("bad: %#v", changed)
+	}
+}
+
 func TestBuild_Run(t *testing.T) {
 	cache := &TestCache{}
 	ui := testUi()
@@ -217,6 +344,46 @@ func TestBuild_Run(t *testing.T) {
 	}
 }
 
+func TestBuild_Run_ProvisionerDryRun(t *testing.T) {
+	cache := &TestCache{}
+	ui := testUi()
+
+	build := testBuild()
+	build.SetProvisionerDryRun(true)
+	build.Prepare()
+
+	packerConfig := testDefaultPackerConfig()
+	packerConfig[ProvisionerDryRunConfigKey] = true
+	prov := build.provisioners[0].provisioner.(*MockProvisioner)
+	if !reflect.DeepEqual(prov.PrepConfigs, []interface{}{42, packerConfig}) {
+		t.Fatalf("bad: %#v", prov.PrepConfigs)
+	}
+
+	artifacts, err := build.Run(ui, cache)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(artifacts) != 0 {
+		t.Fatalf("bad: %#v", artifacts)
+	}
+
+	builder := build.builder.(*MockBuilder)
+	if builder.RunCalled {
+		t.Fatal("builder should not be run")
+	}
+	if !prov.ProvCalled {
+		t.Fatal("provisioner should be run")
+	}
+	if _, ok := prov.ProvCommunicator.(*DryRunCommunicator); !ok {
+		t.Fatalf("bad communicator: %#v", prov.ProvCommunicator)
+	}
+
+	pp := build.postProcessors[0][0].processor.(*MockPostProcessor)
+	if pp.PostProcessCalled {
+		t.Fatal("post-processor should not be run")
+	}
+}
+
 func TestBuild_Run_Artifacts(t *testing.T) {
 	cache := &TestCache{}
 	ui := testUi()
@@ -247,7 +414,7 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	build = testBuild()
 	build.postProcessors = [][]coreBuildPostProcessor{
 		{
-			{&MockPostProcessor{ArtifactId: "pp"}, "pp", make(map[string]interface{}), false},
+			{&MockPostProcessor{ArtifactId: "pp"}, "pp", make(map[string]interface{}), false, ""},
 		},
 	}
 
@@ -272,10 +439,10 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	build = testBuild()
 	build.postProcessors = [][]coreBuildPostProcessor{
 		{
-			{&MockPostProcessor{ArtifactId: "pp1"}, "pp", make(map[string]interface{}), false},
+			{&MockPostProcessor{ArtifactId: "pp1"}, "pp", make(map[string]interface{}), false, ""},
 		},
 		{
-			{&MockPostProcessor{ArtifactId: "pp2"}, "pp", make(map[string]interface{}), true},
+			{&MockPostProcessor{ArtifactId: "pp2"}, "pp", make(map[string]interface{}), true, ""},
 		},
 	}
 
@@ -300,12 +467,12 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	build = testBuild()
 	build.postProcessors = [][]coreBuildPostProcessor{
 		{
-			{&MockPostProcessor{ArtifactId: "pp1a"}, "pp", make(map[string]interface{}), false},
-			{&MockPostProcessor{ArtifactId: "pp1b"}, "pp", make(map[string]interface{}), true},
+			{&MockPostProcessor{ArtifactId: "pp1a"}, "pp", make(map[string]interface{}), false, ""},
+			{&MockPostProcessor{ArtifactId: "pp1b"}, "pp", make(map[string]interface{}), true, ""},
 		},
 		{
-			{&MockPostProcessor{ArtifactId: "pp2a"}, "pp", make(map[string]interface{}), false},
-			{&MockPostProcessor{ArtifactId: "pp2b"}, "pp", make(map[string]interface{}), false},
+			{&MockPostProcessor{ArtifactId: "pp2a"}, "pp", make(map[string]interface{}), false, ""},
+			{&MockPostProcessor{ArtifactId: "pp2b"}, "pp", make(map[string]interface{}), false, ""},
 		},
 	}
 
@@ -331,7 +498,7 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	build.postProcessors = [][]coreBuildPostProcessor{
 		{
 			{
-				&MockPostProcessor{ArtifactId: "pp", Keep: true}, "pp", make(map[string]interface{}), false,
+				&MockPostProcessor{ArtifactId: "pp", Keep: true}, "pp", make(map[string]interface{}), false, "",
 			},
 		},
 	}
@@ -353,6 +520,154 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	}
 }
 
+func TestBuild_Run_NamedArtifacts(t *testing.T) {
+	cache := &TestCache{}
+	ui := testUi()
+
+	vmdk := &MockPostProcessor{ArtifactId: "pp1"}
+	main := &MockPostProcessor{ArtifactId: "pp2"}
+	build := testBuild()
+	build.builder = &MockBuilder{ArtifactId: "b", NamedArtifactIds: []string{"vmdk"}}
+	build.postProcessors = [][]coreBuildPostProcessor{
+		{
+			{vmdk, "pp", make(map[string]interface{}), true, "vmdk"},
+		},
+		{
+			{main, "pp", make(map[string]interface{}), false, ""},
+		},
+	}
+
+	build.Prepare()
+	artifacts, err := build.Run(ui, cache)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if vmdk.PostProcessArtifact.Id() != "vmdk" {
+		t.Fatalf("bad artifact: %s", vmdk.PostProcessArtifact.Id())
+	}
+	if main.PostProcessArtifact.Id() != "b" {
+		t.Fatalf("bad artifact: %s", main.PostProcessArtifact.Id())
+	}
+
+	// Only the named artifact is kept, not the main one
+	expectedIds := []string{"vmdk", "pp1", "pp2"}
+	artifactIds := make([]string, len(artifacts))
+	for i, artifact := range artifacts {
+		artifactIds[i] = artifact.Id()
+	}
+
+	if !reflect.DeepEqual(artifactIds, expectedIds) {
+		t.Fatalf("unexpected ids: %#v", artifactIds)
+	}
+
+	// Test case: two chains using named artifacts, only one of them asking
+	// to keep its input, and the main artifact kept.
+	build = testBuild()
+	build.builder = &MockBuilder{ArtifactId: "b", NamedArtifactIds: []string{"ova", "vmdk"}}
+	build.postProcessors = [][]coreBuildPostProcessor{
+		{
+			{&MockPostProcessor{ArtifactId: "pp1"}, "pp", make(map[string]interface{}), true, "vmdk"},
+		},
+		{
+			{&MockPostProcessor{ArtifactId: "pp2"}, "pp", make(map[string]interface{}), false, "ova"},
+		},
+		{
+			{&MockPostProcessor{ArtifactId: "pp3"}, "pp", make(map[string]interface{}), true, ""},
+		},
+	}
+
+	build.Prepare()
+	artifacts, err = build.Run(ui, cache)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expectedIds = []string{"b", "vmdk", "pp1", "pp2", "pp3"}
+	artifactIds = make([]string, len(artifacts))
+	for i, artifact := range artifacts {
+		artifactIds[i] = artifact.Id()
+	}
+
+	if !reflect.DeepEqual(artifactIds, expectedIds) {
+		t.Fatalf("unexpected ids: %#v", artifactIds)
+	}
+
+	// Test case: a chain asking for an artifact the build doesn't have
+	// fails, and the others still run.
+	main = &MockPostProcessor{ArtifactId: "pp2"}
+	build = testBuild()
+	build.postProcessors = [][]coreBuildPostProcessor{
+		{
+			{&MockPostProcessor{ArtifactId: "pp1"}, "pp", make(map[string]interface{}), false, "vmdk"},
+		},
+		{
+			{main, "pp", make(map[string]interface{}), false, ""},
+		},
+	}
+
+	build.Prepare()
+	artifacts, err = build.Run(ui, cache)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if !main.PostProcessCalled {
+		t.Fatal("post-processor should be called")
+	}
+	if len(artifacts) != 1 || artifacts[0].Id() != "pp2" {
+		t.Fatalf("bad: %#v", artifacts)
+	}
+}
+
+func TestBuild_keepBuilderArtifacts(t *testing.T) {
+	cases := []struct {
+		keepMain  bool
+		keepNamed map[string]bool
+		kept      []string
+		destroyed []string
+	}{
+		{false, nil, nil, []string{"b", "ova", "vmdk"}},
+		{true, nil, []string{"b"}, nil},
+		{true, map[string]bool{"vmdk": false}, []string{"b", "ova"}, []string{"vmdk"}},
+		{false, map[string]bool{"vmdk": true, "ova": false}, []string{"vmdk"}, []string{"b", "ova"}},
+		{false, map[string]bool{"vmdk": true}, []string{"vmdk"}, []string{"b", "ova"}},
+	}
+
+	for i, tc := range cases {
+		main := &MockArtifact{IdValue: "b"}
+		set := &ArtifactSet{
+			Artifact: main,
+			Named: map[string]Artifact{
+				"ova":  &MockArtifact{IdValue: "ova"},
+				"vmdk": &MockArtifact{IdValue: "vmdk"},
+			},
+		}
+
+		kept, err := testBuild().keepBuilderArtifacts(set, tc.keepMain, tc.keepNamed)
+		if err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+
+		var keptIds []string
+		for _, artifact := range kept {
+			keptIds = append(keptIds, artifact.Id())
+		}
+		if !reflect.DeepEqual(keptIds, tc.kept) {
+			t.Fatalf("%d: bad kept: %#v", i, keptIds)
+		}
+
+		var destroyed []string
+		for _, artifact := range []*MockArtifact{main, set.Named["ova"].(*MockArtifact), set.Named["vmdk"].(*MockArtifact)} {
+			if artifact.DestroyCalled {
+				destroyed = append(destroyed, artifact.IdValue)
+			}
+		}
+		if !reflect.DeepEqual(destroyed, tc.destroyed) {
+			t.Fatalf("%d: bad destroyed: %#v", i, destroyed)
+		}
+	}
+}
+
 func TestBuild_RunBeforePrepare(t *testing.T) {
 	defer func() {
 		p := recover()
@@ -377,3 +692,110 @@ func TestBuild_Cancel(t *testing.T) {
 		t.Fatal("cancel should be called")
 	}
 }
+
+// flakyBuilder fails with the given errors, one per run, before running
+// like a MockBuilder.
+type flakyBuilder struct {
+	MockBuilder
+	errs []error
+	runs int
+}
+
+func (b *flakyBuilder) Run(ui Ui, h Hook, c Cache) (Artifact, error) {
+	b.runs++
+	if len(b.errs) > 0 {
+		err := b.errs[0]
+		b.errs = b.errs[1:]
+		return nil, err
+	}
+
+	return b.MockBuilder.Run(ui, h, c)
+}
+
+func TestBuild_RunRetry(t *testing.T) {
+	defer func(initial, max time.Duration) {
+		buildRetryInitialBackoff, buildRetryMaxBackoff = initial, max
+	}(buildRetryInitialBackoff, buildRetryMaxBackoff)
+	buildRetryInitialBackoff, buildRetryMaxBackoff = time.Millisecond, time.Millisecond
+
+	capacity := NewClassifiedError(ErrorClassCapacity, errors.New("capacity"))
+	throttle := errors.New("RequestLimitExceeded: Request limit exceeded.")
+	provisioner := NewClassifiedError(ErrorClassProvisioner, errors.New("script failed"))
+
+	cases := []struct {
+		retry   *template.BuildRetry
+		errs    []error
+		runs    int
+		success bool
+	}{
+		{nil, []error{capacity}, 1, false},
+		{&template.BuildRetry{Attempts: 2}, []error{capacity, throttle}, 3, true},
+		{&template.BuildRetry{Attempts: 1}, []error{capacity, capacity}, 2, false},
+		{&template.BuildRetry{Attempts: 2}, []error{provisioner}, 1, false},
+		{&template.BuildRetry{Attempts: 2, On: []string{"provisioner"}}, []error{provisioner}, 2, true},
+		{&template.BuildRetry{Attempts: 2, On: []string{"provisioner"}}, []error{capacity}, 1, false},
+	}
+
+	for i, tc := range cases {
+		builder := &flakyBuilder{MockBuilder: MockBuilder{ArtifactId: "b"}, errs: tc.errs}
+		build := testBuild()
+		build.builder = builder
+		build.retry = tc.retry
+		build.Prepare()
+
+		_, err := build.Run(testUi(), &TestCache{})
+		if (err == nil) != tc.success {
+			t.Fatalf("%d: bad err: %v", i, err)
+		}
+		if builder.runs != tc.runs {
+			t.Fatalf("%d: bad runs: %d, expected %d", i, builder.runs, tc.runs)
+		}
+	}
+}
+
+func TestBuild_RunRetryCancelled(t *testing.T) {
+	builder := &flakyBuilder{
+		MockBuilder: MockBuilder{ArtifactId: "b"},
+		errs:        []error{NewClassifiedError(ErrorClassCapacity, errors.New("capacity"))},
+	}
+	build := testBuild()
+	build.builder = builder
+	build.retry = &template.BuildRetry{Attempts: 2}
+	build.Prepare()
+	build.Cancel()
+
+	if _, err := build.Run(testUi(), &TestCache{}); err == nil {
+		t.Fatal("should have error")
+	}
+	if builder.runs != 1 {
+		t.Fatalf("cancelled build should not be retried: %d runs", builder.runs)
+	}
+}
+
+func TestBuild_RunRetryCancelledWaiting(t *testing.T) {
+	builder := &flakyBuilder{
+		MockBuilder: MockBuilder{ArtifactId: "b"},
+		errs:        []error{NewClassifiedError(ErrorClassCapacity, errors.New("capacity"))},
+	}
+	build := testBuild()
+	build.builder = builder
+	build.retry = &template.BuildRetry{Attempts: 2}
+	build.Prepare()
+
+	// The build is cancelled while it waits to run again
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		build.Cancel()
+	}()
+
+	start := time.Now()
+	if _, err := build.Run(testUi(), &TestCache{}); err == nil || err.Error() != "capacity" {
+		t.Fatalf("bad: %v", err)
+	}
+	if builder.runs != 1 {
+		t.Fatalf("cancelled build should not be retried: %d runs", builder.runs)
+	}
+	if time.Since(start) > buildRetryInitialBackoff/2 {
+		t.Fatal("should stop waiting once cancelled")
+	}
+}
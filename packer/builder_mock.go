@@ -8,10 +8,11 @@ import (
 // You can set some fake return values and you can keep track of what
 // methods were called on the builder. It is fairly basic.
 type MockBuilder struct {
-	ArtifactId      string
-	PrepareWarnings []string
-	RunErrResult    bool
-	RunNilResult    bool
+	ArtifactId       string
+	NamedArtifactIds []string
+	PrepareWarnings  []string
+	RunErrResult     bool
+	RunNilResult     bool
 
 	PrepareCalled bool
 	PrepareConfig []interface{}
@@ -48,9 +49,18 @@ func (tb *MockBuilder) Run(ui Ui, h Hook, c Cache) (Artifact, error) {
 		}
 	}
 
-	return &MockArtifact{
+	artifact := &MockArtifact{
 		IdValue: tb.ArtifactId,
-	}, nil
+	}
+	if len(tb.NamedArtifactIds) > 0 {
+		named := make(map[string]Artifact)
+		for _, id := range tb.NamedArtifactIds {
+			named[id] = &MockArtifact{IdValue: id}
+		}
+		return &ArtifactSet{Artifact: artifact, Named: named}, nil
+	}
+
+	return artifact, nil
 }
 
 func (tb *MockBuilder) Cancel() {
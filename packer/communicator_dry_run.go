@@ -0,0 +1,83 @@
+package packer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// dryRunMaxShownSize is the size up to which the content of the text files
+// uploaded during a dry run is shown.
+const dryRunMaxShownSize = 64 * 1024
+
+// DryRunCommunicator is a Communicator that doesn't connect to any machine.
+// It shows the commands run and the files transferred on the UI instead,
+// so that what the provisioners would do can be reviewed. Commands always
+// succeed without any output.
+type DryRunCommunicator struct {
+	Ui Ui
+}
+
+func (c *DryRunCommunicator) Start(rc *RemoteCmd) error {
+	c.Ui.Message(fmt.Sprintf("Would run: %s", rc.Command))
+	go rc.SetExited(0)
+	return nil
+}
+
+func (c *DryRunCommunicator) Upload(path string, r io.Reader, fi *os.FileInfo) error {
+	var data bytes.Buffer
+	if _, err := io.Copy(&data, r); err != nil {
+		return err
+	}
+
+	c.Ui.Message(fmt.Sprintf("Would upload %d bytes to: %s", data.Len(), path))
+	if data.Len() <= dryRunMaxShownSize && isText(data.Bytes()) {
+		for _, line := range strings.Split(strings.TrimRight(data.String(), "\n"), "\n") {
+			c.Ui.Message("    " + line)
+		}
+	}
+	return nil
+}
+
+func (c *DryRunCommunicator) UploadDir(dst string, src string, exclude []string) error {
+	c.Ui.Message(fmt.Sprintf("Would upload directory %s to: %s", src, dst))
+	if len(exclude) > 0 {
+		c.Ui.Message(fmt.Sprintf("    excluding: %s", strings.Join(exclude, ", ")))
+	}
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		c.Ui.Message("    " + filepath.ToSlash(rel))
+		return nil
+	})
+}
+
+func (c *DryRunCommunicator) Download(path string, w io.Writer) error {
+	c.Ui.Message(fmt.Sprintf("Would download: %s", path))
+	return nil
+}
+
+func (c *DryRunCommunicator) DownloadDir(src string, dst string, exclude []string) error {
+	c.Ui.Message(fmt.Sprintf("Would download directory %s to: %s", src, dst))
+	return nil
+}
+
+// isText reports whether the data looks like text, rather than binary
+// content that isn't worth showing.
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) == -1
+}
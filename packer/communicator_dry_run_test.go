@@ -0,0 +1,74 @@
+package packer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRunCommunicator_impl(t *testing.T) {
+	var _ Communicator = new(DryRunCommunicator)
+}
+
+func TestDryRunCommunicator(t *testing.T) {
+	ui := testUi()
+	c := &DryRunCommunicator{Ui: ui}
+
+	cmd := &RemoteCmd{Command: "echo foo"}
+	if err := c.Start(cmd); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd.Wait()
+	if cmd.ExitStatus != 0 {
+		t.Fatalf("bad exit status: %d", cmd.ExitStatus)
+	}
+
+	if err := c.Upload("/tmp/script.sh", strings.NewReader("#!/bin/sh\necho bar\n"), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := c.Upload("/tmp/blob", bytes.NewReader([]byte{0, 1, 2}), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := "Would run: echo foo\n" +
+		"Would upload 19 bytes to: /tmp/script.sh\n" +
+		"    #!/bin/sh\n" +
+		"    echo bar\n" +
+		"Would upload 3 bytes to: /tmp/blob\n"
+	if out := readWriter(ui); out != expected {
+		t.Fatalf("bad output: %q", out)
+	}
+}
+
+func TestDryRunCommunicator_UploadDir(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	if err := os.MkdirAll(filepath.Join(td, "sub"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, name := range []string{"a", filepath.Join("sub", "b")} {
+		if err := ioutil.WriteFile(filepath.Join(td, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	ui := testUi()
+	c := &DryRunCommunicator{Ui: ui}
+	if err := c.UploadDir("/dst", td, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := "Would upload directory " + td + " to: /dst\n" +
+		"    a\n" +
+		"    sub/b\n"
+	if out := readWriter(ui); out != expected {
+		t.Fatalf("bad output: %q", out)
+	}
+}
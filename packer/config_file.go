@@ -38,3 +38,28 @@ func ConfigTmpDir() (string, error) {
 	}
 	return td, nil
 }
+
+// ProjectConfigFile returns the path to the configuration file of the
+// project: the closest ".packerconfig" file of the current directory or
+// one of its parents, other than the default configuration file. It
+// returns an empty path if there is none.
+func ProjectConfigFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	global, _ := configFile()
+
+	for {
+		path := filepath.Join(dir, ".packerconfig")
+		if _, err := os.Stat(path); err == nil && path != global {
+			return path, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
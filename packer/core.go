@@ -19,6 +19,12 @@ type Core struct {
 	variables  map[string]string
 	builds     map[string]*template.Builder
 	version    string
+	outputs    *buildOutputs
+
+	// lineage is the image family the builds produce a version of, and
+	// lineageVersion the version, when the template sets one.
+	lineage        string
+	lineageVersion int
 }
 
 // CoreConfig is the structure for initializing a new Core. Once a CoreConfig
@@ -81,6 +87,15 @@ func NewCore(c *CoreConfig) (*Core, error) {
 		result.builds[v] = b
 	}
 
+	declared := make(map[string][]string)
+	for _, b := range c.Template.Builders {
+		for name := range b.Outputs {
+			declared[b.Name] = append(declared[b.Name], name)
+		}
+		sort.Strings(declared[b.Name])
+	}
+	result.outputs = newBuildOutputs(declared)
+
 	return result, nil
 }
 
@@ -157,6 +172,7 @@ func (c *Core) Build(n string) (Build, error) {
 			pType:       rawP.Type,
 			provisioner: provisioner,
 			config:      config,
+			checkpoint:  rawP.Checkpoint,
 		})
 	}
 
@@ -187,6 +203,7 @@ func (c *Core) Build(n string) (Build, error) {
 				processorType:     rawP.Type,
 				config:            rawP.Config,
 				keepInputArtifact: rawP.KeepInputArtifact,
+				artifactName:      rawP.InputArtifact,
 			})
 		}
 
@@ -198,6 +215,19 @@ func (c *Core) Build(n string) (Build, error) {
 		postProcessors = append(postProcessors, current)
 	}
 
+	// Setup the build hooks, with the commands rendered for this build
+	ctx := c.Context()
+	ctx.BuildName = n
+	ctx.BuildType = configBuilder.Type
+	preBuild, err := buildHookCommands("pre_build", c.Template.PreBuild, rawName, ctx)
+	if err != nil {
+		return nil, err
+	}
+	postBuild, err := buildHookCommands("post_build", c.Template.PostBuild, rawName, ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// TODO hooks one day
 
 	return &coreBuild{
@@ -207,16 +237,47 @@ func (c *Core) Build(n string) (Build, error) {
 		builderType:    configBuilder.Type,
 		postProcessors: postProcessors,
 		provisioners:   provisioners,
+		preBuild:       preBuild,
+		postBuild:      postBuild,
+		retry:          c.Template.BuildRetry,
 		templatePath:   c.Template.Path,
 		variables:      c.variables,
+		lineage:        c.lineage,
+		lineageVersion: c.lineageVersion,
+		rawName:        rawName,
+		dependsOn:      configBuilder.DependsOn,
+		outputs:        configBuilder.Outputs,
+		buildOutputs:   c.outputs,
 	}, nil
 }
 
+// BuildDependencies returns the names of the builds the given build depends
+// on, which must finish before it's prepared and run.
+func (c *Core) BuildDependencies(n string) ([]string, error) {
+	configBuilder, ok := c.builds[n]
+	if !ok {
+		return nil, fmt.Errorf("no such build found: %s", n)
+	}
+
+	var result []string
+	for _, dep := range configBuilder.DependsOn {
+		for name, b := range c.builds {
+			if b.Name == dep {
+				result = append(result, name)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
 // Context returns an interpolation context.
 func (c *Core) Context() *interpolate.Context {
 	return &interpolate.Context{
-		TemplatePath:  c.Template.Path,
-		UserVariables: c.variables,
+		TemplatePath:   c.Template.Path,
+		UserVariables:  c.variables,
+		Lineage:        c.lineage,
+		LineageVersion: c.lineageVersion,
 	}
 }
 
@@ -253,8 +314,27 @@ func (c *Core) validate() error {
 		}
 	}
 
-	// Validate variables are set
+	// Validate the required version and capabilities are satisfied, before
+	// anything that would fail in a less helpful way if they aren't
+	if err := template.CheckRequiredVersion(c.Template.RequiredVersion, c.version); err != nil {
+		return err
+	}
+	if err := c.validateCapabilities(); err != nil {
+		return err
+	}
+
+	// Validate the build retry policy only retries on known error classes
 	var err error
+	if retry := c.Template.BuildRetry; retry != nil {
+		for _, on := range retry.On {
+			if !isErrorClass(ErrorClass(on)) {
+				err = multierror.Append(err, fmt.Errorf(
+					"build_retry: unknown error class: %s", on))
+			}
+		}
+	}
+
+	// Validate variables are set
 	for n, v := range c.Template.Variables {
 		if v.Required {
 			if _, ok := c.variables[n]; !ok {
@@ -271,6 +351,40 @@ func (c *Core) validate() error {
 	return err
 }
 
+// validateCapabilities checks the components the template requires exist,
+// built in or as plugins.
+func (c *Core) validateCapabilities() error {
+	required := c.Template.RequiredCapabilities
+	if required == nil {
+		return nil
+	}
+
+	var err error
+	missing := func(kind, name string) {
+		err = multierror.Append(err, fmt.Errorf(
+			"This template requires the %s '%s', which this Packer doesn't "+
+				"have. It may be in a newer version of Packer, or in a "+
+				"plugin that has to be installed.", kind, name))
+	}
+	for _, name := range required.Builders {
+		if b, lerr := c.components.Builder(name); lerr != nil || b == nil {
+			missing("builder", name)
+		}
+	}
+	for _, name := range required.Provisioners {
+		if p, lerr := c.components.Provisioner(name); lerr != nil || p == nil {
+			missing("provisioner", name)
+		}
+	}
+	for _, name := range required.PostProcessors {
+		if p, lerr := c.components.PostProcessor(name); lerr != nil || p == nil {
+			missing("post-processor", name)
+		}
+	}
+
+	return err
+}
+
 func (c *Core) init() error {
 	if c.variables == nil {
 		c.variables = make(map[string]string)
@@ -302,6 +416,29 @@ func (c *Core) init() error {
 		c.variables[k] = def
 	}
 
+	// Work out the version of the lineage this run builds, after the
+	// latest one recorded in the manifest.
+	if lineage := c.Template.Lineage; lineage != nil {
+		name, err := interpolate.Render(lineage.Name, c.Context())
+		if err != nil {
+			return fmt.Errorf("Error interpolating lineage name: %s", err)
+		}
+		manifest, err := interpolate.Render(lineage.Manifest, c.Context())
+		if err != nil {
+			return fmt.Errorf("Error interpolating lineage manifest: %s", err)
+		}
+		if manifest == "" {
+			manifest = DefaultLineageManifest
+		}
+
+		version, err := NextLineageVersion(manifest, name)
+		if err != nil {
+			return fmt.Errorf("Error reading lineage history: %s", err)
+		}
+		c.lineage = name
+		c.lineageVersion = version
+	}
+
 	// Interpolate the push configuration
 	if _, err := interpolate.RenderInterface(&c.Template.Push, c.Context()); err != nil {
 		return fmt.Errorf("Error interpolating 'push': %s", err)
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	configHelper "github.com/hashicorp/packer/helper/config"
@@ -200,6 +201,84 @@ func TestCoreBuild_buildTypeVar(t *testing.T) {
 	}
 }
 
+func TestCoreBuild_outputs(t *testing.T) {
+	config := TestCoreConfig(t)
+	testCoreTemplate(t, config, fixtureDir("build-outputs.json"))
+	b := TestBuilder(t, config, "test")
+	core := TestCore(t, config)
+
+	b.ArtifactId = "hello"
+
+	deps, err := core.BuildDependencies("app")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(deps, []string{"base"}) {
+		t.Fatalf("bad: %#v", deps)
+	}
+
+	// Before the build it depends on ran, outputs are placeholders
+	build, err := core.Build("app")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	outputs := b.PrepareConfig[1].(map[string]interface{})[BuildOutputsConfigKey]
+	expected := map[string]string{"base.id": "<base.id>"}
+	if !reflect.DeepEqual(outputs, expected) {
+		t.Fatalf("bad: %#v", outputs)
+	}
+
+	build, err = core.Build("base")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := build.Run(nil, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	build, err = core.Build("app")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	outputs = b.PrepareConfig[1].(map[string]interface{})[BuildOutputsConfigKey]
+	expected = map[string]string{"base.id": "hello"}
+	if !reflect.DeepEqual(outputs, expected) {
+		t.Fatalf("bad: %#v", outputs)
+	}
+}
+
+func TestCoreBuild_lineage(t *testing.T) {
+	config := TestCoreConfig(t)
+	testCoreTemplate(t, config, fixtureDir("build-lineage.json"))
+	b := TestBuilder(t, config, "test")
+	core := TestCore(t, config)
+
+	build, err := core.Build("test")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	packerConfig := b.PrepareConfig[1].(map[string]interface{})
+	if packerConfig[LineageConfigKey] != "ubuntu-base" {
+		t.Fatalf("bad lineage: %#v", packerConfig[LineageConfigKey])
+	}
+	if packerConfig[LineageVersionConfigKey] != 3 {
+		t.Fatalf("bad lineage version: %#v", packerConfig[LineageVersionConfigKey])
+	}
+}
+
 func TestCoreBuild_nonExist(t *testing.T) {
 	config := TestCoreConfig(t)
 	testCoreTemplate(t, config, fixtureDir("build-basic.json"))
@@ -497,6 +576,32 @@ func TestCoreValidate(t *testing.T) {
 			map[string]string{"foo": "bar"},
 			true,
 		},
+
+		// Required version
+		{
+			"validate-required-version.json",
+			nil,
+			false,
+		},
+
+		{
+			"validate-required-version-high.json",
+			nil,
+			true,
+		},
+
+		// Build retry policy
+		{
+			"validate-build-retry.json",
+			nil,
+			false,
+		},
+
+		{
+			"validate-build-retry-bad-class.json",
+			nil,
+			true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -522,6 +627,26 @@ func TestCoreValidate(t *testing.T) {
 	}
 }
 
+func TestCoreValidate_requiredCapabilities(t *testing.T) {
+	config := TestCoreConfig(t)
+	tpl, err := template.ParseFile(fixtureDir("validate-required-capabilities.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	config.Template = tpl
+
+	_, err = NewCore(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.Error(), "requires the builder 'foo'") {
+		t.Fatalf("bad: %s", err)
+	}
+	if strings.Contains(err.Error(), "'test'") {
+		t.Fatalf("the test builder exists: %s", err)
+	}
+}
+
 func testComponentFinder() *ComponentFinder {
 	builderFactory := func(n string) (Builder, error) { return new(MockBuilder), nil }
 	ppFactory := func(n string) (PostProcessor, error) { return new(MockPostProcessor), nil }
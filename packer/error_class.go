@@ -0,0 +1,192 @@
+package packer
+
+import (
+	"strings"
+)
+
+// ErrorClass broadly categorizes why a build failed, so that callers such
+// as CI systems can decide what to do about it (for example, retrying
+// only capacity errors).
+type ErrorClass string
+
+const (
+	// ErrorClassInternal is used for any failure that couldn't be
+	// classified more precisely.
+	ErrorClassInternal ErrorClass = "internal"
+
+	// ErrorClassConfig is a problem with the template or its
+	// configuration that the user has to fix.
+	ErrorClassConfig ErrorClass = "config"
+
+	// ErrorClassCredential means the credentials for a remote service were
+	// missing, invalid, expired or not allowed to do something.
+	ErrorClassCredential ErrorClass = "credential"
+
+	// ErrorClassCapacity means a remote service was out of capacity or a
+	// quota was hit.
+	ErrorClassCapacity ErrorClass = "capacity"
+
+	// ErrorClassThrottle means a remote service rate limited the build.
+	ErrorClassThrottle ErrorClass = "throttle"
+
+	// ErrorClassProvisioner means a provisioner, usually a script running
+	// on the machine, failed.
+	ErrorClassProvisioner ErrorClass = "provisioner"
+
+	// ErrorClassTimeout means the build gave up waiting for something.
+	ErrorClassTimeout ErrorClass = "timeout"
+)
+
+// ErrorClasses lists every known error class.
+var ErrorClasses = []ErrorClass{
+	ErrorClassInternal,
+	ErrorClassConfig,
+	ErrorClassCredential,
+	ErrorClassCapacity,
+	ErrorClassThrottle,
+	ErrorClassProvisioner,
+	ErrorClassTimeout,
+}
+
+func isErrorClass(class ErrorClass) bool {
+	for _, c := range ErrorClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifiedError is implemented by errors that know their ErrorClass.
+type ClassifiedError interface {
+	error
+	Class() ErrorClass
+}
+
+type classifiedError struct {
+	class ErrorClass
+	err   error
+}
+
+func (e *classifiedError) Error() string     { return e.err.Error() }
+func (e *classifiedError) Class() ErrorClass { return e.class }
+
+// NewClassifiedError wraps err so that ClassifyError reports class for it.
+// The message of the error is left untouched. Errors that are already
+// classified keep their original class.
+func NewClassifiedError(class ErrorClass, err error) error {
+	if err == nil {
+		return nil
+	}
+	if c, ok := err.(ClassifiedError); ok && c.Class() != "" {
+		return err
+	}
+
+	return &classifiedError{class: class, err: err}
+}
+
+// errorClassPatterns are fragments of error messages, mostly error codes
+// returned by cloud APIs, that identify the class of an error that was
+// not explicitly classified. They are matched case insensitively, in
+// order.
+var errorClassPatterns = []struct {
+	class    ErrorClass
+	patterns []string
+}{
+	{ErrorClassThrottle, []string{
+		"RequestLimitExceeded",
+		"Throttling",
+		"TooManyRequests",
+		"rateLimitExceeded",
+		"Rate exceeded",
+	}},
+	{ErrorClassCapacity, []string{
+		"InsufficientInstanceCapacity",
+		"InsufficientHostCapacity",
+		"InsufficientReservedInstanceCapacity",
+		"InsufficientCapacity",
+		"InstanceLimitExceeded",
+		"VcpuLimitExceeded",
+		"MaxSpotInstanceCountExceeded",
+		"capacity-not-available",
+		"SkuNotAvailable",
+		"ZONE_RESOURCE_POOL_EXHAUSTED",
+		"QuotaExceeded",
+		"QUOTA_EXCEEDED",
+	}},
+	{ErrorClassCredential, []string{
+		"NoCredentialProviders",
+		"No valid credential sources",
+		"Error loading credentials",
+		"AuthFailure",
+		"InvalidClientTokenId",
+		"SignatureDoesNotMatch",
+		"ExpiredToken",
+		"UnrecognizedClientException",
+		"UnauthorizedOperation",
+		"AccessDenied",
+	}},
+	{ErrorClassTimeout, []string{
+		"Timeout waiting",
+		"timed out",
+		"deadline exceeded",
+	}},
+}
+
+// causer is implemented by errors wrapping another error, such as the
+// errors of the retry helper once it gave up.
+type causer interface {
+	Cause() error
+}
+
+// RetryOnErrorClass returns a ShouldRetry, for the retry helper, that
+// retries the errors of the classes, as told by ClassifyError.
+func RetryOnErrorClass(classes ...ErrorClass) func(error) bool {
+	return func(err error) bool {
+		class := ClassifyError(err)
+		for _, c := range classes {
+			if c == class {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ClassifyError returns the class of err. Errors implementing
+// ClassifiedError report their own class. For a MultiError the class of
+// the first error is used, and errors wrapping another one with a Cause
+// method are classified like it. Anything else is classified by looking for
+// well known error codes in its message, falling back to
+// ErrorClassInternal. A nil error has no class.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+
+	switch e := err.(type) {
+	case ClassifiedError:
+		if class := e.Class(); class != "" {
+			return class
+		}
+	case *MultiError:
+		if len(e.Errors) > 0 {
+			return ClassifyError(e.Errors[0])
+		}
+	case causer:
+		if cause := e.Cause(); cause != nil {
+			return ClassifyError(cause)
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, p := range errorClassPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(msg, strings.ToLower(pattern)) {
+				return p.class
+			}
+		}
+	}
+
+	return ErrorClassInternal
+}
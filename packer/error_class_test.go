@@ -0,0 +1,59 @@
+package packer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/retry"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected ErrorClass
+	}{
+		{nil, ""},
+		{errors.New("something broke"), ErrorClassInternal},
+		{errors.New("InsufficientInstanceCapacity: We currently do not have sufficient capacity"), ErrorClassCapacity},
+		{errors.New("RequestLimitExceeded: Request limit exceeded."), ErrorClassThrottle},
+		{errors.New("AuthFailure: AWS was not able to validate the provided access credentials"), ErrorClassCredential},
+		{errors.New("Timeout waiting for SSH."), ErrorClassTimeout},
+		{NewClassifiedError(ErrorClassProvisioner, errors.New("Script exited with non-zero exit status: 1")), ErrorClassProvisioner},
+		{NewClassifiedError(ErrorClassConfig, errors.New("Timeout waiting for nothing")), ErrorClassConfig},
+		{MultiErrorAppend(NewClassifiedError(ErrorClassConfig, errors.New("foo")), errors.New("bar")), ErrorClassConfig},
+		{&retry.ExhaustedError{Tries: 3, Err: NewClassifiedError(ErrorClassCapacity, errors.New("foo"))}, ErrorClassCapacity},
+	}
+
+	for _, tc := range cases {
+		if actual := ClassifyError(tc.err); actual != tc.expected {
+			t.Fatalf("bad class for %v: %s, expected %s", tc.err, actual, tc.expected)
+		}
+	}
+}
+
+func TestNewClassifiedError(t *testing.T) {
+	if err := NewClassifiedError(ErrorClassConfig, nil); err != nil {
+		t.Fatalf("should be nil: %#v", err)
+	}
+
+	err := NewClassifiedError(ErrorClassTimeout, errors.New("foo"))
+	if err.Error() != "foo" {
+		t.Fatalf("bad message: %s", err)
+	}
+
+	// Already classified errors keep their class
+	err = NewClassifiedError(ErrorClassProvisioner, err)
+	if class := ClassifyError(err); class != ErrorClassTimeout {
+		t.Fatalf("bad class: %s", class)
+	}
+}
+
+func TestRetryOnErrorClass(t *testing.T) {
+	shouldRetry := RetryOnErrorClass(ErrorClassThrottle, ErrorClassCapacity)
+	if !shouldRetry(errors.New("Throttling: Rate exceeded")) {
+		t.Fatal("should retry throttle errors")
+	}
+	if shouldRetry(errors.New("AccessDenied")) {
+		t.Fatal("should not retry credential errors")
+	}
+}
@@ -0,0 +1,80 @@
+package packer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// DefaultLineageManifest is the manifest the versions of a lineage are
+// read from when the template doesn't say, the default output of the
+// manifest post-processor.
+const DefaultLineageManifest = "packer-manifest.json"
+
+// LineageEntry is a build of a version of a lineage, as the manifest
+// post-processor records it.
+type LineageEntry struct {
+	Lineage     string `json:"lineage"`
+	Version     int    `json:"lineage_version"`
+	BuildName   string `json:"name"`
+	BuilderType string `json:"builder_type"`
+	BuildTime   int64  `json:"build_time"`
+	ArtifactId  string `json:"artifact_id"`
+}
+
+// ReadLineageHistory returns the builds of versions of lineages recorded in
+// the manifest at path, ordered by lineage, version and build name. A
+// manifest that doesn't exist yet has no history.
+func ReadLineageHistory(path string) ([]LineageEntry, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var manifest struct {
+		Builds []LineageEntry `json:"builds"`
+	}
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return nil, fmt.Errorf("Error parsing manifest %s: %s", path, err)
+	}
+
+	var result []LineageEntry
+	for _, entry := range manifest.Builds {
+		if entry.Lineage != "" {
+			result = append(result, entry)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Lineage != b.Lineage {
+			return a.Lineage < b.Lineage
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.BuildName < b.BuildName
+	})
+	return result, nil
+}
+
+// NextLineageVersion returns the version of the lineage the next run
+// builds, which follows the latest one recorded in the manifest at path.
+func NextLineageVersion(path, lineage string) (int, error) {
+	history, err := ReadLineageHistory(path)
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for _, entry := range history {
+		if entry.Lineage == lineage && entry.Version > version {
+			version = entry.Version
+		}
+	}
+	return version + 1, nil
+}
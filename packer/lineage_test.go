@@ -0,0 +1,46 @@
+package packer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadLineageHistory(t *testing.T) {
+	history, err := ReadLineageHistory(fixtureDir("lineage-manifest.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var ids []string
+	for _, entry := range history {
+		ids = append(ids, entry.ArtifactId)
+	}
+	if strings.Join(ids, " ") != "sha256:1234 us-east-1:ami-1 us-east-1:ami-2" {
+		t.Fatalf("bad: %v", ids)
+	}
+}
+
+func TestNextLineageVersion(t *testing.T) {
+	cases := map[string]int{
+		"ubuntu-base": 3,
+		"app":         2,
+		"new":         1,
+	}
+	for lineage, expected := range cases {
+		version, err := NextLineageVersion(fixtureDir("lineage-manifest.json"), lineage)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if version != expected {
+			t.Fatalf("bad version for %s: %d", lineage, version)
+		}
+	}
+
+	version, err := NextLineageVersion(fixtureDir("does-not-exist.json"), "app")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if version != 1 {
+		t.Fatalf("bad version: %d", version)
+	}
+}
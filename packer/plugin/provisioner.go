@@ -29,6 +29,15 @@ func (c *cmdProvisioner) Provision(ui packer.Ui, comm packer.Communicator) error
 	return c.p.Provision(ui, comm)
 }
 
+func (c *cmdProvisioner) ProvisionMachine(ui packer.Ui, comm packer.Communicator, machine *packer.Machine) error {
+	defer func() {
+		r := recover()
+		c.checkExit(r, nil)
+	}()
+
+	return packer.ProvisionWithMachine(c.p, ui, comm, machine)
+}
+
 func (c *cmdProvisioner) Cancel() {
 	defer func() {
 		r := recover()
@@ -27,6 +27,41 @@ type Provisioner interface {
 	Cancel()
 }
 
+// Machine identifies the machine of a build in its cloud, for the
+// provisioners that reach it through the cloud rather than the
+// communicator.
+type Machine struct {
+	// The cloud of the machine: amazon, azure or googlecompute.
+	Cloud string
+
+	// The ID of the EC2 instance, or the name of the Azure VM or of the
+	// Compute Engine instance.
+	ID string
+
+	Region        string
+	Zone          string
+	ProjectID     string
+	ResourceGroup string
+}
+
+// MachineProvisioner is implemented by the provisioners that can be given
+// the machine they provision, such as those running scripts through the
+// agent of its cloud.
+type MachineProvisioner interface {
+	// ProvisionMachine provisions the machine like Provision, with the
+	// machine given if the builder knows it, nil otherwise.
+	ProvisionMachine(Ui, Communicator, *Machine) error
+}
+
+// ProvisionWithMachine runs the provisioner, giving it the machine if it
+// is a MachineProvisioner.
+func ProvisionWithMachine(p Provisioner, ui Ui, comm Communicator, machine *Machine) error {
+	if mp, ok := p.(MachineProvisioner); ok {
+		return mp.ProvisionMachine(ui, comm, machine)
+	}
+	return p.Provision(ui, comm)
+}
+
 // A HookedProvisioner represents a provisioner and information describing it
 type HookedProvisioner struct {
 	Provisioner Provisioner
@@ -34,6 +69,16 @@ type HookedProvisioner struct {
 	TypeName    string
 }
 
+// ProvisionRange is the data of the provision hook that runs the
+// provisioners from From up to To, excluded, instead of all of them.
+type ProvisionRange struct {
+	From int
+	To   int
+
+	// The machine of the build, if the builder knows it.
+	Machine *Machine
+}
+
 // A Hook implementation that runs the given provisioners.
 type ProvisionHook struct {
 	// The provisioners to run as part of the hook. These should already
@@ -44,14 +89,27 @@ type ProvisionHook struct {
 	runningProvisioner Provisioner
 }
 
-// Runs the provisioners in order.
+// Runs the provisioners in order, or the ones in the ProvisionRange given
+// as data. The machine of the build, given as data or in the range, is
+// given to the provisioners.
 func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interface{}) error {
+	provisioners := h.Provisioners
+	machine, _ := data.(*Machine)
+	if r, ok := data.(*ProvisionRange); ok {
+		if r.From < 0 || r.From > r.To || r.To > len(provisioners) {
+			return fmt.Errorf("Invalid range of provisioners: %d to %d", r.From, r.To)
+		}
+		provisioners = provisioners[r.From:r.To]
+		machine = r.Machine
+	}
+
 	// Shortcut
-	if len(h.Provisioners) == 0 {
+	if len(provisioners) == 0 {
 		return nil
 	}
 
-	if comm == nil {
+	// The provisioners given the machine may not need a communicator
+	if comm == nil && machine == nil {
 		return fmt.Errorf(
 			"No communicator found for provisioners! This is usually because the\n" +
 				"`communicator` config was set to \"none\". If you have any provisioners\n" +
@@ -65,18 +123,18 @@ func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interfac
 		h.runningProvisioner = nil
 	}()
 
-	for _, p := range h.Provisioners {
+	for _, p := range provisioners {
 		h.lock.Lock()
 		h.runningProvisioner = p.Provisioner
 		h.lock.Unlock()
 
 		ts := CheckpointReporter.AddSpan(p.TypeName, "provisioner", p.Config)
 
-		err := p.Provisioner.Provision(ui, comm)
+		err := ProvisionWithMachine(p.Provisioner, ui, comm, machine)
 
 		ts.End(err)
 		if err != nil {
-			return err
+			return NewClassifiedError(ErrorClassProvisioner, err)
 		}
 	}
 
@@ -109,6 +167,10 @@ func (p *PausedProvisioner) Prepare(raws ...interface{}) error {
 }
 
 func (p *PausedProvisioner) Provision(ui Ui, comm Communicator) error {
+	return p.ProvisionMachine(ui, comm, nil)
+}
+
+func (p *PausedProvisioner) ProvisionMachine(ui Ui, comm Communicator, machine *Machine) error {
 	p.lock.Lock()
 	cancelCh := make(chan struct{})
 	p.cancelCh = cancelCh
@@ -139,7 +201,7 @@ func (p *PausedProvisioner) Provision(ui Ui, comm Communicator) error {
 	}
 
 	provDoneCh := make(chan error, 1)
-	go p.provision(provDoneCh, ui, comm)
+	go p.provision(provDoneCh, ui, comm, machine)
 
 	select {
 	case err := <-provDoneCh:
@@ -166,8 +228,8 @@ func (p *PausedProvisioner) Cancel() {
 	<-doneCh
 }
 
-func (p *PausedProvisioner) provision(result chan<- error, ui Ui, comm Communicator) {
-	result <- p.Provisioner.Provision(ui, comm)
+func (p *PausedProvisioner) provision(result chan<- error, ui Ui, comm Communicator, machine *Machine) {
+	result <- ProvisionWithMachine(p.Provisioner, ui, comm, machine)
 }
 
 // DebuggedProvisioner is a Provisioner implementation that waits until a key
@@ -185,6 +247,10 @@ func (p *DebuggedProvisioner) Prepare(raws ...interface{}) error {
 }
 
 func (p *DebuggedProvisioner) Provision(ui Ui, comm Communicator) error {
+	return p.ProvisionMachine(ui, comm, nil)
+}
+
+func (p *DebuggedProvisioner) ProvisionMachine(ui Ui, comm Communicator, machine *Machine) error {
 	p.lock.Lock()
 	cancelCh := make(chan struct{})
 	p.cancelCh = cancelCh
@@ -226,7 +292,7 @@ func (p *DebuggedProvisioner) Provision(ui Ui, comm Communicator) error {
 	}
 
 	provDoneCh := make(chan error, 1)
-	go p.provision(provDoneCh, ui, comm)
+	go p.provision(provDoneCh, ui, comm, machine)
 
 	select {
 	case err := <-provDoneCh:
@@ -253,6 +319,6 @@ func (p *DebuggedProvisioner) Cancel() {
 	<-doneCh
 }
 
-func (p *DebuggedProvisioner) provision(result chan<- error, ui Ui, comm Communicator) {
-	result <- p.Provisioner.Provision(ui, comm)
+func (p *DebuggedProvisioner) provision(result chan<- error, ui Ui, comm Communicator, machine *Machine) {
+	result <- ProvisionWithMachine(p.Provisioner, ui, comm, machine)
 }
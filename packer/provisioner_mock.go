@@ -10,6 +10,7 @@ type MockProvisioner struct {
 	ProvCalled       bool
 	ProvCommunicator Communicator
 	ProvUi           Ui
+	ProvMachine      *Machine
 	CancelCalled     bool
 }
 
@@ -31,6 +32,11 @@ func (t *MockProvisioner) Provision(ui Ui, comm Communicator) error {
 	return t.ProvFunc()
 }
 
+func (t *MockProvisioner) ProvisionMachine(ui Ui, comm Communicator, machine *Machine) error {
+	t.ProvMachine = machine
+	return t.Provision(ui, comm)
+}
+
 func (t *MockProvisioner) Cancel() {
 	t.CancelCalled = true
 }
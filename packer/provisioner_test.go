@@ -1,6 +1,8 @@
 package packer
 
 import (
+	"bytes"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -40,6 +42,68 @@ func TestProvisionHook(t *testing.T) {
 	}
 }
 
+func TestProvisionHook_range(t *testing.T) {
+	pA := &MockProvisioner{}
+	pB := &MockProvisioner{}
+	pC := &MockProvisioner{}
+
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{pA, nil, ""},
+			{pB, nil, ""},
+			{pC, nil, ""},
+		},
+	}
+
+	err := hook.Run("foo", testUi(), new(MockCommunicator), &ProvisionRange{From: 1, To: 2})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if pA.ProvCalled || !pB.ProvCalled || pC.ProvCalled {
+		t.Fatalf("only pB should be called: %t %t %t", pA.ProvCalled, pB.ProvCalled, pC.ProvCalled)
+	}
+
+	// Bad
+	err = hook.Run("foo", testUi(), new(MockCommunicator), &ProvisionRange{From: 2, To: 4})
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionHook_machine(t *testing.T) {
+	pA := &MockProvisioner{}
+	pB := &MockProvisioner{}
+	pC := &MockProvisioner{}
+
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{&PausedProvisioner{Provisioner: pA}, nil, ""},
+			{&DebuggedProvisioner{Provisioner: pB}, nil, ""},
+			{pC, nil, ""},
+		},
+	}
+
+	// The debugged provisioner waits for a line to be entered
+	ui := &BasicUi{Reader: strings.NewReader("\n"), Writer: new(bytes.Buffer)}
+	machine := &Machine{Cloud: "amazon", ID: "i-12345"}
+	if err := hook.Run("foo", ui, nil, machine); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, p := range []*MockProvisioner{pA, pB, pC} {
+		if p.ProvMachine != machine {
+			t.Fatalf("bad: %#v", p.ProvMachine)
+		}
+	}
+
+	err := hook.Run("foo", ui, new(MockCommunicator), &ProvisionRange{From: 2, To: 3, Machine: machine})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if pC.ProvMachine != machine {
+		t.Fatalf("bad: %#v", pC.ProvMachine)
+	}
+}
+
 func TestProvisionHook_nilComm(t *testing.T) {
 	pA := &MockProvisioner{}
 	pB := &MockProvisioner{}
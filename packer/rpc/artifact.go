@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"log"
 	"net/rpc"
 
 	"github.com/hashicorp/packer/packer"
@@ -11,12 +12,14 @@ import (
 type artifact struct {
 	client   *rpc.Client
 	endpoint string
+	mux      *muxBroker
 }
 
 // ArtifactServer wraps a packer.Artifact implementation and makes it
 // exportable as part of a Golang RPC server.
 type ArtifactServer struct {
 	artifact packer.Artifact
+	mux      *muxBroker
 }
 
 func (a *artifact) BuilderId() (result string) {
@@ -53,6 +56,51 @@ func (a *artifact) Destroy() error {
 	return result
 }
 
+// ArtifactNames returns no names for artifacts that aren't made of several
+// ones, or that are served by plugins built before named artifacts existed.
+func (a *artifact) ArtifactNames() (result []string) {
+	a.client.Call(a.endpoint+".ArtifactNames", new(interface{}), &result)
+	return
+}
+
+func (a *artifact) NamedArtifact(name string) packer.Artifact {
+	var streamId uint32
+	if err := a.client.Call(a.endpoint+".NamedArtifact", name, &streamId); err != nil {
+		log.Printf("Error getting artifact '%s': %s", name, err)
+		return nil
+	}
+
+	return a.streamArtifact(streamId)
+}
+
+// MainArtifact returns nil for artifacts served by plugins built before
+// the main artifact could be had alone.
+func (a *artifact) MainArtifact() packer.Artifact {
+	var streamId uint32
+	if err := a.client.Call(a.endpoint+".MainArtifact", new(interface{}), &streamId); err != nil {
+		log.Printf("Error getting the main artifact: %s", err)
+		return nil
+	}
+
+	return a.streamArtifact(streamId)
+}
+
+// streamArtifact returns the artifact served on the stream, or nil if
+// there is none.
+func (a *artifact) streamArtifact(streamId uint32) packer.Artifact {
+	if streamId == 0 {
+		return nil
+	}
+
+	client, err := newClientWithMux(a.mux, streamId)
+	if err != nil {
+		log.Printf("Error getting artifact: %s", err)
+		return nil
+	}
+
+	return client.Artifact()
+}
+
 func (s *ArtifactServer) BuilderId(args *interface{}, reply *string) error {
 	*reply = s.artifact.BuilderId()
 	return nil
@@ -87,3 +135,43 @@ func (s *ArtifactServer) Destroy(args *interface{}, reply *error) error {
 	*reply = err
 	return nil
 }
+
+func (s *ArtifactServer) ArtifactNames(args *interface{}, reply *[]string) error {
+	if named, ok := s.artifact.(packer.NamedArtifacts); ok {
+		*reply = named.ArtifactNames()
+	}
+	return nil
+}
+
+func (s *ArtifactServer) NamedArtifact(name string, reply *uint32) error {
+	*reply = 0
+	named, ok := s.artifact.(packer.NamedArtifacts)
+	if !ok {
+		return nil
+	}
+
+	*reply = s.serveArtifact(named.NamedArtifact(name))
+	return nil
+}
+
+func (s *ArtifactServer) MainArtifact(args *interface{}, reply *uint32) error {
+	*reply = 0
+	if named, ok := s.artifact.(packer.NamedArtifacts); ok {
+		*reply = s.serveArtifact(named.MainArtifact())
+	}
+	return nil
+}
+
+// serveArtifact serves the artifact on a new stream and returns the ID of
+// the stream, or 0 if the artifact is nil.
+func (s *ArtifactServer) serveArtifact(artifact packer.Artifact) uint32 {
+	if artifact == nil {
+		return 0
+	}
+
+	streamId := s.mux.NextId()
+	server := newServerWithMux(s.mux, streamId)
+	server.RegisterArtifact(artifact)
+	go server.Serve()
+	return streamId
+}
@@ -37,6 +37,58 @@ func TestArtifactRPC(t *testing.T) {
 	}
 }
 
+func TestArtifactRPC_NamedArtifacts(t *testing.T) {
+	a := &packer.ArtifactSet{
+		Artifact: new(packer.MockArtifact),
+		Named: map[string]packer.Artifact{
+			"vmdk": &packer.MockArtifact{IdValue: "vmdk"},
+		},
+	}
+
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+	server.RegisterArtifact(a)
+
+	aClient := client.Artifact().(packer.NamedArtifacts)
+	if !reflect.DeepEqual(aClient.ArtifactNames(), []string{"vmdk"}) {
+		t.Fatalf("bad: %#v", aClient.ArtifactNames())
+	}
+
+	named := aClient.NamedArtifact("vmdk")
+	if named == nil || named.Id() != "vmdk" {
+		t.Fatalf("bad: %#v", named)
+	}
+
+	if aClient.NamedArtifact("foo") != nil {
+		t.Fatal("should not have artifact foo")
+	}
+
+	main := aClient.MainArtifact()
+	if main == nil || len(main.(packer.NamedArtifacts).ArtifactNames()) > 0 {
+		t.Fatalf("bad: %#v", main)
+	}
+}
+
+func TestArtifactRPC_NoNamedArtifacts(t *testing.T) {
+	client, server := testClientServer(t)
+	defer client.Close()
+	defer server.Close()
+	server.RegisterArtifact(new(packer.MockArtifact))
+
+	aClient := client.Artifact().(packer.NamedArtifacts)
+	if names := aClient.ArtifactNames(); len(names) > 0 {
+		t.Fatalf("bad: %#v", names)
+	}
+	if aClient.NamedArtifact("vmdk") != nil {
+		t.Fatal("should not have artifact vmdk")
+	}
+	if aClient.MainArtifact() != nil {
+		t.Fatal("should not have a main artifact")
+	}
+}
+
 func TestArtifact_Implements(t *testing.T) {
 	var _ packer.Artifact = new(artifact)
+	var _ packer.NamedArtifacts = new(artifact)
 }
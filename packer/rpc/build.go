@@ -2,6 +2,7 @@ package rpc
 
 import (
 	"net/rpc"
+	"time"
 
 	"github.com/hashicorp/packer/packer"
 )
@@ -52,7 +53,7 @@ func (b *build) Run(ui packer.Ui, cache packer.Cache) ([]packer.Artifact, error)
 
 	var result []uint32
 	if err := b.client.Call("Build.Run", nextId, &result); err != nil {
-		return nil, err
+		return nil, rpcClientError(err)
 	}
 
 	artifacts := make([]packer.Artifact, len(result))
@@ -86,6 +87,18 @@ func (b *build) SetOnError(val string) {
 	}
 }
 
+func (b *build) SetOnErrorGracePeriod(val time.Duration) {
+	if err := b.client.Call("Build.SetOnErrorGracePeriod", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
+func (b *build) SetProvisionerDryRun(val bool) {
+	if err := b.client.Call("Build.SetProvisionerDryRun", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
 func (b *build) Cancel() {
 	if err := b.client.Call("Build.Cancel", new(interface{}), new(interface{})); err != nil {
 		panic(err)
@@ -115,7 +128,7 @@ func (b *BuildServer) Run(streamId uint32, reply *[]uint32) error {
 
 	artifacts, err := b.build.Run(client.Ui(), client.Cache())
 	if err != nil {
-		return NewBasicError(err)
+		return rpcServerError(err)
 	}
 
 	*reply = make([]uint32, len(artifacts))
@@ -146,6 +159,16 @@ func (b *BuildServer) SetOnError(val *string, reply *interface{}) error {
 	return nil
 }
 
+func (b *BuildServer) SetOnErrorGracePeriod(val *time.Duration, reply *interface{}) error {
+	b.build.SetOnErrorGracePeriod(*val)
+	return nil
+}
+
+func (b *BuildServer) SetProvisionerDryRun(val *bool, reply *interface{}) error {
+	b.build.SetProvisionerDryRun(*val)
+	return nil
+}
+
 func (b *BuildServer) Cancel(args *interface{}, reply *interface{}) error {
 	b.build.Cancel()
 	return nil
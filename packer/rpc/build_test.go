@@ -4,6 +4,7 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer/packer"
 )
@@ -20,6 +21,8 @@ type testBuild struct {
 	setDebugCalled   bool
 	setForceCalled   bool
 	setOnErrorCalled bool
+	setGraceCalled   bool
+	setDryRunCalled  bool
 	cancelCalled     bool
 
 	errRunResult bool
@@ -59,6 +62,14 @@ func (b *testBuild) SetOnError(string) {
 	b.setOnErrorCalled = true
 }
 
+func (b *testBuild) SetOnErrorGracePeriod(time.Duration) {
+	b.setGraceCalled = true
+}
+
+func (b *testBuild) SetProvisionerDryRun(bool) {
+	b.setDryRunCalled = true
+}
+
 func (b *testBuild) Cancel() {
 	b.cancelCalled = true
 }
@@ -128,6 +139,18 @@ func TestBuild(t *testing.T) {
 		t.Fatal("should be called")
 	}
 
+	// Test SetOnErrorGracePeriod
+	bClient.SetOnErrorGracePeriod(time.Hour)
+	if !b.setGraceCalled {
+		t.Fatal("should be called")
+	}
+
+	// Test SetProvisionerDryRun
+	bClient.SetProvisionerDryRun(true)
+	if !b.setDryRunCalled {
+		t.Fatal("should be called")
+	}
+
 	// Test Cancel
 	bClient.Cancel()
 	if !b.cancelCalled {
@@ -54,7 +54,7 @@ func (b *builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	var responseId uint32
 	if err := b.client.Call("Builder.Run", nextId, &responseId); err != nil {
-		return nil, err
+		return nil, rpcClientError(err)
 	}
 
 	if responseId == 0 {
@@ -93,7 +93,7 @@ func (b *BuilderServer) Run(streamId uint32, reply *uint32) error {
 
 	artifact, err := b.builder.Run(client.Ui(), client.Hook(), client.Cache())
 	if err != nil {
-		return NewBasicError(err)
+		return rpcServerError(err)
 	}
 
 	*reply = 0
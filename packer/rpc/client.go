@@ -71,6 +71,7 @@ func (c *Client) Artifact() packer.Artifact {
 	return &artifact{
 		client:   c.client,
 		endpoint: DefaultArtifactEndpoint,
+		mux:      c.mux,
 	}
 }
 
@@ -1,11 +1,21 @@
 package rpc
 
+import (
+	"fmt"
+	"net/rpc"
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+)
+
 // This is a type that wraps error types so that they can be messaged
 // across RPC channels. Since "error" is an interface, we can't always
 // gob-encode the underlying structure. This is a valid error interface
-// implementer that we will push across.
+// implementer that we will push across. The class of errors that were
+// explicitly classified is kept so it survives the trip.
 type BasicError struct {
-	Message string
+	Message        string
+	Classification packer.ErrorClass
 }
 
 func NewBasicError(err error) *BasicError {
@@ -13,9 +23,54 @@ func NewBasicError(err error) *BasicError {
 		return nil
 	}
 
-	return &BasicError{err.Error()}
+	e := &BasicError{Message: err.Error()}
+	if c, ok := err.(packer.ClassifiedError); ok {
+		e.Classification = c.Class()
+	}
+	return e
 }
 
 func (e *BasicError) Error() string {
 	return e.Message
 }
+
+func (e *BasicError) Class() packer.ErrorClass {
+	return e.Classification
+}
+
+// net/rpc only sends the message of errors returned by RPC methods, so the
+// class of classified errors is prefixed to the message on the server with
+// rpcServerError and recovered on the client with rpcClientError.
+const errorClassPrefix = "packer-error-class:"
+
+func rpcServerError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	c, ok := err.(packer.ClassifiedError)
+	if !ok || c.Class() == "" {
+		return NewBasicError(err)
+	}
+
+	return &BasicError{
+		Message: fmt.Sprintf("%s%s\n%s", errorClassPrefix, c.Class(), err.Error()),
+	}
+}
+
+func rpcClientError(err error) error {
+	serverErr, ok := err.(rpc.ServerError)
+	if !ok || !strings.HasPrefix(string(serverErr), errorClassPrefix) {
+		return err
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(string(serverErr), errorClassPrefix), "\n", 2)
+	if len(parts) != 2 {
+		return err
+	}
+
+	return &BasicError{
+		Message:        parts[1],
+		Classification: packer.ErrorClass(parts[0]),
+	}
+}
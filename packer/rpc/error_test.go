@@ -2,7 +2,10 @@ package rpc
 
 import (
 	"errors"
+	"net/rpc"
 	"testing"
+
+	"github.com/hashicorp/packer/packer"
 )
 
 func TestBasicError_ImplementsError(t *testing.T) {
@@ -17,3 +20,32 @@ func TestBasicError_MatchesMessage(t *testing.T) {
 		t.Fatalf("bad: %#v", wrapped.Error())
 	}
 }
+
+func TestBasicError_KeepsClass(t *testing.T) {
+	err := packer.NewClassifiedError(packer.ErrorClassCapacity, errors.New("foo"))
+	wrapped := NewBasicError(err)
+
+	if class := packer.ClassifyError(wrapped); class != packer.ErrorClassCapacity {
+		t.Fatalf("bad: %s", class)
+	}
+}
+
+func TestRPCError_KeepsClass(t *testing.T) {
+	err := packer.NewClassifiedError(packer.ErrorClassProvisioner, errors.New("foo"))
+
+	// net/rpc only passes the message of the error along
+	sent := rpc.ServerError(rpcServerError(err).Error())
+	received := rpcClientError(sent)
+	if received.Error() != "foo" {
+		t.Fatalf("bad message: %s", received)
+	}
+	if class := packer.ClassifyError(received); class != packer.ErrorClassProvisioner {
+		t.Fatalf("bad class: %s", class)
+	}
+
+	// Unclassified errors are passed through untouched
+	sent = rpc.ServerError(rpcServerError(errors.New("bar")).Error())
+	if received := rpcClientError(sent); received != sent {
+		t.Fatalf("bad: %#v", received)
+	}
+}
@@ -40,7 +40,7 @@ func (h *hook) Run(name string, ui packer.Ui, comm packer.Communicator, data int
 		StreamId: nextId,
 	}
 
-	return h.client.Call("Hook.Run", &args, new(interface{}))
+	return rpcClientError(h.client.Call("Hook.Run", &args, new(interface{})))
 }
 
 func (h *hook) Cancel() {
@@ -58,7 +58,7 @@ func (h *HookServer) Run(args *HookRunArgs, reply *interface{}) error {
 	defer client.Close()
 
 	if err := h.hook.Run(args.Name, client.Ui(), client.Communicator(), args.Data); err != nil {
-		return NewBasicError(err)
+		return rpcServerError(err)
 	}
 
 	*reply = nil
@@ -1,10 +1,16 @@
 package rpc
 
-import "encoding/gob"
+import (
+	"encoding/gob"
+
+	"github.com/hashicorp/packer/packer"
+)
 
 func init() {
 	gob.Register(new(map[string]interface{}))
 	gob.Register(new(map[string]string))
 	gob.Register(make([]interface{}, 0))
 	gob.Register(new(BasicError))
+	gob.Register(new(packer.ProvisionRange))
+	gob.Register(new(packer.Machine))
 }
@@ -25,6 +25,11 @@ type ProvisionerPrepareArgs struct {
 	Configs []interface{}
 }
 
+type ProvisionerProvisionMachineArgs struct {
+	StreamId uint32
+	Machine  *packer.Machine
+}
+
 func (p *provisioner) Prepare(configs ...interface{}) (err error) {
 	args := &ProvisionerPrepareArgs{configs}
 	if cerr := p.client.Call("Provisioner.Prepare", args, new(interface{})); cerr != nil {
@@ -41,7 +46,18 @@ func (p *provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	server.RegisterUi(ui)
 	go server.Serve()
 
-	return p.client.Call("Provisioner.Provision", nextId, new(interface{}))
+	return rpcClientError(p.client.Call("Provisioner.Provision", nextId, new(interface{})))
+}
+
+func (p *provisioner) ProvisionMachine(ui packer.Ui, comm packer.Communicator, machine *packer.Machine) error {
+	nextId := p.mux.NextId()
+	server := newServerWithMux(p.mux, nextId)
+	server.RegisterCommunicator(comm)
+	server.RegisterUi(ui)
+	go server.Serve()
+
+	args := &ProvisionerProvisionMachineArgs{StreamId: nextId, Machine: machine}
+	return rpcClientError(p.client.Call("Provisioner.ProvisionMachine", args, new(interface{})))
 }
 
 func (p *provisioner) Cancel() {
@@ -63,8 +79,22 @@ func (p *ProvisionerServer) Provision(streamId uint32, reply *interface{}) error
 	defer client.Close()
 
 	if err := p.p.Provision(client.Ui(), client.Communicator()); err != nil {
+		return rpcServerError(err)
+	}
+
+	return nil
+}
+
+func (p *ProvisionerServer) ProvisionMachine(args *ProvisionerProvisionMachineArgs, reply *interface{}) error {
+	client, err := newClientWithMux(p.mux, args.StreamId)
+	if err != nil {
 		return NewBasicError(err)
 	}
+	defer client.Close()
+
+	if err := packer.ProvisionWithMachine(p.p, client.Ui(), client.Communicator(), args.Machine); err != nil {
+		return rpcServerError(err)
+	}
 
 	return nil
 }
@@ -37,6 +37,13 @@ func TestProvisionerRPC(t *testing.T) {
 		t.Fatal("should be called")
 	}
 
+	// Test ProvisionMachine
+	machine := &packer.Machine{Cloud: "googlecompute", ID: "packer-12345", Zone: "us-central1-a"}
+	pClient.(packer.MachineProvisioner).ProvisionMachine(ui, comm, machine)
+	if !reflect.DeepEqual(p.ProvMachine, machine) {
+		t.Fatalf("bad: %#v", p.ProvMachine)
+	}
+
 	// Test Cancel
 	pClient.Cancel()
 	if !p.CancelCalled {
@@ -61,6 +61,7 @@ func (s *Server) Close() error {
 func (s *Server) RegisterArtifact(a packer.Artifact) {
 	s.server.RegisterName(DefaultArtifactEndpoint, &ArtifactServer{
 		artifact: a,
+		mux:      s.mux,
 	})
 }
 
@@ -18,6 +18,7 @@ type Config struct {
 
 	Repository string `mapstructure:"repository"`
 	Tag        string `mapstructure:"tag"`
+	Runtime    string `mapstructure:"runtime"`
 
 	ctx interpolate.Context
 }
@@ -38,6 +39,10 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	if err := docker.CheckRuntime(p.config.Runtime); err != nil {
+		return err
+	}
+
 	return nil
 
 }
@@ -58,7 +63,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		importRepo += ":" + p.config.Tag
 	}
 
-	driver := &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
+	driver := &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui, Runtime: docker.ArtifactRuntime(artifact, p.config.Runtime)}
 
 	ui.Message("Importing image: " + artifact.Id())
 	ui.Message("Repository: " + importRepo)
@@ -74,6 +79,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		BuilderIdValue: BuilderId,
 		Driver:         driver,
 		IdValue:        importRepo,
+		Runtime:        driver.Runtime,
 	}
 
 	return artifact, false, nil
@@ -20,6 +20,7 @@ type Config struct {
 	LoginPassword          string `mapstructure:"login_password"`
 	LoginServer            string `mapstructure:"login_server"`
 	EcrLogin               bool   `mapstructure:"ecr_login"`
+	Runtime                string `mapstructure:"runtime"`
 	docker.AwsAccessConfig `mapstructure:",squash"`
 
 	ctx interpolate.Context
@@ -46,6 +47,9 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 	if p.config.EcrLogin && p.config.LoginServer == "" {
 		return fmt.Errorf("ECR login requires login server to be provided.")
 	}
+	if err := docker.CheckRuntime(p.config.Runtime); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -61,7 +65,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	driver := p.Driver
 	if driver == nil {
 		// If no driver is set, then we use the real driver
-		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
+		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui, Runtime: docker.ArtifactRuntime(artifact, p.config.Runtime)}
 	}
 
 	if p.config.EcrLogin {
@@ -33,6 +33,19 @@ func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
 	var _ packer.PostProcessor = new(PostProcessor)
 }
 
+func TestPostProcessor_Configure_runtime(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"runtime": "podman"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Bad
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"runtime": "rkt"}); err == nil {
+		t.Fatal("should error")
+	}
+}
+
 func TestPostProcessor_PostProcess(t *testing.T) {
 	driver := &docker.MockDriver{}
 	p := &PostProcessor{Driver: driver}
@@ -18,7 +18,8 @@ const BuilderId = "packer.post-processor.docker-save"
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	Path string `mapstructure:"path"`
+	Path    string `mapstructure:"path"`
+	Runtime string `mapstructure:"runtime"`
 
 	ctx interpolate.Context
 }
@@ -41,6 +42,10 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	if err := docker.CheckRuntime(p.config.Runtime); err != nil {
+		return err
+	}
+
 	return nil
 
 }
@@ -66,7 +71,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	driver := p.Driver
 	if driver == nil {
 		// If no driver is set, then we use the real driver
-		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
+		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui, Runtime: docker.ArtifactRuntime(artifact, p.config.Runtime)}
 	}
 
 	ui.Message("Saving image: " + artifact.Id())
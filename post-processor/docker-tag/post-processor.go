@@ -19,6 +19,7 @@ type Config struct {
 	Repository string `mapstructure:"repository"`
 	Tag        string `mapstructure:"tag"`
 	Force      bool
+	Runtime    string `mapstructure:"runtime"`
 
 	ctx interpolate.Context
 }
@@ -41,6 +42,10 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	if err := docker.CheckRuntime(p.config.Runtime); err != nil {
+		return err
+	}
+
 	return nil
 
 }
@@ -54,10 +59,11 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		return nil, false, err
 	}
 
+	runtime := docker.ArtifactRuntime(artifact, p.config.Runtime)
 	driver := p.Driver
 	if driver == nil {
 		// If no driver is set, then we use the real driver
-		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
+		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui, Runtime: runtime}
 	}
 
 	importRepo := p.config.Repository
@@ -77,6 +83,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		BuilderIdValue: BuilderId,
 		Driver:         driver,
 		IdValue:        importRepo,
+		Runtime:        runtime,
 	}
 
 	return artifact, true, nil
@@ -73,6 +73,28 @@ func TestPostProcessor_PostProcess(t *testing.T) {
 	}
 }
 
+func TestPostProcessor_PostProcess_runtime(t *testing.T) {
+	driver := &docker.MockDriver{}
+	p := &PostProcessor{Driver: driver}
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "1234567890abcdef",
+		StateValues:    map[string]interface{}{"runtime": docker.RuntimePodman},
+	}
+
+	result, _, err := p.PostProcess(testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if runtime := result.State("runtime"); runtime != docker.RuntimePodman {
+		t.Fatalf("bad runtime: %#v", runtime)
+	}
+}
+
 func TestPostProcessor_PostProcess_Force(t *testing.T) {
 	driver := &docker.MockDriver{}
 	p := &PostProcessor{Driver: driver}
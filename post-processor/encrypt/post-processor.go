@@ -0,0 +1,256 @@
+package encrypt
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// These are the tools the files can be encrypted with.
+const (
+	MethodAge = "age"
+	MethodGPG = "gpg"
+)
+
+// VaultRecipientsConfig is the field of a secret of the Vault KV secrets
+// engine the recipients are read from, either a string of recipients
+// separated by commas or newlines, or a list of strings.
+type VaultRecipientsConfig struct {
+	Path  string `mapstructure:"path"`
+	Field string `mapstructure:"field"`
+}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	Method            string                `mapstructure:"method"`
+	Recipients        []string              `mapstructure:"recipients"`
+	RecipientsEnv     string                `mapstructure:"recipients_env"`
+	VaultRecipients   VaultRecipientsConfig `mapstructure:"vault_recipients"`
+	OutputPath        string                `mapstructure:"output"`
+	KeepInputArtifact bool                  `mapstructure:"keep_input_artifact"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+
+	// lookPath is only overridden by tests.
+	lookPath func(file string) (string, error)
+}
+
+type outputPathTemplate struct {
+	BuildName   string
+	BuilderType string
+	Method      string
+	Name        string
+	Path        string
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"output"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packer.MultiError)
+
+	if p.config.Method == "" {
+		p.config.Method = MethodAge
+	}
+	if p.config.Method != MethodAge && p.config.Method != MethodGPG {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("method must be %s or %s, not %q", MethodAge, MethodGPG, p.config.Method))
+	}
+
+	if len(p.config.Recipients) == 0 && p.config.RecipientsEnv == "" && p.config.VaultRecipients.Path == "" {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("One of recipients, recipients_env or vault_recipients must be set"))
+	}
+	if p.config.VaultRecipients.Path == "" {
+		if p.config.VaultRecipients.Field != "" {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("vault_recipients: `path` must be set"))
+		}
+	} else if p.config.VaultRecipients.Field == "" {
+		p.config.VaultRecipients.Field = "recipients"
+	}
+
+	if p.config.OutputPath == "" {
+		p.config.OutputPath = "{{.Path}}.{{.Method}}"
+	}
+	if err = interpolate.Validate(p.config.OutputPath, &p.config.ctx); err != nil {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("Error parsing target template: %s", err))
+	}
+	// Each file of the artifact, and of each build, is encrypted to its own
+	// file
+	if !strings.Contains(p.config.OutputPath, ".Path") && !strings.Contains(p.config.OutputPath, ".Name") {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("output must use {{.Path}} or {{.Name}}, so that each file is encrypted to its own file"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	lookPath := p.lookPath
+	if lookPath == nil {
+		lookPath = exec.LookPath
+	}
+	tool, err := lookPath(p.config.Method)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error finding %s to encrypt the artifact with: %s", p.config.Method, err)
+	}
+
+	recipients, err := p.recipients()
+	if err != nil {
+		return nil, false, err
+	}
+
+	files := artifact.Files()
+	if len(files) == 0 {
+		return nil, false, fmt.Errorf("The artifact of %s has no files to encrypt", artifact.BuilderId())
+	}
+
+	// The files are only encrypted once it is known they each have their
+	// own encrypted file
+	targets := make([]string, len(files))
+	sources := make(map[string]string)
+	for i, path := range files {
+		p.config.ctx.Data = &outputPathTemplate{
+			BuildName:   p.config.PackerBuildName,
+			BuilderType: p.config.PackerBuilderType,
+			Method:      p.config.Method,
+			Name:        filepath.Base(path),
+			Path:        path,
+		}
+		target, err := interpolate.Render(p.config.OutputPath, &p.config.ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("Error interpolating output value: %s", err)
+		}
+		if target == path {
+			return nil, false, fmt.Errorf("The encrypted file can't overwrite %s", path)
+		}
+		if other, ok := sources[target]; ok {
+			return nil, false, fmt.Errorf("%s and %s would both be encrypted to %s", other, path, target)
+		}
+		sources[target] = path
+		targets[i] = target
+	}
+
+	newArtifact := &Artifact{}
+	for i, path := range files {
+		target := targets[i]
+		if err := os.MkdirAll(filepath.Dir(target), os.FileMode(0755)); err != nil {
+			return nil, false, fmt.Errorf("unable to create dir: %s", err.Error())
+		}
+
+		ui.Say(fmt.Sprintf("Encrypting %s to %s with %s...", path, target, p.config.Method))
+		cmd := encryptCommand(tool, p.config.Method, recipients, path, target)
+		log.Printf("Encrypting with: %s", strings.Join(cmd.Args, " "))
+
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			// Don't leave a partly encrypted file behind
+			os.Remove(target)
+			return nil, false, fmt.Errorf("Error encrypting %s: %s\n%s", path, err, strings.TrimSpace(stderr.String()))
+		}
+		newArtifact.files = append(newArtifact.files, target)
+	}
+
+	return newArtifact, p.config.KeepInputArtifact, nil
+}
+
+// recipients returns the configured recipients along with the ones of the
+// environment variable and of Vault, without duplicates.
+func (p *PostProcessor) recipients() ([]string, error) {
+	raw := append([]string{}, p.config.Recipients...)
+
+	if p.config.RecipientsEnv != "" {
+		value := os.Getenv(p.config.RecipientsEnv)
+		if value == "" {
+			return nil, fmt.Errorf("The environment variable %s of the recipients is not set", p.config.RecipientsEnv)
+		}
+		raw = append(raw, value)
+	}
+
+	if p.config.VaultRecipients.Path != "" {
+		values, err := readVaultRecipients(&p.config.VaultRecipients)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, values...)
+	}
+
+	var recipients []string
+	seen := make(map[string]bool)
+	for _, r := range raw {
+		for _, recipient := range splitRecipients(r) {
+			if seen[recipient] {
+				continue
+			}
+			seen[recipient] = true
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("No recipients to encrypt the artifact to")
+	}
+	return recipients, nil
+}
+
+// splitRecipients splits a list of recipients separated by commas or
+// newlines. A GPG user ID like "Jane Doe <jane@example.com>" has spaces.
+func splitRecipients(s string) []string {
+	var recipients []string
+	for _, r := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if r = strings.TrimSpace(r); r != "" {
+			recipients = append(recipients, r)
+		}
+	}
+	return recipients
+}
+
+// encryptCommand returns the command encrypting the file to the recipients.
+// GPG trusts the keys of the recipients since they were imported on purpose
+// into the keyring Packer runs with.
+func encryptCommand(tool, method string, recipients []string, path, target string) *exec.Cmd {
+	var args []string
+	switch method {
+	case MethodAge:
+		for _, r := range recipients {
+			args = append(args, "-r", r)
+		}
+		args = append(args, "-o", target, path)
+	case MethodGPG:
+		args = []string{"--batch", "--yes", "--trust-model", "always", "--output", target, "--encrypt"}
+		for _, r := range recipients {
+			args = append(args, "--recipient", r)
+		}
+		args = append(args, path)
+	}
+	return exec.Command(tool, args...)
+}
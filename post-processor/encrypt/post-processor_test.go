@@ -0,0 +1,218 @@
+package encrypt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"recipients": []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"},
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.Method != MethodAge {
+		t.Fatalf("bad method: %s", p.config.Method)
+	}
+
+	// Bad method
+	config := testConfig()
+	config["method"] = "zip"
+	p = PostProcessor{}
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// No recipients
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"method": "gpg"}); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Vault field without a path
+	config = testConfig()
+	config["vault_recipients"] = map[string]interface{}{"field": "keys"}
+	p = PostProcessor{}
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Vault field defaults
+	config = map[string]interface{}{
+		"vault_recipients": map[string]interface{}{"path": "secret/data/packer"},
+	}
+	p = PostProcessor{}
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.VaultRecipients.Field != "recipients" {
+		t.Fatalf("bad field: %s", p.config.VaultRecipients.Field)
+	}
+
+	// Output shared by the files
+	config = testConfig()
+	config["output"] = "{{.BuildName}}.age"
+	p = PostProcessor{}
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestPostProcessor_recipients(t *testing.T) {
+	os.Setenv("PACKER_TEST_RECIPIENTS", "B,\nJane Doe <jane@example.com>\n")
+	defer os.Unsetenv("PACKER_TEST_RECIPIENTS")
+
+	var p PostProcessor
+	config := map[string]interface{}{
+		"method":         "gpg",
+		"recipients":     []string{"A", "B"},
+		"recipients_env": "PACKER_TEST_RECIPIENTS",
+	}
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	recipients, err := p.recipients()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"A", "B", "Jane Doe <jane@example.com>"}
+	if !reflect.DeepEqual(recipients, expected) {
+		t.Fatalf("bad: %#v", recipients)
+	}
+
+	os.Unsetenv("PACKER_TEST_RECIPIENTS")
+	if _, err := p.recipients(); err == nil {
+		t.Fatal("should error without the environment variable")
+	}
+}
+
+func TestEncryptCommand(t *testing.T) {
+	cmd := encryptCommand("/usr/bin/age", MethodAge, []string{"A", "B"}, "disk.qcow2", "disk.qcow2.age")
+	expected := []string{"/usr/bin/age", "-r", "A", "-r", "B", "-o", "disk.qcow2.age", "disk.qcow2"}
+	if !reflect.DeepEqual(cmd.Args, expected) {
+		t.Fatalf("bad: %#v", cmd.Args)
+	}
+
+	cmd = encryptCommand("/usr/bin/gpg", MethodGPG, []string{"A"}, "disk.qcow2", "disk.qcow2.gpg")
+	expected = []string{"/usr/bin/gpg", "--batch", "--yes", "--trust-model", "always",
+		"--output", "disk.qcow2.gpg", "--encrypt", "--recipient", "A", "disk.qcow2"}
+	if !reflect.DeepEqual(cmd.Args, expected) {
+		t.Fatalf("bad: %#v", cmd.Args)
+	}
+}
+
+// testAge writes a script standing in for age, which prefixes the file with
+// the recipient, or fails after writing part of the output if the
+// recipient is "fail".
+func testAge(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "age")
+	script := `#!/bin/sh
+while [ $# -gt 1 ]; do
+  case "$1" in
+    -r) recipient="$2"; shift ;;
+    -o) out="$2"; shift ;;
+  esac
+  shift
+done
+echo "$recipient" > "$out"
+[ "$recipient" = fail ] && exit 1
+cat "$1" >> "$out"
+`
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return path
+}
+
+func TestPostProcessorPostProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stand-in of age is a shell script")
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	age := testAge(t, td)
+	disk := filepath.Join(td, "disk.qcow2")
+	if err := ioutil.WriteFile(disk, []byte("image"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p := PostProcessor{lookPath: func(string) (string, error) { return age, nil }}
+	config := map[string]interface{}{
+		"recipients":          []string{"age1key"},
+		"keep_input_artifact": true,
+	}
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{FilesValue: []string{disk}}
+	result, keep, err := p.PostProcess(packer.TestUi(t), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !keep {
+		t.Fatal("should keep the input artifact")
+	}
+	if !reflect.DeepEqual(result.Files(), []string{disk + ".age"}) {
+		t.Fatalf("bad: %#v", result.Files())
+	}
+	if contents, _ := ioutil.ReadFile(disk + ".age"); string(contents) != "age1key\nimage" {
+		t.Fatalf("bad: %q", contents)
+	}
+
+	// A failure leaves no encrypted file behind
+	p = PostProcessor{lookPath: func(string) (string, error) { return age, nil }}
+	config["recipients"] = []string{"fail"}
+	config["output"] = filepath.Join(td, "out", "{{.BuildName}}-{{.Name}}.age")
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, _, err := p.PostProcess(packer.TestUi(t), artifact); err == nil {
+		t.Fatal("should have error")
+	}
+	if files, _ := ioutil.ReadDir(filepath.Join(td, "out")); len(files) != 0 {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	// Files with the same name are not encrypted to the same file
+	other := filepath.Join(td, "other", "disk.qcow2")
+	if err := os.MkdirAll(filepath.Dir(other), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(other, []byte("image"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	p = PostProcessor{lookPath: func(string) (string, error) { return age, nil }}
+	config["recipients"] = []string{"age1key"}
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	artifact = &packer.MockArtifact{FilesValue: []string{disk, other}}
+	if _, _, err := p.PostProcess(packer.TestUi(t), artifact); err == nil {
+		t.Fatal("should have error")
+	}
+	if files, _ := ioutil.ReadDir(filepath.Join(td, "out")); len(files) != 0 {
+		t.Fatalf("bad: %#v", files)
+	}
+}
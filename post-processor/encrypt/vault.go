@@ -0,0 +1,100 @@
+package encrypt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/mitchellh/go-homedir"
+)
+
+const defaultVaultAddress = "https://127.0.0.1:8200"
+
+// readVaultRecipients reads the recipients from a secret of the Vault KV
+// secrets engine, of either version. The address of Vault and the token
+// used to authenticate are read from the usual VAULT_ADDR, VAULT_TOKEN and
+// VAULT_NAMESPACE environment variables.
+func readVaultRecipients(c *VaultRecipientsConfig) ([]string, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		address = defaultVaultAddress
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		// This is where `vault login` stores the token.
+		path, err := homedir.Expand("~/.vault-token")
+		if err != nil {
+			return nil, err
+		}
+		raw, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("Error reading Vault token from %s: %s", path, err)
+		}
+		token = strings.TrimSpace(string(raw))
+	}
+	if token == "" {
+		return nil, fmt.Errorf(
+			"No Vault token found to read the recipients with. " +
+				"Set VAULT_TOKEN or log in with `vault login`.")
+	}
+
+	url := fmt.Sprintf("%s/v1/%s", strings.TrimRight(address, "/"), strings.Trim(c.Path, "/"))
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the recipients from Vault at %s: %s", c.Path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Error reading the recipients from Vault at %s: %s: %s",
+			c.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("Error decoding the secret at %s: %s", c.Path, err)
+	}
+
+	// The data of version 2 of the engine is along with its metadata
+	data := secret.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	switch value := data[c.Field].(type) {
+	case string:
+		return []string{value}, nil
+	case []interface{}:
+		recipients := make([]string, 0, len(value))
+		for _, v := range value {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("The field %s of the secret at %s must be a list of strings", c.Field, c.Path)
+			}
+			recipients = append(recipients, s)
+		}
+		return recipients, nil
+	case nil:
+		return nil, fmt.Errorf("The secret at %s has no field %s", c.Path, c.Field)
+	default:
+		return nil, fmt.Errorf("The field %s of the secret at %s must be a string or a list of strings", c.Field, c.Path)
+	}
+}
@@ -0,0 +1,71 @@
+package encrypt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func testVault(t *testing.T, body string) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/packer", "/v1/kv/packer":
+			w.Write([]byte(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	os.Setenv("VAULT_ADDR", server.URL)
+	os.Setenv("VAULT_TOKEN", "token")
+	return func() {
+		os.Unsetenv("VAULT_ADDR")
+		os.Unsetenv("VAULT_TOKEN")
+		server.Close()
+	}
+}
+
+func TestReadVaultRecipients_kv2(t *testing.T) {
+	defer testVault(t, `{"data": {"data": {"recipients": ["A", "B"]}, "metadata": {"version": 3}}}`)()
+
+	recipients, err := readVaultRecipients(&VaultRecipientsConfig{Path: "secret/data/packer", Field: "recipients"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(recipients, []string{"A", "B"}) {
+		t.Fatalf("bad: %#v", recipients)
+	}
+}
+
+func TestReadVaultRecipients_kv1(t *testing.T) {
+	defer testVault(t, `{"data": {"keys": "A\nB"}}`)()
+
+	recipients, err := readVaultRecipients(&VaultRecipientsConfig{Path: "/kv/packer", Field: "keys"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(recipients, []string{"A\nB"}) {
+		t.Fatalf("bad: %#v", recipients)
+	}
+}
+
+func TestReadVaultRecipients_errors(t *testing.T) {
+	defer testVault(t, `{"data": {"keys": 42}}`)()
+
+	cases := []*VaultRecipientsConfig{
+		{Path: "kv/packer", Field: "keys"},
+		{Path: "kv/packer", Field: "recipients"},
+		{Path: "kv/missing", Field: "keys"},
+	}
+	for _, c := range cases {
+		if _, err := readVaultRecipients(c); err == nil {
+			t.Errorf("%#v: should have error", c)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package googlecomputeimport
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/builder/googlecompute"
+)
+
+// Artifact is the image imported to Compute Engine.
+type Artifact struct {
+	imageName string
+	projectId string
+	driver    googlecompute.Driver
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Id() string {
+	return a.imageName
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("A disk image was imported: %s", a.imageName)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "ImageName":
+		return a.imageName
+	case "ProjectId":
+		return a.projectId
+	}
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying image: %s", a.imageName)
+	errCh := a.driver.DeleteImage(a.imageName)
+	return <-errCh
+}
@@ -0,0 +1,129 @@
+package googlecomputeimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+
+	"github.com/hashicorp/packer/helper/useragent"
+)
+
+// NOTE: the vendored client libraries don't have the Cloud Build API, which
+// runs the image import tool, so it is called with its JSON API.
+const (
+	cloudBuildURL         = "https://cloudbuild.googleapis.com/v1/"
+	imageImportToolImage  = "gcr.io/compute-image-tools/gce_vm_image_import:release"
+	imageImportBuildSlack = 300
+)
+
+type cloudBuild struct {
+	Steps   []cloudBuildStep `json:"steps"`
+	Tags    []string         `json:"tags,omitempty"`
+	Timeout string           `json:"timeout,omitempty"`
+}
+
+type cloudBuildStep struct {
+	Name string   `json:"name"`
+	Args []string `json:"args"`
+}
+
+type cloudBuildOperation struct {
+	Name     string `json:"name"`
+	Done     bool   `json:"done"`
+	Metadata struct {
+		Build struct {
+			ID     string `json:"id"`
+			LogURL string `json:"logUrl"`
+		} `json:"build"`
+	} `json:"metadata"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// importImageBuild returns the build running the image import tool, to
+// create the image of the config from the disk in Cloud Storage.
+func importImageBuild(c *Config, source string) *cloudBuild {
+	timeout := int(c.importTimeout.Seconds())
+	args := []string{
+		fmt.Sprintf("-image_name=%s", c.ImageName),
+		fmt.Sprintf("-source_file=%s", source),
+		fmt.Sprintf("-os=%s", c.ImageOS),
+		fmt.Sprintf("-description=%s", c.ImageDescription),
+		fmt.Sprintf("-timeout=%ds", timeout),
+		"-client_id=packer",
+	}
+	if c.ImageFamily != "" {
+		args = append(args, fmt.Sprintf("-family=%s", c.ImageFamily))
+	}
+	if len(c.ImageLabels) > 0 {
+		labels := make([]string, 0, len(c.ImageLabels))
+		for k, v := range c.ImageLabels {
+			labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(labels)
+		args = append(args, fmt.Sprintf("-labels=%s", strings.Join(labels, ",")))
+	}
+	if len(c.ImageGuestOsFeatures) > 0 {
+		args = append(args, fmt.Sprintf("-guest_os_features=%s", strings.Join(c.ImageGuestOsFeatures, ",")))
+	}
+
+	// The build outlives the tool, so that it reports its timeout
+	return &cloudBuild{
+		Steps: []cloudBuildStep{
+			{Name: imageImportToolImage, Args: args},
+		},
+		Tags:    []string{"gce-daisy", "gce-daisy-image-import"},
+		Timeout: fmt.Sprintf("%ds", timeout+imageImportBuildSlack),
+	}
+}
+
+// cloudBuildClient starts builds of Cloud Build and follows their
+// operations with an authorized HTTP client.
+type cloudBuildClient struct {
+	client *http.Client
+}
+
+func (c *cloudBuildClient) create(project string, build *cloudBuild) (*cloudBuildOperation, error) {
+	body, err := json.Marshal(build)
+	if err != nil {
+		return nil, err
+	}
+	return c.do("POST", fmt.Sprintf("%sprojects/%s/builds", cloudBuildURL, project), body)
+}
+
+func (c *cloudBuildClient) get(operation string) (*cloudBuildOperation, error) {
+	return c.do("GET", cloudBuildURL+operation, nil)
+}
+
+func (c *cloudBuildClient) do(method, url string, body []byte) (*cloudBuildOperation, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", useragent.String())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(resp)
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	op := &cloudBuildOperation{}
+	if err := json.NewDecoder(resp.Body).Decode(op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
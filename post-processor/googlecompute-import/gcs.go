@@ -0,0 +1,92 @@
+package googlecomputeimport
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"google.golang.org/api/googleapi"
+
+	"github.com/hashicorp/packer/helper/useragent"
+	"github.com/hashicorp/packer/packer"
+)
+
+// NOTE: the vendored client libraries don't have the Cloud Storage API, so
+// the objects are uploaded and deleted with its JSON API.
+const (
+	gcsUploadURL = "https://www.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s"
+	gcsObjectURL = "https://www.googleapis.com/storage/v1/b/%s/o/%s"
+)
+
+// gcsClient uploads and deletes objects of Cloud Storage with an authorized
+// HTTP client.
+type gcsClient struct {
+	client *http.Client
+}
+
+// upload uploads the file to the object of the bucket, reporting the
+// progress of the upload to the UI.
+func (g *gcsClient) upload(ui packer.Ui, bucket, object, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	body := &progressReader{reader: file, size: info.Size(), ui: ui}
+	req, err := http.NewRequest("POST", fmt.Sprintf(gcsUploadURL, bucket, url.QueryEscape(object)), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("User-Agent", useragent.String())
+	return g.do(req)
+}
+
+// delete deletes the object of the bucket.
+func (g *gcsClient) delete(bucket, object string) error {
+	req, err := http.NewRequest("DELETE", fmt.Sprintf(gcsObjectURL, bucket, url.PathEscape(object)), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", useragent.String())
+	return g.do(req)
+}
+
+func (g *gcsClient) do(req *http.Request) error {
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(resp)
+	return googleapi.CheckResponse(resp)
+}
+
+// progressReader reports to the UI each 10 percent of the size read.
+type progressReader struct {
+	reader   io.Reader
+	size     int64
+	ui       packer.Ui
+	read     int64
+	reported int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.size > 0 {
+		percent := r.read * 100 / r.size
+		if percent/10 > r.reported/10 {
+			r.reported = percent
+			r.ui.Message(fmt.Sprintf("Upload progress: %d%%", percent))
+		}
+	}
+	return n, err
+}
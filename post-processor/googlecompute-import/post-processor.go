@@ -0,0 +1,299 @@
+package googlecomputeimport
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	compute "google.golang.org/api/compute/v1"
+
+	"github.com/hashicorp/packer/builder/googlecompute"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/helper/useragent"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+const BuilderId = "packer.post-processor.googlecompute-import"
+
+// The scopes of the Compute Engine, Cloud Storage and Cloud Build APIs.
+var importScopes = []string{"https://www.googleapis.com/auth/cloud-platform"}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	AccountFile string `mapstructure:"account_file"`
+	ProjectId   string `mapstructure:"project_id"`
+
+	Bucket               string            `mapstructure:"bucket"`
+	GCSObjectName        string            `mapstructure:"gcs_object_name"`
+	ImageDescription     string            `mapstructure:"image_description"`
+	ImageFamily          string            `mapstructure:"image_family"`
+	ImageGuestOsFeatures []string          `mapstructure:"image_guest_os_features"`
+	ImageLabels          map[string]string `mapstructure:"image_labels"`
+	ImageName            string            `mapstructure:"image_name"`
+	ImageOS              string            `mapstructure:"image_os"`
+	ImportTimeout        string            `mapstructure:"import_timeout"`
+	KeepOriginalImage    bool              `mapstructure:"keep_input_artifact"`
+	SkipClean            bool              `mapstructure:"skip_clean"`
+
+	account       googlecompute.AccountFile
+	importTimeout time.Duration
+	ctx           interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"gcs_object_name",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	// Set defaults
+	if p.config.GCSObjectName == "" {
+		p.config.GCSObjectName = "packer-import-{{timestamp}}"
+	}
+	if p.config.ImageDescription == "" {
+		p.config.ImageDescription = "Imported by Packer"
+	}
+	if p.config.ImportTimeout == "" {
+		p.config.ImportTimeout = "1h"
+	}
+
+	errs := new(packer.MultiError)
+
+	if err = interpolate.Validate(p.config.GCSObjectName, &p.config.ctx); err != nil {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("Error parsing gcs_object_name template: %s", err))
+	}
+
+	p.config.importTimeout, err = time.ParseDuration(p.config.ImportTimeout)
+	if err != nil {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("Failed parsing import_timeout: %s", err))
+	}
+
+	if p.config.AccountFile != "" {
+		if err := googlecompute.ProcessAccountFile(&p.config.account, p.config.AccountFile); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+
+	templates := map[string]*string{
+		"bucket":     &p.config.Bucket,
+		"image_name": &p.config.ImageName,
+		"project_id": &p.config.ProjectId,
+	}
+	for key, ptr := range templates {
+		if *ptr == "" {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("%s must be set", key))
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	log.Println(common.ScrubConfig(p.config, p.config.account.PrivateKey))
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	ui.Say("Starting googlecompute-import...")
+
+	source, format := findSource(artifact.Files())
+	if source == "" {
+		return nil, p.config.KeepOriginalImage, fmt.Errorf(
+			"No disk found in the artifact, googlecompute-import imports .tar.gz, .raw, .img, .qcow2, .vmdk, .vhd and .vhdx files")
+	}
+	if format != formatTarball && format != formatRaw && p.config.ImageOS == "" {
+		return nil, p.config.KeepOriginalImage, fmt.Errorf(
+			"image_os must be set to import a %s disk", format)
+	}
+
+	objectName, err := interpolate.Render(p.config.GCSObjectName, &p.config.ctx)
+	if err != nil {
+		return nil, p.config.KeepOriginalImage, fmt.Errorf("Error rendering gcs_object_name template: %s", err)
+	}
+
+	client, err := googlecompute.NewClientGCE(&p.config.account, importScopes)
+	if err != nil {
+		return nil, p.config.KeepOriginalImage, err
+	}
+	service, err := compute.New(client)
+	if err != nil {
+		return nil, p.config.KeepOriginalImage, err
+	}
+	service.UserAgent = useragent.String()
+
+	// Compute Engine creates images from tarballs with a disk.raw file
+	if format == formatRaw {
+		ui.Message(fmt.Sprintf("Packing %s into a tarball...", source))
+		tarball, err := createRawDiskTarball(source, os.TempDir())
+		if err != nil {
+			return nil, p.config.KeepOriginalImage, fmt.Errorf("Error packing %s: %s", source, err)
+		}
+		defer os.Remove(tarball)
+		source, format = tarball, formatTarball
+	}
+	if !strings.HasSuffix(objectName, "."+format) {
+		objectName = fmt.Sprintf("%s.%s", objectName, format)
+	}
+
+	gcs := &gcsClient{client: client}
+	ui.Message(fmt.Sprintf("Uploading %s to gs://%s/%s...", source, p.config.Bucket, objectName))
+	if err := gcs.upload(ui, p.config.Bucket, objectName, source); err != nil {
+		return nil, p.config.KeepOriginalImage, fmt.Errorf("Failed to upload %s: %s", source, err)
+	}
+	if !p.config.SkipClean {
+		defer func() {
+			ui.Message(fmt.Sprintf("Deleting import source gs://%s/%s", p.config.Bucket, objectName))
+			if err := gcs.delete(p.config.Bucket, objectName); err != nil {
+				ui.Error(fmt.Sprintf("Failed to delete gs://%s/%s: %s", p.config.Bucket, objectName, err))
+			}
+		}()
+	}
+
+	if format == formatTarball {
+		err = p.insertImage(ui, service, gcsURL(p.config.Bucket, objectName))
+	} else {
+		cloudBuild := &cloudBuildClient{client: client}
+		err = p.importImage(ui, cloudBuild, fmt.Sprintf("gs://%s/%s", p.config.Bucket, objectName))
+	}
+	if err != nil {
+		return nil, p.config.KeepOriginalImage, err
+	}
+
+	driver, err := googlecompute.NewDriverGCE(ui, p.config.ProjectId, &p.config.account)
+	if err != nil {
+		return nil, p.config.KeepOriginalImage, err
+	}
+	result := &Artifact{
+		imageName: p.config.ImageName,
+		projectId: p.config.ProjectId,
+		driver:    driver,
+	}
+	return result, p.config.KeepOriginalImage, nil
+}
+
+// insertImage creates the image from the tarball in Cloud Storage.
+func (p *PostProcessor) insertImage(ui packer.Ui, service *compute.Service, source string) error {
+	var guestOsFeatures []*compute.GuestOsFeature
+	for _, feature := range p.config.ImageGuestOsFeatures {
+		guestOsFeatures = append(guestOsFeatures, &compute.GuestOsFeature{Type: feature})
+	}
+
+	ui.Message(fmt.Sprintf("Creating the image %s...", p.config.ImageName))
+	op, err := service.Images.Insert(p.config.ProjectId, &compute.Image{
+		Description:     p.config.ImageDescription,
+		Family:          p.config.ImageFamily,
+		GuestOsFeatures: guestOsFeatures,
+		Labels:          p.config.ImageLabels,
+		Name:            p.config.ImageName,
+		RawDisk: &compute.ImageRawDisk{
+			Source: source,
+		},
+	}).Do()
+	if err != nil {
+		return fmt.Errorf("Error creating the image: %s", err)
+	}
+
+	deadline := time.Now().Add(p.config.importTimeout)
+	for op.Status != "DONE" {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("time out while waiting for the image %s to be created", p.config.ImageName)
+		}
+		time.Sleep(5 * time.Second)
+		op, err = service.GlobalOperations.Get(p.config.ProjectId, op.Name).Do()
+		if err != nil {
+			return fmt.Errorf("Error waiting for the image: %s", err)
+		}
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		return fmt.Errorf("Error creating the image: %s", op.Error.Errors[0].Message)
+	}
+	ui.Message(fmt.Sprintf("Image %s has been created!", p.config.ImageName))
+	return nil
+}
+
+// importImage converts the disk in Cloud Storage to an image with the image
+// import tool, in Cloud Build.
+func (p *PostProcessor) importImage(ui packer.Ui, cloudBuild *cloudBuildClient, source string) error {
+	ui.Message(fmt.Sprintf("Importing the image %s with Cloud Build...", p.config.ImageName))
+	build := importImageBuild(&p.config, source)
+	op, err := cloudBuild.create(p.config.ProjectId, build)
+	if err != nil {
+		return fmt.Errorf("Error starting the image import: %s", err)
+	}
+	if op.Metadata.Build.LogURL != "" {
+		ui.Message(fmt.Sprintf("Logs of the import: %s", op.Metadata.Build.LogURL))
+	}
+
+	deadline := time.Now().Add(p.config.importTimeout)
+	for !op.Done {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("time out while waiting for the import of the image %s", p.config.ImageName)
+		}
+		time.Sleep(15 * time.Second)
+		op, err = cloudBuild.get(op.Name)
+		if err != nil {
+			return fmt.Errorf("Error waiting for the image import: %s", err)
+		}
+	}
+	if op.Error != nil {
+		return fmt.Errorf("Error importing the image: %s", op.Error.Message)
+	}
+	ui.Message(fmt.Sprintf("Image %s has been imported!", p.config.ImageName))
+	return nil
+}
+
+// The formats of the disks, by their file extension.
+const (
+	formatTarball = "tar.gz"
+	formatRaw     = "raw"
+)
+
+var sourceFormats = map[string]string{
+	".tar.gz": formatTarball,
+	".raw":    formatRaw,
+	".img":    formatRaw,
+	".qcow2":  "qcow2",
+	".vmdk":   "vmdk",
+	".vhd":    "vhd",
+	".vhdx":   "vhdx",
+}
+
+// findSource returns the first disk of the files and its format.
+func findSource(files []string) (string, string) {
+	for _, path := range files {
+		name := strings.ToLower(filepath.Base(path))
+		for ext, format := range sourceFormats {
+			if strings.HasSuffix(name, ext) {
+				return path, format
+			}
+		}
+	}
+	return "", ""
+}
+
+// gcsURL returns the URL Compute Engine reads an object of Cloud Storage
+// from.
+func gcsURL(bucket, object string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucket, object)
+}
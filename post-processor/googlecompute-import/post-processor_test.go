@@ -0,0 +1,150 @@
+package googlecomputeimport
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"bucket":     "packer-bucket",
+		"image_name": "packer-image",
+		"project_id": "hashicorp",
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	p := new(PostProcessor)
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assert.Equal(t, "packer-import-{{timestamp}}", p.config.GCSObjectName, "The object name should be rendered later.")
+	assert.Equal(t, time.Hour, p.config.importTimeout, "Incorrect default import timeout.")
+
+	for _, key := range []string{"bucket", "image_name", "project_id"} {
+		raw := testConfig()
+		delete(raw, key)
+		if err := new(PostProcessor).Configure(raw); err == nil {
+			t.Errorf("%s should be required", key)
+		}
+	}
+
+	raw := testConfig()
+	raw["import_timeout"] = "soon"
+	if err := new(PostProcessor).Configure(raw); err == nil {
+		t.Error("An invalid import_timeout should be an error")
+	}
+}
+
+func TestFindSource(t *testing.T) {
+	source, format := findSource([]string{"output/packer.ovf", "output/disk.QCOW2"})
+	assert.Equal(t, "output/disk.QCOW2", source, "Incorrect source.")
+	assert.Equal(t, "qcow2", format, "Incorrect format.")
+
+	source, format = findSource([]string{"output/image.tar.gz"})
+	assert.Equal(t, "output/image.tar.gz", source, "Incorrect source.")
+	assert.Equal(t, formatTarball, format, "Incorrect format.")
+
+	source, format = findSource([]string{"output/disk.img"})
+	assert.Equal(t, formatRaw, format, "Raw disks should be packed.")
+
+	source, _ = findSource([]string{"output/packer.ovf"})
+	assert.Equal(t, "", source, "There should be no source.")
+}
+
+func TestCreateRawDiskTarball(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk := filepath.Join(dir, "disk.img")
+	if err := ioutil.WriteFile(disk, []byte("disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tarball, err := createRawDiskTarball(disk, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(tarball)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(gz)
+	header, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "disk.raw", header.Name, "Incorrect name of the disk in the tarball.")
+	assert.Equal(t, int64(4), header.Size, "Incorrect size of the disk in the tarball.")
+	content, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "disk", string(content), "Incorrect content of the disk.")
+	_, err = tr.Next()
+	assert.Equal(t, io.EOF, err, "The tarball should only have the disk.")
+}
+
+func TestGNUHeader(t *testing.T) {
+	modTime := time.Unix(1500000000, 0)
+	block := gnuHeader("disk.raw", 10<<30, modTime)
+	assert.Equal(t, "ustar  \x00", string(block[257:265]), "The header should be in the GNU format.")
+
+	header, err := tar.NewReader(bytes.NewReader(block)).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "disk.raw", header.Name, "Incorrect name.")
+	assert.Equal(t, int64(10<<30), header.Size, "Incorrect size of more than 8GiB.")
+	assert.Equal(t, byte(tar.TypeReg), header.Typeflag, "Incorrect type.")
+	assert.Equal(t, modTime.Unix(), header.ModTime.Unix(), "Incorrect modification time.")
+}
+
+func TestImportImageBuild(t *testing.T) {
+	c := &Config{
+		ImageName:        "packer-image",
+		ImageDescription: "Imported by Packer",
+		ImageFamily:      "packer",
+		ImageLabels:      map[string]string{"b": "2", "a": "1"},
+		ImageOS:          "debian-9",
+		importTimeout:    time.Hour,
+	}
+
+	build := importImageBuild(c, "gs://packer-bucket/disk.vmdk")
+	assert.Equal(t, "3900s", build.Timeout, "The build should outlive the import tool.")
+	assert.Equal(t, 1, len(build.Steps), "There should be one step.")
+	assert.Equal(t, imageImportToolImage, build.Steps[0].Name, "Incorrect image of the step.")
+	assert.Equal(t, []string{
+		"-image_name=packer-image",
+		"-source_file=gs://packer-bucket/disk.vmdk",
+		"-os=debian-9",
+		"-description=Imported by Packer",
+		"-timeout=3600s",
+		"-client_id=packer",
+		"-family=packer",
+		"-labels=a=1,b=2",
+	}, build.Steps[0].Args, "Incorrect arguments of the import tool.")
+}
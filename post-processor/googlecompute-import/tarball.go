@@ -0,0 +1,114 @@
+package googlecomputeimport
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// createRawDiskTarball packs the raw disk into a gzipped tarball in the
+// directory, as the disk.raw file Compute Engine creates images from, and
+// returns the path of the tarball.
+func createRawDiskTarball(disk, dir string) (string, error) {
+	in, err := os.Open(disk)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ioutil.TempFile(dir, "packer-import-")
+	if err != nil {
+		return "", err
+	}
+	tarball := out.Name()
+	if err := writeRawDiskTarball(out, in, info); err != nil {
+		out.Close()
+		os.Remove(tarball)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tarball)
+		return "", err
+	}
+	return tarball, nil
+}
+
+func writeRawDiskTarball(w io.Writer, disk io.Reader, info os.FileInfo) error {
+	gz := gzip.NewWriter(w)
+
+	// Compute Engine only reads the GNU format, which archive/tar only
+	// writes since Go 1.10, so the header is written here.
+	if _, err := gz.Write(gnuHeader("disk.raw", info.Size(), info.ModTime())); err != nil {
+		return err
+	}
+	n, err := io.Copy(gz, disk)
+	if err != nil {
+		return err
+	}
+	if n != info.Size() {
+		return fmt.Errorf("the disk was %d bytes long, not %d", n, info.Size())
+	}
+
+	// The content is padded to a block, and the archive ends with two
+	// zero blocks.
+	padding := (tarBlockSize - n%tarBlockSize) % tarBlockSize
+	if _, err := gz.Write(make([]byte, padding+2*tarBlockSize)); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+const tarBlockSize = 512
+
+// gnuHeader returns the header block of the GNU tar format of a regular
+// file.
+func gnuHeader(name string, size int64, modTime time.Time) []byte {
+	block := make([]byte, tarBlockSize)
+	copy(block[0:100], name)
+	tarOctal(block[100:108], 0644)
+	tarOctal(block[108:116], 0)
+	tarOctal(block[116:124], 0)
+	tarNumeric(block[124:136], size)
+	tarOctal(block[136:148], modTime.Unix())
+	block[156] = tar.TypeReg
+	copy(block[257:265], "ustar  \x00")
+	copy(block[265:297], "root")
+	copy(block[297:329], "root")
+
+	// The checksum is computed with its own field filled with spaces
+	copy(block[148:156], "        ")
+	var checksum int64
+	for _, b := range block {
+		checksum += int64(b)
+	}
+	copy(block[148:156], fmt.Sprintf("%06o\x00 ", checksum))
+	return block
+}
+
+// tarOctal writes the number in the field, in NUL terminated octal.
+func tarOctal(field []byte, n int64) {
+	copy(field, fmt.Sprintf("%0*o\x00", len(field)-1, n))
+}
+
+// tarNumeric writes the number in the field in octal, or in the base-256
+// encoding of GNU tar if it doesn't fit, such as for the sizes of 8GiB or
+// more.
+func tarNumeric(field []byte, n int64) {
+	if n < 1<<uint(3*(len(field)-1)) {
+		tarOctal(field, n)
+		return
+	}
+	for i := len(field) - 1; i > 0; i-- {
+		field[i] = byte(n)
+		n >>= 8
+	}
+	field[0] = 0x80
+}
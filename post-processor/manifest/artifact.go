@@ -16,6 +16,17 @@ type Artifact struct {
 	ArtifactFiles []ArtifactFile `json:"files"`
 	ArtifactId    string         `json:"artifact_id"`
 	PackerRunUUID string         `json:"packer_run_uuid"`
+
+	// Lineage and LineageVersion are the version of the image family the
+	// build produced, if the template has a lineage.
+	Lineage        string `json:"lineage,omitempty"`
+	LineageVersion int    `json:"lineage_version,omitempty"`
+
+	// CustomData and Metadata are what the template recorded about the
+	// build, such as its inputs or the packages installed on the image,
+	// for builds to be compared with packer diff.
+	CustomData map[string]string   `json:"custom_data,omitempty"`
+	Metadata   map[string][]string `json:"metadata,omitempty"`
 }
 
 func (a *Artifact) BuilderId() string {
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/packer/common"
@@ -18,9 +19,11 @@ import (
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	OutputPath string `mapstructure:"output"`
-	StripPath  bool   `mapstructure:"strip_path"`
-	ctx        interpolate.Context
+	OutputPath    string            `mapstructure:"output"`
+	StripPath     bool              `mapstructure:"strip_path"`
+	CustomData    map[string]string `mapstructure:"custom_data"`
+	MetadataFiles map[string]string `mapstructure:"metadata_files"`
+	ctx           interpolate.Context
 }
 
 type PostProcessor struct {
@@ -78,6 +81,21 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, source packer.Artifact) (packe
 	artifact.BuilderType = p.config.PackerBuilderType
 	artifact.BuildName = p.config.PackerBuildName
 	artifact.BuildTime = time.Now().Unix()
+	artifact.Lineage = p.config.PackerLineage
+	artifact.LineageVersion = p.config.PackerLineageVersion
+	if len(p.config.CustomData) > 0 {
+		artifact.CustomData = p.config.CustomData
+	}
+	for name, path := range p.config.MetadataFiles {
+		lines, err := readMetadataFile(path)
+		if err != nil {
+			return source, true, fmt.Errorf("Unable to read metadata file %s: %s", path, err)
+		}
+		if artifact.Metadata == nil {
+			artifact.Metadata = make(map[string][]string)
+		}
+		artifact.Metadata[name] = lines
+	}
 	// Since each post-processor runs in a different process we need a way to
 	// coordinate between various post-processors in a single packer run. We do
 	// this by setting a UUID per run and tracking this in the manifest file.
@@ -116,9 +134,17 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, source packer.Artifact) (packe
 	}
 
 	// If -force is set and we are not on same run, truncate the file. Otherwise
-	// we will continue to add new builds to the existing manifest file.
+	// we will continue to add new builds to the existing manifest file. The
+	// builds of lineages are kept, as they're the history the next versions
+	// are numbered from.
 	if p.config.PackerForce && os.Getenv("PACKER_RUN_UUID") != manifestFile.LastRunUUID {
-		manifestFile = &ManifestFile{}
+		var lineageBuilds []Artifact
+		for _, build := range manifestFile.Builds {
+			if build.Lineage != "" {
+				lineageBuilds = append(lineageBuilds, build)
+			}
+		}
+		manifestFile = &ManifestFile{Builds: lineageBuilds}
 	}
 
 	// Add the current artifact to the manifest file
@@ -136,3 +162,20 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, source packer.Artifact) (packe
 
 	return source, true, nil
 }
+
+// readMetadataFile returns the non-empty lines of a metadata file, such as
+// a package inventory the file provisioner downloaded from the machine.
+func readMetadataFile(path string) ([]string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.Replace(string(contents), "\r\n", "\n", -1), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
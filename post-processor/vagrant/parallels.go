@@ -38,13 +38,13 @@ func (p *ParallelsProvider) Process(ui packer.Ui, artifact packer.Artifact, dir
 		}
 
 		tmpPath := filepath.ToSlash(path)
-		pathRe := regexp.MustCompile(`^(.+?)([^/]+\.pvm/.+?)$`)
+		pathRe := regexp.MustCompile(`^(.+?)([^/]+\.(pvm|macvm)/.+?)$`)
 		matches := pathRe.FindStringSubmatch(tmpPath)
 		var pvmPath string
 		if matches != nil {
 			pvmPath = filepath.FromSlash(matches[2])
 		} else {
-			continue // Just copy a pvm
+			continue // Just copy a pvm, or the macvm of a macOS VM on Apple silicon
 		}
 		dstPath := filepath.Join(dir, pvmPath)
 
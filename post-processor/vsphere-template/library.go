@@ -0,0 +1,244 @@
+package vsphere_template
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NOTE: the vendored govmomi doesn't have the vSphere Automation API, which
+// serves the Content Libraries, so the few calls the post-processor makes are
+// done with the client here.
+
+// libraryClient is a client of the vSphere Automation REST API of a vCenter.
+type libraryClient struct {
+	url        string
+	session    string
+	httpClient *http.Client
+}
+
+// libraryItem is an item of a Content Library.
+type libraryItem struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Description    string `json:"description"`
+	ContentVersion string `json:"content_version"`
+}
+
+// libraryError is the error returned by the API.
+type libraryError struct {
+	StatusCode int
+	Type       string `json:"type"`
+	Value      struct {
+		Messages []struct {
+			DefaultMessage string `json:"default_message"`
+		} `json:"messages"`
+	} `json:"value"`
+}
+
+func (e *libraryError) Error() string {
+	messages := make([]string, 0, len(e.Value.Messages))
+	for _, m := range e.Value.Messages {
+		messages = append(messages, m.DefaultMessage)
+	}
+	if len(messages) == 0 {
+		return fmt.Sprintf("vSphere API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("vSphere API error: %d %s", e.StatusCode, strings.Join(messages, ", "))
+}
+
+// newLibraryClient logs in to the vCenter at the host.
+func newLibraryClient(host string, username string, password string, insecure bool) (*libraryClient, error) {
+	transport := http.DefaultTransport.(*http.Transport)
+	if insecure {
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	c := &libraryClient{
+		url: fmt.Sprintf("https://%s/rest", host),
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Minute,
+		},
+	}
+
+	req, err := http.NewRequest("POST", c.url+"/com/vmware/cis/session", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(username, password)
+	var session string
+	if err := c.send(req, &session); err != nil {
+		return nil, fmt.Errorf("Error logging in to the vSphere API: %s", err)
+	}
+	c.session = session
+	return c, nil
+}
+
+// Logout ends the session of the client.
+func (c *libraryClient) Logout() error {
+	return c.do("DELETE", "com/vmware/cis/session", nil, nil)
+}
+
+// FindLibrary returns the ID of the Content Library with the name.
+func (c *libraryClient) FindLibrary(name string) (string, error) {
+	var ids []string
+	body := map[string]interface{}{"spec": map[string]string{"name": name}}
+	if err := c.do("POST", "com/vmware/content/library?~action=find", body, &ids); err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("Content Library %s not found", name)
+	}
+	return ids[0], nil
+}
+
+// FindItem returns the item with the name in the library, or nil if there is
+// none.
+func (c *libraryClient) FindItem(libraryID string, name string) (*libraryItem, error) {
+	var ids []string
+	body := map[string]interface{}{
+		"spec": map[string]string{"library_id": libraryID, "name": name},
+	}
+	if err := c.do("POST", "com/vmware/content/library/item?~action=find", body, &ids); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return c.GetItem(ids[0])
+}
+
+// GetItem returns an item.
+func (c *libraryClient) GetItem(id string) (*libraryItem, error) {
+	item := new(libraryItem)
+	err := c.do("GET", "com/vmware/content/library/item/id:"+id, nil, item)
+	return item, err
+}
+
+// UpdateItem sets the name and the description of an item, those that
+// aren't empty.
+func (c *libraryClient) UpdateItem(id string, name string, description string) error {
+	spec := map[string]string{}
+	if name != "" {
+		spec["name"] = name
+	}
+	if description != "" {
+		spec["description"] = description
+	}
+	body := map[string]interface{}{"update_spec": spec}
+	return c.do("PATCH", "com/vmware/content/library/item/id:"+id, body, nil)
+}
+
+// DeleteItem deletes an item, and its content.
+func (c *libraryClient) DeleteItem(id string) error {
+	return c.do("DELETE", "com/vmware/content/library/item/id:"+id, nil, nil)
+}
+
+// DeployOVF exports the powered off VM as an OVF template. It is a new item
+// of the library, or a new version of the item, when itemID is set.
+func (c *libraryClient) DeployOVF(vmID string, libraryID string, itemID string, name string, description string) (string, error) {
+	target := map[string]string{"library_id": libraryID}
+	if itemID != "" {
+		target = map[string]string{"library_item_id": itemID}
+	}
+	body := map[string]interface{}{
+		"source": map[string]string{"type": "VirtualMachine", "id": vmID},
+		"target": target,
+		"create_spec": map[string]string{
+			"name":        name,
+			"description": description,
+		},
+	}
+
+	var result struct {
+		Succeeded        bool   `json:"succeeded"`
+		OVFLibraryItemID string `json:"ovf_library_item_id"`
+		Error            struct {
+			Errors []struct {
+				Message struct {
+					DefaultMessage string `json:"default_message"`
+				} `json:"message"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	if err := c.do("POST", "com/vmware/vcenter/ovf/library-item", body, &result); err != nil {
+		return "", err
+	}
+	if !result.Succeeded {
+		messages := []string{}
+		for _, e := range result.Error.Errors {
+			messages = append(messages, e.Message.DefaultMessage)
+		}
+		return "", fmt.Errorf("Error exporting the OVF template: %s", strings.Join(messages, ", "))
+	}
+	return result.OVFLibraryItemID, nil
+}
+
+// CreateVMTemplate clones the VM into a VM template item of the library.
+func (c *libraryClient) CreateVMTemplate(vmID string, libraryID string, name string, description string) (string, error) {
+	body := map[string]interface{}{
+		"spec": map[string]string{
+			"source_vm":   vmID,
+			"library":     libraryID,
+			"name":        name,
+			"description": description,
+		},
+	}
+	var id string
+	err := c.do("POST", "vcenter/vm-template/library-items", body, &id)
+	return id, err
+}
+
+func (c *libraryClient) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.url+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("vmware-api-session-id", c.session)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, result)
+}
+
+// send sends the request, and decodes the value the API wraps its results in.
+func (c *libraryClient) send(req *http.Request, result interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &libraryError{StatusCode: resp.StatusCode}
+		// The errors are best effort, the status is enough to fail
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(&struct {
+		Value interface{} `json:"value"`
+	}{result})
+}
@@ -0,0 +1,205 @@
+package vsphere_template
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testLibrary is a stub of the Content Library API, with the items of the
+// library "templates".
+type testLibrary struct {
+	t      *testing.T
+	items  map[string]*libraryItem
+	nextID int
+
+	// failCreate fails the creation of the VM templates
+	failCreate bool
+}
+
+func newTestLibrary(t *testing.T, items ...*libraryItem) (*testLibrary, *httptest.Server) {
+	l := &testLibrary{t: t, items: make(map[string]*libraryItem)}
+	for _, item := range items {
+		l.items[item.ID] = item
+	}
+	return l, httptest.NewTLSServer(l)
+}
+
+func (l *testLibrary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Spec       map[string]string `json:"spec"`
+		UpdateSpec map[string]string `json:"update_spec"`
+		Source     map[string]string `json:"source"`
+		Target     map[string]string `json:"target"`
+		CreateSpec map[string]string `json:"create_spec"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	if r.URL.Path != "/rest/com/vmware/cis/session" && r.Header.Get("vmware-api-session-id") != "session" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/rest/")
+	switch {
+	case path == "com/vmware/cis/session" && r.Method == "POST":
+		if username, password, _ := r.BasicAuth(); username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		l.reply(w, "session")
+	case path == "com/vmware/cis/session" && r.Method == "DELETE":
+	case path == "com/vmware/content/library":
+		ids := []string{}
+		if body.Spec["name"] == "templates" {
+			ids = append(ids, "library")
+		}
+		l.reply(w, ids)
+	case path == "com/vmware/content/library/item":
+		ids := []string{}
+		for id, item := range l.items {
+			if body.Spec["library_id"] == "library" && item.Name == body.Spec["name"] {
+				ids = append(ids, id)
+			}
+		}
+		l.reply(w, ids)
+	case strings.HasPrefix(path, "com/vmware/content/library/item/id:"):
+		item := l.items[strings.TrimPrefix(path, "com/vmware/content/library/item/id:")]
+		if item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type": "com.vmware.vapi.std.errors.not_found", "value": {"messages": [{"default_message": "Item not found"}]}}`))
+			return
+		}
+		switch r.Method {
+		case "GET":
+			l.reply(w, item)
+		case "PATCH":
+			if name, ok := body.UpdateSpec["name"]; ok {
+				item.Name = name
+			}
+			if description, ok := body.UpdateSpec["description"]; ok {
+				item.Description = description
+			}
+		case "DELETE":
+			delete(l.items, item.ID)
+		}
+	case path == "com/vmware/vcenter/ovf/library-item":
+		item := l.items[body.Target["library_item_id"]]
+		if item == nil {
+			item = l.add(body.CreateSpec["name"], libraryItemOVF, body.CreateSpec["description"])
+		} else {
+			version, _ := strconv.Atoi(item.ContentVersion)
+			item.ContentVersion = strconv.Itoa(version + 1)
+		}
+		l.reply(w, map[string]interface{}{"succeeded": true, "ovf_library_item_id": item.ID})
+	case path == "vcenter/vm-template/library-items":
+		if l.failCreate {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, item := range l.items {
+			if item.Name == body.Spec["name"] {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"value": {"messages": [{"default_message": "Duplicate item name"}]}}`))
+				return
+			}
+		}
+		l.reply(w, l.add(body.Spec["name"], libraryItemVMTemplate, body.Spec["description"]).ID)
+	default:
+		l.t.Errorf("bad request: %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (l *testLibrary) add(name string, itemType string, description string) *libraryItem {
+	l.nextID++
+	item := &libraryItem{
+		ID:             fmt.Sprintf("item-%d", l.nextID),
+		Name:           name,
+		Type:           itemType,
+		Description:    description,
+		ContentVersion: "1",
+	}
+	l.items[item.ID] = item
+	return item
+}
+
+func (l *testLibrary) reply(w http.ResponseWriter, value interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+}
+
+func testLibraryClient(t *testing.T, server *httptest.Server) *libraryClient {
+	host := strings.TrimPrefix(server.URL, "https://")
+	lc, err := newLibraryClient(host, "user", "pass", true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return lc
+}
+
+func TestLibraryClient_login(t *testing.T) {
+	_, server := newTestLibrary(t)
+	defer server.Close()
+
+	lc := testLibraryClient(t, server)
+	if lc.session != "session" {
+		t.Fatalf("bad: %s", lc.session)
+	}
+	if err := lc.Logout(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Bad
+	host := strings.TrimPrefix(server.URL, "https://")
+	if _, err := newLibraryClient(host, "user", "bad", true); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestLibraryClient_FindItem(t *testing.T) {
+	_, server := newTestLibrary(t, &libraryItem{ID: "item", Name: "centos", Type: libraryItemOVF})
+	defer server.Close()
+
+	lc := testLibraryClient(t, server)
+	libraryID, err := lc.FindLibrary("templates")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	item, err := lc.FindItem(libraryID, "centos")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if item == nil || item.ID != "item" || item.Type != libraryItemOVF {
+		t.Fatalf("bad: %#v", item)
+	}
+
+	item, err = lc.FindItem(libraryID, "ubuntu")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if item != nil {
+		t.Fatalf("bad: %#v", item)
+	}
+
+	// Bad
+	if _, err := lc.FindLibrary("other"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestLibraryClient_error(t *testing.T) {
+	_, server := newTestLibrary(t)
+	defer server.Close()
+
+	lc := testLibraryClient(t, server)
+	_, err := lc.GetItem("missing")
+	expected := "vSphere API error: 404 Item not found"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("bad: %v", err)
+	}
+}
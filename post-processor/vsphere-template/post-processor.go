@@ -32,6 +32,11 @@ type Config struct {
 	Datacenter          string `mapstructure:"datacenter"`
 	Folder              string `mapstructure:"folder"`
 
+	ContentLibrary      string `mapstructure:"content_library"`
+	ContentLibraryItem  string `mapstructure:"content_library_item"`
+	ContentLibraryOVF   bool   `mapstructure:"content_library_ovf"`
+	ContentLibraryNotes string `mapstructure:"content_library_notes"`
+
 	ctx interpolate.Context
 }
 
@@ -72,6 +77,12 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 			errs, fmt.Errorf("Folder must be bound to the root"))
 	}
 
+	if p.config.ContentLibrary == "" &&
+		(p.config.ContentLibraryItem != "" || p.config.ContentLibraryOVF || p.config.ContentLibraryNotes != "") {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("content_library_item, content_library_ovf and content_library_notes require content_library"))
+	}
+
 	sdk, err := url.Parse(fmt.Sprintf("https://%v/sdk", p.config.Host))
 	if err != nil {
 		errs = packer.MultiErrorAppend(
@@ -122,10 +133,16 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		&stepChooseDatacenter{
 			Datacenter: p.config.Datacenter,
 		},
-		&stepCreateFolder{
-			Folder: p.config.Folder,
-		},
-		NewStepMarkAsTemplate(artifact),
+	}
+	if p.config.ContentLibrary != "" {
+		steps = append(steps, NewStepPublishToLibrary(artifact, &p.config))
+	} else {
+		steps = append(steps,
+			&stepCreateFolder{
+				Folder: p.config.Folder,
+			},
+			NewStepMarkAsTemplate(artifact),
+		)
 	}
 	runner := common.NewRunnerWithPauseFn(steps, p.config.PackerConfig, ui, state)
 	runner.Run(state)
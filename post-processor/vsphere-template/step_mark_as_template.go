@@ -21,6 +21,17 @@ type stepMarkAsTemplate struct {
 }
 
 func NewStepMarkAsTemplate(artifact packer.Artifact) *stepMarkAsTemplate {
+	vmname, remoteFolder := vmLocation(artifact)
+
+	return &stepMarkAsTemplate{
+		VMName:       vmname,
+		RemoteFolder: remoteFolder,
+	}
+}
+
+// vmLocation returns the name of the VM of the artifact, and the folder it is
+// registered in.
+func vmLocation(artifact packer.Artifact) (string, string) {
 	remoteFolder := "Discovered virtual machine"
 	vmname := artifact.Id()
 
@@ -30,10 +41,7 @@ func NewStepMarkAsTemplate(artifact packer.Artifact) *stepMarkAsTemplate {
 		vmname = id[2]
 	}
 
-	return &stepMarkAsTemplate{
-		VMName:       vmname,
-		RemoteFolder: remoteFolder,
-	}
+	return vmname, remoteFolder
 }
 
 func (s *stepMarkAsTemplate) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -0,0 +1,145 @@
+package vsphere_template
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/vmware/govmomi"
+)
+
+// The types of the items of a Content Library.
+const (
+	libraryItemOVF        = "ovf"
+	libraryItemVMTemplate = "vm-template"
+)
+
+// stepPublishToLibrary publishes the VM to a Content Library, as an OVF
+// template or a VM template, in place of marking it as a template. An
+// existing item of the same name is updated, or replaced once the new one
+// is published.
+type stepPublishToLibrary struct {
+	VMName       string
+	RemoteFolder string
+	Config       *Config
+}
+
+func NewStepPublishToLibrary(artifact packer.Artifact, config *Config) *stepPublishToLibrary {
+	vmname, remoteFolder := vmLocation(artifact)
+	return &stepPublishToLibrary{
+		VMName:       vmname,
+		RemoteFolder: remoteFolder,
+		Config:       config,
+	}
+}
+
+func (s *stepPublishToLibrary) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	cli := state.Get("client").(*govmomi.Client)
+	dcPath := state.Get("dcPath").(string)
+
+	itemName := s.Config.ContentLibraryItem
+	if itemName == "" {
+		itemName = s.VMName
+	}
+	itemType := libraryItemVMTemplate
+	if s.Config.ContentLibraryOVF {
+		itemType = libraryItemOVF
+	}
+
+	ui.Message(fmt.Sprintf("Publishing to Content Library %s as %s...", s.Config.ContentLibrary, itemName))
+
+	vm, err := findRuntimeVM(cli, dcPath, s.VMName, s.RemoteFolder)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	lc, err := newLibraryClient(s.Config.Host, s.Config.Username, s.Config.Password, s.Config.Insecure)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	defer lc.Logout()
+
+	item, err := s.publish(ui, lc, vm.Reference().Value, itemName, itemType)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	ui.Message(fmt.Sprintf("Published %s (ID: %s), version %s", item.Name, item.ID, item.ContentVersion))
+
+	return multistep.ActionContinue
+}
+
+// publish publishes the VM as the item of the library, and returns the item.
+func (s *stepPublishToLibrary) publish(ui packer.Ui, lc *libraryClient, vmID string, itemName string, itemType string) (*libraryItem, error) {
+	libraryID, err := lc.FindLibrary(s.Config.ContentLibrary)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := lc.FindItem(libraryID, itemName)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Type != itemType {
+		return nil, fmt.Errorf("the item %s of the Content Library is a %s template, not a %s one",
+			itemName, existing.Type, itemType)
+	}
+
+	var itemID string
+	if itemType == libraryItemOVF {
+		// The OVF templates have versions, the existing one gets a new one
+		updateID := ""
+		if existing != nil {
+			ui.Message(fmt.Sprintf("Updating the OVF template of version %s...", existing.ContentVersion))
+			updateID = existing.ID
+		}
+		itemID, err = lc.DeployOVF(vmID, libraryID, updateID, itemName, s.Config.ContentLibraryNotes)
+		if err != nil {
+			return nil, err
+		}
+
+		// The notes are of the version published, not of the first one
+		if existing != nil && s.Config.ContentLibraryNotes != "" {
+			if err := lc.UpdateItem(itemID, "", s.Config.ContentLibraryNotes); err != nil {
+				return nil, fmt.Errorf("Error setting the notes of the item: %s", err)
+			}
+		}
+	} else if existing == nil {
+		itemID, err = lc.CreateVMTemplate(vmID, libraryID, itemName, s.Config.ContentLibraryNotes)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// The VM templates can only be updated from the VMs checked out of
+		// them, so the existing one is replaced. The new one is created
+		// under a temporary name first, leaving the existing one in place
+		// if that fails.
+		ui.Message("Replacing the existing VM template...")
+		tempName := fmt.Sprintf("%s-packer-%d", itemName, time.Now().Unix())
+		itemID, err = lc.CreateVMTemplate(vmID, libraryID, tempName, s.Config.ContentLibraryNotes)
+		if err != nil {
+			return nil, err
+		}
+		if err := lc.DeleteItem(existing.ID); err != nil {
+			if err := lc.DeleteItem(itemID); err != nil {
+				ui.Error(fmt.Sprintf("Error deleting the new VM template %s: %s", tempName, err))
+			}
+			return nil, fmt.Errorf("Error deleting the existing VM template: %s", err)
+		}
+		if err := lc.UpdateItem(itemID, itemName, ""); err != nil {
+			return nil, fmt.Errorf("Error renaming the new VM template %s to %s: %s", tempName, itemName, err)
+		}
+	}
+
+	return lc.GetItem(itemID)
+}
+
+func (s *stepPublishToLibrary) Cleanup(multistep.StateBag) {}
@@ -0,0 +1,82 @@
+package vsphere_template
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestStepPublishToLibrary_createVMTemplate(t *testing.T) {
+	library, server := newTestLibrary(t)
+	defer server.Close()
+
+	step := &stepPublishToLibrary{Config: &Config{ContentLibrary: "templates", ContentLibraryNotes: "notes"}}
+	item, err := step.publish(packer.TestUi(t), testLibraryClient(t, server), "vm-1", "centos", libraryItemVMTemplate)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if item.Name != "centos" || item.Type != libraryItemVMTemplate || item.Description != "notes" {
+		t.Fatalf("bad: %#v", item)
+	}
+	if len(library.items) != 1 {
+		t.Fatalf("bad: %#v", library.items)
+	}
+}
+
+func TestStepPublishToLibrary_replaceVMTemplate(t *testing.T) {
+	library, server := newTestLibrary(t, &libraryItem{ID: "old", Name: "centos", Type: libraryItemVMTemplate})
+	defer server.Close()
+
+	step := &stepPublishToLibrary{Config: &Config{ContentLibrary: "templates"}}
+	item, err := step.publish(packer.TestUi(t), testLibraryClient(t, server), "vm-1", "centos", libraryItemVMTemplate)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if item.ID == "old" || item.Name != "centos" {
+		t.Fatalf("bad: %#v", item)
+	}
+	if _, ok := library.items["old"]; ok || len(library.items) != 1 {
+		t.Fatalf("the existing VM template should have been replaced: %#v", library.items)
+	}
+}
+
+func TestStepPublishToLibrary_replaceVMTemplateFailure(t *testing.T) {
+	library, server := newTestLibrary(t, &libraryItem{ID: "old", Name: "centos", Type: libraryItemVMTemplate})
+	defer server.Close()
+	library.failCreate = true
+
+	step := &stepPublishToLibrary{Config: &Config{ContentLibrary: "templates"}}
+	if _, err := step.publish(packer.TestUi(t), testLibraryClient(t, server), "vm-1", "centos", libraryItemVMTemplate); err == nil {
+		t.Fatal("should have error")
+	}
+	if item, ok := library.items["old"]; !ok || item.Name != "centos" || len(library.items) != 1 {
+		t.Fatalf("the existing VM template should have been kept: %#v", library.items)
+	}
+}
+
+func TestStepPublishToLibrary_updateOVF(t *testing.T) {
+	library, server := newTestLibrary(t, &libraryItem{ID: "old", Name: "centos", Type: libraryItemOVF, ContentVersion: "1"})
+	defer server.Close()
+
+	step := &stepPublishToLibrary{Config: &Config{ContentLibrary: "templates", ContentLibraryNotes: "notes"}}
+	item, err := step.publish(packer.TestUi(t), testLibraryClient(t, server), "vm-1", "centos", libraryItemOVF)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if item.ID != "old" || item.ContentVersion != "2" || item.Description != "notes" {
+		t.Fatalf("bad: %#v", item)
+	}
+	if len(library.items) != 1 {
+		t.Fatalf("bad: %#v", library.items)
+	}
+}
+
+func TestStepPublishToLibrary_typeMismatch(t *testing.T) {
+	_, server := newTestLibrary(t, &libraryItem{ID: "old", Name: "centos", Type: libraryItemOVF})
+	defer server.Close()
+
+	step := &stepPublishToLibrary{Config: &Config{ContentLibrary: "templates"}}
+	if _, err := step.publish(packer.TestUi(t), testLibraryClient(t, server), "vm-1", "centos", libraryItemVMTemplate); err == nil {
+		t.Fatal("should have error")
+	}
+}
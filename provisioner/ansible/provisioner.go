@@ -189,6 +189,11 @@ func (p *Provisioner) getVersion() error {
 func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 	ui.Say("Provisioning with Ansible...")
 
+	if p.config.PackerProvisionerDryRun {
+		p.dryRun(ui)
+		return nil
+	}
+
 	k, err := newUserKey(p.config.SSHAuthorizedKeyFile)
 	if err != nil {
 		return err
@@ -318,13 +323,32 @@ func (p *Provisioner) Cancel() {
 	os.Exit(0)
 }
 
-func (p *Provisioner) executeAnsible(ui packer.Ui, comm packer.Communicator, privKeyFile string) error {
-	playbook, _ := filepath.Abs(p.config.PlaybookFile)
+// dryRun shows the Ansible command the provisioner would execute. The
+// inventory and the key it runs with are generated, unless given, so they
+// are only shown as placeholders.
+func (p *Provisioner) dryRun(ui packer.Ui) {
 	inventory := p.config.InventoryFile
+	if len(inventory) == 0 {
+		inventory = "<generated inventory>"
+	}
+	var privKeyFile string
+	if len(p.config.SSHAuthorizedKeyFile) == 0 {
+		privKeyFile = "<generated key>"
+	}
+
+	for _, envvar := range p.config.AnsibleEnvVars {
+		ui.Message(fmt.Sprintf("Would set environment variable: %s", envvar))
+	}
+	cmd := exec.Command(p.config.Command, p.ansibleArgs(inventory, privKeyFile)...)
+	ui.Message(fmt.Sprintf("Would execute Ansible: %s", strings.Join(cmd.Args, " ")))
+}
+
+// ansibleArgs returns the arguments the playbook is executed with.
+func (p *Provisioner) ansibleArgs(inventory string, privKeyFile string) []string {
+	playbook, _ := filepath.Abs(p.config.PlaybookFile)
 	if len(p.config.InventoryDirectory) > 0 {
 		inventory = p.config.InventoryDirectory
 	}
-	var envvars []string
 
 	args := []string{"--extra-vars", fmt.Sprintf("packer_build_name=%s packer_builder_type=%s",
 		p.config.PackerBuildName, p.config.PackerBuilderType),
@@ -336,7 +360,13 @@ func (p *Provisioner) executeAnsible(ui packer.Ui, comm packer.Communicator, pri
 		// args = append(args, "--private-key", privKeyFile)
 		args = append(args, "-e", fmt.Sprintf("ansible_ssh_private_key_file=%s", privKeyFile))
 	}
-	args = append(args, p.config.ExtraArguments...)
+	return append(args, p.config.ExtraArguments...)
+}
+
+func (p *Provisioner) executeAnsible(ui packer.Ui, comm packer.Communicator, privKeyFile string) error {
+	var envvars []string
+
+	args := p.ansibleArgs(p.config.InventoryFile, privKeyFile)
 	if len(p.config.AnsibleEnvVars) > 0 {
 		envvars = append(envvars, p.config.AnsibleEnvVars...)
 	}
@@ -302,6 +302,38 @@ func TestProvisionerPrepare_InventoryDirectory(t *testing.T) {
 	}
 }
 
+func TestProvisionerProvision_DryRun(t *testing.T) {
+	var p Provisioner
+	p.config.Command = "ansible-playbook"
+	p.config.PlaybookFile = "/playbook.yml"
+	p.config.AnsibleEnvVars = []string{"ANSIBLE_NOCOLOR=True"}
+	p.config.ExtraArguments = []string{"-vvv"}
+	p.config.PackerBuildName = "test"
+	p.config.PackerBuilderType = "foo"
+	p.config.PackerProvisionerDryRun = true
+
+	comm := &packer.MockCommunicator{}
+	ui := &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+
+	if err := p.Provision(ui, comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if comm.StartCalled || comm.UploadCalled {
+		t.Fatal("should not use the communicator")
+	}
+
+	expected := "Provisioning with Ansible...\n" +
+		"Would set environment variable: ANSIBLE_NOCOLOR=True\n" +
+		"Would execute Ansible: ansible-playbook --extra-vars packer_build_name=test packer_builder_type=foo " +
+		"-i <generated inventory> /playbook.yml -e ansible_ssh_private_key_file=<generated key> -vvv\n"
+	if out := ui.Writer.(*bytes.Buffer).String(); out != expected {
+		t.Fatalf("bad output: %q", out)
+	}
+}
+
 func TestAnsibleGetVersion(t *testing.T) {
 	if os.Getenv("PACKER_ACC") == "" {
 		t.Skip("This test is only run with PACKER_ACC=1 and it requires Ansible to be installed")
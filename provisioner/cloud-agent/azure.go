@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/packer"
+)
+
+// azureRunCommander is the part of the virtual machines API scripts are
+// run with.
+type azureRunCommander interface {
+	RunCommand(ctx context.Context, resourceGroup, vmName string, input compute.RunCommandInput) (compute.RunCommandResult, error)
+}
+
+// azureVMClient runs commands with the vendored virtual machines client,
+// waiting for these long-running operations to complete.
+type azureVMClient struct {
+	compute.VirtualMachinesClient
+}
+
+func (c *azureVMClient) RunCommand(ctx context.Context, resourceGroup, vmName string, input compute.RunCommandInput) (compute.RunCommandResult, error) {
+	future, err := c.VirtualMachinesClient.RunCommand(ctx, resourceGroup, vmName, input)
+	if err != nil {
+		return compute.RunCommandResult{}, err
+	}
+	if err := future.WaitForCompletion(ctx, c.Client); err != nil {
+		return compute.RunCommandResult{}, err
+	}
+	return future.Result(c.VirtualMachinesClient)
+}
+
+// azureAgent runs scripts on an Azure virtual machine with Run Command.
+type azureAgent struct {
+	client azureRunCommander
+	config *Config
+}
+
+func newAzureAgent(config *Config) (*azureAgent, error) {
+	env, err := azure.EnvironmentFromName(config.CloudEnvironmentName)
+	if err != nil {
+		return nil, err
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, config.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := adal.NewServicePrincipalToken(
+		*oauthConfig, config.ClientID, config.ClientSecret, env.ResourceManagerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	client := compute.NewVirtualMachinesClientWithBaseURI(env.ResourceManagerEndpoint, config.SubscriptionID)
+	client.Authorizer = autorest.NewBearerAuthorizer(token)
+	client.PollingDuration = config.Timeout
+
+	return &azureAgent{
+		client: &azureVMClient{client},
+		config: config,
+	}, nil
+}
+
+func (a *azureAgent) Run(ctx context.Context, ui packer.Ui, script []string) error {
+	commandId := "RunShellScript"
+	if a.config.Platform == PlatformWindows {
+		commandId = "RunPowerShellScript"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.config.Timeout)
+	defer cancel()
+
+	result, err := a.client.RunCommand(ctx, a.config.ResourceGroupName, a.config.VMName, compute.RunCommandInput{
+		CommandID: to.StringPtr(commandId),
+		Script:    &script,
+	})
+	if err != nil {
+		return fmt.Errorf("Error running command: %s", err)
+	}
+	if result.Error != nil {
+		return fmt.Errorf("Error running command: %s", to.String(result.Error.Message))
+	}
+
+	var failed bool
+	for _, status := range azureRunCommandStatuses(result) {
+		showOutput(ui, to.String(status.Message))
+		if strings.HasSuffix(to.String(status.Code), "/failed") ||
+			strings.HasPrefix(to.String(status.Message), "Enable failed") {
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("Command %s didn't succeed on %s", commandId, a.config.VMName)
+	}
+	return nil
+}
+
+// azureRunCommandStatuses returns the statuses the output of the command
+// is reported in. The API reports them as raw JSON, a list of instance view
+// statuses under "value".
+func azureRunCommandStatuses(result compute.RunCommandResult) []compute.InstanceViewStatus {
+	if result.RunCommandResultProperties == nil || result.Output == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(result.Output)
+	if err != nil {
+		return nil
+	}
+	var output struct {
+		Value []compute.InstanceViewStatus `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &output); err != nil {
+		return nil
+	}
+	return output.Value
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	"github.com/hashicorp/packer/packer"
+)
+
+type mockRunCommander struct {
+	input  compute.RunCommandInput
+	output interface{}
+}
+
+func (m *mockRunCommander) RunCommand(_ context.Context, _, _ string, input compute.RunCommandInput) (compute.RunCommandResult, error) {
+	m.input = input
+	return compute.RunCommandResult{
+		RunCommandResultProperties: &compute.RunCommandResultProperties{Output: m.output},
+	}, nil
+}
+
+func testAzureOutput(code, message string) interface{} {
+	return map[string]interface{}{
+		"value": []interface{}{
+			map[string]interface{}{"code": code, "message": message},
+		},
+	}
+}
+
+func TestAzureAgentRun(t *testing.T) {
+	client := &mockRunCommander{
+		output: testAzureOutput("ProvisioningState/succeeded", "Enable succeeded: \n[stdout]\nhello\n"),
+	}
+	a := &azureAgent{
+		client: client,
+		config: &Config{Platform: PlatformLinux, Timeout: time.Minute},
+	}
+
+	out := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: out}
+	if err := a.Run(context.Background(), ui, []string{"echo hello"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if *client.input.CommandID != "RunShellScript" {
+		t.Fatalf("bad command: %s", *client.input.CommandID)
+	}
+	if !strings.Contains(out.String(), "hello") {
+		t.Fatalf("bad output: %s", out.String())
+	}
+}
+
+func TestAzureAgentRun_Failed(t *testing.T) {
+	client := &mockRunCommander{
+		output: testAzureOutput("ProvisioningState/failed", "Enable failed: exit status 1"),
+	}
+	a := &azureAgent{
+		client: client,
+		config: &Config{Platform: PlatformWindows, Timeout: time.Minute},
+	}
+
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)}
+	if err := a.Run(context.Background(), ui, []string{"exit 1"}); err == nil {
+		t.Fatal("should have error")
+	}
+	if *client.input.CommandID != "RunPowerShellScript" {
+		t.Fatalf("bad command: %s", *client.input.CommandID)
+	}
+}
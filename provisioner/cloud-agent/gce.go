@@ -0,0 +1,277 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/builder/googlecompute"
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/packer"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// gcePollInterval is how often the operations on the instance and its
+// serial port output are checked on. It is modified in tests.
+var gcePollInterval = 5 * time.Second
+
+// gceAPI is the part of the Compute Engine API scripts are run with.
+type gceAPI interface {
+	GetInstance(ctx context.Context, project, zone, name string) (*compute.Instance, error)
+	SetMetadata(ctx context.Context, project, zone, name string, metadata *compute.Metadata) error
+	Stop(ctx context.Context, project, zone, name string) error
+	Start(ctx context.Context, project, zone, name string) error
+	SerialPortOutput(ctx context.Context, project, zone, name string, start int64) (*compute.SerialPortOutput, error)
+}
+
+// gceClient calls the Compute Engine API with the vendored client, waiting
+// for the zone operations to complete.
+type gceClient struct {
+	service *compute.Service
+}
+
+func (c *gceClient) GetInstance(ctx context.Context, project, zone, name string) (*compute.Instance, error) {
+	return c.service.Instances.Get(project, zone, name).Context(ctx).Do()
+}
+
+func (c *gceClient) SetMetadata(ctx context.Context, project, zone, name string, metadata *compute.Metadata) error {
+	op, err := c.service.Instances.SetMetadata(project, zone, name, metadata).Context(ctx).Do()
+	return c.wait(ctx, project, zone, op, err)
+}
+
+func (c *gceClient) Stop(ctx context.Context, project, zone, name string) error {
+	op, err := c.service.Instances.Stop(project, zone, name).Context(ctx).Do()
+	return c.wait(ctx, project, zone, op, err)
+}
+
+func (c *gceClient) Start(ctx context.Context, project, zone, name string) error {
+	op, err := c.service.Instances.Start(project, zone, name).Context(ctx).Do()
+	return c.wait(ctx, project, zone, op, err)
+}
+
+func (c *gceClient) SerialPortOutput(ctx context.Context, project, zone, name string, start int64) (*compute.SerialPortOutput, error) {
+	return c.service.Instances.GetSerialPortOutput(project, zone, name).Port(1).Start(start).Context(ctx).Do()
+}
+
+// wait waits for the zone operation started with the error to be done.
+func (c *gceClient) wait(ctx context.Context, project, zone string, op *compute.Operation, err error) error {
+	if err != nil {
+		return err
+	}
+	for op.Status != "DONE" {
+		if err := sleep(ctx, gcePollInterval); err != nil {
+			return err
+		}
+		op, err = c.service.ZoneOperations.Get(project, zone, op.Name).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		return errors.New(op.Error.Errors[0].Message)
+	}
+	return nil
+}
+
+// gceAgent runs scripts on a Compute Engine instance as its startup script,
+// restarting the instance for the guest agent to run them. The guest agent
+// logs the output of the startup scripts to the serial port, where the
+// script marks where it starts and how it exits.
+type gceAgent struct {
+	client gceAPI
+	config *Config
+}
+
+func newGCEAgent(config *Config) (*gceAgent, error) {
+	var account *googlecompute.AccountFile
+	if config.AccountFile != "" {
+		account = new(googlecompute.AccountFile)
+		if err := googlecompute.ProcessAccountFile(account, config.AccountFile); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := googlecompute.NewClientGCE(account, googlecompute.DriverScopes)
+	if err != nil {
+		return nil, err
+	}
+	service, err := compute.New(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gceAgent{
+		client: &gceClient{service},
+		config: config,
+	}, nil
+}
+
+func (a *gceAgent) Run(ctx context.Context, ui packer.Ui, script []string) error {
+	project, zone, name := a.config.ProjectID, a.config.Zone, a.config.InstanceName
+
+	ctx, cancel := context.WithTimeout(ctx, a.config.Timeout)
+	defer cancel()
+
+	instance, err := a.client.GetInstance(ctx, project, zone, name)
+	if err != nil {
+		return fmt.Errorf("Error getting instance %s: %s", name, err)
+	}
+	metadata := instance.Metadata
+	if metadata == nil {
+		metadata = new(compute.Metadata)
+	}
+
+	key := "startup-script"
+	if a.config.Platform == PlatformWindows {
+		key = "windows-startup-script-ps1"
+	}
+	id := uuid.TimeOrderedUUID()
+	original := setMetadataItem(metadata, key, gceStartupScript(a.config.Platform, id, script))
+
+	if err := a.client.SetMetadata(ctx, project, zone, name, metadata); err != nil {
+		return fmt.Errorf("Error setting the startup script of instance %s: %s", name, err)
+	}
+	defer a.restoreStartupScript(ui, key, original)
+
+	ui.Message(fmt.Sprintf("Restarting instance %s to run the script...", name))
+	if err := a.client.Stop(ctx, project, zone, name); err != nil {
+		return fmt.Errorf("Error stopping instance %s: %s", name, err)
+	}
+	if err := a.client.Start(ctx, project, zone, name); err != nil {
+		return fmt.Errorf("Error starting instance %s: %s", name, err)
+	}
+
+	code, err := a.waitScript(ctx, ui, key, id)
+	if err != nil {
+		return err
+	}
+	if code != "0" {
+		return fmt.Errorf("Script didn't succeed on %s (exit status %s)", name, code)
+	}
+	return nil
+}
+
+// waitScript shows the output of the script the guest agent logs to the
+// serial port, and returns the exit status of the script once it exited.
+func (a *gceAgent) waitScript(ctx context.Context, ui packer.Ui, key, id string) (string, error) {
+	start := "packer-cloud-agent " + id + " start"
+	exit := "packer-cloud-agent " + id + " exit "
+	prefix := key + ": "
+
+	var offset int64
+	var partial string
+	var started bool
+	for {
+		output, err := a.client.SerialPortOutput(ctx, a.config.ProjectID, a.config.Zone, a.config.InstanceName, offset)
+		if err != nil {
+			return "", fmt.Errorf("Error reading the serial port output: %s", err)
+		}
+		offset = output.Next
+
+		lines := strings.Split(partial+output.Contents, "\n")
+		partial = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			i := strings.Index(line, prefix)
+			if i < 0 {
+				continue
+			}
+			line = strings.TrimRight(line[i+len(prefix):], "\r")
+
+			switch {
+			case line == start:
+				started = true
+			case started && strings.HasPrefix(line, exit):
+				return strings.TrimSpace(strings.TrimPrefix(line, exit)), nil
+			case started:
+				ui.Message(line)
+			}
+		}
+
+		if err := sleep(ctx, gcePollInterval); err != nil {
+			return "", fmt.Errorf("Error waiting for the script to exit: %s", err)
+		}
+	}
+}
+
+// restoreStartupScript sets the startup script of the instance back to
+// what it was, or removes it if there was none, so that it isn't run again
+// by the next boots.
+func (a *gceAgent) restoreStartupScript(ui packer.Ui, key string, original *string) {
+	project, zone, name := a.config.ProjectID, a.config.Zone, a.config.InstanceName
+
+	// The script may have been cancelled, or timed out
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// The metadata is updated with the fingerprint of its last version
+	instance, err := a.client.GetInstance(ctx, project, zone, name)
+	if err == nil {
+		metadata := instance.Metadata
+		if metadata == nil {
+			metadata = new(compute.Metadata)
+		}
+		setMetadataItem(metadata, key, original)
+		err = a.client.SetMetadata(ctx, project, zone, name, metadata)
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error restoring the startup script of instance %s, "+
+			"the script may run again when it boots: %s", name, err))
+		return
+	}
+	log.Printf("[INFO] Restored the %s of instance %s", key, name)
+}
+
+// setMetadataItem sets the item of the metadata, removing it if the value
+// is nil, and returns its previous value.
+func setMetadataItem(metadata *compute.Metadata, key string, value *string) *string {
+	var previous *string
+	items := metadata.Items[:0]
+	for _, item := range metadata.Items {
+		if item.Key == key {
+			previous = item.Value
+			continue
+		}
+		items = append(items, item)
+	}
+	if value != nil {
+		items = append(items, &compute.MetadataItems{Key: key, Value: value})
+	}
+	metadata.Items = items
+	return previous
+}
+
+// gceStartupScript returns the startup script running the script, which
+// marks where its output starts and how it exits.
+func gceStartupScript(platform, id string, script []string) *string {
+	var startup string
+	if platform == PlatformWindows {
+		startup = fmt.Sprintf(`Write-Output "packer-cloud-agent %[1]s start"
+$code = 0
+try {
+  & {
+%[2]s
+  }
+  if ($LASTEXITCODE) { $code = $LASTEXITCODE }
+} catch {
+  Write-Output $_
+  $code = 1
+}
+Write-Output "packer-cloud-agent %[1]s exit $code"
+`, id, strings.Join(script, "\n"))
+	} else {
+		startup = fmt.Sprintf(`#!/bin/sh
+echo "packer-cloud-agent %[1]s start"
+cat > /tmp/packer-cloud-agent-%[1]s <<'PACKER_CLOUD_AGENT_%[1]s'
+%[2]s
+PACKER_CLOUD_AGENT_%[1]s
+sh /tmp/packer-cloud-agent-%[1]s 2>&1
+code=$?
+rm -f /tmp/packer-cloud-agent-%[1]s
+echo "packer-cloud-agent %[1]s exit $code"
+`, id, strings.Join(script, "\n"))
+	}
+	return &startup
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+	compute "google.golang.org/api/compute/v1"
+)
+
+type mockGCE struct {
+	metadata *compute.Metadata
+	exit     string
+	calls    []string
+	scripts  []string
+}
+
+func (m *mockGCE) GetInstance(_ context.Context, _, _, _ string) (*compute.Instance, error) {
+	m.calls = append(m.calls, "get")
+	return &compute.Instance{Metadata: m.metadata}, nil
+}
+
+func (m *mockGCE) SetMetadata(_ context.Context, _, _, _ string, metadata *compute.Metadata) error {
+	m.calls = append(m.calls, "metadata")
+	m.metadata = metadata
+	return nil
+}
+
+func (m *mockGCE) Stop(_ context.Context, _, _, _ string) error {
+	m.calls = append(m.calls, "stop")
+	return nil
+}
+
+func (m *mockGCE) Start(_ context.Context, _, _, _ string) error {
+	m.calls = append(m.calls, "start")
+	for _, item := range m.metadata.Items {
+		if strings.HasSuffix(item.Key, "startup-script") || strings.HasSuffix(item.Key, "startup-script-ps1") {
+			m.scripts = append(m.scripts, *item.Value)
+		}
+	}
+	return nil
+}
+
+// SerialPortOutput returns the output of the last startup script in two
+// parts, the first one ending in the middle of a line.
+func (m *mockGCE) SerialPortOutput(_ context.Context, _, _, _ string, start int64) (*compute.SerialPortOutput, error) {
+	script := m.scripts[len(m.scripts)-1]
+	id := strings.Fields(strings.SplitN(script, "packer-cloud-agent ", 2)[1])[0]
+	output := fmt.Sprintf("boot\nstartup-script: packer-cloud-agent %[1]s start\n"+
+		"Oct 14 google_metadata_script_runner[1]: startup-script: hello\n"+
+		"startup-script: packer-cloud-agent %[1]s exit %[2]s\n", id, m.exit)
+	if start == 0 {
+		return &compute.SerialPortOutput{Contents: output[:20], Next: 20}, nil
+	}
+	return &compute.SerialPortOutput{Contents: output[start:], Next: int64(len(output))}, nil
+}
+
+func TestGCEAgentRun(t *testing.T) {
+	defer func(interval time.Duration) { gcePollInterval = interval }(gcePollInterval)
+	gcePollInterval = time.Millisecond
+
+	original := "echo original"
+	client := &mockGCE{
+		metadata: &compute.Metadata{Items: []*compute.MetadataItems{
+			{Key: "startup-script", Value: &original},
+		}},
+		exit: "0",
+	}
+	a := &gceAgent{
+		client: client,
+		config: &Config{Platform: PlatformLinux, Timeout: time.Minute, InstanceName: "packer"},
+	}
+
+	out := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: out}
+	if err := a.Run(context.Background(), ui, []string{"echo hello"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := "get,metadata,stop,start,get,metadata"
+	if strings.Join(client.calls, ",") != expected {
+		t.Fatalf("bad calls: %v", client.calls)
+	}
+	if len(client.scripts) != 1 || !strings.Contains(client.scripts[0], "echo hello") {
+		t.Fatalf("bad scripts: %#v", client.scripts)
+	}
+	if !strings.Contains(out.String(), "hello") || strings.Contains(out.String(), "boot") {
+		t.Fatalf("bad output: %s", out.String())
+	}
+	if len(client.metadata.Items) != 1 || *client.metadata.Items[0].Value != original {
+		t.Fatalf("should restore the startup script: %#v", client.metadata.Items)
+	}
+}
+
+func TestGCEAgentRun_Failed(t *testing.T) {
+	defer func(interval time.Duration) { gcePollInterval = interval }(gcePollInterval)
+	gcePollInterval = time.Millisecond
+
+	client := &mockGCE{metadata: new(compute.Metadata), exit: "1"}
+	a := &gceAgent{
+		client: client,
+		config: &Config{Platform: PlatformLinux, Timeout: time.Minute, InstanceName: "packer"},
+	}
+
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)}
+	if err := a.Run(context.Background(), ui, []string{"exit 1"}); err == nil {
+		t.Fatal("should have error")
+	}
+	if len(client.metadata.Items) != 0 {
+		t.Fatalf("should remove the startup script: %#v", client.metadata.Items)
+	}
+}
+
+func TestSetMetadataItem(t *testing.T) {
+	foo, bar := "foo", "bar"
+	metadata := &compute.Metadata{Items: []*compute.MetadataItems{
+		{Key: "ssh-keys", Value: &foo},
+	}}
+
+	if previous := setMetadataItem(metadata, "startup-script", &bar); previous != nil {
+		t.Fatalf("bad: %s", *previous)
+	}
+	if previous := setMetadataItem(metadata, "startup-script", nil); previous == nil || *previous != "bar" {
+		t.Fatalf("bad: %v", previous)
+	}
+	if len(metadata.Items) != 1 || metadata.Items[0].Key != "ssh-keys" {
+		t.Fatalf("bad: %#v", metadata.Items)
+	}
+}
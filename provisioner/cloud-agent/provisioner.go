@@ -0,0 +1,361 @@
+// This package implements a provisioner for Packer that runs scripts on
+// the machine through the agent of its cloud, SSM Run Command on AWS, Run
+// Command on Azure and the startup script on Compute Engine, instead of the
+// communicator. Builds can then run without SSH or WinRM access to the
+// machine.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+const (
+	AgentSSM   = "ssm"
+	AgentAzure = "azure"
+	AgentGCE   = "gce"
+
+	PlatformLinux   = "linux"
+	PlatformWindows = "windows"
+)
+
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	awscommon.AccessConfig `mapstructure:",squash"`
+
+	// The agent the scripts are run through, ssm, azure or gce. Defaults to
+	// the agent of the cloud of the machine of the build.
+	Agent string `mapstructure:"agent"`
+
+	// The platform of the machine, linux or windows, which decides whether
+	// the scripts are shell or PowerShell scripts.
+	Platform string `mapstructure:"platform"`
+
+	// An inline script to run.
+	Inline []string
+
+	// The local path of the script to run.
+	Script string
+
+	// An array of multiple scripts to run.
+	Scripts []string
+
+	// How long each script may run, and the SSM agent may take to be
+	// online.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// The EC2 instance to run the scripts on, given by its ID or by tags
+	// only it has. Defaults to the instance of the build.
+	InstanceId   string            `mapstructure:"instance_id"`
+	InstanceTags map[string]string `mapstructure:"instance_tags"`
+
+	// The Azure virtual machine to run the scripts on, and the service
+	// principal to authenticate as. The virtual machine defaults to the one
+	// of the build.
+	SubscriptionID       string `mapstructure:"subscription_id"`
+	ClientID             string `mapstructure:"client_id"`
+	ClientSecret         string `mapstructure:"client_secret"`
+	TenantID             string `mapstructure:"tenant_id"`
+	CloudEnvironmentName string `mapstructure:"cloud_environment_name"`
+	ResourceGroupName    string `mapstructure:"resource_group_name"`
+	VMName               string `mapstructure:"vm_name"`
+
+	// The Compute Engine instance to run the scripts on, which defaults to
+	// the one of the build, and the account to authenticate as.
+	AccountFile  string `mapstructure:"account_file"`
+	ProjectID    string `mapstructure:"project_id"`
+	Zone         string `mapstructure:"zone"`
+	InstanceName string `mapstructure:"instance_name"`
+
+	ctx interpolate.Context
+}
+
+// agent runs scripts on the machine through the agent of its cloud.
+type agent interface {
+	// Run runs the script, given as its lines, showing its output on the
+	// UI. It fails if the script doesn't succeed.
+	Run(ctx context.Context, ui packer.Ui, script []string) error
+}
+
+type Provisioner struct {
+	config Config
+	agent  agent
+	cancel context.CancelFunc
+}
+
+func (p *Provisioner) Prepare(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.Platform == "" {
+		p.config.Platform = PlatformLinux
+	}
+
+	if p.config.Timeout == 0 {
+		p.config.Timeout = 30 * time.Minute
+	}
+
+	if p.config.Inline != nil && len(p.config.Inline) == 0 {
+		p.config.Inline = nil
+	}
+
+	var errs *packer.MultiError
+	if p.config.Platform != PlatformLinux && p.config.Platform != PlatformWindows {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("platform must be either linux or windows."))
+	}
+
+	if p.config.Script != "" && len(p.config.Scripts) > 0 {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("Only one of script or scripts can be specified."))
+	}
+
+	if p.config.Script != "" {
+		p.config.Scripts = []string{p.config.Script}
+	}
+
+	if len(p.config.Scripts) == 0 && p.config.Inline == nil {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("Either a script file or inline script must be specified."))
+	} else if len(p.config.Scripts) > 0 && p.config.Inline != nil {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("Only a script file or an inline script can be specified, not both."))
+	}
+
+	for _, path := range p.config.Scripts {
+		if _, err := ioutil.ReadFile(path); err != nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Bad script '%s': %s", path, err))
+		}
+	}
+
+	switch p.config.Agent {
+	case AgentSSM:
+		if p.config.InstanceId != "" && len(p.config.InstanceTags) > 0 {
+			errs = packer.MultiErrorAppend(errs,
+				errors.New("Only one of instance_id or instance_tags can be specified."))
+		}
+		errs = packer.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
+	case AgentAzure:
+		if p.config.CloudEnvironmentName == "" {
+			p.config.CloudEnvironmentName = "AzurePublicCloud"
+		}
+		if _, err := azure.EnvironmentFromName(p.config.CloudEnvironmentName); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+		for _, required := range []struct{ key, value string }{
+			{"subscription_id", p.config.SubscriptionID},
+			{"client_id", p.config.ClientID},
+			{"client_secret", p.config.ClientSecret},
+			{"tenant_id", p.config.TenantID},
+		} {
+			if required.value == "" {
+				errs = packer.MultiErrorAppend(errs,
+					fmt.Errorf("%s must be specified with the azure agent.", required.key))
+			}
+		}
+	case AgentGCE, "":
+		// Without an agent, the one of the cloud of the machine is used
+	default:
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("agent must be one of ssm, azure or gce."))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
+	return p.ProvisionMachine(ui, comm, nil)
+}
+
+// ProvisionMachine runs the scripts on the machine of the build, through
+// the agent of its cloud, unless the machine is configured.
+func (p *Provisioner) ProvisionMachine(ui packer.Ui, _ packer.Communicator, machine *packer.Machine) error {
+	config := p.config
+	if err := config.setMachine(machine); err != nil {
+		return err
+	}
+
+	ui.Say(fmt.Sprintf("Provisioning through the %s agent...", config.Agent))
+
+	scripts, err := p.scripts()
+	if err != nil {
+		return err
+	}
+
+	if p.config.PackerProvisionerDryRun {
+		for _, script := range scripts {
+			ui.Message(fmt.Sprintf("Would run through the %s agent:", config.Agent))
+			showOutput(ui, strings.Join(script, "\n"))
+		}
+		return nil
+	}
+
+	if p.agent == nil {
+		switch config.Agent {
+		case AgentSSM:
+			p.agent, err = newSSMAgent(&config)
+		case AgentAzure:
+			p.agent, err = newAzureAgent(&config)
+		default:
+			p.agent, err = newGCEAgent(&config)
+		}
+		if err != nil {
+			return fmt.Errorf("Error setting up the %s agent: %s", config.Agent, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	defer cancel()
+
+	for i, script := range scripts {
+		name := "inline script"
+		if len(p.config.Scripts) > 0 {
+			name = p.config.Scripts[i]
+		}
+		ui.Say(fmt.Sprintf("Running %s", name))
+
+		if err := p.agent.Run(ctx, ui, script); err != nil {
+			return fmt.Errorf("Error running %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Provisioner) Cancel() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// machineAgents are the agents of the clouds of the machines of the
+// builds.
+var machineAgents = map[string]string{
+	"amazon":        AgentSSM,
+	"azure":         AgentAzure,
+	"googlecompute": AgentGCE,
+}
+
+// setMachine sets the agent, and the machine to run the scripts on, to
+// those of the machine of the build, unless they are configured. It fails
+// if the machine to run the scripts on isn't known.
+func (c *Config) setMachine(machine *packer.Machine) error {
+	if machine != nil && (c.Agent == "" || c.Agent == machineAgents[machine.Cloud]) {
+		c.Agent = machineAgents[machine.Cloud]
+		switch c.Agent {
+		case AgentSSM:
+			if c.InstanceId == "" && len(c.InstanceTags) == 0 {
+				c.InstanceId = machine.ID
+			}
+			if c.RawRegion == "" {
+				c.RawRegion = machine.Region
+			}
+		case AgentAzure:
+			if c.VMName == "" {
+				c.VMName = machine.ID
+				c.ResourceGroupName = machine.ResourceGroup
+			}
+		case AgentGCE:
+			if c.InstanceName == "" {
+				c.InstanceName = machine.ID
+				c.Zone = machine.Zone
+			}
+			if c.ProjectID == "" {
+				c.ProjectID = machine.ProjectID
+			}
+		}
+	}
+
+	var missing []string
+	switch c.Agent {
+	case AgentSSM:
+		if c.InstanceId == "" && len(c.InstanceTags) == 0 {
+			missing = []string{"instance_id or instance_tags"}
+		}
+	case AgentAzure:
+		if c.ResourceGroupName == "" {
+			missing = append(missing, "resource_group_name")
+		}
+		if c.VMName == "" {
+			missing = append(missing, "vm_name")
+		}
+	case AgentGCE:
+		if c.ProjectID == "" {
+			missing = append(missing, "project_id")
+		}
+		if c.Zone == "" {
+			missing = append(missing, "zone")
+		}
+		if c.InstanceName == "" {
+			missing = append(missing, "instance_name")
+		}
+	default:
+		return errors.New("The builder doesn't tell the machine of the build, agent must be specified.")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("The builder doesn't tell the machine of the build, %s must be specified.",
+			strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// scripts returns the lines of the scripts to run.
+func (p *Provisioner) scripts() ([][]string, error) {
+	if p.config.Inline != nil {
+		return [][]string{p.config.Inline}, nil
+	}
+
+	var scripts [][]string
+	for _, path := range p.config.Scripts {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading script '%s': %s", path, err)
+		}
+		lines := strings.Split(strings.Replace(string(content), "\r\n", "\n", -1), "\n")
+		scripts = append(scripts, lines)
+	}
+	return scripts, nil
+}
+
+// showOutput shows the output of a script line by line.
+func showOutput(ui packer.Ui, output string) {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return
+	}
+	for _, line := range strings.Split(output, "\n") {
+		ui.Message(line)
+	}
+}
+
+// sleep waits for the duration, unless the context is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
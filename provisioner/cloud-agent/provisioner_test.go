@@ -0,0 +1,311 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"agent":       "ssm",
+		"region":      "us-east-1",
+		"instance_id": "i-12345",
+		"inline":      []interface{}{"foo", "bar"},
+	}
+}
+
+func TestProvisioner_Impl(t *testing.T) {
+	var raw interface{}
+	raw = &Provisioner{}
+	if _, ok := raw.(packer.Provisioner); !ok {
+		t.Fatalf("must be a Provisioner")
+	}
+}
+
+func TestProvisionerPrepare_Defaults(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if p.config.Platform != PlatformLinux {
+		t.Fatalf("bad platform: %s", p.config.Platform)
+	}
+	if p.config.Timeout.Minutes() != 30 {
+		t.Fatalf("bad timeout: %s", p.config.Timeout)
+	}
+}
+
+func TestProvisionerPrepare_Agent(t *testing.T) {
+	config := testConfig()
+	delete(config, "agent")
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config["agent"] = "gce"
+	p = Provisioner{}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config["agent"] = "oci"
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_SSMInstance(t *testing.T) {
+	config := testConfig()
+	delete(config, "instance_id")
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config["instance_tags"] = map[string]string{"Name": "packer"}
+	p = Provisioner{}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config["instance_id"] = "i-12345"
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_Azure(t *testing.T) {
+	config := map[string]interface{}{
+		"agent":               "azure",
+		"subscription_id":     "sub",
+		"client_id":           "client",
+		"client_secret":       "secret",
+		"tenant_id":           "tenant",
+		"resource_group_name": "packer-Resource-Group-abc",
+		"vm_name":             "pkrvmabc",
+		"inline":              []interface{}{"foo"},
+	}
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.CloudEnvironmentName != "AzurePublicCloud" {
+		t.Fatalf("bad cloud environment: %s", p.config.CloudEnvironmentName)
+	}
+
+	config["cloud_environment_name"] = "Mars"
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	delete(config, "cloud_environment_name")
+	delete(config, "resource_group_name")
+	delete(config, "vm_name")
+	p = Provisioner{}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	delete(config, "client_id")
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_Platform(t *testing.T) {
+	config := testConfig()
+	config["platform"] = "windows"
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config["platform"] = "plan9"
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestProvisionerPrepare_Scripts(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	config := testConfig()
+	config["script"] = tf.Name()
+	var p Provisioner
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error with both inline and script")
+	}
+
+	delete(config, "inline")
+	p = Provisioner{}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config["scripts"] = []string{tf.Name()}
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error with both script and scripts")
+	}
+
+	delete(config, "script")
+	config["scripts"] = []string{"/does/not/exist"}
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("should have error with a missing script")
+	}
+}
+
+type fakeAgent struct {
+	scripts [][]string
+}
+
+func (a *fakeAgent) Run(_ context.Context, _ packer.Ui, script []string) error {
+	a.scripts = append(a.scripts, script)
+	return nil
+}
+
+func TestProvisionerProvision_Scripts(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.WriteString("echo foo\r\necho bar")
+	tf.Close()
+
+	config := testConfig()
+	delete(config, "inline")
+	config["scripts"] = []string{tf.Name()}
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	agent := new(fakeAgent)
+	p.agent = agent
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)}
+	if err := p.Provision(ui, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(agent.scripts) != 1 || strings.Join(agent.scripts[0], ",") != "echo foo,echo bar" {
+		t.Fatalf("bad scripts: %#v", agent.scripts)
+	}
+}
+
+func TestProvisionerProvision_DryRun(t *testing.T) {
+	config := testConfig()
+	config["packer_provisioner_dry_run"] = true
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	agent := new(fakeAgent)
+	p.agent = agent
+	out := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: out}
+	if err := p.Provision(ui, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(agent.scripts) != 0 {
+		t.Fatalf("should not run scripts: %#v", agent.scripts)
+	}
+	if !strings.Contains(out.String(), "Would run through the ssm agent") {
+		t.Fatalf("bad output: %s", out.String())
+	}
+}
+
+func TestProvisionerProvisionMachine(t *testing.T) {
+	config := testConfig()
+	delete(config, "agent")
+	delete(config, "region")
+	delete(config, "instance_id")
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	agent := new(fakeAgent)
+	p.agent = agent
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)}
+
+	// Bad
+	if err := p.Provision(ui, nil); err == nil {
+		t.Fatal("should have error without a machine")
+	}
+
+	// Good
+	machine := &packer.Machine{Cloud: "amazon", ID: "i-67890", Region: "eu-west-1"}
+	if err := p.ProvisionMachine(ui, nil, machine); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(agent.scripts) != 1 {
+		t.Fatalf("bad scripts: %#v", agent.scripts)
+	}
+	if p.config.Agent != "" || p.config.InstanceId != "" {
+		t.Fatalf("should not change the config: %#v", p.config)
+	}
+}
+
+func TestConfigSetMachine(t *testing.T) {
+	machine := &packer.Machine{
+		Cloud:     "googlecompute",
+		ID:        "packer-abc",
+		Zone:      "us-central1-a",
+		ProjectID: "project",
+	}
+
+	// Good
+	c := Config{}
+	if err := c.setMachine(machine); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.Agent != AgentGCE || c.InstanceName != "packer-abc" || c.Zone != "us-central1-a" || c.ProjectID != "project" {
+		t.Fatalf("bad: %#v", c)
+	}
+
+	c = Config{Agent: AgentGCE, Zone: "europe-west1-b", InstanceName: "other"}
+	if err := c.setMachine(machine); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.InstanceName != "other" || c.Zone != "europe-west1-b" || c.ProjectID != "project" {
+		t.Fatalf("bad: %#v", c)
+	}
+
+	c = Config{Agent: AgentAzure, ResourceGroupName: "rg", VMName: "vm"}
+	if err := c.setMachine(machine); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Bad
+	c = Config{Agent: AgentAzure}
+	if err := c.setMachine(machine); err == nil {
+		t.Fatal("should have error with the machine of another cloud")
+	}
+
+	c = Config{}
+	if err := c.setMachine(&packer.Machine{Cloud: "docker", ID: "abc"}); err == nil {
+		t.Fatal("should have error with a cloud without agent")
+	}
+}
@@ -0,0 +1,309 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/packer"
+)
+
+// ssmPollInterval is how often the instance and the commands sent to it
+// are checked on. It is modified in tests.
+var ssmPollInterval = 5 * time.Second
+
+// ssmClient calls the few SSM operations needed to run commands. The
+// vendored SDK doesn't include the SSM service, so this sets up a client
+// for its JSON API the way the generated service clients do.
+type ssmClient struct {
+	*client.Client
+}
+
+func newSSMClient(p client.ConfigProvider) *ssmClient {
+	c := p.ClientConfig("ssm")
+	svc := &ssmClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "ssm",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2014-11-06",
+				JSONVersion:   "1.1",
+				TargetPrefix:  "AmazonSSM",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(jsonrpc.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(jsonrpc.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(jsonrpc.UnmarshalErrorHandler)
+
+	return svc
+}
+
+func (c *ssmClient) send(ctx context.Context, operation string, input, output interface{}) error {
+	op := &request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	req := c.NewRequest(op, input, output)
+	req.SetContext(ctx)
+	return req.Send()
+}
+
+type ssmFilter struct {
+	Key    *string
+	Values []*string
+}
+
+type ssmDescribeInstanceInformationInput struct {
+	_       struct{} `type:"structure"`
+	Filters []*ssmFilter
+}
+
+type ssmDescribeInstanceInformationOutput struct {
+	InstanceInformationList []*struct {
+		InstanceId *string
+		PingStatus *string
+	}
+}
+
+type ssmSendCommandInput struct {
+	_            struct{} `type:"structure"`
+	DocumentName *string
+	InstanceIds  []*string
+	Parameters   map[string][]*string
+	Comment      *string
+}
+
+type ssmSendCommandOutput struct {
+	Command *struct {
+		CommandId *string
+	}
+}
+
+type ssmGetCommandInvocationInput struct {
+	_          struct{} `type:"structure"`
+	CommandId  *string
+	InstanceId *string
+}
+
+type ssmGetCommandInvocationOutput struct {
+	Status                *string
+	StatusDetails         *string
+	ResponseCode          *int64
+	StandardOutputContent *string
+	StandardErrorContent  *string
+}
+
+// ec2Describer is the part of the EC2 API the instance is looked up with.
+type ec2Describer interface {
+	DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error)
+}
+
+// ssmAPI is the part of the SSM API commands are run with.
+type ssmAPI interface {
+	DescribeInstanceInformation(context.Context, *ssmDescribeInstanceInformationInput) (*ssmDescribeInstanceInformationOutput, error)
+	SendCommand(context.Context, *ssmSendCommandInput) (*ssmSendCommandOutput, error)
+	GetCommandInvocation(context.Context, *ssmGetCommandInvocationInput) (*ssmGetCommandInvocationOutput, error)
+}
+
+func (c *ssmClient) DescribeInstanceInformation(ctx context.Context, input *ssmDescribeInstanceInformationInput) (*ssmDescribeInstanceInformationOutput, error) {
+	output := new(ssmDescribeInstanceInformationOutput)
+	return output, c.send(ctx, "DescribeInstanceInformation", input, output)
+}
+
+func (c *ssmClient) SendCommand(ctx context.Context, input *ssmSendCommandInput) (*ssmSendCommandOutput, error) {
+	output := new(ssmSendCommandOutput)
+	return output, c.send(ctx, "SendCommand", input, output)
+}
+
+func (c *ssmClient) GetCommandInvocation(ctx context.Context, input *ssmGetCommandInvocationInput) (*ssmGetCommandInvocationOutput, error) {
+	output := new(ssmGetCommandInvocationOutput)
+	return output, c.send(ctx, "GetCommandInvocation", input, output)
+}
+
+// ssmAgent runs scripts on an EC2 instance with SSM Run Command.
+type ssmAgent struct {
+	ec2     ec2Describer
+	ssm     ssmAPI
+	config  *Config
+	timeout time.Duration
+
+	instanceId string
+}
+
+func newSSMAgent(config *Config) (*ssmAgent, error) {
+	session, err := config.AccessConfig.Session()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssmAgent{
+		ec2:     ec2.New(session),
+		ssm:     newSSMClient(session),
+		config:  config,
+		timeout: config.Timeout,
+	}, nil
+}
+
+func (a *ssmAgent) Run(ctx context.Context, ui packer.Ui, script []string) error {
+	if a.instanceId == "" {
+		instanceId, err := a.findInstance()
+		if err != nil {
+			return err
+		}
+		ui.Message(fmt.Sprintf("Waiting for the SSM agent of instance %s to be online...", instanceId))
+		if err := a.waitOnline(ctx, instanceId); err != nil {
+			return err
+		}
+		a.instanceId = instanceId
+	}
+
+	document := "AWS-RunShellScript"
+	if a.config.Platform == PlatformWindows {
+		document = "AWS-RunPowerShellScript"
+	}
+
+	resp, err := a.ssm.SendCommand(ctx, &ssmSendCommandInput{
+		DocumentName: aws.String(document),
+		InstanceIds:  []*string{aws.String(a.instanceId)},
+		Parameters: map[string][]*string{
+			"commands":         aws.StringSlice(script),
+			"executionTimeout": {aws.String(strconv.Itoa(int(a.timeout.Seconds())))},
+		},
+		Comment: aws.String(fmt.Sprintf("Packer build %s", a.config.PackerBuildName)),
+	})
+	if err != nil {
+		return fmt.Errorf("Error sending command: %s", err)
+	}
+	commandId := aws.StringValue(resp.Command.CommandId)
+	log.Printf("[INFO] Sent SSM command %s to instance %s", commandId, a.instanceId)
+
+	invocation, err := a.waitCommand(ctx, commandId)
+	if err != nil {
+		return err
+	}
+
+	showOutput(ui, aws.StringValue(invocation.StandardOutputContent))
+	showOutput(ui, aws.StringValue(invocation.StandardErrorContent))
+
+	if status := aws.StringValue(invocation.Status); status != "Success" {
+		return fmt.Errorf("Command %s didn't succeed: %s (exit status %d)",
+			commandId, aws.StringValue(invocation.StatusDetails), aws.Int64Value(invocation.ResponseCode))
+	}
+	return nil
+}
+
+// findInstance returns the ID of the instance to run the scripts on,
+// looking it up by its tags when its ID isn't given.
+func (a *ssmAgent) findInstance() (string, error) {
+	if a.config.InstanceId != "" {
+		return a.config.InstanceId, nil
+	}
+
+	filters := []*ec2.Filter{{
+		Name:   aws.String("instance-state-name"),
+		Values: []*string{aws.String("running")},
+	}}
+	for k, v := range a.config.InstanceTags {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String("tag:" + k),
+			Values: []*string{aws.String(v)},
+		})
+	}
+
+	resp, err := a.ec2.DescribeInstances(&ec2.DescribeInstancesInput{Filters: filters})
+	if err != nil {
+		return "", fmt.Errorf("Error looking up the instance: %s", err)
+	}
+
+	var ids []string
+	for _, r := range resp.Reservations {
+		for _, i := range r.Instances {
+			ids = append(ids, aws.StringValue(i.InstanceId))
+		}
+	}
+	if len(ids) != 1 {
+		return "", fmt.Errorf("Exactly one running instance must have the instance_tags, found %d: %v", len(ids), ids)
+	}
+
+	return ids[0], nil
+}
+
+// waitOnline waits for the SSM agent of the instance to register and be
+// reachable, which takes a little while once the instance booted.
+func (a *ssmAgent) waitOnline(ctx context.Context, instanceId string) error {
+	input := &ssmDescribeInstanceInformationInput{
+		Filters: []*ssmFilter{{
+			Key:    aws.String("InstanceIds"),
+			Values: []*string{aws.String(instanceId)},
+		}},
+	}
+
+	deadline := time.Now().Add(a.timeout)
+	for {
+		resp, err := a.ssm.DescribeInstanceInformation(ctx, input)
+		if err != nil {
+			return fmt.Errorf("Error describing the SSM agent of the instance: %s", err)
+		}
+		for _, info := range resp.InstanceInformationList {
+			if aws.StringValue(info.PingStatus) == "Online" {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timed out waiting for the SSM agent of instance %s to be online", instanceId)
+		}
+		if err := sleep(ctx, ssmPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// waitCommand waits for the command to complete on the instance.
+func (a *ssmAgent) waitCommand(ctx context.Context, commandId string) (*ssmGetCommandInvocationOutput, error) {
+	input := &ssmGetCommandInvocationInput{
+		CommandId:  aws.String(commandId),
+		InstanceId: aws.String(a.instanceId),
+	}
+
+	for {
+		if err := sleep(ctx, ssmPollInterval); err != nil {
+			return nil, err
+		}
+
+		invocation, err := a.ssm.GetCommandInvocation(ctx, input)
+		if err != nil {
+			// The invocation only shows up some time after the command was sent
+			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "InvocationDoesNotExist" {
+				continue
+			}
+			return nil, fmt.Errorf("Error getting the result of command %s: %s", commandId, err)
+		}
+
+		switch aws.StringValue(invocation.Status) {
+		case "Pending", "InProgress", "Delayed":
+			continue
+		default:
+			return invocation, nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/packer"
+)
+
+func init() {
+	ssmPollInterval = 0
+}
+
+type mockEC2 struct {
+	ids []string
+}
+
+func (m *mockEC2) DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	var instances []*ec2.Instance
+	for _, id := range m.ids {
+		instances = append(instances, &ec2.Instance{InstanceId: aws.String(id)})
+	}
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{{Instances: instances}},
+	}, nil
+}
+
+type mockSSM struct {
+	pings       []string
+	statuses    []string
+	sent        *ssmSendCommandInput
+	invocations int
+}
+
+func (m *mockSSM) DescribeInstanceInformation(context.Context, *ssmDescribeInstanceInformationInput) (*ssmDescribeInstanceInformationOutput, error) {
+	out := new(ssmDescribeInstanceInformationOutput)
+	if len(m.pings) > 0 {
+		status := m.pings[0]
+		m.pings = m.pings[1:]
+		out.InstanceInformationList = append(out.InstanceInformationList, &struct {
+			InstanceId *string
+			PingStatus *string
+		}{aws.String("i-12345"), aws.String(status)})
+	}
+	return out, nil
+}
+
+func (m *mockSSM) SendCommand(_ context.Context, input *ssmSendCommandInput) (*ssmSendCommandOutput, error) {
+	m.sent = input
+	return &ssmSendCommandOutput{Command: &struct{ CommandId *string }{aws.String("cmd-1")}}, nil
+}
+
+func (m *mockSSM) GetCommandInvocation(context.Context, *ssmGetCommandInvocationInput) (*ssmGetCommandInvocationOutput, error) {
+	m.invocations++
+	if m.invocations == 1 {
+		return nil, awserr.New("InvocationDoesNotExist", "not yet", nil)
+	}
+	status := m.statuses[0]
+	m.statuses = m.statuses[1:]
+	return &ssmGetCommandInvocationOutput{
+		Status:                aws.String(status),
+		StatusDetails:         aws.String(status),
+		ResponseCode:          aws.Int64(0),
+		StandardOutputContent: aws.String("hello\n"),
+	}, nil
+}
+
+func testSSMAgent(tags map[string]string, ids []string, ssm *mockSSM) *ssmAgent {
+	return &ssmAgent{
+		ec2: &mockEC2{ids: ids},
+		ssm: ssm,
+		config: &Config{
+			InstanceTags: tags,
+			Platform:     PlatformWindows,
+		},
+		timeout: time.Minute,
+	}
+}
+
+func TestSSMAgentRun(t *testing.T) {
+	ssm := &mockSSM{
+		pings:    []string{"ConnectionLost", "Online"},
+		statuses: []string{"InProgress", "Success"},
+	}
+	a := testSSMAgent(map[string]string{"Name": "packer"}, []string{"i-12345"}, ssm)
+
+	out := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: out}
+	if err := a.Run(context.Background(), ui, []string{"Write-Host hello"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if a.instanceId != "i-12345" {
+		t.Fatalf("bad instance: %s", a.instanceId)
+	}
+	if *ssm.sent.DocumentName != "AWS-RunPowerShellScript" {
+		t.Fatalf("bad document: %s", *ssm.sent.DocumentName)
+	}
+	if *ssm.sent.Parameters["executionTimeout"][0] != "60" {
+		t.Fatalf("bad timeout: %s", *ssm.sent.Parameters["executionTimeout"][0])
+	}
+	if !strings.Contains(out.String(), "hello") {
+		t.Fatalf("bad output: %s", out.String())
+	}
+}
+
+func TestSSMAgentRun_Failed(t *testing.T) {
+	ssm := &mockSSM{
+		pings:    []string{"Online"},
+		statuses: []string{"Failed"},
+	}
+	a := testSSMAgent(map[string]string{"Name": "packer"}, []string{"i-12345"}, ssm)
+
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)}
+	if err := a.Run(context.Background(), ui, []string{"exit 1"}); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestSSMAgentRun_InstanceTags(t *testing.T) {
+	a := testSSMAgent(map[string]string{"Name": "packer"}, []string{"i-1", "i-2"}, new(mockSSM))
+
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)}
+	if err := a.Run(context.Background(), ui, []string{"true"}); err == nil {
+		t.Fatal("should have error with several instances")
+	}
+}
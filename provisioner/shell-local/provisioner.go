@@ -1,6 +1,8 @@
 package shell
 
 import (
+	"fmt"
+
 	sl "github.com/hashicorp/packer/common/shell-local"
 	"github.com/hashicorp/packer/packer"
 )
@@ -24,6 +26,11 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 }
 
 func (p *Provisioner) Provision(ui packer.Ui, _ packer.Communicator) error {
+	if p.config.PackerProvisionerDryRun {
+		p.dryRun(ui)
+		return nil
+	}
+
 	_, retErr := sl.Run(ui, &p.config)
 	if retErr != nil {
 		return retErr
@@ -32,6 +39,20 @@ func (p *Provisioner) Provision(ui packer.Ui, _ packer.Communicator) error {
 	return nil
 }
 
+// dryRun reports the scripts, or inline commands, that would be run on the
+// machine running Packer, without running them.
+func (p *Provisioner) dryRun(ui packer.Ui) {
+	for _, script := range p.config.Scripts {
+		ui.Message(fmt.Sprintf("Would run local shell script: %s", script))
+	}
+	if len(p.config.Scripts) > 0 {
+		return
+	}
+	for _, command := range p.config.Inline {
+		ui.Message(fmt.Sprintf("Would run locally: %s", command))
+	}
+}
+
 func (p *Provisioner) Cancel() {
 	// Just do nothing. When the process ends, so will our provisioner
 }
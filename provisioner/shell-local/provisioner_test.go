@@ -1,6 +1,11 @@
 package shell
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/hashicorp/packer/packer"
@@ -48,6 +53,45 @@ func TestConfigPrepare(t *testing.T) {
 	}
 }
 
+func TestProvisionerProvision_DryRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the inline command is a unix shell command")
+	}
+
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	touched := filepath.Join(dir, "touched")
+
+	raw := testConfig(t)
+	raw["inline"] = []string{"touch " + touched}
+	delete(raw, "command")
+	raw["packer_provisioner_dry_run"] = true
+
+	var p Provisioner
+	if err := p.Prepare(raw); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+	if err := p.Provision(ui, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := os.Stat(touched); err == nil {
+		t.Fatal("should not have run the command")
+	}
+	expected := "Would run locally: touch " + touched + "\n"
+	if out := ui.Writer.(*bytes.Buffer).String(); out != expected {
+		t.Fatalf("bad output: %q", out)
+	}
+}
+
 func testConfig(t *testing.T) map[string]interface{} {
 	return map[string]interface{}{
 		"command": "echo foo",
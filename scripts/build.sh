@@ -2,7 +2,7 @@
 
 # This script builds the application from source for multiple platforms.
 # Determine the arch/os combos we're building for
-ALL_XC_ARCH="386 amd64 arm arm64 ppc64le"
+ALL_XC_ARCH="386 amd64 arm arm64 ppc64le riscv64"
 ALL_XC_OS="linux darwin windows freebsd openbsd solaris"
 
 set -e
@@ -75,11 +75,13 @@ if [ -n "${PACKER_DEV+x}" ]; then
     XC_ARCH=$(go env GOARCH)
 fi
 
+# The vendored golang.org/x/sys doesn't build for darwin/arm64, nor for
+# riscv64 on anything but linux, which requires Go 1.14 or later.
 set +e
 ${GOX:?command not found} \
     -os="${XC_OS:-$ALL_XC_OS}" \
     -arch="${XC_ARCH:-$ALL_XC_ARCH}" \
-    -osarch="!darwin/arm !darwin/arm64" \
+    -osarch="!darwin/arm !darwin/arm64 !windows/riscv64 !darwin/riscv64 !solaris/riscv64 !freebsd/riscv64 !openbsd/riscv64" \
     -ldflags "${GOLDFLAGS}" \
     -output "pkg/{{.OS}}_{{.Arch}}/packer" \
     .
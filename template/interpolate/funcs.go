@@ -25,16 +25,19 @@ func init() {
 
 // Funcs are the interpolation funcs that are available within interpolations.
 var FuncGens = map[string]FuncGenerator{
-	"build_name":     funcGenBuildName,
-	"build_type":     funcGenBuildType,
-	"env":            funcGenEnv,
-	"isotime":        funcGenIsotime,
-	"pwd":            funcGenPwd,
-	"template_dir":   funcGenTemplateDir,
-	"timestamp":      funcGenTimestamp,
-	"uuid":           funcGenUuid,
-	"user":           funcGenUser,
-	"packer_version": funcGenPackerVersion,
+	"build_name":      funcGenBuildName,
+	"build_output":    funcGenBuildOutput,
+	"build_type":      funcGenBuildType,
+	"env":             funcGenEnv,
+	"isotime":         funcGenIsotime,
+	"lineage":         funcGenLineage,
+	"lineage_version": funcGenLineageVersion,
+	"pwd":             funcGenPwd,
+	"template_dir":    funcGenTemplateDir,
+	"timestamp":       funcGenTimestamp,
+	"uuid":            funcGenUuid,
+	"user":            funcGenUser,
+	"packer_version":  funcGenPackerVersion,
 
 	"upper": funcGenPrimitive(strings.ToUpper),
 	"lower": funcGenPrimitive(strings.ToLower),
@@ -70,6 +73,20 @@ func funcGenBuildName(ctx *Context) interface{} {
 	}
 }
 
+func funcGenBuildOutput(ctx *Context) interface{} {
+	return func(build, name string) (string, error) {
+		if ctx != nil {
+			if v, ok := ctx.BuildOutputs[build+"."+name]; ok {
+				return v, nil
+			}
+		}
+
+		return "", fmt.Errorf(
+			"output '%s' of build '%s' not available: the build must declare it, "+
+				"and be listed in depends_on", name, build)
+	}
+}
+
 func funcGenBuildType(ctx *Context) interface{} {
 	return func() (string, error) {
 		if ctx == nil || ctx.BuildType == "" {
@@ -106,6 +123,26 @@ func funcGenIsotime(ctx *Context) interface{} {
 	}
 }
 
+func funcGenLineage(ctx *Context) interface{} {
+	return func() (string, error) {
+		if ctx == nil || ctx.Lineage == "" {
+			return "", errors.New("lineage not available: the template doesn't set one")
+		}
+
+		return ctx.Lineage, nil
+	}
+}
+
+func funcGenLineageVersion(ctx *Context) interface{} {
+	return func() (string, error) {
+		if ctx == nil || ctx.Lineage == "" {
+			return "", errors.New("lineage_version not available: the template doesn't set a lineage")
+		}
+
+		return strconv.Itoa(ctx.LineageVersion), nil
+	}
+}
+
 func funcGenPrimitive(value interface{}) FuncGenerator {
 	return func(ctx *Context) interface{} {
 		return value
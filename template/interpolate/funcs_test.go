@@ -261,6 +261,48 @@ func TestFuncUser(t *testing.T) {
 	}
 }
 
+func TestFuncBuildOutput(t *testing.T) {
+	ctx := &Context{
+		BuildOutputs: map[string]string{
+			"base.ami_id": "ami-1234",
+		},
+	}
+
+	i := &I{Value: `{{build_output "base" "ami_id"}}`}
+	result, err := i.Render(ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result != "ami-1234" {
+		t.Fatalf("bad: %s", result)
+	}
+
+	i = &I{Value: `{{build_output "base" "version"}}`}
+	if _, err := i.Render(ctx); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestFuncLineage(t *testing.T) {
+	ctx := &Context{
+		Lineage:        "ubuntu-base",
+		LineageVersion: 3,
+	}
+
+	i := &I{Value: `{{lineage}}-v{{lineage_version}}`}
+	result, err := i.Render(ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result != "ubuntu-base-v3" {
+		t.Fatalf("bad: %s", result)
+	}
+
+	if _, err := i.Render(&Context{}); err == nil {
+		t.Fatal("should error without a lineage")
+	}
+}
+
 func TestFuncPackerVersion(t *testing.T) {
 	template := `{{packer_version}}`
 
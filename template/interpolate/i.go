@@ -21,6 +21,16 @@ type Context struct {
 	// EnableEnv enables the env function
 	EnableEnv bool
 
+	// BuildOutputs are the outputs of the builds the current one depends
+	// on, keyed by "<build>.<output>", that the "build_output" function
+	// reads from.
+	BuildOutputs map[string]string
+
+	// Lineage and LineageVersion are the image family the template builds
+	// versions of, and the version being built.
+	Lineage        string
+	LineageVersion int
+
 	// All the fields below are used for built-in functions.
 	//
 	// BuildName and BuildType are the name and type, respectively,
@@ -20,15 +20,22 @@ import (
 // This is what is decoded directly from the file, and then it is turned
 // into a Template object thereafter.
 type rawTemplate struct {
-	MinVersion  string `mapstructure:"min_packer_version"`
-	Description string
+	MinVersion      string `mapstructure:"min_packer_version"`
+	RequiredVersion string `mapstructure:"required_version"`
+	Description     string
 
 	Builders       []map[string]interface{}
+	BuildRetry     map[string]interface{} `mapstructure:"build_retry"`
+	Lineage        map[string]interface{}
+	PreBuild       []interface{} `mapstructure:"pre_build"`
+	PostBuild      []interface{} `mapstructure:"post_build"`
 	Push           map[string]interface{}
 	PostProcessors []interface{} `mapstructure:"post-processors"`
 	Provisioners   []map[string]interface{}
 	Variables      map[string]interface{}
 
+	RequiredCapabilities map[string]interface{} `mapstructure:"required_capabilities"`
+
 	RawContents []byte
 }
 
@@ -41,6 +48,7 @@ func (r *rawTemplate) Template() (*Template, error) {
 	// Copy some literals
 	result.Description = r.Description
 	result.MinVersion = r.MinVersion
+	result.RequiredVersion = r.RequiredVersion
 	result.RawContents = r.RawContents
 
 	// Gather the variables
@@ -79,6 +87,8 @@ func (r *rawTemplate) Template() (*Template, error) {
 		b.Config = rawB
 		delete(b.Config, "name")
 		delete(b.Config, "type")
+		delete(b.Config, "depends_on")
+		delete(b.Config, "outputs")
 		if len(b.Config) == 0 {
 			b.Config = nil
 		}
@@ -141,6 +151,7 @@ func (r *rawTemplate) Template() (*Template, error) {
 			delete(c, "except")
 			delete(c, "only")
 			delete(c, "keep_input_artifact")
+			delete(c, "input_artifact")
 			delete(c, "type")
 			if len(c) > 0 {
 				pp.Config = c
@@ -172,6 +183,7 @@ func (r *rawTemplate) Template() (*Template, error) {
 		}
 
 		// Copy the configuration
+		delete(v, "checkpoint")
 		delete(v, "except")
 		delete(v, "only")
 		delete(v, "override")
@@ -196,6 +208,43 @@ func (r *rawTemplate) Template() (*Template, error) {
 		result.Push = p
 	}
 
+	// Build retry policy
+	if len(r.BuildRetry) > 0 {
+		var retry BuildRetry
+		if err := r.decoder(&retry, nil).Decode(r.BuildRetry); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf(
+				"build_retry: %s", err))
+		}
+
+		result.BuildRetry = &retry
+	}
+
+	// Lineage
+	if len(r.Lineage) > 0 {
+		var lineage Lineage
+		if err := r.decoder(&lineage, nil).Decode(r.Lineage); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf(
+				"lineage: %s", err))
+		}
+
+		result.Lineage = &lineage
+	}
+
+	// Build hooks
+	result.PreBuild, errs = r.parseBuildHooks("pre_build", r.PreBuild, errs)
+	result.PostBuild, errs = r.parseBuildHooks("post_build", r.PostBuild, errs)
+
+	// Required capabilities
+	if len(r.RequiredCapabilities) > 0 {
+		var capabilities Capabilities
+		if err := r.decoder(&capabilities, nil).Decode(r.RequiredCapabilities); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf(
+				"required_capabilities: %s", err))
+		}
+
+		result.RequiredCapabilities = &capabilities
+	}
+
 	// If we have errors, return those with a nil result
 	if errs != nil {
 		return nil, errs
@@ -221,6 +270,35 @@ func (r *rawTemplate) decoder(
 	return d
 }
 
+// parseBuildHooks parses the hooks of the pre_build or post_build section,
+// which are either a command or an object with the command and the
+// only/except of the hook.
+func (r *rawTemplate) parseBuildHooks(
+	name string, raw []interface{}, errs error) ([]*BuildHook, error) {
+	var result []*BuildHook
+	for i, v := range raw {
+		var h BuildHook
+		switch v := v.(type) {
+		case string:
+			h.Command = v
+		case map[string]interface{}:
+			if err := r.decoder(&h, nil).Decode(v); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"%s %d: %s", name, i+1, err))
+				continue
+			}
+		default:
+			errs = multierror.Append(errs, fmt.Errorf(
+				"%s %d: bad format", name, i+1))
+			continue
+		}
+
+		result = append(result, &h)
+	}
+
+	return result, errs
+}
+
 func (r *rawTemplate) parsePostProcessor(
 	i int, raw interface{}) ([]map[string]interface{}, error) {
 	switch v := raw.(type) {
@@ -293,6 +371,13 @@ func Parse(r io.Reader) (*Template, error) {
 		return nil, err
 	}
 
+	// Check the required version before anything else, the template may
+	// use what a newer Packer supports, which this one would only report
+	// as unknown keys
+	if err := CheckRequiredVersion(rawTpl.RequiredVersion, packerVersion); err != nil {
+		return nil, err
+	}
+
 	// Build an error if there are unused root level keys
 	if len(md.Unused) > 0 {
 		sort.Strings(md.Unused)
@@ -39,6 +39,26 @@ func TestParse(t *testing.T) {
 			nil,
 			true,
 		},
+		{
+			"parse-builder-outputs.json",
+			&Template{
+				Builders: map[string]*Builder{
+					"base": {
+						Name: "base",
+						Type: "something",
+						Outputs: map[string]string{
+							"id": "{{ .ArtifactId }}",
+						},
+					},
+					"app": {
+						Name:      "app",
+						Type:      "something",
+						DependsOn: []string{"base"},
+					},
+				},
+			},
+			false,
+		},
 
 		/*
 		 * Provisioners
@@ -175,6 +195,21 @@ func TestParse(t *testing.T) {
 			false,
 		},
 
+		{
+			"parse-pp-input-artifact.json",
+			&Template{
+				PostProcessors: [][]*PostProcessor{
+					{
+						{
+							Type:          "foo",
+							InputArtifact: "vmdk",
+						},
+					},
+				},
+			},
+			false,
+		},
+
 		{
 			"parse-pp-only.json",
 			&Template{
@@ -295,6 +330,64 @@ func TestParse(t *testing.T) {
 			false,
 		},
 
+		{
+			"parse-build-retry.json",
+			&Template{
+				BuildRetry: &BuildRetry{
+					Attempts: 2,
+					On:       []string{"capacity", "throttle"},
+				},
+			},
+			false,
+		},
+
+		{
+			"parse-lineage.json",
+			&Template{
+				Lineage: &Lineage{
+					Name:     "ubuntu-base",
+					Manifest: "manifest.json",
+				},
+			},
+			false,
+		},
+
+		{
+			"parse-build-hooks.json",
+			&Template{
+				PreBuild: []*BuildHook{
+					{Command: "echo pre"},
+				},
+				PostBuild: []*BuildHook{
+					{
+						OnlyExcept: OnlyExcept{Only: []string{"foo"}},
+						Command:    "echo post",
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"parse-required-version.json",
+			&Template{
+				RequiredVersion: ">= 1.0",
+			},
+			false,
+		},
+
+		{
+			"parse-required-capabilities.json",
+			&Template{
+				RequiredCapabilities: &Capabilities{
+					Builders:       []string{"amazon-ebs"},
+					PostProcessors: []string{"manifest"},
+					Provisioners:   []string{"shell"},
+				},
+			},
+			false,
+		},
+
 		{
 			"parse-push.json",
 			&Template{
@@ -351,6 +444,38 @@ func TestParse_contents(t *testing.T) {
 	}
 }
 
+func TestParse_requiredVersion(t *testing.T) {
+	_, err := ParseFile(fixtureDir("parse-required-version-unknown-key.json"))
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.Error(), "requires Packer version >= 99.0") {
+		t.Fatalf("the version should be reported rather than the unknown key: %s", err)
+	}
+}
+
+func TestCheckRequiredVersion(t *testing.T) {
+	cases := []struct {
+		Constraint string
+		Version    string
+		Err        bool
+	}{
+		{"", "1.3.0", false},
+		{">= 1.2", "1.3.0", false},
+		{">= 1.2, < 1.3", "1.3.0", true},
+		{"~> 1.3.0", "1.3.2", false},
+		{"> 2", "1.3.0", true},
+		{"bad", "1.3.0", true},
+	}
+
+	for _, tc := range cases {
+		err := CheckRequiredVersion(tc.Constraint, tc.Version)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s %s: %v", tc.Constraint, tc.Version, err)
+		}
+	}
+}
+
 func TestParse_bad(t *testing.T) {
 	cases := []struct {
 		File     string
@@ -0,0 +1,38 @@
+package template
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+	packerversion "github.com/hashicorp/packer/version"
+)
+
+// packerVersion is the version Parse checks required_version against. It
+// is a variable for tests.
+var packerVersion = packerversion.Version
+
+// CheckRequiredVersion returns an error if the Packer version doesn't
+// satisfy the required_version constraint of a template. An empty
+// constraint is satisfied by any version.
+func CheckRequiredVersion(constraint string, v string) error {
+	if constraint == "" {
+		return nil
+	}
+
+	constraints, err := version.NewConstraint(constraint)
+	if err != nil {
+		return fmt.Errorf("required_version is invalid: %s", err)
+	}
+	actual, err := version.NewVersion(v)
+	if err != nil {
+		return fmt.Errorf("invalid Packer version %s: %s", v, err)
+	}
+
+	if !constraints.Check(actual) {
+		return fmt.Errorf(
+			"This template requires Packer version %s; using %s. Please "+
+				"install a version of Packer matching %s to use it.",
+			constraints, actual, constraints)
+	}
+	return nil
+}
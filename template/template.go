@@ -3,6 +3,7 @@ package template
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -18,21 +19,52 @@ type Template struct {
 	Description string
 	MinVersion  string
 
+	// RequiredVersion is a version constraint, such as ">= 1.3, < 2", the
+	// version of the Packer running the template has to satisfy.
+	RequiredVersion string
+
+	// RequiredCapabilities are the components the template needs the
+	// Packer running it to have.
+	RequiredCapabilities *Capabilities
+
 	Variables      map[string]*Variable
 	Builders       map[string]*Builder
 	Provisioners   []*Provisioner
 	PostProcessors [][]*PostProcessor
 	Push           Push
+	BuildRetry     *BuildRetry
+	Lineage        *Lineage
+
+	// PreBuild and PostBuild are the local commands run before each build
+	// starts and after it finished.
+	PreBuild  []*BuildHook
+	PostBuild []*BuildHook
 
 	// RawContents is just the raw data for this template
 	RawContents []byte
 }
 
+// Capabilities are the types of the components a template needs, whether
+// they are built in or plugins.
+type Capabilities struct {
+	Builders       []string
+	PostProcessors []string `mapstructure:"post-processors"`
+	Provisioners   []string
+}
+
 // Builder represents a builder configured in the template
 type Builder struct {
 	Name   string
 	Type   string
 	Config map[string]interface{}
+
+	// DependsOn are the names of the builds that must finish before this
+	// one starts, and whose outputs it can use.
+	DependsOn []string `mapstructure:"depends_on"`
+
+	// Outputs are the values the build exports to the builds depending on
+	// it, rendered once it's finished.
+	Outputs map[string]string
 }
 
 // PostProcessor represents a post-processor within the template.
@@ -40,7 +72,8 @@ type PostProcessor struct {
 	OnlyExcept `mapstructure:",squash"`
 
 	Type              string
-	KeepInputArtifact bool `mapstructure:"keep_input_artifact"`
+	KeepInputArtifact bool   `mapstructure:"keep_input_artifact"`
+	InputArtifact     string `mapstructure:"input_artifact"`
 	Config            map[string]interface{}
 }
 
@@ -52,6 +85,40 @@ type Provisioner struct {
 	Config      map[string]interface{}
 	Override    map[string]interface{}
 	PauseBefore time.Duration `mapstructure:"pause_before"`
+
+	// Checkpoint is the group of consecutive provisioners the provisioner
+	// is in, which builders that save checkpoints save one after, instead
+	// of one after each of them.
+	Checkpoint string `mapstructure:"checkpoint"`
+}
+
+// BuildRetry is the policy for rerunning builds that failed because of
+// errors that are likely to go away by themselves.
+type BuildRetry struct {
+	// Attempts is the maximum number of times a failed build is rerun.
+	Attempts int
+
+	// On are the classes of errors that cause a build to be rerun.
+	On []string
+}
+
+// BuildHook is a command run on the machine running Packer before or after
+// a build, with environment variables describing the build.
+type BuildHook struct {
+	OnlyExcept `mapstructure:",squash"`
+
+	Command string
+}
+
+// Lineage is the image family the builds of the template produce versions
+// of.
+type Lineage struct {
+	// Name identifies the lineage, for example "ubuntu-base".
+	Name string
+
+	// Manifest is the manifest file the versions of the lineage built so
+	// far are read from.
+	Manifest string
 }
 
 // Push represents the configuration for pushing the template to Atlas.
@@ -96,6 +163,55 @@ func (t *Template) Validate() error {
 			"at least one builder must be defined"))
 	}
 
+	if t.BuildRetry != nil && t.BuildRetry.Attempts < 1 {
+		err = multierror.Append(err, errors.New(
+			"build_retry: attempts must be at least 1"))
+	}
+
+	if t.Lineage != nil && t.Lineage.Name == "" {
+		err = multierror.Append(err, errors.New(
+			"lineage: name is required"))
+	}
+
+	// Verify the build hooks
+	for _, hooks := range []struct {
+		name  string
+		hooks []*BuildHook
+	}{{"pre_build", t.PreBuild}, {"post_build", t.PostBuild}} {
+		for i, h := range hooks.hooks {
+			if h.Command == "" {
+				err = multierror.Append(err, fmt.Errorf(
+					"%s %d: command is required", hooks.name, i+1))
+			}
+			if verr := h.OnlyExcept.Validate(t); verr != nil {
+				for _, e := range multierror.Append(verr).Errors {
+					err = multierror.Append(err, fmt.Errorf(
+						"%s %d: %s", hooks.name, i+1, e))
+				}
+			}
+		}
+	}
+
+	// Verify the dependencies between builds
+	names := make([]string, 0, len(t.Builders))
+	for name := range t.Builders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, dep := range t.Builders[name].DependsOn {
+			if _, ok := t.Builders[dep]; !ok {
+				err = multierror.Append(err, fmt.Errorf(
+					"builder '%s': depends_on specified builder '%s' not found",
+					name, dep))
+			}
+		}
+		if t.dependsOn(name, name, map[string]bool{}) {
+			err = multierror.Append(err, fmt.Errorf(
+				"builder '%s': depends_on makes a cycle", name))
+		}
+	}
+
 	// Verify that the provisioner overrides target builders that exist
 	for i, p := range t.Provisioners {
 		// Validate only/except
@@ -126,12 +242,36 @@ func (t *Template) Validate() error {
 						"post-processor %d.%d: %s", i+1, j+1, e))
 				}
 			}
+
+			// The input artifact is the input of the whole chain
+			if p.InputArtifact != "" && j > 0 {
+				err = multierror.Append(err, fmt.Errorf(
+					"post-processor %d.%d: input_artifact can only be set on the first "+
+						"post-processor of a chain", i+1, j+1))
+			}
 		}
 	}
 
 	return err
 }
 
+// dependsOn reports whether the build named from depends, directly or not,
+// on the one named to.
+func (t *Template) dependsOn(from, to string, seen map[string]bool) bool {
+	b, ok := t.Builders[from]
+	if !ok || seen[from] {
+		return false
+	}
+	seen[from] = true
+
+	for _, dep := range b.DependsOn {
+		if dep == to || t.dependsOn(dep, to, seen) {
+			return true
+		}
+	}
+	return false
+}
+
 // Skip says whether or not to skip the build with the given name.
 func (o *OnlyExcept) Skip(n string) bool {
 	if len(o.Only) > 0 {
@@ -196,6 +336,14 @@ func (p *PostProcessor) GoString() string {
 	return fmt.Sprintf("*%#v", *p)
 }
 
+func (r *BuildRetry) GoString() string {
+	return fmt.Sprintf("*%#v", *r)
+}
+
+func (h *BuildHook) GoString() string {
+	return fmt.Sprintf("*%#v", *h)
+}
+
 func (v *Variable) GoString() string {
 	return fmt.Sprintf("*%#v", *v)
 }
@@ -28,11 +28,41 @@ func TestTemplateValidate(t *testing.T) {
 			true,
 		},
 
+		{
+			"validate-bad-build-retry.json",
+			true,
+		},
+
+		{
+			"validate-bad-lineage.json",
+			true,
+		},
+
+		{
+			"validate-bad-build-hook.json",
+			true,
+		},
+
 		{
 			"validate-good-override.json",
 			false,
 		},
 
+		{
+			"validate-good-depends-on.json",
+			false,
+		},
+
+		{
+			"validate-bad-depends-on.json",
+			true,
+		},
+
+		{
+			"validate-bad-depends-on-cycle.json",
+			true,
+		},
+
 		{
 			"validate-bad-prov-only.json",
 			true,
@@ -72,6 +102,16 @@ func TestTemplateValidate(t *testing.T) {
 			"validate-good-pp-except.json",
 			false,
 		},
+
+		{
+			"validate-bad-pp-input-artifact.json",
+			true,
+		},
+
+		{
+			"validate-good-pp-input-artifact.json",
+			false,
+		},
 	}
 
 	for _, tc := range cases {